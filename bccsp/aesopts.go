@@ -80,3 +80,12 @@ type AESCBCPKCS7ModeOpts struct {
 	// It is used only if different from nil.
 	PRNG io.Reader
 }
+
+// AESGCMModeOpts contains options for AES encryption in GCM mode.
+// The BCCSP implementation is supposed to sample the nonce using a
+// cryptographic secure PRNG, and to prepend it to the ciphertext.
+type AESGCMModeOpts struct {
+	// AdditionalData is authenticated along with the plaintext, but not
+	// encrypted. The same additional data must be supplied to decrypt.
+	AdditionalData []byte
+}
@@ -0,0 +1,384 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package approval gates the sensitive operations on flagged keys, such as
+// their deletion or export, behind the signed approvals of a number of
+// approvers. An operation on a flagged key is first queued as pending, and
+// is only executed once enough approvers signed it.
+package approval
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Kinds of the operations gated by approvals.
+const (
+	Delete = "delete"
+	Export = "export"
+)
+
+// Policy designates the keys whose operations require approvals, and the
+// approvers.
+type Policy struct {
+	// Keys are the SKIs of the flagged keys.
+	Keys [][]byte
+	// Approvers are the certificates of the approvers.
+	Approvers []*x509.Certificate
+	// Required is the number of distinct approvers who must approve an
+	// operation.
+	Required int
+	// Expiry is the time after which a pending operation is discarded, or
+	// zero for pending operations to never expire.
+	Expiry time.Duration
+}
+
+// Operation is an operation on a flagged key.
+type Operation struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	SKI       []byte     `json:"ski"`
+	Requested time.Time  `json:"requested"`
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+// Approval is the approval of an operation by an approver.
+type Approval struct {
+	// Approver is the PEM encoded certificate of the approver.
+	Approver []byte `json:"approver"`
+	// Signature is the signature of the message of the operation, by the
+	// key of the approver.
+	Signature []byte    `json:"signature"`
+	Time      time.Time `json:"time"`
+}
+
+// Message returns the message approvers sign to approve the operation.
+func (op *Operation) Message() []byte {
+	return []byte(fmt.Sprintf("approve %s %s %x %d", op.ID, op.Kind, op.SKI, op.Requested.UnixNano()))
+}
+
+// PendingError is returned for the operations on flagged keys that did not
+// get enough approvals yet.
+type PendingError struct {
+	Operation *Operation
+	Required  int
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("%s of key %x requires the approval of %d approvers and has %d: operation %s is pending",
+		e.Operation.Kind, e.Operation.SKI, e.Required, len(e.Operation.Approvals), e.Operation.ID)
+}
+
+// Gate gates the operations on the flagged keys of its policy. The pending
+// operations are queued in a directory, one file per operation.
+type Gate struct {
+	csp    bccsp.BCCSP
+	policy Policy
+	dir    string
+	mutex  sync.Mutex
+}
+
+// New returns a gate enforcing the policy, which verifies the signatures of
+// the approvers with the BCCSP and queues the pending operations in dir.
+func New(csp bccsp.BCCSP, policy Policy, dir string) (*Gate, error) {
+	switch {
+	case csp == nil:
+		return nil, errors.New("a BCCSP is required")
+	case policy.Required < 1:
+		return nil, errors.Errorf("invalid number of required approvals %d: must be at least 1", policy.Required)
+	case policy.Required > len(policy.Approvers):
+		return nil, errors.Errorf("%d approvals are required but there are only %d approvers", policy.Required, len(policy.Approvers))
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed creating the queue of pending operations %s", dir)
+	}
+	return &Gate{csp: csp, policy: policy, dir: dir}, nil
+}
+
+// Flagged returns whether the operations on the key require approvals.
+func (g *Gate) Flagged(ski []byte) bool {
+	for _, flagged := range g.policy.Keys {
+		if bytes.Equal(flagged, ski) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize authorizes an operation on a key. Operations on keys that are not
+// flagged are authorized right away, and nil is returned. Otherwise, the
+// approved operation is returned, to be completed once executed, or a
+// PendingError when it is not approved yet, the operation being queued
+// when first requested.
+func (g *Gate) Authorize(kind string, ski []byte) (*Operation, error) {
+	if !g.Flagged(ski) {
+		return nil, nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ops, err := g.pending()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.Kind != kind || !bytes.Equal(op.SKI, ski) {
+			continue
+		}
+		if len(op.Approvals) < g.policy.Required {
+			return nil, &PendingError{Operation: op, Required: g.policy.Required}
+		}
+		return op, nil
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed generating operation identifier")
+	}
+	op := &Operation{
+		ID:        hex.EncodeToString(id),
+		Kind:      kind,
+		SKI:       ski,
+		Requested: time.Now().UTC(),
+	}
+	if err := g.save(op); err != nil {
+		return nil, err
+	}
+	return nil, &PendingError{Operation: op, Required: g.policy.Required}
+}
+
+// Complete removes an executed operation from the queue, so that the
+// approvals it got are not used again.
+func (g *Gate) Complete(op *Operation) error {
+	if op == nil {
+		return nil
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.remove(op.ID)
+}
+
+// Approve adds the approval of an approver to a pending operation, after
+// verifying that the approver is one of the policy and that the signature
+// is valid.
+func (g *Gate) Approve(id string, approver []byte, signature []byte) (*Operation, error) {
+	cert, err := parseCertificate(approver)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid approver certificate")
+	}
+	if !g.isApprover(cert) {
+		return nil, errors.Errorf("%s is not an approver", cert.Subject)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	op, err := g.operation(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, approval := range op.Approvals {
+		other, err := parseCertificate(approval.Approver)
+		if err == nil && other.Equal(cert) {
+			return nil, errors.Errorf("operation %s is already approved by %s", id, cert.Subject)
+		}
+	}
+	if err := g.verify(op, cert, signature); err != nil {
+		return nil, err
+	}
+
+	op.Approvals = append(op.Approvals, Approval{
+		Approver:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		Signature: signature,
+		Time:      time.Now().UTC(),
+	})
+	if err := g.save(op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// Pending returns the pending operations, in the order they were requested.
+func (g *Gate) Pending() ([]*Operation, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.pending()
+}
+
+// Operation returns a pending operation.
+func (g *Gate) Operation(id string) (*Operation, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.operation(id)
+}
+
+// Required returns the number of approvals an operation requires.
+func (g *Gate) Required() int {
+	return g.policy.Required
+}
+
+func (g *Gate) isApprover(cert *x509.Certificate) bool {
+	for _, approver := range g.policy.Approvers {
+		if approver.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gate) verify(op *Operation, cert *x509.Certificate, signature []byte) error {
+	pk, err := g.csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return errors.WithMessagef(err, "failed importing the key of %s", cert.Subject)
+	}
+	digest, err := g.csp.Hash(op.Message(), &bccsp.SHA256Opts{})
+	if err != nil {
+		return errors.WithMessage(err, "failed hashing the operation")
+	}
+	valid, err := g.csp.Verify(pk, signature, digest, nil)
+	if err != nil {
+		return errors.WithMessagef(err, "failed verifying the approval of %s", cert.Subject)
+	}
+	if !valid {
+		return errors.Errorf("the approval of %s is not a valid signature of operation %s", cert.Subject, op.ID)
+	}
+	return nil
+}
+
+// pending loads the queued operations, discarding the expired ones
+func (g *Gate) pending() ([]*Operation, error) {
+	files, err := ioutil.ReadDir(g.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading the queue of pending operations %s", g.dir)
+	}
+	var ops []*Operation
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		op, err := g.load(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if g.expired(op) {
+			if err := g.remove(op.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Requested.Before(ops[j].Requested)
+	})
+	return ops, nil
+}
+
+// operation loads a pending operation, discarding it if expired
+func (g *Gate) operation(id string) (*Operation, error) {
+	op, err := g.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if g.expired(op) {
+		if err := g.remove(op.ID); err != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("operation %s is not pending", id)
+	}
+	return op, nil
+}
+
+func (g *Gate) expired(op *Operation) bool {
+	return g.policy.Expiry != 0 && time.Since(op.Requested) > g.policy.Expiry
+}
+
+func (g *Gate) path(id string) string {
+	return filepath.Join(g.dir, id+".json")
+}
+
+func (g *Gate) load(id string) (*Operation, error) {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, errors.Errorf("invalid operation identifier %s", id)
+	}
+	raw, err := ioutil.ReadFile(g.path(id))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("operation %s is not pending", id)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading operation %s", id)
+	}
+	op := &Operation{}
+	if err := json.Unmarshal(raw, op); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing operation %s", id)
+	}
+	if op.ID != id {
+		return nil, errors.Errorf("operation %s is stored as %s", op.ID, id)
+	}
+	return op, nil
+}
+
+func (g *Gate) save(op *Operation) error {
+	raw, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed marshaling operation %s", op.ID)
+	}
+	tmp := g.path(op.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing operation %s", op.ID)
+	}
+	return errors.Wrapf(os.Rename(tmp, g.path(op.ID)), "failed writing operation %s", op.ID)
+}
+
+func (g *Gate) remove(id string) error {
+	err := os.Remove(g.path(id))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed removing operation %s", id)
+	}
+	return nil
+}
+
+func parseCertificate(raw []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	}
+	return x509.ParseCertificate(raw)
+}
+
+// KeyManager is a bccsp.KeyManager whose deletion of flagged keys requires
+// the approvals of the policy of its gate.
+type KeyManager struct {
+	bccsp.KeyManager
+	Gate *Gate
+}
+
+// DeleteKey deletes the key once approved.
+func (m *KeyManager) DeleteKey(ski []byte) error {
+	op, err := m.Gate.Authorize(Delete, ski)
+	if err != nil {
+		return err
+	}
+	if err := m.KeyManager.DeleteKey(ski); err != nil {
+		return err
+	}
+	return m.Gate.Complete(op)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package approval
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+type approver struct {
+	cert   *x509.Certificate
+	pem    []byte
+	signer crypto.Signer
+}
+
+func newApprover(t *testing.T, csp bccsp.BCCSP, name string) *approver {
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	s, err := signer.New(csp, k)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &approver{
+		cert:   cert,
+		pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		signer: s,
+	}
+}
+
+func (a *approver) sign(t *testing.T, op *Operation) []byte {
+	digest := sha256.Sum256(op.Message())
+	signature, err := a.signer.Sign(rand.Reader, digest[:], nil)
+	require.NoError(t, err)
+	return signature
+}
+
+type fakeKeyManager struct {
+	deleted [][]byte
+}
+
+func (f *fakeKeyManager) ListKeys() ([]bccsp.Key, error) { return nil, nil }
+
+func (f *fakeKeyManager) DeleteKey(ski []byte) error {
+	f.deleted = append(f.deleted, ski)
+	return nil
+}
+
+func newTestGate(t *testing.T, csp bccsp.BCCSP, policy Policy) (*Gate, func()) {
+	dir, err := ioutil.TempDir("", "approval")
+	require.NoError(t, err)
+	gate, err := New(csp, policy, dir)
+	require.NoError(t, err)
+	return gate, func() { os.RemoveAll(dir) }
+}
+
+func TestApproval(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	alice, bob, carol := newApprover(t, csp, "alice"), newApprover(t, csp, "bob"), newApprover(t, csp, "carol")
+	flagged, other := []byte("flagged key"), []byte("other key")
+
+	gate, cleanup := newTestGate(t, csp, Policy{
+		Keys:      [][]byte{flagged},
+		Approvers: []*x509.Certificate{alice.cert, bob.cert, carol.cert},
+		Required:  2,
+	})
+	defer cleanup()
+	km := &KeyManager{KeyManager: &fakeKeyManager{}, Gate: gate}
+
+	// keys that are not flagged are deleted right away
+	require.NoError(t, km.DeleteKey(other))
+	require.Equal(t, [][]byte{other}, km.KeyManager.(*fakeKeyManager).deleted)
+
+	// the deletion of a flagged key is queued until approved
+	err = km.DeleteKey(flagged)
+	require.IsType(t, &PendingError{}, err)
+	op := err.(*PendingError).Operation
+	require.Equal(t, Delete, op.Kind)
+	require.EqualError(t, err, "delete of key 666c6167676564206b6579 requires the approval of 2 approvers and has 0: operation "+op.ID+" is pending")
+
+	ops, err := gate.Pending()
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, op.ID, ops[0].ID)
+
+	// requesting again returns the same pending operation
+	err = km.DeleteKey(flagged)
+	require.Equal(t, op.ID, err.(*PendingError).Operation.ID)
+
+	_, err = gate.Approve(op.ID, alice.pem, alice.sign(t, op))
+	require.NoError(t, err)
+	_, err = gate.Approve(op.ID, alice.pem, alice.sign(t, op))
+	require.EqualError(t, err, "operation "+op.ID+" is already approved by CN=alice")
+	err = km.DeleteKey(flagged)
+	require.EqualError(t, err, "delete of key 666c6167676564206b6579 requires the approval of 2 approvers and has 1: operation "+op.ID+" is pending")
+
+	_, err = gate.Approve(op.ID, carol.pem, carol.sign(t, op))
+	require.NoError(t, err)
+	require.NoError(t, km.DeleteKey(flagged))
+	require.Equal(t, [][]byte{other, flagged}, km.KeyManager.(*fakeKeyManager).deleted)
+
+	// the approvals are consumed by the execution of the operation
+	ops, err = gate.Pending()
+	require.NoError(t, err)
+	require.Empty(t, ops)
+	_, err = gate.Approve(op.ID, bob.pem, bob.sign(t, op))
+	require.EqualError(t, err, "operation "+op.ID+" is not pending")
+}
+
+func TestApproveFailures(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	alice, bob, mallory := newApprover(t, csp, "alice"), newApprover(t, csp, "bob"), newApprover(t, csp, "mallory")
+	flagged := []byte("flagged key")
+
+	gate, cleanup := newTestGate(t, csp, Policy{
+		Keys:      [][]byte{flagged},
+		Approvers: []*x509.Certificate{alice.cert, bob.cert},
+		Required:  2,
+	})
+	defer cleanup()
+
+	_, err = gate.Authorize(Export, flagged)
+	op := err.(*PendingError).Operation
+	require.Equal(t, Export, op.Kind)
+
+	_, err = gate.Approve(op.ID, mallory.pem, mallory.sign(t, op))
+	require.EqualError(t, err, "CN=mallory is not an approver")
+
+	_, err = gate.Approve(op.ID, bob.pem, alice.sign(t, op))
+	require.EqualError(t, err, "the approval of CN=bob is not a valid signature of operation "+op.ID)
+
+	other := *op
+	other.ID = "00"
+	_, err = gate.Approve(op.ID, alice.pem, alice.sign(t, &other))
+	require.EqualError(t, err, "the approval of CN=alice is not a valid signature of operation "+op.ID)
+
+	_, err = gate.Approve("../../etc/passwd", alice.pem, alice.sign(t, op))
+	require.EqualError(t, err, "invalid operation identifier ../../etc/passwd")
+
+	_, err = gate.Approve(op.ID, []byte("garbage"), nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid approver certificate")
+}
+
+func TestExpiry(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	alice := newApprover(t, csp, "alice")
+	flagged := []byte("flagged key")
+
+	gate, cleanup := newTestGate(t, csp, Policy{
+		Keys:      [][]byte{flagged},
+		Approvers: []*x509.Certificate{alice.cert},
+		Required:  1,
+		Expiry:    time.Millisecond,
+	})
+	defer cleanup()
+
+	_, err = gate.Authorize(Delete, flagged)
+	op := err.(*PendingError).Operation
+	pending, err := gate.Operation(op.ID)
+	require.NoError(t, err)
+	require.Equal(t, op.Requested.UnixNano(), pending.Requested.UnixNano())
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = gate.Approve(op.ID, alice.pem, alice.sign(t, op))
+	require.EqualError(t, err, "operation "+op.ID+" is not pending")
+	_, err = gate.Operation(op.ID)
+	require.EqualError(t, err, "operation "+op.ID+" is not pending")
+
+	_, err = gate.Authorize(Delete, flagged)
+	op = err.(*PendingError).Operation
+	time.Sleep(10 * time.Millisecond)
+	ops, err := gate.Pending()
+	require.NoError(t, err)
+	require.Empty(t, ops)
+	_, err = gate.Approve(op.ID, alice.pem, alice.sign(t, op))
+	require.EqualError(t, err, "operation "+op.ID+" is not pending")
+}
+
+func TestNew(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	alice := newApprover(t, csp, "alice")
+
+	_, err = New(nil, Policy{}, "")
+	require.EqualError(t, err, "a BCCSP is required")
+	_, err = New(csp, Policy{Approvers: []*x509.Certificate{alice.cert}}, "")
+	require.EqualError(t, err, "invalid number of required approvals 0: must be at least 1")
+	_, err = New(csp, Policy{Approvers: []*x509.Certificate{alice.cert}, Required: 2}, "")
+	require.EqualError(t, err, "2 approvals are required but there are only 1 approvers")
+}
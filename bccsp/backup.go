@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"io"
+	"time"
+)
+
+// BackupOpts are the options of the backup and restore of the keys of a
+// provider.
+type BackupOpts struct {
+	// Passphrase encrypts the backup.
+	Passphrase []byte
+	// Signer is the private key signing the manifest of the backup. It is
+	// required to export a backup.
+	Signer Key
+	// Trusted are the public keys whose signatures of the manifest are
+	// accepted on restore. When empty, the signature of the manifest is
+	// verified with the public key of the signer embedded in the backup.
+	Trusted []Key
+}
+
+// BackupManifest describes the keys of a backup. The manifest is signed, and
+// the keys are checked against it before any of them is restored.
+type BackupManifest struct {
+	// Created is the time the backup was exported.
+	Created time.Time `json:"created"`
+	// Keys are the keys of the backup, sorted by SKI.
+	Keys []BackupEntry `json:"keys"`
+	// Signer is the DER encoded public key of the signer of the manifest.
+	Signer []byte `json:"signer"`
+	// Signature is the signature of the manifest, without its signature.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// BackupEntry describes a key of a backup.
+type BackupEntry struct {
+	SKI []byte `json:"ski"`
+	// Type is private, public or symmetric.
+	Type string `json:"type"`
+	// Algorithm is the algorithm of the key, such as ECDSA, ED25519 or AES.
+	Algorithm string `json:"algorithm"`
+	// Digest is the SHA-256 digest of the encoded key.
+	Digest []byte `json:"digest"`
+}
+
+// BackupManager is implemented by the BCCSP providers which back up the keys
+// of their key store to a single encrypted archive, and restore them.
+type BackupManager interface {
+	// ExportBackup writes the keys of the key store to w, encrypted with the
+	// passphrase of the options, along with a manifest signed by the signer
+	// of the options.
+	ExportBackup(w io.Writer, opts *BackupOpts) (*BackupManifest, error)
+
+	// ImportBackup verifies the backup read from r, and restores its keys to
+	// the key store. Keys already held by the key store are left untouched.
+	ImportBackup(r io.Reader, opts *BackupOpts) (*BackupManifest, error)
+}
@@ -34,6 +34,14 @@ type Key interface {
 	PublicKey() (Key, error)
 }
 
+// KeyFingerprinter is implemented by the asymmetric keys that report the
+// fingerprint of their public key, that is the SHA-256 digest of its DER
+// encoded SubjectPublicKeyInfo, for allow-lists and audit tooling.
+type KeyFingerprinter interface {
+	// Fingerprint returns the fingerprint of the public key of this key.
+	Fingerprint() ([]byte, error)
+}
+
 // KeyGenOpts contains options for key-generation with a CSP.
 type KeyGenOpts interface {
 
@@ -132,3 +140,63 @@ type BCCSP interface {
 	// The opts argument should be appropriate for the algorithm used.
 	Decrypt(k Key, ciphertext []byte, opts DecrypterOpts) (plaintext []byte, err error)
 }
+
+// VerifyRequest carries the inputs of a single signature verification
+// in a batch of verifications.
+type VerifyRequest struct {
+	Key       Key
+	Signature []byte
+	Digest    []byte
+	Opts      SignerOpts
+}
+
+// VerifyResult carries the outcome of a single signature verification
+// in a batch of verifications.
+type VerifyResult struct {
+	Valid bool
+	Err   error
+}
+
+// BatchVerifier is implemented by the BCCSP implementations that can
+// verify many signatures at once, for instance, by spreading the
+// verifications across a pool of workers.
+type BatchVerifier interface {
+	// VerifyBatch verifies the signatures of all the requests and returns
+	// the results in the order of the requests.
+	VerifyBatch(requests []*VerifyRequest) []*VerifyResult
+}
+
+// KeyWrapper is implemented by the BCCSP implementations that export their
+// keys wrapped, that is encrypted, with another of their keys, so that the
+// key material never leaves them in the clear.
+type KeyWrapper interface {
+	// WrapKey returns the material of key k encrypted with the wrapping key.
+	WrapKey(k Key, wrapping Key) (wrapped []byte, err error)
+}
+
+// Committer is implemented by the BCCSP implementations that support
+// Pedersen commitments to values. The blinding factors of the commitments
+// are keys generated with PedersenBlindingKeyGenOpts or imported with
+// PedersenBlindingImportOpts.
+type Committer interface {
+	// Commit commits to the value with the blinding factor.
+	Commit(value uint64, blinding Key) (commitment []byte, err error)
+
+	// VerifyOpening returns whether the commitment opens to the value
+	// with the blinding factor.
+	VerifyOpening(commitment []byte, value uint64, blinding Key) (valid bool, err error)
+
+	// AddCommitments returns the commitment to the sum of the values of
+	// the commitments, blinded by the sum of their blinding factors.
+	AddCommitments(commitments ...[]byte) (commitment []byte, err error)
+
+	// SubCommitments returns the commitment to the difference of the values
+	// of the commitments, blinded by the difference of their blinding factors.
+	SubCommitments(c1, c2 []byte) (commitment []byte, err error)
+
+	// AddBlindings returns the sum of the blinding factors.
+	AddBlindings(blindings ...Key) (blinding Key, err error)
+
+	// SubBlindings returns the difference of the blinding factors.
+	SubBlindings(b1, b2 Key) (blinding Key, err error)
+}
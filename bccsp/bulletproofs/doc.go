@@ -0,0 +1,15 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package bulletproofs implements Pedersen commitments over P-256 and Bulletproofs
+// range proofs, which prove that a committed value lies in [0, 2^n) without revealing
+// it, in a proof of size logarithmic in n.
+//
+// Together with the homomorphism of the commitments, range proofs enable confidential
+// amounts: a transaction committing to its inputs and outputs proves that the outputs
+// balance the inputs by opening the difference of the commitments to zero, and that
+// no output is negative by proving the range of each output.
+package bulletproofs
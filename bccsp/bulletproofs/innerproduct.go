@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/pkg/errors"
+)
+
+// innerProductProof proves the knowledge of vectors a and b such that
+// P = <a, G> + <b, H> + <a, b>*Q, in log2(n) rounds halving the vectors
+type innerProductProof struct {
+	ls, rs []p256.Point
+	a, b   *big.Int
+}
+
+func proveInnerProduct(t *transcript, gs, hs []p256.Point, q p256.Point, a, b []*big.Int) *innerProductProof {
+	proof := &innerProductProof{}
+	for n := len(a); n > 1; n = n / 2 {
+		m := n / 2
+		cL := p256.InnerProduct(a[:m], b[m:])
+		cR := p256.InnerProduct(a[m:], b[:m])
+		l := p256.MultiExp(a[:m], gs[m:]).Add(p256.MultiExp(b[m:], hs[:m])).Add(q.Mul(cL))
+		r := p256.MultiExp(a[m:], gs[:m]).Add(p256.MultiExp(b[:m], hs[m:])).Add(q.Mul(cR))
+		proof.ls = append(proof.ls, l)
+		proof.rs = append(proof.rs, r)
+
+		t.appendPoints(l, r)
+		u := t.challenge()
+		uInv := p256.ScalarInverse(u)
+
+		gs, hs = fold(gs, uInv, u), fold(hs, u, uInv)
+		a = foldScalars(a, u, uInv)
+		b = foldScalars(b, uInv, u)
+	}
+	proof.a, proof.b = a[0], b[0]
+	return proof
+}
+
+func (proof *innerProductProof) verify(t *transcript, gs, hs []p256.Point, q, p p256.Point) error {
+	if len(gs) != 1<<uint(len(proof.ls)) || len(proof.ls) != len(proof.rs) {
+		return errors.Errorf("expected %d rounds of the inner product argument", log2(len(gs)))
+	}
+	for i := range proof.ls {
+		t.appendPoints(proof.ls[i], proof.rs[i])
+		u := t.challenge()
+		uInv := p256.ScalarInverse(u)
+		u2, uInv2 := p256.ScalarMul(u, u), p256.ScalarMul(uInv, uInv)
+
+		gs, hs = fold(gs, uInv, u), fold(hs, u, uInv)
+		p = proof.ls[i].Mul(u2).Add(p).Add(proof.rs[i].Mul(uInv2))
+	}
+
+	expected := gs[0].Mul(proof.a).Add(hs[0].Mul(proof.b)).Add(q.Mul(p256.ScalarMul(proof.a, proof.b)))
+	if !expected.Equal(p) {
+		return errors.New("the inner product argument is invalid")
+	}
+	return nil
+}
+
+// fold returns the vector x*v[:n/2] + y*v[n/2:]
+func fold(v []p256.Point, x, y *big.Int) []p256.Point {
+	m := len(v) / 2
+	folded := make([]p256.Point, m)
+	for i := 0; i < m; i++ {
+		folded[i] = v[i].Mul(x).Add(v[m+i].Mul(y))
+	}
+	return folded
+}
+
+// foldScalars returns the vector x*v[:n/2] + y*v[n/2:]
+func foldScalars(v []*big.Int, x, y *big.Int) []*big.Int {
+	m := len(v) / 2
+	folded := make([]*big.Int, m)
+	for i := 0; i < m; i++ {
+		folded[i] = p256.ScalarAdd(p256.ScalarMul(v[i], x), p256.ScalarMul(v[m+i], y))
+	}
+	return folded
+}
+
+func log2(n int) int {
+	k := 0
+	for ; n > 1; n = n / 2 {
+		k++
+	}
+	return k
+}
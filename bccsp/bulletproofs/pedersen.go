@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/pkg/errors"
+)
+
+var (
+	// g is the generator committing to the values, the base point of P-256
+	g = p256.Generator()
+	// h is the generator committing to the blinding factors
+	h = p256.HashToPoint("fabric/bulletproofs/h")
+)
+
+// Commitment is a Pedersen commitment v*G + r*H to a value v with a blinding factor r.
+// Commitments are additively homomorphic: the sum of the commitments to two values
+// is a commitment to the sum of the values, blinded by the sum of the blinding factors.
+type Commitment struct {
+	p p256.Point
+}
+
+// Commit commits to the value with the blinding factor, which must be uniformly random
+// and kept secret for the commitment to hide the value.
+func Commit(value uint64, blinding *big.Int) *Commitment {
+	v := new(big.Int).SetUint64(value)
+	return &Commitment{p: g.Mul(v).Add(h.Mul(blinding))}
+}
+
+// NewBlinding returns a random blinding factor.
+func NewBlinding() (*big.Int, error) {
+	return p256.RandomScalar()
+}
+
+// BlindingBytes returns the 32 byte big endian encoding of the blinding factor.
+func BlindingBytes(blinding *big.Int) []byte {
+	return p256.ScalarBytes(new(big.Int).Mod(blinding, p256.Order))
+}
+
+// ParseBlinding parses the encoding of a blinding factor.
+func ParseBlinding(raw []byte) (*big.Int, error) {
+	if len(raw) != 32 {
+		return nil, errors.Errorf("invalid blinding factor size %d, expected 32", len(raw))
+	}
+	blinding, err := p256.ParseScalar(raw)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid blinding factor")
+	}
+	return blinding, nil
+}
+
+// AddBlindings returns the blinding factor of the sum of two commitments.
+func AddBlindings(r1, r2 *big.Int) *big.Int {
+	return p256.ScalarAdd(r1, r2)
+}
+
+// SubBlindings returns the blinding factor of the difference of two commitments.
+func SubBlindings(r1, r2 *big.Int) *big.Int {
+	return p256.ScalarSub(r1, r2)
+}
+
+// Add returns the commitment to the sum of the committed values.
+func (c *Commitment) Add(other *Commitment) *Commitment {
+	return &Commitment{p: c.p.Add(other.p)}
+}
+
+// Sub returns the commitment to the difference of the committed values.
+func (c *Commitment) Sub(other *Commitment) *Commitment {
+	return &Commitment{p: c.p.Add(other.p.Neg())}
+}
+
+// Equal returns whether the commitments are to the same value with the same blinding factor.
+func (c *Commitment) Equal(other *Commitment) bool {
+	return c.p.Equal(other.p)
+}
+
+// Bytes returns the encoding of the commitment.
+func (c *Commitment) Bytes() []byte {
+	return c.p.Bytes()
+}
+
+// ParseCommitment parses the encoding of a commitment.
+func ParseCommitment(raw []byte) (*Commitment, error) {
+	p, err := p256.ParsePoint(raw)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid commitment")
+	}
+	return &Commitment{p: p}, nil
+}
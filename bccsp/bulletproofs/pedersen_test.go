@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitmentHomomorphism(t *testing.T) {
+	r1, err := NewBlinding()
+	require.NoError(t, err)
+	r2, err := NewBlinding()
+	require.NoError(t, err)
+
+	c1 := Commit(30, r1)
+	c2 := Commit(12, r2)
+	require.False(t, c1.Equal(c2))
+	require.True(t, c1.Add(c2).Equal(Commit(42, AddBlindings(r1, r2))))
+	require.True(t, c1.Sub(c2).Equal(Commit(18, SubBlindings(r1, r2))))
+	require.False(t, c1.Add(c2).Equal(Commit(43, AddBlindings(r1, r2))))
+
+	// a commitment to zero with a zero blinding factor is the point at infinity
+	zero := c1.Sub(c1)
+	require.True(t, zero.Equal(Commit(0, SubBlindings(r1, r1))))
+	parsed, err := ParseCommitment(zero.Bytes())
+	require.NoError(t, err)
+	require.True(t, parsed.Equal(zero))
+}
+
+func TestParseCommitment(t *testing.T) {
+	r, err := NewBlinding()
+	require.NoError(t, err)
+	c := Commit(7, r)
+
+	parsed, err := ParseCommitment(c.Bytes())
+	require.NoError(t, err)
+	require.True(t, parsed.Equal(c))
+
+	_, err = ParseCommitment(c.Bytes()[:10])
+	require.EqualError(t, err, "invalid commitment: invalid point size 10, expected 65")
+
+	raw := c.Bytes()
+	raw[10] ^= 0xFF
+	_, err = ParseCommitment(raw)
+	require.EqualError(t, err, "invalid commitment: invalid point, not on the curve")
+}
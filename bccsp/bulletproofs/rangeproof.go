@@ -0,0 +1,281 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/pkg/errors"
+)
+
+// Params are the public generators of the range proofs of values of a given bit size.
+// The generators are derived deterministically, so that provers and verifiers agree on
+// them without any setup.
+type Params struct {
+	bits int
+	gs   []p256.Point
+	hs   []p256.Point
+	u    p256.Point
+}
+
+// NewParams returns the parameters proving that values lie in [0, 2^bits),
+// bits being 8, 16, 32 or 64.
+func NewParams(bits int) (*Params, error) {
+	switch bits {
+	case 8, 16, 32, 64:
+	default:
+		return nil, errors.Errorf("unsupported bit size %d, expected 8, 16, 32 or 64", bits)
+	}
+
+	params := &Params{
+		bits: bits,
+		gs:   make([]p256.Point, bits),
+		hs:   make([]p256.Point, bits),
+		u:    p256.HashToPoint("fabric/bulletproofs/u"),
+	}
+	for i := 0; i < bits; i++ {
+		params.gs[i] = p256.HashToPoint(fmt.Sprintf("fabric/bulletproofs/g/%d", i))
+		params.hs[i] = p256.HashToPoint(fmt.Sprintf("fabric/bulletproofs/h/%d", i))
+	}
+	return params, nil
+}
+
+// Bits returns the bit size of the values the parameters prove the range of.
+func (params *Params) Bits() int {
+	return params.bits
+}
+
+// RangeProof proves that a commitment is to a value in [0, 2^bits) without revealing it.
+type RangeProof struct {
+	a, s, t1, t2   p256.Point
+	taux, mu, tHat *big.Int
+	ipp            *innerProductProof
+}
+
+// Prove proves that the commitment to the value with the blinding factor
+// is to a value in the range of the parameters.
+func (params *Params) Prove(value uint64, blinding *big.Int) (*RangeProof, error) {
+	n := params.bits
+	if n < 64 && value>>uint(n) != 0 {
+		return nil, errors.Errorf("value %d is out of the range of %d bits", value, n)
+	}
+	v := Commit(value, blinding)
+
+	// aL are the bits of the value, and aR = aL - 1
+	aL := make([]*big.Int, n)
+	aR := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		aL[i] = big.NewInt(int64((value >> uint(i)) & 1))
+		aR[i] = p256.ScalarSub(aL[i], big.NewInt(1))
+	}
+
+	random, err := randomScalars(2*n + 4)
+	if err != nil {
+		return nil, err
+	}
+	alpha, rho, tau1, tau2 := random[0], random[1], random[2], random[3]
+	sL, sR := random[4:4+n], random[4+n:]
+
+	proof := &RangeProof{}
+	proof.a = h.Mul(alpha).Add(p256.MultiExp(aL, params.gs)).Add(p256.MultiExp(aR, params.hs))
+	proof.s = h.Mul(rho).Add(p256.MultiExp(sL, params.gs)).Add(p256.MultiExp(sR, params.hs))
+
+	t := params.transcript(v.p)
+	t.appendPoints(proof.a, proof.s)
+	y := t.challenge()
+	z := t.challenge()
+	z2 := p256.ScalarMul(z, z)
+	yn := p256.Powers(y, n)
+	twon := p256.Powers(big.NewInt(2), n)
+
+	// l(X) = l0 + l1*X and r(X) = r0 + r1*X, where
+	// l0 = aL - z, l1 = sL, r0 = y^n o (aR + z) + z^2*2^n and r1 = y^n o sR
+	l0 := make([]*big.Int, n)
+	r0 := make([]*big.Int, n)
+	r1 := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		l0[i] = p256.ScalarSub(aL[i], z)
+		r0[i] = p256.ScalarAdd(p256.ScalarMul(yn[i], p256.ScalarAdd(aR[i], z)), p256.ScalarMul(z2, twon[i]))
+		r1[i] = p256.ScalarMul(yn[i], sR[i])
+	}
+	// t(X) = <l(X), r(X)> = t0 + t1*X + t2*X^2
+	t1 := p256.ScalarAdd(p256.InnerProduct(l0, r1), p256.InnerProduct(sL, r0))
+	t2 := p256.InnerProduct(sL, r1)
+	proof.t1 = g.Mul(t1).Add(h.Mul(tau1))
+	proof.t2 = g.Mul(t2).Add(h.Mul(tau2))
+
+	t.appendPoints(proof.t1, proof.t2)
+	x := t.challenge()
+
+	l := make([]*big.Int, n)
+	r := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		l[i] = p256.ScalarAdd(l0[i], p256.ScalarMul(sL[i], x))
+		r[i] = p256.ScalarAdd(r0[i], p256.ScalarMul(r1[i], x))
+	}
+	proof.tHat = p256.InnerProduct(l, r)
+	proof.taux = p256.ScalarAdd(p256.ScalarAdd(p256.ScalarMul(tau2, p256.ScalarMul(x, x)), p256.ScalarMul(tau1, x)), p256.ScalarMul(z2, blinding))
+	proof.mu = p256.ScalarAdd(alpha, p256.ScalarMul(rho, x))
+
+	t.appendScalars(proof.taux, proof.mu, proof.tHat)
+	w := t.challenge()
+	proof.ipp = proveInnerProduct(t, params.gs, params.scaledHs(y), params.u.Mul(w), l, r)
+	return proof, nil
+}
+
+// Verify verifies that the proof proves that the commitment is to a value
+// in the range of the parameters.
+func (params *Params) Verify(commitment *Commitment, proof *RangeProof) error {
+	n := params.bits
+	v := commitment.p
+
+	t := params.transcript(v)
+	t.appendPoints(proof.a, proof.s)
+	y := t.challenge()
+	z := t.challenge()
+	z2 := p256.ScalarMul(z, z)
+	z3 := p256.ScalarMul(z2, z)
+	yn := p256.Powers(y, n)
+	twon := p256.Powers(big.NewInt(2), n)
+	t.appendPoints(proof.t1, proof.t2)
+	x := t.challenge()
+
+	// tHat*G + taux*H = z^2*V + delta(y,z)*G + x*T1 + x^2*T2, where
+	// delta(y,z) = (z - z^2)*<1, y^n> - z^3*<1, 2^n>
+	sumY, sumTwo := new(big.Int), new(big.Int)
+	for i := 0; i < n; i++ {
+		sumY = p256.ScalarAdd(sumY, yn[i])
+		sumTwo = p256.ScalarAdd(sumTwo, twon[i])
+	}
+	delta := p256.ScalarSub(p256.ScalarMul(p256.ScalarSub(z, z2), sumY), p256.ScalarMul(z3, sumTwo))
+	lhs := g.Mul(proof.tHat).Add(h.Mul(proof.taux))
+	rhs := v.Mul(z2).Add(g.Mul(delta)).Add(proof.t1.Mul(x)).Add(proof.t2.Mul(p256.ScalarMul(x, x)))
+	if !lhs.Equal(rhs) {
+		return errors.New("invalid range proof: the polynomial commitment does not open to the inner product")
+	}
+
+	// P = A + x*S - z*<1, G> + <z*y^n + z^2*2^n, H'> - mu*H + tHat*Q
+	// is a commitment to l and r in the generators G, H' and Q
+	t.appendScalars(proof.taux, proof.mu, proof.tHat)
+	w := t.challenge()
+	q := params.u.Mul(w)
+	hs := params.scaledHs(y)
+
+	negZ := p256.ScalarSub(new(big.Int), z)
+	gScalars := make([]*big.Int, n)
+	hScalars := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		gScalars[i] = negZ
+		hScalars[i] = p256.ScalarAdd(p256.ScalarMul(z, yn[i]), p256.ScalarMul(z2, twon[i]))
+	}
+	p := proof.a.Add(proof.s.Mul(x)).
+		Add(p256.MultiExp(gScalars, params.gs)).
+		Add(p256.MultiExp(hScalars, hs)).
+		Add(h.Mul(p256.ScalarSub(new(big.Int), proof.mu))).
+		Add(q.Mul(proof.tHat))
+	if err := proof.ipp.verify(t, params.gs, hs, q, p); err != nil {
+		return errors.WithMessage(err, "invalid range proof")
+	}
+	return nil
+}
+
+// transcript starts the transcript of a proof of the range of the commitment
+func (params *Params) transcript(v p256.Point) *transcript {
+	t := newTranscript(fmt.Sprintf("rangeproof/%d", params.bits))
+	t.appendPoints(v)
+	return t
+}
+
+// scaledHs returns the generators H'_i = y^-i * H_i
+func (params *Params) scaledHs(y *big.Int) []p256.Point {
+	yInv := p256.Powers(p256.ScalarInverse(y), params.bits)
+	hs := make([]p256.Point, params.bits)
+	for i := range hs {
+		hs[i] = params.hs[i].Mul(yInv[i])
+	}
+	return hs
+}
+
+func randomScalars(n int) ([]*big.Int, error) {
+	scalars := make([]*big.Int, n)
+	for i := range scalars {
+		k, err := p256.RandomScalar()
+		if err != nil {
+			return nil, err
+		}
+		scalars[i] = k
+	}
+	return scalars, nil
+}
+
+// Bytes returns the encoding of the proof.
+func (proof *RangeProof) Bytes() []byte {
+	var raw []byte
+	for _, p := range []p256.Point{proof.a, proof.s, proof.t1, proof.t2} {
+		raw = append(raw, p.Bytes()...)
+	}
+	for _, k := range []*big.Int{proof.taux, proof.mu, proof.tHat, proof.ipp.a, proof.ipp.b} {
+		raw = append(raw, p256.ScalarBytes(k)...)
+	}
+	for i := range proof.ipp.ls {
+		raw = append(raw, proof.ipp.ls[i].Bytes()...)
+		raw = append(raw, proof.ipp.rs[i].Bytes()...)
+	}
+	return raw
+}
+
+// ParseRangeProof parses the encoding of a proof.
+func ParseRangeProof(raw []byte) (*RangeProof, error) {
+	const fixedSize = 4*p256.PointSize + 5*32
+	if len(raw) < fixedSize || (len(raw)-fixedSize)%(2*p256.PointSize) != 0 {
+		return nil, errors.Errorf("invalid range proof: unexpected size %d", len(raw))
+	}
+
+	var points []p256.Point
+	for offset := 0; offset < 4*p256.PointSize; offset += p256.PointSize {
+		p, err := p256.ParsePoint(raw[offset : offset+p256.PointSize])
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid range proof")
+		}
+		points = append(points, p)
+	}
+	var scalars []*big.Int
+	for offset := 4 * p256.PointSize; offset < fixedSize; offset += 32 {
+		k, err := p256.ParseScalar(raw[offset : offset+32])
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid range proof")
+		}
+		scalars = append(scalars, k)
+	}
+
+	ipp := &innerProductProof{a: scalars[3], b: scalars[4]}
+	for offset := fixedSize; offset < len(raw); offset += 2 * p256.PointSize {
+		l, err := p256.ParsePoint(raw[offset : offset+p256.PointSize])
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid range proof")
+		}
+		r, err := p256.ParsePoint(raw[offset+p256.PointSize : offset+2*p256.PointSize])
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid range proof")
+		}
+		ipp.ls = append(ipp.ls, l)
+		ipp.rs = append(ipp.rs, r)
+	}
+
+	return &RangeProof{
+		a:    points[0],
+		s:    points[1],
+		t1:   points[2],
+		t2:   points[3],
+		taux: scalars[0],
+		mu:   scalars[1],
+		tHat: scalars[2],
+		ipp:  ipp,
+	}, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParams(t *testing.T) {
+	params, err := NewParams(32)
+	require.NoError(t, err)
+	require.Equal(t, 32, params.Bits())
+
+	// the generators are deterministic
+	other, err := NewParams(32)
+	require.NoError(t, err)
+	require.Equal(t, params, other)
+
+	_, err = NewParams(12)
+	require.EqualError(t, err, "unsupported bit size 12, expected 8, 16, 32 or 64")
+}
+
+func TestRangeProof(t *testing.T) {
+	tests := []struct {
+		bits  int
+		value uint64
+	}{
+		{bits: 8, value: 0},
+		{bits: 8, value: 255},
+		{bits: 16, value: 1000},
+		{bits: 32, value: math.MaxUint32},
+		{bits: 64, value: math.MaxUint64},
+	}
+	for _, test := range tests {
+		params, err := NewParams(test.bits)
+		require.NoError(t, err)
+		r, err := NewBlinding()
+		require.NoError(t, err)
+
+		proof, err := params.Prove(test.value, r)
+		require.NoError(t, err)
+		require.NoError(t, params.Verify(Commit(test.value, r), proof))
+
+		parsed, err := ParseRangeProof(proof.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, proof, parsed)
+		require.NoError(t, params.Verify(Commit(test.value, r), parsed))
+	}
+}
+
+func TestRangeProofOutOfRange(t *testing.T) {
+	params, err := NewParams(8)
+	require.NoError(t, err)
+	r, err := NewBlinding()
+	require.NoError(t, err)
+
+	_, err = params.Prove(256, r)
+	require.EqualError(t, err, "value 256 is out of the range of 8 bits")
+}
+
+func TestRangeProofVerifyFailures(t *testing.T) {
+	params, err := NewParams(16)
+	require.NoError(t, err)
+	r, err := NewBlinding()
+	require.NoError(t, err)
+	proof, err := params.Prove(1234, r)
+	require.NoError(t, err)
+	commitment := Commit(1234, r)
+
+	t.Run("other value", func(t *testing.T) {
+		err := params.Verify(Commit(1235, r), proof)
+		require.EqualError(t, err, "invalid range proof: the polynomial commitment does not open to the inner product")
+	})
+
+	t.Run("other parameters", func(t *testing.T) {
+		other, err := NewParams(32)
+		require.NoError(t, err)
+		err = other.Verify(commitment, proof)
+		require.Error(t, err)
+	})
+
+	t.Run("tampered inner product", func(t *testing.T) {
+		tampered, err := ParseRangeProof(proof.Bytes())
+		require.NoError(t, err)
+		tampered.ipp.a = p256.ScalarAdd(tampered.ipp.a, big.NewInt(1))
+		err = params.Verify(commitment, tampered)
+		require.EqualError(t, err, "invalid range proof: the inner product argument is invalid")
+	})
+
+	t.Run("tampered commitment to the vectors", func(t *testing.T) {
+		tampered, err := ParseRangeProof(proof.Bytes())
+		require.NoError(t, err)
+		tampered.s = tampered.s.Add(g)
+		err = params.Verify(commitment, tampered)
+		require.Error(t, err)
+	})
+
+	t.Run("missing rounds", func(t *testing.T) {
+		tampered, err := ParseRangeProof(proof.Bytes())
+		require.NoError(t, err)
+		tampered.ipp.ls = tampered.ipp.ls[1:]
+		tampered.ipp.rs = tampered.ipp.rs[1:]
+		err = params.Verify(commitment, tampered)
+		require.EqualError(t, err, "invalid range proof: expected 4 rounds of the inner product argument")
+	})
+
+	// a negative value wraps around the order of the group, out of the range of
+	// the parameters: a proof of the range of its low bits does not verify
+	t.Run("negative value", func(t *testing.T) {
+		negative := &Commitment{p: g.Mul(big.NewInt(-1)).Add(h.Mul(r))}
+		err := params.Verify(negative, proof)
+		require.Error(t, err)
+	})
+}
+
+func TestParseRangeProof(t *testing.T) {
+	params, err := NewParams(8)
+	require.NoError(t, err)
+	r, err := NewBlinding()
+	require.NoError(t, err)
+	proof, err := params.Prove(42, r)
+	require.NoError(t, err)
+	raw := proof.Bytes()
+	require.Len(t, raw, 4*65+5*32+3*2*65)
+
+	_, err = ParseRangeProof(raw[:len(raw)-1])
+	require.EqualError(t, err, "invalid range proof: unexpected size 809")
+
+	tampered := append([]byte{}, raw...)
+	tampered[1] ^= 0xFF
+	_, err = ParseRangeProof(tampered)
+	require.EqualError(t, err, "invalid range proof: invalid point, not on the curve")
+
+	tampered = append([]byte{}, raw...)
+	copy(tampered[4*65:], p256.Order.Bytes())
+	_, err = ParseRangeProof(tampered)
+	require.EqualError(t, err, "invalid range proof: invalid scalar, not reduced")
+}
@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproofs
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+)
+
+// transcript derives the challenges of the verifier from the messages of the prover
+// (Fiat-Shamir), by chaining the hashes of the messages
+type transcript struct {
+	state []byte
+}
+
+func newTranscript(label string) *transcript {
+	digest := sha256.Sum256([]byte("fabric/bulletproofs/" + label))
+	return &transcript{state: digest[:]}
+}
+
+func (t *transcript) appendPoints(points ...p256.Point) {
+	for _, p := range points {
+		t.append(p.Bytes())
+	}
+}
+
+func (t *transcript) appendScalars(scalars ...*big.Int) {
+	for _, k := range scalars {
+		t.append(p256.ScalarBytes(k))
+	}
+}
+
+func (t *transcript) append(data []byte) {
+	h := sha256.New()
+	h.Write(t.state)
+	h.Write(data)
+	t.state = h.Sum(nil)
+}
+
+// challenge returns a non-zero scalar bound to all the messages appended so far
+func (t *transcript) challenge() *big.Int {
+	for {
+		t.append([]byte("challenge"))
+		c := new(big.Int).Mod(new(big.Int).SetBytes(t.state), p256.Order)
+		if c.Sign() != 0 {
+			return c
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package decorator provides the base of the BCCSP decorators, which wrap a
+// BCCSP to change some of its operations. The base forwards every operation
+// to the wrapped BCCSP, including those of the optional interfaces it
+// implements, so that a decorator only overrides the operations it changes
+// and hides none of the capabilities of the wrapped BCCSP.
+package decorator
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Base forwards the operations to the wrapped BCCSP. The operations of the
+// optional interfaces fail when the wrapped BCCSP does not implement them.
+type Base struct {
+	bccsp.BCCSP
+}
+
+// VerifyBatch verifies the signatures of the requests with the wrapped BCCSP,
+// one at a time when it does not verify signatures in batches.
+func (b *Base) VerifyBatch(requests []*bccsp.VerifyRequest) []*bccsp.VerifyResult {
+	if verifier, ok := b.BCCSP.(bccsp.BatchVerifier); ok {
+		return verifier.VerifyBatch(requests)
+	}
+	results := make([]*bccsp.VerifyResult, len(requests))
+	for i, r := range requests {
+		valid, err := b.BCCSP.Verify(r.Key, r.Signature, r.Digest, r.Opts)
+		results[i] = &bccsp.VerifyResult{Valid: valid, Err: err}
+	}
+	return results
+}
+
+// ListKeys returns the keys held by the wrapped BCCSP.
+func (b *Base) ListKeys() ([]bccsp.Key, error) {
+	manager, ok := b.BCCSP.(bccsp.KeyManager)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support listing keys")
+	}
+	return manager.ListKeys()
+}
+
+// DeleteKey deletes the key from the wrapped BCCSP.
+func (b *Base) DeleteKey(ski []byte) error {
+	manager, ok := b.BCCSP.(bccsp.KeyManager)
+	if !ok {
+		return errors.New("the crypto provider does not support deleting keys")
+	}
+	return manager.DeleteKey(ski)
+}
+
+// ExportBackup backs up the keys of the wrapped BCCSP.
+func (b *Base) ExportBackup(w io.Writer, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	manager, ok := b.BCCSP.(bccsp.BackupManager)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support backups")
+	}
+	return manager.ExportBackup(w, opts)
+}
+
+// ImportBackup restores the keys of a backup to the wrapped BCCSP.
+func (b *Base) ImportBackup(r io.Reader, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	manager, ok := b.BCCSP.(bccsp.BackupManager)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support backups")
+	}
+	return manager.ImportBackup(r, opts)
+}
+
+// Status returns the status of the wrapped BCCSP.
+func (b *Base) Status() (*bccsp.Status, error) {
+	reporter, ok := b.BCCSP.(bccsp.StatusReporter)
+	if !ok {
+		return &bccsp.Status{Provider: "unknown"}, nil
+	}
+	return reporter.Status()
+}
+
+// WrapKey returns the material of the key encrypted with the wrapping key by
+// the wrapped BCCSP.
+func (b *Base) WrapKey(k bccsp.Key, wrapping bccsp.Key) ([]byte, error) {
+	wrapper, ok := b.BCCSP.(bccsp.KeyWrapper)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support wrapping keys")
+	}
+	return wrapper.WrapKey(k, wrapping)
+}
+
+// Commit commits to the value with the blinding factor with the wrapped BCCSP.
+func (b *Base) Commit(value uint64, blinding bccsp.Key) ([]byte, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return nil, err
+	}
+	return committer.Commit(value, blinding)
+}
+
+// VerifyOpening returns whether the commitment opens to the value with the
+// blinding factor, as verified by the wrapped BCCSP.
+func (b *Base) VerifyOpening(commitment []byte, value uint64, blinding bccsp.Key) (bool, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return false, err
+	}
+	return committer.VerifyOpening(commitment, value, blinding)
+}
+
+// AddCommitments adds the commitments with the wrapped BCCSP.
+func (b *Base) AddCommitments(commitments ...[]byte) ([]byte, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return nil, err
+	}
+	return committer.AddCommitments(commitments...)
+}
+
+// SubCommitments subtracts the commitments with the wrapped BCCSP.
+func (b *Base) SubCommitments(c1, c2 []byte) ([]byte, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return nil, err
+	}
+	return committer.SubCommitments(c1, c2)
+}
+
+// AddBlindings adds the blinding factors with the wrapped BCCSP.
+func (b *Base) AddBlindings(blindings ...bccsp.Key) (bccsp.Key, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return nil, err
+	}
+	return committer.AddBlindings(blindings...)
+}
+
+// SubBlindings subtracts the blinding factors with the wrapped BCCSP.
+func (b *Base) SubBlindings(b1, b2 bccsp.Key) (bccsp.Key, error) {
+	committer, err := b.committer()
+	if err != nil {
+		return nil, err
+	}
+	return committer.SubBlindings(b1, b2)
+}
+
+func (b *Base) committer() (bccsp.Committer, error) {
+	committer, ok := b.BCCSP.(bccsp.Committer)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support commitments")
+	}
+	return committer, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package decorator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+// plainCSP hides the optional interfaces of the BCCSP it wraps
+type plainCSP struct {
+	bccsp.BCCSP
+}
+
+func TestForwarding(t *testing.T) {
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	base := &Base{BCCSP: provider}
+
+	r1, err := base.KeyGen(&bccsp.PedersenBlindingKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	r2, err := base.KeyGen(&bccsp.PedersenBlindingKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	c1, err := base.Commit(70, r1)
+	require.NoError(t, err)
+	c2, err := base.Commit(30, r2)
+	require.NoError(t, err)
+	sum, err := base.AddCommitments(c1, c2)
+	require.NoError(t, err)
+	r, err := base.AddBlindings(r1, r2)
+	require.NoError(t, err)
+	valid, err := base.VerifyOpening(sum, 100, r)
+	require.NoError(t, err)
+	require.True(t, valid)
+	diff, err := base.SubCommitments(sum, c2)
+	require.NoError(t, err)
+	r, err = base.SubBlindings(r, r2)
+	require.NoError(t, err)
+	valid, err = base.VerifyOpening(diff, 70, r)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	k, err := base.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	sig, err := base.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	results := base.VerifyBatch([]*bccsp.VerifyRequest{{Key: k, Signature: sig, Digest: digest[:]}})
+	require.Equal(t, []*bccsp.VerifyResult{{Valid: true}}, results)
+
+	status, err := base.Status()
+	require.NoError(t, err)
+	require.Equal(t, "SW", status.Provider)
+
+	// the errors of the wrapped CSP are returned as is
+	_, err = base.ExportBackup(&bytes.Buffer{}, &bccsp.BackupOpts{})
+	require.EqualError(t, err, "a passphrase is required to export a backup")
+}
+
+func TestUnsupported(t *testing.T) {
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	base := &Base{BCCSP: &plainCSP{BCCSP: provider}}
+
+	k, err := base.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	sig, err := base.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	results := base.VerifyBatch([]*bccsp.VerifyRequest{
+		{Key: k, Signature: sig, Digest: digest[:]},
+		{Key: k, Signature: sig, Digest: []byte("other")},
+	})
+	require.Equal(t, []*bccsp.VerifyResult{{Valid: true}, {Valid: false}}, results)
+
+	_, err = base.WrapKey(k, k)
+	require.EqualError(t, err, "the crypto provider does not support wrapping keys")
+	_, err = base.Commit(1, k)
+	require.EqualError(t, err, "the crypto provider does not support commitments")
+	_, err = base.AddBlindings(k)
+	require.EqualError(t, err, "the crypto provider does not support commitments")
+	_, err = base.ListKeys()
+	require.EqualError(t, err, "the crypto provider does not support listing keys")
+	require.EqualError(t, base.DeleteKey(k.SKI()), "the crypto provider does not support deleting keys")
+	_, err = base.ExportBackup(&bytes.Buffer{}, nil)
+	require.EqualError(t, err, "the crypto provider does not support backups")
+	_, err = base.ImportBackup(&bytes.Buffer{}, nil)
+	require.EqualError(t, err, "the crypto provider does not support backups")
+	status, err := base.Status()
+	require.NoError(t, err)
+	require.Equal(t, &bccsp.Status{Provider: "unknown"}, status)
+}
@@ -0,0 +1,506 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package dkg implements a distributed key generation protocol, by which n
+// parties jointly generate a P-256 key whose secret no party ever holds: each
+// party ends up with a share of the secret, any threshold of which can sign or
+// decrypt, for instance in threshold signature schemes.
+//
+// The parties exchange messages over a Transport, which the application
+// provides, such as the gRPC connections between orderers or between the
+// machines of organization admins.
+package dkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/pkg/errors"
+)
+
+// DefaultRoundTimeout is how long a party waits for the messages of a round
+// when the configuration does not say.
+const DefaultRoundTimeout = 30 * time.Second
+
+// Config configures a party of the protocol.
+type Config struct {
+	// Index is the index of the party, from 1 to Parties.
+	Index int
+	// Parties is the number of parties.
+	Parties int
+	// Threshold is the number of parties whose shares of the secret key
+	// are needed to use it.
+	Threshold int
+	// RoundTimeout is how long the party waits for the messages of a round,
+	// after which the parties it did not hear from are considered crashed.
+	// The rounds follow one schedule from the start of the protocol: the
+	// window of a round starts when the one of the previous round ends, so
+	// that the messages sent by a party at the end of a window reach the
+	// parties that completed the previous round early.
+	RoundTimeout time.Duration
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Threshold < 1:
+		return errors.Errorf("invalid threshold %d: must be at least 1", c.Threshold)
+	case c.Parties < c.Threshold:
+		return errors.Errorf("invalid number of parties %d: must be at least the threshold %d", c.Parties, c.Threshold)
+	case c.Index < 1 || c.Index > c.Parties:
+		return errors.Errorf("invalid index %d: must be between 1 and %d", c.Index, c.Parties)
+	}
+	return nil
+}
+
+// KeyShare is the outcome of the protocol for a party: its share of the
+// secret key, which no party holds, and the public key.
+type KeyShare struct {
+	// Index is the index of the party.
+	Index int
+	// Threshold is the number of shares needed to use the secret key.
+	Threshold int
+	// Secret is the share of the secret key of the party. The secret key is
+	// the interpolation at 0 of the shares of any Threshold parties.
+	Secret *big.Int
+	// PublicKey is the public key.
+	PublicKey *ecdsa.PublicKey
+	// VerificationKeys are the public counterparts of the shares of all the
+	// parties, by index, which verify their contributions to threshold signatures.
+	VerificationKeys map[int]*ecdsa.PublicKey
+	// Qualified are the indexes of the dealers whose contributions make the key.
+	Qualified []int
+}
+
+// MisbehaviorError is returned when a dealer provably deviates from the
+// protocol after being qualified, which aborts the protocol. The protocol
+// should be run again without the dealer.
+type MisbehaviorError struct {
+	Dealer int
+	Reason string
+}
+
+func (e *MisbehaviorError) Error() string {
+	return fmt.Sprintf("dealer %d misbehaved: %s", e.Dealer, e.Reason)
+}
+
+// payloads of the messages
+
+type polynomialCommitments struct {
+	Commitments [][]byte `json:"commitments"`
+}
+
+type dealShare struct {
+	Share    []byte `json:"share"`
+	Blinding []byte `json:"blinding"`
+}
+
+type complaints struct {
+	Dealers []int `json:"dealers"`
+}
+
+type justification struct {
+	Shares map[int]*dealShare `json:"shares"`
+}
+
+// share is a share of the secret of a dealer, with the share of its blinding
+type share struct {
+	value    *big.Int
+	blinding *big.Int
+}
+
+// Run runs the Pedersen distributed key generation protocol of Gennaro,
+// Jarecki, Krawczyk and Rabin over P-256 as the party of the configuration,
+// exchanging messages over the transport, and returns the key share of the party.
+func Run(ctx context.Context, config Config, transport Transport) (*KeyShare, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.RoundTimeout == 0 {
+		config.RoundTimeout = DefaultRoundTimeout
+	}
+	p := &party{
+		config:     config,
+		transport:  transport,
+		broadcasts: map[Round]map[int]*Message{},
+		privates:   map[Round]map[int]*Message{},
+	}
+	return p.run(ctx)
+}
+
+type party struct {
+	config    Config
+	transport Transport
+
+	// broadcasts and privates are the messages received, by round and sender
+	broadcasts map[Round]map[int]*Message
+	privates   map[Round]map[int]*Message
+}
+
+func (p *party) run(ctx context.Context) (*KeyShare, error) {
+	n, t, self := p.config.Parties, p.config.Threshold, p.config.Index
+	// deadline is the end of the window of the current round
+	deadline := time.Now()
+
+	// deal: f(x) = a_0 + ... + a_{t-1} x^{t-1} shares the secret a_0 of the dealer,
+	// f'(x) blinds the Pedersen commitments C_k = a_k G + b_k H
+	f, err := randomPolynomial(t - 1)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := randomPolynomial(t - 1)
+	if err != nil {
+		return nil, err
+	}
+	commitments := make([][]byte, t)
+	for k := range f {
+		commitments[k] = g.Mul(f[k]).Add(h.Mul(fb[k])).Bytes()
+	}
+	if err := p.broadcast(RoundDeal, &polynomialCommitments{Commitments: commitments}); err != nil {
+		return nil, err
+	}
+	for j := 1; j <= n; j++ {
+		if j == self {
+			continue
+		}
+		if err := p.send(RoundDeal, j, &dealShare{Share: f.evaluate(j).Bytes(), Blinding: fb.evaluate(j).Bytes()}); err != nil {
+			return nil, err
+		}
+	}
+
+	others := p.others()
+	deadline = deadline.Add(p.config.RoundTimeout)
+	if err := p.collect(ctx, deadline, func() bool {
+		return len(p.broadcasts[RoundDeal]) == len(others) && len(p.privates[RoundDeal]) == len(others)
+	}); err != nil {
+		return nil, err
+	}
+
+	// the candidate dealers are those whose commitments were broadcast, and the
+	// complaints are against those whose share is missing or does not match the
+	// commitments: as the other parties see the broadcast, a dealer withholding
+	// a share must reveal it, or be disqualified by all
+	pedersen := map[int][]p256.Point{self: parsePoints(commitments)}
+	shares := map[int]*share{self: {value: f.evaluate(self), blinding: fb.evaluate(self)}}
+	var complained []int
+	for _, i := range others {
+		bm, pm := p.broadcasts[RoundDeal][i], p.privates[RoundDeal][i]
+		if bm == nil {
+			continue
+		}
+		c, err := decodeCommitments(bm.Payload, t)
+		if err != nil {
+			complained = append(complained, i)
+			continue
+		}
+		pedersen[i] = c
+		if pm == nil {
+			complained = append(complained, i)
+			continue
+		}
+		s, err := decodeShare(pm.Payload)
+		if err != nil || !verifyPedersen(c, self, s) {
+			complained = append(complained, i)
+			continue
+		}
+		shares[i] = s
+	}
+
+	// complaint
+	if err := p.broadcast(RoundComplaint, &complaints{Dealers: complained}); err != nil {
+		return nil, err
+	}
+	deadline = deadline.Add(p.config.RoundTimeout)
+	if err := p.collect(ctx, deadline, func() bool {
+		return len(p.broadcasts[RoundComplaint]) == len(others)
+	}); err != nil {
+		return nil, err
+	}
+	// against[i] are the parties complaining about dealer i
+	against := map[int][]int{}
+	for _, j := range append(others, self) {
+		dealers := complained
+		if j != self {
+			m := p.broadcasts[RoundComplaint][j]
+			if m == nil {
+				continue
+			}
+			c := &complaints{}
+			if err := json.Unmarshal(m.Payload, c); err != nil {
+				continue
+			}
+			dealers = c.Dealers
+		}
+		for _, i := range dealers {
+			against[i] = append(against[i], j)
+		}
+	}
+
+	// justification: the dealer reveals the shares of the complaining parties
+	revealed := &justification{Shares: map[int]*dealShare{}}
+	for _, j := range against[self] {
+		if j != self {
+			revealed.Shares[j] = &dealShare{Share: f.evaluate(j).Bytes(), Blinding: fb.evaluate(j).Bytes()}
+		}
+	}
+	if err := p.broadcast(RoundJustification, revealed); err != nil {
+		return nil, err
+	}
+	deadline = deadline.Add(p.config.RoundTimeout)
+	if err := p.collect(ctx, deadline, func() bool {
+		return len(p.broadcasts[RoundJustification]) == len(others)
+	}); err != nil {
+		return nil, err
+	}
+
+	// the qualified dealers are the candidates that answered all the complaints
+	// about them with shares matching their commitments
+	var qualified []int
+	for i, c := range pedersen {
+		if len(against[i]) == 0 {
+			qualified = append(qualified, i)
+			continue
+		}
+		answer := revealed
+		if i != self {
+			m := p.broadcasts[RoundJustification][i]
+			if m == nil {
+				continue
+			}
+			answer = &justification{}
+			if err := json.Unmarshal(m.Payload, answer); err != nil {
+				continue
+			}
+		}
+		justified := true
+		for _, j := range against[i] {
+			ds, ok := answer.Shares[j]
+			if !ok {
+				justified = false
+				break
+			}
+			s, err := decodeDealShare(ds)
+			if err != nil || !verifyPedersen(c, j, s) {
+				justified = false
+				break
+			}
+			if j == self {
+				shares[i] = s
+			}
+		}
+		if justified {
+			qualified = append(qualified, i)
+		}
+	}
+	sort.Ints(qualified)
+	if len(qualified) < t {
+		return nil, errors.Errorf("only %d dealers qualified, at least %d are required", len(qualified), t)
+	}
+
+	// extract: the qualified dealers broadcast A_k = a_k G
+	feldman := map[int][]p256.Point{}
+	if contains(qualified, self) {
+		own := make([][]byte, t)
+		for k := range f {
+			own[k] = g.Mul(f[k]).Bytes()
+		}
+		feldman[self] = parsePoints(own)
+		if err := p.broadcast(RoundExtract, &polynomialCommitments{Commitments: own}); err != nil {
+			return nil, err
+		}
+	}
+	deadline = deadline.Add(p.config.RoundTimeout)
+	if err := p.collect(ctx, deadline, func() bool {
+		for _, i := range qualified {
+			if i != self && p.broadcasts[RoundExtract][i] == nil {
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	secret := new(big.Int)
+	for _, i := range qualified {
+		if i != self {
+			m := p.broadcasts[RoundExtract][i]
+			if m == nil {
+				return nil, &MisbehaviorError{Dealer: i, Reason: "no commitments to its polynomial"}
+			}
+			c, err := decodeCommitments(m.Payload, t)
+			if err != nil {
+				return nil, &MisbehaviorError{Dealer: i, Reason: err.Error()}
+			}
+			feldman[i] = c
+		}
+		s := shares[i]
+		if !g.Mul(s.value).Equal(evaluateCommitments(feldman[i], self)) {
+			return nil, &MisbehaviorError{Dealer: i, Reason: "the share does not match the commitments to its polynomial"}
+		}
+		secret.Add(secret, s.value)
+	}
+	secret.Mod(secret, p256.Order)
+
+	public := p256.Identity()
+	for _, i := range qualified {
+		public = public.Add(feldman[i][0])
+	}
+	verificationKeys := map[int]*ecdsa.PublicKey{}
+	for j := 1; j <= n; j++ {
+		vk := p256.Identity()
+		for _, i := range qualified {
+			vk = vk.Add(evaluateCommitments(feldman[i], j))
+		}
+		verificationKeys[j] = toPublicKey(vk)
+	}
+
+	return &KeyShare{
+		Index:            self,
+		Threshold:        t,
+		Secret:           secret,
+		PublicKey:        toPublicKey(public),
+		VerificationKeys: verificationKeys,
+		Qualified:        qualified,
+	}, nil
+}
+
+func (p *party) others() []int {
+	var others []int
+	for j := 1; j <= p.config.Parties; j++ {
+		if j != p.config.Index {
+			others = append(others, j)
+		}
+	}
+	return others
+}
+
+func (p *party) broadcast(round Round, payload interface{}) error {
+	return p.send(round, 0, payload)
+}
+
+func (p *party) send(round Round, to int, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "failed marshaling %s message", round)
+	}
+	if err := p.transport.Send(&Message{Round: round, From: p.config.Index, To: to, Payload: raw}); err != nil {
+		return errors.WithMessagef(err, "failed sending %s message to %d", round, to)
+	}
+	return nil
+}
+
+// collect receives messages until done or the deadline of the round, after
+// which the parties that did not send their messages are considered crashed
+func (p *party) collect(ctx context.Context, deadline time.Time, done func() bool) error {
+	roundCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	for !done() {
+		msg, err := p.transport.Receive(roundCtx)
+		if err != nil {
+			if ctx.Err() == nil && roundCtx.Err() != nil {
+				return nil
+			}
+			return errors.WithMessage(err, "failed receiving message")
+		}
+		p.store(msg)
+	}
+	return nil
+}
+
+// store keeps the first message of each sender for each round, and ignores
+// the messages that are not addressed to the party
+func (p *party) store(msg *Message) {
+	if msg.From < 1 || msg.From > p.config.Parties || msg.From == p.config.Index {
+		return
+	}
+	var received map[Round]map[int]*Message
+	switch msg.To {
+	case 0:
+		received = p.broadcasts
+	case p.config.Index:
+		received = p.privates
+	default:
+		return
+	}
+	if received[msg.Round] == nil {
+		received[msg.Round] = map[int]*Message{}
+	}
+	if _, ok := received[msg.Round][msg.From]; !ok {
+		received[msg.Round][msg.From] = msg
+	}
+}
+
+func verifyPedersen(commitments []p256.Point, index int, s *share) bool {
+	return g.Mul(s.value).Add(h.Mul(s.blinding)).Equal(evaluateCommitments(commitments, index))
+}
+
+func decodeCommitments(payload []byte, threshold int) ([]p256.Point, error) {
+	c := &polynomialCommitments{}
+	if err := json.Unmarshal(payload, c); err != nil {
+		return nil, errors.Wrap(err, "invalid commitments")
+	}
+	if len(c.Commitments) != threshold {
+		return nil, errors.Errorf("invalid commitments: expected %d, got %d", threshold, len(c.Commitments))
+	}
+	points := make([]p256.Point, threshold)
+	for k, raw := range c.Commitments {
+		pt, err := p256.ParsePoint(raw)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid commitments")
+		}
+		points[k] = pt
+	}
+	return points, nil
+}
+
+func decodeShare(payload []byte) (*share, error) {
+	ds := &dealShare{}
+	if err := json.Unmarshal(payload, ds); err != nil {
+		return nil, errors.Wrap(err, "invalid share")
+	}
+	return decodeDealShare(ds)
+}
+
+func decodeDealShare(ds *dealShare) (*share, error) {
+	value, err := p256.ParseScalar(ds.Share)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid share")
+	}
+	blinding, err := p256.ParseScalar(ds.Blinding)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid share")
+	}
+	return &share{value: value, blinding: blinding}, nil
+}
+
+// parsePoints parses points this party encoded itself
+func parsePoints(raw [][]byte) []p256.Point {
+	points := make([]p256.Point, len(raw))
+	for k := range raw {
+		points[k], _ = p256.ParsePoint(raw[k])
+	}
+	return points
+}
+
+func toPublicKey(p p256.Point) *ecdsa.PublicKey {
+	x, y := p.Coordinates()
+	return &ecdsa.PublicKey{Curve: p256.Curve, X: x, Y: y}
+}
+
+func contains(indexes []int, i int) bool {
+	for _, j := range indexes {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dkg
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/stretchr/testify/require"
+)
+
+// tamperingTransport lets a test tamper with, or drop, the messages a party sends
+type tamperingTransport struct {
+	Transport
+	tamper func(msg *Message) *Message
+}
+
+func (t *tamperingTransport) Send(msg *Message) error {
+	if msg = t.tamper(msg); msg == nil {
+		return nil
+	}
+	return t.Transport.Send(msg)
+}
+
+type result struct {
+	share *KeyShare
+	err   error
+}
+
+// runParties runs the protocol for the parties with the given indexes
+func runParties(t *testing.T, transports []Transport, threshold int, indexes []int) map[int]*result {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	results := map[int]*result{}
+	for _, i := range indexes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			share, err := Run(context.Background(), Config{
+				Index:        i,
+				Parties:      len(transports),
+				Threshold:    threshold,
+				RoundTimeout: time.Second,
+			}, transports[i-1])
+			mutex.Lock()
+			results[i] = &result{share: share, err: err}
+			mutex.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// interpolate recovers the secret key from the shares of the parties
+func interpolate(shares []*KeyShare) *big.Int {
+	secret := new(big.Int)
+	for _, si := range shares {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for _, sj := range shares {
+			if si.Index == sj.Index {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(sj.Index)))
+			den.Mul(den, big.NewInt(int64(sj.Index-si.Index)))
+		}
+		coefficient := new(big.Int).Mul(num, new(big.Int).ModInverse(new(big.Int).Mod(den, p256.Order), p256.Order))
+		secret.Add(secret, new(big.Int).Mul(coefficient, si.Secret))
+	}
+	return secret.Mod(secret, p256.Order)
+}
+
+func requireConsistent(t *testing.T, results map[int]*result, indexes []int, qualified []int) {
+	first := results[indexes[0]].share
+	var shares []*KeyShare
+	for _, i := range indexes {
+		r := results[i]
+		require.NoError(t, r.err)
+		require.Equal(t, i, r.share.Index)
+		require.Equal(t, qualified, r.share.Qualified)
+		require.Equal(t, first.PublicKey, r.share.PublicKey)
+		require.Equal(t, first.VerificationKeys, r.share.VerificationKeys)
+		// the verification key of a party is the public counterpart of its share
+		x, y := g.Mul(r.share.Secret).Coordinates()
+		require.Equal(t, x, r.share.VerificationKeys[i].X)
+		require.Equal(t, y, r.share.VerificationKeys[i].Y)
+		shares = append(shares, r.share)
+	}
+
+	// any threshold shares recover the secret key of the public key
+	threshold := first.Threshold
+	for start := 0; start+threshold <= len(shares); start++ {
+		secret := interpolate(shares[start : start+threshold])
+		x, y := g.Mul(secret).Coordinates()
+		require.Equal(t, first.PublicKey.X, x)
+		require.Equal(t, first.PublicKey.Y, y)
+	}
+	// fewer shares do not
+	if threshold > 1 {
+		x, _ := g.Mul(interpolate(shares[:threshold-1])).Coordinates()
+		require.NotEqual(t, first.PublicKey.X, x)
+	}
+}
+
+func TestRun(t *testing.T) {
+	transports := NewLocalTransports(5)
+	results := runParties(t, transports, 3, []int{1, 2, 3, 4, 5})
+	requireConsistent(t, results, []int{1, 2, 3, 4, 5}, []int{1, 2, 3, 4, 5})
+}
+
+func TestRunWithCrashedParty(t *testing.T) {
+	transports := NewLocalTransports(4)
+	results := runParties(t, transports, 2, []int{1, 2, 3})
+	requireConsistent(t, results, []int{1, 2, 3}, []int{1, 2, 3})
+}
+
+func TestRunWithJustifiedComplaint(t *testing.T) {
+	transports := NewLocalTransports(4)
+	// the share of party 1 to party 2 is corrupted in transit, so party 2
+	// complains and party 1 reveals the share, which qualifies it
+	transports[0] = &tamperingTransport{Transport: transports[0], tamper: func(msg *Message) *Message {
+		if msg.Round == RoundDeal && msg.To == 2 {
+			return &Message{Round: msg.Round, To: msg.To, Payload: []byte(`{"share":"AQ==","blinding":"AQ=="}`)}
+		}
+		return msg
+	}}
+	results := runParties(t, transports, 3, []int{1, 2, 3, 4})
+	requireConsistent(t, results, []int{1, 2, 3, 4}, []int{1, 2, 3, 4})
+}
+
+func TestRunWithDisqualifiedDealer(t *testing.T) {
+	transports := NewLocalTransports(4)
+	// party 1 sends a bad share to party 2 and does not justify it
+	transports[0] = &tamperingTransport{Transport: transports[0], tamper: func(msg *Message) *Message {
+		switch {
+		case msg.Round == RoundDeal && msg.To == 2:
+			return &Message{Round: msg.Round, To: msg.To, Payload: []byte(`{"share":"AQ==","blinding":"AQ=="}`)}
+		case msg.Round == RoundJustification:
+			payload, _ := json.Marshal(&justification{})
+			return &Message{Round: msg.Round, To: msg.To, Payload: payload}
+		}
+		return msg
+	}}
+	results := runParties(t, transports, 2, []int{1, 2, 3, 4})
+	requireConsistent(t, results, []int{2, 3, 4}, []int{2, 3, 4})
+}
+
+func TestRunWithWithheldShare(t *testing.T) {
+	transports := NewLocalTransports(4)
+	// party 1 broadcasts its commitments but does not send its share to party 2,
+	// so party 2 complains and party 1 reveals the share, which qualifies it
+	transports[0] = &tamperingTransport{Transport: transports[0], tamper: func(msg *Message) *Message {
+		if msg.Round == RoundDeal && msg.To == 2 {
+			return nil
+		}
+		return msg
+	}}
+	results := runParties(t, transports, 3, []int{1, 2, 3, 4})
+	requireConsistent(t, results, []int{1, 2, 3, 4}, []int{1, 2, 3, 4})
+}
+
+func TestRunWithWithheldShareNotJustified(t *testing.T) {
+	transports := NewLocalTransports(4)
+	// party 1 withholds the share of party 2 and does not reveal it either,
+	// so all the parties disqualify it
+	transports[0] = &tamperingTransport{Transport: transports[0], tamper: func(msg *Message) *Message {
+		switch {
+		case msg.Round == RoundDeal && msg.To == 2:
+			return nil
+		case msg.Round == RoundJustification:
+			payload, _ := json.Marshal(&justification{})
+			return &Message{Round: msg.Round, To: msg.To, Payload: payload}
+		}
+		return msg
+	}}
+	results := runParties(t, transports, 2, []int{1, 2, 3, 4})
+	requireConsistent(t, results, []int{2, 3, 4}, []int{2, 3, 4})
+}
+
+func TestRunWithMisbehavingQualifiedDealer(t *testing.T) {
+	transports := NewLocalTransports(3)
+	// party 1 deals consistently but broadcasts commitments to another polynomial
+	other, err := randomPolynomial(1)
+	require.NoError(t, err)
+	transports[0] = &tamperingTransport{Transport: transports[0], tamper: func(msg *Message) *Message {
+		if msg.Round == RoundExtract {
+			payload, _ := json.Marshal(&polynomialCommitments{Commitments: [][]byte{g.Mul(other[0]).Bytes(), g.Mul(other[1]).Bytes()}})
+			return &Message{Round: msg.Round, To: msg.To, Payload: payload}
+		}
+		return msg
+	}}
+	results := runParties(t, transports, 2, []int{1, 2, 3})
+	for _, i := range []int{2, 3} {
+		require.EqualError(t, results[i].err, "dealer 1 misbehaved: the share does not match the commitments to its polynomial")
+		require.Equal(t, 1, results[i].err.(*MisbehaviorError).Dealer)
+	}
+}
+
+func TestRunNotEnoughParties(t *testing.T) {
+	transports := NewLocalTransports(3)
+	results := runParties(t, transports, 3, []int{1, 2})
+	for _, i := range []int{1, 2} {
+		require.EqualError(t, results[i].err, "only 2 dealers qualified, at least 3 are required")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Run(ctx, Config{Index: 1, Parties: 2, Threshold: 2}, NewLocalTransports(2)[0])
+	require.EqualError(t, err, "failed receiving message: context canceled")
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		config   Config
+		expected string
+	}{
+		{config: Config{Index: 1, Parties: 3, Threshold: 0}, expected: "invalid threshold 0: must be at least 1"},
+		{config: Config{Index: 1, Parties: 2, Threshold: 3}, expected: "invalid number of parties 2: must be at least the threshold 3"},
+		{config: Config{Index: 4, Parties: 3, Threshold: 2}, expected: "invalid index 4: must be between 1 and 3"},
+	}
+	for _, test := range tests {
+		_, err := Run(context.Background(), test.config, nil)
+		require.EqualError(t, err, test.expected)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dkg
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp/internal/p256"
+	"github.com/pkg/errors"
+)
+
+var (
+	// g is the base point of P-256
+	g = p256.Generator()
+	// h is the second generator of Pedersen commitments, whose discrete
+	// logarithm with respect to g is unknown
+	h = p256.HashToPoint("fabric/dkg/h")
+)
+
+// polynomial is a polynomial over the scalars, whose coefficients are
+// in increasing order of degree
+type polynomial []*big.Int
+
+func randomPolynomial(degree int) (polynomial, error) {
+	p := make(polynomial, degree+1)
+	for i := range p {
+		k, err := rand.Int(rand.Reader, p256.Order)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed generating random coefficients")
+		}
+		p[i] = k
+	}
+	return p, nil
+}
+
+func (p polynomial) evaluate(x int) *big.Int {
+	y := new(big.Int)
+	xx := big.NewInt(int64(x))
+	for i := len(p) - 1; i >= 0; i-- {
+		y.Mul(y, xx)
+		y.Add(y, p[i])
+		y.Mod(y, p256.Order)
+	}
+	return y
+}
+
+// evaluateCommitments evaluates at x the polynomial committed to by the
+// commitments to its coefficients
+func evaluateCommitments(commitments []p256.Point, x int) p256.Point {
+	sum := p256.Identity()
+	xk := big.NewInt(1)
+	xx := big.NewInt(int64(x))
+	for _, c := range commitments {
+		sum = sum.Add(c.Mul(xk))
+		xk = new(big.Int).Mod(new(big.Int).Mul(xk, xx), p256.Order)
+	}
+	return sum
+}
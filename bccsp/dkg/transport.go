@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dkg
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Round is a round of the protocol.
+type Round int
+
+// The rounds of the protocol
+const (
+	// RoundDeal is the round in which each party broadcasts the Pedersen
+	// commitments to its polynomials and sends a share to every other party.
+	RoundDeal Round = iota + 1
+	// RoundComplaint is the round in which each party broadcasts the dealers
+	// whose shares do not match their commitments.
+	RoundComplaint
+	// RoundJustification is the round in which each dealer broadcasts the
+	// shares it sent to the parties that complained about it.
+	RoundJustification
+	// RoundExtract is the round in which each qualified dealer broadcasts the
+	// Feldman commitments to its polynomial, which yield the public key.
+	RoundExtract
+)
+
+func (r Round) String() string {
+	switch r {
+	case RoundDeal:
+		return "deal"
+	case RoundComplaint:
+		return "complaint"
+	case RoundJustification:
+		return "justification"
+	case RoundExtract:
+		return "extract"
+	default:
+		return "unknown"
+	}
+}
+
+// Message is a message of the protocol.
+type Message struct {
+	Round Round
+	// From is the index of the sender.
+	From int
+	// To is the index of the recipient, or 0 for a message broadcast to all
+	// the other parties.
+	To      int
+	Payload []byte
+}
+
+// Transport carries the messages of the protocol between the parties.
+//
+// Implementations must authenticate the senders of the messages they receive,
+// keep the messages to a single recipient confidential, and deliver broadcast
+// messages identically to all the parties, for instance over mutual TLS
+// connections with an echo broadcast.
+type Transport interface {
+	// Send sends the message to its recipient, or to all the other parties
+	// if the message is broadcast.
+	Send(msg *Message) error
+
+	// Receive returns the next message for the party, blocking until one
+	// arrives or the context is done.
+	Receive(ctx context.Context) (*Message, error)
+}
+
+// NewLocalTransports returns the transports of parties that run in the same
+// process and exchange messages in memory. The transport of the party with
+// index i is at position i-1.
+func NewLocalTransports(parties int) []Transport {
+	inboxes := make([]chan *Message, parties)
+	for i := range inboxes {
+		// a party receives at most two messages per round from each party
+		inboxes[i] = make(chan *Message, 2*int(RoundExtract)*parties)
+	}
+	transports := make([]Transport, parties)
+	for i := range transports {
+		transports[i] = &localTransport{index: i + 1, inboxes: inboxes}
+	}
+	return transports
+}
+
+type localTransport struct {
+	index   int
+	inboxes []chan *Message
+}
+
+func (t *localTransport) Send(msg *Message) error {
+	if msg.To < 0 || msg.To > len(t.inboxes) {
+		return errors.Errorf("unknown recipient %d", msg.To)
+	}
+	// the transport authenticates the sender
+	msg = &Message{Round: msg.Round, From: t.index, To: msg.To, Payload: msg.Payload}
+	if msg.To != 0 {
+		t.inboxes[msg.To-1] <- msg
+		return nil
+	}
+	for i, inbox := range t.inboxes {
+		if i+1 != t.index {
+			inbox <- msg
+		}
+	}
+	return nil
+}
+
+func (t *localTransport) Receive(ctx context.Context) (*Message, error) {
+	select {
+	case msg := <-t.inboxes[t.index-1]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import "crypto"
+
+// ECVRF Elliptic Curve Verifiable Random Function ECVRF-P256-SHA256-TAI (RFC 9381).
+// ECVRF keys are ECDSA P-256 keys: Sign with ECVRFSignerOpts proves the VRF for the input
+// passed as digest, Verify with ECVRFSignerOpts verifies the proof, and Hash with
+// ECVRFProofToHashOpts returns the pseudorandom output of a proof.
+const ECVRF = "ECVRF"
+
+// ECVRFKeyGenOpts contains options for ECVRF key generation.
+type ECVRFKeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key generation algorithm identifier (to be used).
+func (opts *ECVRFKeyGenOpts) Algorithm() string {
+	return ECVRF
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *ECVRFKeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ECVRFSignerOpts contains options to prove and verify a VRF with an ECDSA P-256 key.
+type ECVRFSignerOpts struct{}
+
+// HashFunc returns 0, as the input of the VRF is hashed to the curve by the VRF itself.
+func (opts *ECVRFSignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// ECVRFProofToHashOpts contains options to compute the output of a VRF proof.
+// The proof must have been verified before its output is trusted.
+type ECVRFProofToHashOpts struct{}
+
+// Algorithm returns the hash algorithm identifier (to be used).
+func (opts *ECVRFProofToHashOpts) Algorithm() string {
+	return ECVRF
+}
@@ -0,0 +1,230 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package envelope implements envelope encryption: each payload is encrypted
+// with a fresh data encryption key (DEK), which is itself encrypted, or
+// wrapped, with a key encryption key (KEK) held by a BCCSP. Only the KEK needs
+// to be protected by the BCCSP, which can keep it in software, in an HSM or
+// in a key manager, while the payloads of any size are encrypted locally.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the envelopes sealed by this package.
+const Version = 1
+
+// Algorithms of the envelopes.
+const (
+	// AES256GCM encrypts the payloads.
+	AES256GCM = "AES-256-GCM"
+	// AESCBCPKCS7 wraps the data keys, with the AES encryption of the BCCSP.
+	AESCBCPKCS7 = "AES-CBC-PKCS7"
+)
+
+// Envelope is a payload encrypted with a data key, along with the data key
+// wrapped with a key encryption key. It describes itself: opening it only
+// requires the BCCSP holding the key encryption key.
+type Envelope struct {
+	Version int `json:"version"`
+	// KEK is the SKI of the key encryption key.
+	KEK []byte `json:"kek"`
+	// WrapAlgorithm is the algorithm wrapping the data key.
+	WrapAlgorithm string `json:"wrapAlgorithm"`
+	// WrappedKey is the wrapped data key.
+	WrappedKey []byte `json:"wrappedKey"`
+	// Algorithm is the algorithm encrypting the payload.
+	Algorithm  string `json:"algorithm"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Sealer seals payloads in envelopes whose data keys are wrapped with a key
+// encryption key.
+type Sealer struct {
+	csp bccsp.BCCSP
+	kek bccsp.Key
+}
+
+// NewSealer returns a Sealer wrapping the data keys with kek, an AES key of
+// the BCCSP, such as a key of the SW provider, or of a KMIP server with the
+// KMIP provider.
+func NewSealer(csp bccsp.BCCSP, kek bccsp.Key) (*Sealer, error) {
+	if csp == nil {
+		return nil, errors.New("a BCCSP is required")
+	}
+	if kek == nil {
+		return nil, errors.New("a key encryption key is required")
+	}
+	if !kek.Symmetric() {
+		return nil, errors.Errorf("key %x cannot encrypt keys: the key encryption key must be an AES key", kek.SKI())
+	}
+	return &Sealer{csp: csp, kek: kek}, nil
+}
+
+// Seal encrypts the payload with a fresh data key, and returns the encoded
+// envelope. The additional data, which may be nil, is authenticated but not
+// encrypted: the same additional data is required to open the envelope.
+func (s *Sealer) Seal(payload, additionalData []byte) ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, errors.Wrap(err, "failed generating data key")
+	}
+	defer zeroize(dek)
+
+	wrapped, err := s.csp.Encrypt(s.kek, dek, &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed wrapping data key with key %x", s.kek.SKI())
+	}
+
+	e := &Envelope{
+		Version:       Version,
+		KEK:           s.kek.SKI(),
+		WrapAlgorithm: AESCBCPKCS7,
+		WrappedKey:    wrapped,
+		Algorithm:     AES256GCM,
+	}
+	gcm, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	e.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating nonce")
+	}
+	e.Ciphertext = gcm.Seal(nil, e.Nonce, payload, e.additionalData(additionalData))
+
+	return json.Marshal(e)
+}
+
+// Rewrap wraps the data key of the encoded envelope again with the key
+// encryption key of the Sealer, for instance after the rotation of the key
+// encryption key, without decrypting the payload.
+func (s *Sealer) Rewrap(raw []byte) ([]byte, error) {
+	e, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := unwrap(s.csp, e)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(dek)
+
+	wrapped, err := s.csp.Encrypt(s.kek, dek, &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed wrapping data key with key %x", s.kek.SKI())
+	}
+	e.KEK, e.WrappedKey = s.kek.SKI(), wrapped
+	return json.Marshal(e)
+}
+
+// Open decrypts the payload of the encoded envelope, whose data key is
+// unwrapped with the key encryption key of the BCCSP the envelope refers to.
+func Open(csp bccsp.BCCSP, raw, additionalData []byte) ([]byte, error) {
+	e, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := unwrap(csp, e)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(dek)
+
+	gcm, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() {
+		return nil, errors.Errorf("invalid envelope: nonce of %d bytes instead of %d", len(e.Nonce), gcm.NonceSize())
+	}
+	payload, err := gcm.Open(nil, e.Nonce, e.Ciphertext, e.additionalData(additionalData))
+	if err != nil {
+		return nil, errors.New("failed decrypting envelope: the envelope or its additional data were altered")
+	}
+	return payload, nil
+}
+
+// Parse decodes an envelope, and checks that its version and algorithms are
+// supported.
+func Parse(raw []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, errors.Wrap(err, "invalid envelope")
+	}
+	switch {
+	case e.Version != Version:
+		return nil, errors.Errorf("unsupported envelope version %d", e.Version)
+	case e.WrapAlgorithm != AESCBCPKCS7:
+		return nil, errors.Errorf("unsupported key wrapping algorithm %s", e.WrapAlgorithm)
+	case e.Algorithm != AES256GCM:
+		return nil, errors.Errorf("unsupported encryption algorithm %s", e.Algorithm)
+	case len(e.KEK) == 0 || len(e.WrappedKey) == 0:
+		return nil, errors.New("invalid envelope: missing key encryption key or wrapped key")
+	}
+	return e, nil
+}
+
+// unwrap returns the data key of the envelope, unwrapped with the key
+// encryption key of the BCCSP the envelope refers to.
+func unwrap(csp bccsp.BCCSP, e *Envelope) ([]byte, error) {
+	kek, err := csp.GetKey(e.KEK)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "key encryption key %x not found", e.KEK)
+	}
+	dek, err := csp.Decrypt(kek, e.WrappedKey, &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed unwrapping data key with key %x", e.KEK)
+	}
+	if len(dek) != 32 {
+		zeroize(dek)
+		return nil, errors.Errorf("failed unwrapping data key with key %x: invalid data key", e.KEK)
+	}
+	return dek, nil
+}
+
+// additionalData returns the data authenticated along with the payload: the
+// version and the algorithm of the envelope, and the additional data of the
+// caller. The wrapped key is not authenticated, so that it can be wrapped
+// again with another key encryption key; altering it alters the data key,
+// which fails the decryption.
+func (e *Envelope) additionalData(data []byte) []byte {
+	var raw []byte
+	for _, field := range [][]byte{{byte(e.Version)}, []byte(e.Algorithm), data} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		raw = append(raw, size[:]...)
+		raw = append(raw, field...)
+	}
+	return raw
+}
+
+func newGCM(dek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	return gcm, nil
+}
+
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
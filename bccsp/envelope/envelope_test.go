@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package envelope
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func newCSP(t *testing.T) bccsp.BCCSP {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return csp
+}
+
+func TestSealAndOpen(t *testing.T) {
+	csp := newCSP(t)
+	kek, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	sealer, err := NewSealer(csp, kek)
+	require.NoError(t, err)
+
+	for _, payload := range [][]byte{nil, []byte("hello"), make([]byte, 1<<16)} {
+		raw, err := sealer.Seal(payload, []byte("channel1"))
+		require.NoError(t, err)
+
+		e, err := Parse(raw)
+		require.NoError(t, err)
+		require.Equal(t, Version, e.Version)
+		require.Equal(t, kek.SKI(), e.KEK)
+		require.Equal(t, AES256GCM, e.Algorithm)
+		require.Equal(t, AESCBCPKCS7, e.WrapAlgorithm)
+
+		opened, err := Open(csp, raw, []byte("channel1"))
+		require.NoError(t, err)
+		require.Equal(t, len(payload), len(opened))
+		require.Equal(t, string(payload), string(opened))
+
+		_, err = Open(csp, raw, []byte("channel2"))
+		require.EqualError(t, err, "failed decrypting envelope: the envelope or its additional data were altered")
+	}
+
+	// Each envelope has its own data key
+	first, err := sealer.Seal([]byte("hello"), nil)
+	require.NoError(t, err)
+	second, err := sealer.Seal([]byte("hello"), nil)
+	require.NoError(t, err)
+	e1, err := Parse(first)
+	require.NoError(t, err)
+	e2, err := Parse(second)
+	require.NoError(t, err)
+	require.NotEqual(t, e1.WrappedKey, e2.WrappedKey)
+	require.NotEqual(t, e1.Ciphertext, e2.Ciphertext)
+}
+
+func TestRewrap(t *testing.T) {
+	csp := newCSP(t)
+	oldKEK, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	newKEK, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+
+	oldSealer, err := NewSealer(csp, oldKEK)
+	require.NoError(t, err)
+	raw, err := oldSealer.Seal([]byte("hello"), nil)
+	require.NoError(t, err)
+
+	newSealer, err := NewSealer(csp, newKEK)
+	require.NoError(t, err)
+	rewrapped, err := newSealer.Rewrap(raw)
+	require.NoError(t, err)
+	e, err := Parse(rewrapped)
+	require.NoError(t, err)
+	require.Equal(t, newKEK.SKI(), e.KEK)
+	original, err := Parse(raw)
+	require.NoError(t, err)
+	require.Equal(t, original.Ciphertext, e.Ciphertext)
+
+	// The envelope is opened with the new key encryption key alone
+	other := newCSP(t)
+	_, err = Open(other, rewrapped, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("key encryption key %x not found", newKEK.SKI()))
+	opened, err := Open(csp, rewrapped, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), opened)
+}
+
+func TestNewSealerFailures(t *testing.T) {
+	csp := newCSP(t)
+	_, err := NewSealer(nil, nil)
+	require.EqualError(t, err, "a BCCSP is required")
+	_, err = NewSealer(csp, nil)
+	require.EqualError(t, err, "a key encryption key is required")
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	_, err = NewSealer(csp, k)
+	require.EqualError(t, err, fmt.Sprintf("key %x cannot encrypt keys: the key encryption key must be an AES key", k.SKI()))
+}
+
+func TestOpenFailures(t *testing.T) {
+	csp := newCSP(t)
+	kek, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	sealer, err := NewSealer(csp, kek)
+	require.NoError(t, err)
+	raw, err := sealer.Seal([]byte("hello"), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(*Envelope)
+		err    string
+	}{
+		{"version", func(e *Envelope) { e.Version = 2 }, "unsupported envelope version 2"},
+		{"wrap algorithm", func(e *Envelope) { e.WrapAlgorithm = "RSA-OAEP" }, "unsupported key wrapping algorithm RSA-OAEP"},
+		{"algorithm", func(e *Envelope) { e.Algorithm = "AES-128-GCM" }, "unsupported encryption algorithm AES-128-GCM"},
+		{"wrapped key", func(e *Envelope) { e.WrappedKey = nil }, "invalid envelope: missing key encryption key or wrapped key"},
+		{"nonce", func(e *Envelope) { e.Nonce = e.Nonce[:4] }, "invalid envelope: nonce of 4 bytes instead of 12"},
+		{"ciphertext", func(e *Envelope) { e.Ciphertext[0] ^= 1 }, "failed decrypting envelope: the envelope or its additional data were altered"},
+		{"altered wrapped key", func(e *Envelope) { e.WrappedKey[len(e.WrappedKey)-20] ^= 1 }, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(raw)
+			require.NoError(t, err)
+			tt.modify(e)
+			altered, err := json.Marshal(e)
+			require.NoError(t, err)
+			_, err = Open(csp, altered, nil)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err = Open(csp, []byte("{"), nil)
+	require.EqualError(t, err, "invalid envelope: unexpected end of JSON input")
+}
@@ -0,0 +1,274 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package expiry enforces the lifetime of the keys of a BCCSP provider. Keys
+// carry a not-after time, past which the provider refuses to sign or encrypt
+// with them, so that the lifetime of a key can be aligned with the lifetime
+// of its certificates.
+package expiry
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/decorator"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_expiry")
+
+// Opts configure the enforcement of the lifetime of keys.
+type Opts struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// Store is the path of the file persisting the lifetimes of the keys. If
+	// empty, the lifetimes are only kept in memory.
+	Store string `mapstructure:"store" json:"store" yaml:"Store"`
+	// Grace is the time during which an expired key is still used, with a
+	// warning, before being refused.
+	Grace time.Duration `mapstructure:"grace" json:"grace" yaml:"Grace"`
+	// Warning is the time before their expiration from which keys are
+	// reported as expiring.
+	Warning time.Duration `mapstructure:"warning" json:"warning" yaml:"Warning"`
+}
+
+// CSP is a BCCSP whose keys carry a lifetime. It refuses to sign or encrypt
+// with the keys past their lifetime and its grace period.
+type CSP struct {
+	decorator.Base
+
+	opts      Opts
+	mutex     sync.RWMutex
+	lifetimes map[string]time.Time
+	warned    map[string]bool
+	now       func() time.Time
+}
+
+// New returns a CSP enforcing the lifetime of the keys of the given CSP. The
+// lifetimes persisted in the store of the options are loaded.
+func New(csp bccsp.BCCSP, opts Opts) (*CSP, error) {
+	if opts.Grace < 0 {
+		return nil, errors.Errorf("invalid grace period %s: must not be negative", opts.Grace)
+	}
+	c := &CSP{
+		Base:      decorator.Base{BCCSP: csp},
+		opts:      opts,
+		lifetimes: map[string]time.Time{},
+		warned:    map[string]bool{},
+		now:       time.Now,
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetKeyNotAfter sets the time after which the key must no longer be used,
+// and persists it in the store.
+func (c *CSP) SetKeyNotAfter(ski []byte, notAfter time.Time) error {
+	if len(ski) == 0 {
+		return errors.New("invalid SKI: it must not be empty")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	id := hex.EncodeToString(ski)
+	previous, existed := c.lifetimes[id]
+	c.lifetimes[id] = notAfter.UTC()
+	delete(c.warned, id)
+	if err := c.save(); err != nil {
+		if existed {
+			c.lifetimes[id] = previous
+		} else {
+			delete(c.lifetimes, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// KeyNotAfter returns the time after which the key must no longer be used.
+func (c *CSP) KeyNotAfter(ski []byte) (time.Time, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	notAfter, ok := c.lifetimes[hex.EncodeToString(ski)]
+	return notAfter, ok
+}
+
+// ExpiringKeys returns the lifetimes of the keys which expire within the given
+// duration, soonest first.
+func (c *CSP) ExpiringKeys(within time.Duration) []bccsp.KeyLifetime {
+	deadline := c.now().Add(within)
+	var expiring []bccsp.KeyLifetime
+	for _, l := range c.keyLifetimes() {
+		if l.NotAfter.After(deadline) {
+			break
+		}
+		expiring = append(expiring, l)
+	}
+	return expiring
+}
+
+// keyLifetimes returns the lifetimes of all the keys, soonest expiring first
+func (c *CSP) keyLifetimes() []bccsp.KeyLifetime {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	lifetimes := make([]bccsp.KeyLifetime, 0, len(c.lifetimes))
+	for id, notAfter := range c.lifetimes {
+		ski, _ := hex.DecodeString(id)
+		lifetimes = append(lifetimes, bccsp.KeyLifetime{SKI: ski, NotAfter: notAfter})
+	}
+	sort.Slice(lifetimes, func(i, j int) bool {
+		return lifetimes[i].NotAfter.Before(lifetimes[j].NotAfter)
+	})
+	return lifetimes
+}
+
+// Sign signs digest with the key, unless it expired.
+func (c *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if err := c.check(k, "sign"); err != nil {
+		return nil, err
+	}
+	return c.BCCSP.Sign(k, digest, opts)
+}
+
+// Encrypt encrypts plaintext with the key, unless it expired.
+func (c *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	if err := c.check(k, "encrypt"); err != nil {
+		return nil, err
+	}
+	return c.BCCSP.Encrypt(k, plaintext, opts)
+}
+
+// DeleteKey deletes the key from the wrapped CSP, along with its lifetime.
+func (c *CSP) DeleteKey(ski []byte) error {
+	if err := c.Base.DeleteKey(ski); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := hex.EncodeToString(ski)
+	if _, ok := c.lifetimes[id]; !ok {
+		return nil
+	}
+	delete(c.lifetimes, id)
+	delete(c.warned, id)
+	return c.save()
+}
+
+// ExportBackup backs up the keys of the wrapped CSP.
+func (c *CSP) ExportBackup(w io.Writer, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	if opts != nil && opts.Signer != nil {
+		if err := c.check(opts.Signer, "sign"); err != nil {
+			return nil, err
+		}
+	}
+	return c.Base.ExportBackup(w, opts)
+}
+
+// Status returns the status of the wrapped CSP, along with the keys expiring
+// within the warning period.
+func (c *CSP) Status() (*bccsp.Status, error) {
+	status, err := c.Base.Status()
+	if status != nil {
+		status.ExpiringKeys = c.ExpiringKeys(c.opts.Warning)
+	}
+	return status, err
+}
+
+// check returns an error when the key expired more than the grace period ago
+func (c *CSP) check(k bccsp.Key, operation string) error {
+	if k == nil {
+		return nil
+	}
+	ski := k.SKI()
+	id := hex.EncodeToString(ski)
+
+	c.mutex.RLock()
+	notAfter, ok := c.lifetimes[id]
+	c.mutex.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	now := c.now()
+	if !now.After(notAfter) {
+		return nil
+	}
+	if now.After(notAfter.Add(c.opts.Grace)) {
+		logger.Errorf("Refusing to %s with key %x, which expired at %s", operation, ski, notAfter.Format(time.RFC3339))
+		return &bccsp.ExpiredKeyError{SKI: ski, NotAfter: notAfter}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.warned[id] {
+		c.warned[id] = true
+		logger.Warningf("Key %x expired at %s and will be refused after %s", ski,
+			notAfter.Format(time.RFC3339), notAfter.Add(c.opts.Grace).Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *CSP) load() error {
+	if c.opts.Store == "" {
+		return nil
+	}
+	raw, err := ioutil.ReadFile(c.opts.Store)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed reading key lifetimes from %s", c.opts.Store)
+	}
+	var lifetimes []bccsp.KeyLifetime
+	if err := json.Unmarshal(raw, &lifetimes); err != nil {
+		return errors.Wrapf(err, "failed parsing key lifetimes from %s", c.opts.Store)
+	}
+	for _, l := range lifetimes {
+		c.lifetimes[hex.EncodeToString(l.SKI)] = l.NotAfter.UTC()
+	}
+	return nil
+}
+
+// save persists the lifetimes in the store, if any; it must be called with
+// the mutex held
+func (c *CSP) save() error {
+	if c.opts.Store == "" {
+		return nil
+	}
+	lifetimes := make([]bccsp.KeyLifetime, 0, len(c.lifetimes))
+	for id, notAfter := range c.lifetimes {
+		ski, _ := hex.DecodeString(id)
+		lifetimes = append(lifetimes, bccsp.KeyLifetime{SKI: ski, NotAfter: notAfter})
+	}
+	sort.Slice(lifetimes, func(i, j int) bool {
+		return hex.EncodeToString(lifetimes[i].SKI) < hex.EncodeToString(lifetimes[j].SKI)
+	})
+	raw, err := json.MarshalIndent(lifetimes, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling key lifetimes")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.opts.Store), 0700); err != nil {
+		return errors.Wrapf(err, "failed writing key lifetimes to %s", c.opts.Store)
+	}
+	tmp := c.opts.Store + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing key lifetimes to %s", c.opts.Store)
+	}
+	return errors.Wrapf(os.Rename(tmp, c.opts.Store), "failed writing key lifetimes to %s", c.opts.Store)
+}
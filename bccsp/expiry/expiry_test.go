@@ -0,0 +1,191 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCSP(t *testing.T, opts Opts) (*CSP, bccsp.Key, func()) {
+	dir, err := ioutil.TempDir("", "expiry")
+	require.NoError(t, err)
+	ks, err := sw.NewFileBasedKeyStore(nil, filepath.Join(dir, "keystore"), false)
+	require.NoError(t, err)
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	if opts.Store != "" {
+		opts.Store = filepath.Join(dir, opts.Store)
+	}
+	csp, err := New(provider, opts)
+	require.NoError(t, err)
+	return csp, k, func() { os.RemoveAll(dir) }
+}
+
+func TestSign(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{Grace: time.Hour})
+	defer cleanup()
+	digest := sha256.Sum256([]byte("message"))
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	csp.now = func() time.Time { return now }
+
+	// keys without a lifetime are always used
+	_, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), now.Add(time.Minute)))
+	notAfter, ok := csp.KeyNotAfter(k.SKI())
+	require.True(t, ok)
+	require.Equal(t, now.Add(time.Minute), notAfter)
+	_, err = csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	// expired keys are used during the grace period
+	now = now.Add(time.Hour)
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = csp.Sign(k, digest[:], nil)
+	require.IsType(t, &bccsp.ExpiredKeyError{}, err)
+	require.EqualError(t, err, "key "+hex.EncodeToString(k.SKI())+" expired at 2020-06-01T00:01:00Z")
+
+	// verification is unaffected
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	results := csp.VerifyBatch([]*bccsp.VerifyRequest{{Key: k, Signature: signature, Digest: digest[:]}})
+	require.True(t, results[0].Valid)
+
+	// renewing the lifetime allows the key to be used again
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), now.Add(time.Hour)))
+	_, err = csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+}
+
+func TestEncrypt(t *testing.T) {
+	csp, _, cleanup := newTestCSP(t, Opts{})
+	defer cleanup()
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.Encrypt(k, []byte("plaintext"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), time.Now().Add(-time.Second)))
+	_, err = csp.Encrypt(k, []byte("plaintext"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.IsType(t, &bccsp.ExpiredKeyError{}, err)
+}
+
+func TestExpiringKeys(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{Store: "lifetimes.json", Warning: 24 * time.Hour})
+	defer cleanup()
+	now := time.Now().UTC()
+	expired, soon, later := []byte{1}, []byte{2}, []byte{3}
+	require.NoError(t, csp.SetKeyNotAfter(later, now.Add(48*time.Hour)))
+	require.NoError(t, csp.SetKeyNotAfter(soon, now.Add(time.Hour)))
+	require.NoError(t, csp.SetKeyNotAfter(expired, now.Add(-time.Hour)))
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), now.Add(72*time.Hour)))
+
+	require.Equal(t, []bccsp.KeyLifetime{
+		{SKI: expired, NotAfter: now.Add(-time.Hour)},
+		{SKI: soon, NotAfter: now.Add(time.Hour)},
+	}, csp.ExpiringKeys(24*time.Hour))
+	require.Len(t, csp.ExpiringKeys(60*time.Hour), 3)
+
+	status, err := csp.Status()
+	require.NoError(t, err)
+	require.Equal(t, "SW", status.Provider)
+	require.Equal(t, csp.ExpiringKeys(24*time.Hour), status.ExpiringKeys)
+
+	// the lifetimes are persisted in the store
+	reloaded, err := New(csp.BCCSP, csp.opts)
+	require.NoError(t, err)
+	require.Len(t, reloaded.ExpiringKeys(100*time.Hour), 4)
+
+	// deleting a key deletes its lifetime
+	keys, err := csp.ListKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NoError(t, csp.DeleteKey(k.SKI()))
+	_, ok := csp.KeyNotAfter(k.SKI())
+	require.False(t, ok)
+	reloaded, err = New(csp.BCCSP, csp.opts)
+	require.NoError(t, err)
+	_, ok = reloaded.KeyNotAfter(k.SKI())
+	require.False(t, ok)
+}
+
+func TestNewFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "expiry")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = New(nil, Opts{Grace: -time.Second})
+	require.EqualError(t, err, "invalid grace period -1s: must not be negative")
+
+	store := filepath.Join(dir, "lifetimes.json")
+	require.NoError(t, ioutil.WriteFile(store, []byte("garbage"), 0600))
+	_, err = New(nil, Opts{Store: store})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing key lifetimes from "+store)
+}
+
+func TestSetKeyNotAfterFailure(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{})
+	defer cleanup()
+
+	require.EqualError(t, csp.SetKeyNotAfter(nil, time.Now()), "invalid SKI: it must not be empty")
+
+	// the lifetime is not kept when it cannot be persisted
+	dir, err := ioutil.TempDir("", "expiry")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csp.opts.Store = dir
+	require.Error(t, csp.SetKeyNotAfter(k.SKI(), time.Now()))
+	_, ok := csp.KeyNotAfter(k.SKI())
+	require.False(t, ok)
+}
+
+func TestBackup(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{})
+	defer cleanup()
+	now := time.Now()
+	csp.now = func() time.Time { return now }
+
+	buf := &bytes.Buffer{}
+	opts := &bccsp.BackupOpts{Passphrase: []byte("passphrase"), Signer: k}
+	manifest, err := csp.ExportBackup(buf, opts)
+	require.NoError(t, err)
+	require.Len(t, manifest.Keys, 1)
+	_, err = csp.ImportBackup(buf, &bccsp.BackupOpts{Passphrase: []byte("passphrase")})
+	require.NoError(t, err)
+
+	// expired keys do not sign the manifest of backups
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), now.Add(-time.Minute)))
+	_, err = csp.ExportBackup(buf, opts)
+	require.IsType(t, &bccsp.ExpiredKeyError{}, err)
+
+	unsupported, err := New(struct{ bccsp.BCCSP }{}, Opts{})
+	require.NoError(t, err)
+	_, err = unsupported.ExportBackup(buf, opts)
+	require.EqualError(t, err, "the crypto provider does not support backups")
+	_, err = unsupported.ImportBackup(buf, opts)
+	require.EqualError(t, err, "the crypto provider does not support backups")
+}
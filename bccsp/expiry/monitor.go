@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/pkg/errors"
+)
+
+var (
+	keyDaysToExpiryOpts = metrics.GaugeOpts{
+		Namespace:    "bccsp",
+		Name:         "key_days_to_expiry",
+		Help:         "The number of days left until the key expires, negative once it has expired.",
+		LabelNames:   []string{"ski"},
+		StatsdFormat: "%{#fqname}.%{ski}",
+	}
+
+	expiringKeysOpts = metrics.GaugeOpts{
+		Namespace:    "bccsp",
+		Name:         "expiring_keys",
+		Help:         "The number of keys expiring within the warning period, or expired.",
+		StatsdFormat: "%{#fqname}",
+	}
+)
+
+// Metrics are the metrics of a Monitor.
+type Metrics struct {
+	KeyDaysToExpiry metrics.Gauge
+	ExpiringKeys    metrics.Gauge
+}
+
+// NewMetrics creates the metrics of a Monitor.
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		KeyDaysToExpiry: p.NewGauge(keyDaysToExpiryOpts),
+		ExpiringKeys:    p.NewGauge(expiringKeysOpts),
+	}
+}
+
+// Monitor periodically exports the days left until the keys of a CSP expire,
+// and warns of the keys expiring within the warning period.
+type Monitor struct {
+	CSP      *CSP
+	Interval time.Duration
+	Metrics  *Metrics
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewMonitor returns a monitor of the lifetimes of the keys of the CSP.
+func NewMonitor(csp *CSP, interval time.Duration, metrics *Metrics) *Monitor {
+	return &Monitor{CSP: csp, Interval: interval, Metrics: metrics}
+}
+
+// Scan exports the days left until the keys expire, and returns the keys
+// expiring within the warning period.
+func (m *Monitor) Scan() []bccsp.KeyLifetime {
+	now := m.CSP.now()
+	var expiring []bccsp.KeyLifetime
+	for _, l := range m.CSP.keyLifetimes() {
+		left := l.NotAfter.Sub(now)
+		m.Metrics.KeyDaysToExpiry.With("ski", hex.EncodeToString(l.SKI)).Set(left.Hours() / 24)
+		if left > m.CSP.opts.Warning {
+			continue
+		}
+		expiring = append(expiring, l)
+		if left <= 0 {
+			logger.Warningf("Key %x expired at %s", l.SKI, l.NotAfter.Format(time.RFC3339))
+		} else {
+			logger.Warningf("Key %x expires at %s", l.SKI, l.NotAfter.Format(time.RFC3339))
+		}
+	}
+	m.Metrics.ExpiringKeys.Set(float64(len(expiring)))
+	return expiring
+}
+
+// Start scans the keys, and then keeps scanning them at every interval until
+// the monitor is stopped.
+func (m *Monitor) Start() {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	m.Scan()
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Scan()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic scans of a started monitor.
+func (m *Monitor) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+// AlignWithCertificates sets the lifetime of the keys of the certificates
+// which have none to the expiration of their certificate, the latest one when
+// a key has several certificates. Nothing is done when the CSP does not
+// manage the lifetime of its keys.
+func AlignWithCertificates(csp bccsp.BCCSP, certs ...*x509.Certificate) error {
+	manager, ok := csp.(bccsp.KeyLifetimeManager)
+	if !ok {
+		return nil
+	}
+
+	notAfter := map[string]time.Time{}
+	for _, cert := range certs {
+		pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		if err != nil {
+			return errors.WithMessagef(err, "failed importing the public key of certificate %s", cert.Subject)
+		}
+		id := hex.EncodeToString(pk.SKI())
+		if cert.NotAfter.After(notAfter[id]) {
+			notAfter[id] = cert.NotAfter
+		}
+	}
+
+	for id, t := range notAfter {
+		ski, _ := hex.DecodeString(id)
+		if _, ok := manager.KeyNotAfter(ski); ok {
+			continue
+		}
+		if err := manager.SetKeyNotAfter(ski, t); err != nil {
+			return err
+		}
+		logger.Infof("Key %x expires with its certificate at %s", ski, t.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{Warning: 24 * time.Hour})
+	defer cleanup()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	csp.now = func() time.Time { return now }
+	require.NoError(t, csp.SetKeyNotAfter(k.SKI(), now.Add(48*time.Hour)))
+	require.NoError(t, csp.SetKeyNotAfter([]byte{1}, now.Add(12*time.Hour)))
+
+	daysToExpiry, expiring := &metricsfakes.Gauge{}, &metricsfakes.Gauge{}
+	daysToExpiry.WithReturns(daysToExpiry)
+	m := NewMonitor(csp, time.Hour, &Metrics{KeyDaysToExpiry: daysToExpiry, ExpiringKeys: expiring})
+
+	require.Equal(t, []bccsp.KeyLifetime{{SKI: []byte{1}, NotAfter: now.Add(12 * time.Hour)}}, m.Scan())
+	require.Equal(t, 2, daysToExpiry.SetCallCount())
+	require.Equal(t, []string{"ski", "01"}, daysToExpiry.WithArgsForCall(0))
+	require.Equal(t, 0.5, daysToExpiry.SetArgsForCall(0))
+	require.Equal(t, []string{"ski", hex.EncodeToString(k.SKI())}, daysToExpiry.WithArgsForCall(1))
+	require.Equal(t, 2.0, daysToExpiry.SetArgsForCall(1))
+	require.Equal(t, 1.0, expiring.SetArgsForCall(0))
+
+	now = now.Add(36 * time.Hour)
+	require.Len(t, m.Scan(), 2)
+	require.Equal(t, -1.0, daysToExpiry.SetArgsForCall(2))
+	require.Equal(t, 2.0, expiring.SetArgsForCall(1))
+
+	m.Start()
+	m.Stop()
+}
+
+func TestAlignWithCertificates(t *testing.T) {
+	csp, k, cleanup := newTestCSP(t, Opts{})
+	defer cleanup()
+	notAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	cert := newCertificate(t, csp, k, notAfter)
+	renewed := newCertificate(t, csp, k, notAfter.Add(time.Hour))
+
+	other, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	require.NoError(t, csp.SetKeyNotAfter(other.SKI(), notAfter.Add(-time.Hour)))
+	otherCert := newCertificate(t, csp, other, notAfter)
+
+	require.NoError(t, AlignWithCertificates(csp, cert, renewed, otherCert))
+	aligned, ok := csp.KeyNotAfter(k.SKI())
+	require.True(t, ok)
+	require.Equal(t, notAfter.Add(time.Hour), aligned)
+
+	// the lifetimes set explicitly are kept
+	kept, ok := csp.KeyNotAfter(other.SKI())
+	require.True(t, ok)
+	require.Equal(t, notAfter.Add(-time.Hour), kept)
+
+	// providers which do not manage the lifetime of keys are left untouched
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	require.NoError(t, AlignWithCertificates(provider, cert))
+}
+
+func newCertificate(t *testing.T, csp bccsp.BCCSP, k bccsp.Key, notAfter time.Time) *x509.Certificate {
+	s, err := signer.New(csp.(*CSP).BCCSP, k)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peer0.org1.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
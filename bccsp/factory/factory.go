@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/expiry"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
 )
@@ -60,5 +61,23 @@ func initBCCSP(f BCCSPFactory, config *FactoryOpts) (bccsp.BCCSP, error) {
 		return nil, errors.Errorf("Could not initialize BCCSP %s [%s]", f.Name(), err)
 	}
 
-	return csp, nil
+	return decorate(csp, config)
+}
+
+// decorate wraps the provider to apply the policies configured on top of it.
+func decorate(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	return withExpiry(csp, config)
+}
+
+// withExpiry wraps the provider to enforce the lifetime of its keys, when
+// configured to.
+func withExpiry(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.Expiry == nil || !config.Expiry.Enabled {
+		return csp, nil
+	}
+	expiring, err := expiry.New(csp, *config.Expiry)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Could not enforce the lifetime of keys")
+	}
+	return expiring, nil
 }
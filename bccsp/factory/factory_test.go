@@ -11,7 +11,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/expiry"
 	"github.com/hyperledger/fabric/bccsp/pkcs11"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
@@ -84,3 +87,20 @@ func TestGetDefault(t *testing.T) {
 	bccsp := GetDefault()
 	require.NotNil(t, bccsp, "Failed getting default BCCSP. Nil instance.")
 }
+
+func TestWithExpiry(t *testing.T) {
+	opts := &FactoryOpts{ProviderName: "SW", SwOpts: &SwOpts{HashFamily: "SHA2", SecLevel: 256, Ephemeral: true}}
+	csp, err := GetBCCSPFromOpts(opts)
+	require.NoError(t, err)
+	_, ok := csp.(bccsp.KeyLifetimeManager)
+	require.False(t, ok)
+
+	opts.Expiry = &expiry.Opts{Enabled: true, Grace: time.Hour}
+	csp, err = GetBCCSPFromOpts(opts)
+	require.NoError(t, err)
+	require.IsType(t, &expiry.CSP{}, csp)
+
+	opts.Expiry.Grace = -time.Hour
+	_, err = GetBCCSPFromOpts(opts)
+	require.EqualError(t, err, "Could not enforce the lifetime of keys: invalid grace period -1h0m0s: must not be negative")
+}
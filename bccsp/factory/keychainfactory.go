@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/keychain"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeychainBasedFactoryName is the name of the factory of the macOS Keychain-based BCCSP implementation
+	KeychainBasedFactoryName = "KEYCHAIN"
+)
+
+// KeychainFactory is the factory of the BCCSP whose keys are held by the
+// macOS Keychain.
+type KeychainFactory struct{}
+
+// Name returns the name of this factory
+func (f *KeychainFactory) Name() string {
+	return KeychainBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *KeychainFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.KeychainOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	return keychain.New(*config.KeychainOpts, sw.NewDummyKeyStore())
+}
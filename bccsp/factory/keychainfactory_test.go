@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeychainFactoryName(t *testing.T) {
+	f := &KeychainFactory{}
+	assert.Equal(t, f.Name(), KeychainBasedFactoryName)
+}
+
+func TestKeychainFactoryGetInvalidArgs(t *testing.T) {
+	f := &KeychainFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+}
+
+func TestGetBCCSPFromOptsKeychain(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("the Keychain is available on macOS")
+	}
+	opts := &FactoryOpts{
+		ProviderName: "KEYCHAIN",
+		KeychainOpts: &keychain.KeychainOpts{SecLevel: 256, HashFamily: "SHA2"},
+	}
+	_, err := GetBCCSPFromOpts(opts)
+	assert.EqualError(t, err, "Could not initialize BCCSP KEYCHAIN: the Keychain is only available on macOS, in binaries built with cgo")
+}
@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/kmip"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KMIPBasedFactoryName is the name of the factory of the KMIP-based BCCSP implementation
+	KMIPBasedFactoryName = "KMIP"
+)
+
+// KMIPFactory is the factory of the BCCSP whose keys are held by a KMIP server.
+type KMIPFactory struct{}
+
+// Name returns the name of this factory
+func (f *KMIPFactory) Name() string {
+	return KMIPBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *KMIPFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.KMIPOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	return kmip.New(*config.KMIPOpts, sw.NewDummyKeyStore())
+}
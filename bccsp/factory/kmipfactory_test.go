@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp/kmip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKMIPFactoryName(t *testing.T) {
+	f := &KMIPFactory{}
+	assert.Equal(t, f.Name(), KMIPBasedFactoryName)
+}
+
+func TestKMIPFactoryGetInvalidArgs(t *testing.T) {
+	f := &KMIPFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	opts := &FactoryOpts{
+		KMIPOpts: &kmip.KMIPOpts{SecLevel: 256, HashFamily: "SHA2"},
+	}
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "the address of the KMIP server is required")
+}
+
+func TestGetBCCSPFromOptsKMIP(t *testing.T) {
+	opts := &FactoryOpts{
+		ProviderName: "KMIP",
+		KMIPOpts:     &kmip.KMIPOpts{SecLevel: 256, HashFamily: "SHA2"},
+	}
+	_, err := GetBCCSPFromOpts(opts)
+	assert.EqualError(t, err, "Could not initialize BCCSP KMIP: the address of the KMIP server is required")
+}
@@ -10,6 +10,10 @@ package factory
 
 import (
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/expiry"
+	"github.com/hyperledger/fabric/bccsp/keychain"
+	"github.com/hyperledger/fabric/bccsp/kmip"
+	"github.com/hyperledger/fabric/bccsp/piv"
 	"github.com/pkg/errors"
 )
 
@@ -17,8 +21,12 @@ const pkcs11Enabled = false
 
 // FactoryOpts holds configuration information used to initialize factory implementations
 type FactoryOpts struct {
-	ProviderName string  `mapstructure:"default" json:"default" yaml:"Default"`
-	SwOpts       *SwOpts `mapstructure:"SW,omitempty" json:"SW,omitempty" yaml:"SwOpts"`
+	ProviderName string                 `mapstructure:"default" json:"default" yaml:"Default"`
+	SwOpts       *SwOpts                `mapstructure:"SW,omitempty" json:"SW,omitempty" yaml:"SwOpts"`
+	KMIPOpts     *kmip.KMIPOpts         `mapstructure:"KMIP,omitempty" json:"KMIP,omitempty" yaml:"KMIP"`
+	KeychainOpts *keychain.KeychainOpts `mapstructure:"KEYCHAIN,omitempty" json:"KEYCHAIN,omitempty" yaml:"Keychain"`
+	PIVOpts      *piv.PIVOpts           `mapstructure:"PIV,omitempty" json:"PIV,omitempty" yaml:"PIV"`
+	Expiry       *expiry.Opts           `mapstructure:"expiry,omitempty" json:"expiry,omitempty" yaml:"Expiry"`
 }
 
 // InitFactories must be called before using factory interfaces
@@ -57,6 +65,36 @@ func initFactories(config *FactoryOpts) error {
 		}
 	}
 
+	// KMIP-Based BCCSP
+	if config.ProviderName == "KMIP" && config.KMIPOpts != nil {
+		f := &KMIPFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing KMIP.BCCSP")
+		}
+	}
+
+	// Keychain-Based BCCSP
+	if config.ProviderName == "KEYCHAIN" && config.KeychainOpts != nil {
+		f := &KeychainFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing KEYCHAIN.BCCSP")
+		}
+	}
+
+	// PIV-Based BCCSP
+	if config.ProviderName == "PIV" && config.PIVOpts != nil {
+		f := &PIVFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing PIV.BCCSP")
+		}
+	}
+
 	if defaultBCCSP == nil {
 		return errors.Errorf("Could not find default `%s` BCCSP", config.ProviderName)
 	}
@@ -70,6 +108,12 @@ func GetBCCSPFromOpts(config *FactoryOpts) (bccsp.BCCSP, error) {
 	switch config.ProviderName {
 	case "SW":
 		f = &SWFactory{}
+	case "KMIP":
+		f = &KMIPFactory{}
+	case "KEYCHAIN":
+		f = &KeychainFactory{}
+	case "PIV":
+		f = &PIVFactory{}
 	default:
 		return nil, errors.Errorf("Could not find BCCSP, no '%s' provider", config.ProviderName)
 	}
@@ -78,5 +122,5 @@ func GetBCCSPFromOpts(config *FactoryOpts) (bccsp.BCCSP, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not initialize BCCSP %s", f.Name())
 	}
-	return csp, nil
+	return decorate(csp, config)
 }
@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/piv"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PIVBasedFactoryName is the name of the factory of the PIV token-based BCCSP implementation
+	PIVBasedFactoryName = "PIV"
+)
+
+// PIVFactory is the factory of the BCCSP whose signing keys are held by a
+// PIV token, such as a YubiKey.
+type PIVFactory struct{}
+
+// Name returns the name of this factory
+func (f *PIVFactory) Name() string {
+	return PIVBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *PIVFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.PIVOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	return piv.New(*config.PIVOpts, sw.NewDummyKeyStore())
+}
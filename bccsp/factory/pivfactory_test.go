@@ -0,0 +1,43 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp/piv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPIVFactoryName(t *testing.T) {
+	f := &PIVFactory{}
+	assert.Equal(t, f.Name(), PIVBasedFactoryName)
+}
+
+func TestPIVFactoryGetInvalidArgs(t *testing.T) {
+	f := &PIVFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+}
+
+func TestGetBCCSPFromOptsPIV(t *testing.T) {
+	opts := &FactoryOpts{
+		ProviderName: "PIV",
+		PIVOpts:      &piv.PIVOpts{SecLevel: 256, HashFamily: "SHA2", Slots: []string{"9f"}},
+	}
+	_, err := GetBCCSPFromOpts(opts)
+	assert.EqualError(t, err, "Could not initialize BCCSP PIV: Invalid PIV slot 9f: must be 9a, 9c, 9d or 9e")
+
+	opts.PIVOpts.Slots = []string{"9c"}
+	csp, err := GetBCCSPFromOpts(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+}
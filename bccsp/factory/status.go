@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+// StatusResponse is the JSON representation of the status of a BCCSP
+// provider served by the StatusHandler.
+type StatusResponse struct {
+	*bccsp.Status
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StatusHandler reports the status of a BCCSP provider upon GET requests, and
+// checks the health of the provider for the health check handler of the
+// operations service.
+type StatusHandler struct {
+	CSP bccsp.BCCSP
+}
+
+// NewStatusHandler returns a StatusHandler for the given provider.
+func NewStatusHandler(csp bccsp.BCCSP) *StatusHandler {
+	return &StatusHandler{CSP: csp}
+}
+
+// Status returns the status of the provider. Providers that do not report
+// their status are reported as healthy, without any detail.
+func (h *StatusHandler) Status() (*bccsp.Status, error) {
+	reporter, ok := h.CSP.(bccsp.StatusReporter)
+	if !ok {
+		return &bccsp.Status{Provider: "unknown"}, nil
+	}
+	return reporter.Status()
+}
+
+// HealthCheck returns an error when the provider is unable to perform
+// cryptographic operations.
+func (h *StatusHandler) HealthCheck(ctx context.Context) error {
+	_, err := h.Status()
+	return err
+}
+
+func (h *StatusHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		h.sendResponse(resp, http.StatusBadRequest, &StatusResponse{
+			Error: fmt.Sprintf("invalid request method: %s", req.Method),
+		})
+		return
+	}
+
+	status, err := h.Status()
+	if err != nil {
+		h.sendResponse(resp, http.StatusServiceUnavailable, &StatusResponse{Status: status, Error: err.Error()})
+		return
+	}
+	h.sendResponse(resp, http.StatusOK, &StatusResponse{Status: status, Healthy: true})
+}
+
+func (h *StatusHandler) sendResponse(resp http.ResponseWriter, code int, payload *StatusResponse) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	if err := json.NewEncoder(resp).Encode(payload); err != nil {
+		logger.Errorw("failed to encode payload", "error", err)
+	}
+}
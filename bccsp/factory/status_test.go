@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingCSP struct {
+	bccsp.BCCSP
+}
+
+func (failingCSP) Status() (*bccsp.Status, error) {
+	return &bccsp.Status{Provider: "PKCS11"}, errors.New("failed opening session: CKR_DEVICE_REMOVED")
+}
+
+func TestStatusHandler(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	_, err = csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	h := NewStatusHandler(csp)
+	assert.NoError(t, h.HealthCheck(context.Background()))
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/keystore", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"provider": "SW",
+		"fipsMode": false,
+		"keys": {"private": 1, "public": 0, "symmetric": 0},
+		"healthy": true
+	}`, resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/keystore", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"healthy": false, "error": "invalid request method: POST"}`, resp.Body.String())
+}
+
+func TestStatusHandlerUnhealthy(t *testing.T) {
+	h := NewStatusHandler(failingCSP{})
+	assert.EqualError(t, h.HealthCheck(context.Background()), "failed opening session: CKR_DEVICE_REMOVED")
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/keystore", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.JSONEq(t, `{
+		"provider": "PKCS11",
+		"fipsMode": false,
+		"keys": {"private": 0, "public": 0, "symmetric": 0},
+		"healthy": false,
+		"error": "failed opening session: CKR_DEVICE_REMOVED"
+	}`, resp.Body.String())
+}
+
+func TestStatusHandlerUnknownProvider(t *testing.T) {
+	type opaqueCSP struct{ bccsp.BCCSP }
+	h := NewStatusHandler(opaqueCSP{})
+	assert.NoError(t, h.HealthCheck(context.Background()))
+
+	status, err := h.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, &bccsp.Status{Provider: "unknown"}, status)
+}
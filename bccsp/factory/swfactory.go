@@ -16,6 +16,10 @@ limitations under the License.
 package factory
 
 import (
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/sw"
 	"github.com/pkg/errors"
@@ -48,7 +52,11 @@ func (f *SWFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
 	case swOpts.Ephemeral:
 		ks = sw.NewDummyKeyStore()
 	case swOpts.FileKeystore != nil:
-		fks, err := sw.NewFileBasedKeyStore(nil, swOpts.FileKeystore.KeyStorePath, false)
+		opts, err := swOpts.FileKeystore.keyStoreOpts()
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to initialize software key store")
+		}
+		fks, err := sw.NewFileBasedKeyStoreWithOpts(nil, swOpts.FileKeystore.KeyStorePath, false, opts)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to initialize software key store")
 		}
@@ -60,6 +68,22 @@ func (f *SWFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
 		ks = sw.NewDummyKeyStore()
 	}
 
+	if swOpts.KernelKeyring != nil {
+		// The keys of ephemeral key stores only live in the keyring
+		backing := ks
+		if swOpts.Ephemeral || (swOpts.FileKeystore == nil && swOpts.InmemKeystore == nil) {
+			backing = nil
+		}
+		kks, err := sw.NewKernelKeyringKeyStore(backing, sw.KernelKeyringOpts{
+			Keyring: swOpts.KernelKeyring.Keyring,
+			Timeout: swOpts.KernelKeyring.Timeout,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to initialize kernel keyring key store")
+		}
+		ks = kks
+	}
+
 	return sw.NewWithParams(swOpts.SecLevel, swOpts.HashFamily, ks)
 }
 
@@ -74,11 +98,166 @@ type SwOpts struct {
 	FileKeystore  *FileKeystoreOpts  `mapstructure:"filekeystore,omitempty" json:"filekeystore,omitempty" yaml:"FileKeyStore"`
 	DummyKeystore *DummyKeystoreOpts `mapstructure:"dummykeystore,omitempty" json:"dummykeystore,omitempty"`
 	InmemKeystore *InmemKeystoreOpts `mapstructure:"inmemkeystore,omitempty" json:"inmemkeystore,omitempty"`
+	// KernelKeyring holds the material of the private and symmetric keys of
+	// the keystore in the Linux kernel keyring, out of the memory of the
+	// process.
+	KernelKeyring *KernelKeyringOpts `mapstructure:"kernelkeyring,omitempty" json:"kernelkeyring,omitempty" yaml:"KernelKeyring,omitempty"`
 }
 
 // Pluggable Keystores, could add JKS, P12, etc..
 type FileKeystoreOpts struct {
 	KeyStorePath string `mapstructure:"keystore" yaml:"KeyStore"`
+	// IntegrityKey is the path of the master key of the MACs protecting the
+	// key files, generated when missing. The key files are not protected
+	// when it is empty.
+	IntegrityKey string `mapstructure:"integritykey,omitempty" json:"integritykey,omitempty" yaml:"IntegrityKey,omitempty"`
+	// Permissions are the permissions enforced on the keystore, which are
+	// not enforced when nil.
+	Permissions *FilePermissionsOpts `mapstructure:"permissions,omitempty" json:"permissions,omitempty" yaml:"Permissions,omitempty"`
+	// DPAPI protects the key files with the Windows Data Protection API,
+	// which is only available on Windows. The key files are stored in the
+	// clear when it is nil.
+	DPAPI *DPAPIOpts `mapstructure:"dpapi,omitempty" json:"dpapi,omitempty" yaml:"DPAPI,omitempty"`
+	// SEVSNP seals the key files to the AMD SEV-SNP confidential VM running
+	// the process, so that they are only unsealed by the guests launched
+	// with the same measurement.
+	SEVSNP *SEVSNPOpts `mapstructure:"sevsnp,omitempty" json:"sevsnp,omitempty" yaml:"SEVSNP,omitempty"`
+	// NitroEnclave seals the key files under a data key that KMS only
+	// decrypts for the attested AWS Nitro Enclaves.
+	NitroEnclave *NitroEnclaveOpts `mapstructure:"nitroenclave,omitempty" json:"nitroenclave,omitempty" yaml:"NitroEnclave,omitempty"`
+}
+
+// DPAPIOpts configures the protection of the key files of the file keystore
+// with the Windows Data Protection API.
+type DPAPIOpts struct {
+	// LocalMachine protects the key files with the credentials of the
+	// machine instead of those of the user running the process.
+	LocalMachine bool `mapstructure:"localmachine,omitempty" json:"localmachine,omitempty" yaml:"LocalMachine,omitempty"`
+}
+
+// SEVSNPOpts configures the sealing of the key files of the file keystore
+// to an AMD SEV-SNP confidential VM.
+type SEVSNPOpts struct {
+	// Fields are the fields of the guest the key files are sealed to, among
+	// policy, image-id, family-id and measurement. The key files are sealed
+	// to the measurement and the policy by default.
+	Fields []string `mapstructure:"fields,omitempty" json:"fields,omitempty" yaml:"Fields,omitempty"`
+	// VMPL is the VM privilege level the key files are sealed to.
+	VMPL int `mapstructure:"vmpl,omitempty" json:"vmpl,omitempty" yaml:"VMPL,omitempty"`
+}
+
+// NitroEnclaveOpts configures the sealing of the key files of the file
+// keystore to an AWS Nitro Enclave.
+type NitroEnclaveOpts struct {
+	// DataKey is the path of the base64 encoded ciphertext of the data key,
+	// generated by KMS with a key only decrypting for the attested enclaves.
+	DataKey string `mapstructure:"datakey,omitempty" json:"datakey,omitempty" yaml:"DataKey,omitempty"`
+	// Region is the region of the KMS key.
+	Region string `mapstructure:"region,omitempty" json:"region,omitempty" yaml:"Region,omitempty"`
+	// ProxyPort is the vsock port of the KMS proxy, 8000 by default.
+	ProxyPort int `mapstructure:"proxyport,omitempty" json:"proxyport,omitempty" yaml:"ProxyPort,omitempty"`
+	// Tool is the KMS tool of the Nitro Enclaves SDK, kmstool_enclave_cli
+	// by default.
+	Tool string `mapstructure:"tool,omitempty" json:"tool,omitempty" yaml:"Tool,omitempty"`
+}
+
+// FilePermissionsOpts configures the permissions enforced on the folder and
+// the key files of the file keystore. The modes are octal strings.
+type FilePermissionsOpts struct {
+	// FileMode is the mode of the key files, 0600 by default.
+	FileMode string `mapstructure:"filemode,omitempty" json:"filemode,omitempty" yaml:"FileMode,omitempty"`
+	// DirMode is the mode of the keystore folder, 0700 by default.
+	DirMode string `mapstructure:"dirmode,omitempty" json:"dirmode,omitempty" yaml:"DirMode,omitempty"`
+	// AllowPermissive loads key files more permissive than FileMode, such as
+	// world-readable ones, with a warning instead of refusing them.
+	AllowPermissive bool `mapstructure:"allowpermissive,omitempty" json:"allowpermissive,omitempty" yaml:"AllowPermissive,omitempty"`
+	// CheckOwner requires the keystore to be owned by the user running the
+	// process.
+	CheckOwner bool `mapstructure:"checkowner,omitempty" json:"checkowner,omitempty" yaml:"CheckOwner,omitempty"`
+	// Umask are the permission bits the umask of the process must mask, such
+	// as 0077, or empty for the umask not to be checked.
+	Umask string `mapstructure:"umask,omitempty" json:"umask,omitempty" yaml:"Umask,omitempty"`
+}
+
+// keyStoreOpts returns the protections of the file keystore, loading its
+// integrity key and its protector.
+func (o *FileKeystoreOpts) keyStoreOpts() (*sw.FileKeyStoreOpts, error) {
+	opts := &sw.FileKeyStoreOpts{}
+	if o.IntegrityKey != "" {
+		key, err := sw.LoadIntegrityKey(o.IntegrityKey)
+		if err != nil {
+			return nil, err
+		}
+		opts.IntegrityKey = key
+	}
+	if o.Permissions != nil {
+		opts.Permissions = &sw.FilePermissions{
+			AllowPermissive: o.Permissions.AllowPermissive,
+			CheckOwner:      o.Permissions.CheckOwner,
+		}
+		modes := []struct {
+			name  string
+			value string
+			mode  *os.FileMode
+		}{
+			{"FileMode", o.Permissions.FileMode, &opts.Permissions.FileMode},
+			{"DirMode", o.Permissions.DirMode, &opts.Permissions.DirMode},
+			{"Umask", o.Permissions.Umask, &opts.Permissions.Umask},
+		}
+		for _, m := range modes {
+			if m.value == "" {
+				continue
+			}
+			mode, err := strconv.ParseUint(m.value, 8, 32)
+			if err != nil || mode > 0777 {
+				return nil, errors.Errorf("invalid %s %s: it must be an octal mode such as 0600", m.name, m.value)
+			}
+			*m.mode = os.FileMode(mode)
+		}
+	}
+	protectors := 0
+	for _, set := range []bool{o.DPAPI != nil, o.SEVSNP != nil, o.NitroEnclave != nil} {
+		if set {
+			protectors++
+		}
+	}
+	if protectors > 1 {
+		return nil, errors.New("only one of DPAPI, SEVSNP and NitroEnclave can protect the key files")
+	}
+
+	var err error
+	switch {
+	case o.DPAPI != nil:
+		opts.Protector, err = sw.NewDPAPIProtector(o.DPAPI.LocalMachine, nil)
+	case o.SEVSNP != nil:
+		opts.Protector, err = sw.NewSEVSNPProtector(sw.SEVSNPOpts{
+			Fields: o.SEVSNP.Fields,
+			VMPL:   o.SEVSNP.VMPL,
+		})
+	case o.NitroEnclave != nil:
+		opts.Protector, err = sw.NewNitroEnclaveProtector(sw.NitroEnclaveOpts{
+			DataKey:   o.NitroEnclave.DataKey,
+			Region:    o.NitroEnclave.Region,
+			ProxyPort: o.NitroEnclave.ProxyPort,
+			Tool:      o.NitroEnclave.Tool,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// KernelKeyringOpts configures the caching of the material of the keys in
+// the Linux kernel keyring.
+type KernelKeyringOpts struct {
+	// Keyring is the keyring holding the key material: session (the
+	// default), user or process.
+	Keyring string `mapstructure:"keyring,omitempty" json:"keyring,omitempty" yaml:"Keyring,omitempty"`
+	// Timeout is the time after which the key material expires from the
+	// keyring, to be loaded again from the keystore. It never expires when
+	// zero.
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"Timeout,omitempty"`
 }
 
 type DummyKeystoreOpts struct{}
@@ -16,7 +16,10 @@ limitations under the License.
 package factory
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,4 +70,69 @@ func TestSWFactoryGet(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, csp)
 
+	tempDir, err := ioutil.TempDir("", "swfactory")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	opts = &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:   256,
+			HashFamily: "SHA2",
+			FileKeystore: &FileKeystoreOpts{
+				KeyStorePath: filepath.Join(tempDir, "keystore"),
+				IntegrityKey: filepath.Join(tempDir, "integrity.key"),
+			},
+		},
+	}
+	csp, err = f.Get(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+	assert.FileExists(t, filepath.Join(tempDir, "integrity.key"))
+
+	opts.SwOpts.FileKeystore.IntegrityKey = tempDir
+	_, err = f.Get(opts)
+	assert.Error(t, err)
+
+	opts.SwOpts.FileKeystore = &FileKeystoreOpts{
+		KeyStorePath: filepath.Join(tempDir, "protected"),
+		Permissions:  &FilePermissionsOpts{FileMode: "0400", DirMode: "0700"},
+	}
+	csp, err = f.Get(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+
+	opts.SwOpts.FileKeystore.Permissions.FileMode = "rw-------"
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize software key store: invalid FileMode rw-------: it must be an octal mode such as 0600")
+
+	if runtime.GOOS != "windows" {
+		opts.SwOpts.FileKeystore = &FileKeystoreOpts{
+			KeyStorePath: filepath.Join(tempDir, "dpapi"),
+			DPAPI:        &DPAPIOpts{},
+		}
+		_, err = f.Get(opts)
+		assert.EqualError(t, err, "Failed to initialize software key store: the Data Protection API is only available on Windows")
+	}
+
+	opts.SwOpts.FileKeystore = &FileKeystoreOpts{
+		KeyStorePath: filepath.Join(tempDir, "sealed"),
+		SEVSNP:       &SEVSNPOpts{},
+		NitroEnclave: &NitroEnclaveOpts{},
+	}
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize software key store: only one of DPAPI, SEVSNP and NitroEnclave can protect the key files")
+	opts.SwOpts.FileKeystore.SEVSNP = nil
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize software key store: the data key of the Nitro Enclave must be set")
+	opts.SwOpts.FileKeystore.NitroEnclave = nil
+	opts.SwOpts.FileKeystore.SEVSNP = &SEVSNPOpts{Fields: []string{"svn"}}
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize software key store: unknown SEV-SNP guest field svn: must be policy, image-id, family-id or measurement")
+
+	opts.SwOpts.FileKeystore = &FileKeystoreOpts{KeyStorePath: filepath.Join(tempDir, "keyring")}
+	opts.SwOpts.KernelKeyring = &KernelKeyringOpts{Keyring: "thread"}
+	_, err = f.Get(opts)
+	assert.Error(t, err)
+	if runtime.GOOS == "linux" {
+		assert.EqualError(t, err, "Failed to initialize kernel keyring key store: unknown kernel keyring thread: must be session, user or process")
+	}
 }
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+// HD hierarchical deterministic derivation of ECDSA P-256 and Ed25519 keys from
+// a seed, as specified by SLIP-0010 (seed import and key derivation). The keys
+// derived from the master key of a seed are identified by their path in the
+// hierarchy, so that all of them are recovered from the seed alone.
+const HD = "HD"
+
+// HDSeedImportOpts contains options for the importation of the seed of a
+// hierarchy of keys, between 16 and 64 bytes long. The imported key is the
+// master key of the hierarchy, from which keys are derived with
+// HDKeyDerivOpts. It is never stored: the seed is expected to be backed up.
+type HDSeedImportOpts struct {
+	// KeyAlgorithm is the algorithm of the keys of the hierarchy, ECDSAP256
+	// or ED25519.
+	KeyAlgorithm string
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *HDSeedImportOpts) Algorithm() string {
+	return HD
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *HDSeedImportOpts) Ephemeral() bool {
+	return true
+}
+
+// HDKeyDerivOpts contains options for the derivation of a key from the master
+// key of a hierarchy. The derived key is an ECDSA or Ed25519 private key, as
+// the ones generated by the BCCSP.
+type HDKeyDerivOpts struct {
+	// Path is the path of the key in the hierarchy, such as m/44'/0'/3', in
+	// which hardened indices are marked with ' or h. Ed25519 keys only have
+	// hardened indices.
+	Path      string
+	Temporary bool
+}
+
+// Algorithm returns the key derivation algorithm identifier (to be used).
+func (opts *HDKeyDerivOpts) Algorithm() string {
+	return HD
+}
+
+// Ephemeral returns true if the key to derive has to be ephemeral,
+// false otherwise.
+func (opts *HDKeyDerivOpts) Ephemeral() bool {
+	return opts.Temporary
+}
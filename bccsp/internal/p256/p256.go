@@ -0,0 +1,227 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package p256 implements the arithmetic of the points of the P-256 curve,
+// and of the scalars modulo the order of its group, shared by the
+// cryptographic protocols of BCCSP built on this group.
+package p256
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// PointSize is the size of a marshaled point
+const PointSize = 65
+
+var (
+	// Curve is the P-256 curve
+	Curve = elliptic.P256()
+	// Order is the order of the group of the points of the curve
+	Order = Curve.Params().N
+	// sqrtExp is (p+1)/4, the exponent computing square roots modulo p, as p = 3 mod 4
+	sqrtExp = new(big.Int).Rsh(new(big.Int).Add(Curve.Params().P, big.NewInt(1)), 2)
+)
+
+// Point is a point of the P-256 curve, (0,0) being the point at infinity
+type Point struct {
+	x, y *big.Int
+}
+
+// Identity returns the point at infinity
+func Identity() Point {
+	return Point{x: new(big.Int), y: new(big.Int)}
+}
+
+// Generator returns the base point of the curve
+func Generator() Point {
+	return Point{x: Curve.Params().Gx, y: Curve.Params().Gy}
+}
+
+// Coordinates returns the affine coordinates of the point
+func (p Point) Coordinates() (x, y *big.Int) {
+	return p.x, p.y
+}
+
+// IsIdentity returns true if the point is the point at infinity
+func (p Point) IsIdentity() bool {
+	return p.x.Sign() == 0 && p.y.Sign() == 0
+}
+
+// Add returns the sum of the points
+func (p Point) Add(q Point) Point {
+	switch {
+	case p.IsIdentity():
+		return q
+	case q.IsIdentity():
+		return p
+	}
+	x, y := Curve.Add(p.x, p.y, q.x, q.y)
+	return Point{x: x, y: y}
+}
+
+// Neg returns the opposite of the point
+func (p Point) Neg() Point {
+	if p.IsIdentity() {
+		return p
+	}
+	return Point{x: p.x, y: new(big.Int).Sub(Curve.Params().P, p.y)}
+}
+
+// Mul returns the product of the point by the scalar
+func (p Point) Mul(k *big.Int) Point {
+	k = new(big.Int).Mod(k, Order)
+	if k.Sign() == 0 || p.IsIdentity() {
+		return Identity()
+	}
+	x, y := Curve.ScalarMult(p.x, p.y, ScalarBytes(k))
+	return Point{x: x, y: y}
+}
+
+// Equal returns true if the points are the same
+func (p Point) Equal(q Point) bool {
+	return p.x.Cmp(q.x) == 0 && p.y.Cmp(q.y) == 0
+}
+
+// Bytes marshals the point in uncompressed form, the point at infinity being all zeros
+func (p Point) Bytes() []byte {
+	if p.IsIdentity() {
+		return make([]byte, PointSize)
+	}
+	return elliptic.Marshal(Curve, p.x, p.y)
+}
+
+// ParsePoint unmarshals a point marshaled by Bytes
+func ParsePoint(raw []byte) (Point, error) {
+	if len(raw) != PointSize {
+		return Point{}, errors.Errorf("invalid point size %d, expected %d", len(raw), PointSize)
+	}
+	if isZero(raw) {
+		return Identity(), nil
+	}
+	x, y := elliptic.Unmarshal(Curve, raw)
+	if x == nil {
+		return Point{}, errors.New("invalid point, not on the curve")
+	}
+	return Point{x: x, y: y}, nil
+}
+
+// MultiExp computes the sum of the products of the scalars and the points
+func MultiExp(scalars []*big.Int, points []Point) Point {
+	sum := Identity()
+	for i := range scalars {
+		sum = sum.Add(points[i].Mul(scalars[i]))
+	}
+	return sum
+}
+
+// HashToPoint derives a point whose discrete logarithm is unknown, by hashing the label
+// to the x coordinate of a point until one is found on the curve
+func HashToPoint(label string) Point {
+	params := Curve.Params()
+	var counter [4]byte
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		digest := sha256.Sum256(append([]byte(label), counter[:]...))
+		x := new(big.Int).SetBytes(digest[:])
+		if x.Cmp(params.P) >= 0 {
+			continue
+		}
+		// y^2 = x^3 - 3x + b
+		y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+		y2.Sub(y2, new(big.Int).Mul(x, big.NewInt(3)))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+		y := new(big.Int).Exp(y2, sqrtExp, params.P)
+		if new(big.Int).Exp(y, big.NewInt(2), params.P).Cmp(y2) != 0 {
+			continue
+		}
+		return Point{x: x, y: y}
+	}
+}
+
+// ScalarBytes returns the 32 byte big endian encoding of a reduced scalar
+func ScalarBytes(k *big.Int) []byte {
+	b := k.Bytes()
+	return append(make([]byte, 32-len(b)), b...)
+}
+
+// ParseScalar unmarshals a big endian scalar, which must be reduced
+func ParseScalar(raw []byte) (*big.Int, error) {
+	k := new(big.Int).SetBytes(raw)
+	if k.Cmp(Order) >= 0 {
+		return nil, errors.New("invalid scalar, not reduced")
+	}
+	return k, nil
+}
+
+// RandomScalar returns a uniformly random non-zero scalar
+func RandomScalar() (*big.Int, error) {
+	for {
+		k, err := rand.Int(rand.Reader, Order)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed generating random scalar")
+		}
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// scalar arithmetic modulo the order of the group
+
+// ScalarAdd returns a+b
+func ScalarAdd(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Add(a, b), Order)
+}
+
+// ScalarSub returns a-b
+func ScalarSub(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Sub(a, b), Order)
+}
+
+// ScalarMul returns a*b
+func ScalarMul(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(a, b), Order)
+}
+
+// ScalarInverse returns the inverse of a
+func ScalarInverse(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, Order)
+}
+
+// Powers returns the vector (1, x, x^2, ..., x^(n-1))
+func Powers(x *big.Int, n int) []*big.Int {
+	v := make([]*big.Int, n)
+	v[0] = big.NewInt(1)
+	for i := 1; i < n; i++ {
+		v[i] = ScalarMul(v[i-1], x)
+	}
+	return v
+}
+
+// InnerProduct returns the inner product of the vectors of scalars
+func InnerProduct(a, b []*big.Int) *big.Int {
+	sum := new(big.Int)
+	for i := range a {
+		sum.Add(sum, new(big.Int).Mul(a[i], b[i]))
+	}
+	return sum.Mod(sum, Order)
+}
@@ -0,0 +1,25 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+// KeychainOpts contains options for the KeychainFactory
+type KeychainOpts struct {
+	// Default algorithms when not specified. The keys held by the Keychain
+	// are P-256 keys, so that the security level must be 256.
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Label tags the keys of the provider in the Keychain, so that the keys
+	// of different applications are kept apart. It defaults to
+	// "org.hyperledger.fabric".
+	Label string `mapstructure:"label,omitempty" json:"label,omitempty"`
+	// SecureEnclave generates the keys in the Secure Enclave, which never
+	// releases them and signs with them itself. The Secure Enclave requires
+	// a Mac with a T2 or Apple silicon chip, and a binary signed with a
+	// keychain access group entitlement.
+	SecureEnclave bool `mapstructure:"secureenclave,omitempty" json:"secureenclave,omitempty"`
+}
@@ -0,0 +1,276 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_keychain")
+
+// defaultLabel is the label of the keys of the provider in the Keychain when
+// none is configured.
+const defaultLabel = "org.hyperledger.fabric"
+
+// keychain holds P-256 private keys, which it identifies by their public
+// keys, and signs with them.
+type keychain interface {
+	// generate generates a private key and returns its public key.
+	generate() (*ecdsa.PublicKey, error)
+	// keys returns the public keys of the private keys held.
+	keys() ([]*ecdsa.PublicKey, error)
+	// sign returns the DER encoded ECDSA signature of the digest with the
+	// private key of pub.
+	sign(pub *ecdsa.PublicKey, digest []byte) ([]byte, error)
+	// remove deletes the private key of pub.
+	remove(pub *ecdsa.PublicKey) error
+}
+
+// New returns a BCCSP whose P-256 keys are held by the macOS Keychain, in
+// the Secure Enclave when configured, which generates them and signs with
+// them. Hashing, verification and the operations on the other keys are
+// performed in software, with the keys of keyStore.
+func New(opts KeychainOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	if opts.Label == "" {
+		opts.Label = defaultLabel
+	}
+	kc, err := newSystemKeychain(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newWithKeychain(opts, keyStore, kc)
+}
+
+func newWithKeychain(opts KeychainOpts, keyStore bccsp.KeyStore, kc keychain) (bccsp.BCCSP, error) {
+	if opts.SecLevel != 256 {
+		return nil, errors.Errorf("Failed initializing configuration: Security level not supported [%d]: the Keychain holds P-256 keys", opts.SecLevel)
+	}
+
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	return &impl{
+		BCCSP:    swCSP,
+		keychain: kc,
+		keys:     map[string]*ecdsaPrivateKey{},
+	}, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	keychain keychain
+
+	// keys caches the keys of the Keychain by the hex encoding of their SKI
+	mutex sync.Mutex
+	keys  map[string]*ecdsaPrivateKey
+}
+
+// KeyGen generates a key using opts.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+	// Ephemeral keys are not worth storing in the Keychain
+	if opts.Ephemeral() {
+		return csp.BCCSP.KeyGen(opts)
+	}
+
+	switch opts.(type) {
+	case *bccsp.ECDSAKeyGenOpts, *bccsp.ECDSAP256KeyGenOpts:
+		return csp.generateECKey()
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+}
+
+func (csp *impl) generateECKey() (bccsp.Key, error) {
+	pub, err := csp.keychain.generate()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed generating ECDSA key in the Keychain")
+	}
+	k := csp.cache(pub)
+	logger.Debugf("Generated ECDSA key %x in the Keychain", k.SKI())
+	return k, nil
+}
+
+// cache caches the key of pub and returns it.
+func (csp *impl) cache(pub *ecdsa.PublicKey) *ecdsaPrivateKey {
+	// The SKI is computed as the SW provider does, to find the key of the
+	// certificates of the key
+	hash := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	k := &ecdsaPrivateKey{ecdsaPublicKey{hash[:], pub}}
+
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	csp.keys[hex.EncodeToString(k.SKI())] = k
+	return k
+}
+
+// lookup returns the key of the Keychain whose SKI is ski, reloading the
+// keys of the Keychain when it is not cached.
+func (csp *impl) lookup(ski []byte) (*ecdsaPrivateKey, error) {
+	csp.mutex.Lock()
+	k, ok := csp.keys[hex.EncodeToString(ski)]
+	csp.mutex.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	if _, err := csp.reload(); err != nil {
+		return nil, err
+	}
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	return csp.keys[hex.EncodeToString(ski)], nil
+}
+
+// reload replaces the cached keys with the keys of the Keychain, which are
+// returned.
+func (csp *impl) reload() ([]bccsp.Key, error) {
+	pubs, err := csp.keychain.keys()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed listing the keys of the Keychain")
+	}
+	csp.mutex.Lock()
+	csp.keys = map[string]*ecdsaPrivateKey{}
+	csp.mutex.Unlock()
+
+	var keys []bccsp.Key
+	for _, pub := range pubs {
+		keys = append(keys, csp.cache(pub))
+	}
+	return keys, nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	k, err := csp.lookup(ski)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return csp.BCCSP.GetKey(ski)
+	}
+	return k, nil
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+//
+// Note that when a signature of a hash of a larger message is needed,
+// the caller is responsible for hashing the larger message and passing
+// the hash (as digest).
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.signECDSA(key, digest)
+	default:
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+}
+
+func (csp *impl) signECDSA(k *ecdsaPrivateKey, digest []byte) ([]byte, error) {
+	sig, err := csp.keychain.sign(k.pub.pub, digest)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed signing with ECDSA key in the Keychain")
+	}
+	if _, _, err := utils.UnmarshalECDSASignature(sig); err != nil {
+		return nil, errors.WithMessage(err, "Invalid signature returned by the Keychain")
+	}
+	return utils.SignatureToLowS(k.pub.pub, sig)
+}
+
+// Verify verifies signature against key k and digest
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+
+	// Verification only needs the public key, so it is done in software
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.verifyECDSA(&key.pub, signature, digest, opts)
+	case *ecdsaPublicKey:
+		return csp.verifyECDSA(key, signature, digest, opts)
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+}
+
+func (csp *impl) verifyECDSA(k *ecdsaPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pk, err := csp.BCCSP.KeyImport(k.pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return false, err
+	}
+	return csp.BCCSP.Verify(pk, signature, digest, opts)
+}
+
+// ListKeys returns the keys held by the Keychain for this provider.
+func (csp *impl) ListKeys() ([]bccsp.Key, error) {
+	return csp.reload()
+}
+
+// DeleteKey deletes the key from the Keychain.
+func (csp *impl) DeleteKey(ski []byte) error {
+	k, err := csp.lookup(ski)
+	if err != nil {
+		return err
+	}
+	if k == nil {
+		return errors.Errorf("key %x not found", ski)
+	}
+	if err := csp.keychain.remove(k.pub.pub); err != nil {
+		return errors.WithMessagef(err, "failed deleting key %x from the Keychain", ski)
+	}
+
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	delete(csp.keys, hex.EncodeToString(ski))
+	return nil
+}
+
+// Status returns the status of the provider, whose keys are counted from the
+// Keychain. An error is returned when the Keychain is unavailable.
+func (csp *impl) Status() (*bccsp.Status, error) {
+	status := &bccsp.Status{Provider: "KEYCHAIN"}
+	keys, err := csp.reload()
+	if err != nil {
+		return status, err
+	}
+	status.Keys.Private = len(keys)
+	status.Keys.Public = len(keys)
+	return status, nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memKeychain is a keychain holding its keys in memory.
+type memKeychain struct {
+	mutex sync.Mutex
+	privs []*ecdsa.PrivateKey
+	err   error
+}
+
+func (kc *memKeychain) generate() (*ecdsa.PublicKey, error) {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if kc.err != nil {
+		return nil, kc.err
+	}
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kc.privs = append(kc.privs, k)
+	return &k.PublicKey, nil
+}
+
+func (kc *memKeychain) keys() ([]*ecdsa.PublicKey, error) {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if kc.err != nil {
+		return nil, kc.err
+	}
+	var pubs []*ecdsa.PublicKey
+	for _, k := range kc.privs {
+		pubs = append(pubs, &k.PublicKey)
+	}
+	return pubs, nil
+}
+
+func (kc *memKeychain) sign(pub *ecdsa.PublicKey, digest []byte) ([]byte, error) {
+	k, err := kc.find(pub)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, kc.privs[k], digest)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MarshalECDSASignature(r, s)
+}
+
+func (kc *memKeychain) remove(pub *ecdsa.PublicKey) error {
+	k, err := kc.find(pub)
+	if err != nil {
+		return err
+	}
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	kc.privs = append(kc.privs[:k], kc.privs[k+1:]...)
+	return nil
+}
+
+func (kc *memKeychain) find(pub *ecdsa.PublicKey) (int, error) {
+	kc.mutex.Lock()
+	defer kc.mutex.Unlock()
+	if kc.err != nil {
+		return 0, kc.err
+	}
+	for i, k := range kc.privs {
+		if k.X.Cmp(pub.X) == 0 && k.Y.Cmp(pub.Y) == 0 {
+			return i, nil
+		}
+	}
+	return 0, errors.New("The specified item could not be found in the keychain.")
+}
+
+func newTestCSP(t *testing.T, kc keychain) bccsp.BCCSP {
+	csp, err := newWithKeychain(KeychainOpts{SecLevel: 256, HashFamily: "SHA2"}, sw.NewInMemoryKeyStore(), kc)
+	require.NoError(t, err)
+	return csp
+}
+
+func TestNew(t *testing.T) {
+	_, err := newWithKeychain(KeychainOpts{SecLevel: 384, HashFamily: "SHA2"}, sw.NewDummyKeyStore(), &memKeychain{})
+	assert.EqualError(t, err, "Failed initializing configuration: Security level not supported [384]: the Keychain holds P-256 keys")
+	_, err = newWithKeychain(KeychainOpts{SecLevel: 256, HashFamily: "SHA2"}, nil, &memKeychain{})
+	assert.EqualError(t, err, "Invalid bccsp.KeyStore instance. It must be different from nil")
+	_, err = newWithKeychain(KeychainOpts{SecLevel: 256, HashFamily: "SHA1"}, sw.NewDummyKeyStore(), &memKeychain{})
+	assert.Error(t, err)
+
+	if runtime.GOOS != "darwin" {
+		_, err = New(KeychainOpts{SecLevel: 256, HashFamily: "SHA2"}, sw.NewDummyKeyStore())
+		assert.EqualError(t, err, "the Keychain is only available on macOS, in binaries built with cgo")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	kc := &memKeychain{}
+	csp := newTestCSP(t, kc)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	require.IsType(t, &ecdsaPrivateKey{}, k)
+	assert.True(t, k.Private())
+	_, err = k.Bytes()
+	assert.Error(t, err)
+	require.Len(t, kc.privs, 1)
+
+	// the SKI is the one of the SW provider
+	pub := &kc.privs[0].PublicKey
+	hash := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	assert.Equal(t, hash[:], k.SKI())
+
+	digest := sha256.Sum256([]byte("message"))
+	sig, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	lowS, err := utils.IsLowS(pub, mustS(t, sig))
+	require.NoError(t, err)
+	assert.True(t, lowS)
+	valid, err := csp.Verify(k, sig, digest[:], nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	assert.False(t, pk.Private())
+	raw, err := pk.Bytes()
+	require.NoError(t, err)
+	imported, err := csp.KeyImport(raw, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	valid, err = csp.Verify(imported, sig, digest[:], nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = csp.Sign(k, nil, nil)
+	assert.EqualError(t, err, "Invalid digest. Cannot be empty")
+	_, err = csp.Sign(nil, digest[:], nil)
+	assert.EqualError(t, err, "Invalid Key. It must not be nil")
+
+	kc.err = errors.New("User interaction is not allowed.")
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.EqualError(t, err, "Failed signing with ECDSA key in the Keychain: User interaction is not allowed.")
+	_, err = csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	assert.EqualError(t, err, "Failed generating ECDSA key in the Keychain: User interaction is not allowed.")
+}
+
+func TestKeysOutsideTheKeychain(t *testing.T) {
+	kc := &memKeychain{}
+	csp := newTestCSP(t, kc)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAKeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{},
+		&bccsp.AES256KeyGenOpts{},
+	} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		assert.NotEqual(t, "*keychain.ecdsaPrivateKey", fmt.Sprintf("%T", k))
+		if !opts.Ephemeral() {
+			loaded, err := csp.GetKey(k.SKI())
+			require.NoError(t, err)
+			assert.Equal(t, k.SKI(), loaded.SKI())
+		}
+	}
+	assert.Empty(t, kc.privs)
+}
+
+func TestKeyManagement(t *testing.T) {
+	kc := &memKeychain{}
+	csp := newTestCSP(t, kc)
+	k1, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	k2, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+
+	// the keys are found in the Keychain by another instance
+	other := newTestCSP(t, kc)
+	loaded, err := other.GetKey(k1.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, k1.SKI(), loaded.SKI())
+	keys, err := other.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	status, err := other.(bccsp.StatusReporter).Status()
+	require.NoError(t, err)
+	assert.Equal(t, "KEYCHAIN", status.Provider)
+	assert.Equal(t, 2, status.Keys.Private)
+
+	require.NoError(t, other.(bccsp.KeyManager).DeleteKey(k2.SKI()))
+	_, err = other.GetKey(k2.SKI())
+	assert.Error(t, err)
+	err = other.(bccsp.KeyManager).DeleteKey(k2.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("key %x not found", k2.SKI()))
+	// the other instance drops the key from its cache once it is missing
+	_, err = csp.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	_, err = csp.GetKey(k2.SKI())
+	assert.Error(t, err)
+
+	kc.err = errors.New("The user name or passphrase you entered is not correct.")
+	_, err = newTestCSP(t, kc).GetKey(k1.SKI())
+	assert.EqualError(t, err, "Failed listing the keys of the Keychain: The user name or passphrase you entered is not correct.")
+	_, err = other.(bccsp.StatusReporter).Status()
+	assert.Error(t, err)
+}
+
+func mustS(t *testing.T, sig []byte) *big.Int {
+	_, s, err := utils.UnmarshalECDSASignature(sig)
+	require.NoError(t, err)
+	return s
+}
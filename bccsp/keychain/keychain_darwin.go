@@ -0,0 +1,330 @@
+// +build darwin,cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+// kcMessage returns the description of err, to be freed by the caller.
+static char *kcMessage(CFErrorRef err) {
+	char *msg = malloc(512);
+	CFStringRef desc = err ? CFErrorCopyDescription(err) : NULL;
+	if (!desc || !CFStringGetCString(desc, msg, 512, kCFStringEncodingUTF8)) {
+		strcpy(msg, "unknown Keychain error");
+	}
+	if (desc) CFRelease(desc);
+	if (err) CFRelease(err);
+	return msg;
+}
+
+// kcStatusMessage returns the description of status, to be freed by the
+// caller.
+static char *kcStatusMessage(OSStatus status) {
+	char *msg = malloc(512);
+	CFStringRef desc = SecCopyErrorMessageString(status, NULL);
+	if (!desc || !CFStringGetCString(desc, msg, 512, kCFStringEncodingUTF8)) {
+		snprintf(msg, 512, "Keychain error %d", (int)status);
+	}
+	if (desc) CFRelease(desc);
+	return msg;
+}
+
+// kcQuery returns a query of the private keys tagged with tag, restricted to
+// the key whose application label is label when it is not NULL.
+static CFMutableDictionaryRef kcQuery(const void *tag, int tagLen, const void *label, int labelLen, int enclave) {
+	CFMutableDictionaryRef query = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(query, kSecClass, kSecClassKey);
+	CFDictionarySetValue(query, kSecAttrKeyClass, kSecAttrKeyClassPrivate);
+	CFDictionarySetValue(query, kSecAttrKeyType, kSecAttrKeyTypeECSECPrimeRandom);
+	CFDataRef tagData = CFDataCreate(NULL, tag, tagLen);
+	CFDictionarySetValue(query, kSecAttrApplicationTag, tagData);
+	CFRelease(tagData);
+	if (label) {
+		CFDataRef labelData = CFDataCreate(NULL, label, labelLen);
+		CFDictionarySetValue(query, kSecAttrApplicationLabel, labelData);
+		CFRelease(labelData);
+	}
+	if (enclave) {
+		CFDictionarySetValue(query, kSecUseDataProtectionKeychain, kCFBooleanTrue);
+	}
+	return query;
+}
+
+// kcPublicKey copies the uncompressed point of the public key of key to pub,
+// which holds 65 bytes.
+static int kcPublicKey(SecKeyRef key, void *pub, char **msg) {
+	CFErrorRef err = NULL;
+	SecKeyRef public = SecKeyCopyPublicKey(key);
+	if (!public) {
+		*msg = kcMessage(NULL);
+		return -1;
+	}
+	CFDataRef raw = SecKeyCopyExternalRepresentation(public, &err);
+	CFRelease(public);
+	if (!raw) {
+		*msg = kcMessage(err);
+		return -1;
+	}
+	if (CFDataGetLength(raw) != 65) {
+		CFRelease(raw);
+		*msg = strdup("the Keychain returned a public key which is not a P-256 point");
+		return -1;
+	}
+	memcpy(pub, CFDataGetBytePtr(raw), 65);
+	CFRelease(raw);
+	return 0;
+}
+
+static int kcGenerate(const void *tag, int tagLen, const char *label, int enclave, void *pub, char **msg) {
+	CFErrorRef err = NULL;
+	int rv = -1;
+	int bits = 256;
+	CFMutableDictionaryRef attrs = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFMutableDictionaryRef private = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFNumberRef size = CFNumberCreate(NULL, kCFNumberIntType, &bits);
+	CFDataRef tagData = CFDataCreate(NULL, tag, tagLen);
+	CFStringRef labelString = CFStringCreateWithCString(NULL, label, kCFStringEncodingUTF8);
+	SecAccessControlRef access = NULL;
+	SecKeyRef key = NULL;
+
+	CFDictionarySetValue(attrs, kSecAttrKeyType, kSecAttrKeyTypeECSECPrimeRandom);
+	CFDictionarySetValue(attrs, kSecAttrKeySizeInBits, size);
+	CFDictionarySetValue(private, kSecAttrIsPermanent, kCFBooleanTrue);
+	CFDictionarySetValue(private, kSecAttrApplicationTag, tagData);
+	CFDictionarySetValue(private, kSecAttrLabel, labelString);
+	if (enclave) {
+		access = SecAccessControlCreateWithFlags(NULL, kSecAttrAccessibleWhenUnlockedThisDeviceOnly, kSecAccessControlPrivateKeyUsage, &err);
+		if (!access) {
+			*msg = kcMessage(err);
+			goto done;
+		}
+		CFDictionarySetValue(attrs, kSecAttrTokenID, kSecAttrTokenIDSecureEnclave);
+		CFDictionarySetValue(attrs, kSecUseDataProtectionKeychain, kCFBooleanTrue);
+		CFDictionarySetValue(private, kSecAttrAccessControl, access);
+	}
+	CFDictionarySetValue(attrs, kSecPrivateKeyAttrs, private);
+
+	key = SecKeyCreateRandomKey(attrs, &err);
+	if (!key) {
+		*msg = kcMessage(err);
+		goto done;
+	}
+	rv = kcPublicKey(key, pub, msg);
+
+done:
+	if (key) CFRelease(key);
+	if (access) CFRelease(access);
+	CFRelease(labelString);
+	CFRelease(tagData);
+	CFRelease(size);
+	CFRelease(private);
+	CFRelease(attrs);
+	return rv;
+}
+
+// kcList copies the public keys of the private keys tagged with tag to pubs,
+// 65 bytes each, to be freed by the caller.
+static int kcList(const void *tag, int tagLen, int enclave, void **pubs, int *count, char **msg) {
+	CFMutableDictionaryRef query = kcQuery(tag, tagLen, NULL, 0, enclave);
+	CFDictionarySetValue(query, kSecReturnRef, kCFBooleanTrue);
+	CFDictionarySetValue(query, kSecMatchLimit, kSecMatchLimitAll);
+	CFTypeRef result = NULL;
+	OSStatus status = SecItemCopyMatching(query, &result);
+	CFRelease(query);
+
+	*count = 0;
+	*pubs = NULL;
+	if (status == errSecItemNotFound) {
+		return 0;
+	}
+	if (status != errSecSuccess) {
+		*msg = kcStatusMessage(status);
+		return -1;
+	}
+	CFIndex n = CFArrayGetCount(result);
+	*pubs = malloc(65 * (n ? n : 1));
+	for (CFIndex i = 0; i < n; i++) {
+		SecKeyRef key = (SecKeyRef)CFArrayGetValueAtIndex(result, i);
+		if (kcPublicKey(key, (char *)*pubs + 65 * i, msg) != 0) {
+			CFRelease(result);
+			free(*pubs);
+			*pubs = NULL;
+			return -1;
+		}
+	}
+	*count = (int)n;
+	CFRelease(result);
+	return 0;
+}
+
+// kcSign signs the digest with the private key whose application label is
+// label, copying the DER encoded signature to sig, to be freed by the caller.
+static int kcSign(const void *tag, int tagLen, const void *label, int labelLen, int enclave, const void *digest, int digestLen, void **sig, int *sigLen, char **msg) {
+	CFMutableDictionaryRef query = kcQuery(tag, tagLen, label, labelLen, enclave);
+	CFDictionarySetValue(query, kSecReturnRef, kCFBooleanTrue);
+	CFTypeRef key = NULL;
+	OSStatus status = SecItemCopyMatching(query, &key);
+	CFRelease(query);
+	if (status != errSecSuccess) {
+		*msg = kcStatusMessage(status);
+		return -1;
+	}
+
+	CFErrorRef err = NULL;
+	CFDataRef data = CFDataCreate(NULL, digest, digestLen);
+	CFDataRef signature = SecKeyCreateSignature((SecKeyRef)key, kSecKeyAlgorithmECDSASignatureDigestX962, data, &err);
+	CFRelease(data);
+	CFRelease(key);
+	if (!signature) {
+		*msg = kcMessage(err);
+		return -1;
+	}
+	*sigLen = (int)CFDataGetLength(signature);
+	*sig = malloc(*sigLen);
+	memcpy(*sig, CFDataGetBytePtr(signature), *sigLen);
+	CFRelease(signature);
+	return 0;
+}
+
+static int kcDelete(const void *tag, int tagLen, const void *label, int labelLen, int enclave, char **msg) {
+	CFMutableDictionaryRef query = kcQuery(tag, tagLen, label, labelLen, enclave);
+	OSStatus status = SecItemDelete(query);
+	CFRelease(query);
+	if (status != errSecSuccess) {
+		*msg = kcStatusMessage(status);
+		return -1;
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// systemKeychain is the keychain of the user running the process, or the
+// Secure Enclave. Its keys are tagged with the label of the provider, and
+// identified by their application label, which the Keychain sets to the
+// SHA-1 hash of their public key.
+type systemKeychain struct {
+	label   string
+	enclave bool
+}
+
+func newSystemKeychain(opts KeychainOpts) (keychain, error) {
+	return &systemKeychain{label: opts.Label, enclave: opts.SecureEnclave}, nil
+}
+
+func (kc *systemKeychain) generate() (*ecdsa.PublicKey, error) {
+	tag := []byte(kc.label)
+	label := C.CString(kc.label)
+	defer C.free(unsafe.Pointer(label))
+
+	var point [65]byte
+	var msg *C.char
+	if C.kcGenerate(unsafe.Pointer(&tag[0]), C.int(len(tag)), label, cBool(kc.enclave), unsafe.Pointer(&point[0]), &msg) != 0 {
+		return nil, cError(msg)
+	}
+	return unmarshalPoint(point[:])
+}
+
+func (kc *systemKeychain) keys() ([]*ecdsa.PublicKey, error) {
+	tag := []byte(kc.label)
+	var pubs unsafe.Pointer
+	var count C.int
+	var msg *C.char
+	if C.kcList(unsafe.Pointer(&tag[0]), C.int(len(tag)), cBool(kc.enclave), &pubs, &count, &msg) != 0 {
+		return nil, cError(msg)
+	}
+	if pubs == nil {
+		return nil, nil
+	}
+	defer C.free(pubs)
+
+	raw := C.GoBytes(pubs, 65*count)
+	var keys []*ecdsa.PublicKey
+	for i := 0; i < int(count); i++ {
+		pub, err := unmarshalPoint(raw[65*i : 65*(i+1)])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pub)
+	}
+	return keys, nil
+}
+
+func (kc *systemKeychain) sign(pub *ecdsa.PublicKey, digest []byte) ([]byte, error) {
+	tag := []byte(kc.label)
+	label := applicationLabel(pub)
+	var sig unsafe.Pointer
+	var sigLen C.int
+	var msg *C.char
+	rv := C.kcSign(
+		unsafe.Pointer(&tag[0]), C.int(len(tag)),
+		unsafe.Pointer(&label[0]), C.int(len(label)),
+		cBool(kc.enclave),
+		unsafe.Pointer(&digest[0]), C.int(len(digest)),
+		&sig, &sigLen, &msg,
+	)
+	if rv != 0 {
+		return nil, cError(msg)
+	}
+	defer C.free(sig)
+	return C.GoBytes(sig, sigLen), nil
+}
+
+func (kc *systemKeychain) remove(pub *ecdsa.PublicKey) error {
+	tag := []byte(kc.label)
+	label := applicationLabel(pub)
+	var msg *C.char
+	if C.kcDelete(unsafe.Pointer(&tag[0]), C.int(len(tag)), unsafe.Pointer(&label[0]), C.int(len(label)), cBool(kc.enclave), &msg) != 0 {
+		return cError(msg)
+	}
+	return nil
+}
+
+// applicationLabel returns the application label the Keychain gives to the
+// private key of pub.
+func applicationLabel(pub *ecdsa.PublicKey) []byte {
+	hash := sha1.Sum(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	return hash[:]
+}
+
+func unmarshalPoint(point []byte) (*ecdsa.PublicKey, error) {
+	x, y := elliptic.Unmarshal(elliptic.P256(), point)
+	if x == nil {
+		return nil, errors.New("the Keychain returned an invalid P-256 public key")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+func cBool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// cError returns the error of the message allocated by the C helpers, which
+// it frees.
+func cError(msg *C.char) error {
+	defer C.free(unsafe.Pointer(msg))
+	return errors.New(C.GoString(msg))
+}
@@ -0,0 +1,15 @@
+// +build !darwin !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+import "github.com/pkg/errors"
+
+func newSystemKeychain(opts KeychainOpts) (keychain, error) {
+	return nil, errors.New("the Keychain is only available on macOS, in binaries built with cgo")
+}
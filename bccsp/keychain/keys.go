@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keychain
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/pkg/errors"
+)
+
+// ecdsaPrivateKey is a P-256 private key held by the Keychain, which never
+// releases it.
+type ecdsaPrivateKey struct {
+	pub ecdsaPublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *ecdsaPrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *ecdsaPrivateKey) SKI() []byte {
+	return k.pub.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *ecdsaPrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *ecdsaPrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
+	return &k.pub, nil
+}
+
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPrivateKey) Fingerprint() ([]byte, error) {
+	return k.pub.Fingerprint()
+}
+
+type ecdsaPublicKey struct {
+	ski []byte
+	pub *ecdsa.PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *ecdsaPublicKey) Bytes() ([]byte, error) {
+	raw, err := x509.MarshalPKIXPublicKey(k.pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed marshalling key")
+	}
+	return raw, nil
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *ecdsaPublicKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *ecdsaPublicKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *ecdsaPublicKey) Private() bool {
+	return false
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPublicKey) Fingerprint() ([]byte, error) {
+	return utils.SPKIFingerprint(k.pub)
+}
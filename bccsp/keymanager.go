@@ -0,0 +1,17 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+// KeyManager is implemented by the BCCSP providers and key stores that
+// enumerate and delete the keys they hold.
+type KeyManager interface {
+	// ListKeys returns the keys held by the key store.
+	ListKeys() ([]Key, error)
+
+	// DeleteKey deletes the keys whose SKI is the one passed.
+	DeleteKey(ski []byte) error
+}
@@ -0,0 +1,334 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tags of the KMIP items used by the provider.
+const (
+	tagAttribute                     uint32 = 0x420008
+	tagAttributeName                 uint32 = 0x42000A
+	tagAttributeValue                uint32 = 0x42000B
+	tagAuthentication                uint32 = 0x42000C
+	tagBatchCount                    uint32 = 0x42000D
+	tagBatchItem                     uint32 = 0x42000F
+	tagBlockCipherMode               uint32 = 0x420011
+	tagCommonTemplateAttribute       uint32 = 0x42001F
+	tagCredential                    uint32 = 0x420023
+	tagCredentialType                uint32 = 0x420024
+	tagCredentialValue               uint32 = 0x420025
+	tagCryptographicDomainParameters uint32 = 0x420027
+	tagCryptographicAlgorithm        uint32 = 0x420028
+	tagCryptographicLength           uint32 = 0x42002A
+	tagCryptographicParameters       uint32 = 0x42002B
+	tagCryptographicUsageMask        uint32 = 0x42002C
+	tagEncryptionKeyInformation      uint32 = 0x420036
+	tagIVCounterNonce                uint32 = 0x42003D
+	tagKeyBlock                      uint32 = 0x420040
+	tagKeyFormatType                 uint32 = 0x420042
+	tagKeyMaterial                   uint32 = 0x420043
+	tagKeyValue                      uint32 = 0x420045
+	tagKeyWrappingSpecification      uint32 = 0x420047
+	tagName                          uint32 = 0x420053
+	tagNameType                      uint32 = 0x420054
+	tagNameValue                     uint32 = 0x420055
+	tagObjectType                    uint32 = 0x420057
+	tagOperation                     uint32 = 0x42005C
+	tagPaddingMethod                 uint32 = 0x42005F
+	tagPrivateKey                    uint32 = 0x420064
+	tagPrivateKeyTemplateAttribute   uint32 = 0x420065
+	tagPrivateKeyUniqueIdentifier    uint32 = 0x420066
+	tagProtocolVersion               uint32 = 0x420069
+	tagProtocolVersionMajor          uint32 = 0x42006A
+	tagProtocolVersionMinor          uint32 = 0x42006B
+	tagPublicKey                     uint32 = 0x42006D
+	tagPublicKeyTemplateAttribute    uint32 = 0x42006E
+	tagPublicKeyUniqueIdentifier     uint32 = 0x42006F
+	tagRecommendedCurve              uint32 = 0x420075
+	tagRequestHeader                 uint32 = 0x420077
+	tagRequestMessage                uint32 = 0x420078
+	tagRequestPayload                uint32 = 0x420079
+	tagResponseHeader                uint32 = 0x42007A
+	tagResponseMessage               uint32 = 0x42007B
+	tagResponsePayload               uint32 = 0x42007C
+	tagResultMessage                 uint32 = 0x42007D
+	tagResultReason                  uint32 = 0x42007E
+	tagResultStatus                  uint32 = 0x42007F
+	tagRevocationReason              uint32 = 0x420081
+	tagRevocationReasonCode          uint32 = 0x420082
+	tagSymmetricKey                  uint32 = 0x42008F
+	tagTemplateAttribute             uint32 = 0x420091
+	tagUniqueIdentifier              uint32 = 0x420094
+	tagUsername                      uint32 = 0x420099
+	tagWrappingMethod                uint32 = 0x42009E
+	tagPassword                      uint32 = 0x4200A1
+	tagData                          uint32 = 0x4200C2
+	tagSignatureData                 uint32 = 0x4200C3
+	// KMIP 2.0 replaces the template attributes with attributes
+	tagAttributes           uint32 = 0x420125
+	tagCommonAttributes     uint32 = 0x420126
+	tagPrivateKeyAttributes uint32 = 0x420127
+	tagPublicKeyAttributes  uint32 = 0x420128
+)
+
+// Operations of the KMIP protocol used by the provider.
+const (
+	opCreate           int32 = 0x01
+	opCreateKeyPair    int32 = 0x02
+	opGet              int32 = 0x0A
+	opActivate         int32 = 0x12
+	opRevoke           int32 = 0x13
+	opDestroy          int32 = 0x14
+	opDiscoverVersions int32 = 0x1E
+	opEncrypt          int32 = 0x1F
+	opDecrypt          int32 = 0x20
+	opSign             int32 = 0x21
+)
+
+var operationNames = map[int32]string{
+	opCreate:           "Create",
+	opCreateKeyPair:    "Create Key Pair",
+	opGet:              "Get",
+	opActivate:         "Activate",
+	opRevoke:           "Revoke",
+	opDestroy:          "Destroy",
+	opDiscoverVersions: "Discover Versions",
+	opEncrypt:          "Encrypt",
+	opDecrypt:          "Decrypt",
+	opSign:             "Sign",
+}
+
+// Enumerations of the KMIP protocol used by the provider.
+const (
+	objectTypeSymmetricKey int32 = 0x02
+
+	algorithmAES   int32 = 0x03
+	algorithmECDSA int32 = 0x06
+
+	curveP256 int32 = 0x07
+	curveP384 int32 = 0x0A
+
+	usageSign    int32 = 0x01
+	usageVerify  int32 = 0x02
+	usageEncrypt int32 = 0x04
+	usageDecrypt int32 = 0x08
+	usageWrapKey int32 = 0x10
+
+	keyFormatX509 int32 = 0x05
+
+	blockCipherModeCBC         int32 = 0x01
+	blockCipherModeNISTKeyWrap int32 = 0x0D
+	paddingMethodPKCS5         int32 = 0x03
+	wrappingMethodEncrypt      int32 = 0x01
+
+	nameTypeText                int32 = 0x01
+	credentialUsernamePassword  int32 = 0x01
+	revocationReasonUnspecified int32 = 0x01
+
+	resultStatusSuccess int32 = 0x00
+)
+
+// Error is an operation which failed on the KMIP server.
+type Error struct {
+	Operation string
+	Reason    int32
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("KMIP %s failed: %s (reason %d)", e.Operation, e.Message, e.Reason)
+}
+
+// maxResponseLength bounds the length of the responses of the server
+const maxResponseLength = 1 << 20
+
+// client sends requests to a KMIP server over a mutually authenticated TLS
+// connection, which is reopened after a failure.
+type client struct {
+	address  string
+	tls      *tls.Config
+	major    int32
+	minor    int32
+	username string
+	password string
+	timeout  time.Duration
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// call sends a request for the operation with the items of its payload, and
+// returns the payload of the response.
+func (c *client) call(operation int32, payload ...*item) (*item, error) {
+	request, err := c.request(operation, payload...).encode()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	response, err := c.roundTrip(request)
+	if err != nil {
+		if c.conn != nil {
+			c.conn.Close()
+			c.conn = nil
+		}
+		return nil, errors.WithMessagef(err, "KMIP %s request to %s failed", operationNames[operation], c.address)
+	}
+	return c.payload(operation, response)
+}
+
+func (c *client) request(operation int32, payload ...*item) *item {
+	var authentication *item
+	if c.username != "" {
+		authentication = structure(tagAuthentication,
+			structure(tagCredential,
+				enumeration(tagCredentialType, credentialUsernamePassword),
+				structure(tagCredentialValue,
+					text(tagUsername, c.username),
+					text(tagPassword, c.password),
+				),
+			),
+		)
+	}
+	return structure(tagRequestMessage,
+		structure(tagRequestHeader,
+			structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, c.major),
+				integer(tagProtocolVersionMinor, c.minor),
+			),
+			authentication,
+			integer(tagBatchCount, 1),
+		),
+		structure(tagBatchItem,
+			enumeration(tagOperation, operation),
+			structure(tagRequestPayload, payload...),
+		),
+	)
+}
+
+func (c *client) roundTrip(request []byte) ([]byte, error) {
+	if c.conn == nil {
+		dialer := &net.Dialer{Timeout: c.timeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", c.address, c.tls)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := c.conn.Write(request); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length > maxResponseLength {
+		return nil, errors.Errorf("response of %d bytes exceeds the maximum of %d bytes", length, maxResponseLength)
+	}
+	response := make([]byte, 8+length)
+	copy(response, header)
+	if _, err := io.ReadFull(c.conn, response[8:]); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// payload returns the payload of a successful response, and an *Error for a
+// failed operation.
+func (c *client) payload(operation int32, raw []byte) (*item, error) {
+	name := operationNames[operation]
+	response, _, err := decode(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid KMIP %s response", name)
+	}
+	if response.Tag != tagResponseMessage {
+		return nil, errors.Errorf("invalid KMIP %s response: unexpected tag %06x", name, response.Tag)
+	}
+	batch := response.child(tagBatchItem)
+	if batch == nil {
+		return nil, errors.Errorf("invalid KMIP %s response: no batch item", name)
+	}
+	if status, ok := batch.int(tagResultStatus); !ok || status != resultStatusSuccess {
+		reason, _ := batch.int(tagResultReason)
+		return nil, &Error{Operation: name, Reason: reason, Message: batch.text(tagResultMessage)}
+	}
+	payload := batch.child(tagResponsePayload)
+	if payload == nil {
+		payload = structure(tagResponsePayload)
+	}
+	return payload, nil
+}
+
+// attributes returns the attributes of an object to create: the attribute
+// items of a template attribute up to KMIP 1.4, and the items of an
+// attributes structure since KMIP 2.0.
+func (c *client) attributes(tag14, tag20 uint32, attrs ...attribute) *item {
+	var items []*item
+	for _, a := range attrs {
+		if c.major >= 2 {
+			items = append(items, a.value)
+			continue
+		}
+		value := *a.value
+		value.Tag = tagAttributeValue
+		items = append(items, structure(tagAttribute, text(tagAttributeName, a.name), &value))
+	}
+	if c.major >= 2 {
+		return structure(tag20, items...)
+	}
+	return structure(tag14, items...)
+}
+
+// attribute is an attribute of an object, whose value is tagged with the tag
+// of the attribute.
+type attribute struct {
+	name  string
+	value *item
+}
+
+func nameAttribute(name string) attribute {
+	return attribute{"Name", structure(tagName,
+		text(tagNameValue, name),
+		enumeration(tagNameType, nameTypeText),
+	)}
+}
+
+func algorithmAttribute(algorithm int32) attribute {
+	return attribute{"Cryptographic Algorithm", enumeration(tagCryptographicAlgorithm, algorithm)}
+}
+
+func lengthAttribute(length int32) attribute {
+	return attribute{"Cryptographic Length", integer(tagCryptographicLength, length)}
+}
+
+func usageAttribute(mask int32) attribute {
+	return attribute{"Cryptographic Usage Mask", integer(tagCryptographicUsageMask, mask)}
+}
+
+func curveAttribute(curve int32) attribute {
+	return attribute{"Cryptographic Domain Parameters", structure(tagCryptographicDomainParameters,
+		enumeration(tagRecommendedCurve, curve),
+	)}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import "time"
+
+// KMIPOpts contains options for the KMIPFactory
+type KMIPOpts struct {
+	// Default algorithms when not specified
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Address is the host and port of the KMIP server, usually on port 5696.
+	Address string `mapstructure:"address" json:"address"`
+	// Version is the version of the KMIP protocol spoken to the server, 1.4
+	// or 2.0. It defaults to 1.4.
+	Version string `mapstructure:"version,omitempty" json:"version,omitempty"`
+	// ClientCert and ClientKey are the PEM files of the TLS certificate and
+	// key authenticating the provider to the server.
+	ClientCert string `mapstructure:"clientcert" json:"clientcert"`
+	ClientKey  string `mapstructure:"clientkey" json:"clientkey"`
+	// ServerCAs are the PEM files of the CA certificates of the server.
+	ServerCAs []string `mapstructure:"servercas" json:"servercas"`
+	// ServerName overrides the name of the server verified in its
+	// certificate, which defaults to the host of the address.
+	ServerName string `mapstructure:"servername,omitempty" json:"servername,omitempty"`
+	// Username and Password are the credentials sent in the requests, for
+	// servers authenticating their clients with credentials.
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty"`
+	// Timeout bounds the connection to the server and each request.
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Index is the file mapping the SKIs of the keys to their identifiers on
+	// the server.
+	Index string `mapstructure:"index" json:"index"`
+}
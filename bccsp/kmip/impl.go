@@ -0,0 +1,621 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_kmip")
+
+// New returns a BCCSP whose keys are held by a KMIP server, which generates
+// them and performs the signing, encryption and key wrapping operations with
+// them. Hashing, verification and the operations on the keys which are not
+// held by the server are performed in software.
+func New(opts KMIPOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	var curve elliptic.Curve
+	switch opts.SecLevel {
+	case 256:
+		curve = elliptic.P256()
+	case 384:
+		curve = elliptic.P384()
+	default:
+		return nil, errors.Errorf("Failed initializing configuration: Security level not supported [%d]", opts.SecLevel)
+	}
+
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	c, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := newIndex(opts.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	csp := &impl{BCCSP: swCSP, curve: curve, client: c, index: idx}
+	if err := csp.discoverVersion(); err != nil {
+		return nil, errors.WithMessagef(err, "Failed initializing KMIP server %s", opts.Address)
+	}
+	return csp, nil
+}
+
+func newClient(opts KMIPOpts) (*client, error) {
+	if opts.Address == "" {
+		return nil, errors.New("the address of the KMIP server is required")
+	}
+
+	c := &client{
+		address:  opts.Address,
+		username: opts.Username,
+		password: opts.Password,
+		timeout:  opts.Timeout,
+	}
+	switch opts.Version {
+	case "", "1.4":
+		c.major, c.minor = 1, 4
+	case "2.0":
+		c.major, c.minor = 2, 0
+	default:
+		return nil, errors.Errorf("unsupported KMIP version %s: must be 1.4 or 2.0", opts.Version)
+	}
+
+	if len(opts.ServerCAs) == 0 {
+		return nil, errors.New("the CA certificates of the KMIP server are required")
+	}
+	c.tls = &tls.Config{
+		RootCAs:    x509.NewCertPool(),
+		ServerName: opts.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	for _, path := range opts.ServerCAs {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading the CA certificate %s of the KMIP server", path)
+		}
+		if !c.tls.RootCAs.AppendCertsFromPEM(raw) {
+			return nil, errors.Errorf("no PEM encoded certificate found in %s", path)
+		}
+	}
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed loading the client certificate of the KMIP server")
+		}
+		c.tls.Certificates = []tls.Certificate{cert}
+	} else if opts.Username == "" {
+		return nil, errors.New("either a client certificate or a username is required to authenticate to the KMIP server")
+	}
+	return c, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	curve  elliptic.Curve
+	client *client
+	index  *index
+}
+
+// discoverVersion checks that the server speaks the version of the protocol
+// of the client.
+func (csp *impl) discoverVersion() error {
+	payload, err := csp.client.call(opDiscoverVersions, structure(tagProtocolVersion,
+		integer(tagProtocolVersionMajor, csp.client.major),
+		integer(tagProtocolVersionMinor, csp.client.minor),
+	))
+	if err != nil {
+		return err
+	}
+	for _, v := range payload.children(tagProtocolVersion) {
+		major, _ := v.int(tagProtocolVersionMajor)
+		minor, _ := v.int(tagProtocolVersionMinor)
+		if major == csp.client.major && minor == csp.client.minor {
+			return nil
+		}
+	}
+	return errors.Errorf("the server does not support KMIP %d.%d", csp.client.major, csp.client.minor)
+}
+
+// KeyGen generates a key using opts.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+	// Ephemeral keys are not worth a round trip to the server
+	if opts.Ephemeral() {
+		return csp.BCCSP.KeyGen(opts)
+	}
+
+	switch opts.(type) {
+	case *bccsp.ECDSAKeyGenOpts:
+		return csp.generateECKey(csp.curve)
+	case *bccsp.ECDSAP256KeyGenOpts:
+		return csp.generateECKey(elliptic.P256())
+	case *bccsp.ECDSAP384KeyGenOpts:
+		return csp.generateECKey(elliptic.P384())
+	case *bccsp.AESKeyGenOpts, *bccsp.AES256KeyGenOpts:
+		return csp.generateAESKey(256)
+	case *bccsp.AES192KeyGenOpts:
+		return csp.generateAESKey(192)
+	case *bccsp.AES128KeyGenOpts:
+		return csp.generateAESKey(128)
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+}
+
+func (csp *impl) generateECKey(curve elliptic.Curve) (bccsp.Key, error) {
+	kmipCurve := curveP256
+	if curve == elliptic.P384() {
+		kmipCurve = curveP384
+	}
+	name, err := newName()
+	if err != nil {
+		return nil, err
+	}
+
+	c := csp.client
+	payload, err := c.call(opCreateKeyPair,
+		c.attributes(tagCommonTemplateAttribute, tagCommonAttributes,
+			algorithmAttribute(algorithmECDSA),
+			lengthAttribute(int32(curve.Params().BitSize)),
+			curveAttribute(kmipCurve),
+		),
+		c.attributes(tagPrivateKeyTemplateAttribute, tagPrivateKeyAttributes,
+			nameAttribute(name),
+			usageAttribute(usageSign),
+		),
+		c.attributes(tagPublicKeyTemplateAttribute, tagPublicKeyAttributes,
+			nameAttribute(name+"-pub"),
+			usageAttribute(usageVerify),
+		),
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed generating ECDSA key")
+	}
+	private, public := payload.text(tagPrivateKeyUniqueIdentifier), payload.text(tagPublicKeyUniqueIdentifier)
+	if private == "" || public == "" {
+		return nil, errors.New("Failed generating ECDSA key: the server returned no identifiers")
+	}
+	for _, uid := range []string{private, public} {
+		if err := csp.activate(uid); err != nil {
+			return nil, errors.WithMessage(err, "Failed generating ECDSA key")
+		}
+	}
+
+	pub, err := csp.getPublicKey(public)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed generating ECDSA key")
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed marshalling public key")
+	}
+	// The SKI is computed as the SW provider does, to find the key of the
+	// certificates of the key
+	hash := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	ski := hash[:]
+
+	err = csp.index.put(&entry{SKI: ski, Type: keyTypeECDSA, Name: name, Private: private, Public: public, PublicKey: der})
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("Generated ECDSA key %x as %s on the KMIP server", ski, private)
+	return &ecdsaPrivateKey{ski, private, ecdsaPublicKey{ski, public, pub}}, nil
+}
+
+func (csp *impl) generateAESKey(bits int32) (bccsp.Key, error) {
+	name, err := newName()
+	if err != nil {
+		return nil, err
+	}
+
+	c := csp.client
+	payload, err := c.call(opCreate,
+		enumeration(tagObjectType, objectTypeSymmetricKey),
+		c.attributes(tagTemplateAttribute, tagAttributes,
+			algorithmAttribute(algorithmAES),
+			lengthAttribute(bits),
+			usageAttribute(usageEncrypt|usageDecrypt|usageWrapKey),
+			nameAttribute(name),
+		),
+	)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed generating AES %d key", bits)
+	}
+	uid := payload.text(tagUniqueIdentifier)
+	if uid == "" {
+		return nil, errors.Errorf("Failed generating AES %d key: the server returned no identifier", bits)
+	}
+	if err := csp.activate(uid); err != nil {
+		return nil, errors.WithMessagef(err, "Failed generating AES %d key", bits)
+	}
+
+	// The material of the key never leaves the server, so its SKI is random
+	ski := make([]byte, 32)
+	if _, err := rand.Read(ski); err != nil {
+		return nil, errors.Wrap(err, "Failed generating SKI")
+	}
+	if err := csp.index.put(&entry{SKI: ski, Type: keyTypeAES, Name: name, Private: uid}); err != nil {
+		return nil, err
+	}
+	logger.Debugf("Generated AES %d key %x as %s on the KMIP server", bits, ski, uid)
+	return &aesKey{ski, uid}, nil
+}
+
+func (csp *impl) activate(uid string) error {
+	_, err := csp.client.call(opActivate, text(tagUniqueIdentifier, uid))
+	return err
+}
+
+func (csp *impl) getPublicKey(uid string) (*ecdsa.PublicKey, error) {
+	payload, err := csp.client.call(opGet,
+		text(tagUniqueIdentifier, uid),
+		enumeration(tagKeyFormatType, keyFormatX509),
+	)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := keyValue(payload, tagPublicKey).(*item)
+	if !ok || len(value.bytes(tagKeyMaterial)) == 0 {
+		return nil, errors.Errorf("no public key returned for %s", uid)
+	}
+	material := value.bytes(tagKeyMaterial)
+	pub, err := x509.ParsePKIXPublicKey(material)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed parsing public key %s", uid)
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("public key %s is not an ECDSA key", uid)
+	}
+	return ecPub, nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	e, ok := csp.index.get(ski)
+	if !ok {
+		return csp.BCCSP.GetKey(ski)
+	}
+	return e.key()
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+//
+// Note that when a signature of a hash of a larger message is needed,
+// the caller is responsible for hashing the larger message and passing
+// the hash (as digest).
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.signECDSA(key, digest)
+	default:
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+}
+
+// signECDSA signs the digest on the server, which signs it as is since no
+// hashing algorithm is requested
+func (csp *impl) signECDSA(k *ecdsaPrivateKey, digest []byte) ([]byte, error) {
+	payload, err := csp.client.call(opSign,
+		text(tagUniqueIdentifier, k.uid),
+		structure(tagCryptographicParameters, enumeration(tagCryptographicAlgorithm, algorithmECDSA)),
+		byteString(tagData, digest),
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed signing with ECDSA key")
+	}
+	sig := payload.bytes(tagSignatureData)
+
+	// Servers return either DER encoded signatures or the concatenation of
+	// the R and S values
+	size := (k.pub.pub.Curve.Params().BitSize + 7) / 8
+	if len(sig) == 2*size {
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if sig, err = utils.MarshalECDSASignature(r, s); err != nil {
+			return nil, err
+		}
+	}
+	if _, _, err := utils.UnmarshalECDSASignature(sig); err != nil {
+		return nil, errors.WithMessage(err, "Invalid signature returned by the KMIP server")
+	}
+	return utils.SignatureToLowS(k.pub.pub, sig)
+}
+
+// Verify verifies signature against key k and digest
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+
+	// Verification only needs the public key, so it is done in software
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.verifyECDSA(&key.pub, signature, digest, opts)
+	case *ecdsaPublicKey:
+		return csp.verifyECDSA(key, signature, digest, opts)
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+}
+
+func (csp *impl) verifyECDSA(k *ecdsaPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pk, err := csp.BCCSP.KeyImport(k.pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return false, err
+	}
+	return csp.BCCSP.Verify(pk, signature, digest, opts)
+}
+
+// Encrypt encrypts plaintext using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	key, ok := k.(*aesKey)
+	if !ok {
+		return csp.BCCSP.Encrypt(k, plaintext, opts)
+	}
+
+	var o *bccsp.AESCBCPKCS7ModeOpts
+	switch opts := opts.(type) {
+	case *bccsp.AESCBCPKCS7ModeOpts:
+		o = opts
+	case bccsp.AESCBCPKCS7ModeOpts:
+		o = &opts
+	default:
+		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
+	}
+	if len(o.IV) != 0 && o.PRNG != nil {
+		return nil, errors.New("Invalid options. Either IV or PRNG should be different from nil, or both nil.")
+	}
+
+	iv := o.IV
+	if len(iv) == 0 {
+		prng := o.PRNG
+		if prng == nil {
+			prng = rand.Reader
+		}
+		iv = make([]byte, 16)
+		if _, err := io.ReadFull(prng, iv); err != nil {
+			return nil, errors.Wrap(err, "Failed generating IV")
+		}
+	}
+	if len(iv) != 16 {
+		return nil, errors.Errorf("Invalid IV. It must have length 16, was %d", len(iv))
+	}
+
+	payload, err := csp.client.call(opEncrypt,
+		text(tagUniqueIdentifier, key.uid),
+		cbcParameters(),
+		byteString(tagData, plaintext),
+		byteString(tagIVCounterNonce, iv),
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed encrypting with AES key")
+	}
+	// The IV prefixes the ciphertext, as with the SW provider
+	return append(append([]byte(nil), iv...), payload.bytes(tagData)...), nil
+}
+
+// Decrypt decrypts ciphertext using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	key, ok := k.(*aesKey)
+	if !ok {
+		return csp.BCCSP.Decrypt(k, ciphertext, opts)
+	}
+
+	switch opts.(type) {
+	case *bccsp.AESCBCPKCS7ModeOpts, bccsp.AESCBCPKCS7ModeOpts:
+	default:
+		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
+	}
+	if len(ciphertext) < 16 {
+		return nil, errors.New("Invalid ciphertext. It must be a multiple of the block size")
+	}
+
+	payload, err := csp.client.call(opDecrypt,
+		text(tagUniqueIdentifier, key.uid),
+		cbcParameters(),
+		byteString(tagData, ciphertext[16:]),
+		byteString(tagIVCounterNonce, ciphertext[:16]),
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed decrypting with AES key")
+	}
+	return payload.bytes(tagData), nil
+}
+
+// WrapKey returns the material of the key encrypted by the server with the
+// wrapping key, an AES key held by the server, with the NIST key wrap mode.
+func (csp *impl) WrapKey(k bccsp.Key, wrapping bccsp.Key) ([]byte, error) {
+	w, ok := wrapping.(*aesKey)
+	if !ok {
+		return nil, errors.New("the wrapping key must be an AES key held by the KMIP server")
+	}
+	var uid string
+	var objectTag uint32
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		uid, objectTag = key.uid, tagPrivateKey
+	case *aesKey:
+		uid, objectTag = key.uid, tagSymmetricKey
+	default:
+		return nil, errors.Errorf("key %x is not held by the KMIP server", k.SKI())
+	}
+
+	payload, err := csp.client.call(opGet,
+		text(tagUniqueIdentifier, uid),
+		structure(tagKeyWrappingSpecification,
+			enumeration(tagWrappingMethod, wrappingMethodEncrypt),
+			structure(tagEncryptionKeyInformation,
+				text(tagUniqueIdentifier, w.uid),
+				structure(tagCryptographicParameters,
+					enumeration(tagBlockCipherMode, blockCipherModeNISTKeyWrap),
+				),
+			),
+		),
+	)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed wrapping key %x", k.SKI())
+	}
+	// The value of a wrapped key is the byte string of its wrapped material
+	wrapped, ok := keyValue(payload, objectTag).([]byte)
+	if !ok || len(wrapped) == 0 {
+		return nil, errors.Errorf("Failed wrapping key %x: no wrapped key returned", k.SKI())
+	}
+	return wrapped, nil
+}
+
+// ListKeys returns the keys held by the server for this provider.
+func (csp *impl) ListKeys() ([]bccsp.Key, error) {
+	var keys []bccsp.Key
+	for _, e := range csp.index.list() {
+		k, err := e.key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// DeleteKey revokes and destroys the objects of the key on the server.
+func (csp *impl) DeleteKey(ski []byte) error {
+	e, ok := csp.index.get(ski)
+	if !ok {
+		return errors.Errorf("key %x not found", ski)
+	}
+	for _, uid := range []string{e.Private, e.Public} {
+		if uid == "" {
+			continue
+		}
+		// Active objects must be revoked before being destroyed
+		_, err := csp.client.call(opRevoke,
+			text(tagUniqueIdentifier, uid),
+			structure(tagRevocationReason, enumeration(tagRevocationReasonCode, revocationReasonUnspecified)),
+		)
+		if err != nil {
+			logger.Debugf("Failed revoking %s: %s", uid, err)
+		}
+		if _, err := csp.client.call(opDestroy, text(tagUniqueIdentifier, uid)); err != nil {
+			return errors.WithMessagef(err, "failed destroying key %x", ski)
+		}
+	}
+	return csp.index.remove(ski)
+}
+
+// Status returns the status of the provider, whose keys are counted from its
+// index. An error is returned when the server is unavailable.
+func (csp *impl) Status() (*bccsp.Status, error) {
+	status := &bccsp.Status{Provider: "KMIP"}
+	for _, e := range csp.index.list() {
+		switch e.Type {
+		case keyTypeECDSA:
+			status.Keys.Private++
+			status.Keys.Public++
+		case keyTypeAES:
+			status.Keys.Symmetric++
+		}
+	}
+	return status, csp.discoverVersion()
+}
+
+// key returns the key of the entry.
+func (e *entry) key() (bccsp.Key, error) {
+	switch e.Type {
+	case keyTypeECDSA:
+		pub, err := x509.ParsePKIXPublicKey(e.PublicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing the public key of key %x", e.SKI)
+		}
+		ecPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("the public key of key %x is not an ECDSA key", e.SKI)
+		}
+		return &ecdsaPrivateKey{e.SKI, e.Private, ecdsaPublicKey{e.SKI, e.Public, ecPub}}, nil
+	case keyTypeAES:
+		return &aesKey{e.SKI, e.Private}, nil
+	default:
+		return nil, errors.Errorf("unknown type %s of key %x", e.Type, e.SKI)
+	}
+}
+
+// keyValue returns the value of the key block of the object with the given
+// tag: a structure holding the key material, or the byte string of a wrapped
+// key.
+func keyValue(payload *item, objectTag uint32) interface{} {
+	object := payload.child(objectTag)
+	if object == nil {
+		return nil
+	}
+	block := object.child(tagKeyBlock)
+	if block == nil {
+		return nil
+	}
+	value := block.child(tagKeyValue)
+	if value == nil {
+		return nil
+	}
+	if value.Type == typeStructure {
+		return value
+	}
+	return value.Value
+}
+
+func cbcParameters() *item {
+	return structure(tagCryptographicParameters,
+		enumeration(tagBlockCipherMode, blockCipherModeCBC),
+		enumeration(tagPaddingMethod, paddingMethodPKCS5),
+	)
+}
+
+// newName returns a name for the objects of a new key on the server.
+func newName() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", errors.Wrap(err, "Failed generating key name")
+	}
+	return fmt.Sprintf("fabric-%x", raw), nil
+}
@@ -0,0 +1,735 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// Result reasons returned by the test server
+const (
+	reasonItemNotFound          int32 = 0x01
+	reasonAuthentication        int32 = 0x03
+	reasonInvalidField          int32 = 0x07
+	reasonIllegalOperation      int32 = 0x0B
+	reasonPermissionDenied      int32 = 0x0C
+	reasonOperationNotSupported int32 = 0x05
+)
+
+type object struct {
+	private *ecdsa.PrivateKey
+	public  *ecdsa.PublicKey
+	secret  []byte
+	active  bool
+}
+
+// server is a KMIP server holding its objects in memory, which implements the
+// operations used by the provider.
+type server struct {
+	dir      string
+	address  string
+	listener net.Listener
+	versions []string
+	// rawSignatures makes the server return the concatenation of the R and S
+	// values of the signatures instead of their DER encoding
+	rawSignatures bool
+
+	mutex      sync.Mutex
+	objects    map[string]*object
+	next       int
+	operations []int32
+	conns      []net.Conn
+}
+
+func newServer(t *testing.T, versions ...string) *server {
+	dir, err := ioutil.TempDir("", "kmip")
+	require.NoError(t, err)
+
+	// The certificate of the server authenticates the clients as well
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kmip"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600))
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cas := x509.NewCertPool()
+	cas.AppendCertsFromPEM(certPEM)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    cas,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	})
+	require.NoError(t, err)
+
+	s := &server{
+		dir:      dir,
+		address:  listener.Addr().String(),
+		listener: listener,
+		versions: versions,
+		objects:  map[string]*object{},
+	}
+	go s.serve()
+	return s
+}
+
+func (s *server) close() {
+	s.listener.Close()
+	s.closeConnections()
+	os.RemoveAll(s.dir)
+}
+
+func (s *server) closeConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+// opts returns the options of a provider authenticating with credentials.
+func (s *server) opts(version string) KMIPOpts {
+	return KMIPOpts{
+		SecLevel:   256,
+		HashFamily: "SHA2",
+		Address:    s.address,
+		Version:    version,
+		ServerCAs:  []string{filepath.Join(s.dir, "cert.pem")},
+		Username:   "fabric",
+		Password:   "secret",
+		Timeout:    5 * time.Second,
+		Index:      filepath.Join(s.dir, "kmip.json"),
+	}
+}
+
+func (s *server) requested(operation int32) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for _, op := range s.operations {
+		if op == operation {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		raw := make([]byte, 8+binary.BigEndian.Uint32(header[4:]))
+		copy(raw, header)
+		if _, err := io.ReadFull(conn, raw[8:]); err != nil {
+			return
+		}
+		request, _, err := decode(raw)
+		if err != nil {
+			return
+		}
+		response, err := s.handle(request).encode()
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) handle(request *item) *item {
+	header := request.child(tagRequestHeader)
+	version := header.child(tagProtocolVersion)
+	major, _ := version.int(tagProtocolVersionMajor)
+	minor, _ := version.int(tagProtocolVersionMinor)
+	batch := request.child(tagBatchItem)
+	operation, _ := batch.int(tagOperation)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.operations = append(s.operations, operation)
+
+	var payload *item
+	var reason int32
+	var message string
+	credential := header.child(tagAuthentication)
+	if credential != nil {
+		credential = credential.child(tagCredential).child(tagCredentialValue)
+	}
+	tlsAuthenticated := credential == nil
+	if !tlsAuthenticated && (credential.text(tagUsername) != "fabric" || credential.text(tagPassword) != "secret") {
+		reason, message = reasonAuthentication, "invalid credentials"
+	} else {
+		payload, reason, message = s.operation(operation, major, batch.child(tagRequestPayload))
+	}
+
+	result := []*item{enumeration(tagOperation, operation)}
+	if reason != 0 {
+		result = append(result,
+			enumeration(tagResultStatus, 1),
+			enumeration(tagResultReason, reason),
+			text(tagResultMessage, message),
+		)
+	} else {
+		result = append(result, enumeration(tagResultStatus, resultStatusSuccess), payload)
+	}
+	return structure(tagResponseMessage,
+		structure(tagResponseHeader,
+			structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, major),
+				integer(tagProtocolVersionMinor, minor),
+			),
+			integer(tagBatchCount, 1),
+		),
+		structure(tagBatchItem, result...),
+	)
+}
+
+func (s *server) operation(operation, major int32, request *item) (*item, int32, string) {
+	switch operation {
+	case opDiscoverVersions:
+		var versions []*item
+		for _, v := range s.versions {
+			var major, minor int32
+			fmt.Sscanf(v, "%d.%d", &major, &minor)
+			versions = append(versions, structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, major),
+				integer(tagProtocolVersionMinor, minor),
+			))
+		}
+		return structure(tagResponsePayload, versions...), 0, ""
+	case opCreateKeyPair:
+		return s.createKeyPair(major, request)
+	case opCreate:
+		return s.create(major, request)
+	}
+
+	uid := request.text(tagUniqueIdentifier)
+	obj, ok := s.objects[uid]
+	if !ok {
+		return nil, reasonItemNotFound, "no object " + uid
+	}
+	switch operation {
+	case opActivate:
+		obj.active = true
+	case opRevoke:
+		obj.active = false
+	case opDestroy:
+		if obj.active {
+			return nil, reasonPermissionDenied, "the object is active"
+		}
+		delete(s.objects, uid)
+	case opGet:
+		return s.get(uid, obj, request)
+	case opSign:
+		if obj.private == nil || !obj.active {
+			return nil, reasonIllegalOperation, "cannot sign with the object"
+		}
+		r, ss, err := ecdsa.Sign(rand.Reader, obj.private, request.bytes(tagData))
+		if err != nil {
+			return nil, reasonIllegalOperation, err.Error()
+		}
+		sig, _ := utils.MarshalECDSASignature(r, ss)
+		if s.rawSignatures {
+			size := (obj.private.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			rBytes, sBytes := r.Bytes(), ss.Bytes()
+			copy(sig[size-len(rBytes):size], rBytes)
+			copy(sig[2*size-len(sBytes):], sBytes)
+		}
+		return structure(tagResponsePayload, text(tagUniqueIdentifier, uid), byteString(tagSignatureData, sig)), 0, ""
+	case opEncrypt, opDecrypt:
+		if obj.secret == nil || !obj.active {
+			return nil, reasonIllegalOperation, "cannot encrypt with the object"
+		}
+		params := request.child(tagCryptographicParameters)
+		mode, _ := params.int(tagBlockCipherMode)
+		padding, _ := params.int(tagPaddingMethod)
+		if mode != blockCipherModeCBC || padding != paddingMethodPKCS5 {
+			return nil, reasonInvalidField, "unsupported cryptographic parameters"
+		}
+		data, err := cbc(operation == opEncrypt, obj.secret, request.bytes(tagIVCounterNonce), request.bytes(tagData))
+		if err != nil {
+			return nil, reasonIllegalOperation, err.Error()
+		}
+		return structure(tagResponsePayload, text(tagUniqueIdentifier, uid), byteString(tagData, data)), 0, ""
+	default:
+		return nil, reasonOperationNotSupported, "unsupported operation"
+	}
+	return structure(tagResponsePayload, text(tagUniqueIdentifier, uid)), 0, ""
+}
+
+// attributeValue returns the value of an attribute of a template attribute up to
+// KMIP 1.4, or of an attributes structure since KMIP 2.0.
+func attributeValue(attrs *item, major int32, name string, tag uint32) *item {
+	if major >= 2 {
+		return attrs.child(tag)
+	}
+	for _, a := range attrs.children(tagAttribute) {
+		if a.text(tagAttributeName) == name {
+			return a.child(tagAttributeValue)
+		}
+	}
+	return nil
+}
+
+func (s *server) createKeyPair(major int32, request *item) (*item, int32, string) {
+	common := request.child(tagCommonTemplateAttribute)
+	private := request.child(tagPrivateKeyTemplateAttribute)
+	if major >= 2 {
+		common, private = request.child(tagCommonAttributes), request.child(tagPrivateKeyAttributes)
+	}
+	if common == nil || private == nil {
+		return nil, reasonInvalidField, "missing attributes"
+	}
+	algorithm := attributeValue(common, major, "Cryptographic Algorithm", tagCryptographicAlgorithm)
+	params := attributeValue(common, major, "Cryptographic Domain Parameters", tagCryptographicDomainParameters)
+	name := attributeValue(private, major, "Name", tagName)
+	if algorithm == nil || algorithm.Value != algorithmECDSA || params == nil || name == nil || name.text(tagNameValue) == "" {
+		return nil, reasonInvalidField, "invalid attributes"
+	}
+	var curve elliptic.Curve
+	switch c, _ := params.int(tagRecommendedCurve); c {
+	case curveP256:
+		curve = elliptic.P256()
+	case curveP384:
+		curve = elliptic.P384()
+	default:
+		return nil, reasonInvalidField, "unsupported curve"
+	}
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, reasonIllegalOperation, err.Error()
+	}
+	privateUID, publicUID := s.newUID(), s.newUID()
+	s.objects[privateUID] = &object{private: key}
+	s.objects[publicUID] = &object{public: &key.PublicKey}
+	return structure(tagResponsePayload,
+		text(tagPrivateKeyUniqueIdentifier, privateUID),
+		text(tagPublicKeyUniqueIdentifier, publicUID),
+	), 0, ""
+}
+
+func (s *server) create(major int32, request *item) (*item, int32, string) {
+	attrs := request.child(tagTemplateAttribute)
+	if major >= 2 {
+		attrs = request.child(tagAttributes)
+	}
+	if objectType, _ := request.int(tagObjectType); objectType != objectTypeSymmetricKey || attrs == nil {
+		return nil, reasonInvalidField, "invalid request"
+	}
+	length := attributeValue(attrs, major, "Cryptographic Length", tagCryptographicLength)
+	if length == nil {
+		return nil, reasonInvalidField, "missing length"
+	}
+	secret := make([]byte, length.Value.(int32)/8)
+	rand.Read(secret)
+	uid := s.newUID()
+	s.objects[uid] = &object{secret: secret}
+	return structure(tagResponsePayload,
+		enumeration(tagObjectType, objectTypeSymmetricKey),
+		text(tagUniqueIdentifier, uid),
+	), 0, ""
+}
+
+// get returns the public keys, and the other keys wrapped with AES-GCM, which
+// is good enough to check that the right wrapping key is used.
+func (s *server) get(uid string, obj *object, request *item) (*item, int32, string) {
+	spec := request.child(tagKeyWrappingSpecification)
+	if spec == nil {
+		if format, _ := request.int(tagKeyFormatType); obj.public == nil || format != keyFormatX509 {
+			return nil, reasonPermissionDenied, "the key cannot be exported"
+		}
+		der, _ := x509.MarshalPKIXPublicKey(obj.public)
+		return structure(tagResponsePayload,
+			text(tagUniqueIdentifier, uid),
+			structure(tagPublicKey, structure(tagKeyBlock,
+				enumeration(tagKeyFormatType, keyFormatX509),
+				structure(tagKeyValue, byteString(tagKeyMaterial, der)),
+			)),
+		), 0, ""
+	}
+
+	wrapping, ok := s.objects[spec.child(tagEncryptionKeyInformation).text(tagUniqueIdentifier)]
+	if !ok || wrapping.secret == nil {
+		return nil, reasonItemNotFound, "no wrapping key"
+	}
+	objectTag, material := tagSymmetricKey, obj.secret
+	if obj.private != nil {
+		objectTag, material = tagPrivateKey, obj.private.D.Bytes()
+	}
+	wrapped := seal(wrapping.secret, material)
+	return structure(tagResponsePayload,
+		text(tagUniqueIdentifier, uid),
+		structure(objectTag, structure(tagKeyBlock, byteString(tagKeyValue, wrapped))),
+	), 0, ""
+}
+
+func (s *server) newUID() string {
+	s.next++
+	return fmt.Sprintf("%d", s.next)
+}
+
+func seal(secret, material []byte) []byte {
+	block, _ := aes.NewCipher(secret)
+	gcm, _ := cipher.NewGCM(block)
+	return gcm.Seal(nil, make([]byte, gcm.NonceSize()), material, nil)
+}
+
+func cbc(encrypt bool, secret, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if encrypt {
+		padding := aes.BlockSize - len(data)%aes.BlockSize
+		data = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+		out := make([]byte, len(data))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+		return out, nil
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid ciphertext")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	return out[:len(out)-int(out[len(out)-1])], nil
+}
+
+func TestNew(t *testing.T) {
+	s := newServer(t, "1.4")
+	defer s.close()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(s.dir, "invalid.pem"), []byte("invalid"), 0600))
+
+	tests := []struct {
+		name   string
+		modify func(*KMIPOpts)
+		err    string
+	}{
+		{"security level", func(o *KMIPOpts) { o.SecLevel = 0 }, "Failed initializing configuration: Security level not supported [0]"},
+		{"hash family", func(o *KMIPOpts) { o.HashFamily = "SHA8" }, "Failed initializing fallback SW BCCSP: Failed initializing configuration at [256,SHA8]: Hash Family not supported [SHA8]"},
+		{"address", func(o *KMIPOpts) { o.Address = "" }, "the address of the KMIP server is required"},
+		{"version", func(o *KMIPOpts) { o.Version = "3.0" }, "unsupported KMIP version 3.0: must be 1.4 or 2.0"},
+		{"no CAs", func(o *KMIPOpts) { o.ServerCAs = nil }, "the CA certificates of the KMIP server are required"},
+		{"missing CA", func(o *KMIPOpts) { o.ServerCAs = []string{filepath.Join(s.dir, "missing.pem")} }, "failed reading the CA certificate"},
+		{"invalid CA", func(o *KMIPOpts) { o.ServerCAs = []string{filepath.Join(s.dir, "invalid.pem")} }, "no PEM encoded certificate found in"},
+		{"client certificate", func(o *KMIPOpts) { o.ClientCert = filepath.Join(s.dir, "invalid.pem") }, "failed loading the client certificate of the KMIP server"},
+		{"no credentials", func(o *KMIPOpts) { o.Username = "" }, "either a client certificate or a username is required to authenticate to the KMIP server"},
+		{"index", func(o *KMIPOpts) { o.Index = "" }, "the path of the KMIP key index is required"},
+		{"invalid index", func(o *KMIPOpts) { o.Index = filepath.Join(s.dir, "invalid.pem") }, "failed parsing the KMIP key index"},
+		{"version not supported", func(o *KMIPOpts) { o.Version = "2.0" }, "Failed initializing KMIP server " + s.address + ": the server does not support KMIP 2.0"},
+		{"credentials", func(o *KMIPOpts) { o.Password = "guess" }, "Failed initializing KMIP server " + s.address + ": KMIP Discover Versions failed: invalid credentials (reason 3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := s.opts("")
+			tt.modify(&opts)
+			_, err := New(opts, sw.NewDummyKeyStore())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := New(s.opts(""), nil)
+	require.EqualError(t, err, "Invalid bccsp.KeyStore instance. It must be different from nil")
+
+	// Authentication with the client certificate
+	opts := s.opts("1.4")
+	opts.Username, opts.Password = "", ""
+	opts.ClientCert, opts.ClientKey = filepath.Join(s.dir, "cert.pem"), filepath.Join(s.dir, "key.pem")
+	_, err = New(opts, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+}
+
+func TestECDSA(t *testing.T) {
+	for _, version := range []string{"1.4", "2.0"} {
+		for _, raw := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s raw=%t", version, raw), func(t *testing.T) {
+				s := newServer(t, "1.4", "2.0")
+				defer s.close()
+				s.rawSignatures = raw
+
+				csp, err := New(s.opts(version), sw.NewDummyKeyStore())
+				require.NoError(t, err)
+
+				for _, opts := range []bccsp.KeyGenOpts{&bccsp.ECDSAKeyGenOpts{}, &bccsp.ECDSAP384KeyGenOpts{}} {
+					k, err := csp.KeyGen(opts)
+					require.NoError(t, err)
+					require.True(t, k.Private())
+					require.False(t, k.Symmetric())
+					_, err = k.Bytes()
+					require.Error(t, err)
+
+					digest := sha256.Sum256([]byte("hello"))
+					sig, err := csp.Sign(k, digest[:], nil)
+					require.NoError(t, err)
+					valid, err := csp.Verify(k, sig, digest[:], nil)
+					require.NoError(t, err)
+					require.True(t, valid)
+
+					pk, err := k.PublicKey()
+					require.NoError(t, err)
+					valid, err = csp.Verify(pk, sig, digest[:], nil)
+					require.NoError(t, err)
+					require.True(t, valid)
+
+					// The SKI is the one the SW provider computes from the public key
+					raw, err := pk.Bytes()
+					require.NoError(t, err)
+					imported, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+					require.NoError(t, err)
+					swKey, err := imported.KeyImport(raw, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+					require.NoError(t, err)
+					require.Equal(t, swKey.SKI(), k.SKI())
+
+					// The key is found through the index by another instance
+					other, err := New(s.opts(version), sw.NewDummyKeyStore())
+					require.NoError(t, err)
+					found, err := other.GetKey(k.SKI())
+					require.NoError(t, err)
+					sig, err = other.Sign(found, digest[:], nil)
+					require.NoError(t, err)
+					valid, err = other.Verify(pk, sig, digest[:], nil)
+					require.NoError(t, err)
+					require.True(t, valid)
+				}
+			})
+		}
+	}
+}
+
+func TestAES(t *testing.T) {
+	for _, version := range []string{"1.4", "2.0"} {
+		t.Run(version, func(t *testing.T) {
+			s := newServer(t, version)
+			defer s.close()
+			csp, err := New(s.opts(version), sw.NewDummyKeyStore())
+			require.NoError(t, err)
+
+			for _, opts := range []bccsp.KeyGenOpts{&bccsp.AES128KeyGenOpts{}, &bccsp.AES192KeyGenOpts{}, &bccsp.AESKeyGenOpts{}} {
+				k, err := csp.KeyGen(opts)
+				require.NoError(t, err)
+				require.True(t, k.Symmetric())
+				_, err = k.PublicKey()
+				require.Error(t, err)
+
+				ciphertext, err := csp.Encrypt(k, []byte("hello world"), &bccsp.AESCBCPKCS7ModeOpts{})
+				require.NoError(t, err)
+				plaintext, err := csp.Decrypt(k, ciphertext, bccsp.AESCBCPKCS7ModeOpts{})
+				require.NoError(t, err)
+				require.Equal(t, []byte("hello world"), plaintext)
+			}
+
+			k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+			require.NoError(t, err)
+			iv := bytes.Repeat([]byte{1}, 16)
+			ciphertext, err := csp.Encrypt(k, []byte("hello"), bccsp.AESCBCPKCS7ModeOpts{IV: iv})
+			require.NoError(t, err)
+			require.Equal(t, iv, ciphertext[:16])
+
+			_, err = csp.Encrypt(k, []byte("hello"), &bccsp.AESCBCPKCS7ModeOpts{IV: iv, PRNG: rand.Reader})
+			require.EqualError(t, err, "Invalid options. Either IV or PRNG should be different from nil, or both nil.")
+			_, err = csp.Encrypt(k, []byte("hello"), &bccsp.AESCBCPKCS7ModeOpts{IV: iv[:8]})
+			require.EqualError(t, err, "Invalid IV. It must have length 16, was 8")
+			_, err = csp.Encrypt(k, []byte("hello"), nil)
+			require.EqualError(t, err, "Mode not recognized [%!s(<nil>)]")
+			_, err = csp.Decrypt(k, ciphertext[:8], &bccsp.AESCBCPKCS7ModeOpts{})
+			require.EqualError(t, err, "Invalid ciphertext. It must be a multiple of the block size")
+			_, err = csp.Decrypt(k, ciphertext[:16], &bccsp.AESCBCPKCS7ModeOpts{})
+			require.EqualError(t, err, "Failed decrypting with AES key: KMIP Decrypt failed: invalid ciphertext (reason 11)")
+		})
+	}
+}
+
+func TestWrapKey(t *testing.T) {
+	s := newServer(t, "1.4")
+	defer s.close()
+	csp, err := New(s.opts(""), sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	wrapper := csp.(bccsp.KeyWrapper)
+
+	wrapping, err := csp.KeyGen(&bccsp.AESKeyGenOpts{})
+	require.NoError(t, err)
+	for _, opts := range []bccsp.KeyGenOpts{&bccsp.ECDSAKeyGenOpts{}, &bccsp.AES128KeyGenOpts{}} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		wrapped, err := wrapper.WrapKey(k, wrapping)
+		require.NoError(t, err)
+
+		s.mutex.Lock()
+		wrappingKey := s.objects[wrapping.(*aesKey).uid].secret
+		s.mutex.Unlock()
+		block, err := aes.NewCipher(wrappingKey)
+		require.NoError(t, err)
+		gcm, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+		_, err = gcm.Open(nil, make([]byte, gcm.NonceSize()), wrapped, nil)
+		require.NoError(t, err)
+	}
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	_, err = wrapper.WrapKey(wrapping, ecKey)
+	require.EqualError(t, err, "the wrapping key must be an AES key held by the KMIP server")
+	swKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = wrapper.WrapKey(swKey, wrapping)
+	require.EqualError(t, err, fmt.Sprintf("key %x is not held by the KMIP server", swKey.SKI()))
+}
+
+func TestListAndDeleteKeys(t *testing.T) {
+	s := newServer(t, "2.0")
+	defer s.close()
+	csp, err := New(s.opts("2.0"), sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	aesKey, err := csp.KeyGen(&bccsp.AESKeyGenOpts{})
+	require.NoError(t, err)
+
+	keys, err := csp.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	status, err := csp.(bccsp.StatusReporter).Status()
+	require.NoError(t, err)
+	require.Equal(t, "KMIP", status.Provider)
+	require.Equal(t, 1, status.Keys.Private)
+	require.Equal(t, 1, status.Keys.Public)
+	require.Equal(t, 1, status.Keys.Symmetric)
+
+	require.NoError(t, csp.(bccsp.KeyManager).DeleteKey(ecKey.SKI()))
+	require.NoError(t, csp.(bccsp.KeyManager).DeleteKey(aesKey.SKI()))
+	require.Equal(t, 3, s.requested(opDestroy))
+	s.mutex.Lock()
+	require.Empty(t, s.objects)
+	s.mutex.Unlock()
+	keys, err = csp.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	err = csp.(bccsp.KeyManager).DeleteKey(ecKey.SKI())
+	require.EqualError(t, err, fmt.Sprintf("key %x not found", ecKey.SKI()))
+	_, err = csp.GetKey(ecKey.SKI())
+	require.Error(t, err)
+}
+
+func TestSoftwareFallback(t *testing.T) {
+	s := newServer(t, "1.4")
+	defer s.close()
+	csp, err := New(s.opts(""), sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest, err := csp.Hash([]byte("hello"), &bccsp.SHAOpts{})
+	require.NoError(t, err)
+	sig, err := csp.Sign(k, digest, nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, sig, digest, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Zero(t, s.requested(opCreateKeyPair))
+	require.Zero(t, s.requested(opSign))
+
+	_, err = csp.Sign(nil, digest, nil)
+	require.EqualError(t, err, "Invalid Key. It must not be nil")
+	_, err = csp.Sign(k, nil, nil)
+	require.EqualError(t, err, "Invalid digest. Cannot be empty")
+	_, err = csp.KeyGen(nil)
+	require.EqualError(t, err, "Invalid Opts parameter. It must not be nil")
+}
+
+func TestServerFailures(t *testing.T) {
+	s := newServer(t, "1.4")
+	defer s.close()
+	csp, err := New(s.opts(""), sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+
+	// The objects of the key are gone from the server
+	s.mutex.Lock()
+	s.objects = map[string]*object{}
+	s.mutex.Unlock()
+	_, err = csp.Sign(k, []byte("digest"), nil)
+	require.EqualError(t, err, "Failed signing with ECDSA key: KMIP Sign failed: no object "+k.(*ecdsaPrivateKey).uid+" (reason 1)")
+
+	// The connection is reopened after a failure
+	s.closeConnections()
+	csp.(bccsp.StatusReporter).Status()
+	_, err = csp.(bccsp.StatusReporter).Status()
+	require.NoError(t, err)
+
+	s.listener.Close()
+	s.closeConnections()
+	csp.(bccsp.StatusReporter).Status()
+	_, err = csp.(bccsp.StatusReporter).Status()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "KMIP Discover Versions request to "+s.address+" failed")
+}
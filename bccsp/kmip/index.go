@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Types of the keys of the index.
+const (
+	keyTypeECDSA = "ecdsa"
+	keyTypeAES   = "aes"
+)
+
+// entry maps the SKI of a key to the identifiers of its objects on the
+// server.
+type entry struct {
+	SKI  []byte `json:"ski"`
+	Type string `json:"type"`
+	// Name is the name of the objects on the server.
+	Name string `json:"name"`
+	// Private identifies the private or the symmetric key.
+	Private string `json:"private"`
+	// Public identifies the public key of a key pair.
+	Public string `json:"public,omitempty"`
+	// PublicKey is the DER encoded public key of a key pair.
+	PublicKey []byte `json:"publicKey,omitempty"`
+}
+
+// index maps the SKIs of the keys to their objects on the server, and is
+// persisted in a file.
+type index struct {
+	path    string
+	mutex   sync.RWMutex
+	entries map[string]*entry
+}
+
+func newIndex(path string) (*index, error) {
+	if path == "" {
+		return nil, errors.New("the path of the KMIP key index is required")
+	}
+	i := &index{path: path, entries: map[string]*entry{}}
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading the KMIP key index %s", path)
+	}
+	var entries []*entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing the KMIP key index %s", path)
+	}
+	for _, e := range entries {
+		i.entries[hex.EncodeToString(e.SKI)] = e
+	}
+	return i, nil
+}
+
+func (i *index) get(ski []byte) (*entry, bool) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	e, ok := i.entries[hex.EncodeToString(ski)]
+	return e, ok
+}
+
+func (i *index) list() []*entry {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.sorted()
+}
+
+// sorted returns the entries sorted by SKI; it must be called with the mutex
+// held
+func (i *index) sorted() []*entry {
+	entries := make([]*entry, 0, len(i.entries))
+	for _, e := range i.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return hex.EncodeToString(entries[a].SKI) < hex.EncodeToString(entries[b].SKI)
+	})
+	return entries
+}
+
+func (i *index) put(e *entry) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	id := hex.EncodeToString(e.SKI)
+	previous, existed := i.entries[id]
+	i.entries[id] = e
+	if err := i.save(); err != nil {
+		if existed {
+			i.entries[id] = previous
+		} else {
+			delete(i.entries, id)
+		}
+		return err
+	}
+	return nil
+}
+
+func (i *index) remove(ski []byte) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	id := hex.EncodeToString(ski)
+	previous, ok := i.entries[id]
+	if !ok {
+		return nil
+	}
+	delete(i.entries, id)
+	if err := i.save(); err != nil {
+		i.entries[id] = previous
+		return err
+	}
+	return nil
+}
+
+// save persists the entries; it must be called with the mutex held
+func (i *index) save() error {
+	raw, err := json.MarshalIndent(i.sorted(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling the KMIP key index")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0700); err != nil {
+		return errors.Wrapf(err, "failed writing the KMIP key index %s", i.path)
+	}
+	tmp := i.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing the KMIP key index %s", i.path)
+	}
+	return errors.Wrapf(os.Rename(tmp, i.path), "failed writing the KMIP key index %s", i.path)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Types of the TTLV items.
+const (
+	typeStructure   byte = 0x01
+	typeInteger     byte = 0x02
+	typeLongInteger byte = 0x03
+	typeBigInteger  byte = 0x04
+	typeEnumeration byte = 0x05
+	typeBoolean     byte = 0x06
+	typeTextString  byte = 0x07
+	typeByteString  byte = 0x08
+	typeDateTime    byte = 0x09
+	typeInterval    byte = 0x0A
+)
+
+// item is a TTLV (tag, type, length, value) item of a KMIP message. The value
+// of a structure is its items, the value of an integer, an enumeration or an
+// interval is an int32, of a long integer or a date time an int64, of a
+// boolean a bool, of a text string a string, and of a byte string or a big
+// integer a []byte.
+type item struct {
+	Tag   uint32
+	Type  byte
+	Value interface{}
+}
+
+func structure(tag uint32, items ...*item) *item {
+	var children []*item
+	for _, i := range items {
+		if i != nil {
+			children = append(children, i)
+		}
+	}
+	return &item{Tag: tag, Type: typeStructure, Value: children}
+}
+
+func integer(tag uint32, v int32) *item {
+	return &item{Tag: tag, Type: typeInteger, Value: v}
+}
+
+func enumeration(tag uint32, v int32) *item {
+	return &item{Tag: tag, Type: typeEnumeration, Value: v}
+}
+
+func text(tag uint32, v string) *item {
+	return &item{Tag: tag, Type: typeTextString, Value: v}
+}
+
+func byteString(tag uint32, v []byte) *item {
+	return &item{Tag: tag, Type: typeByteString, Value: v}
+}
+
+// items returns the items of a structure.
+func (i *item) items() []*item {
+	children, _ := i.Value.([]*item)
+	return children
+}
+
+// child returns the first item of a structure with the given tag, or nil.
+func (i *item) child(tag uint32) *item {
+	for _, c := range i.items() {
+		if c.Tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// children returns the items of a structure with the given tag.
+func (i *item) children(tag uint32) []*item {
+	var found []*item
+	for _, c := range i.items() {
+		if c.Tag == tag {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// text returns the text string of the child with the given tag.
+func (i *item) text(tag uint32) string {
+	if c := i.child(tag); c != nil {
+		s, _ := c.Value.(string)
+		return s
+	}
+	return ""
+}
+
+// bytes returns the byte string of the child with the given tag.
+func (i *item) bytes(tag uint32) []byte {
+	if c := i.child(tag); c != nil {
+		b, _ := c.Value.([]byte)
+		return b
+	}
+	return nil
+}
+
+// int returns the integer or enumeration of the child with the given tag.
+func (i *item) int(tag uint32) (int32, bool) {
+	if c := i.child(tag); c != nil {
+		v, ok := c.Value.(int32)
+		return v, ok
+	}
+	return 0, false
+}
+
+// encode returns the TTLV encoding of the item.
+func (i *item) encode() ([]byte, error) {
+	var value []byte
+	switch i.Type {
+	case typeStructure:
+		for _, c := range i.items() {
+			raw, err := c.encode()
+			if err != nil {
+				return nil, err
+			}
+			value = append(value, raw...)
+		}
+	case typeInteger, typeEnumeration, typeInterval:
+		v, ok := i.Value.(int32)
+		if !ok {
+			return nil, errors.Errorf("invalid value of item %06x: expected int32, got %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(v))
+	case typeLongInteger, typeDateTime:
+		v, ok := i.Value.(int64)
+		if !ok {
+			return nil, errors.Errorf("invalid value of item %06x: expected int64, got %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(v))
+	case typeBoolean:
+		v, ok := i.Value.(bool)
+		if !ok {
+			return nil, errors.Errorf("invalid value of item %06x: expected bool, got %T", i.Tag, i.Value)
+		}
+		value = make([]byte, 8)
+		if v {
+			value[7] = 1
+		}
+	case typeTextString:
+		v, ok := i.Value.(string)
+		if !ok {
+			return nil, errors.Errorf("invalid value of item %06x: expected string, got %T", i.Tag, i.Value)
+		}
+		value = []byte(v)
+	case typeByteString, typeBigInteger:
+		v, ok := i.Value.([]byte)
+		if !ok {
+			return nil, errors.Errorf("invalid value of item %06x: expected []byte, got %T", i.Tag, i.Value)
+		}
+		value = v
+	default:
+		return nil, errors.Errorf("invalid type %02x of item %06x", i.Type, i.Tag)
+	}
+
+	raw := make([]byte, 8, 8+padded(len(value)))
+	raw[0], raw[1], raw[2] = byte(i.Tag>>16), byte(i.Tag>>8), byte(i.Tag)
+	raw[3] = i.Type
+	binary.BigEndian.PutUint32(raw[4:], uint32(len(value)))
+	raw = append(raw, value...)
+	return append(raw, make([]byte, padded(len(value))-len(value))...), nil
+}
+
+// decode decodes the TTLV item at the start of raw, and returns it along with
+// the bytes following it.
+func decode(raw []byte) (*item, []byte, error) {
+	if len(raw) < 8 {
+		return nil, nil, errors.New("invalid TTLV item: truncated header")
+	}
+	i := &item{
+		Tag:  uint32(raw[0])<<16 | uint32(raw[1])<<8 | uint32(raw[2]),
+		Type: raw[3],
+	}
+	length := int(binary.BigEndian.Uint32(raw[4:8]))
+	raw = raw[8:]
+	if length > len(raw) {
+		return nil, nil, errors.Errorf("invalid TTLV item %06x: length %d exceeds the %d bytes left", i.Tag, length, len(raw))
+	}
+	value := raw[:length]
+	rest := raw[length:]
+	if i.Type != typeStructure {
+		if padded(length) > len(raw) {
+			return nil, nil, errors.Errorf("invalid TTLV item %06x: truncated padding", i.Tag)
+		}
+		rest = raw[padded(length):]
+	}
+
+	switch i.Type {
+	case typeStructure:
+		var children []*item
+		for len(value) > 0 {
+			c, r, err := decode(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, c)
+			value = r
+		}
+		i.Value = children
+	case typeInteger, typeEnumeration, typeInterval:
+		if length != 4 {
+			return nil, nil, errors.Errorf("invalid TTLV item %06x: length %d instead of 4", i.Tag, length)
+		}
+		i.Value = int32(binary.BigEndian.Uint32(value))
+	case typeLongInteger, typeDateTime:
+		if length != 8 {
+			return nil, nil, errors.Errorf("invalid TTLV item %06x: length %d instead of 8", i.Tag, length)
+		}
+		i.Value = int64(binary.BigEndian.Uint64(value))
+	case typeBoolean:
+		if length != 8 {
+			return nil, nil, errors.Errorf("invalid TTLV item %06x: length %d instead of 8", i.Tag, length)
+		}
+		i.Value = binary.BigEndian.Uint64(value) != 0
+	case typeTextString:
+		i.Value = string(value)
+	case typeByteString, typeBigInteger:
+		i.Value = append([]byte(nil), value...)
+	default:
+		return nil, nil, errors.Errorf("invalid TTLV item %06x: unknown type %02x", i.Tag, i.Type)
+	}
+	return i, rest, nil
+}
+
+// padded returns the length of a value padded to a multiple of 8 bytes.
+func padded(length int) int {
+	return (length + 7) / 8 * 8
+}
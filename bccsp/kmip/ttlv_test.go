@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmip
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The vectors are the examples of the encoding of the KMIP specification.
+func TestTTLV(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    *item
+		encoded string
+	}{
+		{"integer", integer(0x420020, 8), "42002002 00000004 0000000800000000"},
+		{"long integer", &item{Tag: 0x420020, Type: typeLongInteger, Value: int64(123456789000000000)}, "42002003 00000008 01B69B4BA5749200"},
+		{"big integer", &item{Tag: 0x420020, Type: typeBigInteger, Value: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x3D, 0x7E, 0x2B, 0xAA, 0x95, 0x1A}}, "42002004 00000010 0000000000000000 00003D7E2BAA951A"},
+		{"enumeration", enumeration(0x420020, 255), "42002005 00000004 000000FF00000000"},
+		{"boolean", &item{Tag: 0x420020, Type: typeBoolean, Value: true}, "42002006 00000008 0000000000000001"},
+		{"text string", text(0x420020, "Hello World"), "42002007 0000000B 48656C6C6F20576F 726C640000000000"},
+		{"byte string", byteString(0x420020, []byte{1, 2, 3}), "42002008 00000003 0102030000000000"},
+		{"date time", &item{Tag: 0x420020, Type: typeDateTime, Value: int64(0x47DA67F8)}, "42002009 00000008 0000000047DA67F8"},
+		{"interval", &item{Tag: 0x420020, Type: typeInterval, Value: int32(864000)}, "4200200A 00000004 000D2F0000000000"},
+		{
+			"structure",
+			structure(0x420020, enumeration(0x420004, 254), nil, integer(0x420005, 255)),
+			"42002001 00000020 42000405 00000004 000000FE00000000 42000502 00000004 000000FF00000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := hex.DecodeString(strings.Replace(tt.encoded, " ", "", -1))
+			require.NoError(t, err)
+
+			raw, err := tt.item.encode()
+			require.NoError(t, err)
+			require.Equal(t, expected, raw)
+
+			decoded, rest, err := decode(append(raw, 0x42))
+			require.NoError(t, err)
+			require.Equal(t, tt.item, decoded)
+			require.Equal(t, []byte{0x42}, rest)
+		})
+	}
+}
+
+func TestTTLVAccessors(t *testing.T) {
+	s := structure(tagResponsePayload,
+		text(tagUniqueIdentifier, "1"),
+		integer(tagProtocolVersionMajor, 2),
+		byteString(tagData, []byte("data")),
+		text(tagUniqueIdentifier, "2"),
+	)
+	require.Equal(t, "1", s.text(tagUniqueIdentifier))
+	require.Len(t, s.children(tagUniqueIdentifier), 2)
+	require.Equal(t, []byte("data"), s.bytes(tagData))
+	v, ok := s.int(tagProtocolVersionMajor)
+	require.True(t, ok)
+	require.Equal(t, int32(2), v)
+
+	require.Nil(t, s.child(tagPassword))
+	require.Empty(t, s.text(tagPassword))
+	require.Nil(t, s.bytes(tagPassword))
+	_, ok = s.int(tagUniqueIdentifier)
+	require.False(t, ok)
+}
+
+func TestTTLVFailures(t *testing.T) {
+	_, err := (&item{Tag: 0x420020, Type: typeInteger, Value: "8"}).encode()
+	require.EqualError(t, err, "invalid value of item 420020: expected int32, got string")
+	_, err = structure(0x420020, &item{Tag: 0x420021, Type: 0x0B}).encode()
+	require.EqualError(t, err, "invalid type 0b of item 420021")
+
+	tests := []struct {
+		encoded string
+		err     string
+	}{
+		{"420020020000", "invalid TTLV item: truncated header"},
+		{"4200200200000004000000", "invalid TTLV item 420020: length 4 exceeds the 3 bytes left"},
+		{"420020020000000400000008", "invalid TTLV item 420020: truncated padding"},
+		{"42002002000000080000000000000008", "invalid TTLV item 420020: length 8 instead of 4"},
+		{"42002003000000040000000800000000", "invalid TTLV item 420020: length 4 instead of 8"},
+		{"4200200B00000000", "invalid TTLV item 420020: unknown type 0b"},
+		{"42002001000000084200210200000004", "invalid TTLV item 420021: length 4 exceeds the 0 bytes left"},
+	}
+	for _, tt := range tests {
+		raw, err := hex.DecodeString(tt.encoded)
+		require.NoError(t, err)
+		_, _, err = decode(raw)
+		require.EqualError(t, err, tt.err, tt.encoded)
+	}
+}
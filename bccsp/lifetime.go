@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeyLifetime is the lifetime of a key.
+type KeyLifetime struct {
+	// SKI identifies the key.
+	SKI []byte `json:"ski"`
+	// NotAfter is the time after which the key must no longer be used.
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// ExpiredKeyError is returned when signing or encrypting with a key past its
+// lifetime.
+type ExpiredKeyError struct {
+	SKI      []byte
+	NotAfter time.Time
+}
+
+func (e *ExpiredKeyError) Error() string {
+	return fmt.Sprintf("key %x expired at %s", e.SKI, e.NotAfter.UTC().Format(time.RFC3339))
+}
+
+// KeyLifetimeManager is implemented by the BCCSP providers whose keys carry a
+// not-after time, past which they refuse to sign or encrypt with them.
+type KeyLifetimeManager interface {
+	// SetKeyNotAfter sets the time after which the key whose SKI is the one
+	// passed must no longer be used.
+	SetKeyNotAfter(ski []byte, notAfter time.Time) error
+
+	// KeyNotAfter returns the time after which the key whose SKI is the one
+	// passed must no longer be used, and false when the key has no lifetime.
+	KeyNotAfter(ski []byte) (time.Time, bool)
+
+	// ExpiringKeys returns the lifetimes of the keys which expire within the
+	// given duration, including the expired ones, soonest first.
+	ExpiringKeys(within time.Duration) []KeyLifetime
+}
@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package lows applies a policy to the high-S ECDSA signatures verified by a
+// BCCSP provider. The providers refuse the ECDSA signatures whose S value is
+// larger than half the order of the curve, as they make signatures
+// malleable. The policy can instead normalize them, so that they are
+// accepted, or report them as invalid without an error.
+package lows
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/decorator"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_lows")
+
+// CSP is a BCCSP applying a low-S policy to the ECDSA signatures it
+// verifies.
+type CSP struct {
+	decorator.Base
+
+	policy utils.LowSPolicy
+}
+
+// New returns a CSP applying the policy to the ECDSA signatures verified by
+// the given CSP.
+func New(csp bccsp.BCCSP, policy utils.LowSPolicy) (*CSP, error) {
+	policy, err := utils.ParseLowSPolicy(string(policy))
+	if err != nil {
+		return nil, err
+	}
+	return &CSP{Base: decorator.Base{BCCSP: csp}, policy: policy}, nil
+}
+
+// Policy returns the low-S policy applied by the CSP.
+func (c *CSP) Policy() utils.LowSPolicy {
+	return c.policy
+}
+
+// Verify verifies the signature with the wrapped CSP, once the policy is
+// applied to it.
+func (c *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	signature, ok := c.apply(k, signature)
+	if !ok {
+		return false, nil
+	}
+	return c.BCCSP.Verify(k, signature, digest, opts)
+}
+
+// VerifyBatch verifies the signatures of the requests with the wrapped CSP,
+// once the policy is applied to them.
+func (c *CSP) VerifyBatch(requests []*bccsp.VerifyRequest) []*bccsp.VerifyResult {
+	results := make([]*bccsp.VerifyResult, len(requests))
+	var pending []*bccsp.VerifyRequest
+	var indexes []int
+	for i, r := range requests {
+		signature, ok := c.apply(r.Key, r.Signature)
+		if !ok {
+			results[i] = &bccsp.VerifyResult{}
+			continue
+		}
+		pending = append(pending, &bccsp.VerifyRequest{Key: r.Key, Signature: signature, Digest: r.Digest, Opts: r.Opts})
+		indexes = append(indexes, i)
+	}
+
+	for i, result := range c.Base.VerifyBatch(pending) {
+		results[indexes[i]] = result
+	}
+	return results
+}
+
+// apply returns the signature to verify with the wrapped CSP, or false when
+// the policy rejects it. The signatures which are not ECDSA signatures, or
+// which are not verified with ECDSA keys, are left to the wrapped CSP.
+func (c *CSP) apply(k bccsp.Key, signature []byte) ([]byte, bool) {
+	if c.policy == utils.LowSEnforce || k == nil {
+		return signature, true
+	}
+	_, s, err := utils.UnmarshalECDSASignature(signature)
+	if err != nil {
+		return signature, true
+	}
+	pub, err := ecdsaPublicKey(k)
+	if err != nil {
+		return signature, true
+	}
+	lowS, err := utils.IsLowS(pub, s)
+	if err != nil || lowS {
+		return signature, true
+	}
+
+	if c.policy == utils.LowSReject {
+		logger.Debugf("Rejecting high-S signature verified with key [%x]", k.SKI())
+		return nil, false
+	}
+	normalized, err := utils.SignatureToLowS(pub, signature)
+	if err != nil {
+		return signature, true
+	}
+	return normalized, true
+}
+
+func ecdsaPublicKey(k bccsp.Key) (*ecdsa.PublicKey, error) {
+	pk, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := pk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := x509.ParsePKIXPublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("the key [%x] is not an ECDSA key", k.SKI())
+	}
+	return ecdsaPub, nil
+}
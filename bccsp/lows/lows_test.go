@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package lows
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignatures(t *testing.T) (bccsp.BCCSP, bccsp.Key, []byte, []byte, []byte) {
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	lowSig, err := provider.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	r, s, err := utils.UnmarshalECDSASignature(lowSig)
+	require.NoError(t, err)
+	highSig, err := utils.MarshalECDSASignature(r, new(big.Int).Sub(elliptic.P256().Params().N, s))
+	require.NoError(t, err)
+	return provider, k, digest[:], lowSig, highSig
+}
+
+func TestNew(t *testing.T) {
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	csp, err := New(provider, "")
+	require.NoError(t, err)
+	require.Equal(t, utils.LowSEnforce, csp.Policy())
+	// the optional interfaces of the provider are passed through
+	var _ bccsp.Committer = csp
+	var _ bccsp.KeyWrapper = csp
+	_, err = New(provider, "ignore")
+	require.EqualError(t, err, "invalid low-S policy ignore: must be enforce, normalize or reject")
+}
+
+func TestVerify(t *testing.T) {
+	provider, k, digest, lowSig, highSig := newSignatures(t)
+
+	for _, policy := range []utils.LowSPolicy{utils.LowSEnforce, utils.LowSNormalize, utils.LowSReject} {
+		csp, err := New(provider, policy)
+		require.NoError(t, err)
+		valid, err := csp.Verify(k, lowSig, digest, nil)
+		require.NoError(t, err, policy)
+		require.True(t, valid, policy)
+	}
+
+	// high-S signatures are refused by the providers
+	csp, err := New(provider, utils.LowSEnforce)
+	require.NoError(t, err)
+	_, err = csp.Verify(k, highSig, digest, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order")
+
+	csp, err = New(provider, utils.LowSNormalize)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, highSig, digest, nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = csp.Verify(k, highSig, []byte("another digest"), nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	csp, err = New(provider, utils.LowSReject)
+	require.NoError(t, err)
+	valid, err = csp.Verify(k, highSig, digest, nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// the signatures which are not ECDSA signatures are left to the provider
+	_, err = csp.Verify(k, []byte("not a signature"), digest, nil)
+	require.Error(t, err)
+}
+
+func TestVerifyBatch(t *testing.T) {
+	provider, k, digest, lowSig, highSig := newSignatures(t)
+	requests := []*bccsp.VerifyRequest{
+		{Key: k, Signature: highSig, Digest: digest},
+		{Key: k, Signature: lowSig, Digest: digest},
+	}
+
+	csp, err := New(provider, utils.LowSNormalize)
+	require.NoError(t, err)
+	results := csp.VerifyBatch(requests)
+	require.Len(t, results, 2)
+	require.True(t, results[0].Valid)
+	require.True(t, results[1].Valid)
+
+	csp, err = New(provider, utils.LowSReject)
+	require.NoError(t, err)
+	results = csp.VerifyBatch(requests)
+	require.Len(t, results, 2)
+	require.False(t, results[0].Valid)
+	require.NoError(t, results[0].Err)
+	require.True(t, results[1].Valid)
+}
+
+func TestStatus(t *testing.T) {
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	csp, err := New(provider, utils.LowSNormalize)
+	require.NoError(t, err)
+
+	status, err := csp.Status()
+	require.NoError(t, err)
+	require.Equal(t, "SW", status.Provider)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+// PEDERSEN Pedersen commitments over P-256 (blinding factor gen and import).
+// The blinding factors of commitments are keys, which are used by the Committer
+// implemented by the BCCSP.
+const PEDERSEN = "PEDERSEN"
+
+// PedersenBlindingKeyGenOpts contains options for the generation of random blinding factors.
+type PedersenBlindingKeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key generation algorithm identifier (to be used).
+func (opts *PedersenBlindingKeyGenOpts) Algorithm() string {
+	return PEDERSEN
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *PedersenBlindingKeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// PedersenBlindingImportOpts contains options for the importation of blinding factors
+// from their 32 byte big endian encoding, as returned by the Bytes method of the keys.
+type PedersenBlindingImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *PedersenBlindingImportOpts) Algorithm() string {
+	return PEDERSEN
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *PedersenBlindingImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
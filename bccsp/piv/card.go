@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// card is a connection to a PIV card, to which APDUs are transmitted.
+type card interface {
+	// transmit sends an APDU to the card and returns its response,
+	// status word included.
+	transmit(apdu []byte) ([]byte, error)
+	// close releases the card.
+	close() error
+}
+
+// slot is a PIV key slot, with the data object holding its certificate.
+type slot struct {
+	name   string
+	key    byte
+	object []byte
+}
+
+// slots are the PIV slots of the keys used for signing, as defined by
+// NIST SP 800-73-4.
+var slots = []slot{
+	{"9a", 0x9a, []byte{0x5f, 0xc1, 0x05}},
+	{"9c", 0x9c, []byte{0x5f, 0xc1, 0x0a}},
+	{"9d", 0x9d, []byte{0x5f, 0xc1, 0x0b}},
+	{"9e", 0x9e, []byte{0x5f, 0xc1, 0x01}},
+}
+
+// aid is the application identifier of the PIV application.
+var aid = []byte{0xa0, 0x00, 0x00, 0x03, 0x08}
+
+const (
+	insSelect       = 0xa4
+	insVerify       = 0x20
+	insGetData      = 0xcb
+	insAuthenticate = 0x87
+	insGetResponse  = 0xc0
+
+	algECCP256 = 0x11
+	algECCP384 = 0x14
+
+	swSuccess   = 0x9000
+	swNotFound  = 0x6a82
+	swBlocked   = 0x6983
+	swNoPIN     = 0x6982
+	swMoreBytes = 0x61
+	swWrongPIN  = 0x63c0
+)
+
+// statusError is the status word of an APDU which failed.
+type statusError uint16
+
+func (sw statusError) Error() string {
+	switch {
+	case sw == swNotFound:
+		return "data object or application not found"
+	case sw == swBlocked:
+		return "PIN blocked"
+	case sw == swNoPIN:
+		return "security status not satisfied"
+	case sw&0xfff0 == swWrongPIN:
+		return fmt.Sprintf("wrong PIN, %d retries left", sw&0x0f)
+	default:
+		return fmt.Sprintf("card returned status %04x", uint16(sw))
+	}
+}
+
+// command transmits an APDU built from its parts, and returns the data of
+// the response, whose parts are collected when the card has more bytes to
+// send. Le is set when a response is expected.
+func command(c card, ins, p1, p2 byte, data []byte, response bool) ([]byte, error) {
+	apdu := []byte{0x00, ins, p1, p2}
+	if len(data) > 0 {
+		if len(data) > 0xff {
+			return nil, errors.Errorf("command data too long [%d]", len(data))
+		}
+		apdu = append(apdu, byte(len(data)))
+		apdu = append(apdu, data...)
+	}
+	if response {
+		apdu = append(apdu, 0x00)
+	}
+
+	var resp []byte
+	for {
+		r, err := c.transmit(apdu)
+		if err != nil {
+			return nil, err
+		}
+		if len(r) < 2 {
+			return nil, errors.New("response without status word")
+		}
+		sw := uint16(r[len(r)-2])<<8 | uint16(r[len(r)-1])
+		resp = append(resp, r[:len(r)-2]...)
+		switch {
+		case sw == swSuccess:
+			return resp, nil
+		case sw>>8 == swMoreBytes:
+			apdu = []byte{0x00, insGetResponse, 0x00, 0x00, byte(sw)}
+		default:
+			return nil, statusError(sw)
+		}
+	}
+}
+
+// selectApplication selects the PIV application of the card.
+func selectApplication(c card) error {
+	_, err := command(c, insSelect, 0x04, 0x00, aid, true)
+	return errors.WithMessage(err, "failed selecting the PIV application")
+}
+
+// verifyPIN verifies the PIN of the card, which is padded to 8 bytes.
+func verifyPIN(c card, pin string) error {
+	if len(pin) < 6 || len(pin) > 8 {
+		return errors.New("the PIN must be 6 to 8 characters long")
+	}
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	copy(data, pin)
+	_, err := command(c, insVerify, 0x00, 0x80, data, false)
+	return errors.WithMessage(err, "failed verifying the PIN")
+}
+
+// readCertificate reads the certificate of the key of s. The error is a
+// statusError with swNotFound when the slot holds no certificate.
+func readCertificate(c card, s slot) (*x509.Certificate, error) {
+	resp, err := command(c, insGetData, 0x3f, 0xff, marshalTLV(0x5c, s.object), true)
+	if err != nil {
+		return nil, err
+	}
+	object, err := unmarshalTLV(resp, 0x53)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := unmarshalTLV(object, 0x71); err == nil && len(info) > 0 && info[0]&0x01 != 0 {
+		return nil, errors.New("compressed certificates are not supported")
+	}
+	raw, err := unmarshalTLV(object, 0x70)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(raw)
+}
+
+// authenticate signs digest with the ECDSA key of s, whose public key is
+// pub, and returns the DER encoded signature.
+func authenticate(c card, s slot, pub *ecdsa.PublicKey, digest []byte) ([]byte, error) {
+	var alg byte
+	switch pub.Curve {
+	case elliptic.P256():
+		alg = algECCP256
+	case elliptic.P384():
+		alg = algECCP384
+	default:
+		return nil, errors.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+	}
+
+	// The card signs digests of the size of the curve, so that they are
+	// truncated or left padded as ECDSA does with the digests of other
+	// sizes.
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	if len(digest) > size {
+		digest = digest[:size]
+	} else if len(digest) < size {
+		digest = append(make([]byte, size-len(digest)), digest...)
+	}
+
+	data := marshalTLV(0x7c, append(marshalTLV(0x82, nil), marshalTLV(0x81, digest)...))
+	resp, err := command(c, insAuthenticate, alg, s.key, data, true)
+	if err != nil {
+		return nil, err
+	}
+	template, err := unmarshalTLV(resp, 0x7c)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalTLV(template, 0x82)
+}
+
+// marshalTLV encodes a BER-TLV with a one byte tag.
+func marshalTLV(tag byte, value []byte) []byte {
+	tlv := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		tlv = append(tlv, byte(n))
+	case n <= 0xff:
+		tlv = append(tlv, 0x81, byte(n))
+	default:
+		tlv = append(tlv, 0x82, byte(n>>8), byte(n))
+	}
+	return append(tlv, value...)
+}
+
+// unmarshalTLV returns the value of the first BER-TLV of data whose tag is
+// tag, skipping the others.
+func unmarshalTLV(data []byte, tag byte) ([]byte, error) {
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("truncated TLV")
+		}
+		t, n, rest := data[0], int(data[1]), data[2:]
+		switch n {
+		case 0x81:
+			if len(rest) < 1 {
+				return nil, errors.New("truncated TLV")
+			}
+			n, rest = int(rest[0]), rest[1:]
+		case 0x82:
+			if len(rest) < 2 {
+				return nil, errors.New("truncated TLV")
+			}
+			n, rest = int(rest[0])<<8|int(rest[1]), rest[2:]
+		default:
+			if n >= 0x80 {
+				return nil, errors.Errorf("unsupported TLV length %02x", n)
+			}
+		}
+		if len(rest) < n {
+			return nil, errors.New("truncated TLV")
+		}
+		if t == tag {
+			return rest[:n], nil
+		}
+		data = rest[n:]
+	}
+	return nil, errors.Errorf("tag %02x not found", tag)
+}
@@ -0,0 +1,24 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+// PIVOpts contains options for the PIVFactory
+type PIVOpts struct {
+	// Default algorithms when not specified
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Reader selects the first smart card reader whose name contains it,
+	// such as "Yubico YubiKey". The first reader is used when empty.
+	Reader string `mapstructure:"reader,omitempty" json:"reader,omitempty"`
+	// Slots lists the PIV slots whose keys are used, among 9a, 9c, 9d and
+	// 9e. All of them are used when empty.
+	Slots []string `mapstructure:"slots,omitempty" json:"slots,omitempty"`
+	// PIN is verified before signing, as the keys of the slots other than
+	// 9e require it.
+	PIN string `mapstructure:"pin,omitempty" json:"pin,omitempty"`
+}
@@ -0,0 +1,268 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_piv")
+
+// New returns a BCCSP signing with the ECDSA P-256 and P-384 keys of a PIV
+// token, such as a YubiKey, which are found by the certificates of their
+// slots. The keys are provisioned on the token with the tools of its vendor,
+// so that the token holds the keys of human admin identities, whose
+// certificates are in the signcerts of their MSP. Hashing, verification and
+// the operations on the other keys are performed in software, with the keys
+// of keyStore.
+func New(opts PIVOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	return newWithCard(opts, keyStore, func() (card, error) {
+		return openCard(opts.Reader)
+	})
+}
+
+func newWithCard(opts PIVOpts, keyStore bccsp.KeyStore, open func() (card, error)) (bccsp.BCCSP, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	used := slots
+	if len(opts.Slots) != 0 {
+		used = nil
+		for _, name := range opts.Slots {
+			s, ok := slotNamed(name)
+			if !ok {
+				return nil, errors.Errorf("Invalid PIV slot %s: must be 9a, 9c, 9d or 9e", name)
+			}
+			used = append(used, s)
+		}
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	return &impl{
+		BCCSP: swCSP,
+		open:  open,
+		pin:   opts.PIN,
+		slots: used,
+		keys:  map[string]*ecdsaPrivateKey{},
+	}, nil
+}
+
+func slotNamed(name string) (slot, bool) {
+	for _, s := range slots {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return slot{}, false
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	open  func() (card, error)
+	pin   string
+	slots []slot
+
+	// mutex serializes the use of the token, and guards keys, which caches
+	// the keys of the token by the hex encoding of their SKI
+	mutex sync.Mutex
+	keys  map[string]*ecdsaPrivateKey
+}
+
+// withCard connects to the token, selects its PIV application and calls f.
+// The token is connected for each operation, so that it can be removed and
+// used by other applications in between. The mutex must be held.
+func (csp *impl) withCard(f func(c card) error) error {
+	c, err := csp.open()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	if err := selectApplication(c); err != nil {
+		return err
+	}
+	return f(c)
+}
+
+// reload replaces the cached keys with the ECDSA keys of the slots of the
+// token, which are returned. The slots without certificate and those of the
+// other keys are skipped. The mutex must be held.
+func (csp *impl) reload() ([]bccsp.Key, error) {
+	var keys []bccsp.Key
+	cache := map[string]*ecdsaPrivateKey{}
+	err := csp.withCard(func(c card) error {
+		for _, s := range csp.slots {
+			cert, err := readCertificate(c, s)
+			if err == statusError(swNotFound) {
+				continue
+			}
+			if err != nil {
+				return errors.WithMessagef(err, "failed reading the certificate of slot %s", s.name)
+			}
+			pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+			if !ok || (pub.Curve != elliptic.P256() && pub.Curve != elliptic.P384()) {
+				logger.Debugf("Skipping the key of slot %s, which is not an ECDSA P-256 or P-384 key", s.name)
+				continue
+			}
+
+			// The SKI is computed as the SW provider does, to find the key
+			// of the certificates of the key
+			hash := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+			k := &ecdsaPrivateKey{slot: s, pub: ecdsaPublicKey{hash[:], pub}}
+			cache[hex.EncodeToString(k.SKI())] = k
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed listing the keys of the PIV token")
+	}
+	csp.keys = cache
+	return keys, nil
+}
+
+// lookup returns the key of the token whose SKI is ski, reloading the keys
+// of the token when it is not cached.
+func (csp *impl) lookup(ski []byte) (*ecdsaPrivateKey, error) {
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	if k, ok := csp.keys[hex.EncodeToString(ski)]; ok {
+		return k, nil
+	}
+	if _, err := csp.reload(); err != nil {
+		return nil, err
+	}
+	return csp.keys[hex.EncodeToString(ski)], nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	k, err := csp.lookup(ski)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return csp.BCCSP.GetKey(ski)
+	}
+	return k, nil
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+//
+// Note that when a signature of a hash of a larger message is needed,
+// the caller is responsible for hashing the larger message and passing
+// the hash (as digest).
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.signECDSA(key, digest)
+	default:
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+}
+
+func (csp *impl) signECDSA(k *ecdsaPrivateKey, digest []byte) ([]byte, error) {
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+
+	var sig []byte
+	err := csp.withCard(func(c card) error {
+		if csp.pin != "" {
+			if err := verifyPIN(c, csp.pin); err != nil {
+				return err
+			}
+		}
+		var err error
+		sig, err = authenticate(c, k.slot, k.pub.pub, digest)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed signing with ECDSA key in slot %s of the PIV token", k.slot.name)
+	}
+	if _, _, err := utils.UnmarshalECDSASignature(sig); err != nil {
+		return nil, errors.WithMessage(err, "Invalid signature returned by the PIV token")
+	}
+	return utils.SignatureToLowS(k.pub.pub, sig)
+}
+
+// Verify verifies signature against key k and digest
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+
+	// Verification only needs the public key, so it is done in software
+	switch key := k.(type) {
+	case *ecdsaPrivateKey:
+		return csp.verifyECDSA(&key.pub, signature, digest, opts)
+	case *ecdsaPublicKey:
+		return csp.verifyECDSA(key, signature, digest, opts)
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+}
+
+func (csp *impl) verifyECDSA(k *ecdsaPublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pk, err := csp.BCCSP.KeyImport(k.pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return false, err
+	}
+	return csp.BCCSP.Verify(pk, signature, digest, opts)
+}
+
+// ListKeys returns the keys held by the slots of the token.
+func (csp *impl) ListKeys() ([]bccsp.Key, error) {
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	return csp.reload()
+}
+
+// DeleteKey is not supported, as the keys of the token are managed with the
+// tools of its vendor.
+func (csp *impl) DeleteKey(ski []byte) error {
+	return errors.New("the keys of the PIV token are managed with the tools of its vendor")
+}
+
+// Status returns the status of the provider, whose keys are counted from the
+// token. An error is returned when the token is unavailable.
+func (csp *impl) Status() (*bccsp.Status, error) {
+	status := &bccsp.Status{Provider: "PIV"}
+	keys, err := csp.ListKeys()
+	if err != nil {
+		return status, err
+	}
+	status.Keys.Private = len(keys)
+	status.Keys.Public = len(keys)
+	return status, nil
+}
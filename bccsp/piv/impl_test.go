@@ -0,0 +1,347 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simCard simulates a PIV card holding keys and certificates in its slots.
+// Its responses are sent in parts of 256 bytes at most.
+type simCard struct {
+	keys    map[byte]crypto.Signer
+	certs   map[byte][]byte
+	pin     string
+	retries int
+
+	selected bool
+	verified bool
+	pending  []byte
+	opened   int
+}
+
+func newSimCard(t *testing.T) *simCard {
+	c := &simCard{
+		keys:    map[byte]crypto.Signer{},
+		certs:   map[byte][]byte{},
+		pin:     "123456",
+		retries: 3,
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	c.provision(t, 0x9a, p256)
+	c.provision(t, 0x9c, p384)
+	c.provision(t, 0x9d, rsaKey)
+	return c
+}
+
+func (c *simCard) provision(t *testing.T, key byte, priv crypto.Signer) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	require.NoError(t, err)
+	c.keys[key] = priv
+	c.certs[key] = raw
+}
+
+func (c *simCard) open() (card, error) {
+	c.opened++
+	return c, nil
+}
+
+func (c *simCard) close() error {
+	c.selected = false
+	c.verified = false
+	return nil
+}
+
+func (c *simCard) transmit(apdu []byte) ([]byte, error) {
+	var data []byte
+	if len(apdu) > 5 {
+		data = apdu[5 : 5+int(apdu[4])]
+	}
+	switch ins := apdu[1]; {
+	case ins == insGetResponse:
+		return c.respond(c.pending), nil
+	case ins == insSelect:
+		if !bytes.Equal(data, aid) {
+			return []byte{0x6a, 0x82}, nil
+		}
+		c.selected = true
+		return c.respond(nil), nil
+	case !c.selected:
+		return []byte{0x6d, 0x00}, nil
+	case ins == insVerify:
+		if c.retries == 0 {
+			return []byte{0x69, 0x83}, nil
+		}
+		if !bytes.Equal(bytes.TrimRight(data, "\xff"), []byte(c.pin)) {
+			c.retries--
+			return []byte{0x63, 0xc0 | byte(c.retries)}, nil
+		}
+		c.retries = 3
+		c.verified = true
+		return c.respond(nil), nil
+	case ins == insGetData:
+		for _, s := range slots {
+			if bytes.Equal(data, marshalTLV(0x5c, s.object)) {
+				cert, ok := c.certs[s.key]
+				if !ok {
+					return []byte{0x6a, 0x82}, nil
+				}
+				object := append(marshalTLV(0x70, cert), marshalTLV(0x71, []byte{0})...)
+				return c.respond(marshalTLV(0x53, append(object, marshalTLV(0xfe, nil)...))), nil
+			}
+		}
+		return []byte{0x6a, 0x82}, nil
+	case ins == insAuthenticate:
+		priv, ok := c.keys[apdu[3]].(*ecdsa.PrivateKey)
+		if !ok {
+			return []byte{0x6a, 0x80}, nil
+		}
+		if apdu[3] != 0x9e && !c.verified {
+			return []byte{0x69, 0x82}, nil
+		}
+		template, err := unmarshalTLV(data, 0x7c)
+		if err != nil {
+			return []byte{0x6a, 0x80}, nil
+		}
+		digest, err := unmarshalTLV(template, 0x81)
+		if err != nil || len(digest) != (priv.Curve.Params().BitSize+7)/8 {
+			return []byte{0x6a, 0x80}, nil
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := utils.MarshalECDSASignature(r, s)
+		if err != nil {
+			return nil, err
+		}
+		return c.respond(marshalTLV(0x7c, marshalTLV(0x82, sig))), nil
+	default:
+		return []byte{0x6d, 0x00}, nil
+	}
+}
+
+func (c *simCard) respond(resp []byte) []byte {
+	if len(resp) <= 256 {
+		c.pending = nil
+		return append(resp, 0x90, 0x00)
+	}
+	c.pending = resp[256:]
+	more := len(c.pending)
+	if more > 0xff {
+		more = 0
+	}
+	return append(append([]byte(nil), resp[:256]...), swMoreBytes, byte(more))
+}
+
+func newTestCSP(t *testing.T, c *simCard, opts PIVOpts) bccsp.BCCSP {
+	opts.SecLevel = 256
+	opts.HashFamily = "SHA2"
+	csp, err := newWithCard(opts, sw.NewDummyKeyStore(), c.open)
+	require.NoError(t, err)
+	return csp
+}
+
+// adminKey returns the key of the certificate of slot, found as the MSP
+// finds the key of its signing identity.
+func adminKey(t *testing.T, csp bccsp.BCCSP, c *simCard, key byte) bccsp.Key {
+	cert, err := x509.ParseCertificate(c.certs[key])
+	require.NoError(t, err)
+	pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	k, err := csp.GetKey(pk.SKI())
+	require.NoError(t, err)
+	require.IsType(t, &ecdsaPrivateKey{}, k)
+	assert.Equal(t, pk.SKI(), k.SKI())
+	return k
+}
+
+func TestNew(t *testing.T) {
+	_, err := newWithCard(PIVOpts{SecLevel: 256, HashFamily: "SHA2"}, nil, newSimCard(t).open)
+	assert.EqualError(t, err, "Invalid bccsp.KeyStore instance. It must be different from nil")
+	_, err = newWithCard(PIVOpts{SecLevel: 256, HashFamily: "SHA2", Slots: []string{"9b"}}, sw.NewDummyKeyStore(), newSimCard(t).open)
+	assert.EqualError(t, err, "Invalid PIV slot 9b: must be 9a, 9c, 9d or 9e")
+	_, err = newWithCard(PIVOpts{SecLevel: 256, HashFamily: "SHA1"}, sw.NewDummyKeyStore(), newSimCard(t).open)
+	assert.Error(t, err)
+}
+
+func TestSignVerify(t *testing.T) {
+	c := newSimCard(t)
+	csp := newTestCSP(t, c, PIVOpts{PIN: "123456"})
+
+	for _, tc := range []struct {
+		slot   byte
+		digest []byte
+	}{
+		{0x9a, sha256.New().Sum(nil)},
+		{0x9c, sha512.New384().Sum(nil)},
+		// the digests are adjusted to the size of the curve
+		{0x9a, sha512.New().Sum(nil)},
+		{0x9c, sha256.New().Sum(nil)},
+	} {
+		k := adminKey(t, csp, c, tc.slot)
+		assert.True(t, k.Private())
+		_, err := k.Bytes()
+		assert.Error(t, err)
+
+		sig, err := csp.Sign(k, tc.digest, nil)
+		require.NoError(t, err)
+		pub := c.keys[tc.slot].Public().(*ecdsa.PublicKey)
+		_, s, err := utils.UnmarshalECDSASignature(sig)
+		require.NoError(t, err)
+		lowS, err := utils.IsLowS(pub, s)
+		require.NoError(t, err)
+		assert.True(t, lowS)
+		valid, err := csp.Verify(k, sig, tc.digest, nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		valid, err = csp.Verify(pk, sig, tc.digest, nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		expected, err := utils.SPKIFingerprint(pub)
+		require.NoError(t, err)
+		fingerprint, err := k.(bccsp.KeyFingerprinter).Fingerprint()
+		require.NoError(t, err)
+		assert.Equal(t, expected, fingerprint)
+	}
+
+	// the keys flow through the signer of the MSP
+	k := adminKey(t, csp, c, 0x9a)
+	s, err := signer.New(csp, k)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("config update"))
+	sig, err := s.Sign(rand.Reader, digest[:], nil)
+	require.NoError(t, err)
+	r, ss, err := utils.UnmarshalECDSASignature(sig)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.Verify(c.keys[0x9a].Public().(*ecdsa.PublicKey), digest[:], r, ss))
+
+	_, err = csp.Sign(k, nil, nil)
+	assert.EqualError(t, err, "Invalid digest. Cannot be empty")
+	_, err = csp.Sign(nil, digest[:], nil)
+	assert.EqualError(t, err, "Invalid Key. It must not be nil")
+}
+
+func TestPIN(t *testing.T) {
+	c := newSimCard(t)
+	digest := sha256.Sum256([]byte("message"))
+
+	csp := newTestCSP(t, c, PIVOpts{})
+	_, err := csp.Sign(adminKey(t, csp, c, 0x9a), digest[:], nil)
+	assert.EqualError(t, err, "Failed signing with ECDSA key in slot 9a of the PIV token: security status not satisfied")
+
+	csp = newTestCSP(t, c, PIVOpts{PIN: "654321"})
+	k := adminKey(t, csp, c, 0x9a)
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.EqualError(t, err, "Failed signing with ECDSA key in slot 9a of the PIV token: failed verifying the PIN: wrong PIN, 2 retries left")
+	csp.Sign(k, digest[:], nil)
+	csp.Sign(k, digest[:], nil)
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.EqualError(t, err, "Failed signing with ECDSA key in slot 9a of the PIV token: failed verifying the PIN: PIN blocked")
+
+	csp = newTestCSP(t, c, PIVOpts{PIN: "1234"})
+	_, err = csp.Sign(adminKey(t, csp, c, 0x9a), digest[:], nil)
+	assert.EqualError(t, err, "Failed signing with ECDSA key in slot 9a of the PIV token: the PIN must be 6 to 8 characters long")
+}
+
+func TestKeyManagement(t *testing.T) {
+	c := newSimCard(t)
+	csp := newTestCSP(t, c, PIVOpts{})
+
+	// the RSA key of 9d and the empty slot 9e are skipped
+	keys, err := csp.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	status, err := csp.(bccsp.StatusReporter).Status()
+	require.NoError(t, err)
+	assert.Equal(t, "PIV", status.Provider)
+	assert.Equal(t, 2, status.Keys.Private)
+	err = csp.(bccsp.KeyManager).DeleteKey(keys[0].SKI())
+	assert.EqualError(t, err, "the keys of the PIV token are managed with the tools of its vendor")
+
+	// the keys are cached, and reloaded when a key is missing
+	opened := c.opened
+	adminKey(t, csp, c, 0x9c)
+	assert.Equal(t, opened, c.opened)
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c.provision(t, 0x9e, priv)
+	k := adminKey(t, csp, c, 0x9e)
+	assert.Equal(t, opened+1, c.opened)
+	// the card authentication key needs no PIN
+	digest := sha256.Sum256([]byte("message"))
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	csp = newTestCSP(t, c, PIVOpts{Slots: []string{"9c"}})
+	keys, err = csp.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, adminKey(t, csp, c, 0x9c).SKI(), keys[0].SKI())
+
+	// the keys of the other slots are left to the fallback provider
+	cert, err := x509.ParseCertificate(c.certs[0x9a])
+	require.NoError(t, err)
+	pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = csp.GetKey(pk.SKI())
+	assert.Error(t, err)
+
+	// the token is connected when used
+	csp, err = New(PIVOpts{SecLevel: 256, HashFamily: "SHA2", Reader: "no such reader"}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	_, err = csp.(bccsp.KeyManager).ListKeys()
+	assert.Error(t, err)
+}
+
+func TestTLV(t *testing.T) {
+	for _, n := range []int{0, 0x7f, 0x80, 0xff, 0x100, 0x1000} {
+		value := bytes.Repeat([]byte{0x42}, n)
+		tlv := append(marshalTLV(0x01, nil), marshalTLV(0x53, value)...)
+		v, err := unmarshalTLV(tlv, 0x53)
+		require.NoError(t, err)
+		assert.Equal(t, value, v)
+	}
+	_, err := unmarshalTLV(marshalTLV(0x53, []byte{1})[:2], 0x53)
+	assert.EqualError(t, err, "truncated TLV")
+	_, err = unmarshalTLV([]byte{0x53, 0x83, 0, 0, 0}, 0x53)
+	assert.EqualError(t, err, "unsupported TLV length 83")
+	_, err = unmarshalTLV(marshalTLV(0x53, nil), 0x70)
+	assert.EqualError(t, err, "tag 70 not found")
+}
@@ -0,0 +1,142 @@
+// +build piv,cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+/*
+#cgo linux CFLAGS: -I/usr/include/PCSC
+#cgo linux LDFLAGS: -lpcsclite
+#cgo darwin LDFLAGS: -framework PCSC
+#cgo windows LDFLAGS: -lwinscard
+
+#ifdef __APPLE__
+#include <PCSC/winscard.h>
+#include <PCSC/wintypes.h>
+#else
+#include <winscard.h>
+#endif
+
+#include <stdlib.h>
+
+// The helpers hide the sizes of the PC/SC types, which differ between the
+// platforms.
+
+static long pcscEstablish(SCARDCONTEXT *ctx) {
+	return SCardEstablishContext(SCARD_SCOPE_SYSTEM, NULL, NULL, ctx);
+}
+
+static long pcscListReaders(SCARDCONTEXT ctx, char *buf, unsigned int *size) {
+	DWORD n = *size;
+	LONG rv = SCardListReaders(ctx, NULL, buf, &n);
+	*size = n;
+	return rv;
+}
+
+static long pcscConnect(SCARDCONTEXT ctx, const char *reader, SCARDHANDLE *handle) {
+	DWORD protocol;
+	return SCardConnect(ctx, reader, SCARD_SHARE_EXCLUSIVE, SCARD_PROTOCOL_T1, handle, &protocol);
+}
+
+static long pcscTransmit(SCARDHANDLE handle, const unsigned char *apdu, unsigned int apduLen, unsigned char *resp, unsigned int *respLen) {
+	DWORD n = *respLen;
+	LONG rv = SCardTransmit(handle, SCARD_PCI_T1, apdu, apduLen, NULL, resp, &n);
+	*respLen = n;
+	return rv;
+}
+
+static long pcscDisconnect(SCARDHANDLE handle) {
+	return SCardDisconnect(handle, SCARD_LEAVE_CARD);
+}
+
+static long pcscRelease(SCARDCONTEXT ctx) {
+	return SCardReleaseContext(ctx);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// pcscError is the return value of a PC/SC function which failed.
+type pcscError C.long
+
+func (e pcscError) Error() string {
+	return fmt.Sprintf("PC/SC error %08x", uint32(e))
+}
+
+// pcscCard is a card connected through PC/SC.
+type pcscCard struct {
+	ctx    C.SCARDCONTEXT
+	handle C.SCARDHANDLE
+}
+
+// openCard connects to the card of the first reader whose name contains
+// reader, or of the first reader when empty.
+func openCard(reader string) (card, error) {
+	c := &pcscCard{}
+	if rv := C.pcscEstablish(&c.ctx); rv != 0 {
+		return nil, errors.WithMessage(pcscError(rv), "failed establishing the PC/SC context")
+	}
+
+	name, err := c.findReader(reader)
+	if err != nil {
+		C.pcscRelease(c.ctx)
+		return nil, err
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	if rv := C.pcscConnect(c.ctx, cname, &c.handle); rv != 0 {
+		C.pcscRelease(c.ctx)
+		return nil, errors.WithMessagef(pcscError(rv), "failed connecting to the card of reader %s", name)
+	}
+	return c, nil
+}
+
+func (c *pcscCard) findReader(reader string) (string, error) {
+	var size C.uint
+	if rv := C.pcscListReaders(c.ctx, nil, &size); rv != 0 {
+		return "", errors.WithMessage(pcscError(rv), "failed listing the smart card readers")
+	}
+	buf := make([]byte, size)
+	if rv := C.pcscListReaders(c.ctx, (*C.char)(unsafe.Pointer(&buf[0])), &size); rv != 0 {
+		return "", errors.WithMessage(pcscError(rv), "failed listing the smart card readers")
+	}
+
+	// The names of the readers are separated by NULs
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) != 0 && strings.Contains(string(name), reader) {
+			return string(name), nil
+		}
+	}
+	return "", errors.Errorf("no smart card reader matching %q", reader)
+}
+
+func (c *pcscCard) transmit(apdu []byte) ([]byte, error) {
+	resp := make([]byte, 258)
+	size := C.uint(len(resp))
+	rv := C.pcscTransmit(c.handle, (*C.uchar)(unsafe.Pointer(&apdu[0])), C.uint(len(apdu)), (*C.uchar)(unsafe.Pointer(&resp[0])), &size)
+	if rv != 0 {
+		return nil, errors.WithMessage(pcscError(rv), "failed transmitting to the card")
+	}
+	return resp[:size], nil
+}
+
+func (c *pcscCard) close() error {
+	rv := C.pcscDisconnect(c.handle)
+	C.pcscRelease(c.ctx)
+	if rv != 0 {
+		return pcscError(rv)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+// +build !piv !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package piv
+
+import "github.com/pkg/errors"
+
+func openCard(reader string) (card, error) {
+	return nil, errors.New("PIV tokens are only available in binaries built with the piv tag and cgo")
+}
@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultConfigureTool is the configure tool of the PKCS#11 library of
+	// the CloudHSM client
+	defaultConfigureTool = "/opt/cloudhsm/bin/configure-pkcs11"
+	// defaultReplicationTimeout bounds how long the keys not found are
+	// looked up again on CloudHSM clusters when it is not configured
+	defaultReplicationTimeout = 5 * time.Second
+	// replicationRetryInterval is the interval at which the keys not found
+	// are looked up again
+	replicationRetryInterval = 250 * time.Millisecond
+)
+
+// cluster is the AWS CloudHSM cluster of a token, whose HSMs are replaced
+// over time
+type cluster struct {
+	endpoints       []string
+	configureTool   string
+	credentialsFile string
+
+	// next is the index of the endpoint the library is configured with
+	// next, guarded by the mutex of the token
+	next int
+}
+
+func newCluster(opts *CloudHSMOpts) *cluster {
+	c := &cluster{
+		endpoints:       opts.Endpoints,
+		configureTool:   opts.ConfigureTool,
+		credentialsFile: opts.CredentialsFile,
+	}
+	if c.configureTool == "" {
+		c.configureTool = defaultConfigureTool
+	}
+	return c
+}
+
+// rotate configures the library with the next endpoint of the cluster, if
+// endpoints are configured.
+func (c *cluster) rotate() error {
+	if len(c.endpoints) == 0 {
+		return nil
+	}
+	endpoint := c.endpoints[c.next%len(c.endpoints)]
+	c.next++
+
+	out, err := exec.Command(c.configureTool, "-a", endpoint).CombinedOutput()
+	if err != nil {
+		return errors.Errorf("failed configuring the CloudHSM client with endpoint %s [%s]: %s", endpoint, err, bytes.TrimSpace(out))
+	}
+	logger.Infof("Configured the CloudHSM client with endpoint %s", endpoint)
+	return nil
+}
+
+// credentials returns the PIN of the token, read from the credentials file
+// of its cluster when configured, so that rotated credentials are used by
+// the next login.
+func (t *token) credentials() (string, error) {
+	if t.cluster == nil || t.cluster.credentialsFile == "" {
+		return t.pin, nil
+	}
+	raw, err := ioutil.ReadFile(t.cluster.credentialsFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed reading the CloudHSM credentials")
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+func (t *token) currentGeneration() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.generation
+}
+
+// recover initializes the library of the token again, configured with the
+// endpoints of its cluster in turn until the token is found, and returns
+// whether it succeeded. The library is not initialized again when it was
+// since generation gen, by a concurrent recovery.
+func (t *token) recover(gen int) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.generation != gen {
+		return t.healthy
+	}
+	t.generation++
+
+	// The sessions do not survive the finalization of the library
+	for len(t.sessions) > 0 {
+		<-t.sessions
+	}
+	if t.ctx != nil {
+		t.ctx.Finalize()
+	}
+
+	attempts := len(t.cluster.endpoints)
+	if attempts == 0 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = t.cluster.rotate(); err != nil {
+			continue
+		}
+		if err = t.initialize(); err == nil {
+			logger.Infof("Initialized the PKCS11 library %s of CloudHSM token %s again", t.lib, t.label)
+			return true
+		}
+	}
+	logger.Warningf("Failed recovering CloudHSM token %s of library %s [%s]", t.label, t.lib, err)
+	return false
+}
+
+// initialize initializes the library of the token, loading it if needed,
+// and opens a first session with the token. The mutex must be held.
+func (t *token) initialize() error {
+	pin, err := t.credentials()
+	if err != nil {
+		return err
+	}
+
+	var session *pkcs11.SessionHandle
+	if t.ctx == nil {
+		t.ctx, t.slot, session, err = loadLib(t.lib, pin, t.label)
+	} else {
+		if err = t.ctx.Initialize(); err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+			return errors.Wrap(err, "Initialize failed")
+		}
+		t.slot, session, err = openToken(t.ctx, pin, t.label)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.healthy = true
+	select {
+	case t.sessions <- *session:
+	default:
+		t.ctx.CloseSession(*session)
+	}
+	return nil
+}
+
+// onKeyReplicas performs op, which uses a key of the provider, like
+// onReplicas. On CloudHSM clusters, the key is looked up again while it is
+// not found, until the replication timeout expires, as the keys reach the
+// HSMs of the cluster, and the HSMs replacing others, after a while.
+func (csp *impl) onKeyReplicas(op func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error) error {
+	err := csp.onReplicas(op)
+	deadline := time.Now().Add(csp.replicationTimeout)
+	for isKeyNotFound(err) && time.Now().Before(deadline) {
+		time.Sleep(replicationRetryInterval)
+		err = csp.onReplicas(op)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConfigureTool writes a configure tool recording its arguments in the
+// returned file.
+func fakeConfigureTool(t *testing.T, dir string) (string, string) {
+	tool := filepath.Join(dir, "configure-pkcs11")
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" >> " + args + "\n"
+	require.NoError(t, ioutil.WriteFile(tool, []byte(script), 0755))
+	return tool, args
+}
+
+func TestCloudHSMOpts(t *testing.T) {
+	lib, pin, label := FindPKCS11Lib()
+	opts := PKCS11Opts{
+		HashFamily: "SHA2",
+		SecLevel:   256,
+		Library:    lib,
+		Label:      label,
+		Pin:        pin,
+		Replicas:   []ReplicaOpts{{Label: label}},
+		CloudHSM:   &CloudHSMOpts{},
+	}
+	_, err := New(opts, currentKS)
+	assert.EqualError(t, err, "replicas cannot be configured along with a CloudHSM cluster, which replicates the keys itself")
+
+	c := newCluster(&CloudHSMOpts{})
+	assert.Equal(t, defaultConfigureTool, c.configureTool)
+	assert.NoError(t, c.rotate())
+}
+
+func TestCloudHSMCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cloudhsm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tok := newToken("lib", "label", "pin")
+	pin, err := tok.credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "pin", pin)
+
+	file := filepath.Join(dir, "credentials")
+	tok.cluster = newCluster(&CloudHSMOpts{CredentialsFile: file})
+	_, err = tok.credentials()
+	assert.Contains(t, err.Error(), "failed reading the CloudHSM credentials")
+
+	// the rotated credentials are read again
+	require.NoError(t, ioutil.WriteFile(file, []byte("fabric:old\n"), 0600))
+	pin, err = tok.credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "fabric:old", pin)
+	require.NoError(t, ioutil.WriteFile(file, []byte("fabric:new\n"), 0600))
+	pin, err = tok.credentials()
+	require.NoError(t, err)
+	assert.Equal(t, "fabric:new", pin)
+}
+
+func TestCloudHSMEndpointRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cloudhsm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tool, args := fakeConfigureTool(t, dir)
+
+	c := newCluster(&CloudHSMOpts{Endpoints: []string{"10.0.1.10", "10.0.2.10"}, ConfigureTool: tool})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.rotate())
+	}
+	recorded, err := ioutil.ReadFile(args)
+	require.NoError(t, err)
+	assert.Equal(t, "-a 10.0.1.10\n-a 10.0.2.10\n-a 10.0.1.10\n", string(recorded))
+
+	c.configureTool = filepath.Join(dir, "missing")
+	err = c.rotate()
+	assert.Contains(t, err.Error(), "failed configuring the CloudHSM client with endpoint 10.0.2.10")
+}
+
+func TestCloudHSMRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestCloudHSMRecovery")
+	}
+	dir, err := ioutil.TempDir("", "cloudhsm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tool, args := fakeConfigureTool(t, dir)
+
+	lib, pin, label := FindPKCS11Lib()
+	credentials := filepath.Join(dir, "credentials")
+	require.NoError(t, ioutil.WriteFile(credentials, []byte(pin), 0600))
+	opts := PKCS11Opts{
+		HashFamily: "SHA2",
+		SecLevel:   256,
+		Library:    lib,
+		Label:      label,
+		CloudHSM: &CloudHSMOpts{
+			Endpoints:       []string{"10.0.1.10", "10.0.2.10"},
+			ConfigureTool:   tool,
+			CredentialsFile: credentials,
+		},
+	}
+	p11, err := New(opts, currentKS)
+	require.NoError(t, err)
+	csp := p11.(*impl)
+	assert.Equal(t, defaultReplicationTimeout, csp.replicationTimeout)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+
+	// The replacement of the HSM leaves the library uninitialized, so that
+	// the library is configured with the next endpoint and initialized again
+	tok := csp.tokens[0]
+	tok.ctx.Finalize()
+	signature, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.True(t, tok.isHealthy())
+	assert.Equal(t, 1, tok.currentGeneration())
+	recorded, err := ioutil.ReadFile(args)
+	require.NoError(t, err)
+	assert.Equal(t, "-a 10.0.1.10\n", string(recorded))
+
+	// A recovery is skipped once another one took place
+	assert.True(t, tok.recover(0))
+	assert.Equal(t, 1, tok.currentGeneration())
+
+	// Recovering fails with credentials which are not accepted anymore
+	require.NoError(t, ioutil.WriteFile(credentials, []byte("wrong"), 0600))
+	assert.False(t, tok.recover(1))
+	require.NoError(t, ioutil.WriteFile(credentials, []byte(pin), 0600))
+	tok.check()
+	assert.True(t, tok.isHealthy())
+}
+
+func TestCloudHSMReplicationTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestCloudHSMReplicationTimeout")
+	}
+	lib, pin, label := FindPKCS11Lib()
+	opts := PKCS11Opts{
+		HashFamily: "SHA2",
+		SecLevel:   256,
+		Library:    lib,
+		Label:      label,
+		Pin:        pin,
+		CloudHSM:   &CloudHSMOpts{ReplicationTimeout: time.Second},
+	}
+	csp, err := New(opts, currentKS)
+	require.NoError(t, err)
+
+	// The key is looked up again until the replication timeout expires
+	k := &ecdsaPrivateKey{ski: []byte("not yet replicated")}
+	digest := sha256.Sum256([]byte("message"))
+	start := time.Now()
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.True(t, isKeyNotFound(err))
+	assert.True(t, time.Since(start) >= time.Second)
+}
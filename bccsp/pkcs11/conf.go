@@ -12,6 +12,7 @@ import (
 	"encoding/asn1"
 	"fmt"
 	"hash"
+	"time"
 
 	"golang.org/x/crypto/sha3"
 )
@@ -81,4 +82,49 @@ type PKCS11Opts struct {
 	Pin        string `mapstructure:"pin" json:"pin"`
 	SoftVerify bool   `mapstructure:"softwareverify,omitempty" json:"softwareverify,omitempty"`
 	Immutable  bool   `mapstructure:"immutable,omitempty" json:"immutable,omitempty"`
+	// FIPSMode declares that the token operates in FIPS-approved mode, in
+	// which case signatures are never verified in software.
+	FIPSMode bool `mapstructure:"fipsmode,omitempty" json:"fipsmode,omitempty"`
+
+	// Replicas are tokens holding copies of the keys of the token above,
+	// to which the provider fails over when the token is unavailable.
+	Replicas []ReplicaOpts `mapstructure:"replicas,omitempty" json:"replicas,omitempty"`
+	// HealthCheckInterval is the interval at which the health of the tokens
+	// is checked when replicas are configured.
+	HealthCheckInterval time.Duration `mapstructure:"healthcheckinterval,omitempty" json:"healthcheckinterval,omitempty"`
+
+	// CloudHSM declares that the token is an AWS CloudHSM cluster, whose
+	// HSMs are replaced over time.
+	CloudHSM *CloudHSMOpts `mapstructure:"cloudhsm,omitempty" json:"cloudhsm,omitempty"`
+}
+
+// CloudHSMOpts configures the handling of the specifics of AWS CloudHSM
+// clusters. The cluster replicates the keys to its HSMs itself, so that
+// replicas are not configured along with it.
+type CloudHSMOpts struct {
+	// Endpoints are the addresses of the HSMs of the cluster. When the
+	// cluster becomes unavailable, the client library is configured with
+	// the next endpoint by ConfigureTool, and initialized again.
+	Endpoints []string `mapstructure:"endpoints,omitempty" json:"endpoints,omitempty"`
+	// ConfigureTool is the configure tool of the CloudHSM client, which
+	// defaults to /opt/cloudhsm/bin/configure-pkcs11.
+	ConfigureTool string `mapstructure:"configuretool,omitempty" json:"configuretool,omitempty"`
+	// CredentialsFile holds the credentials of the crypto user, as
+	// <user>:<password>, in place of the Pin. It is read again at each login,
+	// so that the rotated credentials are used.
+	CredentialsFile string `mapstructure:"credentialsfile,omitempty" json:"credentialsfile,omitempty"`
+	// ReplicationTimeout bounds how long a key of the provider is looked up
+	// again when it is not found, as keys reach the HSMs of the cluster, and
+	// the HSMs replacing others, after a while. It defaults to 5s.
+	ReplicationTimeout time.Duration `mapstructure:"replicationtimeout,omitempty" json:"replicationtimeout,omitempty"`
+}
+
+// ReplicaOpts identifies a replica token, either another slot of the
+// library of the PKCS11Opts or a slot of another library, such as the one of
+// another HSM endpoint. The library and the PIN default to the ones of the
+// PKCS11Opts.
+type ReplicaOpts struct {
+	Library string `mapstructure:"library,omitempty" json:"library,omitempty"`
+	Label   string `mapstructure:"label" json:"label"`
+	Pin     string `mapstructure:"pin,omitempty" json:"pin,omitempty"`
 }
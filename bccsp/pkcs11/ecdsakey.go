@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/utils"
 )
 
 type ecdsaPrivateKey struct {
@@ -58,6 +59,12 @@ func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
 	return &k.pub, nil
 }
 
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPrivateKey) Fingerprint() ([]byte, error) {
+	return k.pub.Fingerprint()
+}
+
 type ecdsaPublicKey struct {
 	ski []byte
 	pub *ecdsa.PublicKey
@@ -95,3 +102,9 @@ func (k *ecdsaPublicKey) Private() bool {
 func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
 	return k, nil
 }
+
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPublicKey) Fingerprint() ([]byte, error) {
+	return utils.SPKIFingerprint(k.pub)
+}
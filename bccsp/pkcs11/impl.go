@@ -10,11 +10,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/x509"
 	"os"
+	"time"
 
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/sw"
 	"github.com/hyperledger/fabric/common/flogging"
-	"github.com/miekg/pkcs11"
 	"github.com/pkg/errors"
 )
 
@@ -33,6 +33,14 @@ func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
 		return nil, errors.Wrapf(err, "Failed initializing configuration")
 	}
 
+	if opts.FIPSMode && opts.SoftVerify {
+		return nil, errors.New("software verification cannot be enabled in FIPS mode")
+	}
+
+	if opts.CloudHSM != nil && len(opts.Replicas) != 0 {
+		return nil, errors.New("replicas cannot be configured along with a CloudHSM cluster, which replicates the keys itself")
+	}
+
 	// Check KeyStore
 	if keyStore == nil {
 		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
@@ -43,18 +51,55 @@ func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
 		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
 	}
 
-	lib := opts.Library
-	pin := opts.Pin
-	label := opts.Label
-	ctx, slot, session, err := loadLib(lib, pin, label)
-	if err != nil {
+	tokens := []*token{newToken(opts.Library, opts.Label, opts.Pin)}
+	var replicationTimeout time.Duration
+	if opts.CloudHSM != nil {
+		tokens[0].cluster = newCluster(opts.CloudHSM)
+		replicationTimeout = opts.CloudHSM.ReplicationTimeout
+		if replicationTimeout == 0 {
+			replicationTimeout = defaultReplicationTimeout
+		}
+	}
+	for _, replica := range opts.Replicas {
+		lib, pin := replica.Library, replica.Pin
+		if lib == "" {
+			lib = opts.Library
+		}
+		if pin == "" {
+			pin = opts.Pin
+		}
+		tokens = append(tokens, newToken(lib, replica.Label, pin))
+	}
+
+	// The provider starts as long as one of the tokens is available, the
+	// others being loaded once they become available
+	var loaded bool
+	for _, t := range tokens {
+		err = t.load()
+		if err != nil && t.cluster != nil && t.recover(t.currentGeneration()) {
+			err = nil
+		}
+		if err != nil {
+			if len(tokens) > 1 {
+				logger.Warningf("Failed initializing PKCS11 library %s %s [%s]", t.lib, t.label, err)
+			}
+			continue
+		}
+		loaded = true
+	}
+	if !loaded {
 		return nil, errors.Wrapf(err, "Failed initializing PKCS11 library %s %s",
-			lib, label)
+			opts.Library, opts.Label)
 	}
 
-	sessions := make(chan pkcs11.SessionHandle, sessionCacheSize)
-	csp := &impl{swCSP, conf, ctx, sessions, slot, pin, lib, opts.SoftVerify, opts.Immutable}
-	csp.returnSession(*session)
+	csp := &impl{swCSP, conf, tokens, opts.SoftVerify, opts.Immutable, opts.FIPSMode, replicationTimeout}
+	if len(tokens) > 1 || opts.CloudHSM != nil {
+		interval := opts.HealthCheckInterval
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		go csp.checkHealth(interval)
+	}
 	return csp, nil
 }
 
@@ -63,15 +108,17 @@ type impl struct {
 
 	conf *config
 
-	ctx      *pkcs11.Ctx
-	sessions chan pkcs11.SessionHandle
-	slot     uint
-	pin      string
+	// tokens are the configured token followed by its replicas
+	tokens []*token
 
-	lib        string
 	softVerify bool
 	//Immutable flag makes object immutable
 	immutable bool
+	fipsMode  bool
+
+	// replicationTimeout bounds how long the keys of the provider are
+	// looked up again when not found, on CloudHSM clusters
+	replicationTimeout time.Duration
 }
 
 // KeyGen generates a key using opts.
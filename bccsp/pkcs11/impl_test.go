@@ -137,6 +137,49 @@ func TestNew(t *testing.T) {
 	_, err = New(opts, currentKS)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Failed initializing PKCS11 library")
+
+	// Test for software verification in FIPS mode
+	opts.Library = lib
+	opts.FIPSMode = true
+	opts.SoftVerify = true
+	_, err = New(opts, currentKS)
+	assert.EqualError(t, err, "software verification cannot be enabled in FIPS mode")
+}
+
+func TestStatus(t *testing.T) {
+	_, err := currentBCCSP.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	status, err := currentBCCSP.(bccsp.StatusReporter).Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "PKCS11", status.Provider)
+	assert.False(t, status.FIPSMode)
+	assert.True(t, status.Keys.Private > 0)
+	assert.True(t, status.Keys.Public > 0)
+	assert.Equal(t, sessionCacheSize, status.Sessions.Size)
+	assert.True(t, status.Sessions.Idle > 0)
+}
+
+func TestListAndDeleteKeys(t *testing.T) {
+	k, err := currentBCCSP.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	manager := currentBCCSP.(bccsp.KeyManager)
+	keys, err := manager.ListKeys()
+	assert.NoError(t, err)
+	var found bccsp.Key
+	for _, key := range keys {
+		if bytes.Equal(key.SKI(), k.SKI()) {
+			found = key
+		}
+	}
+	assert.NotNil(t, found)
+	assert.True(t, found.Private())
+
+	assert.NoError(t, manager.DeleteKey(k.SKI()))
+	_, _, err = currentBCCSP.(*impl).getECKey(k.SKI())
+	assert.Error(t, err)
+	assert.EqualError(t, manager.DeleteKey(k.SKI()), fmt.Sprintf("Key not found [%x]", k.SKI()))
 }
 
 func TestFindPKCS11LibEnvVars(t *testing.T) {
@@ -17,27 +17,38 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hyperledger/fabric/bccsp"
 	"github.com/miekg/pkcs11"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
 
 func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle, error) {
-	var slot uint
 	logger.Debugf("Loading pkcs11 library [%s]\n", lib)
 	if lib == "" {
-		return nil, slot, nil, fmt.Errorf("No PKCS11 library default")
+		return nil, 0, nil, fmt.Errorf("No PKCS11 library default")
 	}
 
 	ctx := pkcs11.New(lib)
 	if ctx == nil {
-		return nil, slot, nil, fmt.Errorf("Instantiate failed [%s]", lib)
+		return nil, 0, nil, fmt.Errorf("Instantiate failed [%s]", lib)
 	}
 
 	ctx.Initialize()
+	slot, session, err := openToken(ctx, pin, label)
+	if err != nil {
+		return nil, slot, nil, err
+	}
+	return ctx, slot, session, nil
+}
+
+// openToken looks up the slot of the token labeled label in the initialized
+// library, and opens a first session with the token
+func openToken(ctx *pkcs11.Ctx, pin, label string) (uint, *pkcs11.SessionHandle, error) {
+	var slot uint
 	slots, err := ctx.GetSlotList(true)
 	if err != nil {
-		return nil, slot, nil, fmt.Errorf("Could not get Slot List [%s]", err)
+		return slot, nil, fmt.Errorf("Could not get Slot List [%s]", err)
 	}
 	found := false
 	for _, s := range slots {
@@ -53,32 +64,42 @@ func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle,
 		}
 	}
 	if !found {
-		return nil, slot, nil, fmt.Errorf("could not find token with label %s", label)
+		return slot, nil, fmt.Errorf("could not find token with label %s", label)
 	}
 
 	session, err := createSession(ctx, slot, pin)
 	if err != nil {
-		return nil, slot, nil, err
+		return slot, nil, err
 	}
 
-	return ctx, slot, &session, nil
+	return slot, &session, nil
 }
 
-func (csp *impl) getSession() (session pkcs11.SessionHandle, err error) {
+func (t *token) getSession() (session pkcs11.SessionHandle, err error) {
+	ctx, slot, err := t.module()
+	if err != nil {
+		return 0, err
+	}
+
+	pin, err := t.credentials()
+	if err != nil {
+		return 0, err
+	}
+
 	select {
-	case session = <-csp.sessions:
-		_, err = csp.ctx.GetSessionInfo(session)
+	case session = <-t.sessions:
+		_, err = ctx.GetSessionInfo(session)
 		if err != nil {
 			logger.Warningf("Get session info failed [%s], closing existing session and getting a new session\n", err)
-			csp.ctx.CloseSession(session)
-			session, err = createSession(csp.ctx, csp.slot, csp.pin)
+			ctx.CloseSession(session)
+			session, err = createSession(ctx, slot, pin)
 		} else {
-			logger.Debugf("Reusing existing pkcs11 session %+v on slot %d\n", session, csp.slot)
+			logger.Debugf("Reusing existing pkcs11 session %+v on slot %d\n", session, slot)
 		}
 
 	default:
 		// cache is empty (or completely in use), create a new session
-		session, err = createSession(csp.ctx, csp.slot, csp.pin)
+		session, err = createSession(ctx, slot, pin)
 	}
 	return session, err
 }
@@ -109,24 +130,29 @@ func createSession(ctx *pkcs11.Ctx, slot uint, pin string) (pkcs11.SessionHandle
 	return session, nil
 }
 
-func (csp *impl) returnSession(session pkcs11.SessionHandle) {
+func (t *token) returnSession(session pkcs11.SessionHandle) {
 	select {
-	case csp.sessions <- session:
+	case t.sessions <- session:
 		// returned session back to session cache
 	default:
 		// have plenty of sessions in cache, dropping
-		csp.ctx.CloseSession(session)
+		ctx, _, err := t.module()
+		if err == nil {
+			ctx.CloseSession(session)
+		}
 	}
 }
 
-// Look for an EC key by SKI, stored in CKA_ID
+// Look for an EC key by SKI, stored in CKA_ID, on the token and its replicas
 func (csp *impl) getECKey(ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err error) {
-	p11lib := csp.ctx
-	session, err := csp.getSession()
-	if err != nil {
-		return nil, false, err
-	}
-	defer csp.returnSession(session)
+	err = csp.onReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		pubKey, isPriv, err = getECKey(p11lib, session, ski)
+		return err
+	})
+	return pubKey, isPriv, err
+}
+
+func getECKey(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err error) {
 	isPriv = true
 	_, err = findKeyPairFromSKI(p11lib, session, ski, privateKeyType)
 	if err != nil {
@@ -136,7 +162,7 @@ func (csp *impl) getECKey(ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err
 
 	publicKey, err := findKeyPairFromSKI(p11lib, session, ski, publicKeyType)
 	if err != nil {
-		return nil, false, fmt.Errorf("Public key not found [%s] for SKI [%s]", err, hex.EncodeToString(ski))
+		return nil, false, errors.WithMessagef(err, "Public key not found for SKI [%s]", hex.EncodeToString(ski))
 	}
 
 	ecpt, marshaledOid, err := ecPoint(p11lib, session, *publicKey)
@@ -199,14 +225,17 @@ func namedCurveFromOID(oid asn1.ObjectIdentifier) elliptic.Curve {
 	return nil
 }
 
+// generateECKey generates a key pair on the first available token, whose
+// replicas are expected to be kept in sync by the HSM
 func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski []byte, pubKey *ecdsa.PublicKey, err error) {
-	p11lib := csp.ctx
-	session, err := csp.getSession()
-	if err != nil {
-		return nil, nil, err
-	}
-	defer csp.returnSession(session)
+	err = csp.onReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		ski, pubKey, err = csp.generateECKeyWith(p11lib, session, curve, ephemeral)
+		return err
+	})
+	return ski, pubKey, err
+}
 
+func (csp *impl) generateECKeyWith(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle, curve asn1.ObjectIdentifier, ephemeral bool) (ski []byte, pubKey *ecdsa.PublicKey, err error) {
 	id := nextIDCtr()
 	publabel := fmt.Sprintf("BCPUB%s", id.Text(16))
 	prvlabel := fmt.Sprintf("BCPRV%s", id.Text(16))
@@ -246,7 +275,7 @@ func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski
 		pubkeyT, prvkeyT)
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("P11: keypair generate failed [%s]", err)
+		return nil, nil, errors.Wrap(err, "P11: keypair generate failed")
 	}
 
 	ecpt, _, err := ecPoint(p11lib, session, pub)
@@ -318,29 +347,28 @@ func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski
 	return ski, pubGoKey, nil
 }
 
+// signP11ECDSA signs with the first available token holding the private key
 func (csp *impl) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error) {
-	p11lib := csp.ctx
-	session, err := csp.getSession()
-	if err != nil {
-		return nil, nil, err
-	}
-	defer csp.returnSession(session)
-
-	privateKey, err := findKeyPairFromSKI(p11lib, session, ski, privateKeyType)
-	if err != nil {
-		return nil, nil, fmt.Errorf("Private key not found [%s]", err)
-	}
-
-	err = p11lib.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, *privateKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("Sign-initialize  failed [%s]", err)
-	}
-
 	var sig []byte
+	err = csp.onKeyReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		privateKey, err := findKeyPairFromSKI(p11lib, session, ski, privateKeyType)
+		if err != nil {
+			return errors.WithMessage(err, "Private key not found")
+		}
 
-	sig, err = p11lib.Sign(session, msg)
+		err = p11lib.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, *privateKey)
+		if err != nil {
+			return errors.Wrap(err, "Sign-initialize failed")
+		}
+
+		sig, err = p11lib.Sign(session, msg)
+		if err != nil {
+			return errors.Wrap(err, "P11: sign failed")
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("P11: sign failed [%s]", err)
+		return nil, nil, err
 	}
 
 	R = new(big.Int)
@@ -351,21 +379,10 @@ func (csp *impl) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error)
 	return R, S, nil
 }
 
+// verifyP11ECDSA verifies with the first available token holding the public key
 func (csp *impl) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize int) (bool, error) {
-	p11lib := csp.ctx
-	session, err := csp.getSession()
-	if err != nil {
-		return false, err
-	}
-	defer csp.returnSession(session)
-
 	logger.Debugf("Verify ECDSA\n")
 
-	publicKey, err := findKeyPairFromSKI(p11lib, session, ski, publicKeyType)
-	if err != nil {
-		return false, fmt.Errorf("Public key not found [%s]", err)
-	}
-
 	r := R.Bytes()
 	s := S.Bytes()
 
@@ -374,20 +391,33 @@ func (csp *impl) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize
 	copy(sig[byteSize-len(r):byteSize], r)
 	copy(sig[2*byteSize-len(s):], s)
 
-	err = p11lib.VerifyInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)},
-		*publicKey)
-	if err != nil {
-		return false, fmt.Errorf("PKCS11: Verify-initialize [%s]", err)
-	}
-	err = p11lib.Verify(session, msg, sig)
-	if err == pkcs11.Error(pkcs11.CKR_SIGNATURE_INVALID) {
-		return false, nil
-	}
+	valid := false
+	err := csp.onKeyReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		publicKey, err := findKeyPairFromSKI(p11lib, session, ski, publicKeyType)
+		if err != nil {
+			return errors.WithMessage(err, "Public key not found")
+		}
+
+		err = p11lib.VerifyInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)},
+			*publicKey)
+		if err != nil {
+			return errors.Wrap(err, "PKCS11: Verify-initialize")
+		}
+		err = p11lib.Verify(session, msg, sig)
+		if err == pkcs11.Error(pkcs11.CKR_SIGNATURE_INVALID) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "PKCS11: Verify failed")
+		}
+		valid = true
+		return nil
+	})
 	if err != nil {
-		return false, fmt.Errorf("PKCS11: Verify failed [%s]", err)
+		return false, err
 	}
 
-	return true, nil
+	return valid, nil
 }
 
 type keyType int8
@@ -397,6 +427,194 @@ const (
 	privateKeyType
 )
 
+// Status checks that a session can be opened with one of the tokens, and
+// reports the keys held by the token, the state of the session pools and
+// the health of the replicas.
+func (csp *impl) Status() (*bccsp.Status, error) {
+	status := &bccsp.Status{
+		Provider: "PKCS11",
+		FIPSMode: csp.fipsMode,
+		Sessions: &bccsp.SessionPoolStatus{},
+	}
+	for _, t := range csp.tokens {
+		status.Sessions.Idle += len(t.sessions)
+		status.Sessions.Size += cap(t.sessions)
+	}
+
+	err := csp.onReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		for _, class := range []struct {
+			class uint
+			count *int
+		}{
+			{pkcs11.CKO_PRIVATE_KEY, &status.Keys.Private},
+			{pkcs11.CKO_PUBLIC_KEY, &status.Keys.Public},
+			{pkcs11.CKO_SECRET_KEY, &status.Keys.Symmetric},
+		} {
+			count, err := countObjects(p11lib, session, class.class)
+			if err != nil {
+				return errors.WithMessage(err, "failed counting keys")
+			}
+			*class.count = count
+		}
+		return nil
+	})
+
+	if len(csp.tokens) > 1 {
+		for _, t := range csp.tokens {
+			status.Replicas = append(status.Replicas, bccsp.ReplicaStatus{
+				Library: t.lib,
+				Label:   t.label,
+				Healthy: t.isHealthy(),
+			})
+		}
+	}
+
+	return status, err
+}
+
+func countObjects(mod *pkcs11.Ctx, session pkcs11.SessionHandle, class uint) (int, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+	}
+	objs, err := findObjects(mod, session, template)
+	if err != nil {
+		return 0, err
+	}
+	return len(objs), nil
+}
+
+// ListKeys returns the EC keys held by the first available token,
+// identified by the CKA_ID of their public key.
+func (csp *impl) ListKeys() ([]bccsp.Key, error) {
+	var ids [][]byte
+	err := csp.onReplicas(func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		var err error
+		ids, err = objectIDs(p11lib, session, pkcs11.CKO_PUBLIC_KEY)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed listing keys")
+	}
+
+	var keys []bccsp.Key
+	for _, ski := range ids {
+		pubKey, isPriv, err := csp.getECKey(ski)
+		if err != nil {
+			logger.Debugf("Skipping key [%x]: %s", ski, err)
+			continue
+		}
+		if isPriv {
+			keys = append(keys, &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pubKey}})
+			continue
+		}
+		keys = append(keys, &ecdsaPublicKey{ski, pubKey})
+	}
+	return keys, nil
+}
+
+// DeleteKey destroys the objects whose CKA_ID is the SKI passed, from the
+// token and from the replicas that are available.
+func (csp *impl) DeleteKey(ski []byte) error {
+	if len(ski) == 0 {
+		return errors.New("invalid SKI. Cannot be of zero length")
+	}
+
+	var unavailableErr error
+	found := false
+	for _, t := range csp.tokens {
+		deleted, err := t.deleteKey(ski)
+		if unavailable(err) {
+			logger.Warningf("Failed deleting key [%x] from token %s [%s]", ski, t.label, err)
+			t.setHealthy(false, err)
+			unavailableErr = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = found || deleted
+	}
+	if !found && unavailableErr != nil {
+		return unavailableErr
+	}
+	if !found {
+		return errors.Errorf("Key not found [%x]", ski)
+	}
+	return nil
+}
+
+// deleteKey destroys the objects of the token whose CKA_ID is the SKI
+// passed, and returns whether there were any.
+func (t *token) deleteKey(ski []byte) (bool, error) {
+	session, err := t.getSession()
+	if err != nil {
+		return false, errors.WithMessage(err, "failed opening session")
+	}
+	defer t.returnSession(session)
+	p11lib, _, err := t.module()
+	if err != nil {
+		return false, err
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+	}
+	objs, err := findObjects(p11lib, session, template)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed looking up key [%x]", ski)
+	}
+	for _, obj := range objs {
+		if err := p11lib.DestroyObject(session, obj); err != nil {
+			return false, errors.Wrapf(err, "failed destroying key [%x]", ski)
+		}
+	}
+	return len(objs) != 0, nil
+}
+
+func findObjects(mod *pkcs11.Ctx, session pkcs11.SessionHandle, template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := mod.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	defer mod.FindObjectsFinal(session)
+
+	var handles []pkcs11.ObjectHandle
+	for {
+		objs, _, err := mod.FindObjects(session, 100)
+		if err != nil {
+			return nil, err
+		}
+		if len(objs) == 0 {
+			return handles, nil
+		}
+		handles = append(handles, objs...)
+	}
+}
+
+func objectIDs(mod *pkcs11.Ctx, session pkcs11.SessionHandle, class uint) ([][]byte, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+	}
+	objs, err := findObjects(mod, session, template)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids [][]byte
+	for _, obj := range objs {
+		attrs, err := mod.GetAttributeValue(session, obj, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, nil)})
+		if err != nil {
+			return nil, err
+		}
+		if len(attrs) != 0 && len(attrs[0].Value) != 0 {
+			ids = append(ids, attrs[0].Value)
+		}
+	}
+	return ids, nil
+}
+
 func findKeyPairFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byte, keyType keyType) (*pkcs11.ObjectHandle, error) {
 	ktype := pkcs11.CKO_PUBLIC_KEY
 	if keyType == privateKeyType {
@@ -421,7 +639,7 @@ func findKeyPairFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byt
 	}
 
 	if len(objs) == 0 {
-		return nil, fmt.Errorf("Key not found [%s]", hex.Dump(ski))
+		return nil, &keyNotFoundError{ski: ski}
 	}
 
 	return &objs[0], nil
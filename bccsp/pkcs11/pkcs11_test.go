@@ -88,38 +88,39 @@ func TestPKCS11GetSession(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping TestPKCS11GetSession")
 	}
+	tok := currentBCCSP.(*impl).tokens[0]
 	var sessions []pkcs11.SessionHandle
 	for i := 0; i < 3*sessionCacheSize; i++ {
-		session, err := currentBCCSP.(*impl).getSession()
+		session, err := tok.getSession()
 		assert.NoError(t, err)
 		sessions = append(sessions, session)
 	}
 
 	// Return all sessions, should leave sessionCacheSize cached
 	for _, session := range sessions {
-		currentBCCSP.(*impl).returnSession(session)
+		tok.returnSession(session)
 	}
 	sessions = nil
 
 	// Lets break OpenSession, so non-cached session cannot be opened
-	oldSlot := currentBCCSP.(*impl).slot
-	currentBCCSP.(*impl).slot = ^uint(0)
+	oldSlot := tok.slot
+	tok.slot = ^uint(0)
 
 	// Should be able to get sessionCacheSize cached sessions
 	for i := 0; i < sessionCacheSize; i++ {
-		session, err := currentBCCSP.(*impl).getSession()
+		session, err := tok.getSession()
 		assert.NoError(t, err)
 		sessions = append(sessions, session)
 	}
 
-	_, err := currentBCCSP.(*impl).getSession()
+	_, err := tok.getSession()
 	assert.EqualError(t, err, "OpenSession failed: pkcs11: 0x3: CKR_SLOT_ID_INVALID")
 
 	// Cleanup
 	for _, session := range sessions {
-		currentBCCSP.(*impl).returnSession(session)
+		tok.returnSession(session)
 	}
-	currentBCCSP.(*impl).slot = oldSlot
+	tok.slot = oldSlot
 }
 
 func TestPKCS11ECKeySignVerify(t *testing.T) {
@@ -0,0 +1,256 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// defaultHealthCheckInterval is the interval at which the health of the
+// tokens is checked when it is not configured
+const defaultHealthCheckInterval = 10 * time.Second
+
+// token is a token of a PKCS#11 library holding the keys of the provider,
+// either the configured one or one of its replicas
+type token struct {
+	lib   string
+	label string
+	pin   string
+
+	// cluster is the CloudHSM cluster the token is, if any
+	cluster *cluster
+
+	sessions chan pkcs11.SessionHandle
+
+	mutex   sync.RWMutex
+	ctx     *pkcs11.Ctx
+	slot    uint
+	healthy bool
+	// generation counts the initializations of the library of the token
+	generation int
+}
+
+func newToken(lib, label, pin string) *token {
+	return &token{
+		lib:      lib,
+		label:    label,
+		pin:      pin,
+		sessions: make(chan pkcs11.SessionHandle, sessionCacheSize),
+	}
+}
+
+// load initializes the library of the token and opens a first session
+// with the token, unless already done
+func (t *token) load() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.ctx != nil {
+		return nil
+	}
+	return t.initialize()
+}
+
+// module returns the library of the token and the slot the token is in,
+// loading the library if needed
+func (t *token) module() (*pkcs11.Ctx, uint, error) {
+	t.mutex.RLock()
+	ctx, slot := t.ctx, t.slot
+	t.mutex.RUnlock()
+	if ctx != nil {
+		return ctx, slot, nil
+	}
+
+	if err := t.load(); err != nil {
+		return nil, 0, err
+	}
+	return t.module()
+}
+
+func (t *token) isHealthy() bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.healthy
+}
+
+// setHealthy records the health of the token. The sessions cached for a
+// token that becomes unhealthy are closed, as they may not survive the
+// failure of the token.
+func (t *token) setHealthy(healthy bool, cause error) {
+	t.mutex.Lock()
+	changed := t.healthy != healthy
+	t.healthy = healthy
+	ctx := t.ctx
+	t.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		logger.Infof("PKCS11 token %s of library %s is available again", t.label, t.lib)
+		return
+	}
+
+	logger.Warningf("PKCS11 token %s of library %s is unavailable [%s]", t.label, t.lib, cause)
+	for {
+		select {
+		case session := <-t.sessions:
+			if ctx != nil {
+				ctx.CloseSession(session)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// check checks that a session can be opened with the token and the token
+// is still present in its slot, and records the health of the token.
+func (t *token) check() {
+	gen := t.currentGeneration()
+	defer func() {
+		if !t.isHealthy() && t.cluster != nil {
+			t.recover(gen)
+		}
+	}()
+
+	session, err := t.getSession()
+	if err == nil {
+		var ctx *pkcs11.Ctx
+		var slot uint
+		ctx, slot, err = t.module()
+		if err == nil {
+			_, err = ctx.GetTokenInfo(slot)
+		}
+		t.returnSession(session)
+	}
+	t.setHealthy(err == nil, err)
+}
+
+// unavailable returns whether the error reports the failure of the token
+// itself rather than the failure of the operation performed with it.
+func unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	p11err, ok := errors.Cause(err).(pkcs11.Error)
+	if !ok {
+		return false
+	}
+	switch p11err {
+	case pkcs11.CKR_GENERAL_ERROR,
+		pkcs11.CKR_DEVICE_ERROR,
+		pkcs11.CKR_DEVICE_MEMORY,
+		pkcs11.CKR_DEVICE_REMOVED,
+		pkcs11.CKR_SESSION_CLOSED,
+		pkcs11.CKR_SESSION_HANDLE_INVALID,
+		pkcs11.CKR_SLOT_ID_INVALID,
+		pkcs11.CKR_TOKEN_NOT_PRESENT,
+		pkcs11.CKR_TOKEN_NOT_RECOGNIZED,
+		pkcs11.CKR_USER_NOT_LOGGED_IN,
+		pkcs11.CKR_CRYPTOKI_NOT_INITIALIZED:
+		return true
+	}
+	return false
+}
+
+// keyNotFoundError reports that a token does not hold the key looked up,
+// which one of its replicas may hold
+type keyNotFoundError struct {
+	ski []byte
+}
+
+func (e *keyNotFoundError) Error() string {
+	return fmt.Sprintf("Key not found [%s]", hex.Dump(e.ski))
+}
+
+func isKeyNotFound(err error) bool {
+	_, ok := errors.Cause(err).(*keyNotFoundError)
+	return ok
+}
+
+// replicas returns the tokens in the order they are tried: the healthy
+// ones first, in the order of the configuration, then the unhealthy ones,
+// which may have recovered since their health was last checked.
+func (csp *impl) replicas() []*token {
+	tokens := make([]*token, 0, len(csp.tokens))
+	for _, t := range csp.tokens {
+		if t.isHealthy() {
+			tokens = append(tokens, t)
+		}
+	}
+	for _, t := range csp.tokens {
+		if !t.isHealthy() {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// onReplicas performs op with a session of the first token that is able to
+// complete it. A token that turns out to be unavailable is marked
+// unhealthy, or recovered and tried again when it is a CloudHSM cluster, and
+// a token that does not hold the key op looks up is skipped, op being then
+// performed with the next token.
+func (csp *impl) onReplicas(op func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error) error {
+	var err error
+	for _, t := range csp.replicas() {
+		gen := t.currentGeneration()
+		var available bool
+		available, err = t.perform(op)
+		if !available && t.cluster != nil && t.recover(gen) {
+			available, err = t.perform(op)
+		}
+		if !available {
+			continue
+		}
+		if !isKeyNotFound(err) {
+			return err
+		}
+		logger.Debugf("%s on token %s, looking up replicas", err, t.label)
+	}
+	return err
+}
+
+// perform performs op with a session of the token, and returns whether the
+// token was available, along with the error of op.
+func (t *token) perform(op func(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle) error) (bool, error) {
+	session, err := t.getSession()
+	if err != nil {
+		t.setHealthy(false, err)
+		return false, err
+	}
+	p11lib, _, _ := t.module()
+
+	err = op(p11lib, session)
+	if unavailable(err) {
+		p11lib.CloseSession(session)
+		t.setHealthy(false, err)
+		return false, err
+	}
+	t.returnSession(session)
+	t.setHealthy(true, nil)
+	return true, err
+}
+
+// checkHealth periodically checks the health of the tokens, so that
+// operations fail over to the replicas without first waiting on an
+// unavailable token, and return to the tokens that recovered.
+func (csp *impl) checkHealth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, t := range csp.tokens {
+			t.check()
+		}
+	}
+}
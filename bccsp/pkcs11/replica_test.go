@@ -0,0 +1,121 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnavailable(t *testing.T) {
+	assert.False(t, unavailable(nil))
+	assert.False(t, unavailable(errors.New("Key not found")))
+	assert.False(t, unavailable(pkcs11.Error(pkcs11.CKR_SIGNATURE_INVALID)))
+	assert.True(t, unavailable(pkcs11.Error(pkcs11.CKR_DEVICE_REMOVED)))
+	assert.True(t, unavailable(errors.Wrap(pkcs11.Error(pkcs11.CKR_TOKEN_NOT_PRESENT), "P11: sign failed")))
+
+	assert.True(t, isKeyNotFound(errors.WithMessage(&keyNotFoundError{ski: []byte{1}}, "Private key not found")))
+	assert.False(t, isKeyNotFound(pkcs11.Error(pkcs11.CKR_DEVICE_ERROR)))
+}
+
+func newReplicatedBCCSP(t *testing.T, replicas ...ReplicaOpts) *impl {
+	lib, pin, label := FindPKCS11Lib()
+	opts := PKCS11Opts{
+		HashFamily: "SHA2",
+		SecLevel:   256,
+		Library:    lib,
+		Label:      label,
+		Pin:        pin,
+		Replicas:   replicas,
+	}
+	csp, err := New(opts, currentKS)
+	assert.NoError(t, err)
+	return csp.(*impl)
+}
+
+func TestReplicaFailover(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestReplicaFailover")
+	}
+	_, _, label := FindPKCS11Lib()
+	csp := newReplicatedBCCSP(t, ReplicaOpts{Label: label})
+	assert.Len(t, csp.tokens, 2)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+
+	// Break the token, so that the operations fail over to its replica
+	primary := csp.tokens[0]
+	oldSlot := primary.slot
+	primary.slot = ^uint(0)
+	primary.setHealthy(false, errors.New("device removed"))
+	assert.Empty(t, primary.sessions)
+
+	signature, err := csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	_, err = csp.GetKey(k.SKI())
+	assert.NoError(t, err)
+	assert.False(t, primary.isHealthy())
+	assert.True(t, csp.tokens[1].isHealthy())
+
+	status, err := csp.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, []bccsp.ReplicaStatus{
+		{Library: primary.lib, Label: label, Healthy: false},
+		{Library: primary.lib, Label: label, Healthy: true},
+	}, status.Replicas)
+
+	// Repair the token, which the health check finds available again
+	primary.slot = oldSlot
+	primary.check()
+	assert.True(t, primary.isHealthy())
+}
+
+func TestReplicaUnavailableAtStartup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestReplicaUnavailableAtStartup")
+	}
+	lib, pin, label := FindPKCS11Lib()
+
+	csp := newReplicatedBCCSP(t, ReplicaOpts{Label: "badLabel"})
+	assert.False(t, csp.tokens[1].isHealthy())
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	// The replica is used when the configured token is unavailable
+	opts := PKCS11Opts{
+		HashFamily: "SHA2",
+		SecLevel:   256,
+		Library:    lib,
+		Label:      "badLabel",
+		Pin:        pin,
+		Replicas:   []ReplicaOpts{{Label: label}},
+	}
+	replicated, err := New(opts, currentKS)
+	assert.NoError(t, err)
+	_, err = replicated.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	opts.Replicas = []ReplicaOpts{{Label: "otherBadLabel"}}
+	_, err = New(opts, currentKS)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed initializing PKCS11 library")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+// Status describes the state of a BCCSP provider.
+type Status struct {
+	// Provider is the name of the provider, such as SW or PKCS11.
+	Provider string `json:"provider"`
+	// FIPSMode reports whether the cryptographic operations of the provider
+	// are performed by a module operating in FIPS-approved mode.
+	FIPSMode bool `json:"fipsMode"`
+	// Keys counts the keys held by the key store of the provider.
+	Keys KeyCounts `json:"keys"`
+	// Sessions describes the session pool of hardware providers.
+	Sessions *SessionPoolStatus `json:"sessions,omitempty"`
+	// Replicas reports the health of the tokens of hardware providers
+	// configured with replicas.
+	Replicas []ReplicaStatus `json:"replicas,omitempty"`
+	// ExpiringKeys are the lifetimes of the keys about to expire, or expired,
+	// for providers whose keys carry a lifetime.
+	ExpiringKeys []KeyLifetime `json:"expiringKeys,omitempty"`
+}
+
+// KeyCounts counts the keys of a key store by type.
+type KeyCounts struct {
+	Private   int `json:"private"`
+	Public    int `json:"public"`
+	Symmetric int `json:"symmetric"`
+}
+
+// SessionPoolStatus describes the pool of sessions a provider keeps open
+// with a hardware security module.
+type SessionPoolStatus struct {
+	// Idle is the number of open sessions waiting in the pool.
+	Idle int `json:"idle"`
+	// Size is the maximum number of sessions kept in the pool.
+	Size int `json:"size"`
+}
+
+// ReplicaStatus reports the health of a token holding the keys of a
+// hardware provider.
+type ReplicaStatus struct {
+	// Library is the path of the library of the token.
+	Library string `json:"library"`
+	// Label is the label of the token.
+	Label string `json:"label"`
+	// Healthy reports whether the token was available when last used or
+	// checked.
+	Healthy bool `json:"healthy"`
+}
+
+// StatusReporter is implemented by the BCCSP providers that report their
+// status.
+type StatusReporter interface {
+	// Status returns the status of the provider, or an error if the provider
+	// is unable to perform cryptographic operations.
+	Status() (*Status, error)
+}
+
+// KeyCounter is implemented by the key stores that count the keys they hold.
+type KeyCounter interface {
+	// KeyCounts counts the keys held by the key store.
+	KeyCounts() (KeyCounts, error)
+}
@@ -180,6 +180,42 @@ func AESCBCPKCS7Decrypt(key, src []byte) ([]byte, error) {
 	return nil, err
 }
 
+// AESGCMEncrypt encrypts and authenticates src, along with the additional
+// data, in GCM mode. The returned ciphertext is prefixed with the nonce.
+func AESGCMEncrypt(key, src, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GetRandomBytes(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, src, additionalData), nil
+}
+
+// AESGCMDecrypt authenticates and decrypts src, produced by AESGCMEncrypt
+// with the same additional data.
+func AESGCMDecrypt(key, src, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("Invalid ciphertext. It is shorter than the nonce and the tag")
+	}
+	nonce, ciphertext := src[:gcm.NonceSize()], src[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 type aescbcpkcs7Encryptor struct{}
 
 func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
@@ -202,6 +238,10 @@ func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp
 		return AESCBCPKCS7Encrypt(k.(*aesPrivateKey).privKey, plaintext)
 	case bccsp.AESCBCPKCS7ModeOpts:
 		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.AESGCMModeOpts:
+		return AESGCMEncrypt(k.(*aesPrivateKey).privKey, plaintext, o.AdditionalData)
+	case bccsp.AESGCMModeOpts:
+		return e.Encrypt(k, plaintext, &o)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
@@ -211,10 +251,14 @@ type aescbcpkcs7Decryptor struct{}
 
 func (*aescbcpkcs7Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
 	// check for mode
-	switch opts.(type) {
+	switch o := opts.(type) {
 	case *bccsp.AESCBCPKCS7ModeOpts, bccsp.AESCBCPKCS7ModeOpts:
 		// AES in CBC mode with PKCS7 padding
 		return AESCBCPKCS7Decrypt(k.(*aesPrivateKey).privKey, ciphertext)
+	case *bccsp.AESGCMModeOpts:
+		return AESGCMDecrypt(k.(*aesPrivateKey).privKey, ciphertext, o.AdditionalData)
+	case bccsp.AESGCMModeOpts:
+		return AESGCMDecrypt(k.(*aesPrivateKey).privKey, ciphertext, o.AdditionalData)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
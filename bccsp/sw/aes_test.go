@@ -676,3 +676,40 @@ func TestAESCBCPKCS7EncryptorWithRandSameCiphertext(t *testing.T) {
 
 	assert.Equal(t, ct, ct2)
 }
+
+func TestAESGCMEncryptorDecrypt(t *testing.T) {
+	t.Parallel()
+
+	raw, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+
+	k := &aesPrivateKey{privKey: raw, exportable: false}
+
+	msg := []byte("Hello World")
+	ad := []byte("additional data")
+	encryptor := &aescbcpkcs7Encryptor{}
+	decryptor := &aescbcpkcs7Decryptor{}
+
+	ct, err := encryptor.Encrypt(k, msg, &bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.NoError(t, err)
+	ct2, err := encryptor.Encrypt(k, msg, bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.NoError(t, err)
+	assert.NotEqual(t, ct, ct2)
+
+	msg2, err := decryptor.Decrypt(k, ct, &bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, msg2)
+	msg2, err = decryptor.Decrypt(k, ct2, bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, msg2)
+
+	_, err = decryptor.Decrypt(k, ct, &bccsp.AESGCMModeOpts{AdditionalData: []byte("other data")})
+	assert.Error(t, err)
+
+	ct[len(ct)-1] ^= 1
+	_, err = decryptor.Decrypt(k, ct, &bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.Error(t, err)
+
+	_, err = decryptor.Decrypt(k, ct[:10], &bccsp.AESGCMModeOpts{AdditionalData: ad})
+	assert.EqualError(t, err, "Invalid ciphertext. It is shorter than the nonce and the tag")
+}
@@ -0,0 +1,458 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+const (
+	backupVersion    = 1
+	backupKDF        = "PBKDF2-HMAC-SHA256"
+	backupAlgorithm  = "AES-256-GCM"
+	backupIterations = 200000
+	backupSaltSize   = 16
+
+	// maxBackupIterations bounds the work of the key derivation on restore
+	maxBackupIterations = 10000000
+)
+
+// backupHeader describes the encryption of a backup. It is authenticated
+// along with the content of the backup.
+type backupHeader struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Algorithm  string `json:"algorithm"`
+}
+
+// backupArchive is the encoded backup: its header, and its encrypted content.
+type backupArchive struct {
+	backupHeader
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// backupContent is the content of a backup: the signed manifest, and the
+// encoded keys it describes.
+type backupContent struct {
+	Manifest *bccsp.BackupManifest `json:"manifest"`
+	Keys     []backupKey           `json:"keys"`
+}
+
+// backupKey is an encoded key. A private key and its public key share their
+// SKI, and are told apart by their type.
+type backupKey struct {
+	SKI      []byte `json:"ski"`
+	Type     string `json:"type"`
+	Material []byte `json:"material"`
+}
+
+// ExportBackup writes the keys of the KeyStore of the CSP to w, in an archive
+// encrypted with a key derived from the passphrase of the options. The
+// manifest of the archive lists the keys along with the digests of their
+// encoding, and is signed with the signer of the options, a private key of
+// the CSP.
+func (csp *CSP) ExportBackup(w io.Writer, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	if opts == nil || len(opts.Passphrase) == 0 {
+		return nil, errors.New("a passphrase is required to export a backup")
+	}
+	if opts.Signer == nil || !opts.Signer.Private() {
+		return nil, errors.New("a private key is required to sign the manifest of a backup")
+	}
+	keys, err := csp.ListKeys()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed listing keys")
+	}
+
+	content := &backupContent{Manifest: &bccsp.BackupManifest{Created: time.Now().UTC()}}
+	for _, k := range keys {
+		entry, material, err := encodeBackupKey(k)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed exporting key %x", k.SKI())
+		}
+		content.Manifest.Keys = append(content.Manifest.Keys, *entry)
+		content.Keys = append(content.Keys, backupKey{SKI: k.SKI(), Type: entry.Type, Material: material})
+	}
+	sort.Slice(content.Manifest.Keys, func(i, j int) bool {
+		a, b := content.Manifest.Keys[i], content.Manifest.Keys[j]
+		return backupKeyID(a.SKI, a.Type) < backupKeyID(b.SKI, b.Type)
+	})
+	sort.Slice(content.Keys, func(i, j int) bool {
+		a, b := content.Keys[i], content.Keys[j]
+		return backupKeyID(a.SKI, a.Type) < backupKeyID(b.SKI, b.Type)
+	})
+
+	if err := csp.signManifest(content.Manifest, opts.Signer); err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed encoding backup")
+	}
+	defer zeroize(plaintext)
+
+	archive := &backupArchive{backupHeader: backupHeader{
+		Version:    backupVersion,
+		KDF:        backupKDF,
+		Salt:       make([]byte, backupSaltSize),
+		Iterations: backupIterations,
+		Algorithm:  backupAlgorithm,
+	}}
+	if _, err := rand.Read(archive.Salt); err != nil {
+		return nil, errors.Wrap(err, "failed generating salt")
+	}
+	gcm, err := archive.cipher(opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	archive.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(archive.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating nonce")
+	}
+	archive.Ciphertext = gcm.Seal(nil, archive.Nonce, plaintext, archive.additionalData())
+
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		return nil, errors.Wrap(err, "failed writing backup")
+	}
+	return content.Manifest, nil
+}
+
+// ImportBackup decrypts the archive read from r with the passphrase of the
+// options, verifies the signature of its manifest and the digests of its
+// keys, and only then stores the keys to the KeyStore of the CSP. Keys
+// already held by the KeyStore are skipped.
+func (csp *CSP) ImportBackup(r io.Reader, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	if opts == nil || len(opts.Passphrase) == 0 {
+		return nil, errors.New("a passphrase is required to import a backup")
+	}
+	if csp.ks.ReadOnly() {
+		return nil, errors.New("cannot import a backup to a read only KeyStore")
+	}
+
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading backup")
+	}
+	archive := &backupArchive{}
+	if err := json.Unmarshal(raw, archive); err != nil {
+		return nil, errors.Wrap(err, "invalid backup")
+	}
+	switch {
+	case archive.Version != backupVersion:
+		return nil, errors.Errorf("unsupported backup version %d", archive.Version)
+	case archive.KDF != backupKDF:
+		return nil, errors.Errorf("unsupported key derivation function %s", archive.KDF)
+	case archive.Algorithm != backupAlgorithm:
+		return nil, errors.Errorf("unsupported encryption algorithm %s", archive.Algorithm)
+	case archive.Iterations <= 0 || archive.Iterations > maxBackupIterations:
+		return nil, errors.Errorf("invalid backup: %d iterations of the key derivation function", archive.Iterations)
+	}
+	gcm, err := archive.cipher(opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(archive.Nonce) != gcm.NonceSize() {
+		return nil, errors.Errorf("invalid backup: nonce of %d bytes instead of %d", len(archive.Nonce), gcm.NonceSize())
+	}
+	plaintext, err := gcm.Open(nil, archive.Nonce, archive.Ciphertext, archive.additionalData())
+	if err != nil {
+		return nil, errors.New("failed decrypting backup: wrong passphrase, or the backup was altered")
+	}
+	defer zeroize(plaintext)
+
+	content := &backupContent{}
+	if err := json.Unmarshal(plaintext, content); err != nil {
+		return nil, errors.Wrap(err, "invalid backup content")
+	}
+	if content.Manifest == nil {
+		return nil, errors.New("invalid backup content: missing manifest")
+	}
+	if err := csp.verifyManifest(content.Manifest, opts.Trusted); err != nil {
+		return nil, err
+	}
+	keys, err := decodeBackupKeys(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		if _, err := csp.ks.GetKey(k.SKI()); err == nil {
+			continue
+		}
+		if err := csp.ks.StoreKey(k); err != nil {
+			return nil, errors.WithMessagef(err, "failed restoring key %x", k.SKI())
+		}
+	}
+	return content.Manifest, nil
+}
+
+// signManifest signs the manifest, without its signature, with the signer.
+// ECDSA keys sign the SHA-256 digest of the manifest, and Ed25519 keys the
+// manifest itself.
+func (csp *CSP) signManifest(manifest *bccsp.BackupManifest, signer bccsp.Key) error {
+	pub, err := signer.PublicKey()
+	if err != nil {
+		return errors.WithMessage(err, "failed getting the public key of the signer")
+	}
+	manifest.Signer, err = pub.Bytes()
+	if err != nil {
+		return errors.WithMessage(err, "failed encoding the public key of the signer")
+	}
+	digest, err := manifestDigest(manifest)
+	if err != nil {
+		return err
+	}
+	manifest.Signature, err = csp.Sign(signer, digest, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed signing manifest")
+	}
+	return nil
+}
+
+// verifyManifest verifies the signature of the manifest with the public key
+// of its signer, which must be among the trusted keys when there are any.
+func (csp *CSP) verifyManifest(manifest *bccsp.BackupManifest, trusted []bccsp.Key) error {
+	signer, err := x509.ParsePKIXPublicKey(manifest.Signer)
+	if err != nil {
+		return errors.Wrap(err, "invalid signer of the manifest")
+	}
+	var key bccsp.Key
+	switch pub := signer.(type) {
+	case *ecdsa.PublicKey:
+		key = &ecdsaPublicKey{pubKey: pub}
+	case ed25519.PublicKey:
+		key = &ed25519PublicKey{pubKey: pub}
+	default:
+		return errors.Errorf("invalid signer of the manifest: unsupported key type %T", signer)
+	}
+
+	if len(trusted) != 0 {
+		found := false
+		for _, t := range trusted {
+			if t != nil && bytes.Equal(t.SKI(), key.SKI()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("the manifest is signed by key %x, which is not trusted", key.SKI())
+		}
+	}
+
+	signature := manifest.Signature
+	manifest.Signature = nil
+	defer func() { manifest.Signature = signature }()
+	digest, err := manifestDigest(manifest)
+	if err != nil {
+		return err
+	}
+	valid, err := csp.Verify(key, signature, digest, nil)
+	if err != nil || !valid {
+		return errors.New("invalid signature of the manifest")
+	}
+	return nil
+}
+
+// manifestDigest returns what the signer of the manifest signs.
+func manifestDigest(manifest *bccsp.BackupManifest) ([]byte, error) {
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed encoding manifest")
+	}
+	signer, err := x509.ParsePKIXPublicKey(manifest.Signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signer of the manifest")
+	}
+	if _, ok := signer.(*ecdsa.PublicKey); ok {
+		digest := sha256.Sum256(raw)
+		return digest[:], nil
+	}
+	return raw, nil
+}
+
+// encodeBackupKey encodes private keys as PKCS#8, public keys as PKIX, and AES
+// keys as is.
+func encodeBackupKey(k bccsp.Key) (*bccsp.BackupEntry, []byte, error) {
+	entry := &bccsp.BackupEntry{SKI: k.SKI()}
+	var material []byte
+	var err error
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		entry.Type, entry.Algorithm = "private", bccsp.ECDSA
+		material, err = x509.MarshalPKCS8PrivateKey(kk.privKey)
+	case *ecdsaPublicKey:
+		entry.Type, entry.Algorithm = "public", bccsp.ECDSA
+		material, err = x509.MarshalPKIXPublicKey(kk.pubKey)
+	case *ed25519PrivateKey:
+		entry.Type, entry.Algorithm = "private", bccsp.ED25519
+		material, err = x509.MarshalPKCS8PrivateKey(kk.privKey)
+	case *ed25519PublicKey:
+		entry.Type, entry.Algorithm = "public", bccsp.ED25519
+		material, err = x509.MarshalPKIXPublicKey(kk.pubKey)
+	case *aesPrivateKey:
+		entry.Type, entry.Algorithm = "symmetric", bccsp.AES
+		material = append([]byte(nil), kk.privKey...)
+	default:
+		return nil, nil, errors.Errorf("unsupported key type %T", k)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed encoding key")
+	}
+	digest := sha256.Sum256(material)
+	entry.Digest = digest[:]
+	return entry, material, nil
+}
+
+// decodeBackupKeys checks that the keys of the backup are exactly the keys of
+// its manifest, and decodes them.
+func decodeBackupKeys(content *backupContent) ([]bccsp.Key, error) {
+	if len(content.Keys) != len(content.Manifest.Keys) {
+		return nil, errors.Errorf("the backup holds %d keys but its manifest lists %d", len(content.Keys), len(content.Manifest.Keys))
+	}
+	materials := map[string][]byte{}
+	for _, k := range content.Keys {
+		materials[backupKeyID(k.SKI, k.Type)] = k.Material
+	}
+
+	var keys []bccsp.Key
+	for _, entry := range content.Manifest.Keys {
+		material, ok := materials[backupKeyID(entry.SKI, entry.Type)]
+		if !ok {
+			return nil, errors.Errorf("%s key %x of the manifest is missing from the backup", entry.Type, entry.SKI)
+		}
+		digest := sha256.Sum256(material)
+		if !bytes.Equal(digest[:], entry.Digest) {
+			return nil, errors.Errorf("key %x does not match the digest of the manifest", entry.SKI)
+		}
+		k, err := decodeBackupKey(&entry, material)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid key %x", entry.SKI)
+		}
+		if !bytes.Equal(k.SKI(), entry.SKI) {
+			return nil, errors.Errorf("key %x does not match its SKI", entry.SKI)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func backupKeyID(ski []byte, keyType string) string {
+	return hex.EncodeToString(ski) + "_" + keyType
+}
+
+func decodeBackupKey(entry *bccsp.BackupEntry, material []byte) (bccsp.Key, error) {
+	switch entry.Type {
+	case "private":
+		priv, err := x509.ParsePKCS8PrivateKey(material)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parsing private key")
+		}
+		switch kk := priv.(type) {
+		case *ecdsa.PrivateKey:
+			return &ecdsaPrivateKey{privKey: kk}, nil
+		case ed25519.PrivateKey:
+			return &ed25519PrivateKey{privKey: kk}, nil
+		}
+		return nil, errors.Errorf("unsupported private key type %T", priv)
+	case "public":
+		pub, err := x509.ParsePKIXPublicKey(material)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parsing public key")
+		}
+		switch kk := pub.(type) {
+		case *ecdsa.PublicKey:
+			return &ecdsaPublicKey{pubKey: kk}, nil
+		case ed25519.PublicKey:
+			return &ed25519PublicKey{pubKey: kk}, nil
+		}
+		return nil, errors.Errorf("unsupported public key type %T", pub)
+	case "symmetric":
+		if entry.Algorithm != bccsp.AES {
+			return nil, errors.Errorf("unsupported symmetric key algorithm %s", entry.Algorithm)
+		}
+		return &aesPrivateKey{material, false}, nil
+	}
+	return nil, errors.Errorf("unsupported key type %s", entry.Type)
+}
+
+// cipher returns the AES-GCM cipher keyed with the key derived from the
+// passphrase.
+func (a *backupArchive) cipher(passphrase []byte) (cipher.AEAD, error) {
+	key := pbkdf2SHA256(passphrase, a.Salt, a.Iterations, 32)
+	defer zeroize(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	return gcm, nil
+}
+
+// additionalData authenticates the header of the archive.
+func (a *backupArchive) additionalData() []byte {
+	raw, _ := json.Marshal(a.backupHeader)
+	return raw
+}
+
+// pbkdf2SHA256 derives a key of the length from the passphrase, as specified
+// by RFC 8018 with HMAC-SHA256.
+func pbkdf2SHA256(passphrase, salt []byte, iterations, length int) []byte {
+	prf := hmac.New(sha256.New, passphrase)
+	var key []byte
+	u := make([]byte, sha256.Size)
+	t := make([]byte, sha256.Size)
+	for block := uint32(1); len(key) < length; block++ {
+		var index [4]byte
+		binary.BigEndian.PutUint32(index[:], block)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(index[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	zeroize(u)
+	zeroize(t)
+	return key[:length]
+}
+
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
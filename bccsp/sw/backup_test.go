@@ -0,0 +1,212 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBackupCSP(t *testing.T) (*CSP, func()) {
+	tempDir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	ks, err := NewFileBasedKeyStore(nil, tempDir, false)
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	return csp.(*CSP), func() { os.RemoveAll(tempDir) }
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	source, cleanup := newBackupCSP(t)
+	defer cleanup()
+
+	signer, err := source.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	ed, err := source.KeyGen(&bccsp.ED25519KeyGenOpts{})
+	require.NoError(t, err)
+	aesKey, err := source.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	pub, err := signer.PublicKey()
+	require.NoError(t, err)
+	require.NoError(t, source.ks.StoreKey(pub))
+
+	buf := &bytes.Buffer{}
+	opts := &bccsp.BackupOpts{Passphrase: []byte("passphrase"), Signer: signer}
+	manifest, err := source.ExportBackup(buf, opts)
+	require.NoError(t, err)
+	require.Len(t, manifest.Keys, 4)
+	types := map[string]string{}
+	for _, entry := range manifest.Keys {
+		types[hex.EncodeToString(entry.SKI)+entry.Type] = entry.Algorithm
+	}
+	assert.Equal(t, bccsp.ECDSA, types[hex.EncodeToString(signer.SKI())+"private"])
+	assert.Equal(t, bccsp.ECDSA, types[hex.EncodeToString(pub.SKI())+"public"])
+	assert.Equal(t, bccsp.ED25519, types[hex.EncodeToString(ed.SKI())+"private"])
+	assert.Equal(t, bccsp.AES, types[hex.EncodeToString(aesKey.SKI())+"symmetric"])
+	assert.NotContains(t, buf.String(), "PRIVATE KEY")
+
+	target, cleanup := newBackupCSP(t)
+	defer cleanup()
+	restored, err := target.ImportBackup(bytes.NewReader(buf.Bytes()), &bccsp.BackupOpts{
+		Passphrase: []byte("passphrase"),
+		Trusted:    []bccsp.Key{pub},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Keys, restored.Keys)
+	assert.Equal(t, manifest.Signature, restored.Signature)
+
+	// The restored keys are usable
+	digest := sha256.Sum256([]byte("hello"))
+	for _, k := range []bccsp.Key{signer, ed} {
+		restoredKey, err := target.GetKey(k.SKI())
+		require.NoError(t, err)
+		signature, err := target.Sign(restoredKey, digest[:], nil)
+		require.NoError(t, err)
+		valid, err := source.Verify(k, signature, digest[:], nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+	restoredAES, err := target.GetKey(aesKey.SKI())
+	require.NoError(t, err)
+	ciphertext, err := source.Encrypt(aesKey, []byte("hello"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	plaintext, err := target.Decrypt(restoredAES, ciphertext, &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+
+	// Restoring again skips the keys already held
+	_, err = target.ImportBackup(bytes.NewReader(buf.Bytes()), &bccsp.BackupOpts{Passphrase: []byte("passphrase")})
+	require.NoError(t, err)
+}
+
+func TestImportBackupFailures(t *testing.T) {
+	source, cleanup := newBackupCSP(t)
+	defer cleanup()
+	signer, err := source.KeyGen(&bccsp.ED25519KeyGenOpts{})
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	_, err = source.ExportBackup(buf, &bccsp.BackupOpts{Passphrase: []byte("passphrase"), Signer: signer})
+	require.NoError(t, err)
+
+	target, cleanup := newBackupCSP(t)
+	defer cleanup()
+	other, err := target.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = target.ImportBackup(bytes.NewReader(buf.Bytes()), nil)
+	assert.EqualError(t, err, "a passphrase is required to import a backup")
+	_, err = target.ImportBackup(bytes.NewReader(buf.Bytes()), &bccsp.BackupOpts{Passphrase: []byte("wrong")})
+	assert.EqualError(t, err, "failed decrypting backup: wrong passphrase, or the backup was altered")
+	_, err = target.ImportBackup(bytes.NewReader(buf.Bytes()), &bccsp.BackupOpts{Passphrase: []byte("passphrase"), Trusted: []bccsp.Key{other}})
+	assert.EqualError(t, err, fmt.Sprintf("the manifest is signed by key %x, which is not trusted", signer.SKI()))
+	_, err = target.ImportBackup(bytes.NewReader([]byte("{")), &bccsp.BackupOpts{Passphrase: []byte("passphrase")})
+	assert.EqualError(t, err, "invalid backup: unexpected end of JSON input")
+
+	tests := []struct {
+		name   string
+		modify func(*backupArchive)
+		err    string
+	}{
+		{"version", func(a *backupArchive) { a.Version = 2 }, "unsupported backup version 2"},
+		{"kdf", func(a *backupArchive) { a.KDF = "scrypt" }, "unsupported key derivation function scrypt"},
+		{"algorithm", func(a *backupArchive) { a.Algorithm = "AES-128-GCM" }, "unsupported encryption algorithm AES-128-GCM"},
+		{"iterations", func(a *backupArchive) { a.Iterations = 0 }, "invalid backup: 0 iterations of the key derivation function"},
+		{"nonce", func(a *backupArchive) { a.Nonce = a.Nonce[:4] }, "invalid backup: nonce of 4 bytes instead of 12"},
+		{"salt", func(a *backupArchive) { a.Salt[0] ^= 1 }, "failed decrypting backup: wrong passphrase, or the backup was altered"},
+		{"ciphertext", func(a *backupArchive) { a.Ciphertext[0] ^= 1 }, "failed decrypting backup: wrong passphrase, or the backup was altered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := &backupArchive{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), archive))
+			tt.modify(archive)
+			altered, err := json.Marshal(archive)
+			require.NoError(t, err)
+			_, err = target.ImportBackup(bytes.NewReader(altered), &bccsp.BackupOpts{Passphrase: []byte("passphrase")})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	keys, err := target.ListKeys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestBackupManifestVerification(t *testing.T) {
+	csp, cleanup := newBackupCSP(t)
+	defer cleanup()
+	signer, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+
+	entry, material, err := encodeBackupKey(k)
+	require.NoError(t, err)
+	manifest := &bccsp.BackupManifest{Keys: []bccsp.BackupEntry{*entry}}
+	require.NoError(t, csp.signManifest(manifest, signer))
+	require.NoError(t, csp.verifyManifest(manifest, nil))
+
+	manifest.Keys[0].Type = "private"
+	assert.EqualError(t, csp.verifyManifest(manifest, nil), "invalid signature of the manifest")
+	manifest.Keys[0].Type = "symmetric"
+
+	content := &backupContent{Manifest: manifest, Keys: []backupKey{{SKI: k.SKI(), Type: "symmetric", Material: material}}}
+	keys, err := decodeBackupKeys(content)
+	require.NoError(t, err)
+	assert.Equal(t, k.SKI(), keys[0].SKI())
+
+	content.Keys[0].Material = append([]byte{}, material...)
+	content.Keys[0].Material[0] ^= 1
+	_, err = decodeBackupKeys(content)
+	assert.EqualError(t, err, fmt.Sprintf("key %x does not match the digest of the manifest", k.SKI()))
+
+	content.Keys[0].SKI = []byte{1}
+	_, err = decodeBackupKeys(content)
+	assert.EqualError(t, err, fmt.Sprintf("symmetric key %x of the manifest is missing from the backup", k.SKI()))
+
+	content.Keys = nil
+	_, err = decodeBackupKeys(content)
+	assert.EqualError(t, err, "the backup holds 0 keys but its manifest lists 1")
+}
+
+func TestExportBackupFailures(t *testing.T) {
+	csp, cleanup := newBackupCSP(t)
+	defer cleanup()
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+
+	_, err = csp.ExportBackup(ioutil.Discard, nil)
+	assert.EqualError(t, err, "a passphrase is required to export a backup")
+	_, err = csp.ExportBackup(ioutil.Discard, &bccsp.BackupOpts{Passphrase: []byte("passphrase"), Signer: pub})
+	assert.EqualError(t, err, "a private key is required to sign the manifest of a backup")
+
+	readOnly, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	require.NoError(t, err)
+	_, err = readOnly.(*CSP).ImportBackup(bytes.NewReader(nil), &bccsp.BackupOpts{Passphrase: []byte("passphrase")})
+	assert.EqualError(t, err, "cannot import a backup to a read only KeyStore")
+}
+
+// The vectors are from RFC 7914.
+func TestPBKDF2SHA256(t *testing.T) {
+	key := pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+	key = pbkdf2SHA256([]byte("Password"), []byte("NaCl"), 80000, 32)
+	assert.Equal(t, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56", hex.EncodeToString(key))
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+// VerifyBatch verifies the signatures of all the requests across a pool of workers,
+// one per available CPU, and returns the results in the order of the requests.
+func (csp *CSP) VerifyBatch(requests []*bccsp.VerifyRequest) []*bccsp.VerifyResult {
+	results := make([]*bccsp.VerifyResult, len(requests))
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(requests) {
+		numWorkers = len(requests)
+	}
+
+	indexes := make(chan int, len(requests))
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := requests[i]
+				valid, err := csp.Verify(r.Key, r.Signature, r.Digest, r.Opts)
+				results[i] = &bccsp.VerifyResult{Valid: valid, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	var requests []*bccsp.VerifyRequest
+	for i := 0; i < 20; i++ {
+		digest := sha256.Sum256([]byte(fmt.Sprintf("message-%d", i)))
+		signature, err := csp.Sign(k, digest[:], nil)
+		assert.NoError(t, err)
+		requests = append(requests, &bccsp.VerifyRequest{Key: pk, Signature: signature, Digest: digest[:]})
+	}
+	// the signature of another message
+	requests[3].Digest = requests[4].Digest
+	// an empty signature
+	requests[7].Signature = nil
+
+	batchVerifier, ok := csp.(bccsp.BatchVerifier)
+	assert.True(t, ok)
+	results := batchVerifier.VerifyBatch(requests)
+	assert.Len(t, results, len(requests))
+	for i, r := range results {
+		switch i {
+		case 3:
+			assert.False(t, r.Valid)
+			assert.NoError(t, r.Err)
+		case 7:
+			assert.False(t, r.Valid)
+			assert.EqualError(t, r.Err, "Invalid signature. Cannot be empty.")
+		default:
+			assert.True(t, r.Valid)
+			assert.NoError(t, r.Err)
+		}
+	}
+
+	assert.Empty(t, batchVerifier.VerifyBatch(nil))
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import "errors"
+
+// NewDPAPIProtector returns an error, the Windows Data Protection API being
+// only available on Windows.
+func NewDPAPIProtector(localMachine bool, entropy []byte) (KeyProtector, error) {
+	return nil, errors.New("the Data Protection API is only available on Windows")
+}
@@ -0,0 +1,116 @@
+// +build windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	cryptProtectUIForbidden  = 0x1
+	cryptProtectLocalMachine = 0x4
+)
+
+var (
+	crypt32                = windows.NewLazySystemDLL("crypt32.dll")
+	procCryptProtectData   = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+)
+
+// dataBlob is the DATA_BLOB structure of the Data Protection API.
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+func newDataBlob(b []byte) *dataBlob {
+	if len(b) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{size: uint32(len(b)), data: &b[0]}
+}
+
+// bytes copies the content of a blob allocated by the Data Protection API,
+// and frees it.
+func (b *dataBlob) bytes() []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(b.data)))
+	out := make([]byte, b.size)
+	copy(out, (*[1 << 30]byte)(unsafe.Pointer(b.data))[:b.size:b.size])
+	return out
+}
+
+type dpapiProtector struct {
+	localMachine bool
+	entropy      []byte
+}
+
+// NewDPAPIProtector returns a KeyProtector encrypting the key files with the
+// Windows Data Protection API, with the credentials of the user running the
+// process, or with those of the machine when localMachine is true. The
+// optional entropy must be provided again to unprotect the key files.
+func NewDPAPIProtector(localMachine bool, entropy []byte) (KeyProtector, error) {
+	if err := procCryptProtectData.Find(); err != nil {
+		return nil, err
+	}
+	return &dpapiProtector{localMachine: localMachine, entropy: entropy}, nil
+}
+
+func (p *dpapiProtector) Name() string {
+	if p.localMachine {
+		return "DPAPI-Machine"
+	}
+	return "DPAPI"
+}
+
+func (p *dpapiProtector) Protect(raw []byte) ([]byte, error) {
+	flags := uintptr(cryptProtectUIForbidden)
+	if p.localMachine {
+		flags |= cryptProtectLocalMachine
+	}
+	var out dataBlob
+	r, _, err := procCryptProtectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(raw))),
+		0,
+		uintptr(unsafe.Pointer(p.entropyBlob())),
+		0,
+		0,
+		flags,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if r == 0 {
+		return nil, err
+	}
+	return out.bytes(), nil
+}
+
+func (p *dpapiProtector) Unprotect(blob []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(blob))),
+		0,
+		uintptr(unsafe.Pointer(p.entropyBlob())),
+		0,
+		0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if r == 0 {
+		return nil, err
+	}
+	return out.bytes(), nil
+}
+
+func (p *dpapiProtector) entropyBlob() *dataBlob {
+	if len(p.entropy) == 0 {
+		return nil
+	}
+	return newDataBlob(p.entropy)
+}
@@ -47,3 +47,18 @@ func (ks *dummyKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 func (ks *dummyKeyStore) StoreKey(k bccsp.Key) error {
 	return errors.New("Cannot store key. This is a dummy read-only KeyStore")
 }
+
+// KeyCounts returns no keys, as the key store never holds any.
+func (ks *dummyKeyStore) KeyCounts() (bccsp.KeyCounts, error) {
+	return bccsp.KeyCounts{}, nil
+}
+
+// ListKeys returns no keys, as the key store never holds any.
+func (ks *dummyKeyStore) ListKeys() ([]bccsp.Key, error) {
+	return nil, nil
+}
+
+// DeleteKey fails, as the key store is read only.
+func (ks *dummyKeyStore) DeleteKey(ski []byte) error {
+	return errors.New("Cannot delete key. This is a dummy read-only KeyStore")
+}
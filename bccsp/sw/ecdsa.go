@@ -59,17 +59,26 @@ func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.Signer
 type ecdsaSigner struct{}
 
 func (s *ecdsaSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if _, ok := opts.(*bccsp.ECVRFSignerOpts); ok {
+		return proveECVRF(k.(*ecdsaPrivateKey).privKey, digest)
+	}
 	return signECDSA(k.(*ecdsaPrivateKey).privKey, digest, opts)
 }
 
 type ecdsaPrivateKeyVerifier struct{}
 
 func (v *ecdsaPrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if _, ok := opts.(*bccsp.ECVRFSignerOpts); ok {
+		return verifyECVRF(&(k.(*ecdsaPrivateKey).privKey.PublicKey), signature, digest)
+	}
 	return verifyECDSA(&(k.(*ecdsaPrivateKey).privKey.PublicKey), signature, digest, opts)
 }
 
 type ecdsaPublicKeyKeyVerifier struct{}
 
 func (v *ecdsaPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if _, ok := opts.(*bccsp.ECVRFSignerOpts); ok {
+		return verifyECVRF(k.(*ecdsaPublicKey).pubKey, signature, digest)
+	}
 	return verifyECDSA(k.(*ecdsaPublicKey).pubKey, signature, digest, opts)
 }
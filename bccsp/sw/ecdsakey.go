@@ -24,6 +24,7 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/utils"
 )
 
 type ecdsaPrivateKey struct {
@@ -69,6 +70,12 @@ func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
 	return &ecdsaPublicKey{&k.privKey.PublicKey}, nil
 }
 
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPrivateKey) Fingerprint() ([]byte, error) {
+	return utils.SPKIFingerprint(&k.privKey.PublicKey)
+}
+
 type ecdsaPublicKey struct {
 	pubKey *ecdsa.PublicKey
 }
@@ -115,3 +122,9 @@ func (k *ecdsaPublicKey) Private() bool {
 func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
 	return k, nil
 }
+
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *ecdsaPublicKey) Fingerprint() ([]byte, error) {
+	return utils.SPKIFingerprint(k.pubKey)
+}
@@ -0,0 +1,228 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// ECVRF-P256-SHA256-TAI as specified by RFC 9381
+const (
+	ecvrfSuite               = 0x01
+	ecvrfPointSize           = 33
+	ecvrfChallengeSize       = 16
+	ecvrfScalarSize          = 32
+	ecvrfProofSize           = ecvrfPointSize + ecvrfChallengeSize + ecvrfScalarSize
+	ecvrfEncodeToCurveDomain = 0x01
+	ecvrfChallengeDomain     = 0x02
+	ecvrfProofToHashDomain   = 0x03
+)
+
+// ecvrfSqrtExp is (p+1)/4, the exponent computing square roots modulo p, as p = 3 mod 4
+var ecvrfSqrtExp = new(big.Int).Rsh(new(big.Int).Add(elliptic.P256().Params().P, big.NewInt(1)), 2)
+
+// proveECVRF returns the proof of the VRF of the key for the input alpha
+func proveECVRF(k *ecdsa.PrivateKey, alpha []byte) ([]byte, error) {
+	if k.Curve != elliptic.P256() {
+		return nil, errors.New("ECVRF requires a P-256 key")
+	}
+	curve := k.Curve
+	n := curve.Params().N
+
+	hx, hy, err := ecvrfEncodeToCurve(&k.PublicKey, alpha)
+	if err != nil {
+		return nil, err
+	}
+	gammaX, gammaY := curve.ScalarMult(hx, hy, k.D.Bytes())
+	nonce := ecvrfNonce(k.D, compressPoint(hx, hy))
+	ukX, ukY := curve.ScalarBaseMult(nonce.Bytes())
+	vkX, vkY := curve.ScalarMult(hx, hy, nonce.Bytes())
+
+	c := ecvrfChallenge(&k.PublicKey, hx, hy, gammaX, gammaY, ukX, ukY, vkX, vkY)
+	s := new(big.Int).Mul(c, k.D)
+	s.Add(s, nonce)
+	s.Mod(s, n)
+
+	proof := compressPoint(gammaX, gammaY)
+	proof = append(proof, leftPad(c.Bytes(), ecvrfChallengeSize)...)
+	return append(proof, leftPad(s.Bytes(), ecvrfScalarSize)...), nil
+}
+
+// verifyECVRF verifies the proof of the VRF of the key for the input alpha
+func verifyECVRF(k *ecdsa.PublicKey, proof, alpha []byte) (bool, error) {
+	if k.Curve != elliptic.P256() {
+		return false, errors.New("ECVRF requires a P-256 key")
+	}
+	curve := k.Curve
+	n := curve.Params().N
+
+	gammaX, gammaY, c, s, err := decodeECVRFProof(proof)
+	if err != nil {
+		return false, err
+	}
+	hx, hy, err := ecvrfEncodeToCurve(k, alpha)
+	if err != nil {
+		return false, err
+	}
+
+	// U = s*B - c*Y and V = s*H - c*Gamma
+	negC := new(big.Int).Sub(n, c).Bytes()
+	sbX, sbY := curve.ScalarBaseMult(s.Bytes())
+	cyX, cyY := curve.ScalarMult(k.X, k.Y, negC)
+	uX, uY := curve.Add(sbX, sbY, cyX, cyY)
+	shX, shY := curve.ScalarMult(hx, hy, s.Bytes())
+	cgX, cgY := curve.ScalarMult(gammaX, gammaY, negC)
+	vX, vY := curve.Add(shX, shY, cgX, cgY)
+
+	return ecvrfChallenge(k, hx, hy, gammaX, gammaY, uX, uY, vX, vY).Cmp(c) == 0, nil
+}
+
+// ecvrfProofToHash returns the output of the VRF from its proof
+func ecvrfProofToHash(proof []byte) ([]byte, error) {
+	gammaX, gammaY, _, _, err := decodeECVRFProof(proof)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte{ecvrfSuite, ecvrfProofToHashDomain})
+	h.Write(compressPoint(gammaX, gammaY))
+	h.Write([]byte{0x00})
+	return h.Sum(nil), nil
+}
+
+func decodeECVRFProof(proof []byte) (gammaX, gammaY, c, s *big.Int, err error) {
+	if len(proof) != ecvrfProofSize {
+		return nil, nil, nil, nil, errors.Errorf("invalid ECVRF proof length. Must be %d bytes, was %d", ecvrfProofSize, len(proof))
+	}
+	gammaX, gammaY, ok := decompressPoint(proof[:ecvrfPointSize])
+	if !ok {
+		return nil, nil, nil, nil, errors.New("invalid ECVRF proof: Gamma is not a point of the curve")
+	}
+	c = new(big.Int).SetBytes(proof[ecvrfPointSize : ecvrfPointSize+ecvrfChallengeSize])
+	s = new(big.Int).SetBytes(proof[ecvrfPointSize+ecvrfChallengeSize:])
+	if s.Cmp(elliptic.P256().Params().N) >= 0 {
+		return nil, nil, nil, nil, errors.New("invalid ECVRF proof: s is not reduced")
+	}
+	return gammaX, gammaY, c, s, nil
+}
+
+// ecvrfEncodeToCurve hashes the input to a point of the curve with try-and-increment,
+// salted with the public key
+func ecvrfEncodeToCurve(k *ecdsa.PublicKey, alpha []byte) (*big.Int, *big.Int, error) {
+	pk := compressPoint(k.X, k.Y)
+	for ctr := 0; ctr < 256; ctr++ {
+		h := sha256.New()
+		h.Write([]byte{ecvrfSuite, ecvrfEncodeToCurveDomain})
+		h.Write(pk)
+		h.Write(alpha)
+		h.Write([]byte{byte(ctr), 0x00})
+		if x, y, ok := decompressPoint(append([]byte{0x02}, h.Sum(nil)...)); ok {
+			return x, y, nil
+		}
+	}
+	return nil, nil, errors.New("failed hashing the ECVRF input to the curve")
+}
+
+// ecvrfChallenge hashes the points to the challenge of the proof
+func ecvrfChallenge(k *ecdsa.PublicKey, coordinates ...*big.Int) *big.Int {
+	h := sha256.New()
+	h.Write([]byte{ecvrfSuite, ecvrfChallengeDomain})
+	h.Write(compressPoint(k.X, k.Y))
+	for i := 0; i < len(coordinates); i += 2 {
+		h.Write(compressPoint(coordinates[i], coordinates[i+1]))
+	}
+	h.Write([]byte{0x00})
+	return new(big.Int).SetBytes(h.Sum(nil)[:ecvrfChallengeSize])
+}
+
+// ecvrfNonce generates the nonce of the proof deterministically as specified by
+// RFC 6979, section 3.2, the message being the encoding of the hashed input
+func ecvrfNonce(d *big.Int, msg []byte) *big.Int {
+	n := elliptic.P256().Params().N
+	h1 := sha256.Sum256(msg)
+	m := new(big.Int).SetBytes(h1[:])
+	m.Mod(m, n)
+	x := leftPad(d.Bytes(), ecvrfScalarSize)
+	z := leftPad(m.Bytes(), ecvrfScalarSize)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+		h := hmac.New(sha256.New, key)
+		for _, b := range data {
+			h.Write(b)
+		}
+		return h.Sum(nil)
+	}
+
+	v := make([]byte, sha256.Size)
+	for i := range v {
+		v[i] = 0x01
+	}
+	key := make([]byte, sha256.Size)
+	key = mac(key, v, []byte{0x00}, x, z)
+	v = mac(key, v)
+	key = mac(key, v, []byte{0x01}, x, z)
+	v = mac(key, v)
+	for {
+		v = mac(key, v)
+		k := new(big.Int).SetBytes(v)
+		if k.Sign() > 0 && k.Cmp(n) < 0 {
+			return k
+		}
+		key = mac(key, v, []byte{0x00})
+		v = mac(key, v)
+	}
+}
+
+func compressPoint(x, y *big.Int) []byte {
+	return append([]byte{byte(0x02 + y.Bit(0))}, leftPad(x.Bytes(), ecvrfPointSize-1)...)
+}
+
+func decompressPoint(raw []byte) (*big.Int, *big.Int, bool) {
+	params := elliptic.P256().Params()
+	if len(raw) != ecvrfPointSize || (raw[0] != 0x02 && raw[0] != 0x03) {
+		return nil, nil, false
+	}
+	x := new(big.Int).SetBytes(raw[1:])
+	if x.Cmp(params.P) >= 0 {
+		return nil, nil, false
+	}
+	// y^2 = x^3 - 3x + b
+	y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	y2.Sub(y2, new(big.Int).Mul(x, big.NewInt(3)))
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+	y := new(big.Int).Exp(y2, ecvrfSqrtExp, params.P)
+	if new(big.Int).Exp(y, big.NewInt(2), params.P).Cmp(y2) != 0 {
+		return nil, nil, false
+	}
+	if y.Bit(0) != uint(raw[0]&1) {
+		y.Sub(params.P, y)
+	}
+	return x, y, true
+}
+
+func leftPad(b []byte, size int) []byte {
+	return append(make([]byte, size-len(b)), b...)
+}
+
+type ecvrfHasher struct{}
+
+func (c *ecvrfHasher) Hash(proof []byte, opts bccsp.HashOpts) ([]byte, error) {
+	return ecvrfProofToHash(proof)
+}
+
+func (c *ecvrfHasher) GetHash(opts bccsp.HashOpts) (hash.Hash, error) {
+	return nil, errors.New("the output of an ECVRF proof cannot be computed incrementally")
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECVRFProveVerify(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECVRFKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	alpha := []byte("block 42")
+	proof, err := csp.Sign(k, alpha, &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, proof, ecvrfProofSize)
+
+	// proofs are deterministic
+	again, err := csp.Sign(k, alpha, &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, proof, again)
+
+	valid, err := csp.Verify(pk, proof, alpha, &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.Verify(k, proof, alpha, &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = csp.Verify(pk, proof, []byte("block 43"), &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	other, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	valid, err = csp.Verify(other, proof, alpha, &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	beta, err := csp.Hash(proof, &bccsp.ECVRFProofToHashOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, beta, 32)
+	otherProof, err := csp.Sign(k, []byte("block 43"), &bccsp.ECVRFSignerOpts{})
+	assert.NoError(t, err)
+	otherBeta, err := csp.Hash(otherProof, &bccsp.ECVRFProofToHashOpts{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, beta, otherBeta)
+
+	_, err = csp.GetHash(&bccsp.ECVRFProofToHashOpts{})
+	assert.EqualError(t, err, "Failed getting hash function with opts [&{}]: the output of an ECVRF proof cannot be computed incrementally")
+
+	// the ECDSA signatures of the key are unaffected
+	digest := []byte("0123456789abcdef0123456789abcdef")
+	signature, err := csp.Sign(k, digest, nil)
+	assert.NoError(t, err)
+	valid, err = csp.Verify(pk, signature, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestECVRFVerifyFailures(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	alpha := []byte("alpha")
+	proof, err := proveECVRF(key, alpha)
+	assert.NoError(t, err)
+
+	_, err = verifyECVRF(&key.PublicKey, proof[1:], alpha)
+	assert.EqualError(t, err, "invalid ECVRF proof length. Must be 81 bytes, was 80")
+
+	tampered := append([]byte{}, proof...)
+	tampered[0] = 0x04
+	_, err = verifyECVRF(&key.PublicKey, tampered, alpha)
+	assert.EqualError(t, err, "invalid ECVRF proof: Gamma is not a point of the curve")
+
+	tampered = append([]byte{}, proof...)
+	copy(tampered[ecvrfPointSize+ecvrfChallengeSize:], elliptic.P256().Params().N.Bytes())
+	_, err = verifyECVRF(&key.PublicKey, tampered, alpha)
+	assert.EqualError(t, err, "invalid ECVRF proof: s is not reduced")
+
+	tampered = append([]byte{}, proof...)
+	tampered[len(tampered)-1] ^= 0x01
+	valid, err := verifyECVRF(&key.PublicKey, tampered, alpha)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = proveECVRF(p384Key, alpha)
+	assert.EqualError(t, err, "ECVRF requires a P-256 key")
+	_, err = verifyECVRF(&p384Key.PublicKey, proof, alpha)
+	assert.EqualError(t, err, "ECVRF requires a P-256 key")
+}
+
+// TestECVRFVector checks the example 10 of RFC 9381, appendix B.1
+func TestECVRFVector(t *testing.T) {
+	t.Parallel()
+
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(d.Bytes())
+	assert.Equal(t, "0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6", hex.EncodeToString(compressPoint(key.X, key.Y)))
+
+	proof, err := proveECVRF(key, []byte("sample"))
+	assert.NoError(t, err)
+	assert.Equal(t, "035b5c726e8c0e2c488a107c600578ee75cb702343c153cb1eb8dec77f4b5071b4a53f0a46f018bc2c56e58d383f2305e0975972c26feea0eb122fe7893c15af376b33edf7de17c6ea056d4d82de6bc02f", hex.EncodeToString(proof))
+
+	beta, err := ecvrfProofToHash(proof)
+	assert.NoError(t, err)
+	assert.Equal(t, "a3ad7b0ef73d8fc6655053ea22f9bede8c743f08bbed3d38821f0e16474b505e", hex.EncodeToString(beta))
+}
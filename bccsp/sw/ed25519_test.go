@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestED25519SignVerify(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.True(t, k.Private())
+	assert.False(t, k.Symmetric())
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), pk.SKI())
+
+	msg := []byte("hello world")
+	signature, err := csp.Sign(k, msg, nil)
+	assert.NoError(t, err)
+	assert.Len(t, signature, ed25519.SignatureSize)
+
+	valid, err := csp.Verify(pk, signature, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.Verify(k, signature, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = csp.Verify(pk, signature, []byte("another message"), nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = csp.Verify(pk, signature[1:], msg, nil)
+	assert.Contains(t, err.Error(), "Invalid signature length. Must be 64 bytes, was 63.")
+
+	raw, err := pk.Bytes()
+	assert.NoError(t, err)
+	pub, err := x509.ParsePKIXPublicKey(raw)
+	assert.NoError(t, err)
+	assert.True(t, ed25519.Verify(pub.(ed25519.PublicKey), msg, signature))
+
+	_, err = k.Bytes()
+	assert.EqualError(t, err, "Not supported.")
+}
+
+func TestED25519KeyImport(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	t.Run("private key", func(t *testing.T) {
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		assert.NoError(t, err)
+		k, err := csp.KeyImport(der, &bccsp.ED25519PrivateKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.True(t, k.Private())
+		assert.Equal(t, ed25519SKI(pub), k.SKI())
+
+		_, err = csp.KeyImport("not der", &bccsp.ED25519PrivateKeyImportOpts{Temporary: true})
+		assert.Contains(t, err.Error(), "[ED25519PrivateKeyImportOpts] Invalid raw material. Expected byte array.")
+		_, err = csp.KeyImport([]byte{}, &bccsp.ED25519PrivateKeyImportOpts{Temporary: true})
+		assert.Contains(t, err.Error(), "[ED25519PrivateKeyImportOpts] Invalid raw. It must not be nil.")
+	})
+
+	t.Run("public key", func(t *testing.T) {
+		k, err := csp.KeyImport(pub, &bccsp.ED25519GoPublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.False(t, k.Private())
+		assert.Equal(t, ed25519SKI(pub), k.SKI())
+
+		_, err = csp.KeyImport([]byte(pub), &bccsp.ED25519GoPublicKeyImportOpts{Temporary: true})
+		assert.Contains(t, err.Error(), "Invalid raw material. Expected ed25519.PublicKey.")
+	})
+
+	t.Run("certificate", func(t *testing.T) {
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "peer0"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+		assert.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		assert.NoError(t, err)
+
+		k, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.Equal(t, ed25519SKI(pub), k.SKI())
+
+		msg := []byte("hello world")
+		valid, err := csp.Verify(k, ed25519.Sign(priv, msg), msg, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
+
+func TestED25519FileKeyStore(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "ed25519-keystore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ks, err := NewFileBasedKeyStore(nil, tempDir, false)
+	assert.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{})
+	assert.NoError(t, err)
+
+	// a fresh keystore reads the keys back from the disk
+	ks, err = NewFileBasedKeyStore(nil, tempDir, true)
+	assert.NoError(t, err)
+	loaded, err := ks.GetKey(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, k, loaded)
+}
+
+func TestVerifyBatchED25519(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	var requests []*bccsp.VerifyRequest
+	for i := 0; i < 10; i++ {
+		msg := []byte(fmt.Sprintf("message-%d", i))
+		signature, err := csp.Sign(k, msg, nil)
+		assert.NoError(t, err)
+		requests = append(requests, &bccsp.VerifyRequest{Key: pk, Signature: signature, Digest: msg})
+	}
+	requests[5].Digest = requests[6].Digest
+
+	results := csp.(bccsp.BatchVerifier).VerifyBatch(requests)
+	assert.Len(t, results, len(requests))
+	for i, r := range results {
+		assert.NoError(t, r.Err)
+		assert.Equal(t, i != 5, r.Valid)
+	}
+}
@@ -9,6 +9,7 @@ package sw
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -46,6 +47,18 @@ type fileBasedKeyStore struct {
 
 	pwd []byte
 
+	// macKey keys the MACs of the key files, or is nil when the key files
+	// are not protected
+	macKey []byte
+
+	// perms are the permissions enforced on the folder and the key files, or
+	// nil when they are not enforced
+	perms *FilePermissions
+
+	// protector protects the key files, or is nil when they are stored in the
+	// clear
+	protector KeyProtector
+
 	// Sync
 	m sync.Mutex
 }
@@ -142,6 +155,8 @@ func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 		switch k := key.(type) {
 		case *ecdsa.PrivateKey:
 			return &ecdsaPrivateKey{k}, nil
+		case ed25519.PrivateKey:
+			return &ed25519PrivateKey{k}, nil
 		default:
 			return nil, errors.New("secret key type not recognized")
 		}
@@ -155,6 +170,8 @@ func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 		switch k := key.(type) {
 		case *ecdsa.PublicKey:
 			return &ecdsaPublicKey{k}, nil
+		case ed25519.PublicKey:
+			return &ed25519PublicKey{k}, nil
 		default:
 			return nil, errors.New("public key type not recognized")
 		}
@@ -186,6 +203,18 @@ func (ks *fileBasedKeyStore) StoreKey(k bccsp.Key) (err error) {
 			return fmt.Errorf("failed storing ECDSA public key [%s]", err)
 		}
 
+	case *ed25519PrivateKey:
+		err = ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey)
+		if err != nil {
+			return fmt.Errorf("failed storing ED25519 private key [%s]", err)
+		}
+
+	case *ed25519PublicKey:
+		err = ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey)
+		if err != nil {
+			return fmt.Errorf("failed storing ED25519 public key [%s]", err)
+		}
+
 	case *aesPrivateKey:
 		err = ks.storeKey(hex.EncodeToString(k.SKI()), kk.privKey)
 		if err != nil {
@@ -211,7 +240,7 @@ func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err
 			continue
 		}
 
-		raw, err := ioutil.ReadFile(filepath.Join(ks.path, f.Name()))
+		raw, err := ks.readKeyFile(filepath.Join(ks.path, f.Name()))
 		if err != nil {
 			continue
 		}
@@ -224,6 +253,8 @@ func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err
 		switch kk := key.(type) {
 		case *ecdsa.PrivateKey:
 			k = &ecdsaPrivateKey{kk}
+		case ed25519.PrivateKey:
+			k = &ed25519PrivateKey{kk}
 		default:
 			continue
 		}
@@ -237,6 +268,132 @@ func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err
 	return nil, fmt.Errorf("key with SKI %x not found in %s", ski, ks.path)
 }
 
+// KeyCounts counts the keys stored in the folder of the KeyStore.
+func (ks *fileBasedKeyStore) KeyCounts() (bccsp.KeyCounts, error) {
+	var counts bccsp.KeyCounts
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return counts, fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(f.Name(), "sk"):
+			counts.Private++
+		case strings.HasSuffix(f.Name(), "pk"):
+			counts.Public++
+		case strings.HasSuffix(f.Name(), "key"):
+			counts.Symmetric++
+		}
+	}
+	return counts, nil
+}
+
+// ListKeys returns the keys stored in the folder of the KeyStore, including
+// the ones stored in files not named after their SKI. The files which do not
+// hold a key are skipped.
+func (ks *fileBasedKeyStore) ListKeys() ([]bccsp.Key, error) {
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+
+	var keys []bccsp.Key
+	for _, f := range files {
+		if f.IsDir() || f.Size() > (1<<16) {
+			continue
+		}
+		k, err := ks.loadKeyFile(f.Name())
+		if err != nil {
+			logger.Debugf("Skipping file %s of keystore %s: %s", f.Name(), ks.path, err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// DeleteKey removes the files of the keys whose SKI is the one passed.
+// If this KeyStore is read only then the method will fail.
+func (ks *fileBasedKeyStore) DeleteKey(ski []byte) error {
+	if ks.readOnly {
+		return errors.New("read only KeyStore")
+	}
+	if len(ski) == 0 {
+		return errors.New("invalid SKI. Cannot be of zero length")
+	}
+
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+
+	deleted := false
+	for _, f := range files {
+		if f.IsDir() || f.Size() > (1<<16) {
+			continue
+		}
+		k, err := ks.loadKeyFile(f.Name())
+		if err != nil || !bytes.Equal(k.SKI(), ski) {
+			continue
+		}
+		if err := ks.removeKeyFile(filepath.Join(ks.path, f.Name())); err != nil {
+			return fmt.Errorf("failed deleting key [%x] [%s]", ski, err)
+		}
+		deleted = true
+	}
+	if !deleted {
+		return fmt.Errorf("key with SKI %x not found in %s", ski, ks.path)
+	}
+	return nil
+}
+
+// loadKeyFile loads the key stored in a file of the KeyStore, relying on the
+// suffix of the file to tell public and symmetric keys from private ones.
+func (ks *fileBasedKeyStore) loadKeyFile(name string) (bccsp.Key, error) {
+	raw, err := ks.readKeyFile(filepath.Join(ks.path, name))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(name, "key"):
+		key, err := pemToAES(raw, ks.pwd)
+		if err != nil {
+			return nil, err
+		}
+		return &aesPrivateKey{key, false}, nil
+	case strings.HasSuffix(name, "pk"):
+		key, err := pemToPublicKey(raw, ks.pwd)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			return &ecdsaPublicKey{k}, nil
+		case ed25519.PublicKey:
+			return &ed25519PublicKey{k}, nil
+		default:
+			return nil, errors.New("public key type not recognized")
+		}
+	default:
+		key, err := pemToPrivateKey(raw, ks.pwd)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			return &ecdsaPrivateKey{k}, nil
+		case ed25519.PrivateKey:
+			return &ed25519PrivateKey{k}, nil
+		default:
+			return nil, errors.New("secret key type not recognized")
+		}
+	}
+}
+
 func (ks *fileBasedKeyStore) getSuffix(alias string) string {
 	files, _ := ioutil.ReadDir(ks.path)
 	for _, f := range files {
@@ -263,7 +420,7 @@ func (ks *fileBasedKeyStore) storePrivateKey(alias string, privateKey interface{
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "sk"), rawKey, 0600)
+	err = ks.writeKeyFile(ks.getPathForAlias(alias, "sk"), rawKey)
 	if err != nil {
 		logger.Errorf("Failed storing private key [%s]: [%s]", alias, err)
 		return err
@@ -279,7 +436,7 @@ func (ks *fileBasedKeyStore) storePublicKey(alias string, publicKey interface{})
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "pk"), rawKey, 0600)
+	err = ks.writeKeyFile(ks.getPathForAlias(alias, "pk"), rawKey)
 	if err != nil {
 		logger.Errorf("Failed storing private key [%s]: [%s]", alias, err)
 		return err
@@ -295,7 +452,7 @@ func (ks *fileBasedKeyStore) storeKey(alias string, key []byte) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "key"), pem, 0600)
+	err = ks.writeKeyFile(ks.getPathForAlias(alias, "key"), pem)
 	if err != nil {
 		logger.Errorf("Failed storing key [%s]: [%s]", alias, err)
 		return err
@@ -308,7 +465,7 @@ func (ks *fileBasedKeyStore) loadPrivateKey(alias string) (interface{}, error) {
 	path := ks.getPathForAlias(alias, "sk")
 	logger.Debugf("Loading private key [%s] at [%s]...", alias, path)
 
-	raw, err := ioutil.ReadFile(path)
+	raw, err := ks.readKeyFile(path)
 	if err != nil {
 		logger.Errorf("Failed loading private key [%s]: [%s].", alias, err.Error())
 
@@ -329,7 +486,7 @@ func (ks *fileBasedKeyStore) loadPublicKey(alias string) (interface{}, error) {
 	path := ks.getPathForAlias(alias, "pk")
 	logger.Debugf("Loading public key [%s] at [%s]...", alias, path)
 
-	raw, err := ioutil.ReadFile(path)
+	raw, err := ks.readKeyFile(path)
 	if err != nil {
 		logger.Errorf("Failed loading public key [%s]: [%s].", alias, err.Error())
 
@@ -350,7 +507,7 @@ func (ks *fileBasedKeyStore) loadKey(alias string) ([]byte, error) {
 	path := ks.getPathForAlias(alias, "key")
 	logger.Debugf("Loading key [%s] at [%s]...", alias, path)
 
-	pem, err := ioutil.ReadFile(path)
+	pem, err := ks.readKeyFile(path)
 	if err != nil {
 		logger.Errorf("Failed loading key [%s]: [%s].", alias, err.Error())
 
@@ -372,10 +529,15 @@ func (ks *fileBasedKeyStore) createKeyStore() error {
 	ksPath := ks.path
 	logger.Debugf("Creating KeyStore at [%s]...", ksPath)
 
-	err := os.MkdirAll(ksPath, 0755)
+	err := os.MkdirAll(ksPath, ks.dirMode())
 	if err != nil {
 		return err
 	}
+	if ks.perms != nil {
+		if err := os.Chmod(ksPath, ks.dirMode()); err != nil {
+			return err
+		}
+	}
 
 	logger.Debugf("KeyStore created at [%s].", ksPath)
 	return nil
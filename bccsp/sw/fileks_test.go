@@ -8,14 +8,17 @@ package sw
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/hyperledger/fabric/bccsp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -138,3 +141,84 @@ func TestDirEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, false, r)
 }
+
+func TestFileKeyCounts(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ksPath := filepath.Join(tempDir, "bccspks")
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.NoError(t, ks.StoreKey(&ecdsaPrivateKey{privKey}))
+	assert.NoError(t, ks.StoreKey(&ecdsaPublicKey{&privKey.PublicKey}))
+	assert.NoError(t, ks.StoreKey(&aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}))
+
+	counts, err := ks.(bccsp.KeyCounter).KeyCounts()
+	assert.NoError(t, err)
+	assert.Equal(t, bccsp.KeyCounts{Private: 1, Public: 1, Symmetric: 1}, counts)
+
+	os.RemoveAll(ksPath)
+	_, err = ks.(bccsp.KeyCounter).KeyCounts()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed reading keystore "+ksPath)
+}
+
+func TestFileListAndDeleteKeys(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ks, err := NewFileBasedKeyStore(nil, tempDir, false)
+	assert.NoError(t, err)
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.NoError(t, ks.StoreKey(&ecdsaPrivateKey{privKey}))
+	aesKey := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	assert.NoError(t, ks.StoreKey(aesKey))
+
+	// keys generated by cryptogen are not named after their SKI
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	raw, err := privateKeyToPEM(edKey, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "priv_sk"), raw, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "README"), []byte("not a key"), 0600))
+
+	manager := ks.(bccsp.KeyManager)
+	keys, err := manager.ListKeys()
+	assert.NoError(t, err)
+	var skis []string
+	for _, k := range keys {
+		skis = append(skis, hex.EncodeToString(k.SKI()))
+	}
+	edSKI := (&ed25519PrivateKey{edKey}).SKI()
+	assert.ElementsMatch(t, []string{
+		hex.EncodeToString((&ecdsaPrivateKey{privKey}).SKI()),
+		hex.EncodeToString(aesKey.SKI()),
+		hex.EncodeToString(edSKI),
+	}, skis)
+
+	assert.NoError(t, manager.DeleteKey(edSKI))
+	_, err = os.Stat(filepath.Join(tempDir, "priv_sk"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, manager.DeleteKey(aesKey.SKI()))
+	keys, err = manager.ListKeys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	err = manager.DeleteKey(edSKI)
+	assert.EqualError(t, err, fmt.Sprintf("key with SKI %x not found in %s", edSKI, tempDir))
+
+	readOnly, err := NewFileBasedKeyStore(nil, tempDir, true)
+	assert.NoError(t, err)
+	assert.EqualError(t, readOnly.(bccsp.KeyManager).DeleteKey(keys[0].SKI()), "read only KeyStore")
+}
@@ -0,0 +1,257 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+// integrityDir is the folder of the KeyStore holding the MACs of its key
+// files, one file per key file, named after it.
+const integrityDir = "integrity"
+
+// integrityKeySize is the size of the master keys of the MACs.
+const integrityKeySize = 32
+
+// reasonNoMAC is the reason of the integrity errors of the key files without
+// a MAC.
+const reasonNoMAC = "no MAC found"
+
+// IntegrityError is returned for the key files whose MAC is missing or does
+// not match their content, because they were corrupted or tampered with.
+type IntegrityError struct {
+	Path   string
+	Reason string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("integrity check of key file %s failed: %s", e.Path, e.Reason)
+}
+
+// NewFileBasedKeyStoreWithIntegrity returns a file-based key store which
+// protects each of its key files with a MAC keyed by macKey. The MACs are
+// verified when the keys are loaded, and all of them when the key store is
+// opened, which fails for the key files without a MAC. The key files stored
+// before the protection was enabled are given one by ProtectKeyFiles.
+func NewFileBasedKeyStoreWithIntegrity(pwd []byte, path string, readOnly bool, macKey []byte) (bccsp.KeyStore, error) {
+	if len(macKey) < integrityKeySize {
+		return nil, fmt.Errorf("invalid integrity key: it must be at least %d bytes long", integrityKeySize)
+	}
+	return NewFileBasedKeyStoreWithOpts(pwd, path, readOnly, &FileKeyStoreOpts{IntegrityKey: macKey})
+}
+
+// LoadIntegrityKey reads the master key of the MACs of a key store from the
+// file at path, which is generated when it does not exist.
+func LoadIntegrityKey(path string) ([]byte, error) {
+	key, err := ioutil.ReadFile(path)
+	if err == nil {
+		if len(key) < integrityKeySize {
+			return nil, fmt.Errorf("invalid integrity key %s: it must be at least %d bytes long", path, integrityKeySize)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed reading integrity key %s: %s", path, err)
+	}
+
+	key = make([]byte, integrityKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed generating integrity key: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed writing integrity key %s: %s", path, err)
+	}
+	if err := ioutil.WriteFile(path, key, 0400); err != nil {
+		return nil, fmt.Errorf("failed writing integrity key %s: %s", path, err)
+	}
+	logger.Infof("Generated the integrity key of the keystore at %s", path)
+	return key, nil
+}
+
+// readKeyFile reads a key file, verifying its permissions and its MAC when
+// the key store enforces them, and unprotecting it when it is protected.
+func (ks *fileBasedKeyStore) readKeyFile(path string) ([]byte, error) {
+	if ks.perms != nil {
+		if err := ks.checkKeyFile(path); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ks.macKey != nil {
+		if err := ks.verifyMAC(path, raw); err != nil {
+			return nil, err
+		}
+	}
+	return ks.unprotect(path, raw)
+}
+
+// writeKeyFile writes a key file with the mode of the key store, protected
+// by its protector, along with its MAC when the key store is protected.
+func (ks *fileBasedKeyStore) writeKeyFile(path string, raw []byte) error {
+	if ks.protector != nil {
+		var err error
+		if raw, err = ks.protect(path, raw); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(path, raw, ks.fileMode()); err != nil {
+		return err
+	}
+	if ks.perms != nil {
+		// the mode of an existing file is left unchanged by WriteFile
+		if err := os.Chmod(path, ks.fileMode()); err != nil {
+			return err
+		}
+	}
+	if ks.macKey == nil {
+		return nil
+	}
+	return ks.writeMAC(path, raw)
+}
+
+// removeKeyFile removes a key file, along with its MAC.
+func (ks *fileBasedKeyStore) removeKeyFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if err := os.Remove(ks.macPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (ks *fileBasedKeyStore) verifyMAC(path string, raw []byte) error {
+	expected, err := ioutil.ReadFile(ks.macPath(path))
+	if os.IsNotExist(err) {
+		return &IntegrityError{Path: path, Reason: reasonNoMAC}
+	}
+	if err != nil {
+		return &IntegrityError{Path: path, Reason: fmt.Sprintf("failed reading MAC: %s", err)}
+	}
+	mac, err := hex.DecodeString(strings.TrimSpace(string(expected)))
+	if err != nil || !hmac.Equal(mac, ks.mac(path, raw)) {
+		return &IntegrityError{Path: path, Reason: "the key file does not match its MAC"}
+	}
+	return nil
+}
+
+func (ks *fileBasedKeyStore) writeMAC(path string, raw []byte) error {
+	if err := os.MkdirAll(filepath.Join(ks.path, integrityDir), 0700); err != nil {
+		return fmt.Errorf("failed storing MAC of key file %s: %s", path, err)
+	}
+	mac := hex.EncodeToString(ks.mac(path, raw))
+	if err := ioutil.WriteFile(ks.macPath(path), []byte(mac), 0600); err != nil {
+		return fmt.Errorf("failed storing MAC of key file %s: %s", path, err)
+	}
+	return nil
+}
+
+// mac returns the MAC of the content of a key file, bound to the name of the
+// file, which carries the SKI and the type of the key.
+func (ks *fileBasedKeyStore) mac(path string, raw []byte) []byte {
+	h := hmac.New(sha256.New, ks.macKey)
+	h.Write([]byte(filepath.Base(path)))
+	h.Write([]byte{0})
+	h.Write(raw)
+	return h.Sum(nil)
+}
+
+func (ks *fileBasedKeyStore) macPath(path string) string {
+	return filepath.Join(ks.path, integrityDir, filepath.Base(path))
+}
+
+// ProtectKeyFiles gives a MAC keyed by macKey to the key files of the key
+// store at path which have none, such as those stored before the protection
+// was enabled, and returns their names. It is a one-time migration that an
+// operator runs explicitly: nothing is written when the MAC of a key file
+// does not match its content.
+func ProtectKeyFiles(path string, macKey []byte) ([]string, error) {
+	if len(macKey) < integrityKeySize {
+		return nil, fmt.Errorf("invalid integrity key: it must be at least %d bytes long", integrityKeySize)
+	}
+	ks := &fileBasedKeyStore{path: path, macKey: macKey}
+	var unprotected []string
+	raws := map[string][]byte{}
+	err := ks.checkKeyFiles(func(path string, raw []byte, err error) error {
+		if ie, ok := err.(*IntegrityError); ok && ie.Reason == reasonNoMAC {
+			unprotected = append(unprotected, filepath.Base(path))
+			raws[path] = raw
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range unprotected {
+		path := filepath.Join(ks.path, name)
+		if err := ks.writeMAC(path, raws[path]); err != nil {
+			return nil, err
+		}
+		logger.Infof("Key file %s is protected by a MAC from now on", path)
+	}
+	return unprotected, nil
+}
+
+// verifyKeyFiles verifies the MACs of all the key files.
+func (ks *fileBasedKeyStore) verifyKeyFiles() error {
+	return ks.checkKeyFiles(func(path string, raw []byte, err error) error {
+		if ie, ok := err.(*IntegrityError); ok && ie.Reason == reasonNoMAC {
+			logger.Errorf("%s: the key files stored before the protection was enabled must be given a MAC once, with peer keystore protect", err)
+			return err
+		}
+		if err != nil {
+			logger.Errorf("%s", err)
+		}
+		return err
+	})
+}
+
+// checkKeyFiles verifies the MAC of each key file, passing the outcome to
+// check, and fails for the key files check returns an error for.
+func (ks *fileBasedKeyStore) checkKeyFiles(check func(path string, raw []byte, err error) error) error {
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+
+	var failed []string
+	for _, f := range files {
+		if f.IsDir() || !isKeyFile(f.Name()) {
+			continue
+		}
+		path := filepath.Join(ks.path, f.Name())
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed reading key file %s: %s", path, err)
+		}
+		if err := check(path, raw, ks.verifyMAC(path, raw)); err != nil {
+			failed = append(failed, f.Name())
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("integrity check of keystore %s failed for key files %s", ks.path, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func isKeyFile(name string) bool {
+	return strings.HasSuffix(name, "sk") || strings.HasSuffix(name, "pk") || strings.HasSuffix(name, "key")
+}
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeyStoreIntegrity(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "integrity")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "keystore")
+	macKey, err := LoadIntegrityKey(filepath.Join(tempDir, "integrity.key"))
+	require.NoError(t, err)
+
+	ks, err := NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	edKey, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{})
+	require.NoError(t, err)
+
+	for _, k := range []bccsp.Key{ecKey, aesKey, edKey} {
+		loaded, err := ks.GetKey(k.SKI())
+		require.NoError(t, err)
+		assert.Equal(t, k.SKI(), loaded.SKI())
+	}
+	keys, err := ks.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	// a tampered key file is detected when loaded, and when the key store
+	// is opened
+	ecPath := filepath.Join(path, hex.EncodeToString(ecKey.SKI())+"_sk")
+	raw, err := ioutil.ReadFile(ecPath)
+	require.NoError(t, err)
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)/2] ^= 1
+	require.NoError(t, ioutil.WriteFile(ecPath, tampered, 0600))
+	_, err = ks.GetKey(ecKey.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("failed loading secret key [%x] [integrity check of key file %s failed: the key file does not match its MAC]", ecKey.SKI(), ecPath))
+	keys, err = ks.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	_, err = NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	assert.EqualError(t, err, fmt.Sprintf("integrity check of keystore %s failed for key files %x_sk", path, ecKey.SKI()))
+	require.NoError(t, ioutil.WriteFile(ecPath, raw, 0600))
+
+	// the MAC is bound to the name of the key file
+	aesPath := filepath.Join(path, hex.EncodeToString(aesKey.SKI())+"_key")
+	aesRaw, err := ioutil.ReadFile(aesPath)
+	require.NoError(t, err)
+	otherPath := filepath.Join(path, hex.EncodeToString(ecKey.SKI())+"_key")
+	require.NoError(t, ioutil.WriteFile(otherPath, aesRaw, 0600))
+	_, err = ks.(*fileBasedKeyStore).readKeyFile(otherPath)
+	assert.IsType(t, &IntegrityError{}, err)
+	require.NoError(t, os.Remove(otherPath))
+
+	// a key file without MAC is rejected once the key store is open
+	macPath := filepath.Join(path, integrityDir, hex.EncodeToString(edKey.SKI())+"_sk")
+	require.NoError(t, os.Remove(macPath))
+	_, err = ks.GetKey(edKey.SKI())
+	assert.Contains(t, err.Error(), "no MAC found")
+
+	// a key file without MAC fails the opening of the key store
+	_, err = NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	assert.EqualError(t, err, fmt.Sprintf("integrity check of keystore %s failed for key files %x_sk", path, edKey.SKI()))
+	_, err = os.Stat(macPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// deleting a key deletes its MAC
+	require.NoError(t, ks.(bccsp.KeyManager).DeleteKey(aesKey.SKI()))
+	_, err = os.Stat(filepath.Join(path, integrityDir, hex.EncodeToString(aesKey.SKI())+"_key"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the key files stored before the protection was enabled are protected
+	// by the migration
+	protected, err := ProtectKeyFiles(path, macKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{hex.EncodeToString(edKey.SKI()) + "_sk"}, protected)
+	ks, err = NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	require.NoError(t, err)
+	_, err = ks.GetKey(edKey.SKI())
+	require.NoError(t, err)
+	protected, err = ProtectKeyFiles(path, macKey)
+	require.NoError(t, err)
+	assert.Empty(t, protected)
+
+	// another integrity key fails the verification, and the migration
+	otherKey := make([]byte, integrityKeySize)
+	_, err = NewFileBasedKeyStoreWithIntegrity(nil, path, false, otherKey)
+	assert.Error(t, err)
+	_, err = ProtectKeyFiles(path, otherKey)
+	assert.Error(t, err)
+	_, err = ProtectKeyFiles(path, otherKey[:16])
+	assert.EqualError(t, err, "invalid integrity key: it must be at least 32 bytes long")
+	_, err = NewFileBasedKeyStoreWithIntegrity(nil, path, false, otherKey[:16])
+	assert.EqualError(t, err, "invalid integrity key: it must be at least 32 bytes long")
+}
+
+func TestLoadIntegrityKey(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "integrity")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "config", "integrity.key")
+	key, err := LoadIntegrityKey(path)
+	require.NoError(t, err)
+	assert.Len(t, key, integrityKeySize)
+	loaded, err := LoadIntegrityKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	short := filepath.Join(tempDir, "short.key")
+	require.NoError(t, ioutil.WriteFile(short, []byte("short"), 0600))
+	_, err = LoadIntegrityKey(short)
+	assert.EqualError(t, err, fmt.Sprintf("invalid integrity key %s: it must be at least 32 bytes long", short))
+}
@@ -0,0 +1,40 @@
+// +build !windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// modesSupported reports whether the permissions of the key files are
+// enforced on the platform.
+const modesSupported = true
+
+// processUmask returns the umask of the process. The umask can only be read
+// by setting it, so it is set back right away.
+func processUmask() (os.FileMode, bool) {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask), true
+}
+
+// checkOwner returns an error when the file is not owned by the effective
+// user of the process.
+func checkOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := os.Geteuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("%s is owned by uid %d instead of uid %d running the process: run chown %d %s", path, stat.Uid, uid, uid, path)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+// +build windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import "os"
+
+// modesSupported reports whether the permissions of the key files are
+// enforced on the platform. Windows relies on access control lists instead.
+const modesSupported = false
+
+func processUmask() (os.FileMode, bool) {
+	return 0, false
+}
+
+func checkOwner(path string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+// Default permissions of the folder and the key files of a file-based key
+// store enforcing permissions.
+const (
+	DefaultKeyFileMode os.FileMode = 0600
+	DefaultKeyDirMode  os.FileMode = 0700
+)
+
+// FilePermissions are the permissions enforced on the folder and the key
+// files of a file-based key store.
+type FilePermissions struct {
+	// FileMode is the mode of the key files, and the most permissive mode
+	// accepted when loading them. It defaults to 0600.
+	FileMode os.FileMode
+	// DirMode is the mode of the folder of the key store, and the most
+	// permissive mode accepted when opening it. It defaults to 0700.
+	DirMode os.FileMode
+	// AllowPermissive loads the key files and opens the folders more
+	// permissive than their mode, such as world-readable ones, with a
+	// warning instead of refusing them.
+	AllowPermissive bool
+	// CheckOwner requires the folder and the key files to be owned by the
+	// effective user of the process.
+	CheckOwner bool
+	// Umask, when not zero, are the permission bits the umask of the process
+	// must mask when the key store is opened, such as 0077.
+	Umask os.FileMode
+}
+
+// FileKeyStoreOpts are the protections of a file-based key store.
+type FileKeyStoreOpts struct {
+	// IntegrityKey keys the MACs protecting the key files, which are not
+	// protected when it is nil.
+	IntegrityKey []byte
+	// Permissions are the permissions enforced on the key store, which are
+	// not enforced when nil.
+	Permissions *FilePermissions
+	// Protector protects the key files, which are stored in the clear when
+	// it is nil.
+	Protector KeyProtector
+}
+
+// NewFileBasedKeyStoreWithOpts returns a file-based key store with the given
+// protections, which are verified for all the key files when the key store
+// is opened, and for each key file when it is loaded.
+func NewFileBasedKeyStoreWithOpts(pwd []byte, path string, readOnly bool, opts *FileKeyStoreOpts) (bccsp.KeyStore, error) {
+	ks := &fileBasedKeyStore{}
+	if opts != nil && opts.IntegrityKey != nil {
+		if len(opts.IntegrityKey) < integrityKeySize {
+			return nil, fmt.Errorf("invalid integrity key: it must be at least %d bytes long", integrityKeySize)
+		}
+		ks.macKey = append([]byte(nil), opts.IntegrityKey...)
+	}
+	if opts != nil {
+		ks.protector = opts.Protector
+	}
+	if opts != nil && opts.Permissions != nil {
+		perms := *opts.Permissions
+		if perms.FileMode == 0 {
+			perms.FileMode = DefaultKeyFileMode
+		}
+		if perms.DirMode == 0 {
+			perms.DirMode = DefaultKeyDirMode
+		}
+		ks.perms = &perms
+		if err := perms.checkUmask(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ks.Init(pwd, path, readOnly); err != nil {
+		return nil, err
+	}
+	if ks.perms != nil {
+		if err := ks.verifyPermissions(); err != nil {
+			return nil, err
+		}
+	}
+	if ks.macKey != nil {
+		if err := ks.verifyKeyFiles(); err != nil {
+			return nil, err
+		}
+	}
+	if ks.protector != nil {
+		if err := ks.protectKeyFiles(); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+func (p *FilePermissions) checkUmask() error {
+	if p.Umask == 0 {
+		return nil
+	}
+	current, ok := processUmask()
+	if !ok {
+		return nil
+	}
+	if current&p.Umask != p.Umask {
+		return fmt.Errorf("the umask %04o of the process does not mask %04o, so that the key files could be created with permissive modes: start the process with umask %04o", current, p.Umask, current|p.Umask)
+	}
+	return nil
+}
+
+// fileMode returns the mode of the key files.
+func (ks *fileBasedKeyStore) fileMode() os.FileMode {
+	if ks.perms == nil {
+		return 0600
+	}
+	return ks.perms.FileMode
+}
+
+// dirMode returns the mode of the folder of the key store.
+func (ks *fileBasedKeyStore) dirMode() os.FileMode {
+	if ks.perms == nil {
+		return 0755
+	}
+	return ks.perms.DirMode
+}
+
+// verifyPermissions verifies the permissions of the folder and of all the key
+// files of the key store.
+func (ks *fileBasedKeyStore) verifyPermissions() error {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+	if err := ks.checkPermissions(ks.path, info, ks.perms.DirMode); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+	var failed []string
+	for _, f := range files {
+		if f.IsDir() || !isKeyFile(f.Name()) {
+			continue
+		}
+		path := filepath.Join(ks.path, f.Name())
+		if err := ks.checkPermissions(path, f, ks.perms.FileMode); err != nil {
+			logger.Errorf("%s", err)
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("permissions of keystore %s are not enforced: %s", ks.path, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// checkKeyFile verifies the permissions of a key file before it is loaded.
+func (ks *fileBasedKeyStore) checkKeyFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ks.checkPermissions(path, info, ks.perms.FileMode)
+}
+
+// checkPermissions returns an error describing the remediation when the file
+// is more permissive than mode, or not owned by the user of the process.
+func (ks *fileBasedKeyStore) checkPermissions(path string, info os.FileInfo, mode os.FileMode) error {
+	if !modesSupported {
+		return nil
+	}
+	if perm := info.Mode().Perm(); perm&^mode != 0 {
+		err := fmt.Errorf("%s has mode %04o, more permissive than %04o: run chmod %04o %s", path, perm, mode, mode, path)
+		if !ks.perms.AllowPermissive {
+			return err
+		}
+		logger.Warningf("%s", err)
+	}
+	if ks.perms.CheckOwner {
+		return checkOwner(path, info)
+	}
+	return nil
+}
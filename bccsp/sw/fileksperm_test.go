@@ -0,0 +1,101 @@
+// +build !windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeyStorePermissions(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "permissions")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "keystore")
+	opts := &FileKeyStoreOpts{Permissions: &FilePermissions{CheckOwner: true}}
+
+	ks, err := NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeyDirMode, info.Mode().Perm())
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	key, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	keyPath := filepath.Join(path, hex.EncodeToString(key.SKI())+"_sk")
+	info, err = os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeyFileMode, info.Mode().Perm())
+
+	// a world-readable key file is refused when loaded, and when the key
+	// store is opened
+	require.NoError(t, os.Chmod(keyPath, 0644))
+	remediation := fmt.Sprintf("%s has mode 0644, more permissive than 0600: run chmod 0600 %s", keyPath, keyPath)
+	_, err = ks.GetKey(key.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("failed loading secret key [%x] [%s]", key.SKI(), remediation))
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	assert.EqualError(t, err, fmt.Sprintf("permissions of keystore %s are not enforced: %s", path, remediation))
+
+	// unless permissive modes are allowed
+	permissive := &FileKeyStoreOpts{Permissions: &FilePermissions{AllowPermissive: true}}
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, false, permissive)
+	require.NoError(t, err)
+	_, err = ks.GetKey(key.SKI())
+	require.NoError(t, err)
+
+	// storing the key again enforces the mode of its key file
+	require.NoError(t, ks.(*fileBasedKeyStore).StoreKey(key))
+	info, err = os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeyFileMode, info.Mode().Perm())
+
+	// a permissive folder is refused as well
+	require.NoError(t, os.Chmod(path, 0755))
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	assert.EqualError(t, err, fmt.Sprintf("%s has mode 0755, more permissive than 0700: run chmod 0700 %s", path, path))
+	require.NoError(t, os.Chmod(path, 0700))
+
+	// a stricter mode refuses the key files written with the default one
+	strict := &FileKeyStoreOpts{Permissions: &FilePermissions{FileMode: 0400}}
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, strict)
+	assert.Contains(t, err.Error(), "run chmod 0400 "+keyPath)
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, nil)
+	require.NoError(t, err)
+}
+
+func TestFileKeyStoreUmask(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "permissions")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "keystore")
+
+	previous := syscall.Umask(0022)
+	defer syscall.Umask(previous)
+
+	opts := &FileKeyStoreOpts{Permissions: &FilePermissions{Umask: 0077}}
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	assert.EqualError(t, err, "the umask 0022 of the process does not mask 0077, so that the key files could be created with permissive modes: start the process with umask 0077")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	syscall.Umask(0077)
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	require.NoError(t, err)
+}
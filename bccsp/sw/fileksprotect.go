@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// protectedKeyType is the PEM type of the key files protected by a
+// KeyProtector.
+const protectedKeyType = "PROTECTED KEY"
+
+// protectorHeader is the PEM header naming the KeyProtector of a protected
+// key file.
+const protectorHeader = "Protector"
+
+// KeyProtector protects the key files of a file-based key store with a
+// secret kept outside of the key store, such as one managed by the
+// operating system.
+type KeyProtector interface {
+	// Name identifies the protector in the protected key files.
+	Name() string
+
+	// Protect returns the protected form of the content of a key file.
+	Protect(raw []byte) ([]byte, error)
+
+	// Unprotect returns the content of a key file from its protected form.
+	Unprotect(blob []byte) ([]byte, error)
+}
+
+// protect returns the content of a key file protected by the protector of
+// the key store.
+func (ks *fileBasedKeyStore) protect(path string, raw []byte) ([]byte, error) {
+	blob, err := ks.protector.Protect(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed protecting key file %s with %s: %s", path, ks.protector.Name(), err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:    protectedKeyType,
+		Headers: map[string]string{protectorHeader: ks.protector.Name()},
+		Bytes:   blob,
+	}), nil
+}
+
+// unprotect returns the content of a key file, which is returned as is when
+// it is not protected.
+func (ks *fileBasedKeyStore) unprotect(path string, raw []byte) ([]byte, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != protectedKeyType {
+		return raw, nil
+	}
+	name := block.Headers[protectorHeader]
+	if ks.protector == nil {
+		return nil, fmt.Errorf("key file %s is protected by %s, which is not configured", path, name)
+	}
+	if name != ks.protector.Name() {
+		return nil, fmt.Errorf("key file %s is protected by %s instead of %s", path, name, ks.protector.Name())
+	}
+	plain, err := ks.protector.Unprotect(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed unprotecting key file %s with %s: %s", path, name, err)
+	}
+	return plain, nil
+}
+
+// protectKeyFiles protects the key files stored before the protector was
+// configured, so that no key is left in the clear. The key files are left
+// as they are when the key store is read only.
+func (ks *fileBasedKeyStore) protectKeyFiles() error {
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("failed reading keystore %s: %s", ks.path, err)
+	}
+
+	var unprotected []string
+	for _, f := range files {
+		if f.IsDir() || !isKeyFile(f.Name()) {
+			continue
+		}
+		path := filepath.Join(ks.path, f.Name())
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed reading key file %s: %s", path, err)
+		}
+		if block, _ := pem.Decode(raw); block != nil && block.Type == protectedKeyType {
+			continue
+		}
+		if ks.readOnly {
+			unprotected = append(unprotected, f.Name())
+			continue
+		}
+		// the key file is read again to verify its permissions and its MAC
+		// before it is protected
+		raw, err = ks.readKeyFile(path)
+		if err != nil {
+			return err
+		}
+		if err := ks.writeKeyFile(path, raw); err != nil {
+			return err
+		}
+		logger.Infof("Protected key file %s with %s", path, ks.protector.Name())
+	}
+	if len(unprotected) != 0 {
+		logger.Warningf("Key files %s of read only keystore %s are not protected by %s", strings.Join(unprotected, ", "), ks.path, ks.protector.Name())
+	}
+	return nil
+}
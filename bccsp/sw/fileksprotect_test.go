@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xorProtector is a KeyProtector for the tests, which is not meant to
+// protect anything.
+type xorProtector struct {
+	name string
+	fail bool
+}
+
+func (p *xorProtector) Name() string { return p.name }
+
+func (p *xorProtector) Protect(raw []byte) ([]byte, error) {
+	if p.fail {
+		return nil, errors.New("protector unavailable")
+	}
+	return p.xor(raw), nil
+}
+
+func (p *xorProtector) Unprotect(blob []byte) ([]byte, error) {
+	if p.fail {
+		return nil, errors.New("protector unavailable")
+	}
+	return p.xor(blob), nil
+}
+
+func (p *xorProtector) xor(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[i] = b ^ 0x5a
+	}
+	return out
+}
+
+func TestFileKeyStoreProtector(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "protector")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "keystore")
+
+	// the key files stored in the clear are protected when the key store is
+	// opened with a protector
+	ks, err := NewFileBasedKeyStore(nil, path, false)
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	ecPath := filepath.Join(path, hex.EncodeToString(ecKey.SKI())+"_sk")
+	plain, err := ioutil.ReadFile(ecPath)
+	require.NoError(t, err)
+
+	macKey := bytes.Repeat([]byte{1}, integrityKeySize)
+	_, err = ProtectKeyFiles(path, macKey)
+	require.NoError(t, err)
+	opts := &FileKeyStoreOpts{IntegrityKey: macKey, Protector: &xorProtector{name: "XOR"}}
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, false, opts)
+	require.NoError(t, err)
+	raw, err := ioutil.ReadFile(ecPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "-----BEGIN PROTECTED KEY-----\nProtector: XOR\n")
+	assert.NotContains(t, string(raw), "PRIVATE KEY")
+	loaded, err := ks.GetKey(ecKey.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, ecKey.SKI(), loaded.SKI())
+
+	// the new key files are protected
+	csp, err = NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	aesPath := filepath.Join(path, hex.EncodeToString(aesKey.SKI())+"_key")
+	raw, err = ioutil.ReadFile(aesPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "PROTECTED KEY")
+	keys, err := ks.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	// the protected key files cannot be loaded without their protector
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, false, &FileKeyStoreOpts{IntegrityKey: macKey})
+	require.NoError(t, err)
+	_, err = ks.GetKey(aesKey.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("failed loading key [%x] [key file %s is protected by XOR, which is not configured]", aesKey.SKI(), aesPath))
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, false, &FileKeyStoreOpts{Protector: &xorProtector{name: "ROT13"}})
+	require.NoError(t, err)
+	_, err = ks.GetKey(aesKey.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("failed loading key [%x] [key file %s is protected by XOR instead of ROT13]", aesKey.SKI(), aesPath))
+
+	// the failures of the protector are reported
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, false, &FileKeyStoreOpts{Protector: &xorProtector{name: "XOR", fail: true}})
+	require.NoError(t, err)
+	_, err = ks.GetKey(aesKey.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("failed loading key [%x] [failed unprotecting key file %s with XOR: protector unavailable]", aesKey.SKI(), aesPath))
+	require.NoError(t, ioutil.WriteFile(ecPath, plain, 0600))
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, false, &FileKeyStoreOpts{Protector: &xorProtector{name: "XOR", fail: true}})
+	assert.EqualError(t, err, fmt.Sprintf("failed protecting key file %s with XOR: protector unavailable", ecPath))
+
+	// a read only key store leaves the key files in the clear
+	_, err = NewFileBasedKeyStoreWithOpts(nil, path, true, &FileKeyStoreOpts{Protector: &xorProtector{name: "XOR"}})
+	require.NoError(t, err)
+	raw, err = ioutil.ReadFile(ecPath)
+	require.NoError(t, err)
+	assert.Equal(t, plain, raw)
+}
+
+func TestNewDPAPIProtector(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the Data Protection API is available on Windows")
+	}
+	_, err := NewDPAPIProtector(false, nil)
+	assert.EqualError(t, err, "the Data Protection API is only available on Windows")
+}
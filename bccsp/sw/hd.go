@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// hardened is the first hardened index of a hierarchy
+const hardened uint32 = 1 << 31
+
+// hdNode is a node of a SLIP-0010 hierarchy: a private key and its chain code.
+type hdNode struct {
+	algorithm string
+	key       []byte
+	chainCode []byte
+}
+
+// hdMaster returns the master node of the hierarchy of the seed.
+func hdMaster(algorithm string, seed []byte) (*hdNode, error) {
+	var curveKey string
+	switch algorithm {
+	case bccsp.ECDSAP256:
+		curveKey = "Nist256p1 seed"
+	case bccsp.ED25519:
+		curveKey = "ed25519 seed"
+	default:
+		return nil, errors.Errorf("unsupported algorithm %s: keys derived from a seed are ECDSAP256 or ED25519 keys", algorithm)
+	}
+
+	data := seed
+	for {
+		i := hmacSHA512([]byte(curveKey), data)
+		node := &hdNode{algorithm: algorithm, key: i[:32], chainCode: i[32:]}
+		if algorithm == bccsp.ED25519 || validScalar(new(big.Int).SetBytes(node.key)) {
+			return node, nil
+		}
+		data = i
+	}
+}
+
+// child returns the child of the node with the given index.
+func (n *hdNode) child(index uint32) (*hdNode, error) {
+	var data []byte
+	if index >= hardened {
+		data = append([]byte{0}, n.key...)
+	} else {
+		if n.algorithm == bccsp.ED25519 {
+			return nil, errors.Errorf("index %d is not hardened: Ed25519 keys only have hardened indices", index)
+		}
+		// The compressed encoding of the public key
+		x, y := elliptic.P256().ScalarBaseMult(n.key)
+		data = append([]byte{byte(2 + y.Bit(0))}, padded32(x)...)
+	}
+	data = append(data, ser32(index)...)
+
+	for {
+		i := hmacSHA512(n.chainCode, data)
+		child := &hdNode{algorithm: n.algorithm, key: i[:32], chainCode: i[32:]}
+		if n.algorithm == bccsp.ED25519 {
+			return child, nil
+		}
+		il := new(big.Int).SetBytes(child.key)
+		if il.Cmp(elliptic.P256().Params().N) < 0 {
+			k := il.Add(il, new(big.Int).SetBytes(n.key))
+			k.Mod(k, elliptic.P256().Params().N)
+			if k.Sign() != 0 {
+				child.key = padded32(k)
+				return child, nil
+			}
+		}
+		data = append(append([]byte{1}, i[32:]...), ser32(index)...)
+	}
+}
+
+// derive returns the node at the path, such as m/44'/0'/3', relative to the
+// master node n.
+func (n *hdNode) derive(path string) (*hdNode, error) {
+	elements := strings.Split(path, "/")
+	if elements[0] != "m" {
+		return nil, errors.Errorf("invalid path %s: it must start with m", path)
+	}
+	node := n
+	for _, element := range elements[1:] {
+		var offset uint32
+		if trimmed := strings.TrimRight(element, "'hH"); trimmed != element {
+			if len(element)-len(trimmed) != 1 {
+				return nil, errors.Errorf("invalid index %s in path %s", element, path)
+			}
+			element, offset = trimmed, hardened
+		}
+		index, err := strconv.ParseUint(element, 10, 32)
+		if err != nil || uint32(index) >= hardened {
+			return nil, errors.Errorf("invalid index %s in path %s", element, path)
+		}
+		if node, err = node.child(uint32(index) + offset); err != nil {
+			return nil, errors.WithMessagef(err, "invalid path %s", path)
+		}
+	}
+	return node, nil
+}
+
+// privateKey returns the private key of the node.
+func (n *hdNode) privateKey() (bccsp.Key, error) {
+	switch n.algorithm {
+	case bccsp.ED25519:
+		return &ed25519PrivateKey{ed25519.NewKeyFromSeed(n.key)}, nil
+	case bccsp.ECDSAP256:
+		priv := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(n.key)}
+		priv.Curve = elliptic.P256()
+		priv.X, priv.Y = priv.Curve.ScalarBaseMult(n.key)
+		return &ecdsaPrivateKey{priv}, nil
+	default:
+		return nil, errors.Errorf("unsupported algorithm %s", n.algorithm)
+	}
+}
+
+func validScalar(k *big.Int) bool {
+	return k.Sign() != 0 && k.Cmp(elliptic.P256().Params().N) < 0
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// padded32 returns the 32 byte big endian encoding of k
+func padded32(k *big.Int) []byte {
+	raw := make([]byte, 32)
+	b := k.Bytes()
+	copy(raw[32-len(b):], b)
+	return raw
+}
+
+func ser32(i uint32) []byte {
+	raw := make([]byte, 4)
+	binary.BigEndian.PutUint32(raw, i)
+	return raw
+}
+
+type hdSeedImportOptsKeyImporter struct{}
+
+func (*hdSeedImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	seed, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("Invalid raw material. Expected byte array.")
+	}
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.Errorf("Invalid seed of %d bytes. It must be between 16 and 64 bytes long.", len(seed))
+	}
+
+	node, err := hdMaster(opts.(*bccsp.HDSeedImportOpts).KeyAlgorithm, seed)
+	if err != nil {
+		return nil, err
+	}
+	return &hdMasterKey{node}, nil
+}
+
+type hdMasterKeyKeyDeriver struct{}
+
+func (kd *hdMasterKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	hdOpts, ok := opts.(*bccsp.HDKeyDerivOpts)
+	if !ok {
+		return nil, errors.Errorf("Unsupported 'KeyDerivOpts' provided [%v]", opts)
+	}
+
+	node, err := key.(*hdMasterKey).node.derive(hdOpts.Path)
+	if err != nil {
+		return nil, err
+	}
+	return node.privateKey()
+}
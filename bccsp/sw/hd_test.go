@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The vectors are test vector 1 of SLIP-0010.
+func TestHDVectors(t *testing.T) {
+	t.Parallel()
+
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		algorithm string
+		path      string
+		chainCode string
+		key       string
+	}{
+		{bccsp.ECDSAP256, "m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{bccsp.ECDSAP256, "m/0'", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+		{bccsp.ECDSAP256, "m/0H/1", "4187afff1aafa8445010097fb99d23aee9f599450c7bd140b6826ac22ba21d0c", "284e9d38d07d21e4e281b645089a94f4cf5a5a81369acf151a1c3a57f18b2129"},
+		{bccsp.ED25519, "m", "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{bccsp.ED25519, "m/0h", "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+	}
+	for _, tt := range tests {
+		master, err := hdMaster(tt.algorithm, seed)
+		require.NoError(t, err)
+		node, err := master.derive(tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.chainCode, hex.EncodeToString(node.chainCode), "%s %s", tt.algorithm, tt.path)
+		assert.Equal(t, tt.key, hex.EncodeToString(node.key), "%s %s", tt.algorithm, tt.path)
+	}
+}
+
+func TestHDKeyDeriv(t *testing.T) {
+	t.Parallel()
+
+	ks := NewInMemoryKeyStore()
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	seed := make([]byte, 32)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	for _, algorithm := range []string{bccsp.ECDSAP256, bccsp.ED25519} {
+		master, err := csp.KeyImport(seed, &bccsp.HDSeedImportOpts{KeyAlgorithm: algorithm})
+		require.NoError(t, err)
+		assert.True(t, master.Private())
+		assert.False(t, master.Symmetric())
+		_, err = master.Bytes()
+		assert.Error(t, err)
+		_, err = ks.GetKey(master.SKI())
+		assert.Error(t, err, "the master key is not stored")
+
+		device1, err := csp.KeyDeriv(master, &bccsp.HDKeyDerivOpts{Path: "m/44'/1'/1'"})
+		require.NoError(t, err)
+		device2, err := csp.KeyDeriv(master, &bccsp.HDKeyDerivOpts{Path: "m/44'/1'/2'", Temporary: true})
+		require.NoError(t, err)
+		assert.NotEqual(t, device1.SKI(), device2.SKI())
+		assert.NotEqual(t, master.SKI(), device1.SKI())
+
+		// The derived keys are stored unless temporary, and sign as the
+		// generated ones
+		stored, err := csp.GetKey(device1.SKI())
+		require.NoError(t, err)
+		assert.Equal(t, device1.SKI(), stored.SKI())
+		_, err = ks.GetKey(device2.SKI())
+		assert.Error(t, err)
+
+		digest := []byte("message")
+		if algorithm == bccsp.ECDSAP256 {
+			digest, err = csp.Hash(digest, &bccsp.SHA256Opts{})
+			require.NoError(t, err)
+		}
+		signature, err := csp.Sign(device1, digest, nil)
+		require.NoError(t, err)
+		pk, err := device1.PublicKey()
+		require.NoError(t, err)
+		valid, err := csp.Verify(pk, signature, digest, nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		// The keys are recovered from the seed
+		again, err := csp.KeyImport(seed, &bccsp.HDSeedImportOpts{KeyAlgorithm: algorithm})
+		require.NoError(t, err)
+		assert.Equal(t, master.SKI(), again.SKI())
+		recovered, err := csp.KeyDeriv(again, &bccsp.HDKeyDerivOpts{Path: "m/44h/1h/1h", Temporary: true})
+		require.NoError(t, err)
+		assert.Equal(t, device1.SKI(), recovered.SKI())
+		masterPK, err := master.PublicKey()
+		require.NoError(t, err)
+		assert.Equal(t, master.SKI(), masterPK.SKI())
+	}
+}
+
+func TestHDFailures(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	require.NoError(t, err)
+	seed := make([]byte, 16)
+
+	_, err = csp.KeyImport("seed", &bccsp.HDSeedImportOpts{KeyAlgorithm: bccsp.ED25519})
+	assert.EqualError(t, err, "Failed importing key with opts [&{ED25519}]: Invalid raw material. Expected byte array.")
+	_, err = csp.KeyImport(seed[:8], &bccsp.HDSeedImportOpts{KeyAlgorithm: bccsp.ED25519})
+	assert.Contains(t, err.Error(), "Invalid seed of 8 bytes. It must be between 16 and 64 bytes long.")
+	_, err = csp.KeyImport(seed, &bccsp.HDSeedImportOpts{KeyAlgorithm: bccsp.ECDSAP384})
+	assert.Contains(t, err.Error(), "unsupported algorithm ECDSAP384: keys derived from a seed are ECDSAP256 or ED25519 keys")
+
+	master, err := csp.KeyImport(seed, &bccsp.HDSeedImportOpts{KeyAlgorithm: bccsp.ED25519})
+	require.NoError(t, err)
+	for path, expected := range map[string]string{
+		"44'/0'":        "invalid path 44'/0': it must start with m",
+		"m/x'":          "invalid index x in path m/x'",
+		"m/1''":         "invalid index 1'' in path m/1''",
+		"m/2147483648'": "invalid index 2147483648 in path m/2147483648'",
+		"m/0'/1":        "invalid path m/0'/1: index 1 is not hardened: Ed25519 keys only have hardened indices",
+	} {
+		_, err = csp.KeyDeriv(master, &bccsp.HDKeyDerivOpts{Path: path, Temporary: true})
+		require.Error(t, err, path)
+		assert.Contains(t, err.Error(), expected)
+	}
+
+	_, err = csp.KeyDeriv(master, &bccsp.ECDSAReRandKeyOpts{Temporary: true})
+	assert.Contains(t, err.Error(), "Unsupported 'KeyDerivOpts' provided")
+}
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+// hdMasterKey is the master key of a hierarchy of keys derived from a seed.
+// It only derives keys: its private key does not sign.
+type hdMasterKey struct {
+	node *hdNode
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *hdMasterKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *hdMasterKey) SKI() []byte {
+	pk, err := k.node.privateKey()
+	if err != nil {
+		return nil
+	}
+	return pk.SKI()
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *hdMasterKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *hdMasterKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *hdMasterKey) PublicKey() (bccsp.Key, error) {
+	pk, err := k.node.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	return pk.PublicKey()
+}
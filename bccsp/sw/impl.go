@@ -338,3 +338,37 @@ func (csp *CSP) AddWrapper(t reflect.Type, w interface{}) error {
 	}
 	return nil
 }
+
+// Status reports the keys held by the KeyStore of the CSP. The software-based
+// CSP relies on the cryptography of the Go standard library, which is not
+// a FIPS validated module.
+func (csp *CSP) Status() (*bccsp.Status, error) {
+	status := &bccsp.Status{Provider: "SW"}
+	if counter, ok := csp.ks.(bccsp.KeyCounter); ok {
+		counts, err := counter.KeyCounts()
+		if err != nil {
+			return status, errors.WithMessage(err, "failed counting keys")
+		}
+		status.Keys = counts
+	}
+	return status, nil
+}
+
+// ListKeys returns the keys held by the KeyStore of the CSP.
+func (csp *CSP) ListKeys() ([]bccsp.Key, error) {
+	manager, ok := csp.ks.(bccsp.KeyManager)
+	if !ok {
+		return nil, errors.New("the KeyStore does not support listing keys")
+	}
+	return manager.ListKeys()
+}
+
+// DeleteKey deletes the keys whose SKI is the one passed from the KeyStore
+// of the CSP.
+func (csp *CSP) DeleteKey(ski []byte) error {
+	manager, ok := csp.ks.(bccsp.KeyManager)
+	if !ok {
+		return errors.New("the KeyStore does not support deleting keys")
+	}
+	return manager.DeleteKey(ski)
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric/bccsp"
+	mocks2 "github.com/hyperledger/fabric/bccsp/mocks"
 	"github.com/hyperledger/fabric/bccsp/signer"
 	"github.com/hyperledger/fabric/bccsp/sw/mocks"
 	"github.com/hyperledger/fabric/bccsp/utils"
@@ -1397,3 +1398,48 @@ func getCryptoHashIndex(t *testing.T) crypto.Hash {
 
 	return crypto.SHA3_256
 }
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewInMemoryKeyStore())
+	assert.NoError(t, err)
+	_, err = csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	status, err := csp.(bccsp.StatusReporter).Status()
+	assert.NoError(t, err)
+	assert.Equal(t, &bccsp.Status{
+		Provider: "SW",
+		Keys:     bccsp.KeyCounts{Private: 1},
+	}, status)
+
+	csp, err = NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	status, err = csp.(bccsp.StatusReporter).Status()
+	assert.NoError(t, err)
+	assert.Equal(t, &bccsp.Status{Provider: "SW"}, status)
+}
+
+func TestListAndDeleteKeys(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewInMemoryKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	manager := csp.(bccsp.KeyManager)
+	keys, err := manager.ListKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []bccsp.Key{k}, keys)
+	assert.NoError(t, manager.DeleteKey(k.SKI()))
+	_, err = csp.GetKey(k.SKI())
+	assert.Error(t, err)
+
+	csp, err = NewDefaultSecurityLevelWithKeystore(&mocks2.KeyStore{})
+	assert.NoError(t, err)
+	_, err = csp.(bccsp.KeyManager).ListKeys()
+	assert.EqualError(t, err, "the KeyStore does not support listing keys")
+	assert.EqualError(t, csp.(bccsp.KeyManager).DeleteKey(k.SKI()), "the KeyStore does not support deleting keys")
+}
@@ -66,3 +66,48 @@ func (ks *inmemoryKeyStore) StoreKey(k bccsp.Key) error {
 
 	return nil
 }
+
+// KeyCounts counts the keys held by the key store.
+func (ks *inmemoryKeyStore) KeyCounts() (bccsp.KeyCounts, error) {
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	var counts bccsp.KeyCounts
+	for _, k := range ks.keys {
+		switch {
+		case k.Symmetric():
+			counts.Symmetric++
+		case k.Private():
+			counts.Private++
+		default:
+			counts.Public++
+		}
+	}
+	return counts, nil
+}
+
+// ListKeys returns the keys held by the key store.
+func (ks *inmemoryKeyStore) ListKeys() ([]bccsp.Key, error) {
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	keys := make([]bccsp.Key, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// DeleteKey deletes the key whose SKI is the one passed.
+func (ks *inmemoryKeyStore) DeleteKey(ski []byte) error {
+	skiStr := hex.EncodeToString(ski)
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if _, found := ks.keys[skiStr]; !found {
+		return errors.Errorf("no key found for ski %x", ski)
+	}
+	delete(ks.keys, skiStr)
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/hyperledger/fabric/bccsp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,3 +91,38 @@ func TestStoreExisting(t *testing.T) {
 	err = ks.StoreKey(cspKey)
 	assert.EqualError(t, err, fmt.Sprintf("ski %x already exists in the keystore", cspKey.SKI()))
 }
+
+func TestInMemoryKeyCounts(t *testing.T) {
+	t.Parallel()
+
+	ks := NewInMemoryKeyStore()
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.NoError(t, ks.StoreKey(&ecdsaPrivateKey{privKey}))
+	assert.NoError(t, ks.StoreKey(&aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}))
+
+	counts, err := ks.(bccsp.KeyCounter).KeyCounts()
+	assert.NoError(t, err)
+	assert.Equal(t, bccsp.KeyCounts{Private: 1, Symmetric: 1}, counts)
+}
+
+func TestInMemoryListAndDeleteKeys(t *testing.T) {
+	t.Parallel()
+
+	ks := NewInMemoryKeyStore()
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	key := &ecdsaPrivateKey{privKey}
+	assert.NoError(t, ks.StoreKey(key))
+
+	manager := ks.(bccsp.KeyManager)
+	keys, err := manager.ListKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []bccsp.Key{key}, keys)
+
+	assert.NoError(t, manager.DeleteKey(key.SKI()))
+	keys, err = manager.ListKeys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.EqualError(t, manager.DeleteKey(key.SKI()), fmt.Sprintf("no key found for ski %x", key.SKI()))
+}
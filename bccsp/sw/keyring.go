@@ -0,0 +1,420 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Kinds of the keys held by the kernel keyring, which prefix the description
+// of their key material.
+const (
+	keyringECDSA   = "ecdsa"
+	keyringED25519 = "ed25519"
+	keyringAES     = "aes"
+)
+
+// KernelKeyringOpts configures a key store holding key material in the
+// kernel keyring.
+type KernelKeyringOpts struct {
+	// Keyring is the keyring holding the key material: session, user or
+	// process. It defaults to the session keyring.
+	Keyring string
+	// Timeout is the time after which the key material expires from the
+	// keyring, to be loaded again from the backing key store when it is
+	// used. The key material does not expire when it is zero.
+	Timeout time.Duration
+}
+
+// NewKernelKeyringKeyStore returns a key store holding the material of the
+// private and symmetric keys in the kernel keyring, out of the memory of the
+// process, which is thus not exposed to memory scraping or core dumps. The
+// material of the keys is read from the keyring for each operation, and
+// wiped right after it.
+//
+// The keys are stored to, and loaded first from, the backing key store,
+// such as a file-based key store, which the keyring caches. When backing is
+// nil, the keys only live in the keyring. The keys returned by the key
+// store only support signing, verification, encryption and decryption.
+func NewKernelKeyringKeyStore(backing bccsp.KeyStore, opts KernelKeyringOpts) (bccsp.KeyStore, error) {
+	ring, err := keyringID(opts.Keyring)
+	if err != nil {
+		return nil, err
+	}
+	return &kernelKeyringKeyStore{backing: backing, ring: ring, timeout: opts.Timeout}, nil
+}
+
+type kernelKeyringKeyStore struct {
+	backing bccsp.KeyStore
+	ring    int
+	timeout time.Duration
+
+	// m serializes the caching of the keys of the backing key store
+	m sync.Mutex
+}
+
+// ReadOnly returns true if the backing key store is read only.
+func (ks *kernelKeyringKeyStore) ReadOnly() bool {
+	return ks.backing != nil && ks.backing.ReadOnly()
+}
+
+// GetKey returns the key whose SKI is ski, from the keyring, or from the
+// backing key store, whose key material is then cached in the keyring.
+func (ks *kernelKeyringKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	if len(ski) == 0 {
+		return nil, errors.New("invalid SKI. Cannot be of zero length.")
+	}
+
+	for _, kind := range []string{keyringECDSA, keyringED25519, keyringAES} {
+		id, err := keyringSearch(ks.ring, keyringDescription(kind, ski))
+		if err != nil {
+			continue
+		}
+		k, err := ks.keyringKey(id, kind, ski)
+		if err != nil {
+			return nil, err
+		}
+		return k, nil
+	}
+
+	if ks.backing == nil {
+		return nil, errors.Errorf("no key found for ski %x in the kernel keyring", ski)
+	}
+	k, err := ks.backing.GetKey(ski)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := ks.cache(k)
+	if err != nil {
+		return nil, err
+	}
+	if cached == nil {
+		return k, nil
+	}
+	return cached, nil
+}
+
+// StoreKey stores the key in the backing key store, and its material in
+// the keyring.
+func (ks *kernelKeyringKeyStore) StoreKey(k bccsp.Key) error {
+	if k == nil {
+		return errors.New("Invalid key. It must be different from nil.")
+	}
+	if ks.backing != nil {
+		if err := ks.backing.StoreKey(k); err != nil {
+			return err
+		}
+	}
+
+	cached, err := ks.cache(k)
+	if err != nil {
+		if ks.backing == nil {
+			return err
+		}
+		// the key is safe in the backing key store
+		logger.Warningf("Failed caching key %x in the kernel keyring: %s", k.SKI(), err)
+		return nil
+	}
+	if cached == nil && ks.backing == nil {
+		return errors.Errorf("cannot store key of type %T in the kernel keyring", k)
+	}
+	return nil
+}
+
+// ListKeys returns the keys of the backing key store, those whose material
+// is held by the keyring included.
+func (ks *kernelKeyringKeyStore) ListKeys() ([]bccsp.Key, error) {
+	manager, ok := ks.backing.(bccsp.KeyManager)
+	if !ok {
+		return nil, errors.New("the backing key store does not list its keys")
+	}
+	keys, err := manager.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		if kind, _, _ := keyringMaterial(k); kind == "" {
+			continue
+		}
+		if keys[i], err = ks.GetKey(k.SKI()); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// DeleteKey deletes the key from the keyring, and from the backing key
+// store.
+func (ks *kernelKeyringKeyStore) DeleteKey(ski []byte) error {
+	deleted := false
+	for _, kind := range []string{keyringECDSA, keyringED25519, keyringAES} {
+		id, err := keyringSearch(ks.ring, keyringDescription(kind, ski))
+		if err != nil {
+			continue
+		}
+		if err := keyringUnlink(id, ks.ring); err != nil {
+			return errors.Wrapf(err, "failed deleting key %x from the kernel keyring", ski)
+		}
+		deleted = true
+	}
+
+	if manager, ok := ks.backing.(bccsp.KeyManager); ok {
+		return manager.DeleteKey(ski)
+	}
+	if !deleted {
+		return errors.Errorf("key with SKI %x not found in the kernel keyring", ski)
+	}
+	return nil
+}
+
+// KeyCounts counts the keys of the backing key store.
+func (ks *kernelKeyringKeyStore) KeyCounts() (bccsp.KeyCounts, error) {
+	counter, ok := ks.backing.(bccsp.KeyCounter)
+	if !ok {
+		return bccsp.KeyCounts{}, errors.New("the backing key store does not count its keys")
+	}
+	return counter.KeyCounts()
+}
+
+// cache adds the material of the key to the keyring, and returns the key
+// backed by the keyring, or nil if the key has no material to protect.
+func (ks *kernelKeyringKeyStore) cache(k bccsp.Key) (bccsp.Key, error) {
+	kind, material, err := keyringMaterial(k)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		return nil, nil
+	}
+	defer zeroize(material)
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+	id, err := keyringAdd(keyringDescription(kind, k.SKI()), material, ks.ring)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed adding key %x to the kernel keyring", k.SKI())
+	}
+	if ks.timeout > 0 {
+		if err := keyringSetTimeout(id, ks.timeout); err != nil {
+			return nil, errors.Wrapf(err, "failed setting the timeout of key %x in the kernel keyring", k.SKI())
+		}
+	}
+
+	var pub bccsp.Key
+	if kind != keyringAES {
+		if pub, err = k.PublicKey(); err != nil {
+			return nil, err
+		}
+	}
+	return &keyringKey{ks: ks, id: id, kind: kind, ski: k.SKI(), pub: pub}, nil
+}
+
+// keyringKey returns the key of the keyring with the given id, loading its
+// material once to get its public key.
+func (ks *kernelKeyringKeyStore) keyringKey(id int, kind string, ski []byte) (bccsp.Key, error) {
+	k := &keyringKey{ks: ks, id: id, kind: kind, ski: ski}
+	if kind == keyringAES {
+		return k, nil
+	}
+	err := k.use(func(key bccsp.Key) error {
+		var err error
+		k.pub, err = key.PublicKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// material returns the material of the key, which is cached again from the
+// backing key store when it expired from the keyring.
+func (ks *kernelKeyringKeyStore) material(k *keyringKey) ([]byte, error) {
+	material, err := keyringRead(k.id)
+	if err == nil {
+		return material, nil
+	}
+	if ks.backing == nil || !keyringExpired(err) {
+		return nil, errors.Wrapf(err, "failed reading key %x from the kernel keyring", k.ski)
+	}
+
+	backed, err := ks.backing.GetKey(k.ski)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := ks.cache(backed)
+	if err != nil {
+		return nil, err
+	}
+	kk, ok := cached.(*keyringKey)
+	if !ok {
+		return nil, errors.Errorf("key %x cannot be held by the kernel keyring", k.ski)
+	}
+	k.id = kk.id
+	return keyringRead(k.id)
+}
+
+// keyringMaterial returns the kind and the material of the private and
+// symmetric keys, or an empty kind for the other keys.
+func keyringMaterial(k bccsp.Key) (string, []byte, error) {
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		der, err := x509.MarshalECPrivateKey(kk.privKey)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed marshalling key %x", k.SKI())
+		}
+		return keyringECDSA, der, nil
+	case *ed25519PrivateKey:
+		return keyringED25519, append([]byte(nil), kk.privKey...), nil
+	case *aesPrivateKey:
+		return keyringAES, append([]byte(nil), kk.privKey...), nil
+	default:
+		return "", nil, nil
+	}
+}
+
+func keyringDescription(kind string, ski []byte) string {
+	return fmt.Sprintf("fabric:%s:%x", kind, ski)
+}
+
+// keyringKey is a private or symmetric key whose material is held by the
+// kernel keyring.
+type keyringKey struct {
+	ks   *kernelKeyringKeyStore
+	id   int
+	kind string
+	ski  []byte
+	// pub is the public key of asymmetric keys
+	pub bccsp.Key
+}
+
+// use calls f with the key loaded from the keyring, whose material is wiped
+// when f returns.
+func (k *keyringKey) use(f func(bccsp.Key) error) error {
+	material, err := k.ks.material(k)
+	if err != nil {
+		return err
+	}
+	defer zeroize(material)
+
+	switch k.kind {
+	case keyringECDSA:
+		priv, err := x509.ParseECPrivateKey(material)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing key %x from the kernel keyring", k.ski)
+		}
+		defer zeroizeECDSA(priv)
+		return f(&ecdsaPrivateKey{priv})
+	case keyringED25519:
+		if len(material) != ed25519.PrivateKeySize {
+			return errors.Errorf("invalid key %x in the kernel keyring", k.ski)
+		}
+		return f(&ed25519PrivateKey{ed25519.PrivateKey(material)})
+	default:
+		return f(&aesPrivateKey{material, false})
+	}
+}
+
+func zeroizeECDSA(priv *ecdsa.PrivateKey) {
+	words := priv.D.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *keyringKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *keyringKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *keyringKey) Symmetric() bool {
+	return k.kind == keyringAES
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *keyringKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *keyringKey) PublicKey() (bccsp.Key, error) {
+	if k.pub == nil {
+		return nil, errors.New("Cannot call this method on a symmetric key.")
+	}
+	return k.pub, nil
+}
+
+// Fingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key.
+func (k *keyringKey) Fingerprint() ([]byte, error) {
+	fingerprinter, ok := k.pub.(bccsp.KeyFingerprinter)
+	if !ok {
+		return nil, errors.New("Cannot call this method on a symmetric key.")
+	}
+	return fingerprinter.Fingerprint()
+}
+
+// The operations on the keys of the kernel keyring are performed with the
+// keys loaded from the keyring for the time of the operation.
+
+type keyringKeyEncryptor struct{ csp *CSP }
+
+func (e *keyringKeyEncryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error) {
+	err = k.(*keyringKey).use(func(key bccsp.Key) error {
+		ciphertext, err = e.csp.Encrypt(key, plaintext, opts)
+		return err
+	})
+	return ciphertext, err
+}
+
+type keyringKeyDecryptor struct{ csp *CSP }
+
+func (d *keyringKeyDecryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
+	err = k.(*keyringKey).use(func(key bccsp.Key) error {
+		plaintext, err = d.csp.Decrypt(key, ciphertext, opts)
+		return err
+	})
+	return plaintext, err
+}
+
+type keyringKeySigner struct{ csp *CSP }
+
+func (s *keyringKeySigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+	err = k.(*keyringKey).use(func(key bccsp.Key) error {
+		signature, err = s.csp.Sign(key, digest, opts)
+		return err
+	})
+	return signature, err
+}
+
+type keyringKeyVerifier struct{ csp *CSP }
+
+func (v *keyringKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return false, err
+	}
+	return v.csp.Verify(pub, signature, digest, opts)
+}
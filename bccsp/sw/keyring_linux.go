@@ -0,0 +1,90 @@
+// +build linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// keyringPossessorAll grants all the permissions to the possessors of the
+// keys, which are the processes of the keyring, and none to the others.
+const keyringPossessorAll = 0x3f000000
+
+// keyringID returns the serial number of the named keyring, which is created
+// when missing.
+func keyringID(name string) (int, error) {
+	var spec int
+	switch name {
+	case "", "session":
+		spec = unix.KEY_SPEC_SESSION_KEYRING
+	case "user":
+		spec = unix.KEY_SPEC_USER_KEYRING
+	case "process":
+		spec = unix.KEY_SPEC_PROCESS_KEYRING
+	default:
+		return 0, fmt.Errorf("unknown kernel keyring %s: must be session, user or process", name)
+	}
+	id, err := unix.KeyctlGetKeyringID(spec, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed opening the %s kernel keyring: %s", name, err)
+	}
+	return id, nil
+}
+
+// keyringAdd adds a key of the user type to the keyring, or updates it.
+func keyringAdd(description string, payload []byte, ring int) (int, error) {
+	id, err := unix.AddKey("user", description, payload, ring)
+	if err != nil {
+		return 0, err
+	}
+	if err := unix.KeyctlSetperm(id, keyringPossessorAll); err != nil {
+		unix.KeyctlInt(unix.KEYCTL_UNLINK, id, ring, 0, 0)
+		return 0, err
+	}
+	return id, nil
+}
+
+func keyringSearch(ring int, description string) (int, error) {
+	return unix.KeyctlSearch(ring, "user", description, 0)
+}
+
+// keyringRead reads the payload of a key, whose size is first queried.
+func keyringRead(id int) ([]byte, error) {
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, payload, 0)
+	if err != nil {
+		zeroize(payload)
+		return nil, err
+	}
+	return payload[:n], nil
+}
+
+func keyringUnlink(id, ring int) error {
+	_, err := unix.KeyctlInt(unix.KEYCTL_UNLINK, id, ring, 0, 0)
+	return err
+}
+
+func keyringSetTimeout(id int, timeout time.Duration) error {
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	_, err := unix.KeyctlInt(unix.KEYCTL_SET_TIMEOUT, id, seconds, 0, 0)
+	return err
+}
+
+// keyringExpired returns true for the errors of the keys which expired, or
+// were revoked or garbage collected.
+func keyringExpired(err error) bool {
+	return err == unix.EKEYEXPIRED || err == unix.EKEYREVOKED || err == unix.ENOKEY
+}
@@ -0,0 +1,44 @@
+// +build !linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"errors"
+	"time"
+)
+
+var errNoKeyring = errors.New("the kernel keyring is only available on Linux")
+
+func keyringID(name string) (int, error) {
+	return 0, errNoKeyring
+}
+
+func keyringAdd(description string, payload []byte, ring int) (int, error) {
+	return 0, errNoKeyring
+}
+
+func keyringSearch(ring int, description string) (int, error) {
+	return 0, errNoKeyring
+}
+
+func keyringRead(id int) ([]byte, error) {
+	return nil, errNoKeyring
+}
+
+func keyringUnlink(id, ring int) error {
+	return errNoKeyring
+}
+
+func keyringSetTimeout(id int, timeout time.Duration) error {
+	return errNoKeyring
+}
+
+func keyringExpired(err error) bool {
+	return false
+}
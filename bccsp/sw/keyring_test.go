@@ -0,0 +1,158 @@
+// +build linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func newKeyringKeyStore(t *testing.T, backing bccsp.KeyStore) bccsp.KeyStore {
+	ks, err := NewKernelKeyringKeyStore(backing, KernelKeyringOpts{Keyring: "process"})
+	if err != nil {
+		t.Skipf("kernel keyring unavailable: %s", err)
+	}
+	// the keyring may exist while adding keys to it is denied, as in
+	// containers filtering the keyctl system calls
+	ring := ks.(*kernelKeyringKeyStore).ring
+	id, err := keyringAdd("fabric-keyring-probe", []byte("probe"), ring)
+	if err != nil {
+		t.Skipf("kernel keyring unavailable: %s", err)
+	}
+	keyringUnlink(id, ring)
+	return ks
+}
+
+func TestKernelKeyringKeyStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	backing, err := NewFileBasedKeyStore(nil, tempDir, false)
+	require.NoError(t, err)
+	ks := newKeyringKeyStore(t, backing)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	// the symmetric keys are held by the keyring
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	loaded, err := ks.GetKey(aesKey.SKI())
+	require.NoError(t, err)
+	require.IsType(t, &keyringKey{}, loaded)
+	assert.True(t, loaded.Symmetric())
+	_, err = loaded.Bytes()
+	assert.EqualError(t, err, "Not supported.")
+	_, err = loaded.PublicKey()
+	assert.Error(t, err)
+	ciphertext, err := csp.Encrypt(loaded, []byte("payload"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	// the ciphertext is decrypted in place
+	plaintext, err := csp.Decrypt(aesKey, append([]byte(nil), ciphertext...), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("payload"), plaintext)
+	plaintext, err = csp.Decrypt(loaded, append([]byte(nil), ciphertext...), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("payload"), plaintext)
+
+	// and so are the private keys
+	digest := sha256.Sum256([]byte("message"))
+	for _, opts := range []bccsp.KeyGenOpts{&bccsp.ECDSAKeyGenOpts{}, &bccsp.ED25519KeyGenOpts{}} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+		loaded, err := ks.GetKey(k.SKI())
+		require.NoError(t, err)
+		require.IsType(t, &keyringKey{}, loaded)
+		assert.False(t, loaded.Symmetric())
+		assert.True(t, loaded.Private())
+		pub, err := loaded.PublicKey()
+		require.NoError(t, err)
+		assert.Equal(t, k.SKI(), pub.SKI())
+
+		signature, err := csp.Sign(loaded, digest[:], nil)
+		require.NoError(t, err)
+		valid, err := csp.Verify(k, signature, digest[:], nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = csp.Verify(loaded, signature, digest[:], nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	keys, err := ks.(bccsp.KeyManager).ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+	for _, k := range keys {
+		assert.IsType(t, &keyringKey{}, k)
+	}
+
+	// the revoked or expired keys are loaded again from the backing key
+	// store
+	_, err = unix.KeyctlInt(unix.KEYCTL_REVOKE, loaded.(*keyringKey).id, 0, 0, 0)
+	require.NoError(t, err)
+	plaintext, err = csp.Decrypt(loaded, ciphertext, &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("payload"), plaintext)
+
+	// deleting a key deletes it from the keyring and the backing key store
+	require.NoError(t, ks.(bccsp.KeyManager).DeleteKey(aesKey.SKI()))
+	_, err = keyringSearch(ks.(*kernelKeyringKeyStore).ring, keyringDescription(keyringAES, aesKey.SKI()))
+	assert.Error(t, err)
+	_, err = ks.GetKey(aesKey.SKI())
+	assert.Error(t, err)
+}
+
+func TestKernelKeyringKeyStoreWithoutBacking(t *testing.T) {
+	ks := newKeyringKeyStore(t, nil)
+	assert.False(t, ks.ReadOnly())
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	loaded, err := ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.IsType(t, &keyringKey{}, loaded)
+
+	// the public keys have no material to protect
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	err = ks.StoreKey(pub)
+	assert.EqualError(t, err, "cannot store key of type *sw.ecdsaPublicKey in the kernel keyring")
+
+	_, err = ks.(bccsp.KeyManager).ListKeys()
+	assert.EqualError(t, err, "the backing key store does not list its keys")
+	require.NoError(t, ks.(bccsp.KeyManager).DeleteKey(k.SKI()))
+	_, err = ks.GetKey(k.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("no key found for ski %x in the kernel keyring", k.SKI()))
+	err = ks.(bccsp.KeyManager).DeleteKey(k.SKI())
+	assert.EqualError(t, err, fmt.Sprintf("key with SKI %x not found in the kernel keyring", k.SKI()))
+
+	// the material of revoked keys is lost without backing key store
+	k, err = csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	loaded, err = ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	_, err = unix.KeyctlInt(unix.KEYCTL_REVOKE, loaded.(*keyringKey).id, 0, 0, 0)
+	require.NoError(t, err)
+	_, err = csp.Encrypt(loaded, []byte("payload"), &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.Contains(t, err.Error(), "failed reading key")
+}
+
+func TestKernelKeyringOpts(t *testing.T) {
+	_, err := NewKernelKeyringKeyStore(nil, KernelKeyringOpts{Keyring: "thread"})
+	assert.EqualError(t, err, "unknown kernel keyring thread: must be session, user or process")
+}
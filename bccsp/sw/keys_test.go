@@ -8,13 +8,16 @@ package sw
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/pem"
 	"testing"
 
+	"github.com/hyperledger/fabric/bccsp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -369,3 +372,25 @@ func TestNil(t *testing.T) {
 	_, err = publicKeyToEncryptedPEM("hello world", []byte("Hello world"))
 	assert.Error(t, err)
 }
+
+func TestKeyFingerprint(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, k := range []bccsp.Key{&ecdsaPrivateKey{ecdsaKey}, &ed25519PrivateKey{ed25519Key}} {
+		pub, err := k.PublicKey()
+		assert.NoError(t, err)
+		der, err := pub.Bytes()
+		assert.NoError(t, err)
+		expected := sha256.Sum256(der)
+
+		fingerprint, err := k.(bccsp.KeyFingerprinter).Fingerprint()
+		assert.NoError(t, err)
+		assert.Equal(t, expected[:], fingerprint)
+		fingerprint, err = pub.(bccsp.KeyFingerprinter).Fingerprint()
+		assert.NoError(t, err)
+		assert.Equal(t, expected[:], fingerprint)
+	}
+}
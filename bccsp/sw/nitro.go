@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultKMSTool is the command decrypting with KMS from within Nitro
+	// Enclaves, looked up in the PATH.
+	defaultKMSTool = "kmstool_enclave_cli"
+	// defaultKMSProxyPort is the vsock port of the KMS proxy of the parent
+	// instance.
+	defaultKMSProxyPort = 8000
+)
+
+// NitroEnclaveOpts configures the sealing of the key files to an AWS Nitro
+// Enclave.
+type NitroEnclaveOpts struct {
+	// DataKey is the path of the file holding the base64 encoded
+	// ciphertext of the data key, as generated by the KMS GenerateDataKey
+	// operation with a key whose policy only allows the enclaves with
+	// the expected measurements to decrypt.
+	DataKey string
+	// Region is the region of the KMS key.
+	Region string
+	// ProxyPort is the vsock port of the KMS proxy of the parent instance,
+	// 8000 by default.
+	ProxyPort int
+	// Tool is the KMS tool of the Nitro Enclaves SDK, kmstool_enclave_cli
+	// by default.
+	Tool string
+}
+
+// NewNitroEnclaveProtector returns a KeyProtector sealing the key files to
+// an AWS Nitro Enclave. The data key is decrypted by KMS, which only
+// releases it to the enclaves whose attestation document satisfies the
+// policy of the KMS key. The AWS credentials are taken from the environment.
+func NewNitroEnclaveProtector(opts NitroEnclaveOpts) (KeyProtector, error) {
+	if opts.DataKey == "" {
+		return nil, errors.New("the data key of the Nitro Enclave must be set")
+	}
+	ciphertext, err := ioutil.ReadFile(opts.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the data key: %s", err)
+	}
+	ciphertext = bytes.TrimSpace(ciphertext)
+	if _, err := base64.StdEncoding.DecodeString(string(ciphertext)); err != nil {
+		return nil, fmt.Errorf("invalid data key %s: it must be base64 encoded", opts.DataKey)
+	}
+
+	tool, port := opts.Tool, opts.ProxyPort
+	if tool == "" {
+		tool = defaultKMSTool
+	}
+	if port == 0 {
+		port = defaultKMSProxyPort
+	}
+	args := []string{"decrypt", "--region", opts.Region, "--proxy-port", strconv.Itoa(port)}
+	for _, cred := range []struct {
+		flag string
+		env  string
+	}{
+		{"--aws-access-key-id", "AWS_ACCESS_KEY_ID"},
+		{"--aws-secret-access-key", "AWS_SECRET_ACCESS_KEY"},
+		{"--aws-session-token", "AWS_SESSION_TOKEN"},
+	} {
+		if v := os.Getenv(cred.env); v != "" {
+			args = append(args, cred.flag, v)
+		}
+	}
+	args = append(args, "--ciphertext", string(ciphertext))
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed decrypting the data key with KMS: %s: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	defer zeroize(out)
+
+	key, err := kmsPlaintext(out)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(key)
+	return newSealer("NITRO", key)
+}
+
+// kmsPlaintext returns the plaintext decrypted by the KMS tool, which prints
+// it base64 encoded after PLAINTEXT:.
+func kmsPlaintext(out []byte) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "PLAINTEXT:") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(line, "PLAINTEXT:")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid plaintext returned by KMS: %s", err)
+		}
+		if len(key) != 32 {
+			zeroize(key)
+			return nil, fmt.Errorf("invalid data key of %d bytes: it must be an AES-256 key", len(key))
+		}
+		return key, nil
+	}
+	return nil, errors.New("no plaintext returned by KMS")
+}
@@ -0,0 +1,143 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/bulletproofs"
+	"github.com/pkg/errors"
+)
+
+type pedersenBlindingKeyGenerator struct{}
+
+func (kg *pedersenBlindingKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	blinding, err := bulletproofs.NewBlinding()
+	if err != nil {
+		return nil, fmt.Errorf("Failed generating blinding factor: [%s]", err)
+	}
+
+	return &pedersenBlindingKey{blinding}, nil
+}
+
+type pedersenBlindingImportOptsKeyImporter struct{}
+
+func (*pedersenBlindingImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("Invalid raw material. Expected byte array.")
+	}
+
+	blinding, err := bulletproofs.ParseBlinding(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pedersenBlindingKey{blinding}, nil
+}
+
+// Commit commits to the value with the blinding factor.
+func (csp *CSP) Commit(value uint64, blinding bccsp.Key) ([]byte, error) {
+	r, err := blindingOf(blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	return bulletproofs.Commit(value, r).Bytes(), nil
+}
+
+// VerifyOpening returns whether the commitment opens to the value with the blinding factor.
+func (csp *CSP) VerifyOpening(commitment []byte, value uint64, blinding bccsp.Key) (bool, error) {
+	c, err := bulletproofs.ParseCommitment(commitment)
+	if err != nil {
+		return false, err
+	}
+	r, err := blindingOf(blinding)
+	if err != nil {
+		return false, err
+	}
+
+	return c.Equal(bulletproofs.Commit(value, r)), nil
+}
+
+// AddCommitments returns the commitment to the sum of the values of the commitments.
+func (csp *CSP) AddCommitments(commitments ...[]byte) ([]byte, error) {
+	if len(commitments) == 0 {
+		return nil, errors.New("Invalid commitments. At least one is required.")
+	}
+
+	sum, err := bulletproofs.ParseCommitment(commitments[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range commitments[1:] {
+		c, err := bulletproofs.ParseCommitment(raw)
+		if err != nil {
+			return nil, err
+		}
+		sum = sum.Add(c)
+	}
+
+	return sum.Bytes(), nil
+}
+
+// SubCommitments returns the commitment to the difference of the values of the commitments.
+func (csp *CSP) SubCommitments(c1, c2 []byte) ([]byte, error) {
+	minuend, err := bulletproofs.ParseCommitment(c1)
+	if err != nil {
+		return nil, err
+	}
+	subtrahend, err := bulletproofs.ParseCommitment(c2)
+	if err != nil {
+		return nil, err
+	}
+
+	return minuend.Sub(subtrahend).Bytes(), nil
+}
+
+// AddBlindings returns the sum of the blinding factors.
+func (csp *CSP) AddBlindings(blindings ...bccsp.Key) (bccsp.Key, error) {
+	if len(blindings) == 0 {
+		return nil, errors.New("Invalid blinding factors. At least one is required.")
+	}
+
+	sum := new(big.Int)
+	for _, blinding := range blindings {
+		r, err := blindingOf(blinding)
+		if err != nil {
+			return nil, err
+		}
+		sum = bulletproofs.AddBlindings(sum, r)
+	}
+
+	return &pedersenBlindingKey{sum}, nil
+}
+
+// SubBlindings returns the difference of the blinding factors.
+func (csp *CSP) SubBlindings(b1, b2 bccsp.Key) (bccsp.Key, error) {
+	r1, err := blindingOf(b1)
+	if err != nil {
+		return nil, err
+	}
+	r2, err := blindingOf(b2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pedersenBlindingKey{bulletproofs.SubBlindings(r1, r2)}, nil
+}
+
+func blindingOf(k bccsp.Key) (*big.Int, error) {
+	blinding, ok := k.(*pedersenBlindingKey)
+	if !ok {
+		return nil, errors.Errorf("Invalid blinding factor. Expected a Pedersen blinding key, got [%T].", k)
+	}
+
+	return blinding.blinding, nil
+}
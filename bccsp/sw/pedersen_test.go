@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPedersenCommitments(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	committer, ok := csp.(bccsp.Committer)
+	assert.True(t, ok)
+
+	r1, err := csp.KeyGen(&bccsp.PedersenBlindingKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.True(t, r1.Private())
+	assert.True(t, r1.Symmetric())
+	r2, err := csp.KeyGen(&bccsp.PedersenBlindingKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, r1.SKI(), r2.SKI())
+
+	c1, err := committer.Commit(70, r1)
+	assert.NoError(t, err)
+	c2, err := committer.Commit(30, r2)
+	assert.NoError(t, err)
+
+	valid, err := committer.VerifyOpening(c1, 70, r1)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = committer.VerifyOpening(c1, 71, r1)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = committer.VerifyOpening(c1, 70, r2)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	sum, err := committer.AddCommitments(c1, c2)
+	assert.NoError(t, err)
+	sumBlinding, err := committer.AddBlindings(r1, r2)
+	assert.NoError(t, err)
+	valid, err = committer.VerifyOpening(sum, 100, sumBlinding)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	diff, err := committer.SubCommitments(c1, c2)
+	assert.NoError(t, err)
+	diffBlinding, err := committer.SubBlindings(r1, r2)
+	assert.NoError(t, err)
+	valid, err = committer.VerifyOpening(diff, 40, diffBlinding)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// blinding factors are exported and imported
+	raw, err := r1.Bytes()
+	assert.NoError(t, err)
+	assert.Len(t, raw, 32)
+	imported, err := csp.KeyImport(raw, &bccsp.PedersenBlindingImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, r1.SKI(), imported.SKI())
+	valid, err = committer.VerifyOpening(c1, 70, imported)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestPedersenCommitmentsFailures(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	committer := csp.(bccsp.Committer)
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = committer.Commit(1, aesKey)
+	assert.EqualError(t, err, "Invalid blinding factor. Expected a Pedersen blinding key, got [*sw.aesPrivateKey].")
+
+	_, err = committer.AddCommitments()
+	assert.EqualError(t, err, "Invalid commitments. At least one is required.")
+	_, err = committer.AddBlindings()
+	assert.EqualError(t, err, "Invalid blinding factors. At least one is required.")
+
+	r, err := csp.KeyGen(&bccsp.PedersenBlindingKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	c, err := committer.Commit(1, r)
+	assert.NoError(t, err)
+	_, err = committer.AddCommitments(c, c[1:])
+	assert.EqualError(t, err, "invalid commitment: invalid point size 64, expected 65")
+	_, err = committer.VerifyOpening(c[1:], 1, r)
+	assert.EqualError(t, err, "invalid commitment: invalid point size 64, expected 65")
+
+	_, err = csp.KeyImport([]byte{1, 2, 3}, &bccsp.PedersenBlindingImportOpts{Temporary: true})
+	assert.Contains(t, err.Error(), "invalid blinding factor size 3, expected 32")
+	_, err = csp.KeyImport("blinding", &bccsp.PedersenBlindingImportOpts{Temporary: true})
+	assert.Contains(t, err.Error(), "Invalid raw material. Expected byte array.")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/bulletproofs"
+)
+
+// pedersenBlindingKey is the blinding factor of Pedersen commitments
+type pedersenBlindingKey struct {
+	blinding *big.Int
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *pedersenBlindingKey) Bytes() (raw []byte, err error) {
+	return bulletproofs.BlindingBytes(k.blinding), nil
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *pedersenBlindingKey) SKI() (ski []byte) {
+	hash := sha256.New()
+	hash.Write([]byte{0x02})
+	hash.Write(bulletproofs.BlindingBytes(k.blinding))
+	return hash.Sum(nil)
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *pedersenBlindingKey) Symmetric() bool {
+	return true
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *pedersenBlindingKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *pedersenBlindingKey) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("Cannot call this method on a symmetric key.")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sealingLabel separates the keys sealing the key files from the other uses
+// of the secrets they are derived from.
+const sealingLabel = "fabric keystore sealing"
+
+// sealer is a KeyProtector sealing the key files with AES-256-GCM, under a
+// key derived from a secret that is only available inside an attested
+// confidential computing environment.
+type sealer struct {
+	name string
+	aead cipher.AEAD
+}
+
+func newSealer(name string, secret []byte) (*sealer, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sealingLabel))
+	key := mac.Sum(nil)
+	defer zeroize(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{name: name, aead: aead}, nil
+}
+
+func (s *sealer) Name() string {
+	return s.name
+}
+
+// Protect seals raw under a random nonce, which prefixes the sealed blob.
+func (s *sealer) Protect(raw []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed generating nonce: %s", err)
+	}
+	return s.aead.Seal(nonce, nonce, raw, []byte(s.name)), nil
+}
+
+// Unprotect opens a sealed blob, which fails outside of the environment the
+// blob was sealed to.
+func (s *sealer) Unprotect(blob []byte) ([]byte, error) {
+	if len(blob) < s.aead.NonceSize() {
+		return nil, errors.New("sealed blob too short")
+	}
+	nonce, sealed := blob[:s.aead.NonceSize()], blob[s.aead.NonceSize():]
+	raw, err := s.aead.Open(nil, nonce, sealed, []byte(s.name))
+	if err != nil {
+		return nil, errors.New("the key file was sealed to another environment")
+	}
+	return raw, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealer(t *testing.T) {
+	s, err := newSealer("SEV-SNP", bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	assert.Equal(t, "SEV-SNP", s.Name())
+
+	blob, err := s.Protect([]byte("key material"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(blob), "key material")
+	raw, err := s.Unprotect(blob)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key material"), raw)
+
+	// the blobs are only unsealed with the key of the environment they
+	// were sealed to
+	other, err := newSealer("SEV-SNP", bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	_, err = other.Unprotect(blob)
+	assert.EqualError(t, err, "the key file was sealed to another environment")
+	other, err = newSealer("NITRO", bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	_, err = other.Unprotect(blob)
+	assert.EqualError(t, err, "the key file was sealed to another environment")
+	_, err = s.Unprotect(blob[:4])
+	assert.EqualError(t, err, "sealed blob too short")
+}
+
+// fakeKMSTool writes a KMS tool recording its arguments and printing
+// output.
+func fakeKMSTool(t *testing.T, dir, output string) (string, string) {
+	tool := filepath.Join(dir, "kmstool_enclave_cli")
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\necho '" + output + "'\n"
+	require.NoError(t, ioutil.WriteFile(tool, []byte(script), 0755))
+	return tool, args
+}
+
+func TestNitroEnclaveProtector(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake KMS tool is a shell script")
+	}
+	tempDir, err := ioutil.TempDir("", "nitro")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	os.Unsetenv("AWS_SESSION_TOKEN")
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+
+	dataKey := filepath.Join(tempDir, "datakey")
+	require.NoError(t, ioutil.WriteFile(dataKey, []byte("Y2lwaGVydGV4dA==\n"), 0600))
+	plaintext := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	tool, args := fakeKMSTool(t, tempDir, "PLAINTEXT: "+plaintext)
+
+	opts := NitroEnclaveOpts{DataKey: dataKey, Region: "us-east-1", Tool: tool}
+	protector, err := NewNitroEnclaveProtector(opts)
+	require.NoError(t, err)
+	assert.Equal(t, "NITRO", protector.Name())
+	recorded, err := ioutil.ReadFile(args)
+	require.NoError(t, err)
+	assert.Equal(t, "decrypt --region us-east-1 --proxy-port 8000 --aws-access-key-id AKIDEXAMPLE --ciphertext Y2lwaGVydGV4dA==", strings.TrimSpace(string(recorded)))
+
+	// the key files are sealed, and unsealed inside the enclave only
+	path := filepath.Join(tempDir, "keystore")
+	ks, err := NewFileBasedKeyStoreWithOpts(nil, path, false, &FileKeyStoreOpts{Protector: protector})
+	require.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	protector, err = NewNitroEnclaveProtector(opts)
+	require.NoError(t, err)
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, true, &FileKeyStoreOpts{Protector: protector})
+	require.NoError(t, err)
+	loaded, err := ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, k.SKI(), loaded.SKI())
+
+	other, err := newSealer("NITRO", bytes.Repeat([]byte{8}, 32))
+	require.NoError(t, err)
+	ks, err = NewFileBasedKeyStoreWithOpts(nil, path, true, &FileKeyStoreOpts{Protector: other})
+	require.NoError(t, err)
+	_, err = ks.GetKey(k.SKI())
+	assert.Contains(t, err.Error(), "the key file was sealed to another environment")
+
+	// KMS refuses to decrypt outside of the attested enclaves
+	opts.Tool = filepath.Join(tempDir, "refusing")
+	require.NoError(t, ioutil.WriteFile(opts.Tool, []byte("#!/bin/sh\necho AccessDeniedException >&2\nexit 1\n"), 0755))
+	_, err = NewNitroEnclaveProtector(opts)
+	assert.EqualError(t, err, "failed decrypting the data key with KMS: exit status 1: AccessDeniedException")
+
+	_, err = NewNitroEnclaveProtector(NitroEnclaveOpts{})
+	assert.EqualError(t, err, "the data key of the Nitro Enclave must be set")
+	require.NoError(t, ioutil.WriteFile(dataKey, []byte("not base64!"), 0600))
+	_, err = NewNitroEnclaveProtector(opts)
+	assert.EqualError(t, err, "invalid data key "+dataKey+": it must be base64 encoded")
+}
+
+func TestKMSPlaintext(t *testing.T) {
+	_, err := kmsPlaintext([]byte("Decrypting\n"))
+	assert.EqualError(t, err, "no plaintext returned by KMS")
+	_, err = kmsPlaintext([]byte("PLAINTEXT: !!!\n"))
+	assert.Contains(t, err.Error(), "invalid plaintext returned by KMS")
+	_, err = kmsPlaintext([]byte("PLAINTEXT: " + base64.StdEncoding.EncodeToString([]byte("short"))))
+	assert.EqualError(t, err, "invalid data key of 5 bytes: it must be an AES-256 key")
+}
+
+func TestSEVSNPProtector(t *testing.T) {
+	sel, err := snpFieldSelect(nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x9), sel)
+	sel, err = snpFieldSelect([]string{"Image-ID", "family-id"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x6), sel)
+	_, err = snpFieldSelect([]string{"svn"})
+	assert.EqualError(t, err, "unknown SEV-SNP guest field svn: must be policy, image-id, family-id or measurement")
+
+	_, err = NewSEVSNPProtector(SEVSNPOpts{VMPL: 4})
+	assert.EqualError(t, err, "invalid VMPL 4: must be 0 to 3")
+	if _, err := os.Stat("/dev/sev-guest"); err == nil {
+		t.Skip("running in a SEV-SNP guest")
+	}
+	_, err = NewSEVSNPProtector(SEVSNPOpts{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// snpGuestFields are the bits of the GUEST_FIELD_SELECT of the derived key
+// requests of SEV-SNP, selecting the fields of the guest mixed into the key.
+var snpGuestFields = map[string]uint64{
+	"policy":      1 << 0,
+	"image-id":    1 << 1,
+	"family-id":   1 << 2,
+	"measurement": 1 << 3,
+}
+
+// SEVSNPOpts configures the sealing of the key files to an AMD SEV-SNP
+// confidential VM.
+type SEVSNPOpts struct {
+	// Fields are the fields of the guest the sealing key is bound to,
+	// among policy, image-id, family-id and measurement. The key files are
+	// sealed to the measurement and the policy of the guest by default.
+	Fields []string
+	// VMPL is the VM privilege level the sealing key is bound to, which must
+	// not be lower than the one of the guest.
+	VMPL int
+}
+
+// snpFieldSelect returns the GUEST_FIELD_SELECT of the fields.
+func snpFieldSelect(fields []string) (uint64, error) {
+	if len(fields) == 0 {
+		fields = []string{"measurement", "policy"}
+	}
+	var sel uint64
+	for _, f := range fields {
+		bit, ok := snpGuestFields[strings.ToLower(f)]
+		if !ok {
+			return 0, fmt.Errorf("unknown SEV-SNP guest field %s: must be policy, image-id, family-id or measurement", f)
+		}
+		sel |= bit
+	}
+	return sel, nil
+}
+
+// NewSEVSNPProtector returns a KeyProtector sealing the key files to an AMD
+// SEV-SNP confidential VM, under a key derived by the AMD secure processor
+// from the fields of the guest, such as its launch measurement, so that the
+// key files are only unsealed by the guests launched with the same fields.
+func NewSEVSNPProtector(opts SEVSNPOpts) (KeyProtector, error) {
+	sel, err := snpFieldSelect(opts.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if opts.VMPL < 0 || opts.VMPL > 3 {
+		return nil, fmt.Errorf("invalid VMPL %d: must be 0 to 3", opts.VMPL)
+	}
+	key, err := snpDerivedKey(sel, uint32(opts.VMPL))
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(key)
+	return newSealer("SEV-SNP", key)
+}
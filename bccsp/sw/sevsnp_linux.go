@@ -0,0 +1,78 @@
+// +build linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// snpGuestDevice is the device of the SEV-SNP guest driver.
+	snpGuestDevice = "/dev/sev-guest"
+	// snpGetDerivedKey is the SNP_GET_DERIVED_KEY ioctl of the driver.
+	snpGetDerivedKey = 0xc0205301
+)
+
+// snpGuestRequest is the snp_guest_request_ioctl structure of the driver.
+type snpGuestRequest struct {
+	msgVersion uint8
+	_          [7]byte
+	reqData    uint64
+	respData   uint64
+	exitInfo2  uint64
+}
+
+// snpDerivedKeyRequest is the snp_derived_key_req structure of the driver.
+type snpDerivedKeyRequest struct {
+	rootKeySelect    uint32
+	_                uint32
+	guestFieldSelect uint64
+	vmpl             uint32
+	guestSVN         uint32
+	tcbVersion       uint64
+}
+
+// snpDerivedKey requests a key derived from the VCEK and the selected
+// fields of the guest to the AMD secure processor.
+func snpDerivedKey(fieldSelect uint64, vmpl uint32) ([]byte, error) {
+	f, err := os.OpenFile(snpGuestDevice, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening the SEV-SNP guest device: %s", err)
+	}
+	defer f.Close()
+
+	req := &snpDerivedKeyRequest{guestFieldSelect: fieldSelect, vmpl: vmpl}
+	resp := &[64]byte{}
+	ioc := &snpGuestRequest{
+		msgVersion: 1,
+		reqData:    uint64(uintptr(unsafe.Pointer(req))),
+		respData:   uint64(uintptr(unsafe.Pointer(resp))),
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), snpGetDerivedKey, uintptr(unsafe.Pointer(ioc)))
+	runtime.KeepAlive(req)
+	runtime.KeepAlive(resp)
+	defer zeroize(resp[:])
+	if errno != 0 {
+		return nil, fmt.Errorf("failed deriving the SEV-SNP sealing key: %s [firmware error %#x]", errno, ioc.exitInfo2)
+	}
+
+	// The response is a MSG_KEY_RSP, whose status precedes the key
+	if status := binary.LittleEndian.Uint32(resp[0:4]); status != 0 {
+		return nil, fmt.Errorf("failed deriving the SEV-SNP sealing key: status %#x", status)
+	}
+	key := make([]byte, 32)
+	copy(key, resp[32:64])
+	return key, nil
+}
@@ -0,0 +1,15 @@
+// +build !linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import "errors"
+
+func snpDerivedKey(fieldSelect uint64, vmpl uint32) ([]byte, error) {
+	return nil, errors.New("SEV-SNP sealing is only available on Linux")
+}
@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 )
 
 type ECDSASignature struct {
@@ -107,3 +108,72 @@ func ToLowS(k *ecdsa.PublicKey, s *big.Int) (*big.Int, error) {
 
 	return s, nil
 }
+
+// LowSPolicy is the policy applied to the ECDSA signatures whose S value is
+// larger than half the order of the curve. Such high-S signatures are valid
+// ECDSA signatures, but (r, N - s) is a valid signature of the same message
+// as well, which makes signatures malleable. Fabric only produces low-S
+// signatures.
+type LowSPolicy string
+
+const (
+	// LowSEnforce refuses the high-S signatures with an error. This is the
+	// policy of the providers when none is configured.
+	LowSEnforce LowSPolicy = "enforce"
+	// LowSNormalize normalizes the high-S signatures to low-S before
+	// verifying them, so that they are accepted.
+	LowSNormalize LowSPolicy = "normalize"
+	// LowSReject reports the high-S signatures as invalid, without an error.
+	LowSReject LowSPolicy = "reject"
+)
+
+// ParseLowSPolicy returns the low-S policy of the given name, regardless of
+// its case. The empty name is the LowSEnforce policy.
+func ParseLowSPolicy(name string) (LowSPolicy, error) {
+	switch policy := LowSPolicy(strings.ToLower(name)); policy {
+	case "":
+		return LowSEnforce, nil
+	case LowSEnforce, LowSNormalize, LowSReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid low-S policy %s: must be enforce, normalize or reject", name)
+	}
+}
+
+// IsLowSSignature checks that the S value of the DER encoded signature is a
+// low-S for the curve of the key.
+func IsLowSSignature(k *ecdsa.PublicKey, signature []byte) (bool, error) {
+	_, s, err := UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, err
+	}
+	return IsLowS(k, s)
+}
+
+// VerifyECDSA verifies the DER encoded signature of the digest with the key,
+// applying the policy to high-S signatures. It lets the verifiers outside of
+// the providers, such as the SDKs and the tools checking transactions, agree
+// with the providers about the signatures they accept.
+func VerifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, policy LowSPolicy) (bool, error) {
+	r, s, err := UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, err
+	}
+
+	lowS, err := IsLowS(k, s)
+	if err != nil {
+		return false, err
+	}
+	if !lowS {
+		switch policy {
+		case LowSNormalize:
+			s = new(big.Int).Sub(k.Params().N, s)
+		case LowSReject:
+			return false, nil
+		default:
+			return false, fmt.Errorf("Invalid S. Must be smaller than half the order [%s][%s].", s, GetCurveHalfOrdersAt(k.Curve))
+		}
+	}
+
+	return ecdsa.Verify(k, digest, r, s), nil
+}
@@ -108,3 +108,63 @@ func TestSignatureToLowS(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, lowS)
 }
+
+func TestParseLowSPolicy(t *testing.T) {
+	for name, expected := range map[string]LowSPolicy{
+		"":          LowSEnforce,
+		"enforce":   LowSEnforce,
+		"Normalize": LowSNormalize,
+		"REJECT":    LowSReject,
+	} {
+		policy, err := ParseLowSPolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := ParseLowSPolicy("ignore")
+	assert.EqualError(t, err, "invalid low-S policy ignore: must be enforce, normalize or reject")
+}
+
+func TestVerifyECDSA(t *testing.T) {
+	lowSk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	digest := make([]byte, 32)
+
+	r, s, err := ecdsa.Sign(rand.Reader, lowSk, digest)
+	assert.NoError(t, err)
+	s, err = ToLowS(&lowSk.PublicKey, s)
+	assert.NoError(t, err)
+	lowSig, err := MarshalECDSASignature(r, s)
+	assert.NoError(t, err)
+	highSig, err := MarshalECDSASignature(r, new(big.Int).Sub(elliptic.P256().Params().N, s))
+	assert.NoError(t, err)
+
+	lowS, err := IsLowSSignature(&lowSk.PublicKey, lowSig)
+	assert.NoError(t, err)
+	assert.True(t, lowS)
+	lowS, err = IsLowSSignature(&lowSk.PublicKey, highSig)
+	assert.NoError(t, err)
+	assert.False(t, lowS)
+
+	for _, policy := range []LowSPolicy{LowSEnforce, LowSNormalize, LowSReject} {
+		valid, err := VerifyECDSA(&lowSk.PublicKey, lowSig, digest, policy)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	_, err = VerifyECDSA(&lowSk.PublicKey, highSig, digest, LowSEnforce)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order")
+	valid, err := VerifyECDSA(&lowSk.PublicKey, highSig, digest, LowSNormalize)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = VerifyECDSA(&lowSk.PublicKey, highSig, digest, LowSReject)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	valid, err = VerifyECDSA(&lowSk.PublicKey, highSig, []byte("another digest"), LowSNormalize)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	_, err = VerifyECDSA(&lowSk.PublicKey, []byte{0}, digest, LowSNormalize)
+	assert.Error(t, err)
+}
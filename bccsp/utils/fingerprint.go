@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// SPKIFingerprint returns the SHA-256 digest of the DER encoded
+// SubjectPublicKeyInfo of the public key. This is the canonical fingerprint
+// of the keys, regardless of the certificates they are found in.
+func SPKIFingerprint(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshalling public key [%s]", err)
+	}
+	digest := sha256.Sum256(der)
+	return digest[:], nil
+}
+
+// SSHFingerprint returns the fingerprint of the public key in the format of
+// OpenSSH, that is SHA256: followed by the base64 encoded SHA-256 digest of
+// the public key in the SSH wire format, so that it can be compared with
+// the fingerprints displayed by ssh-keygen -l.
+func SSHFingerprint(pub crypto.PublicKey) (string, error) {
+	var blob []byte
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		var curve string
+		switch pub.Curve {
+		case elliptic.P256():
+			curve = "nistp256"
+		case elliptic.P384():
+			curve = "nistp384"
+		case elliptic.P521():
+			curve = "nistp521"
+		default:
+			return "", fmt.Errorf("curve %s is not supported by SSH", pub.Curve.Params().Name)
+		}
+		blob = appendSSHString(blob, []byte("ecdsa-sha2-"+curve))
+		blob = appendSSHString(blob, []byte(curve))
+		blob = appendSSHString(blob, elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	case ed25519.PublicKey:
+		blob = appendSSHString(blob, []byte("ssh-ed25519"))
+		blob = appendSSHString(blob, pub)
+	case *rsa.PublicKey:
+		blob = appendSSHString(blob, []byte("ssh-rsa"))
+		blob = appendSSHString(blob, sshMPInt(big.NewInt(int64(pub.E))))
+		blob = appendSSHString(blob, sshMPInt(pub.N))
+	default:
+		return "", fmt.Errorf("public key type %T is not supported by SSH", pub)
+	}
+
+	digest := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(digest[:]), nil
+}
+
+// PublicKeysEqual tells whether the two public keys are the same, comparing
+// their DER encoded SubjectPublicKeyInfo in constant time.
+func PublicKeysEqual(a, b crypto.PublicKey) (bool, error) {
+	derA, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false, fmt.Errorf("failed marshalling public key [%s]", err)
+	}
+	derB, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false, fmt.Errorf("failed marshalling public key [%s]", err)
+	}
+	return subtle.ConstantTimeCompare(derA, derB) == 1, nil
+}
+
+// FingerprintsEqual tells whether the two fingerprints are the same,
+// comparing them in constant time.
+func FingerprintsEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// appendSSHString appends the data to the buffer as an SSH string, that is
+// prefixed with its length as a 32-bit big endian integer.
+func appendSSHString(buf, data []byte) []byte {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	return append(append(buf, length[:]...), data...)
+}
+
+// sshMPInt returns the SSH encoding of the positive integer, which is
+// prefixed with a zero byte when its most significant bit is set.
+func sshMPInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
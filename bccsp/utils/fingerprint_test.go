@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the fingerprints of these keys were computed with OpenSSL and ssh-keygen
+const (
+	ecdsaPublicKeyPEM = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAECVdv41wazYc/dCtigque4VigmNQq
+djjzfQ+7GwAvJ0OKJW0RhlkD9liJWxJ7Nl00jYWEo8njgL+2XLJMDVyybA==
+-----END PUBLIC KEY-----
+`
+	rsaPublicKeyPEM = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAzO0AJGneyZ8ZV3OhdaGB
+OzYth3VpnOwMavjFhJxMYF+rTarVZ2UkLxC6slevyynJoganWljZRyUSiINifMmc
+RtYmEWe20Jqrh8gF7beUVYvgR04jUEDmACvvbnWl5RVmckZ/LfLWkAgF3x05Dz9+
+nMqqnMessnQnjDegyARb2osEBAt1lmmwfwZ13ghOsxVQjauVedkH9Y3mjDWBgBOl
+ySk26QgUTaRolm4y6RZEYl0zHhymRAd9WVGa3ji9CCpLBrd5uG7t1xbQ1TTivTfi
+64fkGBCt2Gjk5ElLqbwR+VyXGBX47kFA6VlPH2EVRm7BPDpt1e7JpCc1hQKX8Hib
+hQIDAQAB
+-----END PUBLIC KEY-----
+`
+	ed25519PublicKeyHex = "005a48cfb73f652714ce8caf4f1560a5c52cc1cbed3741d750b0abf26ab55c86"
+)
+
+func parsePublicKey(t *testing.T, pemKey string) interface{} {
+	block, _ := pem.Decode([]byte(pemKey))
+	assert.NotNil(t, block)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	assert.NoError(t, err)
+	return pub
+}
+
+func TestSPKIFingerprint(t *testing.T) {
+	fingerprint, err := SPKIFingerprint(parsePublicKey(t, ecdsaPublicKeyPEM))
+	assert.NoError(t, err)
+	assert.Equal(t, "7b607981ec1830cec334756ff58b9220dc0c8c5e11f5a85528801898b17da76c", hex.EncodeToString(fingerprint))
+
+	_, err = SPKIFingerprint("not a key")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed marshalling public key [")
+}
+
+func TestSSHFingerprint(t *testing.T) {
+	fingerprint, err := SSHFingerprint(parsePublicKey(t, ecdsaPublicKeyPEM))
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA256:DOLOdh8ycjuTkeZP0i0U42IEDNoyLrByB5m2nMSiYZk", fingerprint)
+
+	fingerprint, err = SSHFingerprint(parsePublicKey(t, rsaPublicKeyPEM))
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA256:VIcmt4QmVYwrCiwJZc6TwLJpjZxOD6CqSLbQDgm2Ink", fingerprint)
+
+	raw, err := hex.DecodeString(ed25519PublicKeyHex)
+	assert.NoError(t, err)
+	fingerprint, err = SSHFingerprint(ed25519.PublicKey(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA256:cqQ6LvsgARmHgVf9bYkjHpIU/V+9kuaNhD53BEPFW4M", fingerprint)
+
+	k, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = SSHFingerprint(&k.PublicKey)
+	assert.EqualError(t, err, "curve P-224 is not supported by SSH")
+	_, err = SSHFingerprint("not a key")
+	assert.EqualError(t, err, "public key type string is not supported by SSH")
+}
+
+func TestPublicKeysEqual(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	copied := ecdsa.PublicKey{Curve: k.Curve, X: k.X, Y: k.Y}
+
+	equal, err := PublicKeysEqual(&k.PublicKey, &copied)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+	equal, err = PublicKeysEqual(&k.PublicKey, parsePublicKey(t, ecdsaPublicKeyPEM))
+	assert.NoError(t, err)
+	assert.False(t, equal)
+	_, err = PublicKeysEqual(&k.PublicKey, "not a key")
+	assert.Error(t, err)
+
+	a, err := SPKIFingerprint(&k.PublicKey)
+	assert.NoError(t, err)
+	b, err := SPKIFingerprint(&copied)
+	assert.NoError(t, err)
+	assert.True(t, FingerprintsEqual(a, b))
+	assert.False(t, FingerprintsEqual(a, b[:16]))
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// The BCCSP providers produce and verify ECDSA signatures encoded in ASN.1
+// DER, as a sequence of the two integers r and s. Other systems encode them
+// differently: WebAuthn authenticators, PKCS#11 tokens and many blockchains
+// use the raw encoding, that is r and s as big endian integers of the size of
+// the order of the curve, concatenated, and JOSE (JWS and JWT) encodes the
+// raw signature in base64url without padding.
+//
+// RSA signatures are the same in all these formats, once left padded to the
+// size of the modulus. A PKCS#1 v1.5 signature cannot be converted to a PSS
+// signature, or the other way around, as they pad the digest differently
+// before the operation of the private key.
+
+// ECDSASignatureToRaw converts the DER encoded ECDSA signature to its raw
+// encoding for the curve.
+func ECDSASignatureToRaw(curve elliptic.Curve, signature []byte) ([]byte, error) {
+	r, s, err := UnmarshalECDSASignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	size := curveOrderSize(curve)
+	if r.BitLen() > size*8 || s.BitLen() > size*8 {
+		return nil, fmt.Errorf("invalid signature, R and S must not be larger than the order of curve %s", curve.Params().Name)
+	}
+	raw := make([]byte, 2*size)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(raw[size-len(rBytes):size], rBytes)
+	copy(raw[2*size-len(sBytes):], sBytes)
+	return raw, nil
+}
+
+// RawToECDSASignature converts the raw ECDSA signature for the curve to its
+// DER encoding.
+func RawToECDSASignature(curve elliptic.Curve, raw []byte) ([]byte, error) {
+	size := curveOrderSize(curve)
+	if len(raw) != 2*size {
+		return nil, fmt.Errorf("invalid raw signature length %d for curve %s: must be %d", len(raw), curve.Params().Name, 2*size)
+	}
+
+	r := new(big.Int).SetBytes(raw[:size])
+	s := new(big.Int).SetBytes(raw[size:])
+	if r.Sign() != 1 || s.Sign() != 1 {
+		return nil, errors.New("invalid signature, R and S must be larger than zero")
+	}
+	return MarshalECDSASignature(r, s)
+}
+
+// ECDSASignatureToJOSE converts the DER encoded ECDSA signature to the
+// encoding of JWS signatures.
+func ECDSASignatureToJOSE(curve elliptic.Curve, signature []byte) (string, error) {
+	raw, err := ECDSASignatureToRaw(curve, signature)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// JOSEToECDSASignature converts the ECDSA signature of a JWS to its DER
+// encoding.
+func JOSEToECDSASignature(curve elliptic.Curve, jose string) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(jose)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding JOSE signature [%s]", err)
+	}
+	return RawToECDSASignature(curve, raw)
+}
+
+// ECDSAJOSEAlgorithm returns the JWS algorithm of the ECDSA signatures for
+// the curve.
+func ECDSAJOSEAlgorithm(curve elliptic.Curve) (string, error) {
+	switch curve {
+	case elliptic.P256():
+		return "ES256", nil
+	case elliptic.P384():
+		return "ES384", nil
+	case elliptic.P521():
+		return "ES512", nil
+	default:
+		return "", fmt.Errorf("curve %s has no JWS algorithm", curve.Params().Name)
+	}
+}
+
+// RSASignatureToJOSE converts the PKCS#1 v1.5 or PSS signature made with
+// the private key of k to the encoding of JWS signatures.
+func RSASignatureToJOSE(k *rsa.PublicKey, signature []byte) (string, error) {
+	padded, err := PadRSASignature(k, signature)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(padded), nil
+}
+
+// JOSEToRSASignature converts the RSA signature of a JWS, made with the
+// private key of k, to its PKCS#1 v1.5 or PSS encoding.
+func JOSEToRSASignature(k *rsa.PublicKey, jose string) ([]byte, error) {
+	signature, err := base64.RawURLEncoding.DecodeString(jose)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding JOSE signature [%s]", err)
+	}
+	return PadRSASignature(k, signature)
+}
+
+// PadRSASignature left pads the RSA signature made with the private key of k
+// to the size of the modulus, as some encoders strip its leading zeros.
+func PadRSASignature(k *rsa.PublicKey, signature []byte) ([]byte, error) {
+	size := k.Size()
+	if len(signature) > size {
+		return nil, fmt.Errorf("invalid RSA signature length %d: must not be larger than the modulus size %d", len(signature), size)
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(signature):], signature)
+	return padded, nil
+}
+
+func curveOrderSize(curve elliptic.Curve) int {
+	return (curve.Params().N.BitLen() + 7) / 8
+}
@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECDSASignatureToRaw(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		k, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NoError(t, err)
+		digest := sha256.Sum256([]byte("message"))
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.NoError(t, err)
+		signature, err := MarshalECDSASignature(r, s)
+		assert.NoError(t, err)
+
+		raw, err := ECDSASignatureToRaw(curve, signature)
+		assert.NoError(t, err)
+		size := (curve.Params().BitSize + 7) / 8
+		assert.Len(t, raw, 2*size)
+		assert.Equal(t, r, new(big.Int).SetBytes(raw[:size]))
+		assert.Equal(t, s, new(big.Int).SetBytes(raw[size:]))
+
+		der, err := RawToECDSASignature(curve, raw)
+		assert.NoError(t, err)
+		assert.Equal(t, signature, der)
+	}
+
+	// short integers are left padded
+	signature, err := MarshalECDSASignature(big.NewInt(1), big.NewInt(2))
+	assert.NoError(t, err)
+	raw, err := ECDSASignatureToRaw(elliptic.P256(), signature)
+	assert.NoError(t, err)
+	assert.Len(t, raw, 64)
+	assert.Equal(t, byte(1), raw[31])
+	assert.Equal(t, byte(2), raw[63])
+
+	signature, err = MarshalECDSASignature(elliptic.P384().Params().N, big.NewInt(2))
+	assert.NoError(t, err)
+	_, err = ECDSASignatureToRaw(elliptic.P256(), signature)
+	assert.EqualError(t, err, "invalid signature, R and S must not be larger than the order of curve P-256")
+	_, err = ECDSASignatureToRaw(elliptic.P256(), []byte{0})
+	assert.Error(t, err)
+
+	_, err = RawToECDSASignature(elliptic.P256(), make([]byte, 96))
+	assert.EqualError(t, err, "invalid raw signature length 96 for curve P-256: must be 64")
+	_, err = RawToECDSASignature(elliptic.P256(), make([]byte, 64))
+	assert.EqualError(t, err, "invalid signature, R and S must be larger than zero")
+}
+
+func TestECDSASignatureToJOSE(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+	assert.NoError(t, err)
+	signature, err := MarshalECDSASignature(r, s)
+	assert.NoError(t, err)
+
+	jose, err := ECDSASignatureToJOSE(elliptic.P256(), signature)
+	assert.NoError(t, err)
+	assert.Len(t, jose, 86)
+	assert.NotContains(t, jose, "=")
+	der, err := JOSEToECDSASignature(elliptic.P256(), jose)
+	assert.NoError(t, err)
+	assert.Equal(t, signature, der)
+
+	_, err = JOSEToECDSASignature(elliptic.P256(), "not base64url!")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed decoding JOSE signature [")
+
+	for curve, expected := range map[elliptic.Curve]string{
+		elliptic.P256(): "ES256",
+		elliptic.P384(): "ES384",
+		elliptic.P521(): "ES512",
+	} {
+		algorithm, err := ECDSAJOSEAlgorithm(curve)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, algorithm)
+	}
+	_, err = ECDSAJOSEAlgorithm(elliptic.P224())
+	assert.EqualError(t, err, "curve P-224 has no JWS algorithm")
+}
+
+func TestRSASignatureToJOSE(t *testing.T) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+
+	for _, sign := range []func() ([]byte, error){
+		func() ([]byte, error) { return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]) },
+		func() ([]byte, error) { return rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], nil) },
+	} {
+		signature, err := sign()
+		assert.NoError(t, err)
+		jose, err := RSASignatureToJOSE(&k.PublicKey, signature)
+		assert.NoError(t, err)
+		decoded, err := JOSEToRSASignature(&k.PublicKey, jose)
+		assert.NoError(t, err)
+		assert.Equal(t, signature, decoded)
+	}
+
+	// stripped leading zeros are restored
+	signature, err := JOSEToRSASignature(&k.PublicKey, base64.RawURLEncoding.EncodeToString([]byte{1, 2}))
+	assert.NoError(t, err)
+	assert.Len(t, signature, 256)
+	assert.Equal(t, []byte{0, 1, 2}, signature[253:])
+
+	_, err = PadRSASignature(&k.PublicKey, make([]byte, 257))
+	assert.EqualError(t, err, "invalid RSA signature length 257: must not be larger than the modulus size 256")
+	_, err = JOSEToRSASignature(&k.PublicKey, "not base64url!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,489 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package versions keeps several versions of a named key, so that rotating a
+// key does not break the verification of the signatures of its previous
+// versions. Signing with a named key uses its active version, whereas
+// verifying accepts any version that was not revoked.
+package versions
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/decorator"
+	"github.com/pkg/errors"
+)
+
+// States of the versions of a key.
+const (
+	// Active is the state of the version used to sign.
+	Active = "active"
+	// Retired is the state of the previous versions, still accepted to
+	// verify signatures.
+	Retired = "retired"
+	// Revoked is the state of the versions no longer accepted to verify
+	// signatures.
+	Revoked = "revoked"
+)
+
+// Version is a version of a named key.
+type Version struct {
+	Number  int       `json:"number"`
+	SKI     []byte    `json:"ski"`
+	State   string    `json:"state"`
+	Created time.Time `json:"created"`
+}
+
+// Lineage is the list of the versions of a named key, oldest first.
+type Lineage struct {
+	Name     string     `json:"name"`
+	Versions []*Version `json:"versions"`
+}
+
+// Active returns the active version of the key.
+func (l *Lineage) Active() *Version {
+	for _, v := range l.Versions {
+		if v.State == Active {
+			return v
+		}
+	}
+	return nil
+}
+
+func (l *Lineage) copy() *Lineage {
+	c := &Lineage{Name: l.Name}
+	for _, v := range l.Versions {
+		version := *v
+		c.Versions = append(c.Versions, &version)
+	}
+	return c
+}
+
+// CSP is a BCCSP signing and verifying with the versions of named keys. The
+// lineages of the keys are persisted in a file.
+type CSP struct {
+	decorator.Base
+
+	path     string
+	mutex    sync.RWMutex
+	lineages map[string]*Lineage
+}
+
+// New returns a CSP keeping the lineages of the keys of the given CSP in the
+// file at path.
+func New(csp bccsp.BCCSP, path string) (*CSP, error) {
+	if path == "" {
+		return nil, errors.New("the path of the key versions file is required")
+	}
+	c := &CSP{Base: decorator.Base{BCCSP: csp}, path: path, lineages: map[string]*Lineage{}}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Names returns the names of the keys, in alphabetical order.
+func (c *CSP) Names() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var names []string
+	for name := range c.lineages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lineage returns the versions of the named key.
+func (c *CSP) Lineage(name string) (*Lineage, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	l, ok := c.lineages[name]
+	if !ok {
+		return nil, errors.Errorf("key %s not found", name)
+	}
+	return l.copy(), nil
+}
+
+// Rotate generates a new version of the named key with opts, which becomes
+// the active version. The key is created if it does not exist.
+func (c *CSP) Rotate(name string, opts bccsp.KeyGenOpts) (*Version, error) {
+	if opts == nil || opts.Ephemeral() {
+		return nil, errors.New("the versions of a key must not be ephemeral")
+	}
+	k, err := c.BCCSP.KeyGen(opts)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed generating a new version of key %s", name)
+	}
+	return c.AddVersion(name, k.SKI())
+}
+
+// AddVersion adds the key whose SKI is the one passed as the active version
+// of the named key. The key is created if it does not exist.
+func (c *CSP) AddVersion(name string, ski []byte) (*Version, error) {
+	if name == "" {
+		return nil, errors.New("the name of the key is required")
+	}
+	if _, err := c.BCCSP.GetKey(ski); err != nil {
+		return nil, errors.WithMessagef(err, "key %x not found", ski)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if l, v := c.find(ski); v != nil {
+		return nil, errors.Errorf("key %x is already version %d of key %s", ski, v.Number, l.Name)
+	}
+	l, ok := c.lineages[name]
+	if !ok {
+		l = &Lineage{Name: name}
+	}
+	previous := l.copy()
+
+	v := &Version{Number: len(l.Versions) + 1, SKI: ski, State: Active, Created: time.Now().UTC()}
+	if active := l.Active(); active != nil {
+		active.State = Retired
+	}
+	l.Versions = append(l.Versions, v)
+	c.lineages[name] = l
+
+	if err := c.save(); err != nil {
+		if ok {
+			c.lineages[name] = previous
+		} else {
+			delete(c.lineages, name)
+		}
+		return nil, err
+	}
+	version := *v
+	return &version, nil
+}
+
+// Revoke revokes a version of the named key, whose signatures are no longer
+// accepted. The active version cannot be revoked: the key must be rotated
+// first.
+func (c *CSP) Revoke(name string, number int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	l, ok := c.lineages[name]
+	if !ok {
+		return errors.Errorf("key %s not found", name)
+	}
+	if number < 1 || number > len(l.Versions) {
+		return errors.Errorf("version %d of key %s not found", number, name)
+	}
+	v := l.Versions[number-1]
+	switch v.State {
+	case Active:
+		return errors.Errorf("version %d of key %s is active: rotate the key before revoking it", number, name)
+	case Revoked:
+		return nil
+	}
+
+	v.State = Revoked
+	if err := c.save(); err != nil {
+		v.State = Retired
+		return err
+	}
+	return nil
+}
+
+// Key returns the named key, which signs with its active version and
+// verifies with any version not revoked.
+func (c *CSP) Key(name string) (*Key, error) {
+	if _, err := c.Lineage(name); err != nil {
+		return nil, err
+	}
+	return &Key{csp: c, name: name}, nil
+}
+
+// Sign signs digest with the active version of a named key. Signing with a
+// version which is not active is refused.
+func (c *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	k, err := c.signingKey(k)
+	if err != nil {
+		return nil, err
+	}
+	return c.BCCSP.Sign(k, digest, opts)
+}
+
+// Verify verifies the signature with the versions of a named key which are
+// not revoked, newest first. Verifying with a revoked version fails. An error
+// is only returned for a named key when one of its versions cannot be
+// retrieved.
+func (c *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	named, ok := k.(*Key)
+	if !ok {
+		if l, v := c.lookup(k.SKI()); v != nil && v.State == Revoked {
+			return false, errors.Errorf("version %d of key %s is revoked", v.Number, l.Name)
+		}
+		return c.BCCSP.Verify(k, signature, digest, opts)
+	}
+
+	l, err := c.Lineage(named.name)
+	if err != nil {
+		return false, err
+	}
+	var lastErr error
+	for i := len(l.Versions) - 1; i >= 0; i-- {
+		v := l.Versions[i]
+		if v.State == Revoked {
+			continue
+		}
+		vk, err := c.BCCSP.GetKey(v.SKI)
+		if err != nil {
+			lastErr = errors.WithMessagef(err, "failed getting version %d of key %s", v.Number, l.Name)
+			continue
+		}
+		// the versions may use different algorithms, so the signatures
+		// which cannot be verified with a version are not from it
+		if valid, err := c.BCCSP.Verify(vk, signature, digest, opts); err == nil && valid {
+			return true, nil
+		}
+	}
+	return false, lastErr
+}
+
+// VerifyBatch verifies the signatures of the requests one at a time when one
+// of them is made with a named key or a version of it, and with the wrapped
+// CSP otherwise.
+func (c *CSP) VerifyBatch(requests []*bccsp.VerifyRequest) []*bccsp.VerifyResult {
+	batch := true
+	for _, r := range requests {
+		if _, ok := r.Key.(*Key); ok {
+			batch = false
+			break
+		}
+		if _, v := c.lookup(r.Key.SKI()); v != nil {
+			batch = false
+			break
+		}
+	}
+	if batch {
+		return c.Base.VerifyBatch(requests)
+	}
+	results := make([]*bccsp.VerifyResult, len(requests))
+	for i, r := range requests {
+		valid, err := c.Verify(r.Key, r.Signature, r.Digest, r.Opts)
+		results[i] = &bccsp.VerifyResult{Valid: valid, Err: err}
+	}
+	return results
+}
+
+// DeleteKey deletes the key from the wrapped CSP, unless it is the active
+// version of a named key.
+func (c *CSP) DeleteKey(ski []byte) error {
+	if l, v := c.lookup(ski); v != nil && v.State == Active {
+		return errors.Errorf("key %x is the active version of key %s: rotate the key before deleting it", ski, l.Name)
+	}
+	return c.Base.DeleteKey(ski)
+}
+
+// ExportBackup backs up the keys of the wrapped CSP, signing the manifest
+// with the active version of the signer when it is a named key.
+func (c *CSP) ExportBackup(w io.Writer, opts *bccsp.BackupOpts) (*bccsp.BackupManifest, error) {
+	if opts != nil && opts.Signer != nil {
+		signer, err := c.resolve(opts.Signer)
+		if err != nil {
+			return nil, err
+		}
+		resolved := *opts
+		resolved.Signer = signer
+		opts = &resolved
+	}
+	return c.Base.ExportBackup(w, opts)
+}
+
+// Encrypt encrypts plaintext with the active version of a named key.
+func (c *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	k, err := c.resolve(k)
+	if err != nil {
+		return nil, err
+	}
+	return c.BCCSP.Encrypt(k, plaintext, opts)
+}
+
+// Decrypt decrypts ciphertext with the active version of a named key.
+func (c *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	k, err := c.resolve(k)
+	if err != nil {
+		return nil, err
+	}
+	return c.BCCSP.Decrypt(k, ciphertext, opts)
+}
+
+// signingKey returns the key to sign with, refusing the versions which are
+// not active
+func (c *CSP) signingKey(k bccsp.Key) (bccsp.Key, error) {
+	if _, ok := k.(*Key); ok {
+		return c.resolve(k)
+	}
+	if l, v := c.lookup(k.SKI()); v != nil && v.State != Active {
+		return nil, errors.Errorf("version %d of key %s is %s: sign with the active version", v.Number, l.Name, v.State)
+	}
+	return k, nil
+}
+
+// resolve returns the active version of a named key, and other keys as is
+func (c *CSP) resolve(k bccsp.Key) (bccsp.Key, error) {
+	named, ok := k.(*Key)
+	if !ok {
+		return k, nil
+	}
+	return named.active()
+}
+
+func (c *CSP) lookup(ski []byte) (*Lineage, *Version) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.find(ski)
+}
+
+// find returns the version whose SKI is the one passed; it must be called
+// with the mutex held
+func (c *CSP) find(ski []byte) (*Lineage, *Version) {
+	for _, l := range c.lineages {
+		for _, v := range l.Versions {
+			if bytes.Equal(v.SKI, ski) {
+				return l, v
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (c *CSP) load() error {
+	raw, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed reading key versions from %s", c.path)
+	}
+	var lineages []*Lineage
+	if err := json.Unmarshal(raw, &lineages); err != nil {
+		return errors.Wrapf(err, "failed parsing key versions from %s", c.path)
+	}
+	for _, l := range lineages {
+		c.lineages[l.Name] = l
+	}
+	return nil
+}
+
+// save persists the lineages; it must be called with the mutex held
+func (c *CSP) save() error {
+	lineages := make([]*Lineage, 0, len(c.lineages))
+	for _, l := range c.lineages {
+		lineages = append(lineages, l)
+	}
+	sort.Slice(lineages, func(i, j int) bool {
+		return lineages[i].Name < lineages[j].Name
+	})
+	raw, err := json.MarshalIndent(lineages, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling key versions")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return errors.Wrapf(err, "failed writing key versions to %s", c.path)
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing key versions to %s", c.path)
+	}
+	return errors.Wrapf(os.Rename(tmp, c.path), "failed writing key versions to %s", c.path)
+}
+
+// Key is a named key. Its SKI and public key are those of its active version.
+type Key struct {
+	csp  *CSP
+	name string
+}
+
+// Name returns the name of the key.
+func (k *Key) Name() string {
+	return k.name
+}
+
+func (k *Key) active() (bccsp.Key, error) {
+	l, err := k.csp.Lineage(k.name)
+	if err != nil {
+		return nil, err
+	}
+	v := l.Active()
+	if v == nil {
+		return nil, errors.Errorf("key %s has no active version", k.name)
+	}
+	active, err := k.csp.BCCSP.GetKey(v.SKI)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting version %d of key %s", v.Number, k.name)
+	}
+	return active, nil
+}
+
+// Bytes converts the active version of the key to its byte representation.
+func (k *Key) Bytes() ([]byte, error) {
+	active, err := k.active()
+	if err != nil {
+		return nil, err
+	}
+	return active.Bytes()
+}
+
+// SKI returns the subject key identifier of the active version of the key,
+// or nil when it cannot be retrieved.
+func (k *Key) SKI() []byte {
+	active, err := k.active()
+	if err != nil {
+		return nil
+	}
+	return active.SKI()
+}
+
+// Symmetric returns true if the active version of the key is symmetric.
+func (k *Key) Symmetric() bool {
+	active, err := k.active()
+	return err == nil && active.Symmetric()
+}
+
+// Private returns true if the active version of the key is private.
+func (k *Key) Private() bool {
+	active, err := k.active()
+	return err == nil && active.Private()
+}
+
+// PublicKey returns the public key of the active version of the key.
+func (k *Key) PublicKey() (bccsp.Key, error) {
+	active, err := k.active()
+	if err != nil {
+		return nil, err
+	}
+	return active.PublicKey()
+}
+
+// Fingerprint returns the fingerprint of the public key of the active
+// version of the key.
+func (k *Key) Fingerprint() ([]byte, error) {
+	active, err := k.active()
+	if err != nil {
+		return nil, err
+	}
+	fingerprinter, ok := active.(bccsp.KeyFingerprinter)
+	if !ok {
+		return nil, errors.Errorf("key %s does not report its fingerprint", k.name)
+	}
+	return fingerprinter.Fingerprint()
+}
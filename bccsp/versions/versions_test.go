@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCSP(t *testing.T) (*CSP, func()) {
+	dir, err := ioutil.TempDir("", "versions")
+	require.NoError(t, err)
+	ks, err := sw.NewFileBasedKeyStore(nil, filepath.Join(dir, "keystore"), false)
+	require.NoError(t, err)
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	csp, err := New(provider, filepath.Join(dir, "keyversions.json"))
+	require.NoError(t, err)
+	return csp, func() { os.RemoveAll(dir) }
+}
+
+func TestRotate(t *testing.T) {
+	csp, cleanup := newTestCSP(t)
+	defer cleanup()
+	digest := sha256.Sum256([]byte("artifact"))
+
+	v1, err := csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	require.Equal(t, 1, v1.Number)
+	require.Equal(t, Active, v1.State)
+	k, err := csp.Key("release")
+	require.NoError(t, err)
+	require.Equal(t, "release", k.Name())
+	require.Equal(t, v1.SKI, k.SKI())
+	require.True(t, k.Private())
+	require.False(t, k.Symmetric())
+
+	signature1, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	v2, err := csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	require.Equal(t, 2, v2.Number)
+	require.Equal(t, v2.SKI, k.SKI())
+	signature2, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	fingerprint, err := k.Fingerprint()
+	require.NoError(t, err)
+	expected, err := pub.(bccsp.KeyFingerprinter).Fingerprint()
+	require.NoError(t, err)
+	require.Equal(t, expected, fingerprint)
+
+	l, err := csp.Lineage("release")
+	require.NoError(t, err)
+	require.Len(t, l.Versions, 2)
+	require.Equal(t, Retired, l.Versions[0].State)
+	require.Equal(t, v2.SKI, l.Active().SKI)
+	require.Equal(t, []string{"release"}, csp.Names())
+
+	// signatures of every version which is not revoked are accepted
+	for _, signature := range [][]byte{signature1, signature2} {
+		valid, err := csp.Verify(k, signature, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+	results := csp.VerifyBatch([]*bccsp.VerifyRequest{
+		{Key: k, Signature: signature1, Digest: digest[:]},
+		{Key: k, Signature: signature1, Digest: []byte("tampered")},
+	})
+	require.True(t, results[0].Valid)
+	require.False(t, results[1].Valid)
+
+	// retired versions can no longer sign
+	retired, err := csp.GetKey(v1.SKI)
+	require.NoError(t, err)
+	_, err = csp.Sign(retired, digest[:], nil)
+	require.EqualError(t, err, "version 1 of key release is retired: sign with the active version")
+
+	require.NoError(t, csp.Revoke("release", 1))
+	valid, err := csp.Verify(k, signature1, digest[:], nil)
+	require.NoError(t, err)
+	require.False(t, valid)
+	_, err = csp.Verify(retired, signature1, digest[:], nil)
+	require.EqualError(t, err, "version 1 of key release is revoked")
+	valid, err = csp.Verify(k, signature2, digest[:], nil)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// the versions are persisted
+	reloaded, err := New(csp.BCCSP, csp.path)
+	require.NoError(t, err)
+	l, err = reloaded.Lineage("release")
+	require.NoError(t, err)
+	require.Equal(t, Revoked, l.Versions[0].State)
+	require.Equal(t, Active, l.Versions[1].State)
+}
+
+func TestRevokeFailures(t *testing.T) {
+	csp, cleanup := newTestCSP(t)
+	defer cleanup()
+	v, err := csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	require.EqualError(t, csp.Revoke("unknown", 1), "key unknown not found")
+	require.EqualError(t, csp.Revoke("release", 2), "version 2 of key release not found")
+	require.EqualError(t, csp.Revoke("release", 1), "version 1 of key release is active: rotate the key before revoking it")
+	require.EqualError(t, csp.DeleteKey(v.SKI), "key "+hex.EncodeToString(v.SKI)+" is the active version of key release: rotate the key before deleting it")
+}
+
+func TestAddVersionFailures(t *testing.T) {
+	csp, cleanup := newTestCSP(t)
+	defer cleanup()
+
+	_, err := csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.EqualError(t, err, "the versions of a key must not be ephemeral")
+	_, err = csp.AddVersion("", []byte{1})
+	require.EqualError(t, err, "the name of the key is required")
+	_, err = csp.AddVersion("release", []byte{1})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "key 01 not found")
+
+	v, err := csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	_, err = csp.AddVersion("other", v.SKI)
+	require.EqualError(t, err, "key "+hex.EncodeToString(v.SKI)+" is already version 1 of key release")
+
+	_, err = csp.Key("other")
+	require.EqualError(t, err, "key other not found")
+
+	// the version is not kept when it cannot be persisted
+	dir, err := ioutil.TempDir("", "versions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csp.path = dir
+	_, err = csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.Error(t, err)
+	l, err := csp.Lineage("release")
+	require.NoError(t, err)
+	require.Len(t, l.Versions, 1)
+	require.Equal(t, Active, l.Versions[0].State)
+}
+
+func TestNewFailures(t *testing.T) {
+	_, err := New(nil, "")
+	require.EqualError(t, err, "the path of the key versions file is required")
+
+	dir, err := ioutil.TempDir("", "versions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keyversions.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("garbage"), 0600))
+	_, err = New(nil, path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing key versions from "+path)
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -57,6 +58,11 @@ var (
 	computeUpdateChannelID = computeUpdate.Flag("channel_id", "The name of the channel for this update.").Required().String()
 	computeUpdateDest      = computeUpdate.Flag("output", "A file to write the JSON document to.").Default(os.Stdout.Name()).OpenFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 
+	describeUpdate         = app.Command("describe_update", "Takes two marshaled config blocks and describes the changes between their configs, along with the policies which the config update must satisfy.")
+	describeUpdateOriginal = describeUpdate.Flag("original", "The original config block.").Required().File()
+	describeUpdateUpdated  = describeUpdate.Flag("updated", "The updated config block.").Required().File()
+	describeUpdateDest     = describeUpdate.Flag("output", "A file to write the JSON document to.").Default(os.Stdout.Name()).OpenFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+
 	version = app.Command("version", "Show version information")
 )
 
@@ -91,6 +97,14 @@ func main() {
 		if err != nil {
 			app.Fatalf("Error computing update: %s", err)
 		}
+	case describeUpdate.FullCommand():
+		defer (*describeUpdateOriginal).Close()
+		defer (*describeUpdateUpdated).Close()
+		defer (*describeUpdateDest).Close()
+		err := describeUpdt(*describeUpdateOriginal, *describeUpdateUpdated, *describeUpdateDest)
+		if err != nil {
+			app.Fatalf("Error describing update: %s", err)
+		}
 	// "version" command
 	case version.FullCommand():
 		printVersion()
@@ -218,3 +232,47 @@ func computeUpdt(original, updated, output *os.File, channelID string) error {
 
 	return nil
 }
+
+func readConfigBlock(input *os.File) (*cb.Config, error) {
+	in, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading block")
+	}
+
+	block := &cb.Block{}
+	err = proto.Unmarshal(in, block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling block")
+	}
+
+	return update.ConfigFromBlock(block)
+}
+
+func describeUpdt(original, updated, output *os.File) error {
+	origConf, err := readConfigBlock(original)
+	if err != nil {
+		return errors.WithMessage(err, "error reading original config block")
+	}
+
+	updtConf, err := readConfigBlock(updated)
+	if err != nil {
+		return errors.WithMessage(err, "error reading updated config block")
+	}
+
+	description, err := update.Describe(origConf, updtConf)
+	if err != nil {
+		return errors.Wrapf(err, "error describing config update")
+	}
+
+	outBytes, err := json.MarshalIndent(description, "", "\t")
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling description")
+	}
+
+	_, err = output.Write(append(outBytes, '\n'))
+	if err != nil {
+		return errors.Wrapf(err, "error writing description to output")
+	}
+
+	return nil
+}
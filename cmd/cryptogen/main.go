@@ -7,6 +7,7 @@ package main
 
 import (
 	"bytes"
+	"crypto"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,6 +15,7 @@ import (
 	"path/filepath"
 	"text/template"
 
+	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/internal/cryptogen/ca"
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
 	"github.com/hyperledger/fabric/internal/cryptogen/metadata"
@@ -66,22 +68,52 @@ type UsersSpec struct {
 	Count int `yaml:"Count"`
 }
 
+type KeySpec struct {
+	Algorithm string `yaml:"Algorithm"`
+	Curve     string `yaml:"Curve"`
+	Encoding  string `yaml:"Encoding"`
+	Password  string `yaml:"Password"`
+}
+
 type OrgSpec struct {
 	Name          string       `yaml:"Name"`
 	Domain        string       `yaml:"Domain"`
 	EnableNodeOUs bool         `yaml:"EnableNodeOUs"`
 	CA            NodeSpec     `yaml:"CA"`
+	Key           KeySpec      `yaml:"Key"`
 	Template      NodeTemplate `yaml:"Template"`
 	Specs         []NodeSpec   `yaml:"Specs"`
 	Users         UsersSpec    `yaml:"Users"`
 }
 
+type PKCS11Spec struct {
+	Library string `yaml:"Library"`
+	Label   string `yaml:"Label"`
+	Pin     string `yaml:"Pin"`
+}
+
 type Config struct {
-	OrdererOrgs []OrgSpec `yaml:"OrdererOrgs"`
-	PeerOrgs    []OrgSpec `yaml:"PeerOrgs"`
+	PKCS11      *PKCS11Spec `yaml:"PKCS11"`
+	OrdererOrgs []OrgSpec   `yaml:"OrdererOrgs"`
+	PeerOrgs    []OrgSpec   `yaml:"PeerOrgs"`
 }
 
 var defaultConfig = `
+# ---------------------------------------------------------------------------
+# "PKCS11" - Token in which to generate the keys of the identities
+# ---------------------------------------------------------------------------
+# Uncomment this section to generate the keys of the CAs, nodes and users in a
+# PKCS#11 token, such as SoftHSM, rather than in the keystore of their MSP. The
+# keystore is then left empty: the MSP finds the key in the token by the SKI of
+# the public key of its certificate, also set in its Subject Key Identifier.
+# TLS keys are still written to files, where peers and orderers read them.
+# This requires cryptogen to be built with the pkcs11 build tag.
+# ---------------------------------------------------------------------------
+# PKCS11:
+#   Library: /usr/lib/softhsm/libsofthsm2.so
+#   Label: ForFabric
+#   Pin: 98765432
+
 # ---------------------------------------------------------------------------
 # "OrdererOrgs" - Definition of organizations managing orderer nodes
 # ---------------------------------------------------------------------------
@@ -125,6 +157,25 @@ PeerOrgs:
     #    StreetAddress: address for org # default nil
     #    PostalCode: postalCode for org # default nil
 
+    # ---------------------------------------------------------------------------
+    # "Key"
+    # ---------------------------------------------------------------------------
+    # Uncomment this section to choose how the keys of the CAs, nodes and users
+    # of this organization are generated and stored.
+    #   - Algorithm: (Optional) ECDSA (default) or Ed25519.
+    #   - Curve:     (Optional) The curve of ECDSA keys, P-256 (default) or P-384.
+    #   - Encoding:  (Optional) The encoding of the private keys, PKCS8 (default)
+    #                or SEC1. Ed25519 keys are always encoded in PKCS8.
+    #   - Password:  (Optional) Encrypts the private keys with this password.
+    #                Peers and orderers cannot read encrypted keys from their
+    #                local MSP, so only use it for client identities.
+    # ---------------------------------------------------------------------------
+    # Key:
+    #    Algorithm: ECDSA
+    #    Curve: P-384
+    #    Encoding: PKCS8
+    #    Password: secret
+
     # ---------------------------------------------------------------------------
     # "Specs"
     # ---------------------------------------------------------------------------
@@ -238,6 +289,17 @@ func main() {
 
 }
 
+// keyProvider generates the keys of the identities when they are kept in a
+// PKCS#11 token
+var keyProvider bccsp.BCCSP
+
+func newKeyProvider(config *Config) (bccsp.BCCSP, error) {
+	if config.PKCS11 == nil {
+		return nil, nil
+	}
+	return newPKCS11Provider(*config.PKCS11)
+}
+
 func getConfig() (*Config, error) {
 	var configData string
 
@@ -275,6 +337,12 @@ func extend() {
 		os.Exit(-1)
 	}
 
+	keyProvider, err = newKeyProvider(config)
+	if err != nil {
+		fmt.Printf("Error initializing PKCS#11 token: %s", err)
+		os.Exit(-1)
+	}
+
 	for _, orgSpec := range config.PeerOrgs {
 		err = renderOrgSpec(&orgSpec, "peer")
 		if err != nil {
@@ -308,10 +376,10 @@ func extendPeerOrg(orgSpec OrgSpec) {
 	caDir := filepath.Join(orgDir, "ca")
 	tlscaDir := filepath.Join(orgDir, "tlsca")
 
-	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName)
-	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName, keyOptions(orgSpec))
+	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName, tlsKeyOptions(orgSpec))
 
-	generateNodes(peersDir, orgSpec.Specs, signCA, tlsCA, msp.PEER, orgSpec.EnableNodeOUs)
+	generateNodes(peersDir, orgSpec.Specs, signCA, tlsCA, msp.PEER, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	adminUser := NodeSpec{
 		isAdmin:    true,
@@ -341,7 +409,7 @@ func extendPeerOrg(orgSpec OrgSpec) {
 		users = append(users, user)
 	}
 
-	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs)
+	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 }
 
 func extendOrdererOrg(orgSpec OrgSpec) {
@@ -357,10 +425,10 @@ func extendOrdererOrg(orgSpec OrgSpec) {
 		return
 	}
 
-	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName)
-	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName, keyOptions(orgSpec))
+	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName, tlsKeyOptions(orgSpec))
 
-	generateNodes(orderersDir, orgSpec.Specs, signCA, tlsCA, msp.ORDERER, orgSpec.EnableNodeOUs)
+	generateNodes(orderersDir, orgSpec.Specs, signCA, tlsCA, msp.ORDERER, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	adminUser := NodeSpec{
 		isAdmin:    true,
@@ -389,6 +457,12 @@ func generate() {
 		os.Exit(-1)
 	}
 
+	keyProvider, err = newKeyProvider(config)
+	if err != nil {
+		fmt.Printf("Error initializing PKCS#11 token: %s", err)
+		os.Exit(-1)
+	}
+
 	for _, orgSpec := range config.PeerOrgs {
 		err = renderOrgSpec(&orgSpec, "peer")
 		if err != nil {
@@ -468,7 +542,31 @@ func renderNodeSpec(domain string, spec *NodeSpec) error {
 	return nil
 }
 
+func keyOptions(orgSpec OrgSpec) csp.KeyOptions {
+	opts := tlsKeyOptions(orgSpec)
+	opts.CSP = keyProvider
+	return opts
+}
+
+// TLS keys are always written to files, where peers and orderers read them
+func tlsKeyOptions(orgSpec OrgSpec) csp.KeyOptions {
+	opts := csp.KeyOptions{
+		Algorithm: orgSpec.Key.Algorithm,
+		Curve:     orgSpec.Key.Curve,
+		Encoding:  orgSpec.Key.Encoding,
+	}
+	if orgSpec.Key.Password != "" {
+		opts.Password = []byte(orgSpec.Key.Password)
+	}
+	return opts
+}
+
 func renderOrgSpec(orgSpec *OrgSpec, prefix string) error {
+	err := keyOptions(*orgSpec).Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid key specification for org %s: %s", orgSpec.Name, err)
+	}
+
 	// First process all of our templated nodes
 	for i := 0; i < orgSpec.Template.Count; i++ {
 		data := HostnameData{
@@ -503,7 +601,7 @@ func renderOrgSpec(orgSpec *OrgSpec, prefix string) error {
 	if len(orgSpec.CA.Hostname) == 0 {
 		orgSpec.CA.Hostname = "ca"
 	}
-	err := renderNodeSpec(orgSpec.Domain, &orgSpec.CA)
+	err = renderNodeSpec(orgSpec.Domain, &orgSpec.CA)
 	if err != nil {
 		return err
 	}
@@ -525,13 +623,13 @@ func generatePeerOrg(baseDir string, orgSpec OrgSpec) {
 	usersDir := filepath.Join(orgDir, "users")
 	adminCertsDir := filepath.Join(mspDir, "admincerts")
 	// generate signing CA
-	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode, keyOptions(orgSpec))
 	if err != nil {
 		fmt.Printf("Error generating signCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
 	// generate TLS CA
-	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode, tlsKeyOptions(orgSpec))
 	if err != nil {
 		fmt.Printf("Error generating tlsCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
@@ -543,7 +641,7 @@ func generatePeerOrg(baseDir string, orgSpec OrgSpec) {
 		os.Exit(1)
 	}
 
-	generateNodes(peersDir, orgSpec.Specs, signCA, tlsCA, msp.PEER, orgSpec.EnableNodeOUs)
+	generateNodes(peersDir, orgSpec.Specs, signCA, tlsCA, msp.PEER, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	// TODO: add ability to specify usernames
 	users := []NodeSpec{}
@@ -561,7 +659,7 @@ func generatePeerOrg(baseDir string, orgSpec OrgSpec) {
 	}
 
 	users = append(users, adminUser)
-	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs)
+	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	// copy the admin cert to the org's MSP admincerts
 	if !orgSpec.EnableNodeOUs {
@@ -612,7 +710,7 @@ func copyAdminCert(usersDir, adminCertsDir, adminUserName string) error {
 	return nil
 }
 
-func generateNodes(baseDir string, nodes []NodeSpec, signCA *ca.CA, tlsCA *ca.CA, nodeType int, nodeOUs bool) {
+func generateNodes(baseDir string, nodes []NodeSpec, signCA *ca.CA, tlsCA *ca.CA, nodeType int, nodeOUs bool, keyOpts csp.KeyOptions) {
 	for _, node := range nodes {
 		nodeDir := filepath.Join(baseDir, node.CommonName)
 		if _, err := os.Stat(nodeDir); os.IsNotExist(err) {
@@ -620,7 +718,7 @@ func generateNodes(baseDir string, nodes []NodeSpec, signCA *ca.CA, tlsCA *ca.CA
 			if node.isAdmin && nodeOUs {
 				currentNodeType = msp.ADMIN
 			}
-			err := msp.GenerateLocalMSP(nodeDir, node.CommonName, node.SANS, signCA, tlsCA, currentNodeType, nodeOUs)
+			err := msp.GenerateLocalMSP(nodeDir, node.CommonName, node.SANS, signCA, tlsCA, currentNodeType, nodeOUs, keyOpts)
 			if err != nil {
 				fmt.Printf("Error generating local MSP for %v:\n%v\n", node, err)
 				os.Exit(1)
@@ -642,13 +740,13 @@ func generateOrdererOrg(baseDir string, orgSpec OrgSpec) {
 	usersDir := filepath.Join(orgDir, "users")
 	adminCertsDir := filepath.Join(mspDir, "admincerts")
 	// generate signing CA
-	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode, keyOptions(orgSpec))
 	if err != nil {
 		fmt.Printf("Error generating signCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
 	// generate TLS CA
-	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode, tlsKeyOptions(orgSpec))
 	if err != nil {
 		fmt.Printf("Error generating tlsCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
@@ -660,7 +758,7 @@ func generateOrdererOrg(baseDir string, orgSpec OrgSpec) {
 		os.Exit(1)
 	}
 
-	generateNodes(orderersDir, orgSpec.Specs, signCA, tlsCA, msp.ORDERER, orgSpec.EnableNodeOUs)
+	generateNodes(orderersDir, orgSpec.Specs, signCA, tlsCA, msp.ORDERER, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	adminUser := NodeSpec{
 		isAdmin:    true,
@@ -671,7 +769,7 @@ func generateOrdererOrg(baseDir string, orgSpec OrgSpec) {
 	users := []NodeSpec{}
 	// add an admin user
 	users = append(users, adminUser)
-	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs)
+	generateNodes(usersDir, users, signCA, tlsCA, msp.CLIENT, orgSpec.EnableNodeOUs, keyOptions(orgSpec))
 
 	// copy the admin cert to the org's MSP admincerts
 	if !orgSpec.EnableNodeOUs {
@@ -722,10 +820,21 @@ func printVersion() {
 	fmt.Println(metadata.GetVersionInfo())
 }
 
-func getCA(caDir string, spec OrgSpec, name string) *ca.CA {
-	priv, _ := csp.LoadPrivateKey(caDir)
+func getCA(caDir string, spec OrgSpec, name string, keyOpts csp.KeyOptions) *ca.CA {
 	cert, _ := ca.LoadCertificateECDSA(caDir)
 
+	var priv crypto.Signer
+	if keyOpts.CSP != nil && cert != nil {
+		var err error
+		priv, err = csp.LoadCSPSigner(keyOpts.CSP, cert)
+		if err != nil {
+			fmt.Printf("Error loading the key of CA %s from the PKCS#11 token:\n%v\n", name, err)
+			os.Exit(1)
+		}
+	} else {
+		priv, _ = csp.LoadSigner(caDir, keyOpts.Password)
+	}
+
 	return &ca.CA{
 		Name:               name,
 		Signer:             priv,
@@ -0,0 +1,19 @@
+// +build !pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric/bccsp"
+)
+
+func newPKCS11Provider(spec PKCS11Spec) (bccsp.BCCSP, error) {
+	return nil, errors.New("cryptogen was built without PKCS#11 support, rebuild it with the pkcs11 build tag")
+}
@@ -0,0 +1,25 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/pkcs11"
+	"github.com/hyperledger/fabric/bccsp/sw"
+)
+
+func newPKCS11Provider(spec PKCS11Spec) (bccsp.BCCSP, error) {
+	return pkcs11.New(pkcs11.PKCS11Opts{
+		SecLevel:   256,
+		HashFamily: "SHA2",
+		Library:    spec.Library,
+		Label:      spec.Label,
+		Pin:        spec.Pin,
+	}, sw.NewDummyKeyStore())
+}
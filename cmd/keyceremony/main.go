@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+// keyceremony is a command line tool that generates the root key of an
+// organization and its admin identities on an air-gapped machine, splits the
+// root key into Shamir shares for its custodians, and keeps a transcript of
+// the ceremony that its participants sign
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/hyperledger/fabric/common/metadata"
+	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/hyperledger/fabric/internal/keyceremony"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const programName = "keyceremony"
+
+// command line flags
+var (
+	app = kingpin.New(programName, "Utility for generating the root keys of organizations in auditable key ceremonies")
+
+	transcriptFile = app.Flag("transcript", "The transcript of the ceremony").Default("transcript.json").String()
+
+	generate             = app.Command("generate", "Generate the root key, root CA certificate and admin identities of an organization, and split the root key into shares")
+	generateOutput       = generate.Flag("output", "The output directory in which to place artifacts").Default("ceremony").String()
+	generateOrg          = generate.Flag("org", "The name of the organization").Required().String()
+	generateCommonName   = generate.Flag("ca-name", "The common name of the root CA certificate").Required().String()
+	generateShares       = generate.Flag("shares", "The number of shares the root key is split into").Default("5").Int()
+	generateThreshold    = generate.Flag("threshold", "The number of shares recovering the root key").Default("3").Int()
+	generateAdmins       = generate.Flag("admin", "The common name of an admin identity issued by the root CA (repeatable)").Strings()
+	generateParticipants = generate.Flag("participant", "The PEM encoded certificate of a participant signing the transcript (repeatable)").Required().ExistingFiles()
+
+	sign         = app.Command("sign", "Sign all the entries of the transcript as a participant")
+	signKeystore = sign.Flag("keystore", "The directory holding the private key of the participant").Required().ExistingDir()
+	signCert     = sign.Flag("cert", "The PEM encoded certificate of the participant").Required().ExistingFile()
+
+	verify          = app.Command("verify", "Verify the signatures of the transcript and the digests of the artifacts")
+	verifyArtifacts = verify.Flag("artifacts", "The directory holding the artifacts of the ceremony, if any").ExistingDir()
+
+	recoverKey      = app.Command("recover", "Recover the root key from shares")
+	recoverShares   = recoverKey.Flag("share", "A share of the root key (repeatable)").Required().ExistingFiles()
+	recoverCert     = recoverKey.Flag("cert", "The root CA certificate").Required().ExistingFile()
+	recoverKeystore = recoverKey.Flag("keystore", "The directory in which to place the recovered root key").Required().String()
+
+	escrow               = app.Command("escrow", "Place exported keys in escrow with custodians")
+	escrowSplit          = escrow.Command("split", "Split an exported, preferably wrapped, key into shares")
+	escrowSplitKey       = escrowSplit.Flag("key", "The file holding the exported key").Required().ExistingFile()
+	escrowSplitLabel     = escrowSplit.Flag("label", "The description of the key for its custodians").Required().String()
+	escrowSplitOutput    = escrowSplit.Flag("output", "The output directory in which to place the shares").Default("escrow").String()
+	escrowSplitShares    = escrowSplit.Flag("shares", "The number of shares the key is split into").Default("5").Int()
+	escrowSplitThreshold = escrowSplit.Flag("threshold", "The number of shares reconstructing the key").Default("3").Int()
+	escrowRecover        = escrow.Command("recover", "Reconstruct an escrowed key from shares")
+	escrowRecoverShare   = escrowRecover.Flag("share", "A share of the key (repeatable)").Required().ExistingFiles()
+	escrowRecoverKey     = escrowRecover.Flag("key", "The file in which to write the reconstructed key").Required().String()
+
+	version = app.Command("version", "Show version information")
+)
+
+func main() {
+	app.HelpFlag.Short('h')
+
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+
+	case generate.FullCommand():
+		if _, err := os.Stat(*generateOutput); err == nil {
+			handleError(errors.Errorf("Directory %s already exists", *generateOutput))
+		}
+		var participants [][]byte
+		for _, path := range *generateParticipants {
+			participants = append(participants, readFile(path))
+		}
+		t, err := keyceremony.Generate(*generateOutput, keyceremony.Config{
+			Org:          *generateOrg,
+			CommonName:   *generateCommonName,
+			Shares:       *generateShares,
+			Threshold:    *generateThreshold,
+			Admins:       *generateAdmins,
+			Participants: participants,
+		})
+		handleError(err)
+		fmt.Printf("Ceremony %s: generated the root key of %s in %d shares, %d of which recover it\n", t.Ceremony, t.Org, *generateShares, *generateThreshold)
+		fmt.Printf("The transcript is %s\n", filepath.Join(*generateOutput, "transcript.json"))
+
+	case sign.FullCommand():
+		t, err := keyceremony.LoadTranscript(*transcriptFile)
+		handleError(err)
+		signer, err := csp.LoadSigner(*signKeystore, nil)
+		handleError(err)
+		handleError(t.Sign(signer, readFile(*signCert)))
+		handleError(t.Save(*transcriptFile))
+		fmt.Printf("Signed the %d entries of ceremony %s\n", len(t.Entries), t.Ceremony)
+
+	case verify.FullCommand():
+		t, err := keyceremony.LoadTranscript(*transcriptFile)
+		handleError(err)
+		handleError(t.Verify())
+		if *verifyArtifacts != "" {
+			names := t.Artifacts()
+			sort.Strings(names)
+			for _, name := range names {
+				content, err := ioutil.ReadFile(filepath.Join(*verifyArtifacts, filepath.FromSlash(name)))
+				if os.IsNotExist(err) {
+					fmt.Printf("%s: absent\n", name)
+					continue
+				}
+				handleError(err)
+				handleError(t.VerifyArtifact(name, content))
+				fmt.Printf("%s: ok\n", name)
+			}
+		}
+		for _, entry := range t.Entries {
+			fmt.Printf("%s %s %v\n", entry.Time.Format("2006-01-02T15:04:05Z"), entry.Step, entry.Details)
+		}
+		fmt.Printf("The transcript of ceremony %s is signed by all its participants\n", t.Ceremony)
+
+	case recoverKey.FullCommand():
+		t, err := keyceremony.LoadTranscript(*transcriptFile)
+		handleError(err)
+		var shares [][]byte
+		for _, path := range *recoverShares {
+			shares = append(shares, readFile(path))
+		}
+		handleError(keyceremony.Recover(t, shares, readFile(*recoverCert), *recoverKeystore))
+		handleError(t.Save(*transcriptFile))
+		fmt.Printf("Recovered the root key of %s in %s\n", t.Org, *recoverKeystore)
+
+	case escrowSplit.FullCommand():
+		if _, err := os.Stat(*escrowSplitOutput); err == nil {
+			handleError(errors.Errorf("Directory %s already exists", *escrowSplitOutput))
+		}
+		shares, err := keyceremony.EscrowKey(readFile(*escrowSplitKey), *escrowSplitLabel, *escrowSplitShares, *escrowSplitThreshold)
+		handleError(err)
+		handleError(os.MkdirAll(*escrowSplitOutput, 0700))
+		for _, share := range shares {
+			raw, err := keyceremony.MarshalEscrowShare(share)
+			handleError(err)
+			path := filepath.Join(*escrowSplitOutput, fmt.Sprintf("share-%d.json", share.Index))
+			handleError(ioutil.WriteFile(path, raw, 0600))
+		}
+		fmt.Printf("Escrow %s: split %s in %d shares, %d of which reconstruct it\n", shares[0].Escrow, *escrowSplitLabel, *escrowSplitShares, *escrowSplitThreshold)
+
+	case escrowRecover.FullCommand():
+		var shares []*keyceremony.EscrowShare
+		for _, path := range *escrowRecoverShare {
+			share, err := keyceremony.ParseEscrowShare(readFile(path))
+			handleError(errors.WithMessagef(err, "invalid share %s", path))
+			shares = append(shares, share)
+		}
+		key, err := keyceremony.RecoverEscrowedKey(shares)
+		handleError(err)
+		handleError(ioutil.WriteFile(*escrowRecoverKey, key, 0600))
+		fmt.Printf("Reconstructed %s from escrow %s in %s\n", shares[0].Label, shares[0].Escrow, *escrowRecoverKey)
+
+	case version.FullCommand():
+		printVersion()
+
+	}
+}
+
+func printVersion() {
+	fmt.Printf(
+		"%s:\n Version: %s\n Commit SHA: %s\n Go version: %s\n OS/Arch: %s\n",
+		programName,
+		metadata.Version,
+		metadata.CommitSHA,
+		runtime.Version(),
+		fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+	)
+}
+
+func readFile(path string) []byte {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		handleError(errors.Wrapf(err, "failed to read %s", path))
+	}
+	return raw
+}
+
+func handleError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/hyperledger/fabric/common/metadata"
+	"github.com/hyperledger/fabric/internal/ledgerutil"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const programName = "ledgerutil"
+
+// command line flags
+var (
+	app = kingpin.New(programName, "Utility for troubleshooting the ledgers of Hyperledger Fabric peers")
+
+	compare       = app.Command("compare", "Compare the state snapshots of two peers for the same channel at the same height, and report the keys that differ per namespace. The keys and values of collections are the hashes of the private data.")
+	snapshotPath1 = compare.Arg("snapshotPath1", "The directory of the first state snapshot").Required().ExistingDir()
+	snapshotPath2 = compare.Arg("snapshotPath2", "The directory of the second state snapshot").Required().ExistingDir()
+	maxKeys       = compare.Flag("maxKeys", "The maximum number of differing keys to report, 0 for all").Default("1000").Int()
+	outputFile    = compare.Flag("output", "The file to write the report to, instead of the standard output").Short('o').String()
+
+	version = app.Command("version", "Show version information")
+)
+
+func main() {
+	app.HelpFlag.Short('h')
+
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	case compare.FullCommand():
+		report, err := ledgerutil.Compare(*snapshotPath1, *snapshotPath2, *maxKeys)
+		if err != nil {
+			app.Fatalf("%s", err)
+		}
+		out := os.Stdout
+		if *outputFile != "" {
+			if out, err = os.Create(*outputFile); err != nil {
+				app.Fatalf("Error creating the report file: %s", err)
+			}
+			defer out.Close()
+		}
+		if err := ledgerutil.WriteReport(out, report); err != nil {
+			app.Fatalf("Error writing the report: %s", err)
+		}
+
+	case version.FullCommand():
+		fmt.Printf("%s:\n Version: %s\n Commit SHA: %s\n Go version: %s\n OS/Arch: %s\n",
+			programName, metadata.Version, metadata.CommitSHA, runtime.Version(),
+			fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH))
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package attestation verifies the attestation reports of trusted execution
+// environments, so that a peer, a chaincode or a client can check that a
+// counterparty executes a given program inside a genuine enclave.
+//
+// Intel SGX ECDSA quotes (version 3) and AMD SEV-SNP attestation reports are
+// supported. The signature of a report is verified along with the certificate
+// chain of its signing key, up to the root certificates of the hardware vendor
+// the verifier trusts. The collateral the vendor publishes is used to check
+// that none of the certificates is revoked, and that the TCB of the platform
+// is up to date.
+package attestation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The platforms attestation reports are issued by.
+const (
+	PlatformSGX    = "sgx"
+	PlatformSEVSNP = "sev-snp"
+)
+
+// Evidence is the attestation report of an enclave, along with the certificates
+// needed to verify it.
+type Evidence struct {
+	// Report is an SGX quote or an SEV-SNP attestation report
+	Report []byte
+	// Certificates are the PEM encoded certificates of the key that signed
+	// the report and of its intermediate CAs. SGX quotes usually embed them.
+	Certificates []byte
+	// Collateral is the collateral of the platform that issued the report
+	Collateral *Collateral
+}
+
+// Collateral is the data the hardware vendor publishes to assess whether a
+// platform can be trusted, as served by its certification service.
+type Collateral struct {
+	// CRLs are the PEM or DER encoded revocation lists of the CAs of the
+	// certificate chains: the PCK CA and the root CA for SGX, and the ARK
+	// for SEV-SNP.
+	CRLs [][]byte
+	// TCBInfo is the signed TCB info of the SGX platform, in version 2 of
+	// the JSON format of the Intel Provisioning Certification Service
+	TCBInfo []byte
+	// QEIdentity is the signed identity of the SGX quoting enclave, in
+	// version 2 of the JSON format of the Intel Provisioning Certification
+	// Service
+	QEIdentity []byte
+	// SigningCertificates are the PEM encoded certificates of the key that
+	// signed the TCB info and the QE identity, and of its intermediate CAs.
+	SigningCertificates []byte
+}
+
+// Claims are the verified properties of an enclave.
+type Claims struct {
+	// Platform is the platform that issued the report
+	Platform string
+	// Measurement is the hash of the initial state of the enclave:
+	// MRENCLAVE for SGX, and the launch measurement for SEV-SNP
+	Measurement []byte
+	// Signer is the hash of the key that signed the enclave: MRSIGNER for SGX,
+	// and the ID key digest for SEV-SNP
+	Signer []byte
+	// SVN is the security version of the enclave: ISVSVN for SGX,
+	// and the guest SVN for SEV-SNP
+	SVN uint32
+	// ReportData is the data the enclave bound to the report, usually the
+	// hash of a public key or of a nonce
+	ReportData []byte
+	// Debug is whether the enclave may be debugged, which exposes its memory
+	Debug bool
+}
+
+// Verifier verifies attestation reports of a platform.
+type Verifier interface {
+	// Verify verifies the evidence, and returns the claims of the enclave
+	Verify(evidence *Evidence) (*Claims, error)
+}
+
+// Policy constrains the enclaves that are trusted.
+type Policy struct {
+	// Measurements are the accepted measurements. Empty accepts any measurement.
+	Measurements [][]byte
+	// Signers are the accepted signers. Empty accepts any signer.
+	Signers [][]byte
+	// MinSVN is the lowest accepted security version
+	MinSVN uint32
+	// ReportData, if set, is the data the enclave must have bound to the report.
+	// Report data longer than it must be padded with zeros.
+	ReportData []byte
+	// AllowDebug accepts enclaves that may be debugged
+	AllowDebug bool
+}
+
+// Check returns an error if the claims do not satisfy the policy.
+func (p *Policy) Check(c *Claims) error {
+	if len(p.Measurements) != 0 && !contains(p.Measurements, c.Measurement) {
+		return errors.Errorf("measurement %x is not trusted", c.Measurement)
+	}
+	if len(p.Signers) != 0 && !contains(p.Signers, c.Signer) {
+		return errors.Errorf("signer %x is not trusted", c.Signer)
+	}
+	if c.SVN < p.MinSVN {
+		return errors.Errorf("security version %d is lower than %d", c.SVN, p.MinSVN)
+	}
+	if p.ReportData != nil && !reportDataMatches(c.ReportData, p.ReportData) {
+		return errors.Errorf("report data %x does not match %x", c.ReportData, p.ReportData)
+	}
+	if c.Debug && !p.AllowDebug {
+		return errors.New("debug enclaves are not trusted")
+	}
+	return nil
+}
+
+// Verify verifies the evidence with the given verifier, and checks that its claims
+// satisfy the policy.
+func Verify(v Verifier, evidence *Evidence, p *Policy) (*Claims, error) {
+	claims, err := v.Verify(evidence)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Check(claims); err != nil {
+		return nil, errors.WithMessagef(err, "%s enclave is not trusted", claims.Platform)
+	}
+	return claims, nil
+}
+
+func contains(values [][]byte, value []byte) bool {
+	for _, v := range values {
+		if bytes.Equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func reportDataMatches(reportData, expected []byte) bool {
+	if len(reportData) < len(expected) || !bytes.Equal(reportData[:len(expected)], expected) {
+		return false
+	}
+	for _, b := range reportData[len(expected):] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCertificates parses the PEM encoded certificates
+func parseCertificates(raw []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parsing certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// verifyChain verifies that the first of the certificates is issued by one of the roots,
+// possibly through the others, and returns the chain from it to the root
+func verifyChain(certs []*x509.Certificate, roots *x509.CertPool) ([]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed verifying the certificate of %s", subject(certs[0]))
+	}
+	return chains[0], nil
+}
+
+// parseCRLs parses the PEM or DER encoded revocation lists
+func parseCRLs(raw [][]byte) ([]*pkix.CertificateList, error) {
+	var crls []*pkix.CertificateList
+	for _, r := range raw {
+		crl, err := x509.ParseCRL(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed parsing CRL")
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+// checkRevocation returns an error unless one of the lists is a current revocation
+// list of the issuer that doesn't revoke the certificate
+func checkRevocation(cert, issuer *x509.Certificate, crls []*pkix.CertificateList, now time.Time) error {
+	for _, crl := range crls {
+		if issuer.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		if crl.HasExpired(now) {
+			return errors.Errorf("the CRL of %s expired on %s", subject(issuer), crl.TBSCertList.NextUpdate.UTC().Format(time.RFC3339))
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return errors.Errorf("the certificate of %s is revoked", subject(cert))
+			}
+		}
+		return nil
+	}
+	return errors.Errorf("no CRL of %s", subject(issuer))
+}
+
+// extension returns the extension of the certificate with the given identifier, or nil
+func extension(cert *x509.Certificate, oid asn1.ObjectIdentifier) *pkix.Extension {
+	for i := range cert.Extensions {
+		if cert.Extensions[i].Id.Equal(oid) {
+			return &cert.Extensions[i]
+		}
+	}
+	return nil
+}
+
+func subject(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return fmt.Sprintf("serial %s", cert.SerialNumber)
+}
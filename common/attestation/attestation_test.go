@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority issuing the certificates of test signing keys
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA creates an intermediate CA issued by the parent
+func newTestCA(t *testing.T, name string, parent *testCA) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	cert := issue(t, name, &key.PublicKey, parent, true)
+	return &testCA{cert: cert, key: key}
+}
+
+func issue(t *testing.T, name string, pub *ecdsa.PublicKey, parent *testCA, isCA bool, extensions ...pkix.Extension) *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		ExtraExtensions:       extensions,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent.cert, pub, parent.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func newRootCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// crl returns a revocation list of the CA, revoking the given certificates, which
+// expires after the given duration
+func (ca *testCA) crl(t *testing.T, validity time.Duration, revoked ...*x509.Certificate) []byte {
+	var entries []pkix.RevokedCertificate
+	for _, cert := range revoked {
+		entries = append(entries, pkix.RevokedCertificate{SerialNumber: cert.SerialNumber, RevocationTime: time.Now()})
+	}
+	der, err := ca.cert.CreateCRL(rand.Reader, ca.key, entries, time.Now().Add(-time.Hour), time.Now().Add(validity))
+	require.NoError(t, err)
+	return der
+}
+
+func pemOf(certs ...*x509.Certificate) []byte {
+	var out []byte
+	for _, cert := range certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return out
+}
+
+func TestPolicyCheck(t *testing.T) {
+	claims := &Claims{
+		Platform:    PlatformSGX,
+		Measurement: []byte{1},
+		Signer:      []byte{2},
+		SVN:         3,
+		ReportData:  []byte{4, 5, 0, 0},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		debug    bool
+		expected string
+	}{
+		{name: "any enclave", policy: &Policy{}},
+		{name: "trusted enclave", policy: &Policy{Measurements: [][]byte{{9}, {1}}, Signers: [][]byte{{2}}, MinSVN: 3, ReportData: []byte{4, 5}}},
+		{name: "untrusted measurement", policy: &Policy{Measurements: [][]byte{{9}}}, expected: "measurement 01 is not trusted"},
+		{name: "untrusted signer", policy: &Policy{Signers: [][]byte{{9}}}, expected: "signer 02 is not trusted"},
+		{name: "outdated", policy: &Policy{MinSVN: 4}, expected: "security version 3 is lower than 4"},
+		{name: "other report data", policy: &Policy{ReportData: []byte{4}}, expected: "report data 04050000 does not match 04"},
+		{name: "longer report data", policy: &Policy{ReportData: []byte{4, 5, 0, 0, 0}}, expected: "report data 04050000 does not match 0405000000"},
+		{name: "debug enclave", policy: &Policy{}, debug: true, expected: "debug enclaves are not trusted"},
+		{name: "allowed debug enclave", policy: &Policy{AllowDebug: true}, debug: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := *claims
+			c.Debug = test.debug
+			err := test.policy.Check(&c)
+			if test.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	root := newRootCA(t, "Root CA")
+	ca := newTestCA(t, "CA", root)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := issue(t, "leaf", &key.PublicKey, ca, false)
+	other := issue(t, "other leaf", &key.PublicKey, ca, false)
+
+	parse := func(raw ...[]byte) []*pkix.CertificateList {
+		crls, err := parseCRLs(raw)
+		require.NoError(t, err)
+		return crls
+	}
+	now := time.Now()
+	require.NoError(t, checkRevocation(cert, ca.cert, parse(root.crl(t, time.Hour, ca.cert), ca.crl(t, time.Hour, other)), now))
+	// PEM encoded lists are parsed too
+	pemCRL := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: ca.crl(t, time.Hour)})
+	require.NoError(t, checkRevocation(cert, ca.cert, parse(pemCRL), now))
+
+	err = checkRevocation(cert, ca.cert, parse(ca.crl(t, time.Hour, other, cert)), now)
+	require.EqualError(t, err, "the certificate of leaf is revoked")
+	err = checkRevocation(cert, ca.cert, parse(ca.crl(t, -time.Minute)), now)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the CRL of CA expired on")
+	// the lists of other CAs don't apply
+	err = checkRevocation(cert, ca.cert, parse(root.crl(t, time.Hour)), now)
+	require.EqualError(t, err, "no CRL of CA")
+
+	_, err = parseCRLs([][]byte{[]byte("garbage")})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing CRL")
+}
+
+func TestParseCertificates(t *testing.T) {
+	_, err := parseCertificates([]byte("garbage"))
+	require.EqualError(t, err, "no certificates found")
+
+	_, err = parseCertificates(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing certificate")
+}
@@ -0,0 +1,207 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The layout of an SEV-SNP attestation report
+const (
+	snpReportSize      = 0x4A0
+	snpSignedSize      = 0x2A0
+	snpSignatureOffset = 0x2A0
+	// r and s are little endian, each in a 72 byte field
+	snpSignatureComponentSize = 72
+
+	snpVersionOffset      = 0x00
+	snpGuestSVNOffset     = 0x04
+	snpPolicyOffset       = 0x08
+	snpSignatureAlgOffset = 0x34
+	snpReportDataOffset   = 0x50
+	snpMeasurementOffset  = 0x90
+	snpIDKeyDigestOffset  = 0xE0
+	snpReportedTCBOffset  = 0x180
+	snpChipIDOffset       = 0x1A0
+	snpChipIDSize         = 64
+
+	snpMinVersion        = 2
+	snpSignatureAlgoP384 = 1 // ECDSA P-384 with SHA-384
+
+	snpPolicyDebug = 1 << 19
+)
+
+// The extensions of the VCEK certificates
+var (
+	oidSNPBootloaderSPL = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 1}
+	oidSNPTEESPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 2}
+	oidSNPSPL           = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 3}
+	oidSNPMicrocodeSPL  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 8}
+	oidSNPHardwareID    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+)
+
+// SNPTCB is the TCB version of an SEV-SNP platform: the security patch levels
+// of its firmware components.
+type SNPTCB struct {
+	Bootloader uint8
+	TEE        uint8
+	SNP        uint8
+	Microcode  uint8
+}
+
+// atLeast returns whether each component is at least at the level of the other TCB
+func (t SNPTCB) atLeast(other SNPTCB) bool {
+	return t.Bootloader >= other.Bootloader && t.TEE >= other.TEE && t.SNP >= other.SNP && t.Microcode >= other.Microcode
+}
+
+// parseSNPTCB parses a TCB version, which holds the bootloader, TEE, SNP and
+// microcode SPLs in its bytes 0, 1, 6 and 7
+func parseSNPTCB(b []byte) SNPTCB {
+	return SNPTCB{Bootloader: b[0], TEE: b[1], SNP: b[6], Microcode: b[7]}
+}
+
+// SEVSNPVerifier verifies AMD SEV-SNP attestation reports.
+type SEVSNPVerifier struct {
+	roots  *x509.CertPool
+	minTCB SNPTCB
+}
+
+// NewSEVSNPVerifier creates a verifier of SEV-SNP reports whose VCEK certificates
+// are issued, through an ASK, by one of the given roots, such as the AMD Root Key
+// of the processor family. The reported TCB of the platforms must be at least the
+// given TCB, which is usually the latest the platforms were updated to.
+func NewSEVSNPVerifier(roots *x509.CertPool, minTCB SNPTCB) *SEVSNPVerifier {
+	return &SEVSNPVerifier{roots: roots, minTCB: minTCB}
+}
+
+// Verify verifies that the report is signed by the key of the first of the
+// certificates of the evidence, which is issued by one of the roots of the
+// verifier and certifies the chip and the TCB of the report, checks that the
+// ASK is not revoked and that the TCB is recent enough, and returns the claims
+// of the guest.
+func (v *SEVSNPVerifier) Verify(evidence *Evidence) (*Claims, error) {
+	report := evidence.Report
+	if len(report) != snpReportSize {
+		return nil, errors.Errorf("invalid SEV-SNP report: the report is %d bytes, expected %d", len(report), snpReportSize)
+	}
+	if version := binary.LittleEndian.Uint32(report[snpVersionOffset:]); version < snpMinVersion {
+		return nil, errors.Errorf("invalid SEV-SNP report: unsupported version %d", version)
+	}
+	if algo := binary.LittleEndian.Uint32(report[snpSignatureAlgOffset:]); algo != snpSignatureAlgoP384 {
+		return nil, errors.Errorf("invalid SEV-SNP report: unsupported signature algorithm %d", algo)
+	}
+
+	chain, err := parseCertificates(evidence.Certificates)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid VCEK certificate chain")
+	}
+	chain, err = verifyChain(chain, v.roots)
+	if err != nil {
+		return nil, err
+	}
+	vcek := chain[0]
+	key, ok := vcek.PublicKey.(*ecdsa.PublicKey)
+	if !ok || key.Curve != elliptic.P384() {
+		return nil, errors.New("the VCEK certificate does not hold a P-384 key")
+	}
+
+	digest := sha512.Sum384(report[:snpSignedSize])
+	r := littleEndianInt(report[snpSignatureOffset : snpSignatureOffset+snpSignatureComponentSize])
+	s := littleEndianInt(report[snpSignatureOffset+snpSignatureComponentSize : snpSignatureOffset+2*snpSignatureComponentSize])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return nil, errors.New("the signature of the SEV-SNP report is invalid")
+	}
+
+	if evidence.Collateral == nil {
+		return nil, errors.New("the evidence holds no collateral")
+	}
+	crls, err := parseCRLs(evidence.Collateral.CRLs)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid SEV-SNP collateral")
+	}
+	// AMD revokes ASKs, but does not publish revocation lists of VCEKs
+	now := time.Now()
+	for i := 1; i < len(chain)-1; i++ {
+		if err := checkRevocation(chain[i], chain[i+1], crls, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := v.checkTCB(vcek, report); err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		Platform:    PlatformSEVSNP,
+		Measurement: clone(report[snpMeasurementOffset : snpMeasurementOffset+48]),
+		Signer:      clone(report[snpIDKeyDigestOffset : snpIDKeyDigestOffset+48]),
+		SVN:         binary.LittleEndian.Uint32(report[snpGuestSVNOffset:]),
+		ReportData:  clone(report[snpReportDataOffset : snpReportDataOffset+64]),
+		Debug:       binary.LittleEndian.Uint64(report[snpPolicyOffset:])&snpPolicyDebug != 0,
+	}, nil
+}
+
+// checkTCB checks that the VCEK certifies the chip and the TCB of the report, and
+// that the TCB is at least the minimum TCB of the verifier
+func (v *SEVSNPVerifier) checkTCB(vcek *x509.Certificate, report []byte) error {
+	var vcekTCB SNPTCB
+	components := []struct {
+		oid   asn1.ObjectIdentifier
+		value *uint8
+	}{
+		{oidSNPBootloaderSPL, &vcekTCB.Bootloader},
+		{oidSNPTEESPL, &vcekTCB.TEE},
+		{oidSNPSPL, &vcekTCB.SNP},
+		{oidSNPMicrocodeSPL, &vcekTCB.Microcode},
+	}
+	for _, c := range components {
+		ext := extension(vcek, c.oid)
+		if ext == nil {
+			return errors.Errorf("invalid VCEK certificate: missing extension %s", c.oid)
+		}
+		var spl int
+		if _, err := asn1.Unmarshal(ext.Value, &spl); err != nil || spl < 0 || spl > 0xFF {
+			return errors.Errorf("invalid VCEK certificate: malformed extension %s", c.oid)
+		}
+		*c.value = uint8(spl)
+	}
+
+	reportedTCB := parseSNPTCB(report[snpReportedTCBOffset:])
+	if vcekTCB != reportedTCB {
+		return errors.Errorf("the TCB %+v of the VCEK does not match the reported TCB %+v", vcekTCB, reportedTCB)
+	}
+	if ext := extension(vcek, oidSNPHardwareID); ext != nil {
+		var hwID []byte
+		if _, err := asn1.Unmarshal(ext.Value, &hwID); err != nil {
+			return errors.New("invalid VCEK certificate: malformed hardware ID")
+		}
+		if chipID := report[snpChipIDOffset : snpChipIDOffset+snpChipIDSize]; !bytes.Equal(hwID, chipID) {
+			return errors.Errorf("the VCEK is not the one of chip %x", chipID)
+		}
+	}
+	if !reportedTCB.atLeast(v.minTCB) {
+		return errors.Errorf("the reported TCB %+v is older than %+v", reportedTCB, v.minTCB)
+	}
+	return nil
+}
+
+func littleEndianInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
@@ -0,0 +1,308 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// snpReportBuilder builds SEV-SNP reports signed by a test VCEK, and their collateral
+type snpReportBuilder struct {
+	t     *testing.T
+	ark   *testCA
+	ask   *testCA
+	chain []byte
+	vcek  *ecdsa.PrivateKey
+
+	guestSVN    uint32
+	policy      uint64
+	measurement []byte
+	idKeyDigest []byte
+	reportData  []byte
+	reportedTCB SNPTCB
+	chipID      []byte
+
+	// the collateral
+	revoked     []*x509.Certificate
+	crlValidity time.Duration
+}
+
+var (
+	snpTCB    = SNPTCB{Bootloader: 3, TEE: 0, SNP: 8, Microcode: 115}
+	snpChipID = bytes.Repeat([]byte{0xD}, snpChipIDSize)
+)
+
+func newSNPReportBuilder(t *testing.T) *snpReportBuilder {
+	ark := newRootCA(t, "ARK-Milan")
+	ask := newTestCA(t, "SEV-Milan", ark)
+	vcekKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	vcek := issue(t, "SEV-VCEK", &vcekKey.PublicKey, ask, false, vcekExtensionsOf(t, snpTCB, snpChipID)...)
+
+	return &snpReportBuilder{
+		t:           t,
+		ark:         ark,
+		ask:         ask,
+		chain:       pemOf(vcek, ask.cert),
+		vcek:        vcekKey,
+		guestSVN:    2,
+		policy:      0x30000,
+		measurement: bytes.Repeat([]byte{0xA}, 48),
+		idKeyDigest: bytes.Repeat([]byte{0xB}, 48),
+		reportData:  bytes.Repeat([]byte{0xC}, 64),
+		reportedTCB: snpTCB,
+		chipID:      snpChipID,
+		crlValidity: time.Hour,
+	}
+}
+
+// vcekExtensionsOf returns the extensions of a VCEK certifying the given TCB and chip
+func vcekExtensionsOf(t *testing.T, tcb SNPTCB, chipID []byte) []pkix.Extension {
+	extension := func(oid asn1.ObjectIdentifier, value interface{}) pkix.Extension {
+		der, err := asn1.Marshal(value)
+		require.NoError(t, err)
+		return pkix.Extension{Id: oid, Value: der}
+	}
+	return []pkix.Extension{
+		extension(oidSNPBootloaderSPL, int(tcb.Bootloader)),
+		extension(oidSNPTEESPL, int(tcb.TEE)),
+		extension(oidSNPSPL, int(tcb.SNP)),
+		extension(oidSNPMicrocodeSPL, int(tcb.Microcode)),
+		extension(oidSNPHardwareID, chipID),
+	}
+}
+
+func (b *snpReportBuilder) evidence() *Evidence {
+	return &Evidence{
+		Report:       b.build(),
+		Certificates: b.chain,
+		Collateral:   &Collateral{CRLs: [][]byte{b.ark.crl(b.t, b.crlValidity, b.revoked...)}},
+	}
+}
+
+func (b *snpReportBuilder) build() []byte {
+	report := make([]byte, snpReportSize)
+	binary.LittleEndian.PutUint32(report[snpVersionOffset:], 2)
+	binary.LittleEndian.PutUint32(report[snpGuestSVNOffset:], b.guestSVN)
+	binary.LittleEndian.PutUint64(report[snpPolicyOffset:], b.policy)
+	binary.LittleEndian.PutUint32(report[snpSignatureAlgOffset:], snpSignatureAlgoP384)
+	copy(report[snpReportDataOffset:], b.reportData)
+	copy(report[snpMeasurementOffset:], b.measurement)
+	copy(report[snpIDKeyDigestOffset:], b.idKeyDigest)
+	tcb := report[snpReportedTCBOffset:]
+	tcb[0], tcb[1], tcb[6], tcb[7] = b.reportedTCB.Bootloader, b.reportedTCB.TEE, b.reportedTCB.SNP, b.reportedTCB.Microcode
+	copy(report[snpChipIDOffset:], b.chipID)
+
+	digest := sha512.Sum384(report[:snpSignedSize])
+	r, s, err := ecdsa.Sign(rand.Reader, b.vcek, digest[:])
+	require.NoError(b.t, err)
+	copy(report[snpSignatureOffset:], reversed(padded(r, snpSignatureComponentSize)))
+	copy(report[snpSignatureOffset+snpSignatureComponentSize:], reversed(padded(s, snpSignatureComponentSize)))
+	return report
+}
+
+func reversed(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+func TestSEVSNPVerify(t *testing.T) {
+	b := newSNPReportBuilder(t)
+	v := NewSEVSNPVerifier(b.ark.pool(), snpTCB)
+
+	claims, err := v.Verify(b.evidence())
+	require.NoError(t, err)
+	require.Equal(t, &Claims{
+		Platform:    PlatformSEVSNP,
+		Measurement: b.measurement,
+		Signer:      b.idKeyDigest,
+		SVN:         2,
+		ReportData:  b.reportData,
+	}, claims)
+
+	b.policy |= snpPolicyDebug
+	claims, err = v.Verify(b.evidence())
+	require.NoError(t, err)
+	require.True(t, claims.Debug)
+
+	claims, err = Verify(v, b.evidence(), &Policy{AllowDebug: true, MinSVN: 2})
+	require.NoError(t, err)
+	require.Equal(t, b.measurement, claims.Measurement)
+
+	_, err = Verify(v, b.evidence(), &Policy{AllowDebug: true, MinSVN: 3})
+	require.EqualError(t, err, "sev-snp enclave is not trusted: security version 2 is lower than 3")
+}
+
+func TestSEVSNPVerifyFailures(t *testing.T) {
+	b := newSNPReportBuilder(t)
+	v := NewSEVSNPVerifier(b.ark.pool(), snpTCB)
+	report := b.build()
+	collateral := b.evidence().Collateral
+
+	tamper := func(offset int, value byte) []byte {
+		tampered := append([]byte{}, report...)
+		tampered[offset] = value
+		return tampered
+	}
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ask := newTestCA(t, "SEV-Milan", b.ark)
+	p256Chain := pemOf(issue(t, "SEV-VCEK", &p256Key.PublicKey, ask, false), ask.cert)
+
+	tests := []struct {
+		name         string
+		report       []byte
+		certificates []byte
+		roots        *testCA
+		expected     string
+	}{
+		{
+			name:     "truncated",
+			report:   report[:snpSignedSize],
+			expected: "invalid SEV-SNP report: the report is 672 bytes, expected 1184",
+		},
+		{
+			name:     "unsupported version",
+			report:   tamper(snpVersionOffset, 1),
+			expected: "invalid SEV-SNP report: unsupported version 1",
+		},
+		{
+			name:     "unsupported signature algorithm",
+			report:   tamper(snpSignatureAlgOffset, 2),
+			expected: "invalid SEV-SNP report: unsupported signature algorithm 2",
+		},
+		{
+			name:     "tampered report",
+			report:   tamper(snpMeasurementOffset, 0),
+			expected: "the signature of the SEV-SNP report is invalid",
+		},
+		{
+			name:         "missing certificates",
+			report:       report,
+			certificates: []byte{},
+			expected:     "invalid VCEK certificate chain: no certificates found",
+		},
+		{
+			name:     "untrusted VCEK",
+			report:   report,
+			roots:    newRootCA(t, "ARK-Genoa"),
+			expected: "failed verifying the certificate of SEV-VCEK: x509: certificate signed by unknown authority",
+		},
+		{
+			name:         "VCEK of another curve",
+			report:       report,
+			certificates: p256Chain,
+			expected:     "the VCEK certificate does not hold a P-384 key",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := v
+			if test.roots != nil {
+				verifier = NewSEVSNPVerifier(test.roots.pool(), snpTCB)
+			}
+			certificates := b.chain
+			if test.certificates != nil {
+				certificates = test.certificates
+			}
+			_, err := verifier.Verify(&Evidence{Report: test.report, Certificates: certificates, Collateral: collateral})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
+
+func TestSEVSNPVerifyCollateral(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  func(b *snpReportBuilder)
+		evidence func(e *Evidence)
+		minTCB   SNPTCB
+		expected string
+	}{
+		{
+			name:   "older TCB than the minimum",
+			minTCB: SNPTCB{Bootloader: 2, SNP: 7, Microcode: 100},
+		},
+		{
+			name:     "no collateral",
+			evidence: func(e *Evidence) { e.Collateral = nil },
+			expected: "the evidence holds no collateral",
+		},
+		{
+			name:     "revoked ASK",
+			builder:  func(b *snpReportBuilder) { b.revoked = []*x509.Certificate{b.ask.cert} },
+			expected: "the certificate of SEV-Milan is revoked",
+		},
+		{
+			name:     "expired CRL",
+			builder:  func(b *snpReportBuilder) { b.crlValidity = -time.Minute },
+			expected: "the CRL of ARK-Milan expired on",
+		},
+		{
+			name:     "missing CRL",
+			evidence: func(e *Evidence) { e.Collateral.CRLs = nil },
+			expected: "no CRL of ARK-Milan",
+		},
+		{
+			name:     "out of date TCB",
+			minTCB:   SNPTCB{Bootloader: 3, SNP: 8, Microcode: 209},
+			expected: "the reported TCB {Bootloader:3 TEE:0 SNP:8 Microcode:115} is older than {Bootloader:3 TEE:0 SNP:8 Microcode:209}",
+		},
+		{
+			name:     "TCB other than the VCEK's",
+			builder:  func(b *snpReportBuilder) { b.reportedTCB.Microcode = 209 },
+			expected: "the TCB {Bootloader:3 TEE:0 SNP:8 Microcode:115} of the VCEK does not match the reported TCB {Bootloader:3 TEE:0 SNP:8 Microcode:209}",
+		},
+		{
+			name:     "other chip",
+			builder:  func(b *snpReportBuilder) { b.chipID = bytes.Repeat([]byte{0xE}, snpChipIDSize) },
+			expected: "the VCEK is not the one of chip 0e0e0e",
+		},
+		{
+			name: "VCEK without TCB",
+			builder: func(b *snpReportBuilder) {
+				b.chain = pemOf(issue(t, "SEV-VCEK", &b.vcek.PublicKey, b.ask, false), b.ask.cert)
+			},
+			expected: "invalid VCEK certificate: missing extension 1.3.6.1.4.1.3704.1.3.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newSNPReportBuilder(t)
+			if test.builder != nil {
+				test.builder(b)
+			}
+			e := b.evidence()
+			if test.evidence != nil {
+				test.evidence(e)
+			}
+			_, err := NewSEVSNPVerifier(b.ark.pool(), test.minTCB).Verify(e)
+			if test.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
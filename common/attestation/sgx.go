@@ -0,0 +1,221 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// The layout of an SGX ECDSA quote of version 3
+const (
+	sgxQuoteVersion       = 3
+	sgxAttestationKeyType = 2 // ECDSA-256-with-P-256
+
+	sgxHeaderSize     = 48
+	sgxReportBodySize = 384
+	sgxSignatureSize  = 64
+	sgxPublicKeySize  = 64
+
+	// certification data holding the PEM encoded PCK certificate chain
+	sgxCertDataPCKChain = 5
+)
+
+// The layout of an SGX report body
+const (
+	sgxAttributesOffset = 48
+	sgxMREnclaveOffset  = 64
+	sgxMRSignerOffset   = 128
+	sgxISVSVNOffset     = 258
+	sgxReportDataOffset = 320
+
+	sgxDebugFlag = 0x02
+)
+
+// SGXVerifier verifies SGX ECDSA quotes (version 3) produced with the
+// Data Center Attestation Primitives.
+type SGXVerifier struct {
+	roots            *x509.CertPool
+	acceptedStatuses []string
+}
+
+// NewSGXVerifier creates a verifier of SGX quotes whose PCK certificates
+// are issued by one of the given roots, such as the Intel SGX Root CA.
+// Platforms and quoting enclaves whose TCB is up to date are trusted, as are
+// those whose TCB has one of the given statuses, such as SWHardeningNeeded.
+func NewSGXVerifier(roots *x509.CertPool, acceptedStatuses ...string) *SGXVerifier {
+	return &SGXVerifier{roots: roots, acceptedStatuses: acceptedStatuses}
+}
+
+type sgxQuote struct {
+	signedData     []byte // header and ISV enclave report body
+	reportBody     []byte
+	signature      []byte
+	attestationKey []byte
+	qeReportBody   []byte
+	qeSignature    []byte
+	qeAuthData     []byte
+	certData       []byte
+	certDataType   uint16
+}
+
+// Verify verifies that the quote is signed by an attestation key certified by a
+// quoting enclave whose report is signed by a PCK certificate issued by one of the
+// roots of the verifier, checks the PCK certificate and the quoting enclave against
+// the collateral of the evidence, and returns the claims of the enclave.
+func (v *SGXVerifier) Verify(evidence *Evidence) (*Claims, error) {
+	q, err := parseSGXQuote(evidence.Report)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid SGX quote")
+	}
+
+	certs := evidence.Certificates
+	if q.certDataType == sgxCertDataPCKChain {
+		certs = q.certData
+	}
+	chain, err := parseCertificates(certs)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid PCK certificate chain")
+	}
+	chain, err = verifyChain(chain, v.roots)
+	if err != nil {
+		return nil, err
+	}
+	pck := chain[0]
+	pckKey, ok := pck.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("the PCK certificate does not hold an ECDSA key")
+	}
+
+	if !verifyRawECDSA(pckKey, q.qeReportBody, q.qeSignature) {
+		return nil, errors.New("the signature of the quoting enclave report is invalid")
+	}
+	// the quoting enclave binds the attestation key to its report
+	keyHash := sha256.Sum256(append(append([]byte{}, q.attestationKey...), q.qeAuthData...))
+	qeReportData := q.qeReportBody[sgxReportDataOffset : sgxReportDataOffset+64]
+	if !bytes.Equal(qeReportData[:32], keyHash[:]) || !bytes.Equal(qeReportData[32:], make([]byte, 32)) {
+		return nil, errors.New("the attestation key is not certified by the quoting enclave")
+	}
+
+	attestationKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(q.attestationKey[:32]),
+		Y:     new(big.Int).SetBytes(q.attestationKey[32:]),
+	}
+	if !attestationKey.Curve.IsOnCurve(attestationKey.X, attestationKey.Y) {
+		return nil, errors.New("the attestation key is not a P-256 point")
+	}
+	if !verifyRawECDSA(attestationKey, q.signedData, q.signature) {
+		return nil, errors.New("the signature of the quote is invalid")
+	}
+
+	if evidence.Collateral == nil {
+		return nil, errors.New("the evidence holds no collateral")
+	}
+	if err := v.checkCollateral(chain, q.qeReportBody, evidence.Collateral); err != nil {
+		return nil, err
+	}
+
+	body := q.reportBody
+	return &Claims{
+		Platform:    PlatformSGX,
+		Measurement: clone(body[sgxMREnclaveOffset : sgxMREnclaveOffset+32]),
+		Signer:      clone(body[sgxMRSignerOffset : sgxMRSignerOffset+32]),
+		SVN:         uint32(binary.LittleEndian.Uint16(body[sgxISVSVNOffset:])),
+		ReportData:  clone(body[sgxReportDataOffset : sgxReportDataOffset+64]),
+		Debug:       body[sgxAttributesOffset]&sgxDebugFlag != 0,
+	}, nil
+}
+
+func parseSGXQuote(raw []byte) (*sgxQuote, error) {
+	r := &reader{data: raw}
+	header := r.next(sgxHeaderSize)
+	reportBody := r.next(sgxReportBodySize)
+	sigDataLen := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version := binary.LittleEndian.Uint16(header[0:]); version != sgxQuoteVersion {
+		return nil, errors.Errorf("unsupported version %d, expected %d", version, sgxQuoteVersion)
+	}
+	if keyType := binary.LittleEndian.Uint16(header[2:]); keyType != sgxAttestationKeyType {
+		return nil, errors.Errorf("unsupported attestation key type %d, expected %d", keyType, sgxAttestationKeyType)
+	}
+	if int(sigDataLen) != len(raw)-r.offset {
+		return nil, errors.Errorf("signature data is %d bytes, expected %d", len(raw)-r.offset, sigDataLen)
+	}
+
+	q := &sgxQuote{
+		signedData:     raw[:sgxHeaderSize+sgxReportBodySize],
+		reportBody:     reportBody,
+		signature:      r.next(sgxSignatureSize),
+		attestationKey: r.next(sgxPublicKeySize),
+		qeReportBody:   r.next(sgxReportBodySize),
+		qeSignature:    r.next(sgxSignatureSize),
+	}
+	q.qeAuthData = r.next(int(r.uint16()))
+	q.certDataType = r.uint16()
+	q.certData = r.next(int(r.uint32()))
+	if r.err != nil {
+		return nil, r.err
+	}
+	return q, nil
+}
+
+// verifyRawECDSA verifies a signature encoded as the big endian r and s, each of the size of the curve
+func verifyRawECDSA(key *ecdsa.PublicKey, msg, signature []byte) bool {
+	digest := sha256.Sum256(msg)
+	half := len(signature) / 2
+	r := new(big.Int).SetBytes(signature[:half])
+	s := new(big.Int).SetBytes(signature[half:])
+	return ecdsa.Verify(key, digest[:], r, s)
+}
+
+func clone(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+// reader reads consecutive fields of a binary structure
+type reader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.offset+n > len(r.data) {
+		r.err = errors.Errorf("truncated at offset %d, expected %d more bytes", r.offset, n)
+		return nil
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
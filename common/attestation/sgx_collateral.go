@@ -0,0 +1,346 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The TCB statuses of the TCB info and of the QE identity
+const (
+	SGXTCBUpToDate            = "UpToDate"
+	SGXTCBSWHardeningNeeded   = "SWHardeningNeeded"
+	SGXTCBConfigurationNeeded = "ConfigurationNeeded"
+	SGXTCBOutOfDate           = "OutOfDate"
+	SGXTCBRevoked             = "Revoked"
+)
+
+// The layout of the report body of the quoting enclave, beyond the fields of
+// the enclave reports
+const (
+	sgxMiscSelectOffset = 16
+	sgxAttributesSize   = 16
+	sgxISVProdIDOffset  = 256
+)
+
+// The SGX extensions of the PCK certificates
+var (
+	oidSGXExtensions = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	oidSGXTCB        = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2}
+	oidSGXPCESVN     = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2, 17}
+	oidSGXPCEID      = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 3}
+	oidSGXFMSPC      = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
+)
+
+type sgxExtension struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// sgxPlatformTCB is the TCB of the platform of a PCK certificate
+type sgxPlatformTCB struct {
+	compSVN [16]int
+	pceSVN  int
+	pceID   []byte
+	fmspc   []byte
+}
+
+type sgxTCBInfo struct {
+	Version    int           `json:"version"`
+	NextUpdate time.Time     `json:"nextUpdate"`
+	FMSPC      string        `json:"fmspc"`
+	PCEID      string        `json:"pceId"`
+	TCBLevels  []sgxTCBLevel `json:"tcbLevels"`
+}
+
+type sgxTCBLevel struct {
+	TCB    map[string]int `json:"tcb"`
+	Status string         `json:"tcbStatus"`
+}
+
+type sgxEnclaveIdentity struct {
+	ID             string          `json:"id"`
+	Version        int             `json:"version"`
+	NextUpdate     time.Time       `json:"nextUpdate"`
+	MiscSelect     hexBytes        `json:"miscselect"`
+	MiscSelectMask hexBytes        `json:"miscselectMask"`
+	Attributes     hexBytes        `json:"attributes"`
+	AttributesMask hexBytes        `json:"attributesMask"`
+	MRSigner       hexBytes        `json:"mrsigner"`
+	ISVProdID      uint16          `json:"isvprodid"`
+	TCBLevels      []sgxQETCBLevel `json:"tcbLevels"`
+}
+
+type sgxQETCBLevel struct {
+	TCB struct {
+		ISVSVN uint16 `json:"isvsvn"`
+	} `json:"tcb"`
+	Status string `json:"tcbStatus"`
+}
+
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
+// checkCollateral checks that none of the certificates of the PCK chain is revoked,
+// and that the TCB of the platform and the identity of the quoting enclave match
+// the TCB info and the QE identity the vendor published, with an accepted status.
+func (v *SGXVerifier) checkCollateral(chain []*x509.Certificate, qeReportBody []byte, collateral *Collateral) error {
+	now := time.Now()
+	crls, err := parseCRLs(collateral.CRLs)
+	if err != nil {
+		return errors.WithMessage(err, "invalid SGX collateral")
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		if err := checkRevocation(chain[i], chain[i+1], crls, now); err != nil {
+			return err
+		}
+	}
+
+	signingCerts, err := parseCertificates(collateral.SigningCertificates)
+	if err != nil {
+		return errors.WithMessage(err, "invalid TCB signing certificate chain")
+	}
+	signingChain, err := verifyChain(signingCerts, v.roots)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(signingChain)-1; i++ {
+		if err := checkRevocation(signingChain[i], signingChain[i+1], crls, now); err != nil {
+			return err
+		}
+	}
+	signingKey, ok := signingChain[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok || signingKey.Curve != elliptic.P256() {
+		return errors.New("the TCB signing certificate does not hold a P-256 key")
+	}
+
+	tcb, err := parseSGXPlatformTCB(chain[0])
+	if err != nil {
+		return errors.WithMessage(err, "invalid PCK certificate")
+	}
+	if err := v.checkTCBInfo(collateral.TCBInfo, signingKey, tcb, now); err != nil {
+		return err
+	}
+	return v.checkQEIdentity(collateral.QEIdentity, signingKey, qeReportBody, now)
+}
+
+func (v *SGXVerifier) checkTCBInfo(raw []byte, key *ecdsa.PublicKey, tcb *sgxPlatformTCB, now time.Time) error {
+	info := &sgxTCBInfo{}
+	if err := verifySignedJSON(raw, "tcbInfo", key, info); err != nil {
+		return errors.WithMessage(err, "invalid TCB info")
+	}
+	if info.Version != 2 {
+		return errors.Errorf("invalid TCB info: unsupported version %d, expected 2", info.Version)
+	}
+	if now.After(info.NextUpdate) {
+		return errors.Errorf("the TCB info expired on %s", info.NextUpdate.UTC().Format(time.RFC3339))
+	}
+	if !hexEqual(info.FMSPC, tcb.fmspc) || !hexEqual(info.PCEID, tcb.pceID) {
+		return errors.Errorf("the TCB info of FMSPC %s and PCE %s does not apply to the platform of FMSPC %x and PCE %x", info.FMSPC, info.PCEID, tcb.fmspc, tcb.pceID)
+	}
+
+	for _, level := range info.TCBLevels {
+		if !tcb.atLeast(level.TCB) {
+			continue
+		}
+		if !v.accepts(level.Status) {
+			return errors.Errorf("the TCB of the platform is %s", level.Status)
+		}
+		return nil
+	}
+	return errors.New("the TCB of the platform is lower than all the TCB levels of the TCB info")
+}
+
+func (v *SGXVerifier) checkQEIdentity(raw []byte, key *ecdsa.PublicKey, qeReportBody []byte, now time.Time) error {
+	identity := &sgxEnclaveIdentity{}
+	if err := verifySignedJSON(raw, "enclaveIdentity", key, identity); err != nil {
+		return errors.WithMessage(err, "invalid QE identity")
+	}
+	if identity.ID != "QE" || identity.Version != 2 {
+		return errors.Errorf("invalid QE identity: unsupported identity %s of version %d, expected QE of version 2", identity.ID, identity.Version)
+	}
+	if now.After(identity.NextUpdate) {
+		return errors.Errorf("the QE identity expired on %s", identity.NextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	miscSelect := qeReportBody[sgxMiscSelectOffset : sgxMiscSelectOffset+4]
+	attributes := qeReportBody[sgxAttributesOffset : sgxAttributesOffset+sgxAttributesSize]
+	switch {
+	case !maskedEqual(miscSelect, identity.MiscSelectMask, identity.MiscSelect):
+		return errors.Errorf("the MISCSELECT %x of the quoting enclave does not match its identity", miscSelect)
+	case !maskedEqual(attributes, identity.AttributesMask, identity.Attributes):
+		return errors.Errorf("the attributes %x of the quoting enclave do not match its identity", attributes)
+	case !bytes.Equal(qeReportBody[sgxMRSignerOffset:sgxMRSignerOffset+32], identity.MRSigner):
+		return errors.Errorf("the MRSIGNER %x of the quoting enclave does not match its identity", qeReportBody[sgxMRSignerOffset:sgxMRSignerOffset+32])
+	}
+	if prodID := binary.LittleEndian.Uint16(qeReportBody[sgxISVProdIDOffset:]); prodID != identity.ISVProdID {
+		return errors.Errorf("the product ID %d of the quoting enclave does not match its identity", prodID)
+	}
+
+	isvSVN := binary.LittleEndian.Uint16(qeReportBody[sgxISVSVNOffset:])
+	for _, level := range identity.TCBLevels {
+		if isvSVN < level.TCB.ISVSVN {
+			continue
+		}
+		if !v.accepts(level.Status) {
+			return errors.Errorf("the TCB of the quoting enclave is %s", level.Status)
+		}
+		return nil
+	}
+	return errors.Errorf("the security version %d of the quoting enclave is lower than all the TCB levels of its identity", isvSVN)
+}
+
+func (v *SGXVerifier) accepts(status string) bool {
+	if status == SGXTCBUpToDate {
+		return true
+	}
+	for _, s := range v.acceptedStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignedJSON verifies the signature of the given field of the JSON document, which
+// covers the bytes of the field as they appear in the document, and unmarshals the field
+func verifySignedJSON(raw []byte, field string, key *ecdsa.PublicKey, v interface{}) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return errors.Wrap(err, "failed parsing JSON")
+	}
+	body, ok := doc[field]
+	if !ok {
+		return errors.Errorf("missing %s", field)
+	}
+	var signature string
+	if err := json.Unmarshal(doc["signature"], &signature); err != nil {
+		return errors.Wrap(err, "failed parsing signature")
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != sgxSignatureSize {
+		return errors.New("malformed signature")
+	}
+	if !verifyRawECDSA(key, body, sig) {
+		return errors.New("the signature is invalid")
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.Wrapf(err, "failed parsing %s", field)
+	}
+	return nil
+}
+
+// parseSGXPlatformTCB returns the TCB of the platform held by the SGX extensions of the PCK certificate
+func parseSGXPlatformTCB(pck *x509.Certificate) (*sgxPlatformTCB, error) {
+	ext := extension(pck, oidSGXExtensions)
+	if ext == nil {
+		return nil, errors.New("missing SGX extensions")
+	}
+	var extensions []sgxExtension
+	if _, err := asn1.Unmarshal(ext.Value, &extensions); err != nil {
+		return nil, errors.Wrap(err, "failed parsing SGX extensions")
+	}
+
+	tcb := &sgxPlatformTCB{}
+	found := map[string]bool{}
+	for _, ext := range extensions {
+		var err error
+		switch {
+		case ext.ID.Equal(oidSGXTCB):
+			err = tcb.parseComponents(ext.Value.FullBytes)
+		case ext.ID.Equal(oidSGXPCEID):
+			_, err = asn1.Unmarshal(ext.Value.FullBytes, &tcb.pceID)
+		case ext.ID.Equal(oidSGXFMSPC):
+			_, err = asn1.Unmarshal(ext.Value.FullBytes, &tcb.fmspc)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing SGX extension %s", ext.ID)
+		}
+		found[ext.ID.String()] = true
+	}
+	for _, oid := range []asn1.ObjectIdentifier{oidSGXTCB, oidSGXPCEID, oidSGXFMSPC} {
+		if !found[oid.String()] {
+			return nil, errors.Errorf("missing SGX extension %s", oid)
+		}
+	}
+	return tcb, nil
+}
+
+func (tcb *sgxPlatformTCB) parseComponents(raw []byte) error {
+	var components []sgxExtension
+	if _, err := asn1.Unmarshal(raw, &components); err != nil {
+		return err
+	}
+	for _, c := range components {
+		var svn int
+		switch {
+		case c.ID.Equal(oidSGXPCESVN):
+			if _, err := asn1.Unmarshal(c.Value.FullBytes, &svn); err != nil {
+				return err
+			}
+			tcb.pceSVN = svn
+		case len(c.ID) == len(oidSGXTCB)+1 && c.ID[:len(oidSGXTCB)].Equal(oidSGXTCB) && c.ID[len(oidSGXTCB)] >= 1 && c.ID[len(oidSGXTCB)] <= 16:
+			if _, err := asn1.Unmarshal(c.Value.FullBytes, &svn); err != nil {
+				return err
+			}
+			tcb.compSVN[c.ID[len(oidSGXTCB)]-1] = svn
+		}
+	}
+	return nil
+}
+
+// atLeast returns whether the TCB of the platform is at least the given TCB level
+func (tcb *sgxPlatformTCB) atLeast(level map[string]int) bool {
+	for i, svn := range tcb.compSVN {
+		if svn < level[fmt.Sprintf("sgxtcbcomp%02dsvn", i+1)] {
+			return false
+		}
+	}
+	return tcb.pceSVN >= level["pcesvn"]
+}
+
+func hexEqual(s string, b []byte) bool {
+	decoded, err := hex.DecodeString(s)
+	return err == nil && bytes.Equal(decoded, b)
+}
+
+func maskedEqual(value, mask, expected []byte) bool {
+	if len(mask) != len(value) || len(expected) != len(value) {
+		return false
+	}
+	for i := range value {
+		if value[i]&mask[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
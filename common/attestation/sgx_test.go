@@ -0,0 +1,528 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sgxQuoteBuilder builds SGX quotes signed by a test PCK key, and their collateral
+type sgxQuoteBuilder struct {
+	t          *testing.T
+	root       *testCA
+	platformCA *testCA
+	pck        *x509.Certificate
+	chain      []byte
+	pckKey     *ecdsa.PrivateKey
+	attKey     *ecdsa.PrivateKey
+	authData   []byte
+	tcbSigning *testCA
+
+	mrEnclave  []byte
+	mrSigner   []byte
+	isvSVN     uint16
+	reportData []byte
+	debug      bool
+
+	qeMRSigner []byte
+	qeProdID   uint16
+	qeISVSVN   uint16
+
+	// the collateral
+	revoked          []*x509.Certificate
+	crlValidity      time.Duration
+	fmspc            []byte
+	tcbLevels        []map[string]interface{}
+	tcbNextUpdate    time.Time
+	identityMRSigner []byte
+	identityProdID   uint16
+	qeLevels         []map[string]interface{}
+	qeNextUpdate     time.Time
+}
+
+var (
+	sgxFMSPC   = []byte{0x00, 0x90, 0x6E, 0xA1, 0x00, 0x00}
+	sgxPCEID   = []byte{0x00, 0x00}
+	sgxCompSVN = []int{2, 2, 2, 2, 255, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	sgxPCESVN  = 10
+)
+
+func newSGXQuoteBuilder(t *testing.T) *sgxQuoteBuilder {
+	root := newRootCA(t, "SGX Root CA")
+	platformCA := newTestCA(t, "SGX PCK Platform CA", root)
+	pckKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pck := issue(t, "SGX PCK Certificate", &pckKey.PublicKey, platformCA, false, sgxExtensionsOf(t, sgxCompSVN, sgxPCESVN))
+	attKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return &sgxQuoteBuilder{
+		t:          t,
+		root:       root,
+		platformCA: platformCA,
+		pck:        pck,
+		chain:      pemOf(pck, platformCA.cert, root.cert),
+		pckKey:     pckKey,
+		attKey:     attKey,
+		authData:   []byte("auth data"),
+		tcbSigning: newTCBSigningCA(t, root),
+		mrEnclave:  bytes.Repeat([]byte{0xE}, 32),
+		mrSigner:   bytes.Repeat([]byte{0x5}, 32),
+		isvSVN:     7,
+		reportData: append([]byte("public key hash"), make([]byte, 49)...),
+		qeMRSigner: bytes.Repeat([]byte{0x8C}, 32),
+		qeProdID:   1,
+		qeISVSVN:   6,
+
+		crlValidity: time.Hour,
+		fmspc:       sgxFMSPC,
+		tcbLevels: []map[string]interface{}{
+			{"tcb": sgxTCBOf([]int{2, 2, 2, 2, 255, 1}, 10), "tcbStatus": SGXTCBUpToDate},
+			{"tcb": sgxTCBOf([]int{1, 1, 2, 2, 255, 1}, 7), "tcbStatus": SGXTCBOutOfDate},
+		},
+		tcbNextUpdate: time.Now().Add(time.Hour),
+		qeLevels: []map[string]interface{}{
+			{"tcb": map[string]int{"isvsvn": 6}, "tcbStatus": SGXTCBUpToDate},
+			{"tcb": map[string]int{"isvsvn": 4}, "tcbStatus": SGXTCBOutOfDate},
+		},
+		identityMRSigner: bytes.Repeat([]byte{0x8C}, 32),
+		identityProdID:   1,
+		qeNextUpdate:     time.Now().Add(time.Hour),
+	}
+}
+
+// newTCBSigningCA creates the P-256 key signing the TCB info and the QE identity
+func newTCBSigningCA(t *testing.T, root *testCA) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &testCA{cert: issue(t, "SGX TCB Signing", &key.PublicKey, root, false), key: key}
+}
+
+// sgxExtensionsOf returns the SGX extensions of a PCK certificate of the test platform
+func sgxExtensionsOf(t *testing.T, compSVN []int, pceSVN int) pkix.Extension {
+	entry := func(oid asn1.ObjectIdentifier, value interface{}) sgxExtension {
+		der, err := asn1.Marshal(value)
+		require.NoError(t, err)
+		return sgxExtension{ID: oid, Value: asn1.RawValue{FullBytes: der}}
+	}
+	var components []sgxExtension
+	for i, svn := range compSVN {
+		components = append(components, entry(append(append(asn1.ObjectIdentifier{}, oidSGXTCB...), i+1), svn))
+	}
+	components = append(components, entry(oidSGXPCESVN, pceSVN))
+	extensions := []sgxExtension{
+		entry(asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 1}, []byte("PPID")),
+		entry(oidSGXTCB, components),
+		entry(oidSGXPCEID, sgxPCEID),
+		entry(oidSGXFMSPC, sgxFMSPC),
+	}
+	der, err := asn1.Marshal(extensions)
+	require.NoError(t, err)
+	return pkix.Extension{Id: oidSGXExtensions, Value: der}
+}
+
+// sgxTCBOf returns a TCB of the TCB info, with the given first components
+func sgxTCBOf(compSVN []int, pceSVN int) map[string]int {
+	tcb := map[string]int{"pcesvn": pceSVN}
+	for i := 0; i < 16; i++ {
+		tcb[fmt.Sprintf("sgxtcbcomp%02dsvn", i+1)] = 0
+		if i < len(compSVN) {
+			tcb[fmt.Sprintf("sgxtcbcomp%02dsvn", i+1)] = compSVN[i]
+		}
+	}
+	return tcb
+}
+
+// signedJSON returns the document holding the given body under the given field,
+// signed by the TCB signing key
+func (b *sgxQuoteBuilder) signedJSON(field string, body interface{}) []byte {
+	raw, err := json.Marshal(body)
+	require.NoError(b.t, err)
+	signature := rawSign(b.t, b.tcbSigning.key, raw)
+	return []byte(fmt.Sprintf(`{"%s":%s,"signature":"%x"}`, field, raw, signature))
+}
+
+func (b *sgxQuoteBuilder) collateral() *Collateral {
+	var platformRevoked, rootRevoked []*x509.Certificate
+	for _, cert := range b.revoked {
+		if bytes.Equal(cert.RawIssuer, b.platformCA.cert.RawSubject) {
+			platformRevoked = append(platformRevoked, cert)
+		} else {
+			rootRevoked = append(rootRevoked, cert)
+		}
+	}
+	return &Collateral{
+		CRLs: [][]byte{
+			b.platformCA.crl(b.t, b.crlValidity, platformRevoked...),
+			b.root.crl(b.t, b.crlValidity, rootRevoked...),
+		},
+		TCBInfo: b.signedJSON("tcbInfo", map[string]interface{}{
+			"version":    2,
+			"issueDate":  time.Now().Add(-time.Hour),
+			"nextUpdate": b.tcbNextUpdate,
+			"fmspc":      hex.EncodeToString(b.fmspc),
+			"pceId":      hex.EncodeToString(sgxPCEID),
+			"tcbLevels":  b.tcbLevels,
+		}),
+		QEIdentity: b.signedJSON("enclaveIdentity", map[string]interface{}{
+			"id":             "QE",
+			"version":        2,
+			"issueDate":      time.Now().Add(-time.Hour),
+			"nextUpdate":     b.qeNextUpdate,
+			"miscselect":     "00000000",
+			"miscselectMask": "FFFFFFFF",
+			"attributes":     "11000000000000000000000000000000",
+			"attributesMask": "FBFFFFFFFFFFFFFF0000000000000000",
+			"mrsigner":       hex.EncodeToString(b.identityMRSigner),
+			"isvprodid":      b.identityProdID,
+			"tcbLevels":      b.qeLevels,
+		}),
+		SigningCertificates: pemOf(b.tcbSigning.cert, b.root.cert),
+	}
+}
+
+func (b *sgxQuoteBuilder) evidence() *Evidence {
+	return &Evidence{Report: b.build(), Collateral: b.collateral()}
+}
+
+func (b *sgxQuoteBuilder) build() []byte {
+	header := make([]byte, sgxHeaderSize)
+	binary.LittleEndian.PutUint16(header[0:], sgxQuoteVersion)
+	binary.LittleEndian.PutUint16(header[2:], sgxAttestationKeyType)
+
+	body := make([]byte, sgxReportBodySize)
+	if b.debug {
+		body[sgxAttributesOffset] |= sgxDebugFlag
+	}
+	copy(body[sgxMREnclaveOffset:], b.mrEnclave)
+	copy(body[sgxMRSignerOffset:], b.mrSigner)
+	binary.LittleEndian.PutUint16(body[sgxISVSVNOffset:], b.isvSVN)
+	copy(body[sgxReportDataOffset:], b.reportData)
+
+	attPub := append(padded(b.attKey.X, 32), padded(b.attKey.Y, 32)...)
+
+	qeBody := make([]byte, sgxReportBodySize)
+	qeBody[sgxAttributesOffset] = 0x11
+	copy(qeBody[sgxMRSignerOffset:], b.qeMRSigner)
+	binary.LittleEndian.PutUint16(qeBody[sgxISVProdIDOffset:], b.qeProdID)
+	binary.LittleEndian.PutUint16(qeBody[sgxISVSVNOffset:], b.qeISVSVN)
+	keyHash := sha256.Sum256(append(append([]byte{}, attPub...), b.authData...))
+	copy(qeBody[sgxReportDataOffset:], keyHash[:])
+
+	var sigData []byte
+	sigData = append(sigData, rawSign(b.t, b.attKey, append(append([]byte{}, header...), body...))...)
+	sigData = append(sigData, attPub...)
+	sigData = append(sigData, qeBody...)
+	sigData = append(sigData, rawSign(b.t, b.pckKey, qeBody)...)
+	sigData = appendUint16(sigData, uint16(len(b.authData)))
+	sigData = append(sigData, b.authData...)
+	sigData = appendUint16(sigData, sgxCertDataPCKChain)
+	sigData = appendUint32(sigData, uint32(len(b.chain)))
+	sigData = append(sigData, b.chain...)
+
+	quote := append(append([]byte{}, header...), body...)
+	quote = appendUint32(quote, uint32(len(sigData)))
+	return append(quote, sigData...)
+}
+
+func rawSign(t *testing.T, key *ecdsa.PrivateKey, msg []byte) []byte {
+	digest := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	return append(padded(r, 32), padded(s, 32)...)
+}
+
+// padded returns the big endian bytes of the integer, left padded with zeros to the given size
+func padded(i *big.Int, size int) []byte {
+	b := i.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func TestSGXVerify(t *testing.T) {
+	b := newSGXQuoteBuilder(t)
+	v := NewSGXVerifier(b.root.pool())
+
+	claims, err := v.Verify(b.evidence())
+	require.NoError(t, err)
+	require.Equal(t, &Claims{
+		Platform:    PlatformSGX,
+		Measurement: b.mrEnclave,
+		Signer:      b.mrSigner,
+		SVN:         7,
+		ReportData:  b.reportData,
+	}, claims)
+
+	b.debug = true
+	claims, err = v.Verify(b.evidence())
+	require.NoError(t, err)
+	require.True(t, claims.Debug)
+
+	_, err = Verify(v, b.evidence(), &Policy{Measurements: [][]byte{b.mrEnclave}})
+	require.EqualError(t, err, "sgx enclave is not trusted: debug enclaves are not trusted")
+}
+
+func TestSGXVerifyFailures(t *testing.T) {
+	b := newSGXQuoteBuilder(t)
+	v := NewSGXVerifier(b.root.pool())
+	quote := b.build()
+	collateral := b.collateral()
+
+	tamper := func(offset int) []byte {
+		tampered := append([]byte{}, quote...)
+		tampered[offset] ^= 0xFF
+		return tampered
+	}
+	sigDataOffset := sgxHeaderSize + sgxReportBodySize + 4
+	qeBodyOffset := sigDataOffset + sgxSignatureSize + sgxPublicKeySize
+
+	tests := []struct {
+		name     string
+		quote    []byte
+		roots    *testCA
+		expected string
+	}{
+		{
+			name:     "truncated",
+			quote:    quote[:100],
+			expected: "invalid SGX quote: truncated at offset 48, expected 384 more bytes",
+		},
+		{
+			name:     "unsupported version",
+			quote:    tamper(0),
+			expected: "invalid SGX quote: unsupported version 252, expected 3",
+		},
+		{
+			name:     "inconsistent signature data",
+			quote:    append(append([]byte{}, quote...), 0),
+			expected: "invalid SGX quote: signature data is " + strconv.Itoa(len(quote)-sigDataOffset+1) + " bytes, expected " + strconv.Itoa(len(quote)-sigDataOffset),
+		},
+		{
+			name:     "tampered report",
+			quote:    tamper(sgxHeaderSize + sgxMREnclaveOffset),
+			expected: "the signature of the quote is invalid",
+		},
+		{
+			name:     "tampered quoting enclave report",
+			quote:    tamper(qeBodyOffset + 1),
+			expected: "the signature of the quoting enclave report is invalid",
+		},
+		{
+			name:     "untrusted PCK certificate",
+			quote:    quote,
+			roots:    newRootCA(t, "Other Root CA"),
+			expected: "failed verifying the certificate of SGX PCK Certificate: x509: certificate signed by unknown authority",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := v
+			if test.roots != nil {
+				verifier = NewSGXVerifier(test.roots.pool())
+			}
+			_, err := verifier.Verify(&Evidence{Report: test.quote, Collateral: collateral})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.expected)
+		})
+	}
+
+	t.Run("attestation key not certified", func(t *testing.T) {
+		b.authData = []byte("other auth data")
+		quote := b.build()
+		// forge the authentication data the quoting enclave report binds
+		authOffset := qeBodyOffset + sgxReportBodySize + sgxSignatureSize + 2
+		copy(quote[authOffset:], "tampered")
+		_, err := v.Verify(&Evidence{Report: quote, Collateral: collateral})
+		require.EqualError(t, err, "the attestation key is not certified by the quoting enclave")
+	})
+}
+
+func TestSGXVerifyCollateral(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  func(b *sgxQuoteBuilder)
+		evidence func(e *Evidence)
+		accepted []string
+		expected string
+	}{
+		{
+			name:     "no collateral",
+			evidence: func(e *Evidence) { e.Collateral = nil },
+			expected: "the evidence holds no collateral",
+		},
+		{
+			name:     "revoked PCK certificate",
+			builder:  func(b *sgxQuoteBuilder) { b.revoked = []*x509.Certificate{b.pck} },
+			expected: "the certificate of SGX PCK Certificate is revoked",
+		},
+		{
+			name:     "revoked PCK CA",
+			builder:  func(b *sgxQuoteBuilder) { b.revoked = []*x509.Certificate{b.platformCA.cert} },
+			expected: "the certificate of SGX PCK Platform CA is revoked",
+		},
+		{
+			name:     "revoked TCB signing certificate",
+			builder:  func(b *sgxQuoteBuilder) { b.revoked = []*x509.Certificate{b.tcbSigning.cert} },
+			expected: "the certificate of SGX TCB Signing is revoked",
+		},
+		{
+			name:     "expired CRLs",
+			builder:  func(b *sgxQuoteBuilder) { b.crlValidity = -time.Minute },
+			expected: "the CRL of SGX PCK Platform CA expired on",
+		},
+		{
+			name:     "missing CRL",
+			evidence: func(e *Evidence) { e.Collateral.CRLs = e.Collateral.CRLs[:1] },
+			expected: "no CRL of SGX Root CA",
+		},
+		{
+			name: "out of date platform",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbLevels = []map[string]interface{}{
+					{"tcb": sgxTCBOf([]int{3, 3, 2, 2, 255, 1}, 11), "tcbStatus": SGXTCBUpToDate},
+					{"tcb": sgxTCBOf([]int{2, 2, 2, 2, 255, 1}, 7), "tcbStatus": SGXTCBOutOfDate},
+				}
+			},
+			expected: "the TCB of the platform is OutOfDate",
+		},
+		{
+			name: "revoked platform",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbLevels[0]["tcbStatus"] = SGXTCBRevoked
+			},
+			accepted: []string{SGXTCBSWHardeningNeeded},
+			expected: "the TCB of the platform is Revoked",
+		},
+		{
+			name: "platform needing software hardening",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbLevels[0]["tcbStatus"] = SGXTCBSWHardeningNeeded
+			},
+			expected: "the TCB of the platform is SWHardeningNeeded",
+		},
+		{
+			name: "accepted platform needing software hardening",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbLevels[0]["tcbStatus"] = SGXTCBSWHardeningNeeded
+				b.qeLevels[0]["tcbStatus"] = SGXTCBSWHardeningNeeded
+			},
+			accepted: []string{SGXTCBSWHardeningNeeded},
+		},
+		{
+			name: "unknown platform TCB",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbLevels = b.tcbLevels[:1]
+				b.tcbLevels[0]["tcb"] = sgxTCBOf([]int{2, 2, 2, 2, 255, 1}, 11)
+			},
+			expected: "the TCB of the platform is lower than all the TCB levels of the TCB info",
+		},
+		{
+			name:     "out of date TCB info",
+			builder:  func(b *sgxQuoteBuilder) { b.tcbNextUpdate = time.Now().Add(-time.Minute) },
+			expected: "the TCB info expired on",
+		},
+		{
+			name:     "TCB info of another platform",
+			builder:  func(b *sgxQuoteBuilder) { b.fmspc = []byte{0x00, 0x60, 0x6A, 0x00, 0x00, 0x00} },
+			expected: "the TCB info of FMSPC 00606a000000 and PCE 0000 does not apply to the platform of FMSPC 00906ea10000 and PCE 0000",
+		},
+		{
+			name: "tampered TCB info",
+			evidence: func(e *Evidence) {
+				e.Collateral.TCBInfo = bytes.Replace(e.Collateral.TCBInfo, []byte(`"tcbStatus":"OutOfDate"`), []byte(`"tcbStatus":"UpToDate"`), 1)
+			},
+			expected: "invalid TCB info: the signature is invalid",
+		},
+		{
+			name: "untrusted TCB signing certificate",
+			builder: func(b *sgxQuoteBuilder) {
+				b.tcbSigning = newTCBSigningCA(t, newRootCA(t, "Other Root CA"))
+			},
+			expected: "failed verifying the certificate of SGX TCB Signing: x509: certificate signed by unknown authority",
+		},
+		{
+			name: "PCK certificate without SGX extensions",
+			builder: func(b *sgxQuoteBuilder) {
+				b.pck = issue(t, "SGX PCK Certificate", &b.pckKey.PublicKey, b.platformCA, false)
+				b.chain = pemOf(b.pck, b.platformCA.cert, b.root.cert)
+			},
+			expected: "invalid PCK certificate: missing SGX extensions",
+		},
+		{
+			name:     "out of date quoting enclave",
+			builder:  func(b *sgxQuoteBuilder) { b.qeISVSVN = 5 },
+			expected: "the TCB of the quoting enclave is OutOfDate",
+		},
+		{
+			name:     "unknown quoting enclave TCB",
+			builder:  func(b *sgxQuoteBuilder) { b.qeISVSVN = 3 },
+			expected: "the security version 3 of the quoting enclave is lower than all the TCB levels of its identity",
+		},
+		{
+			name:     "out of date QE identity",
+			builder:  func(b *sgxQuoteBuilder) { b.qeNextUpdate = time.Now().Add(-time.Minute) },
+			expected: "the QE identity expired on",
+		},
+		{
+			name:     "quoting enclave of another signer",
+			builder:  func(b *sgxQuoteBuilder) { b.identityMRSigner = bytes.Repeat([]byte{0x8D}, 32) },
+			expected: "the MRSIGNER 8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c8c of the quoting enclave does not match its identity",
+		},
+		{
+			name:     "other quoting enclave",
+			builder:  func(b *sgxQuoteBuilder) { b.identityProdID = 2 },
+			expected: "the product ID 1 of the quoting enclave does not match its identity",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newSGXQuoteBuilder(t)
+			if test.builder != nil {
+				test.builder(b)
+			}
+			e := b.evidence()
+			if test.evidence != nil {
+				test.evidence(e)
+			}
+			_, err := NewSGXVerifier(b.root.pool(), test.accepted...).Verify(e)
+			if test.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
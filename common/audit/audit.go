@@ -0,0 +1,247 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package audit records the security relevant events of a node in a
+// tamper-evident log, separate from the debug logs. The records are written
+// as JSON lines, and each record carries the hash of the record before it, so
+// that altering, removing or reordering records breaks the chain.
+package audit
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("audit")
+
+// The types of the events recorded by the nodes.
+const (
+	// AdminRequest is a request changing the node through the operations service.
+	AdminRequest = "admin.request"
+	// ChannelJoin is the join of a channel by the node.
+	ChannelJoin = "channel.join"
+	// ConfigUpdate is the update of the configuration of a channel.
+	ConfigUpdate = "config.update"
+	// KeyLoad is the load of the signing identity or the TLS credentials of
+	// the node at startup.
+	KeyLoad = "key.load"
+	// KeyRotate is the rotation of the TLS credentials of the node.
+	KeyRotate = "key.rotate"
+)
+
+// Event is an event to record.
+type Event struct {
+	// Type is the type of the event.
+	Type string
+	// Actor identifies who caused the event, if known.
+	Actor string
+	// Channel is the channel the event relates to, if any.
+	Channel string
+	// Attributes describe the event.
+	Attributes map[string]string
+}
+
+// Record is an event recorded in the audit log.
+type Record struct {
+	Sequence   uint64            `json:"seq"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Type       string            `json:"type"`
+	Actor      string            `json:"actor,omitempty"`
+	Channel    string            `json:"channel,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// PrevHash is the hash of the previous record, empty for the first record.
+	PrevHash string `json:"prevHash"`
+	// Hash is the hex encoded SHA-256 hash of the JSON encoding of the record
+	// with an empty hash.
+	Hash string `json:"hash"`
+}
+
+// ComputeHash returns the hash of the record.
+func (r *Record) ComputeHash() (string, error) {
+	unhashed := *r
+	unhashed.Hash = ""
+	b, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Actor returns the actor of an event caused by the given serialized
+// identity, formed of the MSP ID and the subject of the certificate of the
+// identity. It returns an empty actor when the identity is malformed.
+func Actor(serializedIdentity []byte) string {
+	sid := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sid); err != nil {
+		return ""
+	}
+	block, _ := pem.Decode(sid.IdBytes)
+	if block == nil {
+		return sid.Mspid
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return sid.Mspid
+	}
+	return sid.Mspid + "/" + cert.Subject.String()
+}
+
+// KeyLoadEvent returns the event of the load of the key certified by the
+// given PEM encoded certificate, used by the node for the given purpose.
+func KeyLoadEvent(purpose string, certPEM []byte) Event {
+	attributes := map[string]string{"purpose": purpose}
+	if block, _ := pem.Decode(certPEM); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			attributes["subject"] = cert.Subject.String()
+			attributes["issuer"] = cert.Issuer.String()
+			attributes["serial"] = cert.SerialNumber.String()
+			attributes["notAfter"] = cert.NotAfter.UTC().Format(time.RFC3339)
+		}
+	}
+	return Event{Type: KeyLoad, Attributes: attributes}
+}
+
+// IdentityKeyLoadEvent returns the event of the load of the key of the given
+// serialized identity, used by the node for the given purpose.
+func IdentityKeyLoadEvent(purpose string, serializedIdentity []byte) Event {
+	sid := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sid); err != nil {
+		return Event{Type: KeyLoad, Attributes: map[string]string{"purpose": purpose}}
+	}
+	event := KeyLoadEvent(purpose, sid.IdBytes)
+	event.Attributes["mspID"] = sid.Mspid
+	return event
+}
+
+// Log appends the records of events to its sinks. A nil Log records nothing.
+type Log struct {
+	sinks []Sink
+	now   func() time.Time
+
+	mutex    sync.Mutex
+	sequence uint64
+	lastHash string
+}
+
+// NewLog returns a Log writing to the given sinks. The chain of records
+// continues after the given record, or starts anew when it is nil.
+func NewLog(last *Record, sinks ...Sink) *Log {
+	l := &Log{
+		sinks: sinks,
+		now:   time.Now,
+	}
+	if last != nil {
+		l.sequence = last.Sequence
+		l.lastHash = last.Hash
+	}
+	return l
+}
+
+// Record appends a record of the event to the sinks of the log. Failures to
+// write the record are logged, and do not prevent the event.
+func (l *Log) Record(e Event) {
+	if l == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	r := &Record{
+		Sequence:   l.sequence + 1,
+		Timestamp:  l.now().UTC(),
+		Type:       e.Type,
+		Actor:      e.Actor,
+		Channel:    e.Channel,
+		Attributes: e.Attributes,
+		PrevHash:   l.lastHash,
+	}
+	hash, err := r.ComputeHash()
+	if err != nil {
+		logger.Errorf("Failed hashing audit record %d: %s", r.Sequence, err)
+		return
+	}
+	r.Hash = hash
+	b, err := json.Marshal(r)
+	if err != nil {
+		logger.Errorf("Failed encoding audit record %d: %s", r.Sequence, err)
+		return
+	}
+	b = append(b, '\n')
+
+	for _, s := range l.sinks {
+		if err := s.Append(b); err != nil {
+			logger.Errorf("Failed writing audit record %d: %s", r.Sequence, err)
+		}
+	}
+	l.sequence = r.Sequence
+	l.lastHash = r.Hash
+}
+
+// Close closes the sinks of the log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var firstErr error
+	for _, s := range l.sinks {
+		if c, ok := s.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Verify reads the records of an audit log and checks that their hashes and
+// sequences form an unbroken chain, starting with the first record. It returns
+// the last record, or nil if the log is empty.
+func Verify(r io.Reader) (*Record, error) {
+	var last *Record
+	err := scanLines(r, func(line int, data []byte) error {
+		record := &Record{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return errors.Wrapf(err, "malformed record on line %d", line)
+		}
+		hash, err := record.ComputeHash()
+		if err != nil {
+			return errors.WithMessagef(err, "failed hashing the record on line %d", line)
+		}
+		if hash != record.Hash {
+			return errors.Errorf("the record on line %d has hash %s, expected %s", line, record.Hash, hash)
+		}
+		prevHash, sequence := "", uint64(1)
+		if last != nil {
+			prevHash, sequence = last.Hash, last.Sequence+1
+		}
+		if record.PrevHash != prevHash {
+			return errors.Errorf("the record on line %d does not follow the previous record", line)
+		}
+		if record.Sequence != sequence {
+			return errors.Errorf("the record on line %d has sequence %d, expected %d", line, record.Sequence, sequence)
+		}
+		last = record
+		return nil
+	})
+	return last, err
+}
@@ -0,0 +1,231 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRecord(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log := NewLog(nil, NewWriterSink(buffer))
+	log.now = func() time.Time { return time.Unix(1000, 0) }
+
+	log.Record(Event{Type: KeyLoad, Actor: "Org1MSP", Attributes: map[string]string{"key": "signing"}})
+	log.Record(Event{Type: ChannelJoin, Channel: "mychannel"})
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	first := &Record{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), first))
+	assert.Equal(t, uint64(1), first.Sequence)
+	assert.Equal(t, time.Unix(1000, 0).UTC(), first.Timestamp)
+	assert.Equal(t, KeyLoad, first.Type)
+	assert.Equal(t, "Org1MSP", first.Actor)
+	assert.Equal(t, map[string]string{"key": "signing"}, first.Attributes)
+	assert.Empty(t, first.PrevHash)
+	hash, err := first.ComputeHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, first.Hash)
+
+	second := &Record{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), second))
+	assert.Equal(t, uint64(2), second.Sequence)
+	assert.Equal(t, "mychannel", second.Channel)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	last, err := Verify(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, second, last)
+}
+
+func TestLogResume(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	NewLog(nil, NewWriterSink(buffer)).Record(Event{Type: KeyLoad})
+	last, err := Verify(bytes.NewReader(buffer.Bytes()))
+	require.NoError(t, err)
+
+	NewLog(last, NewWriterSink(buffer)).Record(Event{Type: KeyRotate})
+	last, err = Verify(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), last.Sequence)
+	assert.Equal(t, KeyRotate, last.Type)
+}
+
+type failingSink struct{ closed bool }
+
+func (f *failingSink) Append([]byte) error { return assert.AnError }
+func (f *failingSink) Close() error        { f.closed = true; return assert.AnError }
+
+func TestLogSinkFailure(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	failing := &failingSink{}
+	log := NewLog(nil, failing, NewWriterSink(buffer))
+
+	log.Record(Event{Type: KeyLoad})
+	log.Record(Event{Type: KeyRotate})
+	last, err := Verify(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), last.Sequence)
+
+	assert.Equal(t, assert.AnError, log.Close())
+	assert.True(t, failing.closed)
+}
+
+func TestActor(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	kp, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+
+	sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: kp.Cert})
+	assert.Equal(t, "Org1MSP/"+kp.TLSCert.Subject.String(), Actor(sid))
+
+	sid = protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("garbage")})
+	assert.Equal(t, "Org1MSP", Actor(sid))
+
+	assert.Empty(t, Actor([]byte("garbage")))
+}
+
+func TestKeyLoadEvent(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	kp, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+
+	assert.Equal(t, Event{
+		Type: KeyLoad,
+		Attributes: map[string]string{
+			"purpose":  "tls",
+			"subject":  kp.TLSCert.Subject.String(),
+			"issuer":   kp.TLSCert.Issuer.String(),
+			"serial":   kp.TLSCert.SerialNumber.String(),
+			"notAfter": kp.TLSCert.NotAfter.UTC().Format(time.RFC3339),
+		},
+	}, KeyLoadEvent("tls", kp.Cert))
+
+	assert.Equal(t, Event{
+		Type:       KeyLoad,
+		Attributes: map[string]string{"purpose": "tls"},
+	}, KeyLoadEvent("tls", []byte("garbage")))
+}
+
+func TestIdentityKeyLoadEvent(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	kp, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+
+	sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: kp.Cert})
+	event := IdentityKeyLoadEvent("signing", sid)
+	assert.Equal(t, KeyLoad, event.Type)
+	assert.Equal(t, "signing", event.Attributes["purpose"])
+	assert.Equal(t, "Org1MSP", event.Attributes["mspID"])
+	assert.Equal(t, kp.TLSCert.SerialNumber.String(), event.Attributes["serial"])
+
+	assert.Equal(t, Event{
+		Type:       KeyLoad,
+		Attributes: map[string]string{"purpose": "signing"},
+	}, IdentityKeyLoadEvent("signing", []byte("garbage")))
+}
+
+func TestNilLog(t *testing.T) {
+	var log *Log
+	log.Record(Event{Type: KeyLoad})
+	assert.NoError(t, log.Close())
+}
+
+func TestVerify(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log := NewLog(nil, NewWriterSink(buffer))
+	for i := 0; i < 3; i++ {
+		log.Record(Event{Type: ConfigUpdate, Channel: "mychannel"})
+	}
+	lines := strings.SplitAfter(buffer.String(), "\n")[:3]
+
+	tests := []struct {
+		name        string
+		log         string
+		expectedErr string
+	}{
+		{
+			name: "empty",
+			log:  "\n",
+		},
+		{
+			name:        "malformed record",
+			log:         lines[0] + "{\"seq\":2",
+			expectedErr: "malformed record on line 2",
+		},
+		{
+			name:        "altered record",
+			log:         lines[0] + strings.Replace(lines[1], "mychannel", "otherchannel", 1),
+			expectedErr: "the record on line 2 has hash",
+		},
+		{
+			name:        "removed record",
+			log:         lines[0] + lines[2],
+			expectedErr: "the record on line 2 does not follow the previous record",
+		},
+		{
+			name:        "reordered records",
+			log:         lines[0] + lines[2] + lines[1],
+			expectedErr: "the record on line 2 does not follow the previous record",
+		},
+		{
+			name:        "removed first record",
+			log:         lines[1] + lines[2],
+			expectedErr: "the record on line 1 does not follow the previous record",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tt.log))
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestVerifySequence(t *testing.T) {
+	first := &Record{Sequence: 1, Type: KeyLoad}
+	first.Hash, _ = first.ComputeHash()
+	second := &Record{Sequence: 3, Type: KeyLoad, PrevHash: first.Hash}
+	second.Hash, _ = second.ComputeHash()
+
+	buffer := &bytes.Buffer{}
+	for _, r := range []*Record{first, second} {
+		b, err := json.Marshal(r)
+		require.NoError(t, err)
+		buffer.Write(append(b, '\n'))
+	}
+
+	_, err := Verify(buffer)
+	assert.EqualError(t, err, "the record on line 2 has sequence 3, expected 2")
+
+	first = &Record{Sequence: 2, Type: KeyLoad}
+	first.Hash, _ = first.ComputeHash()
+	b, err := json.Marshal(first)
+	require.NoError(t, err)
+	_, err = Verify(bytes.NewReader(b))
+	assert.EqualError(t, err, "the record on line 1 has sequence 2, expected 1")
+}
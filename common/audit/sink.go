@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// maxRecordSize is the size of the largest record read back from a log.
+const maxRecordSize = 1024 * 1024
+
+// Sink stores the records of an audit log. Sinks that implement io.Closer are
+// closed with the log.
+type Sink interface {
+	// Append stores a record, encoded as a JSON line.
+	Append(record []byte) error
+}
+
+// NewWriterSink returns a Sink writing the records to the given writer.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{writer: w}
+}
+
+type writerSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *writerSink) Append(record []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := w.writer.Write(record)
+	return err
+}
+
+// FileSink appends the records to a file, and syncs the file after each
+// record.
+type FileSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// OpenFile opens the audit log file at the given path for appending, creating
+// it if needed. It returns the last record of the file, if any, so that the
+// chain of records is resumed.
+func OpenFile(path string) (*FileSink, *Record, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed opening audit log")
+	}
+
+	// a record partially written before a crash is skipped, and left for
+	// Verify to report
+	var last *Record
+	err = scanLines(file, func(line int, data []byte) error {
+		record := &Record{}
+		if err := json.Unmarshal(data, record); err != nil {
+			logger.Warningf("Skipping malformed record on line %d of audit log %s: %s", line, path, err)
+			return nil
+		}
+		last = record
+		return nil
+	})
+	if err == nil {
+		err = terminateLastLine(file)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return &FileSink{file: file}, last, nil
+}
+
+// terminateLastLine appends a newline to the file if its last line is not
+// terminated, so that the next record starts on a line of its own.
+func terminateLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrap(err, "failed reading audit log")
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return errors.Wrap(err, "failed reading audit log")
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte("\n"))
+	return errors.Wrap(err, "failed writing audit log")
+}
+
+func (f *FileSink) Append(record []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, err := f.file.Write(record); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *FileSink) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// Open returns a Log writing the records to the file at the given path when
+// it is set, and to the standard error when stderr is true. It returns nil
+// when neither is set.
+func Open(path string, stderr bool) (*Log, error) {
+	var sinks []Sink
+	var last *Record
+	if path != "" {
+		file, lastRecord, err := OpenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, file)
+		last = lastRecord
+	}
+	if stderr {
+		sinks = append(sinks, NewWriterSink(os.Stderr))
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return NewLog(last, sinks...), nil
+}
+
+// scanLines calls the given function with each non-empty line read from r,
+// along with its line number.
+func scanLines(r io.Reader, f func(line int, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := f(line, scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(scanner.Err(), "failed reading audit log")
+}
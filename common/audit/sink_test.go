@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "audit.log")
+
+	log, err := Open("", false)
+	assert.NoError(t, err)
+	assert.Nil(t, log)
+
+	log, err = Open(path, false)
+	require.NoError(t, err)
+	log.Record(Event{Type: KeyLoad})
+	log.Record(Event{Type: KeyLoad})
+	require.NoError(t, log.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the chain is resumed when the log is reopened
+	log, err = Open(path, false)
+	require.NoError(t, err)
+	log.Record(Event{Type: KeyRotate})
+	require.NoError(t, log.Close())
+
+	last := verifyFile(t, path)
+	assert.Equal(t, uint64(3), last.Sequence)
+	assert.Equal(t, KeyRotate, last.Type)
+
+	_, err = Open(filepath.Join(tempDir, "missing", "audit.log"), false)
+	assert.Contains(t, err.Error(), "failed opening audit log")
+}
+
+func TestOpenFilePartialRecord(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "audit.log")
+
+	log, err := Open(path, false)
+	require.NoError(t, err)
+	log.Record(Event{Type: KeyLoad})
+	require.NoError(t, log.Close())
+
+	// a record partially written before a crash
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = file.Write([]byte(`{"seq":2,"times`))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	sink, last, err := OpenFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), last.Sequence)
+	log = NewLog(last, sink)
+	log.Record(Event{Type: KeyRotate})
+	require.NoError(t, log.Close())
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := 0
+	for _, b := range content {
+		if b == '\n' {
+			lines++
+		}
+	}
+	assert.Equal(t, 3, lines)
+
+	file, err = os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = Verify(file)
+	assert.EqualError(t, err, "malformed record on line 2: unexpected end of JSON input")
+}
+
+func verifyFile(t *testing.T, path string) *Record {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	last, err := Verify(file)
+	require.NoError(t, err)
+	return last
+}
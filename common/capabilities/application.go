@@ -28,6 +28,9 @@ const (
 	// ApplicationV2_0 is the capabilities string for standard new non-backwards compatible fabric v2.0 application capabilities.
 	ApplicationV2_0 = "V2_0"
 
+	// ApplicationPolicyTemplates is the capabilities string allowing policy templates in the application config.
+	ApplicationPolicyTemplates = "PolicyTemplates"
+
 	// ApplicationPvtDataExperimental is the capabilities string for private data using the experimental feature of collections/sideDB.
 	ApplicationPvtDataExperimental = "V1_1_PVTDATA_EXPERIMENTAL"
 
@@ -43,6 +46,7 @@ type ApplicationProvider struct {
 	v13                    bool
 	v142                   bool
 	v20                    bool
+	policyTemplates        bool
 	v11PvtDataExperimental bool
 }
 
@@ -55,6 +59,7 @@ func NewApplicationProvider(capabilities map[string]*cb.Capability) *Application
 	_, ap.v13 = capabilities[ApplicationV1_3]
 	_, ap.v142 = capabilities[ApplicationV1_4_2]
 	_, ap.v20 = capabilities[ApplicationV2_0]
+	_, ap.policyTemplates = capabilities[ApplicationPolicyTemplates]
 	_, ap.v11PvtDataExperimental = capabilities[ApplicationPvtDataExperimental]
 	return ap
 }
@@ -139,6 +144,13 @@ func (ap *ApplicationProvider) StorePvtDataOfInvalidTx() bool {
 	return ap.v142 || ap.v20
 }
 
+// PolicyTemplates returns whether policy templates may be specified in the channel application config.
+// Unlike the other capabilities, it is not implied by any version capability, so that the peers
+// predating policy templates, which reject the config value, can be upgraded beforehand.
+func (ap *ApplicationProvider) PolicyTemplates() bool {
+	return ap.policyTemplates
+}
+
 // HasCapability returns true if the capability is supported by this binary.
 func (ap *ApplicationProvider) HasCapability(capability string) bool {
 	switch capability {
@@ -153,6 +165,8 @@ func (ap *ApplicationProvider) HasCapability(capability string) bool {
 		return true
 	case ApplicationV2_0:
 		return true
+	case ApplicationPolicyTemplates:
+		return true
 	case ApplicationPvtDataExperimental:
 		return true
 	case ApplicationResourcesTreeExperimental:
@@ -87,6 +87,17 @@ func TestApplicationV20(t *testing.T) {
 	assert.True(t, ap.PrivateChannelData())
 	assert.True(t, ap.LifecycleV20())
 	assert.True(t, ap.StorePvtDataOfInvalidTx())
+	assert.False(t, ap.PolicyTemplates())
+}
+
+func TestApplicationPolicyTemplates(t *testing.T) {
+	ap := NewApplicationProvider(map[string]*cb.Capability{
+		ApplicationV2_0:            {},
+		ApplicationPolicyTemplates: {},
+	})
+	assert.NoError(t, ap.Supported())
+	assert.True(t, ap.LifecycleV20())
+	assert.True(t, ap.PolicyTemplates())
 }
 
 func TestApplicationPvtDataExperimental(t *testing.T) {
@@ -102,6 +113,7 @@ func TestHasCapability(t *testing.T) {
 	assert.True(t, ap.HasCapability(ApplicationV1_2))
 	assert.True(t, ap.HasCapability(ApplicationV1_3))
 	assert.True(t, ap.HasCapability(ApplicationV2_0))
+	assert.True(t, ap.HasCapability(ApplicationPolicyTemplates))
 	assert.True(t, ap.HasCapability(ApplicationPvtDataExperimental))
 	assert.True(t, ap.HasCapability(ApplicationResourcesTreeExperimental))
 	assert.False(t, ap.HasCapability("default"))
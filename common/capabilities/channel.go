@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package capabilities
 
 import (
+	"crypto/x509"
+
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/msp"
 )
 
@@ -28,16 +31,34 @@ const (
 
 	// ChannelV2_0 is the capabilities string for standard new non-backwards compatible fabric v2.0 channel capabilities.
 	ChannelV2_0 = "V2_0"
+
+	// ChannelSignatureAlgorithmECDSA is the capabilities string allowing identities with ECDSA keys in the channel.
+	ChannelSignatureAlgorithmECDSA = "SignatureAlgorithm_ECDSA"
+
+	// ChannelSignatureAlgorithmEd25519 is the capabilities string allowing identities with Ed25519 keys in the channel.
+	ChannelSignatureAlgorithmEd25519 = "SignatureAlgorithm_Ed25519"
+
+	// ChannelLowSNormalize is the capabilities string accepting the high-S ECDSA signatures in the channel,
+	// once normalized to low-S.
+	ChannelLowSNormalize = "LowS_Normalize"
+
+	// ChannelLowSReject is the capabilities string reporting the high-S ECDSA signatures as invalid in the
+	// channel, instead of refusing them with an error.
+	ChannelLowSReject = "LowS_Reject"
 )
 
 // ChannelProvider provides capabilities information for channel level config.
 type ChannelProvider struct {
 	*registry
-	v11  bool
-	v13  bool
-	v142 bool
-	v143 bool
-	v20  bool
+	v11           bool
+	v13           bool
+	v142          bool
+	v143          bool
+	v20           bool
+	ecdsa         bool
+	ed25519       bool
+	lowSNormalize bool
+	lowSReject    bool
 }
 
 // NewChannelProvider creates a channel capabilities provider.
@@ -49,6 +70,10 @@ func NewChannelProvider(capabilities map[string]*cb.Capability) *ChannelProvider
 	_, cp.v142 = capabilities[ChannelV1_4_2]
 	_, cp.v143 = capabilities[ChannelV1_4_3]
 	_, cp.v20 = capabilities[ChannelV2_0]
+	_, cp.ecdsa = capabilities[ChannelSignatureAlgorithmECDSA]
+	_, cp.ed25519 = capabilities[ChannelSignatureAlgorithmEd25519]
+	_, cp.lowSNormalize = capabilities[ChannelLowSNormalize]
+	_, cp.lowSReject = capabilities[ChannelLowSReject]
 	return cp
 }
 
@@ -61,6 +86,14 @@ func (cp *ChannelProvider) Type() string {
 func (cp *ChannelProvider) HasCapability(capability string) bool {
 	switch capability {
 	// Add new capability names here
+	case ChannelSignatureAlgorithmECDSA:
+		return true
+	case ChannelSignatureAlgorithmEd25519:
+		return true
+	case ChannelLowSNormalize:
+		return true
+	case ChannelLowSReject:
+		return true
 	case ChannelV2_0:
 		return true
 	case ChannelV1_4_3:
@@ -99,3 +132,31 @@ func (cp *ChannelProvider) ConsensusTypeMigration() bool {
 func (cp *ChannelProvider) OrgSpecificOrdererEndpoints() bool {
 	return cp.v142 || cp.v143 || cp.v20
 }
+
+// SignatureAlgorithms returns the algorithms of the keys of the X.509 identities allowed in the channel,
+// or nil when no signature algorithm capability is set, in which case the identities with keys of any
+// algorithm but Ed25519 are allowed.
+func (cp *ChannelProvider) SignatureAlgorithms() []x509.PublicKeyAlgorithm {
+	var algorithms []x509.PublicKeyAlgorithm
+	if cp.ecdsa {
+		algorithms = append(algorithms, x509.ECDSA)
+	}
+	if cp.ed25519 {
+		algorithms = append(algorithms, x509.Ed25519)
+	}
+	return algorithms
+}
+
+// LowSPolicy returns the policy applied to the high-S ECDSA signatures verified by the MSPs of the channel,
+// or "" when no low-S capability is set and the crypto provider refuses them. Rejecting high-S
+// signatures prevails over normalizing them.
+func (cp *ChannelProvider) LowSPolicy() utils.LowSPolicy {
+	switch {
+	case cp.lowSReject:
+		return utils.LowSReject
+	case cp.lowSNormalize:
+		return utils.LowSNormalize
+	default:
+		return ""
+	}
+}
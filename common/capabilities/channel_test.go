@@ -7,9 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package capabilities
 
 import (
+	"crypto/x509"
 	"testing"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/stretchr/testify/assert"
 )
@@ -108,3 +110,46 @@ func TestChannelNotSupported(t *testing.T) {
 	})
 	assert.EqualError(t, cp.Supported(), "Channel capability Bogus_Not_Supported is required but not supported")
 }
+
+func TestChannelSignatureAlgorithms(t *testing.T) {
+	cp := NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0: {},
+	})
+	assert.Nil(t, cp.SignatureAlgorithms())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0:                      {},
+		ChannelSignatureAlgorithmEd25519: {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.Equal(t, []x509.PublicKeyAlgorithm{x509.Ed25519}, cp.SignatureAlgorithms())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelSignatureAlgorithmECDSA:   {},
+		ChannelSignatureAlgorithmEd25519: {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.Equal(t, []x509.PublicKeyAlgorithm{x509.ECDSA, x509.Ed25519}, cp.SignatureAlgorithms())
+	assert.True(t, cp.MSPVersion() == msp.MSPv1_0)
+}
+
+func TestChannelLowSPolicy(t *testing.T) {
+	cp := NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0: {},
+	})
+	assert.Equal(t, utils.LowSPolicy(""), cp.LowSPolicy())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0:          {},
+		ChannelLowSNormalize: {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.Equal(t, utils.LowSNormalize, cp.LowSPolicy())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelLowSNormalize: {},
+		ChannelLowSReject:    {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.Equal(t, utils.LowSReject, cp.LowSPolicy())
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WarnFunc logs a warning, and can be replaced with Warnf of a logger.
+type WarnFunc func(format string, args ...interface{})
+
+// LogAlerter logs the alerts as warnings.
+type LogAlerter struct {
+	Warn WarnFunc
+}
+
+// Alert logs the alert.
+func (l *LogAlerter) Alert(a Alert) error {
+	owner := a.Source
+	if a.MSPID != "" {
+		owner = fmt.Sprintf("%s of MSP %s", owner, a.MSPID)
+	}
+	if a.Channel != "" {
+		owner = fmt.Sprintf("%s in channel %s", owner, a.Channel)
+	}
+
+	if a.Expired {
+		l.Warn("The %s certificate %s (%s) has expired on %s", a.Role, a.Subject, owner, a.NotAfter)
+		return nil
+	}
+	l.Warn("The %s certificate %s (%s) expires within %d days, on %s", a.Role, a.Subject, owner, a.Threshold, a.NotAfter)
+	return nil
+}
+
+// WebhookAlerter posts the alerts as JSON to an HTTP endpoint.
+type WebhookAlerter struct {
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewWebhookAlerter returns an alerter posting the alerts to the given URL.
+// Credentials in the URL are sent with basic authentication.
+func NewWebhookAlerter(endpoint string, timeout time.Duration) (*WebhookAlerter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("invalid webhook URL %s: the scheme must be http or https", endpoint)
+	}
+	return &WebhookAlerter{
+		endpoint: u,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Alert posts the alert to the webhook.
+func (w *WebhookAlerter) Alert(a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.endpoint.User != nil {
+		password, _ := w.endpoint.User.Password()
+		req.SetBasicAuth(w.endpoint.User.Username(), password)
+		req.URL.User = nil
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAlerter(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	var logged []string
+	alerter := &LogAlerter{Warn: func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}}
+
+	require.NoError(t, alerter.Alert(Alert{
+		Source:    SourceChannel,
+		Channel:   "mychannel",
+		MSPID:     "Org1MSP",
+		Role:      "admin",
+		Subject:   "CN=admin",
+		NotAfter:  notAfter,
+		Threshold: 7,
+	}))
+	require.NoError(t, alerter.Alert(Alert{
+		Source:   SourceTLS,
+		Role:     "server TLS",
+		Subject:  "CN=peer0",
+		NotAfter: notAfter,
+		Expired:  true,
+	}))
+
+	require.Equal(t, []string{
+		"The admin certificate CN=admin (channel of MSP Org1MSP in channel mychannel) expires within 7 days, on 2030-01-02 00:00:00 +0000 UTC",
+		"The server TLS certificate CN=peer0 (tls) has expired on 2030-01-02 00:00:00 +0000 UTC",
+	}, logged)
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	alerter, err := NewWebhookAlerter(strings.Replace(server.URL, "http://", "http://alice:secret@", 1)+"/alerts", time.Second)
+	require.NoError(t, err)
+
+	alert := Alert{Source: SourceMSP, MSPID: "Org1MSP", Role: "enrollment", Subject: "CN=peer0", DaysLeft: 6, Threshold: 7}
+	require.NoError(t, alerter.Alert(alert))
+
+	r := <-received
+	require.Equal(t, http.MethodPost, r.Method)
+	require.Equal(t, "/alerts", r.URL.Path)
+	require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	user, password, ok := r.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "alice", user)
+	require.Equal(t, "secret", password)
+
+	var posted Alert
+	require.NoError(t, json.Unmarshal(body, &posted))
+	require.Equal(t, alert, posted)
+}
+
+func TestWebhookAlerterErrors(t *testing.T) {
+	_, err := NewWebhookAlerter("ftp://example.com", time.Second)
+	require.EqualError(t, err, "invalid webhook URL ftp://example.com: the scheme must be http or https")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	alerter, err := NewWebhookAlerter(server.URL, time.Second)
+	require.NoError(t, err)
+	require.EqualError(t, alerter.Alert(Alert{}), "webhook returned status 503 Service Unavailable")
+}
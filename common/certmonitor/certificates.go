@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/pkg/errors"
+)
+
+// The sources of the certificates tracked by a Monitor.
+const (
+	SourceMSP     = "msp"
+	SourceTLS     = "tls"
+	SourceChannel = "channel"
+)
+
+// Certificate is a certificate tracked by a Monitor, along with where it was
+// found.
+type Certificate struct {
+	Source  string
+	Channel string
+	MSPID   string
+	Role    string
+	Cert    *x509.Certificate
+}
+
+// Source returns the certificates to track. Sources are called at every scan,
+// so that renewed certificates and updated channel configurations are picked
+// up.
+type Source func() ([]Certificate, error)
+
+// StaticSource returns a Source that always returns the given certificates.
+func StaticSource(certs ...Certificate) Source {
+	return func() ([]Certificate, error) {
+		return certs, nil
+	}
+}
+
+// ParseCertificate parses a PEM or DER encoded certificate.
+func ParseCertificate(raw []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing certificate")
+	}
+	return cert, nil
+}
+
+// TLSCertificates returns the leaf certificates of the given PEM or DER
+// encoded TLS certificate chains, with the given role.
+func TLSCertificates(role string, chains ...[]byte) ([]Certificate, error) {
+	var certs []Certificate
+	for _, raw := range chains {
+		if len(raw) == 0 {
+			continue
+		}
+		cert, err := ParseCertificate(raw)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s certificate", role)
+		}
+		certs = append(certs, Certificate{Source: SourceTLS, Role: role, Cert: cert})
+	}
+	return certs, nil
+}
+
+// MSPCertificates returns the certificates of the given MSP configuration,
+// which are tagged with the given source and channel. Only FABRIC MSPs hold
+// certificates; the configurations of other types are ignored.
+func MSPCertificates(source, channel string, config *mspproto.MSPConfig) ([]Certificate, error) {
+	if config == nil || config.Type != int32(msp.FABRIC) {
+		return nil, nil
+	}
+	fabricConfig := &mspproto.FabricMSPConfig{}
+	if err := proto.Unmarshal(config.Config, fabricConfig); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling FABRIC MSP config")
+	}
+
+	var certs []Certificate
+	add := func(role string, raws ...[]byte) error {
+		for _, raw := range raws {
+			if len(raw) == 0 {
+				continue
+			}
+			cert, err := ParseCertificate(raw)
+			if err != nil {
+				return errors.WithMessagef(err, "invalid %s certificate of MSP %s", role, fabricConfig.Name)
+			}
+			certs = append(certs, Certificate{
+				Source:  source,
+				Channel: channel,
+				MSPID:   fabricConfig.Name,
+				Role:    role,
+				Cert:    cert,
+			})
+		}
+		return nil
+	}
+
+	groups := []struct {
+		role  string
+		certs [][]byte
+	}{
+		{"root CA", fabricConfig.RootCerts},
+		{"intermediate CA", fabricConfig.IntermediateCerts},
+		{"admin", fabricConfig.Admins},
+		{"TLS root CA", fabricConfig.TlsRootCerts},
+		{"TLS intermediate CA", fabricConfig.TlsIntermediateCerts},
+	}
+	for _, group := range groups {
+		if err := add(group.role, group.certs...); err != nil {
+			return nil, err
+		}
+	}
+	if fabricConfig.SigningIdentity != nil {
+		if err := add("enrollment", fabricConfig.SigningIdentity.PublicSigner); err != nil {
+			return nil, err
+		}
+	}
+	return certs, nil
+}
+
+// ChannelCertificates returns the certificates of the MSPs defined in the
+// given channel configuration.
+func ChannelCertificates(channel string, config *cb.Config) ([]Certificate, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, nil
+	}
+	var certs []Certificate
+	err := walkGroups(config.ChannelGroup, func(value *cb.ConfigValue) error {
+		mspConfig := &mspproto.MSPConfig{}
+		if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+			return errors.Wrapf(err, "failed unmarshalling MSP config of channel %s", channel)
+		}
+		mspCerts, err := MSPCertificates(SourceChannel, channel, mspConfig)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, mspCerts...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// walkGroups calls f with the MSP values of the given group and its
+// subgroups, in a deterministic order.
+func walkGroups(group *cb.ConfigGroup, f func(*cb.ConfigValue) error) error {
+	if value, ok := group.Values[channelconfig.MSPKey]; ok {
+		if err := f(value); err != nil {
+			return err
+		}
+	}
+	var names []string
+	for name := range group.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := walkGroups(group.Groups[name], f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LocalMSPSource returns a Source of the certificates of the local MSP in the
+// given directory, which are read again at every scan, and of the enrollment
+// certificate of the given serialized signing identity.
+func LocalMSPSource(dir, mspID string, signingIdentity []byte) Source {
+	return func() ([]Certificate, error) {
+		config, err := msp.GetVerifyingMspConfig(dir, mspID, msp.ProviderTypeToString(msp.FABRIC))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed loading local MSP from %s", dir)
+		}
+		certs, err := MSPCertificates(SourceMSP, "", config)
+		if err != nil {
+			return nil, err
+		}
+
+		sID := &mspproto.SerializedIdentity{}
+		if err := proto.Unmarshal(signingIdentity, sID); err != nil {
+			return nil, errors.Wrap(err, "failed unmarshalling signing identity")
+		}
+		cert, err := ParseCertificate(sID.IdBytes)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid enrollment certificate")
+		}
+		return append(certs, Certificate{Source: SourceMSP, MSPID: sID.Mspid, Role: "enrollment", Cert: cert}), nil
+	}
+}
+
+// ChannelSource returns a Source of the certificates of the MSPs defined in
+// the configurations of the channels listed. Channels without configuration,
+// or with an invalid one, are skipped.
+func ChannelSource(channels func() []string, config func(channel string) *cb.Config) Source {
+	return func() ([]Certificate, error) {
+		var certs []Certificate
+		for _, channel := range channels() {
+			channelCerts, err := ChannelCertificates(channel, config(channel))
+			if err != nil {
+				logger.Warningf("Skipping the certificates of channel %s: %s", channel, err)
+				continue
+			}
+			certs = append(certs, channelCerts...)
+		}
+		return certs, nil
+	}
+}
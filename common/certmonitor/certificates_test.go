@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/stretchr/testify/require"
+)
+
+// newCertificate returns a self-signed PEM certificate with the given common
+// name, expiring at the given time.
+func newCertificate(t *testing.T, cn string, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-365 * day),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func mspConfig(t *testing.T, fabricConfig *mspproto.FabricMSPConfig) *mspproto.MSPConfig {
+	b, err := proto.Marshal(fabricConfig)
+	require.NoError(t, err)
+	return &mspproto.MSPConfig{Type: 0, Config: b}
+}
+
+func TestParseCertificate(t *testing.T) {
+	notAfter := time.Now().Add(day).Truncate(time.Second).UTC()
+	pemCert := newCertificate(t, "peer0", notAfter)
+
+	cert, err := ParseCertificate(pemCert)
+	require.NoError(t, err)
+	require.Equal(t, "peer0", cert.Subject.CommonName)
+	require.Equal(t, notAfter, cert.NotAfter)
+
+	block, _ := pem.Decode(pemCert)
+	cert, err = ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	require.Equal(t, "peer0", cert.Subject.CommonName)
+
+	_, err = ParseCertificate([]byte("garbage"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed parsing certificate")
+}
+
+func TestTLSCertificates(t *testing.T) {
+	certs, err := TLSCertificates("server TLS", newCertificate(t, "server", time.Now()), nil)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, SourceTLS, certs[0].Source)
+	require.Equal(t, "server TLS", certs[0].Role)
+	require.Equal(t, "server", certs[0].Cert.Subject.CommonName)
+
+	_, err = TLSCertificates("client TLS", []byte("garbage"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid client TLS certificate")
+}
+
+func TestMSPCertificates(t *testing.T) {
+	now := time.Now()
+	config := mspConfig(t, &mspproto.FabricMSPConfig{
+		Name:                 "Org1MSP",
+		RootCerts:            [][]byte{newCertificate(t, "ca", now)},
+		IntermediateCerts:    [][]byte{newCertificate(t, "ica", now)},
+		Admins:               [][]byte{newCertificate(t, "admin1", now), newCertificate(t, "admin2", now)},
+		TlsRootCerts:         [][]byte{newCertificate(t, "tlsca", now)},
+		TlsIntermediateCerts: [][]byte{newCertificate(t, "tlsica", now)},
+		SigningIdentity:      &mspproto.SigningIdentityInfo{PublicSigner: newCertificate(t, "peer0", now)},
+	})
+
+	certs, err := MSPCertificates(SourceMSP, "", config)
+	require.NoError(t, err)
+
+	var found []string
+	for _, cert := range certs {
+		require.Equal(t, SourceMSP, cert.Source)
+		require.Equal(t, "Org1MSP", cert.MSPID)
+		found = append(found, cert.Role+"="+cert.Cert.Subject.CommonName)
+	}
+	require.Equal(t, []string{
+		"root CA=ca",
+		"intermediate CA=ica",
+		"admin=admin1",
+		"admin=admin2",
+		"TLS root CA=tlsca",
+		"TLS intermediate CA=tlsica",
+		"enrollment=peer0",
+	}, found)
+
+	t.Run("IdemixMSP", func(t *testing.T) {
+		certs, err := MSPCertificates(SourceMSP, "", &mspproto.MSPConfig{Type: 1, Config: []byte("idemix")})
+		require.NoError(t, err)
+		require.Empty(t, certs)
+	})
+
+	t.Run("BadCertificate", func(t *testing.T) {
+		config := mspConfig(t, &mspproto.FabricMSPConfig{Name: "Org1MSP", Admins: [][]byte{[]byte("garbage")}})
+		_, err := MSPCertificates(SourceMSP, "", config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid admin certificate of MSP Org1MSP")
+	})
+}
+
+func TestChannelCertificates(t *testing.T) {
+	now := time.Now()
+	orgGroup := func(name string) *cb.ConfigGroup {
+		b, err := proto.Marshal(mspConfig(t, &mspproto.FabricMSPConfig{
+			Name:      name,
+			RootCerts: [][]byte{newCertificate(t, name+"-ca", now)},
+		}))
+		require.NoError(t, err)
+		return &cb.ConfigGroup{Values: map[string]*cb.ConfigValue{"MSP": {Value: b}}}
+	}
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				"Application": {Groups: map[string]*cb.ConfigGroup{
+					"Org2": orgGroup("Org2MSP"),
+					"Org1": orgGroup("Org1MSP"),
+				}},
+				"Orderer": {Groups: map[string]*cb.ConfigGroup{
+					"OrdererOrg": orgGroup("OrdererMSP"),
+				}},
+			},
+		},
+	}
+
+	certs, err := ChannelCertificates("mychannel", config)
+	require.NoError(t, err)
+	require.Len(t, certs, 3)
+	for i, mspID := range []string{"Org1MSP", "Org2MSP", "OrdererMSP"} {
+		require.Equal(t, SourceChannel, certs[i].Source)
+		require.Equal(t, "mychannel", certs[i].Channel)
+		require.Equal(t, mspID, certs[i].MSPID)
+		require.Equal(t, "root CA", certs[i].Role)
+	}
+
+	config.ChannelGroup.Groups["Application"].Groups["Org1"].Values["MSP"].Value = []byte("garbage")
+	_, err = ChannelCertificates("mychannel", config)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed unmarshalling MSP config of channel mychannel")
+}
+
+func TestLocalMSPSource(t *testing.T) {
+	mspDir := configtest.GetDevMspDir()
+	enrollment, err := ioutil.ReadFile(filepath.Join(mspDir, "signcerts", "peer.pem"))
+	require.NoError(t, err)
+	signingIdentity, err := proto.Marshal(&mspproto.SerializedIdentity{Mspid: "SampleOrg", IdBytes: enrollment})
+	require.NoError(t, err)
+
+	certs, err := LocalMSPSource(mspDir, "SampleOrg", signingIdentity)()
+	require.NoError(t, err)
+	roles := map[string]int{}
+	for _, cert := range certs {
+		require.Equal(t, SourceMSP, cert.Source)
+		require.Equal(t, "SampleOrg", cert.MSPID)
+		roles[cert.Role]++
+	}
+	require.Equal(t, map[string]int{"root CA": 1, "admin": 1, "TLS root CA": 1, "TLS intermediate CA": 1, "enrollment": 1}, roles)
+
+	_, err = LocalMSPSource(filepath.Join(mspDir, "missing"), "SampleOrg", signingIdentity)()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed loading local MSP")
+}
+
+func TestChannelSource(t *testing.T) {
+	b, err := proto.Marshal(mspConfig(t, &mspproto.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{newCertificate(t, "ca", time.Now())},
+	}))
+	require.NoError(t, err)
+	configs := map[string]*cb.Config{
+		"good": {ChannelGroup: &cb.ConfigGroup{Values: map[string]*cb.ConfigValue{"MSP": {Value: b}}}},
+		"bad":  {ChannelGroup: &cb.ConfigGroup{Values: map[string]*cb.ConfigValue{"MSP": {Value: []byte("garbage")}}}},
+	}
+
+	source := ChannelSource(
+		func() []string { return []string{"bad", "good", "unknown"} },
+		func(channel string) *cb.Config { return configs[channel] },
+	)
+	certs, err := source()
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "good", certs[0].Channel)
+}
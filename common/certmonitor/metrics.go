@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import "github.com/hyperledger/fabric/common/metrics"
+
+var (
+	daysToExpiryOpts = metrics.GaugeOpts{
+		Namespace:    "certificate",
+		Name:         "days_to_expiry",
+		Help:         "The number of days left until the certificate expires, negative once it has expired.",
+		LabelNames:   []string{"source", "channel", "msp", "role", "subject"},
+		StatsdFormat: "%{#fqname}.%{source}.%{channel}.%{msp}.%{role}.%{subject}",
+	}
+
+	expirationAlertsOpts = metrics.CounterOpts{
+		Namespace:    "certificate",
+		Name:         "expiration_alerts",
+		Help:         "The number of certificate expiration alerts raised.",
+		LabelNames:   []string{"source", "threshold"},
+		StatsdFormat: "%{#fqname}.%{source}.%{threshold}",
+	}
+)
+
+// Metrics are the metrics of a Monitor.
+type Metrics struct {
+	DaysToExpiry     metrics.Gauge
+	ExpirationAlerts metrics.Counter
+}
+
+// NewMetrics creates the metrics of a Monitor.
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		DaysToExpiry:     p.NewGauge(daysToExpiryOpts),
+		ExpirationAlerts: p.NewCounter(expirationAlertsOpts),
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+var logger = flogging.MustGetLogger("certmonitor")
+
+const day = 24 * time.Hour
+
+// DefaultThresholds are the number of days before the expiration of a
+// certificate at which alerts are raised, when none are configured.
+var DefaultThresholds = []int{30, 7, 1}
+
+// Alert notifies that a certificate expires within a threshold, or has
+// expired.
+type Alert struct {
+	Source    string    `json:"source"`
+	Channel   string    `json:"channel,omitempty"`
+	MSPID     string    `json:"msp_id,omitempty"`
+	Role      string    `json:"role"`
+	Subject   string    `json:"subject"`
+	Serial    string    `json:"serial"`
+	NotAfter  time.Time `json:"not_after"`
+	DaysLeft  int       `json:"days_left"`
+	Threshold int       `json:"threshold"`
+	Expired   bool      `json:"expired"`
+}
+
+// Alerter delivers alerts.
+type Alerter interface {
+	Alert(alert Alert) error
+}
+
+// Monitor periodically scans the certificates returned by its sources,
+// exports the number of days left until they expire, and raises an alert
+// each time a certificate crosses one of the thresholds. Every threshold is
+// alerted at most once per certificate, and so is the expiration.
+type Monitor struct {
+	Sources    []Source
+	Thresholds []int
+	Alerters   []Alerter
+	Metrics    *Metrics
+	Interval   time.Duration
+	Now        func() time.Time
+
+	mutex   sync.Mutex
+	alerted map[string]int
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New returns a Monitor raising alerts at the given thresholds, in days. The
+// default thresholds are used when none are given.
+func New(thresholds []int, interval time.Duration, m *Metrics, alerters ...Alerter) *Monitor {
+	if len(thresholds) == 0 {
+		thresholds = DefaultThresholds
+	}
+	sorted := append([]int(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	return &Monitor{
+		Thresholds: sorted,
+		Alerters:   alerters,
+		Metrics:    m,
+		Interval:   interval,
+		Now:        time.Now,
+		alerted:    map[string]int{},
+	}
+}
+
+// AddSource adds a source of certificates to the monitor.
+func (m *Monitor) AddSource(s Source) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Sources = append(m.Sources, s)
+}
+
+// Scan checks the certificates of all the sources, and returns the alerts
+// raised.
+func (m *Monitor) Scan() []Alert {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.Now()
+	seen := map[string]struct{}{}
+	var alerts []Alert
+	for _, source := range m.Sources {
+		certs, err := source()
+		if err != nil {
+			logger.Warningf("Failed retrieving certificates to monitor: %s", err)
+			continue
+		}
+		for _, cert := range certs {
+			key := certificateKey(cert)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			if alert, ok := m.check(key, cert, now); ok {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	// forget the certificates that were renewed or removed
+	for key := range m.alerted {
+		if _, ok := seen[key]; !ok {
+			delete(m.alerted, key)
+		}
+	}
+
+	for _, alert := range alerts {
+		m.Metrics.ExpirationAlerts.With("source", alert.Source, "threshold", strconv.Itoa(alert.Threshold)).Add(1)
+		for _, alerter := range m.Alerters {
+			if err := alerter.Alert(alert); err != nil {
+				logger.Warningf("Failed delivering expiration alert of %s certificate %s: %s", alert.Role, alert.Subject, err)
+			}
+		}
+	}
+	return alerts
+}
+
+// check exports the days left until the certificate expires, and returns an
+// alert when the certificate crossed a threshold not alerted yet.
+func (m *Monitor) check(key string, cert Certificate, now time.Time) (Alert, bool) {
+	left := cert.Cert.NotAfter.Sub(now)
+	subject := cert.Cert.Subject.String()
+	m.Metrics.DaysToExpiry.With(
+		"source", cert.Source,
+		"channel", cert.Channel,
+		"msp", cert.MSPID,
+		"role", cert.Role,
+		"subject", subject,
+	).Set(left.Hours() / 24)
+
+	// an expired certificate has crossed the threshold 0
+	threshold := -1
+	if left <= 0 {
+		threshold = 0
+	} else {
+		for _, t := range m.Thresholds {
+			if left <= time.Duration(t)*day {
+				threshold = t
+			}
+		}
+	}
+	if threshold < 0 {
+		return Alert{}, false
+	}
+	if alerted, ok := m.alerted[key]; ok && alerted <= threshold {
+		return Alert{}, false
+	}
+	m.alerted[key] = threshold
+
+	return Alert{
+		Source:    cert.Source,
+		Channel:   cert.Channel,
+		MSPID:     cert.MSPID,
+		Role:      cert.Role,
+		Subject:   subject,
+		Serial:    cert.Cert.SerialNumber.String(),
+		NotAfter:  cert.Cert.NotAfter,
+		DaysLeft:  int(left / day),
+		Threshold: threshold,
+		Expired:   left <= 0,
+	}, true
+}
+
+func certificateKey(cert Certificate) string {
+	hash := sha256.Sum256(cert.Cert.Raw)
+	return cert.Source + "/" + cert.Channel + "/" + cert.MSPID + "/" + cert.Role + "/" + hex.EncodeToString(hash[:])
+}
+
+// Start scans the certificates, and then keeps scanning them at every
+// interval until the monitor is stopped.
+func (m *Monitor) Start() {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	m.Scan()
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Scan()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic scans of a started monitor.
+func (m *Monitor) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
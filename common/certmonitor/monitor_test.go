@@ -0,0 +1,167 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certmonitor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAlerter struct {
+	mutex  sync.Mutex
+	alerts []Alert
+	err    error
+}
+
+func (r *recordingAlerter) Alert(a Alert) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.alerts = append(r.alerts, a)
+	return r.err
+}
+
+func (r *recordingAlerter) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.alerts)
+}
+
+type fakeMetrics struct {
+	daysToExpiry     *metricsfakes.Gauge
+	expirationAlerts *metricsfakes.Counter
+}
+
+func newFakeMetrics() (*fakeMetrics, *Metrics) {
+	f := &fakeMetrics{
+		daysToExpiry:     &metricsfakes.Gauge{},
+		expirationAlerts: &metricsfakes.Counter{},
+	}
+	f.daysToExpiry.WithReturns(f.daysToExpiry)
+	f.expirationAlerts.WithReturns(f.expirationAlerts)
+	return f, &Metrics{DaysToExpiry: f.daysToExpiry, ExpirationAlerts: f.expirationAlerts}
+}
+
+func TestNewSortsThresholds(t *testing.T) {
+	_, m := newFakeMetrics()
+	require.Equal(t, []int{30, 7, 1}, New(nil, time.Hour, m).Thresholds)
+	require.Equal(t, []int{60, 14, 3}, New([]int{3, 60, 14}, time.Hour, m).Thresholds)
+}
+
+func TestScan(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	notAfter := start.Add(40 * day)
+	cert, err := ParseCertificate(newCertificate(t, "peer0", notAfter))
+	require.NoError(t, err)
+
+	fakes, m := newFakeMetrics()
+	alerter := &recordingAlerter{}
+	monitor := New([]int{30, 7, 1}, time.Hour, m, alerter)
+	monitor.AddSource(StaticSource(Certificate{Source: SourceMSP, MSPID: "Org1MSP", Role: "enrollment", Cert: cert}))
+
+	scanAt := func(daysLater int) []Alert {
+		monitor.Now = func() time.Time { return start.Add(time.Duration(daysLater) * day) }
+		return monitor.Scan()
+	}
+
+	require.Empty(t, scanAt(0))
+	require.Equal(t, 1, fakes.daysToExpiry.SetCallCount())
+	require.InDelta(t, 40, fakes.daysToExpiry.SetArgsForCall(0), 0.01)
+	require.Equal(t, []string{"source", "msp", "channel", "", "msp", "Org1MSP", "role", "enrollment", "subject", "CN=peer0"}, fakes.daysToExpiry.WithArgsForCall(0))
+
+	alerts := scanAt(15)
+	require.Len(t, alerts, 1)
+	require.Equal(t, 30, alerts[0].Threshold)
+	require.Equal(t, 25, alerts[0].DaysLeft)
+	require.Equal(t, "CN=peer0", alerts[0].Subject)
+	require.Equal(t, "Org1MSP", alerts[0].MSPID)
+	require.False(t, alerts[0].Expired)
+
+	// every threshold is alerted once
+	require.Empty(t, scanAt(16))
+
+	// thresholds crossed between two scans only raise the lowest one
+	alerts = scanAt(39)
+	require.Len(t, alerts, 1)
+	require.Equal(t, 1, alerts[0].Threshold)
+	require.Empty(t, scanAt(39))
+
+	alerts = scanAt(41)
+	require.Len(t, alerts, 1)
+	require.True(t, alerts[0].Expired)
+	require.Equal(t, 0, alerts[0].Threshold)
+	require.Empty(t, scanAt(42))
+
+	require.Len(t, alerter.alerts, 3)
+	require.Equal(t, 3, fakes.expirationAlerts.AddCallCount())
+	require.Equal(t, []string{"source", "msp", "threshold", "0"}, fakes.expirationAlerts.WithArgsForCall(2))
+}
+
+func TestScanRenewedCertificate(t *testing.T) {
+	start := time.Now()
+	old, err := ParseCertificate(newCertificate(t, "peer0", start.Add(5*day)))
+	require.NoError(t, err)
+	renewed, err := ParseCertificate(newCertificate(t, "peer0", start.Add(400*day)))
+	require.NoError(t, err)
+
+	current := old
+	_, m := newFakeMetrics()
+	monitor := New(nil, time.Hour, m)
+	monitor.Now = func() time.Time { return start }
+	monitor.AddSource(func() ([]Certificate, error) {
+		return []Certificate{{Source: SourceTLS, Role: "server TLS", Cert: current}}, nil
+	})
+
+	require.Len(t, monitor.Scan(), 1)
+	require.Len(t, monitor.alerted, 1)
+
+	current = renewed
+	require.Empty(t, monitor.Scan())
+	require.Empty(t, monitor.alerted)
+}
+
+func TestScanFailures(t *testing.T) {
+	cert, err := ParseCertificate(newCertificate(t, "peer0", time.Now().Add(-day)))
+	require.NoError(t, err)
+
+	_, m := newFakeMetrics()
+	failing := &recordingAlerter{err: errors.New("unreachable")}
+	working := &recordingAlerter{}
+	monitor := New(nil, time.Hour, m, failing, working)
+	monitor.AddSource(func() ([]Certificate, error) { return nil, errors.New("no channel config") })
+	monitor.AddSource(StaticSource(
+		Certificate{Source: SourceTLS, Role: "server TLS", Cert: cert},
+		Certificate{Source: SourceTLS, Role: "server TLS", Cert: cert},
+	))
+
+	// a failed source or alerter does not prevent the others, and duplicate
+	// certificates are alerted once
+	require.Len(t, monitor.Scan(), 1)
+	require.Equal(t, 1, failing.count())
+	require.Equal(t, 1, working.count())
+}
+
+func TestStartStop(t *testing.T) {
+	cert, err := ParseCertificate(newCertificate(t, "peer0", time.Now().Add(-day)))
+	require.NoError(t, err)
+
+	fakes, m := newFakeMetrics()
+	alerter := &recordingAlerter{}
+	monitor := New(nil, 10*time.Millisecond, m, alerter)
+	monitor.AddSource(StaticSource(Certificate{Source: SourceTLS, Role: "server TLS", Cert: cert}))
+
+	// the first scan is done before Start returns
+	monitor.Start()
+	require.Equal(t, 1, alerter.count())
+	require.Eventually(t, func() bool { return fakes.daysToExpiry.SetCallCount() > 2 }, 5*time.Second, 10*time.Millisecond)
+	monitor.Stop()
+	require.Equal(t, 1, alerter.count())
+}
@@ -7,11 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package channelconfig
 
 import (
+	"crypto/x509"
 	"time"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/common/configtx"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/msp"
@@ -55,6 +57,10 @@ type Application interface {
 
 	// Capabilities defines the capabilities for the application portion of a channel
 	Capabilities() ApplicationCapabilities
+
+	// PolicyTemplates returns a map of template name to the policy template that
+	// the endorsement policies of chaincode definitions may reference
+	PolicyTemplates() map[string]string
 }
 
 // Channel gives read only access to the channel configuration
@@ -135,6 +141,14 @@ type ChannelCapabilities interface {
 
 	// OrgSpecificOrdererEndpoints return true if the channel config processing allows orderer orgs to specify their own endpoints
 	OrgSpecificOrdererEndpoints() bool
+
+	// SignatureAlgorithms returns the algorithms of the keys of the X.509 identities allowed in the channel,
+	// or nil if the identities with keys of any algorithm but Ed25519 are allowed.
+	SignatureAlgorithms() []x509.PublicKeyAlgorithm
+
+	// LowSPolicy returns the policy applied to the high-S ECDSA signatures verified by the MSPs of the
+	// channel, or "" if the policy of the crypto provider applies.
+	LowSPolicy() utils.LowSPolicy
 }
 
 // ApplicationCapabilities defines the capabilities for the application portion of a channel
@@ -194,6 +208,9 @@ type ApplicationCapabilities interface {
 	// KeyLevelEndorsement returns true if this channel supports endorsement
 	// policies expressible at a ledger key granularity, as described in FAB-8812
 	KeyLevelEndorsement() bool
+
+	// PolicyTemplates returns whether policy templates may be specified in the channel application config
+	PolicyTemplates() bool
 }
 
 // OrdererCapabilities defines the capabilities for the orderer portion of a channel
@@ -7,9 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package channelconfig
 
 import (
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/capabilities"
+	"github.com/hyperledger/fabric/common/policydsl"
 	"github.com/pkg/errors"
 )
 
@@ -19,12 +21,16 @@ const (
 
 	// ACLsKey is the name of the ACLs config
 	ACLsKey = "ACLs"
+
+	// PolicyTemplatesKey is the name of the policy templates config
+	PolicyTemplatesKey = "PolicyTemplates"
 )
 
 // ApplicationProtos is used as the source of the ApplicationConfig
 type ApplicationProtos struct {
-	ACLs         *pb.ACLs
-	Capabilities *cb.Capabilities
+	ACLs            *pb.ACLs
+	Capabilities    *cb.Capabilities
+	PolicyTemplates *structpb.Struct
 }
 
 // ApplicationConfig implements the Application interface
@@ -50,6 +56,15 @@ func NewApplicationConfig(appGroup *cb.ConfigGroup, mspConfig *MSPConfigHandler)
 		}
 	}
 
+	if _, ok := appGroup.Values[PolicyTemplatesKey]; ok {
+		if !ac.Capabilities().LifecycleV20() || !ac.Capabilities().PolicyTemplates() {
+			return nil, errors.New("PolicyTemplates may not be specified without the required capability")
+		}
+		if err := validatePolicyTemplates(ac.protos.PolicyTemplates); err != nil {
+			return nil, err
+		}
+	}
+
 	var err error
 	for orgName, orgGroup := range appGroup.Groups {
 		ac.applicationOrgs[orgName], err = NewApplicationOrgConfig(orgName, orgGroup, mspConfig)
@@ -71,6 +86,28 @@ func (ac *ApplicationConfig) Capabilities() ApplicationCapabilities {
 	return capabilities.NewApplicationProvider(ac.protos.Capabilities.Capabilities)
 }
 
+// PolicyTemplates returns a map of template name to the policy template that
+// the endorsement policies of chaincode definitions may reference
+func (ac *ApplicationConfig) PolicyTemplates() map[string]string {
+	templates := map[string]string{}
+	for name, value := range ac.protos.PolicyTemplates.GetFields() {
+		templates[name] = value.GetStringValue()
+	}
+	return templates
+}
+
+func validatePolicyTemplates(templates *structpb.Struct) error {
+	for name, value := range templates.GetFields() {
+		if err := policydsl.ValidateTemplateName(name); err != nil {
+			return err
+		}
+		if _, ok := value.GetKind().(*structpb.Value_StringValue); !ok || value.GetStringValue() == "" {
+			return errors.Errorf("policy template '%s' must be a non-empty string", name)
+		}
+	}
+	return nil
+}
+
 // APIPolicyMapper returns a PolicyMapper that maps API names to policies
 func (ac *ApplicationConfig) APIPolicyMapper() PolicyMapper {
 	pm := newAPIsProvider(ac.protos.ACLs.Acls)
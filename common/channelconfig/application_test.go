@@ -52,3 +52,74 @@ func TestACL(t *testing.T) {
 		g.Expect(err).To(MatchError("ACLs may not be specified without the required capability"))
 	})
 }
+
+func TestPolicyTemplates(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cgt := &cb.ConfigGroup{
+		Values: map[string]*cb.ConfigValue{
+			PolicyTemplatesKey: {
+				Value: protoutil.MarshalOrPanic(
+					PolicyTemplatesValue(map[string]string{
+						"MAJORITY": "OutOf($# / 2 + 1, $*)",
+					}).Value(),
+				),
+			},
+			CapabilitiesKey: {
+				Value: protoutil.MarshalOrPanic(
+					CapabilitiesValue(map[string]bool{
+						capabilities.ApplicationV2_0:            true,
+						capabilities.ApplicationPolicyTemplates: true,
+					}).Value(),
+				),
+			},
+		},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ac, err := NewApplicationConfig(proto.Clone(cgt).(*cb.ConfigGroup), nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(ac.PolicyTemplates()).To(Equal(map[string]string{"MAJORITY": "OutOf($# / 2 + 1, $*)"}))
+	})
+
+	t.Run("NoTemplates", func(t *testing.T) {
+		cg := proto.Clone(cgt).(*cb.ConfigGroup)
+		delete(cg.Values, PolicyTemplatesKey)
+		ac, err := NewApplicationConfig(cg, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(ac.PolicyTemplates()).To(BeEmpty())
+	})
+
+	t.Run("MissingCapability", func(t *testing.T) {
+		cg := proto.Clone(cgt).(*cb.ConfigGroup)
+		delete(cg.Values, CapabilitiesKey)
+		_, err := NewApplicationConfig(cg, nil)
+		g.Expect(err).To(MatchError("PolicyTemplates may not be specified without the required capability"))
+	})
+
+	t.Run("MissingPolicyTemplatesCapability", func(t *testing.T) {
+		cg := proto.Clone(cgt).(*cb.ConfigGroup)
+		cg.Values[CapabilitiesKey].Value = protoutil.MarshalOrPanic(
+			CapabilitiesValue(map[string]bool{capabilities.ApplicationV2_0: true}).Value(),
+		)
+		_, err := NewApplicationConfig(cg, nil)
+		g.Expect(err).To(MatchError("PolicyTemplates may not be specified without the required capability"))
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		cg := proto.Clone(cgt).(*cb.ConfigGroup)
+		cg.Values[PolicyTemplatesKey].Value = protoutil.MarshalOrPanic(
+			PolicyTemplatesValue(map[string]string{"two regions": "AND($1, $2)"}).Value(),
+		)
+		_, err := NewApplicationConfig(cg, nil)
+		g.Expect(err).To(MatchError("invalid policy template name 'two regions'. Names can only consist of alphanumerics and '_', and must begin with a letter"))
+	})
+
+	t.Run("EmptyTemplate", func(t *testing.T) {
+		cg := proto.Clone(cgt).(*cb.ConfigGroup)
+		cg.Values[PolicyTemplatesKey].Value = protoutil.MarshalOrPanic(
+			PolicyTemplatesValue(map[string]string{"MAJORITY": ""}).Value(),
+		)
+		_, err := NewApplicationConfig(cg, nil)
+		g.Expect(err).To(MatchError("policy template 'MAJORITY' must be a non-empty string"))
+	})
+}
@@ -93,6 +93,8 @@ func NewChannelConfig(channelGroup *cb.ConfigGroup, bccsp bccsp.BCCSP) (*Channel
 	}
 
 	mspConfigHandler := NewMSPConfigHandler(capabilities.MSPVersion(), bccsp)
+	mspConfigHandler.signatureAlgorithms = capabilities.SignatureAlgorithms()
+	mspConfigHandler.lowS = capabilities.LowSPolicy()
 
 	var err error
 	for groupName, group := range channelGroup.Groups {
@@ -7,11 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package channelconfig
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 
 	"github.com/golang/protobuf/proto"
 	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/lows"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/capabilities"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/msp/cache"
 	"github.com/pkg/errors"
@@ -27,6 +32,13 @@ type MSPConfigHandler struct {
 	version msp.MSPVersion
 	idMap   map[string]*pendingMSPConfig
 	bccsp   bccsp.BCCSP
+	// signatureAlgorithms restricts the X.509 identities of the channel
+	// to those whose keys are of these algorithms. When it is not set,
+	// the identities with Ed25519 keys are rejected.
+	signatureAlgorithms []x509.PublicKeyAlgorithm
+	// lowS is the policy applied to the high-S ECDSA signatures verified
+	// by the X.509 identities of the channel, when set
+	lowS utils.LowSPolicy
 }
 
 func NewMSPConfigHandler(mspVersion msp.MSPVersion, bccsp bccsp.BCCSP) *MSPConfigHandler {
@@ -44,15 +56,25 @@ func (bh *MSPConfigHandler) ProposeMSP(mspConfig *mspprotos.MSPConfig) (msp.MSP,
 
 	switch mspConfig.Type {
 	case int32(msp.FABRIC):
+		cryptoProvider := bh.bccsp
+		if bh.lowS != "" {
+			cryptoProvider, err = lows.New(bh.bccsp, bh.lowS)
+			if err != nil {
+				return nil, errors.WithMessage(err, "applying the low-S policy of the channel failed")
+			}
+		}
+
 		// create the bccsp msp instance
 		mspInst, err := msp.New(
 			&msp.BCCSPNewOpts{NewBaseOpts: msp.NewBaseOpts{Version: bh.version}},
-			bh.bccsp,
+			cryptoProvider,
 		)
 		if err != nil {
 			return nil, errors.WithMessage(err, "creating the MSP manager failed")
 		}
 
+		mspInst = &signatureAlgorithmsMSP{MSP: mspInst, allowed: bh.signatureAlgorithms}
+
 		// add a cache layer on top
 		theMsp, err = cache.New(mspInst)
 		if err != nil {
@@ -107,3 +129,57 @@ func (bh *MSPConfigHandler) CreateMSPManager() (msp.MSPManager, error) {
 	err := manager.Setup(mspList)
 	return manager, err
 }
+
+// signatureAlgorithmsMSP is an X.509 MSP which only accepts the identities
+// whose keys are of one of the algorithms allowed in the channel. When no
+// algorithm is allowed explicitly, it accepts the identities of any algorithm
+// but Ed25519, which the peers and orderers predating the signature algorithm
+// capabilities reject.
+type signatureAlgorithmsMSP struct {
+	msp.MSP
+	allowed []x509.PublicKeyAlgorithm
+}
+
+func (m *signatureAlgorithmsMSP) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
+	sID := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sID); err != nil {
+		return nil, errors.Wrap(err, "could not deserialize a SerializedIdentity")
+	}
+	if err := m.checkAlgorithm(sID.IdBytes); err != nil {
+		return nil, err
+	}
+	return m.MSP.DeserializeIdentity(serializedIdentity)
+}
+
+func (m *signatureAlgorithmsMSP) IsWellFormed(identity *mspprotos.SerializedIdentity) error {
+	if err := m.checkAlgorithm(identity.IdBytes); err != nil {
+		return err
+	}
+	return m.MSP.IsWellFormed(identity)
+}
+
+func (m *signatureAlgorithmsMSP) checkAlgorithm(idBytes []byte) error {
+	// malformed identities are left to the MSP to reject
+	block, _ := pem.Decode(idBytes)
+	if block == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	if len(m.allowed) == 0 {
+		if cert.PublicKeyAlgorithm == x509.Ed25519 {
+			return errors.Errorf("identity %s has an %s key, which is not allowed in the channel without the %s capability", cert.Subject, cert.PublicKeyAlgorithm, capabilities.ChannelSignatureAlgorithmEd25519)
+		}
+		return nil
+	}
+
+	for _, algorithm := range m.allowed {
+		if cert.PublicKeyAlgorithm == algorithm {
+			return nil
+		}
+	}
+	return errors.Errorf("identity %s has an %s key, which is not allowed in the channel", cert.Subject, cert.PublicKeyAlgorithm)
+}
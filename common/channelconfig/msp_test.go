@@ -7,11 +7,22 @@ SPDX-License-Identifier: Apache-2.0
 package channelconfig
 
 import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/bccsp/factory"
 	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/stretchr/testify/assert"
@@ -65,3 +76,112 @@ func TestMSPConfigFailure(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestMSPConfigSignatureAlgorithms(t *testing.T) {
+	mspDir := configtest.GetDevMspDir()
+	conf, err := msp.GetLocalMspConfig(mspDir, nil, "SampleOrg")
+	assert.NoError(t, err)
+	certPEM, err := ioutil.ReadFile(filepath.Join(mspDir, "signcerts", "peer.pem"))
+	assert.NoError(t, err)
+	sID := &mspprotos.SerializedIdentity{Mspid: "SampleOrg", IdBytes: certPEM}
+	serialized, err := proto.Marshal(sID)
+	assert.NoError(t, err)
+
+	newManager := func(algorithms ...x509.PublicKeyAlgorithm) msp.MSPManager {
+		mspCH := NewMSPConfigHandler(msp.MSPv1_0, factory.GetDefault())
+		mspCH.signatureAlgorithms = algorithms
+		_, err := mspCH.ProposeMSP(conf)
+		assert.NoError(t, err)
+		mgr, err := mspCH.CreateMSPManager()
+		assert.NoError(t, err)
+		return mgr
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		mgr := newManager(x509.ECDSA, x509.Ed25519)
+		_, err := mgr.DeserializeIdentity(serialized)
+		assert.NoError(t, err)
+		assert.NoError(t, mgr.IsWellFormed(sID))
+	})
+
+	t.Run("Not allowed", func(t *testing.T) {
+		mgr := newManager(x509.Ed25519)
+		_, err := mgr.DeserializeIdentity(serialized)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "has an ECDSA key, which is not allowed in the channel")
+		assert.Error(t, mgr.IsWellFormed(sID))
+	})
+
+	t.Run("No capability", func(t *testing.T) {
+		mgr := newManager()
+		_, err := mgr.DeserializeIdentity(serialized)
+		assert.NoError(t, err)
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ed25519"}}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+		assert.NoError(t, err)
+		edID := &mspprotos.SerializedIdentity{Mspid: "SampleOrg", IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+		edSerialized, err := proto.Marshal(edID)
+		assert.NoError(t, err)
+		_, err = mgr.DeserializeIdentity(edSerialized)
+		assert.EqualError(t, err, "identity CN=ed25519 has an Ed25519 key, which is not allowed in the channel without the SignatureAlgorithm_Ed25519 capability")
+		assert.Error(t, mgr.IsWellFormed(edID))
+	})
+}
+
+func TestMSPConfigLowSPolicy(t *testing.T) {
+	mspDir := configtest.GetDevMspDir()
+	conf, err := msp.GetLocalMspConfig(mspDir, nil, "SampleOrg")
+	assert.NoError(t, err)
+	localMSP, err := msp.New(&msp.BCCSPNewOpts{NewBaseOpts: msp.NewBaseOpts{Version: msp.MSPv1_0}}, factory.GetDefault())
+	assert.NoError(t, err)
+	assert.NoError(t, localMSP.Setup(conf))
+	signer, err := localMSP.GetDefaultSigningIdentity()
+	assert.NoError(t, err)
+	serialized, err := signer.Serialize()
+	assert.NoError(t, err)
+
+	msg := []byte("message")
+	sig, err := signer.Sign(msg)
+	assert.NoError(t, err)
+	r, s, err := utils.UnmarshalECDSASignature(sig)
+	assert.NoError(t, err)
+	highSig, err := utils.MarshalECDSASignature(r, new(big.Int).Sub(elliptic.P256().Params().N, s))
+	assert.NoError(t, err)
+
+	newIdentity := func(policy utils.LowSPolicy) msp.Identity {
+		mspCH := NewMSPConfigHandler(msp.MSPv1_0, factory.GetDefault())
+		mspCH.lowS = policy
+		_, err := mspCH.ProposeMSP(conf)
+		assert.NoError(t, err)
+		mgr, err := mspCH.CreateMSPManager()
+		assert.NoError(t, err)
+		id, err := mgr.DeserializeIdentity(serialized)
+		assert.NoError(t, err)
+		assert.NoError(t, id.Verify(msg, sig))
+		return id
+	}
+
+	t.Run("Provider", func(t *testing.T) {
+		err := newIdentity("").Verify(msg, highSig)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order")
+	})
+
+	t.Run("Normalize", func(t *testing.T) {
+		assert.NoError(t, newIdentity(utils.LowSNormalize).Verify(msg, highSig))
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		assert.EqualError(t, newIdentity(utils.LowSReject).Verify(msg, highSig), "The signature is invalid")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		mspCH := NewMSPConfigHandler(msp.MSPv1_0, factory.GetDefault())
+		mspCH.lowS = "ignore"
+		_, err := mspCH.ProposeMSP(conf)
+		assert.EqualError(t, err, "applying the low-S policy of the channel failed: invalid low-S policy ignore: must be enforce, normalize or reject")
+	})
+}
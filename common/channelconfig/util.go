@@ -12,6 +12,7 @@ import (
 	"math"
 
 	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
@@ -239,6 +240,24 @@ func ACLValues(acls map[string]string) *StandardConfigValue {
 	}
 }
 
+// PolicyTemplatesValue returns the config definition for the policy templates that the
+// endorsement policies of chaincode definitions may reference.
+// It is a value for the /Channel/Application/.
+func PolicyTemplatesValue(templates map[string]string) *StandardConfigValue {
+	pt := &structpb.Struct{
+		Fields: make(map[string]*structpb.Value),
+	}
+
+	for name, template := range templates {
+		pt.Fields[name] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: template}}
+	}
+
+	return &StandardConfigValue{
+		key:   PolicyTemplatesKey,
+		value: pt,
+	}
+}
+
 // ValidateCapabilities validates whether the peer can meet the capabilities requirement in the given config block
 func ValidateCapabilities(block *cb.Block, bccsp bccsp.BCCSP) error {
 	envelopeConfig, err := protoutil.ExtractEnvelope(block, 0)
@@ -51,6 +51,7 @@ func TestUtilsBasic(t *testing.T) {
 	basicTest(t, AnchorPeersValue([]*pb.AnchorPeer{{}, {}}))
 	basicTest(t, ChannelCreationPolicyValue(&cb.Policy{}))
 	basicTest(t, ACLValues(map[string]string{"foo": "fooval", "bar": "barval"}))
+	basicTest(t, PolicyTemplatesValue(map[string]string{"foo": "AND($1, $2)"}))
 }
 
 // createCfgBlockWithSupportedCapabilities will create a config block that contains valid capabilities and should be accepted by the peer
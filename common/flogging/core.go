@@ -44,6 +44,9 @@ type EncodingSelector interface {
 // implementations. The core also references the logging configuration to
 // determine the proper encoding to use, the writer to delegate to, and the
 // enabled levels.
+//
+// When a Router is provided, the encoded records are written to the sinks it
+// selects for the logger instead of the Output.
 type Core struct {
 	zapcore.LevelEnabler
 	Levels   *LoggerLevels
@@ -51,6 +54,10 @@ type Core struct {
 	Selector EncodingSelector
 	Output   zapcore.WriteSyncer
 	Observer Observer
+	Router   SinkRouter
+
+	// fields are the fields added to a core with a router, handed to the sinks
+	fields []zapcore.Field
 }
 
 //go:generate counterfeiter -o mock/observer.go -fake-name Observer . Observer
@@ -68,14 +75,19 @@ func (c *Core) With(fields []zapcore.Field) zapcore.Core {
 		clones[name] = clone
 	}
 
-	return &Core{
+	clone := &Core{
 		LevelEnabler: c.LevelEnabler,
 		Levels:       c.Levels,
 		Encoders:     clones,
 		Selector:     c.Selector,
 		Output:       c.Output,
 		Observer:     c.Observer,
+		Router:       c.Router,
+	}
+	if c.Router != nil {
+		clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
 	}
+	return clone
 }
 
 func (c *Core) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
@@ -97,7 +109,11 @@ func (c *Core) Write(e zapcore.Entry, fields []zapcore.Field) error {
 	if err != nil {
 		return err
 	}
-	_, err = c.Output.Write(buf.Bytes())
+	if c.Router != nil {
+		err = c.writeSinks(e, fields, buf.Bytes())
+	} else {
+		_, err = c.Output.Write(buf.Bytes())
+	}
 	buf.Free()
 	if err != nil {
 		return err
@@ -114,6 +130,20 @@ func (c *Core) Write(e zapcore.Entry, fields []zapcore.Field) error {
 	return nil
 }
 
+func (c *Core) writeSinks(e zapcore.Entry, fields []zapcore.Field, record []byte) error {
+	if len(c.fields) > 0 {
+		fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+
+	var firstErr error
+	for _, sink := range c.Router.Route(e.LoggerName) {
+		if err := sink.WriteEntry(e, fields, record); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 func (c *Core) Sync() error {
 	return c.Output.Sync()
 }
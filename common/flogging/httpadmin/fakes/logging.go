@@ -19,6 +19,38 @@ type Logging struct {
 	activateSpecReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ConfigureSinksStub        func(map[string]string, string) error
+	configureSinksMutex       sync.RWMutex
+	configureSinksArgsForCall []struct {
+		arg1 map[string]string
+		arg2 string
+	}
+	configureSinksReturns struct {
+		result1 error
+	}
+	configureSinksReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RoutesStub        func() string
+	routesMutex       sync.RWMutex
+	routesArgsForCall []struct {
+	}
+	routesReturns struct {
+		result1 string
+	}
+	routesReturnsOnCall map[int]struct {
+		result1 string
+	}
+	SinksStub        func() map[string]string
+	sinksMutex       sync.RWMutex
+	sinksArgsForCall []struct {
+	}
+	sinksReturns struct {
+		result1 map[string]string
+	}
+	sinksReturnsOnCall map[int]struct {
+		result1 map[string]string
+	}
 	SpecStub        func() string
 	specMutex       sync.RWMutex
 	specArgsForCall []struct {
@@ -93,6 +125,171 @@ func (fake *Logging) ActivateSpecReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *Logging) ConfigureSinks(arg1 map[string]string, arg2 string) error {
+	fake.configureSinksMutex.Lock()
+	ret, specificReturn := fake.configureSinksReturnsOnCall[len(fake.configureSinksArgsForCall)]
+	fake.configureSinksArgsForCall = append(fake.configureSinksArgsForCall, struct {
+		arg1 map[string]string
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ConfigureSinks", []interface{}{arg1, arg2})
+	fake.configureSinksMutex.Unlock()
+	if fake.ConfigureSinksStub != nil {
+		return fake.ConfigureSinksStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.configureSinksReturns
+	return fakeReturns.result1
+}
+
+func (fake *Logging) ConfigureSinksCallCount() int {
+	fake.configureSinksMutex.RLock()
+	defer fake.configureSinksMutex.RUnlock()
+	return len(fake.configureSinksArgsForCall)
+}
+
+func (fake *Logging) ConfigureSinksCalls(stub func(map[string]string, string) error) {
+	fake.configureSinksMutex.Lock()
+	defer fake.configureSinksMutex.Unlock()
+	fake.ConfigureSinksStub = stub
+}
+
+func (fake *Logging) ConfigureSinksArgsForCall(i int) (map[string]string, string) {
+	fake.configureSinksMutex.RLock()
+	defer fake.configureSinksMutex.RUnlock()
+	argsForCall := fake.configureSinksArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *Logging) ConfigureSinksReturns(result1 error) {
+	fake.configureSinksMutex.Lock()
+	defer fake.configureSinksMutex.Unlock()
+	fake.ConfigureSinksStub = nil
+	fake.configureSinksReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *Logging) ConfigureSinksReturnsOnCall(i int, result1 error) {
+	fake.configureSinksMutex.Lock()
+	defer fake.configureSinksMutex.Unlock()
+	fake.ConfigureSinksStub = nil
+	if fake.configureSinksReturnsOnCall == nil {
+		fake.configureSinksReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.configureSinksReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *Logging) Routes() string {
+	fake.routesMutex.Lock()
+	ret, specificReturn := fake.routesReturnsOnCall[len(fake.routesArgsForCall)]
+	fake.routesArgsForCall = append(fake.routesArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Routes", []interface{}{})
+	fake.routesMutex.Unlock()
+	if fake.RoutesStub != nil {
+		return fake.RoutesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.routesReturns
+	return fakeReturns.result1
+}
+
+func (fake *Logging) RoutesCallCount() int {
+	fake.routesMutex.RLock()
+	defer fake.routesMutex.RUnlock()
+	return len(fake.routesArgsForCall)
+}
+
+func (fake *Logging) RoutesCalls(stub func() string) {
+	fake.routesMutex.Lock()
+	defer fake.routesMutex.Unlock()
+	fake.RoutesStub = stub
+}
+
+func (fake *Logging) RoutesReturns(result1 string) {
+	fake.routesMutex.Lock()
+	defer fake.routesMutex.Unlock()
+	fake.RoutesStub = nil
+	fake.routesReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *Logging) RoutesReturnsOnCall(i int, result1 string) {
+	fake.routesMutex.Lock()
+	defer fake.routesMutex.Unlock()
+	fake.RoutesStub = nil
+	if fake.routesReturnsOnCall == nil {
+		fake.routesReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.routesReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *Logging) Sinks() map[string]string {
+	fake.sinksMutex.Lock()
+	ret, specificReturn := fake.sinksReturnsOnCall[len(fake.sinksArgsForCall)]
+	fake.sinksArgsForCall = append(fake.sinksArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Sinks", []interface{}{})
+	fake.sinksMutex.Unlock()
+	if fake.SinksStub != nil {
+		return fake.SinksStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.sinksReturns
+	return fakeReturns.result1
+}
+
+func (fake *Logging) SinksCallCount() int {
+	fake.sinksMutex.RLock()
+	defer fake.sinksMutex.RUnlock()
+	return len(fake.sinksArgsForCall)
+}
+
+func (fake *Logging) SinksCalls(stub func() map[string]string) {
+	fake.sinksMutex.Lock()
+	defer fake.sinksMutex.Unlock()
+	fake.SinksStub = stub
+}
+
+func (fake *Logging) SinksReturns(result1 map[string]string) {
+	fake.sinksMutex.Lock()
+	defer fake.sinksMutex.Unlock()
+	fake.SinksStub = nil
+	fake.sinksReturns = struct {
+		result1 map[string]string
+	}{result1}
+}
+
+func (fake *Logging) SinksReturnsOnCall(i int, result1 map[string]string) {
+	fake.sinksMutex.Lock()
+	defer fake.sinksMutex.Unlock()
+	fake.SinksStub = nil
+	if fake.sinksReturnsOnCall == nil {
+		fake.sinksReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+		})
+	}
+	fake.sinksReturnsOnCall[i] = struct {
+		result1 map[string]string
+	}{result1}
+}
+
 func (fake *Logging) Spec() string {
 	fake.specMutex.Lock()
 	ret, specificReturn := fake.specReturnsOnCall[len(fake.specArgsForCall)]
@@ -150,6 +347,12 @@ func (fake *Logging) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.activateSpecMutex.RLock()
 	defer fake.activateSpecMutex.RUnlock()
+	fake.configureSinksMutex.RLock()
+	defer fake.configureSinksMutex.RUnlock()
+	fake.routesMutex.RLock()
+	defer fake.routesMutex.RUnlock()
+	fake.sinksMutex.RLock()
+	defer fake.sinksMutex.RUnlock()
 	fake.specMutex.RLock()
 	defer fake.specMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -19,10 +19,18 @@ import (
 type Logging interface {
 	ActivateSpec(spec string) error
 	Spec() string
+	ConfigureSinks(sinks map[string]string, routes string) error
+	Sinks() map[string]string
+	Routes() string
 }
 
+// LogSpec is the logging configuration read and updated through the handler.
+// The sinks and the routes are only changed when at least one of them is
+// provided; the one that is missing is left unchanged.
 type LogSpec struct {
-	Spec string `json:"spec,omitempty"`
+	Spec   string            `json:"spec,omitempty"`
+	Sinks  map[string]string `json:"sinks,omitempty"`
+	Routes string            `json:"routes,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -52,14 +60,18 @@ func (h *SpecHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		}
 		req.Body.Close()
 
-		if err := h.Logging.ActivateSpec(logSpec.Spec); err != nil {
+		if err := h.update(&logSpec); err != nil {
 			h.sendResponse(resp, http.StatusBadRequest, err)
 			return
 		}
 		resp.WriteHeader(http.StatusNoContent)
 
 	case http.MethodGet:
-		h.sendResponse(resp, http.StatusOK, &LogSpec{Spec: h.Logging.Spec()})
+		h.sendResponse(resp, http.StatusOK, &LogSpec{
+			Spec:   h.Logging.Spec(),
+			Sinks:  flogging.RedactSinks(h.Logging.Sinks()),
+			Routes: h.Logging.Routes(),
+		})
 
 	default:
 		err := fmt.Errorf("invalid request method: %s", req.Method)
@@ -67,6 +79,45 @@ func (h *SpecHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (h *SpecHandler) update(logSpec *LogSpec) error {
+	updateSinks := logSpec.Sinks != nil || logSpec.Routes != ""
+	if !updateSinks {
+		return h.Logging.ActivateSpec(logSpec.Spec)
+	}
+
+	// the spec is checked before the sinks change so that a bad request
+	// leaves the logging configuration unchanged
+	if logSpec.Spec != "" {
+		if err := (&flogging.LoggerLevels{}).ActivateSpec(logSpec.Spec); err != nil {
+			return err
+		}
+	}
+
+	current := h.Logging.Sinks()
+	sinks, routes := logSpec.Sinks, logSpec.Routes
+	if sinks == nil {
+		sinks = current
+	}
+	// sinks sent back as they were read keep their hidden passwords
+	redacted := flogging.RedactSinks(current)
+	for name, url := range sinks {
+		if url == redacted[name] {
+			sinks[name] = current[name]
+		}
+	}
+	if routes == "" {
+		routes = h.Logging.Routes()
+	}
+	if err := h.Logging.ConfigureSinks(sinks, routes); err != nil {
+		return err
+	}
+
+	if logSpec.Spec != "" {
+		return h.Logging.ActivateSpec(logSpec.Spec)
+	}
+	return nil
+}
+
 func (h *SpecHandler) sendResponse(resp http.ResponseWriter, code int, payload interface{}) {
 	encoder := json.NewEncoder(resp)
 	if err, ok := payload.(error); ok {
@@ -13,6 +13,7 @@ import (
 	"sync"
 
 	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/pkg/errors"
 	zaplogfmt "github.com/sykesm/zap-logfmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -39,6 +40,22 @@ type Config struct {
 	//
 	// If a Writer is not provided, os.Stderr will be used as the log sink.
 	Writer io.Writer
+
+	// Sinks names additional sinks records can be routed to, and maps them to
+	// URLs that can be processed by NewSink. The default sink, which writes to
+	// the Writer, is always defined.
+	//
+	// If Sinks is not provided, the sinks are read from the FABRIC_LOGGING_SINKS
+	// environment variable, a list of <name>=<url> separated by white space.
+	Sinks map[string]string
+
+	// Routes determines the sinks the records of the loggers are written to.
+	// The routes must be in a format that can be processed by ConfigureSinks.
+	//
+	// If Routes is not provided, the routes are read from the
+	// FABRIC_LOGGING_ROUTES environment variable, and all records are written
+	// to the default sink when it is not set.
+	Routes string
 }
 
 // Logging maintains the state associated with the fabric logging system. It is
@@ -53,6 +70,7 @@ type Logging struct {
 	multiFormatter *fabenc.MultiFormatter
 	writer         zapcore.WriteSyncer
 	observer       Observer
+	routes         *sinkRoutes
 }
 
 // New creates a new logging system and initializes it with the provided
@@ -68,6 +86,11 @@ func New(c Config) (*Logging, error) {
 		encoderConfig:  encoderConfig,
 		multiFormatter: fabenc.NewMultiFormatter(),
 	}
+	l.routes = &sinkRoutes{
+		routeCache:   map[string][]Sink{},
+		defaultRoute: []string{DefaultSink},
+		sinks:        map[string]Sink{DefaultSink: &loggingSink{logging: l}},
+	}
 
 	err := l.Apply(c)
 	if err != nil {
@@ -100,9 +123,119 @@ func (l *Logging) Apply(c Config) error {
 	}
 	l.SetWriter(c.Writer)
 
+	if c.Sinks == nil {
+		c.Sinks, err = parseSinks(os.Getenv("FABRIC_LOGGING_SINKS"))
+		if err != nil {
+			return err
+		}
+	}
+	if c.Routes == "" {
+		c.Routes = os.Getenv("FABRIC_LOGGING_ROUTES")
+	}
+
+	return l.ConfigureSinks(c.Sinks, c.Routes)
+}
+
+// ConfigureSinks replaces the named sinks and the routes of the log records
+// to the sinks. Sinks whose URL is unchanged are kept open, and sinks that
+// are no longer defined are closed. The sinks and the routes are left
+// unchanged when an error is returned.
+//
+// The routing specification has the following form:
+//   [<logger>[,<logger>...]=]<sink>[+<sink>...][:[<logger>[,<logger>...]=]<sink>[+<sink>...]...]
+//
+// As with logging specifications, the route of a logger applies to its
+// descendants, unless the logger name ends with a period.
+func (l *Logging) ConfigureSinks(sinks map[string]string, routes string) error {
+	specs, defaultRoute, err := parseRoutes(routes)
+	if err != nil {
+		return err
+	}
+	for name := range sinks {
+		if name == DefaultSink || !sinkNameRegexp.MatchString(name) {
+			return errors.Errorf("invalid sink name '%s'", name)
+		}
+	}
+	for _, route := range append([][]string{defaultRoute}, routeValues(specs)...) {
+		for _, name := range route {
+			if _, ok := sinks[name]; !ok && name != DefaultSink {
+				return errors.Errorf("invalid routing specification '%s': undefined sink '%s'", routes, name)
+			}
+		}
+	}
+
+	r := l.routes
+	r.configMutex.Lock()
+	defer r.configMutex.Unlock()
+
+	r.mutex.RLock()
+	current := r.sinks
+	currentURLs := r.urls
+	r.mutex.RUnlock()
+
+	// the sinks that are new or changed are opened before anything is replaced
+	newSinks := map[string]Sink{DefaultSink: current[DefaultSink]}
+	urls := make(map[string]string, len(sinks))
+	var opened []Sink
+	for _, name := range sortedSinkNames(sinks) {
+		url := sinks[name]
+		urls[name] = url
+		if currentURLs[name] == url {
+			newSinks[name] = current[name]
+			continue
+		}
+		sink, err := NewSink(url, os.Stderr)
+		if err != nil {
+			for _, s := range opened {
+				s.Close()
+			}
+			return errors.WithMessagef(err, "failed creating sink '%s'", name)
+		}
+		newSinks[name] = sink
+		opened = append(opened, sink)
+	}
+
+	r.mutex.Lock()
+	r.sinks = newSinks
+	r.urls = urls
+	r.specs = specs
+	r.defaultRoute = defaultRoute
+	r.routeCache = map[string][]Sink{}
+	r.mutex.Unlock()
+
+	for name, url := range currentURLs {
+		if urls[name] != url {
+			current[name].Close()
+		}
+	}
+
 	return nil
 }
 
+func routeValues(specs map[string][]string) [][]string {
+	values := make([][]string, 0, len(specs))
+	for _, route := range specs {
+		values = append(values, route)
+	}
+	return values
+}
+
+// Sinks returns the URLs of the named sinks. The default sink is not included.
+func (l *Logging) Sinks() map[string]string {
+	return l.routes.Sinks()
+}
+
+// Routes returns a normalized version of the active routing specification.
+func (l *Logging) Routes() string {
+	return l.routes.Routes()
+}
+
+// Route satisfies the SinkRouter interface. It returns the sinks the records
+// of the named logger are written to.
+func (l *Logging) Route(loggerName string) []Sink {
+	return l.routes.Route(loggerName)
+}
+
 // SetFormat updates how log records are formatted and encoded. Log entries
 // created after this method has completed will use the new format.
 //
@@ -179,15 +312,43 @@ func (l *Logging) Write(b []byte) (int, error) {
 }
 
 // Sync satisfies the zapcore.WriteSyncer interface. It is used by the Core to
-// flush log records before terminating the process.
+// flush log records before terminating the process. The named sinks are
+// flushed along with the writer.
 func (l *Logging) Sync() error {
 	l.mutex.RLock()
 	w := l.writer
 	l.mutex.RUnlock()
 
+	err := w.Sync()
+	if serr := l.routes.syncSinks(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// loggingSink is the default sink, writing the records to the writer of the
+// logging system.
+type loggingSink struct {
+	logging *Logging
+}
+
+func (s *loggingSink) WriteEntry(e zapcore.Entry, fields []zapcore.Field, record []byte) error {
+	_, err := s.logging.Write(record)
+	return err
+}
+
+func (s *loggingSink) Sync() error {
+	s.logging.mutex.RLock()
+	w := s.logging.writer
+	s.logging.mutex.RUnlock()
+
 	return w.Sync()
 }
 
+func (s *loggingSink) Close() error {
+	return nil
+}
+
 // Encoding satisfies the Encoding interface. It determines whether the JSON or
 // CONSOLE encoder should be used by the Core when log records are written.
 func (l *Logging) Encoding() Encoding {
@@ -216,6 +377,7 @@ func (l *Logging) ZapLogger(name string) *zap.Logger {
 		Selector: l,
 		Output:   l,
 		Observer: l,
+		Router:   l,
 	}
 	l.mutex.RUnlock()
 
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// sinkNameRegexp defines the valid sink names
+var sinkNameRegexp = regexp.MustCompile(`^[[:alnum:]_-]+$`)
+
+// sinkRoutes tracks the sinks the records of named loggers are written to.
+type sinkRoutes struct {
+	// configMutex serializes the changes of the sinks
+	configMutex  sync.Mutex
+	mutex        sync.RWMutex
+	routeCache   map[string][]Sink
+	specs        map[string][]string
+	defaultRoute []string
+	sinks        map[string]Sink
+	urls         map[string]string
+}
+
+// parseRoutes parses a routing specification, of the following form:
+//   [<logger>[,<logger>...]=]<sink>[+<sink>...][:[<logger>[,<logger>...]=]<sink>[+<sink>...]...]
+//
+// The records of loggers without a route are written to the default sink,
+// unless the specification holds a segment without logger.
+func parseRoutes(routes string) (map[string][]string, []string, error) {
+	defaultRoute := []string{DefaultSink}
+	specs := map[string][]string{}
+	for _, field := range strings.Split(routes, ":") {
+		if field == "" {
+			continue
+		}
+		split := strings.Split(field, "=")
+		var loggers []string
+		switch len(split) {
+		case 1: // <sink>[+<sink>...]
+		case 2: // <logger>[,<logger>...]=<sink>[+<sink>...]
+			if split[0] == "" {
+				return nil, nil, errors.Errorf("invalid routing specification '%s': no logger specified in segment '%s'", routes, field)
+			}
+			loggers = strings.Split(split[0], ",")
+		default:
+			return nil, nil, errors.Errorf("invalid routing specification '%s': bad segment '%s'", routes, field)
+		}
+
+		sinks := strings.Split(split[len(split)-1], "+")
+		for _, sink := range sinks {
+			if !sinkNameRegexp.MatchString(sink) {
+				return nil, nil, errors.Errorf("invalid routing specification '%s': bad sink name '%s'", routes, sink)
+			}
+		}
+		if loggers == nil {
+			defaultRoute = sinks
+		}
+		for _, logger := range loggers {
+			// as in logging specifications, a trailing period refers to the
+			// exact logger name
+			if !isValidLoggerName(strings.TrimSuffix(logger, ".")) {
+				return nil, nil, errors.Errorf("invalid routing specification '%s': bad logger name '%s'", routes, logger)
+			}
+			specs[logger] = sinks
+		}
+	}
+	return specs, defaultRoute, nil
+}
+
+// Route returns the sinks the records of a logger are written to.
+func (r *sinkRoutes) Route(loggerName string) []Sink {
+	r.mutex.RLock()
+	sinks, ok := r.routeCache[loggerName]
+	r.mutex.RUnlock()
+	if ok {
+		return sinks
+	}
+
+	r.mutex.Lock()
+	sinks = r.calculateRoute(loggerName)
+	r.routeCache[loggerName] = sinks
+	r.mutex.Unlock()
+
+	return sinks
+}
+
+// calculateRoute walks the logger name back to find the appropriate route
+// from the current specification.
+func (r *sinkRoutes) calculateRoute(loggerName string) []Sink {
+	names := r.defaultRoute
+	candidate := loggerName + "."
+	for {
+		if route, ok := r.specs[candidate]; ok {
+			names = route
+			break
+		}
+
+		idx := strings.LastIndex(candidate, ".")
+		if idx <= 0 {
+			break
+		}
+		candidate = candidate[:idx]
+	}
+
+	sinks := make([]Sink, 0, len(names))
+	for _, name := range names {
+		sinks = append(sinks, r.sinks[name])
+	}
+	return sinks
+}
+
+// Routes returns a normalized version of the active routing specification.
+func (r *sinkRoutes) Routes() string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var fields []string
+	for k, v := range r.specs {
+		fields = append(fields, fmt.Sprintf("%s=%s", k, strings.Join(v, "+")))
+	}
+
+	sort.Strings(fields)
+	fields = append(fields, strings.Join(r.defaultRoute, "+"))
+
+	return strings.Join(fields, ":")
+}
+
+// Sinks returns the URLs of the named sinks, without the default sink.
+func (r *sinkRoutes) Sinks() map[string]string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	urls := make(map[string]string, len(r.urls))
+	for name, url := range r.urls {
+		urls[name] = url
+	}
+	return urls
+}
+
+// syncSinks flushes the named sinks.
+func (r *sinkRoutes) syncSinks() error {
+	r.mutex.RLock()
+	sinks := make([]Sink, 0, len(r.urls))
+	for _, name := range sortedSinkNames(r.urls) {
+		sinks = append(sinks, r.sinks[name])
+	}
+	r.mutex.RUnlock()
+
+	var firstErr error
+	for _, s := range sinks {
+		if err := s.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
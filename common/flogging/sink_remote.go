@@ -0,0 +1,375 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultFlushInterval = 2 * time.Second
+	defaultQueueSize     = 8192
+)
+
+// remoteRecord is a log record queued for sending.
+type remoteRecord struct {
+	entry      zapcore.Entry
+	attributes map[string]string
+	line       string
+}
+
+// batchSink queues the records written to it, and sends them in batches to a
+// remote service from a background goroutine. Records are dropped when the
+// queue is full, so that logging never blocks on the service.
+type batchSink struct {
+	send          func([]*remoteRecord) error
+	name          string
+	errorOutput   io.Writer
+	batchSize     int
+	flushInterval time.Duration
+
+	records   chan *remoteRecord
+	flushes   chan chan struct{}
+	closeOnce sync.Once
+	closing   chan struct{}
+	closed    chan struct{}
+}
+
+func newBatchSink(name string, errorOutput io.Writer, send func([]*remoteRecord) error) *batchSink {
+	b := &batchSink{
+		send:          send,
+		name:          name,
+		errorOutput:   errorOutput,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		records:       make(chan *remoteRecord, defaultQueueSize),
+		flushes:       make(chan chan struct{}),
+		closing:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *batchSink) WriteEntry(e zapcore.Entry, fields []zapcore.Field, record []byte) error {
+	r := &remoteRecord{
+		entry:      e,
+		attributes: fieldAttributes(fields),
+		line:       strings.TrimSuffix(string(record), "\n"),
+	}
+	select {
+	case <-b.closing:
+		return nil
+	default:
+	}
+	select {
+	case b.records <- r:
+	default:
+		// dropped, the queue is full
+	}
+	return nil
+}
+
+// Sync sends the queued records.
+func (b *batchSink) Sync() error {
+	done := make(chan struct{})
+	select {
+	case b.flushes <- done:
+		<-done
+	case <-b.closed:
+	}
+	return nil
+}
+
+// Close sends the queued records and stops the sink.
+func (b *batchSink) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closing)
+	})
+	<-b.closed
+	return nil
+}
+
+func (b *batchSink) run() {
+	defer close(b.closed)
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*remoteRecord
+	for {
+		select {
+		case r := <-b.records:
+			batch = append(batch, r)
+			if len(batch) < b.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case done := <-b.flushes:
+			batch = b.drain(batch)
+			b.flush(batch)
+			batch = nil
+			close(done)
+			continue
+		case <-b.closing:
+			b.flush(b.drain(batch))
+			return
+		}
+		b.flush(batch)
+		batch = nil
+	}
+}
+
+func (b *batchSink) drain(batch []*remoteRecord) []*remoteRecord {
+	for {
+		select {
+		case r := <-b.records:
+			batch = append(batch, r)
+		default:
+			return batch
+		}
+	}
+}
+
+func (b *batchSink) flush(batch []*remoteRecord) {
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > b.batchSize {
+			n = b.batchSize
+		}
+		if err := b.send(batch[:n]); err != nil {
+			reportSinkError(b.errorOutput, "failed sending %d log records to %s: %s", n, b.name, err)
+		}
+		batch = batch[n:]
+	}
+}
+
+// fieldAttributes returns the fields of a record as string attributes.
+func fieldAttributes(fields []zapcore.Field) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	attributes := make(map[string]string, len(enc.Fields))
+	for k, v := range enc.Fields {
+		attributes[k] = fmt.Sprint(v)
+	}
+	return attributes
+}
+
+// postJSON posts the JSON encoding of body to the URL.
+func postJSON(client *http.Client, endpoint *url.URL, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if endpoint.User != nil {
+		password, _ := endpoint.User.Password()
+		req.SetBasicAuth(endpoint.User.Username(), password)
+		req.URL.User = nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// newOTLPSink returns a sink sending the records to the logs endpoint of an
+// OpenTelemetry collector, with the JSON encoding of OTLP over HTTP.
+func newOTLPSink(endpoint url.URL, service string, errorOutput io.Writer) Sink {
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/v1/logs"
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newBatchSink(redactSinkURL(endpoint.String()), errorOutput, func(batch []*remoteRecord) error {
+		return postJSON(client, &endpoint, otlpLogsRequest(service, batch))
+	})
+}
+
+// The types below are the JSON encoding of the ExportLogsServiceRequest of OTLP.
+
+type otlpLogs struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpSeverity returns the OTLP severity number of a level.
+func otlpSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	default:
+		return 21
+	}
+}
+
+func otlpLogsRequest(service string, batch []*remoteRecord) *otlpLogs {
+	// the records are grouped by logger, reported as the instrumentation scope
+	var loggers []string
+	records := map[string][]otlpLogRecord{}
+	for _, r := range batch {
+		if _, ok := records[r.entry.LoggerName]; !ok {
+			loggers = append(loggers, r.entry.LoggerName)
+		}
+		attributes := map[string]string{}
+		for k, v := range r.attributes {
+			attributes[k] = v
+		}
+		if r.entry.Caller.Defined {
+			attributes["code.filepath"] = r.entry.Caller.File
+			attributes["code.lineno"] = strconv.Itoa(r.entry.Caller.Line)
+		}
+		records[r.entry.LoggerName] = append(records[r.entry.LoggerName], otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(r.entry.Time.UnixNano(), 10),
+			SeverityNumber: otlpSeverity(r.entry.Level),
+			SeverityText:   r.entry.Level.CapitalString(),
+			Body:           otlpAnyValue{StringValue: r.entry.Message},
+			Attributes:     otlpKeyValues(attributes),
+		})
+	}
+
+	scopeLogs := make([]otlpScopeLogs, 0, len(loggers))
+	for _, name := range loggers {
+		scopeLogs = append(scopeLogs, otlpScopeLogs{
+			Scope:      otlpScope{Name: name},
+			LogRecords: records[name],
+		})
+	}
+	return &otlpLogs{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource:  otlpResource{Attributes: otlpKeyValues(map[string]string{"service.name": service})},
+			ScopeLogs: scopeLogs,
+		}},
+	}
+}
+
+func otlpKeyValues(attributes map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: attributes[k]}})
+	}
+	return kvs
+}
+
+// newLokiSink returns a sink pushing the records to a Loki server. The records
+// are labeled with the service, the logger and the level, and their lines are
+// the records encoded in the format of the logging system.
+func newLokiSink(endpoint url.URL, service string, errorOutput io.Writer) Sink {
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/loki/api/v1/push"
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newBatchSink(redactSinkURL(endpoint.String()), errorOutput, func(batch []*remoteRecord) error {
+		return postJSON(client, &endpoint, lokiPushRequest(service, batch))
+	})
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func lokiPushRequest(service string, batch []*remoteRecord) *lokiPush {
+	var streams []lokiStream
+	index := map[[2]string]int{}
+	for _, r := range batch {
+		key := [2]string{r.entry.LoggerName, r.entry.Level.String()}
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, lokiStream{
+				Stream: map[string]string{
+					"service_name": service,
+					"logger":       r.entry.LoggerName,
+					"level":        r.entry.Level.String(),
+				},
+			})
+		}
+		streams[i].Values = append(streams[i].Values, [2]string{
+			strconv.FormatInt(r.entry.Time.UnixNano(), 10),
+			r.line,
+		})
+	}
+	return &lokiPush{Streams: streams}
+}
@@ -0,0 +1,54 @@
+// +build !windows
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"log/syslog"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogSink sends the records to a syslog daemon, with the severity of their
+// level.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(network, address, tag string) (Sink, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed connecting to syslog")
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) WriteEntry(e zapcore.Entry, fields []zapcore.Field, record []byte) error {
+	message := string(record)
+	switch e.Level {
+	case zapcore.DebugLevel:
+		return s.writer.Debug(message)
+	case zapcore.InfoLevel:
+		return s.writer.Info(message)
+	case zapcore.WarnLevel:
+		return s.writer.Warning(message)
+	case zapcore.ErrorLevel:
+		return s.writer.Err(message)
+	default:
+		return s.writer.Crit(message)
+	}
+}
+
+func (s *syslogSink) Sync() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,13 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import "github.com/pkg/errors"
+
+func newSyslogSink(network, address, tag string) (Sink, error) {
+	return nil, errors.New("syslog sinks are not supported on windows")
+}
@@ -0,0 +1,184 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSink is the name of the sink writing to the writer of the logging
+// system. It is always defined.
+const DefaultSink = "default"
+
+// Sink receives the log records routed to it.
+type Sink interface {
+	// WriteEntry writes a log entry. The record is the entry encoded in the
+	// format of the logging system, and must not be retained after WriteEntry
+	// returns. The fields include the fields added to the logger.
+	WriteEntry(e zapcore.Entry, fields []zapcore.Field, record []byte) error
+	// Sync flushes the records written to the sink.
+	Sync() error
+	// Close releases the resources of the sink. Records written after the sink
+	// is closed are dropped.
+	Close() error
+}
+
+// SinkRouter selects the sinks the log records of a logger are written to.
+type SinkRouter interface {
+	Route(loggerName string) []Sink
+}
+
+// NewSink creates the sink described by the given URL:
+//
+//   stderr, stdout                    the standard error or output
+//   file:///var/log/peer/gossip.log   a file, appended to
+//   syslog[+tcp|+udp]://host:514      a syslog daemon, or the local daemon
+//                                     when the host is empty
+//   otlp+http[s]://collector:4318     an OpenTelemetry collector, with OTLP
+//                                     logs over HTTP
+//   loki+http[s]://loki:3100          a Loki server, with its push API
+//
+// The service query parameter of the syslog, OTLP and Loki sinks sets the name
+// of the service the records are reported under, fabric by default. The
+// failures of the sinks sending their records in the background are written
+// to errorOutput.
+func NewSink(rawurl string, errorOutput io.Writer) (Sink, error) {
+	switch rawurl {
+	case "stderr":
+		return &writerSink{writer: zapcore.Lock(os.Stderr)}, nil
+	case "stdout":
+		return &writerSink{writer: zapcore.Lock(os.Stdout)}, nil
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sink URL '%s'", rawurl)
+	}
+	service := u.Query().Get("service")
+	if service == "" {
+		service = "fabric"
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" || !filepath.IsAbs(u.Path) {
+			return nil, errors.Errorf("invalid sink URL '%s': the path of a file must be absolute", rawurl)
+		}
+		file, err := os.OpenFile(u.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed opening sink '%s'", rawurl)
+		}
+		return &writerSink{writer: zapcore.Lock(file), closer: file}, nil
+
+	case "syslog", "syslog+tcp", "syslog+udp":
+		network := strings.TrimPrefix(strings.TrimPrefix(u.Scheme, "syslog"), "+")
+		if u.Host != "" && network == "" {
+			network = "udp"
+		}
+		return newSyslogSink(network, u.Host, service)
+
+	case "otlp+http", "otlp+https", "loki+http", "loki+https":
+		if u.Host == "" {
+			return nil, errors.Errorf("invalid sink URL '%s': no host", rawurl)
+		}
+		endpoint := *u
+		endpoint.RawQuery = ""
+		endpoint.Scheme = strings.SplitN(u.Scheme, "+", 2)[1]
+		if strings.HasPrefix(u.Scheme, "otlp") {
+			return newOTLPSink(endpoint, service, errorOutput), nil
+		}
+		return newLokiSink(endpoint, service, errorOutput), nil
+
+	default:
+		return nil, errors.Errorf("invalid sink URL '%s': unsupported scheme '%s'", rawurl, u.Scheme)
+	}
+}
+
+// writerSink writes the encoded records to a writer.
+type writerSink struct {
+	writer zapcore.WriteSyncer
+	closer io.Closer
+}
+
+func (w *writerSink) WriteEntry(e zapcore.Entry, fields []zapcore.Field, record []byte) error {
+	_, err := w.writer.Write(record)
+	return err
+}
+
+func (w *writerSink) Sync() error {
+	return w.writer.Sync()
+}
+
+func (w *writerSink) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// parseSinks parses the definitions of named sinks, separated by white space,
+// in the form <name>=<url>.
+func parseSinks(definitions string) (map[string]string, error) {
+	sinks := map[string]string{}
+	for _, definition := range strings.Fields(definitions) {
+		split := strings.SplitN(definition, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, errors.Errorf("invalid sink definition '%s'", definition)
+		}
+		sinks[split[0]] = split[1]
+	}
+	return sinks, nil
+}
+
+// redactSinkURL hides the password of the URL of a sink.
+func redactSinkURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+	if _, ok := u.User.Password(); !ok {
+		return rawurl
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
+// RedactSinks returns the definitions of the sinks with the passwords of their
+// URLs hidden.
+func RedactSinks(sinks map[string]string) map[string]string {
+	redacted := make(map[string]string, len(sinks))
+	for name, rawurl := range sinks {
+		redacted[name] = redactSinkURL(rawurl)
+	}
+	return redacted
+}
+
+// reportSinkError writes a failure of a sink to the error output, in the
+// format zap reports its own failures.
+func reportSinkError(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(w, "%v %s\n", time.Now(), fmt.Sprintf(format, args...))
+}
+
+// sortedSinkNames returns the names of the sinks, sorted.
+func sortedSinkNames(sinks map[string]string) []string {
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
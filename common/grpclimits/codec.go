@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpclimits
+
+import (
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	encproto "google.golang.org/grpc/encoding/proto"
+)
+
+// Codec returns the codec the server must use for the limiter to reject the
+// messages received beyond the size limits before they are decoded.
+//
+// The messages received on streams are handed to the codec along with the
+// limits of their method. The requests of unary calls are decoded by the
+// generated handlers before any interceptor runs, so the codec only knows
+// their type: it sets aside, undecoded, the requests larger than the smallest
+// limit of the unary methods receiving that type, for the unary interceptor
+// to reject them or to decode them once it knows their method.
+func (l *Limiter) Codec() grpc.Codec {
+	return &codec{
+		limiter: l,
+		base:    encoding.GetCodec(encproto.Name),
+	}
+}
+
+type codec struct {
+	limiter *Limiter
+	base    encoding.Codec
+}
+
+func (c *codec) Marshal(v interface{}) ([]byte, error) {
+	return c.base.Marshal(v)
+}
+
+func (c *codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(*limitedMessage); ok {
+		// gRPC fails the stream with an internal error when the codec
+		// fails, so the rejection is left to the stream
+		if m.err = m.stream.checkRecvSize(len(data)); m.err != nil {
+			return nil
+		}
+		return c.base.Unmarshal(data, m.msg)
+	}
+	if c.limiter.requests.setAside(data, v) {
+		return nil
+	}
+	return c.base.Unmarshal(data, v)
+}
+
+func (c *codec) String() string {
+	return c.base.Name()
+}
+
+// limitedMessage is a message received on a stream, along with the stream
+// and the error rejecting it.
+type limitedMessage struct {
+	msg    interface{}
+	stream *serverStream
+	err    error
+}
+
+// unaryRequests tracks the requests of unary calls the codec sets aside.
+type unaryRequests struct {
+	lock     sync.Mutex
+	limits   map[reflect.Type]int
+	deferred map[interface{}][]byte
+}
+
+// learn records that the unary method with the given limit receives
+// requests of the type of req.
+func (u *unaryRequests) learn(req interface{}, limit int) {
+	if limit <= 0 {
+		return
+	}
+	t := reflect.TypeOf(req)
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if known, ok := u.limits[t]; !ok || limit < known {
+		u.limits[t] = limit
+	}
+}
+
+// setAside sets aside the given request when it's larger than the smallest
+// limit of its type, and returns whether it did.
+func (u *unaryRequests) setAside(data []byte, req interface{}) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	limit, ok := u.limits[reflect.TypeOf(req)]
+	if !ok || len(data) <= limit {
+		return false
+	}
+	u.deferred[req] = data
+	return true
+}
+
+// take returns the data of the given request if it was set aside.
+func (u *unaryRequests) take(req interface{}) ([]byte, bool) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	data, ok := u.deferred[req]
+	delete(u.deferred, req)
+	return data, ok
+}
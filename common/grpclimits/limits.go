@@ -0,0 +1,258 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package grpclimits enforces per-service limits on the calls served by a
+// gRPC server: the number of concurrent calls and the size of the messages
+// received and sent. The limits of the server itself apply to all the
+// services, and bound the limits of each one. Keepalive enforcement applies
+// to connections rather than to calls, and therefore remains set for the
+// whole server.
+package grpclimits
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/semaphore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	encproto "google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/status"
+)
+
+var logger = flogging.MustGetLogger("grpclimits")
+
+// The reasons requests are rejected for.
+const (
+	ReasonConcurrency = "concurrency"
+	ReasonRecvMsgSize = "recv_msg_size"
+	ReasonSendMsgSize = "send_msg_size"
+)
+
+// Limits are the limits of a service. A zero value leaves the corresponding
+// limit to the server.
+type Limits struct {
+	// MaxConcurrency is the number of calls, unary or streaming, that are
+	// served concurrently. Calls beyond it are rejected.
+	MaxConcurrency int
+	// MaxRecvMsgSize is the size, in bytes, of the largest message received.
+	MaxRecvMsgSize int
+	// MaxSendMsgSize is the size, in bytes, of the largest message sent.
+	MaxSendMsgSize int
+}
+
+type serviceLimits struct {
+	Limits
+	semaphore semaphore.Semaphore
+}
+
+// Limiter enforces the limits of the services through its codec and server
+// interceptors.
+type Limiter struct {
+	services         map[string]*serviceLimits
+	requests         *unaryRequests
+	rejectedRequests metrics.Counter
+}
+
+// NewLimiter returns a limiter enforcing the given limits. The limits are
+// keyed by the name of the service, such as protos.Endorser, or by the name
+// of a method of a service, such as orderer.AtomicBroadcast/Broadcast. The
+// limits of a method take precedence over the limits of its service.
+func NewLimiter(limits map[string]Limits, provider metrics.Provider) *Limiter {
+	services := map[string]*serviceLimits{}
+	for name, l := range limits {
+		if l == (Limits{}) {
+			continue
+		}
+		sl := &serviceLimits{Limits: l}
+		if l.MaxConcurrency != 0 {
+			sl.semaphore = semaphore.New(l.MaxConcurrency)
+		}
+		logger.Infof("Limits of %s: concurrency %d, received message size %d, sent message size %d", name, l.MaxConcurrency, l.MaxRecvMsgSize, l.MaxSendMsgSize)
+		services[name] = sl
+	}
+	return &Limiter{
+		services: services,
+		requests: &unaryRequests{
+			limits:   map[reflect.Type]int{},
+			deferred: map[interface{}][]byte{},
+		},
+		rejectedRequests: provider.NewCounter(rejectedRequestsOpts),
+	}
+}
+
+// Empty returns whether the limiter has no limits to enforce.
+func (l *Limiter) Empty() bool {
+	return len(l.services) == 0
+}
+
+func (l *Limiter) lookup(fullMethod string) *serviceLimits {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if sl, ok := l.services[name]; ok {
+		return sl
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return l.services[name[:i]]
+	}
+	return nil
+}
+
+func (l *Limiter) reject(fullMethod, reason, format string, args ...interface{}) error {
+	service, method := serviceMethod(fullMethod)
+	l.rejectedRequests.With("service", service, "method", method, "reason", reason).Add(1)
+	logger.Warningf("Rejecting call to %s: "+format, append([]interface{}{fullMethod}, args...)...)
+	return status.Errorf(codes.ResourceExhausted, format, args...)
+}
+
+func (l *Limiter) acquire(sl *serviceLimits, fullMethod string) error {
+	if sl.semaphore == nil {
+		return nil
+	}
+	if !sl.semaphore.TryAcquire() {
+		return l.reject(fullMethod, ReasonConcurrency, "too many requests for %s, exceeding concurrency limit (%d)", fullMethod, sl.MaxConcurrency)
+	}
+	return nil
+}
+
+func (l *Limiter) release(sl *serviceLimits) {
+	if sl.semaphore != nil {
+		sl.semaphore.Release()
+	}
+}
+
+func (l *Limiter) checkRecvSize(sl *serviceLimits, fullMethod string, size int) error {
+	if sl == nil || sl.MaxRecvMsgSize <= 0 || size <= sl.MaxRecvMsgSize {
+		return nil
+	}
+	return l.reject(fullMethod, ReasonRecvMsgSize, "received message larger than max (%d vs. %d)", size, sl.MaxRecvMsgSize)
+}
+
+// checkRequest enforces the size limit of the request of a unary call,
+// decoding it if the codec set it aside.
+func (l *Limiter) checkRequest(sl *serviceLimits, fullMethod string, req interface{}) error {
+	data, ok := l.requests.take(req)
+	if !ok {
+		// The codec doesn't know the type of the requests of the method
+		// before its first call
+		if sl == nil || sl.MaxRecvMsgSize <= 0 {
+			return nil
+		}
+		return l.checkRecvSize(sl, fullMethod, messageSize(req))
+	}
+	if err := l.checkRecvSize(sl, fullMethod, len(data)); err != nil {
+		return err
+	}
+	if err := encoding.GetCodec(encproto.Name).Unmarshal(data, req); err != nil {
+		return status.Errorf(codes.Internal, "grpc: error unmarshalling request: %v", err)
+	}
+	return nil
+}
+
+func (l *Limiter) checkSend(sl *serviceLimits, fullMethod string, msg interface{}) error {
+	if sl.MaxSendMsgSize <= 0 {
+		return nil
+	}
+	if size := messageSize(msg); size > sl.MaxSendMsgSize {
+		return l.reject(fullMethod, ReasonSendMsgSize, "sent message larger than max (%d vs. %d)", size, sl.MaxSendMsgSize)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns an interceptor enforcing the limits of the
+// unary calls. It must be the first interceptor of the server, as the
+// requests the codec sets aside are only decoded once it has seen them.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		sl := l.lookup(info.FullMethod)
+		if err := l.checkRequest(sl, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		if sl == nil {
+			return handler(ctx, req)
+		}
+		l.requests.learn(req, sl.MaxRecvMsgSize)
+		if err := l.acquire(sl, info.FullMethod); err != nil {
+			return nil, err
+		}
+		defer l.release(sl)
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if err := l.checkSend(sl, info.FullMethod, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns an interceptor enforcing the limits of the
+// streaming calls. A stream counts towards the concurrency limit for as long
+// as it is open, and fails as soon as a message exceeds the size limits.
+// The messages received on all the streams, limited or not, are handed to
+// the codec with the limits of their method.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		sl := l.lookup(info.FullMethod)
+		if sl != nil {
+			if err := l.acquire(sl, info.FullMethod); err != nil {
+				return err
+			}
+			defer l.release(sl)
+		}
+		return handler(srv, &serverStream{ServerStream: ss, limiter: l, limits: sl, fullMethod: info.FullMethod})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	limiter    *Limiter
+	limits     *serviceLimits
+	fullMethod string
+}
+
+func (ss *serverStream) SendMsg(msg interface{}) error {
+	if ss.limits != nil {
+		if err := ss.limiter.checkSend(ss.limits, ss.fullMethod, msg); err != nil {
+			return err
+		}
+	}
+	return ss.ServerStream.SendMsg(msg)
+}
+
+func (ss *serverStream) RecvMsg(msg interface{}) error {
+	m := &limitedMessage{msg: msg, stream: ss}
+	if err := ss.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return m.err
+}
+
+func (ss *serverStream) checkRecvSize(size int) error {
+	return ss.limiter.checkRecvSize(ss.limits, ss.fullMethod, size)
+}
+
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+func serviceMethod(fullMethod string) (service, method string) {
+	normalizedMethod := strings.Replace(fullMethod, ".", "_", -1)
+	parts := strings.SplitN(normalizedMethod, "/", -1)
+	if len(parts) != 3 {
+		return "unknown", "unknown"
+	}
+	return parts[1], parts[2]
+}
@@ -0,0 +1,275 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpclimits
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func envelope(size int) *cb.Envelope {
+	return &cb.Envelope{Payload: make([]byte, size)}
+}
+
+func newFakeLimiter(limits map[string]Limits) (*Limiter, *metricsfakes.Counter) {
+	rejected := &metricsfakes.Counter{}
+	rejected.WithReturns(rejected)
+	provider := &metricsfakes.Provider{}
+	provider.NewCounterReturns(rejected)
+	return NewLimiter(limits, provider), rejected
+}
+
+func requireResourceExhausted(t *testing.T, err error, message string) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Equal(t, message, st.Message())
+}
+
+// decode decodes the given message with the given codec, as gRPC does with
+// the requests of unary calls.
+func decode(t *testing.T, codec grpc.Codec, msg proto.Message) *cb.Envelope {
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	req := &cb.Envelope{}
+	require.NoError(t, codec.Unmarshal(data, req))
+	return req
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	codec    grpc.Codec
+	received proto.Message
+	sent     []interface{}
+}
+
+func (f *fakeServerStream) RecvMsg(m interface{}) error {
+	data, err := proto.Marshal(f.received)
+	if err != nil {
+		return err
+	}
+	return f.codec.Unmarshal(data, m)
+}
+
+func (f *fakeServerStream) SendMsg(m interface{}) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func TestNewLimiter(t *testing.T) {
+	limiter := NewLimiter(map[string]Limits{
+		"protos.Endorser":                   {MaxConcurrency: 10},
+		"orderer.AtomicBroadcast/Broadcast": {MaxRecvMsgSize: 1024},
+		"protos.Deliver":                    {},
+	}, &disabled.Provider{})
+	require.False(t, limiter.Empty())
+	require.Len(t, limiter.services, 2)
+
+	require.Equal(t, 10, limiter.lookup("/protos.Endorser/ProcessProposal").MaxConcurrency)
+	require.Equal(t, 1024, limiter.lookup("/orderer.AtomicBroadcast/Broadcast").MaxRecvMsgSize)
+	require.Nil(t, limiter.lookup("/orderer.AtomicBroadcast/Deliver"))
+	require.Nil(t, limiter.lookup("/protos.Deliver/Deliver"))
+
+	require.True(t, NewLimiter(nil, &disabled.Provider{}).Empty())
+	require.PanicsWithValue(t, "permits must be greater than 0", func() {
+		NewLimiter(map[string]Limits{"protos.Endorser": {MaxConcurrency: -1}}, &disabled.Provider{})
+	})
+}
+
+func TestMethodLimitsTakePrecedence(t *testing.T) {
+	limiter := NewLimiter(map[string]Limits{
+		"orderer.AtomicBroadcast":           {MaxConcurrency: 10},
+		"orderer.AtomicBroadcast/Broadcast": {MaxConcurrency: 5},
+	}, &disabled.Provider{})
+	require.Equal(t, 5, limiter.lookup("/orderer.AtomicBroadcast/Broadcast").MaxConcurrency)
+	require.Equal(t, 10, limiter.lookup("/orderer.AtomicBroadcast/Deliver").MaxConcurrency)
+}
+
+func TestUnaryConcurrency(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"protos.Endorser": {MaxConcurrency: 1}})
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/protos.Endorser/ProcessProposal"}
+
+	var nestedErr error
+	resp, err := interceptor(context.Background(), envelope(1), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// the only permit is held by the outer call
+		_, nestedErr = interceptor(ctx, req, info, func(context.Context, interface{}) (interface{}, error) { return "nested", nil })
+		return "outer", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "outer", resp)
+	requireResourceExhausted(t, nestedErr, "too many requests for /protos.Endorser/ProcessProposal, exceeding concurrency limit (1)")
+	require.Equal(t, 1, rejected.AddCallCount())
+	require.Equal(t, []string{"service", "protos_Endorser", "method", "ProcessProposal", "reason", "concurrency"}, rejected.WithArgsForCall(0))
+
+	// the permit is released once the call completes
+	resp, err = interceptor(context.Background(), envelope(1), info, func(context.Context, interface{}) (interface{}, error) { return "again", nil })
+	require.NoError(t, err)
+	require.Equal(t, "again", resp)
+}
+
+func TestUnaryMessageSize(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"protos.Endorser": {MaxRecvMsgSize: 100, MaxSendMsgSize: 200}})
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/protos.Endorser/ProcessProposal"}
+	echo := func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+
+	resp, err := interceptor(context.Background(), envelope(50), info, echo)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	called := false
+	_, err = interceptor(context.Background(), envelope(150), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+	require.False(t, called)
+
+	_, err = interceptor(context.Background(), envelope(50), info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return envelope(250), nil
+	})
+	requireResourceExhausted(t, err, "sent message larger than max (253 vs. 200)")
+
+	// once the type of the requests is known, the codec sets aside the
+	// requests beyond the limit without decoding them
+	req := decode(t, limiter.Codec(), envelope(150))
+	require.Empty(t, req.Payload)
+	_, err = interceptor(context.Background(), req, info, echo)
+	requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+	require.Empty(t, limiter.requests.deferred)
+
+	req = decode(t, limiter.Codec(), envelope(50))
+	require.Len(t, req.Payload, 50)
+
+	require.Equal(t, 3, rejected.AddCallCount())
+	require.Equal(t, []string{"service", "protos_Endorser", "method", "ProcessProposal", "reason", "recv_msg_size"}, rejected.WithArgsForCall(0))
+	require.Equal(t, []string{"service", "protos_Endorser", "method", "ProcessProposal", "reason", "send_msg_size"}, rejected.WithArgsForCall(1))
+	require.Equal(t, []string{"service", "protos_Endorser", "method", "ProcessProposal", "reason", "recv_msg_size"}, rejected.WithArgsForCall(2))
+}
+
+func TestUnaryDeferredRequests(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{
+		"orderer.AtomicBroadcast/Broadcast": {MaxRecvMsgSize: 100},
+		"orderer.AtomicBroadcast/Deliver":   {MaxRecvMsgSize: 200},
+	})
+	interceptor := limiter.UnaryServerInterceptor()
+	echo := func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil }
+	small := &grpc.UnaryServerInfo{FullMethod: "/orderer.AtomicBroadcast/Broadcast"}
+	large := &grpc.UnaryServerInfo{FullMethod: "/orderer.AtomicBroadcast/Deliver"}
+	unlimited := &grpc.UnaryServerInfo{FullMethod: "/protos.Deliver/Deliver"}
+	_, err := interceptor(context.Background(), envelope(1), small, echo)
+	require.NoError(t, err)
+	_, err = interceptor(context.Background(), envelope(1), large, echo)
+	require.NoError(t, err)
+
+	// the requests set aside are decoded for the methods they fit in
+	for _, info := range []*grpc.UnaryServerInfo{large, unlimited} {
+		resp, err := interceptor(context.Background(), decode(t, limiter.Codec(), envelope(150)), info, echo)
+		require.NoError(t, err)
+		require.Len(t, resp.(*cb.Envelope).Payload, 150)
+	}
+
+	_, err = interceptor(context.Background(), decode(t, limiter.Codec(), envelope(150)), small, echo)
+	requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+	_, err = interceptor(context.Background(), decode(t, limiter.Codec(), envelope(250)), large, echo)
+	requireResourceExhausted(t, err, "received message larger than max (253 vs. 200)")
+	require.Equal(t, 2, rejected.AddCallCount())
+	require.Empty(t, limiter.requests.deferred)
+
+	// the requests of other types are decoded
+	req := &cb.Payload{}
+	data, err := proto.Marshal(&cb.Payload{Data: make([]byte, 150)})
+	require.NoError(t, err)
+	require.NoError(t, limiter.Codec().Unmarshal(data, req))
+	require.Len(t, req.Data, 150)
+}
+
+func TestUnaryUnlimitedService(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"protos.Endorser": {MaxRecvMsgSize: 10}})
+	interceptor := limiter.UnaryServerInterceptor()
+	resp, err := interceptor(context.Background(), envelope(50), &grpc.UnaryServerInfo{FullMethod: "/gossip.Gossip/Ping"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "pong", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pong", resp)
+	require.Equal(t, 0, rejected.AddCallCount())
+}
+
+func TestStreamConcurrency(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"protos.Deliver": {MaxConcurrency: 1}})
+	interceptor := limiter.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/protos.Deliver/Deliver"}
+
+	var nestedErr error
+	err := interceptor(nil, &fakeServerStream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		nestedErr = interceptor(srv, ss, &grpc.StreamServerInfo{FullMethod: "/protos.Deliver/DeliverFiltered"}, func(interface{}, grpc.ServerStream) error { return nil })
+		return nil
+	})
+	require.NoError(t, err)
+	requireResourceExhausted(t, nestedErr, "too many requests for /protos.Deliver/DeliverFiltered, exceeding concurrency limit (1)")
+	require.Equal(t, []string{"service", "protos_Deliver", "method", "DeliverFiltered", "reason", "concurrency"}, rejected.WithArgsForCall(0))
+
+	require.NoError(t, interceptor(nil, &fakeServerStream{}, info, func(interface{}, grpc.ServerStream) error { return nil }))
+}
+
+func TestStreamMessageSize(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"gossip.Gossip": {MaxRecvMsgSize: 100, MaxSendMsgSize: 200}})
+	interceptor := limiter.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/gossip.Gossip/GossipStream"}
+
+	stream := &fakeServerStream{codec: limiter.Codec(), received: envelope(50)}
+	err := interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		require.NoError(t, ss.RecvMsg(&cb.Envelope{}))
+		require.NoError(t, ss.SendMsg(envelope(150)))
+		err := ss.SendMsg(envelope(250))
+		requireResourceExhausted(t, err, "sent message larger than max (253 vs. 200)")
+		return err
+	})
+	require.Error(t, err)
+	require.Len(t, stream.sent, 1)
+
+	stream = &fakeServerStream{codec: limiter.Codec(), received: envelope(150)}
+	err = interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&cb.Envelope{})
+	})
+	requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+
+	require.Equal(t, 2, rejected.AddCallCount())
+	require.Equal(t, []string{"service", "gossip_Gossip", "method", "GossipStream", "reason", "send_msg_size"}, rejected.WithArgsForCall(0))
+	require.Equal(t, []string{"service", "gossip_Gossip", "method", "GossipStream", "reason", "recv_msg_size"}, rejected.WithArgsForCall(1))
+}
+
+func TestStreamUnlimitedService(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"protos.Endorser": {MaxRecvMsgSize: 100}})
+	_, err := limiter.UnaryServerInterceptor()(context.Background(), envelope(1), &grpc.UnaryServerInfo{FullMethod: "/protos.Endorser/ProcessProposal"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	// the messages of the streams are not mistaken for the requests of unary calls
+	stream := &fakeServerStream{codec: limiter.Codec(), received: envelope(150)}
+	err = limiter.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/protos.Deliver/Deliver"}, func(srv interface{}, ss grpc.ServerStream) error {
+		msg := &cb.Envelope{}
+		require.NoError(t, ss.RecvMsg(msg))
+		require.Len(t, msg.Payload, 150)
+		return ss.SendMsg(envelope(150))
+	})
+	require.NoError(t, err)
+	require.Len(t, stream.sent, 1)
+	require.Empty(t, limiter.requests.deferred)
+	require.Equal(t, 0, rejected.AddCallCount())
+}
@@ -0,0 +1,18 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpclimits
+
+import "github.com/hyperledger/fabric/common/metrics"
+
+var rejectedRequestsOpts = metrics.CounterOpts{
+	Namespace:    "grpc",
+	Subsystem:    "server",
+	Name:         "rejected_requests",
+	Help:         "The number of requests or messages rejected for exceeding the limits of their service.",
+	LabelNames:   []string{"service", "method", "reason"},
+	StatsdFormat: "%{#fqname}.%{service}.%{method}.%{reason}",
+}
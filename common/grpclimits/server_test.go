@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpclimits
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/grpclogging/testpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type echoServer struct{}
+
+func (echoServer) Echo(ctx context.Context, msg *testpb.Message) (*testpb.Message, error) {
+	return msg, nil
+}
+
+func (echoServer) EchoStream(stream testpb.EchoService_EchoStreamServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	limiter, rejected := newFakeLimiter(map[string]Limits{"testpb.EchoService": {MaxRecvMsgSize: 100}})
+	server := grpc.NewServer(
+		grpc.CustomCodec(limiter.Codec()),
+		grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(limiter.StreamServerInterceptor()),
+	)
+	testpb.RegisterEchoServiceServer(server, echoServer{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := testpb.NewEchoServiceClient(conn)
+
+	small := &testpb.Message{Message: strings.Repeat("a", 50)}
+	large := &testpb.Message{Message: strings.Repeat("a", 150)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Echo(context.Background(), small)
+		require.NoError(t, err)
+		require.Equal(t, small.Message, resp.Message)
+		_, err = client.Echo(context.Background(), large)
+		requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+	}
+
+	stream, err := client.EchoStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(small))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, small.Message, resp.Message)
+	require.NoError(t, stream.Send(large))
+	_, err = stream.Recv()
+	requireResourceExhausted(t, err, "received message larger than max (153 vs. 100)")
+
+	require.Equal(t, 3, rejected.AddCallCount())
+	require.Empty(t, limiter.requests.deferred)
+}
@@ -0,0 +1,326 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/pkg/errors"
+)
+
+const defaultArchiveCacheSize = 4
+
+// archiveInfoFileName is the name of the file, in the block files dir, that records the suffix of the latest archived
+// block file. This is not stored in the index db, as the index can be dropped and rebuilt from the block files
+const archiveInfoFileName = "archiveinfo"
+
+// ArchiveStore is the storage to which the block files that are no longer expected to be accessed
+// frequently are moved. An implementation is expected to be backed by a durable storage, typically
+// an object storage such as S3, GCS, or Azure Blob. The name of an object is of the form
+// `<ledgerid>/blockfile_<suffix>`
+type ArchiveStore interface {
+	// Put stores the content read from the reader as the object with the given name.
+	// The object is expected to be durable when this function returns without an error
+	Put(name string, r io.Reader) error
+	// Get writes the content of the object with the given name to the writer
+	Get(name string, w io.Writer) error
+}
+
+// ArchiveConf encapsulates the configurations for moving the block files to an `ArchiveStore`.
+// Note that the offline operations on the block store, i.e., reset and rollback, operate only on the block
+// files present on the local disk and hence are not supported on a ledger for which some block files have been archived
+type ArchiveConf struct {
+	// Store is the storage to which the block files are moved
+	Store ArchiveStore
+	// RetainBlocks is the number of most recent blocks that are always kept on the local disk.
+	// A block file is moved to the Store only when all its blocks are older than these
+	RetainBlocks uint64
+	// CacheSize is the maximum number of archived block files that are kept on the local disk,
+	// after these have been fetched back for serving the reads of the archived blocks
+	CacheSize int
+}
+
+// FileSystemArchiveStore implements `ArchiveStore` on a directory. The directory may be on a separate
+// volume or a mount of an object storage bucket (e.g., via s3fs, gcsfuse, or blobfuse)
+type FileSystemArchiveStore struct {
+	dir string
+}
+
+// NewFileSystemArchiveStore constructs a `FileSystemArchiveStore` that stores the objects under the given dir
+func NewFileSystemArchiveStore(dir string) *FileSystemArchiveStore {
+	return &FileSystemArchiveStore{dir: dir}
+}
+
+// Put implements the function in the interface `ArchiveStore`
+func (s *FileSystemArchiveStore) Put(name string, r io.Reader) error {
+	objectPath := filepath.Join(s.dir, name)
+	if _, err := util.CreateDirIfMissing(filepath.Dir(objectPath)); err != nil {
+		return errors.Wrapf(err, "error creating archive dir for object [%s]", name)
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(objectPath), filepath.Base(objectPath)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "error creating temporary file for object [%s]", name)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := io.Copy(tmpFile, r); err != nil {
+		tmpFile.Close()
+		return errors.Wrapf(err, "error writing object [%s]", name)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return errors.Wrapf(err, "error syncing object [%s]", name)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrapf(err, "error closing object [%s]", name)
+	}
+	return errors.Wrapf(os.Rename(tmpFile.Name(), objectPath), "error renaming object [%s]", name)
+}
+
+// Get implements the function in the interface `ArchiveStore`
+func (s *FileSystemArchiveStore) Get(name string, w io.Writer) error {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return errors.Wrapf(err, "error opening object [%s]", name)
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return errors.Wrapf(err, "error reading object [%s]", name)
+}
+
+// blockfileArchiver moves the older block files of a ledger to the `ArchiveStore` and fetches these back
+// on demand. The fetched files are kept in the block files dir with their original names, so that
+// the rest of the block store can read them as usual, and are removed in the least recently used order
+// once the number of such files exceeds the configured cache size. The suffix of the latest archived
+// block file is persisted and, hence, a block file with a suffix not greater than this is present on the
+// local disk only as a cached copy
+type blockfileArchiver struct {
+	ledgerID string
+	rootDir  string
+	conf     *ArchiveConf
+
+	mutex         sync.Mutex
+	archivedUpto  int
+	cachedFiles   *list.List
+	cachedFileMap map[int]*list.Element
+
+	running                 int32
+	wg                      sync.WaitGroup
+	candidateFileNum        int
+	candidateNextFirstBlock *uint64
+}
+
+func newBlockfileArchiver(ledgerID, rootDir string, conf *ArchiveConf) (*blockfileArchiver, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	a := &blockfileArchiver{
+		ledgerID:      ledgerID,
+		rootDir:       rootDir,
+		conf:          conf,
+		archivedUpto:  -1,
+		cachedFiles:   list.New(),
+		cachedFileMap: map[int]*list.Element{},
+	}
+	b, err := ioutil.ReadFile(filepath.Join(rootDir, archiveInfoFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "error reading the archive info")
+	}
+	if err == nil {
+		archivedUpto, n := proto.DecodeVarint(b)
+		if n == 0 {
+			return nil, errors.Errorf("unexpected bytes [%x] for the archive info", b)
+		}
+		a.archivedUpto = int(archivedUpto)
+	}
+	// the cached copies of the archived block files that are left over from the previous run are removed
+	for fileNum := 0; fileNum <= a.archivedUpto; fileNum++ {
+		if err := os.Remove(deriveBlockfilePath(rootDir, fileNum)); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "error removing the cached copy of the archived block file [%d]", fileNum)
+		}
+	}
+	a.candidateFileNum = a.archivedUpto + 1
+	return a, nil
+}
+
+// ensureLocal makes sure that the block file with the given suffix is present on the local disk,
+// fetching it from the archive store, if it has been archived and is not present in the cache
+func (a *blockfileArchiver) ensureLocal(fileNum int) error {
+	if a == nil {
+		return nil
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if fileNum > a.archivedUpto {
+		return nil
+	}
+	if e, ok := a.cachedFileMap[fileNum]; ok {
+		a.cachedFiles.MoveToFront(e)
+		return nil
+	}
+
+	logger.Debugf("Fetching the archived block file [%d] for ledger [%s]", fileNum, a.ledgerID)
+	filePath := deriveBlockfilePath(a.rootDir, fileNum)
+	// the name of the temporary file must not carry the block file prefix, as the block files dir is scanned for that
+	tmpFile, err := ioutil.TempFile(a.rootDir, "fetched_"+filepath.Base(filePath))
+	if err != nil {
+		return errors.Wrapf(err, "error creating temporary file for fetching the archived block file [%d]", fileNum)
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := a.conf.Store.Get(a.objectName(fileNum), tmpFile); err != nil {
+		tmpFile.Close()
+		return errors.WithMessagef(err, "error fetching the archived block file [%d]", fileNum)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrapf(err, "error closing the fetched block file [%d]", fileNum)
+	}
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return errors.Wrapf(err, "error renaming the fetched block file [%d]", fileNum)
+	}
+	a.addToCache(fileNum)
+	return nil
+}
+
+// addToCache expects the caller to hold the mutex
+func (a *blockfileArchiver) addToCache(fileNum int) {
+	a.cachedFileMap[fileNum] = a.cachedFiles.PushFront(fileNum)
+	cacheSize := a.conf.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultArchiveCacheSize
+	}
+	for a.cachedFiles.Len() > cacheSize {
+		e := a.cachedFiles.Back()
+		evictedFileNum := a.cachedFiles.Remove(e).(int)
+		delete(a.cachedFileMap, evictedFileNum)
+		if err := os.Remove(deriveBlockfilePath(a.rootDir, evictedFileNum)); err != nil && !os.IsNotExist(err) {
+			logger.Warningf("Error removing the cached copy of the archived block file [%d] for ledger [%s]: %s",
+				evictedFileNum, a.ledgerID, err)
+		}
+	}
+}
+
+// blockAdded is invoked after a block is added to the block store. If the oldest block file that is not yet
+// archived has become eligible for archival, this starts the archival in the background, unless an archival
+// is already in progress
+func (a *blockfileArchiver) blockAdded(lastBlockNum uint64, latestFileNum int) {
+	if a == nil {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&a.running, 0, 1) {
+		return
+	}
+	if a.candidateFileNum >= latestFileNum ||
+		(a.candidateNextFirstBlock != nil && !a.isEligible(*a.candidateNextFirstBlock, lastBlockNum)) {
+		atomic.StoreInt32(&a.running, 0)
+		return
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer atomic.StoreInt32(&a.running, 0)
+		if err := a.archiveEligibleBlockfiles(lastBlockNum, latestFileNum); err != nil {
+			logger.Errorf("Error archiving block files for ledger [%s]: %s", a.ledgerID, err)
+		}
+	}()
+}
+
+// archiveEligibleBlockfiles moves to the archive store the block files, older than the latest block file,
+// in which all the blocks are older than the configured number of blocks to retain
+func (a *blockfileArchiver) archiveEligibleBlockfiles(lastBlockNum uint64, latestFileNum int) error {
+	for a.candidateFileNum < latestFileNum {
+		if a.candidateNextFirstBlock == nil {
+			// the blocks in the candidate file are the ones that precede the first block in the next file
+			nextFirstBlock, err := retrieveFirstBlockNumFromFile(a.rootDir, a.candidateFileNum+1)
+			if err != nil {
+				return err
+			}
+			a.candidateNextFirstBlock = &nextFirstBlock
+		}
+		if !a.isEligible(*a.candidateNextFirstBlock, lastBlockNum) {
+			return nil
+		}
+		if err := a.archive(a.candidateFileNum); err != nil {
+			return err
+		}
+		a.candidateFileNum++
+		a.candidateNextFirstBlock = nil
+	}
+	return nil
+}
+
+// isEligible returns true if all the blocks in a block file, which are the blocks preceding the first block in
+// the next block file, are older than the blocks to retain
+func (a *blockfileArchiver) isEligible(nextFirstBlock, lastBlockNum uint64) bool {
+	return nextFirstBlock+a.conf.RetainBlocks <= lastBlockNum
+}
+
+func (a *blockfileArchiver) archive(fileNum int) error {
+	filePath := deriveBlockfilePath(a.rootDir, fileNum)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "error opening block file [%s] for archival", filePath)
+	}
+	defer f.Close()
+	if err := a.conf.Store.Put(a.objectName(fileNum), f); err != nil {
+		return errors.WithMessagef(err, "error archiving block file [%s]", filePath)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.saveArchiveInfo(fileNum); err != nil {
+		return err
+	}
+	a.archivedUpto = fileNum
+	// the local file is treated as a cached copy from here on, which gets removed eventually by the
+	// cache eviction. This avoids failing a reader that has just found the file present locally
+	a.addToCache(fileNum)
+	logger.Infof("Archived block file [%d] for ledger [%s]", fileNum, a.ledgerID)
+	return nil
+}
+
+func (a *blockfileArchiver) saveArchiveInfo(archivedUpto int) error {
+	tmpFile, err := ioutil.TempFile(a.rootDir, archiveInfoFileName)
+	if err != nil {
+		return errors.Wrap(err, "error creating temporary file for the archive info")
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(proto.EncodeVarint(uint64(archivedUpto))); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "error writing the archive info")
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return errors.Wrap(err, "error syncing the archive info")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errors.Wrap(err, "error closing the archive info")
+	}
+	return errors.Wrap(
+		os.Rename(tmpFile.Name(), filepath.Join(a.rootDir, archiveInfoFileName)),
+		"error renaming the archive info",
+	)
+}
+
+func (a *blockfileArchiver) objectName(fileNum int) string {
+	return fmt.Sprintf("%s/%s%06d", a.ledgerID, blockfilePrefix, fileNum)
+}
+
+// close waits for an in-progress archival, if any, to finish
+func (a *blockfileArchiver) close() {
+	if a == nil {
+		return
+	}
+	a.wg.Wait()
+}
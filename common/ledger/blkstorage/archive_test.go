@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockfileArchival(t *testing.T) {
+	archiveDir := testPath()
+	defer os.RemoveAll(archiveDir)
+	archiveConf := &ArchiveConf{
+		Store:        NewFileSystemArchiveStore(archiveDir),
+		RetainBlocks: 10,
+		CacheSize:    2,
+	}
+	conf := NewConf(testPath(), 8*1024).WithArchive(archiveConf)
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	blkfileMgr := blkfileMgrWrapper.blockfileMgr
+
+	blocks := testutil.ConstructTestBlocks(t, 100)
+	blkfileMgrWrapper.addBlocks(blocks)
+	archiveAllEligibleBlockfiles(t, blkfileMgr)
+
+	archiver := blkfileMgr.archiver
+	latestFileNum := blkfileMgr.cpInfo.latestFileChunkSuffixNum
+	require.True(t, archiver.archivedUpto > 0)
+	require.True(t, archiver.archivedUpto < latestFileNum)
+	for fileNum := 0; fileNum <= archiver.archivedUpto; fileNum++ {
+		require.FileExists(t, filepath.Join(archiveDir, archiver.objectName(fileNum)))
+	}
+	// the block file following the last archived block file contains some of the retained blocks
+	if archiver.archivedUpto+1 < latestFileNum {
+		nextFirstBlock, err := retrieveFirstBlockNumFromFile(blkfileMgr.rootDir, archiver.archivedUpto+2)
+		require.NoError(t, err)
+		require.True(t, nextFirstBlock+archiveConf.RetainBlocks > 99)
+	}
+	requireLocalArchivedBlockfiles(t, blkfileMgr, 2)
+
+	// the archived blocks are fetched transparently
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+	requireLocalArchivedBlockfiles(t, blkfileMgr, 2)
+	blkfileMgrWrapper.close()
+
+	// the cached copies are dropped on restart and the index can be rebuilt from the archived block files
+	env.provider.Close()
+	require.NoError(t, os.RemoveAll(conf.getIndexDir()))
+	env = newTestEnv(t, conf)
+	blkfileMgrWrapper = newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	require.Equal(t, archiver.archivedUpto, blkfileMgrWrapper.blockfileMgr.archiver.archivedUpto)
+	requireLocalArchivedBlockfiles(t, blkfileMgrWrapper.blockfileMgr, 2)
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+}
+
+func TestBlockfileArchivalRestart(t *testing.T) {
+	archiveDir := testPath()
+	defer os.RemoveAll(archiveDir)
+	conf := NewConf(testPath(), 8*1024).WithArchive(&ArchiveConf{
+		Store:        NewFileSystemArchiveStore(archiveDir),
+		RetainBlocks: 10,
+		CacheSize:    1,
+	})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	blocks := testutil.ConstructTestBlocks(t, 60)
+	blkfileMgrWrapper.addBlocks(blocks[:50])
+	archiveAllEligibleBlockfiles(t, blkfileMgrWrapper.blockfileMgr)
+	archivedUpto := blkfileMgrWrapper.blockfileMgr.archiver.archivedUpto
+	require.True(t, archivedUpto >= 0)
+	blkfileMgrWrapper.close()
+
+	blkfileMgrWrapper = newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	archiver := blkfileMgrWrapper.blockfileMgr.archiver
+	require.Equal(t, archivedUpto, archiver.archivedUpto)
+	requireLocalArchivedBlockfiles(t, blkfileMgrWrapper.blockfileMgr, 0)
+
+	blkfileMgrWrapper.addBlocks(blocks[50:])
+	archiveAllEligibleBlockfiles(t, blkfileMgrWrapper.blockfileMgr)
+	require.True(t, archiver.archivedUpto >= archivedUpto)
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+	requireLocalArchivedBlockfiles(t, blkfileMgrWrapper.blockfileMgr, 1)
+}
+
+func TestBlockfileArchivalFetchError(t *testing.T) {
+	archiveDir := testPath()
+	defer os.RemoveAll(archiveDir)
+	conf := NewConf(testPath(), 8*1024).WithArchive(&ArchiveConf{
+		Store:        NewFileSystemArchiveStore(archiveDir),
+		RetainBlocks: 0,
+		CacheSize:    1,
+	})
+	env := newTestEnv(t, conf)
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	blkfileMgr := blkfileMgrWrapper.blockfileMgr
+	blkfileMgrWrapper.addBlocks(testutil.ConstructTestBlocks(t, 30))
+	archiveAllEligibleBlockfiles(t, blkfileMgr)
+	require.True(t, blkfileMgr.archiver.archivedUpto > 0)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(archiveDir, "testLedger")))
+	_, err := blkfileMgr.retrieveBlockByNumber(0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error fetching the archived block file")
+}
+
+func TestFileSystemArchiveStore(t *testing.T) {
+	archiveDir := testPath()
+	defer os.RemoveAll(archiveDir)
+	store := NewFileSystemArchiveStore(archiveDir)
+
+	require.NoError(t, store.Put("ledger1/object1", bytes.NewReader([]byte("content1"))))
+	buf := &bytes.Buffer{}
+	require.NoError(t, store.Get("ledger1/object1", buf))
+	require.Equal(t, "content1", buf.String())
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(filepath.Join(archiveDir, "ledger1"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	err = store.Get("ledger1/non-existing-object", buf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error opening object [ledger1/non-existing-object]")
+}
+
+// archiveAllEligibleBlockfiles waits for the archival started in the background, if any, and then archives
+// the remaining eligible block files, as an archival is not started while an earlier one is in progress
+func archiveAllEligibleBlockfiles(t *testing.T, mgr *blockfileMgr) {
+	mgr.archiver.close()
+	require.NoError(t, mgr.archiver.archiveEligibleBlockfiles(mgr.cpInfo.lastBlockNumber, mgr.cpInfo.latestFileChunkSuffixNum))
+}
+
+func requireLocalArchivedBlockfiles(t *testing.T, mgr *blockfileMgr, expected int) {
+	numLocal := 0
+	for fileNum := 0; fileNum <= mgr.archiver.archivedUpto; fileNum++ {
+		if _, err := os.Stat(deriveBlockfilePath(mgr.rootDir, fileNum)); err == nil {
+			numLocal++
+		}
+	}
+	require.Equal(t, expected, numLocal)
+}
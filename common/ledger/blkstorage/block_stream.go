@@ -12,7 +12,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 )
 
@@ -38,6 +37,8 @@ type blockStream struct {
 	currentFileNum    int
 	endFileNum        int
 	currentFileStream *blockfileStream
+	// beforeOpen, if set, is invoked before opening the next file segment
+	beforeOpen func(fileNum int) error
 }
 
 // blockPlacementInfo captures the information related
@@ -46,6 +47,9 @@ type blockPlacementInfo struct {
 	fileNum          int
 	blockStartOffset int64
 	blockBytesOffset int64
+	// compressed indicates that the block is stored in a compressed record. In this
+	// case, blockBytesOffset is the offset of the compressed bytes in the file
+	compressed bool
 }
 
 ///////////////////////////////////
@@ -105,9 +109,12 @@ func (s *blockfileStream) nextBlockBytesAndPlacementInfo() ([]byte, *blockPlacem
 	if lenBytes, err = s.reader.Peek(peekBytes); err != nil {
 		return nil, nil, errors.Wrapf(err, "error peeking [%d] bytes from block file", peekBytes)
 	}
-	length, n := proto.DecodeVarint(lenBytes)
+	length, n, compressed, err := decodeRecordHeader(lenBytes)
+	if err != nil {
+		return nil, nil, err
+	}
 	if n == 0 {
-		// proto.DecodeVarint did not consume any byte at all which means that the bytes
+		// the bytes did not contain the complete record header, which means that the bytes
 		// representing the size of the block are partial bytes
 		if !moreContentAvailable {
 			return nil, nil, ErrUnexpectedEndOfBlockfile
@@ -120,19 +127,25 @@ func (s *blockfileStream) nextBlockBytesAndPlacementInfo() ([]byte, *blockPlacem
 			bytesExpected, remainingBytes, ErrUnexpectedEndOfBlockfile)
 		return nil, nil, ErrUnexpectedEndOfBlockfile
 	}
-	// skip the bytes representing the block size
+	// skip the bytes representing the record header
 	if _, err = s.reader.Discard(n); err != nil {
 		return nil, nil, errors.Wrapf(err, "error discarding [%d] bytes", n)
 	}
-	blockBytes := make([]byte, length)
-	if _, err = io.ReadAtLeast(s.reader, blockBytes, int(length)); err != nil {
+	recordBytes := make([]byte, length)
+	if _, err = io.ReadAtLeast(s.reader, recordBytes, int(length)); err != nil {
 		logger.Errorf("Error reading [%d] bytes from file number [%d], error: %s", length, s.fileNum, err)
 		return nil, nil, errors.Wrapf(err, "error reading [%d] bytes from file number [%d]", length, s.fileNum)
 	}
+	blockBytes, err := decodeRecordPayload(recordBytes, compressed)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "error reading block at offset [%d] in file number [%d]", s.currentOffset, s.fileNum)
+	}
 	blockPlacementInfo := &blockPlacementInfo{
 		fileNum:          s.fileNum,
 		blockStartOffset: s.currentOffset,
-		blockBytesOffset: s.currentOffset + int64(n)}
+		blockBytesOffset: s.currentOffset + int64(n),
+		compressed:       compressed,
+	}
 	s.currentOffset += int64(n) + int64(length)
 	logger.Debugf("Returning blockbytes - length=[%d], placementInfo={%s}", len(blockBytes), blockPlacementInfo)
 	return blockBytes, blockPlacementInfo, nil
@@ -150,7 +163,12 @@ func newBlockStream(rootDir string, startFileNum int, startOffset int64, endFile
 	if err != nil {
 		return nil, err
 	}
-	return &blockStream{rootDir, startFileNum, endFileNum, startFileStream}, nil
+	return &blockStream{
+		rootDir:           rootDir,
+		currentFileNum:    startFileNum,
+		endFileNum:        endFileNum,
+		currentFileStream: startFileStream,
+	}, nil
 }
 
 func (s *blockStream) moveToNextBlockfileStream() error {
@@ -159,6 +177,11 @@ func (s *blockStream) moveToNextBlockfileStream() error {
 		return err
 	}
 	s.currentFileNum++
+	if s.beforeOpen != nil {
+		if err = s.beforeOpen(s.currentFileNum); err != nil {
+			return err
+		}
+	}
 	if s.currentFileStream, err = newBlockfileStream(s.rootDir, s.currentFileNum, 0); err != nil {
 		return err
 	}
@@ -194,6 +217,6 @@ func (s *blockStream) close() error {
 }
 
 func (i *blockPlacementInfo) String() string {
-	return fmt.Sprintf("fileNum=[%d], startOffset=[%d], bytesOffset=[%d]",
-		i.fileNum, i.blockStartOffset, i.blockBytesOffset)
+	return fmt.Sprintf("fileNum=[%d], startOffset=[%d], bytesOffset=[%d], compressed=[%t]",
+		i.fileNum, i.blockStartOffset, i.blockBytesOffset, i.compressed)
 }
@@ -40,6 +40,7 @@ type blockfileMgr struct {
 	cpInfoCond        *sync.Cond
 	currentFileWriter *blockfileWriter
 	bcInfo            atomic.Value
+	archiver          *blockfileArchiver
 }
 
 /*
@@ -133,6 +134,10 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *IndexConfig, indexStore
 		panic(fmt.Sprintf("error in block index: %s", err))
 	}
 
+	if mgr.archiver, err = newBlockfileArchiver(id, rootDir, conf.archive); err != nil {
+		panic(fmt.Sprintf("error in block file archiver: %s", err))
+	}
+
 	// Update the manager with the checkpoint info and the file writer
 	mgr.cpInfo = cpInfo
 	mgr.currentFileWriter = currentFileWriter
@@ -209,6 +214,7 @@ func deriveBlockfilePath(rootDir string, suffixNum int) string {
 }
 
 func (mgr *blockfileMgr) close() {
+	mgr.archiver.close()
 	mgr.currentFileWriter.close()
 }
 
@@ -261,9 +267,11 @@ func (mgr *blockfileMgr) addBlock(block *common.Block) error {
 	txOffsets := info.txOffsets
 	currentOffset := mgr.cpInfo.latestFileChunksize
 
-	blockBytesLen := len(blockBytes)
-	blockBytesEncodedLen := proto.EncodeVarint(uint64(blockBytesLen))
-	totalBytesToAppend := blockBytesLen + len(blockBytesEncodedLen)
+	recordHeader, recordBytes, err := encodeBlockRecord(blockBytes, mgr.conf.compressBlocks)
+	if err != nil {
+		return err
+	}
+	totalBytesToAppend := len(recordHeader) + len(recordBytes)
 
 	//Determine if we need to start a new file since the size of this block
 	//exceeds the amount of space left in the current file
@@ -271,11 +279,11 @@ func (mgr *blockfileMgr) addBlock(block *common.Block) error {
 		mgr.moveToNextFile()
 		currentOffset = 0
 	}
-	//append blockBytesEncodedLen to the file
-	err = mgr.currentFileWriter.append(blockBytesEncodedLen, false)
+	//append the record header (i.e., the length of the bytes that follow) to the file
+	err = mgr.currentFileWriter.append(recordHeader, false)
 	if err == nil {
-		//append the actual block bytes to the file
-		err = mgr.currentFileWriter.append(blockBytes, true)
+		//append the actual block bytes, or the compressed block bytes, to the file
+		err = mgr.currentFileWriter.append(recordBytes, true)
 	}
 	if err != nil {
 		truncateErr := mgr.currentFileWriter.truncateFile(mgr.cpInfo.latestFileChunksize)
@@ -304,20 +312,25 @@ func (mgr *blockfileMgr) addBlock(block *common.Block) error {
 	//Index block file location pointer updated with file suffex and offset for the new block
 	blockFLP := &fileLocPointer{fileSuffixNum: newCPInfo.latestFileChunkSuffixNum}
 	blockFLP.offset = currentOffset
-	// shift the txoffset because we prepend length of bytes before block bytes
-	for _, txOffset := range txOffsets {
-		txOffset.loc.offset += len(blockBytesEncodedLen)
+	// shift the txoffset because we prepend length of bytes before block bytes. For a compressed
+	// block, the txoffsets remain relative to the decompressed block bytes
+	if !mgr.conf.compressBlocks {
+		for _, txOffset := range txOffsets {
+			txOffset.loc.offset += len(recordHeader)
+		}
 	}
 	//save the index in the database
 	if err = mgr.index.indexBlock(&blockIdxInfo{
 		blockNum: block.Header.Number, blockHash: blockHash,
-		flp: blockFLP, txOffsets: txOffsets, metadata: block.Metadata}); err != nil {
+		flp: blockFLP, txOffsets: txOffsets, metadata: block.Metadata,
+		compressed: mgr.conf.compressBlocks}); err != nil {
 		return err
 	}
 
 	//update the checkpoint info (for storage) and the blockchain info (for APIs) in the manager
 	mgr.updateCheckpoint(newCPInfo)
 	mgr.updateBlockchainInfo(blockHash, block)
+	mgr.archiver.blockAdded(block.Header.Number, newCPInfo.latestFileChunkSuffixNum)
 	return nil
 }
 
@@ -364,7 +377,7 @@ func (mgr *blockfileMgr) syncIndex() error {
 
 	//open a blockstream to the file location that was stored in the index
 	var stream *blockStream
-	if stream, err = newBlockStream(mgr.rootDir, startFileNum, int64(startOffset), endFileNum); err != nil {
+	if stream, err = mgr.newBlockStream(startFileNum, int64(startOffset), endFileNum); err != nil {
 		return err
 	}
 	var blockBytes []byte
@@ -397,10 +410,13 @@ func (mgr *blockfileMgr) syncIndex() error {
 		}
 
 		//The blockStartOffset will get applied to the txOffsets prior to indexing within indexBlock(),
-		//therefore just shift by the difference between blockBytesOffset and blockStartOffset
-		numBytesToShift := int(blockPlacementInfo.blockBytesOffset - blockPlacementInfo.blockStartOffset)
-		for _, offset := range info.txOffsets {
-			offset.loc.offset += numBytesToShift
+		//therefore just shift by the difference between blockBytesOffset and blockStartOffset.
+		//For a compressed block, the txOffsets remain relative to the decompressed block bytes
+		if !blockPlacementInfo.compressed {
+			numBytesToShift := int(blockPlacementInfo.blockBytesOffset - blockPlacementInfo.blockStartOffset)
+			for _, offset := range info.txOffsets {
+				offset.loc.offset += numBytesToShift
+			}
 		}
 
 		//Update the blockIndexInfo with what was actually stored in file system
@@ -410,6 +426,7 @@ func (mgr *blockfileMgr) syncIndex() error {
 			locPointer: locPointer{offset: int(blockPlacementInfo.blockStartOffset)}}
 		blockIdxInfo.txOffsets = info.txOffsets
 		blockIdxInfo.metadata = info.metadata
+		blockIdxInfo.compressed = blockPlacementInfo.compressed
 
 		logger.Debugf("syncIndex() indexing block [%d]", blockIdxInfo.blockNum)
 		if err = mgr.index.indexBlock(blockIdxInfo); err != nil {
@@ -540,7 +557,12 @@ func (mgr *blockfileMgr) fetchTransactionEnvelope(lp *fileLocPointer) (*common.E
 	logger.Debugf("Entering fetchTransactionEnvelope() %v\n", lp)
 	var err error
 	var txEnvelopeBytes []byte
-	if txEnvelopeBytes, err = mgr.fetchRawBytes(lp); err != nil {
+	if lp.inCompressedBlock {
+		txEnvelopeBytes, err = mgr.fetchTxBytesFromCompressedBlock(lp)
+	} else {
+		txEnvelopeBytes, err = mgr.fetchRawBytes(lp)
+	}
+	if err != nil {
 		return nil, err
 	}
 	_, n := proto.DecodeVarint(txEnvelopeBytes)
@@ -548,6 +570,9 @@ func (mgr *blockfileMgr) fetchTransactionEnvelope(lp *fileLocPointer) (*common.E
 }
 
 func (mgr *blockfileMgr) fetchBlockBytes(lp *fileLocPointer) ([]byte, error) {
+	if err := mgr.archiver.ensureLocal(lp.fileSuffixNum); err != nil {
+		return nil, err
+	}
 	stream, err := newBlockfileStream(mgr.rootDir, lp.fileSuffixNum, int64(lp.offset))
 	if err != nil {
 		return nil, err
@@ -560,7 +585,26 @@ func (mgr *blockfileMgr) fetchBlockBytes(lp *fileLocPointer) ([]byte, error) {
 	return b, nil
 }
 
+// fetchTxBytesFromCompressedBlock decompresses the block that contains the transaction and
+// returns the bytes of the transaction, which are located relative to the decompressed block bytes
+func (mgr *blockfileMgr) fetchTxBytesFromCompressedBlock(lp *fileLocPointer) ([]byte, error) {
+	blockBytes, err := mgr.fetchBlockBytes(&fileLocPointer{
+		fileSuffixNum: lp.fileSuffixNum,
+		locPointer:    locPointer{offset: lp.blockOffset},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lp.offset+lp.bytesLength > len(blockBytes) {
+		return nil, errors.Errorf("transaction location [%s] exceeds the size of the decompressed block [%d]", lp, len(blockBytes))
+	}
+	return blockBytes[lp.offset : lp.offset+lp.bytesLength], nil
+}
+
 func (mgr *blockfileMgr) fetchRawBytes(lp *fileLocPointer) ([]byte, error) {
+	if err := mgr.archiver.ensureLocal(lp.fileSuffixNum); err != nil {
+		return nil, err
+	}
 	filePath := deriveBlockfilePath(mgr.rootDir, lp.fileSuffixNum)
 	reader, err := newBlockfileReader(filePath)
 	if err != nil {
@@ -574,6 +618,21 @@ func (mgr *blockfileMgr) fetchRawBytes(lp *fileLocPointer) ([]byte, error) {
 	return b, nil
 }
 
+// newBlockStream opens a blockStream that fetches the archived block files, if any, as the stream reaches these
+func (mgr *blockfileMgr) newBlockStream(startFileNum int, startOffset int64, endFileNum int) (*blockStream, error) {
+	if err := mgr.archiver.ensureLocal(startFileNum); err != nil {
+		return nil, err
+	}
+	stream, err := newBlockStream(mgr.rootDir, startFileNum, startOffset, endFileNum)
+	if err != nil {
+		return nil, err
+	}
+	if mgr.archiver != nil {
+		stream.beforeOpen = mgr.archiver.ensureLocal
+	}
+	return stream, nil
+}
+
 //Get the current checkpoint information that is stored in the database
 func (mgr *blockfileMgr) loadCurrentInfo() (*checkpointInfo, error) {
 	var b []byte
@@ -10,7 +10,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash"
+	"io"
 	"path"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/golang/protobuf/proto"
@@ -30,9 +32,11 @@ const (
 	blockNumTranNumIdxKeyPrefix = 'a'
 	indexCheckpointKeyStr       = "indexCheckpointKey"
 
-	snapshotFileFormat       = byte(1)
-	snapshotDataFileName     = "txids.data"
-	snapshotMetadataFileName = "txids.metadata"
+	snapshotFileFormat            = byte(1)
+	snapshotDataFileName          = "txids.data"
+	snapshotMetadataFileName      = "txids.metadata"
+	snapshotDeltaDataFileName     = "txids_delta.data"
+	snapshotDeltaMetadataFileName = "txids_delta.metadata"
 )
 
 var indexCheckpointKey = []byte(indexCheckpointKeyStr)
@@ -44,6 +48,9 @@ type blockIdxInfo struct {
 	flp       *fileLocPointer
 	txOffsets []*txindexInfo
 	metadata  *common.BlockMetadata
+	// compressed indicates that the block is stored compressed and hence the
+	// txOffsets are relative to the decompressed block bytes
+	compressed bool
 }
 
 type blockIndex struct {
@@ -104,7 +111,7 @@ func (index *blockIndex) indexBlock(blockIdxInfo *blockIdxInfo) error {
 	//Index3 Used to find a transaction by its transaction id
 	if index.isAttributeIndexed(IndexableAttrTxID) {
 		for i, txoffset := range txOffsets {
-			txFlp := newFileLocationPointer(flp.fileSuffixNum, flp.offset, txoffset.loc)
+			txFlp := newTxLocationPointer(flp, txoffset.loc, blockIdxInfo.compressed)
 			logger.Debugf("Adding txLoc [%s] for tx ID: [%s] to txid-index", txFlp, txoffset.txID)
 			txFlpBytes, marshalErr := txFlp.marshal()
 			if marshalErr != nil {
@@ -130,7 +137,7 @@ func (index *blockIndex) indexBlock(blockIdxInfo *blockIdxInfo) error {
 	//Index4 - Store BlockNumTranNum will be used to query history data
 	if index.isAttributeIndexed(IndexableAttrBlockNumTranNum) {
 		for i, txoffset := range txOffsets {
-			txFlp := newFileLocationPointer(flp.fileSuffixNum, flp.offset, txoffset.loc)
+			txFlp := newTxLocationPointer(flp, txoffset.loc, blockIdxInfo.compressed)
 			logger.Debugf("Adding txLoc [%s] for tx number:[%d] ID: [%s] to blockNumTranNum index", txFlp, i, txoffset.txID)
 			txFlpBytes, marshalErr := txFlp.marshal()
 			if marshalErr != nil {
@@ -257,12 +264,23 @@ func (index *blockIndex) getTXLocByBlockNumTranNum(blockNum uint64, tranNum uint
 }
 
 func (index *blockIndex) exportUniqueTxIDs(dir string, hasher hash.Hash) (map[string][]byte, error) {
+	return index.exportUniqueTxIDsSince(dir, snapshotDataFileName, snapshotMetadataFileName, 0, hasher)
+}
+
+// exportUniqueTxIDsSince exports the TxIDs that appear for the first time in the block `startBlockNum` or
+// in a later block. A TxID that appears in an earlier block is skipped, even if it appears again
+// (as a duplicate) in a later block, as such a TxID is expected to be present in the earlier snapshot
+func (index *blockIndex) exportUniqueTxIDsSince(
+	dir, dataFileName, metadataFileName string,
+	startBlockNum uint64,
+	hasher hash.Hash,
+) (map[string][]byte, error) {
 	if !index.isAttributeIndexed(IndexableAttrTxID) {
 		return nil, ErrAttrNotIndexed
 	}
 
 	// create the data file
-	dataFile, err := snapshot.CreateFile(path.Join(dir, snapshotDataFileName), snapshotFileFormat, hasher)
+	dataFile, err := snapshot.CreateFile(path.Join(dir, dataFileName), snapshotFileFormat, hasher)
 	if err != nil {
 		return nil, err
 	}
@@ -289,6 +307,17 @@ func (index *blockIndex) exportUniqueTxIDs(dir string, hasher hash.Hash) (map[st
 			continue
 		}
 		previousTxID = txID
+		if startBlockNum > 0 {
+			// the keys for a TxID are sorted by the block number, so the first key
+			// carries the block in which the TxID appeared for the first time
+			blkNum, err := retrieveBlockNumFromTxIDKey(dbItr.Key())
+			if err != nil {
+				return nil, err
+			}
+			if blkNum < startBlockNum {
+				continue
+			}
+		}
 		if err := dataFile.EncodeString(txID); err != nil {
 			return nil, err
 		}
@@ -301,6 +330,88 @@ func (index *blockIndex) exportUniqueTxIDs(dir string, hasher hash.Hash) (map[st
 	}
 
 	// create the metadata file
+	hasher.Reset()
+	metadataFile, err := snapshot.CreateFile(path.Join(dir, metadataFileName), snapshotFileFormat, hasher)
+	if err != nil {
+		return nil, err
+	}
+	defer metadataFile.Close()
+
+	if err = metadataFile.EncodeUVarint(numTxIDs); err != nil {
+		return nil, err
+	}
+	metadataHash, err := metadataFile.Done()
+
+	return map[string][]byte{
+		dataFileName:     dataHash,
+		metadataFileName: metadataHash,
+	}, nil
+}
+
+// MergeIncrementalTxIDs combines the TxIDs files present in the baseSnapshotDir with the TxIDs delta files present
+// in the incrementalSnapshotDir (as generated by the function `BlockStore.ExportTxIdsSince`) and generates, in the
+// specified dir, the files that are identical to the ones that the function `BlockStore.ExportTxIds` would have
+// generated at the height of the incremental snapshot
+func MergeIncrementalTxIDs(dir, baseSnapshotDir, incrementalSnapshotDir string, hasher hash.Hash) (map[string][]byte, error) {
+	base, err := openTxIDsReader(
+		path.Join(baseSnapshotDir, snapshotDataFileName),
+		path.Join(baseSnapshotDir, snapshotMetadataFileName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer base.close()
+
+	delta, err := openTxIDsReader(
+		path.Join(incrementalSnapshotDir, snapshotDeltaDataFileName),
+		path.Join(incrementalSnapshotDir, snapshotDeltaMetadataFileName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer delta.close()
+
+	dataFile, err := snapshot.CreateFile(path.Join(dir, snapshotDataFileName), snapshotFileFormat, hasher)
+	if err != nil {
+		return nil, err
+	}
+	defer dataFile.Close()
+
+	var numTxIDs uint64 = 0
+	for base.current != nil || delta.current != nil {
+		var r *txIDsReader
+		switch {
+		case delta.current == nil:
+			r = base
+		case base.current == nil:
+			r = delta
+		default:
+			switch c := compareTxIDs(*base.current, *delta.current); {
+			case c < 0:
+				r = base
+			case c > 0:
+				r = delta
+			default:
+				if err := base.next(); err != nil {
+					return nil, err
+				}
+				r = delta
+			}
+		}
+		if err := dataFile.EncodeString(*r.current); err != nil {
+			return nil, err
+		}
+		numTxIDs++
+		if err := r.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	dataHash, err := dataFile.Done()
+	if err != nil {
+		return nil, err
+	}
+
 	hasher.Reset()
 	metadataFile, err := snapshot.CreateFile(path.Join(dir, snapshotMetadataFileName), snapshotFileFormat, hasher)
 	if err != nil {
@@ -312,6 +423,9 @@ func (index *blockIndex) exportUniqueTxIDs(dir string, hasher hash.Hash) (map[st
 		return nil, err
 	}
 	metadataHash, err := metadataFile.Done()
+	if err != nil {
+		return nil, err
+	}
 
 	return map[string][]byte{
 		snapshotDataFileName:     dataHash,
@@ -319,6 +433,72 @@ func (index *blockIndex) exportUniqueTxIDs(dir string, hasher hash.Hash) (map[st
 	}, nil
 }
 
+// txIDsReader reads the TxIDs from a pair of TxIDs snapshot files. The field `current`
+// holds the TxID that is read most recently and is nil when the data is exhausted
+type txIDsReader struct {
+	dataFile     *snapshot.FileReader
+	numRemaining uint64
+	current      *string
+}
+
+func openTxIDsReader(dataFilePath, metadataFilePath string) (*txIDsReader, error) {
+	metadataFile, err := snapshot.OpenFile(metadataFilePath, snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	defer metadataFile.Close()
+	numTxIDs, err := metadataFile.DecodeUVarInt()
+	if err != nil {
+		return nil, err
+	}
+
+	dataFile, err := snapshot.OpenFile(dataFilePath, snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	r := &txIDsReader{
+		dataFile:     dataFile,
+		numRemaining: numTxIDs,
+	}
+	if err := r.next(); err != nil {
+		dataFile.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *txIDsReader) next() error {
+	if r.numRemaining == 0 {
+		r.current = nil
+		return nil
+	}
+	txID, err := r.dataFile.DecodeString()
+	if err != nil {
+		return err
+	}
+	r.numRemaining--
+	r.current = &txID
+	return nil
+}
+
+func (r *txIDsReader) close() {
+	if r == nil {
+		return
+	}
+	r.dataFile.Close()
+}
+
+// compareTxIDs compares the TxIDs in the order in which these appear in the txid index, i.e., radix-sort/shortlex
+func compareTxIDs(txID1, txID2 string) int {
+	if len(txID1) != len(txID2) {
+		if len(txID1) < len(txID2) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(txID1, txID2)
+}
+
 func constructBlockNumKey(blockNum uint64) []byte {
 	blkNumBytes := util.EncodeOrderPreservingVarUint64(blockNum)
 	return append([]byte{blockNumIdxKeyPrefix}, blkNumBytes...)
@@ -360,6 +540,23 @@ func retrieveTxID(encodedTxIDKey []byte) (string, error) {
 	return string(remainingBytes[:int(txIDLen)]), nil
 }
 
+// retrieveBlockNumFromTxIDKey takes input an encoded txid key of the format `prefix:len(TxID):TxID:BlkNum:TxNum`
+// and returns the BlkNum from this
+func retrieveBlockNumFromTxIDKey(encodedTxIDKey []byte) (uint64, error) {
+	txID, err := retrieveTxID(encodedTxIDKey)
+	if err != nil {
+		return 0, err
+	}
+	remainingBytes := encodedTxIDKey[utf8.RuneLen(txIDIdxKeyPrefix):]
+	_, n, _ := util.DecodeOrderPreservingVarUint64(remainingBytes)
+	remainingBytes = remainingBytes[n+len(txID):]
+	blkNum, _, err := util.DecodeOrderPreservingVarUint64(remainingBytes)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "invalid txIDKey {%x}", encodedTxIDKey)
+	}
+	return blkNum, nil
+}
+
 type rangeScan struct {
 	startKey []byte
 	stopKey  []byte
@@ -407,6 +604,11 @@ func (lp *locPointer) String() string {
 type fileLocPointer struct {
 	fileSuffixNum int
 	locPointer
+	// inCompressedBlock is set for a transaction that is contained in a compressed block.
+	// In this case, the locPointer is relative to the decompressed block bytes and the
+	// blockOffset is the offset of the block in the file
+	inCompressedBlock bool
+	blockOffset       int
 }
 
 func newFileLocationPointer(fileSuffixNum int, beginningOffset int, relativeLP *locPointer) *fileLocPointer {
@@ -416,6 +618,20 @@ func newFileLocationPointer(fileSuffixNum int, beginningOffset int, relativeLP *
 	return flp
 }
 
+// newTxLocationPointer returns the location of a transaction given the location of
+// the block in the file and the location of the transaction relative to the block
+func newTxLocationPointer(blockFLP *fileLocPointer, txLP *locPointer, compressedBlock bool) *fileLocPointer {
+	if !compressedBlock {
+		return newFileLocationPointer(blockFLP.fileSuffixNum, blockFLP.offset, txLP)
+	}
+	return &fileLocPointer{
+		fileSuffixNum:     blockFLP.fileSuffixNum,
+		locPointer:        *txLP,
+		inCompressedBlock: true,
+		blockOffset:       blockFLP.offset,
+	}
+}
+
 func (flp *fileLocPointer) marshal() ([]byte, error) {
 	buffer := proto.NewBuffer([]byte{})
 	e := buffer.EncodeVarint(uint64(flp.fileSuffixNum))
@@ -430,6 +646,14 @@ func (flp *fileLocPointer) marshal() ([]byte, error) {
 	if e != nil {
 		return nil, errors.Wrapf(e, "unexpected error while marshaling fileLocPointer [%s]", flp)
 	}
+	// the block offset is appended only for the transactions in a compressed block so
+	// that the locations of the remaining transactions and blocks keep the prior encoding
+	if flp.inCompressedBlock {
+		e = buffer.EncodeVarint(uint64(flp.blockOffset))
+		if e != nil {
+			return nil, errors.Wrapf(e, "unexpected error while marshaling fileLocPointer [%s]", flp)
+		}
+	}
 	return buffer.Bytes(), nil
 }
 
@@ -451,10 +675,23 @@ func (flp *fileLocPointer) unmarshal(b []byte) error {
 		return errors.Wrapf(e, "unexpected error while unmarshaling bytes [%#v] into fileLocPointer", b)
 	}
 	flp.bytesLength = int(i)
+	i, e = buffer.DecodeVarint()
+	if e == io.ErrUnexpectedEOF {
+		// the block offset is present only for the transactions in a compressed block
+		return nil
+	}
+	if e != nil {
+		return errors.Wrapf(e, "unexpected error while unmarshaling bytes [%#v] into fileLocPointer", b)
+	}
+	flp.inCompressedBlock = true
+	flp.blockOffset = int(i)
 	return nil
 }
 
 func (flp *fileLocPointer) String() string {
+	if flp.inCompressedBlock {
+		return fmt.Sprintf("fileSuffixNum=%d, %s, compressedBlockOffset=%d", flp.fileSuffixNum, flp.locPointer.String(), flp.blockOffset)
+	}
 	return fmt.Sprintf("fileSuffixNum=%d, %s", flp.fileSuffixNum, flp.locPointer.String())
 }
 
@@ -313,6 +313,105 @@ func TestExportUniqueTxIDs(t *testing.T) {
 	verifyExportedTxIDs(t, testSnapshotDir, fileHashes, "txid-1", "txid-2", "txid-3", "txid-4", "txid-0000000", configTxID) // "txid-1", and "txid-3 appears once and Txids appear in radix sort order
 }
 
+func TestExportUniqueTxIDsSinceAndMerge(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testledger")
+	defer blkfileMgrWrapper.close()
+	blkfileMgr := blkfileMgrWrapper.blockfileMgr
+
+	bg, gb := testutil.NewBlockGenerator(t, "myChannel", false)
+	require.NoError(t, blkfileMgr.addBlock(gb))
+	configTxID, err := protoutil.GetOrComputeTxIDFromEnvelope(gb.Data.Data[0])
+	require.NoError(t, err)
+	block1 := bg.NextBlockWithTxid(
+		[][]byte{
+			[]byte("tx with id=txid-3"),
+			[]byte("tx with id=txid-1"),
+		},
+		[]string{"txid-3", "txid-1"},
+	)
+	require.NoError(t, blkfileMgr.addBlock(block1))
+
+	baseSnapshotDir := testPath()
+	defer os.RemoveAll(baseSnapshotDir)
+	_, err = blkfileMgr.index.exportUniqueTxIDs(baseSnapshotDir, sha256.New())
+	require.NoError(t, err)
+
+	block2 := bg.NextBlockWithTxid(
+		[][]byte{
+			[]byte("tx with id=txid-0000000"),
+			[]byte("tx with id=txid-2"),
+			[]byte("another tx with existing id=txid-1"),
+		},
+		[]string{"txid-0000000", "txid-2", "txid-1"},
+	)
+	require.NoError(t, blkfileMgr.addBlock(block2))
+
+	// the incremental export skips the TxIDs that appeared before block-2, including the duplicate txid-1
+	incrementalSnapshotDir := testPath()
+	defer os.RemoveAll(incrementalSnapshotDir)
+	fileHashes, err := blkfileMgrWrapper.blockfileMgr.index.exportUniqueTxIDsSince(
+		incrementalSnapshotDir,
+		snapshotDeltaDataFileName,
+		snapshotDeltaMetadataFileName,
+		2,
+		sha256.New(),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileHashes, 2)
+	require.Equal(t, []string{"txid-2", "txid-0000000"}, readTxIDsForTest(t,
+		path.Join(incrementalSnapshotDir, snapshotDeltaDataFileName),
+		path.Join(incrementalSnapshotDir, snapshotDeltaMetadataFileName),
+	))
+
+	// merging the base and the incremental exports produces the full export at the latest height
+	fullSnapshotDir := testPath()
+	defer os.RemoveAll(fullSnapshotDir)
+	expectedFileHashes, err := blkfileMgr.index.exportUniqueTxIDs(fullSnapshotDir, sha256.New())
+	require.NoError(t, err)
+
+	mergedSnapshotDir := testPath()
+	require.NoError(t, os.MkdirAll(mergedSnapshotDir, 0700))
+	defer os.RemoveAll(mergedSnapshotDir)
+	mergedFileHashes, err := MergeIncrementalTxIDs(mergedSnapshotDir, baseSnapshotDir, incrementalSnapshotDir, sha256.New())
+	require.NoError(t, err)
+	require.Equal(t, expectedFileHashes, mergedFileHashes)
+	verifyExportedTxIDs(t, mergedSnapshotDir, mergedFileHashes, "txid-1", "txid-2", "txid-3", "txid-0000000", configTxID)
+
+	// base snapshot files missing
+	_, err = MergeIncrementalTxIDs(mergedSnapshotDir, testPath(), incrementalSnapshotDir, sha256.New())
+	require.Contains(t, err.Error(), "error while opening the snapshot file")
+}
+
+func TestRetrieveBlockNumFromTxIDKey(t *testing.T) {
+	blkNum, err := retrieveBlockNumFromTxIDKey(constructTxIDKey("txid-1", 25, 3))
+	require.NoError(t, err)
+	require.Equal(t, uint64(25), blkNum)
+
+	_, err = retrieveBlockNumFromTxIDKey([]byte{txIDIdxKeyPrefix})
+	require.Error(t, err)
+}
+
+func TestCompareTxIDs(t *testing.T) {
+	require.Equal(t, -1, compareTxIDs("txid-2", "txid-00"))
+	require.Equal(t, 1, compareTxIDs("txid-00", "txid-2"))
+	require.Equal(t, -1, compareTxIDs("txid-1", "txid-2"))
+	require.Equal(t, 0, compareTxIDs("txid-1", "txid-1"))
+}
+
+func readTxIDsForTest(t *testing.T, dataFile, metadataFile string) []string {
+	r, err := openTxIDsReader(dataFile, metadataFile)
+	require.NoError(t, err)
+	defer r.close()
+	txIDs := []string{}
+	for r.current != nil {
+		txIDs = append(txIDs, *r.current)
+		require.NoError(t, r.next())
+	}
+	return txIDs
+}
+
 func TestExportUniqueTxIDsWhenTxIDsNotIndexed(t *testing.T) {
 	env := newTestEnvSelectiveIndexing(t, NewConf(testPath(), 0), []IndexableAttr{IndexableAttrBlockNum}, &disabled.Provider{})
 	defer env.Cleanup()
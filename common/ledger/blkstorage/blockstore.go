@@ -97,6 +97,22 @@ func (store *BlockStore) ExportTxIds(dir string, hasher hash.Hash) (map[string][
 	return store.fileMgr.index.exportUniqueTxIDs(dir, hasher)
 }
 
+// ExportTxIdsSince creates two files, txids_delta.data and txids_delta.metadata, in the specified dir and returns
+// a map that contains the mapping between the names of the files and their hashes. The files contain only the TxIDs
+// that appear for the first time in the block `startBlockNum` or later, i.e., the TxIDs that are not present
+// in a snapshot generated at the height `startBlockNum`. The format of the files is the same as that of the files
+// generated by the function `ExportTxIds` and the files can be combined with the files of the earlier snapshot
+// by the function `MergeIncrementalTxIDs`
+func (store *BlockStore) ExportTxIdsSince(dir string, startBlockNum uint64, hasher hash.Hash) (map[string][]byte, error) {
+	return store.fileMgr.index.exportUniqueTxIDsSince(
+		dir,
+		snapshotDeltaDataFileName,
+		snapshotDeltaMetadataFileName,
+		startBlockNum,
+		hasher,
+	)
+}
+
 // Shutdown shuts down the block store
 func (store *BlockStore) Shutdown() {
 	logger.Debugf("closing fs blockStore:%s", store.id)
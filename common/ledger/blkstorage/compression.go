@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// A block is stored in a block file as a record. An uncompressed record consists of the varint
+// encoded length of the serialized block followed by the serialized block. A compressed record
+// begins with compressedRecordMarker and the codec used for the compression, followed by the
+// varint encoded length of the compressed bytes and the compressed bytes. As the length of a
+// serialized block is never zero, the marker keeps the two record formats distinguishable and
+// allows the block files written before enabling the compression to be read as is.
+const (
+	compressedRecordMarker = byte(0x00)
+	zstdCodec              = byte(0x01)
+	zstdCompressionLevel   = 3
+)
+
+// encodeBlockRecord returns the header and the payload of the record for the given serialized block
+func encodeBlockRecord(blockBytes []byte, compress bool) ([]byte, []byte, error) {
+	if !compress {
+		return proto.EncodeVarint(uint64(len(blockBytes))), blockBytes, nil
+	}
+	compressedBytes, err := zstdCompress(blockBytes)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "error compressing block")
+	}
+	header := []byte{compressedRecordMarker, zstdCodec}
+	header = append(header, proto.EncodeVarint(uint64(len(compressedBytes)))...)
+	return header, compressedBytes, nil
+}
+
+// decodeRecordHeader decodes the header of a record from the given bytes and returns the length of the
+// payload, the number of bytes in the header, and whether the payload is compressed. A zero header length
+// indicates that the supplied bytes contain only a part of the header
+func decodeRecordHeader(b []byte) (uint64, int, bool, error) {
+	length, n := proto.DecodeVarint(b)
+	if n == 0 || length != 0 {
+		return length, n, false, nil
+	}
+	if len(b) < 2 {
+		return 0, 0, true, nil
+	}
+	if b[1] != zstdCodec {
+		return 0, 0, true, errors.Errorf("unsupported block compression codec [%d]", b[1])
+	}
+	length, n = proto.DecodeVarint(b[2:])
+	if n == 0 {
+		return 0, 0, true, nil
+	}
+	return length, n + 2, true, nil
+}
+
+func decodeRecordPayload(payload []byte, compressed bool) ([]byte, error) {
+	if !compressed {
+		return payload, nil
+	}
+	blockBytes, err := zstdDecompress(payload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error decompressing block")
+	}
+	return blockBytes, nil
+}
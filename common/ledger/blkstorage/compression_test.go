@@ -0,0 +1,198 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedBlocksReadWrite(t *testing.T) {
+	env := newTestEnv(t, NewConfWithCompression(testPath(), 0))
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	blkfileMgrWrapper.addBlocks(blocks)
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+
+	itr, err := blkfileMgrWrapper.blockfileMgr.retrieveBlocks(0)
+	require.NoError(t, err)
+	defer itr.Close()
+	for _, expectedBlock := range blocks {
+		block, err := itr.Next()
+		require.NoError(t, err)
+		require.Equal(t, expectedBlock, block)
+	}
+}
+
+func TestCompressedBlocksAreSmaller(t *testing.T) {
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	for _, block := range blocks {
+		blockBytes, _, err := serializeBlock(block)
+		require.NoError(t, err)
+		header, payload, err := encodeBlockRecord(blockBytes, true)
+		require.NoError(t, err)
+		require.Equal(t, []byte{compressedRecordMarker, zstdCodec}, header[:2])
+		decodedBytes, err := decodeRecordPayload(payload, true)
+		require.NoError(t, err)
+		require.Equal(t, blockBytes, decodedBytes)
+	}
+
+	blocks = testutil.ConstructTestBlocks(t, 1)
+	block := blocks[0]
+	block.Data.Data = append(block.Data.Data, block.Data.Data...)
+	blockBytes, _, err := serializeBlock(block)
+	require.NoError(t, err)
+	_, payload, err := encodeBlockRecord(blockBytes, true)
+	require.NoError(t, err)
+	require.True(t, len(payload) < len(blockBytes))
+}
+
+func TestCompressionToggledOnExistingLedger(t *testing.T) {
+	blockStorageDir := testPath()
+	defer os.RemoveAll(blockStorageDir)
+	blocks := testutil.ConstructTestBlocks(t, 30)
+
+	confs := []*Conf{
+		NewConf(blockStorageDir, 0),
+		NewConfWithCompression(blockStorageDir, 0),
+		NewConf(blockStorageDir, 0),
+	}
+	for i, conf := range confs {
+		env := newTestEnv(t, conf)
+		blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+		blkfileMgrWrapper.addBlocks(blocks[i*10 : (i+1)*10])
+		verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks[:(i+1)*10])
+		blkfileMgrWrapper.close()
+		env.provider.Close()
+	}
+
+	// the index rebuilt from the block files is expected to locate
+	// the blocks and the transactions in both the record formats
+	require.NoError(t, os.RemoveAll(confs[0].getIndexDir()))
+	env := newTestEnv(t, confs[0])
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+}
+
+func TestCompressedBlocksFileRolling(t *testing.T) {
+	blocks := testutil.ConstructTestBlocks(t, 100)
+	env := newTestEnv(t, NewConfWithCompression(testPath(), 8*1024))
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	blkfileMgrWrapper.addBlocks(blocks)
+	require.True(t, blkfileMgrWrapper.blockfileMgr.cpInfo.latestFileChunkSuffixNum > 0)
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+}
+
+func TestCompressedBlockCrashDuringWriting(t *testing.T) {
+	env := newTestEnvSelectiveIndexing(t, NewConfWithCompression(testPath(), 0), attrsToIndex, &disabled.Provider{})
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	blkfileMgrWrapper.addBlocks(blocks[:9])
+	cpInfo := blkfileMgrWrapper.blockfileMgr.cpInfo
+
+	// simulate a crash after writing a part of the compressed record for the last block
+	blockBytes, _, err := serializeBlock(blocks[9])
+	require.NoError(t, err)
+	header, payload, err := encodeBlockRecord(blockBytes, true)
+	require.NoError(t, err)
+	w := blkfileMgrWrapper.blockfileMgr.currentFileWriter
+	require.NoError(t, w.append(header, true))
+	require.NoError(t, w.append(payload[:len(payload)/2], true))
+	blkfileMgrWrapper.close()
+
+	blkfileMgrWrapper = newTestBlockfileWrapper(env, "testLedger")
+	defer blkfileMgrWrapper.close()
+	require.Equal(t, cpInfo, blkfileMgrWrapper.blockfileMgr.cpInfo)
+	blkfileMgrWrapper.addBlocks(blocks[9:])
+	verifyBlocksAndTxs(t, blkfileMgrWrapper, blocks)
+}
+
+func TestDecodeRecordHeader(t *testing.T) {
+	length, n, compressed, err := decodeRecordHeader(proto.EncodeVarint(300))
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), length)
+	require.Equal(t, 2, n)
+	require.False(t, compressed)
+
+	header := append([]byte{compressedRecordMarker, zstdCodec}, proto.EncodeVarint(300)...)
+	length, n, compressed, err = decodeRecordHeader(header)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), length)
+	require.Equal(t, 4, n)
+	require.True(t, compressed)
+
+	for _, partialHeader := range [][]byte{header[:1], header[:3]} {
+		_, n, _, err = decodeRecordHeader(partialHeader)
+		require.NoError(t, err)
+		require.Equal(t, 0, n)
+	}
+
+	_, _, _, err = decodeRecordHeader([]byte{compressedRecordMarker, 0xff, 0x01})
+	require.EqualError(t, err, "unsupported block compression codec [255]")
+}
+
+func TestFileLocPointerInCompressedBlock(t *testing.T) {
+	blockFLP := &fileLocPointer{fileSuffixNum: 2, locPointer: locPointer{offset: 1000}}
+	txFLP := newTxLocationPointer(blockFLP, &locPointer{offset: 20, bytesLength: 30}, true)
+	b, err := txFLP.marshal()
+	require.NoError(t, err)
+	unmarshaledFLP := &fileLocPointer{}
+	require.NoError(t, unmarshaledFLP.unmarshal(b))
+	require.Equal(t, &fileLocPointer{
+		fileSuffixNum:     2,
+		locPointer:        locPointer{offset: 20, bytesLength: 30},
+		inCompressedBlock: true,
+		blockOffset:       1000,
+	}, unmarshaledFLP)
+
+	txFLP = newTxLocationPointer(blockFLP, &locPointer{offset: 20, bytesLength: 30}, false)
+	b, err = txFLP.marshal()
+	require.NoError(t, err)
+	unmarshaledFLP = &fileLocPointer{}
+	require.NoError(t, unmarshaledFLP.unmarshal(b))
+	require.Equal(t, &fileLocPointer{
+		fileSuffixNum: 2,
+		locPointer:    locPointer{offset: 1020, bytesLength: 30},
+	}, unmarshaledFLP)
+}
+
+func verifyBlocksAndTxs(t *testing.T, w *testBlockfileMgrWrapper, blocks []*common.Block) {
+	w.testGetBlockByHash(blocks, nil)
+	w.testGetBlockByNumber(blocks, 0, nil)
+	w.testGetBlockByTxID(blocks, nil)
+	for blockNum, block := range blocks {
+		for tranNum, txEnvelopeBytes := range block.Data.Data {
+			expectedEnvelope, err := protoutil.GetEnvelopeFromBlock(txEnvelopeBytes)
+			require.NoError(t, err)
+
+			envelope, err := w.blockfileMgr.retrieveTransactionByBlockNumTranNum(uint64(blockNum), uint64(tranNum))
+			require.NoError(t, err)
+			require.True(t, proto.Equal(expectedEnvelope, envelope))
+
+			txID, err := protoutil.GetOrComputeTxIDFromEnvelope(txEnvelopeBytes)
+			require.NoError(t, err)
+			envelope, err = w.blockfileMgr.retrieveTransactionByID(txID)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(expectedEnvelope, envelope))
+		}
+	}
+}
@@ -20,6 +20,8 @@ const (
 type Conf struct {
 	blockStorageDir  string
 	maxBlockfileSize int
+	compressBlocks   bool
+	archive          *ArchiveConf
 }
 
 // NewConf constructs new `Conf`.
@@ -28,7 +30,24 @@ func NewConf(blockStorageDir string, maxBlockfileSize int) *Conf {
 	if maxBlockfileSize <= 0 {
 		maxBlockfileSize = defaultMaxBlockfileSize
 	}
-	return &Conf{blockStorageDir, maxBlockfileSize}
+	return &Conf{blockStorageDir: blockStorageDir, maxBlockfileSize: maxBlockfileSize}
+}
+
+// NewConfWithCompression constructs new `Conf` that enables the zstd compression of the blocks
+// appended to the block files. The blocks written earlier without compression remain readable,
+// and so do the compressed blocks if the compression is disabled later
+func NewConfWithCompression(blockStorageDir string, maxBlockfileSize int) *Conf {
+	conf := NewConf(blockStorageDir, maxBlockfileSize)
+	conf.compressBlocks = true
+	return conf
+}
+
+// WithArchive enables moving the older block files to an archive store, as per the supplied configurations.
+// The archived blocks remain available to the readers of the block store, which fetches the archived block
+// files back on demand
+func (conf *Conf) WithArchive(archiveConf *ArchiveConf) *Conf {
+	conf.archive = archiveConf
+	return conf
 }
 
 func (conf *Conf) getIndexDir() string {
@@ -0,0 +1,409 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// maxReportedErrors is the maximum number of errors of a kind that are listed in a `VerificationReport`
+const maxReportedErrors = 100
+
+// VerificationReport summarizes the outcome of verifying the block store of a ledger
+type VerificationReport struct {
+	LedgerID string
+	// BlocksVerified is the number of the complete blocks read from the block files
+	BlocksVerified uint64
+	// FirstBlockNum and LastBlockNum are the numbers of the first and the last complete blocks that are
+	// found in the block files. These are meaningful only if BlocksVerified is not zero
+	FirstBlockNum uint64
+	LastBlockNum  uint64
+	// LastBlockIndexed is the last block recorded in the block index. This is meaningful only if IndexEmpty is false
+	LastBlockIndexed uint64
+	IndexEmpty       bool
+	// TornTail, if not nil, describes a partially written block at the end of the last block file,
+	// which is left behind by a crash while appending the block
+	TornTail *TornTail
+	// ChainErrors lists the blocks that are unreadable, out of sequence, or do not match the hashes
+	// recorded in the block headers. The blocks following the first such block are not verified
+	ChainErrors []string
+	// FirstInvalidBlockNum is the number of the block that caused the first chain error
+	FirstInvalidBlockNum uint64
+	// IndexErrors lists the entries of the block index that are missing or do not match the block files
+	IndexErrors []string
+}
+
+// TornTail describes the partially written data at the end of a block file
+type TornTail struct {
+	FileNum int
+	// Offset is the end offset of the last complete block in the file
+	Offset int64
+	// Size is the number of bytes that follow the last complete block
+	Size int64
+}
+
+// Healthy returns true if no problems are found
+func (r *VerificationReport) Healthy() bool {
+	return r.TornTail == nil && len(r.ChainErrors) == 0 && len(r.IndexErrors) == 0
+}
+
+func (r *VerificationReport) addChainError(blockNum uint64, format string, args ...interface{}) {
+	if len(r.ChainErrors) == 0 {
+		r.FirstInvalidBlockNum = blockNum
+	}
+	r.ChainErrors = append(r.ChainErrors, fmt.Sprintf(format, args...))
+}
+
+func (r *VerificationReport) addIndexError(format string, args ...interface{}) {
+	if len(r.IndexErrors) < maxReportedErrors {
+		r.IndexErrors = append(r.IndexErrors, fmt.Sprintf(format, args...))
+	}
+}
+
+type verifier struct {
+	ledgerID        string
+	ledgerDir       string
+	blockStorageDir string
+	indexConfig     *IndexConfig
+	dbProvider      *leveldbhelper.Provider
+	indexStore      *blockIndex
+}
+
+// VerifyBlockStore walks the block files of a ledger and verifies that the blocks are readable and form an
+// unbroken hash chain, i.e., the data hash recorded in the header of each block matches the data of the block and
+// the previous hash matches the header of the preceding block. It also cross-checks the block index against the
+// location and the transactions of each block. As the block index is opened exclusively, this is to be invoked
+// only when the peer is not running
+func VerifyBlockStore(blockStorageDir, ledgerID string, indexConfig *IndexConfig) (*VerificationReport, error) {
+	v, err := newVerifier(blockStorageDir, ledgerID, indexConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer v.dbProvider.Close()
+	return v.verify()
+}
+
+// RepairBlockStore verifies the block store of a ledger as in `VerifyBlockStore` and repairs the problems that
+// can be repaired from the block files. A partially written block at the end of the last block file is truncated
+// and the index of the ledger is rebuilt if any of its entries is found missing or mismatched. The blocks that
+// break the hash chain cannot be repaired; in this case, an error is returned and the ledger needs to be rolled
+// back to a block preceding the first invalid block, so that the peer fetches the subsequent blocks again.
+// The returned report is the outcome of verifying the block store after the repair
+func RepairBlockStore(blockStorageDir, ledgerID string, indexConfig *IndexConfig) (*VerificationReport, error) {
+	v, err := newVerifier(blockStorageDir, ledgerID, indexConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer v.dbProvider.Close()
+
+	report, err := v.verify()
+	if err != nil {
+		return nil, err
+	}
+	if len(report.ChainErrors) > 0 {
+		return report, errors.Errorf(
+			"the block files of ledger [%s] are invalid from block [%d] onwards, which cannot be repaired. Roll back the ledger to a preceding block",
+			ledgerID, report.FirstInvalidBlockNum,
+		)
+	}
+	if report.Healthy() {
+		logger.Infof("No problems found in the block store of ledger [%s]", ledgerID)
+		return report, nil
+	}
+
+	if t := report.TornTail; t != nil {
+		filePath := deriveBlockfilePath(v.ledgerDir, t.FileNum)
+		logger.Infof("Truncating [%d] bytes of a partially written block from the block file [%s] at offset [%d]", t.Size, filePath, t.Offset)
+		if err := os.Truncate(filePath, t.Offset); err != nil {
+			return nil, errors.Wrapf(err, "error truncating the block file [%s]", filePath)
+		}
+	}
+	if len(report.IndexErrors) > 0 {
+		if err := v.rebuildIndex(); err != nil {
+			return nil, err
+		}
+	}
+	return v.verify()
+}
+
+func newVerifier(blockStorageDir, ledgerID string, indexConfig *IndexConfig) (*verifier, error) {
+	conf := &Conf{blockStorageDir: blockStorageDir}
+	ledgerDir := conf.getLedgerBlockDir(ledgerID)
+	if err := validateLedgerID(ledgerDir, ledgerID); err != nil {
+		return nil, err
+	}
+	archived, _, err := util.FileExists(filepath.Join(ledgerDir, archiveInfoFileName))
+	if err != nil {
+		return nil, err
+	}
+	if archived {
+		return nil, errors.Errorf("ledger [%s] has archived block files, the verification of which is not supported", ledgerID)
+	}
+
+	v := &verifier{
+		ledgerID:        ledgerID,
+		ledgerDir:       ledgerDir,
+		blockStorageDir: blockStorageDir,
+		indexConfig:     indexConfig,
+	}
+	v.dbProvider, err = leveldbhelper.NewProvider(
+		&leveldbhelper.Conf{
+			DBPath:         conf.getIndexDir(),
+			ExpectedFormat: dataFormatVersion(indexConfig),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	v.indexStore, err = newBlockIndex(indexConfig, v.dbProvider.GetDBHandle(ledgerID))
+	if err != nil {
+		v.dbProvider.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *verifier) verify() (*VerificationReport, error) {
+	logger.Infof("Verifying the block store of ledger [%s]", v.ledgerID)
+	report := &VerificationReport{LedgerID: v.ledgerID}
+	lastBlockIndexed, err := v.indexStore.getLastBlockIndexed()
+	switch {
+	case err == errIndexEmpty:
+		report.IndexEmpty = true
+	case err != nil:
+		return nil, err
+	default:
+		report.LastBlockIndexed = lastBlockIndexed
+	}
+
+	if err := v.verifyBlockFiles(report); err != nil {
+		return nil, err
+	}
+
+	if !report.IndexEmpty && (report.BlocksVerified == 0 || report.LastBlockIndexed > report.LastBlockNum) {
+		report.addIndexError("the index records block [%d] as indexed, which is beyond the last block in the block files", report.LastBlockIndexed)
+	}
+	cpInfo, err := v.loadCheckpointInfo()
+	if err != nil {
+		return nil, err
+	}
+	if cpInfo != nil && !cpInfo.isChainEmpty && (report.BlocksVerified == 0 || cpInfo.lastBlockNumber > report.LastBlockNum) {
+		report.addIndexError("the checkpoint info records block [%d] as the last block, which is beyond the last block in the block files", cpInfo.lastBlockNumber)
+	}
+	logger.Infof("Verified [%d] blocks of ledger [%s]: torn tail=[%t], chain errors=[%d], index errors=[%d]",
+		report.BlocksVerified, v.ledgerID, report.TornTail != nil, len(report.ChainErrors), len(report.IndexErrors))
+	return report, nil
+}
+
+// verifyBlockFiles reads the blocks from all the block files in sequence. It stops at the first chain
+// error, as the hash chain of the blocks that follow cannot be established
+func (v *verifier) verifyBlockFiles(report *VerificationReport) error {
+	lastFileNum, err := retrieveLastFileSuffix(v.ledgerDir)
+	if err != nil {
+		return err
+	}
+	var prevHeader *common.BlockHeader
+	for fileNum := 0; fileNum <= lastFileNum; fileNum++ {
+		if exists, _, err := util.FileExists(deriveBlockfilePath(v.ledgerDir, fileNum)); err != nil || !exists {
+			if err != nil {
+				return err
+			}
+			report.addChainError(nextBlockNum(prevHeader), "block file [%d] is missing", fileNum)
+			return nil
+		}
+		stream, err := newBlockfileStream(v.ledgerDir, fileNum, 0)
+		if err != nil {
+			return err
+		}
+		ok, err := v.verifyBlockfile(stream, lastFileNum, &prevHeader, report)
+		stream.close()
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *verifier) verifyBlockfile(
+	stream *blockfileStream,
+	lastFileNum int,
+	prevHeader **common.BlockHeader,
+	report *VerificationReport,
+) (bool, error) {
+	for {
+		nextBlockNum := nextBlockNum(*prevHeader)
+		blockBytes, placementInfo, err := stream.nextBlockBytesAndPlacementInfo()
+		if err == ErrUnexpectedEndOfBlockfile && stream.fileNum == lastFileNum {
+			fileInfo, err := stream.file.Stat()
+			if err != nil {
+				return false, errors.Wrapf(err, "error getting block file stat")
+			}
+			report.TornTail = &TornTail{
+				FileNum: stream.fileNum,
+				Offset:  stream.currentOffset,
+				Size:    fileInfo.Size() - stream.currentOffset,
+			}
+			return true, nil
+		}
+		if err != nil {
+			report.addChainError(nextBlockNum, "unreadable block at offset [%d] in block file [%d]: %s", stream.currentOffset, stream.fileNum, err)
+			return false, nil
+		}
+		if blockBytes == nil {
+			return true, nil
+		}
+
+		block, err := deserializeBlock(blockBytes)
+		if err != nil {
+			report.addChainError(nextBlockNum, "unreadable block at offset [%d] in block file [%d]: %s", placementInfo.blockStartOffset, stream.fileNum, err)
+			return false, nil
+		}
+		header := block.Header
+		switch {
+		case *prevHeader != nil && header.Number != nextBlockNum:
+			report.addChainError(nextBlockNum, "block [%d] is followed by block [%d] in block file [%d]", (*prevHeader).Number, header.Number, stream.fileNum)
+			return false, nil
+		case *prevHeader != nil && !bytes.Equal(header.PreviousHash, protoutil.BlockHeaderHash(*prevHeader)):
+			report.addChainError(header.Number, "the previous hash of block [%d] does not match the hash of block [%d]", header.Number, (*prevHeader).Number)
+			return false, nil
+		case !bytes.Equal(header.DataHash, protoutil.BlockDataHash(block.Data)):
+			report.addChainError(header.Number, "the data hash of block [%d] does not match the data of the block", header.Number)
+			return false, nil
+		}
+
+		if *prevHeader == nil {
+			report.FirstBlockNum = header.Number
+		}
+		*prevHeader = header
+		report.LastBlockNum = header.Number
+		report.BlocksVerified++
+
+		if !report.IndexEmpty && header.Number <= report.LastBlockIndexed {
+			if err := v.verifyIndexEntries(blockBytes, placementInfo, report); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+// verifyIndexEntries checks that the index entries of the block, for all the indexed attributes, are present
+// and that the entries of the block number and the block hash point to the location of the block in the block files
+func (v *verifier) verifyIndexEntries(blockBytes []byte, placementInfo *blockPlacementInfo, report *VerificationReport) error {
+	info, err := extractSerializedBlockInfo(blockBytes)
+	if err != nil {
+		return err
+	}
+	blockNum := info.blockHeader.Number
+	expectedLoc := &fileLocPointer{
+		fileSuffixNum: placementInfo.fileNum,
+		locPointer:    locPointer{offset: int(placementInfo.blockStartOffset)},
+	}
+	checkBlockLoc := func(attr string, flp *fileLocPointer, err error) error {
+		switch {
+		case err == ErrAttrNotIndexed:
+		case err == ErrNotFoundInIndex:
+			report.addIndexError("the %s index entry of block [%d] is missing", attr, blockNum)
+		case err != nil:
+			return err
+		case flp.fileSuffixNum != expectedLoc.fileSuffixNum || flp.offset != expectedLoc.offset:
+			report.addIndexError("the %s index entry of block [%d] points to [%s] instead of [%s]", attr, blockNum, flp, expectedLoc)
+		}
+		return nil
+	}
+	flp, err := v.indexStore.getBlockLocByBlockNum(blockNum)
+	if err := checkBlockLoc("block number", flp, err); err != nil {
+		return err
+	}
+	flp, err = v.indexStore.getBlockLocByHash(protoutil.BlockHeaderHash(info.blockHeader))
+	if err := checkBlockLoc("block hash", flp, err); err != nil {
+		return err
+	}
+
+	for i, txOffset := range info.txOffsets {
+		if v.indexStore.isAttributeIndexed(IndexableAttrTxID) {
+			val, err := v.indexStore.db.Get(constructTxIDKey(txOffset.txID, blockNum, uint64(i)))
+			if err != nil {
+				return err
+			}
+			if val == nil {
+				report.addIndexError("the transaction ID index entry of transaction [%d] in block [%d] is missing", i, blockNum)
+			}
+		}
+		if v.indexStore.isAttributeIndexed(IndexableAttrBlockNumTranNum) {
+			val, err := v.indexStore.db.Get(constructBlockNumTranNumKey(blockNum, uint64(i)))
+			if err != nil {
+				return err
+			}
+			if val == nil {
+				report.addIndexError("the block and transaction number index entry of transaction [%d] in block [%d] is missing", i, blockNum)
+			}
+		}
+	}
+	return nil
+}
+
+func nextBlockNum(prevHeader *common.BlockHeader) uint64 {
+	if prevHeader == nil {
+		return 0
+	}
+	return prevHeader.Number + 1
+}
+
+func (v *verifier) loadCheckpointInfo() (*checkpointInfo, error) {
+	b, err := v.indexStore.db.Get(blkMgrInfoKey)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	i := &checkpointInfo{}
+	if err := i.unmarshal(b); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// rebuildIndex drops all the index entries of the ledger, including the checkpoint info, and indexes the
+// block files from the beginning, as the block store does on the startup when the index is found empty
+func (v *verifier) rebuildIndex() error {
+	logger.Infof("Dropping the block index of ledger [%s]", v.ledgerID)
+	db := v.indexStore.db
+	itr := db.GetIterator(nil, nil)
+	batch := leveldbhelper.NewUpdateBatch()
+	for itr.Next() {
+		batch.Delete(append([]byte{}, itr.Key()...))
+		if batch.Len() >= 1000 {
+			if err := db.WriteBatch(batch, false); err != nil {
+				itr.Release()
+				return err
+			}
+			batch = leveldbhelper.NewUpdateBatch()
+		}
+	}
+	err := itr.Error()
+	itr.Release()
+	if err != nil {
+		return errors.Wrapf(err, "error iterating the block index of ledger [%s]", v.ledgerID)
+	}
+	if err := db.WriteBatch(batch, true); err != nil {
+		return err
+	}
+
+	logger.Infof("Rebuilding the block index of ledger [%s]", v.ledgerID)
+	mgr := newBlockfileMgr(v.ledgerID, NewConf(v.blockStorageDir, 0), v.indexConfig, db)
+	defer mgr.close()
+	// the error, if any, of syncing the index at the construction of the manager is not returned and hence,
+	// the sync is repeated, which is a no-op if the index is already in sync
+	return mgr.syncIndex()
+}
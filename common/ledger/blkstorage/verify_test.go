@@ -0,0 +1,143 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAndRepairBlockStore(t *testing.T) {
+	path := testPath()
+	defer os.RemoveAll(path)
+	blocks := testutil.ConstructTestBlocks(t, 30)
+	storeTestBlocks(t, path, blocks, 10)
+	indexConfig := &IndexConfig{AttrsToIndex: attrsToIndex}
+
+	report, err := VerifyBlockStore(path, "testLedger", indexConfig)
+	require.NoError(t, err)
+	require.True(t, report.Healthy())
+	require.Equal(t, uint64(30), report.BlocksVerified)
+	require.Equal(t, uint64(0), report.FirstBlockNum)
+	require.Equal(t, uint64(29), report.LastBlockNum)
+	require.Equal(t, uint64(29), report.LastBlockIndexed)
+
+	t.Run("torn tail", func(t *testing.T) {
+		lastFilePath := deriveBlockfilePath((&Conf{blockStorageDir: path}).getLedgerBlockDir("testLedger"), 2)
+		fileInfo, err := os.Stat(lastFilePath)
+		require.NoError(t, err)
+		f, err := os.OpenFile(lastFilePath, os.O_APPEND|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		// a record header announcing 100 bytes followed by only 3 bytes
+		_, err = f.Write([]byte{100, 1, 2, 3})
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		report, err := VerifyBlockStore(path, "testLedger", indexConfig)
+		require.NoError(t, err)
+		require.False(t, report.Healthy())
+		require.Equal(t, &TornTail{FileNum: 2, Offset: fileInfo.Size(), Size: 4}, report.TornTail)
+		require.Equal(t, uint64(29), report.LastBlockNum)
+
+		report, err = RepairBlockStore(path, "testLedger", indexConfig)
+		require.NoError(t, err)
+		require.True(t, report.Healthy())
+		repairedFileInfo, err := os.Stat(lastFilePath)
+		require.NoError(t, err)
+		require.Equal(t, fileInfo.Size(), repairedFileInfo.Size())
+	})
+
+	t.Run("index errors", func(t *testing.T) {
+		p, err := leveldbhelper.NewProvider(&leveldbhelper.Conf{
+			DBPath:         (&Conf{blockStorageDir: path}).getIndexDir(),
+			ExpectedFormat: dataFormatVersion(indexConfig),
+		})
+		require.NoError(t, err)
+		db := p.GetDBHandle("testLedger")
+		require.NoError(t, db.Delete(constructBlockNumKey(5), true))
+		require.NoError(t, db.Delete(constructBlockHashKey(protoutil.BlockHeaderHash(blocks[6].Header)), true))
+		require.NoError(t, db.Put(constructBlockNumKey(7), []byte{}, true))
+		require.NoError(t, db.Delete(constructBlockNumTranNumKey(8, 0), true))
+		p.Close()
+
+		report, err := VerifyBlockStore(path, "testLedger", indexConfig)
+		require.NoError(t, err)
+		require.Len(t, report.IndexErrors, 4)
+		require.Equal(t, "the block number index entry of block [5] is missing", report.IndexErrors[0])
+		require.Equal(t, "the block hash index entry of block [6] is missing", report.IndexErrors[1])
+		require.Contains(t, report.IndexErrors[2], "the block number index entry of block [7] points to")
+		require.Equal(t, "the block and transaction number index entry of transaction [0] in block [8] is missing", report.IndexErrors[3])
+
+		report, err = RepairBlockStore(path, "testLedger", indexConfig)
+		require.NoError(t, err)
+		require.True(t, report.Healthy())
+		require.Equal(t, uint64(29), report.LastBlockIndexed)
+
+		env := newTestEnv(t, NewConf(path, 0))
+		defer env.provider.Close()
+		w := newTestBlockfileWrapper(env, "testLedger")
+		defer w.close()
+		w.testGetBlockByNumber(blocks, 0, nil)
+		w.testGetBlockByHash(blocks, nil)
+	})
+}
+
+func TestVerifyBlockStoreDetectsBrokenHashChain(t *testing.T) {
+	path := testPath()
+	defer os.RemoveAll(path)
+	blocks := testutil.ConstructTestBlocks(t, 20)
+	// the data of a block is altered after its header is constructed
+	blocks[12].Data.Data[0] = []byte("tampered")
+	storeTestBlocks(t, path, blocks, 10)
+	indexConfig := &IndexConfig{AttrsToIndex: attrsToIndex}
+
+	report, err := VerifyBlockStore(path, "testLedger", indexConfig)
+	require.NoError(t, err)
+	require.Equal(t, []string{"the data hash of block [12] does not match the data of the block"}, report.ChainErrors)
+	require.Equal(t, uint64(12), report.FirstInvalidBlockNum)
+	require.Equal(t, uint64(11), report.LastBlockNum)
+
+	_, err = RepairBlockStore(path, "testLedger", indexConfig)
+	require.EqualError(t, err, "the block files of ledger [testLedger] are invalid from block [12] onwards, which cannot be repaired. Roll back the ledger to a preceding block")
+}
+
+func TestVerifyBlockStoreErrors(t *testing.T) {
+	path := testPath()
+	defer os.RemoveAll(path)
+	indexConfig := &IndexConfig{AttrsToIndex: attrsToIndex}
+
+	_, err := VerifyBlockStore(path, "non-existing-ledger", indexConfig)
+	require.EqualError(t, err, "ledgerID [non-existing-ledger] does not exist")
+
+	storeTestBlocks(t, path, testutil.ConstructTestBlocks(t, 5), 10)
+	ledgerDir := (&Conf{blockStorageDir: path}).getLedgerBlockDir("testLedger")
+	f, err := os.Create(ledgerDir + "/" + archiveInfoFileName)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = VerifyBlockStore(path, "testLedger", indexConfig)
+	require.EqualError(t, err, "ledger [testLedger] has archived block files, the verification of which is not supported")
+}
+
+// storeTestBlocks adds the blocks to the ledger "testLedger", moving to a new block file after every blocksPerFile blocks
+func storeTestBlocks(t *testing.T, path string, blocks []*common.Block, blocksPerFile int) {
+	env := newTestEnv(t, NewConf(path, 0))
+	defer env.provider.Close()
+	w := newTestBlockfileWrapper(env, "testLedger")
+	defer w.close()
+	for i, b := range blocks {
+		require.NoError(t, w.blockfileMgr.addBlock(b))
+		if (i+1)%blocksPerFile == 0 && i+1 < len(blocks) {
+			w.blockfileMgr.moveToNextFile()
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// +build cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import "github.com/DataDog/zstd"
+
+func zstdCompress(b []byte) ([]byte, error) {
+	return zstd.CompressLevel(nil, b, zstdCompressionLevel)
+}
+
+func zstdDecompress(b []byte) ([]byte, error) {
+	return zstd.Decompress(nil, b)
+}
@@ -0,0 +1,21 @@
+// +build !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blkstorage
+
+import "github.com/pkg/errors"
+
+var errZstdCgo = errors.New("zstd compression requires building with cgo enabled")
+
+func zstdCompress(b []byte) ([]byte, error) {
+	return nil, errZstdCgo
+}
+
+func zstdDecompress(b []byte) ([]byte, error) {
+	return nil, errZstdCgo
+}
@@ -156,6 +156,18 @@ func (dbInst *DB) GetIterator(startKey []byte, endKey []byte) iterator.Iterator
 	return dbInst.db.NewIterator(&goleveldbutil.Range{Start: startKey, Limit: endKey}, dbInst.readOpts)
 }
 
+// ApproximateSize returns the approximate number of bytes occupied on disk by the keys
+// between the startKey (inclusive) and the endKey (exclusive)
+func (dbInst *DB) ApproximateSize(startKey []byte, endKey []byte) (int64, error) {
+	dbInst.mutex.RLock()
+	defer dbInst.mutex.RUnlock()
+	sizes, err := dbInst.db.SizeOf([]goleveldbutil.Range{{Start: startKey, Limit: endKey}})
+	if err != nil {
+		return 0, errors.Wrap(err, "error computing the size of the leveldb range")
+	}
+	return sizes.Sum(), nil
+}
+
 // WriteBatch writes a batch
 func (dbInst *DB) WriteBatch(batch *leveldb.Batch, sync bool) error {
 	dbInst.mutex.RLock()
@@ -183,6 +183,15 @@ func (h *DBHandle) GetIterator(startKey []byte, endKey []byte) *Iterator {
 	return &Iterator{h.db.GetIterator(sKey, eKey)}
 }
 
+// ApproximateSize returns the approximate number of bytes occupied on disk by the keys of the named db.
+// The size reflects the data flushed to the table files, and not the data held in the memtable
+func (h *DBHandle) ApproximateSize() (int64, error) {
+	sKey := constructLevelKey(h.dbName, nil)
+	eKey := constructLevelKey(h.dbName, nil)
+	eKey[len(eKey)-1] = lastKeyIndicator
+	return h.db.ApproximateSize(sKey, eKey)
+}
+
 // UpdateBatch encloses the details of multiple `updates`
 type UpdateBatch struct {
 	KVs map[string][]byte
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package leveldbhelper
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"testing"
@@ -89,6 +90,30 @@ func TestBatchedUpdates(t *testing.T) {
 	}
 }
 
+func TestApproximateSize(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+
+	batch := NewUpdateBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Put([]byte(fmt.Sprintf("key%d", i)), bytes.Repeat([]byte{byte(i)}, 1000))
+	}
+	assert.NoError(t, env.provider.GetDBHandle("db1").WriteBatch(batch, true))
+
+	// reopening the db flushes the journal to the table files
+	env.provider.Close()
+	p, err := NewProvider(&Conf{DBPath: testDBPath})
+	assert.NoError(t, err)
+	env.provider = p
+
+	size1, err := p.GetDBHandle("db1").ApproximateSize()
+	assert.NoError(t, err)
+	assert.True(t, size1 > 0, "size of db1 should be positive: %d", size1)
+	size2, err := p.GetDBHandle("db2").ApproximateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), size2)
+}
+
 func TestFormatCheck(t *testing.T) {
 	testCases := []struct {
 		dataFormat     string
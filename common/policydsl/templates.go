@@ -0,0 +1,226 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policydsl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+)
+
+// maxTemplateDepth bounds the nesting of template references, which
+// also stops templates that reference each other cyclically
+const maxTemplateDepth = 16
+
+var templateNameRegExp = regexp.MustCompile("^[A-Za-z][A-Za-z0-9_]*$")
+
+// ValidateTemplateName checks that the given name may name a policy template,
+// that is, it's an identifier other than the names of the gates
+func ValidateTemplateName(name string) error {
+	if !templateNameRegExp.MatchString(name) {
+		return fmt.Errorf("invalid policy template name '%s'. Names can only consist of alphanumerics and '_', and must begin with a letter", name)
+	}
+	switch strings.ToLower(name) {
+	case strings.ToLower(GateAnd), strings.ToLower(GateOr), strings.ToLower(GateOutOf):
+		return fmt.Errorf("invalid policy template name '%s'. Names must not be the name of a gate", name)
+	}
+	return nil
+}
+
+// FromStringWithTemplates is like FromString, except the policy may reference the
+// given templates, which map template names to template policies. A reference
+// to a template is written as a call to a gate:
+//
+// NAME(P[, P])
+//
+// where NAME is the name of the template and the arguments P are principals or
+// policies. The reference is replaced with the policy of the template, in which
+// $1, $2... are replaced with the respective arguments, $* with all the arguments
+// and $# with their number. As an example, the template
+//
+// OutOf($# / 2 + 1, $*)
+//
+// named MAJORITY requires the signatures of the majority of its arguments, and
+// the template
+//
+// AND(MAJORITY($1, $2, $3), MAJORITY($4, $5, $6))
+//
+// requires the signatures of the majorities of the organizations of two regions.
+func FromStringWithTemplates(policy string, templates map[string]string) (*cb.SignaturePolicyEnvelope, error) {
+	expanded, err := ExpandTemplates(policy, templates)
+	if err != nil {
+		return nil, err
+	}
+	return FromString(expanded)
+}
+
+// ExpandTemplates replaces the references to the given templates in the given
+// policy with the policies of the templates, as described by FromStringWithTemplates.
+func ExpandTemplates(policy string, templates map[string]string) (string, error) {
+	return expandTemplates(policy, templates, 0)
+}
+
+func expandTemplates(policy string, templates map[string]string, depth int) (string, error) {
+	if depth > maxTemplateDepth {
+		return "", fmt.Errorf("policy templates nested deeper than %d levels", maxTemplateDepth)
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(policy); {
+		c := policy[i]
+		if c == '\'' || c == '"' {
+			end := strings.IndexByte(policy[i+1:], c)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote in policy string '%s'", policy)
+			}
+			sb.WriteString(policy[i : i+end+2])
+			i += end + 2
+			continue
+		}
+		if !isTokenChar(c) {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(policy) && isTokenChar(policy[i]) {
+			i++
+		}
+		name := policy[start:i]
+		template, ok := templates[name]
+		if !ok {
+			sb.WriteString(name)
+			continue
+		}
+
+		var args []string
+		if j := skipSpaces(policy, i); j < len(policy) && policy[j] == '(' {
+			var end int
+			var err error
+			args, end, err = splitArguments(policy, j)
+			if err != nil {
+				return "", err
+			}
+			i = end
+		}
+		// the references in the arguments are expanded along with the instance
+		instance, err := instantiateTemplate(name, template, args)
+		if err != nil {
+			return "", err
+		}
+		expanded, err := expandTemplates(instance, templates, depth+1)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(expanded)
+	}
+
+	return sb.String(), nil
+}
+
+// splitArguments returns the arguments of the call whose opening parenthesis
+// is at the given position, along with the position following the call
+func splitArguments(policy string, open int) ([]string, int, error) {
+	var args []string
+	nesting := 0
+	start := open + 1
+	for i := open + 1; i < len(policy); i++ {
+		switch c := policy[i]; c {
+		case '\'', '"':
+			end := strings.IndexByte(policy[i+1:], c)
+			if end < 0 {
+				return nil, 0, fmt.Errorf("unterminated quote in policy string '%s'", policy)
+			}
+			i += end + 1
+		case '(':
+			nesting++
+		case ',':
+			if nesting == 0 {
+				args = append(args, strings.TrimSpace(policy[start:i]))
+				start = i + 1
+			}
+		case ')':
+			if nesting > 0 {
+				nesting--
+				continue
+			}
+			last := strings.TrimSpace(policy[start:i])
+			if last != "" || len(args) > 0 {
+				args = append(args, last)
+			}
+			for _, arg := range args {
+				if arg == "" {
+					return nil, 0, fmt.Errorf("empty argument in policy string '%s'", policy)
+				}
+			}
+			return args, i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("unbalanced parentheses in policy string '%s'", policy)
+}
+
+// instantiateTemplate replaces the parameters of the given template with the given arguments
+func instantiateTemplate(name, template string, args []string) (string, error) {
+	var sb strings.Builder
+	variadic := false
+	maxParam := 0
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' || i+1 == len(template) {
+			sb.WriteByte(template[i])
+			continue
+		}
+
+		switch next := template[i+1]; {
+		case next == '*':
+			variadic = true
+			sb.WriteString(strings.Join(args, ", "))
+			i++
+		case next == '#':
+			sb.WriteString(strconv.Itoa(len(args)))
+			i++
+		case next >= '1' && next <= '9':
+			j := i + 1
+			for j < len(template) && template[j] >= '0' && template[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(template[i+1 : j])
+			if err != nil {
+				return "", fmt.Errorf("invalid parameter '%s' in policy template %s", template[i:j], name)
+			}
+			if n > len(args) {
+				return "", fmt.Errorf("policy template %s references parameter $%d, but only %d arguments were passed", name, n, len(args))
+			}
+			if n > maxParam {
+				maxParam = n
+			}
+			sb.WriteString(args[n-1])
+			i = j - 1
+		default:
+			sb.WriteByte(template[i])
+		}
+	}
+
+	if !variadic && maxParam != len(args) {
+		return "", fmt.Errorf("policy template %s expects %d arguments, but %d were passed", name, maxParam, len(args))
+	}
+	return sb.String(), nil
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-' || c == '$'
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
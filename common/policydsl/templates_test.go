@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policydsl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTemplates = map[string]string{
+	"MAJORITY":   "OutOf($# / 2 + 1, $*)",
+	"TwoRegions": "AND(MAJORITY($1, $2, $3), MAJORITY($4, $5, $6))",
+	"Auditors":   "OR('Auditor1.peer', 'Auditor2.peer')",
+	"Pair":       "AND($1, $2)",
+}
+
+func TestExpandTemplates(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expanded string
+	}{
+		{
+			policy:   "AND('A.member', 'B.member')",
+			expanded: "AND('A.member', 'B.member')",
+		},
+		{
+			policy:   "MAJORITY('A.member', 'B.member', 'C.member')",
+			expanded: "OutOf(3 / 2 + 1, 'A.member', 'B.member', 'C.member')",
+		},
+		{
+			policy:   "AND(Auditors, Pair(A.peer, OR('B.peer', 'C.peer')))",
+			expanded: "AND(OR('Auditor1.peer', 'Auditor2.peer'), AND(A.peer, OR('B.peer', 'C.peer')))",
+		},
+		{
+			policy:   "Auditors()",
+			expanded: "OR('Auditor1.peer', 'Auditor2.peer')",
+		},
+		{
+			policy:   "OR('Pair.member', Pair.admin)",
+			expanded: "OR('Pair.member', Pair.admin)",
+		},
+		{
+			policy:   "TwoRegions('A.peer', 'B.peer', 'C.peer', 'D.peer', 'E.peer', 'F.peer')",
+			expanded: "AND(OutOf(3 / 2 + 1, 'A.peer', 'B.peer', 'C.peer'), OutOf(3 / 2 + 1, 'D.peer', 'E.peer', 'F.peer'))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			expanded, err := ExpandTemplates(tt.policy, testTemplates)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expanded, expanded)
+		})
+	}
+}
+
+func TestFromStringWithTemplates(t *testing.T) {
+	p1, err := FromStringWithTemplates("MAJORITY('A.member', 'B.member', 'C.member', 'D.member')", testTemplates)
+	require.NoError(t, err)
+	p2, err := FromString("OutOf(3, 'A.member', 'B.member', 'C.member', 'D.member')")
+	require.NoError(t, err)
+	assert.Equal(t, p2, p1)
+
+	p1, err = FromStringWithTemplates("TwoRegions('A.peer', 'B.peer', 'C.peer', 'D.peer', 'E.peer', 'F.peer')", testTemplates)
+	require.NoError(t, err)
+	p2, err = FromString("AND(OutOf(2, 'A.peer', 'B.peer', 'C.peer'), OutOf(2, 'D.peer', 'E.peer', 'F.peer'))")
+	require.NoError(t, err)
+	assert.Equal(t, p2, p1)
+
+	p1, err = FromStringWithTemplates("AND('A.member', 'B.member')", nil)
+	require.NoError(t, err)
+	p2, err = FromString("AND('A.member', 'B.member')")
+	require.NoError(t, err)
+	assert.Equal(t, p2, p1)
+}
+
+func TestExpandTemplatesErrors(t *testing.T) {
+	templates := map[string]string{
+		"Pair":  "AND($1, $2)",
+		"Loop":  "OR(Other, 'A.member')",
+		"Other": "Loop",
+	}
+	tests := []struct {
+		policy string
+		err    string
+	}{
+		{policy: "Pair('A.member')", err: "policy template Pair references parameter $2, but only 1 arguments were passed"},
+		{policy: "Pair('A.member', 'B.member', 'C.member')", err: "policy template Pair expects 2 arguments, but 3 were passed"},
+		{policy: "Pair('A.member', )", err: "empty argument in policy string 'Pair('A.member', )'"},
+		{policy: "Pair('A.member', 'B.member'", err: "unbalanced parentheses in policy string 'Pair('A.member', 'B.member''"},
+		{policy: "Pair('A.member, 'B.member')", err: "unterminated quote in policy string 'Pair('A.member, 'B.member')'"},
+		{policy: "Loop", err: "policy templates nested deeper than 16 levels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			_, err := ExpandTemplates(tt.policy, templates)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestValidateTemplateName(t *testing.T) {
+	assert.NoError(t, ValidateTemplateName("MAJORITY"))
+	assert.NoError(t, ValidateTemplateName("two_regions2"))
+	assert.EqualError(t, ValidateTemplateName("2regions"), "invalid policy template name '2regions'. Names can only consist of alphanumerics and '_', and must begin with a letter")
+	assert.EqualError(t, ValidateTemplateName("two regions"), "invalid policy template name 'two regions'. Names can only consist of alphanumerics and '_', and must begin with a letter")
+	assert.EqualError(t, ValidateTemplateName("outOf"), "invalid policy template name 'outOf'. Names must not be the name of a gate")
+}
@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package reload
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+// Status is the JSON representation of the state of a Manager.
+type Status struct {
+	Components []string `json:"components"`
+	Last       *Result  `json:"last,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns a handler that reports the reloadable components and
+// the result of the last reload upon GET requests, and reloads the
+// configuration upon POST requests.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{
+		Manager: manager,
+		Logger:  flogging.MustGetLogger("reload.http"),
+	}
+}
+
+type Handler struct {
+	Manager *Manager
+	Logger  *flogging.FabricLogger
+}
+
+func (h *Handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		result, err := h.Manager.Reload()
+		if err != nil {
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		h.sendResponse(resp, http.StatusOK, result)
+
+	case http.MethodGet:
+		h.sendResponse(resp, http.StatusOK, &Status{
+			Components: h.Manager.Components(),
+			Last:       h.Manager.Last(),
+		})
+
+	default:
+		err := fmt.Errorf("invalid request method: %s", req.Method)
+		h.sendResponse(resp, http.StatusBadRequest, err)
+	}
+}
+
+func (h *Handler) sendResponse(resp http.ResponseWriter, code int, payload interface{}) {
+	encoder := json.NewEncoder(resp)
+	if err, ok := payload.(error); ok {
+		payload = &errorResponse{Error: err.Error()}
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+
+	if err := encoder.Encode(payload); err != nil {
+		h.Logger.Errorw("failed to encode payload", "error", err)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package reload
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	a := &fakeComponent{key: "a", value: 1}
+	m := newTestManager(map[string]interface{}{"a": 10})
+	m.Register(a)
+	h := NewHandler(m)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/config/reload", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"components":["a"]}`, resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	var result Result
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+	require.Len(t, result.Changes, 1)
+	require.Equal(t, "a", result.Changes[0].Component)
+	require.Equal(t, 10, a.value)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/config/reload", nil))
+	var status Status
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	require.NotNil(t, status.Last)
+	require.Len(t, status.Last.Changes, 1)
+}
+
+func TestHandlerReloadFailure(t *testing.T) {
+	m := newTestManager(map[string]interface{}{"a": 10})
+	m.Register(&fakeComponent{key: "a", prepareErr: errors.New("out of range")})
+
+	resp := httptest.NewRecorder()
+	NewHandler(m).ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid a configuration: out of range"}`, resp.Body.String())
+}
+
+func TestHandlerInvalidMethod(t *testing.T) {
+	resp := httptest.NewRecorder()
+	NewHandler(newTestManager(nil)).ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/config/reload", nil))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid request method: DELETE"}`, resp.Body.String())
+}
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package reload
+
+import (
+	"os"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/spf13/viper"
+)
+
+// Logging is the logging system whose levels are reloaded.
+type Logging interface {
+	ActivateSpec(spec string) error
+	Spec() string
+}
+
+// LoggingComponent reloads the logging specification from a configuration
+// key. The specification is left unchanged when the key is not set, or when
+// the FABRIC_LOGGING_SPEC environment variable is set, which takes
+// precedence over the configuration.
+type LoggingComponent struct {
+	Key     string
+	Logging Logging
+}
+
+func (l *LoggingComponent) Name() string {
+	return "logging"
+}
+
+func (l *LoggingComponent) Prepare(config *viper.Viper) (*Change, error) {
+	spec := config.GetString(l.Key)
+	if spec == "" || os.Getenv("FABRIC_LOGGING_SPEC") != "" {
+		return nil, nil
+	}
+
+	// the specification is normalized in order to compare it with the
+	// current one
+	levels := &flogging.LoggerLevels{}
+	if err := levels.ActivateSpec(spec); err != nil {
+		return nil, err
+	}
+	from, to := l.Logging.Spec(), levels.Spec()
+	if from == to {
+		return nil, nil
+	}
+
+	return NewChange(l.Name(), from, to,
+		func() error { return l.Logging.ActivateSpec(to) },
+		func() error { return l.Logging.ActivateSpec(from) },
+	), nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package reload
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingComponent(t *testing.T) {
+	origEnvValue, ok := os.LookupEnv("FABRIC_LOGGING_SPEC")
+	os.Unsetenv("FABRIC_LOGGING_SPEC")
+	if ok {
+		defer os.Setenv("FABRIC_LOGGING_SPEC", origEnvValue)
+	}
+
+	levels := &flogging.LoggerLevels{}
+	require.NoError(t, levels.ActivateSpec("info"))
+	c := &LoggingComponent{Key: "logging.spec", Logging: levels}
+	require.Equal(t, "logging", c.Name())
+	config := viper.New()
+
+	change, err := c.Prepare(config)
+	require.NoError(t, err)
+	require.Nil(t, change, "an unset specification is left unchanged")
+
+	config.Set("logging.spec", "INFO")
+	change, err = c.Prepare(config)
+	require.NoError(t, err)
+	require.Nil(t, change, "an equivalent specification is left unchanged")
+
+	config.Set("logging.spec", "invalid=")
+	_, err = c.Prepare(config)
+	require.Error(t, err)
+
+	config.Set("logging.spec", "gossip=debug:warning")
+	change, err = c.Prepare(config)
+	require.NoError(t, err)
+	require.Equal(t, "info", change.From)
+	require.Equal(t, "gossip=debug:warn", change.To)
+	require.Equal(t, "info", levels.Spec())
+
+	require.NoError(t, change.apply())
+	require.Equal(t, "gossip=debug:warn", levels.Spec())
+	require.NoError(t, change.revert())
+	require.Equal(t, "info", levels.Spec())
+
+	os.Setenv("FABRIC_LOGGING_SPEC", "debug")
+	defer os.Unsetenv("FABRIC_LOGGING_SPEC")
+	change, err = c.Prepare(config)
+	require.NoError(t, err)
+	require.Nil(t, change, "the environment takes precedence")
+}
@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package reload applies the changes of a whitelisted set of runtime
+// parameters of a node, such as log levels or gossip tuning, when its
+// configuration file changes, without restarting the node. The parameters
+// that are not whitelisted keep the values they were started with.
+package reload
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+var logger = flogging.MustGetLogger("reload")
+
+// Component holds runtime parameters that can be reloaded.
+type Component interface {
+	// Name identifies the parameters of the component, such as gossip.pull.
+	Name() string
+	// Prepare reads the parameters of the component from the configuration
+	// and validates them. It returns nil when the parameters are unchanged.
+	Prepare(config *viper.Viper) (*Change, error)
+}
+
+// Change is a validated change of the parameters of a component.
+type Change struct {
+	// Component is the name of the component.
+	Component string `json:"component"`
+	// From describes the current values of the parameters.
+	From string `json:"from"`
+	// To describes the new values of the parameters.
+	To string `json:"to"`
+
+	apply  func() error
+	revert func() error
+}
+
+// NewChange returns a change from the given values to the given values,
+// applied and reverted with the given functions.
+func NewChange(component, from, to string, apply, revert func() error) *Change {
+	return &Change{
+		Component: component,
+		From:      from,
+		To:        to,
+		apply:     apply,
+		revert:    revert,
+	}
+}
+
+// Result is the outcome of a reload.
+type Result struct {
+	Time    time.Time `json:"time"`
+	Changes []*Change `json:"changes"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Manager reloads the parameters of the registered components.
+type Manager struct {
+	// Load loads the current configuration of the node.
+	Load func() (*viper.Viper, error)
+
+	mutex      sync.Mutex
+	components []Component
+	last       *Result
+}
+
+// NewManager returns a Manager loading the configuration with the given
+// function.
+func NewManager(load func() (*viper.Viper, error)) *Manager {
+	return &Manager{Load: load}
+}
+
+// Register adds a component whose parameters are reloaded. Components are
+// changed in the order they are registered.
+func (m *Manager) Register(c Component) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.components = append(m.components, c)
+}
+
+// Reload loads the configuration, and applies the changes of the parameters
+// of all components. No change is applied unless the parameters of every
+// component are valid, and the changes already applied are reverted when a
+// change fails.
+func (m *Manager) Reload() (*Result, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := &Result{Time: time.Now().UTC(), Changes: []*Change{}}
+	err := m.reload(result)
+	if err != nil {
+		result.Error = err.Error()
+		logger.Errorf("Failed reloading the configuration: %s", err)
+	}
+	m.last = result
+	return result, err
+}
+
+func (m *Manager) reload(result *Result) error {
+	config, err := m.Load()
+	if err != nil {
+		return errors.WithMessage(err, "failed loading the configuration")
+	}
+
+	var changes []*Change
+	for _, c := range m.components {
+		change, err := c.Prepare(config)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid %s configuration", c.Name())
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	for i, change := range changes {
+		if err := change.apply(); err != nil {
+			m.rollback(changes[:i])
+			return errors.WithMessagef(err, "failed applying the %s configuration", change.Component)
+		}
+		logger.Infof("Reloaded the %s configuration from %s to %s", change.Component, change.From, change.To)
+	}
+	result.Changes = changes
+	return nil
+}
+
+// rollback reverts the applied changes, in the reverse order.
+func (m *Manager) rollback(applied []*Change) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		change := applied[i]
+		if err := change.revert(); err != nil {
+			logger.Errorf("Failed reverting the %s configuration to %s: %s", change.Component, change.From, err)
+			continue
+		}
+		logger.Warningf("Reverted the %s configuration to %s", change.Component, change.From)
+	}
+}
+
+// Last returns the result of the last reload, or nil if the configuration
+// was not reloaded.
+func (m *Manager) Last() *Result {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.last
+}
+
+// Components returns the names of the registered components.
+func (m *Manager) Components() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	names := make([]string, 0, len(m.components))
+	for _, c := range m.components {
+		names = append(names, c.Name())
+	}
+	return names
+}
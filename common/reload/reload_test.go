@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package reload
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeComponent holds a single integer parameter read from its key.
+type fakeComponent struct {
+	key        string
+	value      int
+	applyErr   error
+	prepareErr error
+}
+
+func (f *fakeComponent) Name() string { return f.key }
+
+func (f *fakeComponent) Prepare(config *viper.Viper) (*Change, error) {
+	if f.prepareErr != nil {
+		return nil, f.prepareErr
+	}
+	from, to := f.value, config.GetInt(f.key)
+	if from == to {
+		return nil, nil
+	}
+	return NewChange(f.key, "from", "to",
+		func() error {
+			if f.applyErr != nil {
+				return f.applyErr
+			}
+			f.value = to
+			return nil
+		},
+		func() error { f.value = from; return nil },
+	), nil
+}
+
+func newTestManager(values map[string]interface{}) *Manager {
+	return NewManager(func() (*viper.Viper, error) {
+		v := viper.New()
+		for key, value := range values {
+			v.Set(key, value)
+		}
+		return v, nil
+	})
+}
+
+func TestManagerReload(t *testing.T) {
+	a := &fakeComponent{key: "a", value: 1}
+	b := &fakeComponent{key: "b", value: 2}
+	m := newTestManager(map[string]interface{}{"a": 10, "b": 2})
+	m.Register(a)
+	m.Register(b)
+	require.Equal(t, []string{"a", "b"}, m.Components())
+	require.Nil(t, m.Last())
+
+	result, err := m.Reload()
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 1)
+	require.Equal(t, "a", result.Changes[0].Component)
+	require.Equal(t, 10, a.value)
+	require.Equal(t, 2, b.value)
+	require.Equal(t, result, m.Last())
+
+	// nothing changes when the configuration is unchanged
+	result, err = m.Reload()
+	require.NoError(t, err)
+	require.Empty(t, result.Changes)
+}
+
+func TestManagerReloadInvalid(t *testing.T) {
+	a := &fakeComponent{key: "a", value: 1}
+	b := &fakeComponent{key: "b", value: 2, prepareErr: errors.New("out of range")}
+	m := newTestManager(map[string]interface{}{"a": 10, "b": 20})
+	m.Register(a)
+	m.Register(b)
+
+	result, err := m.Reload()
+	require.EqualError(t, err, "invalid b configuration: out of range")
+	require.Equal(t, "invalid b configuration: out of range", result.Error)
+	require.Empty(t, result.Changes)
+	require.Equal(t, 1, a.value)
+}
+
+func TestManagerReloadRollback(t *testing.T) {
+	a := &fakeComponent{key: "a", value: 1}
+	b := &fakeComponent{key: "b", value: 2}
+	c := &fakeComponent{key: "c", value: 3, applyErr: errors.New("boom")}
+	m := newTestManager(map[string]interface{}{"a": 10, "b": 20, "c": 30})
+	m.Register(a)
+	m.Register(b)
+	m.Register(c)
+
+	_, err := m.Reload()
+	require.EqualError(t, err, "failed applying the c configuration: boom")
+	require.Equal(t, 1, a.value)
+	require.Equal(t, 2, b.value)
+	require.Equal(t, 3, c.value)
+}
+
+func TestManagerReloadLoadFailure(t *testing.T) {
+	m := NewManager(func() (*viper.Viper, error) { return nil, errors.New("no such file") })
+	result, err := m.Reload()
+	require.EqualError(t, err, "failed loading the configuration: no such file")
+	require.Equal(t, result, m.Last())
+}
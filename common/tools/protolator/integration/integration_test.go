@@ -16,6 +16,7 @@ import (
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/tools/protolator"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
@@ -234,6 +235,30 @@ func TestChannelCreationPolicy(t *testing.T) {
 	bidirectionalMarshal(t, cu)
 }
 
+func TestPolicyTemplates(t *testing.T) {
+	cu := &cb.ConfigUpdate{
+		WriteSet: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				"Application": {
+					Values: map[string]*cb.ConfigValue{
+						"PolicyTemplates": {
+							Value: protoutil.MarshalOrPanic(channelconfig.PolicyTemplatesValue(map[string]string{
+								"MAJORITY": "OutOf($# / 2 + 1, $*)",
+							}).Value()),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	bidirectionalMarshal(t, cu)
+
+	var buffer bytes.Buffer
+	require.NoError(t, protolator.DeepMarshalJSON(&buffer, cu))
+	assert.Contains(t, buffer.String(), `"MAJORITY": "OutOf($# / 2 + 1, $*)"`)
+}
+
 func TestStaticMarshal(t *testing.T) {
 	// To generate artifacts:
 	// e.g.
@@ -0,0 +1,252 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	defaultQueueSize     = 4096
+
+	// the status codes of OTLP
+	statusCodeOk    = 1
+	statusCodeError = 2
+)
+
+// OTLPExporter exports spans in batches to an OpenTelemetry collector, with
+// the JSON encoding of OTLP over HTTP. Spans are dropped when the queue of
+// the exporter is full.
+type OTLPExporter struct {
+	url           string
+	serviceName   string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+
+	spans    chan *Span
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewOTLPExporter creates an exporter sending the spans of the named service
+// to the traces endpoint of the collector at the given URL, such as
+// http://localhost:4318.
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	e := &OTLPExporter{
+		url:           strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName:   serviceName,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		spans:         make(chan *Span, defaultQueueSize),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export queues the span for export.
+func (e *OTLPExporter) Export(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		logger.Debugf("Dropping span %s, the export queue is full", span.Name)
+	}
+}
+
+// Stop exports the queued spans and stops the exporter.
+func (e *OTLPExporter) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+	<-e.stopped
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) < e.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-e.stop:
+			for {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+				default:
+					e.send(batch)
+					return
+				}
+			}
+		}
+		e.send(batch)
+		batch = nil
+	}
+}
+
+func (e *OTLPExporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		logger.Warningf("Failed to marshal %d spans: %s", len(batch), err)
+		return
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Warningf("Failed to export %d spans to %s: %s", len(batch), e.url, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		logger.Warningf("Failed to export %d spans to %s: %s", len(batch), e.url, resp.Status)
+	}
+}
+
+// The types below are the JSON encoding of the ExportTraceServiceRequest of OTLP.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func (e *OTLPExporter) request(batch []*Span) *exportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Attributes:        keyValues(s.Attributes()),
+			Status:            status{Code: statusCodeOk},
+		}
+		if s.ParentSpanID != (SpanID{}) {
+			span.ParentSpanID = hex.EncodeToString(s.ParentSpanID[:])
+		}
+		if s.Err != nil {
+			span.Status = status{Code: statusCodeError, Message: s.Err.Error()}
+		}
+		spans = append(spans, span)
+	}
+
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: keyValues(map[string]interface{}{"service.name": e.serviceName}),
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: "github.com/hyperledger/fabric"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func keyValues(attributes map[string]interface{}) []keyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		var value anyValue
+		switch v := attributes[k].(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int, int32, int64, uint, uint32, uint64:
+			i := fmt.Sprint(v)
+			value.IntValue = &i
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		kvs = append(kvs, keyValue{Key: k, Value: value})
+	}
+	return kvs
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var request map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL+"/", "peer0")
+	tracer := NewTracer(exporter, 1)
+	ctx, root := tracer.Start(context.Background(), "root")
+	root.SetAttribute("block", uint64(7))
+	root.SetAttribute("valid", true)
+	_, child := tracer.Start(ctx, "child")
+	child.SetError(errors.New("boom"))
+	child.End()
+	root.End()
+	exporter.Stop()
+
+	require.Len(t, requests, 1)
+	request := <-requests
+	resourceSpans := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "peer0"}},
+		},
+	}, resourceSpans["resource"])
+
+	spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	require.Len(t, spans, 2)
+	childSpan := spans[0].(map[string]interface{})
+	assert.Equal(t, "child", childSpan["name"])
+	assert.Equal(t, hex.EncodeToString(root.TraceID[:]), childSpan["traceId"])
+	assert.Equal(t, hex.EncodeToString(child.SpanID[:]), childSpan["spanId"])
+	assert.Equal(t, hex.EncodeToString(root.SpanID[:]), childSpan["parentSpanId"])
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "boom"}, childSpan["status"])
+
+	rootSpan := spans[1].(map[string]interface{})
+	assert.Equal(t, "root", rootSpan["name"])
+	assert.NotContains(t, rootSpan, "parentSpanId")
+	assert.Equal(t, float64(SpanKindInternal), rootSpan["kind"])
+	assert.Equal(t, map[string]interface{}{"code": float64(1)}, rootSpan["status"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "block", "value": map[string]interface{}{"intValue": "7"}},
+		map[string]interface{}{"key": "valid", "value": map[string]interface{}{"boolValue": true}},
+	}, rootSpan["attributes"])
+}
+
+func TestOTLPExporterUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	exporter := NewOTLPExporter(url, "orderer0")
+	_, span := NewTracer(exporter, 1).Start(context.Background(), "span")
+	span.End()
+	exporter.Stop()
+	exporter.Stop()
+}
@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceParentKey is the gRPC metadata key carrying the trace context.
+const TraceParentKey = "traceparent"
+
+// ExtractIncoming returns a context whose spans are the children of the span
+// of the remote party carried by the incoming gRPC metadata, if any.
+func ExtractIncoming(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	values := md.Get(TraceParentKey)
+	if len(values) == 0 {
+		return ctx
+	}
+	sc, err := ParseTraceParent(values[0])
+	if err != nil {
+		logger.Debugf("Ignoring trace context: %s", err)
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// InjectOutgoing adds the context of the current span of the given context to
+// the outgoing gRPC metadata.
+func InjectOutgoing(ctx context.Context) context.Context {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, TraceParentKey, sc.TraceParent())
+}
+
+// UnaryServerInterceptor records a server span for each unary call, as the
+// child of the span of the caller carried by the gRPC metadata.
+func UnaryServerInterceptor(tracer *Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := tracer.start(ExtractIncoming(ctx), info.FullMethod, SpanKindServer)
+		resp, err := handler(ctx, req)
+		span.SetError(err)
+		span.End()
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor propagates the span of the caller carried by the
+// gRPC metadata to the context of the stream. No span is recorded for the
+// stream itself, as streams are often long lived; the handlers of streams
+// record spans for the messages they process instead.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ExtractIncoming(ss.Context())
+		return handler(srv, wrapped)
+	}
+}
+
+// UnaryClientInterceptor propagates the current span of the context of the
+// call to the server through the gRPC metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(InjectOutgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor propagates the current span of the context of the
+// stream to the server through the gRPC metadata.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(InjectOutgoing(ctx), desc, cc, method, opts...)
+	}
+}
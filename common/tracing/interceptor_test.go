@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	exporter := &recordingExporter{}
+	interceptor := UnaryServerInterceptor(NewTracer(exporter, 0))
+	remote, err := ParseTraceParent(traceParent)
+	require.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceParentKey, traceParent))
+	info := &grpc.UnaryServerInfo{FullMethod: "/protos.Endorser/ProcessProposal"}
+	_, err = interceptor(ctx, "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		sc, ok := SpanContextFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, remote.TraceID, sc.TraceID)
+		return nil, errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	require.Len(t, exporter.spans, 1)
+	span := exporter.spans[0]
+	assert.Equal(t, "/protos.Endorser/ProcessProposal", span.Name)
+	assert.Equal(t, SpanKindServer, span.Kind)
+	assert.Equal(t, remote.TraceID, span.TraceID)
+	assert.Equal(t, remote.SpanID, span.ParentSpanID)
+	assert.EqualError(t, span.Err, "boom")
+
+	// unsampled calls without trace context are not exported
+	_, err = interceptor(context.Background(), "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, exporter.spans, 1)
+
+	// calls are handled without a tracer
+	_, err = UnaryServerInterceptor(nil)(ctx, "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "response", nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceParentKey, traceParent))
+	err := StreamServerInterceptor()(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		sc, ok := SpanContextFromContext(stream.Context())
+		assert.True(t, ok)
+		assert.Equal(t, traceParent, sc.TraceParent())
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// invalid trace context is ignored
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceParentKey, "garbage"))
+	err = StreamServerInterceptor()(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		_, ok := SpanContextFromContext(stream.Context())
+		assert.False(t, ok)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestClientInterceptors(t *testing.T) {
+	remote, err := ParseTraceParent(traceParent)
+	require.NoError(t, err)
+	ctx, span := NewTracer(nil, 1).Start(ContextWithRemoteSpanContext(context.Background(), remote), "client")
+
+	err = UnaryClientInterceptor()(ctx, "/method", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []string{span.TraceParent()}, md.Get(TraceParentKey))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	_, err = StreamClientInterceptor()(ctx, &grpc.StreamDesc{}, nil, "/method", func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []string{span.TraceParent()}, md.Get(TraceParentKey))
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	// nothing is propagated without a span
+	ctx = InjectOutgoing(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package tracing records the spans of distributed traces and exports them
+// with the OpenTelemetry protocol (OTLP). The trace context is propagated
+// between processes in the W3C traceparent format.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+var logger = flogging.MustGetLogger("tracing")
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the part of a span that is propagated to its children,
+// including those in other processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent formats the span context as a W3C traceparent header.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header.
+func ParseTraceParent(traceParent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", traceParent)
+	}
+
+	var sc SpanContext
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.TraceID) {
+		return SpanContext{}, fmt.Errorf("invalid trace ID in traceparent '%s'", traceParent)
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.SpanID) {
+		return SpanContext{}, fmt.Errorf("invalid span ID in traceparent '%s'", traceParent)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}, fmt.Errorf("invalid flags in traceparent '%s'", traceParent)
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&0x01 != 0
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent '%s'", traceParent)
+	}
+	return sc, nil
+}
+
+// SpanKind describes the relationship of a span to the remote parties of the operation.
+type SpanKind int
+
+// The values of the span kinds match those of OTLP.
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// Exporter exports the spans which have ended.
+type Exporter interface {
+	Export(span *Span)
+}
+
+// Tracer starts the spans of the traces. A nil Tracer starts no spans.
+type Tracer struct {
+	exporter   Exporter
+	sampleRate float64
+}
+
+// NewTracer creates a tracer exporting the spans of the given fraction of the
+// traces started by the tracer to the exporter. Traces started by a remote
+// party are sampled if the remote party sampled them.
+func NewTracer(exporter Exporter, sampleRate float64) *Tracer {
+	return &Tracer{
+		exporter:   exporter,
+		sampleRate: sampleRate,
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithRemoteSpanContext returns a context whose spans are the children
+// of the given span of a remote party.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the context of the current span of the
+// given context, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	switch v := ctx.Value(spanContextKey{}).(type) {
+	case *Span:
+		return v.SpanContext, true
+	case SpanContext:
+		return v, true
+	default:
+		return SpanContext{}, false
+	}
+}
+
+// Start starts a span with the given name as the child of the current span
+// of the given context. The returned context holds the new span, which must
+// be ended.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	return t.start(ctx, name, SpanKindInternal)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+		tracer:    t,
+	}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+		span.Sampled = parent.Sampled
+	} else {
+		rand.Read(span.TraceID[:])
+		span.Sampled = t.sample(span.TraceID)
+	}
+	rand.Read(span.SpanID[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// sample decides from the random trace ID whether a trace is sampled, so the
+// decision is consistent for the trace
+func (t *Tracer) sample(traceID TraceID) bool {
+	switch {
+	case t.sampleRate >= 1:
+		return true
+	case t.sampleRate <= 0:
+		return false
+	default:
+		return float64(binary.BigEndian.Uint64(traceID[8:])>>11)/(1<<53) < t.sampleRate
+	}
+}
+
+// Span records an operation of a trace. The methods of a nil Span do nothing.
+type Span struct {
+	SpanContext
+	ParentSpanID SpanID
+	Name         string
+	Kind         SpanKind
+	StartTime    time.Time
+	EndTime      time.Time
+	Err          error
+	tracer       *Tracer
+	mutex        sync.Mutex
+	attributes   map[string]interface{}
+}
+
+// SetAttribute sets an attribute of the span. The value is a string, a bool,
+// or an integer.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.attributes == nil {
+		s.attributes = map[string]interface{}{}
+	}
+	s.attributes[key] = value
+}
+
+// Attributes returns a copy of the attributes of the span.
+func (s *Span) Attributes() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	attributes := make(map[string]interface{}, len(s.attributes))
+	for k, v := range s.attributes {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+// SetError records that the operation of the span failed with the given error.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.Err = err
+	s.mutex.Unlock()
+}
+
+// End ends the span, which is exported if its trace is sampled.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.EndTime = time.Now()
+	s.mutex.Unlock()
+	if s.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(s)
+	}
+}
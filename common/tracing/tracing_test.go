@@ -0,0 +1,112 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (r *recordingExporter) Export(span *Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.TraceParent())
+
+	sc, err = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.False(t, sc.Sampled)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0100",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, 1)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	root.SetAttribute("channel", "mychannel")
+	_, child := tracer.Start(ctx, "child")
+	child.SetError(errors.New("boom"))
+	child.End()
+	root.End()
+
+	require.Len(t, exporter.spans, 2)
+	assert.Equal(t, "child", exporter.spans[0].Name)
+	assert.Equal(t, root.TraceID, exporter.spans[0].TraceID)
+	assert.Equal(t, root.SpanID, exporter.spans[0].ParentSpanID)
+	assert.EqualError(t, exporter.spans[0].Err, "boom")
+	assert.Equal(t, "root", exporter.spans[1].Name)
+	assert.Equal(t, SpanID{}, exporter.spans[1].ParentSpanID)
+	assert.Equal(t, map[string]interface{}{"channel": "mychannel"}, exporter.spans[1].Attributes())
+	assert.False(t, exporter.spans[1].EndTime.Before(exporter.spans[1].StartTime))
+}
+
+func TestTracerSampling(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, 0)
+
+	_, span := tracer.Start(context.Background(), "unsampled")
+	span.End()
+	assert.Empty(t, exporter.spans)
+
+	// the sampling decision of the remote party is followed
+	remote, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	_, span = tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "sampled")
+	span.End()
+	require.Len(t, exporter.spans, 1)
+	assert.Equal(t, remote.TraceID, exporter.spans[0].TraceID)
+	assert.Equal(t, remote.SpanID, exporter.spans[0].ParentSpanID)
+
+	tracer = NewTracer(exporter, 0.5)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		_, span := tracer.Start(context.Background(), "span")
+		if span.Sampled {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "span")
+	assert.Equal(t, context.Background(), ctx)
+	assert.Nil(t, span)
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("boom"))
+	span.End()
+}
@@ -303,7 +303,13 @@ func bccspHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, e
 
 	config := factory.GetDefaultOpts()
 
-	err := mapstructure.Decode(data, config)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     config,
+	})
+	if err == nil {
+		err = decoder.Decode(data)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "could not decode bcssp type")
 	}
@@ -70,6 +70,8 @@ type ChaincodeSupport struct {
 	Peer                   *peer.Peer
 	Runtime                Runtime
 	TotalQueryLimit        int
+	MaxPageSize            int
+	EnableResumeTokens     bool
 	UserRunsCC             bool
 }
 
@@ -120,6 +122,8 @@ func (cs *ChaincodeSupport) HandleChaincodeStream(stream ccintf.ChaincodeStream)
 		AppConfig:              cs.AppConfig,
 		Metrics:                cs.HandlerMetrics,
 		TotalQueryLimit:        cs.TotalQueryLimit,
+		MaxPageSize:            cs.MaxPageSize,
+		EnableResumeTokens:     cs.EnableResumeTokens,
 	}
 
 	return handler.ProcessStream(stream)
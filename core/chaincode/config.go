@@ -21,16 +21,27 @@ const (
 )
 
 type Config struct {
-	TotalQueryLimit int
-	TLSEnabled      bool
-	Keepalive       time.Duration
-	ExecuteTimeout  time.Duration
-	InstallTimeout  time.Duration
-	StartupTimeout  time.Duration
-	LogFormat       string
-	LogLevel        string
-	ShimLogLevel    string
-	SCCWhitelist    map[string]bool
+	TotalQueryLimit    int
+	MaxPageSize        int
+	EnableResumeTokens bool
+	TLSEnabled         bool
+	Keepalive          time.Duration
+	ExecuteTimeout     time.Duration
+	InstallTimeout     time.Duration
+	StartupTimeout     time.Duration
+	LogFormat          string
+	LogLevel           string
+	ShimLogLevel       string
+	SCCWhitelist       map[string]bool
+
+	// ExternalReconnectAttempts, ExternalReconnectBackoff and
+	// ExternalMaxReconnectBackoff control the retries of connections to
+	// chaincode running as an external service, which is health checked
+	// at ExternalHealthCheckInterval.
+	ExternalReconnectAttempts   int
+	ExternalReconnectBackoff    time.Duration
+	ExternalMaxReconnectBackoff time.Duration
+	ExternalHealthCheckInterval time.Duration
 }
 
 func GlobalConfig() *Config {
@@ -63,6 +74,11 @@ func (c *Config) load() {
 		c.SCCWhitelist[k] = parseBool(v)
 	}
 
+	c.ExternalReconnectAttempts = viper.GetInt("chaincode.external.reconnectAttempts")
+	c.ExternalReconnectBackoff = viper.GetDuration("chaincode.external.reconnectBackoff")
+	c.ExternalMaxReconnectBackoff = viper.GetDuration("chaincode.external.maxReconnectBackoff")
+	c.ExternalHealthCheckInterval = viper.GetDuration("chaincode.external.healthCheckInterval")
+
 	c.LogFormat = viper.GetString("chaincode.logging.format")
 	c.LogLevel = getLogLevelFromViper("chaincode.logging.level")
 	c.ShimLogLevel = getLogLevelFromViper("chaincode.logging.shim")
@@ -71,6 +87,8 @@ func (c *Config) load() {
 	if viper.IsSet("ledger.state.totalQueryLimit") {
 		c.TotalQueryLimit = viper.GetInt("ledger.state.totalQueryLimit")
 	}
+	c.MaxPageSize = viper.GetInt("ledger.state.maxPageSize")
+	c.EnableResumeTokens = viper.GetBool("ledger.state.enableResumeTokens")
 }
 
 func parseBool(s string) bool {
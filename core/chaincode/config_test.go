@@ -36,6 +36,12 @@ var _ = Describe("Config", func() {
 			viper.Set("chaincode.logging.format", "test-chaincode-logging-format")
 			viper.Set("chaincode.logging.level", "warning")
 			viper.Set("chaincode.logging.shim", "warning")
+			viper.Set("ledger.state.maxPageSize", 500)
+			viper.Set("ledger.state.enableResumeTokens", true)
+			viper.Set("chaincode.external.reconnectAttempts", 5)
+			viper.Set("chaincode.external.reconnectBackoff", "2s")
+			viper.Set("chaincode.external.maxReconnectBackoff", "1m")
+			viper.Set("chaincode.external.healthCheckInterval", "10s")
 
 			config := chaincode.GlobalConfig()
 			Expect(config.TLSEnabled).To(BeTrue())
@@ -46,6 +52,12 @@ var _ = Describe("Config", func() {
 			Expect(config.LogFormat).To(Equal("test-chaincode-logging-format"))
 			Expect(config.LogLevel).To(Equal("warn"))
 			Expect(config.ShimLogLevel).To(Equal("warn"))
+			Expect(config.MaxPageSize).To(Equal(500))
+			Expect(config.EnableResumeTokens).To(BeTrue())
+			Expect(config.ExternalReconnectAttempts).To(Equal(5))
+			Expect(config.ExternalReconnectBackoff).To(Equal(2 * time.Second))
+			Expect(config.ExternalMaxReconnectBackoff).To(Equal(time.Minute))
+			Expect(config.ExternalHealthCheckInterval).To(Equal(10 * time.Second))
 		})
 
 		Context("when an invalid keepalive is configured", func() {
@@ -121,6 +133,11 @@ func capture() (restore func()) {
 		"chaincode.logging.format": viper.GetString("chaincode.logging.format"),
 		"chaincode.logging.level":  viper.GetString("chaincode.logging.level"),
 		"chaincode.logging.shim":   viper.GetString("chaincode.logging.shim"),
+
+		"chaincode.external.reconnectAttempts":   viper.GetString("chaincode.external.reconnectAttempts"),
+		"chaincode.external.reconnectBackoff":    viper.GetString("chaincode.external.reconnectBackoff"),
+		"chaincode.external.maxReconnectBackoff": viper.GetString("chaincode.external.maxReconnectBackoff"),
+		"chaincode.external.healthCheckInterval": viper.GetString("chaincode.external.healthCheckInterval"),
 	}
 
 	return func() {
@@ -8,8 +8,10 @@ package extcc
 
 import (
 	"context"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/container/ccintf"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/pkg/errors"
@@ -17,6 +19,7 @@ import (
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 )
 
 var extccLogger = flogging.MustGetLogger("extcc")
@@ -26,42 +29,113 @@ type StreamHandler interface {
 	HandleChaincodeStream(stream ccintf.ChaincodeStream) error
 }
 
+// ExternalChaincodeRuntime connects to chaincode running as an external service.
 type ExternalChaincodeRuntime struct {
+	// ReconnectAttempts is the number of times connecting to the chaincode
+	// endpoint is retried before the connection fails.
+	ReconnectAttempts int
+	// ReconnectBackoff is the delay before the first retry, which doubles
+	// with every retry up to MaxReconnectBackoff.
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+	// HealthCheckInterval is the interval at which the connection to the
+	// chaincode endpoint is checked while streaming. An unhealthy connection
+	// closes the stream, so the chaincode is reconnected on its next launch.
+	// Zero disables the health check.
+	HealthCheckInterval time.Duration
+	Metrics             *Metrics
 }
 
-// createConnection - standard grpc client creating using ClientConfig info (surprised there isn't
-// a helper method for this)
-func (i *ExternalChaincodeRuntime) createConnection(ccid string, ccinfo *ccintf.ChaincodeServerInfo) (*grpc.ClientConn, error) {
+// connect creates a connection and stream to the chaincode endpoint, retrying
+// with backoff while the endpoint can't be reached
+func (i *ExternalChaincodeRuntime) connect(ccid string, ccinfo *ccintf.ChaincodeServerInfo, metrics *Metrics) (*grpc.ClientConn, pb.Chaincode_ConnectClient, context.CancelFunc, error) {
+	// the configuration of the client is not retried, as it can't succeed later
 	grpcClient, err := comm.NewGRPCClient(ccinfo.ClientConfig)
 	if err != nil {
-		return nil, errors.WithMessagef(err, "error creating grpc client to %s", ccid)
+		return nil, nil, nil, errors.WithMessagef(err, "error cannot create connection for %s: error creating grpc client to %s", ccid, ccid)
 	}
 
+	backoff := i.ReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		conn, stream, cancel, err := i.dial(ccid, ccinfo, grpcClient)
+		if err == nil {
+			return conn, stream, cancel, nil
+		}
+		metrics.ConnectionFailures.With("chaincode", ccid).Add(1)
+
+		if attempt > i.ReconnectAttempts {
+			return nil, nil, nil, errors.WithMessagef(err, "chaincode endpoint %s unreachable after %d attempts", ccinfo.Address, attempt)
+		}
+		extccLogger.Warningf("Failed to connect to chaincode %s at %s, retrying in %s: %s", ccid, ccinfo.Address, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if i.MaxReconnectBackoff > 0 && backoff > i.MaxReconnectBackoff {
+			backoff = i.MaxReconnectBackoff
+		}
+	}
+}
+
+func (i *ExternalChaincodeRuntime) dial(ccid string, ccinfo *ccintf.ChaincodeServerInfo, grpcClient *comm.GRPCClient) (*grpc.ClientConn, pb.Chaincode_ConnectClient, context.CancelFunc, error) {
 	conn, err := grpcClient.NewConnection(ccinfo.Address)
 	if err != nil {
-		return nil, errors.WithMessagef(err, "error creating grpc connection to %s", ccinfo.Address)
+		return nil, nil, nil, errors.WithMessagef(err, "error creating grpc connection to %s", ccinfo.Address)
 	}
-
 	extccLogger.Debugf("Created external chaincode connection: %s", ccid)
 
-	return conn, nil
+	//create the client and start streaming
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewChaincodeClient(conn).Connect(ctx)
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, nil, nil, errors.WithMessagef(err, "error creating grpc client connection to %s", ccid)
+	}
+
+	return conn, stream, cancel, nil
+}
+
+// checkHealth closes the stream by cancelling it when the connection to the
+// chaincode endpoint fails, until done is closed
+func (i *ExternalChaincodeRuntime) checkHealth(ccid string, conn *grpc.ClientConn, cancel context.CancelFunc, metrics *Metrics, done <-chan struct{}) {
+	ticker := time.NewTicker(i.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			switch state := conn.GetState(); state {
+			case connectivity.TransientFailure, connectivity.Shutdown:
+				extccLogger.Warningf("Connection to chaincode %s is in state %s, closing the stream", ccid, state)
+				metrics.HealthCheckFailures.With("chaincode", ccid).Add(1)
+				cancel()
+				return
+			}
+		}
+	}
 }
 
 func (i *ExternalChaincodeRuntime) Stream(ccid string, ccinfo *ccintf.ChaincodeServerInfo, sHandler StreamHandler) error {
 	extccLogger.Debugf("Starting external chaincode connection: %s", ccid)
-	conn, err := i.createConnection(ccid, ccinfo)
-	if err != nil {
-		return errors.WithMessagef(err, "error cannot create connection for %s", ccid)
+	metrics := i.Metrics
+	if metrics == nil {
+		metrics = NewMetrics(&disabled.Provider{})
 	}
 
+	conn, stream, cancel, err := i.connect(ccid, ccinfo, metrics)
+	if err != nil {
+		return err
+	}
 	defer conn.Close()
+	defer cancel()
 
-	//create the client and start streaming
-	client := pb.NewChaincodeClient(conn)
+	metrics.Connected.With("chaincode", ccid).Set(1)
+	defer metrics.Connected.With("chaincode", ccid).Set(0)
 
-	stream, err := client.Connect(context.Background())
-	if err != nil {
-		return errors.WithMessagef(err, "error creating grpc client connection to %s", ccid)
+	if i.HealthCheckInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go i.checkHealth(ccid, conn, cancel, metrics, done)
 	}
 
 	//peer as client has to initiate the stream. Rest of the process is unchanged
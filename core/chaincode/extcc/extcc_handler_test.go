@@ -10,6 +10,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
 	"github.com/hyperledger/fabric/core/chaincode/extcc"
 	"github.com/hyperledger/fabric/core/chaincode/extcc/mock"
 	"github.com/hyperledger/fabric/core/container/ccintf"
@@ -23,13 +24,28 @@ import (
 
 var _ = Describe("Extcc", func() {
 	var (
-		i        *extcc.ExternalChaincodeRuntime
-		shandler *mock.StreamHandler
+		i                       *extcc.ExternalChaincodeRuntime
+		shandler                *mock.StreamHandler
+		fakeConnected           *metricsfakes.Gauge
+		fakeConnectionFailures  *metricsfakes.Counter
+		fakeHealthCheckFailures *metricsfakes.Counter
 	)
 
 	BeforeEach(func() {
 		shandler = &mock.StreamHandler{}
-		i = &extcc.ExternalChaincodeRuntime{}
+		fakeConnected = &metricsfakes.Gauge{}
+		fakeConnected.WithReturns(fakeConnected)
+		fakeConnectionFailures = &metricsfakes.Counter{}
+		fakeConnectionFailures.WithReturns(fakeConnectionFailures)
+		fakeHealthCheckFailures = &metricsfakes.Counter{}
+		fakeHealthCheckFailures.WithReturns(fakeHealthCheckFailures)
+		i = &extcc.ExternalChaincodeRuntime{
+			Metrics: &extcc.Metrics{
+				Connected:           fakeConnected,
+				ConnectionFailures:  fakeConnectionFailures,
+				HealthCheckFailures: fakeHealthCheckFailures,
+			},
+		}
 	})
 
 	Context("Run", func() {
@@ -70,6 +86,66 @@ var _ = Describe("Extcc", func() {
 
 				streamArg := shandler.HandleChaincodeStreamArgsForCall(0)
 				Expect(streamArg).To(Not(BeNil()))
+
+				Expect(fakeConnected.SetCallCount()).To(Equal(2))
+				Expect(fakeConnected.SetArgsForCall(0)).To(Equal(1.0))
+				Expect(fakeConnected.SetArgsForCall(1)).To(Equal(0.0))
+				Expect(fakeConnected.WithArgsForCall(0)).To(Equal([]string{"chaincode", "ccid"}))
+				Expect(fakeConnectionFailures.AddCallCount()).To(Equal(0))
+			})
+
+			When("the connection fails while streaming", func() {
+				BeforeEach(func() {
+					i.HealthCheckInterval = 10 * time.Millisecond
+					shandler.HandleChaincodeStreamStub = func(stream ccintf.ChaincodeStream) error {
+						ccserv.Stop()
+						cclist.Close()
+						<-stream.(grpc.ClientStream).Context().Done()
+						return nil
+					}
+				})
+
+				It("closes the stream", func() {
+					ccinfo := &ccintf.ChaincodeServerInfo{
+						Address: cclist.Addr().String(),
+						ClientConfig: comm.ClientConfig{
+							KaOpts:  comm.DefaultKeepaliveOptions,
+							Timeout: 10 * time.Second,
+						},
+					}
+					err := i.Stream("ccid", ccinfo, shandler)
+					Expect(err).To(BeNil())
+					Expect(fakeHealthCheckFailures.AddCallCount()).To(Equal(1))
+					Expect(fakeHealthCheckFailures.WithArgsForCall(0)).To(Equal([]string{"chaincode", "ccid"}))
+				})
+			})
+		})
+		When("chaincode is unreachable", func() {
+			var ccinfo *ccintf.ChaincodeServerInfo
+
+			BeforeEach(func() {
+				lis, err := net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).NotTo(HaveOccurred())
+				lis.Close()
+
+				i.ReconnectAttempts = 2
+				i.ReconnectBackoff = time.Millisecond
+				ccinfo = &ccintf.ChaincodeServerInfo{
+					Address: lis.Addr().String(),
+					ClientConfig: comm.ClientConfig{
+						KaOpts:  comm.DefaultKeepaliveOptions,
+						Timeout: 100 * time.Millisecond,
+					},
+				}
+			})
+
+			It("retries and returns an error", func() {
+				err := i.Stream("ccid", ccinfo, shandler)
+				Expect(err).To(MatchError(ContainSubstring("chaincode endpoint " + ccinfo.Address + " unreachable after 3 attempts")))
+				Expect(shandler.HandleChaincodeStreamCallCount()).To(Equal(0))
+				Expect(fakeConnectionFailures.AddCallCount()).To(Equal(3))
+				Expect(fakeConnectionFailures.WithArgsForCall(0)).To(Equal([]string{"chaincode", "ccid"}))
+				Expect(fakeConnected.SetCallCount()).To(Equal(0))
 			})
 		})
 		Context("chaincode info incorrect", func() {
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package extcc
+
+import "github.com/hyperledger/fabric/common/metrics"
+
+var (
+	connected = metrics.GaugeOpts{
+		Namespace:    "chaincode",
+		Name:         "external_connected",
+		Help:         "Whether the peer is connected to the external chaincode (1) or not (0).",
+		LabelNames:   []string{"chaincode"},
+		StatsdFormat: "%{#fqname}.%{chaincode}",
+	}
+	connectionFailures = metrics.CounterOpts{
+		Namespace:    "chaincode",
+		Name:         "external_connection_failures",
+		Help:         "The number of attempts to connect to external chaincode that have failed.",
+		LabelNames:   []string{"chaincode"},
+		StatsdFormat: "%{#fqname}.%{chaincode}",
+	}
+	healthCheckFailures = metrics.CounterOpts{
+		Namespace:    "chaincode",
+		Name:         "external_health_check_failures",
+		Help:         "The number of health checks of connections to external chaincode that have failed.",
+		LabelNames:   []string{"chaincode"},
+		StatsdFormat: "%{#fqname}.%{chaincode}",
+	}
+)
+
+type Metrics struct {
+	Connected           metrics.Gauge
+	ConnectionFailures  metrics.Counter
+	HealthCheckFailures metrics.Counter
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		Connected:           p.NewGauge(connected),
+		ConnectionFailures:  p.NewCounter(connectionFailures),
+		HealthCheckFailures: p.NewCounter(healthCheckFailures),
+	}
+}
@@ -98,6 +98,13 @@ type Handler struct {
 	// TotalQueryLimit specifies the maximum number of results to return for
 	// chaincode queries.
 	TotalQueryLimit int
+	// MaxPageSize specifies the maximum number of results to return for a
+	// page of a paginated chaincode query. Zero denotes no limit other than
+	// TotalQueryLimit.
+	MaxPageSize int
+	// EnableResumeTokens specifies whether the bookmarks of the paginated
+	// chaincode queries are returned as resume tokens.
+	EnableResumeTokens bool
 	// Invoker is used to invoke chaincode.
 	Invoker Invoker
 	// Registry is used to track active handlers.
@@ -708,11 +715,16 @@ func (h *Handler) HandleGetStateByRange(msg *pb.ChaincodeMessage, txContext *Tra
 	if err != nil {
 		return nil, err
 	}
+	if isMetadataSetForPagination(metadata) {
+		h.enforcePageSize(metadata)
+	}
 
 	totalReturnLimit := h.calculateTotalReturnLimit(metadata)
 	iterID := h.UUIDGenerator.New()
 	var rangeIter commonledger.ResultsIterator
 	isPaginated := false
+	var queryHash string
+	var offset int32
 	namespaceID := txContext.NamespaceID
 	collection := getStateByRange.Collection
 	if isCollectionSet(collection) {
@@ -726,11 +738,20 @@ func (h *Handler) HandleGetStateByRange(msg *pb.ChaincodeMessage, txContext *Tra
 			getStateByRange.StartKey, getStateByRange.EndKey)
 	} else if isMetadataSetForPagination(metadata) {
 		isPaginated = true
-		startKey := getStateByRange.StartKey
-		if isMetadataSetForPagination(metadata) {
-			if metadata.Bookmark != "" {
-				startKey = metadata.Bookmark
+		if h.EnableResumeTokens {
+			queryHash = rangeQueryHash(namespaceID, getStateByRange.StartKey, getStateByRange.EndKey)
+			token, err := decodeResumeToken(metadata.Bookmark, queryHash)
+			if err != nil {
+				return nil, err
 			}
+			if token != nil {
+				metadata.Bookmark = token.Bookmark
+				offset = token.Offset
+			}
+		}
+		startKey := getStateByRange.StartKey
+		if metadata.Bookmark != "" {
+			startKey = metadata.Bookmark
 		}
 		rangeIter, err = txContext.TXSimulator.GetStateRangeScanIteratorWithPagination(namespaceID,
 			startKey, getStateByRange.EndKey, metadata.PageSize)
@@ -747,6 +768,12 @@ func (h *Handler) HandleGetStateByRange(msg *pb.ChaincodeMessage, txContext *Tra
 		txContext.CleanupQueryContext(iterID)
 		return nil, errors.WithStack(err)
 	}
+	if isPaginated && h.EnableResumeTokens {
+		estimateRemaining := rangeCountEstimator(txContext, namespaceID, getStateByRange.EndKey)
+		if err := h.attachResumeToken(payload, queryHash, offset, metadata.PageSize, estimateRemaining); err != nil {
+			return nil, err
+		}
+	}
 
 	payloadBytes, err := proto.Marshal(payload)
 	if err != nil {
@@ -824,9 +851,14 @@ func (h *Handler) HandleGetQueryResult(msg *pb.ChaincodeMessage, txContext *Tran
 	if err != nil {
 		return nil, err
 	}
+	if isMetadataSetForPagination(metadata) {
+		h.enforcePageSize(metadata)
+	}
 
 	totalReturnLimit := h.calculateTotalReturnLimit(metadata)
 	isPaginated := false
+	var queryHash string
+	var offset int32
 	var executeIter commonledger.ResultsIterator
 	namespaceID := txContext.NamespaceID
 	collection := getQueryResult.Collection
@@ -840,6 +872,17 @@ func (h *Handler) HandleGetQueryResult(msg *pb.ChaincodeMessage, txContext *Tran
 		executeIter, err = txContext.TXSimulator.ExecuteQueryOnPrivateData(namespaceID, collection, getQueryResult.Query)
 	} else if isMetadataSetForPagination(metadata) {
 		isPaginated = true
+		if h.EnableResumeTokens {
+			queryHash = richQueryHash(namespaceID, getQueryResult.Query)
+			token, err := decodeResumeToken(metadata.Bookmark, queryHash)
+			if err != nil {
+				return nil, err
+			}
+			if token != nil {
+				metadata.Bookmark = token.Bookmark
+				offset = token.Offset
+			}
+		}
 		executeIter, err = txContext.TXSimulator.ExecuteQueryWithPagination(namespaceID,
 			getQueryResult.Query, metadata.Bookmark, metadata.PageSize)
 
@@ -857,6 +900,11 @@ func (h *Handler) HandleGetQueryResult(msg *pb.ChaincodeMessage, txContext *Tran
 		txContext.CleanupQueryContext(iterID)
 		return nil, errors.WithStack(err)
 	}
+	if isPaginated && h.EnableResumeTokens {
+		if err := h.attachResumeToken(payload, queryHash, offset, metadata.PageSize, nil); err != nil {
+			return nil, err
+		}
+	}
 
 	payloadBytes, err := proto.Marshal(payload)
 	if err != nil {
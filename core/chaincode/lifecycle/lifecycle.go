@@ -55,6 +55,11 @@ const (
 
 	// DefaultEndorsementPolicyRef is the name of the default endorsement policy for this channel
 	DefaultEndorsementPolicyRef = "/Channel/Application/Endorsement"
+
+	// PolicyTemplateReferencePrefix prefixes the channel config policy references which are
+	// signature policies referencing the policy templates of the channel. Such references are
+	// resolved into signature policies when the chaincode definition is approved or committed.
+	PolicyTemplateReferencePrefix = "template:"
 )
 
 var (
@@ -308,6 +313,7 @@ type ExternalFunctions struct {
 	InstalledChaincodesLister InstalledChaincodesLister
 	ChaincodeBuilder          ChaincodeBuilder
 	BuildRegistry             *container.BuildRegistry
+	SignatureVerifier         *persistence.SignatureVerifier
 }
 
 // CheckCommitReadiness takes a chaincode definition, checks that
@@ -669,6 +675,10 @@ func (ef *ExternalFunctions) InstallChaincode(chaincodeInstallPackage []byte) (*
 		return nil, errors.New("empty metadata for supplied chaincode")
 	}
 
+	if err := ef.SignatureVerifier.Verify(pkg); err != nil {
+		return nil, errors.WithMessage(err, "could not verify chaincode package signature")
+	}
+
 	packageID, err := ef.Resources.ChaincodeStore.Save(pkg.Metadata.Label, chaincodeInstallPackage)
 	if err != nil {
 		return nil, errors.WithMessage(err, "could not save cc install package")
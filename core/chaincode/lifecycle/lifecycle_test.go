@@ -415,6 +415,19 @@ var _ = Describe("ExternalFunctions", func() {
 			})
 		})
 
+		Context("when the package signature cannot be verified", func() {
+			BeforeEach(func() {
+				ef.SignatureVerifier = &persistence.SignatureVerifier{Required: true}
+			})
+
+			It("wraps and returns the error without saving the chaincode", func() {
+				cc, err := ef.InstallChaincode([]byte("cc-package"))
+				Expect(cc).To(BeNil())
+				Expect(err).To(MatchError("could not verify chaincode package signature: chaincode package is not signed"))
+				Expect(fakeCCStore.SaveCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when saving the chaincode fails", func() {
 			BeforeEach(func() {
 				fakeCCStore.SaveReturns("", fmt.Errorf("fake-error"))
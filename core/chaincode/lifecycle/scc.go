@@ -9,6 +9,7 @@ package lifecycle
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-protos-go/common"
@@ -18,11 +19,13 @@ import (
 	"github.com/hyperledger/fabric/common/cauthdsl"
 	"github.com/hyperledger/fabric/common/chaincode"
 	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/policydsl"
 	"github.com/hyperledger/fabric/core/aclmgmt"
 	"github.com/hyperledger/fabric/core/chaincode/persistence"
 	"github.com/hyperledger/fabric/core/dispatcher"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
@@ -363,6 +366,10 @@ func (i *Invocation) ApproveChaincodeDefinitionForMyOrg(input *lb.ApproveChainco
 	if err := i.validateInput(input.Name, input.Version, input.Collections); err != nil {
 		return nil, errors.WithMessage(err, "error validating chaincode definition")
 	}
+	validationParameter, err := i.resolvePolicyTemplates(input.ValidationParameter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error validating chaincode definition")
+	}
 	collectionName := ImplicitCollectionNameForOrg(i.SCC.OrgMSPID)
 	var collectionConfig []*pb.CollectionConfig
 	if input.Collections != nil {
@@ -388,7 +395,7 @@ func (i *Invocation) ApproveChaincodeDefinitionForMyOrg(input *lb.ApproveChainco
 		},
 		ValidationInfo: &lb.ChaincodeValidationInfo{
 			ValidationPlugin:    input.ValidationPlugin,
-			ValidationParameter: input.ValidationParameter,
+			ValidationParameter: validationParameter,
 		},
 		Collections: &pb.CollectionConfigPackage{
 			Config: collectionConfig,
@@ -459,6 +466,11 @@ func (i *Invocation) CheckCommitReadiness(input *lb.CheckCommitReadinessArgs) (p
 		return nil, err
 	}
 
+	validationParameter, err := i.resolvePolicyTemplates(input.ValidationParameter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error validating chaincode definition")
+	}
+
 	cd := &ChaincodeDefinition{
 		Sequence: input.Sequence,
 		EndorsementInfo: &lb.ChaincodeEndorsementInfo{
@@ -468,7 +480,7 @@ func (i *Invocation) CheckCommitReadiness(input *lb.CheckCommitReadinessArgs) (p
 		},
 		ValidationInfo: &lb.ChaincodeValidationInfo{
 			ValidationPlugin:    input.ValidationPlugin,
-			ValidationParameter: input.ValidationParameter,
+			ValidationParameter: validationParameter,
 		},
 		Collections: input.Collections,
 	}
@@ -505,6 +517,11 @@ func (i *Invocation) CommitChaincodeDefinition(input *lb.CommitChaincodeDefiniti
 		return nil, errors.Errorf("no application config for channel '%s'", i.Stub.GetChannelID())
 	}
 
+	validationParameter, err := i.resolvePolicyTemplates(input.ValidationParameter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error validating chaincode definition")
+	}
+
 	orgs := i.ApplicationConfig.Organizations()
 	opaqueStates := make([]OpaqueState, 0, len(orgs))
 	var myOrg string
@@ -531,7 +548,7 @@ func (i *Invocation) CommitChaincodeDefinition(input *lb.CommitChaincodeDefiniti
 		},
 		ValidationInfo: &lb.ChaincodeValidationInfo{
 			ValidationPlugin:    input.ValidationPlugin,
-			ValidationParameter: input.ValidationParameter,
+			ValidationParameter: validationParameter,
 		},
 		Collections: input.Collections,
 	}
@@ -703,6 +720,35 @@ func (i *Invocation) validateInput(name, version string, collections *pb.Collect
 	return nil
 }
 
+// resolvePolicyTemplates resolves an endorsement policy referencing the policy templates of
+// the channel into the signature policy it stands for. Other validation parameters are returned
+// as is. As with the collection configs, the policy templates may change afterwards, which
+// doesn't affect the policy of the definition once resolved.
+func (i *Invocation) resolvePolicyTemplates(validationParameter []byte) ([]byte, error) {
+	applicationPolicy := &pb.ApplicationPolicy{}
+	if err := proto.Unmarshal(validationParameter, applicationPolicy); err != nil {
+		return validationParameter, nil
+	}
+	reference := applicationPolicy.GetChannelConfigPolicyReference()
+	if !strings.HasPrefix(reference, PolicyTemplateReferencePrefix) {
+		return validationParameter, nil
+	}
+
+	var templates map[string]string
+	if i.ApplicationConfig != nil {
+		templates = i.ApplicationConfig.PolicyTemplates()
+	}
+	signaturePolicy, err := policydsl.FromStringWithTemplates(strings.TrimPrefix(reference, PolicyTemplateReferencePrefix), templates)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid endorsement policy '%s'", reference)
+	}
+	return protoutil.Marshal(&pb.ApplicationPolicy{
+		Type: &pb.ApplicationPolicy_SignaturePolicy{
+			SignaturePolicy: signaturePolicy,
+		},
+	})
+}
+
 func extractStaticCollectionConfigs(collConfigPkg *pb.CollectionConfigPackage) ([]*pb.StaticCollectionConfig, error) {
 	if collConfigPkg == nil || len(collConfigPkg.Config) == 0 {
 		return nil, nil
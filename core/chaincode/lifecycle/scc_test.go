@@ -26,6 +26,7 @@ import (
 	"github.com/hyperledger/fabric/core/chaincode/persistence"
 	"github.com/hyperledger/fabric/core/dispatcher"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
 
 	. "github.com/onsi/ginkgo"
@@ -558,6 +559,50 @@ var _ = Describe("SCC", func() {
 				})
 			})
 
+			Context("when the endorsement policy references the policy templates of the channel", func() {
+				BeforeEach(func() {
+					arg.ValidationParameter = protoutil.MarshalOrPanic(&pb.ApplicationPolicy{
+						Type: &pb.ApplicationPolicy_ChannelConfigPolicyReference{
+							ChannelConfigPolicyReference: "template:MAJORITY('Org1.peer', 'Org2.peer', 'Org3.peer')",
+						},
+					})
+					fakeApplicationConfig.PolicyTemplatesReturns(map[string]string{
+						"MAJORITY": "OutOf($# / 2 + 1, $*)",
+					})
+				})
+
+				It("approves the signature policy the reference resolves to", func() {
+					res := scc.Invoke(fakeStub)
+					Expect(res.Status).To(Equal(int32(200)))
+
+					Expect(fakeSCCFuncs.ApproveChaincodeDefinitionForOrgCallCount()).To(Equal(1))
+					_, _, cd, _, _, _ := fakeSCCFuncs.ApproveChaincodeDefinitionForOrgArgsForCall(0)
+					expectedPolicy, err := policydsl.FromString("OutOf(2, 'Org1.peer', 'Org2.peer', 'Org3.peer')")
+					Expect(err).NotTo(HaveOccurred())
+					applicationPolicy := &pb.ApplicationPolicy{}
+					err = proto.Unmarshal(cd.ValidationInfo.ValidationParameter, applicationPolicy)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(proto.Equal(applicationPolicy.GetSignaturePolicy(), expectedPolicy)).To(BeTrue())
+				})
+
+				Context("when the reference is invalid", func() {
+					BeforeEach(func() {
+						arg.ValidationParameter = protoutil.MarshalOrPanic(&pb.ApplicationPolicy{
+							Type: &pb.ApplicationPolicy_ChannelConfigPolicyReference{
+								ChannelConfigPolicyReference: "template:MAJORITY('Org1.peer', 'Org2.peer'",
+							},
+						})
+					})
+
+					It("wraps and returns the error", func() {
+						res := scc.Invoke(fakeStub)
+						Expect(res.Status).To(Equal(int32(500)))
+						Expect(res.Message).To(Equal("failed to invoke backing implementation of 'ApproveChaincodeDefinitionForMyOrg': error validating chaincode definition: invalid endorsement policy 'template:MAJORITY('Org1.peer', 'Org2.peer'': unbalanced parentheses in policy string 'MAJORITY('Org1.peer', 'Org2.peer''"))
+						Expect(fakeSCCFuncs.ApproveChaincodeDefinitionForOrgCallCount()).To(Equal(0))
+					})
+				})
+			})
+
 			Context("when a collection name begins with an invalid character", func() {
 				BeforeEach(func() {
 					collConfigs[0].Name = "_collection"
@@ -1063,6 +1108,37 @@ var _ = Describe("SCC", func() {
 				})
 			})
 
+			Context("when the endorsement policy references the policy templates of the channel", func() {
+				BeforeEach(func() {
+					arg.ValidationParameter = protoutil.MarshalOrPanic(&pb.ApplicationPolicy{
+						Type: &pb.ApplicationPolicy_ChannelConfigPolicyReference{
+							ChannelConfigPolicyReference: "template:Pair('org0.member', 'org1.member')",
+						},
+					})
+					fakeApplicationConfig.PolicyTemplatesReturns(map[string]string{
+						"Pair": "AND($1, $2)",
+					})
+
+					marshaledArg, err = proto.Marshal(arg)
+					Expect(err).NotTo(HaveOccurred())
+					fakeStub.GetArgsReturns([][]byte{[]byte("CommitChaincodeDefinition"), marshaledArg})
+				})
+
+				It("commits the signature policy the reference resolves to", func() {
+					res := scc.Invoke(fakeStub)
+					Expect(res.Status).To(Equal(int32(200)))
+
+					Expect(fakeSCCFuncs.CommitChaincodeDefinitionCallCount()).To(Equal(1))
+					_, _, cd, _, _ := fakeSCCFuncs.CommitChaincodeDefinitionArgsForCall(0)
+					expectedPolicy, err := policydsl.FromString("AND('org0.member', 'org1.member')")
+					Expect(err).NotTo(HaveOccurred())
+					applicationPolicy := &pb.ApplicationPolicy{}
+					err = proto.Unmarshal(cd.ValidationInfo.ValidationParameter, applicationPolicy)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(proto.Equal(applicationPolicy.GetSignaturePolicy(), expectedPolicy)).To(BeTrue())
+				})
+			})
+
 			Context("when a collection name contains invalid characters", func() {
 				BeforeEach(func() {
 					arg.Collections = &pb.CollectionConfigPackage{
@@ -38,6 +38,16 @@ type ApplicationConfig struct {
 	organizationsReturnsOnCall map[int]struct {
 		result1 map[string]channelconfig.ApplicationOrg
 	}
+	PolicyTemplatesStub        func() map[string]string
+	policyTemplatesMutex       sync.RWMutex
+	policyTemplatesArgsForCall []struct {
+	}
+	policyTemplatesReturns struct {
+		result1 map[string]string
+	}
+	policyTemplatesReturnsOnCall map[int]struct {
+		result1 map[string]string
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -198,6 +208,58 @@ func (fake *ApplicationConfig) OrganizationsReturnsOnCall(i int, result1 map[str
 	}{result1}
 }
 
+func (fake *ApplicationConfig) PolicyTemplates() map[string]string {
+	fake.policyTemplatesMutex.Lock()
+	ret, specificReturn := fake.policyTemplatesReturnsOnCall[len(fake.policyTemplatesArgsForCall)]
+	fake.policyTemplatesArgsForCall = append(fake.policyTemplatesArgsForCall, struct {
+	}{})
+	fake.recordInvocation("PolicyTemplates", []interface{}{})
+	fake.policyTemplatesMutex.Unlock()
+	if fake.PolicyTemplatesStub != nil {
+		return fake.PolicyTemplatesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.policyTemplatesReturns
+	return fakeReturns.result1
+}
+
+func (fake *ApplicationConfig) PolicyTemplatesCallCount() int {
+	fake.policyTemplatesMutex.RLock()
+	defer fake.policyTemplatesMutex.RUnlock()
+	return len(fake.policyTemplatesArgsForCall)
+}
+
+func (fake *ApplicationConfig) PolicyTemplatesCalls(stub func() map[string]string) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = stub
+}
+
+func (fake *ApplicationConfig) PolicyTemplatesReturns(result1 map[string]string) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = nil
+	fake.policyTemplatesReturns = struct {
+		result1 map[string]string
+	}{result1}
+}
+
+func (fake *ApplicationConfig) PolicyTemplatesReturnsOnCall(i int, result1 map[string]string) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = nil
+	if fake.policyTemplatesReturnsOnCall == nil {
+		fake.policyTemplatesReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+		})
+	}
+	fake.policyTemplatesReturnsOnCall[i] = struct {
+		result1 map[string]string
+	}{result1}
+}
+
 func (fake *ApplicationConfig) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -207,6 +269,8 @@ func (fake *ApplicationConfig) Invocations() map[string][][]interface{} {
 	defer fake.capabilitiesMutex.RUnlock()
 	fake.organizationsMutex.RLock()
 	defer fake.organizationsMutex.RUnlock()
+	fake.policyTemplatesMutex.RLock()
+	defer fake.policyTemplatesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -38,6 +38,10 @@ const (
 	// CodePackageFile is the expected location of the code package in the
 	// top level of the chaincode package
 	CodePackageFile = "code.tar.gz"
+
+	// SignatureFile is the expected location of the signature of a signed
+	// chaincode package in the top level of the chaincode package
+	SignatureFile = "signature.json"
 )
 
 //go:generate counterfeiter -o mock/legacy_cc_package_locator.go --fake-name LegacyCCPackageLocator . LegacyCCPackageLocator
@@ -219,6 +223,13 @@ type ChaincodePackage struct {
 	Metadata    *ChaincodePackageMetadata
 	CodePackage []byte
 	DBArtifacts []byte
+	// Signature is the signature of the package, or nil if the
+	// package is not signed
+	Signature *ChaincodePackageSignature
+
+	// metadataBytes are the raw bytes of the metadata file, as
+	// covered by the signature
+	metadataBytes []byte
 }
 
 // ChaincodePackageMetadata contains the information necessary to understand
@@ -264,8 +275,9 @@ func (ccpp ChaincodePackageParser) Parse(source []byte) (*ChaincodePackage, erro
 
 	tarReader := tar.NewReader(gzReader)
 
-	var codePackage []byte
+	var codePackage, metadataBytes []byte
 	var ccPackageMetadata *ChaincodePackageMetadata
+	var signature *ChaincodePackageSignature
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -293,9 +305,16 @@ func (ccpp ChaincodePackageParser) Parse(source []byte) (*ChaincodePackage, erro
 			if err != nil {
 				return nil, errors.Wrapf(err, "could not unmarshal %s as json", MetadataFile)
 			}
+			metadataBytes = fileBytes
 
 		case CodePackageFile:
 			codePackage = fileBytes
+		case SignatureFile:
+			signature = &ChaincodePackageSignature{}
+			err := json.Unmarshal(fileBytes, signature)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not unmarshal %s as json", SignatureFile)
+			}
 		default:
 			logger.Warningf("Encountered unexpected file '%s' in top level of chaincode package", header.Name)
 		}
@@ -319,8 +338,10 @@ func (ccpp ChaincodePackageParser) Parse(source []byte) (*ChaincodePackage, erro
 	}
 
 	return &ChaincodePackage{
-		Metadata:    ccPackageMetadata,
-		CodePackage: codePackage,
-		DBArtifacts: dbArtifacts,
+		Metadata:      ccPackageMetadata,
+		CodePackage:   codePackage,
+		DBArtifacts:   dbArtifacts,
+		Signature:     signature,
+		metadataBytes: metadataBytes,
 	}, nil
 }
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package persistence
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+// ChaincodePackageSignature is the content of the signature file of a signed
+// chaincode package.  The signature covers the SHA-256 digests of the metadata
+// file and of the code package, so the signer vouches for both.
+type ChaincodePackageSignature struct {
+	// Certificate is the PEM encoded certificate of the signer
+	Certificate []byte `json:"certificate"`
+	// Signature is the signature over the digests of the metadata
+	// file and of the code package
+	Signature []byte `json:"signature"`
+}
+
+// SignedContent returns the content covered by the signature of a chaincode
+// package with the given metadata file and code package.
+func SignedContent(metadata, codePackage []byte) []byte {
+	metadataHash := sha256.Sum256(metadata)
+	codePackageHash := sha256.Sum256(codePackage)
+	return append(metadataHash[:], codePackageHash[:]...)
+}
+
+// SignChaincodePackage signs the given metadata file and code package with the
+// given PEM encoded certificate and private key, and returns the content of the
+// signature file of the chaincode package.
+func SignChaincodePackage(metadata, codePackage, certPEM, keyPEM []byte) ([]byte, error) {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	content := SignedContent(metadata, codePackage)
+	digest := sha256.Sum256(content)
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not sign chaincode package")
+	}
+
+	if err := checkSignature(cert, content, sig); err != nil {
+		return nil, errors.WithMessage(err, "private key does not match the signing certificate")
+	}
+
+	signatureBytes, err := json.Marshal(&ChaincodePackageSignature{
+		Certificate: certPEM,
+		Signature:   sig,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal chaincode package signature")
+	}
+
+	return signatureBytes, nil
+}
+
+// SignatureVerifier verifies the signatures of chaincode packages against the
+// signing roots configured by the organization of the peer.
+type SignatureVerifier struct {
+	// Roots are the certificates which the certificates of the signers
+	// of chaincode packages must chain to
+	Roots *x509.CertPool
+	// Required rejects chaincode packages which are not signed
+	Required bool
+}
+
+// NewSignatureVerifier creates a SignatureVerifier trusting the given PEM
+// encoded signing roots.
+func NewSignatureVerifier(rootsPEM [][]byte, required bool) (*SignatureVerifier, error) {
+	if required && len(rootsPEM) == 0 {
+		return nil, errors.New("chaincode package signatures are required, but no signing roots were provided")
+	}
+
+	roots := x509.NewCertPool()
+	for _, rootPEM := range rootsPEM {
+		if !roots.AppendCertsFromPEM(rootPEM) {
+			return nil, errors.New("could not parse chaincode package signing root")
+		}
+	}
+
+	return &SignatureVerifier{
+		Roots:    roots,
+		Required: required,
+	}, nil
+}
+
+// Verify checks that the given chaincode package is signed by a signer whose
+// certificate chains to the signing roots.  Unsigned packages are accepted
+// unless signatures are required.  A nil SignatureVerifier accepts any package.
+func (sv *SignatureVerifier) Verify(pkg *ChaincodePackage) error {
+	if sv == nil {
+		return nil
+	}
+
+	if pkg.Signature == nil {
+		if sv.Required {
+			return errors.New("chaincode package is not signed")
+		}
+		return nil
+	}
+
+	cert, err := parseCertificate(pkg.Signature.Certificate)
+	if err != nil {
+		return err
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     sv.Roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "certificate of signer '%s' is not trusted", cert.Subject)
+	}
+
+	err = checkSignature(cert, SignedContent(pkg.metadataBytes, pkg.CodePackage), pkg.Signature.Signature)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid signature of signer '%s'", cert.Subject)
+	}
+
+	logger.Debugf("Chaincode package '%s' is signed by '%s'", pkg.Metadata.Label, cert.Subject)
+	return nil
+}
+
+func checkSignature(cert *x509.Certificate, content, sig []byte) error {
+	var algorithm x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	default:
+		return errors.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+
+	if err := cert.CheckSignature(algorithm, content, sig); err != nil {
+		return errors.Wrap(err, "signature verification failed")
+	}
+
+	return nil
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("could not decode signing certificate as PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse signing certificate")
+	}
+
+	return cert, nil
+}
+
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("could not decode signing key as PEM")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.Errorf("unsupported signing key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("could not parse signing key")
+}
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package persistence_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/core/chaincode/persistence"
+	"github.com/hyperledger/fabric/core/chaincode/persistence/mock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	tm "github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("SignatureVerifier", func() {
+	var (
+		metadata    []byte
+		codePackage []byte
+		ca          tlsgen.CA
+		signer      *tlsgen.CertKeyPair
+		ccpp        persistence.ChaincodePackageParser
+		verifier    *persistence.SignatureVerifier
+	)
+
+	parse := func(signature []byte) *persistence.ChaincodePackage {
+		buf := &bytes.Buffer{}
+		gw := gzip.NewWriter(buf)
+		tw := tar.NewWriter(gw)
+		files := []struct {
+			name    string
+			content []byte
+		}{
+			{persistence.MetadataFile, metadata},
+			{persistence.CodePackageFile, codePackage},
+			{persistence.SignatureFile, signature},
+		}
+		for _, f := range files {
+			if f.content == nil {
+				continue
+			}
+			err := tw.WriteHeader(&tar.Header{Name: f.name, Size: int64(len(f.content)), Mode: 0100644})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = tw.Write(f.content)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tw.Close()).To(Succeed())
+		Expect(gw.Close()).To(Succeed())
+
+		pkg, err := ccpp.Parse(buf.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		return pkg
+	}
+
+	BeforeEach(func() {
+		metadata = []byte(`{"type":"golang","path":"github.com/cc","label":"cc_1"}`)
+		codePackage = []byte("code")
+
+		var err error
+		ca, err = tlsgen.NewCA()
+		Expect(err).NotTo(HaveOccurred())
+		signer, err = ca.NewClientCertKeyPair()
+		Expect(err).NotTo(HaveOccurred())
+
+		mockMetaProvider := &mock.MetadataProvider{}
+		mockMetaProvider.On("GetDBArtifacts", tm.Anything).Return(nil, nil)
+		ccpp.MetadataProvider = mockMetaProvider
+
+		verifier, err = persistence.NewSignatureVerifier([][]byte{ca.CertBytes()}, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("verifies a signed chaincode package", func() {
+		signature, err := persistence.SignChaincodePackage(metadata, codePackage, signer.Cert, signer.Key)
+		Expect(err).NotTo(HaveOccurred())
+
+		pkg := parse(signature)
+		Expect(pkg.Signature).NotTo(BeNil())
+		Expect(pkg.Signature.Certificate).To(Equal(signer.Cert))
+		Expect(verifier.Verify(pkg)).To(Succeed())
+	})
+
+	It("accepts any package when it is nil", func() {
+		verifier = nil
+		Expect(verifier.Verify(parse(nil))).To(Succeed())
+	})
+
+	Context("when the package is not signed", func() {
+		It("rejects the package", func() {
+			err := verifier.Verify(parse(nil))
+			Expect(err).To(MatchError("chaincode package is not signed"))
+		})
+
+		Context("when signatures are not required", func() {
+			BeforeEach(func() {
+				verifier.Required = false
+			})
+
+			It("accepts the package", func() {
+				Expect(verifier.Verify(parse(nil))).To(Succeed())
+			})
+		})
+	})
+
+	Context("when the code package was tampered with", func() {
+		It("rejects the package", func() {
+			signature, err := persistence.SignChaincodePackage(metadata, codePackage, signer.Cert, signer.Key)
+			Expect(err).NotTo(HaveOccurred())
+			codePackage = []byte("malicious code")
+
+			err = verifier.Verify(parse(signature))
+			Expect(err).To(MatchError(ContainSubstring("invalid signature of signer")))
+			Expect(err).To(MatchError(ContainSubstring("signature verification failed")))
+		})
+	})
+
+	Context("when the signer is not trusted", func() {
+		It("rejects the package", func() {
+			otherCA, err := tlsgen.NewCA()
+			Expect(err).NotTo(HaveOccurred())
+			otherSigner, err := otherCA.NewClientCertKeyPair()
+			Expect(err).NotTo(HaveOccurred())
+			signature, err := persistence.SignChaincodePackage(metadata, codePackage, otherSigner.Cert, otherSigner.Key)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = verifier.Verify(parse(signature))
+			Expect(err).To(MatchError(ContainSubstring("is not trusted")))
+		})
+	})
+
+	Context("when the signer certificate is not PEM encoded", func() {
+		It("rejects the package", func() {
+			signature, err := json.Marshal(&persistence.ChaincodePackageSignature{
+				Certificate: []byte("garbage"),
+				Signature:   []byte("signature"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = verifier.Verify(parse(signature))
+			Expect(err).To(MatchError("could not decode signing certificate as PEM"))
+		})
+	})
+
+	Context("when the signature file is not json", func() {
+		It("fails to parse the package", func() {
+			buf := &bytes.Buffer{}
+			gw := gzip.NewWriter(buf)
+			tw := tar.NewWriter(gw)
+			Expect(tw.WriteHeader(&tar.Header{Name: persistence.SignatureFile, Size: 3, Mode: 0100644})).To(Succeed())
+			_, err := tw.Write([]byte("foo"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+			Expect(gw.Close()).To(Succeed())
+
+			_, err = ccpp.Parse(buf.Bytes())
+			Expect(err).To(MatchError(ContainSubstring("could not unmarshal signature.json as json")))
+		})
+	})
+
+	Describe("SignChaincodePackage", func() {
+		Context("when the key does not match the certificate", func() {
+			It("fails", func() {
+				other, err := ca.NewClientCertKeyPair()
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = persistence.SignChaincodePackage(metadata, codePackage, signer.Cert, other.Key)
+				Expect(err).To(MatchError(ContainSubstring("private key does not match the signing certificate")))
+			})
+		})
+
+		Context("when the key is not PEM encoded", func() {
+			It("fails", func() {
+				_, err := persistence.SignChaincodePackage(metadata, codePackage, signer.Cert, []byte("garbage"))
+				Expect(err).To(MatchError("could not decode signing key as PEM"))
+			})
+		})
+	})
+
+	Describe("NewSignatureVerifier", func() {
+		It("requires signing roots when signatures are required", func() {
+			_, err := persistence.NewSignatureVerifier(nil, true)
+			Expect(err).To(MatchError("chaincode package signatures are required, but no signing roots were provided"))
+		})
+
+		It("fails on roots which are not PEM encoded", func() {
+			_, err := persistence.NewSignatureVerifier([][]byte{[]byte("garbage")}, false)
+			Expect(err).To(MatchError("could not parse chaincode package signing root"))
+		})
+	})
+})
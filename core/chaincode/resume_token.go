@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+const resumeTokenPrefix = "rt1."
+
+// resumeToken is returned to the chaincode in place of the bookmark of a paginated query when the resume tokens
+// are enabled. The token carries all that is needed to resume the query and hence, remains valid across the peer
+// restarts and on any peer of the channel. The token is base64 encoded JSON so that the client applications can
+// read the number of records already returned and the estimate of the total number of records
+type resumeToken struct {
+	// Query is the hash of the query to which the token belongs
+	Query string `json:"query"`
+	// Bookmark is the bookmark from which the state database resumes the query
+	Bookmark string `json:"bookmark"`
+	// Offset is the number of records returned in the pages up to, and including, the one carrying the token
+	Offset int32 `json:"offset"`
+	// EstimatedTotal is the estimate of the total number of records returned by the query
+	EstimatedTotal int64 `json:"estimated_total"`
+	// EstimateIsLowerBound is set when the query may return more records than EstimatedTotal
+	EstimateIsLowerBound bool `json:"estimate_is_lower_bound,omitempty"`
+}
+
+func (t *resumeToken) encode() (string, error) {
+	tokenBytes, err := json.Marshal(t)
+	if err != nil {
+		return "", errors.Wrap(err, "error while marshalling the resume token")
+	}
+	return resumeTokenPrefix + base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// decodeResumeToken decodes the bookmark supplied by the chaincode and verifies that it was issued for the same
+// query. It returns nil if the bookmark is not a resume token, for instance, a bookmark obtained from a peer that
+// does not issue the resume tokens, in which case the bookmark is passed to the state database as is
+func decodeResumeToken(bookmark, queryHash string) (*resumeToken, error) {
+	if !strings.HasPrefix(bookmark, resumeTokenPrefix) {
+		return nil, nil
+	}
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(bookmark, resumeTokenPrefix))
+	if err != nil {
+		return nil, nil
+	}
+	token := &resumeToken{}
+	if err := json.Unmarshal(tokenBytes, token); err != nil {
+		return nil, nil
+	}
+	if token.Query != queryHash {
+		return nil, errors.New("the resume token was issued for a different query")
+	}
+	return token, nil
+}
+
+func rangeQueryHash(namespace, startKey, endKey string) string {
+	return computeQueryHash("range", namespace, startKey, endKey)
+}
+
+func richQueryHash(namespace, query string) string {
+	return computeQueryHash("rich", namespace, query)
+}
+
+func computeQueryHash(fields ...string) string {
+	h := sha256.New()
+	lenBuf := make([]byte, 8)
+	for _, f := range fields {
+		binary.BigEndian.PutUint64(lenBuf, uint64(len(f)))
+		h.Write(lenBuf)
+		h.Write([]byte(f))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// enforcePageSize reduces the page size requested by the chaincode to the configured maximum. A page size
+// of zero, which the state databases interpret as unlimited, is reduced to the maximum as well
+func (h *Handler) enforcePageSize(metadata *pb.QueryMetadata) {
+	if h.MaxPageSize <= 0 {
+		return
+	}
+	if metadata.PageSize <= 0 || metadata.PageSize > int32(h.MaxPageSize) {
+		metadata.PageSize = int32(h.MaxPageSize)
+	}
+}
+
+// attachResumeToken replaces the bookmark in the metadata of the response to a paginated query with a resume token.
+// The function estimateRemaining, if not nil, returns the number of records that follow the given bookmark, counting
+// no further than the given limit. If the query has no more records, the bookmark is left empty
+func (h *Handler) attachResumeToken(
+	payload *pb.QueryResponse,
+	queryHash string,
+	offset int32,
+	pageSize int32,
+	estimateRemaining func(bookmark string, limit uint64) (uint64, error),
+) error {
+	metadata := &pb.QueryResponseMetadata{}
+	if err := proto.Unmarshal(payload.Metadata, metadata); err != nil {
+		return errors.Wrap(err, "unmarshal failed")
+	}
+	if metadata.Bookmark == "" {
+		return nil
+	}
+
+	token := &resumeToken{
+		Query:    queryHash,
+		Bookmark: metadata.Bookmark,
+		Offset:   offset + metadata.FetchedRecordsCount,
+	}
+	// unless the page was cut short, a bookmark does not guarantee that any more records follow
+	token.EstimatedTotal = int64(token.Offset)
+	if pageSize <= 0 || metadata.FetchedRecordsCount >= pageSize {
+		token.EstimatedTotal++
+	}
+	token.EstimateIsLowerBound = true
+	if estimateRemaining != nil {
+		limit := uint64(h.TotalQueryLimit)
+		remaining, err := estimateRemaining(metadata.Bookmark, limit)
+		switch {
+		case err != nil:
+			chaincodeLogger.Debugf("Could not estimate the number of records of the query: %s", err)
+		default:
+			token.EstimatedTotal = int64(token.Offset) + int64(remaining)
+			token.EstimateIsLowerBound = limit > 0 && remaining >= limit
+		}
+	}
+
+	encodedToken, err := token.encode()
+	if err != nil {
+		return err
+	}
+	metadata.Bookmark = encodedToken
+	metadataBytes, err := proto.Marshal(metadata)
+	if err != nil {
+		return errors.Wrap(err, "marshal failed")
+	}
+	payload.Metadata = metadataBytes
+	return nil
+}
+
+func rangeCountEstimator(txContext *TransactionContext, namespace, endKey string) func(string, uint64) (uint64, error) {
+	estimator, ok := txContext.TXSimulator.(ledger.StateRangeCountEstimator)
+	if !ok {
+		return nil
+	}
+	return func(bookmark string, limit uint64) (uint64, error) {
+		return estimator.EstimateStateRangeCount(namespace, bookmark, endKey, limit)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/core/chaincode/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforcePageSize(t *testing.T) {
+	testcases := []struct {
+		maxPageSize      int
+		pageSize         int32
+		expectedPageSize int32
+	}{
+		{0, 0, 0},
+		{0, 50, 50},
+		{10, 0, 10},
+		{10, 5, 5},
+		{10, 50, 10},
+	}
+	for _, tc := range testcases {
+		h := &Handler{MaxPageSize: tc.maxPageSize}
+		metadata := &pb.QueryMetadata{PageSize: tc.pageSize}
+		h.enforcePageSize(metadata)
+		require.Equal(t, tc.expectedPageSize, metadata.PageSize)
+	}
+}
+
+func TestResumeTokenEncoding(t *testing.T) {
+	queryHash := rangeQueryHash("ns", "key1", "key9")
+	require.NotEqual(t, queryHash, rangeQueryHash("ns", "key1", "key8"))
+	require.NotEqual(t, queryHash, richQueryHash("ns", "key1key9"))
+
+	encodedToken, err := (&resumeToken{Query: queryHash, Bookmark: "key5", Offset: 4}).encode()
+	require.NoError(t, err)
+	token, err := decodeResumeToken(encodedToken, queryHash)
+	require.NoError(t, err)
+	require.Equal(t, &resumeToken{Query: queryHash, Bookmark: "key5", Offset: 4}, token)
+
+	_, err = decodeResumeToken(encodedToken, rangeQueryHash("ns", "key1", "key8"))
+	require.EqualError(t, err, "the resume token was issued for a different query")
+
+	// legacy bookmarks are passed through
+	for _, bookmark := range []string{"", "key5", resumeTokenPrefix + "!!", resumeTokenPrefix + "bm90LWpzb24"} {
+		token, err := decodeResumeToken(bookmark, queryHash)
+		require.NoError(t, err)
+		require.Nil(t, token)
+	}
+}
+
+func TestPaginatedRangeQueryWithResumeTokens(t *testing.T) {
+	fakeIterator := &mock.QueryResultsIterator{}
+	fakeTxSimulator := &estimatingTxSimulator{TxSimulator: &mock.TxSimulator{}, remaining: 5}
+	fakeTxSimulator.GetStateRangeScanIteratorWithPaginationReturns(fakeIterator, nil)
+	h := &Handler{
+		TotalQueryLimit:      100,
+		MaxPageSize:          2,
+		EnableResumeTokens:   true,
+		QueryResponseBuilder: &QueryResponseGenerator{MaxResultLimit: 100},
+		UUIDGenerator:        UUIDGeneratorFunc(func() string { return "query-id" }),
+	}
+
+	query := func(bookmark string) (*pb.QueryResponseMetadata, error) {
+		fakeIterator.NextReturnsOnCall(fakeIterator.NextCallCount(), &queryresult.KV{Key: "key1"}, nil)
+		fakeIterator.NextReturnsOnCall(fakeIterator.NextCallCount()+1, &queryresult.KV{Key: "key2"}, nil)
+		fakeIterator.GetBookmarkAndCloseReturns("key3")
+		txContext := &TransactionContext{NamespaceID: "ns", TXSimulator: fakeTxSimulator}
+		request := &pb.GetStateByRange{
+			StartKey: "key1",
+			EndKey:   "key9",
+			Metadata: protoMarshal(t, &pb.QueryMetadata{PageSize: 50, Bookmark: bookmark}),
+		}
+		resp, err := h.HandleGetStateByRange(&pb.ChaincodeMessage{Payload: protoMarshal(t, request)}, txContext)
+		if err != nil {
+			return nil, err
+		}
+		queryResponse := &pb.QueryResponse{}
+		require.NoError(t, proto.Unmarshal(resp.Payload, queryResponse))
+		metadata := &pb.QueryResponseMetadata{}
+		require.NoError(t, proto.Unmarshal(queryResponse.Metadata, metadata))
+		return metadata, nil
+	}
+
+	metadata, err := query("")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), metadata.FetchedRecordsCount)
+	_, _, _, pageSize := fakeTxSimulator.GetStateRangeScanIteratorWithPaginationArgsForCall(0)
+	require.Equal(t, int32(2), pageSize)
+	token := readResumeToken(t, metadata.Bookmark)
+	require.Equal(t, &resumeToken{
+		Query:          rangeQueryHash("ns", "key1", "key9"),
+		Bookmark:       "key3",
+		Offset:         2,
+		EstimatedTotal: 7,
+	}, token)
+
+	// the next page resumes from the bookmark in the token
+	metadata, err = query(metadata.Bookmark)
+	require.NoError(t, err)
+	_, startKey, endKey, _ := fakeTxSimulator.GetStateRangeScanIteratorWithPaginationArgsForCall(1)
+	require.Equal(t, "key3", startKey)
+	require.Equal(t, "key9", endKey)
+	require.Equal(t, int32(4), readResumeToken(t, metadata.Bookmark).Offset)
+	require.Equal(t, "key3", fakeTxSimulator.startKey)
+
+	// a legacy bookmark is used as is
+	_, err = query("key5")
+	require.NoError(t, err)
+	_, startKey, _, _ = fakeTxSimulator.GetStateRangeScanIteratorWithPaginationArgsForCall(2)
+	require.Equal(t, "key5", startKey)
+
+	// a token of another query is rejected
+	otherToken, err := (&resumeToken{Query: rangeQueryHash("ns", "key0", "key9"), Bookmark: "key3"}).encode()
+	require.NoError(t, err)
+	_, err = query(otherToken)
+	require.EqualError(t, err, "the resume token was issued for a different query")
+
+	// the estimate is a lower bound if the count reaches the limit or is not available
+	fakeTxSimulator.remaining = 100
+	metadata, err = query("")
+	require.NoError(t, err)
+	token = readResumeToken(t, metadata.Bookmark)
+	require.Equal(t, int64(102), token.EstimatedTotal)
+	require.True(t, token.EstimateIsLowerBound)
+
+	fakeTxSimulator.err = errors.New("not supported")
+	metadata, err = query("")
+	require.NoError(t, err)
+	token = readResumeToken(t, metadata.Bookmark)
+	require.Equal(t, int64(3), token.EstimatedTotal)
+	require.True(t, token.EstimateIsLowerBound)
+}
+
+func TestAttachResumeTokenAtTheEnd(t *testing.T) {
+	h := &Handler{TotalQueryLimit: 100}
+	metadataBytes := protoMarshal(t, &pb.QueryResponseMetadata{FetchedRecordsCount: 1})
+	payload := &pb.QueryResponse{Metadata: metadataBytes}
+	require.NoError(t, h.attachResumeToken(payload, "query-hash", 4, 2, nil))
+	require.Equal(t, metadataBytes, payload.Metadata)
+}
+
+type estimatingTxSimulator struct {
+	*mock.TxSimulator
+	startKey  string
+	remaining uint64
+	err       error
+}
+
+func (s *estimatingTxSimulator) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	s.startKey = startKey
+	if s.remaining > limit {
+		return limit, s.err
+	}
+	return s.remaining, s.err
+}
+
+func readResumeToken(t *testing.T, bookmark string) *resumeToken {
+	require.True(t, strings.HasPrefix(bookmark, resumeTokenPrefix))
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(bookmark, resumeTokenPrefix))
+	require.NoError(t, err)
+	token := &resumeToken{}
+	require.NoError(t, json.Unmarshal(tokenBytes, token))
+	return token
+}
+
+func protoMarshal(t *testing.T, msg proto.Message) []byte {
+	b, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return b
+}
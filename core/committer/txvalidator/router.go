@@ -21,6 +21,13 @@ type Validator interface {
 	Validate(block *common.Block) error
 }
 
+// Prevalidator is implemented by the validators that can perform the checks of a block that do not depend on
+// the state ahead of time, while the preceding blocks are still being validated and committed
+type Prevalidator interface {
+	// Prevalidate starts the checks of the block that do not depend on the state, in the background
+	Prevalidate(block *common.Block)
+}
+
 //go:generate mockery -dir . -name CapabilityProvider -case underscore -output mocks
 
 // CapabilityProvider contains functions to retrieve capability information for a channel
@@ -48,3 +55,14 @@ func (v *ValidationRouter) Validate(block *common.Block) error {
 		return v.V14Validator.Validate(block)
 	}
 }
+
+// Prevalidate starts the checks of the block that do not depend on the state, if
+// the validator for the capabilities currently enabled in the channel supports it
+func (v *ValidationRouter) Prevalidate(block *common.Block) {
+	if !v.Capabilities().V2_0Validation() {
+		return
+	}
+	if p, ok := v.V20Validator.(Prevalidator); ok {
+		p.Prevalidate(block)
+	}
+}
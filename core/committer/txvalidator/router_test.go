@@ -10,6 +10,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/core/committer/txvalidator"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/mocks"
 	"github.com/stretchr/testify/assert"
@@ -64,3 +65,41 @@ func TestRouter(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestRouterPrevalidate(t *testing.T) {
+	c14 := &mocks.ApplicationCapabilities{}
+	c20 := &mocks.ApplicationCapabilities{}
+	mcp := &mocks.CapabilityProvider{}
+	c14.On("V2_0Validation").Return(false)
+	c20.On("V2_0Validation").Return(true)
+
+	pv20 := &prevalidatingValidator{Validator: &mocks.Validator{}}
+	r := &txvalidator.ValidationRouter{
+		CapabilityProvider: mcp,
+		V14Validator:       &mocks.Validator{},
+		V20Validator:       pv20,
+	}
+	block := &common.Block{Header: &common.BlockHeader{Number: 5}}
+
+	mcp.On("Capabilities").Return(c14).Once()
+	r.Prevalidate(block)
+	assert.Empty(t, pv20.prevalidated)
+
+	mcp.On("Capabilities").Return(c20).Once()
+	r.Prevalidate(block)
+	assert.Equal(t, []*common.Block{block}, pv20.prevalidated)
+
+	// a validator that does not support the prevalidation is skipped
+	r.V20Validator = &mocks.Validator{}
+	mcp.On("Capabilities").Return(c20).Once()
+	r.Prevalidate(block)
+}
+
+type prevalidatingValidator struct {
+	*mocks.Validator
+	prevalidated []*common.Block
+}
+
+func (v *prevalidatingValidator) Prevalidate(block *common.Block) {
+	v.prevalidated = append(v.prevalidated, block)
+}
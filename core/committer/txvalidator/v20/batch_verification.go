@@ -0,0 +1,236 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txvalidator
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/lows"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+// verifiedSignatures holds the endorsement signatures of the block being validated that are found valid
+// by the batch verification. The identities supplied to the validation plugins consult it before
+// verifying a signature individually. An empty set makes every signature to be verified individually
+type verifiedSignatures struct {
+	lock sync.RWMutex
+	set  map[[sha256.Size]byte]struct{}
+}
+
+func (s *verifiedSignatures) reset(set map[[sha256.Size]byte]struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.set = set
+}
+
+func (s *verifiedSignatures) contains(identity, data, signature []byte) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if len(s.set) == 0 {
+		return false
+	}
+	_, ok := s.set[signatureKey(identity, data, signature)]
+	return ok
+}
+
+func signatureKey(identity, data, signature []byte) [sha256.Size]byte {
+	h := sha256.New()
+	lenBuf := make([]byte, 8)
+	for _, b := range [][]byte{identity, data, signature} {
+		binary.BigEndian.PutUint64(lenBuf, uint64(len(b)))
+		h.Write(lenBuf)
+		h.Write(b)
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// batchVerifiedIdentity skips the verification of the signatures that are found valid by the batch verification
+type batchVerifiedIdentity struct {
+	msp.Identity
+	serialized []byte
+	verified   *verifiedSignatures
+}
+
+func (id *batchVerifiedIdentity) Verify(msg []byte, sig []byte) error {
+	if id.verified.contains(id.serialized, msg, sig) {
+		return nil
+	}
+	return id.Identity.Verify(msg, sig)
+}
+
+type endorsementSignature struct {
+	identity  []byte
+	data      []byte
+	signature []byte
+}
+
+// batchVerifyEndorsements collects the endorsement signatures of all the endorser transactions in the block and
+// verifies them via a single call to the batch verification of the crypto provider. It returns the set of the
+// signatures found valid, or nil if the crypto provider does not support the batch verification. The signatures
+// are verified over what the MSP of each endorser verifies them over, namely, the digest computed with the hash
+// family of the MSP, or the data itself for Ed25519. The low-S policy of the channel is applied to the signatures,
+// as it is by the MSPs of the channel when verifying them individually. The endorsements that cannot be verified
+// this way, for instance, the endorsements by non-X.509 identities or by identities that do not expose what they
+// sign, are left out and are verified individually by the validation plugins, as are the signatures that are not
+// found valid
+func (v *TxValidator) batchVerifyEndorsements(block *common.Block) map[[sha256.Size]byte]struct{} {
+	var batchVerifier bccsp.BatchVerifier
+	batchVerifier, ok := v.CryptoProvider.(bccsp.BatchVerifier)
+	if !ok {
+		return nil
+	}
+	if policy := v.ChannelResources.LowSPolicy(); policy != "" {
+		csp, err := lows.New(v.CryptoProvider, policy)
+		if err != nil {
+			logger.Warningf("[%s] Skipping the batch verification of block [%d]: %s", v.ChannelID, block.Header.Number, err)
+			return nil
+		}
+		batchVerifier = csp
+	}
+
+	var signatures []*endorsementSignature
+	for _, d := range block.Data.Data {
+		signatures = append(signatures, endorsementSignatures(d)...)
+	}
+	if len(signatures) == 0 {
+		return nil
+	}
+
+	endorsers := map[string]*endorser{}
+	var requests []*bccsp.VerifyRequest
+	var requestSignatures []*endorsementSignature
+	for _, s := range signatures {
+		e, ok := endorsers[string(s.identity)]
+		if !ok {
+			e = v.endorser(s.identity)
+			endorsers[string(s.identity)] = e
+		}
+		if e == nil {
+			continue
+		}
+		digest, err := e.signedData.SignedData(s.data)
+		if err != nil {
+			continue
+		}
+		requests = append(requests, &bccsp.VerifyRequest{Key: e.key, Signature: s.signature, Digest: digest})
+		requestSignatures = append(requestSignatures, s)
+	}
+
+	verified := map[[sha256.Size]byte]struct{}{}
+	for i, res := range batchVerifier.VerifyBatch(requests) {
+		if res.Err == nil && res.Valid {
+			s := requestSignatures[i]
+			verified[signatureKey(s.identity, s.data, s.signature)] = struct{}{}
+		}
+	}
+	logger.Debugf("[%s] Batch verification of block [%d] found %d out of %d endorsement signatures valid",
+		v.ChannelID, block.Header.Number, len(verified), len(requests))
+	return verified
+}
+
+// endorser is an endorser whose signatures are batch verified
+type endorser struct {
+	key bccsp.Key
+	// signedData returns what the signatures of the endorser are computed
+	// over, as its MSP verifies them
+	signedData msp.SignedDataProvider
+}
+
+// endorser returns the endorser with the given serialized identity, or nil if its signatures cannot be batch
+// verified, that is, if the MSP of the channel does not deserialize the identity, or if the identity does not
+// expose what it signs or does not carry an X.509 certificate
+func (v *TxValidator) endorser(serializedIdentity []byte) *endorser {
+	mspManager := v.ChannelResources.MSPManager()
+	if mspManager == nil {
+		return nil
+	}
+	identity, err := mspManager.DeserializeIdentity(serializedIdentity)
+	if err != nil {
+		return nil
+	}
+	signedData, ok := identity.(msp.SignedDataProvider)
+	if !ok {
+		return nil
+	}
+	key := v.importEndorserKey(serializedIdentity)
+	if key == nil {
+		return nil
+	}
+	return &endorser{key: key, signedData: signedData}
+}
+
+// importEndorserKey returns the public key in the certificate of the serialized
+// identity, or nil if the identity does not carry an X.509 certificate
+func (v *TxValidator) importEndorserKey(serializedIdentity []byte) bccsp.Key {
+	sID := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sID); err != nil {
+		return nil
+	}
+	pemBlock, _ := pem.Decode(sID.IdBytes)
+	if pemBlock == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		return nil
+	}
+	key, err := v.CryptoProvider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// endorsementSignatures returns the endorsement signatures of the transaction, if it is
+// an endorser transaction, in the form in which the validation plugins verify them
+func endorsementSignatures(envBytes []byte) []*endorsementSignature {
+	env, err := protoutil.GetEnvelopeFromBlock(envBytes)
+	if err != nil {
+		return nil
+	}
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil || payload.Header == nil {
+		return nil
+	}
+	chdr, err := protoutil.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil || common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+		return nil
+	}
+	tx, err := protoutil.UnmarshalTransaction(payload.Data)
+	if err != nil {
+		return nil
+	}
+	var signatures []*endorsementSignature
+	for _, action := range tx.Actions {
+		cap, err := protoutil.UnmarshalChaincodeActionPayload(action.Payload)
+		if err != nil || cap.Action == nil {
+			continue
+		}
+		for _, endorsement := range cap.Action.Endorsements {
+			data := make([]byte, 0, len(cap.Action.ProposalResponsePayload)+len(endorsement.Endorser))
+			data = append(data, cap.Action.ProposalResponsePayload...)
+			data = append(data, endorsement.Endorser...)
+			signatures = append(signatures, &endorsementSignature{
+				identity:  endorsement.Endorser,
+				data:      data,
+				signature: endorsement.Signature,
+			})
+		}
+	}
+	return signatures
+}
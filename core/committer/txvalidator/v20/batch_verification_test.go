@@ -0,0 +1,273 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txvalidator
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/utils"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	mocktxvalidator "github.com/hyperledger/fabric/core/mocks/txvalidator"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/msp/mgmt"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchVerifyEndorsements(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	tValidator := &TxValidator{
+		CryptoProvider:   cryptoProvider,
+		ChannelResources: &mocktxvalidator.Support{MSPManagerVal: mgmt.GetManagerForChain("testchannelid")},
+	}
+
+	rwsb := rwsetutil.NewRWSetBuilder()
+	rwsb.AddToWriteSet("ns1", "key1", []byte("value1"))
+	simRes, err := rwsb.GetTxSimulationResults()
+	assert.NoError(t, err)
+	pubSimulationResBytes, err := simRes.GetPubSimulationBytes()
+	assert.NoError(t, err)
+	block := testutil.ConstructBlock(t, 1, []byte("prev-hash"), [][]byte{pubSimulationResBytes, pubSimulationResBytes}, true)
+
+	signatures := append(endorsementSignatures(block.Data.Data[0]), endorsementSignatures(block.Data.Data[1])...)
+	assert.Len(t, signatures, 2)
+
+	verified := tValidator.batchVerifyEndorsements(block)
+	assert.Len(t, verified, 2)
+	for _, s := range signatures {
+		assert.Contains(t, verified, signatureKey(s.identity, s.data, s.signature))
+	}
+
+	// a tampered endorsement signature is left to the individual verification
+	env, err := protoutil.GetEnvelopeFromBlock(block.Data.Data[1])
+	assert.NoError(t, err)
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	assert.NoError(t, err)
+	tx, err := protoutil.UnmarshalTransaction(payload.Data)
+	assert.NoError(t, err)
+	cap, err := protoutil.UnmarshalChaincodeActionPayload(tx.Actions[0].Payload)
+	assert.NoError(t, err)
+	cap.Action.Endorsements[0].Signature = []byte("bad-signature")
+	tx.Actions[0].Payload = protoutil.MarshalOrPanic(cap)
+	payload.Data = protoutil.MarshalOrPanic(tx)
+	env.Payload = protoutil.MarshalOrPanic(payload)
+	block.Data.Data[1] = protoutil.MarshalOrPanic(env)
+
+	verified = tValidator.batchVerifyEndorsements(block)
+	assert.Len(t, verified, 1)
+	assert.Contains(t, verified, signatureKey(signatures[0].identity, signatures[0].data, signatures[0].signature))
+
+	// non-endorser transactions carry no endorsement
+	configBlock := &common.Block{
+		Header: &common.BlockHeader{Number: 2},
+		Data: &common.BlockData{Data: [][]byte{protoutil.MarshalOrPanic(&common.Envelope{
+			Payload: protoutil.MarshalOrPanic(&common.Payload{
+				Header: &common.Header{
+					ChannelHeader: protoutil.MarshalOrPanic(&common.ChannelHeader{Type: int32(common.HeaderType_CONFIG)}),
+				},
+			}),
+		})}},
+	}
+	assert.Empty(t, endorsementSignatures(configBlock.Data.Data[0]))
+	assert.Nil(t, tValidator.batchVerifyEndorsements(configBlock))
+}
+
+func TestBatchVerifyEndorsementsLowSPolicy(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	support := &mocktxvalidator.Support{MSPManagerVal: mgmt.GetManagerForChain("testchannelid")}
+	tValidator := &TxValidator{CryptoProvider: cryptoProvider, ChannelResources: support}
+
+	rwsb := rwsetutil.NewRWSetBuilder()
+	rwsb.AddToWriteSet("ns1", "key1", []byte("value1"))
+	simRes, err := rwsb.GetTxSimulationResults()
+	assert.NoError(t, err)
+	pubSimulationResBytes, err := simRes.GetPubSimulationBytes()
+	assert.NoError(t, err)
+	block := testutil.ConstructBlock(t, 1, []byte("prev-hash"), [][]byte{pubSimulationResBytes}, true)
+
+	// the endorsement signature is turned into its high-S counterpart
+	env, err := protoutil.GetEnvelopeFromBlock(block.Data.Data[0])
+	assert.NoError(t, err)
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	assert.NoError(t, err)
+	tx, err := protoutil.UnmarshalTransaction(payload.Data)
+	assert.NoError(t, err)
+	cap, err := protoutil.UnmarshalChaincodeActionPayload(tx.Actions[0].Payload)
+	assert.NoError(t, err)
+	r, s, err := utils.UnmarshalECDSASignature(cap.Action.Endorsements[0].Signature)
+	assert.NoError(t, err)
+	cap.Action.Endorsements[0].Signature, err = utils.MarshalECDSASignature(r, new(big.Int).Sub(elliptic.P256().Params().N, s))
+	assert.NoError(t, err)
+	tx.Actions[0].Payload = protoutil.MarshalOrPanic(cap)
+	payload.Data = protoutil.MarshalOrPanic(tx)
+	env.Payload = protoutil.MarshalOrPanic(payload)
+	block.Data.Data[0] = protoutil.MarshalOrPanic(env)
+	signatures := endorsementSignatures(block.Data.Data[0])
+	assert.Len(t, signatures, 1)
+	highS := signatureKey(signatures[0].identity, signatures[0].data, signatures[0].signature)
+
+	// the crypto provider refuses the high-S signatures, unless the channel sets a low-S policy
+	assert.Empty(t, tValidator.batchVerifyEndorsements(block))
+	support.LowSVal = utils.LowSNormalize
+	assert.Equal(t, map[[32]byte]struct{}{highS: {}}, tValidator.batchVerifyEndorsements(block))
+	support.LowSVal = utils.LowSReject
+	assert.Empty(t, tValidator.batchVerifyEndorsements(block))
+	support.LowSVal = "ignore"
+	assert.Nil(t, tValidator.batchVerifyEndorsements(block))
+}
+
+func TestBatchVerifyED25519Endorsements(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	tValidator := &TxValidator{CryptoProvider: cryptoProvider, ChannelResources: &mocktxvalidator.Support{}}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peer0"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	assert.NoError(t, err)
+	identity := protoutil.MarshalOrPanic(&mspprotos.SerializedIdentity{
+		Mspid:   "Org1MSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+	})
+
+	key := tValidator.importEndorserKey(identity)
+	assert.NotNil(t, key)
+
+	// the Ed25519 signatures are verified over the data rather than its digest
+	data := []byte("proposal response payload")
+	results := cryptoProvider.(bccsp.BatchVerifier).VerifyBatch([]*bccsp.VerifyRequest{
+		{Key: key, Signature: ed25519.Sign(priv, data), Digest: data},
+	})
+	assert.NoError(t, results[0].Err)
+	assert.True(t, results[0].Valid)
+}
+
+func TestBatchVerifyEndorsementsSHA3MSP(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	// the MSP of the channel computes the digests with SHA3, with the same keys as the default signer
+	conf, err := msp.GetLocalMspConfig(configtest.GetDevMspDir(), nil, "SampleOrg")
+	assert.NoError(t, err)
+	fabricConf := &mspprotos.FabricMSPConfig{}
+	assert.NoError(t, proto.Unmarshal(conf.Config, fabricConf))
+	fabricConf.CryptoConfig = &mspprotos.FabricCryptoConfig{
+		SignatureHashFamily:            bccsp.SHA3,
+		IdentityIdentifierHashFunction: bccsp.SHA256,
+	}
+	conf.Config = protoutil.MarshalOrPanic(fabricConf)
+	sha3MSP, err := msp.New(&msp.BCCSPNewOpts{NewBaseOpts: msp.NewBaseOpts{Version: msp.MSPv1_4_3}}, factory.GetDefault())
+	assert.NoError(t, err)
+	assert.NoError(t, sha3MSP.Setup(conf))
+	mspManager := msp.NewMSPManager()
+	assert.NoError(t, mspManager.Setup([]msp.MSP{sha3MSP}))
+	tValidator := &TxValidator{
+		CryptoProvider:   cryptoProvider,
+		ChannelResources: &mocktxvalidator.Support{MSPManagerVal: mspManager},
+	}
+
+	rwsb := rwsetutil.NewRWSetBuilder()
+	rwsb.AddToWriteSet("ns1", "key1", []byte("value1"))
+	simRes, err := rwsb.GetTxSimulationResults()
+	assert.NoError(t, err)
+	pubSimulationResBytes, err := simRes.GetPubSimulationBytes()
+	assert.NoError(t, err)
+
+	// the endorsement signed over the SHA-256 digest is refused by the SHA3 MSP, hence by the batch verification
+	sha2Block := testutil.ConstructBlock(t, 1, []byte("prev-hash"), [][]byte{pubSimulationResBytes}, true)
+	sha2Signatures := endorsementSignatures(sha2Block.Data.Data[0])
+	assert.Len(t, sha2Signatures, 1)
+	id, err := mspManager.DeserializeIdentity(sha2Signatures[0].identity)
+	assert.NoError(t, err)
+	assert.Error(t, id.Verify(sha2Signatures[0].data, sha2Signatures[0].signature))
+	assert.Empty(t, tValidator.batchVerifyEndorsements(sha2Block))
+
+	// the endorsement signed over the SHA3 digest is accepted by both
+	sha3Signer, err := sha3MSP.GetDefaultSigningIdentity()
+	assert.NoError(t, err)
+	env, _, err := testutil.ConstructSignedTxEnv("testchannelid", &peer.ChaincodeID{Name: "foo", Version: "v1"}, &peer.Response{Status: 200},
+		pubSimulationResBytes, "", nil, nil, sha3Signer, common.HeaderType_ENDORSER_TRANSACTION)
+	assert.NoError(t, err)
+	sha3Block := testutil.NewBlock([]*common.Envelope{env}, 2, []byte("prev-hash"))
+	sha3Signatures := endorsementSignatures(sha3Block.Data.Data[0])
+	assert.Len(t, sha3Signatures, 1)
+	assert.NoError(t, id.Verify(sha3Signatures[0].data, sha3Signatures[0].signature))
+	assert.Equal(t,
+		map[[32]byte]struct{}{signatureKey(sha3Signatures[0].identity, sha3Signatures[0].data, sha3Signatures[0].signature): {}},
+		tValidator.batchVerifyEndorsements(sha3Block),
+	)
+}
+
+func TestBatchVerifyEndorsementsWithoutMSP(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	tValidator := &TxValidator{CryptoProvider: cryptoProvider, ChannelResources: &mocktxvalidator.Support{}}
+
+	rwsb := rwsetutil.NewRWSetBuilder()
+	rwsb.AddToWriteSet("ns1", "key1", []byte("value1"))
+	simRes, err := rwsb.GetTxSimulationResults()
+	assert.NoError(t, err)
+	pubSimulationResBytes, err := simRes.GetPubSimulationBytes()
+	assert.NoError(t, err)
+	block := testutil.ConstructBlock(t, 1, []byte("prev-hash"), [][]byte{pubSimulationResBytes}, true)
+
+	// the endorsers that the channel cannot deserialize are left to the individual verification
+	assert.Empty(t, tValidator.batchVerifyEndorsements(block))
+}
+
+func TestBatchVerifiedIdentity(t *testing.T) {
+	verified := &verifiedSignatures{}
+	id := &batchVerifiedIdentity{
+		Identity:   &failingIdentity{},
+		serialized: []byte("identity"),
+		verified:   verified,
+	}
+	assert.EqualError(t, id.Verify([]byte("msg"), []byte("sig")), "signature verification failed")
+
+	verified.reset(map[[32]byte]struct{}{
+		signatureKey([]byte("identity"), []byte("msg"), []byte("sig")): {},
+	})
+	assert.NoError(t, id.Verify([]byte("msg"), []byte("sig")))
+	assert.EqualError(t, id.Verify([]byte("msg"), []byte("other-sig")), "signature verification failed")
+
+	verified.reset(nil)
+	assert.EqualError(t, id.Verify([]byte("msg"), []byte("sig")), "signature verification failed")
+}
+
+type failingIdentity struct {
+	msp.Identity
+}
+
+func (id *failingIdentity) Verify(msg []byte, sig []byte) error {
+	return errors.New("signature verification failed")
+}
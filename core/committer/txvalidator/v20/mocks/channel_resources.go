@@ -9,6 +9,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	msp "github.com/hyperledger/fabric/msp"
+
+	utils "github.com/hyperledger/fabric/bccsp/utils"
 )
 
 // ChannelResources is an autogenerated mock type for the ChannelResources type
@@ -46,6 +48,20 @@ func (_m *ChannelResources) Capabilities() channelconfig.ApplicationCapabilities
 	return r0
 }
 
+// LowSPolicy provides a mock function with given fields:
+func (_m *ChannelResources) LowSPolicy() utils.LowSPolicy {
+	ret := _m.Called()
+
+	var r0 utils.LowSPolicy
+	if rf, ok := ret.Get(0).(func() utils.LowSPolicy); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(utils.LowSPolicy)
+	}
+
+	return r0
+}
+
 // GetMSPIDs provides a mock function with given fields:
 func (_m *ChannelResources) GetMSPIDs() []string {
 	ret := _m.Called()
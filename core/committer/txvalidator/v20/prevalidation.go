@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txvalidator
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/core/common/validation"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+// blockPrevalidation holds the results of the checks of a block that do not depend on the state, i.e., the
+// well-formedness of the transactions and the signatures of their creators. The results are valid only as
+// long as no config transaction is applied after the prevalidation started, as a config transaction may
+// change the MSPs against which the creators are validated
+type blockPrevalidation struct {
+	dataHash  []byte
+	configSeq uint64
+	done      chan struct{}
+	results   []*txPrevalidationResult
+}
+
+type txPrevalidationResult struct {
+	payload        *common.Payload
+	validationCode peer.TxValidationCode
+}
+
+// Prevalidate starts, in the background, the checks of the transactions in the block that do not depend on
+// the state. This is intended to be invoked for a block while the preceding blocks are still being validated
+// and committed so that the signature verification of a block overlaps with the commit of the preceding block.
+// The function Validate uses these results, if available, instead of performing these checks again
+func (v *TxValidator) Prevalidate(block *common.Block) {
+	blockNum := block.Header.Number
+	v.prevalidationsLock.Lock()
+	defer v.prevalidationsLock.Unlock()
+	if v.prevalidations == nil {
+		v.prevalidations = map[uint64]*blockPrevalidation{}
+	}
+	if _, ok := v.prevalidations[blockNum]; ok {
+		return
+	}
+	p := &blockPrevalidation{
+		dataHash:  block.Header.DataHash,
+		configSeq: atomic.LoadUint64(&v.configSeq),
+		done:      make(chan struct{}),
+		results:   make([]*txPrevalidationResult, len(block.Data.Data)),
+	}
+	v.prevalidations[blockNum] = p
+	logger.Debugf("[%s] Prevalidating block [%d]", v.ChannelID, blockNum)
+
+	go func() {
+		defer close(p.done)
+		var wg sync.WaitGroup
+		for tIdx, d := range block.Data.Data {
+			// share the bound on the concurrent validation workers with the validation of the blocks
+			v.Semaphore.Acquire(context.Background())
+			wg.Add(1)
+			go func(index int, data []byte) {
+				defer wg.Done()
+				defer v.Semaphore.Release()
+				p.results[index] = v.prevalidateTx(data)
+			}(tIdx, d)
+		}
+		wg.Wait()
+	}()
+}
+
+// prevalidateTx returns nil if the checks cannot be performed ahead of time, in which
+// case the transaction is checked as usual during the validation of the block
+func (v *TxValidator) prevalidateTx(d []byte) *txPrevalidationResult {
+	if d == nil {
+		return nil
+	}
+	env, err := protoutil.GetEnvelopeFromBlock(d)
+	if err != nil || env == nil {
+		return nil
+	}
+	payload, validationCode := validation.ValidateTransaction(env, v.CryptoProvider)
+	return &txPrevalidationResult{
+		payload:        payload,
+		validationCode: validationCode,
+	}
+}
+
+// takePrevalidation removes the prevalidation of the given block, and of any earlier block, and
+// returns the results after waiting for the prevalidation to finish. It returns nil if the block
+// was not prevalidated or if the results are stale
+func (v *TxValidator) takePrevalidation(block *common.Block) []*txPrevalidationResult {
+	blockNum := block.Header.Number
+	v.prevalidationsLock.Lock()
+	p := v.prevalidations[blockNum]
+	for n := range v.prevalidations {
+		if n <= blockNum {
+			delete(v.prevalidations, n)
+		}
+	}
+	v.prevalidationsLock.Unlock()
+
+	if p == nil {
+		return nil
+	}
+	<-p.done
+	if !bytes.Equal(p.dataHash, block.Header.DataHash) {
+		logger.Warningf("[%s] Discarding the prevalidation of block [%d] as the block has changed", v.ChannelID, blockNum)
+		return nil
+	}
+	if p.configSeq != atomic.LoadUint64(&v.configSeq) {
+		logger.Debugf("[%s] Discarding the prevalidation of block [%d] as a config transaction has been applied since", v.ChannelID, blockNum)
+		return nil
+	}
+	return p.results
+}
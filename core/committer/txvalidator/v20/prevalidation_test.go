@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txvalidator
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/semaphore"
+	tmocks "github.com/hyperledger/fabric/core/committer/txvalidator/mocks"
+	"github.com/hyperledger/fabric/core/committer/txvalidator/v20/mocks"
+	ledger2 "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	mocktxvalidator "github.com/hyperledger/fabric/core/mocks/txvalidator"
+	"github.com/hyperledger/fabric/internal/pkg/txflags"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPrevalidation(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	mockLedger := &mocks.LedgerResources{}
+	mockLedger.On("GetTransactionByID", mock.Anything).Return(nil, ledger2.NotFoundInIndexErr("Alone, alone, all, all alone"))
+	tValidator := &TxValidator{
+		ChannelID:        "",
+		Semaphore:        semaphore.New(10),
+		ChannelResources: &mocktxvalidator.Support{ACVal: &tmocks.ApplicationCapabilities{}},
+		Dispatcher:       &mockDispatcher{},
+		LedgerResources:  mockLedger,
+		CryptoProvider:   cryptoProvider,
+	}
+
+	newBlock := func(blockNum uint64) *common.Block {
+		rwsb := rwsetutil.NewRWSetBuilder()
+		rwsb.AddToWriteSet("ns1", "key1", []byte("value1"))
+		simRes, err := rwsb.GetTxSimulationResults()
+		assert.NoError(t, err)
+		pubSimulationResBytes, err := simRes.GetPubSimulationBytes()
+		assert.NoError(t, err)
+		return testutil.ConstructBlock(t, blockNum, []byte("prev-hash"), [][]byte{pubSimulationResBytes, pubSimulationResBytes}, true)
+	}
+
+	t.Run("prevalidated block is validated", func(t *testing.T) {
+		block := newBlock(1)
+		tValidator.Prevalidate(block)
+		tValidator.Prevalidate(block)
+		assert.Len(t, tValidator.prevalidations, 1)
+
+		assert.NoError(t, tValidator.Validate(block))
+		txsfltr := txflags.ValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+		assert.True(t, txsfltr.IsSetTo(0, peer.TxValidationCode_VALID))
+		assert.True(t, txsfltr.IsSetTo(1, peer.TxValidationCode_VALID))
+		assert.Empty(t, tValidator.prevalidations)
+	})
+
+	t.Run("results of the prevalidation are used", func(t *testing.T) {
+		block := newBlock(2)
+		tValidator.Prevalidate(block)
+		results := tValidator.takePrevalidation(block)
+		assert.Len(t, results, 2)
+		for _, res := range results {
+			assert.Equal(t, peer.TxValidationCode_VALID, res.validationCode)
+			assert.NotNil(t, res.payload)
+		}
+
+		// the validation relies on the prevalidation for the creator signature
+		block = newBlock(3)
+		env, err := protoutil.GetEnvelopeFromBlock(block.Data.Data[0])
+		assert.NoError(t, err)
+		tValidator.Prevalidate(block)
+		<-tValidator.prevalidations[3].done
+		env.Signature = []byte("bad-signature")
+		block.Data.Data[0] = protoutil.MarshalOrPanic(env)
+		assert.NoError(t, tValidator.Validate(block))
+		txsfltr := txflags.ValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+		assert.True(t, txsfltr.IsSetTo(0, peer.TxValidationCode_VALID))
+
+		// without the prevalidation, the bad signature is detected
+		block.Metadata = nil
+		assert.NoError(t, tValidator.Validate(block))
+		txsfltr = txflags.ValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+		assert.True(t, txsfltr.IsSetTo(0, peer.TxValidationCode_BAD_CREATOR_SIGNATURE))
+	})
+
+	t.Run("stale prevalidation is discarded", func(t *testing.T) {
+		block := newBlock(4)
+		tValidator.Prevalidate(block)
+		tValidator.configSeq++
+		assert.Nil(t, tValidator.takePrevalidation(block))
+
+		block = newBlock(5)
+		tValidator.Prevalidate(block)
+		block.Header.DataHash = []byte("another-data-hash")
+		assert.Nil(t, tValidator.takePrevalidation(block))
+
+		assert.Nil(t, tValidator.takePrevalidation(newBlock(6)))
+	})
+
+	t.Run("prevalidations of the earlier blocks are removed", func(t *testing.T) {
+		block := newBlock(8)
+		tValidator.Prevalidate(newBlock(7))
+		tValidator.Prevalidate(block)
+		tValidator.Prevalidate(newBlock(10))
+		assert.NotNil(t, tValidator.takePrevalidation(block))
+		assert.Len(t, tValidator.prevalidations, 1)
+		assert.Contains(t, tValidator.prevalidations, uint64(10))
+	})
+}
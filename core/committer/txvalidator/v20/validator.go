@@ -9,6 +9,8 @@ package txvalidator
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -16,6 +18,7 @@ import (
 	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/configtx"
 	commonerrors "github.com/hyperledger/fabric/common/errors"
@@ -55,6 +58,10 @@ type ChannelResources interface {
 
 	// Capabilities defines the capabilities for the application portion of this channel
 	Capabilities() channelconfig.ApplicationCapabilities
+
+	// LowSPolicy returns the policy applied to the high-S ECDSA signatures verified
+	// in this channel, or "" when they are refused
+	LowSPolicy() utils.LowSPolicy
 }
 
 // LedgerResources provides access to ledger artefacts or
@@ -112,14 +119,23 @@ type TxValidator struct {
 	LedgerResources  LedgerResources
 	Dispatcher       Dispatcher
 	CryptoProvider   bccsp.BCCSP
+
+	// verifiedSignatures holds the endorsement signatures of the block being validated that are found
+	// valid by the batch verification. It is shared with the deserializer of the validation plugins
+	verifiedSignatures *verifiedSignatures
+	// configSeq is incremented whenever a config transaction is applied
+	configSeq          uint64
+	prevalidationsLock sync.Mutex
+	prevalidations     map[uint64]*blockPrevalidation
 }
 
 var logger = flogging.MustGetLogger("committer.txvalidator")
 
 type blockValidationRequest struct {
-	block *common.Block
-	d     []byte
-	tIdx  int
+	block        *common.Block
+	d            []byte
+	tIdx         int
+	prevalidated *txPrevalidationResult
 }
 
 type blockValidationResult struct {
@@ -141,15 +157,17 @@ func NewTxValidator(
 	channelPolicyManagerGetter policies.ChannelPolicyManagerGetter,
 	cryptoProvider bccsp.BCCSP,
 ) *TxValidator {
+	verified := &verifiedSignatures{}
 	// Encapsulates interface implementation
-	pluginValidator := plugindispatcher.NewPluginValidator(pm, ler, &dynamicDeserializer{cr: cr}, &dynamicCapabilities{cr: cr}, channelPolicyManagerGetter, cor)
+	pluginValidator := plugindispatcher.NewPluginValidator(pm, ler, &dynamicDeserializer{cr: cr, verified: verified}, &dynamicCapabilities{cr: cr}, channelPolicyManagerGetter, cor)
 	return &TxValidator{
-		ChannelID:        channelID,
-		Semaphore:        sem,
-		ChannelResources: cr,
-		LedgerResources:  ler,
-		Dispatcher:       plugindispatcher.New(channelID, cr, ler, lcr, pluginValidator),
-		CryptoProvider:   cryptoProvider,
+		ChannelID:          channelID,
+		Semaphore:          sem,
+		ChannelResources:   cr,
+		LedgerResources:    ler,
+		Dispatcher:         plugindispatcher.New(channelID, cr, ler, lcr, pluginValidator),
+		CryptoProvider:     cryptoProvider,
+		verifiedSignatures: verified,
 	}
 }
 
@@ -189,6 +207,11 @@ func (v *TxValidator) Validate(block *common.Block) error {
 	txsfltr := txflags.New(len(block.Data.Data))
 	// array of txids
 	txidArray := make([]string, len(block.Data.Data))
+	prevalidated := v.takePrevalidation(block)
+	if v.verifiedSignatures != nil {
+		v.verifiedSignatures.reset(v.batchVerifyEndorsements(block))
+		defer v.verifiedSignatures.reset(nil)
+	}
 
 	results := make(chan *blockValidationResult)
 	go func() {
@@ -196,15 +219,19 @@ func (v *TxValidator) Validate(block *common.Block) error {
 			// ensure that we don't have too many concurrent validation workers
 			v.Semaphore.Acquire(context.Background())
 
-			go func(index int, data []byte) {
+			req := &blockValidationRequest{
+				d:     d,
+				block: block,
+				tIdx:  tIdx,
+			}
+			if prevalidated != nil {
+				req.prevalidated = prevalidated[tIdx]
+			}
+			go func() {
 				defer v.Semaphore.Release()
 
-				v.validateTx(&blockValidationRequest{
-					d:     data,
-					block: block,
-					tIdx:  index,
-				}, results)
-			}(tIdx, d)
+				v.validateTx(req, results)
+			}()
 		}
 	}()
 
@@ -327,7 +354,12 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 		var err error
 		var txResult peer.TxValidationCode
 
-		if payload, txResult = validation.ValidateTransaction(env, v.CryptoProvider); txResult != peer.TxValidationCode_VALID {
+		if req.prevalidated != nil {
+			payload, txResult = req.prevalidated.payload, req.prevalidated.validationCode
+		} else {
+			payload, txResult = validation.ValidateTransaction(env, v.CryptoProvider)
+		}
+		if txResult != peer.TxValidationCode_VALID {
 			logger.Errorf("Invalid transaction with index %d", tIdx)
 			results <- &blockValidationResult{
 				tIdx:           tIdx,
@@ -416,6 +448,8 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 				}
 				return
 			}
+			// invalidates the prevalidation of the subsequent blocks
+			atomic.AddUint64(&v.configSeq, 1)
 			logger.Debugf("config transaction received for chain %s", channel)
 		} else {
 			logger.Warningf("Unknown transaction type [%s] in block number [%d] transaction index [%d]",
@@ -494,11 +528,20 @@ func (v *TxValidator) generateCCKey(ccName, chainID string) string {
 }
 
 type dynamicDeserializer struct {
-	cr ChannelResources
+	cr       ChannelResources
+	verified *verifiedSignatures
 }
 
 func (ds *dynamicDeserializer) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
-	return ds.cr.MSPManager().DeserializeIdentity(serializedIdentity)
+	identity, err := ds.cr.MSPManager().DeserializeIdentity(serializedIdentity)
+	if err != nil || ds.verified == nil {
+		return identity, err
+	}
+	return &batchVerifiedIdentity{
+		Identity:   identity,
+		serialized: serializedIdentity,
+		verified:   ds.verified,
+	}, nil
 }
 
 func (ds *dynamicDeserializer) IsWellFormed(identity *mspprotos.SerializedIdentity) error {
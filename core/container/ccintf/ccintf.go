@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package ccintf
 
 import (
+	"strings"
+
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 
 	pb "github.com/hyperledger/fabric-protos-go/peer"
@@ -38,3 +40,69 @@ type ChaincodeServerInfo struct {
 	Address      string
 	ClientConfig comm.ClientConfig
 }
+
+// SeccompUnconfined is the seccomp profile that disables seccomp
+const SeccompUnconfined = "unconfined"
+
+// ResourceLimits are the limits on the resources of the runtime of a chaincode
+// and the controls isolating it, which are enforced when the chaincode is launched.
+// The zero value of a field applies no limit or control.
+type ResourceLimits struct {
+	// CPUs is the number of CPUs the chaincode may use, which may be fractional
+	CPUs float64 `json:"cpus,omitempty"`
+	// Memory is the maximum memory of the chaincode in bytes
+	Memory int64 `json:"memory,omitempty"`
+	// Pids is the maximum number of processes and threads of the chaincode
+	Pids int64 `json:"pids,omitempty"`
+	// ReadOnlyRootfs mounts the root file system of the chaincode read-only
+	ReadOnlyRootfs bool `json:"read_only_rootfs,omitempty"`
+	// SeccompProfile is SeccompUnconfined to disable seccomp or the path to a
+	// seccomp profile, instead of the default profile of the container runtime
+	SeccompProfile string `json:"seccomp_profile,omitempty"`
+}
+
+// IsZero returns whether the limits apply no limit or control
+func (rl ResourceLimits) IsZero() bool {
+	return rl == ResourceLimits{}
+}
+
+// ResourceLimitsConfig holds the resource limits of chaincode
+type ResourceLimitsConfig struct {
+	// Default are the limits of all chaincode
+	Default ResourceLimits
+	// Labels are the limits of the chaincode packages with the given labels,
+	// which take precedence over the default limits for the fields they set
+	Labels map[string]ResourceLimits
+}
+
+// ForChaincode returns the limits of the chaincode with the given package ID
+func (c *ResourceLimitsConfig) ForChaincode(ccid string) ResourceLimits {
+	if c == nil {
+		return ResourceLimits{}
+	}
+	limits := c.Default
+	label := ccid
+	if i := strings.Index(ccid, ":"); i >= 0 {
+		label = ccid[:i]
+	}
+	override, ok := c.Labels[label]
+	if !ok {
+		return limits
+	}
+	if override.CPUs != 0 {
+		limits.CPUs = override.CPUs
+	}
+	if override.Memory != 0 {
+		limits.Memory = override.Memory
+	}
+	if override.Pids != 0 {
+		limits.Pids = override.Pids
+	}
+	if override.ReadOnlyRootfs {
+		limits.ReadOnlyRootfs = true
+	}
+	if override.SeccompProfile != "" {
+		limits.SeccompProfile = override.SeccompProfile
+	}
+	return limits
+}
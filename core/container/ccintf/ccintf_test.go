@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccintf_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLimitsForChaincode(t *testing.T) {
+	var config *ccintf.ResourceLimitsConfig
+	assert.True(t, config.ForChaincode("cc:hash").IsZero())
+
+	config = &ccintf.ResourceLimitsConfig{
+		Default: ccintf.ResourceLimits{CPUs: 1, Memory: 512, SeccompProfile: "/profile.json"},
+		Labels: map[string]ccintf.ResourceLimits{
+			"big":  {CPUs: 4, Pids: 200},
+			"free": {ReadOnlyRootfs: true, SeccompProfile: ccintf.SeccompUnconfined},
+		},
+	}
+	assert.Equal(t, config.Default, config.ForChaincode("cc:hash"))
+	assert.Equal(t, ccintf.ResourceLimits{CPUs: 4, Memory: 512, Pids: 200, SeccompProfile: "/profile.json"}, config.ForChaincode("big:hash"))
+	assert.Equal(t, ccintf.ResourceLimits{CPUs: 1, Memory: 512, ReadOnlyRootfs: true, SeccompProfile: "unconfined"}, config.ForChaincode("free:hash"))
+}
@@ -14,8 +14,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -111,6 +114,8 @@ type DockerVM struct {
 	PlatformBuilder PlatformBuilder
 	LoggingEnv      []string
 	MSPID           string
+	// ResourceLimits are applied to the host config of the containers of chaincode
+	ResourceLimits *ccintf.ResourceLimitsConfig
 }
 
 // HealthCheck checks if the DockerVM is able to communicate with the Docker
@@ -122,19 +127,24 @@ func (vm *DockerVM) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-func (vm *DockerVM) createContainer(imageID, containerID string, args, env []string) error {
+func (vm *DockerVM) createContainer(imageID, containerID string, args, env []string, hostConfig *docker.HostConfig) error {
 	logger := dockerLogger.With("imageID", imageID, "containerID", containerID)
 	logger.Debugw("create container")
+	config := &docker.Config{
+		Cmd:          args,
+		Image:        imageID,
+		Env:          env,
+		AttachStdout: vm.AttachStdOut,
+		AttachStderr: vm.AttachStdOut,
+	}
+	if hostConfig != nil && hostConfig.ReadonlyRootfs {
+		// the TLS files can only be uploaded to a volume of a container with a read-only root file system
+		config.Volumes = map[string]struct{}{TLSDir: {}}
+	}
 	_, err := vm.Client.CreateContainer(docker.CreateContainerOptions{
-		Name: containerID,
-		Config: &docker.Config{
-			Cmd:          args,
-			Image:        imageID,
-			Env:          env,
-			AttachStdout: vm.AttachStdOut,
-			AttachStderr: vm.AttachStdOut,
-		},
-		HostConfig: vm.HostConfig,
+		Name:       containerID,
+		Config:     config,
+		HostConfig: hostConfig,
 	})
 	if err != nil {
 		return err
@@ -241,6 +251,7 @@ func (vm *DockerVM) GetArgs(ccType string, peerAddress string) ([]string, error)
 
 const (
 	// Mutual TLS auth client key and cert paths in the chaincode container
+	TLSDir                string = "/etc/hyperledger/fabric"
 	TLSClientKeyPath      string = "/etc/hyperledger/fabric/client.key"
 	TLSClientCertPath     string = "/etc/hyperledger/fabric/client.crt"
 	TLSClientKeyFile      string = "/etc/hyperledger/fabric/client_pem.key"
@@ -296,7 +307,12 @@ func (vm *DockerVM) Start(ccid string, ccType string, peerConnection *ccintf.Pee
 	env := vm.GetEnv(ccid, peerConnection.TLSConfig)
 	dockerLogger.Debugf("start container with env:\n\t%s", strings.Join(env, "\n\t"))
 
-	err = vm.createContainer(imageName, containerName, args, env)
+	hostConfig, err := vm.hostConfig(ccid)
+	if err != nil {
+		return errors.WithMessage(err, "could not apply resource limits")
+	}
+
+	err = vm.createContainer(imageName, containerName, args, env, hostConfig)
 	if err != nil {
 		logger.Errorf("create container failed: %s", err)
 		return err
@@ -317,13 +333,23 @@ func (vm *DockerVM) Start(ccid string, ccType string, peerConnection *ccintf.Pee
 		tw := tar.NewWriter(gw)
 
 		// Note, we goofily base64 encode 2 of the TLS artifacts but not the other for strange historical reasons
-		err = addFiles(tw, map[string][]byte{
+		files := map[string][]byte{
 			TLSClientKeyPath:      []byte(base64.StdEncoding.EncodeToString(peerConnection.TLSConfig.ClientKey)),
 			TLSClientCertPath:     []byte(base64.StdEncoding.EncodeToString(peerConnection.TLSConfig.ClientCert)),
 			TLSClientKeyFile:      peerConnection.TLSConfig.ClientKey,
 			TLSClientCertFile:     peerConnection.TLSConfig.ClientCert,
 			TLSClientRootCertFile: peerConnection.TLSConfig.RootCert,
-		})
+		}
+		uploadPath := "/"
+		if hostConfig != nil && hostConfig.ReadonlyRootfs {
+			uploadPath = TLSDir
+			volumeFiles := map[string][]byte{}
+			for name, payload := range files {
+				volumeFiles[path.Base(name)] = payload
+			}
+			files = volumeFiles
+		}
+		err = addFiles(tw, files)
 		if err != nil {
 			return fmt.Errorf("error writing files to upload to Docker instance into a temporary tar blob: %s", err)
 		}
@@ -337,7 +363,7 @@ func (vm *DockerVM) Start(ccid string, ccType string, peerConnection *ccintf.Pee
 
 		err := vm.Client.UploadToContainer(containerName, docker.UploadToContainerOptions{
 			InputStream:          bytes.NewReader(payload.Bytes()),
-			Path:                 "/",
+			Path:                 uploadPath,
 			NoOverwriteDirNonDir: false,
 		})
 		if err != nil {
@@ -356,6 +382,53 @@ func (vm *DockerVM) Start(ccid string, ccType string, peerConnection *ccintf.Pee
 	return nil
 }
 
+// cpuPeriod is the CFS scheduler period, in microseconds, of the CPU quota of the containers with a limit on CPUs
+const cpuPeriod = 100000
+
+// hostConfig returns the host config of the container of the given chaincode, with its resource limits applied
+func (vm *DockerVM) hostConfig(ccid string) (*docker.HostConfig, error) {
+	limits := vm.ResourceLimits.ForChaincode(ccid)
+	if limits.IsZero() {
+		return vm.HostConfig, nil
+	}
+
+	hostConfig := &docker.HostConfig{}
+	if vm.HostConfig != nil {
+		*hostConfig = *vm.HostConfig
+	}
+	if limits.CPUs > 0 {
+		hostConfig.CPUPeriod = cpuPeriod
+		hostConfig.CPUQuota = int64(limits.CPUs * cpuPeriod)
+	}
+	if limits.Memory > 0 {
+		hostConfig.Memory = limits.Memory
+	}
+	if limits.Pids > 0 {
+		pids := limits.Pids
+		hostConfig.PidsLimit = &pids
+	}
+	if limits.ReadOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+	}
+	if limits.SeccompProfile != "" {
+		seccomp := ccintf.SeccompUnconfined
+		if limits.SeccompProfile != ccintf.SeccompUnconfined {
+			// the Docker API expects the content of the profile rather than its path
+			profile, err := ioutil.ReadFile(limits.SeccompProfile)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not read seccomp profile")
+			}
+			compacted := &bytes.Buffer{}
+			if err := json.Compact(compacted, profile); err != nil {
+				return nil, errors.Wrapf(err, "invalid seccomp profile %s", limits.SeccompProfile)
+			}
+			seccomp = compacted.String()
+		}
+		hostConfig.SecurityOpt = append(append([]string{}, hostConfig.SecurityOpt...), "seccomp="+seccomp)
+	}
+	return hostConfig, nil
+}
+
 func addFiles(tw *tar.Writer, contents map[string][]byte) error {
 	for name, payload := range contents {
 		err := tw.WriteHeader(&tar.Header{
@@ -16,6 +16,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -203,6 +205,87 @@ func Test_Start(t *testing.T) {
 	gt.Expect(err).NotTo(HaveOccurred())
 }
 
+func Test_StartWithResourceLimits(t *testing.T) {
+	gt := NewGomegaWithT(t)
+	dockerClient := &mock.DockerClient{}
+	dockerClient.CreateContainerReturns(&docker.Container{}, nil)
+
+	tempDir, err := ioutil.TempDir("", "seccomp")
+	gt.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(tempDir)
+	profile := filepath.Join(tempDir, "profile.json")
+	err = ioutil.WriteFile(profile, []byte("{\n  \"defaultAction\": \"SCMP_ACT_ERRNO\"\n}\n"), 0600)
+	gt.Expect(err).NotTo(HaveOccurred())
+
+	dvm := DockerVM{
+		BuildMetrics: NewBuildMetrics(&disabled.Provider{}),
+		Client:       dockerClient,
+		HostConfig: &docker.HostConfig{
+			NetworkMode: "host",
+			Memory:      1024,
+			SecurityOpt: []string{"no-new-privileges"},
+		},
+		ResourceLimits: &ccintf.ResourceLimitsConfig{
+			Default: ccintf.ResourceLimits{CPUs: 0.5, Memory: 2048},
+			Labels: map[string]ccintf.ResourceLimits{
+				"limited": {Pids: 64, ReadOnlyRootfs: true, SeccompProfile: profile},
+				"broken":  {SeccompProfile: filepath.Join(tempDir, "missing.json")},
+			},
+		},
+	}
+	peerConnection := &ccintf.PeerConnection{
+		Address: "peer-address",
+		TLSConfig: &ccintf.TLSConfig{
+			ClientKey:  []byte("key"),
+			ClientCert: []byte("cert"),
+			RootCert:   []byte("root"),
+		},
+	}
+
+	err = dvm.Start("simple:1.0", "GOLANG", peerConnection)
+	gt.Expect(err).NotTo(HaveOccurred())
+	opts := dockerClient.CreateContainerArgsForCall(0)
+	gt.Expect(opts.HostConfig.CPUQuota).To(Equal(int64(50000)))
+	gt.Expect(opts.HostConfig.CPUPeriod).To(Equal(int64(100000)))
+	gt.Expect(opts.HostConfig.Memory).To(Equal(int64(2048)))
+	gt.Expect(opts.HostConfig.NetworkMode).To(Equal("host"))
+	gt.Expect(opts.HostConfig.ReadonlyRootfs).To(BeFalse())
+	gt.Expect(opts.Config.Volumes).To(BeEmpty())
+	_, upload := dockerClient.UploadToContainerArgsForCall(0)
+	gt.Expect(upload.Path).To(Equal("/"))
+	gt.Expect(dvm.HostConfig.Memory).To(Equal(int64(1024)))
+
+	err = dvm.Start("limited:1.0", "GOLANG", peerConnection)
+	gt.Expect(err).NotTo(HaveOccurred())
+	opts = dockerClient.CreateContainerArgsForCall(1)
+	gt.Expect(opts.HostConfig.CPUQuota).To(Equal(int64(50000)))
+	gt.Expect(*opts.HostConfig.PidsLimit).To(Equal(int64(64)))
+	gt.Expect(opts.HostConfig.ReadonlyRootfs).To(BeTrue())
+	gt.Expect(opts.HostConfig.SecurityOpt).To(Equal([]string{"no-new-privileges", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`}))
+	gt.Expect(dvm.HostConfig.SecurityOpt).To(Equal([]string{"no-new-privileges"}))
+	gt.Expect(opts.Config.Volumes).To(HaveKey("/etc/hyperledger/fabric"))
+
+	// the TLS files are uploaded to the volume
+	_, upload = dockerClient.UploadToContainerArgsForCall(1)
+	gt.Expect(upload.Path).To(Equal("/etc/hyperledger/fabric"))
+	gr, err := gzip.NewReader(upload.InputStream)
+	gt.Expect(err).NotTo(HaveOccurred())
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		gt.Expect(err).NotTo(HaveOccurred())
+		names = append(names, header.Name)
+	}
+	gt.Expect(names).To(ConsistOf("client.key", "client.crt", "client_pem.key", "client_pem.crt", "peer.crt"))
+
+	err = dvm.Start("broken:1.0", "GOLANG", peerConnection)
+	gt.Expect(err).To(MatchError(ContainSubstring("could not apply resource limits: could not read seccomp profile")))
+}
+
 func Test_streamOutput(t *testing.T) {
 	gt := NewGomegaWithT(t)
 
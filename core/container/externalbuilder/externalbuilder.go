@@ -263,23 +263,25 @@ func SanitizeCCIDPath(ccid string) string {
 
 // A Builder is used to interact with an external chaincode builder and launcher.
 type Builder struct {
-	EnvWhitelist []string
-	Location     string
-	Logger       *flogging.FabricLogger
-	Name         string
-	MSPID        string
+	EnvWhitelist   []string
+	Location       string
+	Logger         *flogging.FabricLogger
+	Name           string
+	MSPID          string
+	ResourceLimits *ccintf.ResourceLimitsConfig
 }
 
 // CreateBuilders will construct builders from the peer configuration.
-func CreateBuilders(builderConfs []peer.ExternalBuilder, mspid string) []*Builder {
+func CreateBuilders(builderConfs []peer.ExternalBuilder, mspid string, resourceLimits *ccintf.ResourceLimitsConfig) []*Builder {
 	var builders []*Builder
 	for _, builderConf := range builderConfs {
 		builders = append(builders, &Builder{
-			Location:     builderConf.Path,
-			Name:         builderConf.Name,
-			EnvWhitelist: builderConf.EnvironmentWhitelist,
-			Logger:       logger.Named(builderConf.Name),
-			MSPID:        mspid,
+			Location:       builderConf.Path,
+			Name:           builderConf.Name,
+			EnvWhitelist:   builderConf.EnvironmentWhitelist,
+			Logger:         logger.Named(builderConf.Name),
+			MSPID:          mspid,
+			ResourceLimits: resourceLimits,
 		})
 	}
 	return builders
@@ -339,15 +341,17 @@ type runConfig struct {
 	ClientKey   string `json:"client_key"`  // PEM encoded client key
 	RootCert    string `json:"root_cert"`   // PEM encoded peer chaincode certificate
 	MSPID       string `json:"mspid"`
+	// ResourceLimits are the limits the builder enforces when it launches the chaincode
+	ResourceLimits *ccintf.ResourceLimits `json:"resource_limits,omitempty"`
 }
 
-func newRunConfig(ccid string, peerConnection *ccintf.PeerConnection, mspid string) runConfig {
+func newRunConfig(ccid string, peerConnection *ccintf.PeerConnection, mspid string, resourceLimits ccintf.ResourceLimits) runConfig {
 	var tlsConfig ccintf.TLSConfig
 	if peerConnection.TLSConfig != nil {
 		tlsConfig = *peerConnection.TLSConfig
 	}
 
-	return runConfig{
+	rc := runConfig{
 		PeerAddress: peerConnection.Address,
 		CCID:        ccid,
 		ClientCert:  string(tlsConfig.ClientCert),
@@ -355,6 +359,10 @@ func newRunConfig(ccid string, peerConnection *ccintf.PeerConnection, mspid stri
 		RootCert:    string(tlsConfig.RootCert),
 		MSPID:       mspid,
 	}
+	if !resourceLimits.IsZero() {
+		rc.ResourceLimits = &resourceLimits
+	}
+	return rc
 }
 
 // Run starts the `run` script and returns a Session that can be used to
@@ -365,7 +373,7 @@ func (b *Builder) Run(ccid, bldDir string, peerConnection *ccintf.PeerConnection
 		return nil, errors.WithMessage(err, "could not create temp run dir")
 	}
 
-	rc := newRunConfig(ccid, peerConnection, b.MSPID)
+	rc := newRunConfig(ccid, peerConnection, b.MSPID, b.ResourceLimits.ForChaincode(ccid))
 	marshaledRC, err := json.Marshal(rc)
 	if err != nil {
 		return nil, errors.WithMessage(err, "could not marshal run config")
@@ -104,7 +104,7 @@ var _ = Describe("externalbuilder", func() {
 					{Path: "bad1", Name: "bad1"},
 					{Path: "testdata/goodbuilder", Name: "goodbuilder"},
 					{Path: "bad2", Name: "bad2"},
-				}, "mspid"),
+				}, "mspid", nil),
 				DurablePath: durablePath,
 			}
 		})
@@ -127,7 +127,7 @@ var _ = Describe("externalbuilder", func() {
 				BeforeEach(func() {
 					detector.Builders = externalbuilder.CreateBuilders([]peer.ExternalBuilder{
 						{Path: "bad1", Name: "bad1"},
-					}, "mspid")
+					}, "mspid", nil)
 				})
 
 				It("returns a nil instance", func() {
@@ -339,6 +339,27 @@ var _ = Describe("externalbuilder", func() {
 				go func() { errCh <- sess.Wait() }()
 				Eventually(errCh).Should(Receive(BeNil()))
 			})
+
+			When("resource limits are configured", func() {
+				BeforeEach(func() {
+					builder.Location = "testdata/limitsbuilder"
+					builder.ResourceLimits = &ccintf.ResourceLimitsConfig{
+						Default: ccintf.ResourceLimits{CPUs: 1, Memory: 268435456},
+						Labels: map[string]ccintf.ResourceLimits{
+							"test-label": {CPUs: 0.5, ReadOnlyRootfs: true},
+						},
+					}
+				})
+
+				It("passes the limits of the chaincode to the external builder", func() {
+					sess, err := builder.Run("test-label:hash", bldDir, fakeConnection)
+					Expect(err).NotTo(HaveOccurred())
+
+					errCh := make(chan error)
+					go func() { errCh <- sess.Wait() }()
+					Eventually(errCh).Should(Receive(BeNil()))
+				})
+			})
 		})
 
 		Describe("NewCommand", func() {
@@ -0,0 +1,312 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	requestTimeout    = 30 * time.Second
+)
+
+// ObjectMeta is the metadata of the Kubernetes objects the launcher manages
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Secret is a Kubernetes secret
+type Secret struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Data       map[string][]byte `json:"data,omitempty"`
+}
+
+// Pod is a Kubernetes pod
+type Pod struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   ObjectMeta `json:"metadata"`
+	Spec       PodSpec    `json:"spec"`
+	Status     PodStatus  `json:"status"`
+}
+
+// PodSpec is the specification of a pod
+type PodSpec struct {
+	RestartPolicy      string      `json:"restartPolicy,omitempty"`
+	ServiceAccountName string      `json:"serviceAccountName,omitempty"`
+	Containers         []Container `json:"containers"`
+	Volumes            []Volume    `json:"volumes,omitempty"`
+}
+
+// Container is a container of a pod
+type Container struct {
+	Name            string           `json:"name"`
+	Image           string           `json:"image"`
+	ImagePullPolicy string           `json:"imagePullPolicy,omitempty"`
+	Args            []string         `json:"args,omitempty"`
+	Env             []EnvVar         `json:"env,omitempty"`
+	VolumeMounts    []VolumeMount    `json:"volumeMounts,omitempty"`
+	Resources       *Resources       `json:"resources,omitempty"`
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+}
+
+// Resources are the compute resources of a container
+type Resources struct {
+	Limits map[string]string `json:"limits,omitempty"`
+}
+
+// SecurityContext is the security configuration of a container
+type SecurityContext struct {
+	ReadOnlyRootFilesystem *bool           `json:"readOnlyRootFilesystem,omitempty"`
+	SeccompProfile         *SeccompProfile `json:"seccompProfile,omitempty"`
+}
+
+// SeccompProfile selects the seccomp profile of a container
+type SeccompProfile struct {
+	Type             string `json:"type"`
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// EnvVar is an environment variable of a container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Volume is a volume of a pod
+type Volume struct {
+	Name   string              `json:"name"`
+	Secret *SecretVolumeSource `json:"secret,omitempty"`
+}
+
+// SecretVolumeSource populates a volume with the data of a secret
+type SecretVolumeSource struct {
+	SecretName string `json:"secretName"`
+}
+
+// VolumeMount mounts a volume in a container
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+// PodStatus is the status of a pod
+type PodStatus struct {
+	Phase             string            `json:"phase,omitempty"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+// ContainerStatus is the status of a container of a pod
+type ContainerStatus struct {
+	Name  string         `json:"name"`
+	State ContainerState `json:"state"`
+}
+
+// ContainerState is the state of a container, of which at most one member is set
+type ContainerState struct {
+	Terminated *ContainerStateTerminated `json:"terminated,omitempty"`
+}
+
+// ContainerStateTerminated is the state of a container that terminated
+type ContainerStateTerminated struct {
+	ExitCode int    `json:"exitCode"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// StatusError is returned when the API server answers with an unexpected status
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes API server returned %d: %s", e.Code, e.Message)
+}
+
+// IsNotFound returns whether the given error reports an object that doesn't exist
+func IsNotFound(err error) bool {
+	se, ok := errors.Cause(err).(*StatusError)
+	return ok && se.Code == http.StatusNotFound
+}
+
+// Client is a minimal client of the Kubernetes API, managing the pods and
+// secrets of chaincode
+type Client struct {
+	// BaseURL is the URL of the API server
+	BaseURL string
+	// Token is the bearer token authenticating the peer
+	Token string
+	// HTTPClient sends the requests to the API server
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client of the API server at the given URL. The peer is authenticated
+// with the bearer token in tokenFile, and the API server with the CA certificates in caFile.
+// When apiServer is empty, the in-cluster configuration of the service account of the pod
+// of the peer is used for all three.
+func NewClient(apiServer, tokenFile, caFile string) (*Client, error) {
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("the Kubernetes API server is not set and the peer is not running in a pod")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+		if tokenFile == "" {
+			tokenFile = serviceAccountDir + "/token"
+		}
+		if caFile == "" {
+			caFile = serviceAccountDir + "/ca.crt"
+		}
+	}
+
+	c := &Client{
+		BaseURL:    strings.TrimSuffix(apiServer, "/"),
+		HTTPClient: &http.Client{Timeout: requestTimeout},
+	}
+	if tokenFile != "" {
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading token file %s", tokenFile)
+		}
+		c.Token = strings.TrimSpace(string(token))
+	}
+	if caFile != "" {
+		caPEM, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading CA file %s", caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Errorf("no CA certificates found in %s", caFile)
+		}
+		c.HTTPClient.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+	return c, nil
+}
+
+// InClusterNamespace returns the namespace of the pod of the peer, or "default"
+// if the peer is not running in a pod
+func InClusterNamespace() string {
+	ns, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil || len(bytes.TrimSpace(ns)) == 0 {
+		return "default"
+	}
+	return string(bytes.TrimSpace(ns))
+}
+
+// CreateSecret creates the given secret in the given namespace
+func (c *Client) CreateSecret(ctx context.Context, namespace string, secret *Secret) error {
+	secret.APIVersion, secret.Kind = "v1", "Secret"
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/secrets", namespace), secret, nil)
+}
+
+// DeleteSecret deletes the secret with the given name from the given namespace
+func (c *Client) DeleteSecret(ctx context.Context, namespace, name string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s/secrets/%s", namespace, name), nil, nil)
+}
+
+// CreatePod creates the given pod in the given namespace
+func (c *Client) CreatePod(ctx context.Context, namespace string, pod *Pod) error {
+	pod.APIVersion, pod.Kind = "v1", "Pod"
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/pods", namespace), pod, nil)
+}
+
+// GetPod returns the pod with the given name from the given namespace
+func (c *Client) GetPod(ctx context.Context, namespace, name string) (*Pod, error) {
+	pod := &Pod{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", namespace, name), nil, pod); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// DeletePod deletes the pod with the given name from the given namespace
+func (c *Client) DeletePod(ctx context.Context, namespace, name string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", namespace, name), nil, nil)
+}
+
+// Version returns the version of the API server
+func (c *Client) Version(ctx context.Context) (string, error) {
+	version := struct {
+		GitVersion string `json:"gitVersion"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/version", nil, &version); err != nil {
+		return "", err
+	}
+	return version.GitVersion, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return errors.Wrap(err, "failed marshaling request")
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return errors.Wrap(err, "failed creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		status := struct {
+			Message string `json:"message"`
+		}{}
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(b, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(b))
+		}
+		return errors.WithMessagef(&StatusError{Code: resp.StatusCode, Message: status.Message}, "%s %s failed", method, path)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrapf(err, "failed decoding response of %s %s", method, path)
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kubernetes
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("chaincode.kubernetes")
+
+const (
+	// PackageType is the type of the chaincode packages launched in Kubernetes pods
+	PackageType = "k8s"
+	// ImageFile is the file in the code package of the chaincode that holds its image
+	ImageFile = "image.json"
+
+	// TLSDir is the directory of the chaincode container holding the TLS material
+	// of the chaincode, at the same paths as in the containers the peer launches in Docker
+	TLSDir = "/etc/hyperledger/fabric"
+
+	defaultPollInterval = time.Second
+	tlsVolume           = "fabric-tls"
+	chaincodeContainer  = "chaincode"
+)
+
+// ImageInfo is the content of the ImageFile of a chaincode package
+type ImageInfo struct {
+	// Name is the reference of the image, such as registry.example.com/chaincode:1.0
+	Name string `json:"name"`
+	// Digest optionally pins the image, such as sha256:...
+	Digest string `json:"digest"`
+}
+
+// Reference returns the reference the image is pulled by
+func (ii ImageInfo) Reference() string {
+	if ii.Digest == "" {
+		return ii.Name
+	}
+	return ii.Name + "@" + ii.Digest
+}
+
+// Launcher launches chaincode packaged as container images in Kubernetes pods,
+// which connect to the peer just as the chaincode containers it launches in Docker.
+type Launcher struct {
+	Client          *Client
+	Namespace       string
+	PeerID          string
+	NetworkID       string
+	MSPID           string
+	ServiceAccount  string
+	ImagePullPolicy string
+	LoggingEnv      []string
+	// PollInterval is the interval the status of the pods of chaincode is polled at
+	PollInterval time.Duration
+	// ResourceLimits are the limits applied to the containers of chaincode
+	ResourceLimits *ccintf.ResourceLimitsConfig
+}
+
+// HealthCheck checks if the Launcher is able to communicate with the API server.
+func (l *Launcher) HealthCheck(ctx context.Context) error {
+	if _, err := l.Client.Version(ctx); err != nil {
+		return errors.WithMessage(err, "failed to reach the Kubernetes API server")
+	}
+	return nil
+}
+
+// Build returns an Instance running the image of the given chaincode package,
+// or nil if the package is not of type PackageType.
+func (l *Launcher) Build(ccid string, mdBytes []byte, codePackage io.Reader) (*Instance, error) {
+	metadata := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(mdBytes, &metadata); err != nil {
+		return nil, errors.Wrap(err, "malformed chaincode package metadata")
+	}
+	if !strings.EqualFold(metadata.Type, PackageType) {
+		return nil, nil
+	}
+
+	image, err := readImageInfo(codePackage)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not read image of chaincode %s", ccid)
+	}
+	logger.Debugf("chaincode %s will be launched from image %s", ccid, image.Reference())
+
+	return &Instance{
+		CCID:     ccid,
+		Image:    image.Reference(),
+		Launcher: l,
+	}, nil
+}
+
+func readImageInfo(codePackage io.Reader) (*ImageInfo, error) {
+	gzr, err := gzip.NewReader(codePackage)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read code package")
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("code package has no %s", ImageFile)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read code package")
+		}
+		if strings.TrimPrefix(header.Name, "./") != ImageFile {
+			continue
+		}
+
+		b, err := ioutil.ReadAll(io.LimitReader(tr, 64*1024))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %s", ImageFile)
+		}
+		image := &ImageInfo{}
+		if err := json.Unmarshal(b, image); err != nil {
+			return nil, errors.Wrapf(err, "malformed %s", ImageFile)
+		}
+		if image.Name == "" {
+			return nil, errors.Errorf("%s has no image name", ImageFile)
+		}
+		return image, nil
+	}
+}
+
+// podPrefix returns the prefix of the names of the pods of the given chaincode, which
+// is a valid DNS label that distinguishes the chaincode of different peers and networks
+func (l *Launcher) podPrefix(ccid string) string {
+	hash := sha256.Sum256([]byte(l.NetworkID + "-" + l.PeerID + "-" + ccid))
+
+	label := ccid
+	if i := strings.LastIndex(ccid, ":"); i >= 0 {
+		label = ccid[:i]
+	}
+	var sb strings.Builder
+	for _, r := range strings.ToLower(label) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-"):
+			sb.WriteRune('-')
+		}
+		if sb.Len() == 24 {
+			break
+		}
+	}
+	name := strings.Trim(sb.String(), "-")
+	if name == "" {
+		name = "cc"
+	}
+	return name + "-" + hex.EncodeToString(hash[:8])
+}
+
+// env returns the environment of the chaincode container, which matches the
+// environment of the chaincode containers the peer launches in Docker
+func (l *Launcher) env(ccid string, peerConnection *ccintf.PeerConnection) []EnvVar {
+	env := []EnvVar{
+		{Name: "CORE_CHAINCODE_ID_NAME", Value: ccid},
+		{Name: "CORE_PEER_ADDRESS", Value: peerConnection.Address},
+		{Name: "CORE_PEER_LOCALMSPID", Value: l.MSPID},
+	}
+	for _, kv := range l.LoggingEnv {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env = append(env, EnvVar{Name: parts[0], Value: parts[1]})
+		}
+	}
+	if peerConnection.TLSConfig == nil {
+		return append(env, EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "false"})
+	}
+	return append(env,
+		EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "true"},
+		EnvVar{Name: "CORE_TLS_CLIENT_KEY_PATH", Value: TLSDir + "/client.key"},
+		EnvVar{Name: "CORE_TLS_CLIENT_CERT_PATH", Value: TLSDir + "/client.crt"},
+		EnvVar{Name: "CORE_TLS_CLIENT_KEY_FILE", Value: TLSDir + "/client_pem.key"},
+		EnvVar{Name: "CORE_TLS_CLIENT_CERT_FILE", Value: TLSDir + "/client_pem.crt"},
+		EnvVar{Name: "CORE_PEER_TLS_ROOTCERT_FILE", Value: TLSDir + "/peer.crt"},
+	)
+}
+
+// applyResourceLimits applies the resource limits of the given chaincode to its container.
+// The seccomp profile, unless unconfined, is a profile file relative to the seccomp
+// directory of the kubelet. Kubernetes doesn't limit the pids of a container, so
+// the pids limit is left to the configuration of the nodes.
+func (l *Launcher) applyResourceLimits(ccid string, container *Container) {
+	limits := l.ResourceLimits.ForChaincode(ccid)
+	if limits.IsZero() {
+		return
+	}
+
+	resources := map[string]string{}
+	if limits.CPUs > 0 {
+		resources["cpu"] = fmt.Sprintf("%dm", int64(math.Ceil(limits.CPUs*1000)))
+	}
+	if limits.Memory > 0 {
+		resources["memory"] = strconv.FormatInt(limits.Memory, 10)
+	}
+	if len(resources) > 0 {
+		container.Resources = &Resources{Limits: resources}
+	}
+	if limits.Pids > 0 {
+		logger.Warningf("the pids limit of chaincode %s is not supported in Kubernetes and is ignored", ccid)
+	}
+
+	securityContext := &SecurityContext{}
+	if limits.ReadOnlyRootfs {
+		readOnly := true
+		securityContext.ReadOnlyRootFilesystem = &readOnly
+	}
+	switch limits.SeccompProfile {
+	case "":
+	case ccintf.SeccompUnconfined:
+		securityContext.SeccompProfile = &SeccompProfile{Type: "Unconfined"}
+	default:
+		securityContext.SeccompProfile = &SeccompProfile{Type: "Localhost", LocalhostProfile: limits.SeccompProfile}
+	}
+	if securityContext.ReadOnlyRootFilesystem != nil || securityContext.SeccompProfile != nil {
+		container.SecurityContext = securityContext
+	}
+}
+
+// Instance is chaincode running in a Kubernetes pod
+type Instance struct {
+	CCID     string
+	Image    string
+	Launcher *Launcher
+
+	mutex sync.Mutex
+	pod   string
+}
+
+// ChaincodeServerInfo returns nil, as the chaincode connects to the peer.
+func (i *Instance) ChaincodeServerInfo() (*ccintf.ChaincodeServerInfo, error) {
+	return nil, nil
+}
+
+// Start creates a pod running the image of the chaincode, along with a secret
+// holding its TLS material. Any pod previously started is deleted.
+func (i *Instance) Start(peerConnection *ccintf.PeerConnection) error {
+	if err := i.Stop(); err != nil {
+		logger.Warningf("failed to delete the previous pod of chaincode %s: %s", i.CCID, err)
+	}
+
+	l := i.Launcher
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return errors.Wrap(err, "failed generating pod name")
+	}
+	// pods are deleted asynchronously, so each pod gets a new name
+	name := l.podPrefix(i.CCID) + "-" + hex.EncodeToString(suffix)
+	meta := ObjectMeta{
+		Name: name,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "fabric-peer",
+			"app.kubernetes.io/component":  "chaincode",
+		},
+		Annotations: map[string]string{
+			"fabric.hyperledger.org/chaincode-id": i.CCID,
+			"fabric.hyperledger.org/peer-id":      l.PeerID,
+			"fabric.hyperledger.org/network-id":   l.NetworkID,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	container := Container{
+		Name:            chaincodeContainer,
+		Image:           i.Image,
+		ImagePullPolicy: l.ImagePullPolicy,
+		Args:            []string{"--peer.address=" + peerConnection.Address},
+		Env:             l.env(i.CCID, peerConnection),
+	}
+	l.applyResourceLimits(i.CCID, &container)
+	pod := &Pod{
+		Metadata: meta,
+		Spec: PodSpec{
+			RestartPolicy:      "Never",
+			ServiceAccountName: l.ServiceAccount,
+		},
+	}
+
+	if tlsConfig := peerConnection.TLSConfig; tlsConfig != nil {
+		// Note, the peer base64 encodes 2 of the TLS artifacts in Docker as well
+		err := l.Client.CreateSecret(ctx, l.Namespace, &Secret{
+			Metadata: meta,
+			Data: map[string][]byte{
+				"client.key":     []byte(base64.StdEncoding.EncodeToString(tlsConfig.ClientKey)),
+				"client.crt":     []byte(base64.StdEncoding.EncodeToString(tlsConfig.ClientCert)),
+				"client_pem.key": tlsConfig.ClientKey,
+				"client_pem.crt": tlsConfig.ClientCert,
+				"peer.crt":       tlsConfig.RootCert,
+			},
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "failed creating TLS secret of chaincode %s", i.CCID)
+		}
+		pod.Spec.Volumes = []Volume{{Name: tlsVolume, Secret: &SecretVolumeSource{SecretName: name}}}
+		container.VolumeMounts = []VolumeMount{{Name: tlsVolume, MountPath: TLSDir, ReadOnly: true}}
+	}
+	pod.Spec.Containers = []Container{container}
+
+	if err := l.Client.CreatePod(ctx, l.Namespace, pod); err != nil {
+		if peerConnection.TLSConfig != nil {
+			l.deleteSecret(ctx, name)
+		}
+		return errors.WithMessagef(err, "failed creating pod of chaincode %s", i.CCID)
+	}
+	logger.Debugf("started pod %s of chaincode %s", name, i.CCID)
+
+	i.mutex.Lock()
+	i.pod = name
+	i.mutex.Unlock()
+	return nil
+}
+
+// Stop deletes the pod of the chaincode and its TLS secret.
+func (i *Instance) Stop() error {
+	i.mutex.Lock()
+	name := i.pod
+	i.pod = ""
+	i.mutex.Unlock()
+	if name == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	i.Launcher.deleteSecret(ctx, name)
+	if err := i.Launcher.Client.DeletePod(ctx, i.Launcher.Namespace, name); err != nil && !IsNotFound(err) {
+		return errors.WithMessagef(err, "failed deleting pod %s of chaincode %s", name, i.CCID)
+	}
+	logger.Debugf("deleted pod %s of chaincode %s", name, i.CCID)
+	return nil
+}
+
+func (l *Launcher) deleteSecret(ctx context.Context, name string) {
+	if err := l.Client.DeleteSecret(ctx, l.Namespace, name); err != nil && !IsNotFound(err) {
+		logger.Warningf("failed deleting TLS secret %s: %s", name, err)
+	}
+}
+
+// Wait blocks until the chaincode container terminates, and returns its exit code.
+func (i *Instance) Wait() (int, error) {
+	i.mutex.Lock()
+	name := i.pod
+	i.mutex.Unlock()
+	if name == "" {
+		return -1, errors.Errorf("chaincode %s has not been started", i.CCID)
+	}
+
+	interval := i.Launcher.PollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		pod, err := i.Launcher.Client.GetPod(ctx, i.Launcher.Namespace, name)
+		cancel()
+		if IsNotFound(err) {
+			return -1, errors.Errorf("pod %s of chaincode %s was deleted", name, i.CCID)
+		}
+		if err != nil {
+			return -1, errors.WithMessagef(err, "failed getting pod %s of chaincode %s", name, i.CCID)
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == chaincodeContainer && cs.State.Terminated != nil {
+				return cs.State.Terminated.ExitCode, nil
+			}
+		}
+		if pod.Status.Phase == "Failed" || pod.Status.Phase == "Succeeded" {
+			return -1, errors.Errorf("pod %s of chaincode %s terminated in phase %s", name, i.CCID, pod.Status.Phase)
+		}
+		time.Sleep(interval)
+	}
+}
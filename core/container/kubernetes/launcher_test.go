@@ -0,0 +1,324 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiServer is a fake Kubernetes API server
+type apiServer struct {
+	sync.Mutex
+	pods    map[string]*Pod
+	secrets map[string]*Secret
+	tokens  []string
+}
+
+func newAPIServer() (*apiServer, *httptest.Server) {
+	as := &apiServer{
+		pods:    map[string]*Pod{},
+		secrets: map[string]*Secret{},
+	}
+	return as, httptest.NewServer(as)
+}
+
+func (as *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	as.Lock()
+	defer as.Unlock()
+	as.tokens = append(as.tokens, r.Header.Get("Authorization"))
+
+	if r.URL.Path == "/version" {
+		w.Write([]byte(`{"gitVersion":"v1.18.0"}`))
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/")
+	if len(parts) < 2 || parts[0] != "fabric" {
+		http.Error(w, `{"message":"bad path"}`, http.StatusBadRequest)
+		return
+	}
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"not found"}`))
+	}
+
+	switch {
+	case parts[1] == "pods" && r.Method == http.MethodPost:
+		pod := &Pod{}
+		json.NewDecoder(r.Body).Decode(pod)
+		as.pods[pod.Metadata.Name] = pod
+		w.WriteHeader(http.StatusCreated)
+	case parts[1] == "secrets" && r.Method == http.MethodPost:
+		secret := &Secret{}
+		json.NewDecoder(r.Body).Decode(secret)
+		as.secrets[secret.Metadata.Name] = secret
+		w.WriteHeader(http.StatusCreated)
+	case parts[1] == "pods" && r.Method == http.MethodGet:
+		pod, exists := as.pods[parts[2]]
+		if !exists {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(pod)
+	case parts[1] == "pods" && r.Method == http.MethodDelete:
+		if _, exists := as.pods[parts[2]]; !exists {
+			notFound()
+			return
+		}
+		delete(as.pods, parts[2])
+	case parts[1] == "secrets" && r.Method == http.MethodDelete:
+		if _, exists := as.secrets[parts[2]]; !exists {
+			notFound()
+			return
+		}
+		delete(as.secrets, parts[2])
+	default:
+		http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+func (as *apiServer) pod() *Pod {
+	as.Lock()
+	defer as.Unlock()
+	for _, pod := range as.pods {
+		return pod
+	}
+	return nil
+}
+
+func codePackage(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0600}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf
+}
+
+func TestBuild(t *testing.T) {
+	l := &Launcher{}
+
+	instance, err := l.Build("cc:1", []byte(`{"type":"golang"}`), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, instance)
+
+	instance, err = l.Build("cc:1", []byte(`{"type":"K8S"}`), codePackage(t, map[string]string{
+		"image.json": `{"name":"registry.example.com/cc","digest":"sha256:1234"}`,
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/cc@sha256:1234", instance.Image)
+
+	instance, err = l.Build("cc:1", []byte(`{"type":"k8s"}`), codePackage(t, map[string]string{
+		"./image.json": `{"name":"registry.example.com/cc:1.0"}`,
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com/cc:1.0", instance.Image)
+
+	_, err = l.Build("cc:1", []byte(`{"type":"k8s"}`), codePackage(t, map[string]string{"other": "{}"}))
+	assert.EqualError(t, err, "could not read image of chaincode cc:1: code package has no image.json")
+
+	_, err = l.Build("cc:1", []byte(`{"type":"k8s"}`), codePackage(t, map[string]string{"image.json": "{}"}))
+	assert.EqualError(t, err, "could not read image of chaincode cc:1: image.json has no image name")
+
+	_, err = l.Build("cc:1", []byte(`{`), nil)
+	assert.EqualError(t, err, "malformed chaincode package metadata: unexpected end of JSON input")
+}
+
+func TestPodPrefix(t *testing.T) {
+	l := &Launcher{PeerID: "peer0", NetworkID: "net"}
+	prefix := l.podPrefix("My_Chaincode.v1:0123abcd")
+	assert.True(t, strings.HasPrefix(prefix, "my-chaincode-v1-"), prefix)
+	assert.Len(t, prefix, len("my-chaincode-v1-")+16)
+	assert.NotEqual(t, prefix, (&Launcher{PeerID: "peer1", NetworkID: "net"}).podPrefix("My_Chaincode.v1:0123abcd"))
+
+	assert.True(t, strings.HasPrefix(l.podPrefix("___:1"), "cc-"))
+	assert.True(t, len(l.podPrefix(strings.Repeat("a", 100)+":1")) <= 24+1+16)
+}
+
+func TestInstance(t *testing.T) {
+	as, server := newAPIServer()
+	defer server.Close()
+
+	l := &Launcher{
+		Client:          &Client{BaseURL: server.URL, Token: "token", HTTPClient: server.Client()},
+		Namespace:       "fabric",
+		PeerID:          "peer0",
+		NetworkID:       "net",
+		MSPID:           "Org1MSP",
+		ServiceAccount:  "chaincode",
+		ImagePullPolicy: "Always",
+		LoggingEnv:      []string{"CORE_CHAINCODE_LOGGING_LEVEL=info"},
+		PollInterval:    10 * time.Millisecond,
+	}
+	require.NoError(t, l.HealthCheck(context.Background()))
+
+	instance := &Instance{CCID: "cc:1", Image: "cc:1.0", Launcher: l}
+
+	info, err := instance.ChaincodeServerInfo()
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+
+	_, err = instance.Wait()
+	assert.EqualError(t, err, "chaincode cc:1 has not been started")
+
+	err = instance.Start(&ccintf.PeerConnection{
+		Address: "peer0:7052",
+		TLSConfig: &ccintf.TLSConfig{
+			ClientKey:  []byte("key"),
+			ClientCert: []byte("cert"),
+			RootCert:   []byte("root"),
+		},
+	})
+	require.NoError(t, err)
+
+	pod := as.pod()
+	require.NotNil(t, pod)
+	assert.Equal(t, "Never", pod.Spec.RestartPolicy)
+	assert.Equal(t, "chaincode", pod.Spec.ServiceAccountName)
+	assert.Equal(t, "cc:1", pod.Metadata.Annotations["fabric.hyperledger.org/chaincode-id"])
+	container := pod.Spec.Containers[0]
+	assert.Equal(t, "cc:1.0", container.Image)
+	assert.Equal(t, "Always", container.ImagePullPolicy)
+	assert.Equal(t, []string{"--peer.address=peer0:7052"}, container.Args)
+	assert.Contains(t, container.Env, EnvVar{Name: "CORE_CHAINCODE_ID_NAME", Value: "cc:1"})
+	assert.Contains(t, container.Env, EnvVar{Name: "CORE_PEER_LOCALMSPID", Value: "Org1MSP"})
+	assert.Contains(t, container.Env, EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "true"})
+	assert.Contains(t, container.Env, EnvVar{Name: "CORE_CHAINCODE_LOGGING_LEVEL", Value: "info"})
+	assert.Equal(t, []VolumeMount{{Name: tlsVolume, MountPath: TLSDir, ReadOnly: true}}, container.VolumeMounts)
+	assert.Nil(t, container.Resources)
+	assert.Nil(t, container.SecurityContext)
+
+	secret := as.secrets[pod.Metadata.Name]
+	require.NotNil(t, secret)
+	assert.Equal(t, []byte("key"), secret.Data["client_pem.key"])
+	assert.Equal(t, []byte("a2V5"), secret.Data["client.key"])
+	assert.Equal(t, []byte("root"), secret.Data["peer.crt"])
+	assert.Equal(t, "Bearer token", as.tokens[0])
+
+	// the pod terminates
+	as.Lock()
+	pod.Status = PodStatus{
+		Phase: "Failed",
+		ContainerStatuses: []ContainerStatus{{
+			Name:  chaincodeContainer,
+			State: ContainerState{Terminated: &ContainerStateTerminated{ExitCode: 3}},
+		}},
+	}
+	as.Unlock()
+	code, err := instance.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	// restarting replaces the pod
+	first := pod.Metadata.Name
+	require.NoError(t, instance.Start(&ccintf.PeerConnection{Address: "peer0:7052"}))
+	pod = as.pod()
+	assert.NotEqual(t, first, pod.Metadata.Name)
+	assert.Len(t, as.pods, 1)
+	assert.Empty(t, as.secrets)
+	assert.Contains(t, pod.Spec.Containers[0].Env, EnvVar{Name: "CORE_PEER_TLS_ENABLED", Value: "false"})
+	assert.Empty(t, pod.Spec.Volumes)
+
+	requests := func() int {
+		as.Lock()
+		defer as.Unlock()
+		return len(as.tokens)
+	}
+	polled := requests()
+	done := make(chan error)
+	go func() {
+		_, err := instance.Wait()
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return requests() > polled }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, instance.Stop())
+	assert.Nil(t, as.pod())
+	select {
+	case err := <-done:
+		assert.Contains(t, err.Error(), "was deleted")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait didn't return after the pod was deleted")
+	}
+
+	// stopping twice is a no-op
+	assert.NoError(t, instance.Stop())
+}
+
+func TestResourceLimits(t *testing.T) {
+	l := &Launcher{
+		ResourceLimits: &ccintf.ResourceLimitsConfig{
+			Default: ccintf.ResourceLimits{CPUs: 0.25, Memory: 128 * 1024 * 1024, Pids: 100},
+			Labels: map[string]ccintf.ResourceLimits{
+				"locked": {ReadOnlyRootfs: true, SeccompProfile: "profiles/chaincode.json"},
+				"free":   {SeccompProfile: ccintf.SeccompUnconfined},
+			},
+		},
+	}
+
+	container := &Container{}
+	l.applyResourceLimits("cc:1", container)
+	assert.Equal(t, &Resources{Limits: map[string]string{"cpu": "250m", "memory": "134217728"}}, container.Resources)
+	assert.Nil(t, container.SecurityContext)
+
+	container = &Container{}
+	l.applyResourceLimits("locked:1", container)
+	assert.Equal(t, "250m", container.Resources.Limits["cpu"])
+	require.NotNil(t, container.SecurityContext)
+	assert.True(t, *container.SecurityContext.ReadOnlyRootFilesystem)
+	assert.Equal(t, &SeccompProfile{Type: "Localhost", LocalhostProfile: "profiles/chaincode.json"}, container.SecurityContext.SeccompProfile)
+
+	container = &Container{}
+	l.applyResourceLimits("free:1", container)
+	assert.Nil(t, container.SecurityContext.ReadOnlyRootFilesystem)
+	assert.Equal(t, &SeccompProfile{Type: "Unconfined"}, container.SecurityContext.SeccompProfile)
+
+	container = &Container{}
+	(&Launcher{}).applyResourceLimits("cc:1", container)
+	assert.Equal(t, &Container{}, container)
+}
+
+func TestClientErrors(t *testing.T) {
+	as, server := newAPIServer()
+	defer server.Close()
+	c := &Client{BaseURL: server.URL, HTTPClient: server.Client()}
+
+	_, err := c.GetPod(context.Background(), "fabric", "missing")
+	assert.True(t, IsNotFound(err))
+	assert.EqualError(t, err, "GET /api/v1/namespaces/fabric/pods/missing failed: kubernetes API server returned 404: not found")
+
+	err = c.CreatePod(context.Background(), "other", &Pod{})
+	assert.False(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), "returned 400: bad path")
+	assert.Equal(t, []string{"", ""}, as.tokens)
+
+	_, err = NewClient("", "", "")
+	if err != nil {
+		assert.EqualError(t, err, "the Kubernetes API server is not set and the peer is not running in a pod")
+	}
+
+	_, err = NewClient(server.URL, "/nonexistent/token", "")
+	assert.Contains(t, err.Error(), "failed reading token file /nonexistent/token")
+}
@@ -44,6 +44,10 @@ type DeliverServiceConfig struct {
 	// OrdererEndpointOverrides is a map of orderer addresses which should be
 	// re-mapped to a different orderer endpoint.
 	OrdererEndpointOverrides map[string]*orderers.Endpoint
+
+	// ReplicaSources are the peers from which the blocks are pulled when the peer
+	// is a read-only replica. It is nil unless peer.replica.enabled is set.
+	ReplicaSources ReplicaSources
 }
 
 type AddressOverride struct {
@@ -52,6 +56,12 @@ type AddressOverride struct {
 	CACertsFile string `mapstructure:"caCertsFile"`
 }
 
+// ReplicaSource is a peer from which a read-only replica pulls blocks.
+type ReplicaSource struct {
+	Address     string `mapstructure:"address"`
+	CACertsFile string `mapstructure:"caCertsFile"`
+}
+
 // GlobalConfig obtains a set of configuration from viper, build and returns the config struct.
 func GlobalConfig() *DeliverServiceConfig {
 	c := &DeliverServiceConfig{}
@@ -94,6 +104,46 @@ func LoadOverridesMap() (map[string]*orderers.Endpoint, error) {
 	return overrideMap, nil
 }
 
+// LoadReplicaSources returns the endpoints of the peers from which a read-only replica
+// pulls blocks, or nil if the peer is not a replica.
+func LoadReplicaSources() (ReplicaSources, error) {
+	if !viper.GetBool("peer.replica.enabled") {
+		return nil, nil
+	}
+
+	var sources []ReplicaSource
+	err := viper.UnmarshalKey("peer.replica.sources", &sources)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not unmarshal peer.replica.sources")
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("peer.replica.enabled is set but no peer.replica.sources are configured")
+	}
+
+	var endpoints ReplicaSources
+	for _, source := range sources {
+		if source.Address == "" {
+			return nil, errors.New("the address of a replica source is missing")
+		}
+		certPool := x509.NewCertPool()
+		if source.CACertsFile != "" {
+			pem, err := ioutil.ReadFile(source.CACertsFile)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "could not read caCertsFile of replica source '%s'", source.Address)
+			}
+			if !certPool.AppendCertsFromPEM(pem) {
+				return nil, errors.Errorf("no valid certs found in caCertsFile '%s' of replica source '%s'", source.CACertsFile, source.Address)
+			}
+		}
+		endpoints = append(endpoints, &orderers.Endpoint{
+			Address:  source.Address,
+			CertPool: certPool,
+		})
+	}
+
+	return endpoints, nil
+}
+
 func (c *DeliverServiceConfig) loadDeliverServiceConfig() {
 	c.PeerTLSEnabled = viper.GetBool("peer.tls.enabled")
 
@@ -154,4 +204,11 @@ func (c *DeliverServiceConfig) loadDeliverServiceConfig() {
 	}
 
 	c.OrdererEndpointOverrides = overridesMap
+
+	replicaSources, err := LoadReplicaSources()
+	if err != nil {
+		panic(err)
+	}
+
+	c.ReplicaSources = replicaSources
 }
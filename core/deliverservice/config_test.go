@@ -227,3 +227,94 @@ func TestLoadOverridesMap(t *testing.T) {
 		assert.Nil(t, res)
 	})
 }
+
+func TestLoadReplicaSources(t *testing.T) {
+	defer viper.Reset()
+
+	t.Run("GreenPath", func(t *testing.T) {
+		config := `
+                  peer:
+                    replica:
+                      enabled: true
+                      sources:
+                        - address: peer0:7051
+                          caCertsFile: testdata/cert.pem
+                        - address: peer1:7051
+                `
+
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer([]byte(config)))
+		res, err := deliverservice.LoadReplicaSources()
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "peer0:7051", res[0].Address)
+		assert.Len(t, res[0].CertPool.Subjects(), 1)
+		assert.Equal(t, "peer1:7051", res[1].Address)
+		assert.Empty(t, res[1].CertPool.Subjects())
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		config := `
+                  peer:
+                    replica:
+                      enabled: false
+                      sources:
+                        - address: peer0:7051
+                `
+
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer([]byte(config)))
+		res, err := deliverservice.LoadReplicaSources()
+		require.NoError(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("NoSources", func(t *testing.T) {
+		config := `
+                  peer:
+                    replica:
+                      enabled: true
+                `
+
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer([]byte(config)))
+		_, err := deliverservice.LoadReplicaSources()
+		require.EqualError(t, err, "peer.replica.enabled is set but no peer.replica.sources are configured")
+	})
+
+	t.Run("MissingCAFile", func(t *testing.T) {
+		config := `
+                  peer:
+                    replica:
+                      enabled: true
+                      sources:
+                        - address: peer0:7051
+                          caCertsFile: missing/cert.pem
+                `
+
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer([]byte(config)))
+		_, err := deliverservice.LoadReplicaSources()
+		require.EqualError(t, err, "could not read caCertsFile of replica source 'peer0:7051': open missing/cert.pem: no such file or directory")
+	})
+
+	t.Run("MissingAddress", func(t *testing.T) {
+		config := `
+                  peer:
+                    replica:
+                      enabled: true
+                      sources:
+                        - caCertsFile: testdata/cert.pem
+                `
+
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		viper.ReadConfig(bytes.NewBuffer([]byte(config)))
+		_, err := deliverservice.LoadReplicaSources()
+		require.EqualError(t, err, "the address of a replica source is missing")
+	})
+}
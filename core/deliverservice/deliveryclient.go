@@ -118,8 +118,6 @@ func (d *deliverServiceImpl) StartDeliverForChannel(chainID string, ledgerInfo b
 		logger.Errorf(errMsg)
 		return errors.New(errMsg)
 	}
-	logger.Info("This peer will retrieve blocks from ordering service and disseminate to other peers in the organization for channel", chainID)
-
 	dc := &blocksprovider.Deliverer{
 		ChannelID:     chainID,
 		Gossip:        d.conf.Gossip,
@@ -139,8 +137,19 @@ func (d *deliverServiceImpl) StartDeliverForChannel(chainID string, ledgerInfo b
 		YieldLeadership:   !d.conf.IsStaticLeader,
 	}
 
+	if d.conf.DeliverServiceConfig.ReplicaSources != nil {
+		logger.Info("This peer is a read-only replica and will retrieve blocks from its replica sources for channel", chainID)
+		dc.Orderers = d.conf.DeliverServiceConfig.ReplicaSources
+		dc.DeliverStreamer = PeerDeliverAdapter{}
+		dc.Gossip = replicaGossipAdapter{GossipServiceAdapter: d.conf.Gossip}
+	} else {
+		logger.Info("This peer will retrieve blocks from ordering service and disseminate to other peers in the organization for channel", chainID)
+	}
+
 	if d.conf.DeliverGRPCClient.MutualTLSRequired() {
-		dc.TLSCertHash = util.ComputeSHA256(d.conf.DeliverGRPCClient.Certificate().Certificate[0])
+		dc.TLSCertHash = func() []byte {
+			return util.ComputeSHA256(d.conf.DeliverGRPCClient.Certificate().Certificate[0])
+		}
 	}
 
 	d.blockProviders[chainID] = dc
@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric/core/deliverservice/fake"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/hyperledger/fabric/internal/pkg/peer/blocksprovider"
+	"github.com/hyperledger/fabric/internal/pkg/peer/orderers"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -81,7 +82,7 @@ eUCutqn1KYDMYh54i6p723cXbdDkmvL2UCciHyHdSWS9lmkKVdyNGIJ6
 
 		bp, ok := ds.blockProviders["channel-id"]
 		require.True(t, ok, "map entry must exist")
-		assert.Equal(t, "76f7a03f8dfdb0ef7c4b28b3901fe163c730e906c70e4cdf887054ad5f608bed", fmt.Sprintf("%x", bp.TLSCertHash))
+		assert.Equal(t, "76f7a03f8dfdb0ef7c4b28b3901fe163c730e906c70e4cdf887054ad5f608bed", fmt.Sprintf("%x", bp.TLSCertHash()))
 	})
 
 	t.Run("Green Path without mutual TLS", func(t *testing.T) {
@@ -114,6 +115,23 @@ eUCutqn1KYDMYh54i6p723cXbdDkmvL2UCciHyHdSWS9lmkKVdyNGIJ6
 		assert.Nil(t, bp.TLSCertHash)
 	})
 
+	t.Run("Replica", func(t *testing.T) {
+		replicaSources := ReplicaSources{&orderers.Endpoint{Address: "peer0:7051"}}
+		ds := NewDeliverService(&Config{
+			DeliverGRPCClient:    grpcClient,
+			DeliverServiceConfig: &DeliverServiceConfig{ReplicaSources: replicaSources},
+		}).(*deliverServiceImpl)
+
+		err := ds.StartDeliverForChannel("channel-id", fakeLedgerInfo, func() {})
+		require.NoError(t, err)
+
+		bp, ok := ds.blockProviders["channel-id"]
+		require.True(t, ok, "map entry must exist")
+		assert.Equal(t, replicaSources, bp.Orderers)
+		assert.Equal(t, PeerDeliverAdapter{}, bp.DeliverStreamer)
+		assert.IsType(t, replicaGossipAdapter{}, bp.Gossip)
+	})
+
 	t.Run("Exists", func(t *testing.T) {
 		ds := NewDeliverService(&Config{
 			DeliverGRPCClient:    grpcClient,
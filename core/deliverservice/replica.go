@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliverservice
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/internal/pkg/peer/blocksprovider"
+	"github.com/hyperledger/fabric/internal/pkg/peer/orderers"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// ReplicaSources are the endpoints of the peers from which a read-only
+// replica pulls blocks, instead of pulling them from the ordering service.
+type ReplicaSources []*orderers.Endpoint
+
+// RandomEndpoint returns one of the replica sources at random.
+func (rs ReplicaSources) RandomEndpoint() (*orderers.Endpoint, error) {
+	if len(rs) == 0 {
+		return nil, errors.New("no replica sources currently defined")
+	}
+	return rs[rand.Intn(len(rs))], nil
+}
+
+// PeerDeliverAdapter opens a block stream to the deliver service of a peer
+// and presents the responses of the peer as those of an orderer.
+type PeerDeliverAdapter struct{}
+
+func (PeerDeliverAdapter) Deliver(ctx context.Context, clientConn *grpc.ClientConn) (orderer.AtomicBroadcast_DeliverClient, error) {
+	client, err := peer.NewDeliverClient(clientConn).Deliver(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &peerDeliverClient{Deliver_DeliverClient: client}, nil
+}
+
+type peerDeliverClient struct {
+	peer.Deliver_DeliverClient
+}
+
+func (c *peerDeliverClient) Recv() (*orderer.DeliverResponse, error) {
+	resp, err := c.Deliver_DeliverClient.Recv()
+	if err != nil {
+		return nil, err
+	}
+	switch t := resp.Type.(type) {
+	case *peer.DeliverResponse_Status:
+		return &orderer.DeliverResponse{Type: &orderer.DeliverResponse_Status{Status: t.Status}}, nil
+	case *peer.DeliverResponse_Block:
+		return &orderer.DeliverResponse{Type: &orderer.DeliverResponse_Block{Block: t.Block}}, nil
+	default:
+		return nil, errors.Errorf("unexpected response type %T received from peer", t)
+	}
+}
+
+// replicaGossipAdapter commits the blocks pulled by a read-only replica
+// without disseminating them to other peers.
+type replicaGossipAdapter struct {
+	blocksprovider.GossipServiceAdapter
+}
+
+func (replicaGossipAdapter) Gossip(*gossip.GossipMessage) {}
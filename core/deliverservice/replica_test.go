@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliverservice
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/internal/pkg/peer/orderers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePeerDeliverClient struct {
+	peer.Deliver_DeliverClient
+	responses []*peer.DeliverResponse
+	err       error
+}
+
+func (f *fakePeerDeliverClient) Recv() (*peer.DeliverResponse, error) {
+	if len(f.responses) == 0 {
+		return nil, f.err
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func TestPeerDeliverClientRecv(t *testing.T) {
+	block := &common.Block{Header: &common.BlockHeader{Number: 5}}
+	client := &peerDeliverClient{
+		Deliver_DeliverClient: &fakePeerDeliverClient{
+			responses: []*peer.DeliverResponse{
+				{Type: &peer.DeliverResponse_Block{Block: block}},
+				{Type: &peer.DeliverResponse_Status{Status: common.Status_NOT_FOUND}},
+				{Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: &peer.FilteredBlock{}}},
+			},
+			err: errors.New("stream-error"),
+		},
+	}
+
+	resp, err := client.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, &orderer.DeliverResponse{Type: &orderer.DeliverResponse_Block{Block: block}}, resp)
+
+	resp, err = client.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, &orderer.DeliverResponse{Type: &orderer.DeliverResponse_Status{Status: common.Status_NOT_FOUND}}, resp)
+
+	_, err = client.Recv()
+	assert.EqualError(t, err, "unexpected response type *peer.DeliverResponse_FilteredBlock received from peer")
+
+	_, err = client.Recv()
+	assert.EqualError(t, err, "stream-error")
+}
+
+func TestReplicaSourcesRandomEndpoint(t *testing.T) {
+	_, err := ReplicaSources{}.RandomEndpoint()
+	assert.EqualError(t, err, "no replica sources currently defined")
+
+	source := &orderers.Endpoint{Address: "peer0:7051"}
+	endpoint, err := ReplicaSources{source}.RandomEndpoint()
+	require.NoError(t, err)
+	assert.Equal(t, source, endpoint)
+}
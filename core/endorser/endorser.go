@@ -14,13 +14,18 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/lifecycle"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
 	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	"github.com/hyperledger/fabric/gossip/privdata"
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/protoutil"
@@ -36,7 +41,9 @@ var endorserLogger = flogging.MustGetLogger("endorser")
 //go:generate counterfeiter -o fake/prvt_data_distributor.go --fake-name PrivateDataDistributor . PrivateDataDistributor
 
 type PrivateDataDistributor interface {
-	DistributePrivateData(channel string, txID string, privateData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) error
+	// DistributePrivateData distributes the private data to the eligible peers, and returns
+	// the receipts of those which acknowledged persisting it, for each of the collections
+	DistributePrivateData(channel string, txID string, privateData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) ([]*privdata.DeliveryReceipt, error)
 }
 
 // Support contains functions that the endorser requires to execute its tasks
@@ -100,6 +107,13 @@ type Endorser struct {
 	Support                Support
 	PvtRWSetAssembler      PvtRWSetAssembler
 	Metrics                *Metrics
+	// Tracer records the spans of the endorsement of proposals, if set.
+	Tracer *tracing.Tracer
+	// ReadOnly is set when the peer is a read-only replica, which evaluates
+	// proposals but refuses those whose simulation writes to the ledger.
+	ReadOnly bool
+	// Scheduler, when set, enforces the quotas of the clients sending proposals.
+	Scheduler *Scheduler
 }
 
 // call specified chaincode (system or user)
@@ -205,6 +219,18 @@ func (e *Endorser) SimulateProposal(txParams *ccprovider.TransactionParams, chai
 		return nil, nil, nil, err
 	}
 
+	if e.ReadOnly {
+		writes, err := containsWrites(simResult)
+		if err != nil {
+			e.Metrics.SimulationFailure.With(meterLabels...).Add(1)
+			return nil, nil, nil, err
+		}
+		if writes {
+			e.Metrics.SimulationFailure.With(meterLabels...).Add(1)
+			return nil, nil, nil, errors.New("this peer is a read-only replica and does not endorse transactions that write to the ledger")
+		}
+	}
+
 	if simResult.PvtSimulationResults != nil {
 		if chaincodeName == "lscc" {
 			// TODO: remove once we can store collection configuration outside of LSCC
@@ -231,10 +257,14 @@ func (e *Endorser) SimulateProposal(txParams *ccprovider.TransactionParams, chai
 		// manage transient store purge for orphaned private writesets (4th parameter in distributePrivateData), this works for now.
 		// Ideally, ledger should add support in the simulator as a first class function `GetHeight()`.
 		pvtDataWithConfig.EndorsedAt = endorsedAt
-		if err := e.PrivateDataDistributor.DistributePrivateData(txParams.ChannelID, txParams.TxID, pvtDataWithConfig, endorsedAt); err != nil {
+		receipts, err := e.PrivateDataDistributor.DistributePrivateData(txParams.ChannelID, txParams.TxID, pvtDataWithConfig, endorsedAt)
+		if err != nil {
 			e.Metrics.SimulationFailure.With(meterLabels...).Add(1)
 			return nil, nil, nil, err
 		}
+		for _, receipt := range receipts {
+			endorserLogger.Infof("[%s][%s] Private data delivered: %s", txParams.ChannelID, shorttxid(txParams.TxID), receipt)
+		}
 	}
 
 	pubSimResBytes, err := simResult.GetPubSimulationBytes()
@@ -328,6 +358,14 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, err
 	}
 
+	if e.Scheduler != nil {
+		release, err := e.schedule(ctx, up, addr)
+		if err != nil {
+			return &pb.ProposalResponse{Response: &pb.Response{Status: int32(cb.Status_SERVICE_UNAVAILABLE), Message: err.Error()}}, nil
+		}
+		defer release()
+	}
+
 	defer func() {
 		meterLabels := []string{
 			"channel", up.ChannelHeader.ChannelId,
@@ -337,8 +375,15 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 		e.Metrics.ProposalDuration.With(meterLabels...).Observe(time.Since(startTime).Seconds())
 	}()
 
-	pResp, err := e.ProcessProposalSuccessfullyOrError(up)
+	ctx, span := e.Tracer.Start(ctx, "endorser.ProcessProposal")
+	span.SetAttribute("channel", up.ChannelHeader.ChannelId)
+	span.SetAttribute("chaincode", up.ChaincodeName)
+	span.SetAttribute("txid", up.ChannelHeader.TxId)
+	defer span.End()
+
+	pResp, err := e.ProcessProposalSuccessfullyOrError(ctx, up)
 	if err != nil {
+		span.SetError(err)
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, nil
 	}
 
@@ -354,7 +399,26 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 	return pResp, nil
 }
 
-func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb.ProposalResponse, error) {
+// schedule waits for the turn of the proposal according to the quotas of its creator
+func (e *Endorser) schedule(ctx context.Context, up *UnpackedProposal, addr string) (func(), error) {
+	creator := &mspproto.SerializedIdentity{}
+	if err := proto.Unmarshal(up.SignatureHeader.Creator, creator); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal the creator of the proposal")
+	}
+	release, err := e.Scheduler.Acquire(ctx, e.Scheduler.Key(creator.Mspid, up.SignatureHeader.Creator))
+	switch err {
+	case nil:
+		return release, nil
+	case ErrRateLimited:
+		e.Metrics.ProposalsThrottled.With("channel", up.ChannelID(), "mspid", creator.Mspid, "reason", ThrottleReasonRateLimit).Add(1)
+	case ErrMaxWait:
+		e.Metrics.ProposalsThrottled.With("channel", up.ChannelID(), "mspid", creator.Mspid, "reason", ThrottleReasonMaxWait).Add(1)
+	}
+	endorserLogger.Debugf("[%s][%s] Rejecting proposal from %s of a client of %s: %s", up.ChannelID(), shorttxid(up.TxID()), addr, creator.Mspid, err)
+	return nil, err
+}
+
+func (e *Endorser) ProcessProposalSuccessfullyOrError(ctx context.Context, up *UnpackedProposal) (*pb.ProposalResponse, error) {
 	txParams := &ccprovider.TransactionParams{
 		ChannelID:  up.ChannelHeader.ChannelId,
 		TxID:       up.ChannelHeader.TxId,
@@ -394,7 +458,13 @@ func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb
 	}
 
 	// 1 -- simulate
+	_, simulateSpan := e.Tracer.Start(ctx, "endorser.SimulateProposal")
 	res, simulationResult, ccevent, err := e.SimulateProposal(txParams, up.ChaincodeName, up.Input)
+	simulateSpan.SetError(err)
+	if res != nil {
+		simulateSpan.SetAttribute("status", int(res.Status))
+	}
+	simulateSpan.End()
 	if err != nil {
 		return nil, errors.WithMessage(err, "error in simulation")
 	}
@@ -446,7 +516,10 @@ func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb
 	logger.Debugf("escc for chaincode %s is %s", up.ChaincodeName, escc)
 
 	// Note, mPrpBytes is the same as prpBytes by default endorsement plugin, but others could change it.
+	_, endorseSpan := e.Tracer.Start(ctx, "endorser.EndorseWithPlugin")
 	endorsement, mPrpBytes, err := e.Support.EndorseWithPlugin(escc, up.ChannelID(), prpBytes, up.SignedProposal)
+	endorseSpan.SetError(err)
+	endorseSpan.End()
 	if err != nil {
 		meterLabels = append(meterLabels, "chaincodeerror", strconv.FormatBool(false))
 		e.Metrics.EndorsementsFailed.With(meterLabels...).Add(1)
@@ -461,6 +534,29 @@ func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb
 	}, nil
 }
 
+// containsWrites returns true if the simulation results include writes to the public,
+// private or hashed state, or to the metadata of the state
+func containsWrites(simResult *ledger.TxSimulationResults) (bool, error) {
+	if simResult.ContainsPvtWrites() {
+		return true, nil
+	}
+	txRWSet, err := rwsetutil.TxRwSetFromProtoMsg(simResult.PubSimulationResults)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to unmarshal the simulation results")
+	}
+	for _, nsRWSet := range txRWSet.NsRwSets {
+		if len(nsRWSet.KvRwSet.Writes) > 0 || len(nsRWSet.KvRwSet.MetadataWrites) > 0 {
+			return true, nil
+		}
+		for _, collRWSet := range nsRWSet.CollHashedRwSets {
+			if len(collRWSet.HashedRwSet.HashedWrites) > 0 || len(collRWSet.HashedRwSet.MetadataWrites) > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // determine whether or not a transaction simulator should be
 // obtained for a proposal.
 func acquireTxSimulator(chainID string, chaincodeName string) bool {
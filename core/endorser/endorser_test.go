@@ -16,9 +16,11 @@ import (
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 	mspproto "github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/chaincode/lifecycle"
 	"github.com/hyperledger/fabric/core/endorser"
 	"github.com/hyperledger/fabric/core/endorser/fake"
@@ -40,6 +42,7 @@ var _ = Describe("Endorser", func() {
 		fakeEndorsementsFailed       *metricsfakes.Counter
 		fakeDuplicateTxsFailure      *metricsfakes.Counter
 		fakeSimulateFailure          *metricsfakes.Counter
+		fakeProposalsThrottled       *metricsfakes.Counter
 
 		fakeLocalIdentity                *fake.Identity
 		fakeLocalMSPIdentityDeserializer *fake.IdentityDeserializer
@@ -89,6 +92,9 @@ var _ = Describe("Endorser", func() {
 		fakeSimulateFailure = &metricsfakes.Counter{}
 		fakeSimulateFailure.WithReturns(fakeSimulateFailure)
 
+		fakeProposalsThrottled = &metricsfakes.Counter{}
+		fakeProposalsThrottled.WithReturns(fakeProposalsThrottled)
+
 		fakeLocalIdentity = &fake.Identity{}
 		fakeLocalMSPIdentityDeserializer = &fake.IdentityDeserializer{}
 		fakeLocalMSPIdentityDeserializer.DeserializeIdentityReturns(fakeLocalIdentity, nil)
@@ -173,6 +179,7 @@ var _ = Describe("Endorser", func() {
 				EndorsementsFailed:       fakeEndorsementsFailed,
 				DuplicateTxsFailure:      fakeDuplicateTxsFailure,
 				SimulationFailure:        fakeSimulateFailure,
+				ProposalsThrottled:       fakeProposalsThrottled,
 			},
 			Support:        fakeSupport,
 			ChannelFetcher: fakeChannelFetcher,
@@ -251,6 +258,50 @@ var _ = Describe("Endorser", func() {
 		Expect(ledgerName).To(Equal("channel-id"))
 	})
 
+	Context("when a tracer is set", func() {
+		var exportedSpans []*tracing.Span
+
+		BeforeEach(func() {
+			exportedSpans = nil
+			e.Tracer = tracing.NewTracer(spanRecorder(func(span *tracing.Span) {
+				exportedSpans = append(exportedSpans, span)
+			}), 1)
+		})
+
+		It("records the spans of the endorsement", func() {
+			_, err := e.ProcessProposal(context.Background(), signedProposal)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(exportedSpans).To(HaveLen(3))
+			Expect(exportedSpans[0].Name).To(Equal("endorser.SimulateProposal"))
+			Expect(exportedSpans[0].Attributes()).To(Equal(map[string]interface{}{"status": 200}))
+			Expect(exportedSpans[1].Name).To(Equal("endorser.EndorseWithPlugin"))
+			Expect(exportedSpans[2].Name).To(Equal("endorser.ProcessProposal"))
+			Expect(exportedSpans[2].Attributes()).To(Equal(map[string]interface{}{
+				"channel":   "channel-id",
+				"chaincode": "chaincode-name",
+				"txid":      "6f142589e4ef6a1e62c9c816e2074f70baa9f7cf67c2f0c287d4ef907d6d2015",
+			}))
+			Expect(exportedSpans[0].ParentSpanID).To(Equal(exportedSpans[2].SpanID))
+			Expect(exportedSpans[1].ParentSpanID).To(Equal(exportedSpans[2].SpanID))
+		})
+
+		Context("when the chaincode endorsement fails", func() {
+			BeforeEach(func() {
+				fakeSupport.EndorseWithPluginReturns(nil, nil, fmt.Errorf("fake-endorserment-error"))
+			})
+
+			It("records the error", func() {
+				_, err := e.ProcessProposal(context.Background(), signedProposal)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(exportedSpans).To(HaveLen(3))
+				Expect(exportedSpans[1].Err).To(MatchError("fake-endorserment-error"))
+				Expect(exportedSpans[2].Err).To(MatchError("endorsing with plugin failed: fake-endorserment-error"))
+			})
+		})
+	})
+
 	Context("when the chaincode endorsement fails", func() {
 		BeforeEach(func() {
 			fakeSupport.EndorseWithPluginReturns(nil, nil, fmt.Errorf("fake-endorserment-error"))
@@ -508,7 +559,7 @@ var _ = Describe("Endorser", func() {
 
 	Context("when the private data cannot be distributed", func() {
 		BeforeEach(func() {
-			fakePrivateDataDistributor.DistributePrivateDataReturns(fmt.Errorf("fake-private-data-error"))
+			fakePrivateDataDistributor.DistributePrivateDataReturns(nil, fmt.Errorf("fake-private-data-error"))
 		})
 
 		It("returns a response with the error and no payload", func() {
@@ -523,6 +574,121 @@ var _ = Describe("Endorser", func() {
 		})
 	})
 
+	Context("when the peer is a read-only replica", func() {
+		BeforeEach(func() {
+			e.ReadOnly = true
+			fakeTxSimulator.GetTxSimulationResultsReturns(
+				&ledger.TxSimulationResults{
+					PubSimulationResults: &rwset.TxReadWriteSet{
+						NsRwset: []*rwset.NsReadWriteSet{
+							{
+								Namespace: "chaincode-name",
+								Rwset: protoutil.MarshalOrPanic(&kvrwset.KVRWSet{
+									Reads: []*kvrwset.KVRead{{Key: "key"}},
+								}),
+							},
+						},
+					},
+				},
+				nil,
+			)
+		})
+
+		It("endorses the proposals that do not write", func() {
+			proposalResponse, err := e.ProcessProposal(context.Background(), signedProposal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposalResponse.Response.Status).To(Equal(int32(200)))
+			Expect(proposalResponse.Endorsement).NotTo(BeNil())
+		})
+
+		Context("when the simulation writes to the public state", func() {
+			BeforeEach(func() {
+				fakeTxSimulator.GetTxSimulationResultsReturns(
+					&ledger.TxSimulationResults{
+						PubSimulationResults: &rwset.TxReadWriteSet{
+							NsRwset: []*rwset.NsReadWriteSet{
+								{
+									Namespace: "chaincode-name",
+									Rwset: protoutil.MarshalOrPanic(&kvrwset.KVRWSet{
+										Writes: []*kvrwset.KVWrite{{Key: "key", Value: []byte("value")}},
+									}),
+								},
+							},
+						},
+					},
+					nil,
+				)
+			})
+
+			It("returns a response with the error and no payload", func() {
+				proposalResponse, err := e.ProcessProposal(context.Background(), signedProposal)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proposalResponse.Payload).To(BeNil())
+				Expect(proposalResponse.Response).To(Equal(&pb.Response{
+					Status:  500,
+					Message: "error in simulation: this peer is a read-only replica and does not endorse transactions that write to the ledger",
+				}))
+				Expect(fakeSimulateFailure.AddCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the simulation writes private data", func() {
+			BeforeEach(func() {
+				fakeTxSimulator.GetTxSimulationResultsReturns(
+					&ledger.TxSimulationResults{
+						PubSimulationResults: &rwset.TxReadWriteSet{},
+						PvtSimulationResults: &rwset.TxPvtReadWriteSet{},
+					},
+					nil,
+				)
+			})
+
+			It("does not distribute the private data", func() {
+				proposalResponse, err := e.ProcessProposal(context.Background(), signedProposal)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proposalResponse.Response.Status).To(Equal(int32(500)))
+				Expect(fakePrivateDataDistributor.DistributePrivateDataCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when the endorser enforces client quotas", func() {
+		BeforeEach(func() {
+			var err error
+			e.Scheduler, err = endorser.NewScheduler(endorser.SchedulerConfig{
+				Scope:              endorser.OrgScope,
+				ProposalsPerSecond: 1,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("endorses the proposals within the quotas", func() {
+			proposalResponse, err := e.ProcessProposal(context.Background(), signedProposal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposalResponse.Response.Status).To(Equal(int32(200)))
+			Expect(fakeProposalsThrottled.AddCallCount()).To(Equal(0))
+		})
+
+		It("rejects the proposals beyond the quotas", func() {
+			_, err := e.ProcessProposal(context.Background(), signedProposal)
+			Expect(err).NotTo(HaveOccurred())
+
+			proposalResponse, err := e.ProcessProposal(context.Background(), signedProposal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposalResponse.Response).To(Equal(&pb.Response{
+				Status:  503,
+				Message: "rate limit exceeded",
+			}))
+			Expect(fakeSupport.ExecuteCallCount()).To(Equal(1))
+			Expect(fakeProposalsThrottled.AddCallCount()).To(Equal(1))
+			Expect(fakeProposalsThrottled.WithArgsForCall(0)).To(Equal([]string{
+				"channel", "channel-id",
+				"mspid", "msp-id",
+				"reason", "rate_limit",
+			}))
+		})
+	})
+
 	It("checks the block height", func() {
 		_, err := e.ProcessProposal(context.Background(), signedProposal)
 		Expect(err).NotTo(HaveOccurred())
@@ -1021,3 +1187,7 @@ var _ = Describe("Endorser", func() {
 		})
 	})
 })
+
+type spanRecorder func(*tracing.Span)
+
+func (s spanRecorder) Export(span *tracing.Span) { s(span) }
@@ -6,10 +6,11 @@ import (
 
 	"github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/core/endorser"
+	"github.com/hyperledger/fabric/gossip/privdata"
 )
 
 type PrivateDataDistributor struct {
-	DistributePrivateDataStub        func(string, string, *transientstore.TxPvtReadWriteSetWithConfigInfo, uint64) error
+	DistributePrivateDataStub        func(string, string, *transientstore.TxPvtReadWriteSetWithConfigInfo, uint64) ([]*privdata.DeliveryReceipt, error)
 	distributePrivateDataMutex       sync.RWMutex
 	distributePrivateDataArgsForCall []struct {
 		arg1 string
@@ -18,16 +19,18 @@ type PrivateDataDistributor struct {
 		arg4 uint64
 	}
 	distributePrivateDataReturns struct {
-		result1 error
+		result1 []*privdata.DeliveryReceipt
+		result2 error
 	}
 	distributePrivateDataReturnsOnCall map[int]struct {
-		result1 error
+		result1 []*privdata.DeliveryReceipt
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *PrivateDataDistributor) DistributePrivateData(arg1 string, arg2 string, arg3 *transientstore.TxPvtReadWriteSetWithConfigInfo, arg4 uint64) error {
+func (fake *PrivateDataDistributor) DistributePrivateData(arg1 string, arg2 string, arg3 *transientstore.TxPvtReadWriteSetWithConfigInfo, arg4 uint64) ([]*privdata.DeliveryReceipt, error) {
 	fake.distributePrivateDataMutex.Lock()
 	ret, specificReturn := fake.distributePrivateDataReturnsOnCall[len(fake.distributePrivateDataArgsForCall)]
 	fake.distributePrivateDataArgsForCall = append(fake.distributePrivateDataArgsForCall, struct {
@@ -42,10 +45,10 @@ func (fake *PrivateDataDistributor) DistributePrivateData(arg1 string, arg2 stri
 		return fake.DistributePrivateDataStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
 	fakeReturns := fake.distributePrivateDataReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *PrivateDataDistributor) DistributePrivateDataCallCount() int {
@@ -54,7 +57,7 @@ func (fake *PrivateDataDistributor) DistributePrivateDataCallCount() int {
 	return len(fake.distributePrivateDataArgsForCall)
 }
 
-func (fake *PrivateDataDistributor) DistributePrivateDataCalls(stub func(string, string, *transientstore.TxPvtReadWriteSetWithConfigInfo, uint64) error) {
+func (fake *PrivateDataDistributor) DistributePrivateDataCalls(stub func(string, string, *transientstore.TxPvtReadWriteSetWithConfigInfo, uint64) ([]*privdata.DeliveryReceipt, error)) {
 	fake.distributePrivateDataMutex.Lock()
 	defer fake.distributePrivateDataMutex.Unlock()
 	fake.DistributePrivateDataStub = stub
@@ -67,27 +70,30 @@ func (fake *PrivateDataDistributor) DistributePrivateDataArgsForCall(i int) (str
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
-func (fake *PrivateDataDistributor) DistributePrivateDataReturns(result1 error) {
+func (fake *PrivateDataDistributor) DistributePrivateDataReturns(result1 []*privdata.DeliveryReceipt, result2 error) {
 	fake.distributePrivateDataMutex.Lock()
 	defer fake.distributePrivateDataMutex.Unlock()
 	fake.DistributePrivateDataStub = nil
 	fake.distributePrivateDataReturns = struct {
-		result1 error
-	}{result1}
+		result1 []*privdata.DeliveryReceipt
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *PrivateDataDistributor) DistributePrivateDataReturnsOnCall(i int, result1 error) {
+func (fake *PrivateDataDistributor) DistributePrivateDataReturnsOnCall(i int, result1 []*privdata.DeliveryReceipt, result2 error) {
 	fake.distributePrivateDataMutex.Lock()
 	defer fake.distributePrivateDataMutex.Unlock()
 	fake.DistributePrivateDataStub = nil
 	if fake.distributePrivateDataReturnsOnCall == nil {
 		fake.distributePrivateDataReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 []*privdata.DeliveryReceipt
+			result2 error
 		})
 	}
 	fake.distributePrivateDataReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 []*privdata.DeliveryReceipt
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *PrivateDataDistributor) Invocations() map[string][][]interface{} {
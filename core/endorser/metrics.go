@@ -74,6 +74,14 @@ var (
 		LabelNames:   []string{"channel", "chaincode"},
 		StatsdFormat: "%{#fqname}.%{channel}.%{chaincode}",
 	}
+
+	throttledProposalsCounterOpts = metrics.CounterOpts{
+		Namespace:    "endorser",
+		Name:         "proposals_throttled",
+		Help:         "The number of proposals rejected for exceeding the quotas of their clients.",
+		LabelNames:   []string{"channel", "mspid", "reason"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{mspid}.%{reason}",
+	}
 )
 
 type Metrics struct {
@@ -86,6 +94,7 @@ type Metrics struct {
 	EndorsementsFailed       metrics.Counter
 	DuplicateTxsFailure      metrics.Counter
 	SimulationFailure        metrics.Counter
+	ProposalsThrottled       metrics.Counter
 }
 
 func NewMetrics(p metrics.Provider) *Metrics {
@@ -99,5 +108,6 @@ func NewMetrics(p metrics.Provider) *Metrics {
 		EndorsementsFailed:       p.NewCounter(endorsementFailureCounterOpts),
 		DuplicateTxsFailure:      p.NewCounter(duplicateTxsFailureCounterOpts),
 		SimulationFailure:        p.NewCounter(simulationFailureCounterOpts),
+		ProposalsThrottled:       p.NewCounter(throttledProposalsCounterOpts),
 	}
 }
@@ -31,6 +31,7 @@ func TestNewMetrics(t *testing.T) {
 		EndorsementsFailed:       &metricsfakes.Counter{},
 		DuplicateTxsFailure:      &metricsfakes.Counter{},
 		SimulationFailure:        &metricsfakes.Counter{},
+		ProposalsThrottled:       &metricsfakes.Counter{},
 	}))
 
 	gt.Expect(provider.NewHistogramCallCount()).To(Equal(1))
@@ -38,7 +39,7 @@ func TestNewMetrics(t *testing.T) {
 		{proposalDurationHistogramOpts},
 	}))
 
-	gt.Expect(provider.NewCounterCallCount()).To(Equal(8))
+	gt.Expect(provider.NewCounterCallCount()).To(Equal(9))
 	gt.Expect(provider.Invocations()["NewCounter"]).To(ConsistOf([][]interface{}{
 		{receivedProposalsCounterOpts},
 		{successfulProposalsCounterOpts},
@@ -48,5 +49,6 @@ func TestNewMetrics(t *testing.T) {
 		{endorsementFailureCounterOpts},
 		{duplicateTxsFailureCounterOpts},
 		{simulationFailureCounterOpts},
+		{throttledProposalsCounterOpts},
 	}))
 }
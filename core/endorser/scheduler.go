@@ -0,0 +1,255 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ClientScope applies the quotas to each client identity.
+	ClientScope = "Client"
+	// OrgScope applies the quotas to all the clients of an organization together.
+	OrgScope = "Org"
+
+	// idleBucketTimeout is the time after which the token bucket of a client
+	// which has not sent any proposal is discarded.
+	idleBucketTimeout = time.Minute
+)
+
+// The reasons proposals are throttled for.
+const (
+	ThrottleReasonRateLimit = "rate_limit"
+	ThrottleReasonMaxWait   = "max_wait"
+)
+
+// ErrRateLimited is returned when a client exceeds its rate of proposals.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrMaxWait is returned when a proposal waited too long for its turn.
+var ErrMaxWait = errors.New("timed out waiting for an endorsement slot")
+
+// SchedulerConfig configures the quotas of the clients of the endorser.
+type SchedulerConfig struct {
+	// Scope is either ClientScope or OrgScope.
+	Scope string
+	// ProposalsPerSecond is the rate of proposals of a client, beyond which its
+	// proposals are rejected. A client may exceed it for a short burst of up to
+	// one second worth of proposals. Zero leaves the rate unlimited.
+	ProposalsPerSecond uint32
+	// Concurrency is the number of proposals processed concurrently. Proposals
+	// beyond it wait for their turn, which is given to the clients in turns so
+	// that a busy client does not starve the others. Zero leaves it unlimited.
+	Concurrency int
+	// MaxWait is the time a proposal may wait for its turn before it is
+	// rejected. Zero waits until the client gives up.
+	MaxWait time.Duration
+}
+
+// Scheduler enforces per-client quotas on the proposals processed by the endorser:
+// a rate limit for each client, and a concurrency limit shared fairly among them.
+type Scheduler struct {
+	conf SchedulerConfig
+	tps  float64
+	now  func() time.Time
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+	running   int
+	// queues holds the proposals waiting for their turn, by client
+	queues map[string][]*waiter
+	// turns lists the clients with waiting proposals in the order they are served
+	turns []string
+}
+
+// tokenBucket holds the proposals a client may still send
+type tokenBucket struct {
+	proposals  float64
+	lastUpdate time.Time
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewScheduler creates a Scheduler with the given quotas.
+func NewScheduler(conf SchedulerConfig) (*Scheduler, error) {
+	if conf.Scope != ClientScope && conf.Scope != OrgScope {
+		return nil, errors.Errorf("unknown quota scope %s, expected %s or %s", conf.Scope, ClientScope, OrgScope)
+	}
+	if conf.Concurrency < 0 {
+		return nil, errors.Errorf("invalid concurrency %d, must not be negative", conf.Concurrency)
+	}
+	if conf.MaxWait < 0 {
+		return nil, errors.Errorf("invalid max wait %s, must not be negative", conf.MaxWait)
+	}
+	return &Scheduler{
+		conf:      conf,
+		tps:       float64(conf.ProposalsPerSecond),
+		now:       time.Now,
+		buckets:   map[string]*tokenBucket{},
+		lastPurge: time.Now(),
+		queues:    map[string][]*waiter{},
+	}, nil
+}
+
+// Key returns the key the quotas of the given client are tracked by, which is
+// its serialized identity or its MSP ID depending on the scope.
+func (s *Scheduler) Key(mspID string, identity []byte) string {
+	if s.conf.Scope == OrgScope {
+		return mspID
+	}
+	return string(identity)
+}
+
+// Acquire waits for the turn of a proposal of the given client, and returns a
+// function that must be called once the proposal is processed. It fails with
+// ErrRateLimited if the client exceeds its rate, with ErrMaxWait if the
+// proposal waits longer than allowed, or with the error of the context.
+func (s *Scheduler) Acquire(ctx context.Context, client string) (release func(), err error) {
+	s.mutex.Lock()
+	if !s.allow(client) {
+		s.mutex.Unlock()
+		return nil, ErrRateLimited
+	}
+	if s.conf.Concurrency == 0 {
+		s.mutex.Unlock()
+		return func() {}, nil
+	}
+	if s.running < s.conf.Concurrency && len(s.turns) == 0 {
+		s.running++
+		s.mutex.Unlock()
+		return s.release, nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(s.queues[client]) == 0 {
+		s.turns = append(s.turns, client)
+	}
+	s.queues[client] = append(s.queues[client], w)
+	s.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if s.conf.MaxWait > 0 {
+		timer := time.NewTimer(s.conf.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-w.ready:
+		return s.release, nil
+	case <-timeout:
+		err = ErrMaxWait
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if w.granted {
+		// the turn came concurrently with giving up, so pass it on
+		s.next()
+		return nil, err
+	}
+	s.dequeue(client, w)
+	return nil, err
+}
+
+// release gives the turn of a finished proposal to the next client
+func (s *Scheduler) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.next()
+}
+
+// next hands the slot of a finished proposal to the first proposal of the client
+// whose turn it is, and moves the client to the end of the turns. Without waiting
+// proposals the slot is freed. The caller must hold the mutex.
+func (s *Scheduler) next() {
+	if len(s.turns) == 0 {
+		s.running--
+		return
+	}
+	client := s.turns[0]
+	s.turns = s.turns[1:]
+	queue := s.queues[client]
+	w := queue[0]
+	if len(queue) == 1 {
+		delete(s.queues, client)
+	} else {
+		s.queues[client] = queue[1:]
+		s.turns = append(s.turns, client)
+	}
+	w.granted = true
+	close(w.ready)
+}
+
+// dequeue removes a proposal that gave up waiting. The caller must hold the mutex.
+func (s *Scheduler) dequeue(client string, w *waiter) {
+	queue := s.queues[client]
+	for i, queued := range queue {
+		if queued == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) != 0 {
+		s.queues[client] = queue
+		return
+	}
+	delete(s.queues, client)
+	for i, c := range s.turns {
+		if c == client {
+			s.turns = append(s.turns[:i:i], s.turns[i+1:]...)
+			break
+		}
+	}
+}
+
+// allow takes a token from the bucket of the client, if the rate is limited.
+// The caller must hold the mutex.
+func (s *Scheduler) allow(client string) bool {
+	if s.tps == 0 {
+		return true
+	}
+	now := s.now()
+	s.purge(now)
+	bucket, ok := s.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{proposals: s.tps, lastUpdate: now}
+		s.buckets[client] = bucket
+	}
+	bucket.proposals += s.tps * now.Sub(bucket.lastUpdate).Seconds()
+	if bucket.proposals > s.tps {
+		bucket.proposals = s.tps
+	}
+	bucket.lastUpdate = now
+	if bucket.proposals < 1 {
+		return false
+	}
+	bucket.proposals--
+	return true
+}
+
+// purge discards the token buckets of the clients which have been idle for long
+// enough for their buckets to refill
+func (s *Scheduler) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < idleBucketTimeout {
+		return
+	}
+	s.lastPurge = now
+	for client, bucket := range s.buckets {
+		if now.Sub(bucket.lastUpdate) >= idleBucketTimeout {
+			delete(s.buckets, client)
+		}
+	}
+}
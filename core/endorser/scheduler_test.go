@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScheduler(t *testing.T) {
+	_, err := NewScheduler(SchedulerConfig{Scope: "Channel"})
+	require.EqualError(t, err, "unknown quota scope Channel, expected Client or Org")
+
+	_, err = NewScheduler(SchedulerConfig{Scope: ClientScope, Concurrency: -1})
+	require.EqualError(t, err, "invalid concurrency -1, must not be negative")
+
+	_, err = NewScheduler(SchedulerConfig{Scope: ClientScope, MaxWait: -time.Second})
+	require.EqualError(t, err, "invalid max wait -1s, must not be negative")
+
+	s, err := NewScheduler(SchedulerConfig{Scope: ClientScope})
+	require.NoError(t, err)
+	require.Equal(t, "identity", s.Key("Org1MSP", []byte("identity")))
+
+	s, err = NewScheduler(SchedulerConfig{Scope: OrgScope})
+	require.NoError(t, err)
+	require.Equal(t, "Org1MSP", s.Key("Org1MSP", []byte("identity")))
+}
+
+func TestSchedulerRateLimit(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{Scope: ClientScope, ProposalsPerSecond: 2})
+	require.NoError(t, err)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	acquire := func(client string) error {
+		release, err := s.Acquire(context.Background(), client)
+		if err == nil {
+			release()
+		}
+		return err
+	}
+
+	// a burst of one second worth of proposals is allowed
+	require.NoError(t, acquire("alice"))
+	require.NoError(t, acquire("alice"))
+	require.Equal(t, ErrRateLimited, acquire("alice"))
+
+	// other clients have their own quota
+	require.NoError(t, acquire("bob"))
+
+	// the quota refills over time
+	now = now.Add(500 * time.Millisecond)
+	require.NoError(t, acquire("alice"))
+	require.Equal(t, ErrRateLimited, acquire("alice"))
+
+	// idle clients are purged
+	now = now.Add(idleBucketTimeout)
+	require.NoError(t, acquire("alice"))
+	require.Len(t, s.buckets, 1)
+}
+
+func TestSchedulerFairness(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{Scope: ClientScope, Concurrency: 1})
+	require.NoError(t, err)
+
+	release, err := s.Acquire(context.Background(), "alice")
+	require.NoError(t, err)
+
+	// alice queues three proposals before bob and carol queue one each
+	granted := make(chan string, 5)
+	waitFor := func(client string, queued int) {
+		go func() {
+			release, err := s.Acquire(context.Background(), client)
+			require.NoError(t, err)
+			granted <- client
+			release()
+		}()
+		require.Eventually(t, func() bool {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			n := 0
+			for _, queue := range s.queues {
+				n += len(queue)
+			}
+			return n == queued
+		}, time.Second, time.Millisecond)
+	}
+	waitFor("alice", 1)
+	waitFor("alice", 2)
+	waitFor("alice", 3)
+	waitFor("bob", 4)
+	waitFor("carol", 5)
+
+	release()
+	var order []string
+	for i := 0; i < 5; i++ {
+		order = append(order, <-granted)
+	}
+	require.Equal(t, []string{"alice", "bob", "carol", "alice", "alice"}, order)
+	require.Eventually(t, func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.running == 0
+	}, time.Second, time.Millisecond)
+	require.Empty(t, s.turns)
+	require.Empty(t, s.queues)
+}
+
+func TestSchedulerMaxWait(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{Scope: ClientScope, Concurrency: 1, MaxWait: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	release, err := s.Acquire(context.Background(), "alice")
+	require.NoError(t, err)
+
+	_, err = s.Acquire(context.Background(), "bob")
+	require.Equal(t, ErrMaxWait, err)
+	require.Empty(t, s.turns)
+	require.Empty(t, s.queues)
+
+	// the slot is freed once the running proposal is released
+	release()
+	release, err = s.Acquire(context.Background(), "bob")
+	require.NoError(t, err)
+	release()
+	require.Equal(t, 0, s.running)
+}
+
+func TestSchedulerCanceled(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{Scope: ClientScope, Concurrency: 1})
+	require.NoError(t, err)
+
+	release, err := s.Acquire(context.Background(), "alice")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Acquire(ctx, "alice")
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, s.turns)
+	require.Empty(t, s.queues)
+
+	release()
+	require.Equal(t, 0, s.running)
+}
+
+func TestSchedulerUnlimitedConcurrency(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{Scope: OrgScope})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.Acquire(context.Background(), "Org1MSP")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, s.running)
+}
@@ -12,9 +12,11 @@ import (
 	"os"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/cauthdsl"
 	commonerrors "github.com/hyperledger/fabric/common/errors"
 	"github.com/hyperledger/fabric/common/policydsl"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/v14"
@@ -25,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/msp"
 	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
 	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/hyperledger/fabric/pkg/chaincode/statebased"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -243,6 +246,47 @@ func TestToApplicationPolicyTranslator_Translate(t *testing.T) {
 	}))
 }
 
+func TestKeyPolicyEvaluation(t *testing.T) {
+	data := []byte("endorsed response")
+	signature, err := id.Sign(data)
+	assert.NoError(t, err)
+	signatureSet := []*protoutil.SignedData{{Data: data, Identity: sid, Signature: signature}}
+
+	evaluate := func(kp *statebased.KeyPolicy) error {
+		policy, err := kp.Policy()
+		assert.NoError(t, err)
+		translated, err := (&toApplicationPolicyTranslator{}).Translate(policy)
+		assert.NoError(t, err)
+		ap := &peer.ApplicationPolicy{}
+		assert.NoError(t, proto.Unmarshal(translated, ap))
+		pp := &cauthdsl.EnvelopeBasedPolicyProvider{Deserializer: mspmgmt.GetManagerForChain(channelID)}
+		p, err := pp.NewPolicy(ap.GetSignaturePolicy())
+		assert.NoError(t, err)
+		return p.EvaluateSignedData(signatureSet)
+	}
+
+	kp, err := statebased.NewKeyPolicy(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, kp.AddOrgs(statebased.RoleMember, mspid))
+	assert.NoError(t, kp.AddOrgs(statebased.RolePeer, "Org2MSP", "Org3MSP"))
+
+	// all organizations are required by default
+	assert.Error(t, evaluate(kp))
+
+	// one out of three is satisfied by our endorsement
+	assert.NoError(t, kp.RequireN(1))
+	assert.NoError(t, evaluate(kp))
+
+	// but not two out of three
+	assert.NoError(t, kp.RequireN(2))
+	assert.Error(t, evaluate(kp))
+
+	// nor one out of the other organizations
+	kp.DelOrgs(mspid)
+	assert.NoError(t, kp.RequireN(1))
+	assert.Error(t, evaluate(kp))
+}
+
 var id msp.SigningIdentity
 var sid []byte
 var mspid string
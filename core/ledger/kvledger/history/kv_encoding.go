@@ -22,6 +22,7 @@ var (
 	compositeKeySep = []byte{0x00} // used as a separator between different components of dataKey
 	dataKeyPrefix   = []byte{'d'}  // prefix added to dataKeys
 	savePointKey    = []byte{'s'}  // a single key in db for persisting savepoint
+	pruneInfoKey    = []byte{'p'}  // a single key in db for persisting the block number below which the history is pruned
 	emptyValue      = []byte{}     // used to store as value for keys where only key needs to be stored (e.g., dataKeys)
 )
 
@@ -39,6 +40,28 @@ func constructDataKey(ns string, key string, blocknum uint64, trannum uint64) da
 	return dataKey(k)
 }
 
+// decodeDataKey decodes a key constructed by the function constructDataKey. The returned prefix is the
+// part namespace~len(key)~key~ that is common to all the history entries of the key
+func decodeDataKey(k dataKey) ([]byte, uint64, error) {
+	nsEnd := bytes.Index(k, compositeKeySep)
+	if nsEnd < 0 {
+		return nil, 0, errors.Errorf("invalid history data key [%#v]", []byte(k))
+	}
+	keyLen, keyLenBytesConsumed, err := util.DecodeOrderPreservingVarUint64(k[nsEnd+1:])
+	if err != nil {
+		return nil, 0, errors.WithMessagef(err, "invalid history data key [%#v]", []byte(k))
+	}
+	prefixLen := nsEnd + 1 + keyLenBytesConsumed + int(keyLen) + 1
+	if prefixLen > len(k) {
+		return nil, 0, errors.Errorf("invalid history data key [%#v]", []byte(k))
+	}
+	blockNum, _, err := util.DecodeOrderPreservingVarUint64(k[prefixLen:])
+	if err != nil {
+		return nil, 0, errors.WithMessagef(err, "invalid history data key [%#v]", []byte(k))
+	}
+	return k[:prefixLen], blockNum, nil
+}
+
 // constructRangescanKeys returns start and endKey for performing a range scan
 // that covers all the keys for <ns, key>.
 // startKey = namespace~len(key)~key~
@@ -56,3 +56,18 @@ func TestSplitCompositeKey(t *testing.T) {
 	assert.Equal(t, blkNum, uint64(20))
 	assert.Equal(t, txNum, uint64(200))
 }
+
+func TestDecodeDataKey(t *testing.T) {
+	for _, key := range []string{"key1", "", "key1\x00", "\x00key\x00\x001"} {
+		dataKey := constructDataKey("ns1", key, 300, 2)
+		prefix, blkNum, err := decodeDataKey(dataKey)
+		assert.NoError(t, err)
+		assert.Equal(t, constructRangeScan("ns1", key).startKey, prefix)
+		assert.Equal(t, uint64(300), blkNum)
+	}
+
+	_, _, err := decodeDataKey(dataKey("ns1"))
+	assert.EqualError(t, err, "invalid history data key [[]byte{0x6e, 0x73, 0x31}]")
+	_, _, err = decodeDataKey(dataKey("ns1\x00\x01\x10key"))
+	assert.EqualError(t, err, "invalid history data key [[]byte{0x6e, 0x73, 0x31, 0x0, 0x1, 0x10, 0x6b, 0x65, 0x79}]")
+}
@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package history
+
+import (
+	"bytes"
+
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/pkg/errors"
+)
+
+var maxPruneBatchSize = 10000
+
+// Prune removes the history entries that correspond to the transactions in the blocks below the given
+// block number and returns the number of the removed entries. As a safeguard, the latest entry of each key is
+// retained irrespective of its block number, so that the history of a key always includes the latest
+// modification of the key. Further, the history cannot be pruned beyond the last committed block.
+// The pruning does not block the commits as the commits add the history entries only for the newer blocks
+func (d *DB) Prune(belowBlockNum uint64) (uint64, error) {
+	d.pruneLock.Lock()
+	defer d.pruneLock.Unlock()
+
+	savepoint, err := d.GetLastSavepoint()
+	if err != nil {
+		return 0, err
+	}
+	if savepoint == nil {
+		return 0, errors.Errorf("cannot prune the history below block [%d] as no block has been committed to the history", belowBlockNum)
+	}
+	if belowBlockNum > savepoint.BlockNum {
+		return 0, errors.Errorf("cannot prune the history below block [%d] as the last block committed to the history is [%d]",
+			belowBlockNum, savepoint.BlockNum)
+	}
+	prunedBelow, err := d.PrunedBelow()
+	if err != nil {
+		return 0, err
+	}
+	if belowBlockNum <= prunedBelow {
+		logger.Debugf("Channel [%s]: history is already pruned below block [%d]", d.name, prunedBelow)
+		return 0, nil
+	}
+
+	logger.Infof("Channel [%s]: pruning the history below block [%d]", d.name, belowBlockNum)
+	dbItr := d.levelDB.GetIterator(nil, nil)
+	defer dbItr.Release()
+	dbBatch := leveldbhelper.NewUpdateBatch()
+	numPruned := uint64(0)
+	// pendingKey holds the previous history entry that falls in the pruned range and is removed only if
+	// the next entry in the iteration belongs to the same key
+	var pendingKey, pendingPrefix []byte
+	for dbItr.Next() {
+		k := dbItr.Key()
+		if bytes.Equal(k, savePointKey) || bytes.Equal(k, pruneInfoKey) {
+			continue
+		}
+		prefix, blockNum, err := decodeDataKey(k)
+		if err != nil {
+			return numPruned, err
+		}
+		if pendingKey != nil && bytes.Equal(prefix, pendingPrefix) {
+			dbBatch.Delete(pendingKey)
+			numPruned++
+		}
+		pendingKey, pendingPrefix = nil, nil
+		if blockNum < belowBlockNum {
+			pendingKey = append([]byte{}, k...)
+			pendingPrefix = pendingKey[:len(prefix)]
+		}
+		if dbBatch.Len() >= maxPruneBatchSize {
+			if err := d.levelDB.WriteBatch(dbBatch, true); err != nil {
+				return numPruned, err
+			}
+			dbBatch = leveldbhelper.NewUpdateBatch()
+		}
+	}
+	if err := dbItr.Error(); err != nil {
+		return numPruned, errors.Wrap(err, "internal leveldb error while pruning the history")
+	}
+	dbBatch.Put(pruneInfoKey, util.EncodeOrderPreservingVarUint64(belowBlockNum))
+	if err := d.levelDB.WriteBatch(dbBatch, true); err != nil {
+		return numPruned, err
+	}
+	logger.Infof("Channel [%s]: pruned [%d] history entries below block [%d]", d.name, numPruned, belowBlockNum)
+	return numPruned, nil
+}
+
+// PrunedBelow returns the block number below which the history has been pruned. The history for
+// the blocks below this block number includes only the latest modification of the keys
+func (d *DB) PrunedBelow() (uint64, error) {
+	b, err := d.levelDB.Get(pruneInfoKey)
+	if err != nil || b == nil {
+		return 0, err
+	}
+	blockNum, _, err := util.DecodeOrderPreservingVarUint64(b)
+	if err != nil {
+		return 0, errors.WithMessage(err, "error decoding the history prune info")
+	}
+	return blockNum, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package history
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	env := newTestHistoryEnv(t)
+	defer env.cleanup()
+	db := env.testHistoryDB
+
+	_, err := db.Prune(1)
+	require.EqualError(t, err, "cannot prune the history below block [1] as no block has been committed to the history")
+
+	type entry struct {
+		ns, key           string
+		blockNum, tranNum uint64
+	}
+	entries := []entry{
+		{"ns1", "key1", 1, 0},
+		{"ns1", "key1", 2, 1},
+		{"ns1", "key1", 5, 0},
+		{"ns1", "key2", 1, 1},
+		{"ns1", "key2", 3, 0},
+		{"ns1", "key3", 2, 0},
+		{"ns2", "key1", 4, 0},
+		{"ns2", "key1\x00", 1, 0},
+		{"ns2", "key1\x00", 6, 0},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.levelDB.Put(constructDataKey(e.ns, e.key, e.blockNum, e.tranNum), emptyValue, true))
+	}
+	require.NoError(t, db.levelDB.Put(savePointKey, version.NewHeight(6, 1).ToBytes(), true))
+
+	remainingEntries := func() []entry {
+		dbKeys := map[string]bool{}
+		itr := db.levelDB.GetIterator(nil, nil)
+		defer itr.Release()
+		for itr.Next() {
+			dbKeys[string(itr.Key())] = true
+		}
+		var remaining []entry
+		for _, e := range entries {
+			if dbKeys[string(constructDataKey(e.ns, e.key, e.blockNum, e.tranNum))] {
+				remaining = append(remaining, e)
+			}
+		}
+		return remaining
+	}
+
+	_, err = db.Prune(7)
+	require.EqualError(t, err, "cannot prune the history below block [7] as the last block committed to the history is [6]")
+
+	numPruned, err := db.Prune(3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), numPruned)
+	prunedBelow, err := db.PrunedBelow()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), prunedBelow)
+	// the latest entry of a key is retained even if it falls in the pruned range
+	require.ElementsMatch(t,
+		[]entry{
+			{"ns1", "key1", 5, 0},
+			{"ns1", "key2", 3, 0},
+			{"ns1", "key3", 2, 0},
+			{"ns2", "key1", 4, 0},
+			{"ns2", "key1\x00", 6, 0},
+		},
+		remainingEntries(),
+	)
+
+	// pruning below an already pruned block is a noop
+	numPruned, err = db.Prune(2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), numPruned)
+	prunedBelow, err = db.PrunedBelow()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), prunedBelow)
+
+	numPruned, err = db.Prune(6)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), numPruned)
+	prunedBelow, err = db.PrunedBelow()
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), prunedBelow)
+	require.Len(t, remainingEntries(), 5)
+}
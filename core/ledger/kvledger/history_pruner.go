@@ -0,0 +1,186 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/history"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// historyPruner prunes the history database on demand and, if configured, periodically in the background
+// after every `Interval` blocks, as per the configured retention limits
+type historyPruner struct {
+	ledgerID   string
+	historyDB  *history.DB
+	blockStore *blkstorage.BlockStore
+	conf       *ledger.HistoryPruneConfig
+	stats      *ledgerStats
+	now        func() time.Time
+
+	running int32
+	wg      sync.WaitGroup
+}
+
+func newHistoryPruner(
+	ledgerID string,
+	historyDB *history.DB,
+	blockStore *blkstorage.BlockStore,
+	conf *ledger.HistoryPruneConfig,
+	stats *ledgerStats,
+) *historyPruner {
+	if conf == nil {
+		conf = &ledger.HistoryPruneConfig{}
+	}
+	return &historyPruner{
+		ledgerID:   ledgerID,
+		historyDB:  historyDB,
+		blockStore: blockStore,
+		conf:       conf,
+		stats:      stats,
+		now:        time.Now,
+	}
+}
+
+func (p *historyPruner) periodicPruningEnabled() bool {
+	return p.conf.Interval > 0 && (p.conf.RetainBlocks > 0 || p.conf.RetentionPeriod > 0)
+}
+
+// blockCommitted starts a background pruning run if the committed block is at the configured interval.
+// A run is skipped if the previous run is still in progress
+func (p *historyPruner) blockCommitted(blockNum uint64) {
+	if !p.periodicPruningEnabled() || blockNum == 0 || blockNum%p.conf.Interval != 0 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&p.running, 0, 1) {
+		logger.Debugf("[%s] Skipping the history pruning at block [%d] as the previous pruning is in progress", p.ledgerID, blockNum)
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer atomic.StoreInt32(&p.running, 0)
+		if err := p.pruneAsPerRetentionLimits(blockNum); err != nil {
+			logger.Errorf("[%s] Error while pruning the history database: %s", p.ledgerID, err)
+		}
+	}()
+}
+
+func (p *historyPruner) pruneAsPerRetentionLimits(lastBlockNum uint64) error {
+	belowBlockNum, err := p.pruneTarget(lastBlockNum)
+	if err != nil {
+		return err
+	}
+	if belowBlockNum == 0 {
+		return nil
+	}
+	return p.prune(belowBlockNum)
+}
+
+// pruneTarget returns the block number below which the history falls outside of all the configured
+// retention limits. The history of the last committed block is never pruned
+func (p *historyPruner) pruneTarget(lastBlockNum uint64) (uint64, error) {
+	target := lastBlockNum
+	if p.conf.RetainBlocks > 0 {
+		if lastBlockNum+1 <= p.conf.RetainBlocks {
+			return 0, nil
+		}
+		if t := lastBlockNum + 1 - p.conf.RetainBlocks; t < target {
+			target = t
+		}
+	}
+	if p.conf.RetentionPeriod > 0 {
+		t, err := p.firstBlockNotBefore(p.now().Add(-p.conf.RetentionPeriod), target)
+		if err != nil {
+			return 0, err
+		}
+		target = t
+	}
+	return target, nil
+}
+
+// firstBlockNotBefore returns the lowest block number, not exceeding the given upper limit, such that the
+// timestamp of the block is not before the given cutoff time. The search starts from the block below which the
+// history is already pruned and assumes that the block timestamps are non-decreasing
+func (p *historyPruner) firstBlockNotBefore(cutoff time.Time, upperLimit uint64) (uint64, error) {
+	prunedBelow, err := p.historyDB.PrunedBelow()
+	if err != nil {
+		return 0, err
+	}
+	if prunedBelow >= upperLimit {
+		return upperLimit, nil
+	}
+	var searchErr error
+	i := sort.Search(int(upperLimit-prunedBelow), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		ts, err := p.blockTimestamp(prunedBelow + uint64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return !ts.Before(cutoff)
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	return prunedBelow + uint64(i), nil
+}
+
+func (p *historyPruner) blockTimestamp(blockNum uint64) (time.Time, error) {
+	return blockTimestamp(p.blockStore, blockNum)
+}
+
+// blockTimestamp returns the timestamp in the channel header of the first transaction in the block
+func blockTimestamp(blockStore *blkstorage.BlockStore, blockNum uint64) (time.Time, error) {
+	block, err := blockStore.RetrieveBlockByNumber(blockNum)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if block.Data == nil || len(block.Data.Data) == 0 {
+		return time.Time{}, errors.Errorf("block [%d] does not contain any transaction", blockNum)
+	}
+	env, err := protoutil.GetEnvelopeFromBlock(block.Data.Data[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if payload.Header == nil {
+		return time.Time{}, errors.Errorf("the first transaction in block [%d] does not contain a header", blockNum)
+	}
+	chdr, err := protoutil.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if chdr.Timestamp == nil {
+		return time.Time{}, errors.Errorf("the first transaction in block [%d] does not carry a timestamp", blockNum)
+	}
+	return time.Unix(chdr.Timestamp.Seconds, int64(chdr.Timestamp.Nanos)), nil
+}
+
+func (p *historyPruner) prune(belowBlockNum uint64) error {
+	start := time.Now()
+	numPruned, err := p.historyDB.Prune(belowBlockNum)
+	p.stats.updateHistoryPruneStats(numPruned, time.Since(start))
+	return err
+}
+
+// close waits for the in-progress pruning run, if any
+func (p *historyPruner) close() {
+	p.wg.Wait()
+}
@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/util"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryPruning(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.HistoryDBConfig.Prune = &lgr.HistoryPruneConfig{
+		RetainBlocks: 2,
+		Interval:     2,
+	}
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+
+	// each block updates the key "key1" and adds a new key
+	for i := 1; i <= 5; i++ {
+		commitHistoryTestBlock(t, kvl, bg, "key1", fmt.Sprintf("key%d", i+1))
+		kvl.historyPruner.close()
+	}
+	// the periodic pruning at block 4 prunes the history below block 3
+	prunedBelow, err := kvl.historyDB.PrunedBelow()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), prunedBelow)
+	require.Equal(t, 3, historyCountForTest(t, kvl, "key1"))
+	require.Equal(t, 1, historyCountForTest(t, kvl, "key2"))
+
+	require.NoError(t, kvl.PruneHistory(5))
+	require.Equal(t, 1, historyCountForTest(t, kvl, "key1"))
+	require.Equal(t, 1, historyCountForTest(t, kvl, "key6"))
+	require.EqualError(t, kvl.PruneHistory(6), "cannot prune the history below block [6] as the last block committed to the history is [5]")
+}
+
+func TestHistoryPruneTarget(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+	for i := 1; i <= 5; i++ {
+		// ensure distinct timestamps for the blocks
+		time.Sleep(2 * time.Millisecond)
+		commitHistoryTestBlock(t, kvl, bg, "key1")
+	}
+	block3Time, err := kvl.historyPruner.blockTimestamp(3)
+	require.NoError(t, err)
+
+	p := kvl.historyPruner
+	require.False(t, p.periodicPruningEnabled())
+
+	tests := []struct {
+		conf           *lgr.HistoryPruneConfig
+		now            time.Time
+		expectedTarget uint64
+	}{
+		{&lgr.HistoryPruneConfig{RetainBlocks: 2}, time.Now(), 4},
+		{&lgr.HistoryPruneConfig{RetainBlocks: 6}, time.Now(), 0},
+		{&lgr.HistoryPruneConfig{RetainBlocks: 10}, time.Now(), 0},
+		{&lgr.HistoryPruneConfig{RetentionPeriod: time.Hour}, block3Time.Add(time.Hour), 3},
+		{&lgr.HistoryPruneConfig{RetentionPeriod: time.Hour}, time.Now().Add(-time.Hour), 0},
+		{&lgr.HistoryPruneConfig{RetentionPeriod: time.Hour}, time.Now().Add(2 * time.Hour), 5},
+		// the history is pruned only outside of both the limits
+		{&lgr.HistoryPruneConfig{RetainBlocks: 4, RetentionPeriod: time.Hour}, block3Time.Add(time.Hour), 2},
+		{&lgr.HistoryPruneConfig{RetainBlocks: 1, RetentionPeriod: time.Hour}, block3Time.Add(time.Hour), 3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p.conf = test.conf
+			p.now = func() time.Time { return test.now }
+			target, err := p.pruneTarget(5)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedTarget, target)
+		})
+	}
+
+	// the search for the retention period starts from the block below which the history is already pruned
+	require.NoError(t, kvl.PruneHistory(4))
+	p.conf = &lgr.HistoryPruneConfig{RetentionPeriod: time.Hour}
+	p.now = func() time.Time { return block3Time.Add(time.Hour) }
+	target, err := p.pruneTarget(5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), target)
+}
+
+func TestPruneHistoryWithHistoryDisabled(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.HistoryDBConfig.Enabled = false
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	_, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	require.EqualError(t, l.(*kvLedger).PruneHistory(1), "history database not enabled")
+}
+
+func commitHistoryTestBlock(t *testing.T, l *kvLedger, bg *testutil.BlockGenerator, keys ...string) {
+	simulator, err := l.NewTxSimulator(util.GenerateUUID())
+	require.NoError(t, err)
+	for _, k := range keys {
+		require.NoError(t, simulator.SetState("ns1", k, []byte("value")))
+	}
+	simulator.Done()
+	simRes, err := simulator.GetTxSimulationResults()
+	require.NoError(t, err)
+	pubSimBytes, err := simRes.GetPubSimulationBytes()
+	require.NoError(t, err)
+	require.NoError(t, l.CommitLegacy(&lgr.BlockAndPvtData{Block: bg.NextBlock([][]byte{pubSimBytes})}, &lgr.CommitOptions{}))
+}
+
+func historyCountForTest(t *testing.T, l *kvLedger, key string) int {
+	qe, err := l.NewHistoryQueryExecutor()
+	require.NoError(t, err)
+	itr, err := qe.GetHistoryForKey("ns1", key)
+	require.NoError(t, err)
+	defer itr.Close()
+	count := 0
+	for {
+		result, err := itr.Next()
+		require.NoError(t, err)
+		if result == nil {
+			return count
+		}
+		count++
+	}
+}
@@ -8,6 +8,7 @@ package kvledger
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/history"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr/lockbasedtxmgr"
 	"github.com/hyperledger/fabric/core/ledger/pvtdatapolicy"
@@ -41,22 +43,36 @@ var (
 	rwsetHashOpts = &bccsp.SHA256Opts{}
 )
 
+// storageSizeSamplingInterval is the minimum time between two estimations of the sizes of the
+// state db and of the private data store of a ledger
+const storageSizeSamplingInterval = time.Minute
+
 // kvLedger provides an implementation of `ledger.PeerLedger`.
 // This implementation provides a key-value based data model
 type kvLedger struct {
 	ledgerID               string
 	blockStore             *blkstorage.BlockStore
 	pvtdataStore           *pvtdatastorage.Store
+	stateDB                *privacyenabledstate.DB
 	txtmgmt                txmgr.TxMgr
 	historyDB              *history.DB
+	historyPruner          *historyPruner
+	pvtdataTTLPurger       *pvtdataTTLPurger
+	stateCheckpointer      *stateCheckpointer
 	configHistoryRetriever *confighistory.Retriever
 	blockAPIsRWLock        *sync.RWMutex
-	stats                  *ledgerStats
-	commitHash             []byte
+	// pvtdataPurgeLock serializes the purging of private keys with the commits of the private data of old blocks
+	pvtdataPurgeLock sync.Mutex
+	stats            *ledgerStats
+	commitHash       []byte
 	// isPvtDataStoreAheadOfBlockStore is read during missing pvtData
 	// reconciliation and may be updated during a regular block commit.
 	// Hence, we use atomic value to ensure consistent read.
 	isPvtstoreAheadOfBlkstore atomic.Value
+	// storageSizesSampled is the time the sizes of the state db and of the private
+	// data store were last sampled, and samplingStorageSizes is set while sampling
+	storageSizesSampled  time.Time
+	samplingStorageSizes int32
 }
 
 type lgrInitializer struct {
@@ -65,6 +81,9 @@ type lgrInitializer struct {
 	pvtdataStore             *pvtdatastorage.Store
 	stateDB                  *privacyenabledstate.DB
 	historyDB                *history.DB
+	historyPruneConfig       *ledger.HistoryPruneConfig
+	pvtdataTTLConfig         *ledger.PvtDataTTLConfig
+	stateCheckpointer        *stateCheckpointer
 	configHistoryMgr         confighistory.Mgr
 	stateListeners           []ledger.StateListener
 	bookkeeperProvider       bookkeeping.Provider
@@ -79,11 +98,13 @@ func newKVLedger(initializer *lgrInitializer) (*kvLedger, error) {
 	ledgerID := initializer.ledgerID
 	logger.Debugf("Creating KVLedger ledgerID=%s: ", ledgerID)
 	l := &kvLedger{
-		ledgerID:        ledgerID,
-		blockStore:      initializer.blockStore,
-		pvtdataStore:    initializer.pvtdataStore,
-		historyDB:       initializer.historyDB,
-		blockAPIsRWLock: &sync.RWMutex{},
+		ledgerID:          ledgerID,
+		blockStore:        initializer.blockStore,
+		pvtdataStore:      initializer.pvtdataStore,
+		stateDB:           initializer.stateDB,
+		historyDB:         initializer.historyDB,
+		stateCheckpointer: initializer.stateCheckpointer,
+		blockAPIsRWLock:   &sync.RWMutex{},
 	}
 
 	btlPolicy := pvtdatapolicy.ConstructBTLPolicy(&collectionInfoRetriever{ledgerID, l, initializer.ccInfoProvider})
@@ -126,8 +147,13 @@ func newKVLedger(initializer *lgrInitializer) (*kvLedger, error) {
 	ccEventListener := initializer.stateDB.GetChaincodeEventListener()
 	logger.Debugf("Register state db for chaincode lifecycle events: %t", ccEventListener != nil)
 	if ccEventListener != nil {
-		cceventmgmt.GetMgr().Register(ledgerID, ccEventListener)
-		initializer.ccLifecycleEventProvider.RegisterListener(ledgerID, &ccEventListenerAdaptor{ccEventListener})
+		// the event sources may not be set up when the ledger is used outside of a peer, for instance, in tests
+		if ccEventMgr := cceventmgmt.GetMgr(); ccEventMgr != nil {
+			ccEventMgr.Register(ledgerID, ccEventListener)
+		}
+		if initializer.ccLifecycleEventProvider != nil {
+			initializer.ccLifecycleEventProvider.RegisterListener(ledgerID, &ccEventListenerAdaptor{ccEventListener})
+		}
 	}
 
 	//Recover both state DB and history DB if they are out of sync with block storage
@@ -137,6 +163,11 @@ func newKVLedger(initializer *lgrInitializer) (*kvLedger, error) {
 	l.configHistoryRetriever = initializer.configHistoryMgr.GetRetriever(ledgerID, l)
 
 	l.stats = initializer.stats
+	if l.historyDB != nil {
+		l.historyPruner = newHistoryPruner(ledgerID, l.historyDB, l.blockStore, initializer.historyPruneConfig, l.stats)
+	}
+	l.pvtdataTTLPurger = newPvtdataTTLPurger(ledgerID, initializer.stateDB, l.blockStore, l.purgePrivateKeys, initializer.pvtdataTTLConfig, l.stats)
+	l.pvtdataTTLPurger.start()
 	return l, nil
 }
 
@@ -493,6 +524,14 @@ func (l *kvLedger) CommitLegacy(pvtdataAndBlock *ledger.BlockAndPvtData, commitO
 		if err := l.historyDB.Commit(block); err != nil {
 			panic(errors.WithMessage(err, "Error during commit to history db"))
 		}
+		l.historyPruner.blockCommitted(blockNo)
+	}
+
+	// a checkpoint is generated while holding the lock so that the state does not move past the block
+	if l.stateCheckpointer != nil {
+		if err := l.stateCheckpointer.blockCommitted(block, l.commitHash); err != nil {
+			logger.Errorf("[%s] Error while generating the state checkpoint for block [%d]: %s", l.ledgerID, blockNo, err)
+		}
 	}
 
 	logger.Infof("[%s] Committed block [%d] with %d transaction(s) in %dms (state_validation=%dms block_and_pvtdata_commit=%dms state_commit=%dms)"+
@@ -510,9 +549,40 @@ func (l *kvLedger) CommitLegacy(pvtdataAndBlock *ledger.BlockAndPvtData, commitO
 		elapsedCommitState,
 		txstatsInfo,
 	)
+	l.stats.updateBlockCommitTime(time.Since(startBlockProcessing))
+	l.sampleStorageSizes()
 	return nil
 }
 
+// sampleStorageSizes updates the metrics of the sizes of the state db and of the private data
+// store, at most once per storageSizeSamplingInterval. The sizes are estimated in the background,
+// as estimating the size of a CouchDB state db takes a request per database
+func (l *kvLedger) sampleStorageSizes() {
+	now := time.Now()
+	if now.Sub(l.storageSizesSampled) < storageSizeSamplingInterval ||
+		!atomic.CompareAndSwapInt32(&l.samplingStorageSizes, 0, 1) {
+		return
+	}
+	l.storageSizesSampled = now
+	go func() {
+		defer atomic.StoreInt32(&l.samplingStorageSizes, 0)
+		if estimator, ok := l.stateDB.VersionedDB.(statedb.SizeEstimator); ok {
+			size, err := estimator.EstimateSize()
+			if err != nil {
+				logger.Debugf("[%s] Error estimating the size of the state database: %s", l.ledgerID, err)
+			} else {
+				l.stats.updateStatedbSize(size)
+			}
+		}
+		size, err := l.pvtdataStore.ApproximateSize()
+		if err != nil {
+			logger.Debugf("[%s] Error estimating the size of the private data store: %s", l.ledgerID, err)
+			return
+		}
+		l.stats.updatePvtdataStoreSize(size)
+	}()
+}
+
 func (l *kvLedger) commitToPvtAndBlockStore(blockAndPvtdata *ledger.BlockAndPvtData) error {
 	pvtdataStoreHt, err := l.pvtdataStore.LastCommittedBlockHeight()
 	if err != nil {
@@ -660,11 +730,19 @@ func (l *kvLedger) CommitPvtDataOfOldBlocks(reconciledPvtdata []*ledger.Reconcil
 	logger.Debugf("[%s:] Comparing pvtData of [%d] old blocks against the hashes in transaction's rwset to find valid and invalid data",
 		l.ledgerID, len(reconciledPvtdata))
 
+	l.pvtdataPurgeLock.Lock()
+	defer l.pvtdataPurgeLock.Unlock()
+
 	hashVerifiedPvtData, hashMismatches, err := constructValidAndInvalidPvtData(reconciledPvtdata, l.blockStore)
 	if err != nil {
 		return nil, err
 	}
 
+	logger.Debugf("[%s:] Removing the purged private keys from the pvtData of old blocks", l.ledgerID)
+	if err := l.pvtdataStore.RemovePurgedKeys(hashVerifiedPvtData); err != nil {
+		return nil, err
+	}
+
 	err = l.applyValidTxPvtDataOfOldBlocks(hashVerifiedPvtData)
 	if err != nil {
 		return nil, err
@@ -704,8 +782,48 @@ func (l *kvLedger) GetMissingPvtDataTracker() (ledger.MissingPvtDataTracker, err
 	return l, nil
 }
 
+// PurgePrivateData purges a private key from the given collections of a namespace on this peer, for instance,
+// to comply with a request for the erasure of personal data. The key is removed from the private data of all
+// the committed blocks and from the private state, whereas the hashes of the key and the value are retained.
+// A purged key is not restored when the missing private data of the committed blocks is reconciled later
+func (l *kvLedger) PurgePrivateData(ns string, colls []string, key string) error {
+	return l.purgePrivateKeys(ns, colls, []string{key}, math.MaxUint64)
+}
+
+// purgePrivateKeys purges the given private keys from the given collections of a namespace, retaining the writes
+// of these keys in the blocks committed after the given block number, both in the private data store and the state
+func (l *kvLedger) purgePrivateKeys(ns string, colls []string, keys []string, tillBlk uint64) error {
+	l.pvtdataPurgeLock.Lock()
+	defer l.pvtdataPurgeLock.Unlock()
+
+	purgedTillBlk, err := l.pvtdataStore.PurgePrivateKeys(ns, colls, keys, tillBlk)
+	if err != nil {
+		return err
+	}
+	logger.Infof("[%s] Purging [%d] private keys of namespace [%s] from the state database", l.ledgerID, len(keys), ns)
+	for _, key := range keys {
+		if err := l.txtmgmt.PurgePrivateKey(ns, colls, key, purgedTillBlk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneHistory removes the history entries that correspond to the transactions in the blocks below the given
+// block number, retaining the latest modification of each key
+func (l *kvLedger) PruneHistory(belowBlockNum uint64) error {
+	if l.historyPruner == nil {
+		return errors.New("history database not enabled")
+	}
+	return l.historyPruner.prune(belowBlockNum)
+}
+
 // Close closes `KVLedger`
 func (l *kvLedger) Close() {
+	l.pvtdataTTLPurger.stop()
+	if l.historyPruner != nil {
+		l.historyPruner.close()
+	}
 	l.blockStore.Shutdown()
 	l.txtmgmt.Shutdown()
 }
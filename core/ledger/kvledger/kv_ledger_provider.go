@@ -9,6 +9,7 @@ package kvledger
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-protos-go/common"
@@ -75,6 +76,7 @@ type Provider struct {
 	stats                *stats
 	fileLock             *leveldbhelper.FileLock
 	hasher               ledger.Hasher
+	checkpointSchedule   checkpointSchedule
 }
 
 // NewProvider instantiates a new Provider.
@@ -107,6 +109,10 @@ func NewProvider(initializer *ledger.Initializer) (pr *Provider, e error) {
 
 	p.fileLock = fileLock
 
+	if err := p.initStateCheckpointSchedule(); err != nil {
+		return nil, err
+	}
+
 	if err := p.initLedgerIDInventory(); err != nil {
 		return nil, err
 	}
@@ -142,6 +148,22 @@ func NewProvider(initializer *ledger.Initializer) (pr *Provider, e error) {
 	return p, nil
 }
 
+func (p *Provider) initStateCheckpointSchedule() error {
+	conf := p.initializer.Config.StateCheckpointConfig
+	if conf == nil || conf.Schedule == "" {
+		return nil
+	}
+	schedule, err := parseCheckpointSchedule(conf.Schedule)
+	if err != nil {
+		return errors.WithMessage(err, "invalid state checkpoint configuration")
+	}
+	if schedule.next(time.Now()).IsZero() {
+		return errors.Errorf("invalid state checkpoint configuration: the schedule [%s] is never due", conf.Schedule)
+	}
+	p.checkpointSchedule = schedule
+	return nil
+}
+
 func (p *Provider) initLedgerIDInventory() error {
 	idStore, err := openIDStore(LedgerProviderPath(p.initializer.Config.RootFSPath))
 	if err != nil {
@@ -153,11 +175,12 @@ func (p *Provider) initLedgerIDInventory() error {
 
 func (p *Provider) initBlockStoreProvider() error {
 	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: attrsToIndex}
+	blkStoreConf, err := blockStoreConf(p.initializer.Config)
+	if err != nil {
+		return err
+	}
 	blkStoreProvider, err := blkstorage.NewProvider(
-		blkstorage.NewConf(
-			BlockStorePath(p.initializer.Config.RootFSPath),
-			maxBlockFileSize,
-		),
+		blkStoreConf,
 		indexConfig,
 		p.initializer.MetricsProvider,
 	)
@@ -168,6 +191,30 @@ func (p *Provider) initBlockStoreProvider() error {
 	return nil
 }
 
+func blockStoreConf(config *ledger.Config) (*blkstorage.Conf, error) {
+	blockStorePath := BlockStorePath(config.RootFSPath)
+	if config.BlockStoreConfig == nil {
+		return blkstorage.NewConf(blockStorePath, maxBlockFileSize), nil
+	}
+	var conf *blkstorage.Conf
+	switch config.BlockStoreConfig.Compression {
+	case "":
+		conf = blkstorage.NewConf(blockStorePath, maxBlockFileSize)
+	case "zstd":
+		conf = blkstorage.NewConfWithCompression(blockStorePath, maxBlockFileSize)
+	default:
+		return nil, errors.Errorf("unsupported block compression [%s]", config.BlockStoreConfig.Compression)
+	}
+	if archive := config.BlockStoreConfig.Archive; archive != nil && archive.Path != "" {
+		conf = conf.WithArchive(&blkstorage.ArchiveConf{
+			Store:        blkstorage.NewFileSystemArchiveStore(archive.Path),
+			RetainBlocks: archive.RetainBlocks,
+			CacheSize:    archive.CacheSize,
+		})
+	}
+	return conf, nil
+}
+
 func (p *Provider) initPvtDataStoreProvider() error {
 	privateDataConfig := &pvtdatastorage.PrivateDataConfig{
 		PrivateDataConfig: p.initializer.Config.PrivateDataConfig,
@@ -236,6 +283,7 @@ func (p *Provider) initStateDBProvider() error {
 	stateDB := &privacyenabledstate.StateDBConfig{
 		StateDBConfig: p.initializer.Config.StateDBConfig,
 		LevelDBPath:   StateDBPath(p.initializer.Config.RootFSPath),
+		Encryptor:     p.initializer.Encryptor,
 	}
 	sysNamespaces := p.initializer.DeployedChaincodeInfoProvider.Namespaces()
 	p.dbProvider, err = privacyenabledstate.NewDBProvider(
@@ -333,12 +381,31 @@ func (p *Provider) open(ledgerID string) (ledger.PeerLedger, error) {
 		}
 	}
 
+	stateDBType := "goleveldb"
+	if p.initializer.Config.StateDBConfig.StateDatabase == "CouchDB" {
+		stateDBType = "CouchDB"
+	} else if len(p.initializer.Config.StateDBConfig.CouchDBNamespaces[ledgerID]) > 0 {
+		stateDBType = "goleveldb+CouchDB"
+	}
+	stateCheckpointer := newStateCheckpointer(
+		ledgerID,
+		StateCheckpointsPath(p.initializer.Config.RootFSPath),
+		db,
+		stateDBType,
+		p.initializer.Config.StateCheckpointConfig,
+		p.checkpointSchedule,
+		p.stats.ledgerStats(ledgerID),
+	)
+
 	initializer := &lgrInitializer{
 		ledgerID:                 ledgerID,
 		blockStore:               blockStore,
 		pvtdataStore:             pvtdataStore,
 		stateDB:                  db,
 		historyDB:                historyDB,
+		historyPruneConfig:       p.initializer.Config.HistoryDBConfig.Prune,
+		pvtdataTTLConfig:         p.initializer.Config.PrivateDataConfig.TimeToLive,
+		stateCheckpointer:        stateCheckpointer,
 		configHistoryMgr:         p.configHistoryMgr,
 		stateListeners:           p.stateListeners,
 		bookkeeperProvider:       p.bookkeepingProvider,
@@ -301,6 +301,54 @@ func TestLedgerProviderHistoryDBDisabled(t *testing.T) {
 
 }
 
+func TestLedgerProviderBlockCompression(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.BlockStoreConfig = &lgr.BlockStoreConfig{Compression: "zstd"}
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	genesisBlock, _ := configtxtest.MakeGenesisBlock(constructTestLedgerID(0))
+	_, err := provider.Create(genesisBlock)
+	require.NoError(t, err)
+	provider.Close()
+
+	conf.BlockStoreConfig.Compression = ""
+	provider = testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+	ledger, err := provider.Open(constructTestLedgerID(0))
+	require.NoError(t, err)
+	defer ledger.Close()
+	block, err := ledger.GetBlockByNumber(0)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(genesisBlock, block), "proto messages are not equal")
+
+	conf.BlockStoreConfig.Compression = "lz4"
+	_, err = blockStoreConf(conf)
+	require.EqualError(t, err, "unsupported block compression [lz4]")
+}
+
+func TestLedgerProviderBlockArchival(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	archiveDir, err := ioutil.TempDir("", "blockarchive")
+	require.NoError(t, err)
+	defer os.RemoveAll(archiveDir)
+	conf.BlockStoreConfig = &lgr.BlockStoreConfig{
+		Archive: &lgr.BlockArchiveConfig{
+			Path:         archiveDir,
+			RetainBlocks: 10,
+		},
+	}
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+	genesisBlock, _ := configtxtest.MakeGenesisBlock(constructTestLedgerID(0))
+	ledger, err := provider.Create(genesisBlock)
+	require.NoError(t, err)
+	defer ledger.Close()
+	block, err := ledger.GetBlockByNumber(0)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(genesisBlock, block), "proto messages are not equal")
+}
+
 func TestRecovery(t *testing.T) {
 	conf, cleanup := testConfig(t)
 	defer cleanup()
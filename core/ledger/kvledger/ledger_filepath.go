@@ -48,3 +48,8 @@ func ConfigHistoryDBPath(rootFSPath string) string {
 func BookkeeperDBPath(rootFSPath string) string {
 	return filepath.Join(rootFSPath, "bookkeeper")
 }
+
+// StateCheckpointsPath returns the absolute path of the directory that holds the state checkpoints
+func StateCheckpointsPath(rootFSPath string) string {
+	return filepath.Join(rootFSPath, "stateCheckpoints")
+}
@@ -15,17 +15,35 @@ import (
 
 type stats struct {
 	blockProcessingTime            metrics.Histogram
+	blockCommitTime                metrics.Histogram
 	blockAndPvtdataStoreCommitTime metrics.Histogram
 	statedbCommitTime              metrics.Histogram
 	transactionsCount              metrics.Counter
+	historyPruneTime               metrics.Histogram
+	historyPrunedEntries           metrics.Counter
+	pvtdataTTLPurgedKeys           metrics.Counter
+	stateCheckpointTime            metrics.Histogram
+	stateCheckpointsGenerated      metrics.Counter
+	lastStateCheckpointBlock       metrics.Gauge
+	statedbSize                    metrics.Gauge
+	pvtdataStoreSize               metrics.Gauge
 }
 
 func newStats(metricsProvider metrics.Provider) *stats {
 	stats := &stats{}
 	stats.blockProcessingTime = metricsProvider.NewHistogram(blockProcessingTimeOpts)
+	stats.blockCommitTime = metricsProvider.NewHistogram(blockCommitTimeOpts)
 	stats.blockAndPvtdataStoreCommitTime = metricsProvider.NewHistogram(blockAndPvtdataStoreCommitTimeOpts)
 	stats.statedbCommitTime = metricsProvider.NewHistogram(statedbCommitTimeOpts)
 	stats.transactionsCount = metricsProvider.NewCounter(transactionCountOpts)
+	stats.historyPruneTime = metricsProvider.NewHistogram(historyPruneTimeOpts)
+	stats.historyPrunedEntries = metricsProvider.NewCounter(historyPrunedEntriesOpts)
+	stats.pvtdataTTLPurgedKeys = metricsProvider.NewCounter(pvtdataTTLPurgedKeysOpts)
+	stats.stateCheckpointTime = metricsProvider.NewHistogram(stateCheckpointTimeOpts)
+	stats.stateCheckpointsGenerated = metricsProvider.NewCounter(stateCheckpointsGeneratedOpts)
+	stats.lastStateCheckpointBlock = metricsProvider.NewGauge(lastStateCheckpointBlockOpts)
+	stats.statedbSize = metricsProvider.NewGauge(statedbSizeOpts)
+	stats.pvtdataStoreSize = metricsProvider.NewGauge(pvtdataStoreSizeOpts)
 	return stats
 }
 
@@ -44,6 +62,18 @@ func (s *ledgerStats) updateBlockProcessingTime(timeTaken time.Duration) {
 	s.stats.blockProcessingTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
 }
 
+func (s *ledgerStats) updateBlockCommitTime(timeTaken time.Duration) {
+	s.stats.blockCommitTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
+}
+
+func (s *ledgerStats) updateStatedbSize(size int64) {
+	s.stats.statedbSize.With("channel", s.ledgerid).Set(float64(size))
+}
+
+func (s *ledgerStats) updatePvtdataStoreSize(size int64) {
+	s.stats.pvtdataStoreSize.With("channel", s.ledgerid).Set(float64(size))
+}
+
 func (s *ledgerStats) updateBlockstorageAndPvtdataCommitTime(timeTaken time.Duration) {
 	s.stats.blockAndPvtdataStoreCommitTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
 }
@@ -52,6 +82,27 @@ func (s *ledgerStats) updateStatedbCommitTime(timeTaken time.Duration) {
 	s.stats.statedbCommitTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
 }
 
+func (s *ledgerStats) updateHistoryPruneStats(numPruned uint64, timeTaken time.Duration) {
+	s.stats.historyPruneTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
+	s.stats.historyPrunedEntries.With("channel", s.ledgerid).Add(float64(numPruned))
+}
+
+func (s *ledgerStats) updatePvtdataTTLPurgeStats(numPurged int) {
+	s.stats.pvtdataTTLPurgedKeys.With("channel", s.ledgerid).Add(float64(numPurged))
+}
+
+func (s *ledgerStats) updateStateCheckpointStats(blockNum uint64, timeTaken time.Duration, succeeded bool) {
+	status := "success"
+	if !succeeded {
+		status = "failure"
+	}
+	s.stats.stateCheckpointsGenerated.With("channel", s.ledgerid, "status", status).Add(1)
+	if succeeded {
+		s.stats.stateCheckpointTime.With("channel", s.ledgerid).Observe(timeTaken.Seconds())
+		s.stats.lastStateCheckpointBlock.With("channel", s.ledgerid).Set(float64(blockNum))
+	}
+}
+
 func (s *ledgerStats) updateTransactionsStats(
 	txstatsInfo []*txmgr.TxStatInfo,
 ) {
@@ -86,6 +137,16 @@ var (
 		Buckets:      []float64{0.005, 0.01, 0.015, 0.05, 0.1, 1, 10},
 	}
 
+	blockCommitTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "block_commit_time",
+		Help:         "Time taken in seconds for committing a block, from the start of its validation to the end of its commit to all the databases.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.005, 0.01, 0.015, 0.05, 0.1, 1, 10},
+	}
+
 	blockAndPvtdataStoreCommitTimeOpts = metrics.HistogramOpts{
 		Namespace:    "ledger",
 		Subsystem:    "",
@@ -114,4 +175,78 @@ var (
 		LabelNames:   []string{"channel", "transaction_type", "chaincode", "validation_code"},
 		StatsdFormat: "%{#fqname}.%{channel}.%{transaction_type}.%{chaincode}.%{validation_code}",
 	}
+
+	historyPruneTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "history_prune_time",
+		Help:         "Time taken in seconds for pruning the history database.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.1, 1, 10, 60, 300, 900},
+	}
+
+	historyPrunedEntriesOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "history_pruned_entries",
+		Help:         "Number of entries pruned from the history database.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	pvtdataTTLPurgedKeysOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "pvtdata_ttl_purged_keys",
+		Help:         "Number of private keys purged after their time-to-live.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	stateCheckpointTimeOpts = metrics.HistogramOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "state_checkpoint_time",
+		Help:         "Time taken in seconds for generating a state checkpoint.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+		Buckets:      []float64{0.1, 1, 10, 60, 300, 900},
+	}
+
+	stateCheckpointsGeneratedOpts = metrics.CounterOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "state_checkpoints_generated",
+		Help:         "Number of state checkpoints generated, by status (success or failure).",
+		LabelNames:   []string{"channel", "status"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{status}",
+	}
+
+	lastStateCheckpointBlockOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "last_state_checkpoint_block",
+		Help:         "Number of the block of the most recent state checkpoint.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	statedbSizeOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "statedb_size_bytes",
+		Help:         "Approximate disk space in bytes occupied by the state database of the channel.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	pvtdataStoreSizeOpts = metrics.GaugeOpts{
+		Namespace:    "ledger",
+		Subsystem:    "",
+		Name:         "pvtdata_store_size_bytes",
+		Help:         "Approximate disk space in bytes occupied by the private data store of the channel.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
 )
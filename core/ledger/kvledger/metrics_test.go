@@ -63,6 +63,23 @@ func TestStatsBlockCommit(t *testing.T) {
 		[]string{"channel", ledgerid},
 		testMetricProvider.fakeStatedbCommitTimeHist.WithArgsForCall(0),
 	)
+	assert.Equal(t,
+		[]string{"channel", ledgerid},
+		testMetricProvider.fakeBlockCommitTimeHist.WithArgsForCall(0),
+	)
+	// the sizes of the databases are sampled in the background
+	assert.Eventually(t, func() bool {
+		return testMetricProvider.fakePvtdataStoreSizeGauge.SetCallCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, testMetricProvider.fakeStatedbSizeGauge.SetCallCount())
+	assert.Equal(t,
+		[]string{"channel", ledgerid},
+		testMetricProvider.fakeStatedbSizeGauge.WithArgsForCall(0),
+	)
+	assert.Equal(t,
+		[]string{"channel", ledgerid},
+		testMetricProvider.fakePvtdataStoreSizeGauge.WithArgsForCall(0),
+	)
 	assert.Equal(t,
 		[]string{
 			"channel", ledgerid,
@@ -148,6 +165,9 @@ type testMetricProvider struct {
 	fakeBlockstorageCommitWithPvtDataTimeHist *metricsfakes.Histogram
 	fakeStatedbCommitTimeHist                 *metricsfakes.Histogram
 	fakeTransactionsCount                     *metricsfakes.Counter
+	fakeBlockCommitTimeHist                   *metricsfakes.Histogram
+	fakeStatedbSizeGauge                      *metricsfakes.Gauge
+	fakePvtdataStoreSizeGauge                 *metricsfakes.Gauge
 }
 
 func testutilConstructMetricProvider() *testMetricProvider {
@@ -156,9 +176,19 @@ func testutilConstructMetricProvider() *testMetricProvider {
 	fakeBlockstorageCommitWithPvtDataTimeHist := testutilConstructHist()
 	fakeStatedbCommitTimeHist := testutilConstructHist()
 	fakeTransactionsCount := testutilConstructCounter()
+	fakeBlockCommitTimeHist := testutilConstructHist()
+	fakeStatedbSizeGauge := testutilConstructGauge()
+	fakePvtdataStoreSizeGauge := testutilConstructGauge()
 	fakeProvider.NewGaugeStub = func(opts metrics.GaugeOpts) metrics.Gauge {
-		// return a gauge for metrics in common/ledger
-		return testutilConstructGauge()
+		switch opts.Name {
+		case statedbSizeOpts.Name:
+			return fakeStatedbSizeGauge
+		case pvtdataStoreSizeOpts.Name:
+			return fakePvtdataStoreSizeGauge
+		default:
+			// return a gauge for metrics in common/ledger
+			return testutilConstructGauge()
+		}
 	}
 	fakeProvider.NewHistogramStub = func(opts metrics.HistogramOpts) metrics.Histogram {
 		switch opts.Name {
@@ -168,6 +198,8 @@ func testutilConstructMetricProvider() *testMetricProvider {
 			return fakeBlockstorageCommitWithPvtDataTimeHist
 		case statedbCommitTimeOpts.Name:
 			return fakeStatedbCommitTimeHist
+		case blockCommitTimeOpts.Name:
+			return fakeBlockCommitTimeHist
 		default:
 			// return a histogram for metrics in common/ledger
 			return testutilConstructHist()
@@ -178,8 +210,9 @@ func testutilConstructMetricProvider() *testMetricProvider {
 		switch opts.Name {
 		case transactionCountOpts.Name:
 			return fakeTransactionsCount
+		default:
+			return testutilConstructCounter()
 		}
-		return nil
 	}
 	return &testMetricProvider{
 		fakeProvider,
@@ -187,6 +220,9 @@ func testutilConstructMetricProvider() *testMetricProvider {
 		fakeBlockstorageCommitWithPvtDataTimeHist,
 		fakeStatedbCommitTimeHist,
 		fakeTransactionsCount,
+		fakeBlockCommitTimeHist,
+		fakeStatedbSizeGauge,
+		fakePvtdataStoreSizeGauge,
 	}
 }
 
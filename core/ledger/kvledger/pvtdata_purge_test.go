@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgePrivateData(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	provider := testutilNewProviderWithCollectionConfig(
+		t,
+		"ns",
+		map[string]uint64{"coll": 0},
+		conf,
+	)
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+
+	blk1 := prepareNextBlockForTest(t, l, bg, "txid-1", nil, map[string]string{"key1": "value1", "key2": "value2"})
+	require.NoError(t, l.CommitLegacy(blk1, &lgr.CommitOptions{}))
+	// the private data of block 2 is missing and is reconciled later
+	blk2, blk2Pvtdata := prepareNextBlockWithMissingPvtDataForTest(t, l, bg, "txid-2", nil, map[string]string{"key1": "value3"})
+	require.NoError(t, l.CommitLegacy(blk2, &lgr.CommitOptions{}))
+	require.Equal(t, []byte("value2"), privateValueForTest(t, l, "key2"))
+
+	require.NoError(t, kvl.PurgePrivateData("ns", []string{"coll"}, "key1"))
+	require.NoError(t, kvl.PurgePrivateData("ns", []string{"coll"}, "key2"))
+	pvtdata, err := l.GetPvtDataByNum(1, nil)
+	require.NoError(t, err)
+	require.Len(t, pvtdata, 1)
+	require.Empty(t, pvtWriteKeysForTest(t, pvtdata[0]))
+	_, err = privateValueOrErrForTest(t, l, "key2")
+	require.IsType(t, &txmgr.ErrPvtdataNotAvailable{}, err)
+
+	// the reconciled private data of block 2 does not restore the purged key
+	hashMismatches, err := l.CommitPvtDataOfOldBlocks([]*lgr.ReconciledPvtdata{
+		{BlockNum: 2, WriteSets: lgr.TxPvtDataMap{0: blk2Pvtdata}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, hashMismatches)
+	pvtdata, err = l.GetPvtDataByNum(2, nil)
+	require.NoError(t, err)
+	require.Len(t, pvtdata, 1)
+	require.Empty(t, pvtWriteKeysForTest(t, pvtdata[0]))
+	_, err = privateValueOrErrForTest(t, l, "key1")
+	require.IsType(t, &txmgr.ErrPvtdataNotAvailable{}, err)
+
+	// the purged key can be written again by a later transaction
+	blk3 := prepareNextBlockForTest(t, l, bg, "txid-3", nil, map[string]string{"key1": "value4"})
+	require.NoError(t, l.CommitLegacy(blk3, &lgr.CommitOptions{}))
+	require.Equal(t, []byte("value4"), privateValueForTest(t, l, "key1"))
+	pvtdata, err = l.GetPvtDataByNum(3, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1"}, pvtWriteKeysForTest(t, pvtdata[0]))
+}
+
+func privateValueForTest(t *testing.T, l lgr.PeerLedger, key string) []byte {
+	val, err := privateValueOrErrForTest(t, l, key)
+	require.NoError(t, err)
+	return val
+}
+
+func privateValueOrErrForTest(t *testing.T, l lgr.PeerLedger, key string) ([]byte, error) {
+	qe, err := l.NewQueryExecutor()
+	require.NoError(t, err)
+	defer qe.Done()
+	return qe.GetPrivateData("ns", "coll", key)
+}
+
+func pvtWriteKeysForTest(t *testing.T, txPvtdata *lgr.TxPvtData) []string {
+	var keys []string
+	for _, nsPvtdata := range txPvtdata.WriteSet.NsPvtRwset {
+		for _, collPvtdata := range nsPvtdata.CollectionPvtRwset {
+			kvRWSet := &kvrwset.KVRWSet{}
+			require.NoError(t, proto.Unmarshal(collPvtdata.Rwset, kvRWSet))
+			for _, w := range kvRWSet.Writes {
+				keys = append(keys, w.Key)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/pkg/chaincode/expiry"
+	"github.com/pkg/errors"
+)
+
+// pvtdataTTLPurger periodically purges the private keys of the configured collections once they outlive
+// the time-to-live of their collection or the expiry set for them by chaincode. Unlike the block-to-live
+// of a collection, the time-to-live is measured in wall-clock time, from the timestamp of the block that
+// last wrote a key, and hence, the keys are purged on schedule irrespective of the rate of the blocks
+type pvtdataTTLPurger struct {
+	ledgerID   string
+	stateDB    *privacyenabledstate.DB
+	blockStore *blkstorage.BlockStore
+	purge      func(ns string, colls []string, keys []string, tillBlk uint64) error
+	conf       *ledger.PvtDataTTLConfig
+	stats      *ledgerStats
+	now        func() time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPvtdataTTLPurger(
+	ledgerID string,
+	stateDB *privacyenabledstate.DB,
+	blockStore *blkstorage.BlockStore,
+	purge func(ns string, colls []string, keys []string, tillBlk uint64) error,
+	conf *ledger.PvtDataTTLConfig,
+	stats *ledgerStats,
+) *pvtdataTTLPurger {
+	if conf == nil {
+		conf = &ledger.PvtDataTTLConfig{}
+	}
+	return &pvtdataTTLPurger{
+		ledgerID:   ledgerID,
+		stateDB:    stateDB,
+		blockStore: blockStore,
+		purge:      purge,
+		conf:       conf,
+		stats:      stats,
+		now:        time.Now,
+		done:       make(chan struct{}),
+	}
+}
+
+func (p *pvtdataTTLPurger) enabled() bool {
+	return p.conf.CheckInterval > 0 && len(p.conf.Collections) > 0
+}
+
+// start launches the periodic purging in the background, if enabled
+func (p *pvtdataTTLPurger) start() {
+	if !p.enabled() {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.conf.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.purgeExpiredKeys(); err != nil {
+					logger.Errorf("[%s] Error while purging the expired private data: %s", p.ledgerID, err)
+				}
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops the periodic purging and waits for the in-progress run, if any
+func (p *pvtdataTTLPurger) stop() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	p.wg.Wait()
+}
+
+// purgeExpiredKeys purges the expired keys of all the configured collections
+func (p *pvtdataTTLPurger) purgeExpiredKeys() error {
+	now := p.now()
+	blockTimes := map[uint64]time.Time{}
+	for _, c := range p.conf.Collections {
+		keys, scannedTillBlk, err := p.expiredKeys(c, now, blockTimes)
+		if err != nil {
+			return errors.WithMessagef(err, "failed looking up the expired keys of collection [%s] of namespace [%s]", c.Collection, c.Namespace)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		// the keys written after the scan are retained, as they may not have expired
+		if err := p.purge(c.Namespace, []string{c.Collection}, keys, scannedTillBlk); err != nil {
+			return errors.WithMessagef(err, "failed purging the expired keys of collection [%s] of namespace [%s]", c.Collection, c.Namespace)
+		}
+		p.stats.updatePvtdataTTLPurgeStats(len(keys))
+		logger.Infof("[%s] Purged [%d] expired private keys of collection [%s] of namespace [%s]", p.ledgerID, len(keys), c.Collection, c.Namespace)
+	}
+	return nil
+}
+
+// expiredKeys returns the keys of the given collection that have expired at the given time, along with the keys
+// holding their expiries, and the number of the last block scanned. The expiry set by chaincode for a key takes
+// precedence over the time-to-live of the collection. The keys holding an expiry are purged only along with their
+// key, or once expired if the key is gone. The keys written after the last block scanned are skipped
+func (p *pvtdataTTLPurger) expiredKeys(c ledger.CollectionTTL, now time.Time, blockTimes map[uint64]time.Time) ([]string, uint64, error) {
+	savepoint, err := p.stateDB.GetLatestSavePoint()
+	if err != nil {
+		return nil, 0, err
+	}
+	if savepoint == nil {
+		return nil, 0, nil
+	}
+	scannedTillBlk := savepoint.BlockNum
+
+	itr, err := p.stateDB.GetPrivateDataRangeScanIterator(c.Namespace, c.Collection, "", "")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer itr.Close()
+
+	writtenInBlock := map[string]uint64{}
+	expiries := map[string]time.Time{}
+	for {
+		res, err := itr.Next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if res == nil {
+			break
+		}
+		kv, ok := res.(*statedb.VersionedKV)
+		if !ok {
+			return nil, 0, errors.Errorf("unexpected result of type %T from the state database", res)
+		}
+		if kv.Version.BlockNum > scannedTillBlk {
+			continue
+		}
+		if key, ok := expiry.SplitKey(kv.Key); ok {
+			expiresAt, err := expiry.Decode(kv.Value)
+			if err != nil {
+				logger.Warningf("[%s] Ignoring the expiry of key [%s] of collection [%s] of namespace [%s]: %s", p.ledgerID, key, c.Collection, c.Namespace, err)
+				continue
+			}
+			expiries[key] = expiresAt
+			continue
+		}
+		writtenInBlock[kv.Key] = kv.Version.BlockNum
+	}
+
+	var expired []string
+	for key, blockNum := range writtenInBlock {
+		expiresAt, ok := expiries[key]
+		if !ok {
+			if c.TTL == 0 {
+				continue
+			}
+			blockTime, ok := blockTimes[blockNum]
+			if !ok {
+				if blockTime, err = blockTimestamp(p.blockStore, blockNum); err != nil {
+					return nil, 0, err
+				}
+				blockTimes[blockNum] = blockTime
+			}
+			expiresAt = blockTime.Add(c.TTL)
+		}
+		if now.Before(expiresAt) {
+			continue
+		}
+		expired = append(expired, key)
+		if _, ok := expiries[key]; ok {
+			expired = append(expired, expiry.Key(key))
+		}
+	}
+	for key, expiresAt := range expiries {
+		if _, ok := writtenInBlock[key]; !ok && !now.Before(expiresAt) {
+			expired = append(expired, expiry.Key(key))
+		}
+	}
+	sort.Strings(expired)
+	return expired, scannedTillBlk, nil
+}
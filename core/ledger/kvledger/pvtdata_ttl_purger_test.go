@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr"
+	"github.com/hyperledger/fabric/pkg/chaincode/expiry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPvtdataTTLPurging(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.PrivateDataConfig.TimeToLive = &lgr.PvtDataTTLConfig{
+		Collections:   []lgr.CollectionTTL{{Namespace: "ns", Collection: "coll", TTL: time.Hour}},
+		CheckInterval: time.Hour,
+	}
+	provider := testutilNewProviderWithCollectionConfig(
+		t,
+		"ns",
+		map[string]uint64{"coll": 0},
+		conf,
+	)
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+	p := kvl.pvtdataTTLPurger
+	require.True(t, p.enabled())
+
+	start := time.Now()
+	blk1 := prepareNextBlockForTest(t, l, bg, "txid-1", nil, map[string]string{
+		"key1":                "value1",
+		"key2":                "value2",
+		expiry.Key("key2"):    string(expiry.Encode(start.Add(10 * time.Hour))),
+		"key3":                "value3",
+		expiry.Key("key3"):    string(expiry.Encode(start.Add(30 * time.Minute))),
+		expiry.Key("deleted"): string(expiry.Encode(start)),
+	})
+	require.NoError(t, l.CommitLegacy(blk1, &lgr.CommitOptions{}))
+	blockTime, err := blockTimestamp(kvl.blockStore, 1)
+	require.NoError(t, err)
+
+	// the expiry set for a key overrides the time-to-live of the collection
+	p.now = func() time.Time { return blockTime.Add(45 * time.Minute) }
+	require.NoError(t, p.purgeExpiredKeys())
+	for _, key := range []string{"key3", expiry.Key("key3"), expiry.Key("deleted")} {
+		_, err = privateValueOrErrForTest(t, l, key)
+		require.IsType(t, &txmgr.ErrPvtdataNotAvailable{}, err)
+	}
+	require.Equal(t, []byte("value1"), privateValueForTest(t, l, "key1"))
+
+	p.now = func() time.Time { return blockTime.Add(2 * time.Hour) }
+	require.NoError(t, p.purgeExpiredKeys())
+	_, err = privateValueOrErrForTest(t, l, "key1")
+	require.IsType(t, &txmgr.ErrPvtdataNotAvailable{}, err)
+	require.Equal(t, []byte("value2"), privateValueForTest(t, l, "key2"))
+	pvtdata, err := l.GetPvtDataByNum(1, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"key2", expiry.Key("key2")}, pvtWriteKeysForTest(t, pvtdata[0]))
+
+	// a key is purged along with its expiry
+	p.now = func() time.Time { return blockTime.Add(10 * time.Hour) }
+	require.NoError(t, p.purgeExpiredKeys())
+	pvtdata, err = l.GetPvtDataByNum(1, nil)
+	require.NoError(t, err)
+	require.Empty(t, pvtWriteKeysForTest(t, pvtdata[0]))
+}
+
+func TestPvtdataTTLPurgingRetainsKeysWrittenAfterScan(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.PrivateDataConfig.TimeToLive = &lgr.PvtDataTTLConfig{
+		Collections:   []lgr.CollectionTTL{{Namespace: "ns", Collection: "coll", TTL: time.Hour}},
+		CheckInterval: time.Hour,
+	}
+	provider := testutilNewProviderWithCollectionConfig(
+		t,
+		"ns",
+		map[string]uint64{"coll": 0},
+		conf,
+	)
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+	p := kvl.pvtdataTTLPurger
+
+	blk1 := prepareNextBlockForTest(t, l, bg, "txid-1", nil, map[string]string{"key1": "value1", "key2": "value2"})
+	require.NoError(t, l.CommitLegacy(blk1, &lgr.CommitOptions{}))
+	blockTime, err := blockTimestamp(kvl.blockStore, 1)
+	require.NoError(t, err)
+
+	// key1 is written again after the scan that found it expired and before its purge
+	purge := p.purge
+	p.purge = func(ns string, colls []string, keys []string, tillBlk uint64) error {
+		require.Equal(t, uint64(1), tillBlk)
+		blk2 := prepareNextBlockForTest(t, l, bg, "txid-2", nil, map[string]string{"key1": "value1-new"})
+		require.NoError(t, l.CommitLegacy(blk2, &lgr.CommitOptions{}))
+		return purge(ns, colls, keys, tillBlk)
+	}
+	p.now = func() time.Time { return blockTime.Add(2 * time.Hour) }
+	require.NoError(t, p.purgeExpiredKeys())
+
+	require.Equal(t, []byte("value1-new"), privateValueForTest(t, l, "key1"))
+	_, err = privateValueOrErrForTest(t, l, "key2")
+	require.IsType(t, &txmgr.ErrPvtdataNotAvailable{}, err)
+	pvtdata, err := l.GetPvtDataByNum(1, nil)
+	require.NoError(t, err)
+	require.Empty(t, pvtWriteKeysForTest(t, pvtdata[0]))
+	pvtdata, err = l.GetPvtDataByNum(2, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"key1"}, pvtWriteKeysForTest(t, pvtdata[0]))
+}
+
+func TestPvtdataTTLPurgerDisabled(t *testing.T) {
+	p := newPvtdataTTLPurger("ledger", nil, nil, nil, nil, nil)
+	require.False(t, p.enabled())
+	p.start()
+	p.stop()
+	p.stop()
+
+	p = newPvtdataTTLPurger("ledger", nil, nil, nil, &lgr.PvtDataTTLConfig{CheckInterval: time.Minute}, nil)
+	require.False(t, p.enabled())
+}
@@ -26,7 +26,7 @@ func RebuildDBs(config *ledger.Config) error {
 	}
 	defer fileLock.Unlock()
 
-	if config.StateDBConfig.StateDatabase == "CouchDB" {
+	if usesCouchDB(config.StateDBConfig) {
 		if err := statecouchdb.DropApplicationDBs(config.StateDBConfig.CouchDB); err != nil {
 			return err
 		}
@@ -38,3 +38,9 @@ func RebuildDBs(config *ledger.Config) error {
 	blockstorePath := BlockStorePath(rootFSPath)
 	return blkstorage.DeleteBlockStoreIndex(blockstorePath)
 }
+
+// usesCouchDB returns true if any state is stored in CouchDB, either as the state database,
+// for some of the namespaces, or as the target of a state database migration
+func usesCouchDB(conf *ledger.StateDBConfig) bool {
+	return conf.StateDatabase == "CouchDB" || len(conf.CouchDBNamespaces) > 0 || conf.MigrationTarget == "CouchDB"
+}
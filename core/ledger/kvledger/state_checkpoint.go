@@ -0,0 +1,354 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	stateCheckpointMetadataFileName = "_checkpoint.json"
+	stateCheckpointTmpDirSuffix     = ".tmp"
+)
+
+// StateCheckpoint describes the checkpoint of the state of a ledger after committing a block. The checkpoint
+// directory contains the files exported from the state database and the marshaled StateCheckpoint that carries
+// the hashes of these files. The organizations of a channel sign the marshaled StateCheckpoint once they have
+// generated identical checkpoints, which enables a new peer to verify the state files obtained from any peer.
+// Note that the exported files carry the values in the format of the state database and hence, the checkpoints
+// are comparable only across the peers that use the same type of state database and do not encrypt the state
+type StateCheckpoint struct {
+	ChannelName     string            `json:"channel_name"`
+	LastBlockNumber uint64            `json:"last_block_number"`
+	LastBlockHash   string            `json:"last_block_hash"`
+	LastCommitHash  string            `json:"last_commit_hash"`
+	StateDBType     string            `json:"state_db_type"`
+	FilesHashes     map[string]string `json:"files_hashes"`
+}
+
+// SignedStateCheckpoint carries the signature of a peer over a marshaled StateCheckpoint
+type SignedStateCheckpoint struct {
+	Checkpoint []byte `json:"checkpoint"`
+	Identity   []byte `json:"identity"`
+	Signature  []byte `json:"signature"`
+}
+
+// StateCheckpointSigner signs a state checkpoint on behalf of a peer
+type StateCheckpointSigner interface {
+	Sign(message []byte) ([]byte, error)
+	Serialize() ([]byte, error)
+}
+
+// StateCheckpointPolicy evaluates whether a set of signatures over a state checkpoint is sufficient
+// for the checkpoint to be adopted, for instance, whether a majority of the channel organizations signed it
+type StateCheckpointPolicy interface {
+	EvaluateSignedData(signatureSet []*protoutil.SignedData) error
+}
+
+// stateCheckpointer generates a checkpoint of the state of a ledger after every `Interval` blocks and at the
+// first block committed after each time of the `Schedule`, and retains the checkpoints as per the configured
+// retention limits
+type stateCheckpointer struct {
+	ledgerID    string
+	dir         string
+	stateDB     *privacyenabledstate.DB
+	stateDBType string
+	conf        *ledger.StateCheckpointConfig
+	schedule    checkpointSchedule
+	stats       *ledgerStats
+	now         func() time.Time
+
+	nextScheduled time.Time
+}
+
+func newStateCheckpointer(
+	ledgerID string,
+	rootDir string,
+	stateDB *privacyenabledstate.DB,
+	stateDBType string,
+	conf *ledger.StateCheckpointConfig,
+	schedule checkpointSchedule,
+	stats *ledgerStats,
+) *stateCheckpointer {
+	if conf == nil {
+		conf = &ledger.StateCheckpointConfig{}
+	}
+	c := &stateCheckpointer{
+		ledgerID:    ledgerID,
+		dir:         filepath.Join(rootDir, ledgerID),
+		stateDB:     stateDB,
+		stateDBType: stateDBType,
+		conf:        conf,
+		schedule:    schedule,
+		stats:       stats,
+		now:         time.Now,
+	}
+	if schedule != nil {
+		c.nextScheduled = schedule.next(c.now())
+	}
+	return c
+}
+
+// blockCommitted generates a checkpoint if the committed block is at the configured interval or if a scheduled
+// checkpoint is due. This is expected to be invoked after committing the block to the state database and before
+// the commit of the next block begins
+func (c *stateCheckpointer) blockCommitted(block *common.Block, commitHash []byte) error {
+	blockNum := block.Header.Number
+	if blockNum == 0 {
+		return nil
+	}
+	atInterval := c.conf.Interval != 0 && blockNum%c.conf.Interval == 0
+	scheduled := false
+	if c.schedule != nil && !c.nextScheduled.IsZero() {
+		if now := c.now(); !now.Before(c.nextScheduled) {
+			scheduled = true
+			c.nextScheduled = c.schedule.next(now)
+		}
+	}
+	if !atInterval && !scheduled {
+		return nil
+	}
+
+	startTime := time.Now()
+	err := c.generate(block, commitHash)
+	if c.stats != nil {
+		c.stats.updateStateCheckpointStats(blockNum, time.Since(startTime), err == nil)
+	}
+	if err != nil {
+		return err
+	}
+	return c.removeOldCheckpoints()
+}
+
+// generate exports the state in a temporary directory and renames the directory once the export
+// completes so that a checkpoint directory with a block number as the name is always complete
+func (c *stateCheckpointer) generate(block *common.Block, commitHash []byte) error {
+	blockNum := block.Header.Number
+	checkpointDir := c.checkpointDir(blockNum)
+	tmpDir := checkpointDir + stateCheckpointTmpDirSuffix
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return errors.Wrapf(err, "error while removing the temporary checkpoint directory [%s]", tmpDir)
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return errors.Wrapf(err, "error while creating the checkpoint directory [%s]", tmpDir)
+	}
+	filesHashes, err := c.stateDB.ExportPubStateAndPvtStateHashes(tmpDir, sha256.New)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	checkpoint := &StateCheckpoint{
+		ChannelName:     c.ledgerID,
+		LastBlockNumber: blockNum,
+		LastBlockHash:   hex.EncodeToString(protoutil.BlockHeaderHash(block.Header)),
+		LastCommitHash:  hex.EncodeToString(commitHash),
+		StateDBType:     c.stateDBType,
+		FilesHashes:     map[string]string{},
+	}
+	for fileName, fileHash := range filesHashes {
+		checkpoint.FilesHashes[fileName] = hex.EncodeToString(fileHash)
+	}
+	checkpointBytes, err := json.Marshal(checkpoint)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return errors.Wrap(err, "error while marshalling the state checkpoint")
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, stateCheckpointMetadataFileName), checkpointBytes, 0644); err != nil {
+		os.RemoveAll(tmpDir)
+		return errors.Wrap(err, "error while writing the state checkpoint")
+	}
+	if err := os.RemoveAll(checkpointDir); err != nil {
+		return errors.Wrapf(err, "error while removing the existing checkpoint directory [%s]", checkpointDir)
+	}
+	if err := os.Rename(tmpDir, checkpointDir); err != nil {
+		return errors.Wrapf(err, "error while renaming the checkpoint directory [%s]", tmpDir)
+	}
+	logger.Infof("[%s] Generated the state checkpoint for block [%d] in directory [%s]", c.ledgerID, blockNum, checkpointDir)
+	return nil
+}
+
+// removeOldCheckpoints removes the checkpoints beyond the `Retain` most recent ones and the checkpoints generated
+// before the `RetentionPeriod`. The most recent checkpoint is never removed
+func (c *stateCheckpointer) removeOldCheckpoints() error {
+	if c.conf.Retain <= 0 && c.conf.RetentionPeriod <= 0 {
+		return nil
+	}
+	blockNums, err := c.checkpoints()
+	if err != nil {
+		return err
+	}
+	if len(blockNums) <= 1 {
+		return nil
+	}
+
+	numToRemove := 0
+	if c.conf.Retain > 0 && len(blockNums) > c.conf.Retain {
+		numToRemove = len(blockNums) - c.conf.Retain
+	}
+	if c.conf.RetentionPeriod > 0 {
+		cutoff := c.now().Add(-c.conf.RetentionPeriod)
+		for numToRemove < len(blockNums)-1 {
+			info, err := os.Stat(filepath.Join(c.checkpointDir(blockNums[numToRemove]), stateCheckpointMetadataFileName))
+			if err == nil && !info.ModTime().Before(cutoff) {
+				break
+			}
+			numToRemove++
+		}
+	}
+
+	for _, blockNum := range blockNums[:numToRemove] {
+		if err := os.RemoveAll(c.checkpointDir(blockNum)); err != nil {
+			return errors.Wrapf(err, "error while removing the state checkpoint for block [%d]", blockNum)
+		}
+		logger.Debugf("[%s] Removed the state checkpoint for block [%d]", c.ledgerID, blockNum)
+	}
+	return nil
+}
+
+// checkpoints returns, in increasing order, the numbers of the blocks for which a checkpoint is present
+func (c *stateCheckpointer) checkpoints() ([]uint64, error) {
+	entries, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while reading the state checkpoints directory [%s]", c.dir)
+	}
+	var blockNums []uint64
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		blockNum, err := strconv.ParseUint(e.Name(), 10, 64)
+		if err != nil {
+			// temporary directory of an incomplete checkpoint
+			continue
+		}
+		blockNums = append(blockNums, blockNum)
+	}
+	sort.Slice(blockNums, func(i, j int) bool { return blockNums[i] < blockNums[j] })
+	return blockNums, nil
+}
+
+func (c *stateCheckpointer) checkpointDir(blockNum uint64) string {
+	return filepath.Join(c.dir, strconv.FormatUint(blockNum, 10))
+}
+
+// ReadStateCheckpoint reads the checkpoint present in the given checkpoint directory. It returns the
+// unmarshaled checkpoint as well as the marshaled bytes that are signed by the peers
+func ReadStateCheckpoint(checkpointDir string) (*StateCheckpoint, []byte, error) {
+	checkpointBytes, err := ioutil.ReadFile(filepath.Join(checkpointDir, stateCheckpointMetadataFileName))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error while reading the state checkpoint from directory [%s]", checkpointDir)
+	}
+	checkpoint := &StateCheckpoint{}
+	if err := json.Unmarshal(checkpointBytes, checkpoint); err != nil {
+		return nil, nil, errors.Wrapf(err, "error while unmarshalling the state checkpoint from directory [%s]", checkpointDir)
+	}
+	return checkpoint, checkpointBytes, nil
+}
+
+// SignStateCheckpoint signs the marshaled state checkpoint with the given signer
+func SignStateCheckpoint(checkpointBytes []byte, signer StateCheckpointSigner) (*SignedStateCheckpoint, error) {
+	identity, err := signer.Serialize()
+	if err != nil {
+		return nil, errors.WithMessage(err, "error while serializing the signing identity")
+	}
+	signature, err := signer.Sign(checkpointBytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error while signing the state checkpoint")
+	}
+	return &SignedStateCheckpoint{
+		Checkpoint: checkpointBytes,
+		Identity:   identity,
+		Signature:  signature,
+	}, nil
+}
+
+// VerifyStateCheckpoint verifies that all the signatures are over the same checkpoint and that the signatures
+// satisfy the given policy. On a successful verification, the checkpoint agreed by the signers is returned
+func VerifyStateCheckpoint(signedCheckpoints []*SignedStateCheckpoint, policy StateCheckpointPolicy) (*StateCheckpoint, error) {
+	if len(signedCheckpoints) == 0 {
+		return nil, errors.New("no signed state checkpoint supplied")
+	}
+	checkpointBytes := signedCheckpoints[0].Checkpoint
+	var signedData []*protoutil.SignedData
+	for _, s := range signedCheckpoints {
+		if !bytes.Equal(s.Checkpoint, checkpointBytes) {
+			return nil, errors.New("the signed state checkpoints do not match")
+		}
+		signedData = append(signedData, &protoutil.SignedData{
+			Data:      s.Checkpoint,
+			Identity:  s.Identity,
+			Signature: s.Signature,
+		})
+	}
+	if err := policy.EvaluateSignedData(signedData); err != nil {
+		return nil, errors.WithMessage(err, "the signatures on the state checkpoint do not satisfy the policy")
+	}
+	checkpoint := &StateCheckpoint{}
+	if err := json.Unmarshal(checkpointBytes, checkpoint); err != nil {
+		return nil, errors.Wrap(err, "error while unmarshalling the state checkpoint")
+	}
+	return checkpoint, nil
+}
+
+// VerifyStateCheckpointFiles verifies that the files in the given directory match the hashes present in
+// the checkpoint. This is intended to be used by a new peer that obtains the state files from another peer
+// and verifies them against a checkpoint signed by the organizations of the channel
+func VerifyStateCheckpointFiles(dir string, checkpoint *StateCheckpoint) error {
+	if len(checkpoint.FilesHashes) == 0 {
+		return errors.New("the state checkpoint does not contain any file hash")
+	}
+	// the files are verified in order, so that the same file is reported
+	// for the same mismatch
+	var fileNames []string
+	for fileName := range checkpoint.FilesHashes {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		fileHash, err := computeFileHash(filepath.Join(dir, fileName), sha256.New)
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(fileHash) != checkpoint.FilesHashes[fileName] {
+			return errors.Errorf("the hash of the file [%s] does not match the state checkpoint", fileName)
+		}
+	}
+	return nil
+}
+
+func computeFileHash(filePath string, newHasher func() hash.Hash) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while opening the file [%s]", filePath)
+	}
+	defer f.Close()
+	h := newHasher()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, errors.Wrapf(err, "error while reading the file [%s]", filePath)
+	}
+	return h.Sum(nil), nil
+}
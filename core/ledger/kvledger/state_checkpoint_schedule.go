@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// checkpointSchedule returns the next time, strictly after the given time, at which a state checkpoint is due
+type checkpointSchedule interface {
+	next(t time.Time) time.Time
+}
+
+var scheduleDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseCheckpointSchedule parses a schedule expressed either as '@every <duration>', as one of the descriptors
+// '@hourly', '@daily', '@midnight', '@weekly' and '@monthly', or as a cron expression of five fields: minute,
+// hour, day of month, month, and day of week. A field is '*' or a comma separated list of values and ranges,
+// each of which is optionally followed by '/<step>', such as '0,30', '9-17', or '*/15'. The times of a cron
+// expression are in the local time zone of the peer
+func parseCheckpointSchedule(spec string) (checkpointSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid schedule [%s]", spec)
+		}
+		if d < time.Minute {
+			return nil, errors.Errorf("invalid schedule [%s]: the interval must be at least one minute", spec)
+		}
+		return &everySchedule{interval: d}, nil
+	}
+	if descriptor, ok := scheduleDescriptors[spec]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid schedule [%s]: expected 5 fields, found %d", spec, len(fields))
+	}
+	s := &cronSchedule{}
+	var err error
+	for _, f := range []struct {
+		field    string
+		min, max int
+		bits     *uint64
+	}{
+		{fields[0], 0, 59, &s.minute},
+		{fields[1], 0, 23, &s.hour},
+		{fields[2], 1, 31, &s.dayOfMonth},
+		{fields[3], 1, 12, &s.month},
+		{fields[4], 0, 7, &s.dayOfWeek},
+	} {
+		if *f.bits, err = parseCronField(f.field, f.min, f.max); err != nil {
+			return nil, errors.WithMessagef(err, "invalid schedule [%s]", spec)
+		}
+	}
+	// both 0 and 7 stand for Sunday
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	s.anyDayOfMonth = fields[2] == "*"
+	s.anyDayOfWeek = fields[4] == "*"
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangeExpr, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangeExpr = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, errors.Errorf("invalid step in [%s]", item)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rangeExpr == "*":
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, errors.Errorf("invalid range [%s]", item)
+			}
+		default:
+			value, err := strconv.Atoi(rangeExpr)
+			if err != nil {
+				return 0, errors.Errorf("invalid value [%s]", item)
+			}
+			low, high = value, value
+			if step != 1 {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, errors.Errorf("[%s] is out of the range [%d-%d]", item, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// everySchedule is due at a fixed interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s *everySchedule) next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronSchedule is due at the minutes that match all the fields of a cron expression. As in cron, when both
+// the day of month and the day of week are restricted, a day matches if it matches either of them
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	anyDayOfMonth, anyDayOfWeek                bool
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	// an expression such as '0 0 30 2 *' never matches, give up after a few years
+	yearLimit := t.Year() + 5
+	for t.Year() <= yearLimit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dowMatch := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
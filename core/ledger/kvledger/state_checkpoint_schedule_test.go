@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckpointSchedule(t *testing.T) {
+	// a Wednesday
+	start := time.Date(2020, time.January, 1, 10, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected []time.Time
+	}{
+		{
+			spec: "@every 90m",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 11, 47, 42, 0, time.UTC),
+				time.Date(2020, time.January, 1, 13, 17, 42, 0, time.UTC),
+			},
+		},
+		{
+			spec: "*/15 * * * *",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 10, 45, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "@daily",
+			expected: []time.Time{
+				time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "30 2,14 * * 1-5",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 14, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 2, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 14, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 3, 2, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 3, 14, 30, 0, 0, time.UTC),
+				time.Date(2020, time.January, 6, 2, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "0 0 29 2 *",
+			expected: []time.Time{
+				time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// either the 15th of the month or a Sunday
+			spec: "0 0 15 * 7",
+			expected: []time.Time{
+				time.Date(2020, time.January, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			schedule, err := parseCheckpointSchedule(test.spec)
+			require.NoError(t, err)
+			next := start
+			for _, expected := range test.expected {
+				next = schedule.next(next)
+				require.Equal(t, expected, next)
+			}
+		})
+	}
+
+	schedule, err := parseCheckpointSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, schedule.next(start).IsZero())
+}
+
+func TestParseCheckpointScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedErr string
+	}{
+		{"@every 1x", `invalid schedule [@every 1x]: time: unknown unit "x" in duration "1x"`},
+		{"@every 10s", "invalid schedule [@every 10s]: the interval must be at least one minute"},
+		{"@yearly", "invalid schedule [@yearly]: expected 5 fields, found 1"},
+		{"0 0 * *", "invalid schedule [0 0 * *]: expected 5 fields, found 4"},
+		{"60 * * * *", "invalid schedule [60 * * * *]: [60] is out of the range [0-59]"},
+		{"* 5-2 * * *", "invalid schedule [* 5-2 * * *]: [5-2] is out of the range [0-23]"},
+		{"* * 0 * *", "invalid schedule [* * 0 * *]: [0] is out of the range [1-31]"},
+		{"*/0 * * * *", "invalid schedule [*/0 * * * *]: invalid step in [*/0]"},
+		{"* * * jan *", "invalid schedule [* * * jan *]: invalid value [jan]"},
+		{"* * * * 1-x", "invalid schedule [* * * * 1-x]: invalid range [1-x]"},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := parseCheckpointSchedule(test.spec)
+			require.EqualError(t, err, test.expectedErr)
+		})
+	}
+}
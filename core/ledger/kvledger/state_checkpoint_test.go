@@ -0,0 +1,283 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateCheckpoints(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.StateCheckpointConfig = &lgr.StateCheckpointConfig{
+		Interval: 2,
+		Retain:   2,
+	}
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+
+	for i := 1; i <= 5; i++ {
+		commitHistoryTestBlock(t, kvl, bg, "key1")
+	}
+	checkpoints, err := kvl.stateCheckpointer.checkpoints()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 4}, checkpoints)
+
+	commitHistoryTestBlock(t, kvl, bg, "key2")
+	checkpoints, err = kvl.stateCheckpointer.checkpoints()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4, 6}, checkpoints)
+
+	checkpointDir := filepath.Join(StateCheckpointsPath(conf.RootFSPath), "testLedger", "6")
+	checkpoint, checkpointBytes, err := ReadStateCheckpoint(checkpointDir)
+	require.NoError(t, err)
+	block6, err := l.GetBlockByNumber(6)
+	require.NoError(t, err)
+	require.Equal(t, "testLedger", checkpoint.ChannelName)
+	require.Equal(t, uint64(6), checkpoint.LastBlockNumber)
+	require.Equal(t, hex.EncodeToString(protoutil.BlockHeaderHash(block6.Header)), checkpoint.LastBlockHash)
+	require.Equal(t, hex.EncodeToString(kvl.commitHash), checkpoint.LastCommitHash)
+	require.Equal(t, "goleveldb", checkpoint.StateDBType)
+	require.Len(t, checkpoint.FilesHashes, 4)
+	require.NotEmpty(t, checkpointBytes)
+	require.NoError(t, VerifyStateCheckpointFiles(checkpointDir, checkpoint))
+
+	// the checkpoint of a different state does not verify the files
+	checkpoint4, _, err := ReadStateCheckpoint(filepath.Join(StateCheckpointsPath(conf.RootFSPath), "testLedger", "4"))
+	require.NoError(t, err)
+	require.EqualError(t,
+		VerifyStateCheckpointFiles(checkpointDir, checkpoint4),
+		"the hash of the file [public_state.data] does not match the state checkpoint",
+	)
+	require.EqualError(t,
+		VerifyStateCheckpointFiles(checkpointDir, &StateCheckpoint{}),
+		"the state checkpoint does not contain any file hash",
+	)
+}
+
+func TestStateCheckpointsDisabled(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+	for i := 1; i <= 3; i++ {
+		commitHistoryTestBlock(t, kvl, bg, "key1")
+	}
+	_, err = os.Stat(StateCheckpointsPath(conf.RootFSPath))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestScheduledStateCheckpoints(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	conf.StateCheckpointConfig = &lgr.StateCheckpointConfig{
+		Schedule:        "@every 1h",
+		RetentionPeriod: 150 * time.Minute,
+	}
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	defer provider.Close()
+
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	l, err := provider.Create(gb)
+	require.NoError(t, err)
+	defer l.Close()
+	kvl := l.(*kvLedger)
+
+	checkpointer := kvl.stateCheckpointer
+	now := time.Now()
+	checkpointer.now = func() time.Time { return now }
+	checkpointer.nextScheduled = now.Add(time.Hour)
+
+	// no checkpoint is due before the scheduled time
+	commitHistoryTestBlock(t, kvl, bg, "key1")
+	checkpoints, err := checkpointer.checkpoints()
+	require.NoError(t, err)
+	require.Empty(t, checkpoints)
+
+	// the first block committed after the scheduled time generates a checkpoint
+	for _, blockNum := range []uint64{2, 3, 4} {
+		now = now.Add(time.Hour)
+		commitHistoryTestBlock(t, kvl, bg, "key1")
+		checkpointDir := checkpointer.checkpointDir(blockNum)
+		require.NoError(t, os.Chtimes(filepath.Join(checkpointDir, stateCheckpointMetadataFileName), now, now))
+	}
+	commitHistoryTestBlock(t, kvl, bg, "key1")
+	checkpoints, err = checkpointer.checkpoints()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3, 4}, checkpoints)
+
+	// the checkpoints older than the retention period are removed
+	now = now.Add(time.Hour)
+	commitHistoryTestBlock(t, kvl, bg, "key1")
+	checkpoints, err = checkpointer.checkpoints()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3, 4, 6}, checkpoints)
+
+	// the most recent checkpoint is always retained
+	checkpointer.conf.Schedule = ""
+	checkpointer.schedule = nil
+	now = now.Add(10 * time.Hour)
+	require.NoError(t, checkpointer.removeOldCheckpoints())
+	checkpoints, err = checkpointer.checkpoints()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{6}, checkpoints)
+}
+
+func TestStateCheckpointInvalidSchedule(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	for schedule, expectedErr := range map[string]string{
+		"0 0 * *":    "invalid state checkpoint configuration: invalid schedule [0 0 * *]: expected 5 fields, found 4",
+		"0 0 30 2 *": "invalid state checkpoint configuration: the schedule [0 0 30 2 *] is never due",
+	} {
+		conf.StateCheckpointConfig = &lgr.StateCheckpointConfig{Schedule: schedule}
+		_, err = NewProvider(&lgr.Initializer{
+			DeployedChaincodeInfoProvider: &mock.DeployedChaincodeInfoProvider{},
+			MetricsProvider:               &disabled.Provider{},
+			Config:                        conf,
+			Hasher:                        cryptoProvider,
+		})
+		require.EqualError(t, err, expectedErr)
+	}
+}
+
+func TestStateCheckpointStats(t *testing.T) {
+	fakeTime := &metricsfakes.Histogram{}
+	fakeTime.WithReturns(fakeTime)
+	fakeGenerated := &metricsfakes.Counter{}
+	fakeGenerated.WithReturns(fakeGenerated)
+	fakeLastBlock := &metricsfakes.Gauge{}
+	fakeLastBlock.WithReturns(fakeLastBlock)
+
+	fakeProvider := &metricsfakes.Provider{}
+	fakeProvider.NewHistogramStub = func(opts metrics.HistogramOpts) metrics.Histogram {
+		if opts.Name == stateCheckpointTimeOpts.Name {
+			return fakeTime
+		}
+		return &metricsfakes.Histogram{}
+	}
+	fakeProvider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
+		if opts.Name == stateCheckpointsGeneratedOpts.Name {
+			return fakeGenerated
+		}
+		return &metricsfakes.Counter{}
+	}
+	fakeProvider.NewGaugeStub = func(opts metrics.GaugeOpts) metrics.Gauge {
+		if opts.Name == lastStateCheckpointBlockOpts.Name {
+			return fakeLastBlock
+		}
+		return &metricsfakes.Gauge{}
+	}
+
+	stats := newStats(fakeProvider).ledgerStats("testLedger")
+	stats.updateStateCheckpointStats(10, 2*time.Second, true)
+	stats.updateStateCheckpointStats(20, time.Second, false)
+
+	require.Equal(t, 2, fakeGenerated.WithCallCount())
+	require.Equal(t, []string{"channel", "testLedger", "status", "success"}, fakeGenerated.WithArgsForCall(0))
+	require.Equal(t, []string{"channel", "testLedger", "status", "failure"}, fakeGenerated.WithArgsForCall(1))
+	require.Equal(t, 1, fakeTime.ObserveCallCount())
+	require.Equal(t, float64(2), fakeTime.ObserveArgsForCall(0))
+	require.Equal(t, 1, fakeLastBlock.SetCallCount())
+	require.Equal(t, float64(10), fakeLastBlock.SetArgsForCall(0))
+}
+
+func TestSignAndVerifyStateCheckpoint(t *testing.T) {
+	checkpointBytes := []byte(`{"channel_name":"testLedger","last_block_number":10}`)
+	signed1, err := SignStateCheckpoint(checkpointBytes, &testCheckpointSigner{identity: "org1"})
+	require.NoError(t, err)
+	signed2, err := SignStateCheckpoint(checkpointBytes, &testCheckpointSigner{identity: "org2"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("org1-signature"), signed1.Signature)
+
+	_, err = SignStateCheckpoint(checkpointBytes, &testCheckpointSigner{err: errors.New("signer error")})
+	require.EqualError(t, err, "error while serializing the signing identity: signer error")
+
+	policy := &testCheckpointPolicy{requiredSigners: 2}
+	checkpoint, err := VerifyStateCheckpoint([]*SignedStateCheckpoint{signed1, signed2}, policy)
+	require.NoError(t, err)
+	require.Equal(t, &StateCheckpoint{ChannelName: "testLedger", LastBlockNumber: 10}, checkpoint)
+
+	_, err = VerifyStateCheckpoint([]*SignedStateCheckpoint{signed1}, policy)
+	require.EqualError(t, err, "the signatures on the state checkpoint do not satisfy the policy: 1 signatures, 2 required")
+
+	signedOther, err := SignStateCheckpoint([]byte(`{"channel_name":"testLedger","last_block_number":12}`), &testCheckpointSigner{identity: "org2"})
+	require.NoError(t, err)
+	_, err = VerifyStateCheckpoint([]*SignedStateCheckpoint{signed1, signedOther}, policy)
+	require.EqualError(t, err, "the signed state checkpoints do not match")
+
+	_, err = VerifyStateCheckpoint(nil, policy)
+	require.EqualError(t, err, "no signed state checkpoint supplied")
+}
+
+func TestReadStateCheckpointErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statecheckpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, _, err = ReadStateCheckpoint(dir)
+	require.Contains(t, err.Error(), "error while reading the state checkpoint from directory")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, stateCheckpointMetadataFileName), []byte("not-json"), 0644))
+	_, _, err = ReadStateCheckpoint(dir)
+	require.Contains(t, err.Error(), "error while unmarshalling the state checkpoint from directory")
+}
+
+type testCheckpointSigner struct {
+	identity string
+	err      error
+}
+
+func (s *testCheckpointSigner) Sign(message []byte) ([]byte, error) {
+	return []byte(s.identity + "-signature"), s.err
+}
+
+func (s *testCheckpointSigner) Serialize() ([]byte, error) {
+	return []byte(s.identity), s.err
+}
+
+type testCheckpointPolicy struct {
+	requiredSigners int
+}
+
+func (p *testCheckpointPolicy) EvaluateSignedData(signatureSet []*protoutil.SignedData) error {
+	if len(signatureSet) < p.requiredSigners {
+		return errors.Errorf("%d signatures, %d required", len(signatureSet), p.requiredSigners)
+	}
+	return nil
+}
@@ -20,7 +20,10 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/statecouchdb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateencryption"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/statehybrid"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/statemigration"
 	"github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/pkg/errors"
 )
@@ -42,6 +45,9 @@ type StateDBConfig struct {
 	// It is internally computed by the ledger component,
 	// so it is not in ledger.StateDBConfig and not exposed to other components.
 	LevelDBPath string
+	// Encryptor is used for encrypting the state values at rest when
+	// StateDBConfig.Encryption enables the encryption for any namespace.
+	Encryptor ledger.Encryptor
 }
 
 // DBProvider encapsulates other providers such as VersionedDBProvider and
@@ -65,13 +71,52 @@ func NewDBProvider(
 	var err error
 
 	if stateDBConf != nil && stateDBConf.StateDatabase == couchDB {
+		if len(stateDBConf.CouchDBNamespaces) > 0 {
+			return nil, errors.New("the namespaces stored in CouchDB cannot be configured when CouchDB is the state database")
+		}
+		if stateDBConf.MigrationTarget != "" {
+			return nil, errors.Errorf("a state database migration requires goleveldb as the source state database, found [%s]", stateDBConf.StateDatabase)
+		}
 		if vdbProvider, err = statecouchdb.NewVersionedDBProvider(stateDBConf.CouchDB, metricsProvider, sysNamespaces); err != nil {
 			return nil, err
 		}
 	} else {
+		if stateDBConf != nil && stateDBConf.MigrationTarget != "" {
+			if stateDBConf.MigrationTarget != couchDB {
+				return nil, errors.Errorf("unsupported state database migration target [%s], supported target is [%s]", stateDBConf.MigrationTarget, couchDB)
+			}
+			if len(stateDBConf.CouchDBNamespaces) > 0 {
+				return nil, errors.New("the namespaces stored in CouchDB cannot be configured during a state database migration")
+			}
+		}
 		if vdbProvider, err = stateleveldb.NewVersionedDBProvider(stateDBConf.LevelDBPath); err != nil {
 			return nil, err
 		}
+		if stateDBConf != nil && stateDBConf.MigrationTarget != "" {
+			couchDBProvider, err := statecouchdb.NewVersionedDBProvider(stateDBConf.CouchDB, metricsProvider, sysNamespaces)
+			if err != nil {
+				vdbProvider.Close()
+				return nil, err
+			}
+			vdbProvider = statemigration.NewVersionedDBProvider(vdbProvider, couchDBProvider)
+		}
+		if stateDBConf != nil && len(stateDBConf.CouchDBNamespaces) > 0 {
+			couchDBProvider, err := statecouchdb.NewVersionedDBProvider(stateDBConf.CouchDB, metricsProvider, sysNamespaces)
+			if err != nil {
+				vdbProvider.Close()
+				return nil, err
+			}
+			vdbProvider = statehybrid.NewVersionedDBProvider(vdbProvider, couchDBProvider, stateDBConf.CouchDBNamespaces)
+		}
+	}
+
+	if stateDBConf != nil && stateencryption.Enabled(stateDBConf.Encryption) {
+		encryptedVDBProvider, err := stateencryption.NewVersionedDBProvider(vdbProvider, stateDBConf.Encryptor, stateDBConf.Encryption)
+		if err != nil {
+			vdbProvider.Close()
+			return nil, err
+		}
+		vdbProvider = encryptedVDBProvider
 	}
 
 	dbProvider := &DBProvider{vdbProvider, healthCheckRegistry, bookkeeperProvider}
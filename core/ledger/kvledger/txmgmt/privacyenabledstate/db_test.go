@@ -11,13 +11,18 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/cceventmgmt"
 	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/statecouchdb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
@@ -418,6 +423,52 @@ func TestHandleChainCodeDeployOnCouchDB(t *testing.T) {
 	}
 }
 
+func TestHandleChaincodeDeployOnLevelDB(t *testing.T) {
+	env := &LevelDBTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+	db := env.GetDBHandle(generateLedgerID(t))
+	require.NotNil(t, db.GetChaincodeEventListener())
+
+	updates := NewUpdateBatch()
+	updates.PubUpdates.Put("ns1", "marble1", []byte(`{"docType":"marble","owner":"tom"}`), version.NewHeight(1, 1))
+	updates.PubUpdates.Put("ns1", "marble2", []byte(`{"docType":"marble","owner":"jerry"}`), version.NewHeight(1, 2))
+	updates.PvtUpdates.Put("ns1", "collectionMarbles", "marble1", []byte(`{"docType":"marble","price":10}`), version.NewHeight(1, 1))
+	updates.PvtUpdates.Put("ns1", "collectionMarbles", "marble2", []byte(`{"docType":"marble","price":20}`), version.NewHeight(1, 2))
+	require.NoError(t, db.ApplyPrivacyAwareUpdates(updates, version.NewHeight(1, 2)))
+
+	chaincodeDef := &cceventmgmt.ChaincodeDefinition{
+		Name:              "ns1",
+		CollectionConfigs: &peer.CollectionConfigPackage{Config: []*peer.CollectionConfig{createCollectionConfig("collectionMarbles")}},
+	}
+	dbArtifactsTarBytes := testutil.CreateTarBytesForTest(
+		[]*testutil.TarFileEntry{
+			{Name: "META-INF/statedb/leveldb/indexes/indexOwner.json", Body: `{"index":{"fields":["docType","owner"]},"name":"indexOwner","type":"json"}`},
+			{Name: "META-INF/statedb/leveldb/collections/collectionMarbles/indexes/indexPrice.json", Body: `{"index":{"fields":["docType","price"]},"name":"indexPrice","type":"json"}`},
+			{Name: "META-INF/statedb/couchdb/indexes/indexColor.json", Body: `{"index":{"fields":["color"]},"name":"indexColor","type":"json"}`},
+		},
+	)
+	require.NoError(t, db.HandleChaincodeDeploy(chaincodeDef, dbArtifactsTarBytes))
+
+	itr, err := db.ExecuteQuery("ns1", `{"selector":{"docType":"marble","owner":"jerry"}}`)
+	require.NoError(t, err)
+	result, err := itr.Next()
+	require.NoError(t, err)
+	require.Equal(t, "marble2", result.(*statedb.VersionedKV).Key)
+	itr.Close()
+
+	itr, err = db.ExecuteQueryOnPrivateData("ns1", "collectionMarbles", `{"selector":{"docType":"marble","price":{"$gt":15}}}`)
+	require.NoError(t, err)
+	result, err = itr.Next()
+	require.NoError(t, err)
+	require.Equal(t, "marble2", result.(*statedb.VersionedKV).Key)
+	itr.Close()
+
+	// the couchdb indexes are ignored
+	_, err = db.ExecuteQuery("ns1", `{"selector":{"color":"blue"}}`)
+	require.EqualError(t, err, `no index defined on the namespace [ns1] can serve the query [{"selector":{"color":"blue"}}]`)
+}
+
 func createCollectionConfig(collectionName string) *peer.CollectionConfig {
 	return &peer.CollectionConfig{
 		Payload: &peer.CollectionConfig_StaticCollectionConfig{
@@ -586,3 +637,90 @@ func generateLedgerID(t *testing.T) string {
 	assert.NoError(t, err)
 	return fmt.Sprintf("x%s", hex.EncodeToString(bytes))
 }
+
+func TestNewDBProviderStateEncryptionError(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "cstestenv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dbPath)
+	bookkeeperTestEnv := bookkeeping.NewTestEnv(t)
+	defer bookkeeperTestEnv.Cleanup()
+
+	_, err = NewDBProvider(
+		bookkeeperTestEnv.TestProvider,
+		&disabled.Provider{},
+		&mock.HealthCheckRegistry{},
+		&StateDBConfig{
+			StateDBConfig: &ledger.StateDBConfig{
+				Encryption: &ledger.StateEncryptionConfig{
+					KeySKIs:    []string{"0a0b"},
+					Namespaces: []string{"ns"},
+				},
+			},
+			LevelDBPath: dbPath,
+		},
+		[]string{"lscc", "_lifecycle"},
+	)
+	require.EqualError(t, err, "an encryptor is required for state encryption")
+}
+
+func TestNewDBProviderCouchDBNamespacesError(t *testing.T) {
+	bookkeeperTestEnv := bookkeeping.NewTestEnv(t)
+	defer bookkeeperTestEnv.Cleanup()
+
+	_, err := NewDBProvider(
+		bookkeeperTestEnv.TestProvider,
+		&disabled.Provider{},
+		&mock.HealthCheckRegistry{},
+		&StateDBConfig{
+			StateDBConfig: &ledger.StateDBConfig{
+				StateDatabase:     "CouchDB",
+				CouchDBNamespaces: map[string][]string{"ch1": {"ns"}},
+			},
+		},
+		[]string{"lscc", "_lifecycle"},
+	)
+	require.EqualError(t, err, "the namespaces stored in CouchDB cannot be configured when CouchDB is the state database")
+}
+
+func TestNewDBProviderMigrationErrors(t *testing.T) {
+	bookkeeperTestEnv := bookkeeping.NewTestEnv(t)
+	defer bookkeeperTestEnv.Cleanup()
+
+	tests := []struct {
+		name          string
+		stateDBConfig *ledger.StateDBConfig
+		expectedErr   string
+	}{
+		{
+			name:          "CouchDB as the source",
+			stateDBConfig: &ledger.StateDBConfig{StateDatabase: "CouchDB", MigrationTarget: "CouchDB"},
+			expectedErr:   "a state database migration requires goleveldb as the source state database, found [CouchDB]",
+		},
+		{
+			name:          "unsupported target",
+			stateDBConfig: &ledger.StateDBConfig{StateDatabase: "goleveldb", MigrationTarget: "Pebble"},
+			expectedErr:   "unsupported state database migration target [Pebble], supported target is [CouchDB]",
+		},
+		{
+			name: "CouchDB namespaces",
+			stateDBConfig: &ledger.StateDBConfig{
+				StateDatabase:     "goleveldb",
+				MigrationTarget:   "CouchDB",
+				CouchDBNamespaces: map[string][]string{"ch1": {"ns"}},
+			},
+			expectedErr: "the namespaces stored in CouchDB cannot be configured during a state database migration",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewDBProvider(
+				bookkeeperTestEnv.TestProvider,
+				&disabled.Provider{},
+				&mock.HealthCheckRegistry{},
+				&StateDBConfig{StateDBConfig: test.stateDBConfig},
+				[]string{"lscc", "_lifecycle"},
+			)
+			require.EqualError(t, err, test.expectedErr)
+		})
+	}
+}
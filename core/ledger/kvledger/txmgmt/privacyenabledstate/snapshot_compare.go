@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/pkg/errors"
+)
+
+// SnapshotKeyDiff is a key whose state differs between two snapshots
+type SnapshotKeyDiff struct {
+	// Namespace is the namespace of the key
+	Namespace string
+	// Collection is the collection of the key, and is empty for the keys of the public state
+	Collection string
+	// Key is the key, or the hash of the private data key for the keys of a collection
+	Key string
+	// First is the state of the key in the first snapshot, or nil if the key is absent from it
+	First *statedb.VersionedValue
+	// Second is the state of the key in the second snapshot, or nil if the key is absent from it
+	Second *statedb.VersionedValue
+}
+
+// CompareSnapshots compares the public state and the private state hashes present in two snapshot dirs, as generated by the
+// function `ExportPubStateAndPvtStateHashes`. The function onDiff is invoked, in the lexical order of <Namespace, Key>, for each
+// key that is present in only one of the snapshots, or whose value, metadata, or version differs between them. The public state
+// is compared first, and then the private state hashes. An error returned by onDiff stops the comparison and is returned as is
+func CompareSnapshots(snapshotDir1, snapshotDir2 string, onDiff func(*SnapshotKeyDiff) error) error {
+	if err := compareSnapshotFiles(
+		path.Join(snapshotDir1, pubStateDataFileName),
+		path.Join(snapshotDir1, pubStateMetadataFileName),
+		path.Join(snapshotDir2, pubStateDataFileName),
+		path.Join(snapshotDir2, pubStateMetadataFileName),
+		onDiff,
+	); err != nil {
+		return err
+	}
+	return compareSnapshotFiles(
+		path.Join(snapshotDir1, pvtStateHashesFileName),
+		path.Join(snapshotDir1, pvtStateHashesMetadataFileName),
+		path.Join(snapshotDir2, pvtStateHashesFileName),
+		path.Join(snapshotDir2, pvtStateHashesMetadataFileName),
+		onDiff,
+	)
+}
+
+func compareSnapshotFiles(
+	dataFilePath1, metadataFilePath1,
+	dataFilePath2, metadataFilePath2 string,
+	onDiff func(*SnapshotKeyDiff) error,
+) error {
+	r1, err := openSnapshotReader(dataFilePath1, metadataFilePath1)
+	if err != nil {
+		return err
+	}
+	defer r1.close()
+	r2, err := openSnapshotReader(dataFilePath2, metadataFilePath2)
+	if err != nil {
+		return err
+	}
+	defer r2.close()
+
+	for r1.current != nil || r2.current != nil {
+		var first, second *snapshotKV
+		switch c := compareSnapshotKVs(r1.current, r2.current); {
+		case c < 0:
+			first = r1.current
+		case c > 0:
+			second = r2.current
+		default:
+			first, second = r1.current, r2.current
+		}
+
+		if first == nil || second == nil || !bytes.Equal(first.dbValue, second.dbValue) {
+			diff, err := newSnapshotKeyDiff(r1, first, r2, second)
+			if err != nil {
+				return err
+			}
+			if err := onDiff(diff); err != nil {
+				return err
+			}
+		}
+
+		if first != nil {
+			if err := r1.next(); err != nil {
+				return err
+			}
+		}
+		if second != nil {
+			if err := r2.next(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newSnapshotKeyDiff(r1 *snapshotReader, first *snapshotKV, r2 *snapshotReader, second *snapshotKV) (*SnapshotKeyDiff, error) {
+	kv := first
+	if kv == nil {
+		kv = second
+	}
+	ck := &kv.ck
+	diff := &SnapshotKeyDiff{
+		Namespace: ck.Namespace,
+		Key:       ck.Key,
+	}
+	if i := strings.Index(ck.Namespace, nsJoiner+hashDataPrefix); i >= 0 {
+		diff.Namespace = ck.Namespace[:i]
+		diff.Collection = ck.Namespace[i+len(nsJoiner+hashDataPrefix):]
+	}
+
+	var err error
+	if first != nil {
+		if diff.First, err = stateleveldb.DecodeFullScanValue(r1.dbValueFormat, first.dbValue); err != nil {
+			return nil, errors.WithMessagef(err, "error decoding the value of key [%s] in namespace [%s]", ck.Key, ck.Namespace)
+		}
+	}
+	if second != nil {
+		if diff.Second, err = stateleveldb.DecodeFullScanValue(r2.dbValueFormat, second.dbValue); err != nil {
+			return nil, errors.WithMessagef(err, "error decoding the value of key [%s] in namespace [%s]", ck.Key, ck.Namespace)
+		}
+	}
+	return diff, nil
+}
+
+// compareSnapshotKVs compares the keys of two tuples, where a nil tuple, i.e., an exhausted reader, sorts last
+func compareSnapshotKVs(kv1, kv2 *snapshotKV) int {
+	switch {
+	case kv2 == nil:
+		return -1
+	case kv1 == nil:
+		return 1
+	default:
+		return compareCompositeKeys(&kv1.ck, &kv2.ck)
+	}
+}
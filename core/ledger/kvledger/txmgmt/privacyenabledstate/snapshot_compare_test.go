@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	env := &LevelDBTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+
+	newHasher := func() hash.Hash {
+		return sha256.New()
+	}
+	exportSnapshot := func(db *DB) string {
+		dir, err := ioutil.TempDir("", "testsnapshot")
+		require.NoError(t, err)
+		_, err = db.ExportPubStateAndPvtStateHashes(dir, newHasher)
+		require.NoError(t, err)
+		return dir
+	}
+
+	db1 := env.GetDBHandle(generateLedgerID(t))
+	updateBatch := NewUpdateBatch()
+	updateBatch.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.PutValAndMetadata("ns1", "key3", []byte("value3"), []byte("metadata3"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns2", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll1", []byte("key1"), []byte("valuehash1"), version.NewHeight(1, 1))
+	require.NoError(t, db1.ApplyPrivacyAwareUpdates(updateBatch, version.NewHeight(1, 1)))
+	snapshotDir1 := exportSnapshot(db1)
+	defer os.RemoveAll(snapshotDir1)
+
+	db2 := env.GetDBHandle(generateLedgerID(t))
+	updateBatch = NewUpdateBatch()
+	updateBatch.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns1", "key2", []byte("value2-diverged"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.PutValAndMetadata("ns1", "key3", []byte("value3"), []byte("metadata3"), version.NewHeight(1, 2))
+	updateBatch.PubUpdates.Put("ns3", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll1", []byte("key1"), []byte("valuehash1"), version.NewHeight(1, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll1", []byte("key2"), []byte("valuehash2"), version.NewHeight(1, 1))
+	require.NoError(t, db2.ApplyPrivacyAwareUpdates(updateBatch, version.NewHeight(1, 2)))
+	snapshotDir2 := exportSnapshot(db2)
+	defer os.RemoveAll(snapshotDir2)
+
+	var diffs []*SnapshotKeyDiff
+	collectDiffs := func(diff *SnapshotKeyDiff) error {
+		diffs = append(diffs, diff)
+		return nil
+	}
+
+	require.NoError(t, CompareSnapshots(snapshotDir1, snapshotDir2, collectDiffs))
+	require.Equal(t,
+		[]*SnapshotKeyDiff{
+			{
+				Namespace: "ns1",
+				Key:       "key2",
+				First:     &statedb.VersionedValue{Value: []byte("value2"), Version: version.NewHeight(1, 1)},
+				Second:    &statedb.VersionedValue{Value: []byte("value2-diverged"), Version: version.NewHeight(1, 1)},
+			},
+			{
+				Namespace: "ns1",
+				Key:       "key3",
+				First:     &statedb.VersionedValue{Value: []byte("value3"), Metadata: []byte("metadata3"), Version: version.NewHeight(1, 1)},
+				Second:    &statedb.VersionedValue{Value: []byte("value3"), Metadata: []byte("metadata3"), Version: version.NewHeight(1, 2)},
+			},
+			{
+				Namespace: "ns2",
+				Key:       "key1",
+				First:     &statedb.VersionedValue{Value: []byte("value1"), Version: version.NewHeight(1, 1)},
+			},
+			{
+				Namespace: "ns3",
+				Key:       "key1",
+				Second:    &statedb.VersionedValue{Value: []byte("value1"), Version: version.NewHeight(1, 1)},
+			},
+			{
+				Namespace:  "ns1",
+				Collection: "coll1",
+				Key:        "key2",
+				Second:     &statedb.VersionedValue{Value: []byte("valuehash2"), Version: version.NewHeight(1, 1)},
+			},
+		},
+		diffs,
+	)
+
+	// a snapshot does not differ from itself
+	diffs = nil
+	require.NoError(t, CompareSnapshots(snapshotDir1, snapshotDir1, collectDiffs))
+	require.Empty(t, diffs)
+
+	// an error returned by onDiff stops the comparison
+	calls := 0
+	err := CompareSnapshots(snapshotDir1, snapshotDir2, func(*SnapshotKeyDiff) error {
+		calls++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, calls)
+
+	err = CompareSnapshots(snapshotDir1, path.Join(snapshotDir2, "non-existent"), collectDiffs)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "non-existent")
+}
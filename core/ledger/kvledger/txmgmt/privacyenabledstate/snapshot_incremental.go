@@ -0,0 +1,398 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"bytes"
+	"hash"
+	"path"
+
+	"github.com/hyperledger/fabric/common/ledger/snapshot"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+)
+
+const (
+	pubStateDeltaDataFileName           = "public_state_delta.data"
+	pubStateDeltaMetadataFileName       = "public_state_delta.metadata"
+	pvtStateHashesDeltaFileName         = "private_state_hashes_delta.data"
+	pvtStateHashesDeltaMetadataFileName = "private_state_hashes_delta.metadata"
+)
+
+// ExportIncrementalPubStateAndPvtStateHashes generates the delta between the current state and the state captured in a
+// previously generated snapshot, present in the baseSnapshotDir. Four files are generated in the specified dir. The files,
+// public_state_delta.data and public_state_delta.metadata contain the delta of the public state and the files
+// private_state_hashes_delta.data and private_state_hashes_delta.metadata contain the delta of the private state hashes.
+// The format of these files is the same as that of the files generated by the function `ExportPubStateAndPvtStateHashes`,
+// except that the data files contain only the keys that have been added, updated, or deleted since the base snapshot.
+// A deleted key is represented by an empty dbValue. The files generated by this function can be combined with the base
+// snapshot files by the function `MergeIncrementalPubStateAndPvtStateHashes` for reconstructing the full snapshot files
+func (s *DB) ExportIncrementalPubStateAndPvtStateHashes(dir, baseSnapshotDir string, newHasher func() hash.Hash) (map[string][]byte, error) {
+	itr, dbValueFormat, err := s.GetFullScanIterator(isPvtdataNs)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	pubStateDiffer, err := newSnapshotDiffer(
+		path.Join(baseSnapshotDir, pubStateDataFileName),
+		path.Join(baseSnapshotDir, pubStateMetadataFileName),
+		path.Join(dir, pubStateDeltaDataFileName),
+		path.Join(dir, pubStateDeltaMetadataFileName),
+		dbValueFormat,
+		newHasher,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pubStateDiffer.close()
+
+	pvtStateHashesDiffer, err := newSnapshotDiffer(
+		path.Join(baseSnapshotDir, pvtStateHashesFileName),
+		path.Join(baseSnapshotDir, pvtStateHashesMetadataFileName),
+		path.Join(dir, pvtStateHashesDeltaFileName),
+		path.Join(dir, pvtStateHashesDeltaMetadataFileName),
+		dbValueFormat,
+		newHasher,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer pvtStateHashesDiffer.close()
+
+	for {
+		compositeKey, dbValue, err := itr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if compositeKey == nil {
+			break
+		}
+		switch {
+		case isHashedDataNs(compositeKey.Namespace):
+			if err := pvtStateHashesDiffer.addCurrentData(compositeKey, dbValue); err != nil {
+				return nil, err
+			}
+		default:
+			if err := pubStateDiffer.addCurrentData(compositeKey, dbValue); err != nil {
+				return nil, err
+			}
+		}
+	}
+	pubStateDeltaDataHash, pubStateDeltaMetadataHash, err := pubStateDiffer.done()
+	if err != nil {
+		return nil, err
+	}
+	pvtStateHashesDeltaDataHash, pvtStateHashesDeltaMetadataHash, err := pvtStateHashesDiffer.done()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+			pubStateDeltaDataFileName:           pubStateDeltaDataHash,
+			pubStateDeltaMetadataFileName:       pubStateDeltaMetadataHash,
+			pvtStateHashesDeltaFileName:         pvtStateHashesDeltaDataHash,
+			pvtStateHashesDeltaMetadataFileName: pvtStateHashesDeltaMetadataHash,
+		},
+		nil
+}
+
+// MergeIncrementalPubStateAndPvtStateHashes combines the snapshot files present in the baseSnapshotDir with the delta
+// files present in the incrementalSnapshotDir (as generated by the function `ExportIncrementalPubStateAndPvtStateHashes`)
+// and generates, in the specified dir, the files that are identical to the ones that the function
+// `ExportPubStateAndPvtStateHashes` would have generated at the height of the incremental snapshot. This allows a peer
+// to bootstrap a channel from an older full snapshot and a series of smaller incremental snapshots
+func MergeIncrementalPubStateAndPvtStateHashes(dir, baseSnapshotDir, incrementalSnapshotDir string, newHasher func() hash.Hash) (map[string][]byte, error) {
+	pubStateDataHash, pubStateMetadataHash, err := mergeSnapshotFiles(
+		path.Join(baseSnapshotDir, pubStateDataFileName),
+		path.Join(baseSnapshotDir, pubStateMetadataFileName),
+		path.Join(incrementalSnapshotDir, pubStateDeltaDataFileName),
+		path.Join(incrementalSnapshotDir, pubStateDeltaMetadataFileName),
+		path.Join(dir, pubStateDataFileName),
+		path.Join(dir, pubStateMetadataFileName),
+		newHasher,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pvtStateHashesDataHash, pvtStateHashesMetadataHash, err := mergeSnapshotFiles(
+		path.Join(baseSnapshotDir, pvtStateHashesFileName),
+		path.Join(baseSnapshotDir, pvtStateHashesMetadataFileName),
+		path.Join(incrementalSnapshotDir, pvtStateHashesDeltaFileName),
+		path.Join(incrementalSnapshotDir, pvtStateHashesDeltaMetadataFileName),
+		path.Join(dir, pvtStateHashesFileName),
+		path.Join(dir, pvtStateHashesMetadataFileName),
+		newHasher,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+			pubStateDataFileName:           pubStateDataHash,
+			pubStateMetadataFileName:       pubStateMetadataHash,
+			pvtStateHashesFileName:         pvtStateHashesDataHash,
+			pvtStateHashesMetadataFileName: pvtStateHashesMetadataHash,
+		},
+		nil
+}
+
+// snapshotDiffer consumes the current state, in the sort order of the full scan iterator, and compares it with
+// the state present in a previously generated pair of snapshot files. The differences are written as a pair of delta files
+type snapshotDiffer struct {
+	base  *snapshotReader
+	delta *snapshotWriter
+}
+
+func newSnapshotDiffer(
+	baseDataFilePath, baseMetadataFilePath string,
+	deltaDataFilePath, deltaMetadataFilePath string,
+	dbValueFormat byte,
+	newHasher func() hash.Hash,
+) (*snapshotDiffer, error) {
+	base, err := openSnapshotReader(baseDataFilePath, baseMetadataFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if base.dbValueFormat != dbValueFormat {
+		base.close()
+		return nil, errors.Errorf(
+			"the db value format [%x] in the base snapshot file [%s] does not match with the current db value format [%x]",
+			base.dbValueFormat, baseDataFilePath, dbValueFormat,
+		)
+	}
+	delta, err := newSnapshotWriter(deltaDataFilePath, deltaMetadataFilePath, dbValueFormat, newHasher)
+	if err != nil {
+		base.close()
+		return nil, err
+	}
+	return &snapshotDiffer{
+		base:  base,
+		delta: delta,
+	}, nil
+}
+
+func (d *snapshotDiffer) addCurrentData(ck *statedb.CompositeKey, dbValue []byte) error {
+	// the keys present in the base snapshot that sort before the current key have been deleted since
+	if err := d.writeDeletesBefore(ck); err != nil {
+		return err
+	}
+	if d.base.current != nil && compareCompositeKeys(&d.base.current.ck, ck) == 0 {
+		unchanged := bytes.Equal(d.base.current.dbValue, dbValue)
+		if err := d.base.next(); err != nil {
+			return err
+		}
+		if unchanged {
+			return nil
+		}
+	}
+	return d.delta.addData(ck, dbValue)
+}
+
+func (d *snapshotDiffer) writeDeletesBefore(ck *statedb.CompositeKey) error {
+	for d.base.current != nil && (ck == nil || compareCompositeKeys(&d.base.current.ck, ck) < 0) {
+		if err := d.delta.addData(&d.base.current.ck, nil); err != nil {
+			return err
+		}
+		if err := d.base.next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *snapshotDiffer) done() ([]byte, []byte, error) {
+	if err := d.writeDeletesBefore(nil); err != nil {
+		return nil, nil, err
+	}
+	return d.delta.done()
+}
+
+func (d *snapshotDiffer) close() {
+	if d == nil {
+		return
+	}
+	d.base.close()
+	d.delta.close()
+}
+
+func mergeSnapshotFiles(
+	baseDataFilePath, baseMetadataFilePath string,
+	deltaDataFilePath, deltaMetadataFilePath string,
+	dataFilePath, metadataFilePath string,
+	newHasher func() hash.Hash,
+) ([]byte, []byte, error) {
+	base, err := openSnapshotReader(baseDataFilePath, baseMetadataFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer base.close()
+
+	delta, err := openSnapshotReader(deltaDataFilePath, deltaMetadataFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer delta.close()
+
+	if base.dbValueFormat != delta.dbValueFormat {
+		return nil, nil, errors.Errorf(
+			"the db value format [%x] in the incremental snapshot file [%s] does not match with the db value format [%x] in the base snapshot file [%s]",
+			delta.dbValueFormat, deltaDataFilePath, base.dbValueFormat, baseDataFilePath,
+		)
+	}
+
+	merged, err := newSnapshotWriter(dataFilePath, metadataFilePath, base.dbValueFormat, newHasher)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer merged.close()
+
+	for base.current != nil || delta.current != nil {
+		var r *snapshotReader
+		switch {
+		case delta.current == nil:
+			r = base
+		case base.current == nil:
+			r = delta
+		default:
+			switch c := compareCompositeKeys(&base.current.ck, &delta.current.ck); {
+			case c < 0:
+				r = base
+			case c > 0:
+				r = delta
+			default:
+				// the key is present in both, the delta supersedes the base
+				if err := base.next(); err != nil {
+					return nil, nil, err
+				}
+				r = delta
+			}
+		}
+		if len(r.current.dbValue) != 0 {
+			if err := merged.addData(&r.current.ck, r.current.dbValue); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := r.next(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return merged.done()
+}
+
+// snapshotReader reads the tuples <key, dbValue> from a pair of snapshot files generated by the snapshotWriter.
+// The field `current` holds the tuple that is read most recently and is nil when the data is exhausted
+type snapshotReader struct {
+	dataFile                *snapshot.FileReader
+	metadataFile            *snapshot.FileReader
+	dbValueFormat           byte
+	numNamespacesRemaining  uint64
+	namespace               string
+	numKVsRemainingInCurrNs uint64
+	current                 *snapshotKV
+}
+
+type snapshotKV struct {
+	ck      statedb.CompositeKey
+	dbValue []byte
+}
+
+func openSnapshotReader(dataFilePath, metadataFilePath string) (*snapshotReader, error) {
+	var dataFile, metadataFile *snapshot.FileReader
+	var err error
+	defer func() {
+		if err != nil {
+			dataFile.Close()
+			metadataFile.Close()
+		}
+	}()
+
+	dataFile, err = snapshot.OpenFile(dataFilePath, snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	var dbValueFormat []byte
+	if dbValueFormat, err = dataFile.DecodeBytes(); err != nil {
+		return nil, err
+	}
+	if len(dbValueFormat) != 1 {
+		err = errors.Errorf("invalid db value format in the snapshot file [%s]", dataFilePath)
+		return nil, err
+	}
+
+	metadataFile, err = snapshot.OpenFile(metadataFilePath, snapshotFileFormat)
+	if err != nil {
+		return nil, err
+	}
+	var numNamespaces uint64
+	if numNamespaces, err = metadataFile.DecodeUVarInt(); err != nil {
+		return nil, err
+	}
+
+	r := &snapshotReader{
+		dataFile:               dataFile,
+		metadataFile:           metadataFile,
+		dbValueFormat:          dbValueFormat[0],
+		numNamespacesRemaining: numNamespaces,
+	}
+	if err = r.next(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *snapshotReader) next() error {
+	for r.numKVsRemainingInCurrNs == 0 {
+		if r.numNamespacesRemaining == 0 {
+			r.current = nil
+			return nil
+		}
+		ns, err := r.metadataFile.DecodeString()
+		if err != nil {
+			return err
+		}
+		numKVs, err := r.metadataFile.DecodeUVarInt()
+		if err != nil {
+			return err
+		}
+		r.namespace = ns
+		r.numKVsRemainingInCurrNs = numKVs
+		r.numNamespacesRemaining--
+	}
+	key, err := r.dataFile.DecodeString()
+	if err != nil {
+		return err
+	}
+	dbValue, err := r.dataFile.DecodeBytes()
+	if err != nil {
+		return err
+	}
+	r.numKVsRemainingInCurrNs--
+	r.current = &snapshotKV{
+		ck: statedb.CompositeKey{
+			Namespace: r.namespace,
+			Key:       key,
+		},
+		dbValue: dbValue,
+	}
+	return nil
+}
+
+func (r *snapshotReader) close() {
+	if r == nil {
+		return
+	}
+	r.dataFile.Close()
+	r.metadataFile.Close()
+}
+
+// compareCompositeKeys compares the composite keys in the sort order of the full scan iterator,
+// i.e., the lexical order of <Namespace, Key>
+func compareCompositeKeys(ck1, ck2 *statedb.CompositeKey) int {
+	if c := bytes.Compare([]byte(ck1.Namespace), []byte(ck2.Namespace)); c != 0 {
+		return c
+	}
+	return bytes.Compare([]byte(ck1.Key), []byte(ck2.Key))
+}
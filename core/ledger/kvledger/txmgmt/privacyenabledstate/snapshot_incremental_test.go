@@ -0,0 +1,187 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalSnapshot(t *testing.T) {
+	env := &LevelDBTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+	db := env.GetDBHandle(generateLedgerID(t))
+
+	newHasher := func() hash.Hash {
+		return sha256.New()
+	}
+	newSnapshotDir := func() string {
+		dir, err := ioutil.TempDir("", "testsnapshot")
+		require.NoError(t, err)
+		return dir
+	}
+
+	updateBatch := NewUpdateBatch()
+	updateBatch.PubUpdates.PutValAndMetadata("ns1", "key1", []byte("value1"), []byte("metadata1"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns1", "key3", []byte("value3"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns2", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.PubUpdates.Put("ns4", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll1", []byte("key1"), []byte("valuehash1"), version.NewHeight(1, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll1", []byte("key2"), []byte("valuehash2"), version.NewHeight(1, 1))
+	updateBatch.PvtUpdates.Put("ns1", "coll1", "key1", []byte("pvt-value1"), version.NewHeight(1, 1))
+	require.NoError(t, db.ApplyPrivacyAwareUpdates(updateBatch, version.NewHeight(1, 1)))
+
+	baseSnapshotDir := newSnapshotDir()
+	defer os.RemoveAll(baseSnapshotDir)
+	_, err := db.ExportPubStateAndPvtStateHashes(baseSnapshotDir, newHasher)
+	require.NoError(t, err)
+
+	updateBatch = NewUpdateBatch()
+	updateBatch.PubUpdates.Put("ns1", "key1", []byte("value1-updated"), version.NewHeight(2, 1))
+	updateBatch.PubUpdates.Delete("ns1", "key2", version.NewHeight(2, 1))
+	updateBatch.PubUpdates.Put("ns1", "key4", []byte("value4"), version.NewHeight(2, 1))
+	updateBatch.PubUpdates.Delete("ns2", "key1", version.NewHeight(2, 1))
+	updateBatch.PubUpdates.Put("ns3", "key1", []byte("value1"), version.NewHeight(2, 1))
+	updateBatch.HashUpdates.Delete("ns1", "coll1", []byte("key2"), version.NewHeight(2, 1))
+	updateBatch.HashUpdates.Put("ns1", "coll2", []byte("key1"), []byte("valuehash1"), version.NewHeight(2, 1))
+	updateBatch.PvtUpdates.Put("ns1", "coll2", "key1", []byte("pvt-value1"), version.NewHeight(2, 1))
+	require.NoError(t, db.ApplyPrivacyAwareUpdates(updateBatch, version.NewHeight(2, 1)))
+
+	incrementalSnapshotDir := newSnapshotDir()
+	defer os.RemoveAll(incrementalSnapshotDir)
+	filesAndHashes, err := db.ExportIncrementalPubStateAndPvtStateHashes(incrementalSnapshotDir, baseSnapshotDir, newHasher)
+	require.NoError(t, err)
+	require.Len(t, filesAndHashes, 4)
+	for f, h := range filesAndHashes {
+		expectedFile := path.Join(incrementalSnapshotDir, f)
+		require.FileExists(t, expectedFile)
+		require.Equal(t, sha256ForFileForTest(t, expectedFile), h)
+	}
+
+	// the delta contains only the updated, added, and deleted keys; deleted keys carry an empty value
+	pubStateDelta := loadDeltaForTest(t,
+		path.Join(incrementalSnapshotDir, pubStateDeltaDataFileName),
+		path.Join(incrementalSnapshotDir, pubStateDeltaMetadataFileName),
+	)
+	require.Equal(t,
+		map[statedb.CompositeKey]bool{
+			{Namespace: "ns1", Key: "key1"}: false,
+			{Namespace: "ns1", Key: "key2"}: true,
+			{Namespace: "ns1", Key: "key4"}: false,
+			{Namespace: "ns2", Key: "key1"}: true,
+			{Namespace: "ns3", Key: "key1"}: false,
+		},
+		pubStateDelta,
+	)
+	pvtStateHashesDelta := loadDeltaForTest(t,
+		path.Join(incrementalSnapshotDir, pvtStateHashesDeltaFileName),
+		path.Join(incrementalSnapshotDir, pvtStateHashesDeltaMetadataFileName),
+	)
+	require.Equal(t,
+		map[statedb.CompositeKey]bool{
+			{Namespace: deriveHashedDataNs("ns1", "coll1"), Key: "key2"}: true,
+			{Namespace: deriveHashedDataNs("ns1", "coll2"), Key: "key1"}: false,
+		},
+		pvtStateHashesDelta,
+	)
+
+	// merging the base and the incremental snapshots produces the full snapshot at the latest height
+	fullSnapshotDir := newSnapshotDir()
+	defer os.RemoveAll(fullSnapshotDir)
+	expectedFilesAndHashes, err := db.ExportPubStateAndPvtStateHashes(fullSnapshotDir, newHasher)
+	require.NoError(t, err)
+
+	mergedSnapshotDir := newSnapshotDir()
+	defer os.RemoveAll(mergedSnapshotDir)
+	mergedFilesAndHashes, err := MergeIncrementalPubStateAndPvtStateHashes(mergedSnapshotDir, baseSnapshotDir, incrementalSnapshotDir, newHasher)
+	require.NoError(t, err)
+	require.Equal(t, expectedFilesAndHashes, mergedFilesAndHashes)
+	for f, h := range mergedFilesAndHashes {
+		require.Equal(t, sha256ForFileForTest(t, path.Join(mergedSnapshotDir, f)), h)
+	}
+
+	// an incremental snapshot with no changes since the base is empty
+	emptyIncrementalSnapshotDir := newSnapshotDir()
+	defer os.RemoveAll(emptyIncrementalSnapshotDir)
+	_, err = db.ExportIncrementalPubStateAndPvtStateHashes(emptyIncrementalSnapshotDir, fullSnapshotDir, newHasher)
+	require.NoError(t, err)
+	require.Empty(t, loadDeltaForTest(t,
+		path.Join(emptyIncrementalSnapshotDir, pubStateDeltaDataFileName),
+		path.Join(emptyIncrementalSnapshotDir, pubStateDeltaMetadataFileName),
+	))
+}
+
+func TestIncrementalSnapshotErrors(t *testing.T) {
+	env := &LevelDBTestEnv{}
+	env.Init(t)
+	defer env.Cleanup()
+	db := env.GetDBHandle(generateLedgerID(t))
+	newHasher := func() hash.Hash {
+		return sha256.New()
+	}
+
+	baseSnapshotDir, err := ioutil.TempDir("", "testsnapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(baseSnapshotDir)
+	incrementalSnapshotDir, err := ioutil.TempDir("", "testsnapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(incrementalSnapshotDir)
+	mergedSnapshotDir, err := ioutil.TempDir("", "testsnapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(mergedSnapshotDir)
+
+	// base snapshot files missing
+	_, err = db.ExportIncrementalPubStateAndPvtStateHashes(incrementalSnapshotDir, baseSnapshotDir, newHasher)
+	require.Contains(t, err.Error(), "error while opening the snapshot file: "+path.Join(baseSnapshotDir, pubStateDataFileName))
+	_, err = MergeIncrementalPubStateAndPvtStateHashes(mergedSnapshotDir, baseSnapshotDir, incrementalSnapshotDir, newHasher)
+	require.Contains(t, err.Error(), "error while opening the snapshot file: "+path.Join(baseSnapshotDir, pubStateDataFileName))
+
+	// incremental snapshot files missing
+	_, err = db.ExportPubStateAndPvtStateHashes(baseSnapshotDir, newHasher)
+	require.NoError(t, err)
+	_, err = MergeIncrementalPubStateAndPvtStateHashes(mergedSnapshotDir, baseSnapshotDir, incrementalSnapshotDir, newHasher)
+	require.Contains(t, err.Error(), "error while opening the snapshot file: "+path.Join(incrementalSnapshotDir, pubStateDeltaDataFileName))
+
+	// base snapshot generated with a different db value format
+	otherDir, err := ioutil.TempDir("", "testsnapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(otherDir)
+	w, err := newSnapshotWriter(
+		path.Join(otherDir, pubStateDataFileName),
+		path.Join(otherDir, pubStateMetadataFileName),
+		byte(0xff),
+		newHasher,
+	)
+	require.NoError(t, err)
+	_, _, err = w.done()
+	require.NoError(t, err)
+	_, err = db.ExportIncrementalPubStateAndPvtStateHashes(incrementalSnapshotDir, otherDir, newHasher)
+	require.Contains(t, err.Error(), "does not match with the current db value format")
+}
+
+// loadDeltaForTest returns the keys present in the delta files mapped to a flag that indicates a delete
+func loadDeltaForTest(t *testing.T, dataFilePath, metadataFilePath string) map[statedb.CompositeKey]bool {
+	r, err := openSnapshotReader(dataFilePath, metadataFilePath)
+	require.NoError(t, err)
+	defer r.close()
+	delta := map[statedb.CompositeKey]bool{}
+	for r.current != nil {
+		delta[r.current.ck] = len(r.current.dbValue) == 0
+		require.NoError(t, r.next())
+	}
+	return delta
+}
@@ -9,50 +9,91 @@ package statecouchdb
 import (
 	"github.com/VictoriaMetrics/fastcache"
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultSysCacheSizeMBs is the size of the system state cache if not configured
+	defaultSysCacheSizeMBs = 64
+
+	// evictionPolicyFIFO evicts the oldest entries first. This is the eviction of fastcache,
+	// which overwrites the oldest chunk of a bucket when the bucket is full
+	evictionPolicyFIFO = "fifo"
+	// evictionPolicyLRU evicts the least recently read or written entries first
+	evictionPolicyLRU = "lru"
 )
 
 var (
 	keySep = []byte{0x00}
 )
 
+// cacheStore is the storage of the cache entries. It is implemented by fastcache, which is
+// used for the FIFO eviction policy, and by lruCache, which is used for the LRU eviction policy
+type cacheStore interface {
+	Get(dst, k []byte) []byte
+	Set(k, v []byte)
+	Del(k []byte)
+	Reset()
+}
+
 // cache holds both the system and user cache
 type cache struct {
-	sysCache      *fastcache.Cache
-	usrCache      *fastcache.Cache
-	sysNamespaces []string
+	sysCache           cacheStore
+	usrCache           cacheStore
+	sysNamespaces      []string
+	uncachedNamespaces []string
+	stats              *stats
 }
 
-// newCache creates a Cache. The cache consists of both system state cache (for lscc, _lifecycle)
-// and user state cache (for all user deployed chaincodes). The size of the
-// system state cache is 64 MB, by default. The size of the user state cache, in terms of MB, is
-// specified via usrCacheSize parameter. Note that the maximum memory consumption of fastcache
-// would be in the multiples of 32 MB (due to 512 buckets & an equal number of 64 KB chunks per bucket).
-// If the usrCacheSizeMBs is not a multiple of 32 MB, the fastcache would round the size
-// to the next multiple of 32 MB.
-func newCache(usrCacheSizeMBs int, sysNamespaces []string) *cache {
-	cache := &cache{}
-	// By default, 64 MB is allocated for the system cache
-	cache.sysCache = fastcache.New(64 * 1024 * 1024)
-	cache.sysNamespaces = sysNamespaces
+// newCache creates a Cache. The cache consists of both system state cache (for lscc, _lifecycle
+// and the namespaces pinned via the configuration) and user state cache (for all other user deployed
+// chaincodes). Because the pinned namespaces do not share the cache with the other chaincodes, their
+// entries are not evicted by the reads of the other chaincodes. The size of the system state cache is
+// 64 MB, by default. The size of the user state cache, in terms of MB, is specified via UserCacheSizeMBs.
+// Note that, with the FIFO eviction policy, the maximum memory consumption of fastcache would be in the
+// multiples of 32 MB (due to 512 buckets & an equal number of 64 KB chunks per bucket). If the size is
+// not a multiple of 32 MB, the fastcache would round the size to the next multiple of 32 MB.
+func newCache(conf *ledger.CouchDBConfig, sysNamespaces []string, stats *stats) (*cache, error) {
+	var newStore func(sizeMBs int) cacheStore
+	switch conf.CacheEvictionPolicy {
+	case "", evictionPolicyFIFO:
+		newStore = func(sizeMBs int) cacheStore {
+			return fastcache.New(sizeMBs * 1024 * 1024)
+		}
+	case evictionPolicyLRU:
+		newStore = func(sizeMBs int) cacheStore {
+			return newLRUCache(sizeMBs * 1024 * 1024)
+		}
+	default:
+		return nil, errors.Errorf("unsupported cache eviction policy [%s], supported policies are [%s] and [%s]",
+			conf.CacheEvictionPolicy, evictionPolicyFIFO, evictionPolicyLRU)
+	}
+
+	cache := &cache{
+		sysNamespaces:      append(append([]string{}, sysNamespaces...), conf.PinnedCacheNamespaces...),
+		uncachedNamespaces: conf.UncachedNamespaces,
+		stats:              stats,
+	}
+	sysCacheSizeMBs := conf.SysCacheSizeMBs
+	if sysCacheSizeMBs <= 0 {
+		sysCacheSizeMBs = defaultSysCacheSizeMBs
+	}
+	cache.sysCache = newStore(sysCacheSizeMBs)
 
 	// User passed size is used to allocate memory for the user cache
-	if usrCacheSizeMBs <= 0 {
-		return cache
+	if conf.UserCacheSizeMBs <= 0 {
+		return cache, nil
 	}
-	cache.usrCache = fastcache.New(usrCacheSizeMBs * 1024 * 1024)
-	return cache
+	cache.usrCache = newStore(conf.UserCacheSizeMBs)
+	return cache, nil
 }
 
 // enabled returns true if the cache is enabled for a given namespace.
-// Namespace can be of two types: system namespace (such as lscc) and user
-// namespace (all user's chaincode states).
+// Namespace can be of two types: system namespace (such as lscc and the
+// pinned namespaces) and user namespace (all user's chaincode states).
 func (c *cache) enabled(namespace string) bool {
-	for _, ns := range c.sysNamespaces {
-		if namespace == ns {
-			return true
-		}
-	}
-	return c.usrCache != nil
+	return c.getCache(namespace) != nil
 }
 
 // getState returns the value for a given namespace and key from
@@ -65,6 +106,7 @@ func (c *cache) getState(chainID, namespace, key string) (*CacheValue, error) {
 
 	cacheKey := constructCacheKey(chainID, namespace, key)
 	valBytes := cache.Get(nil, cacheKey)
+	c.stats.observeCacheLookup(chainID, namespace, valBytes != nil)
 	if valBytes == nil {
 		return nil, nil
 	}
@@ -146,12 +188,17 @@ func (c *cache) Reset() {
 	}
 }
 
-func (c *cache) getCache(namespace string) *fastcache.Cache {
+func (c *cache) getCache(namespace string) cacheStore {
 	for _, ns := range c.sysNamespaces {
 		if namespace == ns {
 			return c.sysCache
 		}
 	}
+	for _, ns := range c.uncachedNamespaces {
+		if namespace == ns {
+			return nil
+		}
+	}
 	return c.usrCache
 }
 
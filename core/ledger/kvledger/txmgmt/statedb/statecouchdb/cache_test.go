@@ -11,37 +11,129 @@ import (
 
 	"github.com/VictoriaMetrics/fastcache"
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/stretchr/testify/require"
 )
 
 var sysNamespaces = []string{"lscc", "_lifecycle"}
 
 func TestNewCache(t *testing.T) {
-	c := newCache(32, sysNamespaces)
-	expectedCache := &cache{
-		sysCache:      fastcache.New(64 * 1024 * 1024),
-		usrCache:      fastcache.New(32 * 1024 * 1024),
-		sysNamespaces: sysNamespaces,
-	}
-	require.Equal(t, expectedCache, c)
+	c := newTestCache(t, &ledger.CouchDBConfig{UserCacheSizeMBs: 32})
+	require.Equal(t, fastcache.New(64*1024*1024), c.sysCache)
+	require.Equal(t, fastcache.New(32*1024*1024), c.usrCache)
+	require.Equal(t, sysNamespaces, c.sysNamespaces)
 	require.True(t, c.enabled("lscc"))
 	require.True(t, c.enabled("_lifecycle"))
 	require.True(t, c.enabled("xyz"))
 
-	c = newCache(0, sysNamespaces)
-	expectedCache = &cache{
-		sysCache:      fastcache.New(64 * 1024 * 1024),
-		usrCache:      nil,
-		sysNamespaces: sysNamespaces,
-	}
-	require.Equal(t, expectedCache, c)
+	c = newTestCache(t, &ledger.CouchDBConfig{})
+	require.Equal(t, fastcache.New(64*1024*1024), c.sysCache)
+	require.Nil(t, c.usrCache)
 	require.True(t, c.enabled("lscc"))
 	require.True(t, c.enabled("_lifecycle"))
 	require.False(t, c.enabled("xyz"))
+
+	c = newTestCache(t, &ledger.CouchDBConfig{
+		UserCacheSizeMBs:      32,
+		SysCacheSizeMBs:       1,
+		CacheEvictionPolicy:   "lru",
+		PinnedCacheNamespaces: []string{"mycc"},
+		UncachedNamespaces:    []string{"bigcc"},
+	})
+	require.Equal(t, newLRUCache(1024*1024), c.sysCache)
+	require.Equal(t, newLRUCache(32*1024*1024), c.usrCache)
+	require.Equal(t, []string{"lscc", "_lifecycle", "mycc"}, c.sysNamespaces)
+	require.Equal(t, []string{"lscc", "_lifecycle"}, sysNamespaces)
+	require.True(t, c.enabled("mycc"))
+	require.True(t, c.enabled("xyz"))
+	require.False(t, c.enabled("bigcc"))
+
+	_, err := newCache(&ledger.CouchDBConfig{CacheEvictionPolicy: "random"}, sysNamespaces, newStats(&disabled.Provider{}))
+	require.EqualError(t, err, "unsupported cache eviction policy [random], supported policies are [fifo] and [lru]")
+}
+
+func TestPinnedAndUncachedNamespaces(t *testing.T) {
+	c := newTestCache(t, &ledger.CouchDBConfig{
+		UserCacheSizeMBs:      32,
+		PinnedCacheNamespaces: []string{"mycc"},
+		UncachedNamespaces:    []string{"bigcc"},
+	})
+	value := &CacheValue{Value: []byte("value1")}
+	for _, ns := range []string{"mycc", "bigcc", "ns1"} {
+		require.NoError(t, c.putState("ch1", ns, "k1", value))
+	}
+
+	require.NotNil(t, c.sysCache.Get(nil, constructCacheKey("ch1", "mycc", "k1")))
+	require.Nil(t, c.usrCache.Get(nil, constructCacheKey("ch1", "mycc", "k1")))
+	require.NotNil(t, c.usrCache.Get(nil, constructCacheKey("ch1", "ns1", "k1")))
+
+	v, err := c.getState("ch1", "bigcc", "k1")
+	require.NoError(t, err)
+	require.Nil(t, v)
+	require.NoError(t, c.UpdateStates("ch1", cacheUpdates{"bigcc": cacheKVs{"k1": value}}))
+	require.Nil(t, c.usrCache.Get(nil, constructCacheKey("ch1", "bigcc", "k1")))
+}
+
+func TestCacheHitAndMissMetrics(t *testing.T) {
+	stats := newStats(&disabled.Provider{})
+	hits := &metricsfakes.Counter{}
+	hits.WithReturns(hits)
+	misses := &metricsfakes.Counter{}
+	misses.WithReturns(misses)
+	stats.stateCacheHits = hits
+	stats.stateCacheMisses = misses
+
+	c, err := newCache(&ledger.CouchDBConfig{UserCacheSizeMBs: 32}, sysNamespaces, stats)
+	require.NoError(t, err)
+	_, err = c.getState("ch1", "ns1", "k1")
+	require.NoError(t, err)
+	require.NoError(t, c.putState("ch1", "ns1", "k1", &CacheValue{Value: []byte("value1")}))
+	_, err = c.getState("ch1", "ns1", "k1")
+	require.NoError(t, err)
+
+	require.Equal(t, 1, misses.AddCallCount())
+	require.Equal(t, []string{"channel", "ch1", "namespace", "ns1"}, misses.WithArgsForCall(0))
+	require.Equal(t, 1, hits.AddCallCount())
+	require.Equal(t, []string{"channel", "ch1", "namespace", "ns1"}, hits.WithArgsForCall(0))
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(12)
+	c.Set([]byte("k1"), []byte("v1"))
+	c.Set([]byte("k2"), []byte("v2"))
+	c.Set([]byte("k3"), []byte("v3"))
+	require.Equal(t, []byte("v1"), c.Get(nil, []byte("k1")))
+
+	// k2 is the least recently used entry
+	c.Set([]byte("k4"), []byte("v4"))
+	require.Nil(t, c.Get(nil, []byte("k2")))
+	require.Equal(t, []byte("v1"), c.Get(nil, []byte("k1")))
+	require.Equal(t, []byte("v3"), c.Get(nil, []byte("k3")))
+	require.Equal(t, []byte("prefix-v4"), c.Get([]byte("prefix-"), []byte("k4")))
+
+	// replacing a value accounts for the change of size
+	c.Set([]byte("k1"), []byte("value1"))
+	require.Equal(t, []byte("value1"), c.Get(nil, []byte("k1")))
+	require.Equal(t, 12, c.bytes)
+	require.Nil(t, c.Get(nil, []byte("k3")))
+
+	// entries larger than the cache are not stored
+	c.Set([]byte("k5"), []byte("a-very-large-value"))
+	require.Nil(t, c.Get(nil, []byte("k5")))
+
+	c.Del([]byte("k1"))
+	require.Nil(t, c.Get(nil, []byte("k1")))
+	require.Equal(t, []byte("v4"), c.Get(nil, []byte("k4")))
+
+	c.Reset()
+	require.Nil(t, c.Get(nil, []byte("k4")))
+	require.Equal(t, 0, c.bytes)
 }
 
 func TestGetPutState(t *testing.T) {
-	cache := newCache(32, sysNamespaces)
+	cache := newTestCache(t, &ledger.CouchDBConfig{UserCacheSizeMBs: 32})
 
 	// test GetState
 	v, err := cache.getState("ch1", "ns1", "k1")
@@ -58,7 +150,7 @@ func TestGetPutState(t *testing.T) {
 }
 
 func TestUpdateStates(t *testing.T) {
-	cache := newCache(32, sysNamespaces)
+	cache := newTestCache(t, &ledger.CouchDBConfig{UserCacheSizeMBs: 32})
 
 	// create states for three namespaces (ns1, ns2, ns3)
 	// each with two keys (k1, k2)
@@ -147,7 +239,7 @@ func TestUpdateStates(t *testing.T) {
 }
 
 func TestCacheReset(t *testing.T) {
-	cache := newCache(32, sysNamespaces)
+	cache := newTestCache(t, &ledger.CouchDBConfig{UserCacheSizeMBs: 32})
 
 	// create states for three namespaces (ns1, ns2, ns3)
 	// each with two keys (k1, k2)
@@ -220,3 +312,9 @@ func TestCacheUpdates(t *testing.T) {
 
 	require.Equal(t, expectedCacheUpdates, u)
 }
+
+func newTestCache(t *testing.T, conf *ledger.CouchDBConfig) *cache {
+	c, err := newCache(conf, sysNamespaces, newStats(&disabled.Provider{}))
+	require.NoError(t, err)
+	return c
+}
@@ -114,9 +114,11 @@ type queryResult struct {
 
 //couchInstance represents a CouchDB instance
 type couchInstance struct {
-	conf   *ledger.CouchDBConfig
-	client *http.Client // a client to connect to this instance
-	stats  *stats
+	conf        *ledger.CouchDBConfig
+	client      *http.Client // a client to connect to this instance
+	stats       *stats
+	retryBudget *retryBudget
+	breaker     *circuitBreaker
 }
 
 //couchDatabase represents a database within a CouchDB instance
@@ -467,6 +469,9 @@ func (couchInstance *couchInstance) healthCheck(ctx context.Context) error {
 		return errors.Wrapf(err, "error parsing CouchDB URL: %s", couchInstance.url())
 	}
 	_, _, err = couchInstance.handleRequest(ctx, http.MethodHead, "", "HealthCheck", connectURL, nil, "", "", 0, true, nil)
+	if errors.Cause(err) == errCircuitBreakerOpen {
+		return errors.New("the circuit breaker for CouchDB is open after consecutive failed requests")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to couch db [%s]", err)
 	}
@@ -1713,6 +1718,7 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 	if maxRetries < 0 {
 		return nil, nil, errors.New("number of retries must be zero or greater")
 	}
+	couchInstance.retryBudget.requestMade()
 
 	requestURL := constructCouchDBUrl(connectURL, dbName, pathElements...)
 
@@ -1728,6 +1734,15 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 	// if maxRetries is 3 (default), a maximum of 4 attempts (one attempt with 3 retries)
 	//    will be made with warning entries for unsuccessful attempts
 	for attempts := 0; attempts <= maxRetries; attempts++ {
+		if attempts > 0 {
+			couchInstance.stats.observeRetry(dbName, functionName)
+		}
+
+		//fail fast, without contacting CouchDB, while the circuit breaker is open
+		if err := couchInstance.breaker.allow(); err != nil {
+			couchInstance.stats.observeCircuitBreakerRejection(dbName, functionName)
+			return nil, couchDBReturn, errors.WithMessagef(err, "rejecting the couchdb request %s", functionName)
+		}
 
 		//Set up a buffer for the payload data
 		payloadData := new(bytes.Buffer)
@@ -1739,7 +1754,7 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "error creating http request")
 		}
-		req.WithContext(ctx)
+		req = req.WithContext(ctx)
 
 		//set the request to close on completion if shared connections are not allowSharedConnection
 		//Current CouchDB has a problem with zero length attachments, do not allow the connection to be reused.
@@ -1783,6 +1798,7 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 
 		//Execute http request
 		resp, errResp = couchInstance.client.Do(req)
+		couchInstance.breaker.record(errResp == nil && resp != nil && resp.StatusCode < 500)
 
 		//check to see if the return from CouchDB is valid
 		if invalidCouchDBReturn(resp, errResp) {
@@ -1814,7 +1830,8 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 		// If the maxRetries is greater than 0, then log the retry info
 		if maxRetries > 0 {
 
-			retryMessage := fmt.Sprintf("Retrying couchdb request in %s", waitDuration)
+			backoff := jitteredBackoff(waitDuration, couchInstance.conf.MaxRetryBackoff)
+			retryMessage := fmt.Sprintf("Retrying couchdb request in %s", backoff)
 			if attempts == maxRetries {
 				retryMessage = "Retries exhausted"
 			}
@@ -1846,9 +1863,17 @@ func (couchInstance *couchInstance) handleRequest(ctx context.Context, method, d
 					attempts+1, maxRetries+1, couchDBReturn.Error, resp.Status, couchDBReturn.Reason, retryMessage)
 
 			}
-			//if there are more retries remaining, sleep for specified sleep time, then retry
+			//if there are more retries remaining and the retry budget allows, sleep for the backoff time, then retry
 			if attempts < maxRetries {
-				time.Sleep(waitDuration)
+				if !couchInstance.retryBudget.withdraw() {
+					logger.Warningf("Not retrying couchdb request %s as the retry budget is exhausted", functionName)
+					break
+				}
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return nil, couchDBReturn, errors.Wrap(ctx.Err(), "couchdb request cancelled while waiting to retry")
+				}
 			}
 
 			//backoff, doubling the retry time for next attempt
@@ -25,6 +25,7 @@ import (
 )
 
 var expectedDatabaseNamePattern = `[a-z][a-z0-9.$_()+-]*`
+const defaultMaxIdleConnections = 2000
 var maxLength = 238
 
 // To restrict the length of couchDB database name to the
@@ -56,6 +57,11 @@ func createCouchInstance(config *ledger.CouchDBConfig, metricsProvider metrics.P
 	// and for efficiency should only be created once and re-used.
 	client := &http.Client{Timeout: config.RequestTimeout}
 
+	maxIdleConns := defaultMaxIdleConnections
+	if config.MaxIdleConnections > 0 {
+		maxIdleConns = config.MaxIdleConnections
+	}
+
 	transport := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -64,8 +70,9 @@ func createCouchInstance(config *ledger.CouchDBConfig, metricsProvider metrics.P
 			DualStack: true,
 		}).DialContext,
 		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          2000,
-		MaxIdleConnsPerHost:   2000,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		MaxConnsPerHost:       config.MaxConnections,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
@@ -83,6 +90,13 @@ func createCouchInstance(config *ledger.CouchDBConfig, metricsProvider metrics.P
 	if verifyErr != nil {
 		return nil, verifyErr
 	}
+	// the retries on startup are bound by MaxRetriesOnStartup only
+	couchInstance.retryBudget = newRetryBudget(config.RetryBudgetRatio)
+	couchInstance.breaker = newCircuitBreaker(
+		config.CircuitBreakerThreshold,
+		config.CircuitBreakerTimeout,
+		couchInstance.stats.circuitBreakerOpen,
+	)
 
 	//return an error if the http return value is not 200
 	if retVal.StatusCode != 200 {
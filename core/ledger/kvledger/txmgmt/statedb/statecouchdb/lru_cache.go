@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statecouchdb
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a size bounded cache that evicts the least recently used entries first. The size of
+// an entry is accounted as the length of its key and value
+type lruCache struct {
+	maxBytes int
+
+	lock    sync.Mutex
+	bytes   int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+func newLRUCache(maxBytes int) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get appends the value of the key to dst and returns the result. It returns
+// dst as is if the key is not present in the cache
+func (c *lruCache) Get(dst, k []byte) []byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[string(k)]
+	if !ok {
+		return dst
+	}
+	c.order.MoveToFront(e)
+	return append(dst, e.Value.(*lruEntry).value...)
+}
+
+// Set stores the value of the key, evicting the least recently used entries as needed.
+// The entries larger than the size of the cache are not stored
+func (c *lruCache) Set(k, v []byte) {
+	size := len(k) + len(v)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove(string(k))
+	if size > c.maxBytes {
+		return
+	}
+	for c.bytes+size > c.maxBytes {
+		c.remove(c.order.Back().Value.(*lruEntry).key)
+	}
+	entry := &lruEntry{key: string(k), value: append([]byte{}, v...)}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.bytes += size
+}
+
+// Del removes the key from the cache
+func (c *lruCache) Del(k []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove(string(k))
+}
+
+// Reset removes all the entries from the cache
+func (c *lruCache) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+	c.bytes = 0
+}
+
+func (c *lruCache) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	entry := c.order.Remove(e).(*lruEntry)
+	delete(c.entries, key)
+	c.bytes -= len(entry.key) + len(entry.value)
+}
@@ -21,15 +21,69 @@ var (
 		LabelNames:   []string{"database", "function_name", "result"},
 		StatsdFormat: "%{#fqname}.%{database}.%{function_name}.%{result}",
 	}
+
+	requestRetriesOpts = metrics.CounterOpts{
+		Namespace:    "couchdb",
+		Subsystem:    "",
+		Name:         "request_retries",
+		Help:         "The number of retries of the requests to CouchDB",
+		LabelNames:   []string{"database", "function_name"},
+		StatsdFormat: "%{#fqname}.%{database}.%{function_name}",
+	}
+
+	circuitBreakerRejectionsOpts = metrics.CounterOpts{
+		Namespace:    "couchdb",
+		Subsystem:    "",
+		Name:         "circuit_breaker_rejections",
+		Help:         "The number of requests to CouchDB rejected while the circuit breaker is open",
+		LabelNames:   []string{"database", "function_name"},
+		StatsdFormat: "%{#fqname}.%{database}.%{function_name}",
+	}
+
+	circuitBreakerOpenOpts = metrics.GaugeOpts{
+		Namespace:    "couchdb",
+		Subsystem:    "",
+		Name:         "circuit_breaker_open",
+		Help:         "The state of the circuit breaker for CouchDB: 1 if open else 0.",
+		StatsdFormat: "%{#fqname}",
+	}
+
+	stateCacheHitsOpts = metrics.CounterOpts{
+		Namespace:    "couchdb",
+		Subsystem:    "",
+		Name:         "state_cache_hits",
+		Help:         "The number of state lookups served by the state cache",
+		LabelNames:   []string{"channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{namespace}",
+	}
+
+	stateCacheMissesOpts = metrics.CounterOpts{
+		Namespace:    "couchdb",
+		Subsystem:    "",
+		Name:         "state_cache_misses",
+		Help:         "The number of state lookups not found in the state cache",
+		LabelNames:   []string{"channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{namespace}",
+	}
 )
 
 type stats struct {
-	apiProcessingTime metrics.Histogram
+	apiProcessingTime        metrics.Histogram
+	requestRetries           metrics.Counter
+	circuitBreakerRejections metrics.Counter
+	circuitBreakerOpen       metrics.Gauge
+	stateCacheHits           metrics.Counter
+	stateCacheMisses         metrics.Counter
 }
 
 func newStats(metricsProvider metrics.Provider) *stats {
 	return &stats{
-		apiProcessingTime: metricsProvider.NewHistogram(apiProcessingTimeOpts),
+		apiProcessingTime:        metricsProvider.NewHistogram(apiProcessingTimeOpts),
+		requestRetries:           metricsProvider.NewCounter(requestRetriesOpts),
+		circuitBreakerRejections: metricsProvider.NewCounter(circuitBreakerRejectionsOpts),
+		circuitBreakerOpen:       metricsProvider.NewGauge(circuitBreakerOpenOpts),
+		stateCacheHits:           metricsProvider.NewCounter(stateCacheHitsOpts),
+		stateCacheMisses:         metricsProvider.NewCounter(stateCacheMissesOpts),
 	}
 }
 
@@ -40,3 +94,28 @@ func (s *stats) observeProcessingTime(startTime time.Time, dbName, functionName,
 		"result", result,
 	).Observe(time.Since(startTime).Seconds())
 }
+
+func (s *stats) observeRetry(dbName, functionName string) {
+	s.requestRetries.With(
+		"database", dbName,
+		"function_name", functionName,
+	).Add(1)
+}
+
+func (s *stats) observeCircuitBreakerRejection(dbName, functionName string) {
+	s.circuitBreakerRejections.With(
+		"database", dbName,
+		"function_name", functionName,
+	).Add(1)
+}
+
+func (s *stats) observeCacheLookup(chainName, namespace string, hit bool) {
+	counter := s.stateCacheMisses
+	if hit {
+		counter = s.stateCacheHits
+	}
+	counter.With(
+		"channel", chainName,
+		"namespace", namespace,
+	).Add(1)
+}
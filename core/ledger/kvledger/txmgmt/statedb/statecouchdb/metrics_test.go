@@ -32,9 +32,7 @@ func TestAPIProcessTimeMetric(t *testing.T) {
 	couchInstance, err := createCouchInstance(config, &disabled.Provider{})
 	gt.Expect(err).NotTo(HaveOccurred(), "Error when trying to create couch instance")
 
-	couchInstance.stats = &stats{
-		apiProcessingTime: fakeHistogram,
-	}
+	couchInstance.stats.apiProcessingTime = fakeHistogram
 
 	url, err := url.Parse("http://locahost:0")
 	gt.Expect(err).NotTo(HaveOccurred(), "Error when trying to parse URL")
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statecouchdb
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/pkg/errors"
+)
+
+// retryBudgetCapacity is the maximum number of retries that the retry budget accumulates. This allows
+// a burst of retries after a period of successful requests while preventing a retry storm under an outage
+const retryBudgetCapacity = 100
+
+// errCircuitBreakerOpen is returned for the requests that are rejected while the circuit breaker is open
+var errCircuitBreakerOpen = errors.New("the circuit breaker for CouchDB is open")
+
+// retryBudget limits the retries of the failed requests to a fraction of all the requests. Every request
+// deposits `ratio` tokens in the budget and every retry withdraws one token
+type retryBudget struct {
+	ratio  float64
+	lock   sync.Mutex
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	if ratio <= 0 {
+		return nil
+	}
+	return &retryBudget{
+		ratio:  ratio,
+		tokens: retryBudgetCapacity,
+	}
+}
+
+func (b *retryBudget) requestMade() {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > retryBudgetCapacity {
+		b.tokens = retryBudgetCapacity
+	}
+}
+
+// withdraw returns false if the budget does not allow another retry
+func (b *retryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// circuitBreaker opens after `threshold` consecutive failed requests and then rejects the requests without
+// contacting CouchDB. After `timeout`, a single request is let through to probe CouchDB; the breaker closes
+// if the request succeeds and remains open for another `timeout` otherwise
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+	openGauge metrics.Gauge
+	now       func() time.Time
+
+	lock     sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, timeout time.Duration, openGauge metrics.Gauge) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	openGauge.Set(0)
+	return &circuitBreaker{
+		threshold: threshold,
+		timeout:   timeout,
+		openGauge: openGauge,
+		now:       time.Now,
+	}
+}
+
+// allow returns an error if the request is to be rejected
+func (cb *circuitBreaker) allow() error {
+	if cb == nil {
+		return nil
+	}
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if cb.failures < cb.threshold {
+		return nil
+	}
+	if cb.probing || cb.now().Sub(cb.openedAt) < cb.timeout {
+		return errCircuitBreakerOpen
+	}
+	cb.probing = true
+	return nil
+}
+
+func (cb *circuitBreaker) record(success bool) {
+	if cb == nil {
+		return
+	}
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.probing = false
+	if success {
+		if cb.failures >= cb.threshold {
+			logger.Info("Closing the circuit breaker for CouchDB as a request succeeded")
+			cb.openGauge.Set(0)
+		}
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures < cb.threshold {
+		return
+	}
+	if cb.failures == cb.threshold {
+		logger.Warningf("Opening the circuit breaker for CouchDB after %d consecutive failed requests", cb.failures)
+		cb.openGauge.Set(1)
+	}
+	cb.openedAt = cb.now()
+}
+
+// jitteredBackoff returns a random duration between the half of the given wait duration and the wait duration,
+// so that the retries of the requests that failed together are spread, capped at maxBackoff if non-zero
+func jitteredBackoff(waitDuration, maxBackoff time.Duration) time.Duration {
+	if maxBackoff > 0 && waitDuration > maxBackoff {
+		waitDuration = maxBackoff
+	}
+	half := int64(waitDuration / 2)
+	if half <= 0 {
+		return waitDuration
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
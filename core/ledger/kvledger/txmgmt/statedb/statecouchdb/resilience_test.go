@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statecouchdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudget(t *testing.T) {
+	require.Nil(t, newRetryBudget(0))
+	var nilBudget *retryBudget
+	nilBudget.requestMade()
+	require.True(t, nilBudget.withdraw())
+
+	b := newRetryBudget(0.5)
+	for i := 0; i < retryBudgetCapacity; i++ {
+		require.True(t, b.withdraw())
+	}
+	require.False(t, b.withdraw())
+
+	b.requestMade()
+	require.False(t, b.withdraw())
+	b.requestMade()
+	require.True(t, b.withdraw())
+	require.False(t, b.withdraw())
+
+	for i := 0; i < 4*retryBudgetCapacity; i++ {
+		b.requestMade()
+	}
+	require.Equal(t, float64(retryBudgetCapacity), b.tokens)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	require.Nil(t, newCircuitBreaker(0, time.Second, &metricsfakes.Gauge{}))
+	var nilBreaker *circuitBreaker
+	require.NoError(t, nilBreaker.allow())
+	nilBreaker.record(false)
+
+	gauge := &metricsfakes.Gauge{}
+	cb := newCircuitBreaker(2, time.Minute, gauge)
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	cb.record(false)
+	cb.record(true)
+	cb.record(false)
+	require.NoError(t, cb.allow())
+	cb.record(false)
+	require.Equal(t, errCircuitBreakerOpen, cb.allow())
+	require.Equal(t, float64(1), gauge.SetArgsForCall(gauge.SetCallCount()-1))
+
+	// a single probe is let through after the timeout
+	now = now.Add(time.Minute)
+	require.NoError(t, cb.allow())
+	require.Equal(t, errCircuitBreakerOpen, cb.allow())
+	cb.record(false)
+	require.Equal(t, errCircuitBreakerOpen, cb.allow())
+
+	now = now.Add(time.Minute)
+	require.NoError(t, cb.allow())
+	cb.record(true)
+	require.NoError(t, cb.allow())
+	require.NoError(t, cb.allow())
+	require.Equal(t, float64(0), gauge.SetArgsForCall(gauge.SetCallCount()-1))
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		backoff := jitteredBackoff(time.Second, 0)
+		require.True(t, backoff >= 500*time.Millisecond && backoff <= time.Second, "backoff %s", backoff)
+		backoff = jitteredBackoff(time.Minute, 10*time.Second)
+		require.True(t, backoff >= 5*time.Second && backoff <= 10*time.Second, "backoff %s", backoff)
+	}
+	require.Equal(t, time.Duration(1), jitteredBackoff(1, 0))
+}
+
+func TestHandleRequestWithCircuitBreakerAndRetryBudget(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"internal_server_error","reason":"test"}`))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	retries := &metricsfakes.Counter{}
+	retries.WithReturns(retries)
+	rejections := &metricsfakes.Counter{}
+	rejections.WithReturns(rejections)
+	couchInstance := &couchInstance{
+		conf:   &ledger.CouchDBConfig{Address: serverURL.Host, MaxRetryBackoff: time.Millisecond},
+		client: server.Client(),
+		stats:  newStats(&disabled.Provider{}),
+	}
+	couchInstance.stats.requestRetries = retries
+	couchInstance.stats.circuitBreakerRejections = rejections
+	couchInstance.retryBudget = newRetryBudget(0.1)
+	couchInstance.retryBudget.tokens = 1
+	couchInstance.breaker = newCircuitBreaker(3, time.Minute, &metricsfakes.Gauge{})
+
+	// the retry budget allows a single retry
+	_, couchDBReturn, err := couchInstance.handleRequest(context.Background(), http.MethodGet, "db", "TestFunction", serverURL, nil, "", "", 5, true, nil)
+	require.EqualError(t, err, "error handling CouchDB request. Error:internal_server_error,  Status Code:500,  Reason:test")
+	require.Equal(t, 500, couchDBReturn.StatusCode)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Equal(t, 1, retries.AddCallCount())
+	require.Equal(t, []string{"database", "db", "function_name", "TestFunction"}, retries.WithArgsForCall(0))
+
+	// the third consecutive failure opens the circuit breaker
+	_, _, err = couchInstance.handleRequest(context.Background(), http.MethodGet, "db", "TestFunction", serverURL, nil, "", "", 0, true, nil)
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	_, _, err = couchInstance.handleRequest(context.Background(), http.MethodGet, "db", "TestFunction", serverURL, nil, "", "", 0, true, nil)
+	require.EqualError(t, err, "rejecting the couchdb request TestFunction: the circuit breaker for CouchDB is open")
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	require.Equal(t, 1, rejections.AddCallCount())
+
+	require.EqualError(t, couchInstance.healthCheck(context.Background()), "the circuit breaker for CouchDB is open after consecutive failed requests")
+}
+
+func TestHandleRequestCancelledWhileWaitingToRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"unavailable","reason":"test"}`))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	couchInstance := &couchInstance{
+		conf:   &ledger.CouchDBConfig{Address: serverURL.Host},
+		client: server.Client(),
+		stats:  newStats(&disabled.Provider{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = couchInstance.handleRequest(ctx, http.MethodGet, "db", "TestFunction", serverURL, nil, "", "", 10, true, nil)
+	require.EqualError(t, err, "couchdb request cancelled while waiting to retry: context deadline exceeded")
+}
@@ -63,7 +63,10 @@ func NewVersionedDBProvider(config *ledger.CouchDBConfig, metricsProvider metric
 		return nil, err
 	}
 
-	cache := newCache(config.UserCacheSizeMBs, sysNamespaces)
+	cache, err := newCache(config, sysNamespaces, couchInstance.stats)
+	if err != nil {
+		return nil, err
+	}
 	return &VersionedDBProvider{
 			couchInstance:      couchInstance,
 			databases:          make(map[string]*VersionedDB),
@@ -320,6 +323,27 @@ func (vdb *VersionedDB) GetDBType() string {
 	return "couchdb"
 }
 
+// EstimateSize implements method in SizeEstimator interface. The size is the sum of the
+// file sizes of the metadata database and of the namespace databases of the channel
+func (vdb *VersionedDB) EstimateSize() (int64, error) {
+	dbs := []*couchDatabase{vdb.metadataDB}
+	vdb.mux.RLock()
+	for _, nsDBInfo := range vdb.channelMetadata.NamespaceDBsInfo {
+		dbs = append(dbs, &couchDatabase{couchInstance: vdb.couchInstance, dbName: nsDBInfo.DBName})
+	}
+	vdb.mux.RUnlock()
+
+	var size int64
+	for _, db := range dbs {
+		info, _, err := db.getDatabaseInfo()
+		if err != nil {
+			return 0, err
+		}
+		size += int64(info.Sizes.File)
+	}
+	return size, nil
+}
+
 // LoadCommittedVersions populates committedVersions and revisionNumbers into cache.
 // A bulk retrieve from couchdb is used to populate the cache.
 // committedVersions cache will be used for state validation of readsets
@@ -11,6 +11,7 @@ import (
 
 	"github.com/hyperledger/fabric/core/ledger/internal/version"
 	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/pkg/errors"
 )
 
 //go:generate counterfeiter -o mock/results_iterator.go -fake-name ResultsIterator . ResultsIterator
@@ -92,6 +93,39 @@ type IndexCapable interface {
 	ProcessIndexesForChaincodeDeploy(namespace string, indexFilesData map[string][]byte) error
 }
 
+//RangeCountEstimator interface provides an additional function for
+//databases capable of counting the keys in a range cheaply
+type RangeCountEstimator interface {
+	// EstimateStateRangeCount returns the number of keys between startKey (inclusive) and endKey (exclusive),
+	// counting no further than the given limit. A limit of zero denotes no limit
+	EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error)
+}
+
+//SizeEstimator interface provides an additional function for
+//databases capable of estimating the disk space they occupy
+type SizeEstimator interface {
+	// EstimateSize returns the approximate number of bytes the db occupies on disk
+	EstimateSize() (int64, error)
+}
+
+// EstimateTotalSize returns the sum of the sizes of the given dbs. It is meant for the
+// VersionedDB implementations layered on top of other dbs
+func EstimateTotalSize(dbs ...VersionedDB) (int64, error) {
+	var total int64
+	for _, db := range dbs {
+		estimator, ok := db.(SizeEstimator)
+		if !ok {
+			return 0, errors.New("the state database does not support estimating its size")
+		}
+		size, err := estimator.EstimateSize()
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
 // FullScanIterator provides a mean to iterate over entire statedb. The intended use of this iterator
 // is to generate the snapshot files for the statedb
 type FullScanIterator interface {
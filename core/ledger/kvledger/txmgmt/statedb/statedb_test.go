@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package statedb
 
 import (
+	"errors"
 	"sort"
 	"testing"
 
@@ -154,3 +155,25 @@ func TestMergeUpdateBatch(t *testing.T) {
 	expectedBatch.Put("ns2", "key6", []byte("batch2_value6"), version.NewHeight(8, 8))
 	assert.Equal(t, expectedBatch, batch1)
 }
+
+type sizedDB struct {
+	VersionedDB
+	size int64
+	err  error
+}
+
+func (db *sizedDB) EstimateSize() (int64, error) {
+	return db.size, db.err
+}
+
+func TestEstimateTotalSize(t *testing.T) {
+	size, err := EstimateTotalSize(&sizedDB{size: 10}, &sizedDB{size: 32})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), size)
+
+	_, err = EstimateTotalSize(&sizedDB{size: 10}, &sizedDB{err: errors.New("couchdb unreachable")})
+	assert.EqualError(t, err, "couchdb unreachable")
+
+	_, err = EstimateTotalSize(&sizedDB{size: 10}, VersionedDB(nil))
+	assert.EqualError(t, err, "the state database does not support estimating its size")
+}
@@ -0,0 +1,426 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateencryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("stateencryption")
+
+// encryptedValuePrefix marks a value that has been encrypted by this package. It is followed by
+// a single byte carrying the length of the key SKI, the SKI itself, and the ciphertext produced
+// by the bccsp AES-GCM encrypter (nonce followed by the encrypted value and its tag), which
+// authenticates the namespace and the key of the value as additional data.
+// A leading zero byte never appears in a JSON value, which keeps the values stored in CouchDB
+// distinguishable from encrypted values.
+var encryptedValuePrefix = []byte{0x00, 'e', 'n', 'c', 0x01}
+
+// VersionedDBProvider wraps a statedb.VersionedDBProvider and returns handles that transparently
+// encrypt the values of the configured namespaces before they are handed to the underlying db
+type VersionedDBProvider struct {
+	statedb.VersionedDBProvider
+	cipher *valueCipher
+}
+
+// NewVersionedDBProvider constructs a VersionedDBProvider. The keys listed in the config are
+// looked up in the supplied Encryptor at construction time so that a missing key is reported
+// at peer start rather than in the commit path
+func NewVersionedDBProvider(
+	vdbProvider statedb.VersionedDBProvider,
+	encryptor ledger.Encryptor,
+	conf *ledger.StateEncryptionConfig,
+) (*VersionedDBProvider, error) {
+	cipher, err := newValueCipher(encryptor, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionedDBProvider{
+		VersionedDBProvider: vdbProvider,
+		cipher:              cipher,
+	}, nil
+}
+
+// GetDBHandle implements the method in interface statedb.VersionedDBProvider
+func (p *VersionedDBProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	vdb, err := p.VersionedDBProvider.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	return newVersionedDB(vdb, p.cipher), nil
+}
+
+// HealthCheck checks the health of the underlying db, if it supports the health checks
+func (p *VersionedDBProvider) HealthCheck(ctx context.Context) error {
+	if healthChecker, ok := p.VersionedDBProvider.(healthz.HealthChecker); ok {
+		return healthChecker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// Enabled returns true if the config requires the encryption of at least one namespace
+func Enabled(conf *ledger.StateEncryptionConfig) bool {
+	return conf != nil && len(conf.Namespaces) > 0
+}
+
+type valueCipher struct {
+	encryptor  ledger.Encryptor
+	currentSKI []byte
+	keys       map[string]bccsp.Key
+	namespaces map[string]struct{}
+}
+
+func newValueCipher(encryptor ledger.Encryptor, conf *ledger.StateEncryptionConfig) (*valueCipher, error) {
+	if encryptor == nil {
+		return nil, errors.New("an encryptor is required for state encryption")
+	}
+	if len(conf.KeySKIs) == 0 {
+		return nil, errors.New("at least one key SKI is required for state encryption")
+	}
+
+	c := &valueCipher{
+		encryptor:  encryptor,
+		keys:       map[string]bccsp.Key{},
+		namespaces: map[string]struct{}{},
+	}
+	for i, skiHex := range conf.KeySKIs {
+		ski, err := hex.DecodeString(skiHex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key SKI [%s]", skiHex)
+		}
+		if len(ski) == 0 || len(ski) > 255 {
+			return nil, errors.Errorf("invalid key SKI length [%d]", len(ski))
+		}
+		if i == 0 {
+			c.currentSKI = ski
+		}
+		key, err := encryptor.GetKey(ski)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to load state encryption key [%x]", ski)
+		}
+		if !key.Symmetric() || !key.Private() {
+			return nil, errors.Errorf("state encryption key [%x] is not a symmetric key", ski)
+		}
+		c.keys[string(ski)] = key
+	}
+	for _, ns := range conf.Namespaces {
+		c.namespaces[ns] = struct{}{}
+	}
+	logger.Infof("State encryption enabled for namespaces %v using key [%x]", conf.Namespaces, c.currentSKI)
+	return c, nil
+}
+
+// appliesTo returns true if the values in the given statedb namespace are to be encrypted.
+// In addition to the chaincode namespaces themselves, this covers the namespaces that the
+// privacyenabledstate package derives for the private data of the chaincode collections
+// (i.e., "<ns>$$p<coll>"). The hashed data namespaces are left in plaintext.
+func (c *valueCipher) appliesTo(ns string) bool {
+	if _, ok := c.namespaces[ns]; ok {
+		return true
+	}
+	i := strings.Index(ns, "$$p")
+	if i <= 0 {
+		return false
+	}
+	_, ok := c.namespaces[ns[:i]]
+	return ok
+}
+
+// additionalData binds the ciphertext of a value to its namespace and key, so that the
+// ciphertext of a value cannot be substituted for the one of another key
+func additionalData(ns, key string) []byte {
+	ad := make([]byte, 0, len(ns)+1+len(key))
+	ad = append(ad, ns...)
+	ad = append(ad, 0x00)
+	return append(ad, key...)
+}
+
+func (c *valueCipher) encrypt(ns, key string, value []byte) ([]byte, error) {
+	ciphertext, err := c.encryptor.Encrypt(c.keys[string(c.currentSKI)], value, &bccsp.AESGCMModeOpts{AdditionalData: additionalData(ns, key)})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to encrypt state value")
+	}
+	encVal := make([]byte, 0, len(encryptedValuePrefix)+1+len(c.currentSKI)+len(ciphertext))
+	encVal = append(encVal, encryptedValuePrefix...)
+	encVal = append(encVal, byte(len(c.currentSKI)))
+	encVal = append(encVal, c.currentSKI...)
+	return append(encVal, ciphertext...), nil
+}
+
+// decrypt returns the plaintext for the given value. Values that do not carry the
+// encryption prefix are returned as is; these are the values written before the
+// encryption was enabled for a namespace.
+func (c *valueCipher) decrypt(ns, key string, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	v := value[len(encryptedValuePrefix):]
+	if len(v) == 0 || len(v) < 1+int(v[0]) {
+		return nil, errors.New("malformed encrypted state value")
+	}
+	ski, ciphertext := v[1:1+int(v[0])], v[1+int(v[0]):]
+	k, ok := c.keys[string(ski)]
+	if !ok {
+		return nil, errors.Errorf("state value is encrypted with unknown key [%s]", hex.EncodeToString(ski))
+	}
+	plaintext, err := c.encryptor.Decrypt(k, ciphertext, &bccsp.AESGCMModeOpts{AdditionalData: additionalData(ns, key)})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt state value")
+	}
+	// a nil value denotes a delete
+	if plaintext == nil {
+		plaintext = []byte{}
+	}
+	return plaintext, nil
+}
+
+func (c *valueCipher) decryptVersionedValue(ns, key string, vv *statedb.VersionedValue) (*statedb.VersionedValue, error) {
+	if vv == nil || vv.Value == nil || !c.appliesTo(ns) {
+		return vv, nil
+	}
+	plaintext, err := c.decrypt(ns, key, vv.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &statedb.VersionedValue{
+		Value:    plaintext,
+		Metadata: vv.Metadata,
+		Version:  vv.Version,
+	}, nil
+}
+
+// versionedDB implements the interface statedb.VersionedDB on top of another VersionedDB
+type versionedDB struct {
+	statedb.VersionedDB
+	cipher *valueCipher
+}
+
+// newVersionedDB wraps the given VersionedDB. The returned value implements the optional
+// interfaces statedb.BulkOptimizable and statedb.IndexCapable only if the wrapped db does, as
+// the callers use type assertions on these interfaces to select the code paths. It always
+// implements statedb.RangeCountEstimator, which fails if the wrapped db does not support it
+func newVersionedDB(vdb statedb.VersionedDB, cipher *valueCipher) statedb.VersionedDB {
+	encDB := &versionedDB{
+		VersionedDB: vdb,
+		cipher:      cipher,
+	}
+	bulkOptimizable, isBulkOptimizable := vdb.(statedb.BulkOptimizable)
+	indexCapable, isIndexCapable := vdb.(statedb.IndexCapable)
+	switch {
+	case isBulkOptimizable && isIndexCapable:
+		return &bulkOptimizableIndexCapableDB{encDB, bulkOptimizable, indexCapable}
+	case isBulkOptimizable:
+		return &bulkOptimizableDB{encDB, bulkOptimizable}
+	case isIndexCapable:
+		return &indexCapableDB{encDB, indexCapable}
+	default:
+		return encDB
+	}
+}
+
+// EstimateSize implements method in SizeEstimator interface
+func (vdb *versionedDB) EstimateSize() (int64, error) {
+	return statedb.EstimateTotalSize(vdb.VersionedDB)
+}
+
+type bulkOptimizableDB struct {
+	*versionedDB
+	statedb.BulkOptimizable
+}
+
+type indexCapableDB struct {
+	*versionedDB
+	statedb.IndexCapable
+}
+
+type bulkOptimizableIndexCapableDB struct {
+	*versionedDB
+	statedb.BulkOptimizable
+	statedb.IndexCapable
+}
+
+// GetState implements method in VersionedDB interface
+func (vdb *versionedDB) GetState(namespace string, key string) (*statedb.VersionedValue, error) {
+	vv, err := vdb.VersionedDB.GetState(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return vdb.cipher.decryptVersionedValue(namespace, key, vv)
+}
+
+// GetStateMultipleKeys implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateMultipleKeys(namespace string, keys []string) ([]*statedb.VersionedValue, error) {
+	vvs, err := vdb.VersionedDB.GetStateMultipleKeys(namespace, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, vv := range vvs {
+		if vvs[i], err = vdb.cipher.decryptVersionedValue(namespace, keys[i], vv); err != nil {
+			return nil, err
+		}
+	}
+	return vvs, nil
+}
+
+// GetStateRangeScanIterator implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (statedb.ResultsIterator, error) {
+	itr, err := vdb.VersionedDB.GetStateRangeScanIterator(namespace, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return vdb.wrapIterator(namespace, itr), nil
+}
+
+// GetStateRangeScanIteratorWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIteratorWithPagination(namespace string, startKey string, endKey string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	itr, err := vdb.VersionedDB.GetStateRangeScanIteratorWithPagination(namespace, startKey, endKey, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &queryResultsIterator{vdb.wrapIterator(namespace, itr), itr}, nil
+}
+
+// EstimateStateRangeCount implements method in RangeCountEstimator interface. The keys are
+// stored in plaintext, so the estimate of the underlying db applies as is
+func (vdb *versionedDB) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	estimator, ok := vdb.VersionedDB.(statedb.RangeCountEstimator)
+	if !ok {
+		return 0, errors.New("the state database does not support estimating the number of keys in a range")
+	}
+	return estimator.EstimateStateRangeCount(namespace, startKey, endKey, limit)
+}
+
+// GetFullScanIterator implements method in VersionedDB interface. The values of the encrypted
+// namespaces are returned in plaintext, so that the snapshots generated from the iterator are
+// the same on all the peers, regardless of their keys and of the nonces of the ciphertexts
+func (vdb *versionedDB) GetFullScanIterator(skipNamespace func(string) bool) (statedb.FullScanIterator, byte, error) {
+	itr, valueFormat, err := vdb.VersionedDB.GetFullScanIterator(skipNamespace)
+	if err != nil {
+		return nil, byte(0), err
+	}
+	return &fullScanIterator{
+		FullScanIterator: itr,
+		valueFormat:      valueFormat,
+		cipher:           vdb.cipher,
+	}, valueFormat, nil
+}
+
+// ExecuteQuery implements method in VersionedDB interface. Rich queries are rejected for the
+// encrypted namespaces because the underlying db cannot inspect the encrypted values
+func (vdb *versionedDB) ExecuteQuery(namespace, query string) (statedb.ResultsIterator, error) {
+	if vdb.cipher.appliesTo(namespace) {
+		return nil, errors.Errorf("rich queries are not supported on encrypted namespace [%s]", namespace)
+	}
+	return vdb.VersionedDB.ExecuteQuery(namespace, query)
+}
+
+// ExecuteQueryWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) ExecuteQueryWithPagination(namespace, query, bookmark string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	if vdb.cipher.appliesTo(namespace) {
+		return nil, errors.Errorf("rich queries are not supported on encrypted namespace [%s]", namespace)
+	}
+	return vdb.VersionedDB.ExecuteQueryWithPagination(namespace, query, bookmark, pageSize)
+}
+
+// ApplyUpdates implements method in VersionedDB interface. The values of the encrypted
+// namespaces are replaced in a copy of the batch so that the caller's batch is not modified
+func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version.Height) error {
+	encBatch := statedb.NewUpdateBatch()
+	encBatch.ContainsPostOrderWrites = batch.ContainsPostOrderWrites
+	for _, ns := range batch.GetUpdatedNamespaces() {
+		for key, vv := range batch.GetUpdates(ns) {
+			if vv.IsDelete() || !vdb.cipher.appliesTo(ns) {
+				encBatch.Update(ns, key, vv)
+				continue
+			}
+			encVal, err := vdb.cipher.encrypt(ns, key, vv.Value)
+			if err != nil {
+				return err
+			}
+			encBatch.PutValAndMetadata(ns, key, encVal, vv.Metadata, vv.Version)
+		}
+	}
+	return vdb.VersionedDB.ApplyUpdates(encBatch, height)
+}
+
+func (vdb *versionedDB) wrapIterator(namespace string, itr statedb.ResultsIterator) *resultsIterator {
+	return &resultsIterator{
+		ResultsIterator: itr,
+		namespace:       namespace,
+		cipher:          vdb.cipher,
+	}
+}
+
+type resultsIterator struct {
+	statedb.ResultsIterator
+	namespace string
+	cipher    *valueCipher
+}
+
+func (itr *resultsIterator) Next() (statedb.QueryResult, error) {
+	queryResult, err := itr.ResultsIterator.Next()
+	if err != nil || queryResult == nil {
+		return queryResult, err
+	}
+	kv := queryResult.(*statedb.VersionedKV)
+	vv, err := itr.cipher.decryptVersionedValue(kv.Namespace, kv.Key, &kv.VersionedValue)
+	if err != nil {
+		return nil, err
+	}
+	return &statedb.VersionedKV{
+		CompositeKey:   kv.CompositeKey,
+		VersionedValue: *vv,
+	}, nil
+}
+
+type queryResultsIterator struct {
+	*resultsIterator
+	dbItr statedb.QueryResultsIterator
+}
+
+func (itr *queryResultsIterator) GetBookmarkAndClose() string {
+	return itr.dbItr.GetBookmarkAndClose()
+}
+
+type fullScanIterator struct {
+	statedb.FullScanIterator
+	valueFormat byte
+	cipher      *valueCipher
+}
+
+func (itr *fullScanIterator) Next() (*statedb.CompositeKey, []byte, error) {
+	compositeKey, dbValue, err := itr.FullScanIterator.Next()
+	if err != nil || compositeKey == nil || !itr.cipher.appliesTo(compositeKey.Namespace) {
+		return compositeKey, dbValue, err
+	}
+	vv, err := stateleveldb.DecodeFullScanValue(itr.valueFormat, dbValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.HasPrefix(vv.Value, encryptedValuePrefix) {
+		return compositeKey, dbValue, nil
+	}
+	if vv, err = itr.cipher.decryptVersionedValue(compositeKey.Namespace, compositeKey.Key, vv); err != nil {
+		return nil, nil, err
+	}
+	if dbValue, err = stateleveldb.EncodeFullScanValue(itr.valueFormat, vv); err != nil {
+		return nil, nil, err
+	}
+	return compositeKey, dbValue, nil
+}
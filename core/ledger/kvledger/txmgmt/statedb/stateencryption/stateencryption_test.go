@@ -0,0 +1,405 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateencryption
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/commontests"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/mock"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	vdbEnv   *stateleveldb.TestVDBEnv
+	csp      bccsp.BCCSP
+	provider *VersionedDBProvider
+}
+
+func newTestEnv(t *testing.T, numKeys int, namespaces ...string) *testEnv {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	var skis []string
+	for i := 0; i < numKeys; i++ {
+		skis = append(skis, newAESKey(t, csp))
+	}
+	vdbEnv := stateleveldb.NewTestVDBEnv(t)
+	provider, err := NewVersionedDBProvider(
+		vdbEnv.DBProvider,
+		csp,
+		&ledger.StateEncryptionConfig{
+			KeySKIs:    skis,
+			Namespaces: namespaces,
+		},
+	)
+	require.NoError(t, err)
+	return &testEnv{
+		vdbEnv:   vdbEnv,
+		csp:      csp,
+		provider: provider,
+	}
+}
+
+func (env *testEnv) cleanup() {
+	env.vdbEnv.Cleanup()
+}
+
+func newAESKey(t *testing.T, csp bccsp.BCCSP) string {
+	k, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+	return hex.EncodeToString(k.SKI())
+}
+
+func TestBasicRW(t *testing.T) {
+	env := newTestEnv(t, 1, "ns1", "ns2")
+	defer env.cleanup()
+	commontests.TestBasicRW(t, env.provider)
+}
+
+func TestDeletes(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+	commontests.TestDeletes(t, env.provider)
+}
+
+func TestIterator(t *testing.T) {
+	env := newTestEnv(t, 1, "ns1", "ns2")
+	defer env.cleanup()
+	commontests.TestIterator(t, env.provider)
+}
+
+func TestValuesEncryptedAtRest(t *testing.T) {
+	env := newTestEnv(t, 1, "secret")
+	defer env.cleanup()
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.PutValAndMetadata("secret", "key1", []byte("value1"), []byte("metadata1"), version.NewHeight(1, 1))
+	batch.Put("secret$$pcoll1", "key1", []byte("pvt-value1"), version.NewHeight(1, 2))
+	batch.Put("secret$$hcoll1", "keyhash1", []byte("hash1"), version.NewHeight(1, 2))
+	batch.Put("public", "key1", []byte("public-value1"), version.NewHeight(1, 3))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 3)))
+
+	// the caller's batch is not modified
+	require.Equal(t, []byte("value1"), batch.Get("secret", "key1").Value)
+
+	rawDB, err := env.vdbEnv.DBProvider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+
+	tests := []struct {
+		ns, key           string
+		value             []byte
+		expectedEncAtRest bool
+	}{
+		{"secret", "key1", []byte("value1"), true},
+		{"secret$$pcoll1", "key1", []byte("pvt-value1"), true},
+		{"secret$$hcoll1", "keyhash1", []byte("hash1"), false},
+		{"public", "key1", []byte("public-value1"), false},
+	}
+	for _, tt := range tests {
+		raw, err := rawDB.GetState(tt.ns, tt.key)
+		require.NoError(t, err)
+		if tt.expectedEncAtRest {
+			require.NotContains(t, string(raw.Value), string(tt.value))
+		} else {
+			require.Equal(t, tt.value, raw.Value)
+		}
+
+		vv, err := db.GetState(tt.ns, tt.key)
+		require.NoError(t, err)
+		require.Equal(t, tt.value, vv.Value)
+	}
+
+	vv, err := db.GetState("secret", "key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("metadata1"), vv.Metadata)
+	require.Equal(t, version.NewHeight(1, 1), vv.Version)
+
+	vvs, err := db.GetStateMultipleKeys("secret", []string{"key1", "non-existing-key"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), vvs[0].Value)
+	require.Nil(t, vvs[1])
+
+	itr, err := db.GetStateRangeScanIteratorWithPagination("secret", "", "", 10)
+	require.NoError(t, err)
+	res, err := itr.Next()
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), res.(*statedb.VersionedKV).Value)
+	require.Equal(t, "", itr.GetBookmarkAndClose())
+}
+
+func TestFullScanIteratorReturnsPlaintext(t *testing.T) {
+	env := newTestEnv(t, 1, "secret")
+	defer env.cleanup()
+	plainEnv := stateleveldb.NewTestVDBEnv(t)
+	defer plainEnv.Cleanup()
+
+	batch := statedb.NewUpdateBatch()
+	batch.PutValAndMetadata("secret", "key1", []byte("value1"), []byte("metadata1"), version.NewHeight(1, 1))
+	batch.Put("secret", "key2", []byte("value2"), version.NewHeight(1, 2))
+	batch.Put("public", "key1", []byte("public-value1"), version.NewHeight(1, 3))
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 3)))
+	plainDB, err := plainEnv.DBProvider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	require.NoError(t, plainDB.ApplyUpdates(batch, version.NewHeight(1, 3)))
+
+	// the snapshots of a peer encrypting the state are the same as the
+	// ones of a peer that does not
+	scan := func(db statedb.VersionedDB) [][]byte {
+		itr, valueFormat, err := db.GetFullScanIterator(func(string) bool { return false })
+		require.NoError(t, err)
+		require.Equal(t, stateleveldb.TestEnvDBValueformat, valueFormat)
+		defer itr.Close()
+		var results [][]byte
+		for {
+			compositeKey, dbValue, err := itr.Next()
+			require.NoError(t, err)
+			if compositeKey == nil {
+				return results
+			}
+			// the leveldb iterator reuses the buffer of the values
+			results = append(results, []byte(compositeKey.Namespace+"/"+compositeKey.Key), append([]byte(nil), dbValue...))
+		}
+	}
+	results := scan(db)
+	require.Len(t, results, 6)
+	require.Equal(t, scan(plainDB), results)
+}
+
+func TestValuesAuthenticated(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns", "key2", []byte("value2"), version.NewHeight(1, 2))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 2)))
+
+	rawDB, err := env.vdbEnv.DBProvider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	raw, err := rawDB.GetState("ns", "key1")
+	require.NoError(t, err)
+	require.Equal(t, encryptedValuePrefix, raw.Value[:len(encryptedValuePrefix)])
+
+	// the ciphertext of a key is rejected for another key
+	batch = statedb.NewUpdateBatch()
+	batch.Put("ns", "key2", raw.Value, version.NewHeight(2, 1))
+	require.NoError(t, rawDB.ApplyUpdates(batch, version.NewHeight(2, 1)))
+	_, err = db.GetState("ns", "key2")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to decrypt state value")
+
+	// and so is a tampered ciphertext
+	tampered := append([]byte(nil), raw.Value...)
+	tampered[len(tampered)-1] ^= 1
+	batch = statedb.NewUpdateBatch()
+	batch.Put("ns", "key1", tampered, version.NewHeight(2, 2))
+	require.NoError(t, rawDB.ApplyUpdates(batch, version.NewHeight(2, 2)))
+	_, err = db.GetState("ns", "key1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to decrypt state value")
+}
+
+func TestEstimateStateRangeCount(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns", "key2", []byte("value2"), version.NewHeight(1, 2))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 2)))
+
+	estimator, ok := db.(statedb.RangeCountEstimator)
+	require.True(t, ok)
+	count, err := estimator.EstimateStateRangeCount("ns", "", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), count)
+
+	_, err = newVersionedDB(&mock.VersionedDB{}, &valueCipher{}).(statedb.RangeCountEstimator).EstimateStateRangeCount("ns", "", "", 0)
+	require.EqualError(t, err, "the state database does not support estimating the number of keys in a range")
+}
+
+type healthCheckingProvider struct {
+	statedb.VersionedDBProvider
+	err error
+}
+
+func (p *healthCheckingProvider) HealthCheck(context.Context) error {
+	return p.err
+}
+
+func TestHealthCheck(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	var healthChecker healthz.HealthChecker = env.provider
+	require.NoError(t, healthChecker.HealthCheck(context.Background()))
+
+	env.provider.VersionedDBProvider = &healthCheckingProvider{err: errors.New("couchdb is down")}
+	require.EqualError(t, env.provider.HealthCheck(context.Background()), "couchdb is down")
+}
+
+func TestPlaintextValuesBeforeEnablement(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	rawDB, err := env.vdbEnv.DBProvider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns", "key1", []byte("plaintext"), version.NewHeight(1, 1))
+	require.NoError(t, rawDB.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	vv, err := db.GetState("ns", "key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), vv.Value)
+}
+
+func TestKeyRotation(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	oldSKI := hex.EncodeToString(env.provider.cipher.currentSKI)
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns", "key1", []byte("value1"), version.NewHeight(1, 1))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	newSKI := newAESKey(t, env.csp)
+	rotated, err := NewVersionedDBProvider(
+		env.vdbEnv.DBProvider,
+		env.csp,
+		&ledger.StateEncryptionConfig{
+			KeySKIs:    []string{newSKI, oldSKI},
+			Namespaces: []string{"ns"},
+		},
+	)
+	require.NoError(t, err)
+	db, err = rotated.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	batch = statedb.NewUpdateBatch()
+	batch.Put("ns", "key2", []byte("value2"), version.NewHeight(2, 1))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(2, 1)))
+
+	for key, val := range map[string]string{"key1": "value1", "key2": "value2"} {
+		vv, err := db.GetState("ns", key)
+		require.NoError(t, err)
+		require.Equal(t, []byte(val), vv.Value)
+	}
+
+	retired, err := NewVersionedDBProvider(
+		env.vdbEnv.DBProvider,
+		env.csp,
+		&ledger.StateEncryptionConfig{
+			KeySKIs:    []string{newSKI},
+			Namespaces: []string{"ns"},
+		},
+	)
+	require.NoError(t, err)
+	db, err = retired.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	_, err = db.GetState("ns", "key1")
+	require.EqualError(t, err, "state value is encrypted with unknown key ["+oldSKI+"]")
+}
+
+func TestRichQueriesRejected(t *testing.T) {
+	env := newTestEnv(t, 1, "ns")
+	defer env.cleanup()
+
+	db, err := env.provider.GetDBHandle("testchannel")
+	require.NoError(t, err)
+	_, err = db.ExecuteQuery("ns", "{}")
+	require.EqualError(t, err, "rich queries are not supported on encrypted namespace [ns]")
+	_, err = db.ExecuteQueryWithPagination("ns$$pcoll", "{}", "", 10)
+	require.EqualError(t, err, "rich queries are not supported on encrypted namespace [ns$$pcoll]")
+	_, err = db.ExecuteQuery("other", "{}")
+	require.EqualError(t, err, "ExecuteQuery not supported for leveldb")
+}
+
+func TestNewVersionedDBProviderErrors(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		encryptor   ledger.Encryptor
+		skis        []string
+		expectedErr string
+	}{
+		{"no encryptor", nil, []string{"0a"}, "an encryptor is required for state encryption"},
+		{"no keys", csp, nil, "at least one key SKI is required for state encryption"},
+		{"bad hex", csp, []string{"zz"}, "invalid key SKI [zz]: encoding/hex: invalid byte: U+007A 'z'"},
+		{"empty ski", csp, []string{""}, "invalid key SKI length [0]"},
+		{"unknown key", csp, []string{"0a0b"}, "failed to load state encryption key [0a0b]"},
+		{"asymmetric key", csp, []string{hex.EncodeToString(ecKey.SKI())}, "state encryption key [" + hex.EncodeToString(ecKey.SKI()) + "] is not a symmetric key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVersionedDBProvider(
+				nil,
+				tt.encryptor,
+				&ledger.StateEncryptionConfig{KeySKIs: tt.skis, Namespaces: []string{"ns"}},
+			)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestOptionalInterfacesPreserved(t *testing.T) {
+	cipher := &valueCipher{}
+
+	_, ok := newVersionedDB(&mock.VersionedDB{}, cipher).(statedb.BulkOptimizable)
+	require.False(t, ok)
+
+	bulkDB := newVersionedDB(&struct {
+		*mock.VersionedDB
+		statedb.BulkOptimizable
+	}{}, cipher)
+	_, ok = bulkDB.(statedb.BulkOptimizable)
+	require.True(t, ok)
+	_, ok = bulkDB.(statedb.IndexCapable)
+	require.False(t, ok)
+
+	fullDB := newVersionedDB(&struct {
+		*mock.VersionedDB
+		statedb.BulkOptimizable
+		statedb.IndexCapable
+	}{}, cipher)
+	_, ok = fullDB.(statedb.BulkOptimizable)
+	require.True(t, ok)
+	_, ok = fullDB.(statedb.IndexCapable)
+	require.True(t, ok)
+}
+
+func TestEnabled(t *testing.T) {
+	require.False(t, Enabled(nil))
+	require.False(t, Enabled(&ledger.StateEncryptionConfig{KeySKIs: []string{"0a"}}))
+	require.True(t, Enabled(&ledger.StateEncryptionConfig{Namespaces: []string{"ns"}}))
+}
@@ -0,0 +1,299 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statehybrid
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("statehybrid")
+
+// derivedNsSeparator separates a chaincode namespace from the collection part of the
+// namespaces that the privacyenabledstate package derives for the private and the hashed
+// data of the chaincode collections (i.e., "<ns>$$p<coll>" and "<ns>$$h<coll>")
+const derivedNsSeparator = "$$"
+
+// VersionedDBProvider returns handles that store the state of the configured namespaces of a channel
+// in CouchDB and the rest of the state of the channel in goleveldb. The handles for the channels that
+// have no namespaces configured are the goleveldb handles
+type VersionedDBProvider struct {
+	levelDBProvider   statedb.VersionedDBProvider
+	couchDBProvider   statedb.VersionedDBProvider
+	couchDBNamespaces map[string]map[string]struct{}
+}
+
+// NewVersionedDBProvider constructs a VersionedDBProvider. couchDBNamespaces maps a channel to the
+// chaincode namespaces of the channel whose state is stored via the couchDBProvider
+func NewVersionedDBProvider(
+	levelDBProvider statedb.VersionedDBProvider,
+	couchDBProvider statedb.VersionedDBProvider,
+	couchDBNamespaces map[string][]string,
+) *VersionedDBProvider {
+	p := &VersionedDBProvider{
+		levelDBProvider:   levelDBProvider,
+		couchDBProvider:   couchDBProvider,
+		couchDBNamespaces: map[string]map[string]struct{}{},
+	}
+	for channel, namespaces := range couchDBNamespaces {
+		if len(namespaces) == 0 {
+			continue
+		}
+		p.couchDBNamespaces[channel] = map[string]struct{}{}
+		for _, ns := range namespaces {
+			p.couchDBNamespaces[channel][ns] = struct{}{}
+		}
+		logger.Infof("The state of namespaces %v of channel [%s] is stored in CouchDB", namespaces, channel)
+	}
+	return p
+}
+
+// GetDBHandle implements the method in interface statedb.VersionedDBProvider
+func (p *VersionedDBProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	levelDB, err := p.levelDBProvider.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	couchDBNamespaces, ok := p.couchDBNamespaces[id]
+	if !ok {
+		return levelDB, nil
+	}
+	couchDB, err := p.couchDBProvider.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	return &versionedDB{
+		levelDB:           levelDB,
+		couchDB:           couchDB,
+		couchDBNamespaces: couchDBNamespaces,
+	}, nil
+}
+
+// HealthCheck checks the health of CouchDB, if the CouchDB provider supports the health checks
+func (p *VersionedDBProvider) HealthCheck(ctx context.Context) error {
+	if healthChecker, ok := p.couchDBProvider.(healthz.HealthChecker); ok {
+		return healthChecker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// Close closes both the providers
+func (p *VersionedDBProvider) Close() {
+	p.couchDBProvider.Close()
+	p.levelDBProvider.Close()
+}
+
+// versionedDB implements the interface statedb.VersionedDB on top of a goleveldb and a CouchDB VersionedDB.
+// It also implements the optional interfaces statedb.BulkOptimizable, statedb.IndexCapable and
+// statedb.RangeCountEstimator by delegating to the db of the namespace, where supported, and
+// statedb.SizeEstimator by adding up the sizes of both the dbs
+type versionedDB struct {
+	levelDB           statedb.VersionedDB
+	couchDB           statedb.VersionedDB
+	couchDBNamespaces map[string]struct{}
+}
+
+// isCouchDBNamespace returns true if the given statedb namespace, or the chaincode namespace
+// from which it is derived, is stored in CouchDB
+func (vdb *versionedDB) isCouchDBNamespace(ns string) bool {
+	if i := strings.Index(ns, derivedNsSeparator); i > 0 {
+		ns = ns[:i]
+	}
+	_, ok := vdb.couchDBNamespaces[ns]
+	return ok
+}
+
+func (vdb *versionedDB) dbFor(ns string) statedb.VersionedDB {
+	if vdb.isCouchDBNamespace(ns) {
+		return vdb.couchDB
+	}
+	return vdb.levelDB
+}
+
+// GetState implements method in VersionedDB interface
+func (vdb *versionedDB) GetState(namespace string, key string) (*statedb.VersionedValue, error) {
+	return vdb.dbFor(namespace).GetState(namespace, key)
+}
+
+// GetVersion implements method in VersionedDB interface
+func (vdb *versionedDB) GetVersion(namespace string, key string) (*version.Height, error) {
+	return vdb.dbFor(namespace).GetVersion(namespace, key)
+}
+
+// GetStateMultipleKeys implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateMultipleKeys(namespace string, keys []string) ([]*statedb.VersionedValue, error) {
+	return vdb.dbFor(namespace).GetStateMultipleKeys(namespace, keys)
+}
+
+// GetStateRangeScanIterator implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (statedb.ResultsIterator, error) {
+	return vdb.dbFor(namespace).GetStateRangeScanIterator(namespace, startKey, endKey)
+}
+
+// GetStateRangeScanIteratorWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIteratorWithPagination(namespace string, startKey string, endKey string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	return vdb.dbFor(namespace).GetStateRangeScanIteratorWithPagination(namespace, startKey, endKey, pageSize)
+}
+
+// ExecuteQuery implements method in VersionedDB interface
+func (vdb *versionedDB) ExecuteQuery(namespace, query string) (statedb.ResultsIterator, error) {
+	return vdb.dbFor(namespace).ExecuteQuery(namespace, query)
+}
+
+// ExecuteQueryWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) ExecuteQueryWithPagination(namespace, query, bookmark string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	return vdb.dbFor(namespace).ExecuteQueryWithPagination(namespace, query, bookmark, pageSize)
+}
+
+// ApplyUpdates implements method in VersionedDB interface. The batch is split between the two dbs
+// and both the dbs record the save point, even if no namespace of a db is updated. The CouchDB
+// updates are applied first; if the peer fails between the two, the updates of the block are
+// applied again to both the dbs during the recovery, as the lower of the save points is reported
+func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version.Height) error {
+	couchDBBatch := statedb.NewUpdateBatch()
+	couchDBBatch.ContainsPostOrderWrites = batch.ContainsPostOrderWrites
+	levelDBBatch := statedb.NewUpdateBatch()
+	levelDBBatch.ContainsPostOrderWrites = batch.ContainsPostOrderWrites
+	for _, ns := range batch.GetUpdatedNamespaces() {
+		target := levelDBBatch
+		if vdb.isCouchDBNamespace(ns) {
+			target = couchDBBatch
+		}
+		for key, vv := range batch.GetUpdates(ns) {
+			target.Update(ns, key, vv)
+		}
+	}
+	if err := vdb.couchDB.ApplyUpdates(couchDBBatch, height); err != nil {
+		return err
+	}
+	return vdb.levelDB.ApplyUpdates(levelDBBatch, height)
+}
+
+// GetLatestSavePoint implements method in VersionedDB interface. It returns the lower of
+// the save points of the two dbs
+func (vdb *versionedDB) GetLatestSavePoint() (*version.Height, error) {
+	couchDBSavepoint, err := vdb.couchDB.GetLatestSavePoint()
+	if err != nil {
+		return nil, err
+	}
+	levelDBSavepoint, err := vdb.levelDB.GetLatestSavePoint()
+	if err != nil {
+		return nil, err
+	}
+	if couchDBSavepoint == nil || levelDBSavepoint == nil {
+		return nil, nil
+	}
+	if couchDBSavepoint.Compare(levelDBSavepoint) < 0 {
+		return couchDBSavepoint, nil
+	}
+	return levelDBSavepoint, nil
+}
+
+// ValidateKeyValue implements method in VersionedDB interface. As the namespace is not known,
+// a key-value is required to be valid for both the dbs
+func (vdb *versionedDB) ValidateKeyValue(key string, value []byte) error {
+	if err := vdb.couchDB.ValidateKeyValue(key, value); err != nil {
+		return err
+	}
+	return vdb.levelDB.ValidateKeyValue(key, value)
+}
+
+// BytesKeySupported implements method in VersionedDB interface
+func (vdb *versionedDB) BytesKeySupported() bool {
+	return vdb.couchDB.BytesKeySupported() && vdb.levelDB.BytesKeySupported()
+}
+
+// GetFullScanIterator implements method in VersionedDB interface
+func (vdb *versionedDB) GetFullScanIterator(skipNamespace func(string) bool) (statedb.FullScanIterator, byte, error) {
+	return nil, byte(0), errors.New("a full scan is not supported for a channel whose state is split between goleveldb and CouchDB")
+}
+
+// Open implements method in VersionedDB interface
+func (vdb *versionedDB) Open() error {
+	if err := vdb.couchDB.Open(); err != nil {
+		return err
+	}
+	return vdb.levelDB.Open()
+}
+
+// Close implements method in VersionedDB interface
+func (vdb *versionedDB) Close() {
+	vdb.couchDB.Close()
+	vdb.levelDB.Close()
+}
+
+// LoadCommittedVersions implements method in BulkOptimizable interface. Only the versions of the
+// keys stored in CouchDB are loaded, as goleveldb doesn't support bulk loading
+func (vdb *versionedDB) LoadCommittedVersions(keys []*statedb.CompositeKey) error {
+	bulkOptimizable, ok := vdb.couchDB.(statedb.BulkOptimizable)
+	if !ok {
+		return nil
+	}
+	var couchDBKeys []*statedb.CompositeKey
+	for _, key := range keys {
+		if vdb.isCouchDBNamespace(key.Namespace) {
+			couchDBKeys = append(couchDBKeys, key)
+		}
+	}
+	return bulkOptimizable.LoadCommittedVersions(couchDBKeys)
+}
+
+// GetCachedVersion implements method in BulkOptimizable interface
+func (vdb *versionedDB) GetCachedVersion(namespace, key string) (*version.Height, bool) {
+	bulkOptimizable, ok := vdb.dbFor(namespace).(statedb.BulkOptimizable)
+	if !ok {
+		return nil, false
+	}
+	return bulkOptimizable.GetCachedVersion(namespace, key)
+}
+
+// ClearCachedVersions implements method in BulkOptimizable interface
+func (vdb *versionedDB) ClearCachedVersions() {
+	if bulkOptimizable, ok := vdb.couchDB.(statedb.BulkOptimizable); ok {
+		bulkOptimizable.ClearCachedVersions()
+	}
+}
+
+// GetDBType implements method in IndexCapable interface. The type of CouchDB is returned so
+// that the CouchDB indexes are extracted from the chaincode packages
+func (vdb *versionedDB) GetDBType() string {
+	if indexCapable, ok := vdb.couchDB.(statedb.IndexCapable); ok {
+		return indexCapable.GetDBType()
+	}
+	return ""
+}
+
+// ProcessIndexesForChaincodeDeploy implements method in IndexCapable interface. The indexes
+// of the namespaces stored in goleveldb are ignored
+func (vdb *versionedDB) ProcessIndexesForChaincodeDeploy(namespace string, indexFilesData map[string][]byte) error {
+	indexCapable, ok := vdb.dbFor(namespace).(statedb.IndexCapable)
+	if !ok {
+		logger.Debugf("Ignoring the indexes of namespace [%s] as its state is not stored in CouchDB", namespace)
+		return nil
+	}
+	return indexCapable.ProcessIndexesForChaincodeDeploy(namespace, indexFilesData)
+}
+
+// EstimateStateRangeCount implements method in RangeCountEstimator interface
+func (vdb *versionedDB) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	estimator, ok := vdb.dbFor(namespace).(statedb.RangeCountEstimator)
+	if !ok {
+		return 0, errors.New("the state database does not support estimating the number of keys in a range")
+	}
+	return estimator.EstimateStateRangeCount(namespace, startKey, endKey, limit)
+}
+
+// EstimateSize implements method in SizeEstimator interface
+func (vdb *versionedDB) EstimateSize() (int64, error) {
+	return statedb.EstimateTotalSize(vdb.levelDB, vdb.couchDB)
+}
@@ -0,0 +1,225 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statehybrid
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCouchDBProvider stands in for the CouchDB provider with a goleveldb provider whose
+// handles additionally implement the interfaces statedb.BulkOptimizable and statedb.IndexCapable
+type fakeCouchDBProvider struct {
+	statedb.VersionedDBProvider
+	dbs map[string]*fakeCouchDB
+}
+
+func (p *fakeCouchDBProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	vdb, err := p.VersionedDBProvider.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	p.dbs[id] = &fakeCouchDB{VersionedDB: vdb}
+	return p.dbs[id], nil
+}
+
+type fakeCouchDB struct {
+	statedb.VersionedDB
+	loadedKeys        []*statedb.CompositeKey
+	indexedNamespaces []string
+}
+
+func (db *fakeCouchDB) LoadCommittedVersions(keys []*statedb.CompositeKey) error {
+	db.loadedKeys = keys
+	return nil
+}
+
+func (db *fakeCouchDB) GetCachedVersion(namespace, key string) (*version.Height, bool) {
+	return version.NewHeight(1, 1), true
+}
+
+func (db *fakeCouchDB) ClearCachedVersions() {}
+
+func (db *fakeCouchDB) GetDBType() string {
+	return "couchdb"
+}
+
+func (db *fakeCouchDB) ProcessIndexesForChaincodeDeploy(namespace string, indexFilesData map[string][]byte) error {
+	db.indexedNamespaces = append(db.indexedNamespaces, namespace)
+	return nil
+}
+
+type testEnv struct {
+	levelDBEnv      *stateleveldb.TestVDBEnv
+	couchDBEnv      *stateleveldb.TestVDBEnv
+	couchDBProvider *fakeCouchDBProvider
+	provider        *VersionedDBProvider
+}
+
+func newTestEnv(t *testing.T, couchDBNamespaces map[string][]string) *testEnv {
+	levelDBEnv := stateleveldb.NewTestVDBEnv(t)
+	couchDBEnv := stateleveldb.NewTestVDBEnv(t)
+	couchDBProvider := &fakeCouchDBProvider{
+		VersionedDBProvider: couchDBEnv.DBProvider,
+		dbs:                 map[string]*fakeCouchDB{},
+	}
+	return &testEnv{
+		levelDBEnv:      levelDBEnv,
+		couchDBEnv:      couchDBEnv,
+		couchDBProvider: couchDBProvider,
+		provider:        NewVersionedDBProvider(levelDBEnv.DBProvider, couchDBProvider, couchDBNamespaces),
+	}
+}
+
+func (env *testEnv) cleanup() {
+	env.levelDBEnv.Cleanup()
+	env.couchDBEnv.Cleanup()
+}
+
+func TestChannelWithoutCouchDBNamespaces(t *testing.T) {
+	env := newTestEnv(t, map[string][]string{"ch1": {"ns1"}, "ch2": {}})
+	defer env.cleanup()
+
+	for _, channel := range []string{"ch2", "ch3"} {
+		db, err := env.provider.GetDBHandle(channel)
+		require.NoError(t, err)
+		require.NotNil(t, db)
+		require.NotContains(t, env.couchDBProvider.dbs, channel)
+		_, ok := db.(*versionedDB)
+		require.False(t, ok)
+	}
+}
+
+func TestApplyUpdatesAndGetState(t *testing.T) {
+	env := newTestEnv(t, map[string][]string{"ch1": {"ns1"}})
+	defer env.cleanup()
+	db, err := env.provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	levelDB, err := env.levelDBEnv.DBProvider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	couchDB := env.couchDBProvider.dbs["ch1"]
+
+	savepoint, err := db.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Nil(t, savepoint)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns1$$pcoll1", "key1", []byte("pvt-value1"), version.NewHeight(1, 1))
+	batch.Put("ns1$$hcoll1", "key1", []byte("hashed-value1"), version.NewHeight(1, 1))
+	batch.Put("ns2", "key1", []byte("value2"), version.NewHeight(1, 2))
+	batch.Put("ns2$$pcoll1", "key1", []byte("pvt-value2"), version.NewHeight(1, 2))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 2)))
+
+	for _, ns := range []string{"ns1", "ns1$$pcoll1", "ns1$$hcoll1"} {
+		vv, err := couchDB.GetState(ns, "key1")
+		require.NoError(t, err)
+		require.NotNil(t, vv, ns)
+		vv, err = levelDB.GetState(ns, "key1")
+		require.NoError(t, err)
+		require.Nil(t, vv, ns)
+	}
+	for _, ns := range []string{"ns2", "ns2$$pcoll1"} {
+		vv, err := levelDB.GetState(ns, "key1")
+		require.NoError(t, err)
+		require.NotNil(t, vv, ns)
+		vv, err = couchDB.GetState(ns, "key1")
+		require.NoError(t, err)
+		require.Nil(t, vv, ns)
+	}
+
+	vv, err := db.GetState("ns1", "key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), vv.Value)
+	vv, err = db.GetState("ns2", "key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value2"), vv.Value)
+	ver, err := db.GetVersion("ns2", "key1")
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 2), ver)
+	vvs, err := db.GetStateMultipleKeys("ns1$$pcoll1", []string{"key1"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("pvt-value1"), vvs[0].Value)
+
+	itr, err := db.GetStateRangeScanIterator("ns2", "", "")
+	require.NoError(t, err)
+	res, err := itr.Next()
+	require.NoError(t, err)
+	require.Equal(t, []byte("value2"), res.(*statedb.VersionedKV).Value)
+	itr.Close()
+
+	savepoint, err = db.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 2), savepoint)
+
+	// a batch that updates only the goleveldb namespaces moves the save points of both the dbs
+	batch = statedb.NewUpdateBatch()
+	batch.Put("ns2", "key2", []byte("value2"), version.NewHeight(2, 1))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(2, 1)))
+	couchDBSavepoint, err := couchDB.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(2, 1), couchDBSavepoint)
+
+	// the lower of the save points is returned
+	require.NoError(t, levelDB.ApplyUpdates(statedb.NewUpdateBatch(), version.NewHeight(3, 1)))
+	savepoint, err = db.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(2, 1), savepoint)
+}
+
+func TestBulkOptimizableAndIndexCapable(t *testing.T) {
+	env := newTestEnv(t, map[string][]string{"ch1": {"ns1"}})
+	defer env.cleanup()
+	db, err := env.provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	couchDB := env.couchDBProvider.dbs["ch1"]
+
+	bulkOptimizable, ok := db.(statedb.BulkOptimizable)
+	require.True(t, ok)
+	require.NoError(t, bulkOptimizable.LoadCommittedVersions([]*statedb.CompositeKey{
+		{Namespace: "ns1", Key: "key1"},
+		{Namespace: "ns2", Key: "key1"},
+		{Namespace: "ns1$$hcoll1", Key: "key1"},
+	}))
+	require.Equal(t, []*statedb.CompositeKey{
+		{Namespace: "ns1", Key: "key1"},
+		{Namespace: "ns1$$hcoll1", Key: "key1"},
+	}, couchDB.loadedKeys)
+	_, found := bulkOptimizable.GetCachedVersion("ns1", "key1")
+	require.True(t, found)
+	_, found = bulkOptimizable.GetCachedVersion("ns2", "key1")
+	require.False(t, found)
+
+	indexCapable, ok := db.(statedb.IndexCapable)
+	require.True(t, ok)
+	require.Equal(t, "couchdb", indexCapable.GetDBType())
+	require.NoError(t, indexCapable.ProcessIndexesForChaincodeDeploy("ns1", nil))
+	require.NoError(t, indexCapable.ProcessIndexesForChaincodeDeploy("ns1$$pcoll1", nil))
+	require.NoError(t, indexCapable.ProcessIndexesForChaincodeDeploy("ns2", nil))
+	require.Equal(t, []string{"ns1", "ns1$$pcoll1"}, couchDB.indexedNamespaces)
+
+	estimator, ok := db.(statedb.RangeCountEstimator)
+	require.True(t, ok)
+	_, err = estimator.EstimateStateRangeCount("ns2", "", "", 0)
+	require.NoError(t, err)
+	_, err = estimator.EstimateStateRangeCount("ns1", "", "", 0)
+	require.EqualError(t, err, "the state database does not support estimating the number of keys in a range")
+}
+
+func TestFullScanIteratorNotSupported(t *testing.T) {
+	env := newTestEnv(t, map[string][]string{"ch1": {"ns1"}})
+	defer env.cleanup()
+	db, err := env.provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+
+	_, _, err = db.GetFullScanIterator(func(string) bool { return false })
+	require.EqualError(t, err, "a full scan is not supported for a channel whose state is split between goleveldb and CouchDB")
+}
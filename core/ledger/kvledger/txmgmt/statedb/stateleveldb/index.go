@@ -0,0 +1,426 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateleveldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	indexDefKeyPrefix   = []byte{'x'}
+	indexEntryKeyPrefix = []byte{'i'}
+	indexFieldSep       = "."
+	maxIndexBuildBatch  = 1000
+)
+
+// tags that precede the encoded field values in the index entries. The tags define the relative order
+// of the values of different JSON types, which follows the CouchDB collation order
+const (
+	nullValueTag   = byte(0x01)
+	falseValueTag  = byte(0x02)
+	trueValueTag   = byte(0x03)
+	numberValueTag = byte(0x04)
+	stringValueTag = byte(0x05)
+)
+
+var (
+	stringEscapedZero  = []byte{0x00, 0xff}
+	stringTerminator   = []byte{0x00, 0x01}
+	errNotIndexedValue = errors.New("value cannot be indexed")
+)
+
+// indexDefinition captures a field index that a chaincode declares in a file under META-INF/statedb/leveldb/indexes.
+// The index file uses the same layout as a CouchDB index definition, for example
+// {"index":{"fields":["owner","size"]},"name":"indexOwnerSize","type":"json"}. A field refers to a nested JSON field
+// with the names separated by '.'. The leveldb maintains an index entry for a key only if the value of the key is a JSON
+// object that carries a null, boolean, number, or string for each of the indexed fields
+type indexDefinition struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+func parseIndexDefinition(indexFileData []byte) (*indexDefinition, error) {
+	indexFile := &struct {
+		Index struct {
+			Fields []string `json:"fields"`
+		} `json:"index"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}{}
+	decoder := json.NewDecoder(bytes.NewReader(indexFileData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(indexFile); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling the index definition")
+	}
+	if indexFile.Type != "" && indexFile.Type != "json" {
+		return nil, errors.Errorf("index type [%s] is not supported, the index type must be json", indexFile.Type)
+	}
+	if indexFile.Name == "" {
+		return nil, errors.New("index name must be specified")
+	}
+	if strings.ContainsRune(indexFile.Name, rune(nsKeySep[0])) {
+		return nil, errors.Errorf("index name [%s] must not contain a nil character", indexFile.Name)
+	}
+	if len(indexFile.Index.Fields) == 0 {
+		return nil, errors.Errorf("index [%s] must include at least one field", indexFile.Name)
+	}
+	fields := map[string]bool{}
+	for _, f := range indexFile.Index.Fields {
+		if f == "" {
+			return nil, errors.Errorf("index [%s] contains an empty field name", indexFile.Name)
+		}
+		if fields[f] {
+			return nil, errors.Errorf("index [%s] contains the field [%s] more than once", indexFile.Name, f)
+		}
+		fields[f] = true
+	}
+	return &indexDefinition{
+		Name:   indexFile.Name,
+		Fields: indexFile.Index.Fields,
+	}, nil
+}
+
+func (d *indexDefinition) sameFields(other *indexDefinition) bool {
+	if len(d.Fields) != len(other.Fields) {
+		return false
+	}
+	for i, f := range d.Fields {
+		if other.Fields[i] != f {
+			return false
+		}
+	}
+	return true
+}
+
+// entryKey returns the index entry for the given key and the JSON document. A nil entry is returned if the
+// document does not carry indexable values for all the fields of the index
+func (d *indexDefinition) entryKey(ns, key string, doc map[string]interface{}) []byte {
+	if doc == nil {
+		return nil
+	}
+	k := encodeIndexEntryPrefix(ns, d.Name)
+	for _, f := range d.Fields {
+		fieldValue, ok := lookupField(doc, f)
+		if !ok {
+			return nil
+		}
+		encodedValue, err := encodeIndexValue(fieldValue)
+		if err != nil {
+			return nil
+		}
+		k = append(k, encodedValue...)
+	}
+	return append(k, []byte(key)...)
+}
+
+// ProcessIndexesForChaincodeDeploy implements method in IndexCapable interface. The index files are processed
+// in the order of the file names so that all the peers end up with the same indexes, in case two files define
+// the indexes with the same name. An index that already exists with the same fields is left untouched, otherwise
+// the index entries are (re)built from the existing data of the namespace. The commits are blocked while an
+// index is being built
+func (vdb *versionedDB) ProcessIndexesForChaincodeDeploy(namespace string, indexFilesData map[string][]byte) error {
+	var indexFilesName []string
+	for fileName := range indexFilesData {
+		indexFilesName = append(indexFilesName, fileName)
+	}
+	sort.Strings(indexFilesName)
+
+	vdb.indexesLock.Lock()
+	defer vdb.indexesLock.Unlock()
+	for _, fileName := range indexFilesName {
+		indexDef, err := parseIndexDefinition(indexFilesData[fileName])
+		if err != nil {
+			logger.Errorf("error processing index file [%s] for chaincode [%s] on channel [%s]: %s",
+				fileName, namespace, vdb.dbName, err)
+			continue
+		}
+		created, err := vdb.createIndex(namespace, indexDef)
+		if err != nil {
+			return err
+		}
+		if created {
+			logger.Infof("successfully created index present in the file [%s] for chaincode [%s] on channel [%s]",
+				fileName, namespace, vdb.dbName)
+		}
+	}
+	return nil
+}
+
+// GetDBType implements method in IndexCapable interface
+func (vdb *versionedDB) GetDBType() string {
+	return "leveldb"
+}
+
+func (vdb *versionedDB) createIndex(ns string, indexDef *indexDefinition) (bool, error) {
+	existingIndexes := vdb.indexes[ns]
+	for _, existing := range existingIndexes {
+		if existing.Name == indexDef.Name && existing.sameFields(indexDef) {
+			return false, nil
+		}
+	}
+
+	// remove a previous definition of the index along with its entries, if any, or the entries left over by an
+	// index build that was interrupted by a crash
+	var otherIndexes []*indexDefinition
+	for _, existing := range existingIndexes {
+		if existing.Name != indexDef.Name {
+			otherIndexes = append(otherIndexes, existing)
+		}
+	}
+	vdb.indexes[ns] = otherIndexes
+	if err := vdb.db.Delete(encodeIndexDefKey(ns, indexDef.Name), true); err != nil {
+		return false, err
+	}
+	if err := vdb.deleteKeysInRange(util.BytesPrefix(encodeIndexEntryPrefix(ns, indexDef.Name))); err != nil {
+		return false, err
+	}
+
+	dbItr := vdb.db.GetIterator(encodeDataKey(ns, ""), dataKeyStarterForNextNamespace(ns))
+	defer dbItr.Release()
+	dbBatch := leveldbhelper.NewUpdateBatch()
+	for dbItr.Next() {
+		_, key := decodeDataKey(dbItr.Key())
+		vv, err := decodeValue(dbItr.Value())
+		if err != nil {
+			return false, err
+		}
+		if entry := indexDef.entryKey(ns, key, unmarshalIndexableDoc(vv.Value)); entry != nil {
+			dbBatch.Put(entry, []byte{})
+		}
+		if dbBatch.Len() >= maxIndexBuildBatch {
+			if err := vdb.db.WriteBatch(dbBatch, false); err != nil {
+				return false, err
+			}
+			dbBatch = leveldbhelper.NewUpdateBatch()
+		}
+	}
+	if err := dbItr.Error(); err != nil {
+		return false, errors.Wrapf(err, "internal leveldb error while building index [%s] for namespace [%s]", indexDef.Name, ns)
+	}
+	// the definition is recorded along with the last set of entries so that a partially built index is not used
+	indexDefBytes, err := json.Marshal(indexDef)
+	if err != nil {
+		return false, errors.Wrap(err, "error marshalling the index definition")
+	}
+	dbBatch.Put(encodeIndexDefKey(ns, indexDef.Name), indexDefBytes)
+	if err := vdb.db.WriteBatch(dbBatch, true); err != nil {
+		return false, err
+	}
+
+	indexes := append(otherIndexes, indexDef)
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	vdb.indexes[ns] = indexes
+	return true, nil
+}
+
+func (vdb *versionedDB) deleteKeysInRange(r *util.Range) error {
+	dbItr := vdb.db.GetIterator(r.Start, r.Limit)
+	defer dbItr.Release()
+	dbBatch := leveldbhelper.NewUpdateBatch()
+	for dbItr.Next() {
+		k := make([]byte, len(dbItr.Key()))
+		copy(k, dbItr.Key())
+		dbBatch.Delete(k)
+		if dbBatch.Len() >= maxIndexBuildBatch {
+			if err := vdb.db.WriteBatch(dbBatch, false); err != nil {
+				return err
+			}
+			dbBatch = leveldbhelper.NewUpdateBatch()
+		}
+	}
+	if err := dbItr.Error(); err != nil {
+		return errors.Wrap(err, "internal leveldb error while deleting index entries")
+	}
+	return vdb.db.WriteBatch(dbBatch, true)
+}
+
+// loadIndexDefinitions loads the definitions of all the indexes that have been created in the db
+func (vdb *versionedDB) loadIndexDefinitions() error {
+	dbItr := vdb.db.GetIterator(indexDefKeyPrefix, util.BytesPrefix(indexDefKeyPrefix).Limit)
+	defer dbItr.Release()
+	for dbItr.Next() {
+		ns, _ := decodeDataKey(dbItr.Key())
+		indexDef := &indexDefinition{}
+		if err := json.Unmarshal(dbItr.Value(), indexDef); err != nil {
+			return errors.Wrapf(err, "error unmarshalling the definition of an index for namespace [%s]", ns)
+		}
+		// the iterator returns the definitions of a namespace sorted by the index names
+		vdb.indexes[ns] = append(vdb.indexes[ns], indexDef)
+	}
+	return errors.Wrap(dbItr.Error(), "internal leveldb error while loading index definitions")
+}
+
+// addIndexUpdates adds to the dbBatch the changes in the index entries that are caused by the update of the given key.
+// The existing value of the key is read from the db for removing the stale index entries
+func (vdb *versionedDB) addIndexUpdates(dbBatch *leveldbhelper.UpdateBatch, ns string, key string, newValue []byte) error {
+	indexes := vdb.indexes[ns]
+	if len(indexes) == 0 {
+		return nil
+	}
+	var oldValue []byte
+	dbVal, err := vdb.db.Get(encodeDataKey(ns, key))
+	if err != nil {
+		return err
+	}
+	if dbVal != nil {
+		vv, err := decodeValue(dbVal)
+		if err != nil {
+			return err
+		}
+		oldValue = vv.Value
+	}
+	oldDoc := unmarshalIndexableDoc(oldValue)
+	newDoc := unmarshalIndexableDoc(newValue)
+	if oldDoc == nil && newDoc == nil {
+		return nil
+	}
+	for _, indexDef := range indexes {
+		oldEntry := indexDef.entryKey(ns, key, oldDoc)
+		newEntry := indexDef.entryKey(ns, key, newDoc)
+		if bytes.Equal(oldEntry, newEntry) {
+			continue
+		}
+		if oldEntry != nil {
+			dbBatch.Delete(oldEntry)
+		}
+		if newEntry != nil {
+			dbBatch.Put(newEntry, []byte{})
+		}
+	}
+	return nil
+}
+
+// unmarshalIndexableDoc returns nil if the value is not a JSON object
+func unmarshalIndexableDoc(value []byte) map[string]interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return nil
+	}
+	return doc
+}
+
+func lookupField(doc map[string]interface{}, field string) (interface{}, bool) {
+	names := strings.Split(field, indexFieldSep)
+	var current interface{} = doc
+	for _, name := range names {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func encodeIndexDefKey(ns, indexName string) []byte {
+	k := append([]byte{}, indexDefKeyPrefix...)
+	k = append(k, []byte(ns)...)
+	k = append(k, nsKeySep...)
+	return append(k, []byte(indexName)...)
+}
+
+func encodeIndexEntryPrefix(ns, indexName string) []byte {
+	k := append([]byte{}, indexEntryKeyPrefix...)
+	k = append(k, []byte(ns)...)
+	k = append(k, nsKeySep...)
+	k = append(k, []byte(indexName)...)
+	return append(k, nsKeySep...)
+}
+
+// encodeIndexValue encodes a JSON value such that the byte order of the encoded values is the same as the
+// order of the values. Within the same type, numbers are ordered numerically and strings are ordered by bytes.
+// Each encoded value is self delimiting so that the multiple values of a composite index can be concatenated
+func encodeIndexValue(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case nil:
+		return []byte{nullValueTag}, nil
+	case bool:
+		if t {
+			return []byte{trueValueTag}, nil
+		}
+		return []byte{falseValueTag}, nil
+	case float64:
+		if t == 0 {
+			// treat -0 the same as 0
+			t = 0
+		}
+		bits := math.Float64bits(t)
+		if t < 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		encoded := make([]byte, 9)
+		encoded[0] = numberValueTag
+		binary.BigEndian.PutUint64(encoded[1:], bits)
+		return encoded, nil
+	case string:
+		encoded := []byte{stringValueTag}
+		for _, b := range []byte(t) {
+			if b == 0x00 {
+				encoded = append(encoded, stringEscapedZero...)
+				continue
+			}
+			encoded = append(encoded, b)
+		}
+		return append(encoded, stringTerminator...), nil
+	default:
+		return nil, errNotIndexedValue
+	}
+}
+
+// decodeKeyFromIndexEntry returns the key that is present at the end of the index entry, after the prefix of the
+// given length and the given number of encoded values
+func decodeKeyFromIndexEntry(entry []byte, prefixLen, numValues int) (string, error) {
+	remaining := entry[prefixLen:]
+	for i := 0; i < numValues; i++ {
+		if len(remaining) == 0 {
+			return "", errors.Errorf("unexpected end of the index entry [%#v]", entry)
+		}
+		valueLen := 0
+		switch remaining[0] {
+		case nullValueTag, falseValueTag, trueValueTag:
+			valueLen = 1
+		case numberValueTag:
+			valueLen = 9
+		case stringValueTag:
+			terminatorPos := 1
+			for ; terminatorPos < len(remaining)-1; terminatorPos++ {
+				if remaining[terminatorPos] == 0x00 && remaining[terminatorPos+1] != stringEscapedZero[1] {
+					break
+				}
+				if remaining[terminatorPos] == 0x00 {
+					terminatorPos++
+				}
+			}
+			valueLen = terminatorPos + len(stringTerminator)
+		default:
+			return "", errors.Errorf("unexpected value tag [%#v] in the index entry [%#v]", remaining[0], entry)
+		}
+		if valueLen > len(remaining) {
+			return "", errors.Errorf("unexpected end of the index entry [%#v]", entry)
+		}
+		remaining = remaining[valueLen:]
+	}
+	return string(remaining), nil
+}
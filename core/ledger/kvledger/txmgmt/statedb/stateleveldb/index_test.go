@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateleveldb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func TestParseIndexDefinition(t *testing.T) {
+	indexDef, err := parseIndexDefinition([]byte(`{"index":{"fields":["owner","size"]},"name":"indexOwnerSize","type":"json"}`))
+	require.NoError(t, err)
+	require.Equal(t, &indexDefinition{Name: "indexOwnerSize", Fields: []string{"owner", "size"}}, indexDef)
+
+	tests := []struct {
+		indexFile   string
+		expectedErr string
+	}{
+		{`{"index":{"fields":["owner"]},"name":"i1","ddoc":"d1"}`, `error unmarshalling the index definition: json: unknown field "ddoc"`},
+		{`{"index":{"fields":["owner"]},"name":"i1","type":"text"}`, "index type [text] is not supported, the index type must be json"},
+		{`{"index":{"fields":["owner"]}}`, "index name must be specified"},
+		{`{"index":{"fields":["owner"]},"name":"i\u0000"}`, "index name [i\x00] must not contain a nil character"},
+		{`{"index":{"fields":[]},"name":"i1"}`, "index [i1] must include at least one field"},
+		{`{"index":{"fields":["owner",""]},"name":"i1"}`, "index [i1] contains an empty field name"},
+		{`{"index":{"fields":["owner","owner"]},"name":"i1"}`, "index [i1] contains the field [owner] more than once"},
+		{`{"index":{"fields":[{"owner":"asc"}]},"name":"i1"}`, "error unmarshalling the index definition: json: cannot unmarshal object into"},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			_, err := parseIndexDefinition([]byte(test.indexFile))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
+
+func TestIndexValueEncoding(t *testing.T) {
+	// the values are listed in the expected order of the encoded values
+	values := []interface{}{
+		nil,
+		false,
+		true,
+		float64(-1000.5),
+		float64(-1),
+		float64(0),
+		float64(0.5),
+		float64(1),
+		float64(1000),
+		"",
+		"a",
+		"a\x00",
+		"a\x00b",
+		"ab",
+		"b",
+	}
+	var previous []byte
+	for i, v := range values {
+		encoded, err := encodeIndexValue(v)
+		require.NoError(t, err)
+		if previous != nil {
+			require.True(t, bytes.Compare(previous, encoded) < 0, "value at position %d is not ordered", i)
+		}
+		previous = encoded
+
+		// the key is recovered from the index entry that contains the encoded values
+		entry := append(append([]byte("prefix"), encoded...), encoded...)
+		entry = append(entry, []byte("key\x00with\x00nil")...)
+		key, err := decodeKeyFromIndexEntry(entry, len("prefix"), 2)
+		require.NoError(t, err)
+		require.Equal(t, "key\x00with\x00nil", key)
+	}
+
+	negativeZero, err := encodeIndexValue(float64(0) * -1)
+	require.NoError(t, err)
+	zero, err := encodeIndexValue(float64(0))
+	require.NoError(t, err)
+	require.Equal(t, zero, negativeZero)
+
+	_, err = encodeIndexValue(map[string]interface{}{})
+	require.Equal(t, errNotIndexedValue, err)
+	_, err = encodeIndexValue([]interface{}{})
+	require.Equal(t, errNotIndexedValue, err)
+
+	_, err = decodeKeyFromIndexEntry([]byte{stringValueTag, 'a'}, 0, 1)
+	require.EqualError(t, err, "unexpected end of the index entry [[]byte{0x5, 0x61}]")
+	_, err = decodeKeyFromIndexEntry([]byte{0xff}, 0, 1)
+	require.EqualError(t, err, "unexpected value tag [0xff] in the index entry [[]byte{0xff}]")
+}
+
+func TestIndexMaintenance(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testindexmaintenance")
+	require.NoError(t, err)
+	vdb := db.(*versionedDB)
+	require.Equal(t, "leveldb", vdb.GetDBType())
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte(`{"owner":"tom","size":1}`), version.NewHeight(1, 1))
+	batch.Put("ns1", "key2", []byte(`{"owner":"jerry","size":2}`), version.NewHeight(1, 2))
+	batch.Put("ns1", "key3", []byte(`{"owner":"tom"}`), version.NewHeight(1, 3))
+	batch.Put("ns1", "key4", []byte(`not a json`), version.NewHeight(1, 4))
+	batch.Put("ns2", "key1", []byte(`{"owner":"tom","size":1}`), version.NewHeight(1, 5))
+	require.NoError(t, vdb.ApplyUpdates(batch, version.NewHeight(1, 5)))
+
+	// the index is built from the existing data; a bad index file is skipped
+	require.NoError(t, vdb.ProcessIndexesForChaincodeDeploy("ns1", map[string][]byte{
+		"META-INF/statedb/leveldb/indexes/indexOwner.json":     []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwner"}`),
+		"META-INF/statedb/leveldb/indexes/badIndex.json":       []byte(`{"index":{"fields":[]},"name":"badIndex"}`),
+		"META-INF/statedb/leveldb/indexes/indexAddress.json":   []byte(`{"index":{"fields":["address.city"]},"name":"indexAddress"}`),
+		"META-INF/statedb/leveldb/indexes/nonJSONContent.json": []byte(`not a json`),
+	}))
+	require.Equal(t, []*indexDefinition{
+		{Name: "indexAddress", Fields: []string{"address.city"}},
+		{Name: "indexOwner", Fields: []string{"owner", "size"}},
+	}, vdb.indexes["ns1"])
+	require.Equal(t, []string{"key2", "key1"}, indexedKeysForTest(t, vdb, "ns1", "indexOwner"))
+	require.Empty(t, indexedKeysForTest(t, vdb, "ns1", "indexAddress"))
+
+	// the index entries follow the updates and deletes
+	batch = statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte(`{"owner":"tom","size":10}`), version.NewHeight(2, 1))
+	batch.Delete("ns1", "key2", version.NewHeight(2, 2))
+	batch.Put("ns1", "key3", []byte(`{"owner":"tom","size":3,"address":{"city":"paris"}}`), version.NewHeight(2, 3))
+	batch.Put("ns1", "key4", []byte(`{"owner":"alice","size":4}`), version.NewHeight(2, 4))
+	batch.Put("ns1", "key5", []byte(`{"owner":"bob","size":{"height":5}}`), version.NewHeight(2, 5))
+	require.NoError(t, vdb.ApplyUpdates(batch, version.NewHeight(2, 5)))
+	require.Equal(t, []string{"key4", "key3", "key1"}, indexedKeysForTest(t, vdb, "ns1", "indexOwner"))
+	require.Equal(t, []string{"key3"}, indexedKeysForTest(t, vdb, "ns1", "indexAddress"))
+
+	// redeploying the same index does not rebuild the index; redefining an index replaces the entries
+	require.NoError(t, vdb.ProcessIndexesForChaincodeDeploy("ns1", map[string][]byte{
+		"META-INF/statedb/leveldb/indexes/indexOwner.json":   []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwner"}`),
+		"META-INF/statedb/leveldb/indexes/indexAddress.json": []byte(`{"index":{"fields":["size"]},"name":"indexAddress"}`),
+	}))
+	require.Equal(t, []string{"key4", "key3", "key1"}, indexedKeysForTest(t, vdb, "ns1", "indexOwner"))
+	require.Equal(t, []string{"key3", "key4", "key1"}, indexedKeysForTest(t, vdb, "ns1", "indexAddress"))
+
+	// the index definitions are loaded when the db is reopened
+	db, err = env.DBProvider.GetDBHandle("testindexmaintenance")
+	require.NoError(t, err)
+	require.Equal(t, []*indexDefinition{
+		{Name: "indexAddress", Fields: []string{"size"}},
+		{Name: "indexOwner", Fields: []string{"owner", "size"}},
+	}, db.(*versionedDB).indexes["ns1"])
+	require.Empty(t, db.(*versionedDB).indexes["ns2"])
+}
+
+func indexedKeysForTest(t *testing.T, vdb *versionedDB, ns, indexName string) []string {
+	indexDef := &indexDefinition{}
+	for _, d := range vdb.indexes[ns] {
+		if d.Name == indexName {
+			indexDef = d
+		}
+	}
+	prefix := encodeIndexEntryPrefix(ns, indexName)
+	r := util.BytesPrefix(prefix)
+	itr := vdb.db.GetIterator(r.Start, r.Limit)
+	defer itr.Release()
+	var keys []string
+	for itr.Next() {
+		key, err := decodeKeyFromIndexEntry(itr.Key(), len(prefix), len(indexDef.Fields))
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,291 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateleveldb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// indexQuery is the subset of the CouchDB query syntax that the leveldb serves by using the indexes declared
+// by the chaincode. A query selects the keys by the conditions on one or more fields. The fields with the
+// equality conditions should form a prefix of the fields of an index and, optionally, the field that follows
+// the prefix in the index can have a range condition, for instance,
+// {"selector":{"owner":"tom","size":{"$gt":5,"$lte":10}},"use_index":"indexOwnerSize"}.
+// The results are returned in the order of the index
+type indexQuery struct {
+	Selector map[string]json.RawMessage `json:"selector"`
+	UseIndex json.RawMessage            `json:"use_index"`
+	Limit    int32                      `json:"limit"`
+}
+
+// fieldCondition is either an equality or a range condition on a field
+type fieldCondition struct {
+	eq                 []byte
+	lower, upper       []byte
+	lowerInclusive     bool
+	upperInclusive     bool
+	hasLower, hasUpper bool
+	lowerTag, upperTag byte
+	isRange            bool
+}
+
+func parseIndexQuery(query string) (*indexQuery, map[string]*fieldCondition, error) {
+	q := &indexQuery{}
+	decoder := json.NewDecoder(strings.NewReader(query))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(q); err != nil {
+		return nil, nil, errors.Wrap(err, "error unmarshalling the query, only the fields [selector, use_index, limit] are supported for leveldb")
+	}
+	if len(q.Selector) == 0 {
+		return nil, nil, errors.New("the query must include a selector with at least one field")
+	}
+	conditions := map[string]*fieldCondition{}
+	for field, rawCondition := range q.Selector {
+		if strings.HasPrefix(field, "$") {
+			return nil, nil, errors.Errorf("operator [%s] is not supported for leveldb", field)
+		}
+		c, err := parseFieldCondition(rawCondition)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "invalid condition for the field [%s]", field)
+		}
+		conditions[field] = c
+	}
+	return q, conditions, nil
+}
+
+func parseFieldCondition(rawCondition json.RawMessage) (*fieldCondition, error) {
+	var condition interface{}
+	if err := json.Unmarshal(rawCondition, &condition); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling the condition")
+	}
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		eq, err := encodeIndexValue(condition)
+		if err != nil {
+			return nil, errors.New("only a null, boolean, number, or string value can be matched")
+		}
+		return &fieldCondition{eq: eq}, nil
+	}
+
+	c := &fieldCondition{}
+	for op, operand := range operators {
+		encodedOperand, err := encodeIndexValue(operand)
+		if err != nil {
+			return nil, errors.Errorf("the operand of [%s] must be a null, boolean, number, or string value", op)
+		}
+		switch op {
+		case "$eq":
+			c.eq = encodedOperand
+		case "$gt", "$gte":
+			if c.hasLower {
+				return nil, errors.New("only one of [$gt, $gte] can be specified")
+			}
+			c.lower, c.lowerInclusive, c.hasLower, c.lowerTag = encodedOperand, op == "$gte", true, encodedOperand[0]
+		case "$lt", "$lte":
+			if c.hasUpper {
+				return nil, errors.New("only one of [$lt, $lte] can be specified")
+			}
+			c.upper, c.upperInclusive, c.hasUpper, c.upperTag = encodedOperand, op == "$lte", true, encodedOperand[0]
+		default:
+			return nil, errors.Errorf("operator [%s] is not supported for leveldb", op)
+		}
+	}
+	c.isRange = c.hasLower || c.hasUpper
+	switch {
+	case c.eq == nil && !c.isRange:
+		return nil, errors.New("at least one operator must be specified")
+	case c.eq != nil && c.isRange:
+		return nil, errors.New("[$eq] cannot be combined with a range operator")
+	case c.hasLower && c.hasUpper && c.lowerTag != c.upperTag:
+		return nil, errors.New("the bounds of a range must be of the same type")
+	}
+	return c, nil
+}
+
+// rangeFor returns the range of the index entries that satisfy the conditions, if all the conditions
+// can be served by the given index
+func (d *indexDefinition) rangeFor(ns string, conditions map[string]*fieldCondition) (*util.Range, bool) {
+	prefix := encodeIndexEntryPrefix(ns, d.Name)
+	numServed := 0
+	var rangeCondition *fieldCondition
+	for _, f := range d.Fields {
+		c, ok := conditions[f]
+		if !ok {
+			break
+		}
+		numServed++
+		if c.isRange {
+			rangeCondition = c
+			break
+		}
+		prefix = append(prefix, c.eq...)
+	}
+	if numServed != len(conditions) {
+		return nil, false
+	}
+	if rangeCondition == nil {
+		return util.BytesPrefix(prefix), true
+	}
+
+	r := &util.Range{}
+	// a range with a single bound is limited to the values of the same type as that of the bound
+	switch {
+	case !rangeCondition.hasLower:
+		r.Start = append(append([]byte{}, prefix...), rangeCondition.upperTag)
+	case rangeCondition.lowerInclusive:
+		r.Start = append(append([]byte{}, prefix...), rangeCondition.lower...)
+	default:
+		r.Start = util.BytesPrefix(append(append([]byte{}, prefix...), rangeCondition.lower...)).Limit
+	}
+	switch {
+	case !rangeCondition.hasUpper:
+		r.Limit = append(append([]byte{}, prefix...), rangeCondition.lowerTag+1)
+	case rangeCondition.upperInclusive:
+		r.Limit = util.BytesPrefix(append(append([]byte{}, prefix...), rangeCondition.upper...)).Limit
+	default:
+		r.Limit = append(append([]byte{}, prefix...), rangeCondition.upper...)
+	}
+	return r, true
+}
+
+func (vdb *versionedDB) hasIndexes(namespace string) bool {
+	vdb.indexesLock.RLock()
+	defer vdb.indexesLock.RUnlock()
+	return len(vdb.indexes[namespace]) > 0
+}
+
+// executeIndexQuery serves the query by using the first index, in the order of the index names, that can serve all the
+// conditions in the query. If the query specifies "use_index", only the index with the given name is considered. The
+// bookmark, if not empty, is the one returned by a previous execution of the same query
+func (vdb *versionedDB) executeIndexQuery(namespace, query, bookmark string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	q, conditions, err := parseIndexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	useIndex, err := indexNameFromUseIndex(q.UseIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	vdb.indexesLock.RLock()
+	indexes := vdb.indexes[namespace]
+	vdb.indexesLock.RUnlock()
+	var indexDef *indexDefinition
+	var r *util.Range
+	for _, d := range indexes {
+		if useIndex != "" && d.Name != useIndex {
+			continue
+		}
+		var ok bool
+		if r, ok = d.rangeFor(namespace, conditions); ok {
+			indexDef = d
+			break
+		}
+	}
+	if indexDef == nil {
+		return nil, errors.Errorf("no index defined on the namespace [%s] can serve the query [%s]", namespace, query)
+	}
+
+	if bookmark != "" {
+		bookmarkKey, err := hex.DecodeString(bookmark)
+		if err != nil || bytes.Compare(bookmarkKey, r.Start) < 0 || bytes.Compare(bookmarkKey, r.Limit) >= 0 {
+			return nil, errors.Errorf("invalid bookmark [%s] for the query [%s]", bookmark, query)
+		}
+		r.Start = bookmarkKey
+	}
+	if pageSize == 0 {
+		pageSize = q.Limit
+	}
+	logger.Debugf("Channel [%s]: executing query [%s] on namespace [%s] using the index [%s]", vdb.dbName, query, namespace, indexDef.Name)
+	return &indexScanner{
+		vdb:            vdb,
+		namespace:      namespace,
+		prefixLen:      len(encodeIndexEntryPrefix(namespace, indexDef.Name)),
+		numFields:      len(indexDef.Fields),
+		dbItr:          vdb.db.GetIterator(r.Start, r.Limit),
+		requestedLimit: pageSize,
+	}, nil
+}
+
+// indexNameFromUseIndex accepts "use_index" in either of the forms "indexName" or ["designDoc", "indexName"]
+func indexNameFromUseIndex(useIndex json.RawMessage) (string, error) {
+	if len(useIndex) == 0 {
+		return "", nil
+	}
+	var name string
+	if err := json.Unmarshal(useIndex, &name); err == nil {
+		return name, nil
+	}
+	var names []string
+	if err := json.Unmarshal(useIndex, &names); err != nil || len(names) == 0 || len(names) > 2 {
+		return "", errors.Errorf("invalid value for use_index [%s]", useIndex)
+	}
+	return names[len(names)-1], nil
+}
+
+type indexScanner struct {
+	vdb                  *versionedDB
+	namespace            string
+	prefixLen            int
+	numFields            int
+	dbItr                iterator.Iterator
+	requestedLimit       int32
+	totalRecordsReturned int32
+}
+
+func (scanner *indexScanner) Next() (statedb.QueryResult, error) {
+	if scanner.requestedLimit > 0 && scanner.totalRecordsReturned >= scanner.requestedLimit {
+		return nil, nil
+	}
+	for scanner.dbItr.Next() {
+		key, err := decodeKeyFromIndexEntry(scanner.dbItr.Key(), scanner.prefixLen, scanner.numFields)
+		if err != nil {
+			return nil, err
+		}
+		dbVal, err := scanner.vdb.db.Get(encodeDataKey(scanner.namespace, key))
+		if err != nil {
+			return nil, err
+		}
+		if dbVal == nil {
+			logger.Warningf("Channel [%s]: skipping the index entry for the non-existing key [%s] in namespace [%s]",
+				scanner.vdb.dbName, key, scanner.namespace)
+			continue
+		}
+		vv, err := decodeValue(dbVal)
+		if err != nil {
+			return nil, err
+		}
+		scanner.totalRecordsReturned++
+		return &statedb.VersionedKV{
+			CompositeKey:   statedb.CompositeKey{Namespace: scanner.namespace, Key: key},
+			VersionedValue: *vv,
+		}, nil
+	}
+	return nil, errors.Wrap(scanner.dbItr.Error(), "internal leveldb error while retrieving data from the index")
+}
+
+func (scanner *indexScanner) Close() {
+	scanner.dbItr.Release()
+}
+
+// GetBookmarkAndClose returns the hex encoded index entry from which the next page of the query results starts
+func (scanner *indexScanner) GetBookmarkAndClose() string {
+	retval := ""
+	if scanner.dbItr.Next() {
+		retval = hex.EncodeToString(scanner.dbItr.Key())
+	}
+	scanner.Close()
+	return retval
+}
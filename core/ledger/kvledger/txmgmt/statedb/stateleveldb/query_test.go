@@ -0,0 +1,167 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package stateleveldb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexQuery(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testindexquery")
+	require.NoError(t, err)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "marble1", []byte(`{"color":"blue","size":1,"owner":"tom"}`), version.NewHeight(1, 1))
+	batch.Put("ns1", "marble2", []byte(`{"color":"red","size":2,"owner":"jerry"}`), version.NewHeight(1, 2))
+	batch.Put("ns1", "marble3", []byte(`{"color":"blue","size":3,"owner":"tom"}`), version.NewHeight(1, 3))
+	batch.Put("ns1", "marble4", []byte(`{"color":"green","size":4,"owner":"tom"}`), version.NewHeight(1, 4))
+	batch.Put("ns1", "marble5", []byte(`{"color":"blue","size":"large","owner":"tom"}`), version.NewHeight(1, 5))
+	batch.Put("ns1", "marble6", []byte(`{"color":"blue","size":6,"owner":"jerry"}`), version.NewHeight(1, 6))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 6)))
+
+	indexCapable := db.(statedb.IndexCapable)
+	require.NoError(t, indexCapable.ProcessIndexesForChaincodeDeploy("ns1", map[string][]byte{
+		"META-INF/statedb/leveldb/indexes/indexOwnerSize.json": []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwnerSize"}`),
+		"META-INF/statedb/leveldb/indexes/indexColor.json":     []byte(`{"index":{"fields":["color"]},"name":"indexColor"}`),
+	}))
+
+	tests := []struct {
+		query        string
+		expectedKeys []string
+	}{
+		{`{"selector":{"owner":"tom"}}`, []string{"marble1", "marble3", "marble4", "marble5"}},
+		{`{"selector":{"owner":{"$eq":"jerry"}}}`, []string{"marble2", "marble6"}},
+		{`{"selector":{"owner":"tom","size":3}}`, []string{"marble3"}},
+		{`{"selector":{"owner":"tom","size":{"$gt":1}}}`, []string{"marble3", "marble4"}},
+		{`{"selector":{"owner":"tom","size":{"$gte":1,"$lt":4}}}`, []string{"marble1", "marble3"}},
+		{`{"selector":{"owner":"tom","size":{"$gt":1,"$lte":4}}}`, []string{"marble3", "marble4"}},
+		{`{"selector":{"owner":"tom","size":{"$lt":100}}}`, []string{"marble1", "marble3", "marble4"}},
+		{`{"selector":{"owner":"tom","size":{"$gte":"a"}}}`, []string{"marble5"}},
+		{`{"selector":{"owner":{"$gt":"jerry"}}}`, []string{"marble1", "marble3", "marble4", "marble5"}},
+		{`{"selector":{"color":"blue"}}`, []string{"marble1", "marble3", "marble5", "marble6"}},
+		{`{"selector":{"color":"blue"},"use_index":["_design/indexColorDoc","indexColor"]}`, []string{"marble1", "marble3", "marble5", "marble6"}},
+		{`{"selector":{"color":"blue"},"limit":2}`, []string{"marble1", "marble3"}},
+		{`{"selector":{"color":"yellow"}}`, nil},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			itr, err := db.ExecuteQuery("ns1", test.query)
+			require.NoError(t, err)
+			defer itr.Close()
+			require.Equal(t, test.expectedKeys, queryResultKeysForTest(t, itr))
+		})
+	}
+
+	// the values are returned along with the keys
+	itr, err := db.ExecuteQuery("ns1", `{"selector":{"owner":"tom","size":4}}`)
+	require.NoError(t, err)
+	defer itr.Close()
+	result, err := itr.Next()
+	require.NoError(t, err)
+	require.Equal(t,
+		&statedb.VersionedKV{
+			CompositeKey:   statedb.CompositeKey{Namespace: "ns1", Key: "marble4"},
+			VersionedValue: statedb.VersionedValue{Value: []byte(`{"color":"green","size":4,"owner":"tom"}`), Version: version.NewHeight(1, 4)},
+		},
+		result,
+	)
+
+	// the queries on a namespace without indexes are not supported
+	_, err = db.ExecuteQuery("ns2", `{"selector":{"owner":"tom"}}`)
+	require.EqualError(t, err, "ExecuteQuery not supported for leveldb")
+	_, err = db.ExecuteQueryWithPagination("ns2", `{"selector":{"owner":"tom"}}`, "", 10)
+	require.EqualError(t, err, "ExecuteQueryWithMetadata not supported for leveldb")
+}
+
+func TestIndexQueryWithPagination(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testindexquerypagination")
+	require.NoError(t, err)
+
+	batch := statedb.NewUpdateBatch()
+	for i := 1; i <= 5; i++ {
+		batch.Put("ns1", fmt.Sprintf("key%d", i), []byte(fmt.Sprintf(`{"owner":"tom","size":%d}`, i)), version.NewHeight(1, uint64(i)))
+	}
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 5)))
+	require.NoError(t, db.(statedb.IndexCapable).ProcessIndexesForChaincodeDeploy("ns1", map[string][]byte{
+		"indexOwnerSize.json": []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwnerSize"}`),
+	}))
+
+	query := `{"selector":{"owner":"tom","size":{"$gt":1}}}`
+	var pages [][]string
+	bookmark := ""
+	for {
+		itr, err := db.ExecuteQueryWithPagination("ns1", query, bookmark, 2)
+		require.NoError(t, err)
+		pages = append(pages, queryResultKeysForTest(t, itr))
+		bookmark = itr.GetBookmarkAndClose()
+		if bookmark == "" {
+			break
+		}
+	}
+	require.Equal(t, [][]string{{"key2", "key3"}, {"key4", "key5"}}, pages)
+
+	_, err = db.ExecuteQueryWithPagination("ns1", query, "not-hex", 2)
+	require.EqualError(t, err, "invalid bookmark [not-hex] for the query ["+query+"]")
+	_, err = db.ExecuteQueryWithPagination("ns1", `{"selector":{"owner":"jerry"}}`, "69", 2)
+	require.EqualError(t, err, `invalid bookmark [69] for the query [{"selector":{"owner":"jerry"}}]`)
+}
+
+func TestIndexQueryErrors(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testindexqueryerrors")
+	require.NoError(t, err)
+	require.NoError(t, db.(statedb.IndexCapable).ProcessIndexesForChaincodeDeploy("ns1", map[string][]byte{
+		"indexOwnerSize.json": []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwnerSize"}`),
+	}))
+
+	tests := []struct {
+		query       string
+		expectedErr string
+	}{
+		{`{"selector":{"owner":"tom"},"sort":["owner"]}`, `error unmarshalling the query, only the fields [selector, use_index, limit] are supported for leveldb: json: unknown field "sort"`},
+		{`{"selector":{}}`, "the query must include a selector with at least one field"},
+		{`{"selector":{"$or":[{"owner":"tom"}]}}`, "operator [$or] is not supported for leveldb"},
+		{`{"selector":{"owner":{"$regex":"t"}}}`, "invalid condition for the field [owner]: operator [$regex] is not supported for leveldb"},
+		{`{"selector":{"owner":["tom"]}}`, "invalid condition for the field [owner]: only a null, boolean, number, or string value can be matched"},
+		{`{"selector":{"owner":{"$gt":["tom"]}}}`, "invalid condition for the field [owner]: the operand of [$gt] must be a null, boolean, number, or string value"},
+		{`{"selector":{"owner":{}}}`, "invalid condition for the field [owner]: at least one operator must be specified"},
+		{`{"selector":{"owner":{"$eq":"tom","$gt":"a"}}}`, "invalid condition for the field [owner]: [$eq] cannot be combined with a range operator"},
+		{`{"selector":{"owner":{"$gt":"a","$lt":5}}}`, "invalid condition for the field [owner]: the bounds of a range must be of the same type"},
+		{`{"selector":{"size":1}}`, `no index defined on the namespace [ns1] can serve the query [{"selector":{"size":1}}]`},
+		{`{"selector":{"owner":{"$gt":"a"},"size":1}}`, `no index defined on the namespace [ns1] can serve the query [{"selector":{"owner":{"$gt":"a"},"size":1}}]`},
+		{`{"selector":{"owner":"tom"},"use_index":"otherIndex"}`, `no index defined on the namespace [ns1] can serve the query [{"selector":{"owner":"tom"},"use_index":"otherIndex"}]`},
+		{`{"selector":{"owner":"tom"},"use_index":["a","b","c"]}`, `invalid value for use_index [["a","b","c"]]`},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			_, err := db.ExecuteQuery("ns1", test.query)
+			require.EqualError(t, err, test.expectedErr)
+		})
+	}
+}
+
+func queryResultKeysForTest(t *testing.T, itr statedb.ResultsIterator) []string {
+	var keys []string
+	for {
+		result, err := itr.Next()
+		require.NoError(t, err)
+		if result == nil {
+			return keys
+		}
+		keys = append(keys, result.(*statedb.VersionedKV).Key)
+	}
+}
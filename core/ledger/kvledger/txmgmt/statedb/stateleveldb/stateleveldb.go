@@ -8,6 +8,7 @@ package stateleveldb
 
 import (
 	"bytes"
+	"sync"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/dataformat"
@@ -50,7 +51,11 @@ func NewVersionedDBProvider(dbPath string) (*VersionedDBProvider, error) {
 
 // GetDBHandle gets the handle to a named database
 func (provider *VersionedDBProvider) GetDBHandle(dbName string) (statedb.VersionedDB, error) {
-	return newVersionedDB(provider.dbProvider.GetDBHandle(dbName), dbName), nil
+	vdb := newVersionedDB(provider.dbProvider.GetDBHandle(dbName), dbName)
+	if err := vdb.loadIndexDefinitions(); err != nil {
+		return nil, err
+	}
+	return vdb, nil
 }
 
 // Close closes the underlying db
@@ -60,13 +65,19 @@ func (provider *VersionedDBProvider) Close() {
 
 // VersionedDB implements VersionedDB interface
 type versionedDB struct {
-	db     *leveldbhelper.DBHandle
-	dbName string
+	db          *leveldbhelper.DBHandle
+	dbName      string
+	indexesLock sync.RWMutex
+	indexes     map[string][]*indexDefinition
 }
 
 // newVersionedDB constructs an instance of VersionedDB
 func newVersionedDB(db *leveldbhelper.DBHandle, dbName string) *versionedDB {
-	return &versionedDB{db, dbName}
+	return &versionedDB{
+		db:      db,
+		dbName:  dbName,
+		indexes: map[string][]*indexDefinition{},
+	}
 }
 
 // Open implements method in VersionedDB interface
@@ -147,18 +158,48 @@ func (vdb *versionedDB) GetStateRangeScanIteratorWithPagination(namespace string
 	return newKVScanner(namespace, dbItr, pageSize), nil
 }
 
-// ExecuteQuery implements method in VersionedDB interface
+// EstimateStateRangeCount implements method in RangeCountEstimator interface
+func (vdb *versionedDB) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	dataStartKey := encodeDataKey(namespace, startKey)
+	dataEndKey := encodeDataKey(namespace, endKey)
+	if endKey == "" {
+		dataEndKey[len(dataEndKey)-1] = lastKeyIndicator
+	}
+	dbItr := vdb.db.GetIterator(dataStartKey, dataEndKey)
+	defer dbItr.Release()
+	count := uint64(0)
+	for (limit == 0 || count < limit) && dbItr.Next() {
+		count++
+	}
+	return count, errors.Wrap(dbItr.Error(), "error while counting the keys in the range")
+}
+
+// EstimateSize implements method in SizeEstimator interface
+func (vdb *versionedDB) EstimateSize() (int64, error) {
+	return vdb.db.ApproximateSize()
+}
+
+// ExecuteQuery implements method in VersionedDB interface. The queries are supported only on the namespaces
+// for which the chaincode has declared the indexes
 func (vdb *versionedDB) ExecuteQuery(namespace, query string) (statedb.ResultsIterator, error) {
-	return nil, errors.New("ExecuteQuery not supported for leveldb")
+	if !vdb.hasIndexes(namespace) {
+		return nil, errors.New("ExecuteQuery not supported for leveldb")
+	}
+	return vdb.executeIndexQuery(namespace, query, "", 0)
 }
 
 // ExecuteQueryWithPagination implements method in VersionedDB interface
 func (vdb *versionedDB) ExecuteQueryWithPagination(namespace, query, bookmark string, pageSize int32) (statedb.QueryResultsIterator, error) {
-	return nil, errors.New("ExecuteQueryWithMetadata not supported for leveldb")
+	if !vdb.hasIndexes(namespace) {
+		return nil, errors.New("ExecuteQueryWithMetadata not supported for leveldb")
+	}
+	return vdb.executeIndexQuery(namespace, query, bookmark, pageSize)
 }
 
 // ApplyUpdates implements method in VersionedDB interface
 func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version.Height) error {
+	vdb.indexesLock.Lock()
+	defer vdb.indexesLock.Unlock()
 	dbBatch := leveldbhelper.NewUpdateBatch()
 	namespaces := batch.GetUpdatedNamespaces()
 	for _, ns := range namespaces {
@@ -167,6 +208,10 @@ func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version
 			dataKey := encodeDataKey(ns, k)
 			logger.Debugf("Channel [%s]: Applying key(string)=[%s] key(bytes)=[%#v]", vdb.dbName, string(dataKey), dataKey)
 
+			if err := vdb.addIndexUpdates(dbBatch, ns, k, vv.Value); err != nil {
+				return err
+			}
+
 			if vv.Value == nil {
 				dbBatch.Delete(dataKey)
 			} else {
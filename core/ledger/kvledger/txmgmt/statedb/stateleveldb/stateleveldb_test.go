@@ -121,6 +121,37 @@ func TestRangeQuerySpecialCharacters(t *testing.T) {
 	commontests.TestRangeQuerySpecialCharacters(t, env.DBProvider)
 }
 
+func TestEstimateStateRangeCount(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testestimaterangecount")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+		batch.Put("ns1", key, []byte("value"), version.NewHeight(1, 1))
+	}
+	batch.Put("ns2", "key1", []byte("value"), version.NewHeight(1, 1))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	estimator := db.(statedb.RangeCountEstimator)
+	testcases := []struct {
+		startKey, endKey string
+		limit            uint64
+		expectedCount    uint64
+	}{
+		{"", "", 0, 5},
+		{"key2", "key4", 0, 2},
+		{"key2", "", 0, 4},
+		{"", "", 3, 3},
+		{"key6", "", 0, 0},
+	}
+	for _, tc := range testcases {
+		count, err := estimator.EstimateStateRangeCount("ns1", tc.startKey, tc.endKey, tc.limit)
+		require.NoError(t, err)
+		require.Equal(t, tc.expectedCount, count, "range [%s, %s) with limit %d", tc.startKey, tc.endKey, tc.limit)
+	}
+}
+
 func TestApplyUpdatesWithNilHeight(t *testing.T) {
 	env := NewTestVDBEnv(t)
 	defer env.Cleanup()
@@ -213,3 +244,19 @@ func TestFullScanIteratorErrorPropagation(t *testing.T) {
 	compositeKey, _, err = itr.Next()
 	require.Contains(t, err.Error(), "internal leveldb error while obtaining db iterator for skipping a namespace [ns2]:")
 }
+
+func TestEstimateSize(t *testing.T) {
+	env := NewTestVDBEnv(t)
+	defer env.Cleanup()
+	db, err := env.DBProvider.GetDBHandle("testestimatesize")
+	require.NoError(t, err)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte("value"), version.NewHeight(1, 1))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	estimator, ok := db.(statedb.SizeEstimator)
+	require.True(t, ok)
+	size, err := estimator.EstimateSize()
+	require.NoError(t, err)
+	require.True(t, size >= 0)
+}
@@ -10,6 +10,7 @@ import (
 	proto "github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/ledger/internal/version"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
 )
 
 // encodeValue encodes the value, version, and metadata
@@ -42,3 +43,20 @@ func decodeValue(encodedValue []byte) (*statedb.VersionedValue, error) {
 	}
 	return &statedb.VersionedValue{Version: ver, Value: val, Metadata: metadata}, nil
 }
+
+// DecodeFullScanValue decodes the value bytes returned by the FullScanIterator, and hence
+// present in the snapshot files of the statedb, in the given format
+func DecodeFullScanValue(valueFormat byte, encodedValue []byte) (*statedb.VersionedValue, error) {
+	if valueFormat != fullScanIteratorValueFormat {
+		return nil, errors.Errorf("unsupported value format [%d]", valueFormat)
+	}
+	return decodeValue(encodedValue)
+}
+
+// EncodeFullScanValue encodes the value in the given format, as returned by the FullScanIterator
+func EncodeFullScanValue(valueFormat byte, v *statedb.VersionedValue) ([]byte, error) {
+	if valueFormat != fullScanIteratorValueFormat {
+		return nil, errors.Errorf("unsupported value format [%d]", valueFormat)
+	}
+	return encodeValue(v)
+}
@@ -51,3 +51,37 @@ func testEncodeDecodeVersionedValues(t *testing.T, v *statedb.VersionedValue) {
 	assert.NoError(t, err)
 	assert.Equal(t, v, decodedVal)
 }
+
+func TestDecodeFullScanValue(t *testing.T) {
+	v := &statedb.VersionedValue{
+		Value:    []byte("value1"),
+		Metadata: []byte("metadata1"),
+		Version:  version.NewHeight(1, 2),
+	}
+	encodedVal, err := encodeValue(v)
+	assert.NoError(t, err)
+
+	decodedVal, err := DecodeFullScanValue(fullScanIteratorValueFormat, encodedVal)
+	assert.NoError(t, err)
+	assert.Equal(t, v, decodedVal)
+
+	_, err = DecodeFullScanValue(byte(2), encodedVal)
+	assert.EqualError(t, err, "unsupported value format [2]")
+}
+
+func TestEncodeFullScanValue(t *testing.T) {
+	v := &statedb.VersionedValue{
+		Value:    []byte("value1"),
+		Metadata: []byte("metadata1"),
+		Version:  version.NewHeight(1, 2),
+	}
+	encodedVal, err := EncodeFullScanValue(fullScanIteratorValueFormat, v)
+	assert.NoError(t, err)
+
+	decodedVal, err := DecodeFullScanValue(fullScanIteratorValueFormat, encodedVal)
+	assert.NoError(t, err)
+	assert.Equal(t, v, decodedVal)
+
+	_, err = EncodeFullScanValue(byte(2), v)
+	assert.EqualError(t, err, "unsupported value format [2]")
+}
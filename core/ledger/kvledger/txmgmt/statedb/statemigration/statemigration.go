@@ -0,0 +1,477 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statemigration
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("statemigration")
+
+const (
+	// hashedDataNsMarker identifies the namespaces that the privacyenabledstate package
+	// derives for the hashes of the private data (i.e., "<ns>$$h<coll>"), the keys of which
+	// are base64 encoded key hashes for the dbs that do not support bytes keys
+	hashedDataNsMarker = "$$h"
+	// migrationBatchSize is the number of keys that are copied, or compared, at a time
+	migrationBatchSize = 1000
+	// maxReportedMismatches is the number of mismatched keys that are logged
+	maxReportedMismatches = 10
+)
+
+var errMigrationStopped = errors.New("the state migration is stopped")
+
+// VersionedDBProvider returns handles that write the state of a channel to both a source and a target db while
+// serving the reads from the source db. When a handle is created, the state already committed is copied from the
+// source db to the target db in the background and then compared between the two dbs. Once the two dbs are found
+// to match, the reads are served from the target db and the writes continue to be applied to both the dbs
+type VersionedDBProvider struct {
+	source statedb.VersionedDBProvider
+	target statedb.VersionedDBProvider
+
+	lock sync.Mutex
+	dbs  map[string]*versionedDB
+}
+
+// NewVersionedDBProvider constructs a VersionedDBProvider that migrates the state from the source to the target
+func NewVersionedDBProvider(source, target statedb.VersionedDBProvider) *VersionedDBProvider {
+	return &VersionedDBProvider{
+		source: source,
+		target: target,
+		dbs:    map[string]*versionedDB{},
+	}
+}
+
+// GetDBHandle implements the method in interface statedb.VersionedDBProvider. The migration of the
+// state of the channel starts with the first call for the channel
+func (p *VersionedDBProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	vdb, ok := p.dbs[id]
+	if !ok {
+		sourceDB, err := p.source.GetDBHandle(id)
+		if err != nil {
+			return nil, err
+		}
+		targetDB, err := p.target.GetDBHandle(id)
+		if err != nil {
+			return nil, err
+		}
+		vdb = newVersionedDB(id, sourceDB, targetDB)
+		p.dbs[id] = vdb
+		go vdb.migrate()
+	}
+	if indexCapable, ok := vdb.target.(statedb.IndexCapable); ok {
+		return &indexCapableDB{vdb, indexCapable}, nil
+	}
+	return vdb, nil
+}
+
+// HealthCheck checks the health of the target db, if it supports the health checks
+func (p *VersionedDBProvider) HealthCheck(ctx context.Context) error {
+	if healthChecker, ok := p.target.(healthz.HealthChecker); ok {
+		return healthChecker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// Close stops the migrations in progress and closes both the providers
+func (p *VersionedDBProvider) Close() {
+	p.lock.Lock()
+	for _, vdb := range p.dbs {
+		vdb.stopMigration()
+	}
+	p.lock.Unlock()
+	p.target.Close()
+	p.source.Close()
+}
+
+// versionedDB implements the interface statedb.VersionedDB on top of a source and a target VersionedDB.
+// When the source db supports bytes keys and the target db does not, the handle presents itself as not
+// supporting bytes keys, so that the key hashes of the private data are base64 encoded by the callers as
+// required by the target db, and the key hashes are decoded for the source db
+type versionedDB struct {
+	ledgerID            string
+	source              statedb.VersionedDB
+	target              statedb.VersionedDB
+	translateHashedKeys bool
+
+	// writeLock serializes the writes to both the dbs with the copying and the comparing of the state
+	writeLock sync.Mutex
+	migrated  int32
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+type indexCapableDB struct {
+	*versionedDB
+	statedb.IndexCapable
+}
+
+func newVersionedDB(ledgerID string, source, target statedb.VersionedDB) *versionedDB {
+	return &versionedDB{
+		ledgerID:            ledgerID,
+		source:              source,
+		target:              target,
+		translateHashedKeys: source.BytesKeySupported() && !target.BytesKeySupported(),
+		stop:                make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+}
+
+// isMigrated returns true once the state has been copied to the target db and found to match
+func (vdb *versionedDB) isMigrated() bool {
+	return atomic.LoadInt32(&vdb.migrated) == 1
+}
+
+// sourceKey converts a key from the encoding used by the callers into that of the source db
+func (vdb *versionedDB) sourceKey(ns, key string) (string, error) {
+	if !vdb.translateHashedKeys || !strings.Contains(ns, hashedDataNsMarker) {
+		return key, nil
+	}
+	keyHash, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid key hash [%s] in namespace [%s]", key, ns)
+	}
+	return string(keyHash), nil
+}
+
+// targetKey converts a key from the encoding of the source db into that of the target db
+func (vdb *versionedDB) targetKey(ns, key string) string {
+	if !vdb.translateHashedKeys || !strings.Contains(ns, hashedDataNsMarker) {
+		return key
+	}
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+// GetState implements method in VersionedDB interface
+func (vdb *versionedDB) GetState(namespace string, key string) (*statedb.VersionedValue, error) {
+	if vdb.isMigrated() {
+		return vdb.target.GetState(namespace, key)
+	}
+	sourceKey, err := vdb.sourceKey(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return vdb.source.GetState(namespace, sourceKey)
+}
+
+// GetVersion implements method in VersionedDB interface
+func (vdb *versionedDB) GetVersion(namespace string, key string) (*version.Height, error) {
+	if vdb.isMigrated() {
+		return vdb.target.GetVersion(namespace, key)
+	}
+	sourceKey, err := vdb.sourceKey(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return vdb.source.GetVersion(namespace, sourceKey)
+}
+
+// GetStateMultipleKeys implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateMultipleKeys(namespace string, keys []string) ([]*statedb.VersionedValue, error) {
+	if vdb.isMigrated() {
+		return vdb.target.GetStateMultipleKeys(namespace, keys)
+	}
+	sourceKeys := make([]string, len(keys))
+	for i, key := range keys {
+		sourceKey, err := vdb.sourceKey(namespace, key)
+		if err != nil {
+			return nil, err
+		}
+		sourceKeys[i] = sourceKey
+	}
+	return vdb.source.GetStateMultipleKeys(namespace, sourceKeys)
+}
+
+// GetStateRangeScanIterator implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (statedb.ResultsIterator, error) {
+	return vdb.reader().GetStateRangeScanIterator(namespace, startKey, endKey)
+}
+
+// GetStateRangeScanIteratorWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) GetStateRangeScanIteratorWithPagination(namespace string, startKey string, endKey string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	return vdb.reader().GetStateRangeScanIteratorWithPagination(namespace, startKey, endKey, pageSize)
+}
+
+// ExecuteQuery implements method in VersionedDB interface
+func (vdb *versionedDB) ExecuteQuery(namespace, query string) (statedb.ResultsIterator, error) {
+	return vdb.reader().ExecuteQuery(namespace, query)
+}
+
+// ExecuteQueryWithPagination implements method in VersionedDB interface
+func (vdb *versionedDB) ExecuteQueryWithPagination(namespace, query, bookmark string, pageSize int32) (statedb.QueryResultsIterator, error) {
+	return vdb.reader().ExecuteQueryWithPagination(namespace, query, bookmark, pageSize)
+}
+
+// EstimateStateRangeCount implements method in RangeCountEstimator interface
+func (vdb *versionedDB) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	estimator, ok := vdb.reader().(statedb.RangeCountEstimator)
+	if !ok {
+		return 0, errors.New("the state database does not support estimating the number of keys in a range")
+	}
+	return estimator.EstimateStateRangeCount(namespace, startKey, endKey, limit)
+}
+
+// EstimateSize implements method in SizeEstimator interface. Both the dbs occupy disk space
+// until the migration completes
+func (vdb *versionedDB) EstimateSize() (int64, error) {
+	return statedb.EstimateTotalSize(vdb.source, vdb.target)
+}
+
+func (vdb *versionedDB) reader() statedb.VersionedDB {
+	if vdb.isMigrated() {
+		return vdb.target
+	}
+	return vdb.source
+}
+
+// ApplyUpdates implements method in VersionedDB interface. The updates are applied to the source db
+// first and then to the target db
+func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version.Height) error {
+	vdb.writeLock.Lock()
+	defer vdb.writeLock.Unlock()
+
+	sourceBatch := batch
+	if vdb.translateHashedKeys {
+		sourceBatch = statedb.NewUpdateBatch()
+		sourceBatch.ContainsPostOrderWrites = batch.ContainsPostOrderWrites
+		for _, ns := range batch.GetUpdatedNamespaces() {
+			for key, vv := range batch.GetUpdates(ns) {
+				sourceKey, err := vdb.sourceKey(ns, key)
+				if err != nil {
+					return err
+				}
+				sourceBatch.Update(ns, sourceKey, vv)
+			}
+		}
+	}
+	if err := vdb.source.ApplyUpdates(sourceBatch, height); err != nil {
+		return err
+	}
+	return vdb.target.ApplyUpdates(batch, height)
+}
+
+// GetLatestSavePoint implements method in VersionedDB interface. It returns the lower of the save points
+// of the two dbs, so that the blocks whose updates were applied to only the source db are applied again to
+// both the dbs during the recovery. A target db that has no save point yet is a new db, the state of which
+// is copied from the source db
+func (vdb *versionedDB) GetLatestSavePoint() (*version.Height, error) {
+	sourceSavepoint, err := vdb.source.GetLatestSavePoint()
+	if err != nil {
+		return nil, err
+	}
+	targetSavepoint, err := vdb.target.GetLatestSavePoint()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSavepoint == nil || targetSavepoint == nil || sourceSavepoint.Compare(targetSavepoint) < 0 {
+		return sourceSavepoint, nil
+	}
+	return targetSavepoint, nil
+}
+
+// ValidateKeyValue implements method in VersionedDB interface. A key-value is required to be
+// valid for both the dbs
+func (vdb *versionedDB) ValidateKeyValue(key string, value []byte) error {
+	if err := vdb.source.ValidateKeyValue(key, value); err != nil {
+		return err
+	}
+	return vdb.target.ValidateKeyValue(key, value)
+}
+
+// BytesKeySupported implements method in VersionedDB interface
+func (vdb *versionedDB) BytesKeySupported() bool {
+	return vdb.source.BytesKeySupported() && vdb.target.BytesKeySupported()
+}
+
+// GetFullScanIterator implements method in VersionedDB interface
+func (vdb *versionedDB) GetFullScanIterator(skipNamespace func(string) bool) (statedb.FullScanIterator, byte, error) {
+	return vdb.source.GetFullScanIterator(skipNamespace)
+}
+
+// Open implements method in VersionedDB interface
+func (vdb *versionedDB) Open() error {
+	if err := vdb.source.Open(); err != nil {
+		return err
+	}
+	return vdb.target.Open()
+}
+
+// Close implements method in VersionedDB interface
+func (vdb *versionedDB) Close() {
+	vdb.stopMigration()
+	vdb.target.Close()
+	vdb.source.Close()
+}
+
+func (vdb *versionedDB) stopMigration() {
+	vdb.stopOnce.Do(func() {
+		close(vdb.stop)
+	})
+	<-vdb.done
+}
+
+// migrate copies the state of the channel from the source db to the target db, compares the state
+// in the two dbs and, if the state matches, switches the reads to the target db
+func (vdb *versionedDB) migrate() {
+	defer close(vdb.done)
+
+	logger.Infof("Copying the state of channel [%s] to the target state database", vdb.ledgerID)
+	numKeys, err := vdb.scanSourceKeys(vdb.copyKeys)
+	if err == errMigrationStopped {
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to copy the state of channel [%s] to the target state database, the reads remain on the source state database: %s", vdb.ledgerID, err)
+		return
+	}
+
+	logger.Infof("Copied [%d] keys of channel [%s], comparing the state in the source and the target state databases", numKeys, vdb.ledgerID)
+	var mismatches []string
+	_, err = vdb.scanSourceKeys(func(keys []*statedb.CompositeKey) error {
+		batchMismatches, err := vdb.compareKeys(keys)
+		mismatches = append(mismatches, batchMismatches...)
+		return err
+	})
+	if err == errMigrationStopped {
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to compare the state of channel [%s] in the source and the target state databases, the reads remain on the source state database: %s", vdb.ledgerID, err)
+		return
+	}
+	if len(mismatches) > 0 {
+		reported := mismatches
+		if len(reported) > maxReportedMismatches {
+			reported = reported[:maxReportedMismatches]
+		}
+		logger.Errorf("The state of [%d] keys of channel [%s] differs between the source and the target state databases, the reads remain on the source state database. Differing keys include %v",
+			len(mismatches), vdb.ledgerID, reported)
+		return
+	}
+
+	atomic.StoreInt32(&vdb.migrated, 1)
+	logger.Infof("The state of channel [%s] has been migrated and verified, the reads are now served by the target state database", vdb.ledgerID)
+}
+
+// scanSourceKeys passes all the keys in the source db, in batches of migrationBatchSize keys, to the given
+// function and returns the number of keys passed
+func (vdb *versionedDB) scanSourceKeys(process func(keys []*statedb.CompositeKey) error) (int, error) {
+	itr, _, err := vdb.source.GetFullScanIterator(func(string) bool { return false })
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to scan the source state database")
+	}
+	defer itr.Close()
+
+	numKeys := 0
+	var keys []*statedb.CompositeKey
+	for {
+		select {
+		case <-vdb.stop:
+			return numKeys, errMigrationStopped
+		default:
+		}
+		compositeKey, _, err := itr.Next()
+		if err != nil {
+			return numKeys, err
+		}
+		if compositeKey != nil {
+			keys = append(keys, compositeKey)
+		}
+		if len(keys) == migrationBatchSize || (compositeKey == nil && len(keys) > 0) {
+			if err := process(keys); err != nil {
+				return numKeys, err
+			}
+			numKeys += len(keys)
+			keys = nil
+		}
+		if compositeKey == nil {
+			return numKeys, nil
+		}
+	}
+}
+
+// copyKeys copies the current values of the given keys from the source db to the target db. The values
+// are read while holding the write lock, so that a concurrent commit does not overwrite them with stale
+// values. The keys deleted since the scan started have been deleted from the target db by the commit.
+// The save point of the target db is not changed, as it is recorded only by the commits
+func (vdb *versionedDB) copyKeys(keys []*statedb.CompositeKey) error {
+	vdb.writeLock.Lock()
+	defer vdb.writeLock.Unlock()
+
+	batch := statedb.NewUpdateBatch()
+	for _, key := range keys {
+		vv, err := vdb.source.GetState(key.Namespace, key.Key)
+		if err != nil {
+			return err
+		}
+		if vv == nil {
+			continue
+		}
+		batch.Update(key.Namespace, vdb.targetKey(key.Namespace, key.Key), vv)
+	}
+	return vdb.target.ApplyUpdates(batch, nil)
+}
+
+// compareKeys returns the keys whose values, metadata or versions differ between the two dbs
+func (vdb *versionedDB) compareKeys(keys []*statedb.CompositeKey) ([]string, error) {
+	vdb.writeLock.Lock()
+	defer vdb.writeLock.Unlock()
+
+	var mismatches []string
+	for _, key := range keys {
+		sourceVV, err := vdb.source.GetState(key.Namespace, key.Key)
+		if err != nil {
+			return nil, err
+		}
+		targetVV, err := vdb.target.GetState(key.Namespace, vdb.targetKey(key.Namespace, key.Key))
+		if err != nil {
+			return nil, err
+		}
+		if !versionedValuesMatch(sourceVV, targetVV) {
+			mismatches = append(mismatches, key.Namespace+":"+vdb.targetKey(key.Namespace, key.Key))
+		}
+	}
+	return mismatches, nil
+}
+
+// versionedValuesMatch compares two versioned values. The values are compared as JSON, if not
+// byte for byte equal, as a db may store a JSON value in a different but equivalent form
+func versionedValuesMatch(vv1, vv2 *statedb.VersionedValue) bool {
+	if vv1 == nil || vv2 == nil {
+		return vv1 == vv2
+	}
+	if !bytes.Equal(vv1.Metadata, vv2.Metadata) {
+		return false
+	}
+	if vv1.Version == nil || vv2.Version == nil || vv1.Version.Compare(vv2.Version) != 0 {
+		return vv1.Version == nil && vv2.Version == nil
+	}
+	if bytes.Equal(vv1.Value, vv2.Value) {
+		return true
+	}
+	var json1, json2 interface{}
+	if json.Unmarshal(vv1.Value, &json1) != nil || json.Unmarshal(vv2.Value, &json2) != nil {
+		return false
+	}
+	return reflect.DeepEqual(json1, json2)
+}
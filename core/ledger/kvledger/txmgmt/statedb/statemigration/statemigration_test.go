@@ -0,0 +1,229 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statemigration
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTargetProvider stands in for the CouchDB provider with a goleveldb provider whose
+// handles, like those of CouchDB, do not support bytes keys
+type fakeTargetProvider struct {
+	statedb.VersionedDBProvider
+}
+
+func (p *fakeTargetProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	vdb, err := p.VersionedDBProvider.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeTargetDB{VersionedDB: vdb}, nil
+}
+
+type fakeTargetDB struct {
+	statedb.VersionedDB
+}
+
+func (db *fakeTargetDB) BytesKeySupported() bool {
+	return false
+}
+
+type testEnv struct {
+	sourceEnv *stateleveldb.TestVDBEnv
+	targetEnv *stateleveldb.TestVDBEnv
+	source    statedb.VersionedDB
+	target    statedb.VersionedDB
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	sourceEnv := stateleveldb.NewTestVDBEnv(t)
+	targetEnv := stateleveldb.NewTestVDBEnv(t)
+	source, err := sourceEnv.DBProvider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	target, err := (&fakeTargetProvider{targetEnv.DBProvider}).GetDBHandle("ch1")
+	require.NoError(t, err)
+	return &testEnv{
+		sourceEnv: sourceEnv,
+		targetEnv: targetEnv,
+		source:    source,
+		target:    target,
+	}
+}
+
+func (env *testEnv) cleanup() {
+	env.sourceEnv.Cleanup()
+	env.targetEnv.Cleanup()
+}
+
+const keyHash = "\x00\x01\xfe\xff"
+
+var encodedKeyHash = base64.StdEncoding.EncodeToString([]byte(keyHash))
+
+func TestMigration(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte(`{"a": 1, "b": "x"}`), version.NewHeight(1, 1))
+	batch.Put("ns1$$pcoll1", "key1", []byte("pvt-value1"), version.NewHeight(1, 1))
+	batch.Put("ns1$$hcoll1", keyHash, []byte("hashed-value1"), version.NewHeight(1, 1))
+	for i := 0; i < migrationBatchSize+10; i++ {
+		batch.Put("ns2", string(rune('a'+i%26))+string(rune(i)), []byte("value"), version.NewHeight(1, 2))
+	}
+	require.NoError(t, env.source.ApplyUpdates(batch, version.NewHeight(1, 2)))
+
+	provider := NewVersionedDBProvider(env.sourceEnv.DBProvider, &fakeTargetProvider{env.targetEnv.DBProvider})
+	db, err := provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	vdb := db.(*versionedDB)
+	require.False(t, db.BytesKeySupported())
+	require.Eventually(t, vdb.isMigrated, 10*time.Second, 10*time.Millisecond)
+
+	vv, err := env.target.GetState("ns1$$hcoll1", encodedKeyHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed-value1"), vv.Value)
+	vv, err = db.GetState("ns1$$hcoll1", encodedKeyHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed-value1"), vv.Value)
+
+	// the copy does not move the save point of the target db
+	targetSavepoint, err := env.target.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Nil(t, targetSavepoint)
+	savepoint, err := db.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 2), savepoint)
+
+	// after the switch, the reads are served from the target db
+	targetBatch := statedb.NewUpdateBatch()
+	targetBatch.Put("ns1", "key2", []byte("only-in-target"), version.NewHeight(2, 1))
+	require.NoError(t, env.target.ApplyUpdates(targetBatch, nil))
+	vv, err = db.GetState("ns1", "key2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("only-in-target"), vv.Value)
+
+	vdb.Close()
+	require.Equal(t, provider.dbs["ch1"], vdb)
+}
+
+func TestApplyUpdates(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	vdb := newVersionedDB("ch1", env.source, env.target)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns1$$hcoll1", encodedKeyHash, []byte("hashed-value1"), version.NewHeight(1, 1))
+	require.NoError(t, vdb.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	vv, err := env.source.GetState("ns1$$hcoll1", keyHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed-value1"), vv.Value)
+	vv, err = env.target.GetState("ns1$$hcoll1", encodedKeyHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed-value1"), vv.Value)
+
+	// before the switch, the reads are served from the source db
+	sourceBatch := statedb.NewUpdateBatch()
+	sourceBatch.Put("ns1", "key2", []byte("only-in-source"), version.NewHeight(1, 2))
+	require.NoError(t, env.source.ApplyUpdates(sourceBatch, version.NewHeight(2, 1)))
+	vv, err = vdb.GetState("ns1", "key2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("only-in-source"), vv.Value)
+	vvs, err := vdb.GetStateMultipleKeys("ns1$$hcoll1", []string{encodedKeyHash})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed-value1"), vvs[0].Value)
+	ver, err := vdb.GetVersion("ns1$$hcoll1", encodedKeyHash)
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 1), ver)
+	_, err = vdb.GetState("ns1$$hcoll1", "not-base64!")
+	require.EqualError(t, err, "invalid key hash [not-base64!] in namespace [ns1$$hcoll1]: illegal base64 data at input byte 3")
+
+	// the lower of the save points is returned
+	savepoint, err := vdb.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 1), savepoint)
+
+	// a batch without a height does not move the save points
+	require.NoError(t, vdb.ApplyUpdates(statedb.NewUpdateBatch(), nil))
+	savepoint, err = vdb.GetLatestSavePoint()
+	require.NoError(t, err)
+	require.Equal(t, version.NewHeight(1, 1), savepoint)
+}
+
+func TestMigrationMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	vdb := newVersionedDB("ch1", env.source, env.target)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	batch.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 2))
+	require.NoError(t, env.source.ApplyUpdates(batch, version.NewHeight(1, 2)))
+
+	_, err := vdb.scanSourceKeys(vdb.copyKeys)
+	require.NoError(t, err)
+	targetBatch := statedb.NewUpdateBatch()
+	targetBatch.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 3))
+	require.NoError(t, env.target.ApplyUpdates(targetBatch, nil))
+
+	var mismatches []string
+	_, err = vdb.scanSourceKeys(func(keys []*statedb.CompositeKey) error {
+		batchMismatches, err := vdb.compareKeys(keys)
+		mismatches = append(mismatches, batchMismatches...)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ns1:key2"}, mismatches)
+}
+
+func TestMigrationStop(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	vdb := newVersionedDB("ch1", env.source, env.target)
+	batch := statedb.NewUpdateBatch()
+	batch.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	require.NoError(t, env.source.ApplyUpdates(batch, version.NewHeight(1, 1)))
+
+	close(vdb.stop)
+	vdb.migrate()
+	require.False(t, vdb.isMigrated())
+	vv, err := env.target.GetState("ns1", "key1")
+	require.NoError(t, err)
+	require.Nil(t, vv)
+}
+
+func TestVersionedValuesMatch(t *testing.T) {
+	height := version.NewHeight(1, 1)
+	tests := []struct {
+		name     string
+		vv1, vv2 *statedb.VersionedValue
+		match    bool
+	}{
+		{"both nil", nil, nil, true},
+		{"one nil", &statedb.VersionedValue{Version: height}, nil, false},
+		{"equal", &statedb.VersionedValue{Value: []byte("v"), Metadata: []byte("m"), Version: height}, &statedb.VersionedValue{Value: []byte("v"), Metadata: []byte("m"), Version: height}, true},
+		{"equivalent JSON", &statedb.VersionedValue{Value: []byte(`{"a":1,"b":"x"}`), Version: height}, &statedb.VersionedValue{Value: []byte(`{"b": "x", "a": 1}`), Version: height}, true},
+		{"different JSON", &statedb.VersionedValue{Value: []byte(`{"a":1}`), Version: height}, &statedb.VersionedValue{Value: []byte(`{"a":2}`), Version: height}, false},
+		{"different value", &statedb.VersionedValue{Value: []byte("v1"), Version: height}, &statedb.VersionedValue{Value: []byte("v2"), Version: height}, false},
+		{"different metadata", &statedb.VersionedValue{Value: []byte("v"), Metadata: []byte("m1"), Version: height}, &statedb.VersionedValue{Value: []byte("v"), Metadata: []byte("m2"), Version: height}, false},
+		{"different version", &statedb.VersionedValue{Value: []byte("v"), Version: height}, &statedb.VersionedValue{Value: []byte("v"), Version: version.NewHeight(1, 2)}, false},
+		{"one version nil", &statedb.VersionedValue{Value: []byte("v"), Version: height}, &statedb.VersionedValue{Value: []byte("v")}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.match, versionedValuesMatch(test.vv1, test.vv2))
+		})
+	}
+}
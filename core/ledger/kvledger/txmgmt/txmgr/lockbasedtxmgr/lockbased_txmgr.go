@@ -255,6 +255,32 @@ func (txmgr *LockBasedTxMgr) RemoveStaleAndCommitPvtDataOfOldBlocks(reconciledPv
 	return nil
 }
 
+// PurgePrivateKey implements method in interface `txmgmt.TxMgr`. It deletes the private value of the key
+// from each of the given collections, if the value was committed in a block not higher than `purgedTillBlk`.
+// The hashed key and value are retained in the state database
+func (txmgr *LockBasedTxMgr) PurgePrivateKey(ns string, colls []string, key string, purgedTillBlk uint64) error {
+	txmgr.pvtdataPurgeMgr.WaitForPrepareToFinish()
+	txmgr.oldBlockCommit.Lock()
+	defer txmgr.oldBlockCommit.Unlock()
+	logger.Debug("lock acquired on oldBlockCommit for purging a private key from the state database")
+
+	batch := privacyenabledstate.NewUpdateBatch()
+	for _, coll := range colls {
+		vv, err := txmgr.db.GetPrivateData(ns, coll, key)
+		if err != nil {
+			return err
+		}
+		if vv == nil || vv.Version.BlockNum > purgedTillBlk {
+			continue
+		}
+		batch.PvtUpdates.Delete(ns, coll, key, vv.Version)
+	}
+	if batch.PvtUpdates.IsEmpty() {
+		return nil
+	}
+	return txmgr.db.ApplyPrivacyAwareUpdates(batch, nil)
+}
+
 type uniquePvtDataMap map[privacyenabledstate.HashedCompositeKey]*privacyenabledstate.PvtKVWrite
 
 func constructUniquePvtData(reconciledPvtdata map[uint64][]*ledger.TxPvtData) (uniquePvtDataMap, error) {
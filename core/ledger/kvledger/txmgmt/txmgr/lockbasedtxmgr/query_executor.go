@@ -167,6 +167,18 @@ func (q *queryExecutor) GetStateRangeScanIteratorWithPagination(namespace string
 	return itr, nil
 }
 
+// EstimateStateRangeCount implements method in interface `ledger.StateRangeCountEstimator`
+func (q *queryExecutor) EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error) {
+	if err := q.checkDone(); err != nil {
+		return 0, err
+	}
+	estimator, ok := q.txmgr.db.VersionedDB.(statedb.RangeCountEstimator)
+	if !ok {
+		return 0, errors.New("the state database does not support estimating the number of keys in a range")
+	}
+	return estimator.EstimateStateRangeCount(namespace, startKey, endKey, limit)
+}
+
 // ExecuteQuery implements method in interface `ledger.QueryExecutor`
 func (q *queryExecutor) ExecuteQuery(namespace, query string) (commonledger.ResultsIterator, error) {
 	if err := q.checkDone(); err != nil {
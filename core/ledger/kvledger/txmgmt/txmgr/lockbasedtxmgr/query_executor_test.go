@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 	commonledger "github.com/hyperledger/fabric/common/ledger"
 	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/internal/version"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
@@ -205,3 +206,38 @@ func putPvtUpdates(t *testing.T, updates *privacyenabledstate.UpdateBatch, ns, c
 	updates.PvtUpdates.Put(ns, coll, key, value, ver)
 	updates.HashUpdates.Put(ns, coll, util.ComputeStringHash(key), util.ComputeHash(value), ver)
 }
+
+func TestEstimateStateRangeCount(t *testing.T) {
+	testEnv := testEnvsMap[levelDBtestEnvName]
+	testEnv.init(t, "test-estimate-range-count", nil)
+	defer testEnv.cleanup()
+	txMgr := testEnv.getTxMgr().(*LockBasedTxMgr)
+
+	updates := privacyenabledstate.NewUpdateBatch()
+	updates.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 1))
+	updates.PubUpdates.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 2))
+	updates.PubUpdates.Put("ns1", "key3", []byte("value3"), version.NewHeight(1, 3))
+	assert.NoError(t, txMgr.db.ApplyPrivacyAwareUpdates(updates, version.NewHeight(1, 3)))
+
+	s, err := txMgr.NewTxSimulator("test_tx1")
+	assert.NoError(t, err)
+	estimator, ok := s.(ledger.StateRangeCountEstimator)
+	assert.True(t, ok)
+	count, err := estimator.EstimateStateRangeCount("ns1", "key2", "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+	count, err = estimator.EstimateStateRangeCount("ns1", "", "", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	// the estimate is not recorded in the read-set
+	s.Done()
+	simRes, err := s.GetTxSimulationResults()
+	assert.NoError(t, err)
+	txrwset, err := rwsetutil.TxRwSetFromProtoMsg(simRes.PubSimulationResults)
+	assert.NoError(t, err)
+	assert.Empty(t, txrwset.NsRwSets)
+
+	_, err = estimator.EstimateStateRangeCount("ns1", "", "", 0)
+	assert.Error(t, err)
+}
@@ -79,6 +79,20 @@ type TxMgr struct {
 		result1 ledger.TxSimulator
 		result2 error
 	}
+	PurgePrivateKeyStub        func(string, []string, string, uint64) error
+	purgePrivateKeyMutex       sync.RWMutex
+	purgePrivateKeyArgsForCall []struct {
+		arg1 string
+		arg2 []string
+		arg3 string
+		arg4 uint64
+	}
+	purgePrivateKeyReturns struct {
+		result1 error
+	}
+	purgePrivateKeyReturnsOnCall map[int]struct {
+		result1 error
+	}
 	RemoveStaleAndCommitPvtDataOfOldBlocksStub        func(map[uint64][]*ledger.TxPvtData) error
 	removeStaleAndCommitPvtDataOfOldBlocksMutex       sync.RWMutex
 	removeStaleAndCommitPvtDataOfOldBlocksArgsForCall []struct {
@@ -478,6 +492,74 @@ func (fake *TxMgr) NewTxSimulatorReturnsOnCall(i int, result1 ledger.TxSimulator
 	}{result1, result2}
 }
 
+func (fake *TxMgr) PurgePrivateKey(arg1 string, arg2 []string, arg3 string, arg4 uint64) error {
+	var arg2Copy []string
+	if arg2 != nil {
+		arg2Copy = make([]string, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.purgePrivateKeyMutex.Lock()
+	ret, specificReturn := fake.purgePrivateKeyReturnsOnCall[len(fake.purgePrivateKeyArgsForCall)]
+	fake.purgePrivateKeyArgsForCall = append(fake.purgePrivateKeyArgsForCall, struct {
+		arg1 string
+		arg2 []string
+		arg3 string
+		arg4 uint64
+	}{arg1, arg2Copy, arg3, arg4})
+	fake.recordInvocation("PurgePrivateKey", []interface{}{arg1, arg2Copy, arg3, arg4})
+	fake.purgePrivateKeyMutex.Unlock()
+	if fake.PurgePrivateKeyStub != nil {
+		return fake.PurgePrivateKeyStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.purgePrivateKeyReturns
+	return fakeReturns.result1
+}
+
+func (fake *TxMgr) PurgePrivateKeyCallCount() int {
+	fake.purgePrivateKeyMutex.RLock()
+	defer fake.purgePrivateKeyMutex.RUnlock()
+	return len(fake.purgePrivateKeyArgsForCall)
+}
+
+func (fake *TxMgr) PurgePrivateKeyCalls(stub func(string, []string, string, uint64) error) {
+	fake.purgePrivateKeyMutex.Lock()
+	defer fake.purgePrivateKeyMutex.Unlock()
+	fake.PurgePrivateKeyStub = stub
+}
+
+func (fake *TxMgr) PurgePrivateKeyArgsForCall(i int) (string, []string, string, uint64) {
+	fake.purgePrivateKeyMutex.RLock()
+	defer fake.purgePrivateKeyMutex.RUnlock()
+	argsForCall := fake.purgePrivateKeyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *TxMgr) PurgePrivateKeyReturns(result1 error) {
+	fake.purgePrivateKeyMutex.Lock()
+	defer fake.purgePrivateKeyMutex.Unlock()
+	fake.PurgePrivateKeyStub = nil
+	fake.purgePrivateKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *TxMgr) PurgePrivateKeyReturnsOnCall(i int, result1 error) {
+	fake.purgePrivateKeyMutex.Lock()
+	defer fake.purgePrivateKeyMutex.Unlock()
+	fake.PurgePrivateKeyStub = nil
+	if fake.purgePrivateKeyReturnsOnCall == nil {
+		fake.purgePrivateKeyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.purgePrivateKeyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *TxMgr) RemoveStaleAndCommitPvtDataOfOldBlocks(arg1 map[uint64][]*ledger.TxPvtData) error {
 	fake.removeStaleAndCommitPvtDataOfOldBlocksMutex.Lock()
 	ret, specificReturn := fake.removeStaleAndCommitPvtDataOfOldBlocksReturnsOnCall[len(fake.removeStaleAndCommitPvtDataOfOldBlocksArgsForCall)]
@@ -732,6 +814,8 @@ func (fake *TxMgr) Invocations() map[string][][]interface{} {
 	defer fake.newQueryExecutorMutex.RUnlock()
 	fake.newTxSimulatorMutex.RLock()
 	defer fake.newTxSimulatorMutex.RUnlock()
+	fake.purgePrivateKeyMutex.RLock()
+	defer fake.purgePrivateKeyMutex.RUnlock()
 	fake.removeStaleAndCommitPvtDataOfOldBlocksMutex.RLock()
 	defer fake.removeStaleAndCommitPvtDataOfOldBlocksMutex.RUnlock()
 	fake.rollbackMutex.RLock()
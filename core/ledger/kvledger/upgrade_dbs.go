@@ -30,7 +30,7 @@ func UpgradeDBs(config *ledger.Config) error {
 
 	logger.Infof("Ledger data folder from config = [%s]", rootFSPath)
 
-	if config.StateDBConfig.StateDatabase == "CouchDB" {
+	if usesCouchDB(config.StateDBConfig) {
 		if err := statecouchdb.DropApplicationDBs(config.StateDBConfig.CouchDB); err != nil {
 			return err
 		}
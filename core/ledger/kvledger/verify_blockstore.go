@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/pkg/errors"
+)
+
+// VerifyBlockStore verifies the block files and the block index of a ledger and, if repair is true,
+// truncates a partially written last block and rebuilds the block index, if found inconsistent
+func VerifyBlockStore(rootFSPath, ledgerID string, repair bool) (*blkstorage.VerificationReport, error) {
+	fileLockPath := fileLockPath(rootFSPath)
+	fileLock := leveldbhelper.NewFileLock(fileLockPath)
+	if err := fileLock.Lock(); err != nil {
+		return nil, errors.Wrap(err, "as another peer node command is executing,"+
+			" wait for that command to complete its execution or terminate it before retrying")
+	}
+	defer fileLock.Unlock()
+
+	blockstorePath := BlockStorePath(rootFSPath)
+	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: attrsToIndex}
+	if repair {
+		return blkstorage.RepairBlockStore(blockstorePath, ledgerID, indexConfig)
+	}
+	return blkstorage.VerifyBlockStore(blockstorePath, ledgerID, indexConfig)
+}
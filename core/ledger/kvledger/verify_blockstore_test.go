@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kvledger
+
+import (
+	"testing"
+
+	configtxtest "github.com/hyperledger/fabric/common/configtx/test"
+	"github.com/hyperledger/fabric/core/ledger/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBlockStore(t *testing.T) {
+	conf, cleanup := testConfig(t)
+	defer cleanup()
+	provider := testutilNewProvider(conf, t, &mock.DeployedChaincodeInfoProvider{})
+	genesisBlock, _ := configtxtest.MakeGenesisBlock("testledger")
+	_, err := provider.Create(genesisBlock)
+	require.NoError(t, err)
+
+	// verification should fail when provider is still open
+	_, err = VerifyBlockStore(conf.RootFSPath, "testledger", false)
+	require.Contains(t, err.Error(), "as another peer node command is executing")
+	provider.Close()
+
+	for _, repair := range []bool{false, true} {
+		report, err := VerifyBlockStore(conf.RootFSPath, "testledger", repair)
+		require.NoError(t, err)
+		require.True(t, report.Healthy())
+		require.Equal(t, uint64(1), report.BlocksVerified)
+	}
+
+	_, err = VerifyBlockStore(conf.RootFSPath, "non-existing-ledger", false)
+	require.EqualError(t, err, "ledgerID [non-existing-ledger] does not exist")
+}
@@ -32,6 +32,7 @@ type Initializer struct {
 	Config                          *Config
 	CustomTxProcessors              map[common.HeaderType]CustomTxProcessor
 	Hasher                          Hasher
+	Encryptor                       Encryptor
 }
 
 // Config is a structure used to configure a ledger provider.
@@ -44,6 +45,10 @@ type Config struct {
 	PrivateDataConfig *PrivateDataConfig
 	// HistoryDBConfig holds the configuration parameters for the transaction history database.
 	HistoryDBConfig *HistoryDBConfig
+	// BlockStoreConfig holds the configuration parameters for the block store.
+	BlockStoreConfig *BlockStoreConfig
+	// StateCheckpointConfig holds the configuration parameters for the periodic state checkpoints.
+	StateCheckpointConfig *StateCheckpointConfig
 }
 
 // StateDBConfig is a structure used to configure the state parameters for the ledger.
@@ -54,6 +59,33 @@ type StateDBConfig struct {
 	// CouchDB is the configuration for CouchDB.  It is used when StateDatabase
 	// is set to "CouchDB".
 	CouchDB *CouchDBConfig
+	// Encryption is the configuration for encrypting state values at rest.
+	// A nil value, or an empty list of namespaces, disables encryption.
+	Encryption *StateEncryptionConfig
+	// CouchDBNamespaces maps a channel to the namespaces (chaincodes) of the
+	// channel whose state, including the private data of their collections, is
+	// stored in CouchDB while the rest of the state of the channel is stored in
+	// goleveldb. It is used when StateDatabase is "goleveldb".
+	CouchDBNamespaces map[string][]string
+	// MigrationTarget is the state database, currently only "CouchDB", to
+	// which the state is migrated while the peer runs. The state is written to
+	// both goleveldb and the target, the reads are served from goleveldb until
+	// the state of a channel has been copied to the target and verified, after
+	// which the reads are served from the target. It is used when
+	// StateDatabase is "goleveldb".
+	MigrationTarget string
+}
+
+// StateEncryptionConfig is a structure used to configure the encryption of state values at rest.
+type StateEncryptionConfig struct {
+	// KeySKIs are the hex encoded subject key identifiers of the AES keys held
+	// in the peer's BCCSP keystore. The first key is used to encrypt new values.
+	// The remaining keys are retained for decrypting values written before a
+	// key rotation.
+	KeySKIs []string
+	// Namespaces lists the chaincode namespaces whose values, including the
+	// values of their private data collections, are encrypted.
+	Namespaces []string
 }
 
 // CouchDBConfig is a structure used to configure a CouchInstance.
@@ -92,6 +124,41 @@ type CouchDBConfig struct {
 	// UserCacheSizeMBs needs to be a multiple of 32 MB. If it is not a multiple of 32 MB,
 	// the peer would round the size to the next multiple of 32 MB.
 	UserCacheSizeMBs int
+	// SysCacheSizeMBs denotes the maximum mega bytes (MB) to be allocated for the state
+	// cache of the system namespaces (i.e., lscc and _lifecycle) and the pinned namespaces.
+	// Zero denotes the default of 64 MB.
+	SysCacheSizeMBs int
+	// CacheEvictionPolicy is the policy by which the state cache evicts entries when full.
+	// The supported policies are "fifo" (the default), which evicts the oldest entries,
+	// and "lru", which evicts the least recently used entries.
+	CacheEvictionPolicy string
+	// PinnedCacheNamespaces are the chaincode namespaces that are cached along with the
+	// system namespaces, so that their entries are not evicted by the other chaincodes.
+	PinnedCacheNamespaces []string
+	// UncachedNamespaces are the chaincode namespaces that are never cached, for instance,
+	// the namespaces with a large number of keys that are rarely read twice.
+	UncachedNamespaces []string
+	// MaxIdleConnections is the maximum number of idle connections to CouchDB that
+	// are kept open for reuse. Zero denotes the default of 2000 connections.
+	MaxIdleConnections int
+	// MaxConnections is the maximum number of connections to CouchDB. The requests
+	// beyond this limit wait for a connection to become available. Zero denotes no limit.
+	MaxConnections int
+	// MaxRetryBackoff is the maximum wait between the retries of a failed CouchDB
+	// request. The wait starts at 125 milliseconds, doubles after every attempt and
+	// is randomized to spread the retries. Zero denotes no maximum.
+	MaxRetryBackoff time.Duration
+	// RetryBudgetRatio is the maximum ratio of the retries to the CouchDB requests.
+	// Once the budget is exhausted, the failed requests are not retried. Zero denotes
+	// no budget, i.e., every failed request is retried up to MaxRetries times.
+	RetryBudgetRatio float64
+	// CircuitBreakerThreshold is the number of consecutive failed CouchDB requests
+	// after which the circuit breaker opens and the requests fail without contacting
+	// CouchDB. Zero disables the circuit breaker.
+	CircuitBreakerThreshold int
+	// CircuitBreakerTimeout is the duration for which the circuit breaker remains open
+	// before a request is let through to probe whether CouchDB has recovered.
+	CircuitBreakerTimeout time.Duration
 }
 
 // PrivateDataConfig is a structure used to configure a private data storage provider.
@@ -105,11 +172,98 @@ type PrivateDataConfig struct {
 	// PurgeInterval is the number of blocks to wait until purging expired
 	// private data entries.
 	PurgeInterval int
+	// TimeToLive configures purging the private data of collections by time,
+	// in addition to the block-to-live of the collections.
+	TimeToLive *PvtDataTTLConfig
+}
+
+// PvtDataTTLConfig is a structure used to configure the purging of the private data of collections
+// after a wall-clock time-to-live, irrespective of the rate at which the blocks are produced. The
+// expiry that chaincode sets for an individual key (see package pkg/chaincode/expiry) overrides
+// the time-to-live of its collection.
+type PvtDataTTLConfig struct {
+	// Collections holds the time-to-live of the collections whose private data is purged by time.
+	Collections []CollectionTTL
+	// CheckInterval is the duration between two consecutive runs of the purging.
+	// A zero value disables the purging.
+	CheckInterval time.Duration
+}
+
+// CollectionTTL is the time-to-live of the private data of a collection.
+type CollectionTTL struct {
+	Namespace  string
+	Collection string
+	// TTL is the duration, counted from the timestamp of the block that last wrote a key, after which the
+	// key is purged. A zero value purges only the keys for which chaincode has set an expiry.
+	TTL time.Duration
+}
+
+// BlockStoreConfig is a structure used to configure the block store.
+type BlockStoreConfig struct {
+	// Compression is the algorithm used to compress the blocks appended to the
+	// block files. The supported options are "" (no compression) and "zstd".
+	// The blocks written earlier remain readable irrespective of this setting.
+	Compression string
+	// Archive configures moving the older block files out of the local disk.
+	// The archival is disabled if this is nil or the Path is empty.
+	Archive *BlockArchiveConfig
+}
+
+// BlockArchiveConfig is a structure used to configure the archival of the older
+// block files. The archived blocks remain available to the peer, which fetches
+// the archived block files back on demand.
+type BlockArchiveConfig struct {
+	// Path is the directory to which the block files are moved. Typically, this
+	// is the mount point of an object storage bucket (S3, GCS, or Azure Blob).
+	Path string
+	// RetainBlocks is the number of most recent blocks that are always kept on
+	// the local disk.
+	RetainBlocks uint64
+	// CacheSize is the maximum number of archived block files that are kept on
+	// the local disk, after these are fetched back for serving the reads.
+	CacheSize int
 }
 
 // HistoryDBConfig is a structure used to configure the transaction history database.
 type HistoryDBConfig struct {
 	Enabled bool
+	// Prune holds the configuration for periodically pruning the older entries from the history database.
+	Prune *HistoryPruneConfig
+}
+
+// HistoryPruneConfig is a structure used to configure the periodic pruning of the transaction history database.
+// The history entries are pruned only when they fall outside of all the configured retention limits. The latest
+// modification of each key is always retained.
+type HistoryPruneConfig struct {
+	// RetainBlocks is the number of most recent blocks for which the history is retained.
+	// A zero value disables the limit.
+	RetainBlocks uint64
+	// RetentionPeriod is the duration, counted back from the time of the pruning, for which the history is retained.
+	// The age of a block is derived from the timestamp of its first transaction. A zero value disables the limit.
+	RetentionPeriod time.Duration
+	// Interval is the number of blocks between two consecutive runs of the pruning.
+	// A zero value disables the periodic pruning.
+	Interval uint64
+}
+
+// StateCheckpointConfig is a structure used to configure the periodic checkpoints of the state of the ledgers.
+// A checkpoint captures the exported state at a block height along with the hashes that the organizations of
+// a channel can compare and sign, so that a new peer can verify a state obtained from another peer.
+type StateCheckpointConfig struct {
+	// Interval is the number of blocks between two consecutive checkpoints.
+	// A zero value disables the checkpoints at a block interval.
+	Interval uint64
+	// Schedule generates a checkpoint at the first block committed after each time of the schedule, in addition
+	// to the checkpoints generated every Interval blocks. The schedule is either '@every <duration>', one of
+	// '@hourly', '@daily', '@weekly' and '@monthly', or a cron expression of five fields such as '0 2 * * *'.
+	// An empty value disables the scheduled checkpoints.
+	Schedule string
+	// Retain is the number of most recent checkpoints retained for each ledger.
+	// A zero value retains all the checkpoints.
+	Retain int
+	// RetentionPeriod is the duration after which a checkpoint is removed. The most recent checkpoint is
+	// always retained. A zero value retains the checkpoints irrespective of their age.
+	RetentionPeriod time.Duration
 }
 
 // PeerLedgerProvider provides handle to ledger instances
@@ -295,6 +449,15 @@ type TxSimulator interface {
 	GetTxSimulationResults() (*TxSimulationResults, error)
 }
 
+// StateRangeCountEstimator is implemented by the query executors that can estimate the number of keys in a range
+// of the public state, for instance, for reporting the expected size of a paginated query. The estimate is not
+// recorded in the read-set of a simulation and hence, should not be used for deriving the transaction writes
+type StateRangeCountEstimator interface {
+	// EstimateStateRangeCount returns the number of keys between startKey (inclusive) and endKey (exclusive),
+	// counting no further than the given limit. A limit of zero denotes no limit
+	EstimateStateRangeCount(namespace, startKey, endKey string, limit uint64) (uint64, error)
+}
+
 // QueryResultsIterator - an iterator for query result set
 type QueryResultsIterator interface {
 	commonledger.ResultsIterator
@@ -697,6 +860,14 @@ type Hasher interface {
 	Hash(msg []byte, opts bccsp.HashOpts) (hash []byte, err error)
 }
 
+// Encryptor implements the symmetric key operations used for encrypting state values at rest.
+// Similar to Hasher, this limits the surface area of bccsp exposed to the ledger
+type Encryptor interface {
+	GetKey(ski []byte) (k bccsp.Key, err error)
+	Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error)
+	Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error)
+}
+
 //go:generate counterfeiter -o mock/state_listener.go -fake-name StateListener . StateListener
 //go:generate counterfeiter -o mock/query_executor.go -fake-name QueryExecutor . QueryExecutor
 //go:generate counterfeiter -o mock/tx_simulator.go -fake-name TxSimulator . TxSimulator
@@ -52,6 +52,7 @@ type Initializer struct {
 	HealthCheckRegistry             ledger.HealthCheckRegistry
 	Config                          *ledger.Config
 	Hasher                          ledger.Hasher
+	Encryptor                       ledger.Encryptor
 	EbMetadataProvider              MetadataProvider
 }
 
@@ -73,6 +74,7 @@ func NewLedgerMgr(initializer *Initializer) *LedgerMgr {
 			Config:                          initializer.Config,
 			CustomTxProcessors:              initializer.CustomTxProcessors,
 			Hasher:                          initializer.Hasher,
+			Encryptor:                       initializer.Encryptor,
 		},
 	)
 	if err != nil {
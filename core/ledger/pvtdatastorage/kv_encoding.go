@@ -27,6 +27,7 @@ var (
 	ineligibleMissingDataKeyPrefix = []byte{5}
 	collElgKeyPrefix               = []byte{6}
 	lastUpdatedOldBlocksKey        = []byte{7}
+	purgedKeyPrefix                = []byte{8}
 
 	nilByte    = byte(0)
 	emptyValue = []byte{}
@@ -163,6 +164,23 @@ func decodeMissingDataValue(bitmapBytes []byte) (*bitset.BitSet, error) {
 	return bitmap, nil
 }
 
+func encodePurgedKeyKey(ns, coll string, keyHash []byte) []byte {
+	keyBytes := append(purgedKeyPrefix, []byte(ns)...)
+	keyBytes = append(keyBytes, nilByte)
+	keyBytes = append(keyBytes, []byte(coll)...)
+	keyBytes = append(keyBytes, nilByte)
+	return append(keyBytes, keyHash...)
+}
+
+func encodePurgedKeyVal(purgedTillBlk uint64) []byte {
+	return proto.EncodeVarint(purgedTillBlk)
+}
+
+func decodePurgedKeyVal(b []byte) uint64 {
+	blkNum, _ := proto.DecodeVarint(b)
+	return blkNum
+}
+
 func encodeCollElgKey(blkNum uint64) []byte {
 	return append(collElgKeyPrefix, encodeReverseOrderVarUint64(blkNum)...)
 }
@@ -0,0 +1,226 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pvtdatastorage
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/internal/version"
+	"github.com/pkg/errors"
+)
+
+// PurgePrivateKey removes the writes of the given key from the private data of the given collections of a namespace
+// in all the committed blocks and returns the last committed block number. In addition, a tombstone is recorded for
+// the key in each of the collections so that the key is excluded when the private data of any of these blocks is
+// received later via reconciliation (see function `RemovePurgedKeys`). The tombstone holds only the hash of the key.
+// Note that the private data of a collection from which a key is purged does not match the hash present in the block
+// anymore and hence, such private data is rejected by the other peers if it is served to them for reconciliation
+func (s *Store) PurgePrivateKey(ns string, colls []string, key string) (uint64, error) {
+	return s.PurgePrivateKeys(ns, colls, []string{key}, math.MaxUint64)
+}
+
+// PurgePrivateKeys removes the writes of the given keys from the private data of the given collections of a namespace,
+// in a single pass over the committed blocks till the given block number, as per the function `PurgePrivateKey`, and
+// returns the last block number purged. The writes in the blocks committed after the given block number are retained
+func (s *Store) PurgePrivateKeys(ns string, colls []string, keys []string, tillBlk uint64) (uint64, error) {
+	if s.isEmpty {
+		return 0, &ErrIllegalCall{"The store is empty"}
+	}
+	if len(colls) == 0 {
+		return 0, &ErrIllegalArgs{"At least one collection must be specified"}
+	}
+	if len(keys) == 0 {
+		return 0, &ErrIllegalArgs{"At least one key must be specified"}
+	}
+	// the purger lock prevents a concurrent removal of the expired data that would otherwise be restored
+	// when the purged data entries are written back
+	s.purgerLock.Lock()
+	defer s.purgerLock.Unlock()
+
+	purgeTillBlk := atomic.LoadUint64(&s.lastCommittedBlock)
+	if tillBlk < purgeTillBlk {
+		purgeTillBlk = tillBlk
+	}
+	purgeColls := make(map[string]bool)
+	for _, coll := range colls {
+		purgeColls[coll] = true
+	}
+	purgeKeys := make(map[string]bool)
+	for _, key := range keys {
+		purgeKeys[key] = true
+	}
+	isPurgedKey := func(k string) (bool, error) {
+		return purgeKeys[k], nil
+	}
+
+	batch := leveldbhelper.NewUpdateBatch()
+	startKey := append(pvtDataKeyPrefix, version.NewHeight(0, 0).ToBytes()...)
+	endKey := append(pvtDataKeyPrefix, version.NewHeight(purgeTillBlk+1, 0).ToBytes()...)
+	itr := s.db.GetIterator(startKey, endKey)
+	defer itr.Release()
+	numPurged := 0
+	for itr.Next() {
+		dataKeyBytes := itr.Key()
+		v11Fmt, err := v11Format(dataKeyBytes)
+		if err != nil {
+			return 0, err
+		}
+		if v11Fmt {
+			return 0, errors.New("purging of the private data stored in the v1.1 format is not supported")
+		}
+		dataKey, err := decodeDatakey(dataKeyBytes)
+		if err != nil {
+			return 0, err
+		}
+		if dataKey.ns != ns || !purgeColls[dataKey.coll] {
+			continue
+		}
+		collPvtdata, err := decodeDataValue(itr.Value())
+		if err != nil {
+			return 0, err
+		}
+		rwsetBytes, purged, err := removeWritesOfKeys(collPvtdata.Rwset, isPurgedKey)
+		if err != nil {
+			return 0, err
+		}
+		if !purged {
+			continue
+		}
+		collPvtdata.Rwset = rwsetBytes
+		dataValueBytes, err := encodeDataValue(collPvtdata)
+		if err != nil {
+			return 0, err
+		}
+		batch.Put(dataKeyBytes, dataValueBytes)
+		numPurged++
+	}
+	if err := itr.Error(); err != nil {
+		return 0, errors.Wrap(err, "internal leveldb error while purging the private data")
+	}
+
+	for key := range purgeKeys {
+		keyHash := util.ComputeSHA256([]byte(key))
+		for coll := range purgeColls {
+			batch.Put(encodePurgedKeyKey(ns, coll, keyHash), encodePurgedKeyVal(purgeTillBlk))
+		}
+	}
+	if err := s.db.WriteBatch(batch, true); err != nil {
+		return 0, err
+	}
+	logger.Infof("[%s] - Purged [%d] private keys of namespace [%s] from [%d] private write sets till block number [%d]",
+		s.ledgerid, len(purgeKeys), ns, numPurged, purgeTillBlk)
+	return purgeTillBlk, nil
+}
+
+// RemovePurgedKeys removes, in place, the writes of the purged keys from the private data of the old blocks, so that
+// the reconciliation of the missing private data does not restore a key that has been purged via `PurgePrivateKey`
+func (s *Store) RemovePurgedKeys(blocksPvtData map[uint64][]*ledger.TxPvtData) error {
+	collHasPurgedKeys := make(map[[2]string]bool)
+	for blkNum, txsPvtData := range blocksPvtData {
+		for _, txPvtData := range txsPvtData {
+			if txPvtData.WriteSet == nil {
+				continue
+			}
+			for _, nsPvtdata := range txPvtData.WriteSet.NsPvtRwset {
+				for _, collPvtdata := range nsPvtdata.CollectionPvtRwset {
+					ns, coll := nsPvtdata.Namespace, collPvtdata.CollectionName
+					hasPurgedKeys, ok := collHasPurgedKeys[[2]string{ns, coll}]
+					if !ok {
+						var err error
+						if hasPurgedKeys, err = s.hasPurgedKeys(ns, coll); err != nil {
+							return err
+						}
+						collHasPurgedKeys[[2]string{ns, coll}] = hasPurgedKeys
+					}
+					if !hasPurgedKeys {
+						continue
+					}
+					isPurgedKey := func(k string) (bool, error) {
+						purgedTillBlk, exists, err := s.purgedTill(ns, coll, k)
+						return exists && blkNum <= purgedTillBlk, err
+					}
+					rwsetBytes, purged, err := removeWritesOfKeys(collPvtdata.Rwset, isPurgedKey)
+					if err != nil {
+						return err
+					}
+					if purged {
+						logger.Debugf("[%s] - Removed the purged keys from the private data of namespace [%s], collection [%s], block [%d]",
+							s.ledgerid, ns, coll, blkNum)
+						collPvtdata.Rwset = rwsetBytes
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) hasPurgedKeys(ns, coll string) (bool, error) {
+	startKey := encodePurgedKeyKey(ns, coll, nil)
+	endKey := append(encodePurgedKeyKey(ns, coll, nil)[:len(startKey)-1], nilByte+1)
+	itr := s.db.GetIterator(startKey, endKey)
+	defer itr.Release()
+	exists := itr.Next()
+	return exists, errors.Wrap(itr.Error(), "internal leveldb error while looking up the purged keys")
+}
+
+func (s *Store) purgedTill(ns, coll, key string) (uint64, bool, error) {
+	v, err := s.db.Get(encodePurgedKeyKey(ns, coll, util.ComputeSHA256([]byte(key))))
+	if err != nil || v == nil {
+		return 0, false, err
+	}
+	return decodePurgedKeyVal(v), true, nil
+}
+
+// removeWritesOfKeys removes the writes and the metadata writes of the keys, for which the given function returns
+// true, from the marshaled KVRWSet of a collection. It returns the marshaled KVRWSet and whether any write is removed
+func removeWritesOfKeys(rwsetBytes []byte, isRemoved func(key string) (bool, error)) ([]byte, bool, error) {
+	kvRWSet := &kvrwset.KVRWSet{}
+	if err := proto.Unmarshal(rwsetBytes, kvRWSet); err != nil {
+		return nil, false, errors.Wrap(err, "error unmarshalling the private write set")
+	}
+	removed := false
+	var writes []*kvrwset.KVWrite
+	for _, w := range kvRWSet.Writes {
+		isRemovedKey, err := isRemoved(w.Key)
+		if err != nil {
+			return nil, false, err
+		}
+		if isRemovedKey {
+			removed = true
+			continue
+		}
+		writes = append(writes, w)
+	}
+	var metadataWrites []*kvrwset.KVMetadataWrite
+	for _, w := range kvRWSet.MetadataWrites {
+		isRemovedKey, err := isRemoved(w.Key)
+		if err != nil {
+			return nil, false, err
+		}
+		if isRemovedKey {
+			removed = true
+			continue
+		}
+		metadataWrites = append(metadataWrites, w)
+	}
+	if !removed {
+		return rwsetBytes, false, nil
+	}
+	kvRWSet.Writes, kvRWSet.MetadataWrites = writes, metadataWrites
+	b, err := proto.Marshal(kvRWSet)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error marshalling the private write set")
+	}
+	return b, true, nil
+}
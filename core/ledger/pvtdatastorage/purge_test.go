@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pvtdatastorage
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	btltestutil "github.com/hyperledger/fabric/core/ledger/pvtdatapolicy/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgePrivateKey(t *testing.T) {
+	btlPolicy := btltestutil.SampleBTLPolicy(
+		map[[2]string]uint64{
+			{"ns-1", "coll-1"}: 0,
+			{"ns-1", "coll-2"}: 0,
+			{"ns-2", "coll-1"}: 0,
+		},
+	)
+	env := NewTestStoreEnv(t, "TestPurgePrivateKey", btlPolicy, pvtDataConf())
+	defer env.Cleanup()
+	store := env.TestStore
+
+	_, err := store.PurgePrivateKey("ns-1", []string{"coll-1"}, "key1")
+	require.EqualError(t, err, "The store is empty")
+
+	nsColls := []string{"ns-1:coll-1", "ns-1:coll-2", "ns-2:coll-1"}
+	missingData := make(ledger.TxMissingPvtDataMap)
+	missingData.Add(3, "ns-1", "coll-1", true)
+	require.NoError(t, store.Commit(0, nil, nil))
+	require.NoError(t, store.Commit(1, []*ledger.TxPvtData{samplePvtdataWithKeys(t, 2, nsColls, "key1", "key2")}, missingData))
+
+	_, err = store.PurgePrivateKey("ns-1", nil, "key1")
+	require.EqualError(t, err, "At least one collection must be specified")
+
+	purgedTill, err := store.PurgePrivateKey("ns-1", []string{"coll-1", "coll-2"}, "key1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), purgedTill)
+	blk1Pvtdata, err := store.GetPvtDataByBlockNum(1, nil)
+	require.NoError(t, err)
+	require.Len(t, blk1Pvtdata, 1)
+	require.Equal(t,
+		map[string][]string{
+			"ns-1:coll-1": {"key2"},
+			"ns-1:coll-2": {"key2"},
+			"ns-2:coll-1": {"key1", "key2"},
+		},
+		pvtdataKeysForTest(t, blk1Pvtdata[0]),
+	)
+
+	// the purged key is removed from the reconciled private data of the blocks till the purged block only
+	reconciledPvtdata := map[uint64][]*ledger.TxPvtData{
+		1: {samplePvtdataWithKeys(t, 3, []string{"ns-1:coll-1", "ns-2:coll-1"}, "key1", "key2")},
+		2: {samplePvtdataWithKeys(t, 0, []string{"ns-1:coll-1"}, "key1", "key2")},
+	}
+	require.NoError(t, store.RemovePurgedKeys(reconciledPvtdata))
+	require.Equal(t,
+		map[string][]string{
+			"ns-1:coll-1": {"key2"},
+			"ns-2:coll-1": {"key1", "key2"},
+		},
+		pvtdataKeysForTest(t, reconciledPvtdata[1][0]),
+	)
+	require.Equal(t,
+		map[string][]string{
+			"ns-1:coll-1": {"key1", "key2"},
+		},
+		pvtdataKeysForTest(t, reconciledPvtdata[2][0]),
+	)
+
+	// the tombstones survive a restart
+	env.CloseAndReopen()
+	store = env.TestStore
+	purgedTillBlk, exists, err := store.purgedTill("ns-1", "coll-2", "key1")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, uint64(1), purgedTillBlk)
+	_, exists, err = store.purgedTill("ns-2", "coll-1", "key1")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestPurgePrivateKeys(t *testing.T) {
+	btlPolicy := btltestutil.SampleBTLPolicy(
+		map[[2]string]uint64{
+			{"ns-1", "coll-1"}: 0,
+		},
+	)
+	env := NewTestStoreEnv(t, "TestPurgePrivateKeys", btlPolicy, pvtDataConf())
+	defer env.Cleanup()
+	store := env.TestStore
+
+	require.NoError(t, store.Commit(0, nil, nil))
+	require.NoError(t, store.Commit(1, []*ledger.TxPvtData{samplePvtdataWithKeys(t, 2, []string{"ns-1:coll-1"}, "key1", "key2", "key3")}, nil))
+
+	_, err := store.PurgePrivateKeys("ns-1", []string{"coll-1"}, nil, math.MaxUint64)
+	require.EqualError(t, err, "At least one key must be specified")
+
+	require.NoError(t, store.Commit(2, []*ledger.TxPvtData{samplePvtdataWithKeys(t, 1, []string{"ns-1:coll-1"}, "key1")}, nil))
+
+	// the writes in the blocks committed after the given block number are retained
+	purgedTill, err := store.PurgePrivateKeys("ns-1", []string{"coll-1"}, []string{"key1", "key3"}, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), purgedTill)
+	blk1Pvtdata, err := store.GetPvtDataByBlockNum(1, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"ns-1:coll-1": {"key2"}}, pvtdataKeysForTest(t, blk1Pvtdata[0]))
+	blk2Pvtdata, err := store.GetPvtDataByBlockNum(2, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"ns-1:coll-1": {"key1"}}, pvtdataKeysForTest(t, blk2Pvtdata[0]))
+	for _, key := range []string{"key1", "key3"} {
+		purgedTillBlk, exists, err := store.purgedTill("ns-1", "coll-1", key)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, uint64(1), purgedTillBlk)
+	}
+
+	purgedTill, err = store.PurgePrivateKeys("ns-1", []string{"coll-1"}, []string{"key1"}, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), purgedTill)
+	blk2Pvtdata, err = store.GetPvtDataByBlockNum(2, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"ns-1:coll-1": {}}, pvtdataKeysForTest(t, blk2Pvtdata[0]))
+}
+
+func samplePvtdataWithKeys(t *testing.T, txNum uint64, nsColls []string, keys ...string) *ledger.TxPvtData {
+	builder := rwsetutil.NewRWSetBuilder()
+	for _, nsColl := range nsColls {
+		nsCollSplit := strings.Split(nsColl, ":")
+		for _, key := range keys {
+			builder.AddToPvtAndHashedWriteSet(nsCollSplit[0], nsCollSplit[1], key, []byte("value-"+key))
+		}
+	}
+	simRes, err := builder.GetTxSimulationResults()
+	require.NoError(t, err)
+	return &ledger.TxPvtData{SeqInBlock: txNum, WriteSet: simRes.PvtSimulationResults}
+}
+
+func pvtdataKeysForTest(t *testing.T, txPvtdata *ledger.TxPvtData) map[string][]string {
+	keys := make(map[string][]string)
+	for _, nsPvtdata := range txPvtdata.WriteSet.NsPvtRwset {
+		for _, collPvtdata := range nsPvtdata.CollectionPvtRwset {
+			kvRWSet := &kvrwset.KVRWSet{}
+			require.NoError(t, proto.Unmarshal(collPvtdata.Rwset, kvRWSet))
+			nsColl := nsPvtdata.Namespace + ":" + collPvtdata.CollectionName
+			keys[nsColl] = []string{}
+			for _, w := range kvRWSet.Writes {
+				keys[nsColl] = append(keys[nsColl], w.Key)
+			}
+		}
+	}
+	return keys
+}
@@ -879,6 +879,11 @@ func (s *Store) LastCommittedBlockHeight() (uint64, error) {
 	return atomic.LoadUint64(&s.lastCommittedBlock) + 1, nil
 }
 
+// ApproximateSize returns the approximate number of bytes the store occupies on disk
+func (s *Store) ApproximateSize() (int64, error) {
+	return s.db.ApproximateSize()
+}
+
 func (s *Store) nextBlockNum() uint64 {
 	if s.isEmpty {
 		return 0
@@ -34,6 +34,9 @@ func TestEmptyStore(t *testing.T) {
 	assert := assert.New(t)
 	store := env.TestStore
 	assert.True(store.isEmpty)
+	size, err := store.ApproximateSize()
+	assert.NoError(err)
+	assert.Equal(int64(0), size)
 }
 
 func TestStoreBasicCommitAndRetrieval(t *testing.T) {
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hyperledger/fabric/common/audit"
+)
+
+type auditRequests struct {
+	log  *audit.Log
+	next http.Handler
+}
+
+// AuditRequests records the requests that may change the state of the node,
+// that is all the requests but GET, HEAD and OPTIONS requests, in the given
+// audit log. The requests rejected by the rest of the chain are recorded too.
+func AuditRequests(log *audit.Log) Middleware {
+	return func(next http.Handler) http.Handler {
+		return &auditRequests{log: log, next: next}
+	}
+}
+
+func (a *auditRequests) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		a.next.ServeHTTP(w, req)
+		return
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	a.next.ServeHTTP(recorder, req)
+
+	var actor string
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		actor = req.TLS.PeerCertificates[0].Subject.String()
+	}
+	a.log.Record(audit.Event{
+		Type:  audit.AdminRequest,
+		Actor: actor,
+		Attributes: map[string]string{
+			"method":     req.Method,
+			"path":       req.URL.Path,
+			"status":     strconv.Itoa(recorder.status),
+			"remoteAddr": req.RemoteAddr,
+			"requestID":  req.Header.Get("X-Request-Id"),
+		},
+	})
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
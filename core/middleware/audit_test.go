@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/core/middleware"
+	"github.com/hyperledger/fabric/core/middleware/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuditRequests", func() {
+	var (
+		buffer  *bytes.Buffer
+		handler *fakes.HTTPHandler
+		chain   http.Handler
+
+		req  *http.Request
+		resp *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+		handler = &fakes.HTTPHandler{}
+		chain = middleware.AuditRequests(audit.NewLog(nil, audit.NewWriterSink(buffer)))(handler)
+
+		req = httptest.NewRequest("PUT", "https:///logspec", nil)
+		req.Header.Set("X-Request-Id", "request-id")
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "admin"}}},
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	It("records the requests changing the node", func() {
+		handler.ServeHTTPStub = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		chain.ServeHTTP(resp, req)
+		Expect(resp.Code).To(Equal(http.StatusNoContent))
+		Expect(handler.ServeHTTPCallCount()).To(Equal(1))
+
+		record := &audit.Record{}
+		Expect(json.Unmarshal(buffer.Bytes(), record)).To(Succeed())
+		Expect(record.Type).To(Equal(audit.AdminRequest))
+		Expect(record.Actor).To(Equal("CN=admin"))
+		Expect(record.Attributes).To(Equal(map[string]string{
+			"method":     "PUT",
+			"path":       "/logspec",
+			"status":     "204",
+			"remoteAddr": "192.0.2.1:1234",
+			"requestID":  "request-id",
+		}))
+	})
+
+	It("records the requests without a client certificate", func() {
+		req.TLS = nil
+		chain.ServeHTTP(resp, req)
+
+		record := &audit.Record{}
+		Expect(json.Unmarshal(buffer.Bytes(), record)).To(Succeed())
+		Expect(record.Actor).To(BeEmpty())
+		Expect(record.Attributes).To(HaveKeyWithValue("status", "200"))
+	})
+
+	It("does not record the requests reading the node", func() {
+		for _, method := range []string{"GET", "HEAD", "OPTIONS"} {
+			req.Method = method
+			chain.ServeHTTP(resp, req)
+		}
+		Expect(handler.ServeHTTPCallCount()).To(Equal(3))
+		Expect(buffer.Len()).To(Equal(0))
+	})
+})
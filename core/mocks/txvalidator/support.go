@@ -20,6 +20,7 @@ import (
 	"sync"
 
 	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/msp"
@@ -30,6 +31,7 @@ type Support struct {
 	MSPManagerVal msp.MSPManager
 	ApplyVal      error
 	ACVal         channelconfig.ApplicationCapabilities
+	LowSVal       utils.LowSPolicy
 
 	sync.Mutex
 	capabilitiesInvokeCount int
@@ -61,6 +63,11 @@ func (ms *Support) Apply(configtx *common.ConfigEnvelope) error {
 	return ms.ApplyVal
 }
 
+// LowSPolicy returns LowSVal
+func (ms *Support) LowSPolicy() utils.LowSPolicy {
+	return ms.LowSVal
+}
+
 func (ms *Support) GetMSPIDs() []string {
 	return []string{"SampleOrg"}
 }
@@ -0,0 +1,315 @@
+/*
+Copyright IBM Corp All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/util"
+)
+
+const (
+	defaultProfileDuration    = 30 * time.Second
+	defaultMaxProfileDuration = 5 * time.Minute
+	defaultMaxProfileCaptures = 8
+)
+
+// The states of a profile capture.
+const (
+	CaptureRunning  = "running"
+	CaptureComplete = "complete"
+	CaptureFailed   = "failed"
+)
+
+// ProfilingOptions configures the capture of profiles through the operations
+// service.
+type ProfilingOptions struct {
+	// Enabled registers the /profiles resource.
+	Enabled bool
+	// MaxDuration is the longest CPU profile or execution trace that can be
+	// captured, five minutes by default.
+	MaxDuration time.Duration
+	// MaxCaptures is the number of captures kept for retrieval, eight by
+	// default. The oldest finished capture is discarded to make room for a
+	// new one.
+	MaxCaptures int
+}
+
+// ProfileRequest requests the capture of a profile.
+type ProfileRequest struct {
+	// Type is cpu or trace, captured over the duration, or the name of a
+	// runtime profile such as heap, allocs or goroutine, captured at once.
+	Type string `json:"type"`
+	// Duration of a cpu profile or an execution trace, such as 30s.
+	Duration string `json:"duration,omitempty"`
+	// Debug selects the text format of runtime profiles, as the debug
+	// parameter of net/http/pprof; 2 dumps the stacks of all goroutines.
+	Debug int `json:"debug,omitempty"`
+}
+
+// Capture describes a profile captured, or being captured, by the
+// ProfileHandler.
+type Capture struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	Status    string     `json:"status"`
+	Started   time.Time  `json:"started"`
+	Completed *time.Time `json:"completed,omitempty"`
+	Size      int        `json:"size,omitempty"`
+	Error     string     `json:"error,omitempty"`
+
+	debug int
+	data  []byte
+}
+
+// ProfileHandler captures profiles of the running process on request, and
+// keeps them for retrieval:
+//
+//   POST /profiles        starts the capture described by a ProfileRequest
+//   GET  /profiles        lists the captures
+//   GET  /profiles/<id>   retrieves the data of a complete capture
+//
+// Only one CPU profile and one execution trace can be captured at a time.
+type ProfileHandler struct {
+	MaxDuration time.Duration
+	MaxCaptures int
+
+	mutex    sync.Mutex
+	captures []*Capture
+	running  map[string]bool
+	now      func() time.Time
+}
+
+// NewProfileHandler returns a ProfileHandler for the given options.
+func NewProfileHandler(o ProfilingOptions) *ProfileHandler {
+	h := &ProfileHandler{
+		MaxDuration: o.MaxDuration,
+		MaxCaptures: o.MaxCaptures,
+		running:     map[string]bool{},
+		now:         time.Now,
+	}
+	if h.MaxDuration <= 0 {
+		h.MaxDuration = defaultMaxProfileDuration
+	}
+	if h.MaxCaptures <= 0 {
+		h.MaxCaptures = defaultMaxProfileCaptures
+	}
+	return h
+}
+
+func (h *ProfileHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/profiles"), "/")
+	switch {
+	case req.Method == http.MethodPost && id == "":
+		h.start(resp, req)
+	case req.Method == http.MethodGet && id == "":
+		h.sendResponse(resp, http.StatusOK, h.list())
+	case req.Method == http.MethodGet:
+		h.retrieve(resp, id)
+	default:
+		err := fmt.Errorf("invalid request method: %s", req.Method)
+		h.sendResponse(resp, http.StatusBadRequest, err)
+	}
+}
+
+func (h *ProfileHandler) start(resp http.ResponseWriter, req *http.Request) {
+	var pr ProfileRequest
+	if err := json.NewDecoder(req.Body).Decode(&pr); err != nil {
+		h.sendResponse(resp, http.StatusBadRequest, err)
+		return
+	}
+	req.Body.Close()
+
+	duration := defaultProfileDuration
+	if pr.Duration != "" {
+		d, err := time.ParseDuration(pr.Duration)
+		if err != nil {
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		duration = d
+	}
+
+	var capture func(*Capture) ([]byte, error)
+	switch pr.Type {
+	case "cpu", "trace":
+		if duration <= 0 || duration > h.MaxDuration {
+			err := fmt.Errorf("invalid duration %s: must be positive and at most %s", duration, h.MaxDuration)
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		capture = func(c *Capture) ([]byte, error) { return captureTimed(c.Type, duration) }
+	default:
+		if pprof.Lookup(pr.Type) == nil {
+			err := fmt.Errorf("unknown profile type: %s", pr.Type)
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		capture = captureSnapshot
+	}
+
+	c, err := h.add(pr)
+	if err != nil {
+		h.sendResponse(resp, http.StatusConflict, err)
+		return
+	}
+	snapshot := *c
+	go h.run(c, capture)
+
+	h.sendResponse(resp, http.StatusAccepted, &snapshot)
+}
+
+// add registers a new capture, and discards the oldest finished captures
+// beyond the maximum.
+func (h *ProfileHandler) add(pr ProfileRequest) (*Capture, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.running[pr.Type] && (pr.Type == "cpu" || pr.Type == "trace") {
+		return nil, fmt.Errorf("a %s capture is already running", pr.Type)
+	}
+
+	for len(h.captures) >= h.MaxCaptures {
+		i := 0
+		for i < len(h.captures) && h.captures[i].Status == CaptureRunning {
+			i++
+		}
+		if i == len(h.captures) {
+			return nil, fmt.Errorf("all %d captures are running", len(h.captures))
+		}
+		h.captures = append(h.captures[:i], h.captures[i+1:]...)
+	}
+
+	c := &Capture{
+		ID:      util.GenerateUUID(),
+		Type:    pr.Type,
+		Status:  CaptureRunning,
+		Started: h.now().UTC(),
+		debug:   pr.Debug,
+	}
+	h.captures = append(h.captures, c)
+	h.running[pr.Type] = true
+	return c, nil
+}
+
+func (h *ProfileHandler) run(c *Capture, capture func(*Capture) ([]byte, error)) {
+	data, err := capture(c)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	completed := h.now().UTC()
+	c.Completed = &completed
+	delete(h.running, c.Type)
+	if err != nil {
+		c.Status = CaptureFailed
+		c.Error = err.Error()
+		return
+	}
+	c.Status = CaptureComplete
+	c.Size = len(data)
+	c.data = data
+}
+
+func (h *ProfileHandler) list() []Capture {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	captures := make([]Capture, 0, len(h.captures))
+	for _, c := range h.captures {
+		captures = append(captures, *c)
+	}
+	return captures
+}
+
+func (h *ProfileHandler) retrieve(resp http.ResponseWriter, id string) {
+	h.mutex.Lock()
+	var capture *Capture
+	for _, c := range h.captures {
+		if c.ID == id {
+			snapshot := *c
+			capture = &snapshot
+		}
+	}
+	h.mutex.Unlock()
+
+	switch {
+	case capture == nil:
+		h.sendResponse(resp, http.StatusNotFound, fmt.Errorf("capture not found: %s", id))
+	case capture.Status == CaptureRunning:
+		h.sendResponse(resp, http.StatusConflict, fmt.Errorf("capture %s is running", id))
+	case capture.Status == CaptureFailed:
+		h.sendResponse(resp, http.StatusInternalServerError, fmt.Errorf("capture %s failed: %s", id, capture.Error))
+	default:
+		contentType := "application/octet-stream"
+		if capture.debug > 0 {
+			contentType = "text/plain; charset=utf-8"
+		}
+		resp.Header().Set("Content-Type", contentType)
+		resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.pprof"`, capture.Type, capture.ID))
+		resp.Header().Set("Content-Length", strconv.Itoa(len(capture.data)))
+		resp.WriteHeader(http.StatusOK)
+		resp.Write(capture.data)
+	}
+}
+
+// captureTimed captures a CPU profile or an execution trace over the
+// duration.
+func captureTimed(profileType string, duration time.Duration) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	stop := pprof.StopCPUProfile
+	if profileType == "trace" {
+		if err := trace.Start(buf); err != nil {
+			return nil, err
+		}
+		stop = trace.Stop
+	} else if err := pprof.StartCPUProfile(buf); err != nil {
+		return nil, err
+	}
+	time.Sleep(duration)
+	stop()
+	return buf.Bytes(), nil
+}
+
+// captureSnapshot captures a runtime profile. The heap is collected before a
+// heap profile is written so that it reflects the live objects.
+func captureSnapshot(c *Capture) ([]byte, error) {
+	if c.Type == "heap" {
+		runtime.GC()
+	}
+	buf := &bytes.Buffer{}
+	if err := pprof.Lookup(c.Type).WriteTo(buf, c.debug); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *ProfileHandler) sendResponse(resp http.ResponseWriter, code int, payload interface{}) {
+	if err, ok := payload.(error); ok {
+		payload = &errorResponse{Error: err.Error()}
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		logger := flogging.MustGetLogger("operations.runner")
+		logger.Errorw("failed to encode payload", "error", err)
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	resp.Write(js)
+}
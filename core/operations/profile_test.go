@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// the profiles in the protocol buffer format of pprof are gzipped
+const gzipMagic = "\x1f\x8b"
+
+var _ = Describe("ProfileHandler", func() {
+	var handler *ProfileHandler
+
+	BeforeEach(func() {
+		handler = NewProfileHandler(ProfilingOptions{MaxDuration: time.Second, MaxCaptures: 2})
+	})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return resp
+	}
+
+	start := func(body string) Capture {
+		resp := request(http.MethodPost, "/profiles", body)
+		Expect(resp.Code).To(Equal(http.StatusAccepted))
+		var c Capture
+		Expect(json.Unmarshal(resp.Body.Bytes(), &c)).To(Succeed())
+		Expect(c.Status).To(Equal(CaptureRunning))
+		return c
+	}
+
+	retrieve := func(id string) *httptest.ResponseRecorder {
+		var resp *httptest.ResponseRecorder
+		Eventually(func() int {
+			resp = request(http.MethodGet, "/profiles/"+id, "")
+			return resp.Code
+		}, 5*time.Second).ShouldNot(Equal(http.StatusConflict))
+		return resp
+	}
+
+	It("captures a cpu profile over the requested duration", func() {
+		c := start(`{"type": "cpu", "duration": "100ms"}`)
+		Expect(c.Type).To(Equal("cpu"))
+
+		resp := request(http.MethodPost, "/profiles", `{"type": "cpu", "duration": "100ms"}`)
+		Expect(resp.Code).To(Equal(http.StatusConflict))
+		Expect(resp.Body).To(MatchJSON(`{"Error": "a cpu capture is already running"}`))
+
+		resp = retrieve(c.ID)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("application/octet-stream"))
+		Expect(resp.Body.String()).To(HavePrefix(gzipMagic))
+	})
+
+	It("captures runtime profiles at once", func() {
+		c := start(`{"type": "heap"}`)
+		resp := retrieve(c.ID)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(HavePrefix(gzipMagic))
+
+		c = start(`{"type": "goroutine", "debug": 2}`)
+		resp = retrieve(c.ID)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+		Expect(resp.Body.String()).To(ContainSubstring("goroutine "))
+	})
+
+	It("keeps the latest captures", func() {
+		first := start(`{"type": "goroutine"}`)
+		retrieve(first.ID)
+		second := start(`{"type": "goroutine"}`)
+		retrieve(second.ID)
+		third := start(`{"type": "goroutine"}`)
+		retrieve(third.ID)
+
+		resp := request(http.MethodGet, "/profiles", "")
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		var captures []Capture
+		Expect(json.Unmarshal(resp.Body.Bytes(), &captures)).To(Succeed())
+		Expect(captures).To(HaveLen(2))
+		Expect(captures[0].ID).To(Equal(second.ID))
+		Expect(captures[1].ID).To(Equal(third.ID))
+		Expect(captures[1].Status).To(Equal(CaptureComplete))
+		Expect(captures[1].Size).To(BeNumerically(">", 0))
+
+		resp = request(http.MethodGet, "/profiles/"+first.ID, "")
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Body).To(MatchJSON(`{"Error": "capture not found: ` + first.ID + `"}`))
+	})
+
+	It("reports failed captures", func() {
+		// a CPU profile started elsewhere prevents the capture
+		Expect(pprof.StartCPUProfile(&strings.Builder{})).To(Succeed())
+		defer pprof.StopCPUProfile()
+
+		c := start(`{"type": "cpu", "duration": "10ms"}`)
+		resp := retrieve(c.ID)
+		Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+		Expect(resp.Body.String()).To(ContainSubstring("capture " + c.ID + " failed: cpu profiling already in use"))
+	})
+
+	DescribeTable("rejecting bad requests",
+		func(method, body string, code int, errMsg string) {
+			resp := request(method, "/profiles", body)
+			Expect(resp.Code).To(Equal(code))
+			Expect(resp.Body).To(MatchJSON(`{"Error": "` + errMsg + `"}`))
+		},
+		Entry("bad payload", http.MethodPost, `goo`, http.StatusBadRequest, "invalid character 'g' looking for beginning of value"),
+		Entry("unknown type", http.MethodPost, `{"type": "bogus"}`, http.StatusBadRequest, "unknown profile type: bogus"),
+		Entry("bad duration", http.MethodPost, `{"type": "cpu", "duration": "soon"}`, http.StatusBadRequest, `time: invalid duration \"soon\"`),
+		Entry("long duration", http.MethodPost, `{"type": "trace", "duration": "1m"}`, http.StatusBadRequest, "invalid duration 1m0s: must be positive and at most 1s"),
+		Entry("bad method", http.MethodDelete, ``, http.StatusBadRequest, "invalid request method: DELETE"),
+	)
+})
@@ -17,6 +17,7 @@ import (
 
 	kitstatsd "github.com/go-kit/kit/metrics/statsd"
 	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/common/audit"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/flogging/httpadmin"
 	"github.com/hyperledger/fabric/common/metadata"
@@ -55,6 +56,10 @@ type Options struct {
 	Metrics       MetricsOptions
 	TLS           TLS
 	Version       string
+	// AuditLog records the requests changing the node, when set.
+	AuditLog *audit.Log
+	// Profiling configures the capture of profiles on request.
+	Profiling ProfilingOptions
 }
 
 type System struct {
@@ -88,6 +93,7 @@ func NewSystem(o Options) *System {
 	system.initializeLoggingHandler()
 	system.initializeMetricsProvider()
 	system.initializeVersionInfoHandler()
+	system.initializeProfileHandler()
 
 	return system
 }
@@ -155,10 +161,16 @@ func (s *System) initializeServer() {
 }
 
 func (s *System) handlerChain(h http.Handler, secure bool) http.Handler {
+	var mws []middleware.Middleware
+	if s.options.AuditLog != nil {
+		// the requests are audited first so that the rejected ones are recorded too
+		mws = append(mws, middleware.AuditRequests(s.options.AuditLog))
+	}
 	if secure {
-		return middleware.NewChain(middleware.RequireCert(), middleware.WithRequestID(util.GenerateUUID)).Handler(h)
+		mws = append(mws, middleware.RequireCert())
 	}
-	return middleware.NewChain(middleware.WithRequestID(util.GenerateUUID)).Handler(h)
+	mws = append(mws, middleware.WithRequestID(util.GenerateUUID))
+	return middleware.NewChain(mws...).Handler(h)
 }
 
 func (s *System) initializeMetricsProvider() error {
@@ -211,6 +223,21 @@ func (s *System) initializeVersionInfoHandler() {
 	s.mux.Handle("/version", s.handlerChain(versionInfo, false))
 }
 
+// initializeProfileHandler registers the /profiles resource when profiling is
+// enabled. Profiles expose the internals of the process, so the resource
+// always requires a client certificate, and is unusable without TLS.
+func (s *System) initializeProfileHandler() {
+	if !s.options.Profiling.Enabled {
+		return
+	}
+	if !s.options.TLS.Enabled {
+		s.logger.Warn("Profiling is enabled, but requires TLS on the operations endpoint; profiles cannot be captured")
+	}
+	handler := s.handlerChain(NewProfileHandler(s.options.Profiling), true)
+	s.mux.Handle("/profiles", handler)
+	s.mux.Handle("/profiles/", handler)
+}
+
 // RegisterHandler registers into the ServeMux a handler chain that borrows its security properties from the
 // operations.System. This method is thread safe because ServeMux.Handle() is thread safe, and options are immutable.
 // This method can be called either before or after System.Start(). If the pattern exists the method panics.
@@ -16,10 +16,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/hyperledger/fabric-lib-go/healthz"
+	"github.com/hyperledger/fabric/common/audit"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/common/metrics/prometheus"
 	"github.com/hyperledger/fabric/common/metrics/statsd"
@@ -185,6 +187,91 @@ var _ = Describe("System", func() {
 		})
 	})
 
+	Context("when an audit log is provided", func() {
+		var auditBuffer *gbytes.Buffer
+
+		BeforeEach(func() {
+			auditBuffer = gbytes.NewBuffer()
+			options.AuditLog = audit.NewLog(nil, audit.NewWriterSink(auditBuffer))
+			system = operations.NewSystem(options)
+		})
+
+		It("records the requests changing the node", func() {
+			system.RegisterHandler(AdditionalTestApiPath, &fakes.Handler{Code: http.StatusOK, Text: "secure"})
+			err := system.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			addApiURL := fmt.Sprintf("https://%s%s", system.Addr(), AdditionalTestApiPath)
+			resp, err := client.Get(addApiURL)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(auditBuffer.Contents()).To(BeEmpty())
+
+			resp, err = client.Post(addApiURL, "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Eventually(auditBuffer).Should(gbytes.Say(`"type":"admin.request".*"method":"POST","path":"/some-additional-test-api",.*"status":"200"`))
+
+			resp, err = unauthClient.Post(addApiURL, "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Eventually(auditBuffer).Should(gbytes.Say(`"type":"admin.request".*"status":"401"`))
+		})
+	})
+
+	Context("when profiling is enabled", func() {
+		BeforeEach(func() {
+			options.Profiling = operations.ProfilingOptions{Enabled: true}
+			system = operations.NewSystem(options)
+		})
+
+		It("captures profiles for authenticated clients", func() {
+			err := system.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			profilesURL := fmt.Sprintf("https://%s/profiles", system.Addr())
+			resp, err := unauthClient.Post(profilesURL, "application/json", strings.NewReader(`{"type": "goroutine"}`))
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+			resp, err = client.Post(profilesURL, "application/json", strings.NewReader(`{"type": "goroutine", "debug": 2}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			var capture operations.Capture
+			err = json.NewDecoder(resp.Body).Decode(&capture)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+
+			captureURL := profilesURL + "/" + capture.ID
+			Eventually(func() int {
+				resp, err := client.Get(captureURL)
+				Expect(err).NotTo(HaveOccurred())
+				resp.Body.Close()
+				return resp.StatusCode
+			}).Should(Equal(http.StatusOK))
+
+			resp, err = client.Get(captureURL)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring("goroutine "))
+		})
+	})
+
+	Context("when profiling is disabled", func() {
+		It("does not serve profiles", func() {
+			err := system.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := client.Get(fmt.Sprintf("https://%s/profiles", system.Addr()))
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
 	Context("when ClientCertRequired is true", func() {
 		BeforeEach(func() {
 			options.TLS.ClientCertRequired = true
@@ -0,0 +1,193 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package peer
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// ChaincodeEventsFilter selects the chaincode events delivered to a client of
+// DeliverFiltered. Clients set the marshaled filter, as returned by Extension,
+// as the extension of the channel header of their deliver request.
+type ChaincodeEventsFilter struct {
+	// ChaincodeID is the name of the chaincode emitting the events, or empty for any chaincode
+	ChaincodeID string
+	// EventName is a regular expression the whole name of the events must match,
+	// or empty for any event
+	EventName string
+	// AfterTxID is the transaction of the first delivered block after which
+	// delivery resumes, or empty to deliver all of its transactions
+	AfterTxID string
+}
+
+// Extension returns the filter as the extension of the channel header of a deliver request
+func (f *ChaincodeEventsFilter) Extension() ([]byte, error) {
+	if _, err := compileEventName(f.EventName); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&peer.ChaincodeEvent{
+		ChaincodeId: f.ChaincodeID,
+		EventName:   f.EventName,
+		TxId:        f.AfterTxID,
+	})
+}
+
+func compileEventName(eventName string) (*regexp.Regexp, error) {
+	if eventName == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + eventName + ")$")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid event name pattern %s", eventName)
+	}
+	return re, nil
+}
+
+// ChaincodeEventsCheckpoint is the position of the last chaincode event consumed by a client
+type ChaincodeEventsCheckpoint struct {
+	// BlockNumber is the number of the last block the client consumed events from
+	BlockNumber uint64
+	// TxID is the last transaction the client consumed events from, or empty
+	// if the client consumed all the events of the block
+	TxID string
+}
+
+// Token returns the checkpoint as an opaque string clients can persist
+func (c ChaincodeEventsCheckpoint) Token() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(c.BlockNumber, 10) + "/" + c.TxID))
+}
+
+// ParseChaincodeEventsCheckpoint parses a token returned by ChaincodeEventsCheckpoint.Token
+func ParseChaincodeEventsCheckpoint(token string) (ChaincodeEventsCheckpoint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ChaincodeEventsCheckpoint{}, errors.Wrap(err, "malformed checkpoint token")
+	}
+	parts := strings.SplitN(string(raw), "/", 2)
+	if len(parts) != 2 {
+		return ChaincodeEventsCheckpoint{}, errors.New("malformed checkpoint token")
+	}
+	blockNumber, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return ChaincodeEventsCheckpoint{}, errors.Wrap(err, "malformed checkpoint token")
+	}
+	return ChaincodeEventsCheckpoint{BlockNumber: blockNumber, TxID: parts[1]}, nil
+}
+
+// Start returns the position of the first block to request to resume from the checkpoint
+func (c ChaincodeEventsCheckpoint) Start() *orderer.SeekPosition {
+	number := c.BlockNumber
+	if c.TxID == "" {
+		number++
+	}
+	return &orderer.SeekPosition{
+		Type: &orderer.SeekPosition_Specified{
+			Specified: &orderer.SeekSpecified{Number: number},
+		},
+	}
+}
+
+// Resume returns the filter with its AfterTxID set to resume from the checkpoint
+func (c ChaincodeEventsCheckpoint) Resume(filter ChaincodeEventsFilter) ChaincodeEventsFilter {
+	filter.AfterTxID = c.TxID
+	return filter
+}
+
+// chaincodeEventsFilter is a parsed ChaincodeEventsFilter
+type chaincodeEventsFilter struct {
+	chaincodeID string
+	eventName   *regexp.Regexp
+	afterTxID   string
+	// delivered is whether a block was delivered to the request
+	delivered bool
+}
+
+// parseChaincodeEventsFilter returns the filter of the given deliver request payload,
+// or nil if it has none
+func parseChaincodeEventsFilter(requestPayload []byte) (*chaincodeEventsFilter, error) {
+	payload, err := protoutil.UnmarshalPayload(requestPayload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, errors.New("request has no header")
+	}
+	chdr, err := protoutil.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(chdr.Extension) == 0 {
+		return nil, nil
+	}
+
+	ccEvent := &peer.ChaincodeEvent{}
+	if err := proto.Unmarshal(chdr.Extension, ccEvent); err != nil {
+		return nil, errors.Wrap(err, "malformed chaincode events filter")
+	}
+	eventName, err := compileEventName(ccEvent.EventName)
+	if err != nil {
+		return nil, err
+	}
+	return &chaincodeEventsFilter{
+		chaincodeID: ccEvent.ChaincodeId,
+		eventName:   eventName,
+		afterTxID:   ccEvent.TxId,
+	}, nil
+}
+
+func (f *chaincodeEventsFilter) matches(event *peer.ChaincodeEvent) bool {
+	if f.chaincodeID != "" && event.ChaincodeId != f.chaincodeID {
+		return false
+	}
+	return f.eventName == nil || f.eventName.MatchString(event.EventName)
+}
+
+// apply removes from the given block the transactions that are not valid or have no
+// matching chaincode events, and in the first block the transactions up to afterTxID.
+// The block is kept even when it's left empty, so that clients can checkpoint it.
+func (f *chaincodeEventsFilter) apply(block *peer.FilteredBlock) {
+	txs := block.FilteredTransactions
+	if !f.delivered && f.afterTxID != "" {
+		for i, tx := range txs {
+			if tx.Txid == f.afterTxID {
+				txs = txs[i+1:]
+				break
+			}
+		}
+	}
+	f.delivered = true
+
+	var filtered []*peer.FilteredTransaction
+	for _, tx := range txs {
+		if tx.TxValidationCode != peer.TxValidationCode_VALID {
+			continue
+		}
+		var actions []*peer.FilteredChaincodeAction
+		for _, action := range tx.GetTransactionActions().GetChaincodeActions() {
+			if f.matches(action.ChaincodeEvent) {
+				actions = append(actions, action)
+			}
+		}
+		if len(actions) == 0 {
+			continue
+		}
+		tx.Data = &peer.FilteredTransaction_TransactionActions{
+			TransactionActions: &peer.FilteredTransactionActions{ChaincodeActions: actions},
+		}
+		filtered = append(filtered, tx)
+	}
+	block.FilteredTransactions = filtered
+}
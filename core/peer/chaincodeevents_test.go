@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package peer
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	txID          string
+	chaincodeName string
+	eventName     string
+	invalid       bool
+}
+
+func createEventsBlock(t *testing.T, number uint64, events ...testEvent) *common.Block {
+	var envs []*common.Envelope
+	for _, event := range events {
+		action, err := createChaincodeAction(event.chaincodeName, event.eventName, event.txID)
+		require.NoError(t, err)
+		payload, err := createEndorsement("testchannelid", event.txID, action)
+		require.NoError(t, err)
+		envs = append(envs, &common.Envelope{Payload: protoutil.MarshalOrPanic(payload)})
+	}
+	block, err := createTestBlock(envs)
+	require.NoError(t, err)
+	block.Header.Number = number
+	for i, event := range events {
+		if event.invalid {
+			block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER][i] = byte(peer.TxValidationCode_MVCC_READ_CONFLICT)
+		}
+	}
+	return block
+}
+
+func deliverRequest(t *testing.T, filter *ChaincodeEventsFilter) *protoutil.SignedData {
+	chdr := &common.ChannelHeader{ChannelId: "testchannelid"}
+	if filter != nil {
+		var err error
+		chdr.Extension, err = filter.Extension()
+		require.NoError(t, err)
+	}
+	payload := &common.Payload{
+		Header: &common.Header{ChannelHeader: protoutil.MarshalOrPanic(chdr)},
+	}
+	return &protoutil.SignedData{Data: protoutil.MarshalOrPanic(payload)}
+}
+
+func txIDs(block *peer.FilteredBlock) []string {
+	var ids []string
+	for _, tx := range block.FilteredTransactions {
+		ids = append(ids, tx.Txid)
+	}
+	return ids
+}
+
+func TestChaincodeEventsFilter(t *testing.T) {
+	server := &mockDeliverServer{}
+	var sent []*peer.DeliverResponse
+	server.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(*peer.DeliverResponse))
+	}).Return(nil)
+	fbrs := &filteredBlockResponseSender{Deliver_DeliverFilteredServer: server}
+
+	events := []testEvent{
+		{txID: "tx1", chaincodeName: "asset", eventName: "Created"},
+		{txID: "tx2", chaincodeName: "asset", eventName: "Transferred"},
+		{txID: "tx3", chaincodeName: "other", eventName: "Created"},
+		{txID: "tx4", chaincodeName: "asset", eventName: "Created", invalid: true},
+		{txID: "tx5", chaincodeName: "asset", eventName: "CreatedTwice"},
+		{txID: "tx6", chaincodeName: "asset", eventName: "Created"},
+	}
+
+	// without a filter, all transactions are delivered
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 5, events...), "testchannelid", nil, deliverRequest(t, nil)))
+	assert.Equal(t, []string{"tx1", "tx2", "tx3", "tx4", "tx5", "tx6"}, txIDs(sent[0].GetFilteredBlock()))
+
+	request := deliverRequest(t, &ChaincodeEventsFilter{ChaincodeID: "asset", EventName: "Created|Deleted", AfterTxID: "tx1"})
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 5, events...), "testchannelid", nil, request))
+	filtered := sent[1].GetFilteredBlock()
+	assert.Equal(t, uint64(5), filtered.Number)
+	assert.Equal(t, []string{"tx6"}, txIDs(filtered))
+	assert.Equal(t, "Created", filtered.FilteredTransactions[0].GetTransactionActions().ChaincodeActions[0].ChaincodeEvent.EventName)
+
+	// only the first block is resumed after the transaction
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 6, events...), "testchannelid", nil, request))
+	assert.Equal(t, []string{"tx1", "tx6"}, txIDs(sent[2].GetFilteredBlock()))
+
+	// blocks without matching events are delivered empty
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 7, events[2]), "testchannelid", nil, request))
+	assert.Equal(t, uint64(7), sent[3].GetFilteredBlock().Number)
+	assert.Empty(t, sent[3].GetFilteredBlock().FilteredTransactions)
+
+	// a new request resets the filter
+	request = deliverRequest(t, &ChaincodeEventsFilter{EventName: "Created.*", AfterTxID: "tx3"})
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 5, events...), "testchannelid", nil, request))
+	assert.Equal(t, []string{"tx5", "tx6"}, txIDs(sent[4].GetFilteredBlock()))
+
+	// an unknown transaction to resume after doesn't drop any event
+	request = deliverRequest(t, &ChaincodeEventsFilter{ChaincodeID: "other", AfterTxID: "tx9"})
+	require.NoError(t, fbrs.SendBlockResponse(createEventsBlock(t, 5, events...), "testchannelid", nil, request))
+	assert.Equal(t, []string{"tx3"}, txIDs(sent[5].GetFilteredBlock()))
+}
+
+func TestChaincodeEventsFilterInvalid(t *testing.T) {
+	_, err := (&ChaincodeEventsFilter{EventName: "("}).Extension()
+	assert.Contains(t, err.Error(), "invalid event name pattern (")
+
+	server := &mockDeliverServer{}
+	server.On("Send", mock.Anything).Return(nil)
+	fbrs := &filteredBlockResponseSender{Deliver_DeliverFilteredServer: server}
+
+	chdr := &common.ChannelHeader{
+		ChannelId: "testchannelid",
+		Extension: protoutil.MarshalOrPanic(&peer.ChaincodeEvent{EventName: "("}),
+	}
+	request := &protoutil.SignedData{Data: protoutil.MarshalOrPanic(&common.Payload{
+		Header: &common.Header{ChannelHeader: protoutil.MarshalOrPanic(chdr)},
+	})}
+	err = fbrs.SendBlockResponse(createEventsBlock(t, 0), "testchannelid", nil, request)
+	assert.Contains(t, err.Error(), "invalid chaincode events filter: invalid event name pattern (")
+	server.AssertCalled(t, "Send", &peer.DeliverResponse{
+		Type: &peer.DeliverResponse_Status{Status: common.Status_BAD_REQUEST},
+	})
+
+	chdr.Extension = []byte("garbage")
+	request.Data = protoutil.MarshalOrPanic(&common.Payload{
+		Header: &common.Header{ChannelHeader: protoutil.MarshalOrPanic(chdr)},
+	})
+	err = fbrs.SendBlockResponse(createEventsBlock(t, 0), "testchannelid", nil, request)
+	assert.Contains(t, err.Error(), "malformed chaincode events filter")
+}
+
+func TestChaincodeEventsCheckpoint(t *testing.T) {
+	checkpoint := ChaincodeEventsCheckpoint{BlockNumber: 12, TxID: "tx/1"}
+	parsed, err := ParseChaincodeEventsCheckpoint(checkpoint.Token())
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint, parsed)
+	assert.Equal(t, uint64(12), parsed.Start().GetSpecified().Number)
+
+	filter := checkpoint.Resume(ChaincodeEventsFilter{ChaincodeID: "asset"})
+	assert.Equal(t, ChaincodeEventsFilter{ChaincodeID: "asset", AfterTxID: "tx/1"}, filter)
+	extension, err := filter.Extension()
+	require.NoError(t, err)
+	ccEvent := &peer.ChaincodeEvent{}
+	require.NoError(t, proto.Unmarshal(extension, ccEvent))
+	assert.Equal(t, "tx/1", ccEvent.TxId)
+
+	// a fully consumed block resumes from the next one
+	parsed, err = ParseChaincodeEventsCheckpoint(ChaincodeEventsCheckpoint{BlockNumber: 12}.Token())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(13), parsed.Start().GetSpecified().Number)
+
+	_, err = ParseChaincodeEventsCheckpoint("!")
+	assert.Contains(t, err.Error(), "malformed checkpoint token")
+	_, err = ParseChaincodeEventsCheckpoint("MTI")
+	assert.EqualError(t, err, "malformed checkpoint token")
+	_, err = ParseChaincodeEventsCheckpoint("YS9i")
+	assert.Contains(t, err.Error(), "malformed checkpoint token")
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/common/ledger/blockledger/fileledger"
@@ -104,6 +105,12 @@ func (c *Channel) Capabilities() channelconfig.ApplicationCapabilities {
 	return ac.Capabilities()
 }
 
+// LowSPolicy returns the policy applied to the high-S ECDSA signatures verified
+// in the current channel configuration, or "" when they are refused.
+func (c *Channel) LowSPolicy() utils.LowSPolicy {
+	return c.Resources().ChannelConfig().Capabilities().LowSPolicy()
+}
+
 // GetMSPIDs retrieves the MSP IDs of the organziations in the current channel
 // configuration.
 func (c *Channel) GetMSPIDs() []string {
@@ -26,9 +26,11 @@ import (
 	"net"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/core/container/ccintf"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -42,6 +44,17 @@ type ExternalBuilder struct {
 	Path                 string   `yaml:"path"`
 }
 
+// ChaincodeResourceLimits overrides the resource limits of the chaincode with the
+// given label.
+type ChaincodeResourceLimits struct {
+	Label          string  `yaml:"label"`
+	CPUs           float64 `yaml:"cpus"`
+	Memory         int64   `yaml:"memory"`
+	Pids           int64   `yaml:"pids"`
+	ReadOnlyRootfs bool    `yaml:"readOnlyRootfs"`
+	SeccompProfile string  `yaml:"seccompProfile"`
+}
+
 // Config is the struct that defines the Peer configurations.
 type Config struct {
 	// LocalMSPID is the identifier of the local MSP.
@@ -105,6 +118,12 @@ type Config struct {
 	// DiscoveryAuthCachePurgeRetentionRatio set the proportion of entries remains in cache
 	// after overpopulation purge.
 	DiscoveryAuthCachePurgeRetentionRatio float64
+	// DiscoveryEndorsementCacheTTL is the time endorsement descriptors are cached for
+	// identical chaincode queries. Zero disables the cache.
+	DiscoveryEndorsementCacheTTL time.Duration
+	// DiscoveryEndorsementCacheMaxSize sets the maximum number of endorsement descriptors
+	// cached per channel.
+	DiscoveryEndorsementCacheMaxSize int
 
 	// ----- Limits -----
 	// Limits is used to configure some internal resource limits.
@@ -119,6 +138,33 @@ type Config struct {
 	// registered to deliver service for blocks and transaction events.
 	LimitsConcurrencyDeliverService int
 
+	// LimitsConcurrencyGossipService sets the limits for concurrent streams
+	// opened to the gossip service by other peers.
+	LimitsConcurrencyGossipService int
+
+	// LimitsMaxRecvMsgSize{Endorser,Deliver,Gossip}Service set the size, in
+	// bytes, of the largest message received by each service. Zero leaves the
+	// limit of the server.
+	LimitsMaxRecvMsgSizeEndorserService int
+	LimitsMaxRecvMsgSizeDeliverService  int
+	LimitsMaxRecvMsgSizeGossipService   int
+
+	// LimitsMaxSendMsgSize{Endorser,Deliver,Gossip}Service set the size, in
+	// bytes, of the largest message sent by each service. Zero leaves the
+	// limit of the server.
+	LimitsMaxSendMsgSizeEndorserService int
+	LimitsMaxSendMsgSizeDeliverService  int
+	LimitsMaxSendMsgSizeGossipService   int
+
+	// LimitsClients{Scope,ProposalsPerSecond,Concurrency,MaxWait} set the quotas of the
+	// clients sending proposals to the endorser service: the rate of proposals of each
+	// client, and the number of proposals endorsed concurrently, which are shared fairly
+	// among the clients. The scope is Client or Org.
+	LimitsClientsScope              string
+	LimitsClientsProposalsPerSecond uint32
+	LimitsClientsConcurrency        int
+	LimitsClientsMaxWait            time.Duration
+
 	// ----- TLS -----
 	// Require server-side TLS.
 	// TODO: create separate sub-struct for PeerTLS config.
@@ -156,6 +202,36 @@ type Config struct {
 	// chaincode. The external builder detection processing will iterate over the
 	// builders in the order specified below.
 	ExternalBuilders []ExternalBuilder
+	// ChaincodeResourceLimits are the resource limits and isolation controls
+	// applied to chaincode when it is launched, by default and per label.
+	ChaincodeResourceLimits *ccintf.ResourceLimitsConfig
+	// ChaincodePackageSigningRoots are the files holding the PEM encoded
+	// certificates which the signers of chaincode packages must chain to.
+	ChaincodePackageSigningRoots []string
+	// ChaincodePackageSignaturesRequired rejects the installation of
+	// chaincode packages which are not signed.
+	ChaincodePackageSignaturesRequired bool
+
+	// ----- Kubernetes launcher config -----
+
+	// KubernetesEnabled enables launching chaincode packaged as container images
+	// in Kubernetes pods.
+	KubernetesEnabled bool
+	// KubernetesAPIServer is the URL of the Kubernetes API server. When empty,
+	// the in-cluster configuration of the pod of the peer is used.
+	KubernetesAPIServer string
+	// KubernetesTokenFile provides the path to the bearer token authenticating
+	// the peer to the Kubernetes API server.
+	KubernetesTokenFile string
+	// KubernetesCAFile provides the path to the PEM encoded CA certificates of
+	// the Kubernetes API server.
+	KubernetesCAFile string
+	// KubernetesNamespace is the namespace of the chaincode pods.
+	KubernetesNamespace string
+	// KubernetesServiceAccount is the service account of the chaincode pods.
+	KubernetesServiceAccount string
+	// KubernetesImagePullPolicy is the pull policy of the chaincode images.
+	KubernetesImagePullPolicy string
 
 	// ----- Operations config -----
 	// TODO: create separate sub-struct for Operations config.
@@ -176,6 +252,15 @@ type Config struct {
 	// OperationsTLSClientRootCAs provides the path to PEM encoded ca certiricates to
 	// trust for client authentication.
 	OperationsTLSClientRootCAs []string
+	// OperationsProfilingEnabled enables the capture of profiles through the
+	// operations endpoint, which then requires TLS client authentication.
+	OperationsProfilingEnabled bool
+	// OperationsProfilingMaxDuration is the longest CPU profile or execution
+	// trace that can be captured.
+	OperationsProfilingMaxDuration time.Duration
+	// OperationsProfilingMaxCaptures is the number of captures kept for
+	// retrieval.
+	OperationsProfilingMaxCaptures int
 
 	// ----- Metrics config -----
 	// TODO: create separate sub-struct for Metrics config.
@@ -193,6 +278,38 @@ type Config struct {
 	// StatsdPrefix provides the prefix that prepended to all emitted statsd metrics.
 	StatsdPrefix string
 
+	// ----- Tracing config -----
+
+	// TracingEndpoint is the OTLP/HTTP endpoint of the OpenTelemetry collector
+	// the spans of the peer are exported to. Tracing is disabled when empty.
+	TracingEndpoint string
+	// TracingServiceName is the service name the spans of the peer are reported under.
+	TracingServiceName string
+	// TracingSampleRate is the fraction of the transactions without trace
+	// context that are sampled.
+	TracingSampleRate float64
+
+	// ----- Audit config -----
+
+	// AuditFile is the file the security audit log of the peer is appended
+	// to. No audit log file is written when empty.
+	AuditFile string
+	// AuditStderr writes the security audit log of the peer to the standard
+	// error as well.
+	AuditStderr bool
+
+	// ----- Certificate monitor config -----
+
+	// CertificateMonitorInterval is how often the expiration of the
+	// certificates is checked.
+	CertificateMonitorInterval time.Duration
+	// CertificateMonitorThresholds are the days before the expiration of a
+	// certificate at which alerts are raised.
+	CertificateMonitorThresholds []int
+	// CertificateMonitorWebhook is the URL the alerts are posted to, in
+	// addition to being logged.
+	CertificateMonitorWebhook string
+
 	// ----- Docker config ------
 
 	// DockerCert is the path to the PEM encoded TLS client certificate required to access
@@ -238,6 +355,17 @@ func (c *Config) load() error {
 	c.NetworkID = viper.GetString("peer.networkId")
 	c.LimitsConcurrencyEndorserService = viper.GetInt("peer.limits.concurrency.endorserService")
 	c.LimitsConcurrencyDeliverService = viper.GetInt("peer.limits.concurrency.deliverService")
+	c.LimitsConcurrencyGossipService = viper.GetInt("peer.limits.concurrency.gossipService")
+	c.LimitsMaxRecvMsgSizeEndorserService = viper.GetInt("peer.limits.maxRecvMsgSize.endorserService")
+	c.LimitsMaxRecvMsgSizeDeliverService = viper.GetInt("peer.limits.maxRecvMsgSize.deliverService")
+	c.LimitsMaxRecvMsgSizeGossipService = viper.GetInt("peer.limits.maxRecvMsgSize.gossipService")
+	c.LimitsMaxSendMsgSizeEndorserService = viper.GetInt("peer.limits.maxSendMsgSize.endorserService")
+	c.LimitsMaxSendMsgSizeDeliverService = viper.GetInt("peer.limits.maxSendMsgSize.deliverService")
+	c.LimitsMaxSendMsgSizeGossipService = viper.GetInt("peer.limits.maxSendMsgSize.gossipService")
+	c.LimitsClientsScope = viper.GetString("peer.limits.clients.scope")
+	c.LimitsClientsProposalsPerSecond = uint32(viper.GetInt("peer.limits.clients.proposalsPerSecond"))
+	c.LimitsClientsConcurrency = viper.GetInt("peer.limits.clients.concurrency")
+	c.LimitsClientsMaxWait = viper.GetDuration("peer.limits.clients.maxWait")
 	c.DiscoveryEnabled = viper.GetBool("peer.discovery.enabled")
 	c.ProfileEnabled = viper.GetBool("peer.profile.enabled")
 	c.ProfileListenAddress = viper.GetString("peer.profile.listenAddress")
@@ -245,6 +373,8 @@ func (c *Config) load() error {
 	c.DiscoveryAuthCacheEnabled = viper.GetBool("peer.discovery.authCacheEnabled")
 	c.DiscoveryAuthCacheMaxSize = viper.GetInt("peer.discovery.authCacheMaxSize")
 	c.DiscoveryAuthCachePurgeRetentionRatio = viper.GetFloat64("peer.discovery.authCachePurgeRetentionRatio")
+	c.DiscoveryEndorsementCacheTTL = viper.GetDuration("peer.discovery.endorsementCacheTTL")
+	c.DiscoveryEndorsementCacheMaxSize = viper.GetInt("peer.discovery.endorsementCacheMaxSize")
 	c.ChaincodeListenAddress = viper.GetString("peer.chaincodeListenAddress")
 	c.ChaincodeAddress = viper.GetString("peer.chaincodeAddress")
 
@@ -286,6 +416,27 @@ func (c *Config) load() error {
 	}
 	c.ExternalBuilders = externalBuilders
 
+	c.ChaincodeResourceLimits, err = chaincodeResourceLimits()
+	if err != nil {
+		return err
+	}
+
+	for _, root := range viper.GetStringSlice("chaincode.packageSigning.roots") {
+		c.ChaincodePackageSigningRoots = append(c.ChaincodePackageSigningRoots, config.TranslatePath(configDir, root))
+	}
+	c.ChaincodePackageSignaturesRequired = viper.GetBool("chaincode.packageSigning.required")
+	if c.ChaincodePackageSignaturesRequired && len(c.ChaincodePackageSigningRoots) == 0 {
+		return errors.New("chaincode package signatures are required, but no signing roots are configured")
+	}
+
+	c.KubernetesEnabled = viper.GetBool("chaincode.kubernetes.enabled")
+	c.KubernetesAPIServer = viper.GetString("chaincode.kubernetes.apiServer")
+	c.KubernetesTokenFile = config.GetPath("chaincode.kubernetes.tokenFile")
+	c.KubernetesCAFile = config.GetPath("chaincode.kubernetes.caFile")
+	c.KubernetesNamespace = viper.GetString("chaincode.kubernetes.namespace")
+	c.KubernetesServiceAccount = viper.GetString("chaincode.kubernetes.serviceAccount")
+	c.KubernetesImagePullPolicy = viper.GetString("chaincode.kubernetes.imagePullPolicy")
+
 	c.OperationsListenAddress = viper.GetString("operations.listenAddress")
 	c.OperationsTLSEnabled = viper.GetBool("operations.tls.enabled")
 	c.OperationsTLSCertFile = config.GetPath("operations.tls.cert.file")
@@ -295,6 +446,9 @@ func (c *Config) load() error {
 	for _, rca := range viper.GetStringSlice("operations.tls.clientRootCAs.files") {
 		c.OperationsTLSClientRootCAs = append(c.OperationsTLSClientRootCAs, config.TranslatePath(configDir, rca))
 	}
+	c.OperationsProfilingEnabled = viper.GetBool("operations.profiling.enabled")
+	c.OperationsProfilingMaxDuration = viper.GetDuration("operations.profiling.maxDuration")
+	c.OperationsProfilingMaxCaptures = viper.GetInt("operations.profiling.maxCaptures")
 
 	c.MetricsProvider = viper.GetString("metrics.provider")
 	c.StatsdNetwork = viper.GetString("metrics.statsd.network")
@@ -302,6 +456,29 @@ func (c *Config) load() error {
 	c.StatsdWriteInterval = viper.GetDuration("metrics.statsd.writeInterval")
 	c.StatsdPrefix = viper.GetString("metrics.statsd.prefix")
 
+	c.TracingEndpoint = viper.GetString("tracing.endpoint")
+	c.TracingServiceName = viper.GetString("tracing.serviceName")
+	if c.TracingServiceName == "" {
+		c.TracingServiceName = "peer"
+	}
+	c.TracingSampleRate = viper.GetFloat64("tracing.sampleRate")
+
+	c.AuditFile = config.GetPath("audit.file")
+	c.AuditStderr = viper.GetBool("audit.stderr")
+
+	c.CertificateMonitorInterval = viper.GetDuration("certificateMonitor.interval")
+	if c.CertificateMonitorInterval == 0 {
+		c.CertificateMonitorInterval = time.Hour
+	}
+	for _, threshold := range viper.GetStringSlice("certificateMonitor.thresholds") {
+		days, err := strconv.Atoi(threshold)
+		if err != nil || days <= 0 {
+			return errors.Errorf("invalid certificate monitor threshold %q: it must be a positive number of days", threshold)
+		}
+		c.CertificateMonitorThresholds = append(c.CertificateMonitorThresholds, days)
+	}
+	c.CertificateMonitorWebhook = viper.GetString("certificateMonitor.webhook")
+
 	c.DockerCert = config.GetPath("vm.docker.tls.cert.file")
 	c.DockerKey = config.GetPath("vm.docker.tls.key.file")
 	c.DockerCA = config.GetPath("vm.docker.tls.ca.file")
@@ -309,6 +486,62 @@ func (c *Config) load() error {
 	return nil
 }
 
+// chaincodeResourceLimits reads the resource limits of chaincode, or returns nil
+// if none is configured.
+func chaincodeResourceLimits() (*ccintf.ResourceLimitsConfig, error) {
+	limits := &ccintf.ResourceLimitsConfig{
+		Default: ccintf.ResourceLimits{
+			CPUs:           viper.GetFloat64("chaincode.resourceLimits.cpus"),
+			Memory:         int64(viper.GetInt("chaincode.resourceLimits.memory")),
+			Pids:           int64(viper.GetInt("chaincode.resourceLimits.pids")),
+			ReadOnlyRootfs: viper.GetBool("chaincode.resourceLimits.readOnlyRootfs"),
+			SeccompProfile: viper.GetString("chaincode.resourceLimits.seccompProfile"),
+		},
+	}
+	if err := validateResourceLimits("default", limits.Default); err != nil {
+		return nil, err
+	}
+
+	var labels []ChaincodeResourceLimits
+	if err := viper.UnmarshalKey("chaincode.resourceLimits.labels", &labels); err != nil {
+		return nil, err
+	}
+	for _, l := range labels {
+		if l.Label == "" {
+			return nil, fmt.Errorf("invalid chaincode resource limits configuration, label attribute missing in one or more labels")
+		}
+		if _, ok := limits.Labels[l.Label]; ok {
+			return nil, fmt.Errorf("duplicate resource limits for chaincode label %s", l.Label)
+		}
+		override := ccintf.ResourceLimits{
+			CPUs:           l.CPUs,
+			Memory:         l.Memory,
+			Pids:           l.Pids,
+			ReadOnlyRootfs: l.ReadOnlyRootfs,
+			SeccompProfile: l.SeccompProfile,
+		}
+		if err := validateResourceLimits("chaincode label "+l.Label, override); err != nil {
+			return nil, err
+		}
+		if limits.Labels == nil {
+			limits.Labels = map[string]ccintf.ResourceLimits{}
+		}
+		limits.Labels[l.Label] = override
+	}
+
+	if limits.Default.IsZero() && len(limits.Labels) == 0 {
+		return nil, nil
+	}
+	return limits, nil
+}
+
+func validateResourceLimits(name string, limits ccintf.ResourceLimits) error {
+	if limits.CPUs < 0 || limits.Memory < 0 || limits.Pids < 0 {
+		return fmt.Errorf("invalid resource limits for %s, limits must not be negative", name)
+	}
+	return nil
+}
+
 // getLocalAddress returns the address:port the local peer is operating on.  Affected by env:peer.addressAutoDetect
 func getLocalAddress() (string, error) {
 	peerAddress := viper.GetString("peer.address")
@@ -364,6 +597,7 @@ func GetServerConfig() (comm.ServerConfig, error) {
 		serverConfig.SecOpts.Certificate = serverCert
 		serverConfig.SecOpts.Key = serverKey
 		serverConfig.SecOpts.RequireClientCert = viper.GetBool("peer.tls.clientAuthRequired")
+		serverConfig.SecOpts.SessionResumption = viper.GetBool("peer.tls.sessionResumption.enabled")
 		if serverConfig.SecOpts.RequireClientCert {
 			var clientRoots [][]byte
 			for _, file := range viper.GetStringSlice("peer.tls.clientRootCAs.files") {
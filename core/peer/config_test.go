@@ -15,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hyperledger/fabric/core/container/ccintf"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -247,6 +248,17 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.networkId", "testNetwork")
 	viper.Set("peer.limits.concurrency.endorserService", 2500)
 	viper.Set("peer.limits.concurrency.deliverService", 2500)
+	viper.Set("peer.limits.concurrency.gossipService", 100)
+	viper.Set("peer.limits.maxRecvMsgSize.endorserService", 1048576)
+	viper.Set("peer.limits.maxRecvMsgSize.deliverService", 4096)
+	viper.Set("peer.limits.maxRecvMsgSize.gossipService", 10485760)
+	viper.Set("peer.limits.maxSendMsgSize.endorserService", 2097152)
+	viper.Set("peer.limits.maxSendMsgSize.deliverService", 104857600)
+	viper.Set("peer.limits.maxSendMsgSize.gossipService", 10485760)
+	viper.Set("peer.limits.clients.scope", "Org")
+	viper.Set("peer.limits.clients.proposalsPerSecond", 100)
+	viper.Set("peer.limits.clients.concurrency", 50)
+	viper.Set("peer.limits.clients.maxWait", "5s")
 	viper.Set("peer.discovery.enabled", true)
 	viper.Set("peer.profile.enabled", false)
 	viper.Set("peer.profile.listenAddress", "peer.authentication.timewindow")
@@ -254,6 +266,8 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.discovery.authCacheEnabled", true)
 	viper.Set("peer.discovery.authCacheMaxSize", 1000)
 	viper.Set("peer.discovery.authCachePurgeRetentionRatio", 0.75)
+	viper.Set("peer.discovery.endorsementCacheTTL", "5s")
+	viper.Set("peer.discovery.endorsementCacheMaxSize", 500)
 	viper.Set("peer.chaincodeListenAddress", "0.0.0.0:7052")
 	viper.Set("peer.chaincodeAddress", "0.0.0.0:7052")
 	viper.Set("peer.validatorPoolSize", 1)
@@ -272,6 +286,9 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("operations.tls.key.file", "test/tls/key/file")
 	viper.Set("operations.tls.clientAuthRequired", false)
 	viper.Set("operations.tls.clientRootCAs.files", []string{"relative/file1", "/absolute/file2"})
+	viper.Set("operations.profiling.enabled", true)
+	viper.Set("operations.profiling.maxDuration", "2m")
+	viper.Set("operations.profiling.maxCaptures", 4)
 
 	viper.Set("metrics.provider", "disabled")
 	viper.Set("metrics.statsd.network", "udp")
@@ -279,6 +296,17 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("metrics.statsd.writeInterval", "10s")
 	viper.Set("metrics.statsd.prefix", "testPrefix")
 
+	viper.Set("tracing.endpoint", "http://localhost:4318")
+	viper.Set("tracing.serviceName", "peer0")
+	viper.Set("tracing.sampleRate", 0.5)
+
+	viper.Set("audit.file", "relative/audit.log")
+	viper.Set("audit.stderr", true)
+
+	viper.Set("certificateMonitor.interval", "30m")
+	viper.Set("certificateMonitor.thresholds", []interface{}{60, 14})
+	viper.Set("certificateMonitor.webhook", "https://alerts.example.com/hooks/certificates")
+
 	viper.Set("chaincode.pull", false)
 	viper.Set("chaincode.externalBuilders", &[]ExternalBuilder{
 		{
@@ -291,6 +319,31 @@ func TestGlobalConfig(t *testing.T) {
 		},
 	})
 
+	viper.Set("chaincode.resourceLimits.cpus", 0.5)
+	viper.Set("chaincode.resourceLimits.memory", 268435456)
+	viper.Set("chaincode.resourceLimits.pids", 100)
+	viper.Set("chaincode.resourceLimits.readOnlyRootfs", true)
+	viper.Set("chaincode.resourceLimits.seccompProfile", "/etc/hyperledger/fabric/seccomp.json")
+	viper.Set("chaincode.resourceLimits.labels", &[]ChaincodeResourceLimits{
+		{
+			Label:          "analytics",
+			CPUs:           2,
+			Memory:         1073741824,
+			SeccompProfile: "unconfined",
+		},
+	})
+
+	viper.Set("chaincode.packageSigning.roots", []string{"relative/signing-ca.pem", "/absolute/signing-ca.pem"})
+	viper.Set("chaincode.packageSigning.required", true)
+
+	viper.Set("chaincode.kubernetes.enabled", true)
+	viper.Set("chaincode.kubernetes.apiServer", "https://kubernetes:6443")
+	viper.Set("chaincode.kubernetes.tokenFile", "test/kubernetes/token")
+	viper.Set("chaincode.kubernetes.caFile", "/absolute/kubernetes/ca.crt")
+	viper.Set("chaincode.kubernetes.namespace", "fabric")
+	viper.Set("chaincode.kubernetes.serviceAccount", "chaincode")
+	viper.Set("chaincode.kubernetes.imagePullPolicy", "Always")
+
 	coreConfig, err := GlobalConfig()
 	assert.NoError(t, err)
 
@@ -304,6 +357,17 @@ func TestGlobalConfig(t *testing.T) {
 		NetworkID:                             "testNetwork",
 		LimitsConcurrencyEndorserService:      2500,
 		LimitsConcurrencyDeliverService:       2500,
+		LimitsConcurrencyGossipService:        100,
+		LimitsMaxRecvMsgSizeEndorserService:   1048576,
+		LimitsMaxRecvMsgSizeDeliverService:    4096,
+		LimitsMaxRecvMsgSizeGossipService:     10485760,
+		LimitsMaxSendMsgSizeEndorserService:   2097152,
+		LimitsMaxSendMsgSizeDeliverService:    104857600,
+		LimitsMaxSendMsgSizeGossipService:     10485760,
+		LimitsClientsScope:                    "Org",
+		LimitsClientsProposalsPerSecond:       100,
+		LimitsClientsConcurrency:              50,
+		LimitsClientsMaxWait:                  5 * time.Second,
 		DiscoveryEnabled:                      true,
 		ProfileEnabled:                        false,
 		ProfileListenAddress:                  "peer.authentication.timewindow",
@@ -311,6 +375,8 @@ func TestGlobalConfig(t *testing.T) {
 		DiscoveryAuthCacheEnabled:             true,
 		DiscoveryAuthCacheMaxSize:             1000,
 		DiscoveryAuthCachePurgeRetentionRatio: 0.75,
+		DiscoveryEndorsementCacheTTL:          5 * time.Second,
+		DiscoveryEndorsementCacheMaxSize:      500,
 		ChaincodeListenAddress:                "0.0.0.0:7052",
 		ChaincodeAddress:                      "0.0.0.0:7052",
 		ValidatorPoolSize:                     1,
@@ -332,6 +398,36 @@ func TestGlobalConfig(t *testing.T) {
 				Name: "absolute",
 			},
 		},
+		ChaincodeResourceLimits: &ccintf.ResourceLimitsConfig{
+			Default: ccintf.ResourceLimits{
+				CPUs:           0.5,
+				Memory:         268435456,
+				Pids:           100,
+				ReadOnlyRootfs: true,
+				SeccompProfile: "/etc/hyperledger/fabric/seccomp.json",
+			},
+			Labels: map[string]ccintf.ResourceLimits{
+				"analytics": {
+					CPUs:           2,
+					Memory:         1073741824,
+					SeccompProfile: "unconfined",
+				},
+			},
+		},
+		ChaincodePackageSigningRoots: []string{
+			filepath.Join(cwd, "relative/signing-ca.pem"),
+			"/absolute/signing-ca.pem",
+		},
+		ChaincodePackageSignaturesRequired: true,
+
+		KubernetesEnabled:         true,
+		KubernetesAPIServer:       "https://kubernetes:6443",
+		KubernetesTokenFile:       filepath.Join(cwd, "test/kubernetes/token"),
+		KubernetesCAFile:          "/absolute/kubernetes/ca.crt",
+		KubernetesNamespace:       "fabric",
+		KubernetesServiceAccount:  "chaincode",
+		KubernetesImagePullPolicy: "Always",
+
 		OperationsListenAddress:         "127.0.0.1:9443",
 		OperationsTLSEnabled:            false,
 		OperationsTLSCertFile:           filepath.Join(cwd, "test/tls/cert/file"),
@@ -341,6 +437,9 @@ func TestGlobalConfig(t *testing.T) {
 			filepath.Join(cwd, "relative", "file1"),
 			"/absolute/file2",
 		},
+		OperationsProfilingEnabled:     true,
+		OperationsProfilingMaxDuration: 2 * time.Minute,
+		OperationsProfilingMaxCaptures: 4,
 
 		MetricsProvider:     "disabled",
 		StatsdNetwork:       "udp",
@@ -348,6 +447,17 @@ func TestGlobalConfig(t *testing.T) {
 		StatsdWriteInterval: 10 * time.Second,
 		StatsdPrefix:        "testPrefix",
 
+		TracingEndpoint:    "http://localhost:4318",
+		TracingServiceName: "peer0",
+		TracingSampleRate:  0.5,
+
+		AuditFile:   filepath.Join(cwd, "relative", "audit.log"),
+		AuditStderr: true,
+
+		CertificateMonitorInterval:   30 * time.Minute,
+		CertificateMonitorThresholds: []int{60, 14},
+		CertificateMonitorWebhook:    "https://alerts.example.com/hooks/certificates",
+
 		DockerCert: filepath.Join(cwd, "test/vm/tls/cert/file"),
 		DockerKey:  filepath.Join(cwd, "test/vm/tls/key/file"),
 		DockerCA:   filepath.Join(cwd, "test/vm/tls/ca/file"),
@@ -369,11 +479,21 @@ func TestGlobalConfigDefault(t *testing.T) {
 		ValidatorPoolSize:             runtime.NumCPU(),
 		VMNetworkMode:                 "host",
 		DeliverClientKeepaliveOptions: comm.DefaultKeepaliveOptions,
+		TracingServiceName:            "peer",
+		CertificateMonitorInterval:    time.Hour,
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
 }
 
+func TestInvalidCertificateMonitorThreshold(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("peer.address", "localhost:8080")
+	viper.Set("certificateMonitor.thresholds", []string{"30", "a week"})
+	_, err := GlobalConfig()
+	assert.EqualError(t, err, `invalid certificate monitor threshold "a week": it must be a positive number of days`)
+}
+
 func TestMissingExternalBuilderPath(t *testing.T) {
 	defer viper.Reset()
 	viper.Set("peer.address", "localhost:8080")
@@ -397,3 +517,32 @@ func TestMissingExternalBuilderName(t *testing.T) {
 	_, err := GlobalConfig()
 	assert.EqualError(t, err, "external builder at path relative/plugin_dir has no name attribute")
 }
+
+func TestInvalidChaincodeResourceLimits(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("peer.address", "localhost:8080")
+	viper.Set("chaincode.resourceLimits.memory", -1)
+	_, err := GlobalConfig()
+	assert.EqualError(t, err, "invalid resource limits for default, limits must not be negative")
+
+	viper.Set("chaincode.resourceLimits.memory", 0)
+	viper.Set("chaincode.resourceLimits.labels", &[]ChaincodeResourceLimits{{CPUs: 1}})
+	_, err = GlobalConfig()
+	assert.EqualError(t, err, "invalid chaincode resource limits configuration, label attribute missing in one or more labels")
+
+	viper.Set("chaincode.resourceLimits.labels", &[]ChaincodeResourceLimits{{Label: "cc", CPUs: 1}, {Label: "cc", Pids: 10}})
+	_, err = GlobalConfig()
+	assert.EqualError(t, err, "duplicate resource limits for chaincode label cc")
+
+	viper.Set("chaincode.resourceLimits.labels", &[]ChaincodeResourceLimits{{Label: "cc", Pids: -10}})
+	_, err = GlobalConfig()
+	assert.EqualError(t, err, "invalid resource limits for chaincode label cc, limits must not be negative")
+}
+
+func TestChaincodePackageSignaturesRequiredWithoutRoots(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("peer.address", "localhost:8080")
+	viper.Set("chaincode.packageSigning.required", true)
+	_, err := GlobalConfig()
+	assert.EqualError(t, err, "chaincode package signatures are required, but no signing roots are configured")
+}
@@ -90,6 +90,10 @@ func (brs *blockResponseSender) DataType() string {
 // filteredBlockResponseSender structure used to send filtered block responses
 type filteredBlockResponseSender struct {
 	peer.Deliver_DeliverFilteredServer
+
+	// request is the payload of the deliver request the filter was parsed from
+	request string
+	filter  *chaincodeEventsFilter
 }
 
 // SendStatusResponse generates status reply proto message
@@ -120,6 +124,22 @@ func (fbrs *filteredBlockResponseSender) SendBlockResponse(
 		logger.Warningf("Failed to generate filtered block due to: %s", err)
 		return fbrs.SendStatusResponse(common.Status_BAD_REQUEST)
 	}
+
+	if signedData != nil && string(signedData.Data) != fbrs.request {
+		fbrs.filter, err = parseChaincodeEventsFilter(signedData.Data)
+		if err != nil {
+			logger.Warningf("Invalid chaincode events filter: %s", err)
+			if err := fbrs.SendStatusResponse(common.Status_BAD_REQUEST); err != nil {
+				return err
+			}
+			return errors.WithMessage(err, "invalid chaincode events filter")
+		}
+		fbrs.request = string(signedData.Data)
+	}
+	if fbrs.filter != nil {
+		fbrs.filter.apply(filteredBlock)
+	}
+
 	response := &peer.DeliverResponse{
 		Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: filteredBlock},
 	}
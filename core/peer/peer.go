@@ -8,11 +8,13 @@ package peer
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/hyperledger/fabric-protos-go/common"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/audit"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	cc "github.com/hyperledger/fabric/common/config"
 	"github.com/hyperledger/fabric/common/configtx"
@@ -21,6 +23,7 @@ import (
 	commonledger "github.com/hyperledger/fabric/common/ledger"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/common/semaphore"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/committer"
 	"github.com/hyperledger/fabric/core/committer/txvalidator"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/plugin"
@@ -101,16 +104,10 @@ func (p *Peer) updateTrustedRoots(cm channelconfig.Resources) {
 
 	p.CredentialSupport.BuildTrustedRootsForChain(cm)
 
-	// now iterate over all roots for all app and orderer channels
-	var trustedRoots [][]byte
-	for _, roots := range p.CredentialSupport.AppRootCAsByChain() {
-		trustedRoots = append(trustedRoots, roots...)
-	}
-	trustedRoots = append(trustedRoots, p.ServerConfig.SecOpts.ClientRootCAs...)
-	trustedRoots = append(trustedRoots, p.ServerConfig.SecOpts.ServerRootCAs...)
-
 	// now update the client roots for the peerServer
-	err := p.server.SetClientRootCAs(trustedRoots)
+	p.tlsRootCAsMutex.Lock()
+	err := p.updateClientRootCAs()
+	p.tlsRootCAsMutex.Unlock()
 	if err != nil {
 		msg := "Failed to update trusted roots from latest config block. " +
 			"This peer may not be able to communicate with members of channel %s (%s)"
@@ -172,6 +169,33 @@ func (c *configSupport) GetChannelConfig(cid string) cc.Config {
 	return channel.Resources().ConfigtxValidator()
 }
 
+// SetTLSRootCAs replaces the root CAs, besides those of the channels, the
+// peer verifies the TLS certificates of servers and clients with.
+func (p *Peer) SetTLSRootCAs(serverRootCAs, clientRootCAs [][]byte) error {
+	p.tlsRootCAsMutex.Lock()
+	defer p.tlsRootCAsMutex.Unlock()
+
+	p.ServerConfig.SecOpts.ServerRootCAs = serverRootCAs
+	p.ServerConfig.SecOpts.ClientRootCAs = clientRootCAs
+	p.CredentialSupport.SetServerRootCAs(serverRootCAs)
+	return p.updateClientRootCAs()
+}
+
+// updateClientRootCAs updates the root CAs the peer server verifies clients
+// with to the roots of all the channels and the roots the peer is configured
+// with. It must be called with the tlsRootCAsMutex held.
+func (p *Peer) updateClientRootCAs() error {
+	// iterate over all roots for all app and orderer channels
+	var trustedRoots [][]byte
+	for _, roots := range p.CredentialSupport.AppRootCAsByChain() {
+		trustedRoots = append(trustedRoots, roots...)
+	}
+	trustedRoots = append(trustedRoots, p.ServerConfig.SecOpts.ClientRootCAs...)
+	trustedRoots = append(trustedRoots, p.ServerConfig.SecOpts.ServerRootCAs...)
+
+	return p.server.SetClientRootCAs(trustedRoots)
+}
+
 // A Peer holds references to subsystems and channels associated with a Fabric peer.
 type Peer struct {
 	ServerConfig             comm.ServerConfig
@@ -181,6 +205,8 @@ type Peer struct {
 	LedgerMgr                *ledgermgmt.LedgerMgr
 	OrdererEndpointOverrides map[string]*orderers.Endpoint
 	CryptoProvider           bccsp.BCCSP
+	Tracer                   *tracing.Tracer
+	AuditLog                 *audit.Log
 
 	// validationWorkersSemaphore is used to limit the number of concurrent validation
 	// go routines.
@@ -190,6 +216,9 @@ type Peer struct {
 	pluginMapper       plugin.Mapper
 	channelInitializer func(cid string)
 
+	// tlsRootCAsMutex guards the root CAs of the ServerConfig
+	tlsRootCAsMutex sync.Mutex
+
 	// channels is a map of channelID to channel
 	mutex    sync.RWMutex
 	channels map[string]*Channel
@@ -314,6 +343,21 @@ func (p *Peer) createChannel(
 		ordererSource.Update(globalAddresses, orgAddresses)
 	}
 
+	// the callbacks are invoked with the current configuration of the channel
+	// first, which is not an update
+	initialSequence := bundle.ConfigtxValidator().Sequence()
+	auditCallback := func(bundle *channelconfig.Bundle) {
+		sequence := bundle.ConfigtxValidator().Sequence()
+		if sequence <= initialSequence {
+			return
+		}
+		p.AuditLog.Record(audit.Event{
+			Type:       audit.ConfigUpdate,
+			Channel:    cid,
+			Attributes: map[string]string{"sequence": strconv.FormatUint(sequence, 10)},
+		})
+	}
+
 	channel := &Channel{
 		ledger:         l,
 		resources:      bundle,
@@ -326,6 +370,7 @@ func (p *Peer) createChannel(
 		gossipCallbackWrapper,
 		trustedRootsCallbackWrapper,
 		mspCallback,
+		auditCallback,
 		channel.bundleUpdate,
 	)
 
@@ -374,6 +419,7 @@ func (p *Peer) createChannel(
 			return mspmgmt.GetManagerForChain(chainID)
 		}),
 		CapabilityProvider: channel,
+		Tracer:             p.Tracer,
 	})
 
 	p.mutex.Lock()
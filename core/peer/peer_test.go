@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package peer
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -17,6 +18,8 @@ import (
 
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	configtxtest "github.com/hyperledger/fabric/common/configtx/test"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/plugin"
@@ -218,6 +221,42 @@ func TestCreateChannel(t *testing.T) {
 	}
 }
 
+func TestCreateChannelAuditsConfigUpdates(t *testing.T) {
+	peerInstance, cleanup := NewTestPeer(t)
+	defer cleanup()
+	buffer := &bytes.Buffer{}
+	peerInstance.AuditLog = audit.NewLog(nil, audit.NewWriterSink(buffer))
+	peerInstance.Initialize(
+		nil,
+		nil,
+		plugin.MapBasedMapper(map[string]validation.PluginFactory{}),
+		&ledgermocks.DeployedChaincodeInfoProvider{},
+		nil,
+		nil,
+		runtime.NumCPU(),
+	)
+
+	testChannelID := fmt.Sprintf("mytestchannelid-%d", rand.Int())
+	block, err := configtxtest.MakeGenesisBlock(testChannelID)
+	require.NoError(t, err)
+	err = peerInstance.CreateChannel(testChannelID, block, &mock.DeployedChaincodeInfoProvider{}, nil, nil)
+	require.NoError(t, err)
+	assert.Zero(t, buffer.Len(), "the initial configuration is not an update")
+
+	config, err := retrievePersistedChannelConfig(peerInstance.GetLedger(testChannelID))
+	require.NoError(t, err)
+	config.Sequence = 1
+	bundle, err := channelconfig.NewBundle(testChannelID, config, peerInstance.CryptoProvider)
+	require.NoError(t, err)
+	peerInstance.Channel(testChannelID).bundleSource.Update(bundle)
+
+	record, err := audit.Verify(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, audit.ConfigUpdate, record.Type)
+	assert.Equal(t, testChannelID, record.Channel)
+	assert.Equal(t, map[string]string{"sequence": "1"}, record.Attributes)
+}
+
 func TestDeliverSupportManager(t *testing.T) {
 	peerInstance, cleanup := NewTestPeer(t)
 	defer cleanup()
@@ -22,6 +22,7 @@ import (
 	mspproto "github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	configtxtest "github.com/hyperledger/fabric/common/configtx/test"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
 	"github.com/hyperledger/fabric/core/ledger/mock"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
@@ -319,3 +320,59 @@ func TestUpdateRootsFromConfigBlock(t *testing.T) {
 		})
 	}
 }
+
+func TestSetTLSRootCAs(t *testing.T) {
+	serverCA, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	clientCA, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	serverKP, err := serverCA.NewServerCertKeyPair("localhost")
+	require.NoError(t, err)
+	clientKP, err := clientCA.NewClientCertKeyPair()
+	require.NoError(t, err)
+
+	serverConfig := comm.ServerConfig{
+		SecOpts: comm.SecureOptions{
+			UseTLS:            true,
+			Certificate:       serverKP.Cert,
+			Key:               serverKP.Key,
+			RequireClientCert: true,
+			ServerRootCAs:     [][]byte{serverCA.CertBytes()},
+			ClientRootCAs:     [][]byte{serverCA.CertBytes()},
+		},
+	}
+	server, err := comm.NewGRPCServer("localhost:0", serverConfig)
+	require.NoError(t, err)
+	testpb.RegisterTestServiceServer(server.Server(), &testServiceServer{})
+	go server.Start()
+	defer server.Stop()
+
+	peerInstance := &peer.Peer{
+		ServerConfig:      serverConfig,
+		CredentialSupport: comm.NewCredentialSupport(serverCA.CertBytes()),
+	}
+	peerInstance.SetServer(server)
+
+	_, port, err := net.SplitHostPort(server.Listener().Addr().String())
+	require.NoError(t, err)
+	testAddress := "localhost:" + port
+
+	clientCert, err := tls.X509KeyPair(clientKP.Cert, clientKP.Key)
+	require.NoError(t, err)
+	rootCAs, err := createCertPool([][]byte{serverCA.CertBytes()})
+	require.NoError(t, err)
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      rootCAs,
+	}))}
+
+	_, err = invokeEmptyCall(testAddress, dialOptions)
+	assert.Error(t, err, "Expected error invoking the EmptyCall service with an untrusted client certificate")
+
+	err = peerInstance.SetTLSRootCAs([][]byte{serverCA.CertBytes()}, [][]byte{clientCA.CertBytes()})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{clientCA.CertBytes()}, peerInstance.ServerConfig.SecOpts.ClientRootCAs)
+
+	_, err = invokeEmptyCall(testAddress, dialOptions)
+	assert.NoError(t, err, "Failed to invoke the EmptyCall service")
+}
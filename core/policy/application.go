@@ -70,6 +70,7 @@ type ChannelPolicyReferenceProvider interface {
 type ApplicationPolicyEvaluator struct {
 	signaturePolicyProvider        SignaturePolicyProvider
 	channelPolicyReferenceProvider ChannelPolicyReferenceProvider
+	deserializer                   msp.IdentityDeserializer
 }
 
 // Manager defines functions to interface with the policy manager of a channel
@@ -119,6 +120,7 @@ func New(deserializer msp.IdentityDeserializer, channel string, channelPolicyMan
 	}
 
 	return &ApplicationPolicyEvaluator{
+		deserializer:            deserializer,
 		signaturePolicyProvider: &cauthdsl.EnvelopeBasedPolicyProvider{Deserializer: deserializer},
 		channelPolicyReferenceProvider: &ChannelPolicyReferenceProviderImpl{Manager: &dynamicPolicyManager{
 			channelID:                  channel,
@@ -142,6 +144,12 @@ func (a *ApplicationPolicyEvaluator) evaluateChannelConfigPolicyReference(channe
 		return errors.WithMessage(err, "could not create evaluator for channel reference policy")
 	}
 
+	if a.deserializer != nil {
+		// the signatures are verified with the identities obtained from the deserializer of the evaluator, as for
+		// the signature policies, so that the identities can skip the signatures already verified, e.g., in a batch
+		// during the validation of a block. Both the deserializers resolve the identities via the channel MSPs
+		return p.EvaluateIdentities(policies.SignatureSetToValidIdentities(signatureSet, a.deserializer))
+	}
 	return p.EvaluateSignedData(signatureSet)
 }
 
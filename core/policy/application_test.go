@@ -145,3 +145,32 @@ func TestChannelPolicyReference(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to retrieve policy for reference")
 }
+
+func TestChannelPolicyReferenceWithDeserializer(t *testing.T) {
+	idds := &mocks.IdentityDeserializer{}
+	goodID := &mocks.Identity{}
+	badID := &mocks.Identity{}
+	idds.On("DeserializeIdentity", []byte("good")).Return(goodID, nil)
+	idds.On("DeserializeIdentity", []byte("bad")).Return(badID, nil)
+	goodID.On("GetIdentifier").Return(&msp.IdentityIdentifier{Id: "good", Mspid: "msp"})
+	badID.On("GetIdentifier").Return(&msp.IdentityIdentifier{Id: "bad", Mspid: "msp"})
+	goodID.On("Verify", []byte("data"), []byte("sig")).Return(nil)
+	badID.On("Verify", []byte("data"), []byte("sig")).Return(errors.New("invalid signature"))
+
+	mcpmg := &mocks.ChannelPolicyManagerGetter{}
+	mm := &mocks.PolicyManager{}
+	mcpmg.On("Manager", "channel").Return(mm, true)
+	ape, err := New(idds, "channel", mcpmg)
+	assert.NoError(t, err)
+
+	// the signatures are verified with the identities of the deserializer of the evaluator
+	mp := &mocks.Policy{}
+	mp.On("EvaluateIdentities", []msp.Identity{goodID}).Return(nil)
+	mm.On("GetPolicy", "Endorsement").Return(mp, true)
+	err = ape.evaluateChannelConfigPolicyReference("Endorsement", []*protoutil.SignedData{
+		{Identity: []byte("good"), Data: []byte("data"), Signature: []byte("sig")},
+		{Identity: []byte("bad"), Data: []byte("data"), Signature: []byte("sig")},
+	})
+	assert.NoError(t, err)
+	mp.AssertNotCalled(t, "EvaluateSignedData", mock.Anything)
+}
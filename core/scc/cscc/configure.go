@@ -19,6 +19,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/common"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/audit"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/config"
 	"github.com/hyperledger/fabric/common/flogging"
@@ -173,7 +174,15 @@ func (e *PeerConfiger) InvokeNoShim(args [][]byte, sp *pb.SignedProposal) pb.Res
 			block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = txsFilter
 		}
 
-		return e.joinChain(cid, block, e.deployedCCInfoProvider, e.legacyLifecycle, e.newLifecycle)
+		resp := e.joinChain(cid, block, e.deployedCCInfoProvider, e.legacyLifecycle, e.newLifecycle)
+		if resp.Status == shim.OK {
+			e.peer.AuditLog.Record(audit.Event{
+				Type:    audit.ChannelJoin,
+				Actor:   audit.Actor(proposalCreator(sp)),
+				Channel: cid,
+			})
+		}
+		return resp
 	case GetConfigBlock:
 		// 2. check policy
 		if err = e.aclProvider.CheckACL(resources.Cscc_GetConfigBlock, string(args[1]), sp); err != nil {
@@ -193,6 +202,24 @@ func (e *PeerConfiger) InvokeNoShim(args [][]byte, sp *pb.SignedProposal) pb.Res
 	return shim.Error(fmt.Sprintf("Requested function %s not found.", fname))
 }
 
+// proposalCreator returns the serialized identity of the creator of a signed
+// proposal, or nil if the proposal is malformed.
+func proposalCreator(sp *pb.SignedProposal) []byte {
+	prop, err := protoutil.UnmarshalProposal(sp.ProposalBytes)
+	if err != nil {
+		return nil
+	}
+	hdr, err := protoutil.UnmarshalHeader(prop.Header)
+	if err != nil {
+		return nil
+	}
+	shdr, err := protoutil.UnmarshalSignatureHeader(hdr.SignatureHeader)
+	if err != nil {
+		return nil
+	}
+	return shdr.Creator
+}
+
 // validateConfigBlock validate configuration block to see whenever it's contains valid config transaction
 func validateConfigBlock(block *common.Block, bccsp bccsp.BCCSP) error {
 	envelopeConfig, err := protoutil.ExtractEnvelope(block, 0)
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package cscc
 
 import (
+	"bytes"
 	"errors"
 	"io/ioutil"
 	"net"
@@ -20,6 +21,7 @@ import (
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/audit"
 	configtxtest "github.com/hyperledger/fabric/common/configtx/test"
 	"github.com/hyperledger/fabric/common/genesis"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
@@ -325,6 +327,7 @@ func TestConfigerInvokeJoinChainCorrectParams(t *testing.T) {
 
 	// setup cscc instance
 	mockACLProvider := &mocks.ACLProvider{}
+	auditBuffer := &bytes.Buffer{}
 	cscc := &PeerConfiger{
 		policyChecker: &mocks.PolicyChecker{},
 		aclProvider:   mockACLProvider,
@@ -333,6 +336,7 @@ func TestConfigerInvokeJoinChainCorrectParams(t *testing.T) {
 			GossipService:  gossipService,
 			LedgerMgr:      ledgerMgr,
 			CryptoProvider: cryptoProvider,
+			AuditLog:       audit.NewLog(nil, audit.NewWriterSink(auditBuffer)),
 		},
 		bccsp: cryptoProvider,
 	}
@@ -370,12 +374,30 @@ func TestConfigerInvokeJoinChainCorrectParams(t *testing.T) {
 	//res = stub.MockInvokeWithSignedProposal("2", [][]byte{[]byte("JoinChain"), badBlockBytes}, sProp)
 	assert.Equal(t, int32(shim.ERROR), res.Status)
 
+	assert.Zero(t, auditBuffer.Len())
+
 	// Now, continue with valid execution path
+	creator, err := signer.Serialize()
+	require.NoError(t, err)
+	prop := &pb.Proposal{}
+	require.NoError(t, proto.Unmarshal(sProp.ProposalBytes, prop))
+	prop.Header = protoutil.MarshalOrPanic(&cb.Header{
+		SignatureHeader: protoutil.MarshalOrPanic(&cb.SignatureHeader{Creator: creator}),
+	})
+	sProp.ProposalBytes = protoutil.MarshalOrPanic(prop)
+	sProp.Signature = sProp.ProposalBytes
 	mockStub.GetArgsReturns(args)
 	mockStub.GetSignedProposalReturns(sProp, nil)
 	res = cscc.Invoke(mockStub)
 	assert.Equal(t, int32(shim.OK), res.Status, "invoke JoinChain failed with: %v", res.Message)
 
+	record, err := audit.Verify(auditBuffer)
+	require.NoError(t, err)
+	assert.Equal(t, audit.ChannelJoin, record.Type)
+	assert.Equal(t, "mytestchannelid", record.Channel)
+	assert.Equal(t, audit.Actor(creator), record.Actor)
+	assert.NotEmpty(t, record.Actor)
+
 	// This call must fail
 	sProp.Signature = nil
 	mockACLProvider.CheckACLReturns(errors.New("Failed authorization"))
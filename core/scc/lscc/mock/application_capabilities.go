@@ -66,6 +66,16 @@ type ApplicationCapabilities struct {
 	metadataLifecycleReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	PolicyTemplatesStub        func() bool
+	policyTemplatesMutex       sync.RWMutex
+	policyTemplatesArgsForCall []struct {
+	}
+	policyTemplatesReturns struct {
+		result1 bool
+	}
+	policyTemplatesReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	PrivateChannelDataStub        func() bool
 	privateChannelDataMutex       sync.RWMutex
 	privateChannelDataArgsForCall []struct {
@@ -452,6 +462,58 @@ func (fake *ApplicationCapabilities) MetadataLifecycleReturnsOnCall(i int, resul
 	}{result1}
 }
 
+func (fake *ApplicationCapabilities) PolicyTemplates() bool {
+	fake.policyTemplatesMutex.Lock()
+	ret, specificReturn := fake.policyTemplatesReturnsOnCall[len(fake.policyTemplatesArgsForCall)]
+	fake.policyTemplatesArgsForCall = append(fake.policyTemplatesArgsForCall, struct {
+	}{})
+	fake.recordInvocation("PolicyTemplates", []interface{}{})
+	fake.policyTemplatesMutex.Unlock()
+	if fake.PolicyTemplatesStub != nil {
+		return fake.PolicyTemplatesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.policyTemplatesReturns
+	return fakeReturns.result1
+}
+
+func (fake *ApplicationCapabilities) PolicyTemplatesCallCount() int {
+	fake.policyTemplatesMutex.RLock()
+	defer fake.policyTemplatesMutex.RUnlock()
+	return len(fake.policyTemplatesArgsForCall)
+}
+
+func (fake *ApplicationCapabilities) PolicyTemplatesCalls(stub func() bool) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = stub
+}
+
+func (fake *ApplicationCapabilities) PolicyTemplatesReturns(result1 bool) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = nil
+	fake.policyTemplatesReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *ApplicationCapabilities) PolicyTemplatesReturnsOnCall(i int, result1 bool) {
+	fake.policyTemplatesMutex.Lock()
+	defer fake.policyTemplatesMutex.Unlock()
+	fake.PolicyTemplatesStub = nil
+	if fake.policyTemplatesReturnsOnCall == nil {
+		fake.policyTemplatesReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.policyTemplatesReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *ApplicationCapabilities) PrivateChannelData() bool {
 	fake.privateChannelDataMutex.Lock()
 	ret, specificReturn := fake.privateChannelDataReturnsOnCall[len(fake.privateChannelDataArgsForCall)]
@@ -831,6 +893,8 @@ func (fake *ApplicationCapabilities) Invocations() map[string][][]interface{} {
 	defer fake.lifecycleV20Mutex.RUnlock()
 	fake.metadataLifecycleMutex.RLock()
 	defer fake.metadataLifecycleMutex.RUnlock()
+	fake.policyTemplatesMutex.RLock()
+	defer fake.policyTemplatesMutex.RUnlock()
 	fake.privateChannelDataMutex.RLock()
 	defer fake.privateChannelDataMutex.RUnlock()
 	fake.storePvtDataOfInvalidTxMutex.RLock()
@@ -51,6 +51,13 @@ type ChannelResponse interface {
 	// The given InvocationChain specifies the chaincode calls (along with collections)
 	// that the client passed during the construction of the request
 	Endorsers(invocationChain InvocationChain, f Filter) (Endorsers, error)
+
+	// Layouts returns the layouts of the endorsement descriptor for a given
+	// chaincode in a given channel context, or error if something went wrong.
+	// Each layout is returned along with the endorsers selected for it under the given
+	// constraints, and the score of the selection. The layouts are sorted from the best
+	// score to the worst, and layouts that can't be satisfied under the constraints are omitted.
+	Layouts(invocationChain InvocationChain, c Constraints) ([]*ScoredLayout, error)
 }
 
 // LocalResponse aggregates responses for a channel-less scope
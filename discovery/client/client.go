@@ -245,6 +245,24 @@ func (cr *channelResponse) Peers(invocationChain ...*discovery.ChaincodeCall) ([
 }
 
 func (cr *channelResponse) Endorsers(invocationChain InvocationChain, f Filter) (Endorsers, error) {
+	desc, err := cr.endorsementDescriptor(invocationChain)
+	if err != nil {
+		return nil, err
+	}
+
+	rand.Seed(time.Now().Unix())
+	// We iterate over all layouts to find one that we have enough peers to select
+	for _, index := range rand.Perm(len(desc.layouts)) {
+		layout := desc.layouts[index]
+		endorsers, canLayoutBeSatisfied := selectPeersForLayout(desc.endorsersByGroups, layout, f)
+		if canLayoutBeSatisfied {
+			return endorsers, nil
+		}
+	}
+	return nil, errors.New("no endorsement combination can be satisfied")
+}
+
+func (cr *channelResponse) endorsementDescriptor(invocationChain InvocationChain) (*endorsementDescriptor, error) {
 	// If we have a key that has no chaincode field,
 	// it means it's an error returned from the service
 	if err, exists := cr.response[key{
@@ -265,17 +283,7 @@ func (cr *channelResponse) Endorsers(invocationChain InvocationChain, f Filter)
 		return nil, ErrNotFound
 	}
 
-	desc := res.(*endorsementDescriptor)
-	rand.Seed(time.Now().Unix())
-	// We iterate over all layouts to find one that we have enough peers to select
-	for _, index := range rand.Perm(len(desc.layouts)) {
-		layout := desc.layouts[index]
-		endorsers, canLayoutBeSatisfied := selectPeersForLayout(desc.endorsersByGroups, layout, f)
-		if canLayoutBeSatisfied {
-			return endorsers, nil
-		}
-	}
-	return nil, errors.New("no endorsement combination can be satisfied")
+	return res.(*endorsementDescriptor), nil
 }
 
 type filter struct {
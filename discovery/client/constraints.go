@@ -0,0 +1,295 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/pkg/errors"
+)
+
+// Constraints constrain the selection of endorsers for the layouts
+// of an endorsement descriptor returned by the discovery service
+type Constraints struct {
+	// RequiredOrgs are the MSP IDs of organizations of which at least one peer must be selected.
+	// Peers of these organizations are selected over other peers of the same group, and layouts
+	// for which no peer of one of these organizations can be selected are discarded
+	RequiredOrgs []string
+	// ExcludedPeers are the endpoints of peers that are never selected
+	ExcludedPeers []string
+	// Labels maps endpoints of peers to their labels, such as the regions they reside in
+	Labels map[string]string
+	// PreferredLabel is the label of peers that are selected over peers
+	// without it, such as the region of the client
+	PreferredLabel string
+	// MaxLedgerLag is the maximum number of blocks the ledger of a selected peer may be behind
+	// the highest ledger among the endorsers of the chaincode. Zero means no limit.
+	MaxLedgerLag uint64
+	// Probe, if set, probes the endorsers of the chaincode before selecting them. Unreachable endorsers
+	// are never selected, and endorsers with a lower latency are selected over endorsers with a higher one.
+	Probe ProbeFunc
+	// ProbeTimeout is the maximum time probing the endorsers may take. Zero means no limit.
+	ProbeTimeout time.Duration
+
+	probes map[string]ProbeResult
+}
+
+// ScoredLayout is a layout of an endorsement descriptor,
+// along with the endorsers selected for it and their score
+type ScoredLayout struct {
+	// QuantitiesByGroup maps the groups of the layout to the number of endorsers required from them
+	QuantitiesByGroup map[string]int
+	// Endorsers are the endorsers selected for the layout
+	Endorsers Endorsers
+	// Score scores the selected endorsers
+	Score LayoutScore
+	// Probes maps the endpoints of the selected endorsers to the results of probing them,
+	// or is nil if the endorsers were not probed
+	Probes map[string]ProbeResult
+}
+
+// LayoutScore scores how well the endorsers selected for a layout fit the constraints
+type LayoutScore struct {
+	// Preferred is the number of selected endorsers that have the preferred label
+	Preferred int
+	// NotPreferred is the number of selected endorsers that don't have the preferred label,
+	// or zero if there is no preferred label
+	NotPreferred int
+	// MaxLedgerLag is the highest number of blocks the ledger of a selected endorser is behind
+	// the highest ledger among the endorsers of the chaincode
+	MaxLedgerLag uint64
+	// MaxLatency is the highest latency of probing a selected endorser,
+	// or zero if the endorsers were not probed
+	MaxLatency time.Duration
+	// Endorsers is the number of selected endorsers
+	Endorsers int
+}
+
+// Better returns whether the score is better than the given score, meaning it has fewer endorsers without
+// the preferred label, or else a lower ledger lag, or else a lower latency, or else fewer endorsers
+func (s LayoutScore) Better(o LayoutScore) bool {
+	if s.NotPreferred != o.NotPreferred {
+		return s.NotPreferred < o.NotPreferred
+	}
+	if s.MaxLedgerLag != o.MaxLedgerLag {
+		return s.MaxLedgerLag < o.MaxLedgerLag
+	}
+	if s.MaxLatency != o.MaxLatency {
+		return s.MaxLatency < o.MaxLatency
+	}
+	return s.Endorsers < o.Endorsers
+}
+
+func (cr *channelResponse) Layouts(invocationChain InvocationChain, c Constraints) ([]*ScoredLayout, error) {
+	desc, err := cr.endorsementDescriptor(invocationChain)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Probe != nil {
+		c.probes = c.probe(desc)
+	}
+
+	maxHeight := desc.maxLedgerHeight()
+	f := c.filter(maxHeight)
+	var layouts []*ScoredLayout
+	for _, layout := range desc.layouts {
+		endorsers, canLayoutBeSatisfied := selectPeersForLayout(desc.endorsersByGroups, layout, f)
+		if !canLayoutBeSatisfied || !c.includesRequiredOrgs(endorsers) {
+			continue
+		}
+		quantitiesByGroup := make(map[string]int, len(layout))
+		for grp, count := range layout {
+			quantitiesByGroup[grp] = count
+		}
+		layouts = append(layouts, &ScoredLayout{
+			QuantitiesByGroup: quantitiesByGroup,
+			Endorsers:         endorsers,
+			Score:             c.score(endorsers, maxHeight),
+			Probes:            c.probesOf(endorsers),
+		})
+	}
+	if len(layouts) == 0 && len(c.RequiredOrgs) != 0 {
+		return nil, errors.Errorf("no endorsement combination can be satisfied with peers of all the required organizations %v", c.RequiredOrgs)
+	}
+	if len(layouts) == 0 {
+		return nil, errors.New("no endorsement combination can be satisfied")
+	}
+
+	sort.SliceStable(layouts, func(i, j int) bool {
+		return layouts[i].Score.Better(layouts[j].Score)
+	})
+	return layouts, nil
+}
+
+// probe probes the endorsers of the given descriptor
+func (c Constraints) probe(desc *endorsementDescriptor) map[string]ProbeResult {
+	var endpoints []string
+	for _, endorsers := range desc.endorsersByGroups {
+		for _, e := range endorsers {
+			endpoints = append(endpoints, endpoint(*e))
+		}
+	}
+
+	ctx := context.Background()
+	if c.ProbeTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.ProbeTimeout)
+		defer cancel()
+	}
+	return Probe(ctx, endpoints, c.Probe)
+}
+
+func (c Constraints) probesOf(endorsers Endorsers) map[string]ProbeResult {
+	if c.probes == nil {
+		return nil
+	}
+	res := make(map[string]ProbeResult, len(endorsers))
+	for _, e := range endorsers {
+		ep := endpoint(*e)
+		res[ep] = c.probes[ep]
+	}
+	return res
+}
+
+// filter returns a Filter that excludes the peers the constraints exclude, and sorts peers of the required
+// organizations first, then peers with the preferred label, then by ascending latency if probed, then by
+// descending height
+func (c Constraints) filter(maxHeight uint64) Filter {
+	excludedHosts := ExcludeHosts(c.ExcludedPeers...)
+	exclusion := selectionFunc(func(p Peer) bool {
+		if excludedHosts.Exclude(p) {
+			return true
+		}
+		if c.probes != nil && !c.probes[endpoint(p)].Reachable {
+			return true
+		}
+		return c.MaxLedgerLag != 0 && ledgerLag(p, maxHeight) > c.MaxLedgerLag
+	})
+	return NewFilter(&byConstraints{Constraints: c}, exclusion)
+}
+
+func (c Constraints) score(endorsers Endorsers, maxHeight uint64) LayoutScore {
+	score := LayoutScore{Endorsers: len(endorsers)}
+	for _, e := range endorsers {
+		if c.PreferredLabel != "" {
+			if c.isPreferred(*e) {
+				score.Preferred++
+			} else {
+				score.NotPreferred++
+			}
+		}
+		if lag := ledgerLag(*e, maxHeight); lag > score.MaxLedgerLag {
+			score.MaxLedgerLag = lag
+		}
+		if latency := c.probes[endpoint(*e)].Latency; latency > score.MaxLatency {
+			score.MaxLatency = latency
+		}
+	}
+	return score
+}
+
+func (c Constraints) isRequired(p Peer) bool {
+	for _, mspID := range c.RequiredOrgs {
+		if p.MSPID == mspID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraints) includesRequiredOrgs(endorsers Endorsers) bool {
+	for _, mspID := range c.RequiredOrgs {
+		included := false
+		for _, e := range endorsers {
+			if e.MSPID == mspID {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraints) isPreferred(p Peer) bool {
+	if c.PreferredLabel == "" {
+		return false
+	}
+	if label, exists := c.Labels[endpoint(p)]; exists {
+		return label == c.PreferredLabel
+	}
+	se := p.AliveMessage.GetSecretEnvelope()
+	if se == nil {
+		return false
+	}
+	label, exists := c.Labels[protoext.InternalEndpoint(se)]
+	return exists && label == c.PreferredLabel
+}
+
+type byConstraints struct {
+	Constraints
+}
+
+func (bc *byConstraints) Compare(left Peer, right Peer) Priority {
+	leftRequired, rightRequired := bc.isRequired(left), bc.isRequired(right)
+	if leftRequired && !rightRequired {
+		return 1
+	}
+	if rightRequired && !leftRequired {
+		return -1
+	}
+	leftPreferred, rightPreferred := bc.isPreferred(left), bc.isPreferred(right)
+	if leftPreferred && !rightPreferred {
+		return 1
+	}
+	if rightPreferred && !leftPreferred {
+		return -1
+	}
+	if bc.probes != nil {
+		leftLatency, rightLatency := bc.probes[endpoint(left)].Latency, bc.probes[endpoint(right)].Latency
+		if leftLatency < rightLatency {
+			return 1
+		}
+		if rightLatency < leftLatency {
+			return -1
+		}
+	}
+	return PrioritiesByHeight.Compare(left, right)
+}
+
+func endpoint(p Peer) string {
+	return p.AliveMessage.GetAliveMsg().GetMembership().GetEndpoint()
+}
+
+func ledgerHeight(p Peer) uint64 {
+	return p.StateInfoMessage.GetStateInfo().GetProperties().GetLedgerHeight()
+}
+
+func ledgerLag(p Peer, maxHeight uint64) uint64 {
+	if height := ledgerHeight(p); height < maxHeight {
+		return maxHeight - height
+	}
+	return 0
+}
+
+func (ed *endorsementDescriptor) maxLedgerHeight() uint64 {
+	var maxHeight uint64
+	for _, endorsers := range ed.endorsersByGroups {
+		for _, e := range endorsers {
+			if height := ledgerHeight(*e); height > maxHeight {
+				maxHeight = height
+			}
+		}
+	}
+	return maxHeight
+}
@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/discovery/protoext"
+	gprotoext "github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayouts(t *testing.T) {
+	newPeer := func(i int, height uint64) *Peer {
+		am, err := gprotoext.EnvelopeToGossipMessage(aliveMessage(i))
+		require.NoError(t, err)
+		return &Peer{
+			StateInfoMessage: stateInfoWithHeight(height),
+			AliveMessage:     am,
+		}
+	}
+	p1, p2, p3, p4, p5 := newPeer(1, 10), newPeer(2, 8), newPeer(3, 4), newPeer(4, 10), newPeer(5, 10)
+	p1.MSPID, p2.MSPID, p3.MSPID, p4.MSPID, p5.MSPID = "Org1MSP", "Org1MSP", "Org3MSP", "Org2MSP", "Org2MSP"
+
+	cr := &channelResponse{
+		channel: "mychannel",
+		response: response{
+			key{
+				queryType:       protoext.ChaincodeQueryType,
+				k:               "mychannel",
+				invocationChain: InvocationChain(ccCall("mycc")).String(),
+			}: &endorsementDescriptor{
+				endorsersByGroups: map[string][]*Peer{
+					"G1": {p1, p2, p3},
+					"G2": {p4, p5},
+				},
+				layouts: []map[string]int{
+					{"G1": 3},
+					{"G1": 1, "G2": 1},
+					{"G1": 1},
+				},
+			},
+		},
+	}
+
+	t.Run("No constraints", func(t *testing.T) {
+		layouts, err := cr.Layouts(ccCall("mycc"), Constraints{})
+		require.NoError(t, err)
+		require.Len(t, layouts, 3)
+		assert.Equal(t, map[string]int{"G1": 1}, layouts[0].QuantitiesByGroup)
+		assert.Equal(t, Endorsers{p1}, layouts[0].Endorsers)
+		assert.Equal(t, LayoutScore{Endorsers: 1}, layouts[0].Score)
+		assert.Equal(t, map[string]int{"G1": 1, "G2": 1}, layouts[1].QuantitiesByGroup)
+		assert.Equal(t, LayoutScore{Endorsers: 2}, layouts[1].Score)
+		assert.Equal(t, map[string]int{"G1": 3}, layouts[2].QuantitiesByGroup)
+		assert.Equal(t, LayoutScore{MaxLedgerLag: 6, Endorsers: 3}, layouts[2].Score)
+	})
+
+	t.Run("Excluded peers and max ledger lag", func(t *testing.T) {
+		layouts, err := cr.Layouts(ccCall("mycc"), Constraints{
+			ExcludedPeers: []string{"p1"},
+			MaxLedgerLag:  3,
+		})
+		require.NoError(t, err)
+		require.Len(t, layouts, 2)
+		assert.Equal(t, Endorsers{p2}, layouts[0].Endorsers)
+		assert.Equal(t, LayoutScore{MaxLedgerLag: 2, Endorsers: 1}, layouts[0].Score)
+		assert.Equal(t, map[string]int{"G1": 1, "G2": 1}, layouts[1].QuantitiesByGroup)
+	})
+
+	t.Run("Preferred label", func(t *testing.T) {
+		layouts, err := cr.Layouts(ccCall("mycc"), Constraints{
+			Labels: map[string]string{
+				"p1": "east",
+				"p2": "west",
+				"p3": "east",
+				"p4": "west",
+				"p5": "east",
+			},
+			PreferredLabel: "east",
+		})
+		require.NoError(t, err)
+		require.Len(t, layouts, 3)
+		// Layouts for which only peers with the preferred label are selected come first
+		assert.Equal(t, Endorsers{p1}, layouts[0].Endorsers)
+		assert.Equal(t, LayoutScore{Preferred: 1, Endorsers: 1}, layouts[0].Score)
+		assert.ElementsMatch(t, Endorsers{p1, p5}, layouts[1].Endorsers)
+		assert.Equal(t, LayoutScore{Preferred: 2, Endorsers: 2}, layouts[1].Score)
+		assert.Equal(t, LayoutScore{Preferred: 2, NotPreferred: 1, MaxLedgerLag: 6, Endorsers: 3}, layouts[2].Score)
+	})
+
+	t.Run("Required organizations", func(t *testing.T) {
+		layouts, err := cr.Layouts(ccCall("mycc"), Constraints{
+			RequiredOrgs: []string{"Org2MSP"},
+		})
+		require.NoError(t, err)
+		require.Len(t, layouts, 1)
+		assert.Equal(t, map[string]int{"G1": 1, "G2": 1}, layouts[0].QuantitiesByGroup)
+		require.Len(t, layouts[0].Endorsers, 2)
+		assert.ElementsMatch(t, []string{"Org1MSP", "Org2MSP"}, []string{layouts[0].Endorsers[0].MSPID, layouts[0].Endorsers[1].MSPID})
+
+		// The peers of the required organizations are selected over the peers with a higher ledger
+		layouts, err = cr.Layouts(ccCall("mycc"), Constraints{
+			RequiredOrgs: []string{"Org3MSP"},
+		})
+		require.NoError(t, err)
+		require.Len(t, layouts, 3)
+		assert.Equal(t, Endorsers{p3}, layouts[0].Endorsers)
+		assert.Equal(t, LayoutScore{MaxLedgerLag: 6, Endorsers: 1}, layouts[0].Score)
+		assert.Contains(t, layouts[1].Endorsers, p3)
+
+		_, err = cr.Layouts(ccCall("mycc"), Constraints{
+			RequiredOrgs:  []string{"Org2MSP", "Org3MSP"},
+			ExcludedPeers: []string{"p3"},
+		})
+		assert.EqualError(t, err, "no endorsement combination can be satisfied with peers of all the required organizations [Org2MSP Org3MSP]")
+	})
+
+	t.Run("Probed endorsers", func(t *testing.T) {
+		latencies := map[string]time.Duration{
+			"p1": 30 * time.Millisecond,
+			"p2": 10 * time.Millisecond,
+			"p3": 20 * time.Millisecond,
+			"p5": 20 * time.Millisecond,
+		}
+		layouts, err := cr.Layouts(ccCall("mycc"), Constraints{
+			Probe: func(ctx context.Context, endpoint string) error {
+				latency, reachable := latencies[endpoint]
+				if !reachable {
+					return errors.Errorf("%s is unreachable", endpoint)
+				}
+				time.Sleep(latency)
+				return nil
+			},
+			ProbeTimeout: time.Second,
+		})
+		require.NoError(t, err)
+		require.Len(t, layouts, 3)
+		// The endorser with the lowest latency is selected over the ones with a higher ledger
+		assert.Equal(t, Endorsers{p2}, layouts[0].Endorsers)
+		assert.Equal(t, 1, layouts[0].Score.Endorsers)
+		assert.True(t, layouts[0].Probes["p2"].Reachable)
+		assert.True(t, layouts[0].Score.MaxLatency >= 10*time.Millisecond)
+		assert.Equal(t, uint64(2), layouts[0].Score.MaxLedgerLag)
+		// p4 is unreachable, so p5 is selected from G2
+		assert.ElementsMatch(t, Endorsers{p2, p5}, layouts[1].Endorsers)
+		assert.Len(t, layouts[1].Probes, 2)
+		assert.Len(t, layouts[2].Endorsers, 3)
+	})
+
+	t.Run("No layout can be satisfied", func(t *testing.T) {
+		_, err := cr.Layouts(ccCall("mycc"), Constraints{
+			ExcludedPeers: []string{"p1", "p2", "p3"},
+		})
+		assert.EqualError(t, err, "no endorsement combination can be satisfied")
+	})
+
+	t.Run("Unknown chaincode", func(t *testing.T) {
+		_, err := cr.Layouts(ccCall("othercc"), Constraints{})
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ProbeFunc probes the peer at the given endpoint, and returns an error if it is not reachable
+type ProbeFunc func(ctx context.Context, endpoint string) error
+
+// ProbeResult is the outcome of probing a peer
+type ProbeResult struct {
+	// Reachable is whether the probe succeeded
+	Reachable bool
+	// Latency is how long the probe took
+	Latency time.Duration
+	// Error is the reason the probe failed, if it failed
+	Error string
+}
+
+// Probe probes the given endpoints concurrently, and returns the results keyed by endpoint
+func Probe(ctx context.Context, endpoints []string, probe ProbeFunc) map[string]ProbeResult {
+	results := make(map[string]ProbeResult, len(endpoints))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		if _, exists := seen[endpoint]; exists {
+			continue
+		}
+		seen[endpoint] = struct{}{}
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			start := time.Now()
+			err := probe(ctx, endpoint)
+			res := ProbeResult{
+				Reachable: err == nil,
+				Latency:   time.Since(start),
+			}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			lock.Lock()
+			results[endpoint] = res
+			lock.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbe(t *testing.T) {
+	var probes int32
+	results := Probe(context.Background(), []string{"p1", "p2", "p1"}, func(ctx context.Context, endpoint string) error {
+		atomic.AddInt32(&probes, 1)
+		if endpoint == "p2" {
+			return errors.New("connection refused")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&probes))
+	assert.Len(t, results, 2)
+	assert.True(t, results["p1"].Reachable)
+	assert.Empty(t, results["p1"].Error)
+	assert.True(t, results["p1"].Latency >= 10*time.Millisecond)
+	assert.False(t, results["p2"].Reachable)
+	assert.Equal(t, "connection refused", results["p2"].Error)
+}
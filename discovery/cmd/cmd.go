@@ -83,4 +83,9 @@ func AddCommands(cli CommandRegistrar) {
 	endorserCmd.SetChaincodes(chaincodes)
 	endorserCmd.SetCollections(collections)
 	endorserCmd.SetNoPrivateReads(noPrivReads)
+
+	probe := endorsers.Flag("probe", "Probes the endorsers, and outputs endorsement plans ranked by their reachability and latency").Bool()
+	probeTimeout := endorsers.Flag("probeTimeout", "Sets the maximum time probing the endorsers may take").Default(defaultProbeTimeout.String()).Duration()
+	endorserCmd.SetProbe(probe, probeTimeout)
+	endorserCmd.SetPlanner(&ClientStub{}, &PlanResponseParser{Writer: responseParserWriter, NewProbe: DialProbe})
 }
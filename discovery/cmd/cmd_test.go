@@ -32,4 +32,6 @@ func TestAddCommands(t *testing.T) {
 	// Ensure that chaincode and collection flags were called for the endorsers
 	assert.NotNil(t, app.GetCommand(discovery.EndorsersCommand).GetFlag("chaincode"))
 	assert.NotNil(t, app.GetCommand(discovery.EndorsersCommand).GetFlag("collection"))
+	assert.NotNil(t, app.GetCommand(discovery.EndorsersCommand).GetFlag("probe"))
+	assert.NotNil(t, app.GetCommand(discovery.EndorsersCommand).GetFlag("probeTimeout"))
 }
@@ -12,6 +12,7 @@ import (
 	"io"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	. "github.com/hyperledger/fabric-protos-go/discovery"
@@ -40,6 +41,25 @@ type EndorsersCmd struct {
 	collections *map[string]string
 	noPrivReads *[]string
 	parser      ResponseParser
+
+	probe        *bool
+	probeTimeout *time.Duration
+	planStub     Stub
+	planParser   *PlanResponseParser
+}
+
+// SetProbe sets whether the endorsers are probed, and the maximum time probing them may take.
+// When the endorsers are probed, endorsement plans ranked by their reachability and latency
+// are emitted instead of the endorsement descriptors.
+func (pc *EndorsersCmd) SetProbe(probe *bool, probeTimeout *time.Duration) {
+	pc.probe = probe
+	pc.probeTimeout = probeTimeout
+}
+
+// SetPlanner sets the stub and the parser used to emit endorsement plans
+func (pc *EndorsersCmd) SetPlanner(stub Stub, parser *PlanResponseParser) {
+	pc.planStub = stub
+	pc.planParser = parser
 }
 
 // SetCollections sets the collections to be the given collections
@@ -105,6 +125,10 @@ func (pc *EndorsersCmd) Execute(conf common.Config) error {
 		return errors.Wrap(err, "failed creating request")
 	}
 
+	if pc.probe != nil && *pc.probe {
+		return pc.plan(server, channel, conf, req, ccCalls)
+	}
+
 	res, err := pc.stub.Send(server, conf, req)
 	if err != nil {
 		return err
@@ -113,6 +137,23 @@ func (pc *EndorsersCmd) Execute(conf common.Config) error {
 	return pc.parser.ParseResponse(channel, res)
 }
 
+func (pc *EndorsersCmd) plan(server, channel string, conf common.Config, req *discovery.Request, ccCalls []*ChaincodeCall) error {
+	if pc.planStub == nil || pc.planParser == nil {
+		return errors.New("probing endorsers is not supported")
+	}
+	timeout := defaultProbeTimeout
+	if pc.probeTimeout != nil && *pc.probeTimeout != 0 {
+		timeout = *pc.probeTimeout
+	}
+
+	res, err := pc.planStub.Send(server, conf, req)
+	if err != nil {
+		return err
+	}
+
+	return pc.planParser.ParsePlans(conf, channel, discovery.InvocationChain(ccCalls), res, timeout)
+}
+
 // EndorserResponseParser parses endorsement responses from the peer
 type EndorserResponseParser struct {
 	io.Writer
@@ -8,7 +8,9 @@ package discovery_test
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	discprotos "github.com/hyperledger/fabric-protos-go/discovery"
 	"github.com/hyperledger/fabric-protos-go/msp"
@@ -78,6 +80,39 @@ func TestEndorserCmd(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Endorsement plans with probed endorsers", func(t *testing.T) {
+		chaincodes := []string{"mycc"}
+		probe := true
+		probeTimeout := 2 * time.Second
+		planStub := &mocks.Stub{}
+		chanRes := &mocks.ChannelResponse{}
+		chanRes.On("Layouts", InvocationChain{{Name: "mycc"}}, mock.Anything).Return(nil, errors.New("no endorsement combination can be satisfied")).Once()
+		res := &mocks.ServiceResponse{}
+		res.On("ForChannel", channel).Return(chanRes)
+		planStub.On("Send", server, mock.Anything, mock.Anything).Return(res, nil).Once()
+		var timeout time.Duration
+
+		cmd := discovery.NewEndorsersCmd(stub, parser)
+		cmd.SetChannel(&channel)
+		cmd.SetServer(&server)
+		cmd.SetChaincodes(&chaincodes)
+		cmd.SetProbe(&probe, &probeTimeout)
+
+		err := cmd.Execute(common.Config{})
+		assert.EqualError(t, err, "probing endorsers is not supported")
+
+		cmd.SetPlanner(planStub, &discovery.PlanResponseParser{
+			NewProbe: func(conf common.Config, t time.Duration) (ProbeFunc, error) {
+				timeout = t
+				return func(context.Context, string) error { return nil }, nil
+			},
+		})
+		err = cmd.Execute(common.Config{})
+		assert.EqualError(t, err, "failed computing endorsement plans (unreachable endorsers: map[]): no endorsement combination can be satisfied")
+		assert.Equal(t, probeTimeout, timeout)
+		planStub.AssertExpectations(t)
+	})
+
 	t.Run("Endorsement query with collections succeeds", func(t *testing.T) {
 		chaincodes := []string{"mycc", "yourcc"}
 		collections := map[string]string{
@@ -58,6 +58,29 @@ func (_m *ChannelResponse) Endorsers(invocationChain client.InvocationChain, f c
 	return r0, r1
 }
 
+// Layouts provides a mock function with given fields: invocationChain, c
+func (_m *ChannelResponse) Layouts(invocationChain client.InvocationChain, c client.Constraints) ([]*client.ScoredLayout, error) {
+	ret := _m.Called(invocationChain, c)
+
+	var r0 []*client.ScoredLayout
+	if rf, ok := ret.Get(0).(func(client.InvocationChain, client.Constraints) []*client.ScoredLayout); ok {
+		r0 = rf(invocationChain, c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*client.ScoredLayout)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(client.InvocationChain, client.Constraints) error); ok {
+		r1 = rf(invocationChain, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Peers provides a mock function with given fields: invocationChain
 func (_m *ChannelResponse) Peers(invocationChain ...*discovery.ChaincodeCall) ([]*client.Peer, error) {
 	_va := make([]interface{}, len(invocationChain))
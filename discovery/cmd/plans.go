@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/cmd/common"
+	"github.com/hyperledger/fabric/cmd/common/comm"
+	discovery "github.com/hyperledger/fabric/discovery/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultProbeTimeout = time.Second * 5
+)
+
+// NewProbe creates a probe of endorsers that can last up to the given timeout
+type NewProbe func(conf common.Config, timeout time.Duration) (discovery.ProbeFunc, error)
+
+// DialProbe creates a probe that establishes a connection to endorsers
+// using the TLS configuration of the CLI
+func DialProbe(conf common.Config, timeout time.Duration) (discovery.ProbeFunc, error) {
+	tlsConf := conf.TLSConfig
+	tlsConf.Timeout = timeout
+	client, err := comm.NewClient(tlsConf)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, endpoint string) error {
+		conn, err := client.NewDialer(endpoint)()
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}, nil
+}
+
+// PlanResponseParser parses endorsement responses into endorsement plans,
+// ranked by the reachability and latency of their endorsers
+type PlanResponseParser struct {
+	io.Writer
+	// NewProbe creates the probe of the endorsers
+	NewProbe NewProbe
+}
+
+// ParsePlans parses the given response for the given channel and invocation chain,
+// probing the endorsers for no longer than the given timeout
+func (parser *PlanResponseParser) ParsePlans(conf common.Config, channel string, ic discovery.InvocationChain, res ServiceResponse, timeout time.Duration) error {
+	probe, err := parser.NewProbe(conf, timeout)
+	if err != nil {
+		return errors.WithMessage(err, "failed creating probe")
+	}
+
+	var lock sync.Mutex
+	unreachable := make(map[string]string)
+	layouts, err := res.ForChannel(channel).Layouts(ic, discovery.Constraints{
+		Probe: func(ctx context.Context, endpoint string) error {
+			err := probe(ctx, endpoint)
+			if err != nil {
+				lock.Lock()
+				unreachable[endpoint] = err.Error()
+				lock.Unlock()
+			}
+			return err
+		},
+		ProbeTimeout: timeout,
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed computing endorsement plans (unreachable endorsers: %v)", unreachable)
+	}
+
+	plans := endorsementPlans{Unreachable: unreachable}
+	for _, layout := range layouts {
+		plans.Plans = append(plans.Plans, planFromLayout(layout))
+	}
+	jsonBytes, _ := json.MarshalIndent(plans, "", "\t")
+	fmt.Fprintln(parser.Writer, string(jsonBytes))
+	return nil
+}
+
+type endorsementPlans struct {
+	Plans       []endorsementPlan
+	Unreachable map[string]string
+}
+
+type endorsementPlan struct {
+	QuantitiesByGroup map[string]int
+	Endorsers         []plannedEndorser
+	Score             discovery.LayoutScore
+}
+
+type plannedEndorser struct {
+	MSPID        string
+	Endpoint     string
+	LedgerHeight uint64
+	Latency      time.Duration
+}
+
+func planFromLayout(layout *discovery.ScoredLayout) endorsementPlan {
+	plan := endorsementPlan{
+		QuantitiesByGroup: layout.QuantitiesByGroup,
+		Score:             layout.Score,
+	}
+	for _, e := range layout.Endorsers {
+		endpoint := e.AliveMessage.GetAliveMsg().GetMembership().GetEndpoint()
+		plan.Endorsers = append(plan.Endorsers, plannedEndorser{
+			MSPID:        e.MSPID,
+			Endpoint:     endpoint,
+			LedgerHeight: e.StateInfoMessage.GetStateInfo().GetProperties().GetLedgerHeight(),
+			Latency:      layout.Probes[endpoint].Latency,
+		})
+	}
+	return plan
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/cmd/common"
+	. "github.com/hyperledger/fabric/discovery/client"
+	discovery "github.com/hyperledger/fabric/discovery/cmd"
+	"github.com/hyperledger/fabric/discovery/cmd/mocks"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParsePlans(t *testing.T) {
+	buff := &bytes.Buffer{}
+	probe := func(ctx context.Context, endpoint string) error {
+		if endpoint == "p2" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	var probeTimeout time.Duration
+	parser := &discovery.PlanResponseParser{
+		Writer: buff,
+		NewProbe: func(conf common.Config, timeout time.Duration) (ProbeFunc, error) {
+			probeTimeout = timeout
+			return probe, nil
+		},
+	}
+	ic := InvocationChain{{Name: "mycc"}}
+
+	t.Run("Probe cannot be created", func(t *testing.T) {
+		parser := &discovery.PlanResponseParser{
+			Writer: buff,
+			NewProbe: func(common.Config, time.Duration) (ProbeFunc, error) {
+				return nil, errors.New("bad TLS config")
+			},
+		}
+		err := parser.ParsePlans(common.Config{}, "mychannel", ic, &mocks.ServiceResponse{}, time.Second)
+		assert.EqualError(t, err, "failed creating probe: bad TLS config")
+	})
+
+	t.Run("No plan can be satisfied", func(t *testing.T) {
+		defer buff.Reset()
+		chanRes := &mocks.ChannelResponse{}
+		chanRes.On("Layouts", ic, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(Constraints).Probe(context.Background(), "p2")
+		}).Return(nil, errors.New("no endorsement combination can be satisfied"))
+		res := &mocks.ServiceResponse{}
+		res.On("ForChannel", "mychannel").Return(chanRes)
+
+		err := parser.ParsePlans(common.Config{}, "mychannel", ic, res, time.Second)
+		assert.EqualError(t, err, "failed computing endorsement plans (unreachable endorsers: map[p2:connection refused]): no endorsement combination can be satisfied")
+		assert.Empty(t, buff.String())
+	})
+
+	t.Run("Plans are emitted", func(t *testing.T) {
+		defer buff.Reset()
+		p1 := &Peer{
+			MSPID:            "Org1MSP",
+			AliveMessage:     aliveMessage(1),
+			StateInfoMessage: stateInfoMessage(100),
+		}
+		chanRes := &mocks.ChannelResponse{}
+		chanRes.On("Layouts", ic, mock.Anything).Run(func(args mock.Arguments) {
+			c := args.Get(1).(Constraints)
+			assert.Equal(t, 3*time.Second, c.ProbeTimeout)
+			c.Probe(context.Background(), "p1")
+			c.Probe(context.Background(), "p2")
+		}).Return([]*ScoredLayout{
+			{
+				QuantitiesByGroup: map[string]int{"G1": 1},
+				Endorsers:         Endorsers{p1},
+				Score:             LayoutScore{MaxLatency: 2 * time.Millisecond, Endorsers: 1},
+				Probes: map[string]ProbeResult{
+					"p1": {Reachable: true, Latency: 2 * time.Millisecond},
+				},
+			},
+		}, nil)
+		res := &mocks.ServiceResponse{}
+		res.On("ForChannel", "mychannel").Return(chanRes)
+
+		err := parser.ParsePlans(common.Config{}, "mychannel", ic, res, 3*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 3*time.Second, probeTimeout)
+		assert.Equal(t, expectedPlansOutput, buff.String())
+	})
+}
+
+const expectedPlansOutput = `{
+	"Plans": [
+		{
+			"QuantitiesByGroup": {
+				"G1": 1
+			},
+			"Endorsers": [
+				{
+					"MSPID": "Org1MSP",
+					"Endpoint": "p1",
+					"LedgerHeight": 100,
+					"Latency": 2000000
+				}
+			],
+			"Score": {
+				"Preferred": 0,
+				"NotPreferred": 0,
+				"MaxLedgerLag": 0,
+				"MaxLatency": 2000000,
+				"Endorsers": 1
+			}
+		}
+	],
+	"Unreachable": {
+		"p2": "connection refused"
+	}
+}
+`
+
+func TestDialProbe(t *testing.T) {
+	probe, err := discovery.DialProbe(common.Config{}, 100*time.Millisecond)
+	assert.NoError(t, err)
+	err = probe(context.Background(), "localhost:1")
+	assert.Error(t, err)
+}
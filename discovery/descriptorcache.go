@@ -0,0 +1,259 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/discovery"
+	common2 "github.com/hyperledger/fabric/gossip/common"
+	gdisc "github.com/hyperledger/fabric/gossip/discovery"
+	"github.com/pkg/errors"
+)
+
+type endorsementSupport interface {
+	// PeersForEndorsement returns an EndorsementDescriptor for a given set of peers, channel, and chaincode
+	PeersForEndorsement(channel common2.ChannelID, interest *discovery.ChaincodeInterest) (*discovery.EndorsementDescriptor, error)
+
+	// ConfigSequence returns the configuration sequence of the given channel
+	ConfigSequence(channel string) uint64
+
+	// PeersOfChannel returns the NetworkMembers considered alive
+	// and also subscribed to the channel given
+	PeersOfChannel(common2.ChannelID) gdisc.Members
+}
+
+type descriptorCacheConfig struct {
+	// ttl is the time an endorsement descriptor is served from the cache
+	// after it has been computed. Zero disables the cache
+	ttl time.Duration
+	// maxCacheSize is the maximum number of descriptors cached per channel
+	maxCacheSize int
+}
+
+// descriptorCache memoizes the endorsement descriptors computed for chaincode interests.
+// A cached descriptor expires once its TTL elapses, and the descriptors of a channel
+// are invalidated as soon as the configuration sequence of the channel changes, the
+// alive peers of the channel or the chaincodes installed on them change, or the
+// definition of a chaincode of the channel is updated
+type descriptorCache struct {
+	endorsementSupport
+	sync.Mutex
+	conf     descriptorCacheConfig
+	now      func() time.Time
+	channels map[string]*channelDescriptors
+	// generations counts the invalidations of the descriptors of each channel
+	generations map[string]uint64
+}
+
+// channelState identifies the state of a channel under which endorsement descriptors are computed
+type channelState struct {
+	sequence   uint64
+	generation uint64
+	membership [sha256.Size]byte
+}
+
+type channelDescriptors struct {
+	state   channelState
+	entries map[string]*cachedDescriptor
+}
+
+type cachedDescriptor struct {
+	descriptor *discovery.EndorsementDescriptor
+	expiration time.Time
+}
+
+func newDescriptorCache(s endorsementSupport, conf descriptorCacheConfig) *descriptorCache {
+	if conf.maxCacheSize <= 0 {
+		conf.maxCacheSize = defaultMaxCacheSize
+	}
+	return &descriptorCache{
+		endorsementSupport: s,
+		conf:               conf,
+		now:                time.Now,
+		channels:           make(map[string]*channelDescriptors),
+		generations:        make(map[string]uint64),
+	}
+}
+
+// invalidate evicts the descriptors cached for the channel, including those being computed
+func (dc *descriptorCache) invalidate(channel string) {
+	dc.Lock()
+	defer dc.Unlock()
+
+	dc.generations[channel]++
+	if descriptors, exists := dc.channels[channel]; exists {
+		logger.Debugf("Invalidating %d endorsement descriptors of channel %s", len(descriptors.entries), channel)
+		delete(dc.channels, channel)
+	}
+}
+
+// PeersForEndorsement returns an EndorsementDescriptor for a given set of peers, channel, and chaincode,
+// serving it from the cache if it has been computed under the current configuration and membership
+// of the channel and has not expired
+func (dc *descriptorCache) PeersForEndorsement(channel common2.ChannelID, interest *discovery.ChaincodeInterest) (*discovery.EndorsementDescriptor, error) {
+	if dc.conf.ttl <= 0 {
+		return dc.endorsementSupport.PeersForEndorsement(channel, interest)
+	}
+	key, err := interestToKey(interest)
+	if err != nil {
+		logger.Warningf("Failed computing key of chaincode interest: %+v", err)
+		return dc.endorsementSupport.PeersForEndorsement(channel, interest)
+	}
+
+	currState := dc.state(channel)
+	if desc := dc.lookup(string(channel), key, currState); desc != nil {
+		return desc, nil
+	}
+
+	desc, err := dc.endorsementSupport.PeersForEndorsement(channel, interest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only cache the descriptor if the state of the channel hasn't changed while it was computed,
+	// otherwise it might override a descriptor computed under the newer state
+	if currState == dc.state(channel) {
+		dc.store(string(channel), key, currState, desc)
+	}
+	return desc, nil
+}
+
+// state returns the current state of the channel
+func (dc *descriptorCache) state(channel common2.ChannelID) channelState {
+	dc.Lock()
+	generation := dc.generations[string(channel)]
+	dc.Unlock()
+
+	return channelState{
+		sequence:   dc.ConfigSequence(string(channel)),
+		generation: generation,
+		membership: membershipDigest(dc.PeersOfChannel(channel)),
+	}
+}
+
+func (dc *descriptorCache) lookup(channel, key string, currState channelState) *discovery.EndorsementDescriptor {
+	dc.Lock()
+	defer dc.Unlock()
+
+	descriptors, exists := dc.channels[channel]
+	if !exists {
+		return nil
+	}
+	if descriptors.state != currState {
+		logger.Debugf("Configuration or membership of channel %s changed, invalidating %d endorsement descriptors",
+			channel, len(descriptors.entries))
+		delete(dc.channels, channel)
+		return nil
+	}
+	entry, exists := descriptors.entries[key]
+	if !exists {
+		return nil
+	}
+	if !dc.now().Before(entry.expiration) {
+		delete(descriptors.entries, key)
+		return nil
+	}
+	return entry.descriptor
+}
+
+func (dc *descriptorCache) store(channel, key string, currState channelState, desc *discovery.EndorsementDescriptor) {
+	dc.Lock()
+	defer dc.Unlock()
+
+	descriptors, exists := dc.channels[channel]
+	if !exists || descriptors.state != currState {
+		descriptors = &channelDescriptors{
+			state:   currState,
+			entries: make(map[string]*cachedDescriptor),
+		}
+		dc.channels[channel] = descriptors
+	}
+
+	now := dc.now()
+	if len(descriptors.entries)+1 > dc.conf.maxCacheSize {
+		descriptors.purge(now, dc.conf.maxCacheSize)
+	}
+	descriptors.entries[key] = &cachedDescriptor{
+		descriptor: desc,
+		expiration: now.Add(dc.conf.ttl),
+	}
+}
+
+// purge evicts the expired descriptors, and if there are still too many of them,
+// evicts arbitrary descriptors to make room for a new one
+func (cd *channelDescriptors) purge(now time.Time, maxCacheSize int) {
+	for key, entry := range cd.entries {
+		if !now.Before(entry.expiration) {
+			delete(cd.entries, key)
+		}
+	}
+	for key := range cd.entries {
+		if len(cd.entries) < maxCacheSize {
+			return
+		}
+		delete(cd.entries, key)
+	}
+}
+
+// membershipDigest returns a digest of the given peers and the chaincodes installed on them,
+// which the endorsement descriptors are computed from. The ledger heights of the peers are
+// left out, as they change with every block
+func membershipDigest(members gdisc.Members) [sha256.Size]byte {
+	entries := make([][]byte, 0, len(members))
+	for _, member := range members {
+		var entry bytes.Buffer
+		writeField(&entry, member.PKIid)
+		writeField(&entry, []byte(member.Endpoint))
+		if props := member.Properties; props != nil {
+			if props.LeftChannel {
+				writeField(&entry, []byte("left"))
+			}
+			for _, cc := range props.Chaincodes {
+				writeField(&entry, []byte(cc.Name))
+				writeField(&entry, []byte(cc.Version))
+				writeField(&entry, cc.Metadata)
+			}
+		}
+		entries = append(entries, entry.Bytes())
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i], entries[j]) < 0
+	})
+
+	h := sha256.New()
+	for _, entry := range entries {
+		writeField(h, entry)
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// writeField writes the length-prefixed field, so that the concatenation of fields is unambiguous
+func writeField(w io.Writer, field []byte) {
+	lenBuf := make([]byte, 8)
+	binary.BigEndian.PutUint64(lenBuf, uint64(len(field)))
+	w.Write(lenBuf)
+	w.Write(field)
+}
+
+func interestToKey(interest *discovery.ChaincodeInterest) (string, error) {
+	b, err := proto.Marshal(interest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed marshaling chaincode interest")
+	}
+	return hex.EncodeToString(b), nil
+}
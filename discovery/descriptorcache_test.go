@@ -0,0 +1,284 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	common2 "github.com/hyperledger/fabric/gossip/common"
+	gdisc "github.com/hyperledger/fabric/gossip/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockEndorsementSupport struct {
+	mock.Mock
+	lock    sync.Mutex
+	members map[string]gdisc.Members
+}
+
+func (es *mockEndorsementSupport) PeersForEndorsement(channel common2.ChannelID, interest *discovery.ChaincodeInterest) (*discovery.EndorsementDescriptor, error) {
+	args := es.Called(string(channel), interest.Chaincodes[0].Name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*discovery.EndorsementDescriptor), args.Error(1)
+}
+
+func (es *mockEndorsementSupport) ConfigSequence(channel string) uint64 {
+	return es.Called(channel).Get(0).(uint64)
+}
+
+func (es *mockEndorsementSupport) PeersOfChannel(channel common2.ChannelID) gdisc.Members {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	return es.members[string(channel)]
+}
+
+func (es *mockEndorsementSupport) setMembers(channel string, members ...gdisc.NetworkMember) {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	if es.members == nil {
+		es.members = map[string]gdisc.Members{}
+	}
+	es.members[channel] = members
+}
+
+func member(endpoint string, chaincodes ...*gossip.Chaincode) gdisc.NetworkMember {
+	return gdisc.NetworkMember{
+		Endpoint:   endpoint,
+		PKIid:      common2.PKIidType(endpoint),
+		Properties: &gossip.Properties{LedgerHeight: 1, Chaincodes: chaincodes},
+	}
+}
+
+func interestOf(ccNames ...string) *discovery.ChaincodeInterest {
+	interest := &discovery.ChaincodeInterest{}
+	for _, name := range ccNames {
+		interest.Chaincodes = append(interest.Chaincodes, &discovery.ChaincodeCall{Name: name})
+	}
+	return interest
+}
+
+func TestDescriptorCacheDisabled(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache := newDescriptorCache(es, descriptorCacheConfig{})
+
+	for i := 0; i < 2; i++ {
+		desc, err := cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+		assert.NoError(t, err)
+		assert.Equal(t, "cc1", desc.Chaincode)
+	}
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+}
+
+func TestDescriptorCacheUsage(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	es.On("ConfigSequence", "bar").Return(uint64(0))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	es.On("PeersForEndorsement", "foo", "cc2").Return(&discovery.EndorsementDescriptor{Chaincode: "cc2"}, nil)
+	es.On("PeersForEndorsement", "bar", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	es.On("PeersForEndorsement", "foo", "unknown").Return(nil, errors.New("unknown chaincode"))
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Minute})
+
+	// Identical interests are only computed once per channel
+	for i := 0; i < 3; i++ {
+		desc, err := cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+		assert.NoError(t, err)
+		assert.Equal(t, "cc1", desc.Chaincode)
+	}
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 1)
+
+	// Different interests and different channels are cached separately
+	desc, err := cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cc2", desc.Chaincode)
+	_, err = cache.PeersForEndorsement(common2.ChannelID("bar"), interestOf("cc1"))
+	assert.NoError(t, err)
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+
+	// Failures are not cached
+	for i := 0; i < 2; i++ {
+		_, err = cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("unknown"))
+		assert.EqualError(t, err, "unknown chaincode")
+	}
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 5)
+}
+
+func TestDescriptorCacheExpiration(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	now = now.Add(59 * time.Second)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 1)
+
+	// Once the TTL elapses, the descriptor is computed again
+	now = now.Add(time.Second)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+}
+
+func TestDescriptorCacheConfigChange(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0)).Times(3)
+	es.On("ConfigSequence", "bar").Return(uint64(0))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	es.On("PeersForEndorsement", "bar", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Hour})
+
+	// Compute and store under sequence 0, then hit the cache
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("bar"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+
+	// A config block of channel foo is committed
+	es.On("ConfigSequence", "foo").Return(uint64(1))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+
+	// The descriptors of other channels are not affected
+	cache.PeersForEndorsement(common2.ChannelID("bar"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+}
+
+func TestDescriptorCacheConfigChangeDuringComputation(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0)).Once()
+	es.On("ConfigSequence", "foo").Return(uint64(1))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Hour})
+
+	// The descriptor was computed while the configuration changed, so it isn't cached
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+}
+
+func TestDescriptorCacheMembershipChange(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cc1 := &gossip.Chaincode{Name: "cc1", Version: "1.0"}
+	es.setMembers("foo", member("p1", cc1), member("p2", cc1))
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Hour})
+
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 1)
+
+	// The order of the peers and their ledger heights don't matter
+	p2, p1 := member("p2", cc1), member("p1", cc1)
+	p2.Properties.LedgerHeight = 10
+	es.setMembers("foo", p2, p1)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 1)
+
+	// A peer joins the channel
+	es.setMembers("foo", member("p1", cc1), member("p2", cc1), member("p3"))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 2)
+
+	// The chaincode is installed on the new peer
+	es.setMembers("foo", member("p1", cc1), member("p2", cc1), member("p3", cc1))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+
+	// A peer is no longer alive
+	es.setMembers("foo", member("p1", cc1), member("p3", cc1))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 4)
+}
+
+func TestDescriptorCacheInvalidation(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	es.On("ConfigSequence", "bar").Return(uint64(0))
+	es.On("PeersForEndorsement", "bar", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Hour})
+
+	// The definition of a chaincode of channel foo is updated while a descriptor is computed,
+	// so it isn't cached
+	es.On("PeersForEndorsement", "foo", "cc1").Run(func(mock.Arguments) {
+		cache.invalidate("foo")
+	}).Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil).Once()
+	es.On("PeersForEndorsement", "foo", "cc1").Return(&discovery.EndorsementDescriptor{Chaincode: "cc1"}, nil)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	assert.NotContains(t, cache.channels, "foo")
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("bar"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 3)
+
+	// The descriptors of the channel are invalidated, but not those of other channels
+	cache.invalidate("foo")
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	cache.PeersForEndorsement(common2.ChannelID("bar"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 4)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc1"))
+	es.AssertNumberOfCalls(t, "PeersForEndorsement", 4)
+}
+
+func TestDescriptorCachePurge(t *testing.T) {
+	es := &mockEndorsementSupport{}
+	es.On("ConfigSequence", "foo").Return(uint64(0))
+	for i := 0; i < 6; i++ {
+		cc := fmt.Sprintf("cc%d", i)
+		es.On("PeersForEndorsement", "foo", cc).Return(&discovery.EndorsementDescriptor{Chaincode: cc}, nil)
+	}
+	cache := newDescriptorCache(es, descriptorCacheConfig{ttl: time.Minute, maxCacheSize: 4})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf(fmt.Sprintf("cc%d", i)))
+	}
+	assert.Len(t, cache.channels["foo"].entries, 4)
+
+	// The cache is full, so an arbitrary descriptor is evicted
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc4"))
+	assert.Len(t, cache.channels["foo"].entries, 4)
+	assert.Contains(t, cache.channels["foo"].entries, mustKey(t, interestOf("cc4")))
+
+	// Expired descriptors are evicted first
+	now = now.Add(time.Minute)
+	cache.PeersForEndorsement(common2.ChannelID("foo"), interestOf("cc5"))
+	assert.Len(t, cache.channels["foo"].entries, 1)
+}
+
+func TestDescriptorCacheDefaultMaxSize(t *testing.T) {
+	cache := newDescriptorCache(&mockEndorsementSupport{}, descriptorCacheConfig{ttl: time.Minute})
+	assert.Equal(t, defaultMaxCacheSize, cache.conf.maxCacheSize)
+}
+
+func mustKey(t *testing.T, interest *discovery.ChaincodeInterest) string {
+	key, err := interestToKey(interest)
+	assert.NoError(t, err)
+	return key
+}
@@ -11,8 +11,10 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric/common/chaincode"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/discovery/protoext"
@@ -41,6 +43,7 @@ type service struct {
 	channelDispatchers map[protoext.QueryType]dispatcher
 	localDispatchers   map[protoext.QueryType]dispatcher
 	auth               *authCache
+	endorsements       *descriptorCache
 	Support
 }
 
@@ -50,14 +53,25 @@ type Config struct {
 	AuthCacheEnabled             bool
 	AuthCacheMaxSize             int
 	AuthCachePurgeRetentionRatio float64
+	// EndorsementCacheTTL is the time an endorsement descriptor computed for a chaincode
+	// query is reused for identical queries. The cached descriptors of a channel are
+	// invalidated when its configuration, its membership or the definition of one of
+	// its chaincodes changes. Zero disables the cache
+	EndorsementCacheTTL time.Duration
+	// EndorsementCacheMaxSize is the maximum number of endorsement descriptors cached per channel
+	EndorsementCacheMaxSize int
 }
 
 // String returns a string representation of this Config
 func (c Config) String() string {
+	endorsementCache := "endorsement cache disabled"
+	if c.EndorsementCacheTTL > 0 {
+		endorsementCache = fmt.Sprintf("endorsementCacheTTL: %s, endorsementCacheMaxSize: %d", c.EndorsementCacheTTL, c.EndorsementCacheMaxSize)
+	}
 	if c.AuthCacheEnabled {
-		return fmt.Sprintf("TLS: %t, authCacheMaxSize: %d, authCachePurgeRatio: %f", c.TLS, c.AuthCacheMaxSize, c.AuthCachePurgeRetentionRatio)
+		return fmt.Sprintf("TLS: %t, authCacheMaxSize: %d, authCachePurgeRatio: %f, %s", c.TLS, c.AuthCacheMaxSize, c.AuthCachePurgeRetentionRatio, endorsementCache)
 	}
-	return fmt.Sprintf("TLS: %t, auth cache disabled", c.TLS)
+	return fmt.Sprintf("TLS: %t, auth cache disabled, %s", c.TLS, endorsementCache)
 }
 
 // peerMapping maps PKI-IDs to Peers
@@ -71,6 +85,10 @@ func NewService(config Config, sup Support) *service {
 			maxCacheSize:        config.AuthCacheMaxSize,
 			purgeRetentionRatio: config.AuthCachePurgeRetentionRatio,
 		}),
+		endorsements: newDescriptorCache(sup, descriptorCacheConfig{
+			ttl:          config.EndorsementCacheTTL,
+			maxCacheSize: config.EndorsementCacheMaxSize,
+		}),
 		Support: sup,
 	}
 	s.channelDispatchers = map[protoext.QueryType]dispatcher{
@@ -85,6 +103,12 @@ func NewService(config Config, sup Support) *service {
 	return s
 }
 
+// HandleMetadataUpdate invalidates the endorsement descriptors cached for the channel,
+// as the definition of one of its chaincodes changed
+func (s *service) HandleMetadataUpdate(channel string, _ chaincode.MetadataSet) {
+	s.endorsements.invalidate(channel)
+}
+
 func (s *service) Discover(ctx context.Context, request *discovery.SignedRequest) (*discovery.Response, error) {
 	addr := util.ExtractRemoteAddress(ctx)
 	req, err := validateStructure(ctx, request, s.config.TLS, comm.ExtractCertificateHashFromContext)
@@ -138,7 +162,7 @@ func (s *service) chaincodeQuery(q *discovery.Query) *discovery.QueryResult {
 	}
 	var descriptors []*discovery.EndorsementDescriptor
 	for _, interest := range q.GetCcQuery().Interests {
-		desc, err := s.PeersForEndorsement(common2.ChannelID(q.Channel), interest)
+		desc, err := s.endorsements.PeersForEndorsement(common2.ChannelID(q.Channel), interest)
 		if err != nil {
 			logger.Errorf("Failed constructing descriptor for chaincode %s,: %v", interest, err)
 			return wrapError(errors.Errorf("failed constructing descriptor for %v", interest))
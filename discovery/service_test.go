@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-protos-go/discovery"
@@ -31,11 +32,15 @@ func TestConfig(t *testing.T) {
 			AuthCacheEnabled:             trueOfFalse,
 			AuthCachePurgeRetentionRatio: 0.5,
 			AuthCacheMaxSize:             42,
+			EndorsementCacheTTL:          time.Second,
+			EndorsementCacheMaxSize:      24,
 		}
 		service := NewService(conf, &mockSupport{})
 		assert.Equal(t, trueOfFalse, service.auth.conf.enabled)
 		assert.Equal(t, 42, service.auth.conf.maxCacheSize)
 		assert.Equal(t, 0.5, service.auth.conf.purgeRetentionRatio)
+		assert.Equal(t, time.Second, service.endorsements.conf.ttl)
+		assert.Equal(t, 24, service.endorsements.conf.maxCacheSize)
 	}
 }
 
@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package api
 
 import (
+	"strings"
+
 	"github.com/hyperledger/fabric/gossip/common"
 )
 
@@ -51,5 +53,12 @@ type AnchorPeer struct {
 	Port int    // Port is the port the remote peer is listening on
 }
 
+// IsSRV returns whether the anchor peer is published as a DNS SRV record,
+// namely, its port is unspecified and its host is the name of the record,
+// such as _gossip._tcp.org1.example.com
+func (ap AnchorPeer) IsSRV() bool {
+	return ap.Port == 0 && strings.HasPrefix(ap.Host, "_")
+}
+
 // OrgIdentityType defines the identity of an organization
 type OrgIdentityType []byte
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"sync"
+	"time"
+)
+
+// dialBackoff tracks the failed dials to remote endpoints, and backs off from dialing
+// an endpoint that failed recently for exponentially longer periods, up to a maximum.
+// Dialing is never backed off when the initial backoff is zero.
+type dialBackoff struct {
+	initial  time.Duration
+	max      time.Duration
+	now      func() time.Time
+	lock     sync.Mutex
+	failures map[string]*dialFailures
+}
+
+type dialFailures struct {
+	count   int
+	retryAt time.Time
+}
+
+func newDialBackoff(initial, max time.Duration) *dialBackoff {
+	if max < initial {
+		max = initial
+	}
+	return &dialBackoff{
+		initial:  initial,
+		max:      max,
+		now:      time.Now,
+		failures: make(map[string]*dialFailures),
+	}
+}
+
+// wait returns how long dialing the given endpoint is still backed off for
+func (db *dialBackoff) wait(endpoint string) time.Duration {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	f, exists := db.failures[endpoint]
+	if !exists {
+		return 0
+	}
+	if wait := f.retryAt.Sub(db.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// failed backs off from dialing the given endpoint
+func (db *dialBackoff) failed(endpoint string) {
+	if db.initial == 0 {
+		return
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	f, exists := db.failures[endpoint]
+	if !exists {
+		f = &dialFailures{}
+		db.failures[endpoint] = f
+	}
+	f.count++
+	backoff := db.initial
+	for i := 1; i < f.count && backoff < db.max; i++ {
+		backoff *= 2
+	}
+	if backoff > db.max {
+		backoff = db.max
+	}
+	f.retryAt = db.now().Add(backoff)
+}
+
+// succeeded resets the backoff of the given endpoint
+func (db *dialBackoff) succeeded(endpoint string) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	delete(db.failures, endpoint)
+}
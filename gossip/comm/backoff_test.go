@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialBackoff(t *testing.T) {
+	now := time.Now()
+	db := newDialBackoff(time.Second, 5*time.Second)
+	db.now = func() time.Time {
+		return now
+	}
+
+	assert.Zero(t, db.wait("p1"))
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		db.failed("p1")
+		assert.Equal(t, expected, db.wait("p1"))
+		assert.Zero(t, db.wait("p2"))
+	}
+
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, 2*time.Second, db.wait("p1"))
+	now = now.Add(3 * time.Second)
+	assert.Zero(t, db.wait("p1"))
+
+	db.failed("p1")
+	db.succeeded("p1")
+	assert.Zero(t, db.wait("p1"))
+
+	t.Run("disabled", func(t *testing.T) {
+		db := newDialBackoff(0, 0)
+		db.failed("p1")
+		assert.Zero(t, db.wait("p1"))
+	})
+}
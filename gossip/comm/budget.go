@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	dropReasonBudget = "budget"
+	dropReasonQueue  = "queue"
+)
+
+// OrgBudget is the budget of the messages the peers of each organization other than ours
+// may send us, so that a misconfigured organization can't saturate our peer.
+type OrgBudget struct {
+	// MessageRate is the number of messages per second, or 0 for no limit
+	MessageRate float64
+	// ByteRate is the number of bytes per second, or 0 for no limit
+	ByteRate float64
+	// QueueSize is the number of received messages queued for each organization,
+	// which are handled in a round robin across the organizations. 0 disables the queueing.
+	QueueSize int
+}
+
+// orgBudgets enforces an OrgBudget on each organization with token buckets
+// that hold up to a second worth of messages and bytes
+type orgBudgets struct {
+	budget  OrgBudget
+	now     func() time.Time
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	messages float64
+	bytes    float64
+	last     time.Time
+}
+
+func newOrgBudgets(budget OrgBudget) *orgBudgets {
+	return &orgBudgets{
+		budget:  budget,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (ob *orgBudgets) enabled() bool {
+	return ob.budget.MessageRate > 0 || ob.budget.ByteRate > 0
+}
+
+// allow consumes a message of the given size from the budget of the given organization,
+// and returns false if the budget has been exhausted
+func (ob *orgBudgets) allow(org string, size int) bool {
+	if !ob.enabled() {
+		return true
+	}
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+
+	now := ob.now()
+	b, exists := ob.buckets[org]
+	if !exists {
+		b = &tokenBucket{
+			messages: ob.budget.MessageRate,
+			bytes:    ob.budget.ByteRate,
+			last:     now,
+		}
+		ob.buckets[org] = b
+	}
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.messages = refill(b.messages, ob.budget.MessageRate, elapsed)
+	b.bytes = refill(b.bytes, ob.budget.ByteRate, elapsed)
+
+	if ob.budget.MessageRate > 0 && b.messages < 1 {
+		return false
+	}
+	if ob.budget.ByteRate > 0 && b.bytes < float64(size) {
+		return false
+	}
+	b.messages--
+	b.bytes -= float64(size)
+	return true
+}
+
+func refill(tokens, rate, elapsed float64) float64 {
+	tokens += rate * elapsed
+	if tokens > rate {
+		return rate
+	}
+	return tokens
+}
+
+// fairQueue queues received messages by the organizations of their senders,
+// and dispatches them in a round robin across the organizations
+type fairQueue struct {
+	size     int
+	dispatch func(*ReceivedMessageImpl)
+
+	lock   sync.Mutex
+	queues map[string][]*ReceivedMessageImpl
+	// orgs are the organizations that have queued messages, in the order they are served
+	orgs    []string
+	pending chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newFairQueue(size int, dispatch func(*ReceivedMessageImpl)) *fairQueue {
+	fq := &fairQueue{
+		size:     size,
+		dispatch: dispatch,
+		queues:   make(map[string][]*ReceivedMessageImpl),
+		pending:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go fq.run()
+	return fq
+}
+
+// enqueue queues the given message of the given organization,
+// and returns false if the queue of the organization is full
+func (fq *fairQueue) enqueue(org string, msg *ReceivedMessageImpl) bool {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	queue, exists := fq.queues[org]
+	if len(queue) >= fq.size {
+		return false
+	}
+	if !exists {
+		fq.orgs = append(fq.orgs, org)
+	}
+	fq.queues[org] = append(queue, msg)
+
+	select {
+	case fq.pending <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// next returns the next message to dispatch, or nil if no messages are queued
+func (fq *fairQueue) next() *ReceivedMessageImpl {
+	fq.lock.Lock()
+	defer fq.lock.Unlock()
+
+	if len(fq.orgs) == 0 {
+		return nil
+	}
+	org := fq.orgs[0]
+	fq.orgs = fq.orgs[1:]
+	queue := fq.queues[org]
+	msg := queue[0]
+	queue[0] = nil
+	if len(queue) == 1 {
+		delete(fq.queues, org)
+	} else {
+		fq.queues[org] = queue[1:]
+		fq.orgs = append(fq.orgs, org)
+	}
+	return msg
+}
+
+func (fq *fairQueue) run() {
+	defer close(fq.done)
+	for {
+		for msg := fq.next(); msg != nil; msg = fq.next() {
+			fq.dispatch(msg)
+			select {
+			case <-fq.stop:
+				return
+			default:
+			}
+		}
+		select {
+		case <-fq.pending:
+		case <-fq.stop:
+			return
+		}
+	}
+}
+
+func (fq *fairQueue) close() {
+	close(fq.stop)
+	<-fq.done
+}
@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgBudgets(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time {
+		return now
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		budgets := newOrgBudgets(OrgBudget{QueueSize: 10})
+		assert.False(t, budgets.enabled())
+		for i := 0; i < 1000; i++ {
+			assert.True(t, budgets.allow("A", 1000))
+		}
+	})
+
+	t.Run("Message rate", func(t *testing.T) {
+		budgets := newOrgBudgets(OrgBudget{MessageRate: 10})
+		budgets.now = clock
+		for i := 0; i < 10; i++ {
+			assert.True(t, budgets.allow("A", 1000))
+		}
+		assert.False(t, budgets.allow("A", 1))
+		// Other organizations have budgets of their own
+		assert.True(t, budgets.allow("B", 1))
+
+		now = now.Add(time.Millisecond * 500)
+		for i := 0; i < 5; i++ {
+			assert.True(t, budgets.allow("A", 1))
+		}
+		assert.False(t, budgets.allow("A", 1))
+
+		// The budget doesn't accumulate beyond a second worth of messages
+		now = now.Add(time.Minute)
+		for i := 0; i < 10; i++ {
+			assert.True(t, budgets.allow("A", 1))
+		}
+		assert.False(t, budgets.allow("A", 1))
+	})
+
+	t.Run("Byte rate", func(t *testing.T) {
+		budgets := newOrgBudgets(OrgBudget{ByteRate: 1000})
+		budgets.now = clock
+		assert.True(t, budgets.allow("A", 600))
+		assert.False(t, budgets.allow("A", 600))
+		assert.True(t, budgets.allow("A", 400))
+		assert.False(t, budgets.allow("A", 1))
+
+		now = now.Add(time.Millisecond * 100)
+		assert.True(t, budgets.allow("A", 100))
+		assert.False(t, budgets.allow("A", 1))
+	})
+}
+
+func TestFairQueue(t *testing.T) {
+	var lock sync.Mutex
+	var dispatched []string
+	block := make(chan struct{})
+	fq := newFairQueue(3, func(msg *ReceivedMessageImpl) {
+		<-block
+		lock.Lock()
+		defer lock.Unlock()
+		dispatched = append(dispatched, msg.connInfo.Endpoint)
+	})
+	defer fq.close()
+
+	msg := func(endpoint string) *ReceivedMessageImpl {
+		return &ReceivedMessageImpl{
+			connInfo: &protoext.ConnectionInfo{Endpoint: endpoint},
+		}
+	}
+
+	// The first message is dispatched right away, and blocks the dispatching of the rest
+	assert.True(t, fq.enqueue("A", msg("a0")))
+	assert.Eventually(t, func() bool {
+		fq.lock.Lock()
+		defer fq.lock.Unlock()
+		return len(fq.orgs) == 0
+	}, time.Second*5, time.Millisecond*10)
+
+	for _, endpoint := range []string{"a1", "a2", "a3"} {
+		assert.True(t, fq.enqueue("A", msg(endpoint)))
+	}
+	assert.False(t, fq.enqueue("A", msg("a4")), "queue of A should be full")
+	assert.True(t, fq.enqueue("B", msg("b1")))
+	assert.True(t, fq.enqueue("C", msg("c1")))
+	assert.True(t, fq.enqueue("B", msg("b2")))
+
+	close(block)
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(dispatched) == 7
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, []string{"a0", "a1", "b1", "c1", "a2", "b2", "a3"}, dispatched)
+}
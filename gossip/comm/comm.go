@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric/gossip/api"
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/protoext"
+	"google.golang.org/grpc"
 )
 
 // Comm is an object that enables to communicate with other peers
@@ -50,6 +51,10 @@ type Comm interface {
 	// CloseConn closes a connection to a certain endpoint
 	CloseConn(peer *RemotePeer)
 
+	// Register binds the module to another gRPC server, for it to also
+	// accept connections on the listeners of that server
+	Register(s *grpc.Server)
+
 	// Stop stops the module
 	Stop()
 }
@@ -12,7 +12,9 @@ import (
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,15 +28,20 @@ import (
 	"github.com/hyperledger/fabric/gossip/util"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
 
 const (
-	handshakeTimeout = time.Second * 10
-	DefDialTimeout   = time.Second * 3
-	DefConnTimeout   = time.Second * 2
-	DefRecvBuffSize  = 20
-	DefSendBuffSize  = 20
+	handshakeTimeout      = time.Second * 10
+	DefDialTimeout        = time.Second * 3
+	DefConnTimeout        = time.Second * 2
+	DefRecvBuffSize       = 20
+	DefSendBuffSize       = 20
+	DefDialBackoffInitial = time.Second
+	DefDialBackoffMax     = time.Second * 30
+	// DefMaxRecvMsgSize is the default maximum size of the gRPC messages
+	DefMaxRecvMsgSize = 100 * 1024 * 1024
 )
 
 var (
@@ -83,26 +90,59 @@ func NewCommInstance(s *grpc.Server, certs *common.TLSCertificates, idStore iden
 		connTimeout:     config.ConnTimeout,
 		recvBuffSize:    config.RecvBuffSize,
 		sendBuffSize:    config.SendBuffSize,
+		maxRecvMsgSize:  config.MaxRecvMsgSize,
+		dialBackoff:     newDialBackoff(config.DialBackoffInitial, config.DialBackoffMax),
+		maxConnsPerOrg:  config.MaxConnectionsPerOrg,
+		wsTunnels:       config.WebSocketTunnels,
+		budgets:         newOrgBudgets(config.OrgBudget),
+	}
+	commInst.compression = supportedCompression(config.Compression, commInst.logger)
+	if config.OrgBudget.QueueSize > 0 {
+		commInst.fairQueue = newFairQueue(config.OrgBudget.QueueSize, func(msg *ReceivedMessageImpl) {
+			commInst.msgPublisher.DeMultiplex(msg)
+		})
 	}
 
 	connConfig := ConnConfig{
-		RecvBuffSize: config.RecvBuffSize,
-		SendBuffSize: config.SendBuffSize,
+		RecvBuffSize:   config.RecvBuffSize,
+		SendBuffSize:   config.SendBuffSize,
+		MaxRecvMsgSize: config.MaxRecvMsgSize,
 	}
 
 	commInst.connStore = newConnStore(commInst, commInst.logger, connConfig)
 
-	proto.RegisterGossipServer(s, commInst)
+	commInst.Register(s)
 
 	return commInst, nil
 }
 
+// Register binds the comm instance to the given gRPC server
+func (c *commImpl) Register(s *grpc.Server) {
+	proto.RegisterGossipServer(s, c)
+}
+
 // CommConfig is the configuration required to initialize a new comm
 type CommConfig struct {
 	DialTimeout  time.Duration // Dial timeout
 	ConnTimeout  time.Duration // Connection timeout
 	RecvBuffSize int           // Buffer size of received messages
 	SendBuffSize int           // Buffer size of sending messages
+	Compression  []string      // Compression codecs to negotiate with remote peers, in order of preference
+	// MaxRecvMsgSize is the size beyond which the received messages, once decompressed, are rejected.
+	// Zero means the default maximum size of the gRPC messages.
+	MaxRecvMsgSize int
+	// DialBackoffInitial is the period dialing a remote endpoint is backed off for after it fails,
+	// which doubles with every consecutive failure up to DialBackoffMax. Zero disables the backoff.
+	DialBackoffInitial time.Duration
+	DialBackoffMax     time.Duration
+	// MaxConnectionsPerOrg caps the connections to the peers of each organization other than ours.
+	// Zero means no cap.
+	MaxConnectionsPerOrg int
+	// WebSocketTunnels maps endpoints of remote peers to the WebSocket URLs
+	// connections to them are tunneled through.
+	WebSocketTunnels map[string]string
+	// OrgBudget is the budget of the messages the peers of each organization other than ours may send us.
+	OrgBudget OrgBudget
 }
 
 type commImpl struct {
@@ -129,6 +169,13 @@ type commImpl struct {
 	connTimeout     time.Duration
 	recvBuffSize    int
 	sendBuffSize    int
+	maxRecvMsgSize  int
+	compression     []string
+	dialBackoff     *dialBackoff
+	maxConnsPerOrg  int
+	wsTunnels       map[string]string
+	budgets         *orgBudgets
+	fairQueue       *fairQueue
 }
 
 func (c *commImpl) createConnection(endpoint string, expectedPKIID common.PKIidType) (*connection, error) {
@@ -145,14 +192,19 @@ func (c *commImpl) createConnection(endpoint string, expectedPKIID common.PKIidT
 	if c.isStopping() {
 		return nil, errors.New("Stopping")
 	}
+	if wait := c.dialBackoff.wait(endpoint); wait > 0 {
+		return nil, errors.Errorf("dialing %s is backed off for %v", endpoint, wait)
+	}
 	dialOpts = append(dialOpts, c.secureDialOpts()...)
 	dialOpts = append(dialOpts, grpc.WithBlock())
 	dialOpts = append(dialOpts, c.opts...)
+	dialOpts = append(dialOpts, c.tunnelDialOpts(endpoint)...)
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, c.dialTimeout)
 	defer cancel()
 	cc, err = grpc.DialContext(ctx, endpoint, dialOpts...)
 	if err != nil {
+		c.dialBackoff.failed(endpoint)
 		return nil, errors.WithStack(err)
 	}
 
@@ -162,11 +214,13 @@ func (c *commImpl) createConnection(endpoint string, expectedPKIID common.PKIidT
 	defer cancel()
 	if _, err = cl.Ping(ctx, &proto.Empty{}); err != nil {
 		cc.Close()
+		c.dialBackoff.failed(endpoint)
 		return nil, errors.WithStack(err)
 	}
+	c.dialBackoff.succeeded(endpoint)
 
 	ctx, cancel = context.WithCancel(context.Background())
-	if stream, err = cl.GossipStream(ctx); err == nil {
+	if stream, err = cl.GossipStream(c.offerCompression(ctx)); err == nil {
 		connInfo, err = c.authenticateRemotePeer(stream, true, false)
 		if err == nil {
 			pkiID = connInfo.ID
@@ -187,25 +241,25 @@ func (c *commImpl) createConnection(endpoint string, expectedPKIID common.PKIidT
 					c.identityChanges <- expectedPKIID
 				}
 			}
+			if err := c.checkOrgConnCap(connInfo); err != nil {
+				c.logger.Warningf("Closing connection to %s: %v", endpoint, err)
+				cc.Close()
+				cancel()
+				return nil, err
+			}
 			connConfig := ConnConfig{
-				RecvBuffSize: c.recvBuffSize,
-				SendBuffSize: c.sendBuffSize,
+				RecvBuffSize:   c.recvBuffSize,
+				SendBuffSize:   c.sendBuffSize,
+				MaxRecvMsgSize: c.maxRecvMsgSize,
 			}
 			conn := newConnection(cl, cc, stream, c.metrics, connConfig)
 			conn.pkiID = pkiID
 			conn.info = connInfo
 			conn.logger = c.logger
 			conn.cancel = cancel
+			conn.compression = c.acceptedCompression(stream)
 
-			h := func(m *protoext.SignedGossipMessage) {
-				c.logger.Debug("Got message:", m)
-				c.msgPublisher.DeMultiplex(&ReceivedMessageImpl{
-					conn:                conn,
-					SignedGossipMessage: m,
-					connInfo:            connInfo,
-				})
-			}
-			conn.handler = interceptAcks(h, connInfo.ID, c.pubSub)
+			conn.handler = interceptAcks(c.msgHandler(conn, connInfo), connInfo.ID, c.pubSub)
 			return conn, nil
 		}
 		c.logger.Warningf("Authentication failed: %+v", err)
@@ -250,6 +304,18 @@ func (c *commImpl) sendToEndpoint(peer *RemotePeer, msg *protoext.SignedGossipMe
 	c.disconnect(peer.PKIID)
 }
 
+// tunnelDialOpts returns the dial options that tunnel connections
+// to the given endpoint over WebSocket, if they are to be tunneled
+func (c *commImpl) tunnelDialOpts(endpoint string) []grpc.DialOption {
+	tunnelURL, tunneled := c.wsTunnels[endpoint]
+	if !tunneled {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return dialWebSocket(ctx, tunnelURL)
+	})}
+}
+
 func (c *commImpl) isStopping() bool {
 	return atomic.LoadInt32(&c.stopping) == int32(1)
 }
@@ -265,6 +331,7 @@ func (c *commImpl) Probe(remotePeer *RemotePeer) error {
 	dialOpts = append(dialOpts, c.secureDialOpts()...)
 	dialOpts = append(dialOpts, grpc.WithBlock())
 	dialOpts = append(dialOpts, c.opts...)
+	dialOpts = append(dialOpts, c.tunnelDialOpts(remotePeer.Endpoint)...)
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, c.dialTimeout)
 	defer cancel()
@@ -287,6 +354,7 @@ func (c *commImpl) Handshake(remotePeer *RemotePeer) (api.PeerIdentityType, erro
 	dialOpts = append(dialOpts, c.secureDialOpts()...)
 	dialOpts = append(dialOpts, grpc.WithBlock())
 	dialOpts = append(dialOpts, c.opts...)
+	dialOpts = append(dialOpts, c.tunnelDialOpts(remotePeer.Endpoint)...)
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, c.dialTimeout)
 	defer cancel()
@@ -388,6 +456,9 @@ func (c *commImpl) Stop() {
 	c.connStore.shutdown()
 	c.logger.Debug("Shut down connection store, connection count:", c.connStore.connNum())
 	c.msgPublisher.Close()
+	if c.fairQueue != nil {
+		c.fairQueue.close()
+	}
 	close(c.exitChan)
 	c.stopWG.Wait()
 	c.closeSubscriptions()
@@ -564,6 +635,7 @@ func (c *commImpl) GossipStream(stream proto.Gossip_GossipStreamServer) error {
 	if c.isStopping() {
 		return fmt.Errorf("Shutting down")
 	}
+	compression := c.selectCompression(stream)
 	connInfo, err := c.authenticateRemotePeer(stream, false, false)
 
 	if err == errProbe {
@@ -575,26 +647,135 @@ func (c *commImpl) GossipStream(stream proto.Gossip_GossipStreamServer) error {
 		c.logger.Errorf("Authentication failed: %v", err)
 		return err
 	}
+	if err := c.checkOrgConnCap(connInfo); err != nil {
+		c.logger.Warningf("Rejecting connection from %s: %v", extractRemoteAddress(stream), err)
+		return err
+	}
 	c.logger.Debug("Servicing", extractRemoteAddress(stream))
 
-	conn := c.connStore.onConnected(stream, connInfo, c.metrics)
+	conn := c.connStore.onConnected(stream, connInfo, compression, c.metrics)
+	if compression != "" {
+		c.logger.Debugf("Compressing messages to %s with %s", connInfo.Endpoint, compression)
+	}
+
+	conn.handler = interceptAcks(c.msgHandler(conn, connInfo), connInfo.ID, c.pubSub)
 
-	h := func(m *protoext.SignedGossipMessage) {
+	defer func() {
+		c.logger.Debug("Client", extractRemoteAddress(stream), " disconnected")
+		c.connStore.closeConnByPKIid(connInfo.ID)
+	}()
+
+	return conn.serviceConnection()
+}
+
+// msgHandler returns the handler of the messages received over the given connection,
+// which enforces the budget of the organization of the remote peer
+func (c *commImpl) msgHandler(conn *connection, connInfo *protoext.ConnectionInfo) handler {
+	dispatch := func(m *protoext.SignedGossipMessage) {
+		c.logger.Debug("Got message:", m)
 		c.msgPublisher.DeMultiplex(&ReceivedMessageImpl{
 			conn:                conn,
 			SignedGossipMessage: m,
 			connInfo:            connInfo,
 		})
 	}
+	if !c.budgets.enabled() && c.fairQueue == nil {
+		return dispatch
+	}
+
+	org := c.sa.OrgByPeerIdentity(connInfo.Identity)
+	budgeted := !bytes.Equal(org, c.sa.OrgByPeerIdentity(c.peerIdentity))
+	return func(m *protoext.SignedGossipMessage) {
+		if budgeted && !c.budgets.allow(string(org), envelopeSize(m.Envelope)) {
+			c.dropMessage(string(org), dropReasonBudget, connInfo, m)
+			return
+		}
+		if c.fairQueue == nil {
+			dispatch(m)
+			return
+		}
+		msg := &ReceivedMessageImpl{
+			conn:                conn,
+			SignedGossipMessage: m,
+			connInfo:            connInfo,
+		}
+		if !c.fairQueue.enqueue(string(org), msg) {
+			c.dropMessage(string(org), dropReasonQueue, connInfo, m)
+		}
+	}
+}
 
-	conn.handler = interceptAcks(h, connInfo.ID, c.pubSub)
+func (c *commImpl) dropMessage(org, reason string, connInfo *protoext.ConnectionInfo, m *protoext.SignedGossipMessage) {
+	c.metrics.DroppedMessages.With("org", org, "reason", reason).Add(1)
+	c.logger.Debugf("Dropping message from %s of %s which exceeded its %s: %s", connInfo.Endpoint, org, reason, m)
+}
 
-	defer func() {
-		c.logger.Debug("Client", extractRemoteAddress(stream), " disconnected")
-		c.connStore.closeConnByPKIid(connInfo.ID)
-	}()
+func envelopeSize(envelope *proto.Envelope) int {
+	if envelope == nil {
+		return 0
+	}
+	return len(envelope.Payload) + len(envelope.Signature) + len(envelope.GetSecretEnvelope().GetPayload())
+}
 
-	return conn.serviceConnection()
+// checkOrgConnCap returns an error if we are connected to MaxConnectionsPerOrg peers
+// of the organization of the given remote peer, besides the remote peer itself
+func (c *commImpl) checkOrgConnCap(connInfo *protoext.ConnectionInfo) error {
+	if c.maxConnsPerOrg == 0 {
+		return nil
+	}
+	org := c.sa.OrgByPeerIdentity(connInfo.Identity)
+	if bytes.Equal(org, c.sa.OrgByPeerIdentity(c.peerIdentity)) {
+		return nil
+	}
+	orgOf := func(conn *connection) bool {
+		return bytes.Equal(org, c.sa.OrgByPeerIdentity(conn.info.Identity))
+	}
+	if c.connStore.connNumOf(orgOf, connInfo.ID) >= c.maxConnsPerOrg {
+		return errors.Errorf("reached the cap of %d connections to peers of %s", c.maxConnsPerOrg, string(org))
+	}
+	return nil
+}
+
+// offerCompression adds the compression codecs this peer supports to the metadata of the gossip stream
+func (c *commImpl) offerCompression(ctx context.Context) context.Context {
+	if len(c.compression) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, compressionMetadataKey, strings.Join(c.compression, ","))
+}
+
+// acceptedCompression returns the compression codec the remote peer picked among the offered ones
+func (c *commImpl) acceptedCompression(stream proto.Gossip_GossipStreamClient) string {
+	if len(c.compression) == 0 {
+		return ""
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return ""
+	}
+	accepted := header.Get(compressionMetadataKey)
+	if len(accepted) == 0 {
+		return ""
+	}
+	return negotiateCompression(c.compression, accepted[0])
+}
+
+// selectCompression picks the preferred compression codec among the ones offered by the remote peer, if any,
+// and returns it to the remote peer in the header of the gossip stream
+func (c *commImpl) selectCompression(stream proto.Gossip_GossipStreamServer) string {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok || len(md.Get(compressionMetadataKey)) == 0 {
+		return ""
+	}
+	codec := negotiateCompression(c.compression, md.Get(compressionMetadataKey)[0])
+	if codec == "" {
+		return ""
+	}
+	if err := stream.SetHeader(metadata.Pairs(compressionMetadataKey, codec)); err != nil {
+		c.logger.Warningf("Failed accepting compression codec %s: %v", codec, err)
+		return ""
+	}
+	return codec
 }
 
 func (c *commImpl) Ping(context.Context, *proto.Empty) (*proto.Empty, error) {
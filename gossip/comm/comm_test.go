@@ -39,6 +39,7 @@ import (
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
@@ -511,6 +512,123 @@ func TestConnectUnexpectedPeer(t *testing.T) {
 	})
 }
 
+func TestMaxConnectionsPerOrg(t *testing.T) {
+	// Scenario: comm1 is allowed a single connection to the peers of each other organization.
+	// It connects to comm2, and then neither connects to comm3 which is in the same organization,
+	// nor accepts a connection from it. It can still connect to comm4 which is in another organization.
+
+	identityByPort := func(port int) api.PeerIdentityType {
+		return api.PeerIdentityType(fmt.Sprintf("127.0.0.1:%d", port))
+	}
+
+	customNaiveSec := &naiveSecProvider{}
+
+	comm1Port, gRPCServer1, certs1, secureDialOpts1, dialOpts1 := util.CreateGRPCLayer()
+	comm2Port, gRPCServer2, certs2, secureDialOpts2, dialOpts2 := util.CreateGRPCLayer()
+	comm3Port, gRPCServer3, certs3, secureDialOpts3, dialOpts3 := util.CreateGRPCLayer()
+	comm4Port, gRPCServer4, certs4, secureDialOpts4, dialOpts4 := util.CreateGRPCLayer()
+
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm1Port)).Return(api.OrgIdentityType("O"))
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm2Port)).Return(api.OrgIdentityType("A"))
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm3Port)).Return(api.OrgIdentityType("A"))
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm4Port)).Return(api.OrgIdentityType("B"))
+
+	comm1 := newCommInstanceOnly(t, customNaiveSec, gRPCServer1, certs1, secureDialOpts1, dialOpts1...)
+	comm1.(*commGRPC).maxConnsPerOrg = 1
+	comm2 := newCommInstanceOnly(t, naiveSec, gRPCServer2, certs2, secureDialOpts2, dialOpts2...)
+	comm3 := newCommInstanceOnly(t, naiveSec, gRPCServer3, certs3, secureDialOpts3, dialOpts3...)
+	comm4 := newCommInstanceOnly(t, naiveSec, gRPCServer4, certs4, secureDialOpts4, dialOpts4...)
+
+	defer comm1.Stop()
+	defer comm2.Stop()
+	defer comm3.Stop()
+	defer comm4.Stop()
+
+	messagesForComm1 := comm1.Accept(acceptAll)
+	messagesForComm2 := comm2.Accept(acceptAll)
+	messagesForComm3 := comm3.Accept(acceptAll)
+	messagesForComm4 := comm4.Accept(acceptAll)
+
+	comm1.Send(createGossipMsg(), remotePeer(comm2Port))
+	select {
+	case <-messagesForComm2:
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "Didn't receive a message within a timely manner")
+	}
+
+	comm1.Send(createGossipMsg(), remotePeer(comm3Port))
+	select {
+	case <-messagesForComm3:
+		assert.Fail(t, "Message shouldn't have been received")
+	case <-time.After(time.Second * 2):
+	}
+
+	comm3.Send(createGossipMsg(), remotePeer(comm1Port))
+	select {
+	case <-messagesForComm1:
+		assert.Fail(t, "Message shouldn't have been received")
+	case <-time.After(time.Second * 2):
+	}
+
+	comm1.Send(createGossipMsg(), remotePeer(comm4Port))
+	select {
+	case <-messagesForComm4:
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "Didn't receive a message within a timely manner")
+	}
+}
+
+func TestWebSocketTunnel(t *testing.T) {
+	// Scenario: comm1 can't reach comm2 at the endpoint it is given, but
+	// tunnels its connection to comm2 over WebSocket, through which they communicate both ways.
+	// comm2 accepts connections over WebSocket on a gRPC server of its own.
+
+	comm1, _ := newCommInstance(t, naiveSec)
+	comm2Port, gRPCServer2, certs2, secureDialOpts2, dialOpts2 := util.CreateGRPCLayer()
+	comm2 := newCommInstanceOnly(t, naiveSec, gRPCServer2, certs2, secureDialOpts2, dialOpts2...)
+	defer comm1.Stop()
+	defer comm2.Stop()
+
+	wsListener, err := ListenWebSocket("127.0.0.1:0")
+	require.NoError(t, err)
+	wsServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{*certs2.TLSServerCert.Load().(*tls.Certificate)},
+		ClientAuth:   tls.RequestClientCert,
+	})))
+	comm2.Register(wsServer)
+	go wsServer.Serve(wsListener)
+	defer wsServer.Stop()
+
+	_, unreachableEndpoint, ll := getAvailablePort(t)
+	ll.Close()
+	comm1.(*commGRPC).wsTunnels = map[string]string{
+		unreachableEndpoint: fmt.Sprintf("ws://%s/gossip", wsListener.Addr()),
+	}
+
+	messagesForComm1 := comm1.Accept(acceptAll)
+	messagesForComm2 := comm2.Accept(acceptAll)
+
+	comm2Peer := &RemotePeer{
+		Endpoint: unreachableEndpoint,
+		PKIID:    remotePeer(comm2Port).PKIID,
+	}
+	assert.NoError(t, comm1.Probe(comm2Peer))
+	comm1.Send(createGossipMsg(), comm2Peer)
+	var msg protoext.ReceivedMessage
+	select {
+	case msg = <-messagesForComm2:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Didn't receive a message within a timely manner")
+	}
+
+	msg.Respond(createGossipMsg().GossipMessage)
+	select {
+	case <-messagesForComm1:
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "Didn't receive a response within a timely manner")
+	}
+}
+
 func TestGetConnectionInfo(t *testing.T) {
 	comm1, port1 := newCommInstance(t, naiveSec)
 	comm2, _ := newCommInstance(t, naiveSec)
@@ -931,7 +1049,7 @@ func TestReadFromStream(t *testing.T) {
 	stream.On("Recv").Return(&proto.Envelope{Payload: []byte{1}}, nil).Once()
 	stream.On("Recv").Return(nil, errors.New("stream closed")).Once()
 
-	conn := newConnection(nil, nil, stream, disabledMetrics, ConnConfig{1, 1})
+	conn := newConnection(nil, nil, stream, disabledMetrics, ConnConfig{RecvBuffSize: 1, SendBuffSize: 1})
 	conn.logger = flogging.MustGetLogger("test")
 
 	errChan := make(chan error, 2)
@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"strings"
+
+	"github.com/golang/snappy"
+	proto "github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/pkg/errors"
+)
+
+// Supported compression codecs of the gossip messages
+const (
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+)
+
+// The compression codecs are negotiated when a connection is established: the initiator of the
+// connection lists the codecs it supports in the metadata of the gossip stream, and the remote peer
+// picks its preferred codec among them and returns it in the header of the stream. Peers that do not
+// support the compression ignore the metadata, hence they keep on receiving uncompressed messages.
+//
+// A compressed payload of an envelope begins with compressedPayloadMarker and the codec used for the
+// compression, followed by the compressed bytes. As a marshaled gossip message never begins with a zero
+// byte, the marker keeps compressed payloads distinguishable from the uncompressed ones. The signature
+// of the envelope is computed over the uncompressed payload, and is verified once the payload is decompressed.
+const (
+	compressionMetadataKey  = "gossip-compression"
+	compressedPayloadMarker = byte(0x00)
+	zstdCodec               = byte(0x01)
+	snappyCodec             = byte(0x02)
+	zstdCompressionLevel    = 3
+	// compressionThreshold is the size of the payloads below which the messages are sent uncompressed
+	compressionThreshold = 1024
+)
+
+var compressionCodecs = map[string]byte{
+	CompressionZstd:   zstdCodec,
+	CompressionSnappy: snappyCodec,
+}
+
+// supportedCompression returns the given codecs that are supported, in the same order
+func supportedCompression(codecs []string, logger util.Logger) []string {
+	var supported []string
+	for _, codec := range codecs {
+		codec = strings.ToLower(strings.TrimSpace(codec))
+		if _, exists := compressionCodecs[codec]; !exists {
+			logger.Warningf("Ignoring unknown compression codec %s", codec)
+			continue
+		}
+		if codec == CompressionZstd && !zstdSupported {
+			logger.Warningf("Ignoring compression codec %s as it requires building with cgo enabled", codec)
+			continue
+		}
+		supported = append(supported, codec)
+	}
+	return supported
+}
+
+// negotiateCompression returns the first of the preferred codecs that is among
+// the comma separated offered codecs, or an empty string if there is none
+func negotiateCompression(preferred []string, offered string) string {
+	for _, codec := range preferred {
+		for _, o := range strings.Split(offered, ",") {
+			if codec == strings.TrimSpace(o) {
+				return codec
+			}
+		}
+	}
+	return ""
+}
+
+// compressible returns whether the message is worth compressing, namely, the state
+// transfer responses and the private data that travel between organizations
+func compressible(msg *protoext.SignedGossipMessage) bool {
+	return msg.GetStateResponse() != nil || msg.GetPrivateData() != nil || msg.GetPrivateRes() != nil
+}
+
+// compressEnvelope returns an envelope with the payload of the given envelope compressed with the
+// given codec, or the given envelope if its payload is too small or does not shrink by compressing it
+func compressEnvelope(codec string, env *proto.Envelope) (*proto.Envelope, error) {
+	if len(env.Payload) < compressionThreshold {
+		return env, nil
+	}
+
+	var compressed []byte
+	var err error
+	switch codec {
+	case CompressionZstd:
+		compressed, err = zstdCompress(env.Payload)
+	case CompressionSnappy:
+		compressed = snappy.Encode(nil, env.Payload)
+	default:
+		err = errors.Errorf("unknown compression codec %s", codec)
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compressing payload")
+	}
+	if len(compressed)+2 >= len(env.Payload) {
+		return env, nil
+	}
+
+	payload := make([]byte, 0, len(compressed)+2)
+	payload = append(payload, compressedPayloadMarker, compressionCodecs[codec])
+	payload = append(payload, compressed...)
+	return &proto.Envelope{
+		Payload:        payload,
+		Signature:      env.Signature,
+		SecretEnvelope: env.SecretEnvelope,
+	}, nil
+}
+
+// decompressEnvelope returns an envelope with the payload of the given envelope decompressed,
+// or the given envelope if its payload is not compressed. Payloads decompressing to more than
+// maxSize bytes are rejected, without being decompressed any further.
+func decompressEnvelope(env *proto.Envelope, maxSize int) (*proto.Envelope, error) {
+	if len(env.Payload) == 0 || env.Payload[0] != compressedPayloadMarker {
+		return env, nil
+	}
+	if len(env.Payload) < 2 {
+		return nil, errors.New("compressed payload is truncated")
+	}
+
+	var payload []byte
+	var err error
+	switch env.Payload[1] {
+	case zstdCodec:
+		payload, err = zstdDecompress(env.Payload[2:], maxSize)
+	case snappyCodec:
+		payload, err = snappyDecompress(env.Payload[2:], maxSize)
+	default:
+		return nil, errors.Errorf("unsupported compression codec [%d]", env.Payload[1])
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decompressing payload")
+	}
+
+	return &proto.Envelope{
+		Payload:        payload,
+		Signature:      env.Signature,
+		SecretEnvelope: env.SecretEnvelope,
+	}, nil
+}
+
+func snappyDecompress(b []byte, maxSize int) ([]byte, error) {
+	size, err := snappy.DecodedLen(b)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSize {
+		return nil, errors.Errorf("decompressed payload size %d exceeds the maximum of %d bytes", size, maxSize)
+	}
+	return snappy.Decode(nil, b)
+}
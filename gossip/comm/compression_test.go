@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	proto "github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric/gossip/identity"
+	"github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func stateResponse(payloadSize int) *protoext.SignedGossipMessage {
+	msg, _ := protoext.NoopSign(&proto.GossipMessage{
+		Tag: proto.GossipMessage_CHAN_OR_ORG,
+		Content: &proto.GossipMessage_StateResponse{
+			StateResponse: &proto.RemoteStateResponse{
+				Payloads: []*proto.Payload{{SeqNum: 1, Data: bytes.Repeat([]byte("block"), payloadSize/5)}},
+			},
+		},
+	})
+	return msg
+}
+
+func TestCompressEnvelope(t *testing.T) {
+	logger := util.GetLogger(util.CommLogger, "")
+	codecs := supportedCompression([]string{"snappy", "lz4", " ZSTD "}, logger)
+	if zstdSupported {
+		assert.Equal(t, []string{CompressionSnappy, CompressionZstd}, codecs)
+	} else {
+		assert.Equal(t, []string{CompressionSnappy}, codecs)
+	}
+
+	env := stateResponse(10000).Envelope
+	for _, codec := range codecs {
+		t.Run(codec, func(t *testing.T) {
+			compressed, err := compressEnvelope(codec, env)
+			assert.NoError(t, err)
+			assert.True(t, len(compressed.Payload) < len(env.Payload))
+			assert.Equal(t, compressedPayloadMarker, compressed.Payload[0])
+
+			decompressed, err := decompressEnvelope(compressed, DefMaxRecvMsgSize)
+			assert.NoError(t, err)
+			assert.Equal(t, env.Payload, decompressed.Payload)
+			assert.Equal(t, env.Signature, decompressed.Signature)
+
+			decompressed, err = decompressEnvelope(compressed, len(env.Payload))
+			assert.NoError(t, err)
+			assert.Equal(t, env.Payload, decompressed.Payload)
+
+			// payloads decompressing beyond the maximum size are rejected
+			_, err = decompressEnvelope(compressed, len(env.Payload)-1)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), fmt.Sprintf("exceeds the maximum of %d bytes", len(env.Payload)-1))
+		})
+	}
+
+	t.Run("small payload", func(t *testing.T) {
+		small := stateResponse(100).Envelope
+		compressed, err := compressEnvelope(CompressionSnappy, small)
+		assert.NoError(t, err)
+		assert.Equal(t, small, compressed)
+	})
+
+	t.Run("uncompressed payload", func(t *testing.T) {
+		decompressed, err := decompressEnvelope(env, DefMaxRecvMsgSize)
+		assert.NoError(t, err)
+		assert.Equal(t, env, decompressed)
+	})
+
+	t.Run("unknown codec", func(t *testing.T) {
+		_, err := decompressEnvelope(&proto.Envelope{Payload: []byte{compressedPayloadMarker, 9, 1, 2}}, DefMaxRecvMsgSize)
+		assert.EqualError(t, err, "unsupported compression codec [9]")
+		_, err = decompressEnvelope(&proto.Envelope{Payload: []byte{compressedPayloadMarker}}, DefMaxRecvMsgSize)
+		assert.EqualError(t, err, "compressed payload is truncated")
+	})
+
+	t.Run("corrupted payload", func(t *testing.T) {
+		_, err := decompressEnvelope(&proto.Envelope{Payload: []byte{compressedPayloadMarker, snappyCodec, 0xff, 0xff}}, DefMaxRecvMsgSize)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed decompressing payload")
+	})
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	assert.Equal(t, "snappy", negotiateCompression([]string{"snappy", "zstd"}, "zstd,snappy"))
+	assert.Equal(t, "zstd", negotiateCompression([]string{"zstd"}, "snappy, zstd"))
+	assert.Equal(t, "", negotiateCompression([]string{"zstd"}, "snappy"))
+	assert.Equal(t, "", negotiateCompression(nil, "snappy"))
+}
+
+func newCommInstanceWithCompression(t *testing.T, compression ...string) (*commGRPC, int) {
+	port, gRPCServer, certs, secureDialOpts, dialOpts := util.CreateGRPCLayer()
+	_, portString, err := net.SplitHostPort(gRPCServer.Address())
+	assert.NoError(t, err)
+	id := []byte(fmt.Sprintf("127.0.0.1:%s", portString))
+	identityMapper := identity.NewIdentityMapper(naiveSec, id, noopPurgeIdentity, naiveSec)
+
+	config := testCommConfig
+	config.Compression = compression
+	commInst, err := NewCommInstance(gRPCServer.Server(), certs, identityMapper, id, secureDialOpts,
+		naiveSec, disabledMetrics, config, dialOpts...)
+	assert.NoError(t, err)
+	go gRPCServer.Start()
+
+	return &commGRPC{commInst.(*commImpl), gRPCServer}, port
+}
+
+func connCompression(c *commGRPC, port int) string {
+	c.connStore.RLock()
+	defer c.connStore.RUnlock()
+	conn, exists := c.connStore.pki2Conn[string(remotePeer(port).PKIID)]
+	if !exists {
+		return "not connected"
+	}
+	return conn.compression
+}
+
+func TestCompressedMessages(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		initiator, responder []string
+		expected             string
+	}{
+		{name: "both peers support snappy", initiator: []string{"zstd", "snappy"}, responder: []string{"snappy"}, expected: "snappy"},
+		{name: "responder prefers snappy", initiator: []string{"snappy"}, responder: []string{"lz4", "snappy"}, expected: "snappy"},
+		{name: "responder without compression", initiator: []string{"snappy"}},
+		{name: "initiator without compression", responder: []string{"snappy"}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			comm1, port1 := newCommInstanceWithCompression(t, tc.initiator...)
+			defer comm1.Stop()
+			comm2, port2 := newCommInstanceWithCompression(t, tc.responder...)
+			defer comm2.Stop()
+
+			ch1 := comm1.Accept(acceptAll)
+			ch2 := comm2.Accept(acceptAll)
+
+			msg := stateResponse(10000)
+			comm1.Send(msg, remotePeer(port2))
+			select {
+			case m := <-ch2:
+				assert.Equal(t, msg.Envelope.Payload, m.GetGossipMessage().Envelope.Payload)
+				assert.Equal(t, msg.GetStateResponse().Payloads[0].Data, m.GetGossipMessage().GetStateResponse().Payloads[0].Data)
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for the message")
+			}
+
+			assert.Equal(t, tc.expected, connCompression(comm1, port2))
+			assert.Equal(t, tc.expected, connCompression(comm2, port1))
+
+			// and the other way around, over the same connection
+			comm2.Send(msg, remotePeer(port1))
+			select {
+			case m := <-ch1:
+				assert.Equal(t, msg.Envelope.Payload, m.GetGossipMessage().Envelope.Payload)
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for the message")
+			}
+		})
+	}
+}
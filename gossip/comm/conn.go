@@ -10,6 +10,7 @@ import (
 	"context"
 	"sync"
 
+	protobuf "github.com/golang/protobuf/proto"
 	proto "github.com/hyperledger/fabric-protos-go/gossip"
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/metrics"
@@ -144,6 +145,19 @@ func (cs *connectionStore) connNum() int {
 	return len(cs.pki2Conn)
 }
 
+// connNumOf returns the number of connections that match the given predicate, other than the connection to the given peer
+func (cs *connectionStore) connNumOf(predicate func(*connection) bool, except common.PKIidType) int {
+	cs.RLock()
+	defer cs.RUnlock()
+	count := 0
+	for pkiID, conn := range cs.pki2Conn {
+		if pkiID != string(except) && conn.info != nil && predicate(conn) {
+			count++
+		}
+	}
+	return count
+}
+
 func (cs *connectionStore) shutdown() {
 	cs.shutdownOnce.Do(func() {
 		cs.Lock()
@@ -161,7 +175,7 @@ func (cs *connectionStore) shutdown() {
 // onConnected closes any connection to the remote peer and creates a new connection object to it in order to have only
 // one single bi-directional connection between a pair of peers
 func (cs *connectionStore) onConnected(serverStream proto.Gossip_GossipStreamServer,
-	connInfo *protoext.ConnectionInfo, metrics *metrics.CommMetrics) *connection {
+	connInfo *protoext.ConnectionInfo, compression string, metrics *metrics.CommMetrics) *connection {
 	cs.Lock()
 	defer cs.Unlock()
 
@@ -173,6 +187,7 @@ func (cs *connectionStore) onConnected(serverStream proto.Gossip_GossipStreamSer
 	conn.pkiID = connInfo.ID
 	conn.info = connInfo
 	conn.logger = cs.logger
+	conn.compression = compression
 	cs.pki2Conn[string(connInfo.ID)] = conn
 	return conn
 }
@@ -195,6 +210,10 @@ func newConnection(cl proto.GossipClient, c *grpc.ClientConn, s stream, metrics
 		gossipStream: s,
 		stopChan:     make(chan struct{}, 1),
 		recvBuffSize: config.RecvBuffSize,
+		maxRecvSize:  config.MaxRecvMsgSize,
+	}
+	if connection.maxRecvSize == 0 {
+		connection.maxRecvSize = DefMaxRecvMsgSize
 	}
 	return connection
 }
@@ -203,10 +222,14 @@ func newConnection(cl proto.GossipClient, c *grpc.ClientConn, s stream, metrics
 type ConnConfig struct {
 	RecvBuffSize int
 	SendBuffSize int
+	// MaxRecvMsgSize is the size beyond which the decompressed messages are rejected,
+	// zero meaning the default maximum size of the gRPC messages
+	MaxRecvMsgSize int
 }
 
 type connection struct {
 	recvBuffSize int
+	maxRecvSize  int
 	metrics      *metrics.CommMetrics
 	cancel       context.CancelFunc
 	info         *protoext.ConnectionInfo
@@ -219,6 +242,7 @@ type connection struct {
 	gossipStream stream             // there can only be one
 	stopChan     chan struct{}      // a method to stop the server-side gRPC call from a different go-routine
 	stopOnce     sync.Once          // once to ensure close is called only once
+	compression  string             // the compression codec negotiated with the remote endpoint, if any
 }
 
 func (conn *connection) close() {
@@ -239,6 +263,8 @@ func (conn *connection) send(msg *protoext.SignedGossipMessage, onErr func(error
 	m := &msgSending{
 		envelope: msg.Envelope,
 		onErr:    onErr,
+		compress: compressible(msg),
+		channel:  string(msg.Channel),
 	}
 
 	select {
@@ -289,12 +315,22 @@ func (conn *connection) writeToStream() {
 	for {
 		select {
 		case m := <-conn.outBuff:
-			err := stream.Send(m.envelope)
+			envelope := m.envelope
+			if m.compress && conn.compression != "" {
+				compressed, err := compressEnvelope(conn.compression, envelope)
+				if err != nil {
+					conn.logger.Warningf("Sending message to %s uncompressed: %v", conn.info.Endpoint, err)
+				} else {
+					envelope = compressed
+				}
+			}
+			err := stream.Send(envelope)
 			if err != nil {
 				go m.onErr(err)
 				return
 			}
 			conn.metrics.SentMessages.Add(1)
+			conn.metrics.SentBytes.With("channel", m.channel).Add(float64(protobuf.Size(envelope)))
 		case <-conn.stopChan:
 			conn.logger.Debug("Closing writing to stream")
 			return
@@ -316,12 +352,20 @@ func (conn *connection) readFromStream(errChan chan error, msgChan chan *protoex
 				return
 			}
 			conn.metrics.ReceivedMessages.Add(1)
+			size := protobuf.Size(envelope)
+			envelope, err = decompressEnvelope(envelope, conn.maxRecvSize)
+			if err != nil {
+				errChan <- err
+				conn.logger.Warningf("Got error, aborting: %v", err)
+				return
+			}
 			msg, err := protoext.EnvelopeToGossipMessage(envelope)
 			if err != nil {
 				errChan <- err
 				conn.logger.Warningf("Got error, aborting: %v", err)
 				return
 			}
+			conn.metrics.ReceivedBytes.With("channel", string(msg.Channel)).Add(float64(size))
 			select {
 			case <-conn.stopChan:
 			case msgChan <- msg:
@@ -333,6 +377,9 @@ func (conn *connection) readFromStream(errChan chan error, msgChan chan *protoex
 type msgSending struct {
 	envelope *proto.Envelope
 	onErr    func(error)
+	compress bool
+	// channel is the channel the message belongs to, empty if none
+	channel string
 }
 
 //go:generate mockery -dir . -name MockStream -case underscore -output mocks/
@@ -7,12 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package comm
 
 import (
+	"fmt"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	proto "github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric/gossip/api"
 	"github.com/hyperledger/fabric/gossip/metrics"
 	"github.com/hyperledger/fabric/gossip/metrics/mocks"
+	"github.com/hyperledger/fabric/gossip/protoext"
 	"github.com/hyperledger/fabric/gossip/util"
 	"github.com/stretchr/testify/assert"
 )
@@ -82,3 +86,73 @@ func TestMetrics(t *testing.T) {
 
 	assert.Equal(t, uint32(1), atomic.LoadUint32(&overflown))
 }
+
+func TestDroppedMessagesMetrics(t *testing.T) {
+	// Scenario: comm1 allows the peers of each other organization 5 messages per second.
+	// comm2 sends it a burst of 20 messages, most of which are dropped.
+	testMetricProvider := mocks.TestUtilConstructMetricProvider()
+	fakeCommMetrics := metrics.NewGossipMetrics(testMetricProvider.FakeProvider).CommMetrics
+
+	identityByPort := func(port int) api.PeerIdentityType {
+		return api.PeerIdentityType(fmt.Sprintf("127.0.0.1:%d", port))
+	}
+	customNaiveSec := &naiveSecProvider{}
+	comm1Port, gRPCServer1, certs1, secureDialOpts1, dialOpts1 := util.CreateGRPCLayer()
+	comm2Port, gRPCServer2, certs2, secureDialOpts2, dialOpts2 := util.CreateGRPCLayer()
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm1Port)).Return(api.OrgIdentityType("O"))
+	customNaiveSec.On("OrgByPeerIdentity", identityByPort(comm2Port)).Return(api.OrgIdentityType("A"))
+
+	comm1 := newCommInstanceOnlyWithMetrics(t, fakeCommMetrics, customNaiveSec, gRPCServer1, certs1, secureDialOpts1, dialOpts1...)
+	comm1.(*commGRPC).budgets = newOrgBudgets(OrgBudget{MessageRate: 5})
+	comm2 := newCommInstanceOnly(t, naiveSec, gRPCServer2, certs2, secureDialOpts2, dialOpts2...)
+	defer comm1.Stop()
+	defer comm2.Stop()
+
+	messagesForComm1 := comm1.Accept(acceptAll)
+	for i := 0; i < 20; i++ {
+		comm2.Send(createGossipMsg(), remotePeer(comm1Port))
+	}
+
+	var received int
+	for {
+		select {
+		case <-messagesForComm1:
+			received++
+			continue
+		case <-time.After(time.Second):
+		}
+		break
+	}
+	assert.True(t, received >= 5 && received < 20, "received %d messages", received)
+	assert.Equal(t, 20-received, testMetricProvider.FakeDroppedMessages.AddCallCount())
+	assert.Equal(t, []string{"org", "A", "reason", "budget"}, testMetricProvider.FakeDroppedMessages.WithArgsForCall(0))
+}
+
+func TestBandwidthMetrics(t *testing.T) {
+	testMetricProvider := mocks.TestUtilConstructMetricProvider()
+	fakeCommMetrics := metrics.NewGossipMetrics(testMetricProvider.FakeProvider).CommMetrics
+
+	comm1, _ := newCommInstanceWithMetrics(t, naiveSec, fakeCommMetrics)
+	comm2, port2 := newCommInstanceWithMetrics(t, naiveSec, fakeCommMetrics)
+	defer comm1.Stop()
+	defer comm2.Stop()
+
+	fromComm1 := comm2.Accept(acceptAll)
+	msg, _ := protoext.NoopSign(&proto.GossipMessage{
+		Tag:     proto.GossipMessage_CHAN_ONLY,
+		Channel: []byte("mychannel"),
+		Content: &proto.GossipMessage_DataMsg{
+			DataMsg: &proto.DataMessage{Payload: &proto.Payload{Data: make([]byte, 1000)}},
+		},
+	})
+	comm1.Send(msg, remotePeer(port2))
+	<-fromComm1
+
+	// the sender counts the bytes once the message is written to the stream
+	assert.Eventually(t, func() bool { return testMetricProvider.FakeSentBytes.AddCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"channel", "mychannel"}, testMetricProvider.FakeSentBytes.WithArgsForCall(0))
+	assert.True(t, testMetricProvider.FakeSentBytes.AddArgsForCall(0) > 1000)
+	assert.Equal(t, 1, testMetricProvider.FakeReceivedBytes.AddCallCount())
+	assert.Equal(t, []string{"channel", "mychannel"}, testMetricProvider.FakeReceivedBytes.WithArgsForCall(0))
+	assert.Equal(t, testMetricProvider.FakeSentBytes.AddArgsForCall(0), testMetricProvider.FakeReceivedBytes.AddArgsForCall(0))
+}
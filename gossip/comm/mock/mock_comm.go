@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/protoext"
 	"github.com/hyperledger/fabric/gossip/util"
+	"google.golang.org/grpc"
 )
 
 // Mock which aims to simulate socket
@@ -190,6 +191,11 @@ func (mock *commMock) CloseConn(peer *comm.RemotePeer) {
 	// NOOP
 }
 
+// Register binds the module to another gRPC server
+func (mock *commMock) Register(s *grpc.Server) {
+	// NOOP
+}
+
 // Stop stops the module
 func (mock *commMock) Stop() {
 	logger.Debug("Stopping communication module, closing all accepting channels.")
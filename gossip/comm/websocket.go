@@ -0,0 +1,427 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Gossip connections can be tunneled over WebSocket (RFC 6455), so that peers behind firewalls
+// that only allow outbound HTTP(S) can still connect to other peers. The tunnel carries the
+// gRPC connection as is, so when TLS is enabled the gossip traffic stays encrypted end to end
+// regardless of whether the tunnel itself is a ws:// or a wss:// one.
+
+const (
+	webSocketGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	webSocketVersion = "13"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	maxControlPayload = 125
+)
+
+// webSocketConn is a net.Conn that sends and receives data in WebSocket binary frames
+type webSocketConn struct {
+	net.Conn
+	r      *bufio.Reader
+	client bool
+
+	readLock  sync.Mutex
+	remaining uint64
+	masked    bool
+	mask      [4]byte
+	maskPos   int
+
+	writeLock sync.Mutex
+	closeOnce sync.Once
+}
+
+func newWebSocketConn(conn net.Conn, r *bufio.Reader, client bool) *webSocketConn {
+	return &webSocketConn{
+		Conn:   conn,
+		r:      r,
+		client: client,
+	}
+}
+
+// Read reads the payload of data frames, and handles the control frames in between
+func (wc *webSocketConn) Read(p []byte) (int, error) {
+	wc.readLock.Lock()
+	defer wc.readLock.Unlock()
+
+	for wc.remaining == 0 {
+		if err := wc.nextDataFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	if uint64(len(p)) > wc.remaining {
+		p = p[:wc.remaining]
+	}
+	n, err := wc.r.Read(p)
+	if wc.masked {
+		for i := 0; i < n; i++ {
+			p[i] ^= wc.mask[wc.maskPos%4]
+			wc.maskPos++
+		}
+	}
+	wc.remaining -= uint64(n)
+	return n, err
+}
+
+func (wc *webSocketConn) nextDataFrame() error {
+	for {
+		op, length, err := wc.readFrameHeader()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opContinuation, opText, opBinary:
+			wc.remaining = length
+			return nil
+		case opClose, opPing, opPong:
+			if length > maxControlPayload {
+				return errors.Errorf("control frame payload of %d bytes is too long", length)
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(wc.r, payload); err != nil {
+				return err
+			}
+			if op == opClose {
+				wc.writeClose()
+				return io.EOF
+			}
+			if op == opPing {
+				if err := wc.writeFrame(opPong, wc.unmask(payload)); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.Errorf("unknown WebSocket opcode %d", op)
+		}
+	}
+}
+
+func (wc *webSocketConn) readFrameHeader() (byte, uint64, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(wc.r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	op := header[0] & 0x0F
+	wc.masked = header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(wc.r, ext[:]); err != nil {
+			return 0, 0, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(wc.r, ext[:]); err != nil {
+			return 0, 0, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if wc.masked {
+		if _, err := io.ReadFull(wc.r, wc.mask[:]); err != nil {
+			return 0, 0, err
+		}
+	}
+	wc.maskPos = 0
+	return op, length, nil
+}
+
+func (wc *webSocketConn) unmask(payload []byte) []byte {
+	if wc.masked {
+		for i := range payload {
+			payload[i] ^= wc.mask[i%4]
+		}
+	}
+	return payload
+}
+
+// Write writes p in a single binary frame
+func (wc *webSocketConn) Write(p []byte) (int, error) {
+	if err := wc.writeFrame(opBinary, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (wc *webSocketConn) writeFrame(op byte, payload []byte) error {
+	wc.writeLock.Lock()
+	defer wc.writeLock.Unlock()
+
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | op
+	length := len(payload)
+	switch {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	// Frames sent by clients must be masked
+	if wc.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return errors.Wrap(err, "failed generating WebSocket frame mask")
+		}
+		header[1] |= 0x80
+		header = append(header, mask[:]...)
+		masked := make([]byte, length)
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := wc.Conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wc *webSocketConn) writeClose() {
+	wc.closeOnce.Do(func() {
+		wc.writeFrame(opClose, nil)
+	})
+}
+
+// Close sends a close frame and closes the underlying connection
+func (wc *webSocketConn) Close() error {
+	wc.SetWriteDeadline(time.Now().Add(time.Second))
+	wc.writeClose()
+	return wc.Conn.Close()
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// dialWebSocket opens a WebSocket connection to the given ws:// or wss:// URL
+func dialWebSocket(ctx context.Context, rawURL string) (net.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid WebSocket URL %s", rawURL)
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, errors.Errorf("invalid WebSocket URL %s: scheme must be ws or wss", rawURL)
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		conn = tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	wc, err := webSocketHandshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return wc, nil
+}
+
+func webSocketHandshake(conn net.Conn, u *url.URL) (*webSocketConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating WebSocket key")
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {webSocketVersion},
+		},
+		Host: u.Host,
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, errors.Wrap(err, "failed sending WebSocket handshake")
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading WebSocket handshake response")
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, errors.Errorf("WebSocket handshake with %s failed: %s", u, resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		return nil, errors.Errorf("WebSocket handshake with %s failed: invalid Sec-WebSocket-Accept", u)
+	}
+	return newWebSocketConn(conn, r, true), nil
+}
+
+// WebSocketListener is a net.Listener of gossip connections tunneled over WebSocket.
+// It is an http.Handler that upgrades the requests it serves to WebSocket connections,
+// which it then returns from Accept.
+type WebSocketListener struct {
+	addr      net.Addr
+	server    *http.Server
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebSocketListener returns a WebSocketListener with the given address
+func NewWebSocketListener(addr net.Addr) *WebSocketListener {
+	return &WebSocketListener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// ListenWebSocket returns a WebSocketListener that serves WebSocket upgrade requests on the given address
+func ListenWebSocket(address string) (*WebSocketListener, error) {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed listening on %s", address)
+	}
+	l := NewWebSocketListener(lis.Addr())
+	l.server = &http.Server{Handler: l}
+	go l.server.Serve(lis)
+	return l, nil
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection
+func (l *WebSocketListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a WebSocket upgrade request", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != webSocketVersion {
+		w.Header().Set("Sec-WebSocket-Version", webSocketVersion)
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	response := fmt.Sprintf("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", webSocketAccept(key))
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return
+	}
+
+	select {
+	case l.conns <- newWebSocketConn(conn, rw.Reader, false):
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// Accept waits for and returns the next WebSocket connection
+func (l *WebSocketListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("WebSocket listener closed")
+	}
+}
+
+// Close closes the listener
+func (l *WebSocketListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		if l.server != nil {
+			err = l.server.Close()
+		}
+	})
+	return err
+}
+
+// Addr returns the address of the listener
+func (l *WebSocketListener) Addr() net.Addr {
+	return l.addr
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
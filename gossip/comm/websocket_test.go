@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketConn(t *testing.T) {
+	listener := NewWebSocketListener(&net.TCPAddr{})
+	server := httptest.NewServer(listener)
+	defer server.Close()
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	clientConn, err := dialWebSocket(ctx, strings.Replace(server.URL, "http://", "ws://", 1)+"/gossip")
+	require.NoError(t, err)
+	defer clientConn.Close()
+	serverConn, err := listener.Accept()
+	require.NoError(t, err)
+	defer serverConn.Close()
+
+	for _, size := range []int{1, 125, 126, 1000, 65535, 65536, 100000} {
+		payload := bytes.Repeat([]byte{byte(size)}, size)
+
+		go clientConn.Write(payload)
+		received := make([]byte, size)
+		_, err := io.ReadFull(serverConn, received)
+		require.NoError(t, err)
+		assert.Equal(t, payload, received, "client to server, size %d", size)
+
+		go serverConn.Write(payload)
+		received = make([]byte, size)
+		_, err = io.ReadFull(clientConn, received)
+		require.NoError(t, err)
+		assert.Equal(t, payload, received, "server to client, size %d", size)
+	}
+
+	// Pings are answered with pongs, which are skipped by the reader
+	require.NoError(t, serverConn.(*webSocketConn).writeFrame(opPing, []byte("ping")))
+	go serverConn.Write([]byte("data"))
+	received := make([]byte, 4)
+	_, err = io.ReadFull(clientConn, received)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), received)
+	go clientConn.Write([]byte("more"))
+	_, err = io.ReadFull(serverConn, received)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("more"), received)
+
+	// Closing one side ends the stream of the other
+	clientConn.Close()
+	_, err = serverConn.Read(received)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWebSocketHandshakeFailures(t *testing.T) {
+	listener := NewWebSocketListener(&net.TCPAddr{})
+	server := httptest.NewServer(listener)
+	defer server.Close()
+	defer listener.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err = dialWebSocket(ctx, server.URL)
+	assert.EqualError(t, err, "invalid WebSocket URL "+server.URL+": scheme must be ws or wss")
+
+	notWebSocket := httptest.NewServer(http.NotFoundHandler())
+	defer notWebSocket.Close()
+	url := strings.Replace(notWebSocket.URL, "http://", "ws://", 1)
+	_, err = dialWebSocket(ctx, url)
+	assert.EqualError(t, err, "WebSocket handshake with "+url+" failed: 404 Not Found")
+
+	// Connections aren't handed over once the listener is closed
+	listener.Close()
+	_, err = listener.Accept()
+	assert.EqualError(t, err, "WebSocket listener closed")
+}
@@ -0,0 +1,39 @@
+// +build cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/DataDog/zstd"
+	"github.com/pkg/errors"
+)
+
+const zstdSupported = true
+
+func zstdCompress(b []byte) ([]byte, error) {
+	return zstd.CompressLevel(nil, b, zstdCompressionLevel)
+}
+
+// zstdDecompress decompresses the data as a stream, as the size in the frame header
+// cannot be trusted, and stops once more than maxSize bytes are decompressed
+func zstdDecompress(b []byte, maxSize int) ([]byte, error) {
+	r := zstd.NewReader(bytes.NewReader(b))
+	defer r.Close()
+	payload, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxSize {
+		return nil, errors.Errorf("decompressed payload size exceeds the maximum of %d bytes", maxSize)
+	}
+	return payload, nil
+}
@@ -0,0 +1,23 @@
+// +build !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import "github.com/pkg/errors"
+
+const zstdSupported = false
+
+var errZstdCgo = errors.New("zstd compression requires building with cgo enabled")
+
+func zstdCompress(b []byte) ([]byte, error) {
+	return nil, errZstdCgo
+}
+
+func zstdDecompress(b []byte, maxSize int) ([]byte, error) {
+	return nil, errZstdCgo
+}
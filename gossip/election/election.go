@@ -141,6 +141,9 @@ type ElectionConfig struct {
 	MembershipSampleInterval time.Duration
 	LeaderAliveThreshold     time.Duration
 	LeaderElectionDuration   time.Duration
+	// Strategy ranks the peer among the candidates to be the leader.
+	// If nil, all peers are ranked the same, and the peer with the lowest ID is elected.
+	Strategy Strategy
 }
 
 // NewLeaderElectionService returns a new LeaderElectionService
@@ -163,6 +166,10 @@ func NewLeaderElectionService(adapter LeaderElectionAdapter, id string, callback
 		le.callback = callback
 	}
 
+	if le.config.Strategy == nil {
+		le.config.Strategy = defaultStrategy
+	}
+
 	go le.start()
 	return le
 }
@@ -291,6 +298,20 @@ func (le *leaderElectionSvcImpl) leaderElection() {
 	if le.isYielding() {
 		return
 	}
+	// Let more suitable peers become the leader first
+	rank := le.config.Strategy.Rank()
+	if rank < 0 {
+		le.logger.Debug(le.id, ": Not a candidate to be the leader")
+		le.waitForInterrupt(le.config.LeaderElectionDuration)
+		return
+	}
+	if rank > 0 {
+		le.logger.Debug(le.id, ": Deferring leader election because of rank", rank)
+		le.waitForInterrupt(deferral(rank, le.config.LeaderElectionDuration))
+		if le.isLeaderExists() || le.isYielding() || le.shouldStop() {
+			return
+		}
+	}
 	// Propose ourselves as a leader
 	le.propose()
 	// Collect other proposals
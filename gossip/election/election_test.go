@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -159,6 +160,10 @@ func createPeers(spawnInterval time.Duration, ids ...int) []*peer {
 }
 
 func createPeerWithCostumeMetrics(id int, peerMap map[string]*peer, l *sync.RWMutex, f func(mock.Arguments)) *peer {
+	return createPeerWithStrategy(id, peerMap, l, f, nil)
+}
+
+func createPeerWithStrategy(id int, peerMap map[string]*peer, l *sync.RWMutex, f func(mock.Arguments), strategy Strategy) *peer {
 	idStr := fmt.Sprintf("p%d", id)
 	c := make(chan Msg, 100)
 	p := &peer{id: idStr, peers: peerMap, sharedLock: l, msgChan: c, mockedMethods: make(map[string]struct{}), leaderFromCallback: false, callbackInvoked: false}
@@ -168,6 +173,7 @@ func createPeerWithCostumeMetrics(id int, peerMap map[string]*peer, l *sync.RWMu
 		MembershipSampleInterval: testMembershipSampleInterval,
 		LeaderAliveThreshold:     testLeaderAliveThreshold,
 		LeaderElectionDuration:   testLeaderElectionDuration,
+		Strategy:                 strategy,
 	}
 	p.LeaderElectionService = NewLeaderElectionService(p, idStr, p.leaderCallback, config)
 	l.Lock()
@@ -253,6 +259,52 @@ func TestInitPeersAtSameTime(t *testing.T) {
 	waitForBoolFunc(t, peers[len(peers)-1].isLeaderFromCallback, true, "Leadership callback result is wrong for ", peers[len(peers)-1].id)
 }
 
+func TestStrategy(t *testing.T) {
+	// Scenario: Peers are spawned at the same time with priority strategies
+	// expected outcome: the peer with the lowest priority is the leader although its ID is highest,
+	// and peers with negative priorities are never elected
+	peerMap := make(map[string]*peer)
+	l := &sync.RWMutex{}
+	priorities := map[int]int{0: -1, 1: 2, 2: 1, 3: 0}
+	var peers []*peer
+	for id := 0; id < 4; id++ {
+		peers = append(peers, createPeerWithStrategy(id, peerMap, l, func(mock.Arguments) {}, NewPriorityStrategy(priorities[id])))
+	}
+	leaders := waitForLeaderElection(t, peers)
+	assert.Equal(t, []string{"p3"}, leaders)
+	waitForBoolFunc(t, peers[3].isLeaderFromCallback, true, "Leadership callback result is wrong for ", peers[3].id)
+
+	// Take down all candidates, and ensure the peer with the negative priority isn't elected
+	for _, p := range peers[1:] {
+		p.Stop()
+		l.Lock()
+		delete(peerMap, p.id)
+		l.Unlock()
+	}
+	time.Sleep(testLeaderAliveThreshold + testLeaderElectionDuration*2)
+	assert.False(t, peers[0].IsLeader())
+	peers[0].Stop()
+}
+
+func TestLatencyStrategy(t *testing.T) {
+	latency := time.Millisecond * 250
+	var err error
+	strategy := NewLatencyStrategy(func() (time.Duration, error) {
+		return latency, err
+	}, time.Millisecond*100, 5)
+	assert.Equal(t, 2, strategy.Rank())
+
+	latency = time.Millisecond * 50
+	assert.Equal(t, 0, strategy.Rank())
+
+	latency = time.Second
+	assert.Equal(t, 5, strategy.Rank())
+
+	latency = 0
+	err = errors.New("unreachable")
+	assert.Equal(t, 5, strategy.Rank())
+}
+
 func TestInitPeersStartAtIntervals(t *testing.T) {
 	// Scenario: Peers are spawned one by one in a slow rate
 	// expected outcome: the first peer is the leader although its ID is highest
@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package election
+
+import (
+	"time"
+)
+
+// Strategy ranks how suitable the peer is to be the leader, and lets organizations
+// control which of their peers becomes the leader and pulls blocks from the ordering service.
+// Peers propose themselves as leaders in the order of their ranks, lowest first, and peers
+// of higher ranks defer their proposals long enough for peers of lower ranks to be elected.
+// Among peers of the same rank, the peer with the lowest ID is elected, as with no strategy.
+// All peers of an organization should use the same kind of strategy.
+type Strategy interface {
+	// Rank returns the rank of the peer, or a negative rank if the peer should never be the leader.
+	// It is invoked whenever the peer is about to take part in a leader election.
+	Rank() int
+}
+
+// StrategyFunc is an adapter to allow the use of ordinary functions as strategies
+type StrategyFunc func() int
+
+// Rank returns the rank of the peer
+func (f StrategyFunc) Rank() int {
+	return f()
+}
+
+// NewPriorityStrategy returns a Strategy that ranks the peer by the given priority,
+// where peers of lower priorities are preferred, and peers of negative priorities are never elected.
+func NewPriorityStrategy(priority int) Strategy {
+	return StrategyFunc(func() int {
+		return priority
+	})
+}
+
+// NewLatencyStrategy returns a Strategy that ranks the peer by the latency the given function measures,
+// in buckets of the given duration. Latencies of maxRank buckets or more, and failed measurements,
+// are ranked maxRank.
+func NewLatencyStrategy(measure func() (time.Duration, error), bucket time.Duration, maxRank int) Strategy {
+	return StrategyFunc(func() int {
+		latency, err := measure()
+		if err != nil {
+			return maxRank
+		}
+		if rank := int(latency / bucket); rank < maxRank {
+			return rank
+		}
+		return maxRank
+	})
+}
+
+// defaultStrategy ranks all peers the same
+var defaultStrategy = NewPriorityStrategy(0)
+
+// deferral returns the time a peer of the given rank defers proposing itself as a leader.
+// Every rank defers by one and a half election durations, so that a peer ranked one lower
+// completes its election and declares its leadership before the peer proposes itself.
+func deferral(rank int, leaderElectionDuration time.Duration) time.Duration {
+	return time.Duration(rank) * (leaderElectionDuration + leaderElectionDuration/2)
+}
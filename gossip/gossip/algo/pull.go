@@ -84,9 +84,13 @@ type PullEngine struct {
 	incomingNONCES     *util.Set
 	digFilter          DigestFilter
 
-	digestWaitTime   time.Duration
-	requestWaitTime  time.Duration
-	responseWaitTime time.Duration
+	// The fields below can be changed while the engine runs,
+	// and are therefore accessed atomically
+	sleepTime        int64
+	digestWaitTime   int64
+	requestWaitTime  int64
+	responseWaitTime int64
+	maxDigestSize    int64
 }
 
 // PullEngineConfig is the configuration required to initialize a new pull engine
@@ -94,6 +98,9 @@ type PullEngineConfig struct {
 	DigestWaitTime   time.Duration
 	RequestWaitTime  time.Duration
 	ResponseWaitTime time.Duration
+	// MaxDigestSize is the maximum number of items sent in a digest, or 0 for no limit.
+	// Larger digests are trimmed to a random subset of their items.
+	MaxDigestSize int
 }
 
 // NewPullEngineWithFilter creates an instance of a PullEngine with a certain sleep time
@@ -112,14 +119,13 @@ func NewPullEngineWithFilter(participant PullAdapter, sleepTime time.Duration, d
 		incomingNONCES:     util.NewSet(),
 		outgoingNONCES:     util.NewSet(),
 		digFilter:          df,
-		digestWaitTime:     config.DigestWaitTime,
-		requestWaitTime:    config.RequestWaitTime,
-		responseWaitTime:   config.ResponseWaitTime,
+		sleepTime:          int64(sleepTime),
 	}
+	engine.SetConfig(config)
 
 	go func() {
 		for !engine.toDie() {
-			time.Sleep(sleepTime)
+			time.Sleep(engine.PullInterval())
 			if engine.toDie() {
 				return
 			}
@@ -170,6 +176,36 @@ func (engine *PullEngine) Stop() {
 	atomic.StoreInt32(&(engine.stopFlag), int32(1))
 }
 
+// PullInterval returns the sleep time between pull initiations
+func (engine *PullEngine) PullInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&engine.sleepTime))
+}
+
+// SetPullInterval changes the sleep time between pull initiations,
+// starting from the next pull initiation
+func (engine *PullEngine) SetPullInterval(sleepTime time.Duration) {
+	atomic.StoreInt64(&engine.sleepTime, int64(sleepTime))
+}
+
+// Config returns the current configuration of the engine
+func (engine *PullEngine) Config() PullEngineConfig {
+	return PullEngineConfig{
+		DigestWaitTime:   time.Duration(atomic.LoadInt64(&engine.digestWaitTime)),
+		RequestWaitTime:  time.Duration(atomic.LoadInt64(&engine.requestWaitTime)),
+		ResponseWaitTime: time.Duration(atomic.LoadInt64(&engine.responseWaitTime)),
+		MaxDigestSize:    int(atomic.LoadInt64(&engine.maxDigestSize)),
+	}
+}
+
+// SetConfig changes the configuration of the engine,
+// starting from the next message it handles
+func (engine *PullEngine) SetConfig(config PullEngineConfig) {
+	atomic.StoreInt64(&engine.digestWaitTime, int64(config.DigestWaitTime))
+	atomic.StoreInt64(&engine.requestWaitTime, int64(config.RequestWaitTime))
+	atomic.StoreInt64(&engine.responseWaitTime, int64(config.ResponseWaitTime))
+	atomic.StoreInt64(&engine.maxDigestSize, int64(config.MaxDigestSize))
+}
+
 func (engine *PullEngine) initiatePull() {
 	engine.lock.Lock()
 	defer engine.lock.Unlock()
@@ -183,7 +219,7 @@ func (engine *PullEngine) initiatePull() {
 		engine.Hello(peer, nonce)
 	}
 
-	time.AfterFunc(engine.digestWaitTime, func() {
+	time.AfterFunc(engine.Config().DigestWaitTime, func() {
 		engine.processIncomingDigests()
 	})
 }
@@ -211,7 +247,7 @@ func (engine *PullEngine) processIncomingDigests() {
 		engine.SendReq(dest, seqsToReq, engine.peers2nonces[dest])
 	}
 
-	time.AfterFunc(engine.responseWaitTime, engine.endPull)
+	time.AfterFunc(engine.Config().ResponseWaitTime, engine.endPull)
 }
 
 func (engine *PullEngine) endPull() {
@@ -266,7 +302,8 @@ func (engine *PullEngine) Remove(seqs ...string) {
 func (engine *PullEngine) OnHello(nonce uint64, context interface{}) {
 	engine.incomingNONCES.Add(nonce)
 
-	time.AfterFunc(engine.requestWaitTime, func() {
+	config := engine.Config()
+	time.AfterFunc(config.RequestWaitTime, func() {
 		engine.incomingNONCES.Remove(nonce)
 	})
 
@@ -283,6 +320,9 @@ func (engine *PullEngine) OnHello(nonce uint64, context interface{}) {
 	if len(digest) == 0 {
 		return
 	}
+	if config.MaxDigestSize > 0 && len(digest) > config.MaxDigestSize {
+		digest = trimDigest(digest, config.MaxDigestSize)
+	}
 	engine.SendDigest(digest, nonce, context)
 }
 
@@ -318,6 +358,16 @@ func (engine *PullEngine) OnRes(items []string, nonce uint64) {
 	engine.Add(items...)
 }
 
+// trimDigest returns a random subset of the given size of the given digest.
+// Initiators receive different subsets, so all items are eventually pulled.
+func trimDigest(digest []string, size int) []string {
+	trimmed := make([]string, 0, size)
+	for _, i := range util.GetRandomIndices(size, len(digest)-1) {
+		trimmed = append(trimmed, digest[i])
+	}
+	return trimmed
+}
+
 func (engine *PullEngine) newNONCE() uint64 {
 	n := uint64(0)
 	for {
@@ -546,3 +546,55 @@ func keySet(selfPeer string, m map[string]*pullTestInstance) []string {
 
 	return peers
 }
+
+func TestMaxDigestSize(t *testing.T) {
+	// Scenario: inst1 has 10 items, and sends digests of at most 3 items.
+	// Expected outcome: every digest inst2 receives has 3 items,
+	// and inst2 eventually pulls all items across several pull rounds.
+	peers := make(map[string]*pullTestInstance)
+	inst1 := newPushPullTestInstance("p1", peers)
+	inst2 := newPushPullTestInstance("p2", peers)
+	defer inst1.stop()
+	defer inst2.stop()
+
+	config := inst1.Config()
+	config.MaxDigestSize = 3
+	inst1.SetConfig(config)
+	inst2.SetPullInterval(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, inst2.PullInterval())
+
+	var digestSizes []int
+	var lock sync.Mutex
+	inst2.hook(func(m interface{}) {
+		if dig, isDig := m.(*digestMsg); isDig {
+			lock.Lock()
+			digestSizes = append(digestSizes, len(dig.digest))
+			lock.Unlock()
+		}
+	})
+
+	inst1.Add("0", "1", "2", "3", "4", "5", "6", "7", "8", "9")
+	inst2.setNextPeerSelection([]string{"p1"})
+
+	assert.Eventually(t, func() bool {
+		return len(inst2.state.ToArray()) == 10
+	}, time.Second*30, time.Millisecond*100)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.NotEmpty(t, digestSizes)
+	for _, size := range digestSizes {
+		assert.Equal(t, 3, size)
+	}
+}
+
+func TestTrimDigest(t *testing.T) {
+	digest := []string{"0", "1", "2", "3", "4"}
+	trimmed := trimDigest(digest, 2)
+	assert.Len(t, trimmed, 2)
+	assert.NotEqual(t, trimmed[0], trimmed[1])
+	for _, item := range trimmed {
+		assert.Contains(t, digest, item)
+	}
+	assert.Len(t, trimDigest(digest, 5), 5)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gossip
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/gossip/api"
+)
+
+// defAnchorPeerSRVRefreshInterval is the interval in which the anchor peers
+// published as DNS SRV records are resolved, unless configured otherwise
+const defAnchorPeerSRVRefreshInterval = time.Minute
+
+// lookupSRV resolves DNS SRV records, and is replaced in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// srvAnchorPeer is an anchor peer of an organization that is published as a DNS SRV record
+type srvAnchorPeer struct {
+	org       api.OrgIdentityType
+	name      string
+	endpoints map[string]struct{}
+}
+
+// srvAnchorPeers tracks the anchor peers of the channels that are published as DNS SRV records,
+// in order to resolve them periodically and connect to the endpoints that the records point to,
+// so that organizations can move their anchor peers without updating the channel configuration.
+type srvAnchorPeers struct {
+	sync.Mutex
+	channels map[string][]*srvAnchorPeer
+}
+
+func newSRVAnchorPeers() *srvAnchorPeers {
+	return &srvAnchorPeers{
+		channels: make(map[string][]*srvAnchorPeer),
+	}
+}
+
+// add starts tracking the given DNS SRV record of the anchor peers of an organization in the channel
+func (sap *srvAnchorPeers) add(channel string, org api.OrgIdentityType, name string) *srvAnchorPeer {
+	sap.Lock()
+	defer sap.Unlock()
+	ap := &srvAnchorPeer{
+		org:       org,
+		name:      name,
+		endpoints: make(map[string]struct{}),
+	}
+	sap.channels[channel] = append(sap.channels[channel], ap)
+	return ap
+}
+
+// reset stops tracking the DNS SRV records of the anchor peers of the channel
+func (sap *srvAnchorPeers) reset(channel string) {
+	sap.Lock()
+	defer sap.Unlock()
+	delete(sap.channels, channel)
+}
+
+// all returns the DNS SRV records of the anchor peers of all channels
+func (sap *srvAnchorPeers) all() []*srvAnchorPeer {
+	sap.Lock()
+	defer sap.Unlock()
+	var res []*srvAnchorPeer
+	for _, anchorPeers := range sap.channels {
+		res = append(res, anchorPeers...)
+	}
+	return res
+}
+
+// update replaces the endpoints the DNS SRV record of the anchor peer resolved to,
+// and returns the endpoints that it didn't resolve to previously
+func (sap *srvAnchorPeers) update(ap *srvAnchorPeer, endpoints []string) []string {
+	sap.Lock()
+	defer sap.Unlock()
+	var added []string
+	resolved := make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		resolved[endpoint] = struct{}{}
+		if _, exists := ap.endpoints[endpoint]; !exists {
+			added = append(added, endpoint)
+		}
+	}
+	ap.endpoints = resolved
+	return added
+}
+
+// resolveSRV returns the endpoints the given DNS SRV record points to, ordered by priority
+func (g *Node) resolveSRV(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.conf.DialTimeout)
+	defer cancel()
+	_, addrs, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []string
+	for _, addr := range addrs {
+		host := strings.TrimSuffix(addr.Target, ".")
+		endpoints = append(endpoints, net.JoinHostPort(host, strconv.Itoa(int(addr.Port))))
+	}
+	return endpoints, nil
+}
+
+// learnSRVAnchorPeer resolves the given DNS SRV record of the anchor peers of an organization and connects to them
+func (g *Node) learnSRVAnchorPeer(channel string, orgOfAnchorPeers api.OrgIdentityType, name string) {
+	ap := g.srvAnchors.add(channel, orgOfAnchorPeers, name)
+	g.resolveSRVAnchorPeer(ap)
+}
+
+func (g *Node) resolveSRVAnchorPeer(ap *srvAnchorPeer) {
+	endpoints, err := g.resolveSRV(ap.name)
+	if err != nil {
+		g.logger.Warningf("Failed resolving DNS SRV record %s of the anchor peers of %s: %v", ap.name, string(ap.org), err)
+		return
+	}
+	for _, endpoint := range g.srvAnchors.update(ap, endpoints) {
+		g.logger.Infof("DNS SRV record %s of the anchor peers of %s resolved to %s", ap.name, string(ap.org), endpoint)
+		g.connect2AnchorPeer(ap.org, endpoint)
+	}
+}
+
+// refreshSRVAnchorPeers periodically resolves the DNS SRV records of the anchor peers,
+// and connects to the anchor peers whose endpoints changed
+func (g *Node) refreshSRVAnchorPeers() {
+	defer g.logger.Debug("Exiting")
+	defer g.stopSignal.Done()
+	interval := g.conf.AnchorPeerSRVRefreshInterval
+	if interval <= 0 {
+		interval = defAnchorPeerSRVRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.toDieChan:
+			return
+		case <-ticker.C:
+			for _, ap := range g.srvAnchors.all() {
+				g.resolveSRVAnchorPeer(ap)
+			}
+		}
+	}
+}
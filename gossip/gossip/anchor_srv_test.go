@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gossip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/gossip/api"
+	"github.com/hyperledger/fabric/gossip/common"
+	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchorPeerIsSRV(t *testing.T) {
+	assert.True(t, api.AnchorPeer{Host: "_gossip._tcp.org1.example.com"}.IsSRV())
+	assert.False(t, api.AnchorPeer{Host: "_gossip._tcp.org1.example.com", Port: 7051}.IsSRV())
+	assert.False(t, api.AnchorPeer{Host: "peer0.org1.example.com"}.IsSRV())
+}
+
+func TestSRVAnchorPeersUpdate(t *testing.T) {
+	sap := newSRVAnchorPeers()
+	ap := sap.add("A", orgInChannelA, "_gossip._tcp.org1.example.com")
+	sap.add("B", orgInChannelA, "_gossip._tcp.org1.example.com")
+	assert.Len(t, sap.all(), 2)
+
+	assert.Equal(t, []string{"p1:7051", "p2:7051"}, sap.update(ap, []string{"p1:7051", "p2:7051"}))
+	assert.Empty(t, sap.update(ap, []string{"p2:7051", "p1:7051"}))
+	assert.Equal(t, []string{"p3:7051"}, sap.update(ap, []string{"p1:7051", "p3:7051"}))
+	assert.Equal(t, []string{"p2:7051"}, sap.update(ap, []string{"p2:7051"}))
+
+	sap.reset("A")
+	assert.Len(t, sap.all(), 1)
+}
+
+func TestConnectToSRVAnchorPeers(t *testing.T) {
+	// Scenario: two peers join a channel in which the anchor peer is published as a DNS SRV record,
+	// which can't be resolved at first. Once the record resolves to the endpoint of the first peer,
+	// the second peer connects to it when the record is refreshed.
+	srvName := "_gossip._tcp.org1.example.com"
+	var lock sync.Mutex
+	var records []*net.SRV
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) {
+		lookupSRV = f
+	}(lookupSRV)
+	lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Empty(t, service)
+		assert.Empty(t, proto)
+		assert.Equal(t, srvName, name)
+		if len(records) == 0 {
+			return "", nil, errors.New("no such host")
+		}
+		return name, records, nil
+	}
+
+	jcm := &joinChanMsg{members2AnchorPeers: map[string][]api.AnchorPeer{
+		string(orgInChannelA): {{Host: srvName}},
+	}}
+
+	port0, grpc0, certs0, secDialOpts0, _ := util.CreateGRPCLayer()
+	p0 := newGossipInstanceWithGRPC(0, port0, grpc0, certs0, secDialOpts0, 100)
+	defer p0.Stop()
+	p1 := newGossipInstanceCreateGRPC(1, 100)
+	defer p1.Stop()
+
+	peers := []*gossipGRPC{p0, p1}
+	for _, p := range peers {
+		p.JoinChan(jcm, common.ChannelID("A"))
+		p.UpdateLedgerHeight(1, common.ChannelID("A"))
+	}
+	assert.Empty(t, p0.Peers())
+	assert.Empty(t, p1.Peers())
+
+	lock.Lock()
+	records = []*net.SRV{{Target: "127.0.0.1.", Port: uint16(port0)}}
+	lock.Unlock()
+
+	for _, ap := range p1.srvAnchors.all() {
+		p1.resolveSRVAnchorPeer(ap)
+	}
+	waitUntilOrFail(t, checkPeersMembership(t, peers, 1), "waiting for peers to form membership view")
+}
@@ -50,6 +50,10 @@ type Config struct {
 	RequestWaitTime             time.Duration
 	ResponseWaitTime            time.Duration
 	MsgExpirationTimeout        time.Duration
+	MaxDigestSize               int
+	PullDedupCacheSize          int
+	DigestPeerThreshold         int
+	PullMetrics                 *metrics.PullMetrics
 }
 
 // GossipChannel defines an object that deals with all channel-related messages
@@ -95,6 +99,9 @@ type GossipChannel interface {
 	// LeaveChannel makes the peer leave the channel
 	LeaveChannel()
 
+	// TunePull changes the tuning of the block pulling of the channel
+	TunePull(tuning pull.Tuning)
+
 	// Stop stops the channel's activity
 	Stop()
 }
@@ -316,6 +323,11 @@ func (gc *gossipChannel) Stop() {
 	gc.blockMsgStore.Stop()
 }
 
+// TunePull changes the tuning of the block pulling of the channel
+func (gc *gossipChannel) TunePull(tuning pull.Tuning) {
+	gc.blocksPuller.Tune(tuning)
+}
+
 func (gc *gossipChannel) periodicalInvocation(fn func(), c <-chan time.Time) {
 	for {
 		select {
@@ -422,7 +434,10 @@ func (gc *gossipChannel) createBlockPuller() pull.Mediator {
 			DigestWaitTime:   gc.GetConf().DigestWaitTime,
 			RequestWaitTime:  gc.GetConf().RequestWaitTime,
 			ResponseWaitTime: gc.GetConf().ResponseWaitTime,
+			MaxDigestSize:    gc.GetConf().MaxDigestSize,
 		},
+		DedupCacheSize:      gc.GetConf().PullDedupCacheSize,
+		DigestPeerThreshold: gc.GetConf().DigestPeerThreshold,
 	}
 	seqNumFromMsg := func(msg *protoext.SignedGossipMessage) string {
 		dataMsg := msg.GetDataMsg()
@@ -439,6 +454,7 @@ func (gc *gossipChannel) createBlockPuller() pull.Mediator {
 		MsgCons: func(msg *protoext.SignedGossipMessage) {
 			gc.DeMultiplex(msg)
 		},
+		Metrics: gc.GetConf().PullMetrics,
 	}
 
 	adapter.IngressDigFilter = func(digestMsg *proto.DataDigest) *proto.DataDigest {
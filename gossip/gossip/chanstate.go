@@ -17,6 +17,7 @@ import (
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/discovery"
 	"github.com/hyperledger/fabric/gossip/gossip/channel"
+	"github.com/hyperledger/fabric/gossip/gossip/pull"
 	"github.com/hyperledger/fabric/gossip/metrics"
 	"github.com/hyperledger/fabric/gossip/protoext"
 )
@@ -40,6 +41,14 @@ func (cs *channelState) stop() {
 	}
 }
 
+func (cs *channelState) tunePull(tuning pull.Tuning) {
+	cs.RLock()
+	defer cs.RUnlock()
+	for _, gc := range cs.channels {
+		gc.TunePull(tuning)
+	}
+}
+
 func (cs *channelState) isStopping() bool {
 	return atomic.LoadInt32(&cs.stopping) == int32(1)
 }
@@ -118,20 +127,25 @@ type gossipAdapterImpl struct {
 }
 
 func (ga *gossipAdapterImpl) GetConf() channel.Config {
+	tuning := ga.PullTuning()
 	return channel.Config{
 		ID:                          ga.conf.ID,
 		MaxBlockCountToStore:        ga.conf.MaxBlockCountToStore,
 		PublishStateInfoInterval:    ga.conf.PublishStateInfoInterval,
-		PullInterval:                ga.conf.PullInterval,
+		PullInterval:                tuning.PullInterval,
 		PullPeerNum:                 ga.conf.PullPeerNum,
 		RequestStateInfoInterval:    ga.conf.RequestStateInfoInterval,
 		BlockExpirationInterval:     ga.conf.PullInterval * 100,
 		StateInfoCacheSweepInterval: ga.conf.PullInterval * 5,
 		TimeForMembershipTracker:    ga.conf.TimeForMembershipTracker,
-		DigestWaitTime:              ga.conf.DigestWaitTime,
-		RequestWaitTime:             ga.conf.RequestWaitTime,
-		ResponseWaitTime:            ga.conf.ResponseWaitTime,
+		DigestWaitTime:              tuning.PullEngineConfig.DigestWaitTime,
+		RequestWaitTime:             tuning.PullEngineConfig.RequestWaitTime,
+		ResponseWaitTime:            tuning.PullEngineConfig.ResponseWaitTime,
 		MsgExpirationTimeout:        ga.conf.MsgExpirationTimeout,
+		MaxDigestSize:               tuning.PullEngineConfig.MaxDigestSize,
+		PullDedupCacheSize:          tuning.DedupCacheSize,
+		DigestPeerThreshold:         tuning.DigestPeerThreshold,
+		PullMetrics:                 ga.gossipMetrics.PullMetrics,
 	}
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/hyperledger/fabric/gossip/election"
 	"github.com/hyperledger/fabric/gossip/gossip/algo"
 	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
@@ -93,6 +94,54 @@ type Config struct {
 	AliveExpirationCheckInterval time.Duration
 	// ReconnectInterval is the Reconnect interval.
 	ReconnectInterval time.Duration
+
+	// VerificationWorkers is the number of goroutines that verify and handle incoming messages.
+	VerificationWorkers int
+
+	// Compression is the compression codecs to negotiate with remote peers, in order of preference.
+	Compression []string
+	// MaxRecvMsgSize is the size, in bytes, beyond which the messages received from remote peers,
+	// once decompressed, are rejected. Zero means the default maximum size of the gRPC messages.
+	MaxRecvMsgSize int
+
+	// AnchorPeerSRVRefreshInterval is the interval in which the anchor peers published as DNS SRV records are resolved.
+	AnchorPeerSRVRefreshInterval time.Duration
+
+	// DialBackoffInitial is the period dialing a peer is backed off for after it fails, which doubles with each failure.
+	DialBackoffInitial time.Duration
+
+	// DialBackoffMax is the maximum period dialing a peer is backed off for.
+	DialBackoffMax time.Duration
+
+	// PullMaxDigestSize is the maximum number of items sent in a pull digest, or 0 for no limit.
+	PullMaxDigestSize int
+
+	// PullDedupCacheSize is the number of recently received items remembered to drop duplicate pulled items, or 0 to disable it.
+	PullDedupCacheSize int
+
+	// PullDigestPeerThreshold is the membership size above which pull digests shrink in proportion to it, or 0 to disable it.
+	PullDigestPeerThreshold int
+
+	// MaxConnectionsPerOrg is the maximum number of connections to the peers of each other organization, or 0 for no limit.
+	MaxConnectionsPerOrg int
+
+	// WebSocketTunnels maps endpoints of peers to the WebSocket URLs connections to them are tunneled through.
+	WebSocketTunnels map[string]string
+
+	// OrgBudgetMessageRate is the number of messages per second the peers of each other organization may send, or 0 for no limit.
+	OrgBudgetMessageRate float64
+
+	// OrgBudgetByteRate is the number of bytes per second the peers of each other organization may send, or 0 for no limit.
+	OrgBudgetByteRate float64
+
+	// OrgQueueSize is the number of received messages queued for each organization to be handled fairly, or 0 to disable it.
+	OrgQueueSize int
+}
+
+// webSocketTunnel is a configured WebSocket tunnel to a peer
+type webSocketTunnel struct {
+	Endpoint string
+	URL      string
 }
 
 // GlobalConfig builds a Config from the given endpoint, certificate and bootstrap peers.
@@ -142,6 +191,34 @@ func (c *Config) loadConfig(endpoint string, certs *common.TLSCertificates, boot
 	c.AliveExpirationTimeout = util.GetDurationOrDefault("peer.gossip.aliveExpirationTimeout", 5*c.AliveTimeInterval)
 	c.AliveExpirationCheckInterval = c.AliveExpirationTimeout / 10
 	c.ReconnectInterval = util.GetDurationOrDefault("peer.gossip.reconnectInterval", c.AliveExpirationTimeout)
+	c.VerificationWorkers = util.GetIntOrDefault("peer.gossip.verificationWorkers", 1)
+	c.Compression = viper.GetStringSlice("peer.gossip.compression")
+	c.MaxRecvMsgSize = viper.GetInt("peer.limits.maxRecvMsgSize.gossipService")
+	c.AnchorPeerSRVRefreshInterval = util.GetDurationOrDefault("peer.gossip.anchorPeerSRVRefreshInterval", defAnchorPeerSRVRefreshInterval)
+	c.DialBackoffInitial = util.GetDurationOrDefault("peer.gossip.dialBackoffInitial", comm.DefDialBackoffInitial)
+	c.DialBackoffMax = util.GetDurationOrDefault("peer.gossip.dialBackoffMax", comm.DefDialBackoffMax)
+	c.MaxConnectionsPerOrg = viper.GetInt("peer.gossip.maxConnectionsPerOrg")
+	c.PullMaxDigestSize = viper.GetInt("peer.gossip.pullMaxDigestSize")
+	c.PullDedupCacheSize = viper.GetInt("peer.gossip.pullDedupCacheSize")
+	c.PullDigestPeerThreshold = viper.GetInt("peer.gossip.pullDigestPeerThreshold")
+
+	c.OrgBudgetMessageRate = viper.GetFloat64("peer.gossip.orgBudget.messageRate")
+	c.OrgBudgetByteRate = viper.GetFloat64("peer.gossip.orgBudget.byteRate")
+	c.OrgQueueSize = viper.GetInt("peer.gossip.orgBudget.queueSize")
+
+	var tunnels []webSocketTunnel
+	if err := viper.UnmarshalKey("peer.gossip.webSocket.tunnels", &tunnels); err != nil {
+		return errors.WithMessage(err, "could not unmarshal peer.gossip.webSocket.tunnels")
+	}
+	for _, tunnel := range tunnels {
+		if tunnel.Endpoint == "" || tunnel.URL == "" {
+			return errors.Errorf("WebSocket tunnel %+v must have both an endpoint and a URL", tunnel)
+		}
+		if c.WebSocketTunnels == nil {
+			c.WebSocketTunnels = make(map[string]string)
+		}
+		c.WebSocketTunnels[tunnel.Endpoint] = tunnel.URL
+	}
 
 	return nil
 }
@@ -55,6 +55,21 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.gossip.aliveTimeInterval", "20s")
 	viper.Set("peer.gossip.aliveExpirationTimeout", "21s")
 	viper.Set("peer.gossip.reconnectInterval", "22s")
+	viper.Set("peer.gossip.verificationWorkers", 23)
+	viper.Set("peer.gossip.compression", []string{"zstd", "snappy"})
+	viper.Set("peer.gossip.anchorPeerSRVRefreshInterval", "24s")
+	viper.Set("peer.gossip.dialBackoffInitial", "25s")
+	viper.Set("peer.gossip.dialBackoffMax", "26s")
+	viper.Set("peer.gossip.maxConnectionsPerOrg", 27)
+	viper.Set("peer.gossip.pullMaxDigestSize", 28)
+	viper.Set("peer.gossip.pullDedupCacheSize", 29)
+	viper.Set("peer.gossip.pullDigestPeerThreshold", 30)
+	viper.Set("peer.gossip.orgBudget.messageRate", 31.5)
+	viper.Set("peer.gossip.orgBudget.byteRate", 32000)
+	viper.Set("peer.gossip.orgBudget.queueSize", 33)
+	viper.Set("peer.gossip.webSocket.tunnels", []map[string]string{
+		{"endpoint": "peer0.org2.example.com:7051", "url": "wss://gateway.org2.example.com/gossip"},
+	})
 
 	coreConfig, err := gossip.GlobalConfig(endpoint, nil, bootstrap...)
 	assert.NoError(t, err)
@@ -95,6 +110,21 @@ func TestGlobalConfig(t *testing.T) {
 		AliveExpirationTimeout:       21 * time.Second,
 		AliveExpirationCheckInterval: 21 * time.Second / 10, // AliveExpirationTimeout / 10
 		ReconnectInterval:            22 * time.Second,
+		VerificationWorkers:          23,
+		Compression:                  []string{"zstd", "snappy"},
+		AnchorPeerSRVRefreshInterval: 24 * time.Second,
+		DialBackoffInitial:           25 * time.Second,
+		DialBackoffMax:               26 * time.Second,
+		MaxConnectionsPerOrg:         27,
+		PullMaxDigestSize:            28,
+		PullDedupCacheSize:           29,
+		PullDigestPeerThreshold:      30,
+		OrgBudgetMessageRate:         31.5,
+		OrgBudgetByteRate:            32000,
+		OrgQueueSize:                 33,
+		WebSocketTunnels: map[string]string{
+			"peer0.org2.example.com:7051": "wss://gateway.org2.example.com/gossip",
+		},
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
@@ -148,6 +178,10 @@ func TestGlobalConfigDefaults(t *testing.T) {
 		AliveExpirationTimeout:       5 * discovery.DefAliveTimeInterval,
 		AliveExpirationCheckInterval: 5 * discovery.DefAliveTimeInterval / 10,
 		ReconnectInterval:            5 * discovery.DefAliveTimeInterval,
+		VerificationWorkers:          1,
+		AnchorPeerSRVRefreshInterval: time.Minute,
+		DialBackoffInitial:           comm.DefDialBackoffInitial,
+		DialBackoffMax:               comm.DefDialBackoffMax,
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
@@ -66,6 +66,7 @@ type Node struct {
 	stateInfoMsgStore msgstore.MessageStore
 	certPuller        pull.Mediator
 	gossipMetrics     *metrics.GossipMetrics
+	srvAnchors        *srvAnchorPeers
 }
 
 // New creates a gossip instance attached to a gRPC server
@@ -91,6 +92,7 @@ func New(conf *Config, s *grpc.Server, sa api.SecurityAdvisor,
 		stopSignal:            &sync.WaitGroup{},
 		includeIdentityPeriod: time.Now().Add(conf.PublishCertPeriod),
 		gossipMetrics:         gossipMetrics,
+		srvAnchors:            newSRVAnchorPeers(),
 	}
 	g.stateInfoMsgStore = g.newStateInfoMsgStore()
 
@@ -104,6 +106,18 @@ func New(conf *Config, s *grpc.Server, sa api.SecurityAdvisor,
 		ConnTimeout:  conf.ConnTimeout,
 		RecvBuffSize: conf.RecvBuffSize,
 		SendBuffSize: conf.SendBuffSize,
+		Compression:  conf.Compression,
+
+		MaxRecvMsgSize:       conf.MaxRecvMsgSize,
+		DialBackoffInitial:   conf.DialBackoffInitial,
+		DialBackoffMax:       conf.DialBackoffMax,
+		MaxConnectionsPerOrg: conf.MaxConnectionsPerOrg,
+		WebSocketTunnels:     conf.WebSocketTunnels,
+		OrgBudget: comm.OrgBudget{
+			MessageRate: conf.OrgBudgetMessageRate,
+			ByteRate:    conf.OrgBudgetByteRate,
+			QueueSize:   conf.OrgQueueSize,
+		},
 	}
 	g.comm, err = comm.NewCommInstance(s, conf.TLSCerts, g.idMapper, selfIdentity, secureDialOpts, sa,
 		gossipMetrics.CommMetrics, commConfig)
@@ -138,11 +152,12 @@ func New(conf *Config, s *grpc.Server, sa api.SecurityAdvisor,
 	if g.conf.ExternalEndpoint == "" {
 		g.logger.Warning("External endpoint is empty, peer will not be accessible outside of its organization")
 	}
-	// Adding delta for handlePresumedDead and
-	// acceptMessages goRoutines to block on Wait
-	g.stopSignal.Add(2)
+	// Adding delta for handlePresumedDead, acceptMessages
+	// and refreshSRVAnchorPeers goRoutines to block on Wait
+	g.stopSignal.Add(3)
 	go g.start()
 	go g.connect2BootstrapPeers()
+	go g.refreshSRVAnchorPeers()
 
 	return g
 }
@@ -186,6 +201,8 @@ func (g *Node) toDie() bool {
 func (g *Node) JoinChan(joinMsg api.JoinChannelMessage, channelID common.ChannelID) {
 	// joinMsg is supposed to have been already verified
 	g.chanState.joinChannel(joinMsg, channelID, g.gossipMetrics.MembershipMetrics)
+	// The anchor peers published as DNS SRV records are learned again from the join message
+	g.srvAnchors.reset(string(channelID))
 
 	g.logger.Info("Joining gossip network of channel", string(channelID), "with", len(joinMsg.Members()), "organizations")
 	for _, org := range joinMsg.Members() {
@@ -201,6 +218,7 @@ func (g *Node) LeaveChan(channelID common.ChannelID) {
 		return
 	}
 	gc.LeaveChannel()
+	g.srvAnchors.reset(string(channelID))
 }
 
 // SuspectPeers makes the gossip instance validate identities of suspected peers, and close
@@ -220,47 +238,54 @@ func (g *Node) learnAnchorPeers(channel string, orgOfAnchorPeers api.OrgIdentity
 			g.logger.Warning("Got empty hostname, skipping connecting to anchor peer", ap)
 			continue
 		}
-		if ap.Port == 0 {
-			g.logger.Warning("Got invalid port (0), skipping connecting to anchor peer", ap)
+		if ap.IsSRV() {
+			g.learnSRVAnchorPeer(channel, orgOfAnchorPeers, ap.Host)
 			continue
 		}
-		endpoint := net.JoinHostPort(ap.Host, fmt.Sprintf("%d", ap.Port))
-		// Skip connecting to self
-		if g.selfNetworkMember().Endpoint == endpoint || g.selfNetworkMember().InternalEndpoint == endpoint {
-			g.logger.Info("Anchor peer with same endpoint, skipping connecting to myself")
+		if ap.Port == 0 {
+			g.logger.Warning("Got invalid port (0), skipping connecting to anchor peer", ap)
 			continue
 		}
+		g.connect2AnchorPeer(orgOfAnchorPeers, net.JoinHostPort(ap.Host, fmt.Sprintf("%d", ap.Port)))
+	}
+}
 
-		inOurOrg := bytes.Equal(g.selfOrg, orgOfAnchorPeers)
-		if !inOurOrg && g.selfNetworkMember().Endpoint == "" {
-			g.logger.Infof("Anchor peer %s:%d isn't in our org(%v) and we have no external endpoint, skipping", ap.Host, ap.Port, string(orgOfAnchorPeers))
-			continue
+func (g *Node) connect2AnchorPeer(orgOfAnchorPeers api.OrgIdentityType, endpoint string) {
+	// Skip connecting to self
+	if g.selfNetworkMember().Endpoint == endpoint || g.selfNetworkMember().InternalEndpoint == endpoint {
+		g.logger.Info("Anchor peer with same endpoint, skipping connecting to myself")
+		return
+	}
+
+	inOurOrg := bytes.Equal(g.selfOrg, orgOfAnchorPeers)
+	if !inOurOrg && g.selfNetworkMember().Endpoint == "" {
+		g.logger.Infof("Anchor peer %s isn't in our org(%v) and we have no external endpoint, skipping", endpoint, string(orgOfAnchorPeers))
+		return
+	}
+	identifier := func() (*discovery.PeerIdentification, error) {
+		remotePeerIdentity, err := g.comm.Handshake(&comm.RemotePeer{Endpoint: endpoint})
+		if err != nil {
+			g.logger.Warningf("Deep probe of %s failed: %s", endpoint, err)
+			return nil, err
 		}
-		identifier := func() (*discovery.PeerIdentification, error) {
-			remotePeerIdentity, err := g.comm.Handshake(&comm.RemotePeer{Endpoint: endpoint})
-			if err != nil {
-				g.logger.Warningf("Deep probe of %s failed: %s", endpoint, err)
-				return nil, err
-			}
-			isAnchorPeerInMyOrg := bytes.Equal(g.selfOrg, g.secAdvisor.OrgByPeerIdentity(remotePeerIdentity))
-			if bytes.Equal(orgOfAnchorPeers, g.selfOrg) && !isAnchorPeerInMyOrg {
-				err := errors.Errorf("Anchor peer %s isn't in our org, but is claimed to be", endpoint)
-				g.logger.Warningf("%s", err)
-				return nil, err
-			}
-			pkiID := g.mcs.GetPKIidOfCert(remotePeerIdentity)
-			if len(pkiID) == 0 {
-				return nil, errors.Errorf("Wasn't able to extract PKI-ID of remote peer with identity of %v", remotePeerIdentity)
-			}
-			return &discovery.PeerIdentification{
-				ID:      pkiID,
-				SelfOrg: isAnchorPeerInMyOrg,
-			}, nil
+		isAnchorPeerInMyOrg := bytes.Equal(g.selfOrg, g.secAdvisor.OrgByPeerIdentity(remotePeerIdentity))
+		if bytes.Equal(orgOfAnchorPeers, g.selfOrg) && !isAnchorPeerInMyOrg {
+			err := errors.Errorf("Anchor peer %s isn't in our org, but is claimed to be", endpoint)
+			g.logger.Warningf("%s", err)
+			return nil, err
 		}
-
-		g.disc.Connect(discovery.NetworkMember{
-			InternalEndpoint: endpoint, Endpoint: endpoint}, identifier)
+		pkiID := g.mcs.GetPKIidOfCert(remotePeerIdentity)
+		if len(pkiID) == 0 {
+			return nil, errors.Errorf("Wasn't able to extract PKI-ID of remote peer with identity of %v", remotePeerIdentity)
+		}
+		return &discovery.PeerIdentification{
+			ID:      pkiID,
+			SelfOrg: isAnchorPeerInMyOrg,
+		}, nil
 	}
+
+	g.disc.Connect(discovery.NetworkMember{
+		InternalEndpoint: endpoint, Endpoint: endpoint}, identifier)
 }
 
 func (g *Node) handlePresumedDead() {
@@ -312,12 +337,18 @@ func (g *Node) start() {
 func (g *Node) acceptMessages(incMsgs <-chan protoext.ReceivedMessage) {
 	defer g.logger.Debug("Exiting")
 	defer g.stopSignal.Done()
+	handleMessage := g.handleMessage
+	if g.conf.VerificationWorkers > 1 {
+		workers := newVerificationWorkers(g.conf.VerificationWorkers, g.conf.RecvBuffSize, g.handleMessage, g.toDieChan)
+		defer workers.wait()
+		handleMessage = workers.dispatch
+	}
 	for {
 		select {
 		case <-g.toDieChan:
 			return
 		case msg := <-incMsgs:
-			g.handleMessage(msg)
+			handleMessage(msg)
 		}
 	}
 }
@@ -742,6 +773,12 @@ func (g *Node) PeerFilter(channel common.ChannelID, messagePredicate api.SubChan
 	return gc.PeerFilter(messagePredicate), nil
 }
 
+// Register binds the gossip component to another gRPC server, for it to also
+// accept connections on the listeners of that server
+func (g *Node) Register(s *grpc.Server) {
+	g.comm.Register(s)
+}
+
 // Stop stops the gossip component
 func (g *Node) Stop() {
 	if g.toDie() {
@@ -1085,7 +1122,10 @@ func (g *Node) createCertStorePuller() pull.Mediator {
 			DigestWaitTime:   g.conf.DigestWaitTime,
 			RequestWaitTime:  g.conf.RequestWaitTime,
 			ResponseWaitTime: g.conf.ResponseWaitTime,
+			MaxDigestSize:    g.conf.PullMaxDigestSize,
 		},
+		DedupCacheSize:      g.conf.PullDedupCacheSize,
+		DigestPeerThreshold: g.conf.PullDigestPeerThreshold,
 	}
 	pkiIDFromMsg := func(msg *protoext.SignedGossipMessage) string {
 		identityMsg := msg.GetPeerIdentity()
@@ -1112,10 +1152,24 @@ func (g *Node) createCertStorePuller() pull.Mediator {
 		IdExtractor:     pkiIDFromMsg,
 		MsgCons:         certConsumer,
 		EgressDigFilter: g.sameOrgOrOurOrgPullFilter,
+		Metrics:         g.gossipMetrics.PullMetrics,
 	}
 	return pull.NewPullMediator(conf, adapter)
 }
 
+// PullTuning returns the current tuning of the pulling of blocks and identities.
+// The identities puller is always tuned along with the channels, so its tuning is the current one.
+func (g *Node) PullTuning() pull.Tuning {
+	return g.certPuller.Tuning()
+}
+
+// TunePull changes the tuning of the pulling of blocks in all channels, and of identities.
+// Channels joined afterwards are tuned the same.
+func (g *Node) TunePull(tuning pull.Tuning) {
+	g.certPuller.Tune(tuning)
+	g.chanState.tunePull(tuning)
+}
+
 func (g *Node) sameOrgOrOurOrgPullFilter(msg protoext.ReceivedMessage) func(string) bool {
 	peersOrg := g.secAdvisor.OrgByPeerIdentity(msg.GetConnectionInfo().Identity)
 	if len(peersOrg) == 0 {
@@ -244,6 +244,8 @@ func newGossipInstanceWithGrpcMcsMetrics(id int, port int, gRPCServer *corecomm.
 		AliveExpirationTimeout:       discoveryConfig.AliveExpirationTimeout,
 		AliveExpirationCheckInterval: discoveryConfig.AliveExpirationCheckInterval,
 		ReconnectInterval:            discoveryConfig.ReconnectInterval,
+		// half of the peers handle the incoming messages in parallel
+		VerificationWorkers: 1 + 3*(id%2),
 	}
 	selfID := api.PeerIdentityType(conf.InternalEndpoint)
 	g := New(conf, gRPCServer.Server(), &orgCryptoService{}, mcs, selfID,
@@ -1648,3 +1650,27 @@ func TestMembershipMetrics(t *testing.T) {
 	waitUntilOrFail(t, waitForMembership(0), "waiting for metrics membership of 0")
 	pI0.Stop()
 }
+
+func TestTunePull(t *testing.T) {
+	g := newGossipInstanceCreateGRPC(0, 100)
+	defer g.Stop()
+	g.JoinChan(&joinChanMsg{}, common.ChannelID("A"))
+
+	tuning := g.PullTuning()
+	assert.Equal(t, g.conf.PullInterval, tuning.PullInterval)
+	assert.Equal(t, g.conf.DigestWaitTime, tuning.PullEngineConfig.DigestWaitTime)
+
+	tuning.PullInterval = 7 * time.Second
+	tuning.PullEngineConfig.MaxDigestSize = 20
+	tuning.DedupCacheSize = 10
+	tuning.DigestPeerThreshold = 30
+	g.TunePull(tuning)
+	assert.Equal(t, tuning, g.PullTuning())
+
+	// Channels joined afterwards are tuned the same
+	conf := (&gossipAdapterImpl{Node: g.Node}).GetConf()
+	assert.Equal(t, 7*time.Second, conf.PullInterval)
+	assert.Equal(t, 20, conf.MaxDigestSize)
+	assert.Equal(t, 10, conf.PullDedupCacheSize)
+	assert.Equal(t, 30, conf.DigestPeerThreshold)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pull
+
+// itemCache remembers the identifiers of a bounded number of items,
+// and forgets the oldest ones when it overflows.
+// It is not thread safe, and should be guarded by its user.
+type itemCache struct {
+	size  int
+	items map[string]struct{}
+	order []string
+}
+
+func newItemCache(size int) *itemCache {
+	return &itemCache{
+		size:  size,
+		items: make(map[string]struct{}),
+	}
+}
+
+// add adds the given item to the cache, and returns whether it wasn't in the cache already.
+// Items are never cached if the size of the cache is 0.
+func (ic *itemCache) add(itemID string) bool {
+	if _, exists := ic.items[itemID]; exists {
+		return false
+	}
+	if ic.size == 0 {
+		return true
+	}
+	ic.items[itemID] = struct{}{}
+	ic.order = append(ic.order, itemID)
+	ic.evict()
+	return true
+}
+
+// contains returns whether the given item is in the cache
+func (ic *itemCache) contains(itemID string) bool {
+	_, exists := ic.items[itemID]
+	return exists
+}
+
+// resize changes the size of the cache, and evicts the oldest items that overflow it
+func (ic *itemCache) resize(size int) {
+	ic.size = size
+	ic.evict()
+}
+
+func (ic *itemCache) evict() {
+	for len(ic.order) > ic.size {
+		delete(ic.items, ic.order[0])
+		ic.order = ic.order[1:]
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pull
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemCache(t *testing.T) {
+	ic := newItemCache(2)
+	assert.True(t, ic.add("a"))
+	assert.False(t, ic.add("a"))
+	assert.True(t, ic.add("b"))
+	assert.True(t, ic.contains("a"))
+
+	// Adding a third item evicts the oldest one
+	assert.True(t, ic.add("c"))
+	assert.False(t, ic.contains("a"))
+	assert.True(t, ic.contains("b"))
+	assert.True(t, ic.contains("c"))
+
+	// Shrinking the cache evicts the oldest items that overflow it
+	ic.resize(1)
+	assert.False(t, ic.contains("b"))
+	assert.True(t, ic.contains("c"))
+
+	// A cache of size 0 caches nothing
+	ic.resize(0)
+	assert.False(t, ic.contains("c"))
+	assert.True(t, ic.add("d"))
+	assert.True(t, ic.add("d"))
+	assert.False(t, ic.contains("d"))
+}
@@ -8,15 +8,18 @@ package pull
 
 import (
 	"encoding/hex"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/gossip"
 	proto "github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/gossip/comm"
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/discovery"
 	"github.com/hyperledger/fabric/gossip/gossip/algo"
+	"github.com/hyperledger/fabric/gossip/metrics"
 	"github.com/hyperledger/fabric/gossip/protoext"
 	"github.com/hyperledger/fabric/gossip/util"
 	"github.com/pkg/errors"
@@ -58,6 +61,20 @@ type Config struct {
 	Tag               proto.GossipMessage_Tag
 	MsgType           proto.PullMsgType
 	PullEngineConfig  algo.PullEngineConfig
+	// DedupCacheSize is the number of recently received items that are remembered
+	// in order to drop them when they are pulled again, or 0 to never drop pulled items.
+	DedupCacheSize int
+	// DigestPeerThreshold is the membership size above which the digests are shrunk
+	// in proportion to the membership size, or 0 to never shrink digests.
+	DigestPeerThreshold int
+}
+
+// Tuning defines the parameters of the pull mediator that can be changed while it runs
+type Tuning struct {
+	PullInterval        time.Duration
+	PullEngineConfig    algo.PullEngineConfig
+	DedupCacheSize      int
+	DigestPeerThreshold int
 }
 
 // IngressDigestFilter filters out entities in digests that are received from remote peers
@@ -91,6 +108,7 @@ type PullAdapter struct {
 	MsgCons          MsgConsumer
 	EgressDigFilter  EgressDigestFilter
 	IngressDigFilter IngressDigestFilter
+	Metrics          *metrics.PullMetrics
 }
 
 // Mediator is a component wrap a PullEngine and provides the methods
@@ -115,6 +133,12 @@ type Mediator interface {
 
 	// HandleMessage handles a message from some remote peer
 	HandleMessage(msg protoext.ReceivedMessage)
+
+	// Tuning returns the current tuning of the Mediator
+	Tuning() Tuning
+
+	// Tune changes the tuning of the Mediator, starting from its next pull round
+	Tune(Tuning)
 }
 
 // pullMediatorImpl is an implementation of Mediator
@@ -126,6 +150,9 @@ type pullMediatorImpl struct {
 	logger       util.Logger
 	itemID2Msg   map[string]*protoext.SignedGossipMessage
 	engine       *algo.PullEngine
+	tuning       Tuning
+	recentItems  *itemCache
+	labels       []string
 }
 
 // NewPullMediator returns a new Mediator
@@ -142,15 +169,29 @@ func NewPullMediator(config Config, adapter *PullAdapter) Mediator {
 		egressDigFilter = acceptAllFilter
 	}
 
+	if adapter.Metrics == nil {
+		adapter.Metrics = metrics.NewGossipMetrics(&disabled.Provider{}).PullMetrics
+	}
+
 	p := &pullMediatorImpl{
 		PullAdapter:  adapter,
 		msgType2Hook: make(map[MsgType][]MessageHook),
 		config:       config,
 		logger:       util.GetLogger(util.PullLogger, config.ID),
 		itemID2Msg:   make(map[string]*protoext.SignedGossipMessage),
+		tuning: Tuning{
+			PullInterval:        config.PullInterval,
+			PullEngineConfig:    config.PullEngineConfig,
+			DedupCacheSize:      config.DedupCacheSize,
+			DigestPeerThreshold: config.DigestPeerThreshold,
+		},
+		recentItems: newItemCache(config.DedupCacheSize),
+		labels:      []string{"channel", string(config.Channel), "type", msgTypeLabel(config.MsgType)},
 	}
 
 	p.engine = algo.NewPullEngineWithFilter(p, config.PullInterval, egressDigFilter.byContext(), config.PullEngineConfig)
+	p.Metrics.Interval.With(p.labels...).Set(config.PullInterval.Seconds())
+	p.Metrics.DigestSizeLimit.With(p.labels...).Set(float64(config.PullEngineConfig.MaxDigestSize))
 
 	if adapter.IngressDigFilter == nil {
 		// Create accept all filter
@@ -191,21 +232,28 @@ func (p *pullMediatorImpl) HandleMessage(m protoext.ReceivedMessage) {
 		pullMsgType = RequestMsgType
 		p.engine.OnReq(itemIDs, req.Nonce, m)
 	} else if res := msg.GetDataUpdate(); res != nil {
-		itemIDs = make([]string, len(res.Data))
-		items = make([]*protoext.SignedGossipMessage, len(res.Data))
+		itemIDs = make([]string, 0, len(res.Data))
+		items = make([]*protoext.SignedGossipMessage, 0, len(res.Data))
 		pullMsgType = ResponseMsgType
-		for i, pulledMsg := range res.Data {
+		for _, pulledMsg := range res.Data {
 			msg, err := protoext.EnvelopeToGossipMessage(pulledMsg)
 			if err != nil {
 				p.logger.Warningf("Data update contains an invalid message: %+v", errors.WithStack(err))
 				return
 			}
+			itemID := p.IdExtractor(msg)
+			if p.isDuplicate(itemID) {
+				p.logger.Debugf("Dropping %s which was received before", itemID)
+				p.Metrics.DuplicateItems.With(p.labels...).Add(1)
+				continue
+			}
 			p.MsgCons(msg)
-			itemIDs[i] = p.IdExtractor(msg)
-			items[i] = msg
+			itemIDs = append(itemIDs, itemID)
+			items = append(items, msg)
 			p.Lock()
-			p.itemID2Msg[itemIDs[i]] = msg
-			p.logger.Debugf("Added %s to the in memory item map, total items: %d", itemIDs[i], len(p.itemID2Msg))
+			p.itemID2Msg[itemID] = msg
+			p.recentItems.add(itemID)
+			p.logger.Debugf("Added %s to the in memory item map, total items: %d", itemID, len(p.itemID2Msg))
 			p.Unlock()
 		}
 		p.engine.OnRes(itemIDs, res.Nonce)
@@ -235,6 +283,7 @@ func (p *pullMediatorImpl) Add(msg *protoext.SignedGossipMessage) {
 	defer p.Unlock()
 	itemID := p.IdExtractor(msg)
 	p.itemID2Msg[itemID] = msg
+	p.recentItems.add(itemID)
 	p.engine.Add(itemID)
 	p.logger.Debugf("Added %s, total items: %d", itemID, len(p.itemID2Msg))
 }
@@ -249,9 +298,82 @@ func (p *pullMediatorImpl) Remove(digest string) {
 	p.logger.Debugf("Removed %s, total items: %d", digest, len(p.itemID2Msg))
 }
 
+// Tuning returns the current tuning of the Mediator
+func (p *pullMediatorImpl) Tuning() Tuning {
+	p.RLock()
+	defer p.RUnlock()
+	return p.tuning
+}
+
+// Tune changes the tuning of the Mediator, starting from its next pull round
+func (p *pullMediatorImpl) Tune(tuning Tuning) {
+	p.Lock()
+	defer p.Unlock()
+	p.tuning = tuning
+	p.recentItems.resize(tuning.DedupCacheSize)
+	p.engine.SetPullInterval(tuning.PullInterval)
+	p.engine.SetConfig(tuning.PullEngineConfig)
+	p.Metrics.Interval.With(p.labels...).Set(tuning.PullInterval.Seconds())
+	p.Metrics.DigestSizeLimit.With(p.labels...).Set(float64(tuning.PullEngineConfig.MaxDigestSize))
+	p.logger.Infof("Tuned %s pull to %+v", p.config.MsgType, tuning)
+}
+
+// isDuplicate returns whether the item with the given identifier was received before,
+// and should be dropped when it is pulled again
+func (p *pullMediatorImpl) isDuplicate(itemID string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	if p.tuning.DedupCacheSize == 0 {
+		return false
+	}
+	_, exists := p.itemID2Msg[itemID]
+	return exists || p.recentItems.contains(itemID)
+}
+
+// adaptDigestSize shrinks the digests sent to remote peers in proportion to the given membership size,
+// once it exceeds the digest peer threshold
+func (p *pullMediatorImpl) adaptDigestSize(peerCount int) {
+	p.RLock()
+	tuning := p.tuning
+	itemCount := len(p.itemID2Msg)
+	p.RUnlock()
+
+	if tuning.DigestPeerThreshold == 0 {
+		return
+	}
+	config := tuning.PullEngineConfig
+	config.MaxDigestSize = adaptiveDigestSize(config.MaxDigestSize, tuning.DigestPeerThreshold, peerCount, itemCount)
+	if config.MaxDigestSize == p.engine.Config().MaxDigestSize {
+		return
+	}
+	p.logger.Debugf("Limiting %s digests to %d items for %d peers", p.config.MsgType, config.MaxDigestSize, peerCount)
+	p.engine.SetConfig(config)
+	p.Metrics.DigestSizeLimit.With(p.labels...).Set(float64(config.MaxDigestSize))
+}
+
+// adaptiveDigestSize returns the maximum digest size for the given number of peers and items,
+// which is the given maximum size, or the number of items if it is not limited,
+// scaled down by the ratio between the peer threshold and the number of peers
+func adaptiveDigestSize(maxSize, peerThreshold, peerCount, itemCount int) int {
+	if peerThreshold == 0 || peerCount <= peerThreshold {
+		return maxSize
+	}
+	size := maxSize
+	if size == 0 || size > itemCount {
+		size = itemCount
+	}
+	size = size * peerThreshold / peerCount
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
 // SelectPeers returns a slice of peers which the engine will initiate the protocol with
 func (p *pullMediatorImpl) SelectPeers() []string {
-	remotePeers := SelectEndpoints(p.config.PeerCountToSelect, p.MemSvc.GetMembership())
+	membership := p.MemSvc.GetMembership()
+	p.adaptDigestSize(len(membership))
+	remotePeers := SelectEndpoints(p.config.PeerCountToSelect, membership)
 	endpoints := make([]string, len(remotePeers))
 	for i, peer := range remotePeers {
 		endpoints[i] = peer.Endpoint
@@ -414,6 +536,11 @@ func (p *pullMediatorImpl) hooksByMsgType(msgType MsgType) []MessageHook {
 	return returnedHooks
 }
 
+// msgTypeLabel returns the metric label of the given type of pull messages
+func msgTypeLabel(msgType proto.PullMsgType) string {
+	return strings.TrimSuffix(strings.ToLower(msgType.String()), "_msg")
+}
+
 // SelectEndpoints select k peers from peerPool and returns them.
 func SelectEndpoints(k int, peerPool []discovery.NetworkMember) []*comm.RemotePeer {
 	if len(peerPool) < k {
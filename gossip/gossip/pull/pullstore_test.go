@@ -20,6 +20,8 @@ import (
 	"github.com/hyperledger/fabric/gossip/comm"
 	"github.com/hyperledger/fabric/gossip/discovery"
 	"github.com/hyperledger/fabric/gossip/gossip/algo"
+	"github.com/hyperledger/fabric/gossip/metrics"
+	"github.com/hyperledger/fabric/gossip/metrics/mocks"
 	"github.com/hyperledger/fabric/gossip/protoext"
 	"github.com/hyperledger/fabric/gossip/util"
 	"github.com/stretchr/testify/assert"
@@ -402,6 +404,123 @@ func TestHandleMessage(t *testing.T) {
 	assert.True(t, inst1.items.Exists(uint64(2)))
 }
 
+func TestDedupPulledItems(t *testing.T) {
+	inst := createPullInstance("localhost:5611", make(map[string]*pullInstance))
+	testMetricProvider := mocks.TestUtilConstructMetricProvider()
+	inst.pullAdapter.Metrics = metrics.NewGossipMetrics(testMetricProvider.FakeProvider).PullMetrics
+	consumed := int32(0)
+	inst.pullAdapter.MsgCons = func(msg *protoext.SignedGossipMessage) {
+		atomic.AddInt32(&consumed, 1)
+	}
+	inst.start()
+	defer inst.stop()
+
+	var responseItems [][]string
+	inst.mediator.RegisterMsgHook(ResponseMsgType, func(itemIDs []string, _ []*protoext.SignedGossipMessage, _ protoext.ReceivedMessage) {
+		responseItems = append(responseItems, itemIDs)
+	})
+
+	// Without a dedup cache, items pulled again are consumed again
+	inst.mediator.HandleMessage(inst.wrapPullMsg(resMsg(0, 1)))
+	inst.mediator.HandleMessage(inst.wrapPullMsg(resMsg(0, 1)))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&consumed))
+	assert.Equal(t, 0, testMetricProvider.FakeDuplicateItems.AddCallCount())
+
+	tuning := inst.mediator.Tuning()
+	tuning.DedupCacheSize = 10
+	inst.mediator.Tune(tuning)
+
+	// An item that is no longer held and was received before the dedup cache was enabled is consumed
+	inst.mediator.Remove("1")
+	inst.mediator.HandleMessage(inst.wrapPullMsg(resMsg(1)))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&consumed))
+
+	// Items that were received before are dropped, whether they are still held or not
+	inst.mediator.Remove("1")
+	inst.mediator.Add(dataMsg(2))
+	inst.mediator.HandleMessage(inst.wrapPullMsg(resMsg(0, 1, 2, 3)))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&consumed))
+	assert.Equal(t, []string{"3"}, responseItems[len(responseItems)-1])
+	assert.Equal(t, 3, testMetricProvider.FakeDuplicateItems.AddCallCount())
+}
+
+func TestTune(t *testing.T) {
+	inst := createPullInstance("localhost:5611", make(map[string]*pullInstance))
+	testMetricProvider := mocks.TestUtilConstructMetricProvider()
+	inst.pullAdapter.Metrics = metrics.NewGossipMetrics(testMetricProvider.FakeProvider).PullMetrics
+	inst.start()
+	defer inst.stop()
+
+	assert.Equal(t, Tuning{
+		PullInterval:     pullInterval,
+		PullEngineConfig: inst.config.PullEngineConfig,
+	}, inst.mediator.Tuning())
+	assert.Equal(t, pullInterval.Seconds(), testMetricProvider.FakePullIntervalGauge.SetArgsForCall(0))
+	assert.Equal(t, []string{"channel", "", "type", "block"}, testMetricProvider.FakePullIntervalGauge.WithArgsForCall(0))
+
+	tuning := Tuning{
+		PullInterval: time.Second,
+		PullEngineConfig: algo.PullEngineConfig{
+			DigestWaitTime:   time.Second,
+			RequestWaitTime:  2 * time.Second,
+			ResponseWaitTime: 3 * time.Second,
+			MaxDigestSize:    4,
+		},
+		DedupCacheSize:      5,
+		DigestPeerThreshold: 6,
+	}
+	inst.mediator.Tune(tuning)
+	assert.Equal(t, tuning, inst.mediator.Tuning())
+	engine := inst.mediator.(*pullMediatorImpl).engine
+	assert.Equal(t, time.Second, engine.PullInterval())
+	assert.Equal(t, tuning.PullEngineConfig, engine.Config())
+	assert.Equal(t, float64(1), testMetricProvider.FakePullIntervalGauge.SetArgsForCall(1))
+	assert.Equal(t, float64(4), testMetricProvider.FakeDigestSizeLimitGauge.SetArgsForCall(1))
+}
+
+func TestAdaptiveDigestSize(t *testing.T) {
+	for _, tc := range []struct {
+		name                                         string
+		maxSize, peerThreshold, peerCount, itemCount int
+		expected                                     int
+	}{
+		{name: "disabled", maxSize: 10, peerThreshold: 0, peerCount: 100, itemCount: 50, expected: 10},
+		{name: "below threshold", maxSize: 10, peerThreshold: 20, peerCount: 20, itemCount: 50, expected: 10},
+		{name: "above threshold", maxSize: 10, peerThreshold: 20, peerCount: 40, itemCount: 50, expected: 5},
+		{name: "unlimited", maxSize: 0, peerThreshold: 20, peerCount: 40, itemCount: 50, expected: 25},
+		{name: "fewer items than limit", maxSize: 100, peerThreshold: 20, peerCount: 40, itemCount: 50, expected: 25},
+		{name: "at least one item", maxSize: 10, peerThreshold: 1, peerCount: 100, itemCount: 50, expected: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, adaptiveDigestSize(tc.maxSize, tc.peerThreshold, tc.peerCount, tc.itemCount))
+		})
+	}
+}
+
+func TestAdaptDigestSize(t *testing.T) {
+	peer2pullInst := make(map[string]*pullInstance)
+	inst1 := createPullInstance("localhost:5611", peer2pullInst)
+	inst2 := createPullInstance("localhost:5612", peer2pullInst)
+	inst3 := createPullInstance("localhost:5613", peer2pullInst)
+	inst1.config.DigestPeerThreshold = 1
+	inst1.start()
+	defer inst1.stop()
+
+	for i := 0; i < 10; i++ {
+		inst1.mediator.Add(dataMsg(i))
+	}
+
+	// inst1 sees 2 peers, which is twice the threshold, so its digests are halved
+	engine := inst1.mediator.(*pullMediatorImpl).engine
+	inst1.mediator.(*pullMediatorImpl).SelectPeers()
+	assert.Equal(t, 5, engine.Config().MaxDigestSize)
+
+	delete(peer2pullInst, inst2.self.Endpoint)
+	delete(peer2pullInst, inst3.self.Endpoint)
+	inst1.mediator.(*pullMediatorImpl).SelectPeers()
+	assert.Equal(t, 0, engine.Config().MaxDigestSize)
+}
+
 func waitUntilOrFail(t *testing.T, pred func() bool) {
 	start := time.Now()
 	limit := start.UnixNano() + timeoutInterval.Nanoseconds()
@@ -431,6 +550,24 @@ func dataMsg(seqNum int) *protoext.SignedGossipMessage {
 	return sMsg
 }
 
+func resMsg(seqNums ...int) *protoext.SignedGossipMessage {
+	var envelopes []*proto.Envelope
+	for _, seqNum := range seqNums {
+		envelopes = append(envelopes, dataMsg(seqNum).Envelope)
+	}
+	sMsg, _ := protoext.NoopSign(&proto.GossipMessage{
+		Channel: []byte(""),
+		Tag:     proto.GossipMessage_EMPTY,
+		Content: &proto.GossipMessage_DataUpdate{
+			DataUpdate: &proto.DataUpdate{
+				MsgType: proto.PullMsgType_BLOCK_MSG,
+				Data:    envelopes,
+			},
+		},
+	})
+	return sMsg
+}
+
 func helloMsg() *proto.GossipMessage {
 	return &proto.GossipMessage{
 		Channel: []byte(""),
@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gossip
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/hyperledger/fabric/gossip/protoext"
+)
+
+// verificationWorkers verifies and handles incoming messages on several goroutines,
+// which spreads the signature verification of high fanout channels across the CPUs.
+// The messages of a remote peer are always handled by the same goroutine, hence
+// in the order in which they are received from that peer.
+type verificationWorkers struct {
+	queues   []chan protoext.ReceivedMessage
+	handle   func(protoext.ReceivedMessage)
+	stopChan <-chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newVerificationWorkers(count, queueSize int, handle func(protoext.ReceivedMessage), stopChan <-chan struct{}) *verificationWorkers {
+	vw := &verificationWorkers{
+		queues:   make([]chan protoext.ReceivedMessage, count),
+		handle:   handle,
+		stopChan: stopChan,
+	}
+	vw.wg.Add(count)
+	for i := range vw.queues {
+		vw.queues[i] = make(chan protoext.ReceivedMessage, queueSize)
+		go vw.work(vw.queues[i])
+	}
+	return vw
+}
+
+func (vw *verificationWorkers) work(queue <-chan protoext.ReceivedMessage) {
+	defer vw.wg.Done()
+	for {
+		select {
+		case <-vw.stopChan:
+			return
+		case msg := <-queue:
+			vw.handle(msg)
+		}
+	}
+}
+
+// dispatch hands the message to the worker of the peer that sent it,
+// blocking while the queue of that worker is full
+func (vw *verificationWorkers) dispatch(msg protoext.ReceivedMessage) {
+	select {
+	case <-vw.stopChan:
+	case vw.queues[vw.shard(msg)] <- msg:
+	}
+}
+
+func (vw *verificationWorkers) shard(msg protoext.ReceivedMessage) int {
+	if msg == nil || msg.GetConnectionInfo() == nil {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(msg.GetConnectionInfo().ID)
+	return int(h.Sum32() % uint32(len(vw.queues)))
+}
+
+// wait waits for the workers to exit once the stop channel is closed
+func (vw *verificationWorkers) wait() {
+	vw.wg.Wait()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gossip
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	proto "github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric/gossip/common"
+	"github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/stretchr/testify/assert"
+)
+
+type peerMsg struct {
+	protoext.ReceivedMessage
+	sender common.PKIidType
+	seq    int
+}
+
+func (m *peerMsg) GetConnectionInfo() *protoext.ConnectionInfo {
+	return &protoext.ConnectionInfo{ID: m.sender}
+}
+
+func (m *peerMsg) GetGossipMessage() *protoext.SignedGossipMessage {
+	return &protoext.SignedGossipMessage{GossipMessage: &proto.GossipMessage{}}
+}
+
+func TestVerificationWorkers(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	handled := map[string][]int{}
+	var wg sync.WaitGroup
+	handle := func(msg protoext.ReceivedMessage) {
+		defer wg.Done()
+		m := msg.(*peerMsg)
+		lock.Lock()
+		defer lock.Unlock()
+		handled[string(m.sender)] = append(handled[string(m.sender)], m.seq)
+	}
+
+	stopChan := make(chan struct{})
+	workers := newVerificationWorkers(4, 2, handle, stopChan)
+
+	peers := 10
+	msgsPerPeer := 50
+	wg.Add(peers * msgsPerPeer)
+	for seq := 0; seq < msgsPerPeer; seq++ {
+		for p := 0; p < peers; p++ {
+			workers.dispatch(&peerMsg{sender: common.PKIidType(fmt.Sprintf("peer%d", p)), seq: seq})
+		}
+	}
+	wg.Wait()
+
+	// the messages of every peer are handled in the order they are dispatched
+	assert.Len(t, handled, peers)
+	for sender, seqs := range handled {
+		assert.Len(t, seqs, msgsPerPeer, sender)
+		for i, seq := range seqs {
+			assert.Equal(t, i, seq, sender)
+		}
+	}
+
+	// the same peer is always assigned the same worker
+	msg := &peerMsg{sender: common.PKIidType("peer0")}
+	assert.Equal(t, workers.shard(msg), workers.shard(msg))
+	assert.Equal(t, 0, workers.shard(nil))
+
+	close(stopChan)
+	workers.wait()
+	// dispatching does not block once the workers are stopped
+	for i := 0; i < 10; i++ {
+		workers.dispatch(msg)
+	}
+}
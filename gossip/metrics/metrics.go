@@ -15,6 +15,7 @@ type GossipMetrics struct {
 	CommMetrics       *CommMetrics
 	MembershipMetrics *MembershipMetrics
 	PrivdataMetrics   *PrivdataMetrics
+	PullMetrics       *PullMetrics
 }
 
 func NewGossipMetrics(p metrics.Provider) *GossipMetrics {
@@ -24,6 +25,7 @@ func NewGossipMetrics(p metrics.Provider) *GossipMetrics {
 		CommMetrics:       newCommMetrics(p),
 		MembershipMetrics: newMembershipMetrics(p),
 		PrivdataMetrics:   newPrivdataMetrics(p),
+		PullMetrics:       newPullMetrics(p),
 	}
 }
 
@@ -98,6 +100,9 @@ type CommMetrics struct {
 	SentMessages     metrics.Counter
 	BufferOverflow   metrics.Counter
 	ReceivedMessages metrics.Counter
+	DroppedMessages  metrics.Counter
+	SentBytes        metrics.Counter
+	ReceivedBytes    metrics.Counter
 }
 
 func newCommMetrics(p metrics.Provider) *CommMetrics {
@@ -105,6 +110,9 @@ func newCommMetrics(p metrics.Provider) *CommMetrics {
 		SentMessages:     p.NewCounter(SentMessagesOpts),
 		BufferOverflow:   p.NewCounter(BufferOverflowOpts),
 		ReceivedMessages: p.NewCounter(ReceivedMessagesOpts),
+		DroppedMessages:  p.NewCounter(DroppedMessagesOpts),
+		SentBytes:        p.NewCounter(SentBytesOpts),
+		ReceivedBytes:    p.NewCounter(ReceivedBytesOpts),
 	}
 }
 
@@ -132,6 +140,33 @@ var (
 		Help:         "Number of messages received",
 		StatsdFormat: "%{#fqname}",
 	}
+
+	DroppedMessagesOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "comm",
+		Name:         "messages_dropped",
+		Help:         "Number of received messages dropped because the organization of the sender exceeded its budget or queue",
+		LabelNames:   []string{"org", "reason"},
+		StatsdFormat: "%{#fqname}.%{org}.%{reason}",
+	}
+
+	SentBytesOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "comm",
+		Name:         "sent_bytes",
+		Help:         "Number of bytes of the messages sent, by channel. The messages not bound to a channel have an empty channel label.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+
+	ReceivedBytesOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "comm",
+		Name:         "received_bytes",
+		Help:         "Number of bytes of the messages received, by channel. The messages not bound to a channel have an empty channel label.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
 )
 
 // MembershipMetrics encapsulates gossip channel membership related metrics
@@ -167,6 +202,10 @@ type PrivdataMetrics struct {
 	ReconciliationDuration         metrics.Histogram
 	PullDuration                   metrics.Histogram
 	RetrieveDuration               metrics.Histogram
+	DisseminatedElements           metrics.Counter
+	AcknowledgedElements           metrics.Counter
+	PulledElements                 metrics.Counter
+	ServedElements                 metrics.Counter
 }
 
 func newPrivdataMetrics(p metrics.Provider) *PrivdataMetrics {
@@ -180,6 +219,10 @@ func newPrivdataMetrics(p metrics.Provider) *PrivdataMetrics {
 		ReconciliationDuration:         p.NewHistogram(ReconciliationDurationOpts),
 		PullDuration:                   p.NewHistogram(PullDurationOpts),
 		RetrieveDuration:               p.NewHistogram(RetrieveDurationOpts),
+		DisseminatedElements:           p.NewCounter(DisseminatedElementsOpts),
+		AcknowledgedElements:           p.NewCounter(AcknowledgedElementsOpts),
+		PulledElements:                 p.NewCounter(PulledElementsOpts),
+		ServedElements:                 p.NewCounter(ServedElementsOpts),
 	}
 }
 
@@ -264,4 +307,84 @@ var (
 		LabelNames:   []string{"channel"},
 		StatsdFormat: "%{#fqname}.%{channel}",
 	}
+
+	DisseminatedElementsOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "privdata",
+		Name:         "disseminated_elements",
+		Help:         "Number of private data elements pushed to eligible peers at endorsement time",
+		LabelNames:   []string{"channel", "chaincode", "collection"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{chaincode}.%{collection}",
+	}
+
+	AcknowledgedElementsOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "privdata",
+		Name:         "acknowledged_elements",
+		Help:         "Number of pushed private data elements that eligible peers acknowledged persisting",
+		LabelNames:   []string{"channel", "chaincode", "collection"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{chaincode}.%{collection}",
+	}
+
+	PulledElementsOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "privdata",
+		Name:         "pulled_elements",
+		Help:         "Number of missing private data elements pulled from remote peers",
+		LabelNames:   []string{"channel", "chaincode", "collection"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{chaincode}.%{collection}",
+	}
+
+	ServedElementsOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "privdata",
+		Name:         "served_elements",
+		Help:         "Number of private data elements sent to remote peers that pulled them",
+		LabelNames:   []string{"channel", "chaincode", "collection"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{chaincode}.%{collection}",
+	}
+)
+
+// PullMetrics encapsulates gossip pull related metrics
+type PullMetrics struct {
+	Interval        metrics.Gauge
+	DigestSizeLimit metrics.Gauge
+	DuplicateItems  metrics.Counter
+}
+
+func newPullMetrics(p metrics.Provider) *PullMetrics {
+	return &PullMetrics{
+		Interval:        p.NewGauge(PullIntervalOpts),
+		DigestSizeLimit: p.NewGauge(DigestSizeLimitOpts),
+		DuplicateItems:  p.NewCounter(DuplicateItemsOpts),
+	}
+}
+
+var (
+	PullIntervalOpts = metrics.GaugeOpts{
+		Namespace:    "gossip",
+		Subsystem:    "pull",
+		Name:         "interval",
+		Help:         "Interval between pull rounds in seconds",
+		LabelNames:   []string{"channel", "type"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{type}",
+	}
+
+	DigestSizeLimitOpts = metrics.GaugeOpts{
+		Namespace:    "gossip",
+		Subsystem:    "pull",
+		Name:         "digest_size_limit",
+		Help:         "Maximum number of items sent in a pull digest, or 0 if not limited",
+		LabelNames:   []string{"channel", "type"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{type}",
+	}
+
+	DuplicateItemsOpts = metrics.CounterOpts{
+		Namespace:    "gossip",
+		Subsystem:    "pull",
+		Name:         "duplicate_items",
+		Help:         "Number of pulled items that were dropped because they had been received before",
+		LabelNames:   []string{"channel", "type"},
+		StatsdFormat: "%{#fqname}.%{channel}.%{type}",
+	}
 )
@@ -42,6 +42,7 @@ func TestMetrics(t *testing.T) {
 	assert.NotNil(t, gossipMetrics.CommMetrics.SentMessages)
 	assert.NotNil(t, gossipMetrics.CommMetrics.ReceivedMessages)
 	assert.NotNil(t, gossipMetrics.CommMetrics.BufferOverflow)
+	assert.NotNil(t, gossipMetrics.CommMetrics.DroppedMessages)
 
 	assert.NotNil(t, gossipMetrics.MembershipMetrics)
 	assert.NotNil(t, gossipMetrics.MembershipMetrics.Total)
@@ -56,4 +57,13 @@ func TestMetrics(t *testing.T) {
 	assert.NotNil(t, gossipMetrics.PrivdataMetrics.ReconciliationDuration)
 	assert.NotNil(t, gossipMetrics.PrivdataMetrics.PullDuration)
 	assert.NotNil(t, gossipMetrics.PrivdataMetrics.RetrieveDuration)
+	assert.NotNil(t, gossipMetrics.PrivdataMetrics.DisseminatedElements)
+	assert.NotNil(t, gossipMetrics.PrivdataMetrics.AcknowledgedElements)
+	assert.NotNil(t, gossipMetrics.PrivdataMetrics.PulledElements)
+	assert.NotNil(t, gossipMetrics.PrivdataMetrics.ServedElements)
+
+	assert.NotNil(t, gossipMetrics.PullMetrics)
+	assert.NotNil(t, gossipMetrics.PullMetrics.Interval)
+	assert.NotNil(t, gossipMetrics.PullMetrics.DigestSizeLimit)
+	assert.NotNil(t, gossipMetrics.PullMetrics.DuplicateItems)
 }
@@ -36,6 +36,19 @@ type TestMetricProvider struct {
 	FakeReconciliationDuration         *metricsfakes.Histogram
 	FakePullDuration                   *metricsfakes.Histogram
 	FakeRetrieveDuration               *metricsfakes.Histogram
+	FakeDisseminatedElements           *metricsfakes.Counter
+	FakeAcknowledgedElements           *metricsfakes.Counter
+	FakePulledElements                 *metricsfakes.Counter
+	FakeServedElements                 *metricsfakes.Counter
+
+	FakePullIntervalGauge    *metricsfakes.Gauge
+	FakeDigestSizeLimitGauge *metricsfakes.Gauge
+	FakeDuplicateItems       *metricsfakes.Counter
+
+	FakeDroppedMessages *metricsfakes.Counter
+
+	FakeSentBytes     *metricsfakes.Counter
+	FakeReceivedBytes *metricsfakes.Counter
 }
 
 func TestUtilConstructMetricProvider() *TestMetricProvider {
@@ -62,6 +75,19 @@ func TestUtilConstructMetricProvider() *TestMetricProvider {
 	fakeReconciliationDuration := testUtilConstructHist()
 	fakePullDuration := testUtilConstructHist()
 	fakeRetrieveDuration := testUtilConstructHist()
+	fakeDisseminatedElements := testUtilConstructCounter()
+	fakeAcknowledgedElements := testUtilConstructCounter()
+	fakePulledElements := testUtilConstructCounter()
+	fakeServedElements := testUtilConstructCounter()
+
+	fakePullIntervalGauge := testUtilConstructGauge()
+	fakeDigestSizeLimitGauge := testUtilConstructGauge()
+	fakeDuplicateItems := testUtilConstructCounter()
+
+	fakeDroppedMessages := testUtilConstructCounter()
+
+	fakeSentBytes := testUtilConstructCounter()
+	fakeReceivedBytes := testUtilConstructCounter()
 
 	fakeProvider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
 		switch opts.Name {
@@ -71,6 +97,22 @@ func TestUtilConstructMetricProvider() *TestMetricProvider {
 			return fakeSentMessages
 		case gmetrics.ReceivedMessagesOpts.Name:
 			return fakeReceivedMessages
+		case gmetrics.DisseminatedElementsOpts.Name:
+			return fakeDisseminatedElements
+		case gmetrics.AcknowledgedElementsOpts.Name:
+			return fakeAcknowledgedElements
+		case gmetrics.PulledElementsOpts.Name:
+			return fakePulledElements
+		case gmetrics.ServedElementsOpts.Name:
+			return fakeServedElements
+		case gmetrics.DuplicateItemsOpts.Name:
+			return fakeDuplicateItems
+		case gmetrics.DroppedMessagesOpts.Name:
+			return fakeDroppedMessages
+		case gmetrics.SentBytesOpts.Name:
+			return fakeSentBytes
+		case gmetrics.ReceivedBytesOpts.Name:
+			return fakeReceivedBytes
 		}
 		return nil
 	}
@@ -109,6 +151,10 @@ func TestUtilConstructMetricProvider() *TestMetricProvider {
 			return fakeDeclarationGauge
 		case gmetrics.TotalOpts.Name:
 			return fakeTotalGauge
+		case gmetrics.PullIntervalOpts.Name:
+			return fakePullIntervalGauge
+		case gmetrics.DigestSizeLimitOpts.Name:
+			return fakeDigestSizeLimitGauge
 		}
 		return nil
 	}
@@ -132,6 +178,16 @@ func TestUtilConstructMetricProvider() *TestMetricProvider {
 		fakeReconciliationDuration,
 		fakePullDuration,
 		fakeRetrieveDuration,
+		fakeDisseminatedElements,
+		fakeAcknowledgedElements,
+		fakePulledElements,
+		fakeServedElements,
+		fakePullIntervalGauge,
+		fakeDigestSizeLimitGauge,
+		fakeDuplicateItems,
+		fakeDroppedMessages,
+		fakeSentBytes,
+		fakeReceivedBytes,
 	}
 }
 
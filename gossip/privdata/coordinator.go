@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package privdata
 
 import (
+	"context"
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/common"
@@ -14,6 +15,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/peer"
 	protostransientstore "github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/committer"
 	"github.com/hyperledger/fabric/core/committer/txvalidator"
 	"github.com/hyperledger/fabric/core/common/privdata"
@@ -103,6 +105,8 @@ type Support struct {
 	committer.Committer
 	Fetcher
 	CapabilityProvider
+	// Tracer records the spans of the validation and commit of blocks, if set.
+	Tracer *tracing.Tracer
 }
 
 // CoordinatorConfig encapsulates the config that is passed to a new coordinator
@@ -145,6 +149,14 @@ func NewCoordinator(mspID string, support Support, store *transientstore.Store,
 	}
 }
 
+// Prevalidate starts, in the background, the checks of the block that do not depend on the state,
+// if the validator supports it. The block is expected to be stored later via StoreBlock
+func (c *coordinator) Prevalidate(block *common.Block) {
+	if p, ok := c.Validator.(txvalidator.Prevalidator); ok {
+		p.Prevalidate(block)
+	}
+}
+
 // StoreBlock stores block with private data into the ledger
 func (c *coordinator) StoreBlock(block *common.Block, privateDataSets util.PvtDataCollections) error {
 	if block.Data == nil {
@@ -158,9 +170,12 @@ func (c *coordinator) StoreBlock(block *common.Block, privateDataSets util.PvtDa
 
 	logger.Debugf("[%s] Validating block [%d]", c.ChainID, block.Header.Number)
 
+	validationSpan := c.startSpan("coordinator.Validate", block)
 	validationStart := time.Now()
 	err := c.Validator.Validate(block)
 	c.reportValidationDuration(time.Since(validationStart))
+	validationSpan.SetError(err)
+	validationSpan.End()
 	if err != nil {
 		logger.Errorf("Validation failed: %+v", err)
 		return err
@@ -178,7 +193,11 @@ func (c *coordinator) StoreBlock(block *common.Block, privateDataSets util.PvtDa
 	}
 	if exist {
 		commitOpts := &ledger.CommitOptions{FetchPvtDataFromLedger: true}
-		return c.CommitLegacy(blockAndPvtData, commitOpts)
+		commitSpan := c.startSpan("coordinator.Commit", block)
+		err = c.CommitLegacy(blockAndPvtData, commitOpts)
+		commitSpan.SetError(err)
+		commitSpan.End()
+		return err
 	}
 
 	listMissingPrivateDataDurationHistogram := c.metrics.ListMissingPrivateDataDuration.With("channel", c.ChainID)
@@ -220,9 +239,12 @@ func (c *coordinator) StoreBlock(block *common.Block, privateDataSets util.PvtDa
 	blockAndPvtData.MissingPvtData = retrievedPvtdata.blockPvtdata.MissingPvtData
 
 	// commit block and private data
+	commitSpan := c.startSpan("coordinator.Commit", block)
 	commitStart := time.Now()
 	err = c.CommitLegacy(blockAndPvtData, &ledger.CommitOptions{})
 	c.reportCommitDuration(time.Since(commitStart))
+	commitSpan.SetError(err)
+	commitSpan.End()
 	if err != nil {
 		return errors.Wrap(err, "commit failed")
 	}
@@ -347,6 +369,15 @@ func (c *coordinator) getTxPvtdataInfoFromBlock(block *common.Block) ([]*ledger.
 	return txPvtdataItemsFromBlock, nil
 }
 
+// startSpan starts a span of the processing of the given block.
+func (c *coordinator) startSpan(name string, block *common.Block) *tracing.Span {
+	_, span := c.Tracer.Start(context.Background(), name)
+	span.SetAttribute("channel", c.ChainID)
+	span.SetAttribute("block", block.Header.Number)
+	span.SetAttribute("transactions", len(block.Data.Data))
+	return span
+}
+
 func (c *coordinator) reportValidationDuration(time time.Duration) {
 	c.metrics.ValidationDuration.With("channel", c.ChainID).Observe(time.Seconds())
 }
@@ -27,6 +27,7 @@ import (
 	tspb "github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/bccsp/factory"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/tracing"
 	util2 "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/common/privdata"
 	"github.com/hyperledger/fabric/core/ledger"
@@ -1983,3 +1984,80 @@ func TestCoordinatorMetrics(t *testing.T) {
 	)
 	assert.True(t, testMetricProvider.FakePurgeDuration.ObserveArgsForCall(0) > 0)
 }
+
+type spanRecorder func(*tracing.Span)
+
+func (s spanRecorder) Export(span *tracing.Span) { s(span) }
+
+func TestCoordinatorTracing(t *testing.T) {
+	var spans []*tracing.Span
+	tracer := tracing.NewTracer(spanRecorder(func(span *tracing.Span) {
+		spans = append(spans, span)
+	}), 1)
+
+	committer := &mocks.Committer{}
+	committer.On("DoesPvtDataInfoExistInLedger", mock.Anything).Return(true, nil)
+	committer.On("CommitLegacy", mock.Anything, mock.Anything).Return(errors.New("disk full"))
+
+	capabilityProvider := &capabilitymock.CapabilityProvider{}
+	appCapability := &capabilitymock.AppCapabilities{}
+	capabilityProvider.On("Capabilities").Return(appCapability)
+	appCapability.On("StorePvtDataOfInvalidTx").Return(true)
+
+	validator := &validatorMock{}
+	coordinator := NewCoordinator("Org1MSP", Support{
+		ChainID:            "testchannelid",
+		Committer:          committer,
+		Fetcher:            &fetcherMock{t: t},
+		Validator:          validator,
+		CapabilityProvider: capabilityProvider,
+		Tracer:             tracer,
+	}, nil, protoutil.SignedData{}, metrics.NewGossipMetrics(&disabled.Provider{}).PrivdataMetrics, testConfig, nil)
+
+	hash := util2.ComputeSHA256([]byte("rws-pre-image"))
+	block := (&blockFactory{channelID: "testchannelid"}).AddTxnWithEndorsement("tx1", "ns1", hash, "org1", true, "c1").
+		AddTxnWithEndorsement("tx2", "ns2", hash, "org2", true, "c1").create()
+
+	err := coordinator.StoreBlock(block, nil)
+	assert.EqualError(t, err, "disk full")
+	require.Len(t, spans, 2)
+	assert.Equal(t, "coordinator.Validate", spans[0].Name)
+	assert.NoError(t, spans[0].Err)
+	assert.Equal(t, map[string]interface{}{
+		"channel":      "testchannelid",
+		"block":        uint64(1),
+		"transactions": 2,
+	}, spans[0].Attributes())
+	assert.Equal(t, "coordinator.Commit", spans[1].Name)
+	assert.EqualError(t, spans[1].Err, "disk full")
+
+	spans = nil
+	validator.err = errors.New("invalid block")
+	err = coordinator.StoreBlock(block, nil)
+	assert.EqualError(t, err, "invalid block")
+	require.Len(t, spans, 1)
+	assert.Equal(t, "coordinator.Validate", spans[0].Name)
+	assert.EqualError(t, spans[0].Err, "invalid block")
+}
+
+type prevalidatingValidatorMock struct {
+	validatorMock
+	prevalidated []*common.Block
+}
+
+func (v *prevalidatingValidatorMock) Prevalidate(block *common.Block) {
+	v.prevalidated = append(v.prevalidated, block)
+}
+
+func TestCoordinatorPrevalidate(t *testing.T) {
+	block := protoutil.NewBlock(3, []byte{})
+
+	v := &prevalidatingValidatorMock{}
+	c := &coordinator{Support: Support{Validator: v}}
+	c.Prevalidate(block)
+	assert.Equal(t, []*common.Block{block}, v.prevalidated)
+
+	// a validator that does not support the prevalidation is skipped
+	c = &coordinator{Support: Support{Validator: &validatorMock{}}}
+	c.Prevalidate(block)
+}
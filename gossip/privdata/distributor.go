@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -52,8 +53,24 @@ type gossipAdapter interface {
 
 // PvtDataDistributor interface to defines API of distributing private data
 type PvtDataDistributor interface {
-	// Distribute broadcast reliably private data read write set based on policies
-	Distribute(txID string, privData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) error
+	// Distribute broadcast reliably private data read write set based on policies,
+	// and returns a delivery receipt for each of the collections
+	Distribute(txID string, privData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) ([]*DeliveryReceipt, error)
+}
+
+// DeliveryReceipt reports the eligible peers that acknowledged persisting
+// the private data of a collection that was distributed to them
+type DeliveryReceipt struct {
+	Namespace         string
+	Collection        string
+	RequiredPeerCount int
+	AcknowledgedBy    []string
+}
+
+// String returns a human readable representation of the receipt
+func (r *DeliveryReceipt) String() string {
+	return fmt.Sprintf("collection %s of %s acknowledged by %d out of %d required peers %v",
+		r.Collection, r.Namespace, len(r.AcknowledgedBy), r.RequiredPeerCount, r.AcknowledgedBy)
 }
 
 // IdentityDeserializerFactory is a factory interface to create
@@ -134,18 +151,21 @@ func NewDistributor(chainID string, gossip gossipAdapter, factory CollectionAcce
 	}
 }
 
-// Distribute broadcast reliably private data read write set based on policies
-func (d *distributorImpl) Distribute(txID string, privData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) error {
+// Distribute broadcast reliably private data read write set based on policies,
+// and returns a delivery receipt for each of the collections
+func (d *distributorImpl) Distribute(txID string, privData *transientstore.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) ([]*DeliveryReceipt, error) {
 	disseminationPlan, err := d.computeDisseminationPlan(txID, privData, blkHt)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	return d.disseminate(disseminationPlan)
 }
 
 type dissemination struct {
-	msg      *protoext.SignedGossipMessage
-	criteria gossipgossip.SendCriteria
+	msg               *protoext.SignedGossipMessage
+	criteria          gossipgossip.SendCriteria
+	endpoint          string
+	requiredPeerCount int
 }
 
 func (d *distributorImpl) computeDisseminationPlan(txID string,
@@ -286,6 +306,8 @@ func (d *distributorImpl) disseminationPlanForMsg(colAP privdata.CollectionAcces
 					Envelope:      proto.Clone(pvtDataMsg.Envelope).(*protosgossip.Envelope),
 					GossipMessage: proto.Clone(pvtDataMsg.GossipMessage).(*protosgossip.GossipMessage),
 				},
+				endpoint:          peerEndpoints[string(peer2SendPerOrg.PKIId)],
+				requiredPeerCount: colAP.RequiredPeerCount(),
 			})
 
 			// Add unselected peers to remainingPeersAcrossOrgs
@@ -339,6 +361,8 @@ func (d *distributorImpl) disseminationPlanForMsg(colAP privdata.CollectionAcces
 				Envelope:      proto.Clone(pvtDataMsg.Envelope).(*protosgossip.Envelope),
 				GossipMessage: proto.Clone(pvtDataMsg.GossipMessage).(*protosgossip.GossipMessage),
 			},
+			endpoint:          peerEndpoints[string(peer2Send.PKIId)],
+			requiredPeerCount: colAP.RequiredPeerCount(),
 		})
 		if requiredPeerRemainingCount > 0 {
 			requiredPeerRemainingCount--
@@ -384,8 +408,24 @@ func (d *distributorImpl) eligiblePeersOfChannel(routingFilter filter.RoutingFil
 	return eligiblePeers
 }
 
-func (d *distributorImpl) disseminate(disseminationPlan []*dissemination) error {
+func (d *distributorImpl) disseminate(disseminationPlan []*dissemination) ([]*DeliveryReceipt, error) {
+	var receipts []*DeliveryReceipt
+	receiptsByCollection := map[string]*DeliveryReceipt{}
+	for _, dis := range disseminationPlan {
+		m := dis.msg.GetPrivateData().Payload
+		key := m.Namespace + "~" + m.CollectionName
+		if _, exists := receiptsByCollection[key]; !exists {
+			receiptsByCollection[key] = &DeliveryReceipt{
+				Namespace:         m.Namespace,
+				Collection:        m.CollectionName,
+				RequiredPeerCount: dis.requiredPeerCount,
+			}
+			receipts = append(receipts, receiptsByCollection[key])
+		}
+	}
+
 	var failures uint32
+	var lock sync.Mutex
 	var wg sync.WaitGroup
 	wg.Add(len(disseminationPlan))
 	start := time.Now()
@@ -393,20 +433,38 @@ func (d *distributorImpl) disseminate(disseminationPlan []*dissemination) error
 		go func(dis *dissemination) {
 			defer wg.Done()
 			defer d.reportSendDuration(start)
+			m := dis.msg.GetPrivateData().Payload
+			d.metrics.DisseminatedElements.With("channel", d.chainID, "chaincode", m.Namespace, "collection", m.CollectionName).Add(1)
 			err := d.SendByCriteria(dis.msg, dis.criteria)
 			if err != nil {
 				atomic.AddUint32(&failures, 1)
-				m := dis.msg.GetPrivateData().Payload
 				logger.Error("Failed disseminating private RWSet for TxID", m.TxId, ", namespace", m.Namespace, "collection", m.CollectionName, ":", err)
+				return
+			}
+			if dis.criteria.MinAck == 0 {
+				return
 			}
+			d.metrics.AcknowledgedElements.With("channel", d.chainID, "chaincode", m.Namespace, "collection", m.CollectionName).Add(1)
+			lock.Lock()
+			defer lock.Unlock()
+			receipt := receiptsByCollection[m.Namespace+"~"+m.CollectionName]
+			receipt.AcknowledgedBy = append(receipt.AcknowledgedBy, dis.endpoint)
 		}(dis)
 	}
 	wg.Wait()
-	failureCount := atomic.LoadUint32(&failures)
-	if failureCount != 0 {
-		return errors.Errorf("Failed disseminating %d out of %d private dissemination plans", failureCount, len(disseminationPlan))
+
+	var unsatisfied []string
+	for _, receipt := range receipts {
+		logger.Debugf("Private data dissemination receipt: %s", receipt)
+		if len(receipt.AcknowledgedBy) < receipt.RequiredPeerCount {
+			unsatisfied = append(unsatisfied, receipt.String())
+		}
+	}
+	if len(unsatisfied) != 0 {
+		return receipts, errors.Errorf("Failed disseminating %d out of %d private dissemination plans: %s",
+			atomic.LoadUint32(&failures), len(disseminationPlan), strings.Join(unsatisfied, ", "))
 	}
-	return nil
+	return receipts, nil
 }
 
 func (d *distributorImpl) reportSendDuration(startTime time.Time) {
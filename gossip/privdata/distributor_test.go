@@ -9,11 +9,13 @@ package privdata
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	proto "github.com/hyperledger/fabric-protos-go/gossip"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-protos-go/transientstore"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/common/privdata"
 	"github.com/hyperledger/fabric/gossip/api"
 	gcommon "github.com/hyperledger/fabric/gossip/common"
@@ -54,6 +56,9 @@ func (g *gossipMock) PeersOfChannel(channelID gcommon.ChannelID) []discovery.Net
 
 func (g *gossipMock) SendByCriteria(message *protoext.SignedGossipMessage, criteria gossip2.SendCriteria) error {
 	args := g.Called(message, criteria)
+	if f, isFunc := args.Get(0).(func(*protoext.SignedGossipMessage, gossip2.SendCriteria) error); isFunc {
+		return f(message, criteria)
+	}
 	if args.Get(0) != nil {
 		return args.Get(0).(error)
 	}
@@ -150,7 +155,7 @@ func TestDistributor(t *testing.T) {
 	d := NewDistributor(channelID, g, accessFactoryMock, metrics, 0)
 	pdFactory := &pvtDataFactory{}
 	pvtData := pdFactory.addRWSet().addNSRWSet("ns1", "c1", "c2").addRWSet().addNSRWSet("ns2", "c1", "c2").create()
-	err := d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
+	receipts, err := d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
 		PvtRwset: pvtData[0].WriteSet,
 		CollectionConfigs: map[string]*peer.CollectionConfigPackage{
 			"ns1": {
@@ -159,7 +164,13 @@ func TestDistributor(t *testing.T) {
 		},
 	}, 0)
 	assert.NoError(t, err)
-	err = d.Distribute("tx2", &transientstore.TxPvtReadWriteSetWithConfigInfo{
+	assert.Len(t, receipts, 2)
+	for _, receipt := range receipts {
+		assert.Equal(t, "ns1", receipt.Namespace)
+		assert.Equal(t, 1, receipt.RequiredPeerCount)
+		assert.Len(t, receipt.AcknowledgedBy, 1)
+	}
+	_, err = d.Distribute("tx2", &transientstore.TxPvtReadWriteSetWithConfigInfo{
 		PvtRwset: pvtData[1].WriteSet,
 		CollectionConfigs: map[string]*peer.CollectionConfigPackage{
 			"ns2": {
@@ -197,7 +208,7 @@ func TestDistributor(t *testing.T) {
 
 	// Bad path: dependencies (gossip and others) don't work properly
 	g.err = errors.New("failed obtaining filter")
-	err = d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
+	_, err = d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
 		PvtRwset: pvtData[0].WriteSet,
 		CollectionConfigs: map[string]*peer.CollectionConfigPackage{
 			"ns1": {
@@ -222,7 +233,7 @@ func TestDistributor(t *testing.T) {
 	})
 
 	g.err = nil
-	err = d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
+	_, err = d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
 		PvtRwset: pvtData[0].WriteSet,
 		CollectionConfigs: map[string]*peer.CollectionConfigPackage{
 			"ns1": {
@@ -232,10 +243,117 @@ func TestDistributor(t *testing.T) {
 	}, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Failed disseminating 2 out of 2 private dissemination plans")
+	assert.Contains(t, err.Error(), "collection c1 of ns1 acknowledged by 0 out of 1 required peers")
 
 	assert.Equal(t,
 		[]string{"channel", channelID},
 		testMetricProvider.FakeSendDuration.WithArgsForCall(0),
 	)
 	assert.True(t, testMetricProvider.FakeSendDuration.ObserveArgsForCall(0) > 0)
+
+	assert.Equal(t, 10, testMetricProvider.FakeDisseminatedElements.AddCallCount())
+	assert.Equal(t, 4, testMetricProvider.FakeAcknowledgedElements.AddCallCount())
+	assert.Equal(t,
+		[]string{"channel", channelID, "chaincode", "ns1"},
+		testMetricProvider.FakeAcknowledgedElements.WithArgsForCall(0)[:4],
+	)
+}
+
+func TestDistributorRequiredPeerCount(t *testing.T) {
+	channelID := "test"
+
+	colConfig := &peer.CollectionConfig{
+		Payload: &peer.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &peer.StaticCollectionConfig{
+				Name: "c1",
+			},
+		},
+	}
+	pdFactory := &pvtDataFactory{}
+	pvtData := pdFactory.addRWSet().addNSRWSet("ns1", "c1").create()
+
+	for _, tc := range []struct {
+		name              string
+		requiredPeerCount int
+		unresponsive      bool
+		expectedAcks      int
+		expectedErr       string
+	}{
+		{name: "no peer required", requiredPeerCount: 0, unresponsive: true, expectedAcks: 0},
+		{name: "one of two peers required", requiredPeerCount: 1, expectedAcks: 1},
+		{name: "both peers required", requiredPeerCount: 2, expectedAcks: 2},
+		{name: "both peers required but one unresponsive", requiredPeerCount: 2, unresponsive: true, expectedAcks: 1,
+			expectedErr: "collection c1 of ns1 acknowledged by 1 out of 2 required peers [p1]"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g := &gossipMock{
+				Mock: mock.Mock{},
+				PeerSignature: api.PeerSignature{
+					Signature:    []byte{3, 4, 5},
+					Message:      []byte{6, 7, 8},
+					PeerIdentity: []byte{0, 1, 2},
+				},
+			}
+			g.On("PeersOfChannel", gcommon.ChannelID(channelID)).Return([]discovery.NetworkMember{
+				{PKIid: gcommon.PKIidType{1}, Endpoint: "p1"},
+				{PKIid: gcommon.PKIidType{2}, Endpoint: "p2"},
+			})
+			g.On("IdentityInfo").Return(api.PeerIdentitySet{
+				{
+					PKIId:        gcommon.PKIidType{1},
+					Organization: api.OrgIdentityType("org1"),
+				},
+				{
+					PKIId:        gcommon.PKIidType{2},
+					Organization: api.OrgIdentityType("org2"),
+				},
+			})
+			// The acknowledgements are awaited only from the required peers,
+			// and the peer of org2 never acknowledges when unresponsive
+			var minAcks int
+			var lock sync.Mutex
+			g.On("SendByCriteria", mock.Anything, mock.Anything).Return(func(_ *protoext.SignedGossipMessage, criteria gossip2.SendCriteria) error {
+				lock.Lock()
+				minAcks += criteria.MinAck
+				lock.Unlock()
+				if criteria.MinAck > 0 && tc.unresponsive && criteria.IsEligible(discovery.NetworkMember{PKIid: gcommon.PKIidType{2}}) {
+					return errors.New("timed out")
+				}
+				return nil
+			})
+
+			policyMock := &mocks2.CollectionAccessPolicy{}
+			Setup(policyMock, tc.requiredPeerCount, 2, func(_ protoutil.SignedData) bool {
+				return true
+			}, map[string]struct{}{
+				"org1": {},
+				"org2": {},
+			}, false)
+			accessFactoryMock := &mocks2.CollectionAccessFactory{}
+			accessFactoryMock.On("AccessPolicy", colConfig, channelID).Return(policyMock, nil)
+
+			d := NewDistributor(channelID, g, accessFactoryMock, metrics.NewGossipMetrics(&disabled.Provider{}).PrivdataMetrics, 0)
+			receipts, err := d.Distribute("tx1", &transientstore.TxPvtReadWriteSetWithConfigInfo{
+				PvtRwset: pvtData[0].WriteSet,
+				CollectionConfigs: map[string]*peer.CollectionConfigPackage{
+					"ns1": {
+						Config: []*peer.CollectionConfig{colConfig},
+					},
+				},
+			}, 0)
+			if tc.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.requiredPeerCount, minAcks)
+			assert.Len(t, receipts, 1)
+			assert.Equal(t, "ns1", receipts[0].Namespace)
+			assert.Equal(t, "c1", receipts[0].Collection)
+			assert.Equal(t, tc.requiredPeerCount, receipts[0].RequiredPeerCount)
+			assert.Len(t, receipts[0].AcknowledgedBy, tc.expectedAcks)
+		})
+	}
 }
@@ -168,6 +168,9 @@ func (p *puller) createResponse(message protoext.ReceivedMessage) []*protosgossi
 			Signature: authInfo.Signature,
 		}, connectionEndpoint)...)
 	}
+	for _, el := range returned {
+		p.metrics.ServedElements.With("channel", p.channel, "chaincode", el.Digest.Namespace, "collection", el.Digest.Collection).Add(1)
+	}
 	return returned
 }
 
@@ -303,6 +306,7 @@ func (p *puller) fetchPrivateData(dig2Filter digestToFilterMapping) (*privdataco
 				Collection: resp.Digest.Collection,
 			})
 			itemsLeftToCollect--
+			p.metrics.PulledElements.With("channel", p.channel, "chaincode", resp.Digest.Namespace, "collection", resp.Digest.Collection).Add(1)
 		}
 		res.AvailableElements = append(res.AvailableElements, responses...)
 	}
@@ -16,6 +16,14 @@ import (
 const (
 	btlPullMarginDefault           = 10
 	transientBlockRetentionDefault = 1000
+	electionLatencyBucketDefault   = 50 * time.Millisecond
+)
+
+const (
+	// ElectionStrategyPriority elects the peer with the lowest configured priority
+	ElectionStrategyPriority = "priority"
+	// ElectionStrategyOrdererLatency elects the peer with the lowest latency to the ordering service
+	ElectionStrategyOrdererLatency = "ordererLatency"
 )
 
 // ServiceConfig is the config struct for gossip services
@@ -40,6 +48,15 @@ type ServiceConfig struct {
 	// ElectionLeaderElectionDuration is the time passes since last declaration message before peer decides to perform
 	// leader election (unit: second).
 	ElectionLeaderElectionDuration time.Duration
+	// ElectionStrategy is the strategy that ranks the peers of the organization as candidates to be the leader,
+	// either "priority" or "ordererLatency". If empty, the peer with the lowest PKI-ID is elected.
+	ElectionStrategy string
+	// ElectionPriority is the priority of the peer under the "priority" election strategy. Peers of lower
+	// priorities are preferred, and peers of negative priorities are never elected.
+	ElectionPriority int
+	// ElectionLatencyBucket is the latency to the ordering service under which peers are ranked the same
+	// under the "ordererLatency" election strategy.
+	ElectionLatencyBucket time.Duration
 	// PvtDataPullRetryThreshold determines the maximum duration of time private data corresponding for
 	// a given block.
 	PvtDataPullRetryThreshold time.Duration
@@ -56,6 +73,9 @@ type ServiceConfig struct {
 	// transaction's private data from other peers need to be skipped during the commit time and pulled
 	// only through reconciler.
 	SkipPullingInvalidTransactionsDuringCommit bool
+	// ReplicaMode makes the peer a read-only replica that pulls the blocks of its channels from other
+	// peers and commits them without joining the gossip of the channels, nor electing a leader.
+	ReplicaMode bool
 }
 
 func GlobalConfig() *ServiceConfig {
@@ -71,11 +91,15 @@ func (c *ServiceConfig) loadGossipConfig() {
 	c.NonBlockingCommitMode = viper.GetBool("peer.gossip.nonBlockingCommitMode")
 	c.UseLeaderElection = viper.GetBool("peer.gossip.useLeaderElection")
 	c.OrgLeader = viper.GetBool("peer.gossip.orgLeader")
+	c.ReplicaMode = viper.GetBool("peer.replica.enabled")
 
 	c.ElectionStartupGracePeriod = util.GetDurationOrDefault("peer.gossip.election.startupGracePeriod", election.DefStartupGracePeriod)
 	c.ElectionMembershipSampleInterval = util.GetDurationOrDefault("peer.gossip.election.membershipSampleInterval", election.DefMembershipSampleInterval)
 	c.ElectionLeaderAliveThreshold = util.GetDurationOrDefault("peer.gossip.election.leaderAliveThreshold", election.DefLeaderAliveThreshold)
 	c.ElectionLeaderElectionDuration = util.GetDurationOrDefault("peer.gossip.election.leaderElectionDuration", election.DefLeaderElectionDuration)
+	c.ElectionStrategy = viper.GetString("peer.gossip.election.strategy")
+	c.ElectionPriority = viper.GetInt("peer.gossip.election.priority")
+	c.ElectionLatencyBucket = util.GetDurationOrDefault("peer.gossip.election.latencyBucket", electionLatencyBucketDefault)
 
 	c.PvtDataPushAckTimeout = viper.GetDuration("peer.gossip.pvtData.pushAckTimeout")
 	c.PvtDataPullRetryThreshold = viper.GetDuration("peer.gossip.pvtData.pullRetryThreshold")
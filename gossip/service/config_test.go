@@ -28,9 +28,13 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.gossip.orgLeader", true)
 	viper.Set("peer.gossip.election.leaderAliveThreshold", "10m")
 	viper.Set("peer.gossip.election.leaderElectionDuration", "5s")
+	viper.Set("peer.gossip.election.strategy", "priority")
+	viper.Set("peer.gossip.election.priority", 2)
+	viper.Set("peer.gossip.election.latencyBucket", "100ms")
 	viper.Set("peer.gossip.pvtData.btlPullMargin", 15)
 	viper.Set("peer.gossip.pvtData.transientstoreMaxBlockRetention", 1000)
 	viper.Set("peer.gossip.pvtData.skipPullingInvalidTransactionsDuringCommit", false)
+	viper.Set("peer.replica.enabled", true)
 
 	coreConfig := service.GlobalConfig()
 
@@ -46,9 +50,13 @@ func TestGlobalConfig(t *testing.T) {
 		ElectionLeaderElectionDuration:             5 * time.Second,
 		ElectionStartupGracePeriod:                 election.DefStartupGracePeriod,
 		ElectionMembershipSampleInterval:           election.DefMembershipSampleInterval,
+		ElectionStrategy:                           service.ElectionStrategyPriority,
+		ElectionPriority:                           2,
+		ElectionLatencyBucket:                      100 * time.Millisecond,
 		BtlPullMargin:                              15,
 		TransientstoreMaxBlockRetention:            uint64(1000),
 		SkipPullingInvalidTransactionsDuringCommit: false,
+		ReplicaMode:                                true,
 	}
 
 	assert.Equal(t, coreConfig, expectedConfig)
@@ -7,11 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package service
 
 import (
+	"net"
 	"sync"
+	"time"
 
 	gproto "github.com/hyperledger/fabric-protos-go/gossip"
 	tspb "github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/committer"
 	"github.com/hyperledger/fabric/core/committer/txvalidator"
 	"github.com/hyperledger/fabric/core/common/privdata"
@@ -25,6 +28,7 @@ import (
 	"github.com/hyperledger/fabric/gossip/election"
 	"github.com/hyperledger/fabric/gossip/filter"
 	"github.com/hyperledger/fabric/gossip/gossip"
+	"github.com/hyperledger/fabric/gossip/gossip/pull"
 	gossipmetrics "github.com/hyperledger/fabric/gossip/metrics"
 	gossipprivdata "github.com/hyperledger/fabric/gossip/privdata"
 	"github.com/hyperledger/fabric/gossip/protoext"
@@ -104,6 +108,16 @@ type gossipSvc interface {
 	// IsInMyOrg checks whether a network member is in this peer's org
 	IsInMyOrg(member discovery.NetworkMember) bool
 
+	// PullTuning returns the current tuning of the pulling of blocks and identities
+	PullTuning() pull.Tuning
+
+	// TunePull changes the tuning of the pulling of blocks and identities
+	TunePull(tuning pull.Tuning)
+
+	// Register binds the gossip component to another gRPC server, for it to
+	// also accept connections on the listeners of that server
+	Register(s *grpc.Server)
+
 	// Stop stops the gossip component
 	Stop()
 }
@@ -255,27 +269,29 @@ func New(
 	}, nil
 }
 
-// DistributePrivateData distribute private read write set inside the channel based on the collections policies
-func (g *GossipService) DistributePrivateData(channelID string, txID string, privData *tspb.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) error {
+// DistributePrivateData distribute private read write set inside the channel based on the collections policies,
+// and returns the receipts of the eligible peers that acknowledged persisting it
+func (g *GossipService) DistributePrivateData(channelID string, txID string, privData *tspb.TxPvtReadWriteSetWithConfigInfo, blkHt uint64) ([]*gossipprivdata.DeliveryReceipt, error) {
 	g.lock.RLock()
 	handler, exists := g.privateHandlers[channelID]
 	g.lock.RUnlock()
 	if !exists {
-		return errors.Errorf("No private data handler for %s", channelID)
+		return nil, errors.Errorf("No private data handler for %s", channelID)
 	}
 
-	if err := handler.distributor.Distribute(txID, privData, blkHt); err != nil {
+	receipts, err := handler.distributor.Distribute(txID, privData, blkHt)
+	if err != nil {
 		err := errors.WithMessagef(err, "failed to distribute private collection, txID %s, channel %s", txID, channelID)
 		logger.Error(err)
-		return err
+		return nil, err
 	}
 
 	if err := handler.coordinator.StorePvtData(txID, privData, blkHt); err != nil {
 		logger.Error("Failed to store private data into transient store, txID",
 			txID, "channel", channelID, "due to", err)
-		return err
+		return nil, err
 	}
-	return nil
+	return receipts, nil
 }
 
 // NewConfigEventer creates a ConfigProcessor which the channelconfig.BundleSource can ultimately route config updates to
@@ -291,6 +307,7 @@ type Support struct {
 	CollectionStore      privdata.CollectionStore
 	IdDeserializeFactory gossipprivdata.IdentityDeserializerFactory
 	CapabilityProvider   gossipprivdata.CapabilityProvider
+	Tracer               *tracing.Tracer
 }
 
 // InitializeChannel allocates the state provider and should be invoked once per channel per execution
@@ -312,6 +329,11 @@ func (g *GossipService) InitializeChannel(channelID string, ordererSource *order
 		PullRetryThreshold:             g.serviceConfig.PvtDataPullRetryThreshold,
 		SkipPullingInvalidTransactions: g.serviceConfig.SkipPullingInvalidTransactionsDuringCommit,
 	}
+	if g.serviceConfig.ReplicaMode {
+		// A replica is not a member of the channel, hence it cannot pull private data from
+		// other peers. The private data is committed as missing without waiting for it.
+		coordinatorConfig.PullRetryThreshold = 0
+	}
 	selfSignedData := g.createSelfSignedData()
 	mspID := string(g.secAdv.OrgByPeerIdentity(selfSignedData.Identity))
 	coordinator := gossipprivdata.NewCoordinator(mspID, gossipprivdata.Support{
@@ -321,12 +343,13 @@ func (g *GossipService) InitializeChannel(channelID string, ordererSource *order
 		Committer:          support.Committer,
 		Fetcher:            fetcher,
 		CapabilityProvider: support.CapabilityProvider,
+		Tracer:             support.Tracer,
 	}, store, selfSignedData, g.metrics.PrivdataMetrics, coordinatorConfig,
 		support.IdDeserializeFactory)
 
 	var reconciler gossipprivdata.PvtDataReconciler
 
-	if g.privdataConfig.ReconciliationEnabled {
+	if g.privdataConfig.ReconciliationEnabled && !g.serviceConfig.ReplicaMode {
 		reconciler = gossipprivdata.NewReconciler(channelID, g.metrics.PrivdataMetrics,
 			support.Committer, fetcher, g.privdataConfig)
 	} else {
@@ -353,7 +376,7 @@ func (g *GossipService) InitializeChannel(channelID string, ordererSource *order
 		blockingMode,
 		stateConfig)
 	if g.deliveryService[channelID] == nil {
-		g.deliveryService[channelID] = g.deliveryFactory.Service(g, ordererSource, g.mcs, g.serviceConfig.OrgLeader)
+		g.deliveryService[channelID] = g.deliveryFactory.Service(g, ordererSource, g.mcs, g.serviceConfig.OrgLeader || g.serviceConfig.ReplicaMode)
 	}
 
 	// Delivery service might be nil only if it was not able to get connected
@@ -372,10 +395,13 @@ func (g *GossipService) InitializeChannel(channelID string, ordererSource *order
 			logger.Panic("Setting both orgLeader and useLeaderElection to true isn't supported, aborting execution")
 		}
 
-		if leaderElection {
+		if g.serviceConfig.ReplicaMode {
+			logger.Info("This peer is a read-only replica and pulls the blocks from its replica sources, channel", channelID)
+			g.deliveryService[channelID].StartDeliverForChannel(channelID, support.Committer, func() {})
+		} else if leaderElection {
 			logger.Debug("Delivery uses dynamic leader election mechanism, channel", channelID)
 			g.leaderElection[channelID] = g.newLeaderElectionComponent(channelID, g.onStatusChangeFactory(channelID,
-				support.Committer), g.metrics.ElectionMetrics)
+				support.Committer), g.metrics.ElectionMetrics, ordererSource)
 		} else if isStaticOrgLeader {
 			logger.Debug("This peer is configured to connect to ordering service for blocks delivery, channel", channelID)
 			g.deliveryService[channelID].StartDeliverForChannel(channelID, support.Committer, func() {})
@@ -403,6 +429,10 @@ func (g *GossipService) createSelfSignedData() protoutil.SignedData {
 
 // updateAnchors constructs a joinChannelMessage and sends it to the gossipSvc
 func (g *GossipService) updateAnchors(config Config) {
+	if g.serviceConfig.ReplicaMode {
+		logger.Debug("Not joining the gossip of channel", config.ChannelID(), "as this peer is a read-only replica")
+		return
+	}
 	myOrg := string(g.secAdv.OrgByPeerIdentity(api.PeerIdentityType(g.peerIdentity)))
 	if !g.amIinChannel(myOrg, config) {
 		logger.Error("Tried joining channel", config.ChannelID(), "but our org(", myOrg, "), isn't "+
@@ -456,7 +486,7 @@ func (g *GossipService) Stop() {
 }
 
 func (g *GossipService) newLeaderElectionComponent(channelID string, callback func(bool),
-	electionMetrics *gossipmetrics.ElectionMetrics) election.LeaderElectionService {
+	electionMetrics *gossipmetrics.ElectionMetrics, ordererSource *orderers.ConnectionSource) election.LeaderElectionService {
 	PKIid := g.mcs.GetPKIidOfCert(g.peerIdentity)
 	adapter := election.NewAdapter(g, PKIid, gossipcommon.ChannelID(channelID), electionMetrics)
 	config := election.ElectionConfig{
@@ -464,10 +494,50 @@ func (g *GossipService) newLeaderElectionComponent(channelID string, callback fu
 		MembershipSampleInterval: g.serviceConfig.ElectionMembershipSampleInterval,
 		LeaderAliveThreshold:     g.serviceConfig.ElectionLeaderAliveThreshold,
 		LeaderElectionDuration:   g.serviceConfig.ElectionLeaderElectionDuration,
+		Strategy:                 g.newElectionStrategy(channelID, ordererSource),
 	}
 	return election.NewLeaderElectionService(adapter, string(PKIid), callback, config)
 }
 
+// maxOrdererLatencyRank is the rank of peers that are the farthest from, or can't reach, the ordering service
+const maxOrdererLatencyRank = 5
+
+func (g *GossipService) newElectionStrategy(channelID string, ordererSource *orderers.ConnectionSource) election.Strategy {
+	switch g.serviceConfig.ElectionStrategy {
+	case "":
+		return nil
+	case ElectionStrategyPriority:
+		return election.NewPriorityStrategy(g.serviceConfig.ElectionPriority)
+	case ElectionStrategyOrdererLatency:
+		measure := func() (time.Duration, error) {
+			return ordererLatency(ordererSource, g.serviceConfig.ElectionLatencyBucket*maxOrdererLatencyRank)
+		}
+		return election.NewLatencyStrategy(measure, g.serviceConfig.ElectionLatencyBucket, maxOrdererLatencyRank)
+	default:
+		logger.Warningf("Unknown leader election strategy %s for channel %s, electing the peer with the lowest PKI-ID",
+			g.serviceConfig.ElectionStrategy, channelID)
+		return nil
+	}
+}
+
+// ordererLatency measures the time it takes to connect to an orderer of the given source
+func ordererLatency(ordererSource *orderers.ConnectionSource, timeout time.Duration) (time.Duration, error) {
+	if ordererSource == nil {
+		return 0, errors.New("no orderer endpoints")
+	}
+	endpoint, err := ordererSource.RandomEndpoint()
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", endpoint.Address, timeout)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed connecting to orderer %s", endpoint.Address)
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
 func (g *GossipService) amIinChannel(myOrg string, config Config) bool {
 	for _, orgName := range orgListFromConfig(config) {
 		if orgName == myOrg {
@@ -489,7 +489,7 @@ func TestLeaderElectionWithRealGossip(t *testing.T) {
 
 	for i := 0; i < n; i++ {
 		services[i] = &electionService{nil, false, 0}
-		services[i].LeaderElectionService = gossips[i].newLeaderElectionComponent(channelName, services[i].callback, electionMetrics)
+		services[i].LeaderElectionService = gossips[i].newLeaderElectionComponent(channelName, services[i].callback, electionMetrics, nil)
 	}
 
 	logger.Warning("Waiting for leader election")
@@ -522,7 +522,7 @@ func TestLeaderElectionWithRealGossip(t *testing.T) {
 	for idx, i := range secondChannelPeerIndexes {
 		secondChannelServices[idx] = &electionService{nil, false, 0}
 		secondChannelServices[idx].LeaderElectionService =
-			gossips[i].newLeaderElectionComponent(secondChannelName, secondChannelServices[idx].callback, electionMetrics)
+			gossips[i].newLeaderElectionComponent(secondChannelName, secondChannelServices[idx].callback, electionMetrics, nil)
 	}
 
 	assert.True(t, waitForLeaderElection(secondChannelServices, time.Second*30, time.Second*2), "One leader should be selected for chanB")
@@ -908,6 +908,31 @@ func TestInvalidInitialization(t *testing.T) {
 	assert.NotNil(t, dc)
 }
 
+func TestElectionStrategy(t *testing.T) {
+	g := &GossipService{serviceConfig: &ServiceConfig{}}
+	assert.Nil(t, g.newElectionStrategy("A", nil))
+
+	g.serviceConfig.ElectionStrategy = "unknown"
+	assert.Nil(t, g.newElectionStrategy("A", nil))
+
+	g.serviceConfig.ElectionStrategy = ElectionStrategyPriority
+	g.serviceConfig.ElectionPriority = 3
+	assert.Equal(t, 3, g.newElectionStrategy("A", nil).Rank())
+
+	// Peers that can't reach the ordering service are ranked last
+	g.serviceConfig.ElectionStrategy = ElectionStrategyOrdererLatency
+	g.serviceConfig.ElectionLatencyBucket = time.Minute
+	ordererSource := orderers.NewConnectionSource(flogging.MustGetLogger("peer.orderers"), nil)
+	assert.Equal(t, maxOrdererLatencyRank, g.newElectionStrategy("A", nil).Rank())
+	assert.Equal(t, maxOrdererLatencyRank, g.newElectionStrategy("A", ordererSource).Rank())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	ordererSource.Update([]string{listener.Addr().String()}, nil)
+	assert.Equal(t, 0, g.newElectionStrategy("A", ordererSource).Rank())
+}
+
 func TestChannelConfig(t *testing.T) {
 	// Test whenever gossip service is indeed singleton
 	grpcServer := grpc.NewServer()
@@ -20,11 +20,13 @@ import (
 	"github.com/hyperledger/fabric/gossip/discovery"
 	"github.com/hyperledger/fabric/gossip/filter"
 	"github.com/hyperledger/fabric/gossip/gossip"
+	"github.com/hyperledger/fabric/gossip/gossip/pull"
 	"github.com/hyperledger/fabric/gossip/protoext"
 	"github.com/hyperledger/fabric/gossip/util"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 )
 
 type secAdvMock struct {
@@ -110,6 +112,18 @@ func (*gossipMock) IsInMyOrg(member discovery.NetworkMember) bool {
 	panic("implement me")
 }
 
+func (*gossipMock) PullTuning() pull.Tuning {
+	panic("implement me")
+}
+
+func (*gossipMock) TunePull(tuning pull.Tuning) {
+	panic("implement me")
+}
+
+func (*gossipMock) Register(*grpc.Server) {
+	panic("implement me")
+}
+
 func (*gossipMock) Stop() {
 	panic("implement me")
 }
@@ -168,7 +182,7 @@ func TestJoinChannelConfig(t *testing.T) {
 	g1SvcMock.On("JoinChan", mock.Anything, mock.Anything).Run(func(_ mock.Arguments) {
 		failChan <- struct{}{}
 	})
-	g1 := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("OrgMSP0"), gossipSvc: g1SvcMock}
+	g1 := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("OrgMSP0"), gossipSvc: g1SvcMock, serviceConfig: &ServiceConfig{}}
 	g1.updateAnchors(&configMock{
 		orgs2AppOrgs: map[string]channelconfig.ApplicationOrg{
 			"Org0": &appOrgMock{id: "Org0"},
@@ -185,7 +199,7 @@ func TestJoinChannelConfig(t *testing.T) {
 	g2SvcMock.On("JoinChan", mock.Anything, mock.Anything).Run(func(_ mock.Arguments) {
 		succChan <- struct{}{}
 	})
-	g2 := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("Org0"), gossipSvc: g2SvcMock}
+	g2 := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("Org0"), gossipSvc: g2SvcMock, serviceConfig: &ServiceConfig{}}
 	g2.updateAnchors(&configMock{
 		orgs2AppOrgs: map[string]channelconfig.ApplicationOrg{
 			"Org0": &appOrgMock{id: "Org0"},
@@ -199,6 +213,23 @@ func TestJoinChannelConfig(t *testing.T) {
 	}
 }
 
+func TestJoinChannelReplicaMode(t *testing.T) {
+	// Scenario: a read-only replica doesn't join the gossip of the channel
+	// even though its org is among the orgs of the channel
+
+	gMock := &gossipMock{}
+	gMock.On("JoinChan", mock.Anything, mock.Anything).Run(func(_ mock.Arguments) {
+		assert.Fail(t, "A read-only replica joined the gossip of a channel")
+	})
+	g := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("Org0"), gossipSvc: gMock, serviceConfig: &ServiceConfig{ReplicaMode: true}}
+	g.updateAnchors(&configMock{
+		orgs2AppOrgs: map[string]channelconfig.ApplicationOrg{
+			"Org0": &appOrgMock{id: "Org0"},
+		},
+	})
+	gMock.AssertNotCalled(t, "JoinChan", mock.Anything, mock.Anything)
+}
+
 func TestJoinChannelNoAnchorPeers(t *testing.T) {
 	// Scenario: The channel we're joining has 2 orgs but no anchor peers
 	// The test ensures that JoinChan is called with a JoinChannelMessage with Members
@@ -217,7 +248,7 @@ func TestJoinChannelNoAnchorPeers(t *testing.T) {
 		assert.Equal(t, "A", string(channel))
 	})
 
-	g := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("Org0"), gossipSvc: gMock}
+	g := &GossipService{secAdv: &secAdvMock{}, peerIdentity: api.PeerIdentityType("Org0"), gossipSvc: gMock, serviceConfig: &ServiceConfig{}}
 
 	appOrg0 := &appOrgMock{id: "Org0"}
 	appOrg1 := &appOrgMock{id: "Org1"}
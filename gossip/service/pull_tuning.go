@@ -0,0 +1,202 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/gossip/gossip/algo"
+	"github.com/hyperledger/fabric/gossip/gossip/pull"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// PullTuner tunes the pulling of blocks and identities while the peer runs
+type PullTuner interface {
+	PullTuning() pull.Tuning
+	TunePull(tuning pull.Tuning)
+}
+
+// PullTuningSpec is the JSON representation of a pull.Tuning
+type PullTuningSpec struct {
+	PullInterval        string `json:"pullInterval"`
+	DigestWaitTime      string `json:"digestWaitTime"`
+	RequestWaitTime     string `json:"requestWaitTime"`
+	ResponseWaitTime    string `json:"responseWaitTime"`
+	MaxDigestSize       int    `json:"maxDigestSize"`
+	DedupCacheSize      int    `json:"dedupCacheSize"`
+	DigestPeerThreshold int    `json:"digestPeerThreshold"`
+}
+
+type pullTuningErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewPullTuningHandler returns a handler that reports the tuning of the given PullTuner upon GET requests,
+// and changes it upon PUT requests. The fields missing from a PUT request keep their current values.
+func NewPullTuningHandler(tuner PullTuner) *PullTuningHandler {
+	return &PullTuningHandler{
+		Tuner:  tuner,
+		Logger: flogging.MustGetLogger("gossip.service.pulltuning"),
+	}
+}
+
+type PullTuningHandler struct {
+	Tuner  PullTuner
+	Logger *flogging.FabricLogger
+}
+
+func (h *PullTuningHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPut:
+		spec := specFromTuning(h.Tuner.PullTuning())
+		decoder := json.NewDecoder(req.Body)
+		if err := decoder.Decode(spec); err != nil {
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		req.Body.Close()
+
+		tuning, err := spec.tuning()
+		if err != nil {
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		h.Tuner.TunePull(tuning)
+		resp.WriteHeader(http.StatusNoContent)
+
+	case http.MethodGet:
+		h.sendResponse(resp, http.StatusOK, specFromTuning(h.Tuner.PullTuning()))
+
+	default:
+		err := fmt.Errorf("invalid request method: %s", req.Method)
+		h.sendResponse(resp, http.StatusBadRequest, err)
+	}
+}
+
+func (h *PullTuningHandler) sendResponse(resp http.ResponseWriter, code int, payload interface{}) {
+	encoder := json.NewEncoder(resp)
+	if err, ok := payload.(error); ok {
+		payload = &pullTuningErrorResponse{Error: err.Error()}
+	}
+
+	resp.WriteHeader(code)
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err := encoder.Encode(payload); err != nil {
+		h.Logger.Errorw("failed to encode payload", "error", err)
+	}
+}
+
+func specFromTuning(tuning pull.Tuning) *PullTuningSpec {
+	return &PullTuningSpec{
+		PullInterval:        tuning.PullInterval.String(),
+		DigestWaitTime:      tuning.PullEngineConfig.DigestWaitTime.String(),
+		RequestWaitTime:     tuning.PullEngineConfig.RequestWaitTime.String(),
+		ResponseWaitTime:    tuning.PullEngineConfig.ResponseWaitTime.String(),
+		MaxDigestSize:       tuning.PullEngineConfig.MaxDigestSize,
+		DedupCacheSize:      tuning.DedupCacheSize,
+		DigestPeerThreshold: tuning.DigestPeerThreshold,
+	}
+}
+
+func (spec *PullTuningSpec) tuning() (pull.Tuning, error) {
+	var durations [4]time.Duration
+	for i, d := range []struct {
+		name  string
+		value string
+	}{
+		{"pullInterval", spec.PullInterval},
+		{"digestWaitTime", spec.DigestWaitTime},
+		{"requestWaitTime", spec.RequestWaitTime},
+		{"responseWaitTime", spec.ResponseWaitTime},
+	} {
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return pull.Tuning{}, errors.Wrapf(err, "invalid %s", d.name)
+		}
+		if duration <= 0 {
+			return pull.Tuning{}, errors.Errorf("%s must be positive", d.name)
+		}
+		durations[i] = duration
+	}
+	if spec.MaxDigestSize < 0 || spec.DedupCacheSize < 0 || spec.DigestPeerThreshold < 0 {
+		return pull.Tuning{}, errors.New("maxDigestSize, dedupCacheSize and digestPeerThreshold must not be negative")
+	}
+	return pull.Tuning{
+		PullInterval: durations[0],
+		PullEngineConfig: algo.PullEngineConfig{
+			DigestWaitTime:   durations[1],
+			RequestWaitTime:  durations[2],
+			ResponseWaitTime: durations[3],
+			MaxDigestSize:    spec.MaxDigestSize,
+		},
+		DedupCacheSize:      spec.DedupCacheSize,
+		DigestPeerThreshold: spec.DigestPeerThreshold,
+	}, nil
+}
+
+// PullTuningComponent reloads the tuning of the pulling of blocks and identities from the
+// peer.gossip section of the configuration. The parameters that are not set keep their
+// current values.
+type PullTuningComponent struct {
+	Tuner PullTuner
+}
+
+func (c *PullTuningComponent) Name() string {
+	return "gossip.pull"
+}
+
+func (c *PullTuningComponent) Prepare(config *viper.Viper) (*reload.Change, error) {
+	current := c.Tuner.PullTuning()
+	spec := specFromTuning(current)
+	for key, value := range map[string]*string{
+		"peer.gossip.pullInterval":     &spec.PullInterval,
+		"peer.gossip.digestWaitTime":   &spec.DigestWaitTime,
+		"peer.gossip.requestWaitTime":  &spec.RequestWaitTime,
+		"peer.gossip.responseWaitTime": &spec.ResponseWaitTime,
+	} {
+		if config.IsSet(key) {
+			*value = config.GetString(key)
+		}
+	}
+	for key, value := range map[string]*int{
+		"peer.gossip.pullMaxDigestSize":       &spec.MaxDigestSize,
+		"peer.gossip.pullDedupCacheSize":      &spec.DedupCacheSize,
+		"peer.gossip.pullDigestPeerThreshold": &spec.DigestPeerThreshold,
+	} {
+		if config.IsSet(key) {
+			*value = config.GetInt(key)
+		}
+	}
+
+	tuning, err := spec.tuning()
+	if err != nil {
+		return nil, err
+	}
+	if tuning == current {
+		return nil, nil
+	}
+
+	from, err := json.Marshal(specFromTuning(current))
+	if err != nil {
+		return nil, err
+	}
+	to, err := json.Marshal(specFromTuning(tuning))
+	if err != nil {
+		return nil, err
+	}
+	return reload.NewChange(c.Name(), string(from), string(to),
+		func() error { c.Tuner.TunePull(tuning); return nil },
+		func() error { c.Tuner.TunePull(current); return nil },
+	), nil
+}
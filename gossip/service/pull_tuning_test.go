@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/gossip/gossip/algo"
+	"github.com/hyperledger/fabric/gossip/gossip/pull"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type pullTunerMock struct {
+	tuning pull.Tuning
+}
+
+func (pt *pullTunerMock) PullTuning() pull.Tuning {
+	return pt.tuning
+}
+
+func (pt *pullTunerMock) TunePull(tuning pull.Tuning) {
+	pt.tuning = tuning
+}
+
+func TestPullTuningHandler(t *testing.T) {
+	tuner := &pullTunerMock{
+		tuning: pull.Tuning{
+			PullInterval: 4 * time.Second,
+			PullEngineConfig: algo.PullEngineConfig{
+				DigestWaitTime:   time.Second,
+				RequestWaitTime:  1500 * time.Millisecond,
+				ResponseWaitTime: 2 * time.Second,
+			},
+		},
+	}
+	handler := NewPullTuningHandler(tuner)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/gossip/pull", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	spec := &PullTuningSpec{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), spec))
+	assert.Equal(t, &PullTuningSpec{
+		PullInterval:     "4s",
+		DigestWaitTime:   "1s",
+		RequestWaitTime:  "1.5s",
+		ResponseWaitTime: "2s",
+	}, spec)
+
+	resp = httptest.NewRecorder()
+	body := `{"pullInterval": "10s", "maxDigestSize": 100, "dedupCacheSize": 200, "digestPeerThreshold": 50}`
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/gossip/pull", strings.NewReader(body)))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, pull.Tuning{
+		PullInterval: 10 * time.Second,
+		PullEngineConfig: algo.PullEngineConfig{
+			DigestWaitTime:   time.Second,
+			RequestWaitTime:  1500 * time.Millisecond,
+			ResponseWaitTime: 2 * time.Second,
+			MaxDigestSize:    100,
+		},
+		DedupCacheSize:      200,
+		DigestPeerThreshold: 50,
+	}, tuner.tuning)
+
+	for _, tc := range []struct {
+		body        string
+		expectedErr string
+	}{
+		{body: `{"pullInterval": 10}`, expectedErr: "cannot unmarshal number"},
+		{body: `{"digestWaitTime": "soon"}`, expectedErr: "invalid digestWaitTime"},
+		{body: `{"responseWaitTime": "0s"}`, expectedErr: "responseWaitTime must be positive"},
+		{body: `{"dedupCacheSize": -1}`, expectedErr: "must not be negative"},
+	} {
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/gossip/pull", strings.NewReader(tc.body)))
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), tc.expectedErr)
+	}
+	assert.Equal(t, 10*time.Second, tuner.tuning.PullInterval)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/gossip/pull", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid request method: POST")
+}
+
+func TestPullTuningComponent(t *testing.T) {
+	initial := pull.Tuning{
+		PullInterval: 4 * time.Second,
+		PullEngineConfig: algo.PullEngineConfig{
+			DigestWaitTime:   time.Second,
+			RequestWaitTime:  1500 * time.Millisecond,
+			ResponseWaitTime: 2 * time.Second,
+		},
+	}
+	tuner := &pullTunerMock{tuning: initial}
+	component := &PullTuningComponent{Tuner: tuner}
+	assert.Equal(t, "gossip.pull", component.Name())
+
+	config := viper.New()
+	config.Set("peer.gossip.pullInterval", "4s")
+	change, err := component.Prepare(config)
+	assert.NoError(t, err)
+	assert.Nil(t, change)
+
+	config.Set("peer.gossip.pullInterval", "8s")
+	config.Set("peer.gossip.pullDedupCacheSize", 1000)
+	change, err = component.Prepare(config)
+	assert.NoError(t, err)
+	assert.NotNil(t, change)
+	assert.Contains(t, change.From, `"pullInterval":"4s"`)
+	assert.Contains(t, change.To, `"pullInterval":"8s"`)
+	assert.Contains(t, change.To, `"dedupCacheSize":1000`)
+	assert.Equal(t, initial, tuner.tuning, "prepare must not apply the change")
+
+	manager := reload.NewManager(func() (*viper.Viper, error) { return config, nil })
+	manager.Register(component)
+	_, err = manager.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, 8*time.Second, tuner.tuning.PullInterval)
+	assert.Equal(t, 1000, tuner.tuning.DedupCacheSize)
+	assert.Equal(t, time.Second, tuner.tuning.PullEngineConfig.DigestWaitTime)
+
+	config.Set("peer.gossip.responseWaitTime", "0s")
+	_, err = component.Prepare(config)
+	assert.EqualError(t, err, "responseWaitTime must be positive")
+}
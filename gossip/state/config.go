@@ -13,23 +13,25 @@ import (
 )
 
 const (
-	DefStateCheckInterval   = 10 * time.Second
-	DefStateResponseTimeout = 3 * time.Second
-	DefStateBatchSize       = 10
-	DefStateMaxRetries      = 3
-	DefStateBlockBufferSize = 100
-	DefStateChannelSize     = 100
-	DefStateEnabled         = true
+	DefStateCheckInterval      = 10 * time.Second
+	DefStateResponseTimeout    = 3 * time.Second
+	DefStateBatchSize          = 10
+	DefStateMaxRetries         = 3
+	DefStateBlockBufferSize    = 100
+	DefStateChannelSize        = 100
+	DefStateEnabled            = true
+	DefStatePrevalidationDepth = 0
 )
 
 type StateConfig struct {
-	StateCheckInterval   time.Duration
-	StateResponseTimeout time.Duration
-	StateBatchSize       uint64
-	StateMaxRetries      int
-	StateBlockBufferSize int
-	StateChannelSize     int
-	StateEnabled         bool
+	StateCheckInterval      time.Duration
+	StateResponseTimeout    time.Duration
+	StateBatchSize          uint64
+	StateMaxRetries         int
+	StateBlockBufferSize    int
+	StateChannelSize        int
+	StateEnabled            bool
+	StatePrevalidationDepth int
 }
 
 func GlobalConfig() *StateConfig {
@@ -68,4 +70,8 @@ func (c *StateConfig) loadStateConfig() {
 	if viper.IsSet("peer.gossip.state.enabled") {
 		c.StateEnabled = viper.GetBool("peer.gossip.state.enabled")
 	}
+	c.StatePrevalidationDepth = DefStatePrevalidationDepth
+	if viper.IsSet("peer.gossip.state.prevalidationDepth") {
+		c.StatePrevalidationDepth = viper.GetInt("peer.gossip.state.prevalidationDepth")
+	}
 }
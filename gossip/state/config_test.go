@@ -25,17 +25,19 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.gossip.state.blockBufferSize", 5)
 	viper.Set("peer.gossip.state.channelSize", 6)
 	viper.Set("peer.gossip.state.enabled", false)
+	viper.Set("peer.gossip.state.prevalidationDepth", 2)
 
 	coreConfig := state.GlobalConfig()
 
 	expectedConfig := &state.StateConfig{
-		StateCheckInterval:   time.Second,
-		StateResponseTimeout: 2 * time.Second,
-		StateBatchSize:       uint64(3),
-		StateMaxRetries:      4,
-		StateBlockBufferSize: 5,
-		StateChannelSize:     6,
-		StateEnabled:         false,
+		StateCheckInterval:      time.Second,
+		StateResponseTimeout:    2 * time.Second,
+		StateBatchSize:          uint64(3),
+		StateMaxRetries:         4,
+		StateBlockBufferSize:    5,
+		StateChannelSize:        6,
+		StateEnabled:            false,
+		StatePrevalidationDepth: 2,
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
@@ -47,13 +49,14 @@ func TestGlobalConfigDefaults(t *testing.T) {
 	coreConfig := state.GlobalConfig()
 
 	expectedConfig := &state.StateConfig{
-		StateCheckInterval:   10 * time.Second,
-		StateResponseTimeout: 3 * time.Second,
-		StateBatchSize:       uint64(10),
-		StateMaxRetries:      3,
-		StateBlockBufferSize: 100,
-		StateChannelSize:     100,
-		StateEnabled:         true,
+		StateCheckInterval:      10 * time.Second,
+		StateResponseTimeout:    3 * time.Second,
+		StateBatchSize:          uint64(10),
+		StateMaxRetries:         3,
+		StateBlockBufferSize:    100,
+		StateChannelSize:        100,
+		StateEnabled:            true,
+		StatePrevalidationDepth: 0,
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
@@ -29,6 +29,9 @@ type PayloadsBuffer interface {
 	// Remove and return payload with given sequence number
 	Pop() *proto.Payload
 
+	// Return, without removing, the payload with the given sequence number
+	Peek(seqNum uint64) *proto.Payload
+
 	// Get current buffer size
 	Size() int
 
@@ -120,6 +123,14 @@ func (b *PayloadsBufferImpl) Pop() *proto.Payload {
 	return result
 }
 
+// Peek function returns the payload with the given sequence number, if
+// it is present in the buffer, without removing it from the buffer.
+func (b *PayloadsBufferImpl) Peek(seqNum uint64) *proto.Payload {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.buf[seqNum]
+}
+
 // drainReadChannel empties ready channel in case last
 // payload has been poped up and there are still awaiting
 // notifications in the channel
@@ -73,6 +73,20 @@ func TestPayloadsBufferImpl_Push(t *testing.T) {
 	assert.Equal(t, buffer.Size(), 1)
 }
 
+func TestPayloadsBufferImpl_Peek(t *testing.T) {
+	buffer := NewPayloadsBuffer(5)
+
+	payload, err := randomPayloadWithSeqNum(6)
+	assert.NoError(t, err, "generating random payload failed")
+	buffer.Push(payload)
+
+	assert.Nil(t, buffer.Peek(5))
+	assert.Equal(t, payload, buffer.Peek(6))
+	// peeking does not remove the payload from the buffer
+	assert.Equal(t, 1, buffer.Size())
+	assert.Equal(t, uint64(5), buffer.Next())
+}
+
 func TestPayloadsBufferImpl_Ready(t *testing.T) {
 	fin := make(chan struct{})
 	buffer := NewPayloadsBuffer(1)
@@ -125,6 +125,12 @@ type ledgerResources interface {
 	Close()
 }
 
+// prevalidator is implemented by the ledger resources that can start the checks
+// of a block that do not depend on the state ahead of the commit of the block
+type prevalidator interface {
+	Prevalidate(block *common.Block)
+}
+
 // ServicesMediator aggregated adapter to compound all mediator
 // required by state transfer into single struct
 type ServicesMediator struct {
@@ -165,6 +171,9 @@ type GossipStateProviderImpl struct {
 	blockingMode bool
 
 	config *StateConfig
+
+	// prevalidatedTill is the sequence number of the last block handed to the ledger for prevalidation
+	prevalidatedTill uint64
 }
 
 // stateRequestValidator facilitates validation of the state request messages
@@ -573,6 +582,7 @@ func (s *GossipStateProviderImpl) deliverPayloads() {
 						continue
 					}
 				}
+				s.prevalidateSubsequentBlocks(payload.SeqNum)
 				if err := s.commitBlock(rawBlock, p); err != nil {
 					if executionErr, isExecutionErr := err.(*vsccErrors.VSCCExecutionFailureError); isExecutionErr {
 						s.logger.Errorf("Failed executing VSCC due to %v. Aborting chain processing", executionErr)
@@ -588,6 +598,32 @@ func (s *GossipStateProviderImpl) deliverPayloads() {
 	}
 }
 
+// prevalidateSubsequentBlocks hands the blocks that follow the given block in the buffer, up to the configured depth,
+// to the ledger for the checks that do not depend on the state, so that these checks overlap with the validation
+// and commit of the given block
+func (s *GossipStateProviderImpl) prevalidateSubsequentBlocks(seqNum uint64) {
+	p, isPrevalidator := s.ledger.(prevalidator)
+	if !isPrevalidator || s.config.StatePrevalidationDepth <= 0 {
+		return
+	}
+	for next := seqNum + 1; next <= seqNum+uint64(s.config.StatePrevalidationDepth); next++ {
+		if next <= s.prevalidatedTill {
+			continue
+		}
+		payload := s.payloads.Peek(next)
+		if payload == nil {
+			return
+		}
+		block := &common.Block{}
+		if err := pb.Unmarshal(payload.Data, block); err != nil || block.Data == nil || block.Header == nil {
+			return
+		}
+		s.logger.Debugf("[%s] Prevalidating block [%d] while block [%d] is being committed", s.chainID, next, seqNum)
+		p.Prevalidate(block)
+		s.prevalidatedTill = next
+	}
+}
+
 func (s *GossipStateProviderImpl) antiEntropy() {
 	defer s.logger.Debug("State Provider stopped, stopping anti entropy procedure.")
 
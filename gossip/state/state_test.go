@@ -1716,3 +1716,53 @@ func waitUntilTrueOrTimeout(t *testing.T, predicate func() bool, timeout time.Du
 	}
 	t.Log("Stop waiting until timeout or true")
 }
+
+type prevalidatingCoordinatorMock struct {
+	*coordinatorMock
+	prevalidated []uint64
+}
+
+func (mock *prevalidatingCoordinatorMock) Prevalidate(block *pcomm.Block) {
+	mock.prevalidated = append(mock.prevalidated, block.Header.Number)
+}
+
+func TestPrevalidateSubsequentBlocks(t *testing.T) {
+	newPayload := func(seqNum uint64) *proto.Payload {
+		return &proto.Payload{
+			SeqNum: seqNum,
+			Data:   protoutil.MarshalOrPanic(protoutil.NewBlock(seqNum, []byte{})),
+		}
+	}
+	buffer := NewPayloadsBuffer(1)
+	for _, seqNum := range []uint64{1, 2, 3, 5} {
+		buffer.Push(newPayload(seqNum))
+	}
+	ledger := &prevalidatingCoordinatorMock{coordinatorMock: &coordinatorMock{}}
+	s := &GossipStateProviderImpl{
+		logger:   flogging.MustGetLogger(gutil.StateLogger),
+		payloads: buffer,
+		ledger:   ledger,
+		config:   &StateConfig{StatePrevalidationDepth: 3},
+	}
+
+	// the block 4 is yet to arrive
+	s.prevalidateSubsequentBlocks(1)
+	assert.Equal(t, []uint64{2, 3}, ledger.prevalidated)
+
+	// an already prevalidated block is not prevalidated again
+	buffer.Push(newPayload(4))
+	s.prevalidateSubsequentBlocks(2)
+	assert.Equal(t, []uint64{2, 3, 4, 5}, ledger.prevalidated)
+
+	// the prevalidation is disabled
+	s.prevalidatedTill = 0
+	s.config.StatePrevalidationDepth = 0
+	s.prevalidateSubsequentBlocks(1)
+	assert.Equal(t, []uint64{2, 3, 4, 5}, ledger.prevalidated)
+
+	// the ledger does not support the prevalidation
+	s.config.StatePrevalidationDepth = 3
+	s.ledger = ledger.coordinatorMock
+	s.prevalidateSubsequentBlocks(1)
+	assert.Equal(t, []uint64{2, 3, 4, 5}, ledger.prevalidated)
+}
@@ -25,17 +25,19 @@ type fileValidator func(fileName string, fileBytes []byte) error
 // AllowedCharsCollectionName captures the regex pattern for a valid collection name
 const AllowedCharsCollectionName = "[A-Za-z0-9_-]+"
 
-// Currently, the only metadata expected and allowed is for META-INF/statedb/couchdb/indexes.
+// Currently, the only metadata expected and allowed is for META-INF/statedb/couchdb/indexes and META-INF/statedb/leveldb/indexes.
 var fileValidators = map[*regexp.Regexp]fileValidator{
 	regexp.MustCompile("^META-INF/statedb/couchdb/indexes/.*[.]json"):                                                couchdbIndexFileValidator,
 	regexp.MustCompile("^META-INF/statedb/couchdb/collections/" + AllowedCharsCollectionName + "/indexes/.*[.]json"): couchdbIndexFileValidator,
+	regexp.MustCompile("^META-INF/statedb/leveldb/indexes/.*[.]json"):                                                leveldbIndexFileValidator,
+	regexp.MustCompile("^META-INF/statedb/leveldb/collections/" + AllowedCharsCollectionName + "/indexes/.*[.]json"): leveldbIndexFileValidator,
 }
 
 var collectionNameValid = regexp.MustCompile("^" + AllowedCharsCollectionName)
 
 var fileNameValid = regexp.MustCompile("^.*[.]json")
 
-var validDatabases = []string{"couchdb"}
+var validDatabases = []string{"couchdb", "leveldb"}
 
 // UnhandledDirectoryError is returned for metadata files in unhandled directories
 type UnhandledDirectoryError struct {
@@ -156,6 +158,23 @@ func couchdbIndexFileValidator(fileName string, fileBytes []byte) error {
 
 }
 
+// leveldbIndexFileValidator implements fileValidator. The leveldb index definitions follow the layout of the
+// couchdb index definitions but support only a list of field names, for example
+// {"index":{"fields":["owner","size"]},"name":"indexOwnerSize","type":"json"}
+func leveldbIndexFileValidator(fileName string, fileBytes []byte) error {
+	boolIsJSON, indexDefinition := isJSON(fileBytes)
+	if !boolIsJSON {
+		return &InvalidIndexContentError{fmt.Sprintf("Index metadata file [%s] is not a valid JSON", fileName)}
+	}
+
+	err := validateLeveldbIndexJSON(indexDefinition)
+	if err != nil {
+		return &InvalidIndexContentError{fmt.Sprintf("Index metadata file [%s] is not a valid index definition: %s", fileName, err)}
+	}
+
+	return nil
+}
+
 // isJSON tests a string to determine if it can be parsed as valid JSON
 func isJSON(s []byte) (bool, map[string]interface{}) {
 	var js map[string]interface{}
@@ -228,6 +247,54 @@ func validateIndexJSON(indexDefinition map[string]interface{}) error {
 
 }
 
+func validateLeveldbIndexJSON(indexDefinition map[string]interface{}) error {
+	nameIncluded := false
+	fieldsIncluded := false
+	for jsonKey, jsonValue := range indexDefinition {
+		switch jsonKey {
+		case "index":
+			index, ok := jsonValue.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("Invalid entry, \"index\" must be a JSON")
+			}
+			for indexKey, indexValue := range index {
+				if indexKey != "fields" {
+					return fmt.Errorf("Invalid Entry.  Entry %s", indexKey)
+				}
+				fields, ok := indexValue.([]interface{})
+				if !ok || len(fields) == 0 {
+					return fmt.Errorf("Expecting a non-empty JSON array of fields")
+				}
+				for _, field := range fields {
+					if fieldName, ok := field.(string); !ok || fieldName == "" {
+						return fmt.Errorf("Invalid field definition, fields must be non-empty field names")
+					}
+				}
+				fieldsIncluded = true
+			}
+		case "name":
+			if name, ok := jsonValue.(string); !ok || name == "" {
+				return fmt.Errorf("Invalid entry, \"name\" must be a non-empty string")
+			}
+			nameIncluded = true
+		case "type":
+			if jsonValue != "json" {
+				return fmt.Errorf("Index type must be json")
+			}
+		default:
+			return fmt.Errorf("Invalid Entry.  Entry %s", jsonKey)
+		}
+	}
+
+	if !fieldsIncluded {
+		return fmt.Errorf("Index definition must include a \"fields\" definition")
+	}
+	if !nameIncluded {
+		return fmt.Errorf("Index definition must include a \"name\"")
+	}
+	return nil
+}
+
 //processIndexMap processes an interface map and wraps field names or traverses
 //the next level of the json query
 func processIndexMap(jsonFragment map[string]interface{}) error {
@@ -291,6 +291,42 @@ func TestIndexValidationInvalidFields(t *testing.T) {
 
 }
 
+func TestLeveldbIndexValidation(t *testing.T) {
+	// Test a valid leveldb index for the chaincode and for a collection
+	fileBytes := []byte(`{"index":{"fields":["owner","size"]},"name":"indexOwnerSize","type":"json"}`)
+	err := ValidateMetadataFile("META-INF/statedb/leveldb/indexes/indexOwnerSize.json", fileBytes)
+	assert.NoError(t, err, "Error validating a good leveldb index")
+	err = ValidateMetadataFile("META-INF/statedb/leveldb/collections/testcoll/indexes/indexOwnerSize.json", fileBytes)
+	assert.NoError(t, err, "Error validating a good leveldb collection index")
+
+	// Test a couchdb index that uses the features not supported by the leveldb indexes
+	fileBytes = []byte(`{"index":{"fields":[{"size":"desc"}]},"ddoc":"indexSizeSortDoc","name":"indexSizeSortDesc","type":"json"}`)
+	err = ValidateMetadataFile("META-INF/statedb/leveldb/indexes/indexSizeSortDesc.json", fileBytes)
+	_, ok := err.(*InvalidIndexContentError)
+	assert.True(t, ok, "Should have received an InvalidIndexContentError")
+
+	tests := []struct {
+		indexDef    string
+		expectedErr string
+	}{
+		{`{"index":{"fields":["owner"]}}`, `Index definition must include a "name"`},
+		{`{"name":"indexOwner"}`, `Index definition must include a "fields" definition`},
+		{`{"index":{},"name":"indexOwner"}`, `Index definition must include a "fields" definition`},
+		{`{"index":"owner","name":"indexOwner"}`, `Invalid entry, "index" must be a JSON`},
+		{`{"index":{"fields":[]},"name":"indexOwner"}`, "Expecting a non-empty JSON array of fields"},
+		{`{"index":{"fields":["owner",1]},"name":"indexOwner"}`, "Invalid field definition, fields must be non-empty field names"},
+		{`{"index":{"fields":["owner"],"partial_filter_selector":{}},"name":"indexOwner"}`, "Invalid Entry.  Entry partial_filter_selector"},
+		{`{"index":{"fields":["owner"]},"name":""}`, `Invalid entry, "name" must be a non-empty string`},
+		{`{"index":{"fields":["owner"]},"name":"indexOwner","type":"text"}`, "Index type must be json"},
+		{`{"index":{"fields":["owner"]},"name":"indexOwner","ddoc":"indexOwnerDoc"}`, "Invalid Entry.  Entry ddoc"},
+	}
+	for _, test := range tests {
+		_, indexDefinition := isJSON([]byte(test.indexDef))
+		err := validateLeveldbIndexJSON(indexDefinition)
+		assert.EqualError(t, err, test.expectedErr)
+	}
+}
+
 func cleanupDir(dir string) error {
 	// clean up any previous files
 	err := os.RemoveAll(dir)
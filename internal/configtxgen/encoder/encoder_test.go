@@ -489,6 +489,9 @@ var _ = Describe("Encoder", func() {
 				ACLs: map[string]string{
 					"SomeACL": "SomePolicy",
 				},
+				PolicyTemplates: map[string]string{
+					"MAJORITY": "OutOf($# / 2 + 1, $*)",
+				},
 				Policies: CreateStandardPolicies(),
 				Capabilities: map[string]bool{
 					"FakeCapability": true,
@@ -505,8 +508,9 @@ var _ = Describe("Encoder", func() {
 			Expect(cg.Policies["Writers"]).NotTo(BeNil())
 			Expect(len(cg.Groups)).To(Equal(1))
 			Expect(cg.Groups["SampleOrg"]).NotTo(BeNil())
-			Expect(len(cg.Values)).To(Equal(2))
+			Expect(len(cg.Values)).To(Equal(3))
 			Expect(cg.Values["ACLs"]).NotTo(BeNil())
+			Expect(cg.Values["PolicyTemplates"]).NotTo(BeNil())
 			Expect(cg.Values["Capabilities"]).NotTo(BeNil())
 		})
 
@@ -10,10 +10,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/hyperledger/fabric/common/capabilities"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/viperutil"
 	cf "github.com/hyperledger/fabric/core/config"
@@ -104,10 +107,11 @@ type Consortium struct {
 // Application encodes the application-level configuration needed in config
 // transactions.
 type Application struct {
-	Organizations []*Organization    `yaml:"Organizations"`
-	Capabilities  map[string]bool    `yaml:"Capabilities"`
-	Policies      map[string]*Policy `yaml:"Policies"`
-	ACLs          map[string]string  `yaml:"ACLs"`
+	Organizations   []*Organization    `yaml:"Organizations"`
+	Capabilities    map[string]bool    `yaml:"Capabilities"`
+	Policies        map[string]*Policy `yaml:"Policies"`
+	ACLs            map[string]string  `yaml:"ACLs"`
+	PolicyTemplates map[string]string  `yaml:"PolicyTemplates"`
 }
 
 // Organization encodes the organization-level configuration needed in
@@ -273,7 +277,10 @@ func (t *TopLevel) completeInitialization(configDir string) {
 }
 
 func (p *Profile) completeInitialization(configDir string) {
+	validateCapabilities("Channel", capabilities.NewChannelProvider(nil), p.Capabilities)
+
 	if p.Application != nil {
+		validateCapabilities("Application", capabilities.NewApplicationProvider(nil), p.Application.Capabilities)
 		for _, org := range p.Application.Organizations {
 			org.completeInitialization(configDir)
 		}
@@ -288,6 +295,7 @@ func (p *Profile) completeInitialization(configDir string) {
 	}
 
 	if p.Orderer != nil {
+		validateCapabilities("Orderer", capabilities.NewOrdererProvider(nil), p.Orderer.Capabilities)
 		for _, org := range p.Orderer.Organizations {
 			org.completeInitialization(configDir)
 		}
@@ -414,11 +422,38 @@ loop:
 			cf.TranslatePathInPlace(configDir, &serverCertPath)
 			c.ServerTlsCert = []byte(serverCertPath)
 		}
+	case "BFT":
+		logger.Panicf("orderer type BFT is not supported by this version of Fabric, use %s instead", EtcdRaft)
 	default:
 		logger.Panicf("unknown orderer type: %s", ord.OrdererType)
 	}
 }
 
+// validateCapabilities panics if one of the required capabilities is not
+// supported, as the channels created with it would not be usable.
+func validateCapabilities(kind string, provider interface{ HasCapability(string) bool }, required map[string]bool) {
+	var names []string
+	for name, enabled := range required {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if provider.HasCapability(name) {
+			continue
+		}
+		if strings.HasPrefix(name, "SignatureAlgorithm_") && kind != "Channel" {
+			logger.Panicf("%s capability %s is not supported: signature algorithms are Channel capabilities", kind, name)
+		}
+		if strings.HasPrefix(name, "LowS_") && kind != "Channel" {
+			logger.Panicf("%s capability %s is not supported: low-S policies are Channel capabilities", kind, name)
+		}
+		logger.Panicf("%s capability %s is not supported by this version of Fabric", kind, name)
+	}
+}
+
 func translatePaths(configDir string, org *Organization) {
 	cf.TranslatePathInPlace(configDir, &org.MSPDir)
 }
@@ -105,6 +105,18 @@ func TestConsensusSpecificInit(t *testing.T) {
 		})
 	})
 
+	t.Run("BFT orderer type", func(t *testing.T) {
+		profile := &Profile{
+			Orderer: &Orderer{
+				OrdererType: "BFT",
+			},
+		}
+
+		assert.PanicsWithValue(t, "orderer type BFT is not supported by this version of Fabric, use etcdraft instead", func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+
 	t.Run("solo", func(t *testing.T) {
 		profile := &Profile{
 			Orderer: &Orderer{
@@ -270,6 +282,51 @@ func TestConsensusSpecificInit(t *testing.T) {
 	})
 }
 
+func TestCapabilitiesValidation(t *testing.T) {
+	cleanup := configtest.SetDevFabricConfigPath(t)
+	defer cleanup()
+
+	devConfigDir := configtest.GetDevConfigDir()
+
+	t.Run("supported", func(t *testing.T) {
+		profile := &Profile{
+			Capabilities: map[string]bool{"V2_0": true, "SignatureAlgorithm_Ed25519": true},
+			Application:  &Application{Capabilities: map[string]bool{"V2_0": true}},
+		}
+		assert.NotPanics(t, func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+
+	t.Run("disabled unsupported", func(t *testing.T) {
+		profile := &Profile{Capabilities: map[string]bool{"V9_9": false}}
+		assert.NotPanics(t, func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		profile := &Profile{Capabilities: map[string]bool{"V9_9": true}}
+		assert.PanicsWithValue(t, "Channel capability V9_9 is not supported by this version of Fabric", func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+
+	t.Run("signature algorithm outside of channel", func(t *testing.T) {
+		profile := &Profile{Application: &Application{Capabilities: map[string]bool{"SignatureAlgorithm_ECDSA": true}}}
+		assert.PanicsWithValue(t, "Application capability SignatureAlgorithm_ECDSA is not supported: signature algorithms are Channel capabilities", func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+
+	t.Run("low-S policy outside of channel", func(t *testing.T) {
+		profile := &Profile{Orderer: &Orderer{OrdererType: "solo", Capabilities: map[string]bool{"LowS_Normalize": true}}}
+		assert.PanicsWithValue(t, "Orderer capability LowS_Normalize is not supported: low-S policies are Channel capabilities", func() {
+			profile.completeInitialization(devConfigDir)
+		})
+	})
+}
+
 func TestLoadConfigCache(t *testing.T) {
 	cleanup := configtest.SetDevFabricConfigPath(t)
 	defer cleanup()
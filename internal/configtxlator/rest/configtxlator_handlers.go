@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package rest
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -41,6 +42,26 @@ func fieldConfigProto(fieldName string, r *http.Request) (*cb.Config, error) {
 	return config, nil
 }
 
+func fieldConfigFromBlock(fieldName string, r *http.Request) (*cb.Config, error) {
+	fieldBytes, err := fieldBytes(fieldName, r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading field bytes: %s", err)
+	}
+
+	block := &cb.Block{}
+	err = proto.Unmarshal(fieldBytes, block)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling field bytes: %s", err)
+	}
+
+	config, err := update.ConfigFromBlock(block)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting config: %s", err)
+	}
+
+	return config, nil
+}
+
 func ComputeUpdateFromConfigs(w http.ResponseWriter, r *http.Request) {
 	originalConfig, err := fieldConfigProto("original", r)
 	if err != nil {
@@ -76,3 +97,37 @@ func ComputeUpdateFromConfigs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Write(encoded)
 }
+
+func DescribeUpdateFromBlocks(w http.ResponseWriter, r *http.Request) {
+	originalConfig, err := fieldConfigFromBlock("original", r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Error with field 'original': %s\n", err)
+		return
+	}
+
+	updatedConfig, err := fieldConfigFromBlock("updated", r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Error with field 'updated': %s\n", err)
+		return
+	}
+
+	description, err := update.Describe(originalConfig, updatedConfig)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Error describing update: %s\n", err)
+		return
+	}
+
+	encoded, err := json.MarshalIndent(description, "", "\t")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error marshaling description: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
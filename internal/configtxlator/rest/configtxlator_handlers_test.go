@@ -8,12 +8,14 @@ package rest
 
 import (
 	"bytes"
+	"encoding/json"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/internal/configtxlator/update"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -146,3 +148,75 @@ func TestProtolatorCorruptProtos(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func configBlock(t *testing.T, config *cb.Config) []byte {
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG, "mychannel", nil, &cb.ConfigEnvelope{Config: config}, 0, 0)
+	assert.NoError(t, err)
+	block := protoutil.NewBlock(0, nil)
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+	return protoutil.MarshalOrPanic(block)
+}
+
+func describeUpdateRequest(t *testing.T, original, updated []byte) *httptest.ResponseRecorder {
+	buffer := &bytes.Buffer{}
+	mpw := multipart.NewWriter(buffer)
+
+	ffw, err := mpw.CreateFormFile("original", "foo")
+	assert.NoError(t, err)
+	_, err = bytes.NewReader(original).WriteTo(ffw)
+	assert.NoError(t, err)
+
+	ffw, err = mpw.CreateFormFile("updated", "bar")
+	assert.NoError(t, err)
+	_, err = bytes.NewReader(updated).WriteTo(ffw)
+	assert.NoError(t, err)
+
+	err = mpw.Close()
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest("POST", "/configtxlator/describe/update-from-blocks", buffer)
+	assert.NoError(t, err)
+
+	req.Header.Set("Content-Type", mpw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r := NewRouter()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDescribeUpdateFromBlocks(t *testing.T) {
+	original := configBlock(t, &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			ModPolicy: "foo",
+		},
+	})
+	updated := configBlock(t, &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			ModPolicy: "bar",
+		},
+	})
+
+	rec := describeUpdateRequest(t, original, updated)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	description := &update.Description{}
+	err := json.Unmarshal(rec.Body.Bytes(), description)
+	assert.NoError(t, err)
+	assert.Equal(t, []*update.Change{
+		{
+			Path:        "/Channel",
+			Element:     update.ElementGroup,
+			Action:      update.ActionModified,
+			Description: "mod_policy changed from 'foo' to 'bar'",
+		},
+	}, description.Changes)
+
+	rec = describeUpdateRequest(t, original, original)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "Error describing update: no differences detected between original and updated config\n", rec.Body.String())
+
+	rec = describeUpdateRequest(t, []byte("Garbage"), updated)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Error with field 'original'")
+}
@@ -22,6 +22,9 @@ func NewRouter() *mux.Router {
 	router.
 		HandleFunc("/configtxlator/compute/update-from-configs", ComputeUpdateFromConfigs).
 		Methods("POST")
+	router.
+		HandleFunc("/configtxlator/describe/update-from-blocks", DescribeUpdateFromBlocks).
+		Methods("POST")
 
 	return router
 }
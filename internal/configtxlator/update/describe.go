@@ -0,0 +1,661 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// The elements of a config
+const (
+	ElementGroup  = "group"
+	ElementValue  = "value"
+	ElementPolicy = "policy"
+)
+
+// The actions of a change
+const (
+	ActionAdded    = "added"
+	ActionRemoved  = "removed"
+	ActionModified = "modified"
+)
+
+// Description describes the differences between two configs, along with the
+// policies that the signatures of the config update between them must satisfy.
+type Description struct {
+	// Changes are the changes of the config, in the order of their paths.
+	Changes []*Change
+	// RequiredPolicies are the policies that the config update must satisfy, in the order of their paths.
+	RequiredPolicies []*RequiredPolicy
+}
+
+// Change is a change of a group, value, or policy of the config.
+type Change struct {
+	// Path is the path of the element, such as /Channel/Application/Org1MSP/MSP.
+	Path string
+	// Element is the type of the element: group, value, or policy.
+	Element string
+	// Action is added, removed, or modified.
+	Action string
+	// Description describes the change.
+	Description string
+}
+
+// RequiredPolicy is a policy of the original config that the signatures of the
+// config update must satisfy.
+type RequiredPolicy struct {
+	// Path is the path of the policy, such as /Channel/Application/Admins.
+	Path string
+	// Signatures describes the signatures that satisfy the policy.
+	Signatures string
+	// Changes are the paths of the changes that require the policy.
+	Changes []string
+}
+
+// Describe describes the differences between the original and the updated
+// config, and the policies that the config update between them must satisfy,
+// which are the mod policies of the modified elements and of the groups whose
+// elements are added or removed.
+func Describe(original, updated *cb.Config) (*Description, error) {
+	if original.ChannelGroup == nil {
+		return nil, fmt.Errorf("no channel group included for original config")
+	}
+
+	if updated.ChannelGroup == nil {
+		return nil, fmt.Errorf("no channel group included for updated config")
+	}
+
+	d := &describer{
+		root:     original.ChannelGroup,
+		required: map[string]*RequiredPolicy{},
+	}
+	d.describeGroup("/"+channelconfig.ChannelGroupKey, original.ChannelGroup, updated.ChannelGroup)
+	if len(d.changes) == 0 {
+		return nil, fmt.Errorf("no differences detected between original and updated config")
+	}
+
+	description := &Description{Changes: d.changes}
+	for _, rp := range d.required {
+		description.RequiredPolicies = append(description.RequiredPolicies, rp)
+	}
+	sort.Slice(description.RequiredPolicies, func(i, j int) bool {
+		return description.RequiredPolicies[i].Path < description.RequiredPolicies[j].Path
+	})
+	return description, nil
+}
+
+// ConfigFromBlock returns the config carried by a config block.
+func ConfigFromBlock(block *cb.Block) (*cb.Config, error) {
+	if block.Data == nil || len(block.Data.Data) != 1 {
+		return nil, errors.New("a config block must contain exactly one transaction")
+	}
+	env, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := protoutil.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, errors.New("missing header in the transaction of the block")
+	}
+	chdr, err := protoutil.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+	if chdr.Type != int32(cb.HeaderType_CONFIG) {
+		return nil, errors.Errorf("block %d is not a config block, its transaction is of type %s", block.GetHeader().GetNumber(), cb.HeaderType(chdr.Type))
+	}
+	configEnv := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling config envelope")
+	}
+	if configEnv.Config == nil {
+		return nil, errors.New("missing config in the config envelope")
+	}
+	return configEnv.Config, nil
+}
+
+type describer struct {
+	root     *cb.ConfigGroup
+	changes  []*Change
+	required map[string]*RequiredPolicy
+}
+
+func (d *describer) addChange(path, element, action, description, modPolicy, modPolicyPath string) {
+	d.changes = append(d.changes, &Change{
+		Path:        path,
+		Element:     element,
+		Action:      action,
+		Description: description,
+	})
+
+	policyPath := modPolicy
+	if modPolicy != "" && !strings.HasPrefix(modPolicy, "/") {
+		policyPath = modPolicyPath + "/" + modPolicy
+	}
+	rp, ok := d.required[policyPath]
+	if !ok {
+		rp = &RequiredPolicy{
+			Path:       policyPath,
+			Signatures: d.describePolicyPath(policyPath),
+		}
+		d.required[policyPath] = rp
+	}
+	rp.Changes = append(rp.Changes, path)
+}
+
+// describeGroup describes the changes of a group present in both configs. The
+// mod policy of a group is relative to the group itself, while the mod policies
+// of its values and policies are relative to the group they belong to.
+func (d *describer) describeGroup(path string, original, updated *cb.ConfigGroup) {
+	if original.ModPolicy != updated.ModPolicy {
+		d.addChange(path, ElementGroup, ActionModified,
+			fmt.Sprintf("mod_policy changed from '%s' to '%s'", original.ModPolicy, updated.ModPolicy),
+			original.ModPolicy, path)
+	}
+
+	for _, key := range unionKeys(original.Values, updated.Values) {
+		valuePath := path + "/" + key
+		o, u := original.Values[key], updated.Values[key]
+		switch {
+		case u == nil:
+			d.addChange(valuePath, ElementValue, ActionRemoved, fmt.Sprintf("value %s removed", key), original.ModPolicy, path)
+		case o == nil:
+			d.addChange(valuePath, ElementValue, ActionAdded, fmt.Sprintf("value %s added", key), original.ModPolicy, path)
+		case o.ModPolicy != u.ModPolicy || !bytes.Equal(o.Value, u.Value):
+			var descriptions []string
+			if !bytes.Equal(o.Value, u.Value) {
+				descriptions = append(descriptions, describeValue(key, o.Value, u.Value))
+			}
+			if o.ModPolicy != u.ModPolicy {
+				descriptions = append(descriptions, fmt.Sprintf("mod_policy changed from '%s' to '%s'", o.ModPolicy, u.ModPolicy))
+			}
+			d.addChange(valuePath, ElementValue, ActionModified, strings.Join(descriptions, "; "), o.ModPolicy, path)
+		}
+	}
+
+	for _, key := range unionKeys(original.Policies, updated.Policies) {
+		policyPath := path + "/" + key
+		o, u := original.Policies[key], updated.Policies[key]
+		switch {
+		case u == nil:
+			d.addChange(policyPath, ElementPolicy, ActionRemoved, fmt.Sprintf("policy %s removed", key), original.ModPolicy, path)
+		case o == nil:
+			d.addChange(policyPath, ElementPolicy, ActionAdded,
+				fmt.Sprintf("policy %s added: %s", key, describePolicy(u.Policy)), original.ModPolicy, path)
+		case o.ModPolicy != u.ModPolicy || !proto.Equal(o.Policy, u.Policy):
+			var descriptions []string
+			if !proto.Equal(o.Policy, u.Policy) {
+				descriptions = append(descriptions, fmt.Sprintf("policy %s changed from %s to %s", key, describePolicy(o.Policy), describePolicy(u.Policy)))
+			}
+			if o.ModPolicy != u.ModPolicy {
+				descriptions = append(descriptions, fmt.Sprintf("mod_policy changed from '%s' to '%s'", o.ModPolicy, u.ModPolicy))
+			}
+			d.addChange(policyPath, ElementPolicy, ActionModified, strings.Join(descriptions, "; "), o.ModPolicy, path)
+		}
+	}
+
+	for _, key := range unionKeys(original.Groups, updated.Groups) {
+		groupPath := path + "/" + key
+		o, u := original.Groups[key], updated.Groups[key]
+		switch {
+		case u == nil:
+			d.addChange(groupPath, ElementGroup, ActionRemoved, describeGroupMembership(key, o, ActionRemoved), original.ModPolicy, path)
+		case o == nil:
+			d.addChange(groupPath, ElementGroup, ActionAdded, describeGroupMembership(key, u, ActionAdded), original.ModPolicy, path)
+		default:
+			d.describeGroup(groupPath, o, u)
+		}
+	}
+}
+
+// unionKeys returns the sorted union of the keys of two maps of the same type.
+func unionKeys(original, updated interface{}) []string {
+	keys := map[string]struct{}{}
+	for _, m := range []reflect.Value{reflect.ValueOf(original), reflect.ValueOf(updated)} {
+		for _, k := range m.MapKeys() {
+			keys[k.String()] = struct{}{}
+		}
+	}
+	var res []string
+	for k := range keys {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// describeGroupMembership describes a group that is added or removed, which
+// is an organization when it carries an MSP.
+func describeGroupMembership(key string, group *cb.ConfigGroup, action string) string {
+	if v, ok := group.Values[channelconfig.MSPKey]; ok {
+		if name, err := mspName(v.Value); err == nil {
+			return fmt.Sprintf("organization %s %s", name, action)
+		}
+	}
+	return fmt.Sprintf("group %s %s", key, action)
+}
+
+// describePolicyPath describes the signatures that satisfy the policy of the
+// original config at the given absolute path.
+func (d *describer) describePolicyPath(policyPath string) string {
+	if policyPath == "" {
+		return "no mod_policy is set, so the update cannot be authorized"
+	}
+	elements := strings.Split(strings.TrimPrefix(policyPath, "/"), "/")
+	if len(elements) < 2 || elements[0] != channelconfig.ChannelGroupKey {
+		return fmt.Sprintf("policy %s not found in the original config", policyPath)
+	}
+	group := d.root
+	for _, key := range elements[1 : len(elements)-1] {
+		if group = group.Groups[key]; group == nil {
+			return fmt.Sprintf("policy %s not found in the original config", policyPath)
+		}
+	}
+	policy, ok := group.Policies[elements[len(elements)-1]]
+	if !ok {
+		return fmt.Sprintf("policy %s not found in the original config", policyPath)
+	}
+	return describeSignatures(group, policy.Policy)
+}
+
+// describeSignatures describes the signatures that satisfy a policy of the
+// given group, expanding implicit meta policies into the policies of the
+// sub-groups they refer to.
+func describeSignatures(group *cb.ConfigGroup, policy *cb.Policy) string {
+	if policy == nil || policy.Type != int32(cb.Policy_IMPLICIT_META) {
+		return describePolicy(policy)
+	}
+	imp := &cb.ImplicitMetaPolicy{}
+	if err := proto.Unmarshal(policy.Value, imp); err != nil {
+		return describePolicy(policy)
+	}
+
+	var subPolicies []string
+	for _, key := range unionKeys(group.Groups, group.Groups) {
+		subGroup := group.Groups[key]
+		if p, ok := subGroup.Policies[imp.SubPolicy]; ok {
+			subPolicies = append(subPolicies, fmt.Sprintf("%s: %s", key, describeSignatures(subGroup, p.Policy)))
+		}
+	}
+	required := 1
+	switch imp.Rule {
+	case cb.ImplicitMetaPolicy_ALL:
+		required = len(subPolicies)
+	case cb.ImplicitMetaPolicy_MAJORITY:
+		required = len(subPolicies)/2 + 1
+	}
+	return fmt.Sprintf("%s %s: %d of %d of [%s]", imp.Rule, imp.SubPolicy, required, len(subPolicies), strings.Join(subPolicies, ", "))
+}
+
+// describePolicy describes a policy in the syntax of the policies of configtx.yaml.
+func describePolicy(policy *cb.Policy) string {
+	if policy == nil {
+		return "an empty policy"
+	}
+	switch policy.Type {
+	case int32(cb.Policy_SIGNATURE):
+		env := &cb.SignaturePolicyEnvelope{}
+		if err := proto.Unmarshal(policy.Value, env); err != nil || env.Rule == nil {
+			return "a malformed signature policy"
+		}
+		return describeSignatureRule(env.Rule, env.Identities)
+	case int32(cb.Policy_IMPLICIT_META):
+		imp := &cb.ImplicitMetaPolicy{}
+		if err := proto.Unmarshal(policy.Value, imp); err != nil {
+			return "a malformed implicit meta policy"
+		}
+		return fmt.Sprintf("'%s %s'", imp.Rule, imp.SubPolicy)
+	default:
+		return fmt.Sprintf("a policy of type %s", cb.Policy_PolicyType(policy.Type))
+	}
+}
+
+func describeSignatureRule(rule *cb.SignaturePolicy, identities []*mspprotos.MSPPrincipal) string {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		if t.SignedBy < 0 || int(t.SignedBy) >= len(identities) {
+			return "'unknown identity'"
+		}
+		return fmt.Sprintf("'%s'", describePrincipal(identities[t.SignedBy]))
+	case *cb.SignaturePolicy_NOutOf_:
+		var rules []string
+		for _, r := range t.NOutOf.Rules {
+			rules = append(rules, describeSignatureRule(r, identities))
+		}
+		switch {
+		case t.NOutOf.N == 1:
+			return fmt.Sprintf("OR(%s)", strings.Join(rules, ", "))
+		case int(t.NOutOf.N) == len(rules):
+			return fmt.Sprintf("AND(%s)", strings.Join(rules, ", "))
+		default:
+			return fmt.Sprintf("OutOf(%d, %s)", t.NOutOf.N, strings.Join(rules, ", "))
+		}
+	default:
+		return "'unknown rule'"
+	}
+}
+
+func describePrincipal(principal *mspprotos.MSPPrincipal) string {
+	switch principal.PrincipalClassification {
+	case mspprotos.MSPPrincipal_ROLE:
+		role := &mspprotos.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err == nil {
+			return fmt.Sprintf("%s.%s", role.MspIdentifier, strings.ToLower(role.Role.String()))
+		}
+	case mspprotos.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &mspprotos.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err == nil {
+			return fmt.Sprintf("%s OU %s", ou.MspIdentifier, ou.OrganizationalUnitIdentifier)
+		}
+	}
+	return fmt.Sprintf("%s principal", strings.ToLower(principal.PrincipalClassification.String()))
+}
+
+// describeValue describes the change of a value, decoding the well known values.
+func describeValue(key string, original, updated []byte) string {
+	var description string
+	var err error
+	switch key {
+	case channelconfig.MSPKey:
+		description, err = describeMSP(original, updated)
+	case channelconfig.ConsensusTypeKey:
+		description, err = describeConsensusType(original, updated)
+	case channelconfig.CapabilitiesKey:
+		description, err = describeCapabilities(original, updated)
+	case channelconfig.AnchorPeersKey:
+		description, err = describeAnchorPeers(original, updated)
+	case channelconfig.OrdererAddressesKey, channelconfig.EndpointsKey:
+		description, err = describeAddresses(key, original, updated)
+	case channelconfig.KafkaBrokersKey:
+		description, err = describeKafkaBrokers(original, updated)
+	case channelconfig.ACLsKey:
+		description, err = describeACLs(original, updated)
+	case channelconfig.BatchSizeKey:
+		description, err = describeMessage(key, &ab.BatchSize{}, &ab.BatchSize{}, original, updated)
+	case channelconfig.BatchTimeoutKey:
+		description, err = describeMessage(key, &ab.BatchTimeout{}, &ab.BatchTimeout{}, original, updated)
+	case channelconfig.ChannelRestrictionsKey:
+		description, err = describeMessage(key, &ab.ChannelRestrictions{}, &ab.ChannelRestrictions{}, original, updated)
+	case channelconfig.HashingAlgorithmKey:
+		description, err = describeMessage(key, &cb.HashingAlgorithm{}, &cb.HashingAlgorithm{}, original, updated)
+	case channelconfig.BlockDataHashingStructureKey:
+		description, err = describeMessage(key, &cb.BlockDataHashingStructure{}, &cb.BlockDataHashingStructure{}, original, updated)
+	case channelconfig.ConsortiumKey:
+		description, err = describeMessage(key, &cb.Consortium{}, &cb.Consortium{}, original, updated)
+	default:
+		return fmt.Sprintf("value %s changed", key)
+	}
+	if err != nil {
+		return fmt.Sprintf("value %s changed, but cannot be decoded: %s", key, err)
+	}
+	return description
+}
+
+func unmarshalBoth(original, updated []byte, o, u proto.Message) error {
+	if err := proto.Unmarshal(original, o); err != nil {
+		return errors.Wrap(err, "original value")
+	}
+	if err := proto.Unmarshal(updated, u); err != nil {
+		return errors.Wrap(err, "updated value")
+	}
+	return nil
+}
+
+func describeMessage(key string, o, u proto.Message, original, updated []byte) (string, error) {
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s changed from {%s} to {%s}", key, compactText(o), compactText(u)), nil
+}
+
+func compactText(m proto.Message) string {
+	return strings.TrimSpace(proto.CompactTextString(m))
+}
+
+// describeSet describes the elements added to and removed from a set.
+func describeSet(noun string, original, updated []string) string {
+	var added, removed []string
+	for _, e := range updated {
+		if !contains(original, e) {
+			added = append(added, e)
+		}
+	}
+	for _, e := range original {
+		if !contains(updated, e) {
+			removed = append(removed, e)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return fmt.Sprintf("%s reordered", noun)
+	}
+	var descriptions []string
+	if len(added) != 0 {
+		descriptions = append(descriptions, fmt.Sprintf("%s added: %s", noun, strings.Join(added, ", ")))
+	}
+	if len(removed) != 0 {
+		descriptions = append(descriptions, fmt.Sprintf("%s removed: %s", noun, strings.Join(removed, ", ")))
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func contains(s []string, e string) bool {
+	for _, x := range s {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+func mspName(value []byte) (string, error) {
+	mspConfig := &mspprotos.MSPConfig{}
+	if err := proto.Unmarshal(value, mspConfig); err != nil {
+		return "", err
+	}
+	fabricConfig := &mspprotos.FabricMSPConfig{}
+	if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+		return "", err
+	}
+	return fabricConfig.Name, nil
+}
+
+func describeMSP(original, updated []byte) (string, error) {
+	o, u := &mspprotos.MSPConfig{}, &mspprotos.MSPConfig{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	if o.Type != u.Type {
+		return fmt.Sprintf("MSP type changed from %d to %d", o.Type, u.Type), nil
+	}
+	of, uf := &mspprotos.FabricMSPConfig{}, &mspprotos.FabricMSPConfig{}
+	if err := unmarshalBoth(o.Config, u.Config, of, uf); err != nil {
+		return "", err
+	}
+
+	var changes []string
+	if of.Name != uf.Name {
+		changes = append(changes, fmt.Sprintf("name changed from %s to %s", of.Name, uf.Name))
+	}
+	for _, certs := range []struct {
+		name             string
+		original, update [][]byte
+	}{
+		{"root certificates", of.RootCerts, uf.RootCerts},
+		{"intermediate certificates", of.IntermediateCerts, uf.IntermediateCerts},
+		{"admins", of.Admins, uf.Admins},
+		{"revocation list", of.RevocationList, uf.RevocationList},
+		{"TLS root certificates", of.TlsRootCerts, uf.TlsRootCerts},
+		{"TLS intermediate certificates", of.TlsIntermediateCerts, uf.TlsIntermediateCerts},
+	} {
+		if !reflect.DeepEqual(certs.original, certs.update) {
+			changes = append(changes, fmt.Sprintf("%s changed (%d -> %d)", certs.name, len(certs.original), len(certs.update)))
+		}
+	}
+	if !proto.Equal(of.SigningIdentity, uf.SigningIdentity) {
+		changes = append(changes, "signing identity changed")
+	}
+	if !reflect.DeepEqual(of.OrganizationalUnitIdentifiers, uf.OrganizationalUnitIdentifiers) {
+		changes = append(changes, "organizational unit identifiers changed")
+	}
+	if !proto.Equal(of.CryptoConfig, uf.CryptoConfig) {
+		changes = append(changes, "crypto config changed")
+	}
+	if !proto.Equal(of.FabricNodeOus, uf.FabricNodeOus) {
+		changes = append(changes, "node OUs changed")
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "encoding changed")
+	}
+	return fmt.Sprintf("MSP %s: %s", uf.Name, strings.Join(changes, ", ")), nil
+}
+
+func describeConsensusType(original, updated []byte) (string, error) {
+	o, u := &ab.ConsensusType{}, &ab.ConsensusType{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+
+	var changes []string
+	if o.Type != u.Type {
+		changes = append(changes, fmt.Sprintf("consensus type changed from %s to %s", o.Type, u.Type))
+	}
+	if o.State != u.State {
+		changes = append(changes, fmt.Sprintf("consensus state changed from %s to %s", o.State, u.State))
+	}
+	if !bytes.Equal(o.Metadata, u.Metadata) {
+		if o.Type == "etcdraft" && u.Type == "etcdraft" {
+			raftChanges, err := describeRaftMetadata(o.Metadata, u.Metadata)
+			if err != nil {
+				return "", err
+			}
+			changes = append(changes, raftChanges...)
+		} else {
+			changes = append(changes, "consensus metadata changed")
+		}
+	}
+	return strings.Join(changes, "; "), nil
+}
+
+func describeRaftMetadata(original, updated []byte) ([]string, error) {
+	o, u := &etcdraft.ConfigMetadata{}, &etcdraft.ConfigMetadata{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return nil, err
+	}
+
+	endpoint := func(c *etcdraft.Consenter) string {
+		return fmt.Sprintf("%s:%d", c.Host, c.Port)
+	}
+	consenters := func(md *etcdraft.ConfigMetadata) ([]string, map[string]*etcdraft.Consenter) {
+		var endpoints []string
+		byEndpoint := map[string]*etcdraft.Consenter{}
+		for _, c := range md.Consenters {
+			endpoints = append(endpoints, endpoint(c))
+			byEndpoint[endpoint(c)] = c
+		}
+		return endpoints, byEndpoint
+	}
+	oEndpoints, oConsenters := consenters(o)
+	uEndpoints, uConsenters := consenters(u)
+
+	var changes []string
+	if !reflect.DeepEqual(oEndpoints, uEndpoints) {
+		changes = append(changes, describeSet("consenters", oEndpoints, uEndpoints))
+	}
+	for _, e := range uEndpoints {
+		if oc, ok := oConsenters[e]; ok && !proto.Equal(oc, uConsenters[e]) {
+			changes = append(changes, fmt.Sprintf("TLS certificates of consenter %s changed", e))
+		}
+	}
+	if !proto.Equal(o.Options, u.Options) {
+		changes = append(changes, fmt.Sprintf("raft options changed from {%s} to {%s}",
+			compactText(o.GetOptions()), compactText(u.GetOptions())))
+	}
+	return changes, nil
+}
+
+func describeCapabilities(original, updated []byte) (string, error) {
+	o, u := &cb.Capabilities{}, &cb.Capabilities{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	names := func(c *cb.Capabilities) []string {
+		return unionKeys(c.Capabilities, c.Capabilities)
+	}
+	return describeSet("capabilities", names(o), names(u)), nil
+}
+
+func describeAnchorPeers(original, updated []byte) (string, error) {
+	o, u := &pb.AnchorPeers{}, &pb.AnchorPeers{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	endpoints := func(ap *pb.AnchorPeers) []string {
+		var res []string
+		for _, p := range ap.AnchorPeers {
+			res = append(res, fmt.Sprintf("%s:%d", p.Host, p.Port))
+		}
+		return res
+	}
+	return describeSet("anchor peers", endpoints(o), endpoints(u)), nil
+}
+
+func describeAddresses(key string, original, updated []byte) (string, error) {
+	o, u := &cb.OrdererAddresses{}, &cb.OrdererAddresses{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	noun := "orderer addresses"
+	if key == channelconfig.EndpointsKey {
+		noun = "orderer endpoints"
+	}
+	return describeSet(noun, o.Addresses, u.Addresses), nil
+}
+
+func describeKafkaBrokers(original, updated []byte) (string, error) {
+	o, u := &ab.KafkaBrokers{}, &ab.KafkaBrokers{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	return describeSet("kafka brokers", o.Brokers, u.Brokers), nil
+}
+
+func describeACLs(original, updated []byte) (string, error) {
+	o, u := &pb.ACLs{}, &pb.ACLs{}
+	if err := unmarshalBoth(original, updated, o, u); err != nil {
+		return "", err
+	}
+	var changes []string
+	for _, resource := range unionKeys(o.Acls, u.Acls) {
+		oa, ua := o.Acls[resource], u.Acls[resource]
+		switch {
+		case ua == nil:
+			changes = append(changes, fmt.Sprintf("ACL %s removed", resource))
+		case oa == nil:
+			changes = append(changes, fmt.Sprintf("ACL %s added with policy %s", resource, ua.PolicyRef))
+		case oa.PolicyRef != ua.PolicyRef:
+			changes = append(changes, fmt.Sprintf("ACL %s changed from policy %s to %s", resource, oa.PolicyRef, ua.PolicyRef))
+		}
+	}
+	return strings.Join(changes, "; "), nil
+}
@@ -0,0 +1,319 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package update
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mspprotos "github.com/hyperledger/fabric-protos-go/msp"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func signaturePolicy(env *cb.SignaturePolicyEnvelope) *cb.ConfigPolicy {
+	return &cb.ConfigPolicy{
+		ModPolicy: "Admins",
+		Policy: &cb.Policy{
+			Type:  int32(cb.Policy_SIGNATURE),
+			Value: protoutil.MarshalOrPanic(env),
+		},
+	}
+}
+
+func implicitMetaPolicy(rule cb.ImplicitMetaPolicy_Rule, subPolicy string) *cb.ConfigPolicy {
+	return &cb.ConfigPolicy{
+		ModPolicy: "Admins",
+		Policy: &cb.Policy{
+			Type:  int32(cb.Policy_IMPLICIT_META),
+			Value: protoutil.MarshalOrPanic(&cb.ImplicitMetaPolicy{Rule: rule, SubPolicy: subPolicy}),
+		},
+	}
+}
+
+func orgGroup(mspID string, rootCerts ...[]byte) *cb.ConfigGroup {
+	return &cb.ConfigGroup{
+		ModPolicy: "Admins",
+		Values: map[string]*cb.ConfigValue{
+			"MSP": {
+				ModPolicy: "Admins",
+				Value: protoutil.MarshalOrPanic(&mspprotos.MSPConfig{
+					Config: protoutil.MarshalOrPanic(&mspprotos.FabricMSPConfig{
+						Name:      mspID,
+						RootCerts: rootCerts,
+					}),
+				}),
+			},
+		},
+		Policies: map[string]*cb.ConfigPolicy{
+			"Admins": signaturePolicy(policydsl.SignedByMspAdmin(mspID)),
+		},
+	}
+}
+
+func raftValue(consenters ...*etcdraft.Consenter) *cb.ConfigValue {
+	return &cb.ConfigValue{
+		ModPolicy: "Admins",
+		Value: protoutil.MarshalOrPanic(&ab.ConsensusType{
+			Type:     "etcdraft",
+			Metadata: protoutil.MarshalOrPanic(&etcdraft.ConfigMetadata{Consenters: consenters}),
+		}),
+	}
+}
+
+func sampleConfig() *cb.Config {
+	return &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			ModPolicy: "Admins",
+			Groups: map[string]*cb.ConfigGroup{
+				"Application": {
+					ModPolicy: "Admins",
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": orgGroup("Org1MSP", []byte("root1")),
+						"Org2MSP": orgGroup("Org2MSP", []byte("root2")),
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins": implicitMetaPolicy(cb.ImplicitMetaPolicy_MAJORITY, "Admins"),
+					},
+				},
+				"Orderer": {
+					ModPolicy: "Admins",
+					Groups: map[string]*cb.ConfigGroup{
+						"OrdererOrg": orgGroup("OrdererMSP", []byte("root3")),
+					},
+					Values: map[string]*cb.ConfigValue{
+						"ConsensusType": raftValue(
+							&etcdraft.Consenter{Host: "orderer1", Port: 7050, ClientTlsCert: []byte("cert1")},
+							&etcdraft.Consenter{Host: "orderer2", Port: 7050, ClientTlsCert: []byte("cert2")},
+						),
+					},
+					Policies: map[string]*cb.ConfigPolicy{
+						"Admins": implicitMetaPolicy(cb.ImplicitMetaPolicy_ANY, "Admins"),
+					},
+				},
+			},
+			Policies: map[string]*cb.ConfigPolicy{
+				"Admins": implicitMetaPolicy(cb.ImplicitMetaPolicy_MAJORITY, "Admins"),
+			},
+		},
+	}
+}
+
+func TestDescribeMissingGroup(t *testing.T) {
+	_, err := Describe(&cb.Config{}, sampleConfig())
+	assert.EqualError(t, err, "no channel group included for original config")
+
+	_, err = Describe(sampleConfig(), &cb.Config{})
+	assert.EqualError(t, err, "no channel group included for updated config")
+}
+
+func TestDescribeNoUpdate(t *testing.T) {
+	_, err := Describe(sampleConfig(), sampleConfig())
+	assert.EqualError(t, err, "no differences detected between original and updated config")
+}
+
+func TestDescribeOrganizations(t *testing.T) {
+	original := sampleConfig()
+	updated := sampleConfig()
+	application := updated.ChannelGroup.Groups["Application"]
+	application.Groups["Org3MSP"] = orgGroup("Org3MSP")
+	application.Groups["Org1MSP"] = orgGroup("Org1MSP", []byte("root1"), []byte("root1bis"))
+	delete(application.Groups, "Org2MSP")
+
+	description, err := Describe(original, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{
+			Path:        "/Channel/Application/Org1MSP/MSP",
+			Element:     ElementValue,
+			Action:      ActionModified,
+			Description: "MSP Org1MSP: root certificates changed (1 -> 2)",
+		},
+		{
+			Path:        "/Channel/Application/Org2MSP",
+			Element:     ElementGroup,
+			Action:      ActionRemoved,
+			Description: "organization Org2MSP removed",
+		},
+		{
+			Path:        "/Channel/Application/Org3MSP",
+			Element:     ElementGroup,
+			Action:      ActionAdded,
+			Description: "organization Org3MSP added",
+		},
+	}, description.Changes)
+	assert.Equal(t, []*RequiredPolicy{
+		{
+			Path:       "/Channel/Application/Admins",
+			Signatures: "MAJORITY Admins: 2 of 2 of [Org1MSP: OR('Org1MSP.admin'), Org2MSP: OR('Org2MSP.admin')]",
+			Changes:    []string{"/Channel/Application/Org2MSP", "/Channel/Application/Org3MSP"},
+		},
+		{
+			Path:       "/Channel/Application/Org1MSP/Admins",
+			Signatures: "OR('Org1MSP.admin')",
+			Changes:    []string{"/Channel/Application/Org1MSP/MSP"},
+		},
+	}, description.RequiredPolicies)
+}
+
+func TestDescribeConsenters(t *testing.T) {
+	original := sampleConfig()
+	updated := sampleConfig()
+	updated.ChannelGroup.Groups["Orderer"].Values["ConsensusType"] = raftValue(
+		&etcdraft.Consenter{Host: "orderer1", Port: 7050, ClientTlsCert: []byte("renewed")},
+		&etcdraft.Consenter{Host: "orderer3", Port: 7050, ClientTlsCert: []byte("cert3")},
+	)
+
+	description, err := Describe(original, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{
+			Path:    "/Channel/Orderer/ConsensusType",
+			Element: ElementValue,
+			Action:  ActionModified,
+			Description: "consenters added: orderer3:7050; consenters removed: orderer2:7050; " +
+				"TLS certificates of consenter orderer1:7050 changed",
+		},
+	}, description.Changes)
+	assert.Equal(t, []*RequiredPolicy{
+		{
+			Path:       "/Channel/Orderer/Admins",
+			Signatures: "ANY Admins: 1 of 1 of [OrdererOrg: OR('OrdererMSP.admin')]",
+			Changes:    []string{"/Channel/Orderer/ConsensusType"},
+		},
+	}, description.RequiredPolicies)
+}
+
+func TestDescribePolicies(t *testing.T) {
+	original := sampleConfig()
+	updated := sampleConfig()
+	updated.ChannelGroup.Policies["Writers"] = implicitMetaPolicy(cb.ImplicitMetaPolicy_ANY, "Writers")
+	updated.ChannelGroup.Groups["Application"].Policies["Admins"] = implicitMetaPolicy(cb.ImplicitMetaPolicy_ANY, "Admins")
+	updated.ChannelGroup.Groups["Application"].Groups["Org1MSP"].Policies["Admins"] = signaturePolicy(
+		policydsl.SignedByNOutOfGivenRole(2, mspprotos.MSPRole_ADMIN, []string{"Org1MSP", "Org2MSP", "Org3MSP"}),
+	)
+	updated.ChannelGroup.Groups["Orderer"].Policies["Admins"].ModPolicy = "/Channel/Admins"
+
+	description, err := Describe(original, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{
+			Path:        "/Channel/Writers",
+			Element:     ElementPolicy,
+			Action:      ActionAdded,
+			Description: "policy Writers added: 'ANY Writers'",
+		},
+		{
+			Path:        "/Channel/Application/Admins",
+			Element:     ElementPolicy,
+			Action:      ActionModified,
+			Description: "policy Admins changed from 'MAJORITY Admins' to 'ANY Admins'",
+		},
+		{
+			Path:        "/Channel/Application/Org1MSP/Admins",
+			Element:     ElementPolicy,
+			Action:      ActionModified,
+			Description: "policy Admins changed from OR('Org1MSP.admin') to OutOf(2, 'Org1MSP.admin', 'Org2MSP.admin', 'Org3MSP.admin')",
+		},
+		{
+			Path:        "/Channel/Orderer/Admins",
+			Element:     ElementPolicy,
+			Action:      ActionModified,
+			Description: "mod_policy changed from 'Admins' to '/Channel/Admins'",
+		},
+	}, description.Changes)
+
+	var paths []string
+	for _, rp := range description.RequiredPolicies {
+		paths = append(paths, rp.Path)
+	}
+	assert.Equal(t, []string{
+		"/Channel/Admins",
+		"/Channel/Application/Admins",
+		"/Channel/Application/Org1MSP/Admins",
+		"/Channel/Orderer/Admins",
+	}, paths)
+	assert.Equal(t, "MAJORITY Admins: 2 of 2 of [Application: MAJORITY Admins: 2 of 2 of [Org1MSP: OR('Org1MSP.admin'), Org2MSP: OR('Org2MSP.admin')], "+
+		"Orderer: ANY Admins: 1 of 1 of [OrdererOrg: OR('OrdererMSP.admin')]]", description.RequiredPolicies[0].Signatures)
+}
+
+func TestDescribeValues(t *testing.T) {
+	original := sampleConfig()
+	original.ChannelGroup.Values = map[string]*cb.ConfigValue{
+		"Capabilities": {
+			ModPolicy: "Admins",
+			Value:     protoutil.MarshalOrPanic(&cb.Capabilities{Capabilities: map[string]*cb.Capability{"V1_4_3": {}}}),
+		},
+		"Custom": {ModPolicy: "Admins", Value: []byte("foo")},
+	}
+	original.ChannelGroup.Groups["Orderer"].Values["BatchSize"] = &cb.ConfigValue{
+		ModPolicy: "Admins",
+		Value:     protoutil.MarshalOrPanic(&ab.BatchSize{MaxMessageCount: 10}),
+	}
+
+	updated := proto.Clone(original).(*cb.Config)
+	updated.ChannelGroup.Values["Capabilities"].Value = protoutil.MarshalOrPanic(&cb.Capabilities{Capabilities: map[string]*cb.Capability{"V2_0": {}}})
+	updated.ChannelGroup.Values["Custom"].Value = []byte("bar")
+	updated.ChannelGroup.Values["Custom"].ModPolicy = "Writers"
+	updated.ChannelGroup.Groups["Orderer"].Values["BatchSize"].Value = protoutil.MarshalOrPanic(&ab.BatchSize{MaxMessageCount: 20})
+
+	description, err := Describe(original, updated)
+	assert.NoError(t, err)
+	var descriptions []string
+	for _, c := range description.Changes {
+		descriptions = append(descriptions, c.Description)
+	}
+	assert.Equal(t, []string{
+		"capabilities added: V2_0; capabilities removed: V1_4_3",
+		"value Custom changed; mod_policy changed from 'Admins' to 'Writers'",
+		"BatchSize changed from {max_message_count:10} to {max_message_count:20}",
+	}, descriptions)
+}
+
+func TestDescribeMissingModPolicy(t *testing.T) {
+	original := sampleConfig()
+	original.ChannelGroup.Groups["Application"].ModPolicy = "Missing"
+	updated := sampleConfig()
+	updated.ChannelGroup.Groups["Application"].ModPolicy = "Missing"
+	updated.ChannelGroup.Groups["Application"].Groups["Org3MSP"] = orgGroup("Org3MSP")
+
+	description, err := Describe(original, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []*RequiredPolicy{
+		{
+			Path:       "/Channel/Application/Missing",
+			Signatures: "policy /Channel/Application/Missing not found in the original config",
+			Changes:    []string{"/Channel/Application/Org3MSP"},
+		},
+	}, description.RequiredPolicies)
+}
+
+func TestConfigFromBlock(t *testing.T) {
+	configEnv := &cb.ConfigEnvelope{Config: sampleConfig()}
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG, "mychannel", nil, configEnv, 0, 0)
+	assert.NoError(t, err)
+	block := protoutil.NewBlock(3, nil)
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+
+	config, err := ConfigFromBlock(block)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(sampleConfig(), config))
+
+	env, err = protoutil.CreateSignedEnvelope(cb.HeaderType_ENDORSER_TRANSACTION, "mychannel", nil, configEnv, 0, 0)
+	assert.NoError(t, err)
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+	_, err = ConfigFromBlock(block)
+	assert.EqualError(t, err, "block 3 is not a config block, its transaction is of type ENDORSER_TRANSACTION")
+
+	block.Data.Data = nil
+	_, err = ConfigFromBlock(block)
+	assert.EqualError(t, err, "a config block must contain exactly one transaction")
+}
@@ -8,6 +8,7 @@ package ca
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
@@ -38,8 +39,8 @@ type CA struct {
 	SignCert           *x509.Certificate
 }
 
-// NewCA creates an instance of CA and saves the signing key pair in
-// baseDir/name
+// NewCA creates an instance of CA and saves the signing key pair, generated
+// as defined by keyOpts, in baseDir/name
 func NewCA(
 	baseDir,
 	org,
@@ -50,6 +51,7 @@ func NewCA(
 	orgUnit,
 	streetAddress,
 	postalCode string,
+	keyOpts csp.KeyOptions,
 ) (*CA, error) {
 
 	var ca *CA
@@ -59,7 +61,7 @@ func NewCA(
 		return nil, err
 	}
 
-	priv, err := csp.GeneratePrivateKey(baseDir)
+	signer, err := csp.GenerateSigner(baseDir, keyOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -81,24 +83,22 @@ func NewCA(
 	subject.CommonName = name
 
 	template.Subject = subject
-	template.SubjectKeyId = computeSKI(priv)
+	template.SubjectKeyId = computeSKI(signer.Public())
 
 	x509Cert, err := genCertificateECDSA(
 		baseDir,
 		name,
 		&template,
 		&template,
-		&priv.PublicKey,
-		priv,
+		signer.Public(),
+		signer,
 	)
 	if err != nil {
 		return nil, err
 	}
 	ca = &CA{
-		Name: name,
-		Signer: &csp.ECDSASigner{
-			PrivateKey: priv,
-		},
+		Name:               name,
+		Signer:             signer,
 		SignCert:           x509Cert,
 		Country:            country,
 		Province:           province,
@@ -118,7 +118,7 @@ func (ca *CA) SignCertificate(
 	name string,
 	orgUnits,
 	alternateNames []string,
-	pub *ecdsa.PublicKey,
+	pub crypto.PublicKey,
 	ku x509.KeyUsage,
 	eku []x509.ExtKeyUsage,
 ) (*x509.Certificate, error) {
@@ -126,6 +126,7 @@ func (ca *CA) SignCertificate(
 	template := x509Template()
 	template.KeyUsage = ku
 	template.ExtKeyUsage = eku
+	template.SubjectKeyId = computeSKI(pub)
 
 	//set the organization for the subject
 	subject := subjectTemplateAdditional(
@@ -168,9 +169,20 @@ func (ca *CA) SignCertificate(
 }
 
 // compute Subject Key Identifier
-func computeSKI(privKey *ecdsa.PrivateKey) []byte {
+func computeSKI(pub crypto.PublicKey) []byte {
 	// Marshall the public key
-	raw := elliptic.Marshal(privKey.Curve, privKey.PublicKey.X, privKey.PublicKey.Y)
+	var raw []byte
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve == nil {
+			return nil
+		}
+		raw = elliptic.Marshal(k.Curve, k.X, k.Y)
+	case ed25519.PublicKey:
+		raw = k
+	default:
+		return nil
+	}
 
 	// Hash it
 	hash := sha256.Sum256(raw)
@@ -241,13 +253,13 @@ func x509Template() x509.Certificate {
 
 }
 
-// generate a signed X509 certificate using ECDSA
+// generate a signed X509 certificate using ECDSA or Ed25519
 func genCertificateECDSA(
 	baseDir,
 	name string,
 	template,
 	parent *x509.Certificate,
-	pub *ecdsa.PublicKey,
+	pub crypto.PublicKey,
 	priv interface{},
 ) (*x509.Certificate, error) {
 
@@ -63,6 +63,7 @@ func TestLoadCertificateECDSA(t *testing.T) {
 		testOrganizationalUnit,
 		testStreetAddress,
 		testPostalCode,
+		csp.KeyOptions{},
 	)
 	assert.NoError(t, err, "Error generating CA")
 
@@ -136,6 +137,7 @@ func TestNewCA(t *testing.T) {
 		testOrganizationalUnit,
 		testStreetAddress,
 		testPostalCode,
+		csp.KeyOptions{},
 	)
 	assert.NoError(t, err, "Error generating CA")
 	assert.NotNil(t, rootCA, "Failed to return CA")
@@ -163,6 +165,40 @@ func TestNewCA(t *testing.T) {
 	assert.Equal(t, testPostalCode, rootCA.SignCert.Subject.PostalCode[0], "Failed to match postalCode")
 }
 
+func TestNewCAEd25519(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "ca-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	rootCA, err := ca.NewCA(
+		filepath.Join(testDir, "ca"),
+		testCAName,
+		testCAName,
+		testCountry,
+		testProvince,
+		testLocality,
+		testOrganizationalUnit,
+		testStreetAddress,
+		testPostalCode,
+		csp.KeyOptions{Algorithm: csp.Ed25519},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, x509.Ed25519, rootCA.SignCert.PublicKeyAlgorithm)
+	assert.Equal(t, x509.PureEd25519, rootCA.SignCert.SignatureAlgorithm)
+	assert.NotEmpty(t, rootCA.SignCert.SubjectKeyId)
+
+	// an Ed25519 CA signs certificates of any key algorithm
+	certDir := filepath.Join(testDir, "certs")
+	require.NoError(t, os.Mkdir(certDir, 0755))
+	priv, err := csp.GenerateSigner(certDir, csp.KeyOptions{Curve: csp.P384})
+	require.NoError(t, err)
+	cert, err := rootCA.SignCertificate(certDir, testName, nil, nil, priv.Public(),
+		x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{})
+	require.NoError(t, err)
+	assert.Equal(t, x509.ECDSA, cert.PublicKeyAlgorithm)
+	assert.NoError(t, cert.CheckSignatureFrom(rootCA.SignCert))
+}
+
 func TestGenerateSignCertificate(t *testing.T) {
 	testDir, err := ioutil.TempDir("", "ca-test")
 	if err != nil {
@@ -190,6 +226,7 @@ func TestGenerateSignCertificate(t *testing.T) {
 		testOrganizationalUnit,
 		testStreetAddress,
 		testPostalCode,
+		csp.KeyOptions{},
 	)
 	assert.NoError(t, err, "Error generating CA")
 
@@ -8,6 +8,7 @@ package csp
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
@@ -20,14 +21,140 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/signer"
 	"github.com/pkg/errors"
 )
 
+// The algorithms of the generated keys.
+const (
+	ECDSA   = "ECDSA"
+	Ed25519 = "Ed25519"
+)
+
+// The curves of the generated ECDSA keys.
+const (
+	P256 = "P-256"
+	P384 = "P-384"
+)
+
+// The encodings of the stored private keys.
+const (
+	PKCS8 = "PKCS8"
+	SEC1  = "SEC1"
+)
+
+// KeyOptions define the algorithm of the generated keys and how their private
+// part is stored. The zero value generates ECDSA P-256 keys stored
+// unencrypted in PKCS#8.
+type KeyOptions struct {
+	// Algorithm is ECDSA or Ed25519.
+	Algorithm string
+	// Curve is the curve of ECDSA keys, P-256 or P-384.
+	Curve string
+	// Encoding is PKCS8, or SEC1 for ECDSA keys.
+	Encoding string
+	// Password, when set, encrypts the stored private keys.
+	Password []byte
+	// CSP, when set, generates ECDSA keys with this provider, such as a
+	// PKCS#11 token, which keeps their private part instead of the keystore.
+	CSP bccsp.BCCSP
+}
+
+// Validate checks that the options name supported algorithms, curves and
+// encodings.
+func (o KeyOptions) Validate() error {
+	switch o.Algorithm {
+	case "", ECDSA:
+		switch o.Curve {
+		case "", P256, P384:
+		default:
+			return errors.Errorf("unsupported curve %s: must be %s or %s", o.Curve, P256, P384)
+		}
+		switch o.Encoding {
+		case "", PKCS8, SEC1:
+		default:
+			return errors.Errorf("unsupported encoding %s: must be %s or %s", o.Encoding, PKCS8, SEC1)
+		}
+	case Ed25519:
+		if o.Curve != "" {
+			return errors.Errorf("%s keys do not take a curve", Ed25519)
+		}
+		switch o.Encoding {
+		case "", PKCS8:
+		default:
+			return errors.Errorf("unsupported encoding %s: %s keys must be encoded in %s", o.Encoding, Ed25519, PKCS8)
+		}
+	default:
+		return errors.Errorf("unsupported key algorithm %s: must be %s or %s", o.Algorithm, ECDSA, Ed25519)
+	}
+	if o.CSP != nil {
+		if o.Algorithm == Ed25519 {
+			return errors.Errorf("%s keys cannot be generated by the crypto service provider", Ed25519)
+		}
+		if o.Encoding != "" || len(o.Password) != 0 {
+			return errors.New("keys generated by the crypto service provider take no encoding or password")
+		}
+	}
+	return nil
+}
+
 // LoadPrivateKey loads a private key from a file in keystorePath.  It looks
 // for a file ending in "_sk" and expects a PEM-encoded PKCS8 EC private key.
 func LoadPrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
 	var priv *ecdsa.PrivateKey
 
+	err := walkKeystore(keystorePath, func(rawKey []byte) error {
+		key, err := parsePrivateKeyPEM(rawKey, nil)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		priv, ok = key.(*ecdsa.PrivateKey)
+		if !ok {
+			return errors.New("pem bytes do not contain an EC private key")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return priv, err
+}
+
+// LoadSigner loads a private key from a file in keystorePath, as
+// LoadPrivateKey does, and returns a signer for it. The key is either an
+// ECDSA or an Ed25519 key, PKCS8 or SEC1 encoded, and is decrypted with
+// password if it is encrypted.
+func LoadSigner(keystorePath string, password []byte) (crypto.Signer, error) {
+	var signer crypto.Signer
+
+	err := walkKeystore(keystorePath, func(rawKey []byte) error {
+		key, err := parsePrivateKeyPEM(rawKey, password)
+		if err != nil {
+			return err
+		}
+
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			signer = &ECDSASigner{PrivateKey: k}
+		case ed25519.PrivateKey:
+			signer = k
+		default:
+			return errors.New("pem bytes do not contain an EC or Ed25519 private key")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+func walkKeystore(keystorePath string, load func(rawKey []byte) error) error {
 	walkFunc := func(path string, info os.FileInfo, pathErr error) error {
 
 		if !strings.HasSuffix(path, "_sk") {
@@ -39,7 +166,7 @@ func LoadPrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
 			return err
 		}
 
-		priv, err = parsePrivateKeyPEM(rawKey)
+		err = load(rawKey)
 		if err != nil {
 			return errors.WithMessage(err, path)
 		}
@@ -47,47 +174,89 @@ func LoadPrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
 		return nil
 	}
 
-	err := filepath.Walk(keystorePath, walkFunc)
-	if err != nil {
-		return nil, err
-	}
-
-	return priv, err
+	return filepath.Walk(keystorePath, walkFunc)
 }
 
-func parsePrivateKeyPEM(rawKey []byte) (*ecdsa.PrivateKey, error) {
+func parsePrivateKeyPEM(rawKey, password []byte) (crypto.PrivateKey, error) {
 	block, _ := pem.Decode(rawKey)
 	if block == nil {
 		return nil, errors.New("bytes are not PEM encoded")
 	}
 
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, errors.WithMessage(err, "pem bytes are not PKCS8 encoded ")
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(password) == 0 {
+			return nil, errors.New("pem bytes are encrypted and no password was provided")
+		}
+		var err error
+		der, err = x509.DecryptPEMBlock(block, password)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to decrypt pem bytes")
+		}
 	}
 
-	priv, ok := key.(*ecdsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("pem bytes do not contain an EC private key")
+	if block.Type == "EC PRIVATE KEY" {
+		key, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, errors.WithMessage(err, "pem bytes are not SEC1 encoded")
+		}
+		return key, nil
 	}
-	return priv, nil
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.WithMessage(err, "pem bytes are not PKCS8 encoded ")
+	}
+	return key, nil
 }
 
 // GeneratePrivateKey creates an EC private key using a P-256 curve and stores
 // it in keystorePath.
 func GeneratePrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
-
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer, err := GenerateSigner(keystorePath, KeyOptions{})
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed to generate private key")
+		return nil, err
 	}
 
-	pkcs8Encoded, err := x509.MarshalPKCS8PrivateKey(priv)
-	if err != nil {
-		return nil, errors.WithMessage(err, "failed to marshal private key")
+	return signer.(*ECDSASigner).PrivateKey, nil
+}
+
+// GenerateSigner creates a private key as defined by opts, stores it in
+// keystorePath and returns a signer for it. Keys generated by opts.CSP are
+// kept by the provider and nothing is stored in keystorePath.
+func GenerateSigner(keystorePath string, opts KeyOptions) (crypto.Signer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.CSP != nil {
+		return generateCSPSigner(opts)
 	}
 
-	pemEncoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Encoded})
+	var signer crypto.Signer
+	var priv crypto.PrivateKey
+	switch opts.Algorithm {
+	case Ed25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to generate private key")
+		}
+		signer, priv = key, key
+	default:
+		curve := elliptic.P256()
+		if opts.Curve == P384 {
+			curve = elliptic.P384()
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to generate private key")
+		}
+		signer, priv = &ECDSASigner{PrivateKey: key}, key
+	}
+
+	pemEncoded, err := privateKeyToPEM(priv, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	keyFile := filepath.Join(keystorePath, "priv_sk")
 	err = ioutil.WriteFile(keyFile, pemEncoded, 0600)
@@ -95,7 +264,67 @@ func GeneratePrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
 		return nil, errors.WithMessagef(err, "failed to save private key to file %s", keyFile)
 	}
 
-	return priv, err
+	return signer, err
+}
+
+func generateCSPSigner(opts KeyOptions) (crypto.Signer, error) {
+	var keyGenOpts bccsp.KeyGenOpts = &bccsp.ECDSAP256KeyGenOpts{}
+	if opts.Curve == P384 {
+		keyGenOpts = &bccsp.ECDSAP384KeyGenOpts{}
+	}
+
+	key, err := opts.CSP.KeyGen(keyGenOpts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate private key")
+	}
+
+	return signer.New(opts.CSP, key)
+}
+
+// LoadCSPSigner returns a signer for the private key of cert kept by the
+// provider, which finds it by the subject key identifier of the public key.
+func LoadCSPSigner(provider bccsp.BCCSP, cert *x509.Certificate) (crypto.Signer, error) {
+	pub, err := provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import the public key of the certificate")
+	}
+
+	priv, err := provider.GetKey(pub.SKI())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get the private key with SKI %x", pub.SKI())
+	}
+	if !priv.Private() {
+		return nil, errors.Errorf("no private key with SKI %x", pub.SKI())
+	}
+
+	return signer.New(provider, priv)
+}
+
+func privateKeyToPEM(priv crypto.PrivateKey, opts KeyOptions) ([]byte, error) {
+	var block *pem.Block
+	if ecKey, ok := priv.(*ecdsa.PrivateKey); ok && opts.Encoding == SEC1 {
+		sec1Encoded, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to marshal private key")
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1Encoded}
+	} else {
+		pkcs8Encoded, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to marshal private key")
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Encoded}
+	}
+
+	if len(opts.Password) != 0 {
+		encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, opts.Password, x509.PEMCipherAES256)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to encrypt private key")
+		}
+		block = encrypted
+	}
+
+	return pem.EncodeToMemory(block), nil
 }
 
 /**
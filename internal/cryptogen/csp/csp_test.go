@@ -6,7 +6,9 @@ SPDX-License-Identifier: Apache-2.0
 package csp_test
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -20,8 +22,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hyperledger/fabric/bccsp/sw"
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadPrivateKey(t *testing.T) {
@@ -124,6 +128,140 @@ func TestGeneratePrivateKey(t *testing.T) {
 	assert.Contains(t, err.Error(), "no such file or directory")
 }
 
+func TestGenerateSigner(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		opts      csp.KeyOptions
+		pemType   string
+		encrypted bool
+		check     func(t *testing.T, pub crypto.PublicKey)
+	}{
+		{
+			name:    "default",
+			pemType: "PRIVATE KEY",
+			check: func(t *testing.T, pub crypto.PublicKey) {
+				assert.Equal(t, elliptic.P256(), pub.(*ecdsa.PublicKey).Curve)
+			},
+		},
+		{
+			name:    "P-384 SEC1",
+			opts:    csp.KeyOptions{Algorithm: csp.ECDSA, Curve: csp.P384, Encoding: csp.SEC1},
+			pemType: "EC PRIVATE KEY",
+			check: func(t *testing.T, pub crypto.PublicKey) {
+				assert.Equal(t, elliptic.P384(), pub.(*ecdsa.PublicKey).Curve)
+			},
+		},
+		{
+			name:    "Ed25519",
+			opts:    csp.KeyOptions{Algorithm: csp.Ed25519},
+			pemType: "PRIVATE KEY",
+			check: func(t *testing.T, pub crypto.PublicKey) {
+				assert.IsType(t, ed25519.PublicKey{}, pub)
+			},
+		},
+		{
+			name:      "encrypted Ed25519",
+			opts:      csp.KeyOptions{Algorithm: csp.Ed25519, Password: []byte("secret")},
+			pemType:   "PRIVATE KEY",
+			encrypted: true,
+			check: func(t *testing.T, pub crypto.PublicKey) {
+				assert.IsType(t, ed25519.PublicKey{}, pub)
+			},
+		},
+		{
+			name:      "encrypted SEC1",
+			opts:      csp.KeyOptions{Encoding: csp.SEC1, Password: []byte("secret")},
+			pemType:   "EC PRIVATE KEY",
+			encrypted: true,
+			check: func(t *testing.T, pub crypto.PublicKey) {
+				assert.Equal(t, elliptic.P256(), pub.(*ecdsa.PublicKey).Curve)
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testDir, err := ioutil.TempDir("", "csp-test")
+			require.NoError(t, err)
+			defer os.RemoveAll(testDir)
+
+			signer, err := csp.GenerateSigner(testDir, test.opts)
+			require.NoError(t, err)
+			test.check(t, signer.Public())
+
+			rawKey, err := ioutil.ReadFile(filepath.Join(testDir, "priv_sk"))
+			require.NoError(t, err)
+			block, _ := pem.Decode(rawKey)
+			require.NotNil(t, block)
+			assert.Equal(t, test.pemType, block.Type)
+			assert.Equal(t, test.encrypted, x509.IsEncryptedPEMBlock(block))
+
+			loaded, err := csp.LoadSigner(testDir, test.opts.Password)
+			require.NoError(t, err)
+			assert.Equal(t, signer.Public(), loaded.Public())
+
+			if test.encrypted {
+				_, err = csp.LoadSigner(testDir, nil)
+				assert.EqualError(t, err, filepath.Join(testDir, "priv_sk")+": pem bytes are encrypted and no password was provided")
+				_, err = csp.LoadSigner(testDir, []byte("wrong"))
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateSignerInvalidOptions(t *testing.T) {
+	for _, test := range []struct {
+		opts   csp.KeyOptions
+		errMsg string
+	}{
+		{opts: csp.KeyOptions{Algorithm: "RSA"}, errMsg: "unsupported key algorithm RSA: must be ECDSA or Ed25519"},
+		{opts: csp.KeyOptions{Curve: "P-521"}, errMsg: "unsupported curve P-521: must be P-256 or P-384"},
+		{opts: csp.KeyOptions{Encoding: "PKCS1"}, errMsg: "unsupported encoding PKCS1: must be PKCS8 or SEC1"},
+		{opts: csp.KeyOptions{Algorithm: csp.Ed25519, Curve: csp.P256}, errMsg: "Ed25519 keys do not take a curve"},
+		{opts: csp.KeyOptions{Algorithm: csp.Ed25519, Encoding: csp.SEC1}, errMsg: "unsupported encoding SEC1: Ed25519 keys must be encoded in PKCS8"},
+	} {
+		t.Run(test.errMsg, func(t *testing.T) {
+			_, err := csp.GenerateSigner(os.TempDir(), test.opts)
+			assert.EqualError(t, err, test.errMsg)
+		})
+	}
+}
+
+func TestGenerateCSPSigner(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "csp-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+
+	signer, err := csp.GenerateSigner(testDir, csp.KeyOptions{Curve: csp.P384, CSP: provider})
+	require.NoError(t, err)
+	assert.Equal(t, elliptic.P384(), signer.Public().(*ecdsa.PublicKey).Curve)
+	assert.False(t, checkForFile(filepath.Join(testDir, "priv_sk")), "Expected the provider to keep the private key")
+
+	// the key is found by the public key of its certificate
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), BasicConstraintsValid: true, IsCA: true}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	require.NoError(t, cert.CheckSignatureFrom(cert))
+
+	loaded, err := csp.LoadCSPSigner(provider, cert)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Public(), loaded.Public())
+
+	other, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	_, err = csp.LoadCSPSigner(other, cert)
+	assert.Contains(t, err.Error(), "failed to get the private key with SKI")
+
+	_, err = csp.GenerateSigner(testDir, csp.KeyOptions{Algorithm: csp.Ed25519, CSP: provider})
+	assert.EqualError(t, err, "Ed25519 keys cannot be generated by the crypto service provider")
+	_, err = csp.GenerateSigner(testDir, csp.KeyOptions{Password: []byte("secret"), CSP: provider})
+	assert.EqualError(t, err, "keys generated by the crypto service provider take no encoding or password")
+}
+
 func TestECDSASigner(t *testing.T) {
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
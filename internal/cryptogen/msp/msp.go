@@ -47,6 +47,7 @@ func GenerateLocalMSP(
 	tlsCA *ca.CA,
 	nodeType int,
 	nodeOUs bool,
+	keyOpts csp.KeyOptions,
 ) error {
 
 	// create folder structure
@@ -70,7 +71,7 @@ func GenerateLocalMSP(
 	keystore := filepath.Join(mspDir, "keystore")
 
 	// generate private key
-	priv, err := csp.GeneratePrivateKey(keystore)
+	priv, err := csp.GenerateSigner(keystore, keyOpts)
 	if err != nil {
 		return err
 	}
@@ -85,7 +86,7 @@ func GenerateLocalMSP(
 		name,
 		ous,
 		nil,
-		&priv.PublicKey,
+		priv.Public(),
 		x509.KeyUsageDigitalSignature,
 		[]x509.ExtKeyUsage{},
 	)
@@ -136,8 +137,11 @@ func GenerateLocalMSP(
 		Generate the TLS artifacts in the TLS folder
 	*/
 
-	// generate private key
-	tlsPrivKey, err := csp.GeneratePrivateKey(tlsDir)
+	// generate private key, which peers and orderers read from a file even
+	// when their identity key is kept by a crypto service provider
+	tlsKeyOpts := keyOpts
+	tlsKeyOpts.CSP = nil
+	tlsPrivKey, err := csp.GenerateSigner(tlsDir, tlsKeyOpts)
 	if err != nil {
 		return err
 	}
@@ -148,7 +152,7 @@ func GenerateLocalMSP(
 		name,
 		nil,
 		sans,
-		&tlsPrivKey.PublicKey,
+		tlsPrivKey.Public(),
 		x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment,
 		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth,
 			x509.ExtKeyUsageClientAuth},
@@ -0,0 +1,283 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/internal/cryptogen/ca"
+	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/pkg/errors"
+)
+
+// The steps recorded in the transcript
+const (
+	StepGenerate = "generate"
+	StepRecover  = "recover"
+)
+
+// Config configures the generation of the root key of an organization.
+type Config struct {
+	// Org is the name of the organization.
+	Org string
+	// CommonName is the common name of the root CA certificate.
+	CommonName string
+	// Shares is the number of shares the root key is split into.
+	Shares int
+	// Threshold is the number of shares recovering the root key.
+	Threshold int
+	// Admins are the common names of the admin identities issued by the root CA.
+	Admins []string
+	// Participants are the PEM encoded certificates of the participants,
+	// who sign the transcript of the ceremony.
+	Participants [][]byte
+}
+
+// ShareFile holds a share of the root key of an organization.
+type ShareFile struct {
+	Ceremony  string `json:"ceremony"`
+	Org       string `json:"org"`
+	Threshold int    `json:"threshold"`
+	Shares    int    `json:"shares"`
+	Index     byte   `json:"index"`
+	Value     []byte `json:"value"`
+}
+
+// Generate generates the root key and CA certificate of an organization and the
+// admin identities it issues, and splits the root key into shares. The root key is
+// never written: only its shares are, each to be handed to a custodian. The
+// artifacts are written in dir:
+//
+//	ca/<common name>-cert.pem                     the root CA certificate
+//	admins/<name>/signcerts/<name>-cert.pem       the certificate of an admin
+//	admins/<name>/keystore/priv_sk                the private key of an admin
+//	shares/share-<index>.json                     a share of the root key
+//	transcript.json                               the transcript of the ceremony
+func Generate(dir string, config Config) (*Transcript, error) {
+	if config.Org == "" || config.CommonName == "" {
+		return nil, errors.New("the organization and the common name of the root CA are required")
+	}
+	t, err := NewTranscript(config.Org, config.Participants)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating the root key")
+	}
+	secret, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling the root key")
+	}
+	defer zero(secret)
+	shares, err := Split(secret, config.Shares, config.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := map[string][]byte{}
+	write := func(name string, content []byte, perm os.FileMode) error {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return errors.Wrapf(err, "failed creating the directory of %s", name)
+		}
+		if err := ioutil.WriteFile(path, content, perm); err != nil {
+			return errors.Wrapf(err, "failed writing %s", name)
+		}
+		artifacts[filepath.ToSlash(name)] = content
+		return nil
+	}
+
+	rootCA, err := newRootCA(config.Org, config.CommonName, key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCA.SignCert.Raw})
+	if err := write(filepath.Join("ca", config.CommonName+"-cert.pem"), certPEM, 0644); err != nil {
+		return nil, err
+	}
+
+	for _, admin := range config.Admins {
+		keystore := filepath.Join(dir, "admins", admin, "keystore")
+		signcerts := filepath.Join(dir, "admins", admin, "signcerts")
+		for _, d := range []string{keystore, signcerts} {
+			if err := os.MkdirAll(d, 0755); err != nil {
+				return nil, errors.Wrapf(err, "failed creating %s", d)
+			}
+		}
+		adminKey, err := csp.GeneratePrivateKey(keystore)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed generating the key of admin %s", admin)
+		}
+		cert, err := rootCA.SignCertificate(signcerts, admin, []string{"admin"}, nil, &adminKey.PublicKey, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed issuing the certificate of admin %s", admin)
+		}
+		artifacts[filepath.ToSlash(filepath.Join("admins", admin, "signcerts", admin+"-cert.pem"))] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	for _, share := range shares {
+		raw, err := json.MarshalIndent(&ShareFile{
+			Ceremony:  t.Ceremony,
+			Org:       config.Org,
+			Threshold: config.Threshold,
+			Shares:    config.Shares,
+			Index:     share.Index,
+			Value:     share.Value,
+		}, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed marshaling share")
+		}
+		if err := write(filepath.Join("shares", fmt.Sprintf("share-%d.json", share.Index)), raw, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	t.Record(StepGenerate, map[string]string{
+		"commonName": config.CommonName,
+		"key":        "ECDSA P-256",
+		"shares":     strconv.Itoa(config.Shares),
+		"threshold":  strconv.Itoa(config.Threshold),
+		"admins":     strings.Join(config.Admins, ","),
+	}, artifacts)
+	if err := t.Save(filepath.Join(dir, "transcript.json")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Recover recovers the root key from shares, checks it against the root CA
+// certificate recorded in the transcript, writes it to keystoreDir/priv_sk
+// and records the recovery in the transcript.
+func Recover(t *Transcript, shareFiles [][]byte, certificate []byte, keystoreDir string) error {
+	var certName string
+	for _, name := range t.Artifacts() {
+		if strings.HasPrefix(name, "ca/") && t.VerifyArtifact(name, certificate) == nil {
+			certName = name
+		}
+	}
+	if certName == "" {
+		return errors.New("the root CA certificate is not recorded in the transcript")
+	}
+	cert, err := parseCertificate(certificate)
+	if err != nil {
+		return err
+	}
+
+	var shares []*Share
+	var indexes []string
+	threshold := 0
+	for i, raw := range shareFiles {
+		sf := &ShareFile{}
+		if err := json.Unmarshal(raw, sf); err != nil {
+			return errors.Wrapf(err, "failed parsing share %d", i)
+		}
+		if sf.Ceremony != t.Ceremony {
+			return errors.Errorf("share %d belongs to ceremony %s, not %s", sf.Index, sf.Ceremony, t.Ceremony)
+		}
+		if err := t.VerifyArtifact(fmt.Sprintf("shares/share-%d.json", sf.Index), raw); err != nil {
+			return err
+		}
+		threshold = sf.Threshold
+		shares = append(shares, &Share{Index: sf.Index, Value: sf.Value})
+		indexes = append(indexes, strconv.Itoa(int(sf.Index)))
+	}
+	if len(shares) == 0 {
+		return errors.New("no shares provided")
+	}
+	if len(shares) < threshold {
+		return errors.Errorf("%d shares are required, got %d", threshold, len(shares))
+	}
+
+	secret, err := Combine(shares)
+	if err != nil {
+		return err
+	}
+	defer zero(secret)
+	key, err := x509.ParsePKCS8PrivateKey(secret)
+	if err != nil {
+		return errors.New("the shares do not recover a valid key")
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || !publicKeyEqual(&ecKey.PublicKey, cert.PublicKey) {
+		return errors.New("the recovered key does not match the root CA certificate")
+	}
+
+	if err := os.MkdirAll(keystoreDir, 0700); err != nil {
+		return errors.Wrapf(err, "failed creating %s", keystoreDir)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: secret})
+	if err := ioutil.WriteFile(filepath.Join(keystoreDir, "priv_sk"), keyPEM, 0600); err != nil {
+		return errors.Wrap(err, "failed writing the root key")
+	}
+
+	sort.Strings(indexes)
+	t.Record(StepRecover, map[string]string{
+		"certificate": certName,
+		"shares":      strings.Join(indexes, ","),
+	}, nil)
+	return nil
+}
+
+// newRootCA creates the self-signed root CA of the organization
+func newRootCA(org, commonName string, key *ecdsa.PrivateKey) (*ca.CA, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating serial number")
+	}
+	ski := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	notBefore := time.Now().Round(time.Minute).Add(-5 * time.Minute).UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{org}, CommonName: commonName},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(3650 * 24 * time.Hour).UTC(),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          ski[:],
+	}
+	signer := &csp.ECDSASigner{PrivateKey: key}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the root CA certificate")
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing the root CA certificate")
+	}
+
+	return &ca.CA{Name: commonName, Signer: signer, SignCert: cert}, nil
+}
+
+func publicKeyEqual(key *ecdsa.PublicKey, other interface{}) bool {
+	o, ok := other.(*ecdsa.PublicKey)
+	return ok && key.Curve == o.Curve && key.X.Cmp(o.X) == 0 && key.Y.Cmp(o.Y) == 0
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
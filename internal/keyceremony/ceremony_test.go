@@ -0,0 +1,181 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/stretchr/testify/require"
+)
+
+type participant struct {
+	signer crypto.Signer
+	cert   []byte
+}
+
+func newParticipant(t *testing.T, name string) *participant {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &participant{
+		signer: &csp.ECDSASigner{PrivateKey: key},
+		cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+	}
+}
+
+func TestCeremony(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyceremony")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	alice, bob := newParticipant(t, "alice"), newParticipant(t, "bob")
+	generated, err := Generate(dir, Config{
+		Org:          "Org1",
+		CommonName:   "ca.org1.example.com",
+		Shares:       3,
+		Threshold:    2,
+		Admins:       []string{"Admin@org1.example.com"},
+		Participants: [][]byte{alice.cert, bob.cert},
+	})
+	require.NoError(t, err)
+	require.Len(t, generated.Entries, 1)
+	require.Equal(t, StepGenerate, generated.Entries[0].Step)
+
+	// the root key is only written as shares
+	files := map[string]bool{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	require.Equal(t, map[string]bool{
+		"ca/ca.org1.example.com-cert.pem":                                         true,
+		"admins/Admin@org1.example.com/keystore/priv_sk":                          true,
+		"admins/Admin@org1.example.com/signcerts/Admin@org1.example.com-cert.pem": true,
+		"shares/share-1.json":                                                     true,
+		"shares/share-2.json":                                                     true,
+		"shares/share-3.json":                                                     true,
+		"transcript.json":                                                         true,
+	}, files)
+
+	// the admin is issued by the root CA
+	rootPEM, err := ioutil.ReadFile(filepath.Join(dir, "ca", "ca.org1.example.com-cert.pem"))
+	require.NoError(t, err)
+	root, err := parseCertificate(rootPEM)
+	require.NoError(t, err)
+	adminPEM, err := ioutil.ReadFile(filepath.Join(dir, "admins", "Admin@org1.example.com", "signcerts", "Admin@org1.example.com-cert.pem"))
+	require.NoError(t, err)
+	admin, err := parseCertificate(adminPEM)
+	require.NoError(t, err)
+	require.NoError(t, admin.CheckSignatureFrom(root))
+	require.Equal(t, []string{"admin"}, admin.Subject.OrganizationalUnit)
+
+	// the participants sign the transcript, possibly on other machines
+	transcriptPath := filepath.Join(dir, "transcript.json")
+	tr, err := LoadTranscript(transcriptPath)
+	require.NoError(t, err)
+	require.EqualError(t, tr.Verify(), "alice did not sign all the entries of the transcript")
+	require.NoError(t, tr.Sign(alice.signer, alice.cert))
+	require.NoError(t, tr.Sign(bob.signer, bob.cert))
+	require.NoError(t, tr.Verify())
+	require.NoError(t, tr.Save(transcriptPath))
+	for name := range files {
+		if name == "transcript.json" || filepath.Base(name) == "priv_sk" {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NoError(t, tr.VerifyArtifact(name, content))
+	}
+
+	// the custodians of two shares recover the root key
+	tr, err = LoadTranscript(transcriptPath)
+	require.NoError(t, err)
+	share1, err := ioutil.ReadFile(filepath.Join(dir, "shares", "share-1.json"))
+	require.NoError(t, err)
+	share3, err := ioutil.ReadFile(filepath.Join(dir, "shares", "share-3.json"))
+	require.NoError(t, err)
+	keystore := filepath.Join(dir, "recovered")
+	require.NoError(t, Recover(tr, [][]byte{share3, share1}, rootPEM, keystore))
+	key, err := csp.LoadPrivateKey(keystore)
+	require.NoError(t, err)
+	require.Equal(t, root.PublicKey, &key.PublicKey)
+	require.Len(t, tr.Entries, 2)
+	require.Equal(t, StepRecover, tr.Entries[1].Step)
+	require.Equal(t, map[string]string{"certificate": "ca/ca.org1.example.com-cert.pem", "shares": "1,3"}, tr.Entries[1].Details)
+
+	// the recovery is not endorsed until the participants sign it again
+	require.EqualError(t, tr.Verify(), "alice did not sign all the entries of the transcript")
+	require.NoError(t, tr.Sign(alice.signer, alice.cert))
+	require.NoError(t, tr.Sign(bob.signer, bob.cert))
+	require.NoError(t, tr.Verify())
+
+	t.Run("not enough shares", func(t *testing.T) {
+		err := Recover(tr, [][]byte{share1}, rootPEM, keystore)
+		require.EqualError(t, err, "2 shares are required, got 1")
+	})
+
+	t.Run("tampered share", func(t *testing.T) {
+		tampered := append([]byte{}, share1...)
+		tampered[len(tampered)-10] ^= 0x01
+		err := Recover(tr, [][]byte{tampered, share3}, rootPEM, keystore)
+		require.Error(t, err)
+	})
+
+	t.Run("other certificate", func(t *testing.T) {
+		err := Recover(tr, [][]byte{share1, share3}, alice.cert, keystore)
+		require.EqualError(t, err, "the root CA certificate is not recorded in the transcript")
+	})
+}
+
+func TestTranscript(t *testing.T) {
+	alice, bob, eve := newParticipant(t, "alice"), newParticipant(t, "bob"), newParticipant(t, "eve")
+
+	_, err := NewTranscript("Org1", nil)
+	require.EqualError(t, err, "a ceremony requires at least one participant")
+	_, err = NewTranscript("Org1", [][]byte{[]byte("not a certificate")})
+	require.EqualError(t, err, "invalid participant: no PEM encoded certificate found")
+
+	tr, err := NewTranscript("Org1", [][]byte{alice.cert, bob.cert})
+	require.NoError(t, err)
+	tr.Record("step", map[string]string{"key": "value"}, map[string][]byte{"artifact": []byte("content")})
+
+	require.EqualError(t, tr.Sign(eve.signer, eve.cert), "eve is not a participant of the ceremony")
+	require.EqualError(t, tr.Sign(alice.signer, bob.cert), "the signer does not hold the key of the certificate of bob")
+	require.NoError(t, tr.Sign(alice.signer, alice.cert))
+	require.NoError(t, tr.Sign(bob.signer, bob.cert))
+	require.NoError(t, tr.Verify())
+
+	require.NoError(t, tr.VerifyArtifact("artifact", []byte("content")))
+	require.EqualError(t, tr.VerifyArtifact("artifact", []byte("other content")), "artifact artifact does not match the digest recorded by step step")
+	require.EqualError(t, tr.VerifyArtifact("other", []byte("content")), "artifact other is not recorded in the transcript")
+
+	tr.Entries[0].Details["key"] = "tampered"
+	require.Contains(t, tr.Verify().Error(), "invalid signature 0 of alice")
+}
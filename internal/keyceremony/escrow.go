@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// EscrowShare is a share of a key placed in escrow, to be handed to a
+// custodian. The key is expected to be exported wrapped, so that the
+// custodians reconstructing it still need the wrapping key to use it.
+//
+// A share protects its own integrity with its checksum, and the integrity of
+// the other shares of the escrow with their checksums: a share altered by its
+// custodian does not match the checksums held by the other custodians. The
+// digest of the key detects the reconstruction of a wrong key.
+type EscrowShare struct {
+	Escrow    string   `json:"escrow"`
+	Label     string   `json:"label"`
+	Threshold int      `json:"threshold"`
+	Shares    int      `json:"shares"`
+	Index     byte     `json:"index"`
+	Value     []byte   `json:"value"`
+	Digest    []byte   `json:"digest"`
+	Checksums [][]byte `json:"checksums"`
+}
+
+// EscrowKey splits the key into n shares, any threshold of which reconstruct
+// it. The label describes the key to the custodians.
+func EscrowKey(key []byte, label string, n, threshold int) ([]*EscrowShare, error) {
+	shares, err := Split(key, n, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed generating escrow identifier")
+	}
+	digest := sha256.Sum256(key)
+
+	escrowShares := make([]*EscrowShare, len(shares))
+	checksums := make([][]byte, len(shares))
+	for i, share := range shares {
+		escrowShares[i] = &EscrowShare{
+			Escrow:    hex.EncodeToString(id),
+			Label:     label,
+			Threshold: threshold,
+			Shares:    n,
+			Index:     share.Index,
+			Value:     share.Value,
+			Digest:    digest[:],
+		}
+		checksums[i] = escrowShares[i].checksum()
+	}
+	for _, share := range escrowShares {
+		share.Checksums = checksums
+	}
+	return escrowShares, nil
+}
+
+// checksum returns the SHA-256 digest of the fields of the share, other than
+// the checksums of the escrow
+func (s *EscrowShare) checksum() []byte {
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(s.Escrow), []byte(s.Label), s.Value, s.Digest} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		h.Write(size[:])
+		h.Write(field)
+	}
+	var params [17]byte
+	binary.BigEndian.PutUint64(params[0:], uint64(s.Threshold))
+	binary.BigEndian.PutUint64(params[8:], uint64(s.Shares))
+	params[16] = s.Index
+	h.Write(params[:])
+	return h.Sum(nil)
+}
+
+// Verify checks the share against its own checksum.
+func (s *EscrowShare) Verify() error {
+	if s.Index == 0 || int(s.Index) > len(s.Checksums) || len(s.Checksums) != s.Shares {
+		return errors.Errorf("share %d of escrow %s is malformed", s.Index, s.Escrow)
+	}
+	if !bytes.Equal(s.Checksums[s.Index-1], s.checksum()) {
+		return errors.Errorf("share %d of escrow %s is corrupted", s.Index, s.Escrow)
+	}
+	return nil
+}
+
+// MarshalEscrowShare serializes the share.
+func MarshalEscrowShare(s *EscrowShare) ([]byte, error) {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling share")
+	}
+	return raw, nil
+}
+
+// ParseEscrowShare parses a serialized share and checks its integrity.
+func ParseEscrowShare(raw []byte) (*EscrowShare, error) {
+	s := &EscrowShare{}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return nil, errors.Wrap(err, "failed parsing share")
+	}
+	if err := s.Verify(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RecoverEscrowedKey reconstructs the key from threshold or more shares of
+// its escrow. Each share is checked against the checksums held by all the
+// others, and the key reconstructed against its digest.
+func RecoverEscrowedKey(shares []*EscrowShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares provided")
+	}
+	first := shares[0]
+	for _, s := range shares {
+		if err := s.Verify(); err != nil {
+			return nil, err
+		}
+		if s.Escrow != first.Escrow {
+			return nil, errors.Errorf("share %d belongs to escrow %s, not %s", s.Index, s.Escrow, first.Escrow)
+		}
+	}
+
+	var parts []*Share
+	for _, s := range shares {
+		for _, other := range shares {
+			if int(other.Index) > len(s.Checksums) || !bytes.Equal(s.Checksums[other.Index-1], other.Checksums[other.Index-1]) {
+				return nil, errors.Errorf("share %d of escrow %s does not match share %d", other.Index, s.Escrow, s.Index)
+			}
+		}
+		parts = append(parts, &Share{Index: s.Index, Value: s.Value})
+	}
+	if len(shares) < first.Threshold {
+		return nil, errors.Errorf("%d shares are required, got %d", first.Threshold, len(shares))
+	}
+
+	key, err := Combine(parts)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(key)
+	if subtle.ConstantTimeCompare(digest[:], first.Digest) != 1 {
+		zero(key)
+		return nil, errors.Errorf("the key reconstructed from the shares of escrow %s does not match its digest", first.Escrow)
+	}
+	return key, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscrowKey(t *testing.T) {
+	key := []byte("a private key wrapped with the key of the organization")
+	shares, err := EscrowKey(key, "org1 signing key", 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+
+	// shares survive their serialization
+	var parsed []*EscrowShare
+	for _, share := range shares {
+		require.Equal(t, "org1 signing key", share.Label)
+		require.Equal(t, shares[0].Escrow, share.Escrow)
+		raw, err := MarshalEscrowShare(share)
+		require.NoError(t, err)
+		s, err := ParseEscrowShare(raw)
+		require.NoError(t, err)
+		parsed = append(parsed, s)
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {0, 1, 2, 3, 4}} {
+		var selected []*EscrowShare
+		for _, i := range subset {
+			selected = append(selected, parsed[i])
+		}
+		recovered, err := RecoverEscrowedKey(selected)
+		require.NoError(t, err)
+		require.Equal(t, key, recovered)
+	}
+
+	_, err = EscrowKey(key, "org1 signing key", 2, 3)
+	require.EqualError(t, err, "invalid number of shares 2: must be at least the threshold 3")
+}
+
+func TestRecoverEscrowedKeyFailures(t *testing.T) {
+	key := []byte("a wrapped key")
+	escrow := func() []*EscrowShare {
+		shares, err := EscrowKey(key, "key", 3, 2)
+		require.NoError(t, err)
+		return shares
+	}
+	shares := escrow()
+	id := shares[0].Escrow
+
+	_, err := RecoverEscrowedKey(nil)
+	require.EqualError(t, err, "no shares provided")
+
+	_, err = RecoverEscrowedKey(shares[:1])
+	require.EqualError(t, err, "2 shares are required, got 1")
+
+	_, err = RecoverEscrowedKey([]*EscrowShare{shares[0], escrow()[1]})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "belongs to escrow")
+
+	// a corrupted share does not match its own checksum
+	corrupted := *shares[1]
+	corrupted.Value = append([]byte{}, shares[1].Value...)
+	corrupted.Value[0] ^= 1
+	_, err = RecoverEscrowedKey([]*EscrowShare{shares[0], &corrupted})
+	require.EqualError(t, err, "share 2 of escrow "+id+" is corrupted")
+
+	raw, err := MarshalEscrowShare(&corrupted)
+	require.NoError(t, err)
+	_, err = ParseEscrowShare(raw)
+	require.EqualError(t, err, "share 2 of escrow "+id+" is corrupted")
+
+	// a share forged along with its checksum does not match the checksums
+	// held by the other shares
+	forged := corrupted
+	forged.Checksums = append([][]byte{}, shares[1].Checksums...)
+	forged.Checksums[1] = forged.checksum()
+	_, err = RecoverEscrowedKey([]*EscrowShare{shares[0], &forged})
+	require.EqualError(t, err, "share 2 of escrow "+id+" does not match share 1")
+
+	malformed := *shares[2]
+	malformed.Index = 4
+	_, err = RecoverEscrowedKey([]*EscrowShare{shares[0], &malformed})
+	require.EqualError(t, err, "share 4 of escrow "+id+" is malformed")
+
+	// shares forged consistently reconstruct a key that does not match the
+	// digest of the escrowed key
+	forgedShares := escrow()
+	for _, share := range forgedShares {
+		share.Escrow = id
+		share.Digest = shares[0].Digest
+	}
+	forgedShares[0].Value[0] ^= 1
+	var checksums [][]byte
+	for _, share := range forgedShares {
+		checksums = append(checksums, share.checksum())
+	}
+	for _, share := range forgedShares {
+		share.Checksums = checksums
+	}
+	_, err = RecoverEscrowedKey(forgedShares[:2])
+	require.EqualError(t, err, "the key reconstructed from the shares of escrow "+id+" does not match its digest")
+}
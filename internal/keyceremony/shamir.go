@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// Share is a share of a secret split with Shamir's secret sharing scheme.
+// Each byte of the secret is shared independently over GF(2^8): the share
+// holds the evaluations at Index of random polynomials of degree threshold-1
+// whose constant terms are the bytes of the secret.
+type Share struct {
+	Index byte
+	Value []byte
+}
+
+// Split splits the secret into n shares, any threshold of which recover it.
+func Split(secret []byte, n, threshold int) ([]*Share, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("cannot split an empty secret")
+	case threshold < 2:
+		return nil, errors.Errorf("invalid threshold %d: must be at least 2", threshold)
+	case n < threshold:
+		return nil, errors.Errorf("invalid number of shares %d: must be at least the threshold %d", n, threshold)
+	case n > 255:
+		return nil, errors.Errorf("invalid number of shares %d: must be at most 255", n)
+	}
+
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{Index: byte(i + 1), Value: make([]byte, len(secret))}
+	}
+
+	coefficients := make([]byte, threshold)
+	for j, b := range secret {
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, errors.Wrap(err, "failed generating random coefficients")
+		}
+		for _, share := range shares {
+			share.Value[j] = evaluate(coefficients, share.Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine recovers the secret from shares. Combining fewer shares than the
+// threshold of the split returns garbage, which callers detect by checking
+// the secret against its public counterpart.
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+	size := len(shares[0].Value)
+	seen := map[byte]bool{}
+	for _, share := range shares {
+		if share.Index == 0 {
+			return nil, errors.New("invalid share index 0")
+		}
+		if seen[share.Index] {
+			return nil, errors.Errorf("duplicate share %d", share.Index)
+		}
+		seen[share.Index] = true
+		if len(share.Value) != size {
+			return nil, errors.Errorf("share %d is %d bytes, expected %d", share.Index, len(share.Value), size)
+		}
+	}
+
+	// Lagrange interpolation at 0: secret = sum(y_i * prod(x_j / (x_j - x_i)))
+	// where subtraction is addition in GF(2^8)
+	secret := make([]byte, size)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = mul(basis, div(other.Index, other.Index^share.Index))
+		}
+		for k := range secret {
+			secret[k] ^= mul(share.Value[k], basis)
+		}
+	}
+
+	return secret, nil
+}
+
+// evaluate evaluates the polynomial at x with Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// gfExp and gfLog are the exponential and logarithm tables of GF(2^8) with the
+// reduction polynomial x^8 + x^4 + x^3 + x + 1 of AES, with generator 3
+var gfExp, gfLog = func() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// multiply by the generator 3 = x + 1
+		x ^= xtime(x)
+	}
+	return exp, log
+}()
+
+func xtime(x byte) byte {
+	if x&0x80 != 0 {
+		return x<<1 ^ 0x1b
+	}
+	return x << 1
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
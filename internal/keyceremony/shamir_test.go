@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("the root key of the organization")
+	shares, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	for i, share := range shares {
+		require.Equal(t, byte(i+1), share.Index)
+		require.Len(t, share.Value, len(secret))
+		require.NotEqual(t, secret, share.Value)
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var selected []*Share
+		for _, i := range subset {
+			selected = append(selected, shares[i])
+		}
+		recovered, err := Combine(selected)
+		require.NoError(t, err)
+		require.Equal(t, secret, recovered)
+	}
+
+	// fewer shares than the threshold do not recover the secret
+	recovered, err := Combine(shares[:2])
+	require.NoError(t, err)
+	require.NotEqual(t, secret, recovered)
+}
+
+func TestSplitCombineFailures(t *testing.T) {
+	_, err := Split(nil, 3, 2)
+	require.EqualError(t, err, "cannot split an empty secret")
+	_, err = Split([]byte("secret"), 3, 1)
+	require.EqualError(t, err, "invalid threshold 1: must be at least 2")
+	_, err = Split([]byte("secret"), 2, 3)
+	require.EqualError(t, err, "invalid number of shares 2: must be at least the threshold 3")
+	_, err = Split([]byte("secret"), 256, 3)
+	require.EqualError(t, err, "invalid number of shares 256: must be at most 255")
+
+	shares, err := Split([]byte("secret"), 3, 2)
+	require.NoError(t, err)
+	_, err = Combine(shares[:1])
+	require.EqualError(t, err, "at least 2 shares are required")
+	_, err = Combine([]*Share{shares[0], shares[0]})
+	require.EqualError(t, err, "duplicate share 1")
+	_, err = Combine([]*Share{shares[0], {Index: 0, Value: shares[1].Value}})
+	require.EqualError(t, err, "invalid share index 0")
+	_, err = Combine([]*Share{shares[0], {Index: 2, Value: shares[1].Value[1:]}})
+	require.EqualError(t, err, "share 2 is 5 bytes, expected 6")
+}
+
+func TestGaloisField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		require.Equal(t, byte(1), div(byte(a), byte(a)))
+		for _, b := range []byte{1, 2, 3, 0x53, 0xCA, 0xFF} {
+			require.Equal(t, byte(a), div(mul(byte(a), b), b))
+		}
+	}
+	// {57} x {83} = {c1} in the field of AES (FIPS 197, section 4.2)
+	require.Equal(t, byte(0xc1), mul(0x57, 0x83))
+}
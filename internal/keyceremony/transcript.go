@@ -0,0 +1,286 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyceremony
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Transcript is the append-only record of the steps of a key ceremony,
+// signed by its participants. Each signature covers the entries recorded
+// when it was made, so that steps performed after a participant signed
+// are visibly not endorsed by that participant.
+type Transcript struct {
+	Ceremony     string       `json:"ceremony"`
+	Org          string       `json:"org"`
+	Participants [][]byte     `json:"participants,omitempty"`
+	Entries      []*Entry     `json:"entries"`
+	Signatures   []*Signature `json:"signatures,omitempty"`
+}
+
+// Entry records a step of a ceremony and the digests of the artifacts it produced.
+type Entry struct {
+	Step      string            `json:"step"`
+	Time      time.Time         `json:"time"`
+	Details   map[string]string `json:"details,omitempty"`
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+}
+
+// Signature is the signature of a participant over the first Entries entries
+// of a transcript.
+type Signature struct {
+	Participant string    `json:"participant"`
+	Certificate []byte    `json:"certificate"`
+	Entries     int       `json:"entries"`
+	Time        time.Time `json:"time"`
+	Signature   []byte    `json:"signature"`
+}
+
+// NewTranscript starts the transcript of a ceremony of the organization,
+// whose participants are identified by their PEM encoded certificates.
+func NewTranscript(org string, participants [][]byte) (*Transcript, error) {
+	if len(participants) == 0 {
+		return nil, errors.New("a ceremony requires at least one participant")
+	}
+	for _, participant := range participants {
+		if _, err := parseCertificate(participant); err != nil {
+			return nil, errors.WithMessage(err, "invalid participant")
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed generating the ceremony identifier")
+	}
+
+	return &Transcript{
+		Ceremony:     hex.EncodeToString(id),
+		Org:          org,
+		Participants: participants,
+	}, nil
+}
+
+// LoadTranscript reads a transcript from a file.
+func LoadTranscript(path string) (*Transcript, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading transcript %s", path)
+	}
+	t := &Transcript{}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, errors.Wrapf(err, "failed parsing transcript %s", path)
+	}
+	return t, nil
+}
+
+// Save writes the transcript to a file.
+func (t *Transcript) Save(path string) error {
+	raw, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling transcript")
+	}
+	if err := ioutil.WriteFile(path, raw, 0644); err != nil {
+		return errors.Wrapf(err, "failed writing transcript %s", path)
+	}
+	return nil
+}
+
+// Record appends a step to the transcript with the digests of its artifacts.
+func (t *Transcript) Record(step string, details map[string]string, artifacts map[string][]byte) {
+	entry := &Entry{
+		Step:    step,
+		Time:    time.Now().UTC().Truncate(time.Second),
+		Details: details,
+	}
+	if len(artifacts) != 0 {
+		entry.Artifacts = map[string]string{}
+		for name, content := range artifacts {
+			entry.Artifacts[name] = digest(content)
+		}
+	}
+	t.Entries = append(t.Entries, entry)
+}
+
+// Sign appends the signature of the participant over all the entries of the transcript.
+// The certificate of the participant is PEM encoded.
+func (t *Transcript) Sign(signer crypto.Signer, certificate []byte) error {
+	cert, err := parseCertificate(certificate)
+	if err != nil {
+		return err
+	}
+	if !t.isParticipant(cert) {
+		return errors.Errorf("%s is not a participant of the ceremony", cert.Subject.CommonName)
+	}
+	signerKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling the public key of the signer")
+	}
+	certKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil || !bytes.Equal(signerKey, certKey) {
+		return errors.Errorf("the signer does not hold the key of the certificate of %s", cert.Subject.CommonName)
+	}
+
+	msg, err := t.signedBytes(len(t.Entries))
+	if err != nil {
+		return err
+	}
+	var signature []byte
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		signature, err = signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	default:
+		d := sha256.Sum256(msg)
+		signature, err = signer.Sign(rand.Reader, d[:], crypto.SHA256)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed signing the transcript")
+	}
+
+	t.Signatures = append(t.Signatures, &Signature{
+		Participant: cert.Subject.CommonName,
+		Certificate: certificate,
+		Entries:     len(t.Entries),
+		Time:        time.Now().UTC().Truncate(time.Second),
+		Signature:   signature,
+	})
+	return nil
+}
+
+// Verify verifies the signatures of the transcript, and that every participant
+// signed all of its entries.
+func (t *Transcript) Verify() error {
+	signedAll := map[string]bool{}
+	for i, s := range t.Signatures {
+		cert, err := parseCertificate(s.Certificate)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid signature %d", i)
+		}
+		if !t.isParticipant(cert) {
+			return errors.Errorf("invalid signature %d: %s is not a participant of the ceremony", i, cert.Subject.CommonName)
+		}
+		if s.Entries < 1 || s.Entries > len(t.Entries) {
+			return errors.Errorf("invalid signature %d: it covers %d entries of %d", i, s.Entries, len(t.Entries))
+		}
+		msg, err := t.signedBytes(s.Entries)
+		if err != nil {
+			return err
+		}
+		if err := cert.CheckSignature(signatureAlgorithm(cert), msg, s.Signature); err != nil {
+			return errors.Wrapf(err, "invalid signature %d of %s", i, cert.Subject.CommonName)
+		}
+		if s.Entries == len(t.Entries) {
+			signedAll[string(cert.Raw)] = true
+		}
+	}
+
+	for _, participant := range t.Participants {
+		cert, err := parseCertificate(participant)
+		if err != nil {
+			return errors.WithMessage(err, "invalid participant")
+		}
+		if !signedAll[string(cert.Raw)] {
+			return errors.Errorf("%s did not sign all the entries of the transcript", cert.Subject.CommonName)
+		}
+	}
+	return nil
+}
+
+// VerifyArtifact checks that the content of the artifact matches the digest
+// recorded by the last step that produced it.
+func (t *Transcript) VerifyArtifact(name string, content []byte) error {
+	for i := len(t.Entries) - 1; i >= 0; i-- {
+		expected, ok := t.Entries[i].Artifacts[name]
+		if !ok {
+			continue
+		}
+		if expected != digest(content) {
+			return errors.Errorf("artifact %s does not match the digest recorded by step %s", name, t.Entries[i].Step)
+		}
+		return nil
+	}
+	return errors.Errorf("artifact %s is not recorded in the transcript", name)
+}
+
+// Artifacts returns the names of the artifacts recorded in the transcript.
+func (t *Transcript) Artifacts() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, entry := range t.Entries {
+		for name := range entry.Artifacts {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// signedBytes returns the bytes signed by the participants over the first n entries
+func (t *Transcript) signedBytes(n int) ([]byte, error) {
+	raw, err := json.Marshal(&Transcript{
+		Ceremony:     t.Ceremony,
+		Org:          t.Org,
+		Participants: t.Participants,
+		Entries:      t.Entries[:n],
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling transcript")
+	}
+	return raw, nil
+}
+
+func (t *Transcript) isParticipant(cert *x509.Certificate) bool {
+	for _, participant := range t.Participants {
+		p, err := parseCertificate(participant)
+		if err == nil && p.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+func signatureAlgorithm(cert *x509.Certificate) x509.SignatureAlgorithm {
+	switch cert.PublicKey.(type) {
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256
+	default:
+		return x509.UnknownSignatureAlgorithm
+	}
+}
+
+func parseCertificate(raw []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing certificate")
+	}
+	return cert, nil
+}
+
+func digest(content []byte) string {
+	d := sha256.Sum256(content)
+	return hex.EncodeToString(d[:])
+}
@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/pkg/errors"
+)
+
+// Report is the outcome of comparing the state snapshots of two peers
+type Report struct {
+	// ChannelName and LastBlockNumber identify the state the snapshots capture, when they are state checkpoints
+	ChannelName     string `json:",omitempty"`
+	LastBlockNumber uint64 `json:",omitempty"`
+	// Namespaces are the namespaces and collections that differ, in the order of the snapshots
+	Namespaces []*NamespaceReport
+	// Truncated is whether differing keys were left out of the report to honor the maximum number of keys
+	Truncated bool
+}
+
+// NamespaceReport reports the keys that differ in a namespace, or in a collection of a namespace
+type NamespaceReport struct {
+	Namespace string
+	// Collection is empty for the public state of the namespace
+	Collection string `json:",omitempty"`
+	// OnlyInFirst is the number of keys present only in the first snapshot
+	OnlyInFirst int
+	// OnlyInSecond is the number of keys present only in the second snapshot
+	OnlyInSecond int
+	// Different is the number of keys present in both snapshots, with a different value, metadata, or version
+	Different int
+	// Keys are the keys that differ
+	Keys []*KeyDiff
+}
+
+// KeyDiff is a key that differs between two snapshots. The keys and values
+// of the collections are the hashes of the private data.
+type KeyDiff struct {
+	Key    string
+	First  *State `json:",omitempty"`
+	Second *State `json:",omitempty"`
+}
+
+// State is the state of a key in a snapshot
+type State struct {
+	Value    []byte
+	Metadata []byte `json:",omitempty"`
+	BlockNum uint64
+	TxNum    uint64
+}
+
+// Compare compares the state snapshots of two peers, which are the public state and the private state hashes
+// exported in the given dirs. When the dirs are state checkpoints, they must capture the state of the same channel
+// at the same block. At most maxKeys differing keys are reported, and zero means no limit.
+func Compare(snapshotDir1, snapshotDir2 string, maxKeys int) (*Report, error) {
+	report := &Report{}
+	checkpoint1, _, err1 := kvledger.ReadStateCheckpoint(snapshotDir1)
+	checkpoint2, _, err2 := kvledger.ReadStateCheckpoint(snapshotDir2)
+	if err1 == nil && err2 == nil {
+		if checkpoint1.ChannelName != checkpoint2.ChannelName || checkpoint1.LastBlockNumber != checkpoint2.LastBlockNumber {
+			return nil, errors.Errorf("the snapshots capture different states: channel [%s] at block [%d] in %s, and channel [%s] at block [%d] in %s",
+				checkpoint1.ChannelName, checkpoint1.LastBlockNumber, snapshotDir1,
+				checkpoint2.ChannelName, checkpoint2.LastBlockNumber, snapshotDir2)
+		}
+		report.ChannelName = checkpoint1.ChannelName
+		report.LastBlockNumber = checkpoint1.LastBlockNumber
+	}
+
+	var current *NamespaceReport
+	var keys int
+	err := privacyenabledstate.CompareSnapshots(snapshotDir1, snapshotDir2, func(diff *privacyenabledstate.SnapshotKeyDiff) error {
+		if current == nil || current.Namespace != diff.Namespace || current.Collection != diff.Collection {
+			current = &NamespaceReport{
+				Namespace:  diff.Namespace,
+				Collection: diff.Collection,
+			}
+			report.Namespaces = append(report.Namespaces, current)
+		}
+		switch {
+		case diff.Second == nil:
+			current.OnlyInFirst++
+		case diff.First == nil:
+			current.OnlyInSecond++
+		default:
+			current.Different++
+		}
+
+		if maxKeys != 0 && keys >= maxKeys {
+			report.Truncated = true
+			return nil
+		}
+		keys++
+		key := diff.Key
+		if diff.Collection != "" {
+			key = hex.EncodeToString([]byte(key))
+		}
+		current.Keys = append(current.Keys, &KeyDiff{
+			Key:    key,
+			First:  newState(diff.First),
+			Second: newState(diff.Second),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed comparing the snapshots in %s and %s", snapshotDir1, snapshotDir2)
+	}
+	return report, nil
+}
+
+func newState(v *statedb.VersionedValue) *State {
+	if v == nil {
+		return nil
+	}
+	return &State{
+		Value:    v.Value,
+		Metadata: v.Metadata,
+		BlockNum: v.Version.BlockNum,
+		TxNum:    v.Version.TxNum,
+	}
+}
+
+// WriteReport writes the report in JSON
+func WriteReport(w io.Writer, report *Report) error {
+	jsonBytes, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling the report")
+	}
+	_, err = fmt.Fprintln(w, string(jsonBytes))
+	return err
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// In the snapshots in testdata, key2 of mycc diverged, key3 of mycc is only in the first snapshot,
+// key4 of mycc is only in the second one, and the hash of the private key 0x0102 of mycc/coll1 diverged
+const (
+	snapshotDir1 = "testdata/snapshot1"
+	snapshotDir2 = "testdata/snapshot2"
+)
+
+func TestCompare(t *testing.T) {
+	report, err := Compare(snapshotDir1, snapshotDir2, 0)
+	require.NoError(t, err)
+	require.False(t, report.Truncated)
+	require.Equal(t, "mychannel", report.ChannelName)
+	require.Equal(t, uint64(7), report.LastBlockNumber)
+	require.Equal(t,
+		[]*NamespaceReport{
+			{
+				Namespace:    "mycc",
+				OnlyInFirst:  1,
+				OnlyInSecond: 1,
+				Different:    1,
+				Keys: []*KeyDiff{
+					{
+						Key:    "key2",
+						First:  &State{Value: []byte("value2"), BlockNum: 5},
+						Second: &State{Value: []byte("value2-diverged"), BlockNum: 5},
+					},
+					{
+						Key:   "key3",
+						First: &State{Value: []byte("value3"), BlockNum: 5, TxNum: 1},
+					},
+					{
+						Key:    "key4",
+						Second: &State{Value: []byte("value4"), BlockNum: 6, TxNum: 1},
+					},
+				},
+			},
+			{
+				Namespace:  "mycc",
+				Collection: "coll1",
+				Different:  1,
+				Keys: []*KeyDiff{
+					{
+						Key:    "0102",
+						First:  &State{Value: []byte{0xaa}, BlockNum: 7},
+						Second: &State{Value: []byte{0xbb}, BlockNum: 7},
+					},
+				},
+			},
+		},
+		report.Namespaces,
+	)
+
+	report, err = Compare(snapshotDir1, snapshotDir1, 0)
+	require.NoError(t, err)
+	require.Empty(t, report.Namespaces)
+}
+
+func TestCompareMaxKeys(t *testing.T) {
+	report, err := Compare(snapshotDir1, snapshotDir2, 2)
+	require.NoError(t, err)
+	require.True(t, report.Truncated)
+	require.Len(t, report.Namespaces, 2)
+	// the counts cover all the keys, including the ones left out of the report
+	require.Len(t, report.Namespaces[0].Keys, 2)
+	require.Equal(t, 1, report.Namespaces[0].OnlyInSecond)
+	require.Empty(t, report.Namespaces[1].Keys)
+	require.Equal(t, 1, report.Namespaces[1].Different)
+}
+
+func TestCompareMissingSnapshot(t *testing.T) {
+	_, err := Compare(snapshotDir1, "testdata/missing", 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed comparing the snapshots in testdata/snapshot1 and testdata/missing")
+}
+
+func TestCompareDifferentStates(t *testing.T) {
+	_, err := Compare(snapshotDir1, "testdata/snapshot3", 0)
+	require.EqualError(t, err, "the snapshots capture different states: channel [mychannel] at block [7] in testdata/snapshot1, and channel [mychannel] at block [8] in testdata/snapshot3")
+}
+
+func TestWriteReport(t *testing.T) {
+	report, err := Compare(snapshotDir1, snapshotDir2, 1)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteReport(buf, report))
+	decoded := &Report{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	require.Equal(t, report, decoded)
+}
@@ -135,7 +135,14 @@ func InitCrypto(mspMgrConfigDir, localMSPID, localMSPType string) error {
 	SetBCCSPKeystorePath()
 	bccspConfig := factory.GetDefaultOpts()
 	if config := viper.Get("peer.BCCSP"); config != nil {
-		err = mapstructure.Decode(config, bccspConfig)
+		var decoder *mapstructure.Decoder
+		decoder, err = mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+			Result:     bccspConfig,
+		})
+		if err == nil {
+			err = decoder.Decode(config)
+		}
 		if err != nil {
 			return errors.WithMessage(err, "could not decode peer BCCSP configuration")
 		}
@@ -298,6 +305,9 @@ func InitCmd(cmd *cobra.Command, args []string) {
 	}
 
 	loggingSpec := os.Getenv("FABRIC_LOGGING_SPEC")
+	if loggingSpec == "" {
+		loggingSpec = viper.GetString("logging.spec")
+	}
 	loggingFormat := os.Getenv("FABRIC_LOGGING_FORMAT")
 
 	flogging.Init(flogging.Config{
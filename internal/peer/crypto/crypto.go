@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var logger = flogging.MustGetLogger("cli.crypto")
+
+// Cmd returns the cobra command for signing and verifying payloads.
+func Cmd() *cobra.Command {
+	cryptoCmd.AddCommand(signCmd())
+	cryptoCmd.AddCommand(verifyCmd())
+	return cryptoCmd
+}
+
+var cryptoCmd = &cobra.Command{
+	Use:   "crypto",
+	Short: "Sign payloads and verify signatures offline: sign|verify.",
+	Long:  "Sign arbitrary payloads with the local MSP of the peer and verify signatures against identity certificates, without connecting to any node: sign|verify.",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		common.InitCmd(cmd, args)
+	},
+}
+
+// readFile reads the file at the given path, or the standard input if the
+// path is -.
+func readFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("no file specified")
+	}
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		raw, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading %s", path)
+	}
+	return raw, nil
+}
+
+// readSignature reads a base64 encoded signature, as written by sign.
+func readSignature(path string) ([]byte, error) {
+	raw, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "signature %s is not base64 encoded", path)
+	}
+	return sig, nil
+}
+
+func checkArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.Errorf("trailing args detected: %s", args)
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/bccsp/versions"
+	"github.com/stretchr/testify/require"
+)
+
+// cspSigner signs messages as the MSP identities do for ECDSA keys.
+type cspSigner struct {
+	provider bccsp.BCCSP
+	key      bccsp.Key
+}
+
+func (s *cspSigner) Sign(message []byte) ([]byte, error) {
+	digest, err := s.provider.Hash(message, &bccsp.SHA256Opts{})
+	if err != nil {
+		return nil, err
+	}
+	return s.provider.Sign(s.key, digest, nil)
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("no key")
+}
+
+func writeCertificate(t *testing.T, path string, pub, priv interface{}) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peer0.org1.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	require.NoError(t, err)
+	raw := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(path, raw, 0644))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crypto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	key, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	s, err := signer.New(provider, key)
+	require.NoError(t, err)
+	certFile := filepath.Join(dir, "cert.pem")
+	writeCertificate(t, certFile, s.Public(), s)
+
+	payload := filepath.Join(dir, "config_update.pb")
+	require.NoError(t, ioutil.WriteFile(payload, []byte("config update"), 0644))
+
+	// the signature is written to stdout unless an output file is given
+	stdout := &bytes.Buffer{}
+	require.NoError(t, Sign(&cspSigner{provider, key}, payload, "", stdout))
+	_, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(stdout.Bytes())))
+	require.NoError(t, err)
+
+	sigFile := filepath.Join(dir, "config_update.sig")
+	require.NoError(t, Sign(&cspSigner{provider, key}, payload, sigFile, stdout))
+
+	out := &bytes.Buffer{}
+	require.NoError(t, Verify(provider, payload, sigFile, certFile, bccsp.SHA2, out))
+	require.Equal(t, "The signature is valid for CN=peer0.org1.example.com\n", out.String())
+
+	err = Verify(provider, payload, sigFile, certFile, bccsp.SHA3, out)
+	require.EqualError(t, err, "the signature is invalid")
+	err = Verify(provider, payload, sigFile, certFile, "MD5", out)
+	require.EqualError(t, err, "hash family not recognized [MD5]")
+
+	require.NoError(t, ioutil.WriteFile(payload, []byte("tampered update"), 0644))
+	err = Verify(provider, payload, sigFile, certFile, bccsp.SHA2, out)
+	require.EqualError(t, err, "the signature is invalid")
+}
+
+func TestVerifyEd25519(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crypto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	certFile := filepath.Join(dir, "cert.pem")
+	writeCertificate(t, certFile, pub, crypto.Signer(priv))
+
+	payload := filepath.Join(dir, "payload")
+	require.NoError(t, ioutil.WriteFile(payload, []byte("payload"), 0644))
+	sigFile := filepath.Join(dir, "payload.sig")
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("payload")))
+	require.NoError(t, ioutil.WriteFile(sigFile, []byte(sig), 0644))
+
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	require.NoError(t, Verify(provider, payload, sigFile, certFile, bccsp.SHA2, ioutil.Discard))
+}
+
+func TestSignAndVerifyErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crypto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	payload := filepath.Join(dir, "payload")
+	require.NoError(t, ioutil.WriteFile(payload, []byte("payload"), 0644))
+	notBase64 := filepath.Join(dir, "payload.sig")
+	require.NoError(t, ioutil.WriteFile(notBase64, []byte("not base64!"), 0644))
+
+	err = Sign(failingSigner{}, payload, "", ioutil.Discard)
+	require.EqualError(t, err, "failed signing payload: no key")
+	err = Sign(failingSigner{}, "", "", ioutil.Discard)
+	require.EqualError(t, err, "no file specified")
+	err = Sign(failingSigner{}, filepath.Join(dir, "missing"), "", ioutil.Discard)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed reading "+filepath.Join(dir, "missing"))
+
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	err = Verify(provider, payload, notBase64, payload, bccsp.SHA2, ioutil.Discard)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("signature %s is not base64 encoded", notBase64))
+
+	validSig := filepath.Join(dir, "valid.sig")
+	require.NoError(t, ioutil.WriteFile(validSig, []byte("c2lnbmF0dXJl"), 0644))
+	err = Verify(provider, payload, validSig, payload, bccsp.SHA2, ioutil.Discard)
+	require.EqualError(t, err, fmt.Sprintf("no PEM data found in %s", payload))
+}
+
+func TestSignAndVerifyWithKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crypto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	csp, err := versions.New(provider, filepath.Join(dir, "keyversions.json"))
+	require.NoError(t, err)
+	_, err = csp.Rotate("release", &bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	payload := filepath.Join(dir, "artifact")
+	require.NoError(t, ioutil.WriteFile(payload, []byte("artifact"), 0644))
+	sigFile := filepath.Join(dir, "artifact.sig")
+	require.NoError(t, Sign(&KeySigner{CSP: csp, Name: "release", HashFamily: bccsp.SHA2}, payload, sigFile, ioutil.Discard))
+
+	// the signatures of the previous versions are accepted after a rotation
+	_, err = csp.Rotate("release", &bccsp.ED25519KeyGenOpts{})
+	require.NoError(t, err)
+	out := &bytes.Buffer{}
+	require.NoError(t, VerifyWithKey(csp, payload, sigFile, "release", bccsp.SHA2, out))
+	require.Equal(t, "The signature is valid for key release\n", out.String())
+
+	require.NoError(t, csp.Revoke("release", 1))
+	err = VerifyWithKey(csp, payload, sigFile, "release", bccsp.SHA2, out)
+	require.EqualError(t, err, "the signature is invalid")
+
+	require.NoError(t, Sign(&KeySigner{CSP: csp, Name: "release", HashFamily: bccsp.SHA3}, payload, sigFile, ioutil.Discard))
+	require.NoError(t, VerifyWithKey(csp, payload, sigFile, "release", bccsp.SHA3, out))
+	err = VerifyWithKey(csp, payload, sigFile, "unknown", bccsp.SHA3, out)
+	require.EqualError(t, err, "key unknown not found")
+	err = Sign(&KeySigner{CSP: csp, Name: "release", HashFamily: "MD5"}, payload, sigFile, ioutil.Discard)
+	require.EqualError(t, err, "failed signing payload: hash family not recognized [MD5]")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/versions"
+	"github.com/pkg/errors"
+)
+
+// KeySigner signs the digest of messages with the active version of a named
+// key of the keystore.
+type KeySigner struct {
+	CSP        *versions.CSP
+	Name       string
+	HashFamily string
+}
+
+// Sign signs the digest of the message with the active version of the key,
+// whatever its algorithm.
+func (s *KeySigner) Sign(message []byte) ([]byte, error) {
+	k, err := s.CSP.Key(s.Name)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := digestOf(s.CSP, s.HashFamily, message)
+	if err != nil {
+		return nil, err
+	}
+	return s.CSP.Sign(k, digest, nil)
+}
+
+// VerifyWithKey verifies the signature of the payload of a file against the
+// versions of a named key of the keystore which are not revoked.
+func VerifyWithKey(csp *versions.CSP, file, signature, name, hashFamily string, w io.Writer) error {
+	payload, err := readFile(file)
+	if err != nil {
+		return err
+	}
+	sig, err := readSignature(signature)
+	if err != nil {
+		return err
+	}
+	k, err := csp.Key(name)
+	if err != nil {
+		return err
+	}
+	digest, err := digestOf(csp, hashFamily, payload)
+	if err != nil {
+		return err
+	}
+
+	valid, err := csp.Verify(k, sig, digest, nil)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the validity of the signature")
+	}
+	if !valid {
+		return errors.New("the signature is invalid")
+	}
+	fmt.Fprintf(w, "The signature is valid for key %s\n", name)
+	return nil
+}
+
+// digestOf hashes the payload with the hash family
+func digestOf(provider bccsp.BCCSP, hashFamily string, payload []byte) ([]byte, error) {
+	var hashOpts bccsp.HashOpts
+	switch hashFamily {
+	case bccsp.SHA2:
+		hashOpts = &bccsp.SHA256Opts{}
+	case bccsp.SHA3:
+		hashOpts = &bccsp.SHA3_256Opts{}
+	default:
+		return nil, errors.Errorf("hash family not recognized [%s]", hashFamily)
+	}
+	digest, err := provider.Hash(payload, hashOpts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed computing digest")
+	}
+	return digest, nil
+}
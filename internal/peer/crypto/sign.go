@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/hyperledger/fabric/internal/peer/keystore"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Signer signs messages.
+type Signer interface {
+	Sign(message []byte) ([]byte, error)
+}
+
+func signCmd() *cobra.Command {
+	var file, output, key, hashFamily string
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign a payload with the local MSP or a named key.",
+		Long: "Sign a payload with the default signing identity of the local MSP, as the peer signs " +
+			"transactions, or with the active version of a named key of the keystore. The signature is written in base64.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args); err != nil {
+				return err
+			}
+			if key != "" {
+				csp, err := keystore.NewVersions(factory.GetDefault())
+				if err != nil {
+					return err
+				}
+				return Sign(&KeySigner{CSP: csp, Name: key, HashFamily: hashFamily}, file, output, cmd.OutOrStdout())
+			}
+			signer, err := common.GetDefaultSignerFnc()
+			if err != nil {
+				return err
+			}
+			return Sign(signer, file, output, cmd.OutOrStdout())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&file, "file", "f", "", "The file holding the payload to sign, or - for the standard input")
+	flags.StringVarP(&output, "output", "o", "", "The file to write the signature to (default stdout)")
+	flags.StringVarP(&key, "key", "", "", "The named key of the keystore to sign with, instead of the local MSP")
+	flags.StringVarP(&hashFamily, "hashFamily", "", bccsp.SHA2, "The hash family of the digest signed with --key, SHA2 or SHA3")
+	return cmd
+}
+
+// Sign signs the payload of a file and writes the base64 encoded signature
+// to the output file, or to w if no output file is given.
+func Sign(signer Signer, file, output string, w io.Writer) error {
+	payload, err := readFile(file)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign(payload)
+	if err != nil {
+		return errors.WithMessage(err, "failed signing payload")
+	}
+	encoded := base64.StdEncoding.EncodeToString(sig)
+
+	if output == "" {
+		fmt.Fprintln(w, encoded)
+		return nil
+	}
+	if err := ioutil.WriteFile(output, []byte(encoded+"\n"), 0644); err != nil {
+		return errors.Wrapf(err, "failed writing %s", output)
+	}
+	logger.Infof("Wrote the signature of %s to %s", file, output)
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/internal/peer/keystore"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func verifyCmd() *cobra.Command {
+	var file, signature, cert, key, hashFamily string
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signature of a payload.",
+		Long: "Verify that a base64 encoded signature of a payload was produced by the key of an " +
+			"identity certificate, as MSPs verify signatures, or by a version of a named key of the keystore which " +
+			"is not revoked. The validity of the certificate is not checked.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args); err != nil {
+				return err
+			}
+			if key == "" {
+				return Verify(factory.GetDefault(), file, signature, cert, hashFamily, cmd.OutOrStdout())
+			}
+			if cert != "" {
+				return errors.New("only one of --cert and --key can be specified")
+			}
+			csp, err := keystore.NewVersions(factory.GetDefault())
+			if err != nil {
+				return err
+			}
+			return VerifyWithKey(csp, file, signature, key, hashFamily, cmd.OutOrStdout())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&file, "file", "f", "", "The file holding the signed payload, or - for the standard input")
+	flags.StringVarP(&signature, "signature", "s", "", "The file holding the base64 encoded signature")
+	flags.StringVarP(&cert, "cert", "", "", "The PEM file holding the certificate of the signer")
+	flags.StringVarP(&key, "key", "", "", "The named key of the keystore whose versions verify the signature, instead of a certificate")
+	flags.StringVarP(&hashFamily, "hashFamily", "", bccsp.SHA2, "The hash family of the MSP of the signer, SHA2 or SHA3")
+	return cmd
+}
+
+// Verify verifies the signature of the payload of a file against the public
+// key of an identity certificate.
+func Verify(provider bccsp.BCCSP, file, signature, certFile, hashFamily string, w io.Writer) error {
+	payload, err := readFile(file)
+	if err != nil {
+		return err
+	}
+	sig, err := readSignature(signature)
+	if err != nil {
+		return err
+	}
+	cert, err := readCertificate(certFile)
+	if err != nil {
+		return err
+	}
+
+	if err := verifySignature(provider, cert, hashFamily, payload, sig); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "The signature is valid for %s\n", cert.Subject)
+	return nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	raw, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed parsing certificate %s", path)
+	}
+	return cert, nil
+}
+
+// verifySignature verifies a signature the way the identities of an MSP do:
+// Ed25519 signs the payload itself, whereas the other schemes sign the digest
+// of the payload.
+func verifySignature(provider bccsp.BCCSP, cert *x509.Certificate, hashFamily string, payload, sig []byte) error {
+	pk, err := provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return errors.WithMessage(err, "failed importing the public key of the certificate")
+	}
+
+	digest := payload
+	if cert.PublicKeyAlgorithm != x509.Ed25519 {
+		if digest, err = digestOf(provider, hashFamily, payload); err != nil {
+			return err
+		}
+	}
+
+	valid, err := provider.Verify(pk, sig, digest, nil)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the validity of the signature")
+	}
+	if !valid {
+		return errors.New("the signature is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/approval"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newGate returns the gate enforcing the approval of the operations on the
+// flagged keys configured in peer.keystoreApproval, or nil when no approvals
+// are required.
+func newGate(provider bccsp.BCCSP) (*approval.Gate, error) {
+	required := viper.GetInt("peer.keystoreApproval.required")
+	if required == 0 {
+		return nil, nil
+	}
+
+	var keys [][]byte
+	for _, ski := range viper.GetStringSlice("peer.keystoreApproval.keys") {
+		raw, err := hex.DecodeString(ski)
+		if err != nil || len(raw) == 0 {
+			return nil, errors.Errorf("invalid flagged key %s: must be a hex encoded SKI", ski)
+		}
+		keys = append(keys, raw)
+	}
+
+	base := filepath.Dir(viper.ConfigFileUsed())
+	var approvers []*x509.Certificate
+	for _, path := range viper.GetStringSlice("peer.keystoreApproval.approvers") {
+		path = config.TranslatePath(base, path)
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading approver certificate %s", path)
+		}
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return nil, errors.Errorf("approver certificate %s is not PEM encoded", path)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing approver certificate %s", path)
+		}
+		approvers = append(approvers, cert)
+	}
+
+	queue := config.GetPath("peer.keystoreApproval.queue")
+	if queue == "" {
+		queue = filepath.Join(config.GetPath("peer.fileSystemPath"), "keystoreapprovals")
+	}
+
+	return approval.New(provider, approval.Policy{
+		Keys:      keys,
+		Approvers: approvers,
+		Required:  required,
+		Expiry:    viper.GetDuration("peer.keystoreApproval.expiry"),
+	}, queue)
+}
+
+// Pending writes the operations on flagged keys pending approval, along with
+// the approvers who approved them.
+func (ks *Keystore) Pending() error {
+	if ks.Gate == nil {
+		return errors.New("no approvals are required for the operations on the keys")
+	}
+	ops, err := ks.Gate.Pending()
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		fmt.Fprintf(ks.Writer, "%s %s %x requested %s approvals %d/%d\n",
+			op.ID, op.Kind, op.SKI, op.Requested.Format("2006-01-02T15:04:05Z"), len(op.Approvals), ks.Gate.Required())
+		for _, a := range op.Approvals {
+			if block, _ := pem.Decode(a.Approver); block != nil {
+				if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+					fmt.Fprintf(ks.Writer, "\t%s\n", cert.Subject)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Approve approves a pending operation with the signature of the signer,
+// whose certificate must be one of the approvers.
+func (ks *Keystore) Approve(id string, signer common.Signer) error {
+	if ks.Gate == nil {
+		return errors.New("no approvals are required for the operations on the keys")
+	}
+	op, err := ks.Gate.Operation(id)
+	if err != nil {
+		return err
+	}
+
+	serialized, err := signer.Serialize()
+	if err != nil {
+		return errors.WithMessage(err, "failed serializing the identity of the approver")
+	}
+	identity := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(serialized, identity); err != nil {
+		return errors.Wrap(err, "failed parsing the identity of the approver")
+	}
+	signature, err := signer.Sign(op.Message())
+	if err != nil {
+		return errors.WithMessagef(err, "failed signing operation %s", id)
+	}
+
+	op, err = ks.Gate.Approve(id, identity.IdBytes, signature)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ks.Writer, "Approved the %s of key %x: %d of %d approvals\n", op.Kind, op.SKI, len(op.Approvals), ks.Gate.Required())
+	return nil
+}
+
+func pendingCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pending",
+		Short: "List the operations on keys of the peer pending approval.",
+		Long:  "List the deletions and exports of flagged keys of the peer pending approval, along with the approvers who approved them.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 0); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Pending()
+		},
+	}
+}
+
+func approveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "approve <id>",
+		Short: "Approve an operation on a key of the peer.",
+		Long:  "Approve the pending operation with the given identifier with the signing identity of the local MSP, which must be one of the approvers configured in peer.keystoreApproval.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			signer, err := common.GetDefaultSigner()
+			if err != nil {
+				return err
+			}
+			return ks.Approve(args[0], signer)
+		},
+	}
+}
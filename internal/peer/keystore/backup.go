@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/approval"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func (ks *Keystore) backupManager() (bccsp.BackupManager, error) {
+	if ks.Versions != nil {
+		return ks.Versions, nil
+	}
+	manager, ok := ks.Provider.(bccsp.BackupManager)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support backups")
+	}
+	return manager, nil
+}
+
+// Backup writes the keys of the provider to an archive encrypted with the
+// passphrase read from passphraseFile, whose manifest is signed with the key
+// of the given SKI. Backing up flagged keys requires the approval of their
+// export.
+func (ks *Keystore) Backup(output, passphraseFile, signerSKI string) error {
+	manager, err := ks.backupManager()
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+	signer, err := ks.getKey(signerSKI)
+	if err != nil {
+		return err
+	}
+
+	var ops []*approval.Operation
+	if ks.Gate != nil {
+		keyManager, err := ks.keyManager()
+		if err != nil {
+			return err
+		}
+		keys, err := keyManager.ListKeys()
+		if err != nil {
+			return errors.WithMessage(err, "failed listing keys")
+		}
+		for _, k := range keys {
+			op, err := ks.Gate.Authorize(approval.Export, k.SKI())
+			if err != nil {
+				return err
+			}
+			if op != nil {
+				ops = append(ops, op)
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	manifest, err := manager.ExportBackup(buf, &bccsp.BackupOpts{Passphrase: passphrase, Signer: signer})
+	if err != nil {
+		return errors.WithMessage(err, "failed exporting backup")
+	}
+	if err := ioutil.WriteFile(output, buf.Bytes(), 0600); err != nil {
+		return errors.Wrapf(err, "failed writing %s", output)
+	}
+	fmt.Fprintf(ks.Writer, "Backed up %d keys to %s, signed by key %x\n", len(manifest.Keys), output, signer.SKI())
+	for _, op := range ops {
+		if err := ks.Gate.Complete(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore verifies the backup read from input, decrypted with the passphrase
+// read from passphraseFile, and restores its keys to the provider. When
+// trusted PEM files of certificates or public keys are given, the manifest of
+// the backup must be signed by one of their keys.
+func (ks *Keystore) Restore(input, passphraseFile string, trusted []string) error {
+	manager, err := ks.backupManager()
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+	var trustedKeys []bccsp.Key
+	for _, path := range trusted {
+		k, err := ks.importTrustedKey(path)
+		if err != nil {
+			return err
+		}
+		trustedKeys = append(trustedKeys, k)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return errors.Wrapf(err, "failed opening %s", input)
+	}
+	defer f.Close()
+	manifest, err := manager.ImportBackup(f, &bccsp.BackupOpts{Passphrase: passphrase, Trusted: trustedKeys})
+	if err != nil {
+		return errors.WithMessage(err, "failed restoring backup")
+	}
+	var signer []byte
+	if k, err := ks.importPublicKey(manifest.Signer); err == nil {
+		signer = k.SKI()
+	}
+	fmt.Fprintf(ks.Writer, "Restored the backup of %s, signed by key %x:\n", manifest.Created.Format("2006-01-02T15:04:05Z"), signer)
+	for _, entry := range manifest.Keys {
+		fmt.Fprintf(ks.Writer, "%x %s %s\n", entry.SKI, entry.Type, entry.Algorithm)
+	}
+	return nil
+}
+
+// importTrustedKey imports the public key of the PEM encoded certificate or
+// public key of the file.
+func (ks *Keystore) importTrustedKey(path string) (bccsp.Key, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading trusted key %s", path)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.Errorf("trusted key %s is not PEM encoded", path)
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed parsing trusted certificate %s", path)
+		}
+		return ks.Provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	}
+	k, err := ks.importPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed importing trusted key %s", path)
+	}
+	return k, nil
+}
+
+// importPublicKey imports a DER encoded public key.
+func (ks *Keystore) importPublicKey(der []byte) (bccsp.Key, error) {
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	switch pk := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ks.Provider.KeyImport(pk, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	case ed25519.PublicKey:
+		return ks.Provider.KeyImport(pk, &bccsp.ED25519GoPublicKeyImportOpts{Temporary: true})
+	default:
+		return nil, errors.Errorf("unsupported key type %T", pub)
+	}
+}
+
+func readPassphrase(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("a passphrase file is required")
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading passphrase file %s", path)
+	}
+	passphrase := bytes.TrimRight(raw, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, errors.Errorf("passphrase file %s is empty", path)
+	}
+	return passphrase, nil
+}
+
+func backupCmd() *cobra.Command {
+	var output, passphraseFile, signer string
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the keys of the peer to an encrypted archive.",
+		Long: "Back up the keys of the peer to a single archive encrypted with the passphrase of the passphrase file. " +
+			"The archive holds a manifest of the keys, signed with the key of the --signer SKI, which is verified on restore.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 0); err != nil {
+				return err
+			}
+			if output == "" {
+				return errors.New("the --output file is required")
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Backup(output, passphraseFile, signer)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The file to write the archive to")
+	cmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "The file holding the passphrase encrypting the archive")
+	cmd.Flags().StringVar(&signer, "signer", "", "The SKI of the key signing the manifest of the archive")
+	return cmd
+}
+
+func restoreCmd() *cobra.Command {
+	var passphraseFile string
+	var trusted []string
+	cmd := &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "Restore the keys of the peer from an encrypted archive.",
+		Long: "Restore the keys of an archive written by the backup command, once its manifest and keys are verified. " +
+			"When --trusted files are given, the manifest must be signed by the key of one of these certificates or public keys. " +
+			"Keys already held by the peer are left untouched.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Restore(args[0], passphraseFile, trusted)
+		},
+	}
+	cmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "The file holding the passphrase of the archive")
+	cmd.Flags().StringSliceVar(&trusted, "trusted", nil, "The PEM files of the certificates or public keys trusted to sign the manifest")
+	return cmd
+}
@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import "github.com/spf13/cobra"
+
+func deleteCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "delete <ski>",
+		Short: "Delete a key of the peer.",
+		Long:  "Delete the key with the given hex-encoded SKI from the keystore or token of the peer. The keys of the certificates of the peer are only deleted with --force.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Delete(args[0], force)
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Delete the key even if a certificate of the peer uses it")
+	return cmd
+}
@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import "github.com/spf13/cobra"
+
+func exportCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export <ski>",
+		Short: "Export the public key of a key of the peer.",
+		Long:  "Export the public key of the key with the given hex-encoded SKI in PEM format. Private and symmetric keys never leave the keystore or token.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Export(args[0], output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The file to write the public key to (default stdout)")
+	return cmd
+}
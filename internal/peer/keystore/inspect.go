@@ -0,0 +1,27 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import "github.com/spf13/cobra"
+
+func inspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <ski>",
+		Short: "Inspect a key of the peer.",
+		Long:  "Print the type, algorithm and public key of the key with the given hex-encoded SKI, along with the certificates of the peer using it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Inspect(args[0])
+		},
+	}
+}
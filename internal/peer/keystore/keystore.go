@@ -0,0 +1,397 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/approval"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/bccsp/versions"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var logger = flogging.MustGetLogger("cli.keystore")
+
+// Cmd returns the cobra command for keystore management.
+func Cmd() *cobra.Command {
+	keystoreCmd.AddCommand(listCmd())
+	keystoreCmd.AddCommand(inspectCmd())
+	keystoreCmd.AddCommand(deleteCmd())
+	keystoreCmd.AddCommand(exportCmd())
+	keystoreCmd.AddCommand(pendingCmd())
+	keystoreCmd.AddCommand(approveCmd())
+	keystoreCmd.AddCommand(rotateCmd())
+	keystoreCmd.AddCommand(versionsCmd())
+	keystoreCmd.AddCommand(revokeCmd())
+	keystoreCmd.AddCommand(backupCmd())
+	keystoreCmd.AddCommand(restoreCmd())
+	keystoreCmd.AddCommand(protectCmd())
+	return keystoreCmd
+}
+
+var keystoreCmd = &cobra.Command{
+	Use:   "keystore",
+	Short: "Manage the keys of the peer: list|inspect|delete|export|pending|approve|rotate|versions|revoke|backup|restore|protect.",
+	Long: "Manage the keys held by the crypto provider of the peer, which is either its file " +
+		"keystore or its PKCS#11 token: list|inspect|delete|export|pending|approve|rotate|versions|revoke|backup|restore|protect.",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		common.InitCmd(cmd, args)
+	},
+}
+
+// Keystore manages the keys held by a BCCSP provider.
+type Keystore struct {
+	Provider bccsp.BCCSP
+	// Certificates are the certificates of the peer, whose keys are in use.
+	Certificates []*Certificate
+	Writer       io.Writer
+	// Gate gates the deletion and export of flagged keys behind approvals,
+	// or is nil when no approvals are required.
+	Gate *approval.Gate
+	// Versions are the versions of the named keys, or nil when they are not
+	// managed.
+	Versions *versions.CSP
+}
+
+// Certificate is a certificate of the peer.
+type Certificate struct {
+	Path string
+	Cert *x509.Certificate
+	// SKI identifies the public key of the certificate in the provider.
+	SKI []byte
+}
+
+// newKeystore returns the keystore of the crypto provider configured for the
+// peer, along with the certificates of its local MSP and TLS, the gate of its
+// flagged keys and the versions of its named keys.
+func newKeystore(w io.Writer) (*Keystore, error) {
+	provider := factory.GetDefault()
+
+	paths := []string{
+		config.GetPath("peer.tls.cert.file"),
+		config.GetPath("peer.tls.clientCert.file"),
+	}
+	signcerts := filepath.Join(config.GetPath("peer.mspConfigPath"), "signcerts")
+	if files, err := ioutil.ReadDir(signcerts); err == nil {
+		for _, f := range files {
+			if !f.IsDir() {
+				paths = append(paths, filepath.Join(signcerts, f.Name()))
+			}
+		}
+	}
+
+	gate, err := newGate(provider)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed loading the approval policy of the keys")
+	}
+	keyVersions, err := NewVersions(provider)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed loading the versions of the keys")
+	}
+
+	return &Keystore{
+		Provider:     provider,
+		Certificates: LoadCertificates(provider, paths...),
+		Writer:       w,
+		Gate:         gate,
+		Versions:     keyVersions,
+	}, nil
+}
+
+// LoadCertificates loads the certificates of the PEM files at the given
+// paths. The files which cannot be read are skipped.
+func LoadCertificates(provider bccsp.BCCSP, paths ...string) []*Certificate {
+	var certs []*Certificate
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			logger.Debugf("Skipping certificate %s: %s", path, err)
+			continue
+		}
+		for block, rest := pem.Decode(raw); block != nil; block, rest = pem.Decode(rest) {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				logger.Debugf("Skipping certificate %s: %s", path, err)
+				continue
+			}
+			pk, err := provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+			if err != nil {
+				logger.Debugf("Skipping certificate %s: %s", path, err)
+				continue
+			}
+			certs = append(certs, &Certificate{Path: path, Cert: cert, SKI: pk.SKI()})
+		}
+	}
+	return certs
+}
+
+func (ks *Keystore) keyManager() (bccsp.KeyManager, error) {
+	manager, ok := ks.Provider.(bccsp.KeyManager)
+	if !ok {
+		return nil, errors.New("the crypto provider does not support managing its keys")
+	}
+	if ks.Versions != nil {
+		manager = ks.Versions
+	}
+	if ks.Gate != nil {
+		return &approval.KeyManager{KeyManager: manager, Gate: ks.Gate}, nil
+	}
+	return manager, nil
+}
+
+func (ks *Keystore) certificates(ski []byte) []*Certificate {
+	var certs []*Certificate
+	for _, c := range ks.Certificates {
+		if bytes.Equal(c.SKI, ski) {
+			certs = append(certs, c)
+		}
+	}
+	return certs
+}
+
+func (ks *Keystore) getKey(ski string) (bccsp.Key, error) {
+	raw, err := hex.DecodeString(ski)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.Errorf("invalid SKI %s: must be hex encoded", ski)
+	}
+	k, err := ks.Provider.GetKey(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "key %s not found", ski)
+	}
+	return k, nil
+}
+
+// List writes the SKI, type, algorithm, expiration and version of the keys,
+// along with the subjects of their certificates. When expiring is not zero, only the keys
+// expiring within it are listed.
+func (ks *Keystore) List(expiring time.Duration) error {
+	manager, err := ks.keyManager()
+	if err != nil {
+		return err
+	}
+	keys, err := manager.ListKeys()
+	if err != nil {
+		return errors.WithMessage(err, "failed listing keys")
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i].SKI(), keys[j].SKI()) < 0
+	})
+
+	var lifetimes bccsp.KeyLifetimeManager
+	if expiring != 0 {
+		var ok bool
+		if lifetimes, ok = ks.Provider.(bccsp.KeyLifetimeManager); !ok {
+			return errors.New("the crypto provider does not enforce the lifetime of its keys")
+		}
+	}
+	for _, k := range keys {
+		if lifetimes != nil {
+			notAfter, ok := lifetimes.KeyNotAfter(k.SKI())
+			if !ok || notAfter.After(time.Now().Add(expiring)) {
+				continue
+			}
+		}
+		fmt.Fprintf(ks.Writer, "%x %s %s%s%s\n", k.SKI(), keyType(k), algorithm(k), ks.lifetime(k.SKI()), ks.version(k.SKI()))
+		for _, c := range ks.certificates(k.SKI()) {
+			fmt.Fprintf(ks.Writer, "\t%s (%s)\n", c.Cert.Subject, c.Path)
+		}
+	}
+	return nil
+}
+
+// Inspect writes the details of a key and of its certificates.
+func (ks *Keystore) Inspect(ski string) error {
+	k, err := ks.getKey(ski)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ks.Writer, "SKI: %x\n", k.SKI())
+	fmt.Fprintf(ks.Writer, "Type: %s\n", keyType(k))
+	fmt.Fprintf(ks.Writer, "Algorithm: %s\n", algorithm(k))
+	if manager, ok := ks.Provider.(bccsp.KeyLifetimeManager); ok {
+		if notAfter, ok := manager.KeyNotAfter(k.SKI()); ok {
+			fmt.Fprintf(ks.Writer, "Not after: %s\n", notAfter.Format(time.RFC3339))
+		}
+	}
+	if raw, err := publicKeyPEM(k); err == nil {
+		fmt.Fprintf(ks.Writer, "Public key:\n%s", raw)
+	}
+
+	certs := ks.certificates(k.SKI())
+	if len(certs) == 0 {
+		fmt.Fprintln(ks.Writer, "Certificates: none")
+		return nil
+	}
+	fmt.Fprintln(ks.Writer, "Certificates:")
+	for _, c := range certs {
+		fmt.Fprintf(ks.Writer, "\t%s\n", c.Path)
+		fmt.Fprintf(ks.Writer, "\t\tSubject: %s\n", c.Cert.Subject)
+		fmt.Fprintf(ks.Writer, "\t\tIssuer: %s\n", c.Cert.Issuer)
+		fmt.Fprintf(ks.Writer, "\t\tSerial number: %s\n", c.Cert.SerialNumber)
+		fmt.Fprintf(ks.Writer, "\t\tNot after: %s\n", c.Cert.NotAfter)
+	}
+	return nil
+}
+
+// Delete deletes a key. The keys of the certificates of the peer are only
+// deleted when forced, and flagged keys once approved.
+func (ks *Keystore) Delete(ski string, force bool) error {
+	manager, err := ks.keyManager()
+	if err != nil {
+		return err
+	}
+	k, err := ks.getKey(ski)
+	if err != nil {
+		return err
+	}
+	if certs := ks.certificates(k.SKI()); len(certs) != 0 && !force {
+		return errors.Errorf("key %x is used by the certificate %s, use --force to delete it anyway", k.SKI(), certs[0].Path)
+	}
+
+	if err := manager.DeleteKey(k.SKI()); err != nil {
+		return errors.WithMessagef(err, "failed deleting key %x", k.SKI())
+	}
+	fmt.Fprintf(ks.Writer, "Deleted key %x\n", k.SKI())
+	return nil
+}
+
+// Export writes the public key of a key in PEM format, once approved for
+// flagged keys. Private and symmetric keys never leave the keystore.
+func (ks *Keystore) Export(ski, output string) error {
+	k, err := ks.getKey(ski)
+	if err != nil {
+		return err
+	}
+	raw, err := publicKeyPEM(k)
+	if err != nil {
+		return errors.WithMessagef(err, "failed exporting key %x", k.SKI())
+	}
+	var op *approval.Operation
+	if ks.Gate != nil {
+		if op, err = ks.Gate.Authorize(approval.Export, k.SKI()); err != nil {
+			return err
+		}
+	}
+
+	if output == "" {
+		if _, err = ks.Writer.Write(raw); err != nil {
+			return err
+		}
+		return ks.Gate.Complete(op)
+	}
+	if err := ioutil.WriteFile(output, raw, 0644); err != nil {
+		return errors.Wrapf(err, "failed writing %s", output)
+	}
+	fmt.Fprintf(ks.Writer, "Exported the public key %x to %s\n", k.SKI(), output)
+	return ks.Gate.Complete(op)
+}
+
+// lifetime describes when the key expires, for providers whose keys carry a
+// lifetime
+func (ks *Keystore) lifetime(ski []byte) string {
+	manager, ok := ks.Provider.(bccsp.KeyLifetimeManager)
+	if !ok {
+		return ""
+	}
+	notAfter, ok := manager.KeyNotAfter(ski)
+	if !ok {
+		return ""
+	}
+	if time.Now().After(notAfter) {
+		return " expired " + notAfter.Format(time.RFC3339)
+	}
+	return " expires " + notAfter.Format(time.RFC3339)
+}
+
+func keyType(k bccsp.Key) string {
+	switch {
+	case k.Symmetric():
+		return "symmetric"
+	case k.Private():
+		return "private"
+	default:
+		return "public"
+	}
+}
+
+func algorithm(k bccsp.Key) string {
+	if k.Symmetric() {
+		return "AES"
+	}
+	pub, err := publicKey(k)
+	if err != nil {
+		return "unknown"
+	}
+	switch pk := pub.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pk.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+func publicKey(k bccsp.Key) (interface{}, error) {
+	if k.Symmetric() {
+		return nil, errors.New("symmetric keys have no public key")
+	}
+	pk, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := pk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKIXPublicKey(raw)
+}
+
+func publicKeyPEM(k bccsp.Key) ([]byte, error) {
+	pub, err := publicKey(k)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: raw}), nil
+}
+
+func checkArgs(cmd *cobra.Command, args []string, expected int) error {
+	if len(args) != expected {
+		return errors.Errorf("expected %d argument(s), got %d", expected, len(args))
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+	return nil
+}
@@ -0,0 +1,447 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/approval"
+	"github.com/hyperledger/fabric/bccsp/expiry"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeystore struct {
+	*Keystore
+	output  *bytes.Buffer
+	dir     string
+	peerKey bccsp.Key
+	other   bccsp.Key
+}
+
+func newTestKeystore(t *testing.T) *testKeystore {
+	dir, err := ioutil.TempDir("", "keystore")
+	require.NoError(t, err)
+
+	ks, err := sw.NewFileBasedKeyStore(nil, filepath.Join(dir, "keystore"), false)
+	require.NoError(t, err)
+	provider, err := sw.NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+
+	peerKey, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	other, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{})
+	require.NoError(t, err)
+
+	s, err := signer.New(provider, peerKey)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "peer0.org1.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	require.NoError(t, err)
+	certPath := filepath.Join(dir, "cert.pem")
+	err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	return &testKeystore{
+		Keystore: &Keystore{
+			Provider:     provider,
+			Certificates: LoadCertificates(provider, certPath, filepath.Join(dir, "missing.pem")),
+			Writer:       output,
+		},
+		output:  output,
+		dir:     dir,
+		peerKey: peerKey,
+		other:   other,
+	}
+}
+
+func (tk *testKeystore) cleanup() {
+	os.RemoveAll(tk.dir)
+}
+
+func TestLoadCertificates(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	require.Len(t, tk.Certificates, 1)
+	require.Equal(t, "peer0.org1.example.com", tk.Certificates[0].Cert.Subject.CommonName)
+	require.Equal(t, tk.peerKey.SKI(), tk.Certificates[0].SKI)
+}
+
+func TestList(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	require.NoError(t, tk.List(0))
+	output := tk.output.String()
+	require.Contains(t, output, fmt.Sprintf("%x private ECDSA P-256\n\tCN=peer0.org1.example.com (%s)\n", tk.peerKey.SKI(), filepath.Join(tk.dir, "cert.pem")))
+	require.Contains(t, output, fmt.Sprintf("%x private ECDSA P-384\n", tk.other.SKI()))
+}
+
+func TestListExpiring(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	require.EqualError(t, tk.List(time.Hour), "the crypto provider does not enforce the lifetime of its keys")
+
+	provider, err := expiry.New(tk.Provider, expiry.Opts{Enabled: true})
+	require.NoError(t, err)
+	tk.Provider = provider
+	notAfter := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, provider.SetKeyNotAfter(tk.peerKey.SKI(), notAfter))
+	expired := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, provider.SetKeyNotAfter(tk.other.SKI(), expired))
+
+	require.NoError(t, tk.List(0))
+	output := tk.output.String()
+	require.Contains(t, output, fmt.Sprintf("%x private ECDSA P-256 expires %s\n", tk.peerKey.SKI(), notAfter.Format(time.RFC3339)))
+	require.Contains(t, output, fmt.Sprintf("%x private ECDSA P-384 expired %s\n", tk.other.SKI(), expired.Format(time.RFC3339)))
+
+	tk.output.Reset()
+	require.NoError(t, tk.List(time.Minute))
+	require.Equal(t, fmt.Sprintf("%x private ECDSA P-384 expired %s\n", tk.other.SKI(), expired.Format(time.RFC3339)), tk.output.String())
+
+	tk.output.Reset()
+	require.NoError(t, tk.Inspect(hex.EncodeToString(tk.peerKey.SKI())))
+	require.Contains(t, tk.output.String(), "Algorithm: ECDSA P-256\nNot after: "+notAfter.Format(time.RFC3339)+"\n")
+}
+
+func TestInspect(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	require.NoError(t, tk.Inspect(hex.EncodeToString(tk.peerKey.SKI())))
+	output := tk.output.String()
+	require.Contains(t, output, fmt.Sprintf("SKI: %x\nType: private\nAlgorithm: ECDSA P-256\nPublic key:\n-----BEGIN PUBLIC KEY-----\n", tk.peerKey.SKI()))
+	require.Contains(t, output, "Subject: CN=peer0.org1.example.com\n")
+	require.Contains(t, output, "Serial number: 42\n")
+
+	tk.output.Reset()
+	require.NoError(t, tk.Inspect(hex.EncodeToString(tk.other.SKI())))
+	require.Contains(t, tk.output.String(), "Certificates: none\n")
+
+	require.EqualError(t, tk.Inspect("not-hex"), "invalid SKI not-hex: must be hex encoded")
+	err := tk.Inspect("0123")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "key 0123 not found")
+}
+
+func TestDelete(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	peerSKI := hex.EncodeToString(tk.peerKey.SKI())
+	err := tk.Delete(peerSKI, false)
+	require.EqualError(t, err, fmt.Sprintf("key %s is used by the certificate %s, use --force to delete it anyway", peerSKI, filepath.Join(tk.dir, "cert.pem")))
+
+	otherSKI := hex.EncodeToString(tk.other.SKI())
+	require.NoError(t, tk.Delete(otherSKI, false))
+	require.Equal(t, fmt.Sprintf("Deleted key %s\n", otherSKI), tk.output.String())
+	_, err = tk.Provider.GetKey(tk.other.SKI())
+	require.Error(t, err)
+
+	require.NoError(t, tk.Delete(peerSKI, true))
+	_, err = tk.Provider.GetKey(tk.peerKey.SKI())
+	require.Error(t, err)
+}
+
+func TestExport(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	pk, err := tk.peerKey.PublicKey()
+	require.NoError(t, err)
+	der, err := pk.Bytes()
+	require.NoError(t, err)
+	expected := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	ski := hex.EncodeToString(tk.peerKey.SKI())
+	require.NoError(t, tk.Export(ski, ""))
+	require.Equal(t, expected, tk.output.Bytes())
+
+	output := filepath.Join(tk.dir, "exported.pem")
+	require.NoError(t, tk.Export(ski, output))
+	exported, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, expected, exported)
+}
+
+type approver struct {
+	provider bccsp.BCCSP
+	key      bccsp.Key
+	cert     *x509.Certificate
+	pem      []byte
+}
+
+func newApprover(t *testing.T, provider bccsp.BCCSP, name string) *approver {
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	s, err := signer.New(provider, k)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &approver{
+		provider: provider,
+		key:      k,
+		cert:     cert,
+		pem:      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (a *approver) Sign(msg []byte) ([]byte, error) {
+	digest, err := a.provider.Hash(msg, &bccsp.SHA256Opts{})
+	if err != nil {
+		return nil, err
+	}
+	return a.provider.Sign(a.key, digest, nil)
+}
+
+func (a *approver) Serialize() ([]byte, error) {
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: a.pem})
+}
+
+func TestApproval(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+
+	alice, bob, mallory := newApprover(t, tk.Provider, "alice"), newApprover(t, tk.Provider, "bob"), newApprover(t, tk.Provider, "mallory")
+	gate, err := approval.New(tk.Provider, approval.Policy{
+		Keys:      [][]byte{tk.other.SKI()},
+		Approvers: []*x509.Certificate{alice.cert, bob.cert},
+		Required:  2,
+	}, filepath.Join(tk.dir, "approvals"))
+	require.NoError(t, err)
+	tk.Gate = gate
+
+	// the keys which are not flagged are exported right away
+	require.NoError(t, tk.Export(hex.EncodeToString(tk.peerKey.SKI()), ""))
+	require.Contains(t, tk.output.String(), "-----BEGIN PUBLIC KEY-----")
+
+	otherSKI := hex.EncodeToString(tk.other.SKI())
+	err = tk.Delete(otherSKI, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "delete of key "+otherSKI+" requires the approval of 2 approvers and has 0")
+	_, err = tk.Provider.GetKey(tk.other.SKI())
+	require.NoError(t, err)
+
+	ops, err := gate.Pending()
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	id := ops[0].ID
+
+	tk.output.Reset()
+	require.NoError(t, tk.Approve(id, alice))
+	require.Equal(t, "Approved the delete of key "+otherSKI+": 1 of 2 approvals\n", tk.output.String())
+	require.EqualError(t, tk.Approve(id, mallory), "CN=mallory is not an approver")
+
+	tk.output.Reset()
+	require.NoError(t, tk.Pending())
+	lines := strings.Split(tk.output.String(), "\n")
+	require.True(t, strings.HasPrefix(lines[0], id+" delete "+otherSKI+" requested "))
+	require.True(t, strings.HasSuffix(lines[0], " approvals 1/2"))
+	require.Equal(t, "\tCN=alice", lines[1])
+
+	require.NoError(t, tk.Approve(id, bob))
+	require.NoError(t, tk.Delete(otherSKI, false))
+	_, err = tk.Provider.GetKey(tk.other.SKI())
+	require.Error(t, err)
+	ops, err = gate.Pending()
+	require.NoError(t, err)
+	require.Empty(t, ops)
+}
+
+func TestNewGate(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+	defer viper.Reset()
+
+	gate, err := newGate(tk.Provider)
+	require.NoError(t, err)
+	require.Nil(t, gate)
+
+	alice := newApprover(t, tk.Provider, "alice")
+	certPath := filepath.Join(tk.dir, "alice.pem")
+	require.NoError(t, ioutil.WriteFile(certPath, alice.pem, 0644))
+	viper.Set("peer.keystoreApproval.required", 1)
+	viper.Set("peer.keystoreApproval.keys", []string{hex.EncodeToString(tk.other.SKI())})
+	viper.Set("peer.keystoreApproval.approvers", []string{certPath})
+	viper.Set("peer.keystoreApproval.queue", filepath.Join(tk.dir, "approvals"))
+	gate, err = newGate(tk.Provider)
+	require.NoError(t, err)
+	require.True(t, gate.Flagged(tk.other.SKI()))
+	require.False(t, gate.Flagged(tk.peerKey.SKI()))
+	require.Equal(t, 1, gate.Required())
+
+	viper.Set("peer.keystoreApproval.keys", []string{"not-hex"})
+	_, err = newGate(tk.Provider)
+	require.EqualError(t, err, "invalid flagged key not-hex: must be a hex encoded SKI")
+
+	viper.Set("peer.keystoreApproval.keys", nil)
+	viper.Set("peer.keystoreApproval.approvers", []string{filepath.Join(tk.dir, "missing.pem")})
+	_, err = newGate(tk.Provider)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed reading approver certificate")
+}
+
+func TestVersions(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+	defer viper.Reset()
+
+	require.EqualError(t, tk.Rotate("release", "ECDSA-P256", ""), "the versions of the keys are not managed")
+	viper.Set("peer.keystoreVersions.file", filepath.Join(tk.dir, "keyversions.json"))
+	keyVersions, err := NewVersions(tk.Provider)
+	require.NoError(t, err)
+	tk.Versions = keyVersions
+
+	otherSKI := hex.EncodeToString(tk.other.SKI())
+	require.NoError(t, tk.Rotate("release", "", otherSKI))
+	require.Equal(t, fmt.Sprintf("Key release is at version 1: %s\n", otherSKI), tk.output.String())
+	require.NoError(t, tk.Rotate("release", "ed25519", ""))
+	l, err := tk.Versions.Lineage("release")
+	require.NoError(t, err)
+	require.Len(t, l.Versions, 2)
+	require.EqualError(t, tk.Rotate("release", "RSA", ""), "unsupported algorithm RSA: must be ECDSA-P256, ECDSA-P384 or Ed25519")
+
+	tk.output.Reset()
+	require.NoError(t, tk.ListVersions(""))
+	output := tk.output.String()
+	require.Contains(t, output, fmt.Sprintf("release\n\t1 %s retired created ", otherSKI))
+	require.Contains(t, output, fmt.Sprintf("\t2 %x active created ", l.Versions[1].SKI))
+
+	tk.output.Reset()
+	require.NoError(t, tk.List(0))
+	require.Contains(t, tk.output.String(), fmt.Sprintf("%s private ECDSA P-384 release/1 retired\n", otherSKI))
+	require.Contains(t, tk.output.String(), fmt.Sprintf("%x private Ed25519 release/2 active\n", l.Versions[1].SKI))
+
+	tk.output.Reset()
+	require.EqualError(t, tk.Revoke("release", "two"), "invalid version two: must be a number")
+	require.EqualError(t, tk.Revoke("release", "2"), "version 2 of key release is active: rotate the key before revoking it")
+	require.NoError(t, tk.Revoke("release", "1"))
+	require.Equal(t, "Revoked version 1 of key release\n", tk.output.String())
+
+	// the active version of a named key is not deleted
+	err = tk.Delete(hex.EncodeToString(l.Versions[1].SKI), false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "rotate the key before deleting it")
+	require.NoError(t, tk.Delete(otherSKI, false))
+}
+
+func TestApprovalNotRequired(t *testing.T) {
+	ks := &Keystore{}
+	require.EqualError(t, ks.Pending(), "no approvals are required for the operations on the keys")
+	require.EqualError(t, ks.Approve("00", nil), "no approvals are required for the operations on the keys")
+}
+
+func TestUnsupportedProvider(t *testing.T) {
+	ks := &Keystore{Provider: struct{ bccsp.BCCSP }{}}
+	require.EqualError(t, ks.List(0), "the crypto provider does not support managing its keys")
+	require.EqualError(t, ks.Delete("0123", false), "the crypto provider does not support managing its keys")
+}
+
+func TestBackupRestore(t *testing.T) {
+	tk := newTestKeystore(t)
+	defer tk.cleanup()
+	passphrase := filepath.Join(tk.dir, "passphrase")
+	require.NoError(t, ioutil.WriteFile(passphrase, []byte("passphrase\n"), 0600))
+	archive := filepath.Join(tk.dir, "backup.json")
+	signerSKI := hex.EncodeToString(tk.peerKey.SKI())
+
+	require.EqualError(t, tk.Backup(archive, "", signerSKI), "a passphrase file is required")
+	require.NoError(t, tk.Backup(archive, passphrase, signerSKI))
+	require.Equal(t, fmt.Sprintf("Backed up 2 keys to %s, signed by key %s\n", archive, signerSKI), tk.output.String())
+
+	target := newTestKeystore(t)
+	defer target.cleanup()
+	_, err := target.Provider.GetKey(tk.other.SKI())
+	require.Error(t, err)
+
+	other := filepath.Join(tk.dir, "other.pem")
+	require.NoError(t, target.Export(hex.EncodeToString(target.peerKey.SKI()), other))
+	err = target.Restore(archive, passphrase, []string{other})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "which is not trusted")
+
+	target.output.Reset()
+	require.NoError(t, target.Restore(archive, passphrase, []string{filepath.Join(tk.dir, "cert.pem")}))
+	output := target.output.String()
+	require.Contains(t, output, fmt.Sprintf(", signed by key %s:\n", signerSKI))
+	require.Contains(t, output, fmt.Sprintf("%s private ECDSA\n", signerSKI))
+	require.Contains(t, output, fmt.Sprintf("%x private ECDSA\n", tk.other.SKI()))
+	restored, err := target.Provider.GetKey(tk.other.SKI())
+	require.NoError(t, err)
+	require.True(t, restored.Private())
+
+	ks := &Keystore{Provider: struct{ bccsp.BCCSP }{}}
+	require.EqualError(t, ks.Backup(archive, passphrase, signerSKI), "the crypto provider does not support backups")
+	require.EqualError(t, ks.Restore(archive, passphrase, nil), "the crypto provider does not support backups")
+}
+
+func TestProtect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "protect")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keystore")
+	integrityKey := filepath.Join(dir, "integrity.key")
+
+	// the keys stored before the integrity key was configured
+	ks, err := sw.NewFileBasedKeyStore(nil, path, false)
+	require.NoError(t, err)
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{})
+	require.NoError(t, err)
+	macKey, err := sw.LoadIntegrityKey(integrityKey)
+	require.NoError(t, err)
+	_, err = sw.NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	require.Error(t, err)
+
+	output := &bytes.Buffer{}
+	require.EqualError(t, Protect(output, path, ""), "no integrity key is configured for the keystore")
+	require.NoError(t, Protect(output, path, integrityKey))
+	require.Equal(t, fmt.Sprintf("Protected key file %x_sk\nProtected 1 key files of %s\n", k.SKI(), path), output.String())
+	ks, err = sw.NewFileBasedKeyStoreWithIntegrity(nil, path, false, macKey)
+	require.NoError(t, err)
+	_, err = ks.GetKey(k.SKI())
+	require.NoError(t, err)
+
+	output.Reset()
+	require.NoError(t, Protect(output, path, integrityKey))
+	require.Equal(t, fmt.Sprintf("Protected 0 key files of %s\n", path), output.String())
+}
@@ -0,0 +1,34 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func listCmd() *cobra.Command {
+	var expiring time.Duration
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the keys of the peer.",
+		Long:  "List the SKI, type, algorithm, expiration and version of the keys of the peer, along with the certificates of the peer using them.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 0); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.List(expiring)
+		},
+	}
+	cmd.Flags().DurationVar(&expiring, "expiring", 0, "Only list the keys expiring within the given duration, such as 720h")
+	return cmd
+}
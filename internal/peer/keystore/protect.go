@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func protectCmd() *cobra.Command {
+	var keystorePath, integrityKeyPath string
+	cmd := &cobra.Command{
+		Use:   "protect",
+		Short: "Give a MAC to the key files of the peer without one.",
+		Long: "Give a MAC keyed by the integrity key of the file keystore of the peer to its key files which have none, " +
+			"such as those stored before peer.BCCSP.SW.FileKeyStore.IntegrityKey was set. The peer refuses to start " +
+			"while a key file has no MAC. Nothing is written when the MAC of a key file does not match its content.",
+		// the crypto provider of the peer does not open the keystore while
+		// key files have no MAC, so only the configuration is loaded
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return common.InitConfig(common.CmdRoot)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 0); err != nil {
+				return err
+			}
+			if keystorePath == "" {
+				keystorePath = config.GetPath("peer.BCCSP.SW.FileKeyStore.KeyStore")
+			}
+			if keystorePath == "" {
+				keystorePath = filepath.Join(config.GetPath("peer.mspConfigPath"), "keystore")
+			}
+			if integrityKeyPath == "" {
+				integrityKeyPath = viper.GetString("peer.BCCSP.SW.FileKeyStore.IntegrityKey")
+			}
+			return Protect(cmd.OutOrStdout(), keystorePath, integrityKeyPath)
+		},
+	}
+	cmd.Flags().StringVar(&keystorePath, "keystore", "", "The keystore folder, the one of the peer by default")
+	cmd.Flags().StringVar(&integrityKeyPath, "integrity-key", "", "The integrity key of the keystore, the one of the peer by default")
+	return cmd
+}
+
+// Protect gives a MAC keyed by the integrity key at integrityKeyPath to the
+// key files of the file keystore at path which have none. It is run once,
+// when the integrity key is first configured for a keystore holding keys.
+func Protect(w io.Writer, path, integrityKeyPath string) error {
+	if integrityKeyPath == "" {
+		return errors.New("no integrity key is configured for the keystore")
+	}
+	key, err := sw.LoadIntegrityKey(integrityKeyPath)
+	if err != nil {
+		return err
+	}
+	protected, err := sw.ProtectKeyFiles(path, key)
+	if err != nil {
+		return errors.WithMessagef(err, "failed protecting the key files of %s", path)
+	}
+	for _, name := range protected {
+		fmt.Fprintf(w, "Protected key file %s\n", name)
+	}
+	fmt.Fprintf(w, "Protected %d key files of %s\n", len(protected), path)
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/versions"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewVersions returns the versions of the named keys of the provider, kept in
+// the file configured in peer.keystoreVersions.file.
+func NewVersions(provider bccsp.BCCSP) (*versions.CSP, error) {
+	path := config.GetPath("peer.keystoreVersions.file")
+	if path == "" {
+		path = filepath.Join(config.GetPath("peer.fileSystemPath"), "keyversions.json")
+	}
+	return versions.New(provider, path)
+}
+
+// Rotate adds a new version to the named key, which becomes the version
+// signing. The new version is generated with the given algorithm, unless the
+// SKI of an existing key is passed.
+func (ks *Keystore) Rotate(name, algorithm, ski string) error {
+	if ks.Versions == nil {
+		return errors.New("the versions of the keys are not managed")
+	}
+
+	var v *versions.Version
+	if ski != "" {
+		k, err := ks.getKey(ski)
+		if err != nil {
+			return err
+		}
+		if v, err = ks.Versions.AddVersion(name, k.SKI()); err != nil {
+			return err
+		}
+	} else {
+		opts, err := keyGenOpts(algorithm)
+		if err != nil {
+			return err
+		}
+		if v, err = ks.Versions.Rotate(name, opts); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(ks.Writer, "Key %s is at version %d: %x\n", name, v.Number, v.SKI)
+	return nil
+}
+
+// ListVersions writes the versions of the named key, or of all the named keys
+// if name is empty.
+func (ks *Keystore) ListVersions(name string) error {
+	if ks.Versions == nil {
+		return errors.New("the versions of the keys are not managed")
+	}
+	names := []string{name}
+	if name == "" {
+		names = ks.Versions.Names()
+	}
+	for _, name := range names {
+		l, err := ks.Versions.Lineage(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(ks.Writer, l.Name)
+		for _, v := range l.Versions {
+			fmt.Fprintf(ks.Writer, "\t%d %x %s created %s\n", v.Number, v.SKI, v.State, v.Created.Format("2006-01-02T15:04:05Z"))
+		}
+	}
+	return nil
+}
+
+// Revoke revokes a version of the named key, whose signatures are no longer
+// accepted.
+func (ks *Keystore) Revoke(name, version string) error {
+	if ks.Versions == nil {
+		return errors.New("the versions of the keys are not managed")
+	}
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		return errors.Errorf("invalid version %s: must be a number", version)
+	}
+	if err := ks.Versions.Revoke(name, number); err != nil {
+		return err
+	}
+	fmt.Fprintf(ks.Writer, "Revoked version %d of key %s\n", number, name)
+	return nil
+}
+
+// version describes the named key the key is a version of, if any
+func (ks *Keystore) version(ski []byte) string {
+	if ks.Versions == nil {
+		return ""
+	}
+	for _, name := range ks.Versions.Names() {
+		l, err := ks.Versions.Lineage(name)
+		if err != nil {
+			continue
+		}
+		for _, v := range l.Versions {
+			if bytes.Equal(v.SKI, ski) {
+				return fmt.Sprintf(" %s/%d %s", name, v.Number, v.State)
+			}
+		}
+	}
+	return ""
+}
+
+func keyGenOpts(algorithm string) (bccsp.KeyGenOpts, error) {
+	switch strings.ToUpper(algorithm) {
+	case "ECDSA-P256":
+		return &bccsp.ECDSAP256KeyGenOpts{}, nil
+	case "ECDSA-P384":
+		return &bccsp.ECDSAP384KeyGenOpts{}, nil
+	case "ED25519":
+		return &bccsp.ED25519KeyGenOpts{}, nil
+	default:
+		return nil, errors.Errorf("unsupported algorithm %s: must be ECDSA-P256, ECDSA-P384 or Ed25519", algorithm)
+	}
+}
+
+func rotateCmd() *cobra.Command {
+	var algorithm, ski string
+	cmd := &cobra.Command{
+		Use:   "rotate <name>",
+		Short: "Add a new version to a named key of the peer.",
+		Long: "Generate a new version of the named key, or add the existing key with the given SKI as its new version. " +
+			"The new version signs, whereas the signatures of the previous versions are still accepted until revoked.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Rotate(args[0], algorithm, ski)
+		},
+	}
+	cmd.Flags().StringVar(&algorithm, "algorithm", "ECDSA-P256", "The algorithm of the new version: ECDSA-P256, ECDSA-P384 or Ed25519")
+	cmd.Flags().StringVar(&ski, "ski", "", "The hex-encoded SKI of an existing key to add as the new version")
+	return cmd
+}
+
+func versionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "versions [<name>]",
+		Short: "List the versions of the named keys of the peer.",
+		Long:  "List the number, SKI, state and creation time of the versions of the named key, or of all the named keys.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.Errorf("expected at most 1 argument, got %d", len(args))
+			}
+			cmd.SilenceUsage = true
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			var name string
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return ks.ListVersions(name)
+		},
+	}
+}
+
+func revokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <name> <version>",
+		Short: "Revoke a version of a named key of the peer.",
+		Long:  "Revoke a retired version of the named key, whose signatures are no longer accepted. The active version must be rotated before being revoked.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 2); err != nil {
+				return err
+			}
+			ks, err := newKeystore(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return ks.Revoke(args[0], args[1])
+		},
+	}
+}
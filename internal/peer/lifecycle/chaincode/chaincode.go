@@ -62,6 +62,8 @@ var (
 	channelID             string
 	chaincodeVersion      string
 	packageLabel          string
+	signingCertFile       string
+	signingKeyFile        string
 	signaturePolicy       string
 	channelConfigPolicy   string
 	endorsementPlugin     string
@@ -104,9 +106,11 @@ func ResetFlags() {
 	flags.StringVarP(&chaincodeName, "name", "n", "", "Name of the chaincode")
 	flags.StringVarP(&chaincodeVersion, "version", "v", "", "Version of the chaincode")
 	flags.StringVarP(&packageLabel, "label", "", "", "The package label contains a human-readable description of the package")
+	flags.StringVarP(&signingCertFile, "signing-cert", "", "", "The path to the PEM encoded certificate of the signer of the chaincode package. Must be specified along with --signing-key")
+	flags.StringVarP(&signingKeyFile, "signing-key", "", "", "The path to the PEM encoded private key signing the chaincode package. Must be specified along with --signing-cert")
 	flags.StringVarP(&channelID, "channelID", "C", "", "The channel on which this command should be executed")
 	flags.StringVarP(&signaturePolicy, "signature-policy", "", "", "The endorsement policy associated to this chaincode specified as a signature policy")
-	flags.StringVarP(&channelConfigPolicy, "channel-config-policy", "", "", "The endorsement policy associated to this chaincode specified as a channel config policy reference")
+	flags.StringVarP(&channelConfigPolicy, "channel-config-policy", "", "", "The endorsement policy associated to this chaincode specified as a channel config policy reference, or as 'template:' followed by a policy referencing the policy templates of the channel")
 	flags.StringVarP(&endorsementPlugin, "endorsement-plugin", "E", "",
 		fmt.Sprint("The name of the endorsement plugin to be used for this chaincode"))
 	flags.StringVarP(&validationPlugin, "validation-plugin", "V", "",
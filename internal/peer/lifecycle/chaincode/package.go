@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
 
@@ -43,6 +44,10 @@ type PackageInput struct {
 	Path       string
 	Type       string
 	Label      string
+	// SigningCertFile and SigningKeyFile are the PEM encoded
+	// certificate and private key signing the package, if any
+	SigningCertFile string
+	SigningKeyFile  string
 }
 
 // Validate checks for the required inputs
@@ -62,6 +67,9 @@ func (p *PackageInput) Validate() error {
 	if err := persistence.ValidateLabel(p.Label); err != nil {
 		return err
 	}
+	if (p.SigningCertFile == "") != (p.SigningKeyFile == "") {
+		return errors.New("both the signing certificate and the signing key must be specified to sign the package")
+	}
 
 	return nil
 }
@@ -91,6 +99,8 @@ func PackageCmd(p *Packager) *cobra.Command {
 		"label",
 		"lang",
 		"path",
+		"signing-cert",
+		"signing-key",
 		"peerAddresses",
 		"tlsRootCertFiles",
 		"connectionProfile",
@@ -121,6 +131,9 @@ func (p *Packager) setInput(outputFile string) {
 		Path:       chaincodePath,
 		Type:       chaincodeLang,
 		Label:      packageLabel,
+
+		SigningCertFile: signingCertFile,
+		SigningKeyFile:  signingKeyFile,
 	}
 }
 
@@ -183,6 +196,17 @@ func (p *Packager) getTarGzBytes() ([]byte, error) {
 		return nil, errors.Wrap(err, "error writing package code bytes to tar")
 	}
 
+	if p.Input.SigningCertFile != "" {
+		signatureBytes, err := p.sign(metadataBytes, codeBytes)
+		if err != nil {
+			return nil, err
+		}
+		err = writeBytesToPackage(tw, persistence.SignatureFile, signatureBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "error writing package signature to tar")
+		}
+	}
+
 	err = tw.Close()
 	if err == nil {
 		err = gw.Close()
@@ -194,6 +218,25 @@ func (p *Packager) getTarGzBytes() ([]byte, error) {
 	return payload.Bytes(), nil
 }
 
+// sign returns the signature of the package with the given metadata and code
+func (p *Packager) sign(metadataBytes, codeBytes []byte) ([]byte, error) {
+	certPEM, err := ioutil.ReadFile(p.Input.SigningCertFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read signing certificate")
+	}
+	keyPEM, err := ioutil.ReadFile(p.Input.SigningKeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read signing key")
+	}
+
+	signatureBytes, err := persistence.SignChaincodePackage(metadataBytes, codeBytes, certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to sign chaincode package")
+	}
+
+	return signatureBytes, nil
+}
+
 func writeBytesToPackage(tw *tar.Writer, name string, payload []byte) error {
 	err := tw.WriteHeader(&tar.Header{
 		Name: name,
@@ -15,6 +15,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/core/chaincode/persistence"
 	"github.com/hyperledger/fabric/internal/peer/lifecycle/chaincode"
 	"github.com/hyperledger/fabric/internal/peer/lifecycle/chaincode/mock"
 	"github.com/pkg/errors"
@@ -157,6 +159,85 @@ var _ = Describe("Package", func() {
 			})
 		})
 
+		Context("when the package is signed", func() {
+			var (
+				tempDir string
+				ca      tlsgen.CA
+			)
+
+			BeforeEach(func() {
+				var err error
+				tempDir, err = ioutil.TempDir("", "package-signing")
+				Expect(err).NotTo(HaveOccurred())
+
+				ca, err = tlsgen.NewCA()
+				Expect(err).NotTo(HaveOccurred())
+				signer, err := ca.NewClientCertKeyPair()
+				Expect(err).NotTo(HaveOccurred())
+
+				input.SigningCertFile = filepath.Join(tempDir, "cert.pem")
+				input.SigningKeyFile = filepath.Join(tempDir, "key.pem")
+				Expect(ioutil.WriteFile(input.SigningCertFile, signer.Cert, 0600)).To(Succeed())
+				Expect(ioutil.WriteFile(input.SigningKeyFile, signer.Key, 0600)).To(Succeed())
+
+				mockPlatformRegistry.GetDeploymentPayloadReturns([]byte("code"), nil)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(tempDir)
+			})
+
+			It("writes a signature which verifies against the signing root", func() {
+				err := packager.Package()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(mockWriter.WriteFileCallCount()).To(Equal(1))
+				_, _, pkgTarGzBytes := mockWriter.WriteFileArgsForCall(0)
+				pkg, err := persistence.ChaincodePackageParser{MetadataProvider: noDBArtifacts{}}.Parse(pkgTarGzBytes)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pkg.Signature).NotTo(BeNil())
+
+				verifier, err := persistence.NewSignatureVerifier([][]byte{ca.CertBytes()}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(pkg)).To(Succeed())
+			})
+
+			Context("when the signing key is not provided", func() {
+				BeforeEach(func() {
+					input.SigningKeyFile = ""
+				})
+
+				It("returns an error", func() {
+					err := packager.Package()
+					Expect(err).To(MatchError("both the signing certificate and the signing key must be specified to sign the package"))
+				})
+			})
+
+			Context("when the signing key cannot be read", func() {
+				BeforeEach(func() {
+					input.SigningKeyFile = filepath.Join(tempDir, "missing.pem")
+				})
+
+				It("returns an error", func() {
+					err := packager.Package()
+					Expect(err).To(MatchError(ContainSubstring("failed to read signing key")))
+				})
+			})
+
+			Context("when the signing key does not match the certificate", func() {
+				BeforeEach(func() {
+					other, err := ca.NewClientCertKeyPair()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ioutil.WriteFile(input.SigningKeyFile, other.Key, 0600)).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					err := packager.Package()
+					Expect(err).To(MatchError(ContainSubstring("failed to sign chaincode package: private key does not match the signing certificate")))
+				})
+			})
+		})
+
 		Context("when writing the file fails", func() {
 			BeforeEach(func() {
 				mockWriter.WriteFileReturns(errors.New("espresso"))
@@ -218,6 +299,12 @@ var _ = Describe("Package", func() {
 	})
 })
 
+type noDBArtifacts struct{}
+
+func (noDBArtifacts) GetDBArtifacts([]byte) ([]byte, error) {
+	return nil, nil
+}
+
 func readMetadataFromBytes(pkgTarGzBytes []byte) ([]byte, error) {
 	buffer := bytes.NewBuffer(pkgTarGzBytes)
 	gzr, err := gzip.NewReader(buffer)
@@ -11,6 +11,7 @@ import (
 
 	coreconfig "github.com/hyperledger/fabric/core/config"
 	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
@@ -41,24 +42,69 @@ func ledgerConfig() *ledger.Config {
 		purgeInterval = viper.GetInt("ledger.pvtdataStore.purgeInterval")
 	}
 
+	var collectionTTLs []ledger.CollectionTTL
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     &collectionTTLs,
+	})
+	if err == nil {
+		err = decoder.Decode(viper.Get("ledger.pvtdataStore.timeToLive.collections"))
+	}
+	if err != nil {
+		logger.Panicf("Invalid ledger.pvtdataStore.timeToLive.collections: %s", err)
+	}
+
 	rootFSPath := filepath.Join(coreconfig.GetPath("peer.fileSystemPath"), "ledgersData")
 	conf := &ledger.Config{
 		RootFSPath: rootFSPath,
 		StateDBConfig: &ledger.StateDBConfig{
 			StateDatabase: viper.GetString("ledger.state.stateDatabase"),
 			CouchDB:       &ledger.CouchDBConfig{},
+			Encryption: &ledger.StateEncryptionConfig{
+				KeySKIs:    viper.GetStringSlice("ledger.state.encryption.keySKIs"),
+				Namespaces: viper.GetStringSlice("ledger.state.encryption.namespaces"),
+			},
 		},
 		PrivateDataConfig: &ledger.PrivateDataConfig{
 			MaxBatchSize:    collElgProcMaxDbBatchSize,
 			BatchesInterval: collElgProcDbBatchesInterval,
 			PurgeInterval:   purgeInterval,
+			TimeToLive: &ledger.PvtDataTTLConfig{
+				Collections:   collectionTTLs,
+				CheckInterval: viper.GetDuration("ledger.pvtdataStore.timeToLive.checkInterval"),
+			},
 		},
 		HistoryDBConfig: &ledger.HistoryDBConfig{
 			Enabled: viper.GetBool("ledger.history.enableHistoryDatabase"),
+			Prune: &ledger.HistoryPruneConfig{
+				RetainBlocks:    uint64(viper.GetInt("ledger.history.prune.retainBlocks")),
+				RetentionPeriod: viper.GetDuration("ledger.history.prune.retentionPeriod"),
+				Interval:        uint64(viper.GetInt("ledger.history.prune.interval")),
+			},
+		},
+		BlockStoreConfig: &ledger.BlockStoreConfig{
+			Compression: viper.GetString("ledger.blockchain.compression"),
+			Archive: &ledger.BlockArchiveConfig{
+				Path:         viper.GetString("ledger.blockchain.archive.path"),
+				RetainBlocks: uint64(viper.GetInt("ledger.blockchain.archive.retainBlocks")),
+				CacheSize:    viper.GetInt("ledger.blockchain.archive.cacheSize"),
+			},
 		},
+		StateCheckpointConfig: &ledger.StateCheckpointConfig{
+			Interval:        uint64(viper.GetInt("ledger.state.checkpoint.interval")),
+			Schedule:        viper.GetString("ledger.state.checkpoint.schedule"),
+			Retain:          viper.GetInt("ledger.state.checkpoint.retain"),
+			RetentionPeriod: viper.GetDuration("ledger.state.checkpoint.retentionPeriod"),
+		},
+	}
+
+	if couchDBNamespaces := viper.GetStringMapStringSlice("ledger.state.couchDBNamespaces"); len(couchDBNamespaces) > 0 {
+		conf.StateDBConfig.CouchDBNamespaces = couchDBNamespaces
 	}
 
-	if conf.StateDBConfig.StateDatabase == "CouchDB" {
+	conf.StateDBConfig.MigrationTarget = viper.GetString("ledger.state.migration.targetDatabase")
+
+	if conf.StateDBConfig.StateDatabase == "CouchDB" || len(conf.StateDBConfig.CouchDBNamespaces) > 0 || conf.StateDBConfig.MigrationTarget == "CouchDB" {
 		conf.StateDBConfig.CouchDB = &ledger.CouchDBConfig{
 			Address:                 viper.GetString("ledger.state.couchDBConfig.couchDBAddress"),
 			Username:                viper.GetString("ledger.state.couchDBConfig.username"),
@@ -72,6 +118,16 @@ func ledgerConfig() *ledger.Config {
 			CreateGlobalChangesDB:   viper.GetBool("ledger.state.couchDBConfig.createGlobalChangesDB"),
 			RedoLogPath:             filepath.Join(rootFSPath, "couchdbRedoLogs"),
 			UserCacheSizeMBs:        viper.GetInt("ledger.state.couchDBConfig.cacheSize"),
+			SysCacheSizeMBs:         viper.GetInt("ledger.state.couchDBConfig.systemCacheSize"),
+			CacheEvictionPolicy:     viper.GetString("ledger.state.couchDBConfig.cacheEvictionPolicy"),
+			PinnedCacheNamespaces:   viper.GetStringSlice("ledger.state.couchDBConfig.pinnedCacheNamespaces"),
+			UncachedNamespaces:      viper.GetStringSlice("ledger.state.couchDBConfig.uncachedNamespaces"),
+			MaxIdleConnections:      viper.GetInt("ledger.state.couchDBConfig.maxIdleConnections"),
+			MaxConnections:          viper.GetInt("ledger.state.couchDBConfig.maxConnections"),
+			MaxRetryBackoff:         viper.GetDuration("ledger.state.couchDBConfig.maxRetryBackoff"),
+			RetryBudgetRatio:        viper.GetFloat64("ledger.state.couchDBConfig.retryBudgetRatio"),
+			CircuitBreakerThreshold: viper.GetInt("ledger.state.couchDBConfig.circuitBreaker.threshold"),
+			CircuitBreakerTimeout:   viper.GetDuration("ledger.state.couchDBConfig.circuitBreaker.timeout"),
 		}
 	}
 	return conf
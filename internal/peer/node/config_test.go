@@ -16,6 +16,7 @@ import (
 )
 
 func TestLedgerConfig(t *testing.T) {
+	defer viper.Reset()
 	defer viper.Set("ledger.state.stateDatabase", "goleveldb")
 	var tests = []struct {
 		name     string
@@ -33,15 +34,22 @@ func TestLedgerConfig(t *testing.T) {
 				StateDBConfig: &ledger.StateDBConfig{
 					StateDatabase: "goleveldb",
 					CouchDB:       &ledger.CouchDBConfig{},
+					Encryption:    &ledger.StateEncryptionConfig{},
 				},
 				PrivateDataConfig: &ledger.PrivateDataConfig{
 					MaxBatchSize:    5000,
 					BatchesInterval: 1000,
 					PurgeInterval:   100,
+					TimeToLive:      &ledger.PvtDataTTLConfig{},
 				},
 				HistoryDBConfig: &ledger.HistoryDBConfig{
 					Enabled: false,
+					Prune:   &ledger.HistoryPruneConfig{},
 				},
+				BlockStoreConfig: &ledger.BlockStoreConfig{
+					Archive: &ledger.BlockArchiveConfig{},
+				},
+				StateCheckpointConfig: &ledger.StateCheckpointConfig{},
 			},
 		},
 		{
@@ -76,37 +84,69 @@ func TestLedgerConfig(t *testing.T) {
 						RedoLogPath:             "/peerfs/ledgersData/couchdbRedoLogs",
 						UserCacheSizeMBs:        64,
 					},
+					Encryption: &ledger.StateEncryptionConfig{},
 				},
 				PrivateDataConfig: &ledger.PrivateDataConfig{
 					MaxBatchSize:    5000,
 					BatchesInterval: 1000,
 					PurgeInterval:   100,
+					TimeToLive:      &ledger.PvtDataTTLConfig{},
 				},
 				HistoryDBConfig: &ledger.HistoryDBConfig{
 					Enabled: false,
+					Prune:   &ledger.HistoryPruneConfig{},
+				},
+				BlockStoreConfig: &ledger.BlockStoreConfig{
+					Archive: &ledger.BlockArchiveConfig{},
 				},
+				StateCheckpointConfig: &ledger.StateCheckpointConfig{},
 			},
 		},
 		{
 			name: "CouchDB Explicit",
 			config: map[string]interface{}{
-				"peer.fileSystemPath":                                "/peerfs",
-				"ledger.state.stateDatabase":                         "CouchDB",
-				"ledger.state.couchDBConfig.couchDBAddress":          "localhost:5984",
-				"ledger.state.couchDBConfig.username":                "username",
-				"ledger.state.couchDBConfig.password":                "password",
-				"ledger.state.couchDBConfig.maxRetries":              3,
-				"ledger.state.couchDBConfig.maxRetriesOnStartup":     10,
-				"ledger.state.couchDBConfig.requestTimeout":          "30s",
-				"ledger.state.couchDBConfig.internalQueryLimit":      500,
-				"ledger.state.couchDBConfig.maxBatchUpdateSize":      600,
-				"ledger.state.couchDBConfig.warmIndexesAfterNBlocks": 5,
-				"ledger.state.couchDBConfig.createGlobalChangesDB":   true,
-				"ledger.state.couchDBConfig.cacheSize":               64,
-				"ledger.pvtdataStore.collElgProcMaxDbBatchSize":      50000,
-				"ledger.pvtdataStore.collElgProcDbBatchesInterval":   10000,
-				"ledger.pvtdataStore.purgeInterval":                  1000,
-				"ledger.history.enableHistoryDatabase":               true,
+				"peer.fileSystemPath":                                 "/peerfs",
+				"ledger.state.stateDatabase":                          "CouchDB",
+				"ledger.state.couchDBConfig.couchDBAddress":           "localhost:5984",
+				"ledger.state.couchDBConfig.username":                 "username",
+				"ledger.state.couchDBConfig.password":                 "password",
+				"ledger.state.couchDBConfig.maxRetries":               3,
+				"ledger.state.couchDBConfig.maxRetriesOnStartup":      10,
+				"ledger.state.couchDBConfig.requestTimeout":           "30s",
+				"ledger.state.couchDBConfig.internalQueryLimit":       500,
+				"ledger.state.couchDBConfig.maxBatchUpdateSize":       600,
+				"ledger.state.couchDBConfig.warmIndexesAfterNBlocks":  5,
+				"ledger.state.couchDBConfig.createGlobalChangesDB":    true,
+				"ledger.state.couchDBConfig.cacheSize":                64,
+				"ledger.state.couchDBConfig.systemCacheSize":          32,
+				"ledger.state.couchDBConfig.cacheEvictionPolicy":      "lru",
+				"ledger.state.couchDBConfig.pinnedCacheNamespaces":    []string{"mycc"},
+				"ledger.state.couchDBConfig.uncachedNamespaces":       []string{"bigcc"},
+				"ledger.state.couchDBConfig.maxIdleConnections":       100,
+				"ledger.state.couchDBConfig.maxConnections":           200,
+				"ledger.state.couchDBConfig.maxRetryBackoff":          "10s",
+				"ledger.state.couchDBConfig.retryBudgetRatio":         0.2,
+				"ledger.state.couchDBConfig.circuitBreaker.threshold": 5,
+				"ledger.state.couchDBConfig.circuitBreaker.timeout":   "30s",
+				"ledger.pvtdataStore.collElgProcMaxDbBatchSize":       50000,
+				"ledger.pvtdataStore.collElgProcDbBatchesInterval":    10000,
+				"ledger.pvtdataStore.purgeInterval":                   1000,
+				"ledger.pvtdataStore.timeToLive.checkInterval":        "1m",
+				"ledger.pvtdataStore.timeToLive.collections": []interface{}{
+					map[string]interface{}{"namespace": "mycc", "collection": "patientRecords", "ttl": "720h"},
+				},
+				"ledger.history.enableHistoryDatabase":    true,
+				"ledger.history.prune.retainBlocks":       100,
+				"ledger.history.prune.retentionPeriod":    "720h",
+				"ledger.history.prune.interval":           10,
+				"ledger.blockchain.compression":           "zstd",
+				"ledger.blockchain.archive.path":          "/archive",
+				"ledger.blockchain.archive.retainBlocks":  1000,
+				"ledger.blockchain.archive.cacheSize":     8,
+				"ledger.state.checkpoint.interval":        1000,
+				"ledger.state.checkpoint.schedule":        "0 2 * * *",
+				"ledger.state.checkpoint.retain":          3,
+				"ledger.state.checkpoint.retentionPeriod": "168h",
 			},
 			expected: &ledger.Config{
 				RootFSPath: "/peerfs/ledgersData",
@@ -125,16 +165,103 @@ func TestLedgerConfig(t *testing.T) {
 						CreateGlobalChangesDB:   true,
 						RedoLogPath:             "/peerfs/ledgersData/couchdbRedoLogs",
 						UserCacheSizeMBs:        64,
+						SysCacheSizeMBs:         32,
+						CacheEvictionPolicy:     "lru",
+						PinnedCacheNamespaces:   []string{"mycc"},
+						UncachedNamespaces:      []string{"bigcc"},
+						MaxIdleConnections:      100,
+						MaxConnections:          200,
+						MaxRetryBackoff:         10 * time.Second,
+						RetryBudgetRatio:        0.2,
+						CircuitBreakerThreshold: 5,
+						CircuitBreakerTimeout:   30 * time.Second,
 					},
+					Encryption: &ledger.StateEncryptionConfig{},
 				},
 				PrivateDataConfig: &ledger.PrivateDataConfig{
 					MaxBatchSize:    50000,
 					BatchesInterval: 10000,
 					PurgeInterval:   1000,
+					TimeToLive: &ledger.PvtDataTTLConfig{
+						Collections: []ledger.CollectionTTL{
+							{Namespace: "mycc", Collection: "patientRecords", TTL: 720 * time.Hour},
+						},
+						CheckInterval: time.Minute,
+					},
 				},
 				HistoryDBConfig: &ledger.HistoryDBConfig{
 					Enabled: true,
+					Prune: &ledger.HistoryPruneConfig{
+						RetainBlocks:    100,
+						RetentionPeriod: 720 * time.Hour,
+						Interval:        10,
+					},
+				},
+				BlockStoreConfig: &ledger.BlockStoreConfig{
+					Compression: "zstd",
+					Archive: &ledger.BlockArchiveConfig{
+						Path:         "/archive",
+						RetainBlocks: 1000,
+						CacheSize:    8,
+					},
+				},
+				StateCheckpointConfig: &ledger.StateCheckpointConfig{
+					Interval:        1000,
+					Schedule:        "0 2 * * *",
+					Retain:          3,
+					RetentionPeriod: 168 * time.Hour,
+				},
+			},
+		},
+		{
+			name: "State Encryption",
+			config: map[string]interface{}{
+				"peer.fileSystemPath":                              "/peerfs",
+				"ledger.state.stateDatabase":                       "goleveldb",
+				"ledger.state.encryption.keySKIs":                  []string{"0a0b", "0c0d"},
+				"ledger.state.encryption.namespaces":               []string{"mycc"},
+				"ledger.history.enableHistoryDatabase":             false,
+				"ledger.history.prune.retainBlocks":                0,
+				"ledger.history.prune.retentionPeriod":             "0s",
+				"ledger.history.prune.interval":                    0,
+				"ledger.blockchain.compression":                    "",
+				"ledger.blockchain.archive.path":                   "",
+				"ledger.blockchain.archive.retainBlocks":           0,
+				"ledger.blockchain.archive.cacheSize":              0,
+				"ledger.state.checkpoint.interval":                 0,
+				"ledger.state.checkpoint.schedule":                 "",
+				"ledger.state.checkpoint.retain":                   0,
+				"ledger.state.checkpoint.retentionPeriod":          "0s",
+				"ledger.pvtdataStore.purgeInterval":                100,
+				"ledger.pvtdataStore.collElgProcMaxDbBatchSize":    5000,
+				"ledger.pvtdataStore.collElgProcDbBatchesInterval": 1000,
+				"ledger.pvtdataStore.timeToLive.checkInterval":     "0s",
+				"ledger.pvtdataStore.timeToLive.collections":       nil,
+			},
+			expected: &ledger.Config{
+				RootFSPath: "/peerfs/ledgersData",
+				StateDBConfig: &ledger.StateDBConfig{
+					StateDatabase: "goleveldb",
+					CouchDB:       &ledger.CouchDBConfig{},
+					Encryption: &ledger.StateEncryptionConfig{
+						KeySKIs:    []string{"0a0b", "0c0d"},
+						Namespaces: []string{"mycc"},
+					},
+				},
+				PrivateDataConfig: &ledger.PrivateDataConfig{
+					MaxBatchSize:    5000,
+					BatchesInterval: 1000,
+					PurgeInterval:   100,
+					TimeToLive:      &ledger.PvtDataTTLConfig{},
+				},
+				HistoryDBConfig: &ledger.HistoryDBConfig{
+					Enabled: false,
+					Prune:   &ledger.HistoryPruneConfig{},
+				},
+				BlockStoreConfig: &ledger.BlockStoreConfig{
+					Archive: &ledger.BlockArchiveConfig{},
 				},
+				StateCheckpointConfig: &ledger.StateCheckpointConfig{},
 			},
 		},
 	}
@@ -150,3 +277,34 @@ func TestLedgerConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLedgerConfigCouchDBNamespaces(t *testing.T) {
+	defer viper.Reset()
+	viper.Reset()
+	viper.Set("peer.fileSystemPath", "/peerfs")
+	viper.Set("ledger.state.stateDatabase", "goleveldb")
+	viper.Set("ledger.state.couchDBNamespaces", map[string]interface{}{
+		"mychannel": []interface{}{"mycc", "othercc"},
+	})
+	viper.Set("ledger.state.couchDBConfig.couchDBAddress", "localhost:5984")
+
+	conf := ledgerConfig()
+	assert.Equal(t, "goleveldb", conf.StateDBConfig.StateDatabase)
+	assert.Equal(t, map[string][]string{"mychannel": {"mycc", "othercc"}}, conf.StateDBConfig.CouchDBNamespaces)
+	assert.Equal(t, "localhost:5984", conf.StateDBConfig.CouchDB.Address)
+	assert.Equal(t, "/peerfs/ledgersData/couchdbRedoLogs", conf.StateDBConfig.CouchDB.RedoLogPath)
+}
+
+func TestLedgerConfigMigrationTarget(t *testing.T) {
+	defer viper.Reset()
+	viper.Reset()
+	viper.Set("peer.fileSystemPath", "/peerfs")
+	viper.Set("ledger.state.stateDatabase", "goleveldb")
+	viper.Set("ledger.state.migration.targetDatabase", "CouchDB")
+	viper.Set("ledger.state.couchDBConfig.couchDBAddress", "localhost:5984")
+
+	conf := ledgerConfig()
+	assert.Equal(t, "goleveldb", conf.StateDBConfig.StateDatabase)
+	assert.Equal(t, "CouchDB", conf.StateDBConfig.MigrationTarget)
+	assert.Equal(t, "localhost:5984", conf.StateDBConfig.CouchDB.Address)
+}
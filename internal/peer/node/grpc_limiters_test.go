@@ -8,71 +8,71 @@ package node
 
 import (
 	"context"
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 
+	"github.com/hyperledger/fabric/common/grpclimits"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/peer"
 )
 
-func TestInitGrpcSemaphores(t *testing.T) {
+func TestGrpcLimits(t *testing.T) {
 	config := peer.Config{
-		LimitsConcurrencyEndorserService: 5,
-		LimitsConcurrencyDeliverService:  5,
+		LimitsConcurrencyEndorserService:    5,
+		LimitsConcurrencyDeliverService:     6,
+		LimitsConcurrencyGossipService:      7,
+		LimitsMaxRecvMsgSizeEndorserService: 100,
+		LimitsMaxSendMsgSizeDeliverService:  200,
+		LimitsMaxRecvMsgSizeGossipService:   300,
+		LimitsMaxSendMsgSizeGossipService:   400,
 	}
-	semaphores := initGrpcSemaphores(&config)
-	require.Equal(t, 2, len(semaphores))
+	require.Equal(t, map[string]grpclimits.Limits{
+		"protos.Endorser": {MaxConcurrency: 5, MaxRecvMsgSize: 100},
+		"protos.Deliver":  {MaxConcurrency: 6, MaxSendMsgSize: 200},
+		"gossip.Gossip":   {MaxConcurrency: 7, MaxRecvMsgSize: 300, MaxSendMsgSize: 400},
+	}, grpcLimits(&config))
 }
 
-func TestInitGrpcNoSemaphores(t *testing.T) {
-	config := peer.Config{
-		LimitsConcurrencyEndorserService: 0,
-		LimitsConcurrencyDeliverService:  0,
-	}
-	semaphores := initGrpcSemaphores(&config)
-	require.Equal(t, 0, len(semaphores))
+func TestGrpcNoLimits(t *testing.T) {
+	limiter := grpclimits.NewLimiter(grpcLimits(&peer.Config{}), &disabled.Provider{})
+	require.True(t, limiter.Empty())
 }
 
-func TestInitGrpcSemaphoresPanic(t *testing.T) {
+func TestGrpcLimitsPanic(t *testing.T) {
 	config := peer.Config{
 		LimitsConcurrencyEndorserService: -1,
 	}
-	require.PanicsWithValue(t, "permits must be greater than 0", func() { initGrpcSemaphores(&config) })
-}
-
-func TestUnaryGrpcLimiterExceedLimit(t *testing.T) {
-	limit := 10
-	fullMethod := "/protos.Endorser/ProcessProposal"
-	semaphores := initGrpcSemaphores(&peer.Config{LimitsConcurrencyEndorserService: limit})
-	// acquire all permits so that semaphore buffer is full, expect error when calling interceptor
-	for i := 0; i < limit; i++ {
-		semaphores["/protos.Endorser"].TryAcquire()
-	}
-	interceptor := unaryGrpcLimiter(semaphores)
-	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, nil)
-	require.EqualError(t, err, fmt.Sprintf("too many requests for %s, exceeding concurrency limit (%d)", getServiceName(fullMethod), limit))
+	require.PanicsWithValue(t, "permits must be greater than 0", func() {
+		grpclimits.NewLimiter(grpcLimits(&config), &disabled.Provider{})
+	})
 }
 
 func TestStreamGrpcLimiterExceedLimit(t *testing.T) {
-	limit := 5
 	fullMethods := []string{"/protos.Deliver/Deliver", "/protos.Deliver/DeliverFiltered", "/protos.Deliver/DeliverWithPrivateData"}
-	semaphores := initGrpcSemaphores(&peer.Config{LimitsConcurrencyDeliverService: limit})
-	// acquire all permits so that semaphore buffer is full, expect error when calling interceptor
-	for i := 0; i < limit; i++ {
-		semaphores["/protos.Deliver"].TryAcquire()
-	}
-	interceptor := streamGrpcLimiter(semaphores)
-	for _, method := range fullMethods {
-		err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: method}, nil)
-		require.EqualError(t, err, fmt.Sprintf("too many requests for %s, exceeding concurrency limit (%d)", getServiceName(method), limit))
-	}
+	limiter := grpclimits.NewLimiter(grpcLimits(&peer.Config{LimitsConcurrencyDeliverService: 1}), &disabled.Provider{})
+	interceptor := limiter.StreamServerInterceptor()
+
+	// hold the only permit, expect an error when calling the interceptor
+	err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: fullMethods[0]}, func(interface{}, grpc.ServerStream) error {
+		for _, method := range fullMethods {
+			err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: method}, nil)
+			require.Equal(t, "too many requests for "+method+", exceeding concurrency limit (1)", status.Convert(err).Message())
+		}
+		return nil
+	})
+	require.NoError(t, err)
 }
 
-func TestGetServiceName(t *testing.T) {
-	require.Equal(t, "/protos.Endorser", getServiceName("/protos.Endorser/ProcessProposal"))
-	require.Equal(t, "/protos.Deliver", getServiceName("/protos.Deliver/Deliver"))
-	require.Equal(t, "/protos.Deliver", getServiceName("/protos.Deliver/DeliverFiltered"))
-	require.Equal(t, "/protos.Deliver", getServiceName("/protos.Deliver/DeliverWithPrivateData"))
+func TestUnaryGrpcLimiterGossip(t *testing.T) {
+	limiter := grpclimits.NewLimiter(grpcLimits(&peer.Config{LimitsConcurrencyGossipService: 1}), &disabled.Provider{})
+	interceptor := limiter.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/gossip.Gossip/Ping"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptor(ctx, req, info, nil)
+	})
+	require.Equal(t, "too many requests for /gossip.Gossip/Ping, exceeding concurrency limit (1)", status.Convert(err).Message())
 }
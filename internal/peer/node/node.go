@@ -15,7 +15,7 @@ import (
 
 const (
 	nodeFuncName = "node"
-	nodeCmdDes   = "Operate a peer node: start|reset|rollback|pause|resume|rebuild-dbs|upgrade-dbs."
+	nodeCmdDes   = "Operate a peer node: start|reset|rollback|pause|resume|rebuild-dbs|upgrade-dbs|verify-blockstore."
 )
 
 var logger = flogging.MustGetLogger("nodeCmd")
@@ -29,6 +29,8 @@ func Cmd() *cobra.Command {
 	nodeCmd.AddCommand(resumeCmd())
 	nodeCmd.AddCommand(rebuildDBsCmd())
 	nodeCmd.AddCommand(upgradeDBsCmd())
+	nodeCmd.AddCommand(verifyBlockStoreCmd())
+	nodeCmd.AddCommand(verifyAuditLogCmd())
 	return nodeCmd
 }
 
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/core/config"
+	gossipservice "github.com/hyperledger/fabric/gossip/service"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// newReloadManager returns the manager reloading the runtime parameters of
+// the peer from its configuration file: the logging specification and the
+// tuning of the gossip pull engines.
+func newReloadManager(tuner gossipservice.PullTuner) *reload.Manager {
+	manager := reload.NewManager(readConfig)
+	manager.Register(&reload.LoggingComponent{Key: "logging.spec", Logging: flogging.Global})
+	manager.Register(&gossipservice.PullTuningComponent{Tuner: tuner})
+	return manager
+}
+
+// readConfig reads the configuration file of the peer again, with the
+// overrides of the environment. The global configuration is left untouched.
+func readConfig() (*viper.Viper, error) {
+	v := viper.New()
+	if err := config.InitViper(v, common.CmdRoot); err != nil {
+		return nil, err
+	}
+	v.SetEnvPrefix(common.CmdRoot)
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.WithMessagef(err, "error when reading %s config file", common.CmdRoot)
+	}
+	return v, nil
+}
@@ -8,6 +8,7 @@ package node
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -25,21 +26,28 @@ import (
 	"github.com/hyperledger/fabric-protos-go/common"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	discprotos "github.com/hyperledger/fabric-protos-go/discovery"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/expiry"
 	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/common/audit"
 	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/common/certmonitor"
 	ccdef "github.com/hyperledger/fabric/common/chaincode"
-	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/crypto/tlsgen"
 	"github.com/hyperledger/fabric/common/deliver"
 	"github.com/hyperledger/fabric/common/flogging"
 	floggingmetrics "github.com/hyperledger/fabric/common/flogging/metrics"
+	"github.com/hyperledger/fabric/common/grpclimits"
 	"github.com/hyperledger/fabric/common/grpclogging"
 	"github.com/hyperledger/fabric/common/grpcmetrics"
 	"github.com/hyperledger/fabric/common/metadata"
 	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/aclmgmt"
 	"github.com/hyperledger/fabric/core/cclifecycle"
 	"github.com/hyperledger/fabric/core/chaincode"
@@ -55,6 +63,7 @@ import (
 	"github.com/hyperledger/fabric/core/container"
 	"github.com/hyperledger/fabric/core/container/dockercontroller"
 	"github.com/hyperledger/fabric/core/container/externalbuilder"
+	"github.com/hyperledger/fabric/core/container/kubernetes"
 	"github.com/hyperledger/fabric/core/deliverservice"
 	"github.com/hyperledger/fabric/core/dispatcher"
 	"github.com/hyperledger/fabric/core/endorser"
@@ -82,6 +91,7 @@ import (
 	ccsupport "github.com/hyperledger/fabric/discovery/support/chaincode"
 	"github.com/hyperledger/fabric/discovery/support/config"
 	"github.com/hyperledger/fabric/discovery/support/gossip"
+	gossipcomm "github.com/hyperledger/fabric/gossip/comm"
 	gossipcommon "github.com/hyperledger/fabric/gossip/common"
 	gossipgossip "github.com/hyperledger/fabric/gossip/gossip"
 	gossipmetrics "github.com/hyperledger/fabric/gossip/metrics"
@@ -131,7 +141,8 @@ var nodeStartCmd = &cobra.Command{
 // externalVMAdapter adapts coerces the result of Build to the
 // container.Interface type expected by the VM interface.
 type externalVMAdapter struct {
-	detector *externalbuilder.Detector
+	detector   *externalbuilder.Detector
+	kubernetes *kubernetes.Launcher
 }
 
 func (e externalVMAdapter) Build(
@@ -139,6 +150,17 @@ func (e externalVMAdapter) Build(
 	mdBytes []byte,
 	codePackage io.Reader,
 ) (container.Instance, error) {
+	if e.kubernetes != nil {
+		// the launcher reads the code package only when the chaincode is its own
+		ki, err := e.kubernetes.Build(ccid, mdBytes, codePackage)
+		if err != nil {
+			return nil, err
+		}
+		if ki != nil {
+			return ki, nil
+		}
+	}
+
 	i, err := e.detector.Build(ccid, mdBytes, codePackage)
 	if err != nil {
 		return nil, err
@@ -215,13 +237,25 @@ func serve(args []string) error {
 		return mgmt.GetManagerForChain(chainID)
 	}
 
-	opsSystem := newOperationsSystem(coreConfig)
+	auditLog, err := audit.Open(coreConfig.AuditFile, coreConfig.AuditStderr)
+	if err != nil {
+		return errors.WithMessage(err, "failed to open the audit log")
+	}
+	defer auditLog.Close()
+
+	opsSystem := newOperationsSystem(coreConfig, auditLog)
 	err = opsSystem.Start()
 	if err != nil {
 		return errors.WithMessage(err, "failed to initialize operations subsystem")
 	}
 	defer opsSystem.Stop()
 
+	keystoreStatus := factory.NewStatusHandler(factory.GetDefault())
+	if err := opsSystem.RegisterChecker("bccsp", keystoreStatus); err != nil {
+		return errors.WithMessage(err, "failed to register the BCCSP health checker")
+	}
+	opsSystem.RegisterHandler("/keystore", keystoreStatus)
+
 	metricsProvider := opsSystem.Provider
 	logObserver := floggingmetrics.NewObserver(metricsProvider)
 	flogging.SetObserver(logObserver)
@@ -260,10 +294,22 @@ func serve(args []string) error {
 		grpclogging.StreamServerInterceptor(flogging.MustGetLogger("comm.grpc.server").Zap()),
 	)
 
-	semaphores := initGrpcSemaphores(coreConfig)
-	if len(semaphores) != 0 {
-		serverConfig.UnaryInterceptors = append(serverConfig.UnaryInterceptors, unaryGrpcLimiter(semaphores))
-		serverConfig.StreamInterceptors = append(serverConfig.StreamInterceptors, streamGrpcLimiter(semaphores))
+	var tracer *tracing.Tracer
+	if coreConfig.TracingEndpoint != "" {
+		logger.Infof("Exporting traces to %s as %s", coreConfig.TracingEndpoint, coreConfig.TracingServiceName)
+		exporter := tracing.NewOTLPExporter(coreConfig.TracingEndpoint, coreConfig.TracingServiceName)
+		defer exporter.Stop()
+		tracer = tracing.NewTracer(exporter, coreConfig.TracingSampleRate)
+		serverConfig.UnaryInterceptors = append(serverConfig.UnaryInterceptors, tracing.UnaryServerInterceptor(tracer))
+		serverConfig.StreamInterceptors = append(serverConfig.StreamInterceptors, tracing.StreamServerInterceptor())
+	}
+
+	limiter := grpclimits.NewLimiter(grpcLimits(coreConfig), metricsProvider)
+	if !limiter.Empty() {
+		// the limiter sees the messages before the other interceptors
+		serverConfig.Codec = limiter.Codec()
+		serverConfig.UnaryInterceptors = append([]grpc.UnaryServerInterceptor{limiter.UnaryServerInterceptor()}, serverConfig.UnaryInterceptors...)
+		serverConfig.StreamInterceptors = append([]grpc.StreamServerInterceptor{limiter.StreamServerInterceptor()}, serverConfig.StreamInterceptors...)
 	}
 
 	cs := comm.NewCredentialSupport()
@@ -277,6 +323,9 @@ func serve(args []string) error {
 			logger.Fatalf("Failed to set TLS client certificate (%s)", err)
 		}
 		cs.SetClientCertificate(clientCert)
+		if serverConfig.SecOpts.SessionResumption {
+			cs.EnableSessionResumption(viper.GetInt("peer.tls.sessionResumption.clientCacheSize"))
+		}
 	}
 
 	transientStoreProvider, err := transientstore.NewStoreProvider(
@@ -294,6 +343,8 @@ func serve(args []string) error {
 		StoreProvider:            transientStoreProvider,
 		CryptoProvider:           factory.GetDefault(),
 		OrdererEndpointOverrides: deliverServiceConfig.OrdererEndpointOverrides,
+		Tracer:                   tracer,
+		AuditLog:                 auditLog,
 	}
 
 	localMSP := mgmt.GetLocalMSP(factory.GetDefault())
@@ -307,15 +358,55 @@ func serve(args []string) error {
 		logger.Panicf("Failed to serialize the signing identity: %v", err)
 	}
 
-	expirationLogger := flogging.MustGetLogger("certmonitor")
-	crypto.TrackExpiration(
-		serverConfig.SecOpts.UseTLS,
-		serverConfig.SecOpts.Certificate,
-		cs.GetClientCertificate().Certificate,
-		signingIdentityBytes,
-		expirationLogger.Warnf, // This can be used to piggyback a metric event in the future
-		time.Now(),
-		time.AfterFunc)
+	auditKeyLoads(auditLog, signingIdentityBytes, serverConfig.SecOpts)
+
+	certMonitor, err := newCertificateMonitor(coreConfig, metricsProvider)
+	if err != nil {
+		return err
+	}
+	certMonitor.AddSource(certmonitor.LocalMSPSource(coreconfig.GetPath("peer.mspConfigPath"), mspID, signingIdentityBytes))
+	certMonitor.AddSource(certmonitor.ChannelSource(
+		func() []string {
+			var channels []string
+			for _, info := range peerInstance.GetChannelsInfo() {
+				channels = append(channels, info.ChannelId)
+			}
+			return channels
+		},
+		func(channel string) *cb.Config {
+			if resources := peerInstance.GetStableChannelConfig(channel); resources != nil {
+				return resources.ConfigtxValidator().ConfigProto()
+			}
+			return nil
+		},
+	))
+	var tlsCerts []certmonitor.Certificate
+	if serverConfig.SecOpts.UseTLS {
+		var clientCert []byte
+		if chain := cs.GetClientCertificate().Certificate; len(chain) > 0 {
+			clientCert = chain[0]
+		}
+		serverCerts, err := certmonitor.TLSCertificates("server TLS", serverConfig.SecOpts.Certificate)
+		if err != nil {
+			return err
+		}
+		clientCerts, err := certmonitor.TLSCertificates("client TLS", clientCert)
+		if err != nil {
+			return err
+		}
+		tlsCerts = append(serverCerts, clientCerts...)
+		certMonitor.AddSource(certmonitor.StaticSource(tlsCerts...))
+	}
+	certMonitor.Start()
+	defer certMonitor.Stop()
+
+	keyExpiryMonitor, err := startKeyExpiryMonitor(factory.GetDefault(), signingIdentityBytes, tlsCerts, coreConfig.CertificateMonitorInterval, metricsProvider)
+	if err != nil {
+		return err
+	}
+	if keyExpiryMonitor != nil {
+		defer keyExpiryMonitor.Stop()
+	}
 
 	policyMgr := policies.PolicyManagerGetterFunc(peerInstance.GetPolicyManager)
 
@@ -427,6 +518,7 @@ func serve(args []string) error {
 			StateListeners:                  []ledger.StateListener{lifecycleCache},
 			Config:                          ledgerConfig(),
 			Hasher:                          factory.GetDefault(),
+			Encryptor:                       factory.GetDefault(),
 			EbMetadataProvider:              ebMetadataProvider,
 		},
 	)
@@ -436,12 +528,22 @@ func serve(args []string) error {
 		logger.Fatalf("Failed to create peer server (%s)", err)
 	}
 
+	var gossipCerts *gossipcommon.TLSCertificates
+	if peerServer.TLSEnabled() {
+		serverCert := peerServer.ServerCertificate()
+		clientCert := cs.GetClientCertificate()
+		gossipCerts = &gossipcommon.TLSCertificates{}
+		gossipCerts.TLSServerCert.Store(&serverCert)
+		gossipCerts.TLSClientCert.Store(&clientCert)
+	}
+
 	// FIXME: Creating the gossip service has the side effect of starting a bunch
 	// of go routines and registration with the grpc server.
 	gossipService, err := initGossipService(
 		policyMgr,
 		metricsProvider,
 		peerServer,
+		gossipCerts,
 		signingIdentity,
 		cs,
 		coreConfig.PeerAddress,
@@ -455,6 +557,9 @@ func serve(args []string) error {
 	defer gossipService.Stop()
 
 	peerInstance.GossipService = gossipService
+	opsSystem.RegisterHandler("/gossip/pull", gossipservice.NewPullTuningHandler(gossipService))
+	reloadManager := newReloadManager(gossipService)
+	opsSystem.RegisterHandler("/config/reload", reload.NewHandler(reloadManager))
 
 	// Configure CC package storage
 	lsccInstallPath := filepath.Join(coreconfig.GetPath("peer.fileSystemPath"), "chaincodes")
@@ -516,8 +621,8 @@ func serve(args []string) error {
 		HandlerRegistry: chaincodeHandlerRegistry,
 	}
 
-	if coreConfig.VMEndpoint == "" && len(coreConfig.ExternalBuilders) == 0 {
-		logger.Panic("VMEndpoint not set and no ExternalBuilders defined")
+	if coreConfig.VMEndpoint == "" && len(coreConfig.ExternalBuilders) == 0 && !coreConfig.KubernetesEnabled {
+		logger.Panic("VMEndpoint not set, no ExternalBuilders defined and the Kubernetes launcher disabled")
 	}
 
 	chaincodeConfig := chaincode.GlobalConfig()
@@ -550,7 +655,8 @@ func serve(args []string) error {
 				"CORE_CHAINCODE_LOGGING_SHIM=" + chaincodeConfig.ShimLogLevel,
 				"CORE_CHAINCODE_LOGGING_FORMAT=" + chaincodeConfig.LogFormat,
 			},
-			MSPID: mspID,
+			MSPID:          mspID,
+			ResourceLimits: coreConfig.ChaincodeResourceLimits,
 		}
 		if err := opsSystem.RegisterChecker("docker", dockerVM); err != nil {
 			logger.Panicf("failed to register docker health check: %s", err)
@@ -564,15 +670,26 @@ func serve(args []string) error {
 	}
 
 	externalVM := &externalbuilder.Detector{
-		Builders:    externalbuilder.CreateBuilders(coreConfig.ExternalBuilders, mspID),
+		Builders:    externalbuilder.CreateBuilders(coreConfig.ExternalBuilders, mspID, coreConfig.ChaincodeResourceLimits),
 		DurablePath: externalBuilderOutput,
 	}
 
+	var kubernetesLauncher *kubernetes.Launcher
+	if coreConfig.KubernetesEnabled {
+		kubernetesLauncher, err = newKubernetesLauncher(coreConfig, mspID, chaincodeConfig)
+		if err != nil {
+			logger.Panicf("cannot create Kubernetes launcher: %s", err)
+		}
+		if err := opsSystem.RegisterChecker("kubernetes", kubernetesLauncher); err != nil {
+			logger.Panicf("failed to register kubernetes health check: %s", err)
+		}
+	}
+
 	buildRegistry := &container.BuildRegistry{}
 
 	containerRouter := &container.Router{
 		DockerBuilder:   dockerBuilder,
-		ExternalBuilder: externalVMAdapter{externalVM},
+		ExternalBuilder: externalVMAdapter{externalVM, kubernetesLauncher},
 		PackageProvider: &persistence.FallbackPackageLocator{
 			ChaincodePackageLocator: &persistence.ChaincodePackageLocator{
 				ChaincodeDir: chaincodeInstallPath,
@@ -615,12 +732,18 @@ func serve(args []string) error {
 		ContainerRouter: containerRouter,
 	}
 
+	packageSignatureVerifier, err := newPackageSignatureVerifier(coreConfig)
+	if err != nil {
+		logger.Panicf("Failed to create chaincode package signature verifier: %s", err)
+	}
+
 	lifecycleFunctions := &lifecycle.ExternalFunctions{
 		Resources:                 lifecycleResources,
 		InstallListener:           lifecycleCache,
 		InstalledChaincodesLister: lifecycleCache,
 		ChaincodeBuilder:          containerRouter,
 		BuildRegistry:             buildRegistry,
+		SignatureVerifier:         packageSignatureVerifier,
 	}
 
 	lifecycleSCC := &lifecycle.SCC{
@@ -636,14 +759,20 @@ func serve(args []string) error {
 	}
 
 	chaincodeLauncher := &chaincode.RuntimeLauncher{
-		Metrics:           chaincode.NewLaunchMetrics(opsSystem.Provider),
-		Registry:          chaincodeHandlerRegistry,
-		Runtime:           containerRuntime,
-		StartupTimeout:    chaincodeConfig.StartupTimeout,
-		CertGenerator:     authenticator,
-		CACert:            ca.CertBytes(),
-		PeerAddress:       ccEndpoint,
-		ConnectionHandler: &extcc.ExternalChaincodeRuntime{},
+		Metrics:        chaincode.NewLaunchMetrics(opsSystem.Provider),
+		Registry:       chaincodeHandlerRegistry,
+		Runtime:        containerRuntime,
+		StartupTimeout: chaincodeConfig.StartupTimeout,
+		CertGenerator:  authenticator,
+		CACert:         ca.CertBytes(),
+		PeerAddress:    ccEndpoint,
+		ConnectionHandler: &extcc.ExternalChaincodeRuntime{
+			ReconnectAttempts:   chaincodeConfig.ExternalReconnectAttempts,
+			ReconnectBackoff:    chaincodeConfig.ExternalReconnectBackoff,
+			MaxReconnectBackoff: chaincodeConfig.ExternalMaxReconnectBackoff,
+			HealthCheckInterval: chaincodeConfig.ExternalHealthCheckInterval,
+			Metrics:             extcc.NewMetrics(opsSystem.Provider),
+		},
 	}
 
 	// Keep TestQueries working
@@ -666,6 +795,8 @@ func serve(args []string) error {
 		Runtime:                containerRuntime,
 		BuiltinSCCs:            builtinSCCs,
 		TotalQueryLimit:        chaincodeConfig.TotalQueryLimit,
+		MaxPageSize:            chaincodeConfig.MaxPageSize,
+		EnableResumeTokens:     chaincodeConfig.EnableResumeTokens,
 		UserRunsCC:             userRunsCC,
 	}
 
@@ -734,6 +865,21 @@ func serve(args []string) error {
 		LocalMSP:               localMSP,
 		Support:                endorserSupport,
 		Metrics:                endorser.NewMetrics(metricsProvider),
+		ReadOnly:               deliverServiceConfig.ReplicaSources != nil,
+		Tracer:                 tracer,
+	}
+	if coreConfig.LimitsClientsProposalsPerSecond != 0 || coreConfig.LimitsClientsConcurrency != 0 {
+		serverEndorser.Scheduler, err = endorser.NewScheduler(endorser.SchedulerConfig{
+			Scope:              coreConfig.LimitsClientsScope,
+			ProposalsPerSecond: coreConfig.LimitsClientsProposalsPerSecond,
+			Concurrency:        coreConfig.LimitsClientsConcurrency,
+			MaxWait:            coreConfig.LimitsClientsMaxWait,
+		})
+		if err != nil {
+			return errors.WithMessage(err, "invalid client quotas")
+		}
+		logger.Infof("Client quotas of the endorser: scope %s, %d proposals per second, concurrency %d, max wait %s",
+			coreConfig.LimitsClientsScope, coreConfig.LimitsClientsProposalsPerSecond, coreConfig.LimitsClientsConcurrency, coreConfig.LimitsClientsMaxWait)
 	}
 
 	// deploy system chaincodes
@@ -791,6 +937,13 @@ func serve(args []string) error {
 		coreConfig.ValidatorPoolSize,
 	)
 
+	if serverConfig.SecOpts.UseTLS {
+		tlsRotator := newTLSRotator(peerInstance, peerServer, deliverGRPCClient, gossipCerts)
+		tlsRotationHandler := comm.NewTLSRotationHandler(tlsRotator)
+		tlsRotationHandler.AuditLog = auditLog
+		opsSystem.RegisterHandler("/tls", tlsRotationHandler)
+	}
+
 	if coreConfig.DiscoveryEnabled {
 		registerDiscoveryService(
 			coreConfig,
@@ -802,6 +955,7 @@ func serve(args []string) error {
 				legacyMetadataManager,
 				peerInstance,
 			),
+			metadataManager,
 			gossipService,
 		)
 	}
@@ -830,6 +984,7 @@ func serve(args []string) error {
 	handleSignals(addPlatformSignals(map[os.Signal]func(){
 		syscall.SIGINT:  func() { containerRouter.Shutdown(5 * time.Second); serve <- nil },
 		syscall.SIGTERM: func() { containerRouter.Shutdown(5 * time.Second); serve <- nil },
+		syscall.SIGHUP:  func() { reloadManager.Reload() },
 	}))
 
 	logger.Infof("Started peer with ID=[%s], network ID=[%s], address=[%s]", coreConfig.PeerID, coreConfig.NetworkID, coreConfig.PeerAddress)
@@ -865,6 +1020,30 @@ func serve(args []string) error {
 	// Register the Endorser server
 	pb.RegisterEndorserServer(peerServer.Server(), auth)
 
+	// Accept gossip connections tunneled over WebSocket, if enabled
+	if wsListenAddress := viper.GetString("peer.gossip.webSocket.listenAddress"); wsListenAddress != "" {
+		wsListener, err := gossipcomm.ListenWebSocket(wsListenAddress)
+		if err != nil {
+			return errors.WithMessage(err, "failed to accept gossip connections over WebSocket")
+		}
+		// Only gossip is served over WebSocket, on a server of its own
+		wsServerConfig := serverConfig
+		wsServerConfig.Logger = flogging.MustGetLogger("core.comm").With("server", "GossipWebSocketServer")
+		wsServer, err := comm.NewGRPCServerFromListener(wsListener, wsServerConfig)
+		if err != nil {
+			wsListener.Close()
+			return errors.WithMessage(err, "failed to create the gossip WebSocket server")
+		}
+		gossipService.Register(wsServer.Server())
+		defer wsServer.Stop()
+		logger.Infof("Accepting gossip connections over WebSocket on %s", wsListener.Addr())
+		go func() {
+			if err := wsServer.Start(); err != nil {
+				logger.Errorf("Stopped accepting gossip connections over WebSocket: %s", err)
+			}
+		}()
+	}
+
 	go func() {
 		var grpcErr error
 		if grpcErr = peerServer.Start(); grpcErr != nil {
@@ -928,6 +1107,7 @@ func registerDiscoveryService(
 	peerServer *comm.GRPCServer,
 	polMgr policies.ChannelPolicyManagerGetter,
 	metadataProvider *lifecycle.MetadataProvider,
+	metadataManager *lifecycle.MetadataManager,
 	gossipService *gossipservice.GossipService,
 ) {
 	mspID := coreConfig.LocalMSPID
@@ -958,7 +1138,11 @@ func registerDiscoveryService(
 		AuthCacheEnabled:             coreConfig.DiscoveryAuthCacheEnabled,
 		AuthCacheMaxSize:             coreConfig.DiscoveryAuthCacheMaxSize,
 		AuthCachePurgeRetentionRatio: coreConfig.DiscoveryAuthCachePurgeRetentionRatio,
+		EndorsementCacheTTL:          coreConfig.DiscoveryEndorsementCacheTTL,
+		EndorsementCacheMaxSize:      coreConfig.DiscoveryEndorsementCacheMaxSize,
 	}, support)
+	// the cached endorsement descriptors are invalidated upon chaincode definition updates
+	metadataManager.AddListener(svc)
 	logger.Info("Discovery service activated")
 	discprotos.RegisterDiscoveryServer(peerServer.Server(), svc)
 }
@@ -1113,6 +1297,49 @@ func createDockerClient(coreConfig *peer.Config) (*docker.Client, error) {
 	return docker.NewClient(coreConfig.VMEndpoint)
 }
 
+func newKubernetesLauncher(coreConfig *peer.Config, mspID string, chaincodeConfig *chaincode.Config) (*kubernetes.Launcher, error) {
+	client, err := kubernetes.NewClient(coreConfig.KubernetesAPIServer, coreConfig.KubernetesTokenFile, coreConfig.KubernetesCAFile)
+	if err != nil {
+		return nil, err
+	}
+	namespace := coreConfig.KubernetesNamespace
+	if namespace == "" {
+		namespace = kubernetes.InClusterNamespace()
+	}
+	return &kubernetes.Launcher{
+		Client:          client,
+		Namespace:       namespace,
+		PeerID:          coreConfig.PeerID,
+		NetworkID:       coreConfig.NetworkID,
+		MSPID:           mspID,
+		ServiceAccount:  coreConfig.KubernetesServiceAccount,
+		ImagePullPolicy: coreConfig.KubernetesImagePullPolicy,
+		LoggingEnv: []string{
+			"CORE_CHAINCODE_LOGGING_LEVEL=" + chaincodeConfig.LogLevel,
+			"CORE_CHAINCODE_LOGGING_SHIM=" + chaincodeConfig.ShimLogLevel,
+			"CORE_CHAINCODE_LOGGING_FORMAT=" + chaincodeConfig.LogFormat,
+		},
+		ResourceLimits: coreConfig.ChaincodeResourceLimits,
+	}, nil
+}
+
+// newPackageSignatureVerifier returns the verifier of the signatures of
+// installed chaincode packages, or nil when package signing is not configured.
+func newPackageSignatureVerifier(coreConfig *peer.Config) (*persistence.SignatureVerifier, error) {
+	if len(coreConfig.ChaincodePackageSigningRoots) == 0 && !coreConfig.ChaincodePackageSignaturesRequired {
+		return nil, nil
+	}
+	var roots [][]byte
+	for _, file := range coreConfig.ChaincodePackageSigningRoots {
+		root, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read chaincode package signing root %s", file)
+		}
+		roots = append(roots, root)
+	}
+	return persistence.NewSignatureVerifier(roots, coreConfig.ChaincodePackageSignaturesRequired)
+}
+
 // secureDialOpts is the callback function for secure dial options for gossip service
 func secureDialOpts(credSupport *comm.CredentialSupport) func() []grpc.DialOption {
 	return func() []grpc.DialOption {
@@ -1142,7 +1369,7 @@ func secureDialOpts(credSupport *comm.CredentialSupport) func() []grpc.DialOptio
 }
 
 // initGossipService will initialize the gossip service by:
-// 1. Enable TLS if configured;
+// 1. Enable TLS with the given certificates if configured;
 // 2. Init the message crypto service;
 // 3. Init the security advisor;
 // 4. Init gossip related struct.
@@ -1150,6 +1377,7 @@ func initGossipService(
 	policyMgr policies.ChannelPolicyManagerGetter,
 	metricsProvider metrics.Provider,
 	peerServer *comm.GRPCServer,
+	certs *gossipcommon.TLSCertificates,
 	signer msp.SigningIdentity,
 	credSupport *comm.CredentialSupport,
 	peerAddress string,
@@ -1158,18 +1386,6 @@ func initGossipService(
 	privdataConfig *gossipprivdata.PrivdataConfig,
 ) (*gossipservice.GossipService, error) {
 
-	var certs *gossipcommon.TLSCertificates
-	if peerServer.TLSEnabled() {
-		serverCert := peerServer.ServerCertificate()
-		clientCert, err := peer.GetClientCertificate()
-		if err != nil {
-			return nil, errors.Wrap(err, "failed obtaining client certificates")
-		}
-		certs = &gossipcommon.TLSCertificates{}
-		certs.TLSServerCert.Store(&serverCert)
-		certs.TLSClientCert.Store(&clientCert)
-	}
-
 	messageCryptoService := peergossip.NewMCS(
 		policyMgr,
 		signer,
@@ -1205,7 +1421,67 @@ func initGossipService(
 	)
 }
 
-func newOperationsSystem(coreConfig *peer.Config) *operations.System {
+// auditKeyLoads records the load of the signing identity and of the TLS
+// certificate of the peer.
+func auditKeyLoads(auditLog *audit.Log, signingIdentity []byte, secOpts comm.SecureOptions) {
+	auditLog.Record(audit.IdentityKeyLoadEvent("signing", signingIdentity))
+	if secOpts.UseTLS {
+		auditLog.Record(audit.KeyLoadEvent("tls", secOpts.Certificate))
+	}
+}
+
+// newCertificateMonitor returns a monitor logging the expiration alerts, and
+// posting them to the configured webhook.
+func newCertificateMonitor(coreConfig *peer.Config, metricsProvider metrics.Provider) (*certmonitor.Monitor, error) {
+	alerters := []certmonitor.Alerter{
+		&certmonitor.LogAlerter{Warn: flogging.MustGetLogger("certmonitor").Warnf},
+	}
+	if coreConfig.CertificateMonitorWebhook != "" {
+		webhook, err := certmonitor.NewWebhookAlerter(coreConfig.CertificateMonitorWebhook, 10*time.Second)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to configure the certificate monitor")
+		}
+		alerters = append(alerters, webhook)
+	}
+	return certmonitor.New(
+		coreConfig.CertificateMonitorThresholds,
+		coreConfig.CertificateMonitorInterval,
+		certmonitor.NewMetrics(metricsProvider),
+		alerters...,
+	), nil
+}
+
+// startKeyExpiryMonitor aligns the lifetime of the keys of the enrollment and
+// TLS certificates of the peer with the expiration of the certificates, and
+// monitors the lifetime of the keys, when the crypto provider enforces it.
+func startKeyExpiryMonitor(csp bccsp.BCCSP, signingIdentity []byte, tlsCerts []certmonitor.Certificate, interval time.Duration, metricsProvider metrics.Provider) (*expiry.Monitor, error) {
+	expiring, ok := csp.(*expiry.CSP)
+	if !ok {
+		return nil, nil
+	}
+
+	sID := &mspproto.SerializedIdentity{}
+	if err := proto.Unmarshal(signingIdentity, sID); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling signing identity")
+	}
+	cert, err := certmonitor.ParseCertificate(sID.IdBytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid enrollment certificate")
+	}
+	certs := []*x509.Certificate{cert}
+	for _, c := range tlsCerts {
+		certs = append(certs, c.Cert)
+	}
+	if err := expiry.AlignWithCertificates(csp, certs...); err != nil {
+		return nil, errors.WithMessage(err, "failed aligning the lifetime of keys with their certificates")
+	}
+
+	m := expiry.NewMonitor(expiring, interval, expiry.NewMetrics(metricsProvider))
+	m.Start()
+	return m, nil
+}
+
+func newOperationsSystem(coreConfig *peer.Config, auditLog *audit.Log) *operations.System {
 	return operations.NewSystem(operations.Options{
 		Logger:        flogging.MustGetLogger("peer.operations"),
 		ListenAddress: coreConfig.OperationsListenAddress,
@@ -1225,7 +1501,13 @@ func newOperationsSystem(coreConfig *peer.Config) *operations.System {
 			ClientCertRequired: coreConfig.OperationsTLSClientAuthRequired,
 			ClientCACertFiles:  coreConfig.OperationsTLSClientRootCAs,
 		},
-		Version: metadata.Version,
+		Version:  metadata.Version,
+		AuditLog: auditLog,
+		Profiling: operations.ProfilingOptions{
+			Enabled:     coreConfig.OperationsProfilingEnabled,
+			MaxDuration: coreConfig.OperationsProfilingMaxDuration,
+			MaxCaptures: coreConfig.OperationsProfilingMaxCaptures,
+		},
 	})
 }
 
@@ -8,17 +8,30 @@ package node
 
 import (
 	"bytes"
+	"crypto/x509"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/common"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/expiry"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/certmonitor"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/hyperledger/fabric/core/handlers/library"
+	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/testutil"
 	"github.com/hyperledger/fabric/internal/peer/node/mock"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
 	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/mitchellh/mapstructure"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/viper"
@@ -166,6 +179,96 @@ func TestGetDockerHostConfig(t *testing.T) {
 	assert.Equal(t, int64(0), hostConfig.CPUShares)
 }
 
+func TestNewPackageSignatureVerifier(t *testing.T) {
+	verifier, err := newPackageSignatureVerifier(&peer.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, verifier)
+
+	tempDir, err := ioutil.TempDir("", "signing-roots")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ca, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	rootFile := filepath.Join(tempDir, "ca.pem")
+	err = ioutil.WriteFile(rootFile, ca.CertBytes(), 0644)
+	assert.NoError(t, err)
+
+	verifier, err = newPackageSignatureVerifier(&peer.Config{
+		ChaincodePackageSigningRoots:       []string{rootFile},
+		ChaincodePackageSignaturesRequired: true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, verifier.Required)
+
+	_, err = newPackageSignatureVerifier(&peer.Config{
+		ChaincodePackageSigningRoots: []string{filepath.Join(tempDir, "missing.pem")},
+	})
+	assert.Contains(t, err.Error(), "could not read chaincode package signing root")
+}
+
+func TestAuditKeyLoads(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	signingKP, err := ca.NewClientCertKeyPair()
+	assert.NoError(t, err)
+	serverKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+	signingIdentity := protoutil.MarshalOrPanic(&mspproto.SerializedIdentity{Mspid: "Org1MSP", IdBytes: signingKP.Cert})
+
+	buffer := &bytes.Buffer{}
+	auditKeyLoads(audit.NewLog(nil, audit.NewWriterSink(buffer)), signingIdentity, comm.SecureOptions{})
+	record, err := audit.Verify(bytes.NewReader(buffer.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), record.Sequence)
+	assert.Equal(t, audit.KeyLoad, record.Type)
+	assert.Equal(t, "signing", record.Attributes["purpose"])
+	assert.Equal(t, "Org1MSP", record.Attributes["mspID"])
+	assert.Equal(t, signingKP.TLSCert.SerialNumber.String(), record.Attributes["serial"])
+
+	buffer.Reset()
+	auditKeyLoads(audit.NewLog(nil, audit.NewWriterSink(buffer)), signingIdentity, comm.SecureOptions{UseTLS: true, Certificate: serverKP.Cert})
+	record, err = audit.Verify(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), record.Sequence)
+	assert.Equal(t, "tls", record.Attributes["purpose"])
+	assert.Equal(t, serverKP.TLSCert.SerialNumber.String(), record.Attributes["serial"])
+}
+
+func TestStartKeyExpiryMonitor(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	signingKP, err := ca.NewClientCertKeyPair()
+	assert.NoError(t, err)
+	serverKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+	signingIdentity := protoutil.MarshalOrPanic(&mspproto.SerializedIdentity{Mspid: "Org1MSP", IdBytes: signingKP.Cert})
+	tlsCerts, err := certmonitor.TLSCertificates("server TLS", serverKP.Cert)
+	assert.NoError(t, err)
+
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	m, err := startKeyExpiryMonitor(csp, signingIdentity, tlsCerts, time.Hour, &disabled.Provider{})
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	expiring, err := expiry.New(csp, expiry.Opts{Enabled: true})
+	assert.NoError(t, err)
+	m, err = startKeyExpiryMonitor(expiring, signingIdentity, tlsCerts, time.Hour, &disabled.Provider{})
+	assert.NoError(t, err)
+	assert.NotNil(t, m)
+	defer m.Stop()
+	for _, cert := range []*x509.Certificate{signingKP.TLSCert, serverKP.TLSCert} {
+		pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		notAfter, ok := expiring.KeyNotAfter(pk.SKI())
+		assert.True(t, ok)
+		assert.True(t, cert.NotAfter.Equal(notAfter))
+	}
+
+	_, err = startKeyExpiryMonitor(expiring, []byte("garbage"), nil, time.Hour, &disabled.Provider{})
+	assert.Error(t, err)
+}
+
 func TestResetLoop(t *testing.T) {
 	peerLedger := &mock.PeerLedger{}
 	peerLedger.GetBlockchainInfoReturnsOnCall(
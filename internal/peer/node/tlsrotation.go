@@ -0,0 +1,112 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/core/peer"
+	gossipcommon "github.com/hyperledger/fabric/gossip/common"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/pkg/errors"
+)
+
+// tlsRootCAsSetter sets the root CAs the peer is configured with.
+type tlsRootCAsSetter interface {
+	SetTLSRootCAs(serverRootCAs, clientRootCAs [][]byte) error
+}
+
+// tlsRotator rotates the TLS credentials of the peer server, of the clients
+// of the peer and of gossip.
+type tlsRotator struct {
+	rootCAs       tlsRootCAsSetter
+	credSupport   *comm.CredentialSupport
+	peerServer    *comm.GRPCServer
+	deliverClient *comm.GRPCClient
+	gossipCerts   *gossipcommon.TLSCertificates
+
+	mutex sync.Mutex
+	creds *comm.TLSCredentials
+}
+
+func newTLSRotator(
+	peerInstance *peer.Peer,
+	peerServer *comm.GRPCServer,
+	deliverClient *comm.GRPCClient,
+	gossipCerts *gossipcommon.TLSCertificates,
+) *tlsRotator {
+	clientCert := peerInstance.CredentialSupport.GetClientCertificate()
+	return &tlsRotator{
+		rootCAs:       peerInstance,
+		credSupport:   peerInstance.CredentialSupport,
+		peerServer:    peerServer,
+		deliverClient: deliverClient,
+		gossipCerts:   gossipCerts,
+		creds: &comm.TLSCredentials{
+			ServerCertificate: peerServer.ServerCertificate(),
+			ClientCertificate: &clientCert,
+			ServerRootCAs:     peerInstance.ServerConfig.SecOpts.ServerRootCAs,
+			ClientRootCAs:     peerInstance.ServerConfig.SecOpts.ClientRootCAs,
+		},
+	}
+}
+
+func (r *tlsRotator) TLSCredentials() *comm.TLSCredentials {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.creds
+}
+
+func (r *tlsRotator) LoadTLSCredentials() (*comm.TLSCredentials, error) {
+	serverConfig, err := peer.GetServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	serverCert, err := serverConfig.SecOpts.KeyPair()
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing TLS key pair")
+	}
+	clientCert, err := peer.GetClientCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &comm.TLSCredentials{
+		ServerCertificate: serverCert,
+		ClientCertificate: &clientCert,
+		ServerRootCAs:     serverConfig.SecOpts.ServerRootCAs,
+		ClientRootCAs:     serverConfig.SecOpts.ClientRootCAs,
+	}, nil
+}
+
+func (r *tlsRotator) RotateTLSCredentials(creds *comm.TLSCredentials) error {
+	if creds.ClientCertificate == nil {
+		return errors.New("a TLS client certificate is required")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.rootCAs.SetTLSRootCAs(creds.ServerRootCAs, creds.ClientRootCAs); err != nil {
+		return err
+	}
+
+	serverCert := creds.ServerCertificate
+	clientCert := *creds.ClientCertificate
+	r.peerServer.SetServerCertificate(serverCert)
+	r.credSupport.SetClientCertificate(clientCert)
+	if r.deliverClient.MutualTLSRequired() {
+		r.deliverClient.SetCertificate(clientCert)
+	}
+	if r.gossipCerts != nil {
+		r.gossipCerts.TLSServerCert.Store(&serverCert)
+		r.gossipCerts.TLSClientCert.Store(&clientCert)
+	}
+
+	r.creds = creds
+	return nil
+}
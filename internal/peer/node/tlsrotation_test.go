@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	gossipcommon "github.com/hyperledger/fabric/gossip/common"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTLSRootCAsSetter struct {
+	serverRootCAs [][]byte
+	clientRootCAs [][]byte
+	err           error
+}
+
+func (f *fakeTLSRootCAsSetter) SetTLSRootCAs(serverRootCAs, clientRootCAs [][]byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.serverRootCAs = serverRootCAs
+	f.clientRootCAs = clientRootCAs
+	return nil
+}
+
+func TestTLSRotatorLoadTLSCredentials(t *testing.T) {
+	defer viper.Reset()
+
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	serverKP, err := ca.NewServerCertKeyPair("localhost")
+	require.NoError(t, err)
+	clientKP, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+
+	tempDir, err := ioutil.TempDir("", "tlsrotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	for name, content := range map[string][]byte{
+		"server.crt": serverKP.Cert,
+		"server.key": serverKP.Key,
+		"client.crt": clientKP.Cert,
+		"client.key": clientKP.Key,
+		"ca.crt":     ca.CertBytes(),
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, name), content, 0600))
+	}
+
+	viper.Set("peer.tls.enabled", true)
+	viper.Set("peer.tls.cert.file", filepath.Join(tempDir, "server.crt"))
+	viper.Set("peer.tls.key.file", filepath.Join(tempDir, "server.key"))
+	viper.Set("peer.tls.rootcert.file", filepath.Join(tempDir, "ca.crt"))
+	viper.Set("peer.tls.clientAuthRequired", true)
+	viper.Set("peer.tls.clientRootCAs.files", []string{filepath.Join(tempDir, "ca.crt")})
+	viper.Set("peer.tls.clientCert.file", filepath.Join(tempDir, "client.crt"))
+	viper.Set("peer.tls.clientKey.file", filepath.Join(tempDir, "client.key"))
+
+	creds, err := (&tlsRotator{}).LoadTLSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, serverKP.TLSCert.Raw, creds.ServerCertificate.Certificate[0])
+	require.NotNil(t, creds.ClientCertificate)
+	assert.Equal(t, clientKP.TLSCert.Raw, creds.ClientCertificate.Certificate[0])
+	assert.Equal(t, [][]byte{ca.CertBytes()}, creds.ServerRootCAs)
+	assert.Equal(t, [][]byte{ca.CertBytes()}, creds.ClientRootCAs)
+
+	viper.Set("peer.tls.key.file", filepath.Join(tempDir, "client.key"))
+	_, err = (&tlsRotator{}).LoadTLSCredentials()
+	assert.EqualError(t, err, "error parsing TLS key pair: tls: private key does not match public key")
+
+	viper.Set("peer.tls.clientKey.file", filepath.Join(tempDir, "missing.key"))
+	viper.Set("peer.tls.key.file", filepath.Join(tempDir, "server.key"))
+	_, err = (&tlsRotator{}).LoadTLSCredentials()
+	assert.Contains(t, err.Error(), "error loading client TLS key")
+}
+
+func TestTLSRotatorRotateTLSCredentials(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	newCredentials := func() *comm.TLSCredentials {
+		serverKP, err := ca.NewServerCertKeyPair("localhost")
+		require.NoError(t, err)
+		serverCert, err := tls.X509KeyPair(serverKP.Cert, serverKP.Key)
+		require.NoError(t, err)
+		clientKP, err := ca.NewClientCertKeyPair()
+		require.NoError(t, err)
+		clientCert, err := tls.X509KeyPair(clientKP.Cert, clientKP.Key)
+		require.NoError(t, err)
+		return &comm.TLSCredentials{
+			ServerCertificate: serverCert,
+			ClientCertificate: &clientCert,
+			ServerRootCAs:     [][]byte{ca.CertBytes()},
+			ClientRootCAs:     [][]byte{ca.CertBytes()},
+		}
+	}
+	initial := newCredentials()
+
+	serverKP, err := ca.NewServerCertKeyPair("localhost")
+	require.NoError(t, err)
+	peerServer, err := comm.NewGRPCServer("localhost:0", comm.ServerConfig{
+		SecOpts: comm.SecureOptions{
+			UseTLS:      true,
+			Certificate: serverKP.Cert,
+			Key:         serverKP.Key,
+		},
+	})
+	require.NoError(t, err)
+	defer peerServer.Stop()
+
+	clientKP, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+	deliverClient, err := comm.NewGRPCClient(comm.ClientConfig{
+		SecOpts: comm.SecureOptions{
+			UseTLS:            true,
+			RequireClientCert: true,
+			Certificate:       clientKP.Cert,
+			Key:               clientKP.Key,
+		},
+	})
+	require.NoError(t, err)
+
+	rootCAs := &fakeTLSRootCAsSetter{}
+	credSupport := comm.NewCredentialSupport()
+	rotator := &tlsRotator{
+		rootCAs:       rootCAs,
+		credSupport:   credSupport,
+		peerServer:    peerServer,
+		deliverClient: deliverClient,
+		gossipCerts:   &gossipcommon.TLSCertificates{},
+		creds:         initial,
+	}
+	assert.Equal(t, initial, rotator.TLSCredentials())
+
+	creds := newCredentials()
+	err = rotator.RotateTLSCredentials(creds)
+	require.NoError(t, err)
+	assert.Equal(t, creds, rotator.TLSCredentials())
+	assert.Equal(t, creds.ServerRootCAs, rootCAs.serverRootCAs)
+	assert.Equal(t, creds.ClientRootCAs, rootCAs.clientRootCAs)
+	assert.Equal(t, creds.ServerCertificate, peerServer.ServerCertificate())
+	assert.Equal(t, *creds.ClientCertificate, credSupport.GetClientCertificate())
+	assert.Equal(t, *creds.ClientCertificate, deliverClient.Certificate())
+	assert.Equal(t, &creds.ServerCertificate, rotator.gossipCerts.TLSServerCert.Load())
+	assert.Equal(t, creds.ClientCertificate, rotator.gossipCerts.TLSClientCert.Load())
+
+	rootCAs.err = errors.New("boom")
+	err = rotator.RotateTLSCredentials(newCredentials())
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, creds, rotator.TLSCredentials())
+
+	err = rotator.RotateTLSCredentials(&comm.TLSCredentials{})
+	assert.EqualError(t, err, "a TLS client certificate is required")
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric/common/audit"
+	coreconfig "github.com/hyperledger/fabric/core/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var auditLogFile string
+
+func verifyAuditLogCmd() *cobra.Command {
+	nodeVerifyAuditLogCmd.ResetFlags()
+	flags := nodeVerifyAuditLogCmd.Flags()
+	flags.StringVarP(&auditLogFile, "file", "f", "", "Audit log file to verify. Defaults to the audit.file of the peer configuration.")
+
+	return nodeVerifyAuditLogCmd
+}
+
+var nodeVerifyAuditLogCmd = &cobra.Command{
+	Use:   "verify-audit-log",
+	Short: "Verifies the audit log of the peer.",
+	Long:  `Verifies that the records of the audit log of the peer are well formed and form an unbroken hash chain, starting with the first record of the file. Altered, removed and reordered records are reported. The command can be executed while the peer is running.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := auditLogFile
+		if path == "" {
+			path = coreconfig.GetPath("audit.file")
+		}
+		if path == "" {
+			return errors.New("Must supply the audit log file")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.Wrap(err, "failed opening audit log")
+		}
+		defer file.Close()
+
+		last, err := audit.Verify(file)
+		if err != nil {
+			return errors.WithMessagef(err, "the audit log %s is not valid", path)
+		}
+		w := cmd.OutOrStdout()
+		if last == nil {
+			fmt.Fprintln(w, "Records verified: 0")
+			return nil
+		}
+		fmt.Fprintf(w, "Records verified: %d\n", last.Sequence)
+		fmt.Fprintf(w, "Last record: %s at %s\n", last.Hash, last.Timestamp)
+		return nil
+	},
+}
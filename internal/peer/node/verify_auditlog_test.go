@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAuditLogCmd(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "verify-audit-log")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "audit.log")
+
+	t.Run("when the file is not supplied", func(t *testing.T) {
+		cmd := verifyAuditLogCmd()
+		cmd.SetArgs([]string{})
+		err := cmd.Execute()
+		require.EqualError(t, err, "Must supply the audit log file")
+	})
+
+	t.Run("when the file does not exist", func(t *testing.T) {
+		cmd := verifyAuditLogCmd()
+		cmd.SetArgs([]string{"-f", path})
+		err := cmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed opening audit log")
+	})
+
+	log, err := audit.Open(path, false)
+	require.NoError(t, err)
+	log.Record(audit.Event{Type: audit.KeyLoad})
+	log.Record(audit.Event{Type: audit.ChannelJoin, Channel: "mychannel"})
+	require.NoError(t, log.Close())
+
+	t.Run("when the audit log is valid", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		cmd := verifyAuditLogCmd()
+		cmd.SetOutput(buf)
+		cmd.SetArgs([]string{"-f", path})
+		err := cmd.Execute()
+		require.NoError(t, err)
+		require.Contains(t, buf.String(), "Records verified: 2\nLast record: ")
+	})
+
+	t.Run("when the audit log was altered", func(t *testing.T) {
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		altered := strings.Replace(string(content), "mychannel", "otherchannel", 1)
+		require.NoError(t, ioutil.WriteFile(path, []byte(altered), 0600))
+
+		cmd := verifyAuditLogCmd()
+		cmd.SetArgs([]string{"--file", path})
+		err = cmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not valid: the record on line 2 has hash")
+	})
+}
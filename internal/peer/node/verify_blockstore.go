@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/core/ledger/kvledger"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var repairBlockStore bool
+
+func verifyBlockStoreCmd() *cobra.Command {
+	nodeVerifyBlockStoreCmd.ResetFlags()
+	flags := nodeVerifyBlockStoreCmd.Flags()
+	flags.StringVarP(&channelID, "channelID", "c", common.UndefinedParamValue, "Channel whose block store is to be verified.")
+	flags.BoolVarP(&repairBlockStore, "repair", "r", false, "Truncate a partially written last block and rebuild the block index, if found inconsistent.")
+
+	return nodeVerifyBlockStoreCmd
+}
+
+var nodeVerifyBlockStoreCmd = &cobra.Command{
+	Use:   "verify-blockstore",
+	Short: "Verifies the block store of a channel.",
+	Long:  `Verifies that the blocks of a channel are readable and form an unbroken hash chain, and that the block index matches the block files. With the repair flag, a partially written block left at the end of the block files by an unclean shutdown is truncated and the block index is rebuilt, if found inconsistent. The blocks that break the hash chain cannot be repaired; the channel needs to be rolled back to a preceding block instead. When the command is executed, the peer must be offline.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if channelID == common.UndefinedParamValue {
+			return errors.New("Must supply channel ID")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+
+		config := ledgerConfig()
+		report, err := kvledger.VerifyBlockStore(config.RootFSPath, channelID, repairBlockStore)
+		if report != nil {
+			printVerificationReport(cmd.OutOrStdout(), report)
+		}
+		if err != nil {
+			return err
+		}
+		if !report.Healthy() {
+			return errors.Errorf("the block store of channel [%s] has problems", channelID)
+		}
+		return nil
+	},
+}
+
+func printVerificationReport(w io.Writer, report *blkstorage.VerificationReport) {
+	fmt.Fprintf(w, "Channel: %s\n", report.LedgerID)
+	fmt.Fprintf(w, "Blocks verified: %d\n", report.BlocksVerified)
+	if report.BlocksVerified > 0 {
+		fmt.Fprintf(w, "Block range: [%d, %d]\n", report.FirstBlockNum, report.LastBlockNum)
+	}
+	if report.IndexEmpty {
+		fmt.Fprintln(w, "Last block indexed: none")
+	} else {
+		fmt.Fprintf(w, "Last block indexed: %d\n", report.LastBlockIndexed)
+	}
+	if t := report.TornTail; t != nil {
+		fmt.Fprintf(w, "Partially written block: %d bytes at offset %d of block file %d\n", t.Size, t.Offset, t.FileNum)
+	}
+	for _, e := range report.ChainErrors {
+		fmt.Fprintf(w, "Chain error: %s\n", e)
+	}
+	for _, e := range report.IndexErrors {
+		fmt.Fprintf(w, "Index error: %s\n", e)
+	}
+	if report.Healthy() {
+		fmt.Fprintln(w, "No problems found")
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBlockStoreCmd(t *testing.T) {
+	testPath, err := ioutil.TempDir("", "verifyblockstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(testPath)
+	viper.Set("peer.fileSystemPath", testPath)
+	defer viper.Set("peer.fileSystemPath", "")
+
+	t.Run("when the channelID is not supplied", func(t *testing.T) {
+		cmd := verifyBlockStoreCmd()
+		cmd.SetArgs([]string{})
+		err := cmd.Execute()
+		require.EqualError(t, err, "Must supply channel ID")
+	})
+
+	t.Run("when the specified channelID does not exist", func(t *testing.T) {
+		cmd := verifyBlockStoreCmd()
+		cmd.SetArgs([]string{"-c", "ch1", "--repair"})
+		err := cmd.Execute()
+		require.EqualError(t, err, "ledgerID [ch1] does not exist")
+	})
+}
+
+func TestPrintVerificationReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printVerificationReport(buf, &blkstorage.VerificationReport{
+		LedgerID:       "ch1",
+		BlocksVerified: 10,
+		LastBlockNum:   9,
+		IndexEmpty:     true,
+		TornTail:       &blkstorage.TornTail{FileNum: 1, Offset: 100, Size: 4},
+		ChainErrors:    []string{"chain-error"},
+		IndexErrors:    []string{"index-error"},
+	})
+	require.Equal(t, `Channel: ch1
+Blocks verified: 10
+Block range: [0, 9]
+Last block indexed: none
+Partially written block: 4 bytes at offset 100 of block file 1
+Chain error: chain-error
+Index error: index-error
+`, buf.String())
+
+	buf.Reset()
+	printVerificationReport(buf, &blkstorage.VerificationReport{LedgerID: "ch1", LastBlockIndexed: 5})
+	require.Equal(t, "Channel: ch1\nBlocks verified: 0\nLast block indexed: 5\nNo problems found\n", buf.String())
+}
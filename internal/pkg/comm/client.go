@@ -86,10 +86,8 @@ func (client *GRPCClient) parseSecureOptions(opts SecureOptions) error {
 	}
 	if opts.RequireClientCert {
 		// make sure we have both Key and Certificate
-		if opts.Key != nil &&
-			opts.Certificate != nil {
-			cert, err := tls.X509KeyPair(opts.Certificate,
-				opts.Key)
+		if opts.hasKeyPair() {
+			cert, err := opts.KeyPair()
 			if err != nil {
 				return errors.WithMessage(err, "failed to "+
 					"load client certificate")
@@ -121,6 +119,15 @@ func (client *GRPCClient) Certificate() tls.Certificate {
 	return cert
 }
 
+// SetCertificate sets the tls.Certificate used to make TLS connections
+// when client certificates are required by the server. Connections
+// established before keep using the previous certificate.
+func (client *GRPCClient) SetCertificate(cert tls.Certificate) {
+	if client.tlsConfig != nil {
+		client.tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+}
+
 // TLSEnabled is a flag indicating whether to use TLS for client
 // connections
 func (client *GRPCClient) TLSEnabled() bool {
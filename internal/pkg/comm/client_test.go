@@ -429,6 +429,32 @@ func TestSetServerRootCAs(t *testing.T) {
 	assert.Contains(t, err.Error(), "error adding root certificate")
 }
 
+func TestSetCertificate(t *testing.T) {
+	t.Parallel()
+	testCerts := loadCerts(t)
+
+	client, err := comm.NewGRPCClient(comm.ClientConfig{
+		SecOpts: comm.SecureOptions{
+			UseTLS:            true,
+			RequireClientCert: true,
+			Certificate:       testCerts.certPEM,
+			Key:               testCerts.keyPEM,
+		},
+		Timeout: testTimeout,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testCerts.clientCert, client.Certificate())
+
+	client.SetCertificate(testCerts.serverCert)
+	assert.Equal(t, testCerts.serverCert, client.Certificate())
+
+	// the certificate of clients without TLS is not set
+	client, err = comm.NewGRPCClient(comm.ClientConfig{Timeout: testTimeout})
+	require.NoError(t, err)
+	client.SetCertificate(testCerts.serverCert)
+	assert.Equal(t, tls.Certificate{}, client.Certificate())
+}
+
 func TestSetMessageSize(t *testing.T) {
 	t.Parallel()
 
@@ -7,12 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package comm
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
 )
@@ -58,6 +62,9 @@ type ServerConfig struct {
 	// UnaryInterceptors specifies a list of interceptors to apply to unary
 	// RPCs.  They are executed in order.
 	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// Codec specifies the codec used to encode and decode messages. When nil,
+	// the proto codec of gRPC is used.
+	Codec grpc.Codec
 	// Logger specifies the logger the server will use
 	Logger *flogging.FabricLogger
 	// HealthCheckEnabled enables the gRPC Health Checking Protocol for the server
@@ -96,6 +103,9 @@ type SecureOptions struct {
 	Certificate []byte
 	// PEM-encoded private key to be used for TLS communication
 	Key []byte
+	// Signer, if not nil, is used instead of Key to sign the TLS handshakes,
+	// e.g., with a private key that is held in an HSM
+	Signer crypto.Signer
 	// Set of PEM-encoded X509 certificate authorities used by clients to
 	// verify server certificates
 	ServerRootCAs [][]byte
@@ -110,6 +120,60 @@ type SecureOptions struct {
 	CipherSuites []uint16
 	// TimeShift makes TLS handshakes time sampling shift to the past by a given duration
 	TimeShift time.Duration
+	// SessionResumption makes servers issue TLS session tickets, which clients
+	// use to resume their sessions without a full handshake when they reconnect
+	SessionResumption bool
+}
+
+// hasKeyPair returns whether the options contain a certificate and a private key
+func (so SecureOptions) hasKeyPair() bool {
+	return so.Certificate != nil && (so.Key != nil || so.Signer != nil)
+}
+
+// KeyPair returns the TLS certificate of the options, whose private key is
+// the Signer when set, or the Key otherwise
+func (so SecureOptions) KeyPair() (tls.Certificate, error) {
+	if so.Signer == nil {
+		return tls.X509KeyPair(so.Certificate, so.Key)
+	}
+
+	var cert tls.Certificate
+	rest := so.Certificate
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, errors.New("failed to find any PEM data in certificate input")
+	}
+	x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "failed to parse certificate")
+	}
+	if !publicKeysEqual(x509Cert.PublicKey, so.Signer.Public()) {
+		return tls.Certificate{}, errors.New("the public key of the signer does not match the certificate")
+	}
+	cert.PrivateKey = so.Signer
+	cert.Leaf = x509Cert
+	return cert, nil
+}
+
+func publicKeysEqual(pub1, pub2 crypto.PublicKey) bool {
+	der1, err := x509.MarshalPKIXPublicKey(pub1)
+	if err != nil {
+		return false
+	}
+	der2, err := x509.MarshalPKIXPublicKey(pub2)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(der1, der2)
 }
 
 // KeepaliveOptions is used to set the gRPC keepalive settings for both
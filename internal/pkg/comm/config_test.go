@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -106,3 +107,37 @@ func TestClientConfigClone(t *testing.T) {
 	assert.Equal(t, expectedOriginState, origin)
 	assert.Equal(t, expectedCloneState, clone)
 }
+
+func TestSecureOptionsKeyPair(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	kp, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+	otherKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+
+	secOpts := SecureOptions{Certificate: kp.Cert, Key: kp.Key}
+	assert.True(t, secOpts.hasKeyPair())
+	cert, err := secOpts.KeyPair()
+	assert.NoError(t, err)
+	assert.Len(t, cert.Certificate, 1)
+
+	// the signer takes the place of the key
+	secOpts = SecureOptions{Certificate: kp.Cert, Signer: kp.Signer}
+	assert.True(t, secOpts.hasKeyPair())
+	cert, err = secOpts.KeyPair()
+	assert.NoError(t, err)
+	assert.Equal(t, kp.Signer, cert.PrivateKey)
+	assert.Equal(t, kp.TLSCert.Raw, cert.Leaf.Raw)
+
+	secOpts = SecureOptions{Certificate: kp.Cert, Signer: otherKP.Signer}
+	_, err = secOpts.KeyPair()
+	assert.EqualError(t, err, "the public key of the signer does not match the certificate")
+
+	secOpts = SecureOptions{Certificate: []byte("garbage"), Signer: kp.Signer}
+	_, err = secOpts.KeyPair()
+	assert.EqualError(t, err, "failed to find any PEM data in certificate input")
+
+	secOpts = SecureOptions{Certificate: kp.Cert}
+	assert.False(t, secOpts.hasKeyPair())
+}
@@ -25,6 +25,7 @@ type CredentialSupport struct {
 	appRootCAsByChain map[string][][]byte
 	serverRootCAs     [][]byte
 	clientCert        tls.Certificate
+	sessionCache      tls.ClientSessionCache
 }
 
 // NewCredentialSupport creates a CredentialSupport instance.
@@ -43,6 +44,22 @@ func (cs *CredentialSupport) SetClientCertificate(cert tls.Certificate) {
 	cs.mutex.Unlock()
 }
 
+// SetServerRootCAs sets the root CAs, besides those of the channels, used to
+// verify the certificates of the servers of gRPC client connections
+func (cs *CredentialSupport) SetServerRootCAs(rootCAs [][]byte) {
+	cs.mutex.Lock()
+	cs.serverRootCAs = rootCAs
+	cs.mutex.Unlock()
+}
+
+// EnableSessionResumption makes the gRPC client connections resume the TLS
+// sessions of the given number of remote endpoints they connected to before
+func (cs *CredentialSupport) EnableSessionResumption(capacity int) {
+	cs.mutex.Lock()
+	cs.sessionCache = tls.NewLRUClientSessionCache(capacity)
+	cs.mutex.Unlock()
+}
+
 // GetClientCertificate returns the client certificate of the CredentialSupport
 func (cs *CredentialSupport) GetClientCertificate() tls.Certificate {
 	cs.mutex.RLock()
@@ -71,8 +88,9 @@ func (cs *CredentialSupport) GetPeerCredentials() credentials.TransportCredentia
 	}
 
 	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cs.clientCert},
-		RootCAs:      certPool,
+		Certificates:       []tls.Certificate{cs.clientCert},
+		RootCAs:            certPool,
+		ClientSessionCache: cs.sessionCache,
 	})
 }
 
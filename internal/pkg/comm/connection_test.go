@@ -96,7 +96,8 @@ func TestCredentialSupport(t *testing.T) {
 	cs.appRootCAsByChain["channel1"] = [][]byte{rootCAs[0]}
 	cs.appRootCAsByChain["channel2"] = [][]byte{rootCAs[1]}
 	cs.appRootCAsByChain["channel3"] = [][]byte{rootCAs[2]}
-	cs.serverRootCAs = [][]byte{rootCAs[5]}
+	cs.SetServerRootCAs([][]byte{rootCAs[5]})
+	assert.Equal(t, [][]byte{rootCAs[5]}, cs.serverRootCAs)
 
 	creds := cs.GetPeerCredentials()
 	assert.Equal(t, "1.2", creds.Info().SecurityVersion,
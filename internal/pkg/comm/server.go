@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package comm
 
 import (
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"net"
@@ -69,9 +70,9 @@ func NewGRPCServerFromListener(listener net.Listener, serverConfig ServerConfig)
 	secureConfig := serverConfig.SecOpts
 	if secureConfig.UseTLS {
 		//both key and cert are required
-		if secureConfig.Key != nil && secureConfig.Certificate != nil {
+		if secureConfig.hasKeyPair() {
 			//load server public and private keys
-			cert, err := tls.X509KeyPair(secureConfig.Certificate, secureConfig.Key)
+			cert, err := secureConfig.KeyPair()
 			if err != nil {
 				return nil, err
 			}
@@ -90,10 +91,18 @@ func NewGRPCServerFromListener(listener net.Listener, serverConfig ServerConfig)
 			grpcServer.tls = NewTLSConfig(&tls.Config{
 				VerifyPeerCertificate:  secureConfig.VerifyCertificate,
 				GetCertificate:         getCert,
-				SessionTicketsDisabled: true,
+				SessionTicketsDisabled: !secureConfig.SessionResumption,
 				CipherSuites:           secureConfig.CipherSuites,
 			})
 
+			if secureConfig.SessionResumption {
+				// The TLS config is cloned for every handshake, hence the session tickets
+				// are encrypted with a fixed key in order for the sessions to be resumable
+				if _, err := rand.Read(grpcServer.tls.config.SessionTicketKey[:]); err != nil {
+					return nil, errors.Wrap(err, "failed generating TLS session ticket key")
+				}
+			}
+
 			if serverConfig.SecOpts.TimeShift > 0 {
 				timeShift := serverConfig.SecOpts.TimeShift
 				grpcServer.tls.config.Time = func() time.Time {
@@ -151,6 +160,10 @@ func NewGRPCServerFromListener(listener net.Listener, serverConfig ServerConfig)
 		)
 	}
 
+	if serverConfig.Codec != nil {
+		serverOpts = append(serverOpts, grpc.CustomCodec(serverConfig.Codec))
+	}
+
 	if serverConfig.ServerStatsHandler != nil {
 		serverOpts = append(serverOpts, grpc.StatsHandler(serverConfig.ServerStatsHandler))
 	}
@@ -735,6 +735,50 @@ func TestVerifyCertificateCallback(t *testing.T) {
 	})
 }
 
+func TestMutualTLSWithSigners(t *testing.T) {
+	t.Parallel()
+
+	ca, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	serverKeyPair, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+	clientKeyPair, err := ca.NewClientCertKeyPair()
+	assert.NoError(t, err)
+
+	// the private keys never leave the signers, as when they are held by an HSM
+	srv, err := comm.NewGRPCServer("127.0.0.1:", comm.ServerConfig{
+		SecOpts: comm.SecureOptions{
+			Certificate:       serverKeyPair.Cert,
+			Signer:            serverKeyPair.Signer,
+			UseTLS:            true,
+			RequireClientCert: true,
+			ClientRootCAs:     [][]byte{ca.CertBytes()},
+		},
+	})
+	assert.NoError(t, err)
+	testpb.RegisterEmptyServiceServer(srv.Server(), &emptyServiceServer{})
+	go srv.Start()
+	defer srv.Stop()
+
+	client, err := comm.NewGRPCClient(comm.ClientConfig{
+		Timeout: time.Second,
+		SecOpts: comm.SecureOptions{
+			Certificate:       clientKeyPair.Cert,
+			Signer:            clientKeyPair.Signer,
+			UseTLS:            true,
+			RequireClientCert: true,
+			ServerRootCAs:     [][]byte{ca.CertBytes()},
+		},
+	})
+	assert.NoError(t, err)
+	conn, err := client.NewConnection(srv.Address())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), &testpb.Empty{})
+	assert.NoError(t, err)
+}
+
 // prior tests used self-signed certficates loaded by the GRPCServer and the test client
 // here we'll use certificates signed by certificate authorities
 func TestWithSignedRootCertificates(t *testing.T) {
@@ -1227,6 +1271,55 @@ func TestCipherSuites(t *testing.T) {
 	}
 }
 
+func TestSessionResumption(t *testing.T) {
+	t.Parallel()
+
+	certPEM, err := ioutil.ReadFile(filepath.Join("testdata", "certs", "Org1-server1-cert.pem"))
+	assert.NoError(t, err)
+	keyPEM, err := ioutil.ReadFile(filepath.Join("testdata", "certs", "Org1-server1-key.pem"))
+	assert.NoError(t, err)
+	caPEM, err := ioutil.ReadFile(filepath.Join("testdata", "certs", "Org1-cert.pem"))
+	assert.NoError(t, err)
+	certPool, err := createCertPool([][]byte{caPEM})
+	assert.NoError(t, err)
+
+	for _, sessionResumption := range []bool{true, false} {
+		sessionResumption := sessionResumption
+		t.Run(fmt.Sprintf("session resumption %t", sessionResumption), func(t *testing.T) {
+			t.Parallel()
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err, "listen failed")
+			srv, err := comm.NewGRPCServerFromListener(lis, comm.ServerConfig{
+				SecOpts: comm.SecureOptions{
+					Certificate:       certPEM,
+					Key:               keyPEM,
+					UseTLS:            true,
+					SessionResumption: sessionResumption,
+				}})
+			assert.NoError(t, err)
+			go srv.Start()
+			defer srv.Stop()
+
+			// TLS 1.2 is used as TLS 1.3 session tickets are sent after the handshake
+			tlsConfig := &tls.Config{
+				RootCAs:            certPool,
+				MaxVersion:         tls.VersionTLS12,
+				ClientSessionCache: tls.NewLRUClientSessionCache(1),
+			}
+			conn, err := tls.Dial("tcp", lis.Addr().String(), tlsConfig)
+			assert.NoError(t, err)
+			assert.False(t, conn.ConnectionState().DidResume)
+			conn.Close()
+
+			conn, err = tls.Dial("tcp", lis.Addr().String(), tlsConfig)
+			assert.NoError(t, err)
+			assert.Equal(t, sessionResumption, conn.ConnectionState().DidResume)
+			conn.Close()
+		})
+	}
+}
+
 func TestServerInterceptors(t *testing.T) {
 	lis, err := net.Listen("tcp", "127.0.0.1:0")
 	assert.NoError(t, err, "listen failed")
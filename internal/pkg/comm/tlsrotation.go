@@ -0,0 +1,335 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+// TLSCredentials are the TLS credentials of a node that are rotated while the
+// node runs.
+type TLSCredentials struct {
+	// ServerCertificate is the certificate presented by the server of the node.
+	ServerCertificate tls.Certificate
+	// ClientCertificate is the certificate presented by the node to the servers
+	// it connects to, or nil if the node has no client certificate to rotate.
+	ClientCertificate *tls.Certificate
+	// ServerRootCAs are the PEM encoded authorities the node verifies the
+	// certificates of servers with.
+	ServerRootCAs [][]byte
+	// ClientRootCAs are the PEM encoded authorities the node verifies the
+	// certificates of clients with, besides the authorities of its channels.
+	ClientRootCAs [][]byte
+}
+
+// TLSRotator rotates the TLS credentials of a node.
+type TLSRotator interface {
+	// TLSCredentials returns the TLS credentials in use.
+	TLSCredentials() *TLSCredentials
+	// LoadTLSCredentials loads the TLS credentials from the files the node is
+	// configured with.
+	LoadTLSCredentials() (*TLSCredentials, error)
+	// RotateTLSCredentials replaces the TLS credentials in use. The connections
+	// established before keep the credentials they were established with.
+	RotateTLSCredentials(creds *TLSCredentials) error
+}
+
+// TLSRotationRequest is the JSON body of a request to rotate the TLS
+// credentials of a node. The fields left empty keep their current value, and
+// the credentials are reloaded from files when all the fields are empty.
+type TLSRotationRequest struct {
+	ServerCertificate string   `json:"serverCertificate,omitempty"`
+	ServerKey         string   `json:"serverKey,omitempty"`
+	ClientCertificate string   `json:"clientCertificate,omitempty"`
+	ClientKey         string   `json:"clientKey,omitempty"`
+	ServerRootCAs     []string `json:"serverRootCAs,omitempty"`
+	ClientRootCAs     []string `json:"clientRootCAs,omitempty"`
+}
+
+// CertificateInfo is the JSON representation of a certificate.
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	NotAfter     time.Time `json:"notAfter"`
+}
+
+// TLSCredentialsInfo is the JSON representation of the TLS credentials of a node.
+type TLSCredentialsInfo struct {
+	ServerCertificate *CertificateInfo  `json:"serverCertificate"`
+	ClientCertificate *CertificateInfo  `json:"clientCertificate,omitempty"`
+	ServerRootCAs     []CertificateInfo `json:"serverRootCAs"`
+	ClientRootCAs     []CertificateInfo `json:"clientRootCAs"`
+}
+
+type tlsRotationErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewTLSRotationHandler returns a handler that reports the TLS credentials of
+// the given TLSRotator upon GET requests, and rotates them upon PUT requests.
+func NewTLSRotationHandler(rotator TLSRotator) *TLSRotationHandler {
+	return &TLSRotationHandler{
+		Rotator: rotator,
+		Logger:  flogging.MustGetLogger("comm.tlsrotation"),
+	}
+}
+
+type TLSRotationHandler struct {
+	Rotator TLSRotator
+	Logger  *flogging.FabricLogger
+	// AuditLog records the rotations, when set.
+	AuditLog *audit.Log
+
+	// mutex serializes the rotations
+	mutex sync.Mutex
+}
+
+func (h *TLSRotationHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPut:
+		request := &TLSRotationRequest{}
+		if err := json.NewDecoder(req.Body).Decode(request); err != nil && err != io.EOF {
+			h.sendResponse(resp, http.StatusBadRequest, err)
+			return
+		}
+		req.Body.Close()
+
+		var actor string
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			actor = req.TLS.PeerCertificates[0].Subject.String()
+		}
+		code, err := h.rotate(request, actor)
+		if err != nil {
+			h.sendResponse(resp, code, err)
+			return
+		}
+		h.sendResponse(resp, http.StatusOK, credentialsInfo(h.Rotator.TLSCredentials()))
+
+	case http.MethodGet:
+		h.sendResponse(resp, http.StatusOK, credentialsInfo(h.Rotator.TLSCredentials()))
+
+	default:
+		err := fmt.Errorf("invalid request method: %s", req.Method)
+		h.sendResponse(resp, http.StatusBadRequest, err)
+	}
+}
+
+func (h *TLSRotationHandler) rotate(request *TLSRotationRequest, actor string) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var creds *TLSCredentials
+	source := "request"
+	if request.empty() {
+		source = "files"
+		loaded, err := h.Rotator.LoadTLSCredentials()
+		if err != nil {
+			return http.StatusInternalServerError, errors.WithMessage(err, "failed loading TLS credentials")
+		}
+		creds = loaded
+	} else {
+		merged, err := request.apply(h.Rotator.TLSCredentials())
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		creds = merged
+	}
+
+	if err := validateTLSCredentials(creds, time.Now()); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := h.Rotator.RotateTLSCredentials(creds); err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed rotating TLS credentials")
+	}
+
+	h.Logger.Infof("Rotated TLS credentials, the server certificate expires at %s", creds.ServerCertificate.Leaf.NotAfter)
+	attributes := map[string]string{
+		"source":             source,
+		"serverCertSubject":  creds.ServerCertificate.Leaf.Subject.String(),
+		"serverCertSerial":   creds.ServerCertificate.Leaf.SerialNumber.String(),
+		"serverCertNotAfter": creds.ServerCertificate.Leaf.NotAfter.UTC().Format(time.RFC3339),
+	}
+	if creds.ClientCertificate != nil {
+		attributes["clientCertSubject"] = creds.ClientCertificate.Leaf.Subject.String()
+		attributes["clientCertSerial"] = creds.ClientCertificate.Leaf.SerialNumber.String()
+	}
+	h.AuditLog.Record(audit.Event{
+		Type:       audit.KeyRotate,
+		Actor:      actor,
+		Attributes: attributes,
+	})
+	return http.StatusOK, nil
+}
+
+func (h *TLSRotationHandler) sendResponse(resp http.ResponseWriter, code int, payload interface{}) {
+	encoder := json.NewEncoder(resp)
+	if err, ok := payload.(error); ok {
+		payload = &tlsRotationErrorResponse{Error: err.Error()}
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+
+	if err := encoder.Encode(payload); err != nil {
+		h.Logger.Errorw("failed to encode payload", "error", err)
+	}
+}
+
+func (r *TLSRotationRequest) empty() bool {
+	return r.ServerCertificate == "" && r.ServerKey == "" &&
+		r.ClientCertificate == "" && r.ClientKey == "" &&
+		r.ServerRootCAs == nil && r.ClientRootCAs == nil
+}
+
+// apply returns a copy of the given credentials, with the fields set in the
+// request replaced.
+func (r *TLSRotationRequest) apply(current *TLSCredentials) (*TLSCredentials, error) {
+	creds := *current
+
+	if r.ServerCertificate != "" || r.ServerKey != "" {
+		cert, err := tls.X509KeyPair([]byte(r.ServerCertificate), []byte(r.ServerKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid server certificate and key")
+		}
+		creds.ServerCertificate = cert
+	}
+
+	if r.ClientCertificate != "" || r.ClientKey != "" {
+		if current.ClientCertificate == nil {
+			return nil, errors.New("the node has no TLS client certificate to rotate")
+		}
+		cert, err := tls.X509KeyPair([]byte(r.ClientCertificate), []byte(r.ClientKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate and key")
+		}
+		creds.ClientCertificate = &cert
+	}
+
+	if r.ServerRootCAs != nil {
+		creds.ServerRootCAs = pemBytes(r.ServerRootCAs)
+	}
+	if r.ClientRootCAs != nil {
+		creds.ClientRootCAs = pemBytes(r.ClientRootCAs)
+	}
+
+	return &creds, nil
+}
+
+func pemBytes(pems []string) [][]byte {
+	result := make([][]byte, 0, len(pems))
+	for _, p := range pems {
+		result = append(result, []byte(p))
+	}
+	return result
+}
+
+// validateTLSCredentials checks that the certificates of the credentials are
+// valid at the given time, and that the root CAs contain certificates. The
+// leaves of the certificates are parsed in the process.
+func validateTLSCredentials(creds *TLSCredentials, now time.Time) error {
+	if err := parseLeaf(&creds.ServerCertificate, now); err != nil {
+		return errors.WithMessage(err, "invalid server certificate")
+	}
+	if creds.ClientCertificate != nil {
+		if err := parseLeaf(creds.ClientCertificate, now); err != nil {
+			return errors.WithMessage(err, "invalid client certificate")
+		}
+	}
+
+	for _, roots := range []struct {
+		name string
+		pems [][]byte
+	}{
+		{"server root CA", creds.ServerRootCAs},
+		{"client root CA", creds.ClientRootCAs},
+	} {
+		for _, pem := range roots.pems {
+			certs, err := pemToX509Certs(pem)
+			if err != nil {
+				return errors.Wrapf(err, "invalid %s", roots.name)
+			}
+			if len(certs) == 0 {
+				return errors.Errorf("invalid %s: no certificates found", roots.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseLeaf(cert *tls.Certificate, now time.Time) error {
+	if len(cert.Certificate) == 0 {
+		return errors.New("no certificate found")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return errors.Wrap(err, "failed parsing certificate")
+	}
+	if now.After(leaf.NotAfter) {
+		return errors.Errorf("certificate expired at %s", leaf.NotAfter)
+	}
+	cert.Leaf = leaf
+	return nil
+}
+
+func credentialsInfo(creds *TLSCredentials) *TLSCredentialsInfo {
+	info := &TLSCredentialsInfo{
+		ServerCertificate: tlsCertificateInfo(creds.ServerCertificate),
+		ServerRootCAs:     rootCAsInfo(creds.ServerRootCAs),
+		ClientRootCAs:     rootCAsInfo(creds.ClientRootCAs),
+	}
+	if creds.ClientCertificate != nil {
+		info.ClientCertificate = tlsCertificateInfo(*creds.ClientCertificate)
+	}
+	return info
+}
+
+func tlsCertificateInfo(cert tls.Certificate) *CertificateInfo {
+	if len(cert.Certificate) == 0 {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil
+	}
+	return certificateInfo(leaf)
+}
+
+func rootCAsInfo(pems [][]byte) []CertificateInfo {
+	infos := []CertificateInfo{}
+	for _, pem := range pems {
+		certs, err := pemToX509Certs(pem)
+		if err != nil {
+			continue
+		}
+		for _, cert := range certs {
+			infos = append(infos, *certificateInfo(cert))
+		}
+	}
+	return infos
+}
+
+func certificateInfo(cert *x509.Certificate) *CertificateInfo {
+	return &CertificateInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		NotAfter:     cert.NotAfter,
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tlsRotatorMock struct {
+	creds   *TLSCredentials
+	loaded  *TLSCredentials
+	loadErr error
+	rotErr  error
+}
+
+func (r *tlsRotatorMock) TLSCredentials() *TLSCredentials {
+	return r.creds
+}
+
+func (r *tlsRotatorMock) LoadTLSCredentials() (*TLSCredentials, error) {
+	return r.loaded, r.loadErr
+}
+
+func (r *tlsRotatorMock) RotateTLSCredentials(creds *TLSCredentials) error {
+	if r.rotErr != nil {
+		return r.rotErr
+	}
+	r.creds = creds
+	return nil
+}
+
+func newTLSCredentials(t *testing.T, ca tlsgen.CA) *TLSCredentials {
+	serverKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	serverCert, err := tls.X509KeyPair(serverKP.Cert, serverKP.Key)
+	require.NoError(t, err)
+	clientKP, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+	clientCert, err := tls.X509KeyPair(clientKP.Cert, clientKP.Key)
+	require.NoError(t, err)
+
+	return &TLSCredentials{
+		ServerCertificate: serverCert,
+		ClientCertificate: &clientCert,
+		ServerRootCAs:     [][]byte{ca.CertBytes()},
+		ClientRootCAs:     [][]byte{ca.CertBytes()},
+	}
+}
+
+func caSubject(t *testing.T, ca tlsgen.CA) string {
+	certs, err := pemToX509Certs(ca.CertBytes())
+	require.NoError(t, err)
+	return certs[0].Subject.String()
+}
+
+func TestTLSRotationHandler(t *testing.T) {
+	ca1, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	ca2, err := tlsgen.NewCA()
+	require.NoError(t, err)
+
+	rotator := &tlsRotatorMock{
+		creds:  newTLSCredentials(t, ca1),
+		loaded: newTLSCredentials(t, ca2),
+	}
+	handler := NewTLSRotationHandler(rotator)
+	auditBuffer := &bytes.Buffer{}
+	handler.AuditLog = audit.NewLog(nil, audit.NewWriterSink(auditBuffer))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/tls", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	info := &TLSCredentialsInfo{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), info))
+	assert.Equal(t, caSubject(t, ca1), info.ServerCertificate.Issuer)
+	require.NotNil(t, info.ClientCertificate)
+	require.Len(t, info.ServerRootCAs, 1)
+	require.Len(t, info.ClientRootCAs, 1)
+
+	// an empty request reloads the credentials from files
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/tls", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, rotator.loaded, rotator.creds)
+	assert.NotNil(t, rotator.creds.ServerCertificate.Leaf)
+	info = &TLSCredentialsInfo{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), info))
+	assert.Equal(t, caSubject(t, ca2), info.ServerCertificate.Issuer)
+	record, err := audit.Verify(bytes.NewReader(auditBuffer.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, audit.KeyRotate, record.Type)
+	assert.Equal(t, "files", record.Attributes["source"])
+	assert.Equal(t, rotator.creds.ServerCertificate.Leaf.SerialNumber.String(), record.Attributes["serverCertSerial"])
+	assert.Equal(t, rotator.creds.ClientCertificate.Leaf.Subject.String(), record.Attributes["clientCertSubject"])
+
+	// the credentials in the request replace the current ones
+	serverKP, err := ca1.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	request, err := json.Marshal(&TLSRotationRequest{
+		ServerCertificate: string(serverKP.Cert),
+		ServerKey:         string(serverKP.Key),
+		ClientRootCAs:     []string{string(ca1.CertBytes()), string(ca2.CertBytes())},
+	})
+	require.NoError(t, err)
+	previous := rotator.creds
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/tls", strings.NewReader(string(request))))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, serverKP.TLSCert.Raw, rotator.creds.ServerCertificate.Certificate[0])
+	assert.Equal(t, previous.ClientCertificate, rotator.creds.ClientCertificate)
+	assert.Equal(t, previous.ServerRootCAs, rotator.creds.ServerRootCAs)
+	assert.Equal(t, [][]byte{ca1.CertBytes(), ca2.CertBytes()}, rotator.creds.ClientRootCAs)
+	record, err = audit.Verify(auditBuffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), record.Sequence)
+	assert.Equal(t, "request", record.Attributes["source"])
+	assert.Equal(t, serverKP.TLSCert.SerialNumber.String(), record.Attributes["serverCertSerial"])
+}
+
+func TestTLSRotationHandlerErrors(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	serverKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name         string
+		method       string
+		body         string
+		rotator      *tlsRotatorMock
+		expectedCode int
+		expectedErr  string
+	}{
+		{
+			name:         "invalid method",
+			method:       http.MethodPost,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "invalid request method: POST",
+		},
+		{
+			name:         "malformed request",
+			method:       http.MethodPut,
+			body:         `{"serverCertificate": 1}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "cannot unmarshal number",
+		},
+		{
+			name:         "key without certificate",
+			method:       http.MethodPut,
+			body:         `{"serverKey": "key"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "invalid server certificate and key",
+		},
+		{
+			name:         "no client certificate",
+			method:       http.MethodPut,
+			body:         `{"clientCertificate": "cert", "clientKey": "key"}`,
+			rotator:      &tlsRotatorMock{creds: &TLSCredentials{}},
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "the node has no TLS client certificate to rotate",
+		},
+		{
+			name:         "invalid root CA",
+			method:       http.MethodPut,
+			body:         `{"serverRootCAs": ["garbage"]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  "invalid server root CA: no certificates found",
+		},
+		{
+			name:         "load failure",
+			method:       http.MethodPut,
+			rotator:      &tlsRotatorMock{loadErr: errors.New("open tls/server.crt: no such file or directory")},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  "failed loading TLS credentials: open tls/server.crt: no such file or directory",
+		},
+		{
+			name:         "rotation failure",
+			method:       http.MethodPut,
+			body:         `{"clientRootCAs": []}`,
+			rotator:      &tlsRotatorMock{rotErr: errors.New("boom")},
+			expectedCode: http.StatusInternalServerError,
+			expectedErr:  "failed rotating TLS credentials: boom",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rotator := tc.rotator
+			if rotator == nil {
+				rotator = &tlsRotatorMock{}
+			}
+			if rotator.creds == nil {
+				cert, err := tls.X509KeyPair(serverKP.Cert, serverKP.Key)
+				require.NoError(t, err)
+				rotator.creds = &TLSCredentials{ServerCertificate: cert}
+			}
+			current := rotator.creds
+
+			auditBuffer := &bytes.Buffer{}
+			handler := NewTLSRotationHandler(rotator)
+			handler.AuditLog = audit.NewLog(nil, audit.NewWriterSink(auditBuffer))
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, httptest.NewRequest(tc.method, "/tls", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.expectedCode, resp.Code)
+			errResp := &tlsRotationErrorResponse{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), errResp))
+			assert.Contains(t, errResp.Error, tc.expectedErr)
+			assert.Equal(t, current, rotator.creds)
+			assert.Zero(t, auditBuffer.Len())
+		})
+	}
+}
+
+func TestValidateTLSCredentials(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	creds := newTLSCredentials(t, ca)
+
+	assert.NoError(t, validateTLSCredentials(creds, time.Now()))
+	assert.Equal(t, creds.ServerCertificate.Certificate[0], creds.ServerCertificate.Leaf.Raw)
+	assert.Equal(t, creds.ClientCertificate.Certificate[0], creds.ClientCertificate.Leaf.Raw)
+
+	err = validateTLSCredentials(creds, time.Now().Add(24*time.Hour*365*20))
+	assert.Regexp(t, "invalid server certificate: certificate expired at", err)
+
+	err = validateTLSCredentials(&TLSCredentials{}, time.Now())
+	assert.EqualError(t, err, "invalid server certificate: no certificate found")
+
+	creds.ClientCertificate = &tls.Certificate{Certificate: [][]byte{[]byte("garbage")}}
+	err = validateTLSCredentials(creds, time.Now())
+	assert.Regexp(t, "invalid client certificate: failed parsing certificate", err)
+}
@@ -100,8 +100,10 @@ type Deliverer struct {
 	InitialRetryDelay time.Duration
 	MaxRetryDuration  time.Duration
 
-	// TLSCertHash should be nil when TLS is not enabled
-	TLSCertHash []byte // util.ComputeSHA256(b.credSupport.GetClientCertificate().Certificate[0])
+	// TLSCertHash returns the hash of the TLS client certificate, and should be
+	// nil when mutual TLS is not enabled. It is called for every request, so
+	// that the rotations of the client certificate are followed.
+	TLSCertHash func() []byte // util.ComputeSHA256(b.credSupport.GetClientCertificate().Certificate[0])
 
 	sleeper sleeper
 }
@@ -314,6 +316,11 @@ func (d *Deliverer) connect(seekInfoEnv *common.Envelope) (orderer.AtomicBroadca
 }
 
 func (d *Deliverer) createSeekInfo(ledgerHeight uint64) (*common.Envelope, error) {
+	var tlsCertHash []byte
+	if d.TLSCertHash != nil {
+		tlsCertHash = d.TLSCertHash()
+	}
+
 	return protoutil.CreateSignedEnvelopeWithTLSBinding(
 		common.HeaderType_DELIVER_SEEK_INFO,
 		d.ChannelID,
@@ -337,6 +344,6 @@ func (d *Deliverer) createSeekInfo(ledgerHeight uint64) (*common.Envelope, error
 		},
 		int32(0),
 		uint64(0),
-		d.TLSCertHash,
+		tlsCertHash,
 	)
 }
@@ -113,7 +113,7 @@ var _ = Describe("Blocksprovider", func() {
 			Signer:            fakeSigner,
 			DeliverStreamer:   fakeDeliverStreamer,
 			Logger:            flogging.MustGetLogger("blocksprovider"),
-			TLSCertHash:       []byte("tls-cert-hash"),
+			TLSCertHash:       func() []byte { return []byte("tls-cert-hash") },
 			MaxRetryDuration:  time.Hour,
 			MaxRetryDelay:     10 * time.Second,
 			InitialRetryDelay: 100 * time.Millisecond,
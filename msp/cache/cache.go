@@ -62,6 +62,14 @@ func (id *cachedIdentity) Validate() error {
 	return id.cache.Validate(id.Identity)
 }
 
+func (id *cachedIdentity) SignedData(msg []byte) ([]byte, error) {
+	provider, ok := id.Identity.(msp.SignedDataProvider)
+	if !ok {
+		return nil, errors.Errorf("identity of type %T does not expose its signed data", id.Identity)
+	}
+	return provider.SignedData(msg)
+}
+
 func (c *cachedMSP) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
 	id, ok := c.deserializeIdentityCache.get(string(serializedIdentity))
 	if ok {
@@ -174,6 +174,31 @@ func TestDeserializeIdentity(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestSignedData(t *testing.T) {
+	mockMSP := &mocks.MockMSP{}
+	wrappedMSP, err := New(mockMSP)
+	assert.NoError(t, err)
+
+	mockMSP.On("DeserializeIdentity", []byte{1, 2, 3}).Return(&mocks.MockIdentity{ID: "Alice"}, nil)
+	id, err := wrappedMSP.DeserializeIdentity([]byte{1, 2, 3})
+	assert.NoError(t, err)
+	_, err = id.(msp.SignedDataProvider).SignedData([]byte("msg"))
+	assert.EqualError(t, err, "identity of type *mocks.MockIdentity does not expose its signed data")
+
+	id = &cachedIdentity{Identity: &signedDataIdentity{}, cache: wrappedMSP.(*cachedMSP)}
+	signedData, err := id.(msp.SignedDataProvider).SignedData([]byte("msg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("digest of msg"), signedData)
+}
+
+type signedDataIdentity struct {
+	mocks.MockIdentity
+}
+
+func (id *signedDataIdentity) SignedData(msg []byte) ([]byte, error) {
+	return append([]byte("digest of "), msg...), nil
+}
+
 func TestValidate(t *testing.T) {
 	mockMSP := &mocks.MockMSP{}
 	i, err := New(mockMSP)
@@ -146,10 +146,23 @@ func SetupBCCSPKeystoreConfig(bccspConfig *factory.FactoryOpts, keystoreDir stri
 		if bccspConfig.SwOpts.FileKeystore == nil ||
 			bccspConfig.SwOpts.FileKeystore.KeyStorePath == "" {
 			bccspConfig.SwOpts.Ephemeral = false
-			bccspConfig.SwOpts.FileKeystore = &factory.FileKeystoreOpts{KeyStorePath: keystoreDir}
+			fileKeystore := &factory.FileKeystoreOpts{KeyStorePath: keystoreDir}
+			if bccspConfig.SwOpts.FileKeystore != nil {
+				fileKeystore.IntegrityKey = bccspConfig.SwOpts.FileKeystore.IntegrityKey
+				fileKeystore.Permissions = bccspConfig.SwOpts.FileKeystore.Permissions
+				fileKeystore.DPAPI = bccspConfig.SwOpts.FileKeystore.DPAPI
+				fileKeystore.SEVSNP = bccspConfig.SwOpts.FileKeystore.SEVSNP
+				fileKeystore.NitroEnclave = bccspConfig.SwOpts.FileKeystore.NitroEnclave
+			}
+			bccspConfig.SwOpts.FileKeystore = fileKeystore
 		}
 	}
 
+	// Only override the index of the KMIP keys if it was left empty
+	if bccspConfig.KMIPOpts != nil && bccspConfig.KMIPOpts.Index == "" {
+		bccspConfig.KMIPOpts.Index = filepath.Join(keystoreDir, "kmip.json")
+	}
+
 	return bccspConfig
 }
 
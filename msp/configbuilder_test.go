@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/bccsp/kmip"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	"github.com/stretchr/testify/assert"
 )
@@ -82,6 +83,41 @@ func TestSetupBCCSPKeystoreConfig(t *testing.T) {
 	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
 	assert.NotNil(t, rtnConfig.SwOpts.FileKeystore)
 	assert.Equal(t, rtnConfig.SwOpts.FileKeystore.KeyStorePath, keystoreDir)
+
+	// Case 4 : Check with 'KMIP' as default provider
+	// Case 4-1 : without KMIPOpts.Index
+	bccspConfig = &factory.FactoryOpts{
+		ProviderName: "KMIP",
+		KMIPOpts:     &kmip.KMIPOpts{Address: "localhost:5696"},
+	}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, filepath.Join(keystoreDir, "kmip.json"), rtnConfig.KMIPOpts.Index)
+
+	// Case 4-2 : with KMIPOpts.Index
+	bccspConfig.KMIPOpts.Index = "/var/kmip/index.json"
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, "/var/kmip/index.json", rtnConfig.KMIPOpts.Index)
+
+	// Case 5 : the integrity key, the permissions and the protection are kept
+	// when the KeyStorePath is defaulted
+	perms := &factory.FilePermissionsOpts{FileMode: "0400", CheckOwner: true}
+	bccspConfig = &factory.FactoryOpts{
+		ProviderName: "SW",
+		SwOpts: &factory.SwOpts{
+			FileKeystore: &factory.FileKeystoreOpts{
+				IntegrityKey: "/etc/fabric/integrity.key",
+				Permissions:  perms,
+				DPAPI:        &factory.DPAPIOpts{LocalMachine: true},
+				SEVSNP:       &factory.SEVSNPOpts{Fields: []string{"measurement"}},
+			},
+		},
+	}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, keystoreDir, rtnConfig.SwOpts.FileKeystore.KeyStorePath)
+	assert.Equal(t, "/etc/fabric/integrity.key", rtnConfig.SwOpts.FileKeystore.IntegrityKey)
+	assert.Equal(t, perms, rtnConfig.SwOpts.FileKeystore.Permissions)
+	assert.Equal(t, &factory.DPAPIOpts{LocalMachine: true}, rtnConfig.SwOpts.FileKeystore.DPAPI)
+	assert.Equal(t, &factory.SEVSNPOpts{Fields: []string{"measurement"}}, rtnConfig.SwOpts.FileKeystore.SEVSNP)
 }
 
 func TestGetLocalMspConfig(t *testing.T) {
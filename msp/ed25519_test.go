@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/stretchr/testify/require"
+)
+
+func TestED25519Identities(t *testing.T) {
+	caPub, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          []byte{1, 2, 3, 4},
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caPub, caPriv)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "peer0.org1.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, pub, caPriv)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	fabricMSPConfig := &msp.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})},
+		Admins:    [][]byte{certPEM},
+		SigningIdentity: &msp.SigningIdentityInfo{
+			PublicSigner: certPEM,
+			PrivateSigner: &msp.KeyInfo{
+				KeyIdentifier: "peer0",
+				KeyMaterial:   pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+			},
+		},
+	}
+	conf, err := proto.Marshal(fabricMSPConfig)
+	require.NoError(t, err)
+
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	thisMSP, err := newBccspMsp(MSPv1_4_3, cryptoProvider)
+	require.NoError(t, err)
+	err = thisMSP.Setup(&msp.MSPConfig{Type: int32(FABRIC), Config: conf})
+	require.NoError(t, err)
+
+	signer, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	require.NoError(t, signer.Validate())
+
+	msg := []byte("hello world")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(pub, msg, sig))
+
+	serialized, err := signer.Serialize()
+	require.NoError(t, err)
+	id, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	require.NoError(t, id.Validate())
+	require.NoError(t, id.Verify(msg, sig))
+	require.EqualError(t, id.Verify([]byte("another message"), sig), "The signature is invalid")
+
+	require.NoError(t, thisMSP.IsWellFormed(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: certPEM}))
+}
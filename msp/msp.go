@@ -166,6 +166,16 @@ type Identity interface {
 	SatisfiesPrincipal(principal *msp.MSPPrincipal) error
 }
 
+// SignedDataProvider is implemented by the identities that expose what their
+// signatures are computed over, which depends on the configuration of their MSP.
+// It lets the signatures of several identities be verified at once, outside of
+// the identities, exactly as the identities themselves would verify them.
+type SignedDataProvider interface {
+	// SignedData returns what the signatures of msg by this identity are
+	// computed over.
+	SignedData(msg []byte) ([]byte, error)
+}
+
 // SigningIdentity is an extension of Identity to cover signing capabilities.
 // E.g., signing identity should be requested in the case of a client who wishes
 // to sign transactions, or fabric endorser who wishes to sign proposal
@@ -227,9 +227,16 @@ func (msp *bccspmsp) getSigningIdentityFromConf(sidInfo *m.SigningIdentityInfo)
 		if pemKey == nil {
 			return nil, errors.Errorf("%s: wrong PEM encoding", sidInfo.PrivateSigner.KeyIdentifier)
 		}
-		privKey, err = msp.bccsp.KeyImport(pemKey.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
-		if err != nil {
-			return nil, errors.WithMessage(err, "getIdentityFromBytes error: Failed to import EC private key")
+		if idPub.(*identity).cert.PublicKeyAlgorithm == x509.Ed25519 {
+			privKey, err = msp.bccsp.KeyImport(pemKey.Bytes, &bccsp.ED25519PrivateKeyImportOpts{Temporary: true})
+			if err != nil {
+				return nil, errors.WithMessage(err, "getIdentityFromBytes error: Failed to import ED25519 private key")
+			}
+		} else {
+			privKey, err = msp.bccsp.KeyImport(pemKey.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+			if err != nil {
+				return nil, errors.WithMessage(err, "getIdentityFromBytes error: Failed to import EC private key")
+			}
 		}
 	}
 
@@ -847,6 +854,11 @@ func (msp *bccspmsp) IsWellFormed(identity *m.SerializedIdentity) error {
 		return err
 	}
 
+	// Ed25519 signatures have a single valid encoding
+	if cert.SignatureAlgorithm == x509.PureEd25519 {
+		return nil
+	}
+
 	return isIdentitySignedInCanonicalForm(cert.Signature, identity.Mspid, identity.IdBytes)
 
 }
@@ -13,8 +13,10 @@ import (
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
 )
 
@@ -60,6 +62,10 @@ type Consenter interface {
 type Handler struct {
 	SupportRegistrar ChannelSupportRegistrar
 	Metrics          *Metrics
+	// RateLimiter, when set, limits the rate of the messages of each client
+	RateLimiter *RateLimiter
+	// Tracer records a span for each message, if set
+	Tracer *tracing.Tracer
 }
 
 // Handle reads requests from a Broadcast stream, processes them, and returns the responses to the stream
@@ -77,7 +83,9 @@ func (bh *Handler) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
 			return err
 		}
 
+		_, span := bh.Tracer.Start(srv.Context(), "broadcast.ProcessMessage")
 		resp := bh.ProcessMessage(msg, addr)
+		endSpan(span, msg, resp)
 		err = srv.Send(resp)
 		if resp.Status != cb.Status_SUCCESS {
 			return err
@@ -91,6 +99,22 @@ func (bh *Handler) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
 
 }
 
+// endSpan records the outcome of the broadcast of the message to its span
+func endSpan(span *tracing.Span, msg *cb.Envelope, resp *ab.BroadcastResponse) {
+	if span == nil {
+		return
+	}
+	if chdr, err := protoutil.ChannelHeader(msg); err == nil {
+		span.SetAttribute("channel", chdr.ChannelId)
+		span.SetAttribute("txid", chdr.TxId)
+	}
+	span.SetAttribute("status", resp.Status.String())
+	if resp.Status != cb.Status_SUCCESS {
+		span.SetError(errors.New(resp.Info))
+	}
+	span.End()
+}
+
 type MetricsTracker struct {
 	ValidateStartTime time.Time
 	EnqueueStartTime  time.Time
@@ -167,6 +191,10 @@ func (bh *Handler) ProcessMessage(msg *cb.Envelope, addr string) (resp *ab.Broad
 		}
 		tracker.EndValidate()
 
+		if resp := bh.limitRate(msg, chdr, addr); resp != nil {
+			return resp
+		}
+
 		tracker.BeginEnqueue()
 		if err = processor.WaitReady(); err != nil {
 			logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rejected by Consenter: %s", chdr.ChannelId, addr, err)
@@ -188,6 +216,10 @@ func (bh *Handler) ProcessMessage(msg *cb.Envelope, addr string) (resp *ab.Broad
 		}
 		tracker.EndValidate()
 
+		if resp := bh.limitRate(msg, chdr, addr); resp != nil {
+			return resp
+		}
+
 		tracker.BeginEnqueue()
 		if err = processor.WaitReady(); err != nil {
 			logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rejected by Consenter: %s", chdr.ChannelId, addr, err)
@@ -206,6 +238,27 @@ func (bh *Handler) ProcessMessage(msg *cb.Envelope, addr string) (resp *ab.Broad
 	return &ab.BroadcastResponse{Status: cb.Status_SUCCESS}
 }
 
+// limitRate returns the response rejecting the message if its submitter exceeds
+// its rate limit, or nil if the message is admitted. It is called once the
+// message has been validated, so that the submitter the rate limit is keyed on
+// is authenticated by the signature of the message.
+func (bh *Handler) limitRate(msg *cb.Envelope, chdr *cb.ChannelHeader, addr string) *ab.BroadcastResponse {
+	if bh.RateLimiter == nil {
+		return nil
+	}
+	mspID, allowed, err := bh.RateLimiter.Allow(msg)
+	if err != nil {
+		logger.Warningf("[channel: %s] Could not determine the submitter of the message from %s: %s", chdr.ChannelId, addr, err)
+		return &ab.BroadcastResponse{Status: cb.Status_BAD_REQUEST, Info: err.Error()}
+	}
+	if !allowed {
+		logger.Debugf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rate limit of %s exceeded", chdr.ChannelId, addr, mspID)
+		bh.Metrics.RateLimitedCount.With("channel", chdr.ChannelId, "mspid", mspID).Add(1)
+		return &ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE, Info: "rate limit exceeded"}
+	}
+	return nil
+}
+
 // ClassifyError converts an error type into a status code.
 func ClassifyError(err error) cb.Status {
 	switch errors.Cause(err) {
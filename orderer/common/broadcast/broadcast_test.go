@@ -16,19 +16,23 @@ import (
 	. "github.com/onsi/gomega"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/orderer/common/broadcast"
 	"github.com/hyperledger/fabric/orderer/common/broadcast/mock"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/protoutil"
 )
 
 var _ = Describe("Broadcast", func() {
 	var (
-		fakeSupportRegistrar  *mock.ChannelSupportRegistrar
-		handler               *broadcast.Handler
-		fakeValidateHistogram *mock.MetricsHistogram
-		fakeEnqueueHistogram  *mock.MetricsHistogram
-		fakeProcessedCounter  *mock.MetricsCounter
+		fakeSupportRegistrar   *mock.ChannelSupportRegistrar
+		handler                *broadcast.Handler
+		fakeValidateHistogram  *mock.MetricsHistogram
+		fakeEnqueueHistogram   *mock.MetricsHistogram
+		fakeProcessedCounter   *mock.MetricsCounter
+		fakeRateLimitedCounter *mock.MetricsCounter
 	)
 
 	BeforeEach(func() {
@@ -43,12 +47,16 @@ var _ = Describe("Broadcast", func() {
 		fakeProcessedCounter = &mock.MetricsCounter{}
 		fakeProcessedCounter.WithReturns(fakeProcessedCounter)
 
+		fakeRateLimitedCounter = &mock.MetricsCounter{}
+		fakeRateLimitedCounter.WithReturns(fakeRateLimitedCounter)
+
 		handler = &broadcast.Handler{
 			SupportRegistrar: fakeSupportRegistrar,
 			Metrics: &broadcast.Metrics{
 				ValidateDuration: fakeValidateHistogram,
 				EnqueueDuration:  fakeEnqueueHistogram,
 				ProcessedCount:   fakeProcessedCounter,
+				RateLimitedCount: fakeRateLimitedCounter,
 			},
 		}
 	})
@@ -128,6 +136,54 @@ var _ = Describe("Broadcast", func() {
 			Expect(proto.Equal(fakeABServer.SendArgsForCall(0), &ab.BroadcastResponse{Status: cb.Status_SUCCESS})).To(BeTrue())
 		})
 
+		Context("when a tracer is set", func() {
+			var exportedSpans []*tracing.Span
+
+			BeforeEach(func() {
+				exportedSpans = nil
+				handler.Tracer = tracing.NewTracer(spanRecorder(func(span *tracing.Span) {
+					exportedSpans = append(exportedSpans, span)
+				}), 1)
+
+				fakeMsg.Payload = protoutil.MarshalOrPanic(&cb.Payload{
+					Header: &cb.Header{
+						ChannelHeader: protoutil.MarshalOrPanic(&cb.ChannelHeader{
+							ChannelId: "fake-channel",
+							TxId:      "fake-txid",
+						}),
+					},
+				})
+			})
+
+			It("records a span for the message", func() {
+				err := handler.Handle(fakeABServer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(exportedSpans).To(HaveLen(1))
+				Expect(exportedSpans[0].Name).To(Equal("broadcast.ProcessMessage"))
+				Expect(exportedSpans[0].Err).To(BeNil())
+				Expect(exportedSpans[0].Attributes()).To(Equal(map[string]interface{}{
+					"channel": "fake-channel",
+					"txid":    "fake-txid",
+					"status":  "SUCCESS",
+				}))
+			})
+
+			Context("when the message is rejected", func() {
+				BeforeEach(func() {
+					fakeSupport.ProcessNormalMsgReturns(0, fmt.Errorf("normal-messsage-processing-error"))
+				})
+
+				It("records the error", func() {
+					handler.Handle(fakeABServer)
+
+					Expect(exportedSpans).To(HaveLen(1))
+					Expect(exportedSpans[0].Err).To(MatchError("normal-messsage-processing-error"))
+					Expect(exportedSpans[0].Attributes()).To(HaveKeyWithValue("status", "BAD_REQUEST"))
+				})
+			})
+		})
+
 		Context("when the channel support cannot be retrieved", func() {
 			BeforeEach(func() {
 				fakeSupportRegistrar.BroadcastChannelSupportReturns(&cb.ChannelHeader{
@@ -169,6 +225,78 @@ var _ = Describe("Broadcast", func() {
 
 		})
 
+		Context("when rate limiting is enabled", func() {
+			BeforeEach(func() {
+				var err error
+				handler.RateLimiter, err = broadcast.NewRateLimiter(broadcast.ClientRateLimitScope, 1, 0)
+				Expect(err).NotTo(HaveOccurred())
+
+				fakeMsg.Payload = protoutil.MarshalOrPanic(&cb.Payload{
+					Header: &cb.Header{
+						SignatureHeader: protoutil.MarshalOrPanic(&cb.SignatureHeader{
+							Creator: protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "org1", IdBytes: []byte("client1")}),
+						}),
+					},
+				})
+				fakeABServer.RecvReturnsOnCall(1, fakeMsg, nil)
+				fakeABServer.RecvReturnsOnCall(2, nil, io.EOF)
+			})
+
+			It("rejects the messages beyond the rate limit", func() {
+				err := handler.Handle(fakeABServer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeSupport.OrderCallCount()).To(Equal(1))
+				Expect(fakeABServer.SendCallCount()).To(Equal(2))
+				Expect(proto.Equal(fakeABServer.SendArgsForCall(0), &ab.BroadcastResponse{Status: cb.Status_SUCCESS})).To(BeTrue())
+				Expect(proto.Equal(fakeABServer.SendArgsForCall(1), &ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE, Info: "rate limit exceeded"})).To(BeTrue())
+
+				Expect(fakeRateLimitedCounter.WithCallCount()).To(Equal(1))
+				Expect(fakeRateLimitedCounter.WithArgsForCall(0)).To(Equal([]string{
+					"channel", "fake-channel",
+					"mspid", "org1",
+				}))
+				Expect(fakeRateLimitedCounter.AddCallCount()).To(Equal(1))
+				Expect(fakeRateLimitedCounter.AddArgsForCall(0)).To(Equal(float64(1)))
+			})
+
+			Context("when the message fails validation", func() {
+				BeforeEach(func() {
+					fakeSupport.ProcessNormalMsgReturnsOnCall(0, 0, msgprocessor.ErrPermissionDenied)
+				})
+
+				It("does not count the message against the rate limit", func() {
+					err := handler.Handle(fakeABServer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeABServer.SendCallCount()).To(Equal(1))
+					Expect(fakeABServer.SendArgsForCall(0).Status).To(Equal(cb.Status_FORBIDDEN))
+					Expect(fakeRateLimitedCounter.WithCallCount()).To(Equal(0))
+
+					err = handler.Handle(fakeABServer)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeSupport.OrderCallCount()).To(Equal(1))
+					Expect(proto.Equal(fakeABServer.SendArgsForCall(1), &ab.BroadcastResponse{Status: cb.Status_SUCCESS})).To(BeTrue())
+				})
+			})
+
+			Context("when the submitter of the message cannot be determined", func() {
+				BeforeEach(func() {
+					fakeMsg.Payload = []byte("garbage")
+				})
+
+				It("returns the error to the client with a bad status", func() {
+					err := handler.Handle(fakeABServer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeSupport.ProcessNormalMsgCallCount()).To(Equal(1))
+					Expect(fakeSupport.OrderCallCount()).To(Equal(0))
+					Expect(fakeABServer.SendCallCount()).To(Equal(1))
+					Expect(fakeABServer.SendArgsForCall(0).Status).To(Equal(cb.Status_BAD_REQUEST))
+				})
+			})
+		})
+
 		Context("when the receive from the client fails", func() {
 			BeforeEach(func() {
 				fakeABServer.RecvReturns(nil, fmt.Errorf("recv-error"))
@@ -399,3 +527,7 @@ var _ = Describe("Broadcast", func() {
 		})
 	})
 })
+
+type spanRecorder func(*tracing.Span)
+
+func (s spanRecorder) Export(span *tracing.Span) { s(span) }
@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ClientRateLimitScope applies the rate limits to each client identity.
+	ClientRateLimitScope = "Client"
+	// OrgRateLimitScope applies the rate limits to all the clients of an organization together.
+	OrgRateLimitScope = "Org"
+
+	// idleBucketTimeout is the time after which the token buckets of a client
+	// which has not submitted any message are discarded.
+	idleBucketTimeout = time.Minute
+
+	// maxBuckets is the number of token buckets the RateLimiter holds at most.
+	// When it is reached, the bucket of the client which has been idle the
+	// longest is discarded to make room for a new client.
+	maxBuckets = 10000
+)
+
+// tokenBucket holds the transactions and bytes a client may still submit
+type tokenBucket struct {
+	transactions float64
+	bytes        float64
+	lastUpdate   time.Time
+}
+
+// RateLimiter limits the rate at which clients may submit messages to the Broadcast
+// service, by the number of transactions per second and the number of bytes per second.
+// A client may exceed the rates for a short burst of up to one second worth of messages.
+type RateLimiter struct {
+	scope     string
+	tps       float64
+	bandwidth float64
+	now       func() time.Time
+
+	maxBuckets int
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+}
+
+// NewRateLimiter creates a RateLimiter for the given scope. A tps or bandwidth of 0
+// leaves the corresponding rate unlimited.
+func NewRateLimiter(scope string, tps, bandwidth uint32) (*RateLimiter, error) {
+	if scope != ClientRateLimitScope && scope != OrgRateLimitScope {
+		return nil, errors.Errorf("unknown rate limit scope %s, expected %s or %s", scope, ClientRateLimitScope, OrgRateLimitScope)
+	}
+	return &RateLimiter{
+		scope:      scope,
+		tps:        float64(tps),
+		bandwidth:  float64(bandwidth),
+		now:        time.Now,
+		maxBuckets: maxBuckets,
+		buckets:    map[string]*tokenBucket{},
+		lastPurge:  time.Now(),
+	}, nil
+}
+
+// Allow reports whether the message is within the rate limits of its submitter, and
+// returns the MSP ID of the submitter. The submitter is read from the signature
+// header of the message, which must have been validated beforehand.
+func (rl *RateLimiter) Allow(msg *cb.Envelope) (string, bool, error) {
+	identity, err := submitter(msg)
+	if err != nil {
+		return "", false, err
+	}
+	key := identity.Mspid
+	if rl.scope == ClientRateLimitScope {
+		key = string(identity.IdBytes)
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	rl.purge(now)
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		if len(rl.buckets) >= rl.maxBuckets {
+			rl.evict()
+		}
+		bucket = &tokenBucket{transactions: rl.tps, bytes: rl.bandwidth, lastUpdate: now}
+		rl.buckets[key] = bucket
+	}
+	elapsed := now.Sub(bucket.lastUpdate).Seconds()
+	bucket.lastUpdate = now
+	bucket.transactions = refill(bucket.transactions, rl.tps, elapsed)
+	bucket.bytes = refill(bucket.bytes, rl.bandwidth, elapsed)
+
+	if rl.tps > 0 && bucket.transactions < 1 {
+		return identity.Mspid, false, nil
+	}
+	// a message larger than the bandwidth is admitted when there are bytes left,
+	// and the bytes it exceeds by are deducted from the following seconds
+	if rl.bandwidth > 0 && bucket.bytes <= 0 {
+		return identity.Mspid, false, nil
+	}
+	bucket.transactions--
+	bucket.bytes -= float64(proto.Size(msg))
+	return identity.Mspid, true, nil
+}
+
+// Limits returns the transactions per second and the bytes per second the
+// clients are limited to, 0 when unlimited.
+func (rl *RateLimiter) Limits() (tps, bandwidth uint32) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return uint32(rl.tps), uint32(rl.bandwidth)
+}
+
+// SetLimits changes the rate limits. The token buckets of the clients are
+// discarded, so that every client starts afresh with a full burst.
+func (rl *RateLimiter) SetLimits(tps, bandwidth uint32) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.tps = float64(tps)
+	rl.bandwidth = float64(bandwidth)
+	rl.buckets = map[string]*tokenBucket{}
+}
+
+// purge discards the token buckets of the clients which have been idle for long enough
+// for their buckets to refill
+func (rl *RateLimiter) purge(now time.Time) {
+	if now.Sub(rl.lastPurge) < idleBucketTimeout {
+		return
+	}
+	rl.lastPurge = now
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.lastUpdate) >= idleBucketTimeout {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// evict discards the token bucket of the client which has been idle the longest
+func (rl *RateLimiter) evict() {
+	var oldestKey string
+	var oldest time.Time
+	for key, bucket := range rl.buckets {
+		if oldestKey == "" || bucket.lastUpdate.Before(oldest) {
+			oldestKey, oldest = key, bucket.lastUpdate
+		}
+	}
+	delete(rl.buckets, oldestKey)
+}
+
+func refill(tokens, rate, elapsed float64) float64 {
+	tokens += rate * elapsed
+	if tokens > rate {
+		return rate
+	}
+	return tokens
+}
+
+func submitter(msg *cb.Envelope) (*msp.SerializedIdentity, error) {
+	payload, err := protoutil.UnmarshalPayload(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, errors.New("missing header in payload")
+	}
+	shdr, err := protoutil.UnmarshalSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	identity := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(shdr.Creator, identity); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal the creator of the message")
+	}
+	return identity, nil
+}
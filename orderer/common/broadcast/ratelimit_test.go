@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func envelope(mspID, client string, data []byte) *cb.Envelope {
+	return &cb.Envelope{
+		Payload: protoutil.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				SignatureHeader: protoutil.MarshalOrPanic(&cb.SignatureHeader{
+					Creator: protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: mspID, IdBytes: []byte(client)}),
+				}),
+			},
+			Data: data,
+		}),
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestRateLimiter(t *testing.T, scope string, tps, bandwidth uint32) (*RateLimiter, *fakeClock) {
+	rl, err := NewRateLimiter(scope, tps, bandwidth)
+	require.NoError(t, err)
+	clock := &fakeClock{now: time.Now()}
+	rl.now = func() time.Time { return clock.now }
+	rl.lastPurge = clock.now
+	return rl, clock
+}
+
+func requireAllowed(t *testing.T, rl *RateLimiter, msg *cb.Envelope, expected bool) {
+	_, allowed, err := rl.Allow(msg)
+	require.NoError(t, err)
+	require.Equal(t, expected, allowed)
+}
+
+func TestNewRateLimiterUnknownScope(t *testing.T) {
+	_, err := NewRateLimiter("Channel", 1, 1)
+	require.EqualError(t, err, "unknown rate limit scope Channel, expected Client or Org")
+}
+
+func TestRateLimiterTPS(t *testing.T) {
+	rl, clock := newTestRateLimiter(t, ClientRateLimitScope, 2, 0)
+	client1 := envelope("org1", "client1", nil)
+	client2 := envelope("org1", "client2", nil)
+
+	requireAllowed(t, rl, client1, true)
+	requireAllowed(t, rl, client1, true)
+	requireAllowed(t, rl, client1, false)
+	// the limits apply to each client separately
+	requireAllowed(t, rl, client2, true)
+
+	clock.advance(500 * time.Millisecond)
+	requireAllowed(t, rl, client1, true)
+	requireAllowed(t, rl, client1, false)
+
+	// the burst does not exceed one second worth of messages
+	clock.advance(10 * time.Second)
+	requireAllowed(t, rl, client1, true)
+	requireAllowed(t, rl, client1, true)
+	requireAllowed(t, rl, client1, false)
+}
+
+func TestRateLimiterOrgScope(t *testing.T) {
+	rl, _ := newTestRateLimiter(t, OrgRateLimitScope, 1, 0)
+
+	mspID, allowed, err := rl.Allow(envelope("org1", "client1", nil))
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, "org1", mspID)
+	mspID, allowed, err = rl.Allow(envelope("org1", "client2", nil))
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, "org1", mspID)
+	requireAllowed(t, rl, envelope("org2", "client3", nil), true)
+}
+
+func TestRateLimiterBandwidth(t *testing.T) {
+	msg := envelope("org1", "client1", make([]byte, 60))
+	size := proto.Size(msg)
+	bandwidth := size + size/2
+	rl, clock := newTestRateLimiter(t, ClientRateLimitScope, 0, uint32(bandwidth))
+
+	requireAllowed(t, rl, msg, true)
+	requireAllowed(t, rl, msg, true)
+	requireAllowed(t, rl, msg, false)
+
+	// the excess of the last message is deducted from the following second
+	excess := float64(2*size-bandwidth) / float64(bandwidth)
+	clock.advance(time.Duration(excess * float64(time.Second) / 2))
+	requireAllowed(t, rl, msg, false)
+	clock.advance(time.Duration(excess * float64(time.Second)))
+	requireAllowed(t, rl, msg, true)
+}
+
+func TestRateLimiterPurge(t *testing.T) {
+	rl, clock := newTestRateLimiter(t, ClientRateLimitScope, 1, 0)
+	requireAllowed(t, rl, envelope("org1", "client1", nil), true)
+	require.Len(t, rl.buckets, 1)
+
+	clock.advance(idleBucketTimeout / 2)
+	requireAllowed(t, rl, envelope("org1", "client2", nil), true)
+	require.Len(t, rl.buckets, 2)
+
+	clock.advance(idleBucketTimeout / 2)
+	requireAllowed(t, rl, envelope("org1", "client3", nil), true)
+	require.Len(t, rl.buckets, 2)
+	require.NotContains(t, rl.buckets, "client1")
+}
+
+func TestRateLimiterEvict(t *testing.T) {
+	rl, clock := newTestRateLimiter(t, ClientRateLimitScope, 1, 0)
+	rl.maxBuckets = 2
+	requireAllowed(t, rl, envelope("org1", "client1", nil), true)
+	clock.advance(time.Second)
+	requireAllowed(t, rl, envelope("org1", "client2", nil), true)
+	clock.advance(time.Second)
+	requireAllowed(t, rl, envelope("org1", "client3", nil), true)
+	require.Len(t, rl.buckets, 2)
+	require.NotContains(t, rl.buckets, "client1")
+	require.Contains(t, rl.buckets, "client2")
+	require.Contains(t, rl.buckets, "client3")
+}
+
+func TestRateLimiterBadMessage(t *testing.T) {
+	rl, _ := newTestRateLimiter(t, ClientRateLimitScope, 1, 0)
+
+	_, _, err := rl.Allow(&cb.Envelope{Payload: []byte("garbage")})
+	require.Error(t, err)
+	_, _, err = rl.Allow(&cb.Envelope{Payload: protoutil.MarshalOrPanic(&cb.Payload{})})
+	require.EqualError(t, err, "missing header in payload")
+	_, _, err = rl.Allow(&cb.Envelope{Payload: protoutil.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{
+			SignatureHeader: protoutil.MarshalOrPanic(&cb.SignatureHeader{Creator: []byte("garbage")}),
+		},
+	})})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "could not unmarshal the creator of the message")
+}
+
+func TestRateLimiterSetLimits(t *testing.T) {
+	rl, _ := newTestRateLimiter(t, ClientRateLimitScope, 1, 0)
+	client := envelope("org1", "client1", nil)
+
+	requireAllowed(t, rl, client, true)
+	requireAllowed(t, rl, client, false)
+
+	rl.SetLimits(3, 100)
+	tps, bandwidth := rl.Limits()
+	require.Equal(t, uint32(3), tps)
+	require.Equal(t, uint32(100), bandwidth)
+
+	// the client starts afresh with a burst of the new limits
+	requireAllowed(t, rl, client, true)
+	requireAllowed(t, rl, client, true)
+	requireAllowed(t, rl, client, true)
+	requireAllowed(t, rl, client, false)
+
+	rl.SetLimits(0, 0)
+	for i := 0; i < 10; i++ {
+		requireAllowed(t, rl, client, true)
+	}
+}
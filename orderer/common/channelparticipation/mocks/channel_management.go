@@ -32,6 +32,50 @@ type ChannelManagement struct {
 	channelListReturnsOnCall map[int]struct {
 		result1 types.ChannelList
 	}
+	DrainStub        func([]string) types.DrainReport
+	drainMutex       sync.RWMutex
+	drainArgsForCall []struct {
+		arg1 []string
+	}
+	drainReturns struct {
+		result1 types.DrainReport
+	}
+	drainReturnsOnCall map[int]struct {
+		result1 types.DrainReport
+	}
+	PauseChannelStub        func(string) error
+	pauseChannelMutex       sync.RWMutex
+	pauseChannelArgsForCall []struct {
+		arg1 string
+	}
+	pauseChannelReturns struct {
+		result1 error
+	}
+	pauseChannelReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ResumeChannelStub        func(string) error
+	resumeChannelMutex       sync.RWMutex
+	resumeChannelArgsForCall []struct {
+		arg1 string
+	}
+	resumeChannelReturns struct {
+		result1 error
+	}
+	resumeChannelReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TransferLeadershipStub        func(string) error
+	transferLeadershipMutex       sync.RWMutex
+	transferLeadershipArgsForCall []struct {
+		arg1 string
+	}
+	transferLeadershipReturns struct {
+		result1 error
+	}
+	transferLeadershipReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -151,6 +195,251 @@ func (fake *ChannelManagement) ChannelListReturnsOnCall(i int, result1 types.Cha
 	}{result1}
 }
 
+func (fake *ChannelManagement) Drain(arg1 []string) types.DrainReport {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.drainMutex.Lock()
+	ret, specificReturn := fake.drainReturnsOnCall[len(fake.drainArgsForCall)]
+	fake.drainArgsForCall = append(fake.drainArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	fake.recordInvocation("Drain", []interface{}{arg1Copy})
+	fake.drainMutex.Unlock()
+	if fake.DrainStub != nil {
+		return fake.DrainStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.drainReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelManagement) DrainCallCount() int {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return len(fake.drainArgsForCall)
+}
+
+func (fake *ChannelManagement) DrainCalls(stub func([]string) types.DrainReport) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = stub
+}
+
+func (fake *ChannelManagement) DrainArgsForCall(i int) []string {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	argsForCall := fake.drainArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *ChannelManagement) DrainReturns(result1 types.DrainReport) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = nil
+	fake.drainReturns = struct {
+		result1 types.DrainReport
+	}{result1}
+}
+
+func (fake *ChannelManagement) DrainReturnsOnCall(i int, result1 types.DrainReport) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = nil
+	if fake.drainReturnsOnCall == nil {
+		fake.drainReturnsOnCall = make(map[int]struct {
+			result1 types.DrainReport
+		})
+	}
+	fake.drainReturnsOnCall[i] = struct {
+		result1 types.DrainReport
+	}{result1}
+}
+
+func (fake *ChannelManagement) PauseChannel(arg1 string) error {
+	fake.pauseChannelMutex.Lock()
+	ret, specificReturn := fake.pauseChannelReturnsOnCall[len(fake.pauseChannelArgsForCall)]
+	fake.pauseChannelArgsForCall = append(fake.pauseChannelArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("PauseChannel", []interface{}{arg1})
+	fake.pauseChannelMutex.Unlock()
+	if fake.PauseChannelStub != nil {
+		return fake.PauseChannelStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.pauseChannelReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelManagement) PauseChannelCallCount() int {
+	fake.pauseChannelMutex.RLock()
+	defer fake.pauseChannelMutex.RUnlock()
+	return len(fake.pauseChannelArgsForCall)
+}
+
+func (fake *ChannelManagement) PauseChannelCalls(stub func(string) error) {
+	fake.pauseChannelMutex.Lock()
+	defer fake.pauseChannelMutex.Unlock()
+	fake.PauseChannelStub = stub
+}
+
+func (fake *ChannelManagement) PauseChannelArgsForCall(i int) string {
+	fake.pauseChannelMutex.RLock()
+	defer fake.pauseChannelMutex.RUnlock()
+	argsForCall := fake.pauseChannelArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *ChannelManagement) PauseChannelReturns(result1 error) {
+	fake.pauseChannelMutex.Lock()
+	defer fake.pauseChannelMutex.Unlock()
+	fake.PauseChannelStub = nil
+	fake.pauseChannelReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChannelManagement) PauseChannelReturnsOnCall(i int, result1 error) {
+	fake.pauseChannelMutex.Lock()
+	defer fake.pauseChannelMutex.Unlock()
+	fake.PauseChannelStub = nil
+	if fake.pauseChannelReturnsOnCall == nil {
+		fake.pauseChannelReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.pauseChannelReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChannelManagement) ResumeChannel(arg1 string) error {
+	fake.resumeChannelMutex.Lock()
+	ret, specificReturn := fake.resumeChannelReturnsOnCall[len(fake.resumeChannelArgsForCall)]
+	fake.resumeChannelArgsForCall = append(fake.resumeChannelArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("ResumeChannel", []interface{}{arg1})
+	fake.resumeChannelMutex.Unlock()
+	if fake.ResumeChannelStub != nil {
+		return fake.ResumeChannelStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.resumeChannelReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelManagement) ResumeChannelCallCount() int {
+	fake.resumeChannelMutex.RLock()
+	defer fake.resumeChannelMutex.RUnlock()
+	return len(fake.resumeChannelArgsForCall)
+}
+
+func (fake *ChannelManagement) ResumeChannelCalls(stub func(string) error) {
+	fake.resumeChannelMutex.Lock()
+	defer fake.resumeChannelMutex.Unlock()
+	fake.ResumeChannelStub = stub
+}
+
+func (fake *ChannelManagement) ResumeChannelArgsForCall(i int) string {
+	fake.resumeChannelMutex.RLock()
+	defer fake.resumeChannelMutex.RUnlock()
+	argsForCall := fake.resumeChannelArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *ChannelManagement) ResumeChannelReturns(result1 error) {
+	fake.resumeChannelMutex.Lock()
+	defer fake.resumeChannelMutex.Unlock()
+	fake.ResumeChannelStub = nil
+	fake.resumeChannelReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChannelManagement) ResumeChannelReturnsOnCall(i int, result1 error) {
+	fake.resumeChannelMutex.Lock()
+	defer fake.resumeChannelMutex.Unlock()
+	fake.ResumeChannelStub = nil
+	if fake.resumeChannelReturnsOnCall == nil {
+		fake.resumeChannelReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.resumeChannelReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChannelManagement) TransferLeadership(arg1 string) error {
+	fake.transferLeadershipMutex.Lock()
+	ret, specificReturn := fake.transferLeadershipReturnsOnCall[len(fake.transferLeadershipArgsForCall)]
+	fake.transferLeadershipArgsForCall = append(fake.transferLeadershipArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("TransferLeadership", []interface{}{arg1})
+	fake.transferLeadershipMutex.Unlock()
+	if fake.TransferLeadershipStub != nil {
+		return fake.TransferLeadershipStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.transferLeadershipReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelManagement) TransferLeadershipCallCount() int {
+	fake.transferLeadershipMutex.RLock()
+	defer fake.transferLeadershipMutex.RUnlock()
+	return len(fake.transferLeadershipArgsForCall)
+}
+
+func (fake *ChannelManagement) TransferLeadershipCalls(stub func(string) error) {
+	fake.transferLeadershipMutex.Lock()
+	defer fake.transferLeadershipMutex.Unlock()
+	fake.TransferLeadershipStub = stub
+}
+
+func (fake *ChannelManagement) TransferLeadershipArgsForCall(i int) string {
+	fake.transferLeadershipMutex.RLock()
+	defer fake.transferLeadershipMutex.RUnlock()
+	argsForCall := fake.transferLeadershipArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *ChannelManagement) TransferLeadershipReturns(result1 error) {
+	fake.transferLeadershipMutex.Lock()
+	defer fake.transferLeadershipMutex.Unlock()
+	fake.TransferLeadershipStub = nil
+	fake.transferLeadershipReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChannelManagement) TransferLeadershipReturnsOnCall(i int, result1 error) {
+	fake.transferLeadershipMutex.Lock()
+	defer fake.transferLeadershipMutex.Unlock()
+	fake.TransferLeadershipStub = nil
+	if fake.transferLeadershipReturnsOnCall == nil {
+		fake.transferLeadershipReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.transferLeadershipReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *ChannelManagement) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -158,6 +447,14 @@ func (fake *ChannelManagement) Invocations() map[string][][]interface{} {
 	defer fake.channelInfoMutex.RUnlock()
 	fake.channelListMutex.RLock()
 	defer fake.channelListMutex.RUnlock()
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	fake.pauseChannelMutex.RLock()
+	defer fake.pauseChannelMutex.RUnlock()
+	fake.resumeChannelMutex.RLock()
+	defer fake.resumeChannelMutex.RUnlock()
+	fake.transferLeadershipMutex.RLock()
+	defer fake.transferLeadershipMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -23,8 +23,16 @@ import (
 const (
 	URLBaseV1           = "/participation/v1/"
 	URLBaseV1Channels   = URLBaseV1 + "channels"
+	URLBaseV1Drain      = URLBaseV1 + "drain"
 	channelIDKey        = "channelID"
 	urlWithChannelIDKey = URLBaseV1Channels + "/{" + channelIDKey + "}"
+	actionKey           = "action"
+	actionPause         = "pause"
+	actionResume        = "resume"
+	urlWithActionKey    = urlWithChannelIDKey + "/{" + actionKey + ":" + actionPause + "|" + actionResume + "}"
+	actionTransfer      = "transfer-leadership"
+	urlWithTransferKey  = urlWithChannelIDKey + "/{" + actionKey + ":" + actionTransfer + "}"
+	drainChannelKey     = "channel"
 )
 
 //go:generate counterfeiter -o mocks/channel_management.go -fake-name ChannelManagement . ChannelManagement
@@ -39,6 +47,20 @@ type ChannelManagement interface {
 	// The URL field is empty, and is to be completed by the caller.
 	ChannelInfo(channelID string) (types.ChannelInfo, error)
 
+	// PauseChannel halts the consenter of a channel without removing the channel.
+	PauseChannel(channelID string) error
+
+	// ResumeChannel starts the consenter of a paused channel.
+	ResumeChannel(channelID string) error
+
+	// TransferLeadership transfers the leadership of the consensus of a channel away from this orderer,
+	// if this orderer is the leader.
+	TransferLeadership(channelID string) error
+
+	// Drain transfers the leadership of the given channels away from this orderer and pauses them,
+	// before maintenance. All the application channels are drained if no channels are given.
+	Drain(channelIDs []string) types.DrainReport
+
 	// TODO skeleton
 }
 
@@ -66,6 +88,15 @@ func NewHTTPHandler(config localconfig.ChannelParticipation, registrar ChannelMa
 	handler.router.HandleFunc(urlWithChannelIDKey, handler.serveRemove).Methods(http.MethodDelete)
 	handler.router.HandleFunc(urlWithChannelIDKey, handler.serveNotAllowed)
 
+	handler.router.HandleFunc(urlWithActionKey, handler.servePauseResume).Methods(http.MethodPost)
+	handler.router.HandleFunc(urlWithActionKey, handler.serveNotAllowed)
+
+	handler.router.HandleFunc(urlWithTransferKey, handler.serveTransferLeadership).Methods(http.MethodPost)
+	handler.router.HandleFunc(urlWithTransferKey, handler.serveNotAllowed)
+
+	handler.router.HandleFunc(URLBaseV1Drain, handler.serveDrain).Methods(http.MethodPost)
+	handler.router.HandleFunc(URLBaseV1Drain, handler.serveNotAllowed)
+
 	handler.router.HandleFunc(URLBaseV1Channels, handler.serveListAll).Methods("GET")
 	handler.router.HandleFunc(URLBaseV1Channels, handler.serveNotAllowed)
 
@@ -124,9 +155,114 @@ func (h *HTTPHandler) serveListOne(resp http.ResponseWriter, req *http.Request)
 		h.sendResponseJsonError(resp, http.StatusNotFound, err)
 		return
 	}
+	infoFull.URL = path.Join(URLBaseV1Channels, channelID)
+	h.sendResponseOK(resp, infoFull)
+}
+
+// Pause or resume the consenter of a channel
+func (h *HTTPHandler) servePauseResume(resp http.ResponseWriter, req *http.Request) {
+	_, err := negotiateContentType(req) // Only application/json for now
+	if err != nil {
+		h.sendResponseJsonError(resp, http.StatusNotAcceptable, err)
+		return
+	}
+
+	channelID := mux.Vars(req)[channelIDKey]
+	if err = configtx.ValidateChannelID(channelID); err != nil {
+		h.sendResponseJsonError(resp, http.StatusBadRequest, errors.Wrap(err, "invalid channel ID"))
+		return
+	}
+
+	if mux.Vars(req)[actionKey] == actionPause {
+		err = h.registrar.PauseChannel(channelID)
+	} else {
+		err = h.registrar.ResumeChannel(channelID)
+	}
+	switch err {
+	case nil:
+	case types.ErrChannelNotExist:
+		h.sendResponseJsonError(resp, http.StatusNotFound, err)
+		return
+	case types.ErrSystemChannelPause:
+		h.sendResponseJsonError(resp, http.StatusBadRequest, err)
+		return
+	case types.ErrChannelPaused, types.ErrChannelNotPaused:
+		h.sendResponseJsonError(resp, http.StatusConflict, err)
+		return
+	default:
+		h.sendResponseJsonError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	infoFull, err := h.registrar.ChannelInfo(channelID)
+	if err != nil {
+		h.sendResponseJsonError(resp, http.StatusNotFound, err)
+		return
+	}
+	infoFull.URL = path.Join(URLBaseV1Channels, channelID)
+	h.sendResponseOK(resp, infoFull)
+}
+
+// Transfer the leadership of a channel away from this orderer
+func (h *HTTPHandler) serveTransferLeadership(resp http.ResponseWriter, req *http.Request) {
+	_, err := negotiateContentType(req) // Only application/json for now
+	if err != nil {
+		h.sendResponseJsonError(resp, http.StatusNotAcceptable, err)
+		return
+	}
+
+	channelID := mux.Vars(req)[channelIDKey]
+	if err = configtx.ValidateChannelID(channelID); err != nil {
+		h.sendResponseJsonError(resp, http.StatusBadRequest, errors.Wrap(err, "invalid channel ID"))
+		return
+	}
+
+	switch err = h.registrar.TransferLeadership(channelID); err {
+	case nil:
+	case types.ErrChannelNotExist:
+		h.sendResponseJsonError(resp, http.StatusNotFound, err)
+		return
+	case types.ErrLeadershipTransferNotSupported:
+		h.sendResponseJsonError(resp, http.StatusBadRequest, err)
+		return
+	default:
+		h.sendResponseJsonError(resp, http.StatusInternalServerError, err)
+		return
+	}
+
+	infoFull, err := h.registrar.ChannelInfo(channelID)
+	if err != nil {
+		h.sendResponseJsonError(resp, http.StatusNotFound, err)
+		return
+	}
+	infoFull.URL = path.Join(URLBaseV1Channels, channelID)
 	h.sendResponseOK(resp, infoFull)
 }
 
+// Drain the channels of this orderer before maintenance, optionally only the channels given in the query
+func (h *HTTPHandler) serveDrain(resp http.ResponseWriter, req *http.Request) {
+	_, err := negotiateContentType(req) // Only application/json for now
+	if err != nil {
+		h.sendResponseJsonError(resp, http.StatusNotAcceptable, err)
+		return
+	}
+
+	channelIDs := req.URL.Query()[drainChannelKey]
+	for _, channelID := range channelIDs {
+		if err = configtx.ValidateChannelID(channelID); err != nil {
+			h.sendResponseJsonError(resp, http.StatusBadRequest, errors.Wrap(err, "invalid channel ID"))
+			return
+		}
+	}
+
+	report := h.registrar.Drain(channelIDs)
+	if len(report.Failed) > 0 {
+		h.sendResponse(resp, http.StatusInternalServerError, report)
+		return
+	}
+	h.sendResponseOK(resp, report)
+}
+
 // Join a channel
 func (h *HTTPHandler) serveJoin(resp http.ResponseWriter, req *http.Request) {
 	_, err := negotiateContentType(req) // Only application/json for now
@@ -162,7 +298,9 @@ func (h *HTTPHandler) serveNotAllowed(resp http.ResponseWriter, req *http.Reques
 	err := errors.Errorf("invalid request method: %s", req.Method)
 	encoder := json.NewEncoder(resp)
 	resp.WriteHeader(http.StatusMethodNotAllowed)
-	if _, ok := mux.Vars(req)[channelIDKey]; ok {
+	if _, ok := mux.Vars(req)[actionKey]; ok || req.URL.Path == URLBaseV1Drain {
+		resp.Header().Set("Allow", "POST")
+	} else if _, ok := mux.Vars(req)[channelIDKey]; ok {
 		resp.Header().Set("Allow", "GET, POST, DELETE")
 	} else {
 		resp.Header().Set("Allow", "GET")
@@ -201,8 +339,12 @@ func (h *HTTPHandler) sendResponseJsonError(resp http.ResponseWriter, code int,
 }
 
 func (h *HTTPHandler) sendResponseOK(resp http.ResponseWriter, content interface{}) {
+	h.sendResponse(resp, http.StatusOK, content)
+}
+
+func (h *HTTPHandler) sendResponse(resp http.ResponseWriter, code int, content interface{}) {
 	encoder := json.NewEncoder(resp)
-	resp.WriteHeader(http.StatusOK)
+	resp.WriteHeader(code)
 	resp.Header().Set("Content-Type", "application/json")
 	if err := encoder.Encode(content); err != nil {
 		h.logger.Errorf("failed to encode content, err: %s", err)
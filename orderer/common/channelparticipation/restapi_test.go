@@ -233,6 +233,228 @@ func TestHTTPHandler_ServeHTTP_ListSingle(t *testing.T) {
 	})
 }
 
+func TestHTTPHandler_ServeHTTP_PauseResume(t *testing.T) {
+	config := localconfig.ChannelParticipation{Enabled: true, RemoveStorage: false}
+
+	t.Run("pause", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		info := types.ChannelInfo{
+			Name:            "app-channel",
+			ClusterRelation: "member",
+			Status:          "paused",
+			Height:          3,
+		}
+		fakeManager.ChannelInfoReturns(info, nil)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/app-channel/pause", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 1, fakeManager.PauseChannelCallCount())
+		assert.Equal(t, "app-channel", fakeManager.PauseChannelArgsForCall(0))
+		assert.Equal(t, 0, fakeManager.ResumeChannelCallCount())
+
+		infoResp := types.ChannelInfo{}
+		err := json.Unmarshal(resp.Body.Bytes(), &infoResp)
+		require.NoError(t, err, "cannot be unmarshaled")
+		info.URL = channelparticipation.URLBaseV1Channels + "/app-channel"
+		assert.Equal(t, info, infoResp)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		info := types.ChannelInfo{
+			Name:            "app-channel",
+			ClusterRelation: "member",
+			Status:          "active",
+			Height:          3,
+			Consensus:       &types.ConsensusStatus{Role: "follower", Leader: "orderer1:7050", HeightLag: 2},
+		}
+		fakeManager.ChannelInfoReturns(info, nil)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/app-channel/resume", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 1, fakeManager.ResumeChannelCallCount())
+		assert.Equal(t, "app-channel", fakeManager.ResumeChannelArgsForCall(0))
+		assert.Equal(t, 0, fakeManager.PauseChannelCallCount())
+
+		infoResp := types.ChannelInfo{}
+		err := json.Unmarshal(resp.Body.Bytes(), &infoResp)
+		require.NoError(t, err, "cannot be unmarshaled")
+		info.URL = channelparticipation.URLBaseV1Channels + "/app-channel"
+		assert.Equal(t, info, infoResp)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		for _, tc := range []struct {
+			err          error
+			expectedCode int
+		}{
+			{types.ErrChannelNotExist, http.StatusNotFound},
+			{types.ErrSystemChannelPause, http.StatusBadRequest},
+			{types.ErrChannelPaused, http.StatusConflict},
+			{types.ErrChannelNotPaused, http.StatusConflict},
+			{errors.New("oops"), http.StatusInternalServerError},
+		} {
+			fakeManager.PauseChannelReturns(tc.err)
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/app-channel/pause", nil)
+			h.ServeHTTP(resp, req)
+			checkErrorResponse(t, tc.expectedCode, tc.err.Error(), resp)
+		}
+	})
+
+	t.Run("invalid channel ID", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/App-Channel/resume", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Equal(t, 0, fakeManager.ResumeChannelCallCount())
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, channelparticipation.URLBaseV1Channels+"/app-channel/pause", nil)
+		h.ServeHTTP(resp, req)
+		checkErrorResponse(t, http.StatusMethodNotAllowed, "invalid request method: GET", resp)
+		assert.Equal(t, "POST", resp.Header().Get("Allow"))
+	})
+}
+
+func TestHTTPHandler_ServeHTTP_TransferLeadership(t *testing.T) {
+	config := localconfig.ChannelParticipation{Enabled: true, RemoveStorage: false}
+
+	t.Run("transfer", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		info := types.ChannelInfo{
+			Name:            "app-channel",
+			ClusterRelation: "member",
+			Status:          "active",
+			Height:          3,
+			Consensus:       &types.ConsensusStatus{Role: "follower", Leader: "orderer2:7050"},
+		}
+		fakeManager.ChannelInfoReturns(info, nil)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/app-channel/transfer-leadership", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 1, fakeManager.TransferLeadershipCallCount())
+		assert.Equal(t, "app-channel", fakeManager.TransferLeadershipArgsForCall(0))
+
+		infoResp := types.ChannelInfo{}
+		err := json.Unmarshal(resp.Body.Bytes(), &infoResp)
+		require.NoError(t, err, "cannot be unmarshaled")
+		info.URL = channelparticipation.URLBaseV1Channels + "/app-channel"
+		assert.Equal(t, info, infoResp)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		for _, tc := range []struct {
+			err          error
+			expectedCode int
+		}{
+			{types.ErrChannelNotExist, http.StatusNotFound},
+			{types.ErrLeadershipTransferNotSupported, http.StatusBadRequest},
+			{errors.New("no other consenter took over"), http.StatusInternalServerError},
+		} {
+			fakeManager.TransferLeadershipReturns(tc.err)
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Channels+"/app-channel/transfer-leadership", nil)
+			h.ServeHTTP(resp, req)
+			checkErrorResponse(t, tc.expectedCode, tc.err.Error(), resp)
+		}
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, channelparticipation.URLBaseV1Channels+"/app-channel/transfer-leadership", nil)
+		h.ServeHTTP(resp, req)
+		checkErrorResponse(t, http.StatusMethodNotAllowed, "invalid request method: GET", resp)
+		assert.Equal(t, "POST", resp.Header().Get("Allow"))
+	})
+}
+
+func TestHTTPHandler_ServeHTTP_Drain(t *testing.T) {
+	config := localconfig.ChannelParticipation{Enabled: true, RemoveStorage: false}
+
+	t.Run("all channels", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		report := types.DrainReport{Drained: []string{"app-channel1", "app-channel2"}}
+		fakeManager.DrainReturns(report)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Drain, nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 1, fakeManager.DrainCallCount())
+		assert.Empty(t, fakeManager.DrainArgsForCall(0))
+
+		reportResp := types.DrainReport{}
+		err := json.Unmarshal(resp.Body.Bytes(), &reportResp)
+		require.NoError(t, err, "cannot be unmarshaled")
+		assert.Equal(t, report, reportResp)
+	})
+
+	t.Run("selected channels", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		report := types.DrainReport{
+			Drained: []string{"app-channel1"},
+			Failed:  map[string]string{"app-channel2": "no other consenter took over"},
+		}
+		fakeManager.DrainReturns(report)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Drain+"?channel=app-channel1&channel=app-channel2", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		assert.Equal(t, 1, fakeManager.DrainCallCount())
+		assert.Equal(t, []string{"app-channel1", "app-channel2"}, fakeManager.DrainArgsForCall(0))
+
+		reportResp := types.DrainReport{}
+		err := json.Unmarshal(resp.Body.Bytes(), &reportResp)
+		require.NoError(t, err, "cannot be unmarshaled")
+		assert.Equal(t, report, reportResp)
+	})
+
+	t.Run("invalid channel ID", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, channelparticipation.URLBaseV1Drain+"?channel=App-Channel", nil)
+		h.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Equal(t, 0, fakeManager.DrainCallCount())
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		fakeManager := &mocks.ChannelManagement{}
+		h := channelparticipation.NewHTTPHandler(config, fakeManager)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, channelparticipation.URLBaseV1Drain, nil)
+		h.ServeHTTP(resp, req)
+		checkErrorResponse(t, http.StatusMethodNotAllowed, "invalid request method: GET", resp)
+		assert.Equal(t, "POST", resp.Header().Get("Allow"))
+	})
+}
+
 func TestHTTPHandler_ServeHTTP_Join(t *testing.T) {
 	t.Run("not implemented yet", func(t *testing.T) {
 		config := localconfig.ChannelParticipation{Enabled: true, RemoveStorage: false}
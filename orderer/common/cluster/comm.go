@@ -104,6 +104,10 @@ type Comm struct {
 	Connections                      *ConnectionStore
 	Chan2Members                     MembersByChannel
 	Metrics                          *Metrics
+	// CertRotationOverlap is the period after the TLS certificates of a node are
+	// rotated, during which its previous certificates are still accepted.
+	// A zero value means the previous certificates are rejected right away.
+	CertRotationOverlap time.Duration
 }
 
 type requestContext struct {
@@ -291,6 +295,19 @@ func (c *Comm) updateStubInMapping(channel string, mapping MemberMapping, node R
 		stub = &Stub{}
 	}
 
+	// Keep accepting the previous TLS certificates of the node during
+	// the overlap window of the rotation, to allow the node to switch
+	// to its new certificates without being cut off from the cluster
+	if certificatesRotated(stub.RemoteNode, node) && c.CertRotationOverlap > 0 {
+		c.Logger.Info("TLS certificates of node", node.ID, "in channel", channel,
+			"are rotated, accepting its previous certificates for", c.CertRotationOverlap)
+		stub.rotated = &rotatedCertificates{
+			serverTLSCert: stub.ServerTLSCert,
+			clientTLSCert: stub.ClientTLSCert,
+			acceptedUntil: time.Now().Add(c.CertRotationOverlap),
+		}
+	}
+
 	// Check if the TLS server certificate of the node is replaced
 	// and if so - then deactivate the stub, to trigger
 	// a re-creation of its gRPC connection
@@ -329,7 +346,12 @@ func (c *Comm) createRemoteContext(stub *Stub, channel string) func() (*RemoteCo
 
 		c.Logger.Debug("Connecting to", stub.RemoteNode, "for channel", channel)
 
-		conn, err := c.Connections.Connection(stub.Endpoint, stub.ServerTLSCert)
+		var conn *grpc.ClientConn
+		if stub.rotated.accepted(time.Now()) && !bytes.Equal(stub.rotated.serverTLSCert, stub.ServerTLSCert) {
+			conn, err = c.Connections.ConnectionDuringRotation(stub.Endpoint, stub.ServerTLSCert, stub.rotated.serverTLSCert, stub.rotated.acceptedUntil)
+		} else {
+			conn, err = c.Connections.Connection(stub.Endpoint, stub.ServerTLSCert)
+		}
 		if err != nil {
 			c.Logger.Warningf("Unable to obtain connection to %d(%s) (channel %s): %v", stub.ID, stub.Endpoint, channel, err)
 			return nil, err
@@ -387,6 +409,31 @@ type Stub struct {
 	lock sync.RWMutex
 	RemoteNode
 	*RemoteContext
+	rotated *rotatedCertificates
+}
+
+// rotatedCertificates are the TLS certificates of a node
+// before they were rotated
+type rotatedCertificates struct {
+	serverTLSCert []byte
+	clientTLSCert []byte
+	// acceptedUntil is the end of the overlap window of the rotation
+	acceptedUntil time.Time
+}
+
+// accepted returns whether the certificates are still accepted at the given time
+func (rc *rotatedCertificates) accepted(now time.Time) bool {
+	return rc != nil && now.Before(rc.acceptedUntil)
+}
+
+// certificatesRotated returns whether the TLS certificates of a known
+// node are replaced by the given node
+func certificatesRotated(existing, updated RemoteNode) bool {
+	if existing.ID == 0 {
+		return false
+	}
+	return !bytes.Equal(existing.ServerTLSCert, updated.ServerTLSCert) ||
+		!bytes.Equal(existing.ClientTLSCert, updated.ClientTLSCert)
 }
 
 // Active returns whether the Stub
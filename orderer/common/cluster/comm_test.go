@@ -11,6 +11,7 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
@@ -144,6 +145,9 @@ type clusterNode struct {
 func (cn *clusterNode) Step(stream orderer.Cluster_StepServer) error {
 	cn.waitIfFrozen()
 	req, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -900,6 +904,64 @@ func TestRenewCertificates(t *testing.T) {
 	assertBiDiCommunication(t, node1, node2, testReq)
 }
 
+func TestCertRotationOverlap(t *testing.T) {
+	// Scenario: node 1 and node 2 are connected, and the certificates
+	// of node 2 are rotated in the configuration of node 1, while node 2
+	// still uses its previous certificates.
+	// Node 1 is expected to keep communicating with node 2 until
+	// the overlap window of the rotation ends.
+
+	node1 := newTestNode(t)
+	defer node1.stop()
+	node1.c.CertRotationOverlap = time.Second * 3
+
+	node2 := newTestNode(t)
+	defer node2.stop()
+
+	config := []cluster.RemoteNode{node1.nodeInfo, node2.nodeInfo}
+	node1.c.Configure(testChannel, config)
+	node2.c.Configure(testChannel, config)
+
+	assertBiDiCommunication(t, node1, node2, testReq)
+
+	clientKeyPair, err := ca.NewClientCertKeyPair()
+	assert.NoError(t, err)
+	serverKeyPair, err := ca.NewServerCertKeyPair("127.0.0.1")
+	assert.NoError(t, err)
+
+	rotated := node2.nodeInfo
+	rotated.ClientTLSCert = clientKeyPair.TLSCert.Raw
+	rotated.ServerTLSCert = serverKeyPair.TLSCert.Raw
+	node1.c.Configure(testChannel, []cluster.RemoteNode{node1.nodeInfo, rotated})
+
+	// Node 2 didn't switch to its new certificates, but it is still accepted
+	assertBiDiCommunication(t, node1, node2, testReq)
+
+	// Once the overlap window ends, the previous client certificate of node 2 is rejected
+	node1.handler.On("OnSubmit", testChannel, node2.nodeInfo.ID, mock.Anything).Return(nil)
+	submitWithPreviousCert := func() error {
+		cl, err := comm_utils.NewGRPCClient(node2.clientConfig)
+		assert.NoError(t, err)
+		conn, err := cl.NewConnection(node1.srv.Address())
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		stream, err := orderer.NewClusterClient(conn).Step(context.Background())
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(wrapSubmitReq(testReq)); err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	gt := gomega.NewGomegaWithT(t)
+	gt.Eventually(submitWithPreviousCert, timeout).Should(gomega.MatchError(
+		"rpc error: code = Unknown desc = certificate extracted from TLS connection isn't authorized"))
+}
+
 func TestMembershipReconfiguration(t *testing.T) {
 	// Scenario: node 1 and node 2 are started up
 	// and node 2 is configured to know about node 1,
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"crypto/x509"
 	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/pkg/errors"
@@ -64,6 +65,20 @@ func (c *ConnectionStore) verifyHandshake(endpoint string, certificate []byte) R
 	}
 }
 
+// verifyHandshakeDuringRotation returns a predicate that verifies that the remote node authenticates
+// itself with the given TLS certificate, or with its previous TLS certificate until the given time
+func (c *ConnectionStore) verifyHandshakeDuringRotation(endpoint string, certificate, previousCertificate []byte, acceptPreviousUntil time.Time) RemoteVerifier {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if bytes.Equal(certificate, rawCerts[0]) {
+			return nil
+		}
+		if bytes.Equal(previousCertificate, rawCerts[0]) && time.Now().Before(acceptPreviousUntil) {
+			return nil
+		}
+		return errors.Errorf("certificate presented by %s doesn't match any authorized certificate", endpoint)
+	}
+}
+
 // Disconnect closes the gRPC connection that is mapped to the given certificate
 func (c *ConnectionStore) Disconnect(expectedServerCert []byte) {
 	c.lock.Lock()
@@ -80,6 +95,18 @@ func (c *ConnectionStore) Disconnect(expectedServerCert []byte) {
 // Connection obtains a connection to the given endpoint and expects the given server certificate
 // to be presented by the remote node
 func (c *ConnectionStore) Connection(endpoint string, expectedServerCert []byte) (*grpc.ClientConn, error) {
+	return c.connection(endpoint, expectedServerCert, c.verifyHandshake(endpoint, expectedServerCert))
+}
+
+// ConnectionDuringRotation obtains a connection to the given endpoint like Connection does,
+// but while the TLS server certificate of the remote node is being rotated it also accepts
+// the previous certificate of the node, until the given time.
+func (c *ConnectionStore) ConnectionDuringRotation(endpoint string, expectedServerCert, previousServerCert []byte, acceptPreviousUntil time.Time) (*grpc.ClientConn, error) {
+	v := c.verifyHandshakeDuringRotation(endpoint, expectedServerCert, previousServerCert, acceptPreviousUntil)
+	return c.connection(endpoint, expectedServerCert, v)
+}
+
+func (c *ConnectionStore) connection(endpoint string, expectedServerCert []byte, v RemoteVerifier) (*grpc.ClientConn, error) {
 	c.lock.RLock()
 	conn, alreadyConnected := c.Connections.Lookup(expectedServerCert)
 	c.lock.RUnlock()
@@ -89,12 +116,12 @@ func (c *ConnectionStore) Connection(endpoint string, expectedServerCert []byte)
 	}
 
 	// Else, we need to connect to the remote endpoint
-	return c.connect(endpoint, expectedServerCert)
+	return c.connect(endpoint, expectedServerCert, v)
 }
 
 // connect connects to the given endpoint and expects the given TLS server certificate
-// to be presented at the time of authentication
-func (c *ConnectionStore) connect(endpoint string, expectedServerCert []byte) (*grpc.ClientConn, error) {
+// to be presented at the time of authentication, as verified by the given RemoteVerifier
+func (c *ConnectionStore) connect(endpoint string, expectedServerCert []byte, v RemoteVerifier) (*grpc.ClientConn, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	// Check again to see if some other goroutine has already connected while
@@ -104,7 +131,6 @@ func (c *ConnectionStore) connect(endpoint string, expectedServerCert []byte) (*
 		return conn, nil
 	}
 
-	v := c.verifyHandshake(endpoint, expectedServerCert)
 	conn, err := c.dialer.Dial(endpoint, v)
 	if err != nil {
 		return nil, err
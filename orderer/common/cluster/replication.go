@@ -8,6 +8,7 @@ package cluster
 
 import (
 	"bytes"
+	"crypto"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
@@ -339,6 +340,7 @@ func (r *Replicator) channelsToPull(channels GenesisBlocks) channelPullHints {
 type PullerConfig struct {
 	TLSKey              []byte
 	TLSCert             []byte
+	TLSSigner           crypto.Signer
 	Timeout             time.Duration
 	Signer              identity.SignerSerializer
 	Channel             string
@@ -369,6 +371,7 @@ func BlockPullerFromConfigBlock(conf PullerConfig, block *common.Block, verifier
 		SecOpts: comm.SecureOptions{
 			Certificate:       conf.TLSCert,
 			Key:               conf.TLSKey,
+			Signer:            conf.TLSSigner,
 			RequireClientCert: true,
 			UseTLS:            true,
 		},
@@ -8,17 +8,20 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/configtx"
@@ -70,13 +73,21 @@ func (mp MemberMapping) ByID(ID uint64) *Stub {
 	return mp[ID]
 }
 
-// LookupByClientCert retrieves a Stub with the given client certificate
+// LookupByClientCert retrieves a Stub with the given client certificate,
+// or with the given previous client certificate if the certificates of the
+// node are being rotated
 func (mp MemberMapping) LookupByClientCert(cert []byte) *Stub {
 	for _, stub := range mp {
 		if bytes.Equal(stub.ClientTLSCert, cert) {
 			return stub
 		}
 	}
+	now := time.Now()
+	for _, stub := range mp {
+		if stub.rotated.accepted(now) && bytes.Equal(stub.rotated.clientTLSCert, cert) {
+			return stub
+		}
+	}
 	return nil
 }
 
@@ -147,6 +158,55 @@ func (dialer *PredicateDialer) Dial(address string, verifyFunc RemoteVerifier) (
 	})
 }
 
+// VerifyConnectivity connects to each of the given nodes with the given client configuration,
+// which is used to check that the cluster accepts a new TLS client certificate of a node before
+// the node switches to it. It returns the errors of the nodes that cannot be connected to,
+// or do not authenticate themselves with their TLS server certificate, by their IDs.
+func VerifyConnectivity(config comm.ClientConfig, nodes []RemoteNode) map[uint64]error {
+	// Wait for the TLS handshakes to complete
+	config.AsyncConnect = false
+	dialer := &PredicateDialer{Config: config}
+	errs := make(map[uint64]error)
+	for _, node := range nodes {
+		serverCert := node.ServerTLSCert
+		endpoint := node.Endpoint
+		conn, err := dialer.Dial(endpoint, func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if bytes.Equal(serverCert, rawCerts[0]) {
+				return nil
+			}
+			return errors.Errorf("certificate presented by %s doesn't match any authorized certificate", endpoint)
+		})
+		if err != nil {
+			errs[node.ID] = err
+			continue
+		}
+		if err := probeStep(conn, config.Timeout); err != nil {
+			errs[node.ID] = err
+		}
+		conn.Close()
+	}
+	return errs
+}
+
+// probeStep opens a Step stream and closes it right away, as with TLS 1.3 the server
+// may only reject the client certificate after the client completes the handshake
+func probeStep(conn *grpc.ClientConn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stream, err := orderer.NewClusterClient(conn).Step(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		return errors.Errorf("stream was not closed gracefully: %v", err)
+	}
+	return nil
+}
+
 // DERtoPEM returns a PEM representation of the DER
 // encoded certificate
 func DERtoPEM(der []byte) string {
@@ -141,6 +141,41 @@ func TestPredicateDialerUpdateRootCAs(t *testing.T) {
 	assert.Fail(t, "could not connect after 10 attempts despite changing TLS CAs")
 }
 
+func TestVerifyConnectivity(t *testing.T) {
+	node1 := newTestNode(t)
+	defer node1.stop()
+	node2 := newTestNode(t)
+	defer node2.stop()
+
+	clientConfig := node1.clientConfig.Clone()
+	clientConfig.Timeout = time.Second
+
+	impostor := node2.nodeInfo
+	impostor.ServerTLSCert = node1.nodeInfo.ServerTLSCert
+
+	errs := cluster.VerifyConnectivity(clientConfig, []cluster.RemoteNode{node2.nodeInfo, impostor})
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, impostor.ID)
+
+	// A client certificate from an unknown CA is rejected by the nodes
+	node2.srv.Stop()
+	node2.serverConfig.SecOpts.RequireClientCert = true
+	node2.serverConfig.SecOpts.ClientRootCAs = [][]byte{ca.CertBytes()}
+	node2.resurrect()
+	errs = cluster.VerifyConnectivity(clientConfig, []cluster.RemoteNode{node2.nodeInfo})
+	assert.Empty(t, errs)
+
+	anotherTLSCA, err := tlsgen.NewCA()
+	assert.NoError(t, err)
+	clientKeyPair, err := anotherTLSCA.NewClientCertKeyPair()
+	assert.NoError(t, err)
+	clientConfig.SecOpts.Certificate = clientKeyPair.Cert
+	clientConfig.SecOpts.Key = clientKeyPair.Key
+
+	errs = cluster.VerifyConnectivity(clientConfig, []cluster.RemoteNode{node2.nodeInfo})
+	assert.Len(t, errs, 1)
+}
+
 func TestDialerBadConfig(t *testing.T) {
 	emptyCertificate := []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----")
 	dialer := &cluster.PredicateDialer{
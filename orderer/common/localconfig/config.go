@@ -35,9 +35,22 @@ type TopLevel struct {
 	Kafka                Kafka
 	Debug                Debug
 	Consensus            interface{}
+	ConsensusPlugins     []ConsensusPlugin
 	Operations           Operations
 	Metrics              Metrics
+	Tracing              Tracing
+	Audit                Audit
 	ChannelParticipation ChannelParticipation
+	Logging              Logging
+	CertificateMonitor   CertificateMonitor
+}
+
+// ConsensusPlugin contains configuration for a consensus type that is implemented
+// by a Go plugin.
+type ConsensusPlugin struct {
+	Type    string
+	Library string
+	Config  interface{}
 }
 
 // General contains config which should be common among all orderer types.
@@ -57,6 +70,9 @@ type General struct {
 	LocalMSPID        string
 	BCCSP             *bccsp.FactoryOpts
 	Authentication    Authentication
+	RateLimit         RateLimit
+	ServiceLimits     ServiceLimits
+	TLSKeysFromBCCSP  bool
 }
 
 type Cluster struct {
@@ -77,6 +93,7 @@ type Cluster struct {
 	SendBufferSize                       int
 	CertExpirationWarningThreshold       time.Duration
 	TLSHandshakeTimeShift                time.Duration
+	CertRotationOverlap                  time.Duration
 }
 
 // Keepalive contains configuration for gRPC servers.
@@ -110,6 +127,30 @@ type Authentication struct {
 	NoExpirationChecks bool
 }
 
+// RateLimit contains configuration parameters for limiting the rate at which
+// clients may broadcast messages.
+type RateLimit struct {
+	Enabled   bool
+	Scope     string
+	TPS       uint32
+	Bandwidth uint32
+}
+
+// ServiceLimits contains the limits of the Broadcast and Deliver services of
+// the orderer.
+type ServiceLimits struct {
+	Broadcast ServiceLimit
+	Deliver   ServiceLimit
+}
+
+// ServiceLimit limits the concurrent streams of a service, and the size of the
+// messages it receives and sends. A zero value leaves the limit of the server.
+type ServiceLimit struct {
+	MaxConcurrency int
+	MaxRecvMsgSize uint32
+	MaxSendMsgSize uint32
+}
+
 // Profile contains configuration for Go pprof profiling.
 type Profile struct {
 	Enabled bool
@@ -190,6 +231,15 @@ type Debug struct {
 type Operations struct {
 	ListenAddress string
 	TLS           TLS
+	Profiling     Profiling
+}
+
+// Profiling configures the capture of profiles through the operations
+// endpoint, which requires TLS client authentication.
+type Profiling struct {
+	Enabled     bool
+	MaxDuration time.Duration // The longest CPU profile or execution trace that can be captured.
+	MaxCaptures int           // The number of captures kept for retrieval.
 }
 
 // Metrics configures the metrics provider for the orderer.
@@ -206,6 +256,34 @@ type Statsd struct {
 	Prefix        string
 }
 
+// Tracing configures the export of the spans of the transactions processed by
+// the orderer to an OpenTelemetry collector.
+type Tracing struct {
+	Endpoint    string  // The OTLP/HTTP endpoint of the collector; tracing is disabled when empty.
+	ServiceName string  // The service name the spans are reported under.
+	SampleRate  float64 // The fraction of transactions without trace context to sample.
+}
+
+// Audit configures the security audit log of the orderer.
+type Audit struct {
+	File   string // The file the audit log is appended to; no file is written when empty.
+	Stderr bool   // Whether the audit log is written to the standard error as well.
+}
+
+// Logging configures the logging of the orderer.
+type Logging struct {
+	Spec string // The logging specification, unless FABRIC_LOGGING_SPEC is set; reloaded on SIGHUP.
+}
+
+// CertificateMonitor configures the early warning of the expiration of the
+// certificates of the local MSP, the TLS certificates and the certificates of
+// the channel configurations.
+type CertificateMonitor struct {
+	Interval   time.Duration // How often the certificates are checked.
+	Thresholds []int         // The days before expiration at which alerts are raised.
+	Webhook    string        // The URL the alerts are posted to, in addition to being logged.
+}
+
 // ChannelParticipation provides the channel participation API configuration for the orderer.
 // Channel participation uses the same ListenAddress and TLS settings of the Operations service.
 type ChannelParticipation struct {
@@ -241,6 +319,9 @@ var Defaults = TopLevel{
 		Authentication: Authentication{
 			TimeWindow: time.Duration(15 * time.Minute),
 		},
+		RateLimit: RateLimit{
+			Scope: "Client",
+		},
 	},
 	FileLedger: FileLedger{
 		Location: "/var/hyperledger/production/orderer",
@@ -284,14 +365,26 @@ var Defaults = TopLevel{
 	},
 	Operations: Operations{
 		ListenAddress: "127.0.0.1:0",
+		Profiling: Profiling{
+			MaxDuration: 5 * time.Minute,
+			MaxCaptures: 8,
+		},
 	},
 	Metrics: Metrics{
 		Provider: "disabled",
 	},
+	Tracing: Tracing{
+		ServiceName: "orderer",
+		SampleRate:  1,
+	},
 	ChannelParticipation: ChannelParticipation{
 		Enabled:       false,
 		RemoveStorage: false,
 	},
+	CertificateMonitor: CertificateMonitor{
+		Interval:   time.Hour,
+		Thresholds: []int{30, 7, 1},
+	},
 }
 
 // Load parses the orderer YAML file and environment, producing
@@ -309,11 +402,9 @@ type configCache struct {
 
 var cache = &configCache{}
 
-// Load will load the configuration and cache it on the first call; subsequent
-// calls will return a clone of the configuration that was previously loaded.
-func (c *configCache) load() (*TopLevel, error) {
-	var uconf TopLevel
-
+// ReadConfig reads the orderer configuration file, with the overrides of the
+// environment, without unmarshaling it or caching it.
+func ReadConfig() (*viper.Viper, error) {
 	config := viper.New()
 	coreconfig.InitViper(config, "orderer")
 	config.SetEnvPrefix(Prefix)
@@ -324,6 +415,18 @@ func (c *configCache) load() (*TopLevel, error) {
 	if err := config.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("Error reading configuration: %s", err)
 	}
+	return config, nil
+}
+
+// Load will load the configuration and cache it on the first call; subsequent
+// calls will return a clone of the configuration that was previously loaded.
+func (c *configCache) load() (*TopLevel, error) {
+	var uconf TopLevel
+
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -345,7 +448,7 @@ func (c *configCache) load() (*TopLevel, error) {
 		c.cache[config.ConfigFileUsed()] = serializedConf
 	}
 
-	err := json.Unmarshal(serializedConf, &uconf)
+	err = json.Unmarshal(serializedConf, &uconf)
 	if err != nil {
 		return nil, err
 	}
@@ -373,6 +476,9 @@ func (c *TopLevel) completeInitialization(configDir string) {
 		coreconfig.TranslatePathInPlace(configDir, &c.General.LocalMSPDir)
 		// Translate file ledger location
 		coreconfig.TranslatePathInPlace(configDir, &c.FileLedger.Location)
+		if c.Audit.File != "" {
+			coreconfig.TranslatePathInPlace(configDir, &c.Audit.File)
+		}
 	}()
 
 	for {
@@ -444,6 +550,21 @@ func (c *TopLevel) completeInitialization(configDir string) {
 			logger.Infof("General.Authentication.TimeWindow unset, setting to %s", Defaults.General.Authentication.TimeWindow)
 			c.General.Authentication.TimeWindow = Defaults.General.Authentication.TimeWindow
 
+		case c.General.RateLimit.Enabled && c.General.RateLimit.Scope == "":
+			logger.Infof("General.RateLimit.Scope unset, setting to %s", Defaults.General.RateLimit.Scope)
+			c.General.RateLimit.Scope = Defaults.General.RateLimit.Scope
+
+		case c.Tracing.Endpoint != "" && c.Tracing.ServiceName == "":
+			logger.Infof("Tracing.ServiceName unset, setting to %s", Defaults.Tracing.ServiceName)
+			c.Tracing.ServiceName = Defaults.Tracing.ServiceName
+
+		case c.CertificateMonitor.Interval == 0:
+			logger.Infof("CertificateMonitor.Interval unset, setting to %v", Defaults.CertificateMonitor.Interval)
+			c.CertificateMonitor.Interval = Defaults.CertificateMonitor.Interval
+		case len(c.CertificateMonitor.Thresholds) == 0:
+			logger.Infof("CertificateMonitor.Thresholds unset, setting to %v", Defaults.CertificateMonitor.Thresholds)
+			c.CertificateMonitor.Thresholds = Defaults.CertificateMonitor.Thresholds
+
 		case c.FileLedger.Prefix == "":
 			logger.Infof("FileLedger.Prefix unset, setting to %s", Defaults.FileLedger.Prefix)
 			c.FileLedger.Prefix = Defaults.FileLedger.Prefix
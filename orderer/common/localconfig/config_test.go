@@ -271,3 +271,48 @@ func TestChannelParticipationDefaults(t *testing.T) {
 	assert.Equal(t, cfg.ChannelParticipation.Enabled, Defaults.ChannelParticipation.Enabled)
 	assert.Equal(t, cfg.ChannelParticipation.RemoveStorage, Defaults.ChannelParticipation.RemoveStorage)
 }
+
+func TestTracingDefaults(t *testing.T) {
+	cfg := TopLevel{Tracing: Tracing{Endpoint: "http://localhost:4318"}}
+	cfg.completeInitialization("/dummy/path")
+	assert.Equal(t, Defaults.Tracing.ServiceName, cfg.Tracing.ServiceName)
+
+	cleanup := configtest.SetDevFabricConfigPath(t)
+	defer cleanup()
+
+	cc := &configCache{}
+	loaded, err := cc.load()
+	assert.NoError(t, err)
+	assert.Equal(t, Tracing{ServiceName: "orderer", SampleRate: 1}, loaded.Tracing)
+}
+
+func TestAuditFileTranslation(t *testing.T) {
+	cfg := TopLevel{}
+	cfg.completeInitialization("/dummy/path")
+	assert.Empty(t, cfg.Audit.File)
+
+	cfg = TopLevel{Audit: Audit{File: "audit.log"}}
+	cfg.completeInitialization("/dummy/path")
+	assert.Equal(t, filepath.Join("/dummy/path", "audit.log"), cfg.Audit.File)
+
+	cfg = TopLevel{Audit: Audit{File: "/var/log/audit.log"}}
+	cfg.completeInitialization("/dummy/path")
+	assert.Equal(t, "/var/log/audit.log", cfg.Audit.File)
+}
+
+func TestServiceLimits(t *testing.T) {
+	os.Setenv("ORDERER_GENERAL_SERVICELIMITS_BROADCAST_MAXRECVMSGSIZE", "10 MB")
+	defer os.Unsetenv("ORDERER_GENERAL_SERVICELIMITS_BROADCAST_MAXRECVMSGSIZE")
+	os.Setenv("ORDERER_GENERAL_SERVICELIMITS_DELIVER_MAXCONCURRENCY", "500")
+	defer os.Unsetenv("ORDERER_GENERAL_SERVICELIMITS_DELIVER_MAXCONCURRENCY")
+	cleanup := configtest.SetDevFabricConfigPath(t)
+	defer cleanup()
+
+	cc := &configCache{}
+	cfg, err := cc.load()
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceLimits{
+		Broadcast: ServiceLimit{MaxRecvMsgSize: 10 * 1024 * 1024},
+		Deliver:   ServiceLimit{MaxConcurrency: 500},
+	}, cfg.General.ServiceLimits)
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package multichannel
 
 import (
+	"github.com/golang/protobuf/proto"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/common/channelconfig"
@@ -78,13 +79,22 @@ func newChainSupport(
 		logger.Panicf("Error retrieving consenter of type: %s", consenterType)
 	}
 
+	plugin, isPlugin := consenter.(consensus.Plugin)
+	if isPlugin {
+		if err := checkPluginCapabilities(plugin, ledgerResources.ConfigtxValidator().ConfigProto()); err != nil {
+			logger.Panicf("[channel: %s] Error creating consenter: %s", cs.ChannelID(), err)
+		}
+	}
+
 	cs.Chain, err = consenter.HandleChain(cs, metadata)
 	if err != nil {
 		logger.Panicf("[channel: %s] Error creating consenter: %s", cs.ChannelID(), err)
 	}
 
 	cs.MetadataValidator, ok = cs.Chain.(consensus.MetadataValidator)
-	if !ok {
+	if !ok && isPlugin {
+		cs.MetadataValidator = plugin
+	} else if !ok {
 		cs.MetadataValidator = consensus.NoOpMetadataValidator{}
 	}
 
@@ -93,6 +103,34 @@ func newChainSupport(
 	return cs
 }
 
+// checkPluginCapabilities verifies that a consensus plugin supports all the orderer capabilities
+// which are required by the config of a channel.
+func checkPluginCapabilities(plugin consensus.Plugin, config *cb.Config) error {
+	ordererGroup, ok := config.GetChannelGroup().GetGroups()[channelconfig.OrdererGroupKey]
+	if !ok {
+		return nil
+	}
+	capabilitiesValue, ok := ordererGroup.Values[channelconfig.CapabilitiesKey]
+	if !ok {
+		return nil
+	}
+	capabilities := &cb.Capabilities{}
+	if err := proto.Unmarshal(capabilitiesValue.Value, capabilities); err != nil {
+		return errors.Wrap(err, "failed unmarshaling orderer capabilities")
+	}
+
+	supported := map[string]bool{}
+	for _, capability := range plugin.SupportedCapabilities() {
+		supported[capability] = true
+	}
+	for capability := range capabilities.Capabilities {
+		if !supported[capability] {
+			return errors.Errorf("consensus plugin does not support the orderer capability %s", capability)
+		}
+	}
+	return nil
+}
+
 // Block returns a block with the following number,
 // or nil if such a block doesn't exist.
 func (cs *ChainSupport) Block(number uint64) *cb.Block {
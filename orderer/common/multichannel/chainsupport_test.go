@@ -184,3 +184,35 @@ func testConfigEnvelope(t *testing.T) *common.ConfigEnvelope {
 		},
 	}
 }
+
+func TestCheckPluginCapabilities(t *testing.T) {
+	config := &common.Config{
+		ChannelGroup: &common.ConfigGroup{
+			Groups: map[string]*common.ConfigGroup{
+				channelconfig.OrdererGroupKey: {
+					Values: map[string]*common.ConfigValue{
+						channelconfig.CapabilitiesKey: {
+							Value: protoutil.MarshalOrPanic(&common.Capabilities{
+								Capabilities: map[string]*common.Capability{"V2_0": {}},
+							}),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := checkPluginCapabilities(&mockPlugin{capabilities: []string{"V1_1", "V2_0"}}, config)
+	assert.NoError(t, err)
+
+	err = checkPluginCapabilities(&mockPlugin{capabilities: []string{"V1_1"}}, config)
+	assert.EqualError(t, err, "consensus plugin does not support the orderer capability V2_0")
+
+	err = checkPluginCapabilities(&mockPlugin{}, &common.Config{ChannelGroup: &common.ConfigGroup{}})
+	assert.NoError(t, err)
+
+	config.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Values[channelconfig.CapabilitiesKey].Value = []byte("garbage")
+	err = checkPluginCapabilities(&mockPlugin{}, config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed unmarshaling orderer capabilities")
+}
@@ -2,8 +2,10 @@
 package mocks
 
 import (
+	"crypto/x509"
 	"sync"
 
+	"github.com/hyperledger/fabric/bccsp/utils"
 	"github.com/hyperledger/fabric/msp"
 )
 
@@ -18,6 +20,16 @@ type ChannelCapabilities struct {
 	consensusTypeMigrationReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	LowSPolicyStub        func() utils.LowSPolicy
+	lowSPolicyMutex       sync.RWMutex
+	lowSPolicyArgsForCall []struct {
+	}
+	lowSPolicyReturns struct {
+		result1 utils.LowSPolicy
+	}
+	lowSPolicyReturnsOnCall map[int]struct {
+		result1 utils.LowSPolicy
+	}
 	MSPVersionStub        func() msp.MSPVersion
 	mSPVersionMutex       sync.RWMutex
 	mSPVersionArgsForCall []struct {
@@ -38,6 +50,16 @@ type ChannelCapabilities struct {
 	orgSpecificOrdererEndpointsReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	SignatureAlgorithmsStub        func() []x509.PublicKeyAlgorithm
+	signatureAlgorithmsMutex       sync.RWMutex
+	signatureAlgorithmsArgsForCall []struct {
+	}
+	signatureAlgorithmsReturns struct {
+		result1 []x509.PublicKeyAlgorithm
+	}
+	signatureAlgorithmsReturnsOnCall map[int]struct {
+		result1 []x509.PublicKeyAlgorithm
+	}
 	SupportedStub        func() error
 	supportedMutex       sync.RWMutex
 	supportedArgsForCall []struct {
@@ -104,6 +126,58 @@ func (fake *ChannelCapabilities) ConsensusTypeMigrationReturnsOnCall(i int, resu
 	}{result1}
 }
 
+func (fake *ChannelCapabilities) LowSPolicy() utils.LowSPolicy {
+	fake.lowSPolicyMutex.Lock()
+	ret, specificReturn := fake.lowSPolicyReturnsOnCall[len(fake.lowSPolicyArgsForCall)]
+	fake.lowSPolicyArgsForCall = append(fake.lowSPolicyArgsForCall, struct {
+	}{})
+	fake.recordInvocation("LowSPolicy", []interface{}{})
+	fake.lowSPolicyMutex.Unlock()
+	if fake.LowSPolicyStub != nil {
+		return fake.LowSPolicyStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.lowSPolicyReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelCapabilities) LowSPolicyCallCount() int {
+	fake.lowSPolicyMutex.RLock()
+	defer fake.lowSPolicyMutex.RUnlock()
+	return len(fake.lowSPolicyArgsForCall)
+}
+
+func (fake *ChannelCapabilities) LowSPolicyCalls(stub func() utils.LowSPolicy) {
+	fake.lowSPolicyMutex.Lock()
+	defer fake.lowSPolicyMutex.Unlock()
+	fake.LowSPolicyStub = stub
+}
+
+func (fake *ChannelCapabilities) LowSPolicyReturns(result1 utils.LowSPolicy) {
+	fake.lowSPolicyMutex.Lock()
+	defer fake.lowSPolicyMutex.Unlock()
+	fake.LowSPolicyStub = nil
+	fake.lowSPolicyReturns = struct {
+		result1 utils.LowSPolicy
+	}{result1}
+}
+
+func (fake *ChannelCapabilities) LowSPolicyReturnsOnCall(i int, result1 utils.LowSPolicy) {
+	fake.lowSPolicyMutex.Lock()
+	defer fake.lowSPolicyMutex.Unlock()
+	fake.LowSPolicyStub = nil
+	if fake.lowSPolicyReturnsOnCall == nil {
+		fake.lowSPolicyReturnsOnCall = make(map[int]struct {
+			result1 utils.LowSPolicy
+		})
+	}
+	fake.lowSPolicyReturnsOnCall[i] = struct {
+		result1 utils.LowSPolicy
+	}{result1}
+}
+
 func (fake *ChannelCapabilities) MSPVersion() msp.MSPVersion {
 	fake.mSPVersionMutex.Lock()
 	ret, specificReturn := fake.mSPVersionReturnsOnCall[len(fake.mSPVersionArgsForCall)]
@@ -122,6 +196,8 @@ func (fake *ChannelCapabilities) MSPVersion() msp.MSPVersion {
 }
 
 func (fake *ChannelCapabilities) MSPVersionCallCount() int {
+	fake.lowSPolicyMutex.RLock()
+	defer fake.lowSPolicyMutex.RUnlock()
 	fake.mSPVersionMutex.RLock()
 	defer fake.mSPVersionMutex.RUnlock()
 	return len(fake.mSPVersionArgsForCall)
@@ -208,6 +284,58 @@ func (fake *ChannelCapabilities) OrgSpecificOrdererEndpointsReturnsOnCall(i int,
 	}{result1}
 }
 
+func (fake *ChannelCapabilities) SignatureAlgorithms() []x509.PublicKeyAlgorithm {
+	fake.signatureAlgorithmsMutex.Lock()
+	ret, specificReturn := fake.signatureAlgorithmsReturnsOnCall[len(fake.signatureAlgorithmsArgsForCall)]
+	fake.signatureAlgorithmsArgsForCall = append(fake.signatureAlgorithmsArgsForCall, struct {
+	}{})
+	fake.recordInvocation("SignatureAlgorithms", []interface{}{})
+	fake.signatureAlgorithmsMutex.Unlock()
+	if fake.SignatureAlgorithmsStub != nil {
+		return fake.SignatureAlgorithmsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.signatureAlgorithmsReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelCapabilities) SignatureAlgorithmsCallCount() int {
+	fake.signatureAlgorithmsMutex.RLock()
+	defer fake.signatureAlgorithmsMutex.RUnlock()
+	return len(fake.signatureAlgorithmsArgsForCall)
+}
+
+func (fake *ChannelCapabilities) SignatureAlgorithmsCalls(stub func() []x509.PublicKeyAlgorithm) {
+	fake.signatureAlgorithmsMutex.Lock()
+	defer fake.signatureAlgorithmsMutex.Unlock()
+	fake.SignatureAlgorithmsStub = stub
+}
+
+func (fake *ChannelCapabilities) SignatureAlgorithmsReturns(result1 []x509.PublicKeyAlgorithm) {
+	fake.signatureAlgorithmsMutex.Lock()
+	defer fake.signatureAlgorithmsMutex.Unlock()
+	fake.SignatureAlgorithmsStub = nil
+	fake.signatureAlgorithmsReturns = struct {
+		result1 []x509.PublicKeyAlgorithm
+	}{result1}
+}
+
+func (fake *ChannelCapabilities) SignatureAlgorithmsReturnsOnCall(i int, result1 []x509.PublicKeyAlgorithm) {
+	fake.signatureAlgorithmsMutex.Lock()
+	defer fake.signatureAlgorithmsMutex.Unlock()
+	fake.SignatureAlgorithmsStub = nil
+	if fake.signatureAlgorithmsReturnsOnCall == nil {
+		fake.signatureAlgorithmsReturnsOnCall = make(map[int]struct {
+			result1 []x509.PublicKeyAlgorithm
+		})
+	}
+	fake.signatureAlgorithmsReturnsOnCall[i] = struct {
+		result1 []x509.PublicKeyAlgorithm
+	}{result1}
+}
+
 func (fake *ChannelCapabilities) Supported() error {
 	fake.supportedMutex.Lock()
 	ret, specificReturn := fake.supportedReturnsOnCall[len(fake.supportedArgsForCall)]
@@ -269,6 +397,8 @@ func (fake *ChannelCapabilities) Invocations() map[string][][]interface{} {
 	defer fake.mSPVersionMutex.RUnlock()
 	fake.orgSpecificOrdererEndpointsMutex.RLock()
 	defer fake.orgSpecificOrdererEndpointsMutex.RUnlock()
+	fake.signatureAlgorithmsMutex.RLock()
+	defer fake.signatureAlgorithmsMutex.RUnlock()
 	fake.supportedMutex.RLock()
 	defer fake.supportedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
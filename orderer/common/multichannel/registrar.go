@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
 	"github.com/hyperledger/fabric/orderer/common/types"
 	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/hyperledger/fabric/orderer/consensus/inactive"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
 )
@@ -98,6 +99,8 @@ type Registrar struct {
 	config localconfig.TopLevel
 	lock   sync.RWMutex
 	chains map[string]*ChainSupport
+	// pauseLock serializes pausing and resuming channels
+	pauseLock sync.Mutex
 
 	consenters         map[string]consensus.Consenter
 	ledgerFactory      blockledger.Factory
@@ -322,6 +325,10 @@ func (r *Registrar) CreateChain(chainName string) {
 	}
 	chain := r.GetChain(chainName)
 	if chain != nil {
+		if _, paused := chain.Chain.(*pausedChain); paused {
+			logger.Infof("Channel %s is paused, its chain is created when the channel is resumed", chainName)
+			return
+		}
 		logger.Infof("A chain of type %T for channel %s already exists. "+
 			"Halting it.", chain.Chain, chainName)
 		chain.Halt()
@@ -400,7 +407,165 @@ func (r *Registrar) ChannelList() types.ChannelList {
 	return list
 }
 
+// ChannelInfo provides extended status information about a channel.
+// The URL field is empty, and is to be completed by the caller.
 func (r *Registrar) ChannelInfo(channelID string) (types.ChannelInfo, error) {
-	//TODO
-	return types.ChannelInfo{}, errors.New("Not implemented yet")
+	cs := r.GetChain(channelID)
+	if cs == nil {
+		return types.ChannelInfo{}, types.ErrChannelNotExist
+	}
+
+	info := types.ChannelInfo{
+		Name:            channelID,
+		ClusterRelation: "member",
+		Status:          "active",
+		Height:          cs.Height(),
+	}
+
+	switch chain := cs.Chain.(type) {
+	case *pausedChain:
+		info.Status = "paused"
+	case *inactive.Chain:
+		info.ClusterRelation = "follower"
+		info.Status = "onboarding"
+	case consensus.StatusReporter:
+		status := chain.StatusReport()
+		info.Consensus = &status
+	}
+
+	return info, nil
+}
+
+// pausedChain denotes a channel whose consenter is halted until the channel is resumed.
+type pausedChain struct {
+	inactive.Chain
+}
+
+// PauseChannel halts the consenter of a channel, while keeping the channel and its ledger, so that
+// the channel can be resumed later, e.g., after a maintenance window. The blocks of a paused channel
+// can still be pulled from this orderer, while transactions are rejected.
+func (r *Registrar) PauseChannel(channelID string) error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	r.lock.Lock()
+	cs, ok := r.chains[channelID]
+	if !ok {
+		r.lock.Unlock()
+		return types.ErrChannelNotExist
+	}
+	if channelID == r.systemChannelID {
+		r.lock.Unlock()
+		return types.ErrSystemChannelPause
+	}
+	if _, paused := cs.Chain.(*pausedChain); paused {
+		r.lock.Unlock()
+		return types.ErrChannelPaused
+	}
+
+	pausedCS := *cs
+	pausedCS.Chain = &pausedChain{inactive.Chain{Err: errors.Errorf("channel %s is paused", channelID)}}
+	newChains := make(map[string]*ChainSupport)
+	for key, value := range r.chains {
+		newChains[key] = value
+	}
+	newChains[channelID] = &pausedCS
+	r.chains = newChains
+	r.lock.Unlock()
+
+	logger.Infof("Pausing channel %s", channelID)
+	cs.Halt()
+	return nil
+}
+
+// ResumeChannel starts the consenter of a paused channel.
+func (r *Registrar) ResumeChannel(channelID string) error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	cs := r.GetChain(channelID)
+	if cs == nil {
+		return types.ErrChannelNotExist
+	}
+	if _, paused := cs.Chain.(*pausedChain); !paused {
+		return types.ErrChannelNotPaused
+	}
+
+	lf, err := r.ledgerFactory.GetOrCreate(channelID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed obtaining ledger of channel %s", channelID)
+	}
+
+	logger.Infof("Resuming channel %s", channelID)
+	r.newChain(configTx(lf))
+	return nil
+}
+
+// TransferLeadership transfers the leadership of the consensus of a channel away from this orderer,
+// if this orderer is the leader. The leadership of a paused channel is not held by this orderer.
+func (r *Registrar) TransferLeadership(channelID string) error {
+	cs := r.GetChain(channelID)
+	if cs == nil {
+		return types.ErrChannelNotExist
+	}
+
+	switch chain := cs.Chain.(type) {
+	case *pausedChain:
+		return nil
+	case consensus.LeadershipTransferrer:
+		logger.Infof("Transferring the leadership of channel %s", channelID)
+		if err := chain.TransferLeadership(); err != nil {
+			return errors.WithMessagef(err, "failed transferring the leadership of channel %s", channelID)
+		}
+		return nil
+	default:
+		return types.ErrLeadershipTransferNotSupported
+	}
+}
+
+// Drain prepares this orderer for maintenance, by transferring the leadership of the given channels
+// away from it and pausing them, so that stopping the orderer does not trigger leader elections.
+// All the application channels are drained if no channels are given. Channels whose leadership
+// cannot be transferred are not paused. The channels are drained concurrently.
+func (r *Registrar) Drain(channelIDs []string) types.DrainReport {
+	if len(channelIDs) == 0 {
+		for _, info := range r.ChannelList().Channels {
+			channelIDs = append(channelIDs, info.Name)
+		}
+	}
+
+	errs := make([]error, len(channelIDs))
+	var wg sync.WaitGroup
+	for i, channelID := range channelIDs {
+		wg.Add(1)
+		go func(i int, channelID string) {
+			defer wg.Done()
+			errs[i] = r.drainChannel(channelID)
+		}(i, channelID)
+	}
+	wg.Wait()
+
+	report := types.DrainReport{Drained: []string{}}
+	for i, channelID := range channelIDs {
+		if errs[i] == nil {
+			report.Drained = append(report.Drained, channelID)
+			continue
+		}
+		logger.Warnf("Failed draining channel %s: %s", channelID, errs[i])
+		if report.Failed == nil {
+			report.Failed = make(map[string]string)
+		}
+		report.Failed[channelID] = errs[i].Error()
+	}
+	return report
+}
+
+func (r *Registrar) drainChannel(channelID string) error {
+	if err := r.TransferLeadership(channelID); err != nil && err != types.ErrLeadershipTransferNotSupported {
+		return err
+	}
+	if err := r.PauseChannel(channelID); err != nil && err != types.ErrChannelPaused {
+		return err
+	}
+	return nil
 }
@@ -7,9 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package multichannel
 
 import (
-	"github.com/hyperledger/fabric/orderer/common/types"
 	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
@@ -28,6 +28,7 @@ import (
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
 	"github.com/hyperledger/fabric/orderer/common/multichannel/mocks"
+	"github.com/hyperledger/fabric/orderer/common/types"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
@@ -235,6 +236,48 @@ func TestNewRegistrar(t *testing.T) {
 	})
 }
 
+func TestConsensusPlugin(t *testing.T) {
+	confSys := genesisconfig.Load(genesisconfig.SampleInsecureSoloProfile, configtest.GetDevConfigDir())
+	genesisBlockSys := encoder.New(confSys).GenesisBlock()
+	var capabilities []string
+	for capability := range confSys.Orderer.Capabilities {
+		capabilities = append(capabilities, capability)
+	}
+	require.NotEmpty(t, capabilities)
+
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	t.Run("capabilities supported", func(t *testing.T) {
+		tmpdir, err := ioutil.TempDir("", "registrar_test-")
+		require.NoError(t, err)
+		defer os.RemoveAll(tmpdir)
+
+		lf, _ := newLedgerAndFactory(tmpdir, "testchannelid", genesisBlockSys)
+
+		plugin := &mockPlugin{capabilities: capabilities}
+		consenters := map[string]consensus.Consenter{confSys.Orderer.OrdererType: plugin}
+		manager := NewRegistrar(localconfig.TopLevel{}, lf, mockCrypto(), &disabled.Provider{}, cryptoProvider)
+		manager.Initialize(consenters)
+
+		chainSupport := manager.GetChain("testchannelid")
+		require.NotNil(t, chainSupport)
+		assert.Equal(t, plugin, chainSupport.MetadataValidator)
+	})
+
+	t.Run("capabilities not supported", func(t *testing.T) {
+		tmpdir, err := ioutil.TempDir("", "registrar_test-")
+		require.NoError(t, err)
+		defer os.RemoveAll(tmpdir)
+
+		lf, _ := newLedgerAndFactory(tmpdir, "testchannelid", genesisBlockSys)
+
+		consenters := map[string]consensus.Consenter{confSys.Orderer.OrdererType: &mockPlugin{}}
+		manager := NewRegistrar(localconfig.TopLevel{}, lf, mockCrypto(), &disabled.Provider{}, cryptoProvider)
+		assert.Panics(t, func() { manager.Initialize(consenters) })
+	})
+}
+
 func TestCreateChain(t *testing.T) {
 	//system channel
 	confSys := genesisconfig.Load(genesisconfig.SampleInsecureSoloProfile, configtest.GetDevConfigDir())
@@ -386,6 +429,135 @@ func TestCreateChain(t *testing.T) {
 	})
 }
 
+func TestPauseAndResumeChannel(t *testing.T) {
+	confSys := genesisconfig.Load(genesisconfig.SampleInsecureSoloProfile, configtest.GetDevConfigDir())
+	genesisBlockSys := encoder.New(confSys).GenesisBlock()
+
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	tmpdir, err := ioutil.TempDir("", "registrar_test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	lf, _ := newLedgerAndFactory(tmpdir, "testchannelid", genesisBlockSys)
+
+	consenters := make(map[string]consensus.Consenter)
+	consenters[confSys.Orderer.OrdererType] = &mockConsenter{}
+
+	manager := NewRegistrar(localconfig.TopLevel{}, lf, mockCrypto(), &disabled.Provider{}, cryptoProvider)
+	manager.Initialize(consenters)
+
+	ledger, err := lf.GetOrCreate("mychannel")
+	assert.NoError(t, err)
+	ledger.Append(encoder.New(confSys).GenesisBlockForChannel("mychannel"))
+	manager.CreateChain("mychannel")
+	chain := manager.GetChain("mychannel")
+
+	info, err := manager.ChannelInfo("mychannel")
+	assert.NoError(t, err)
+	assert.Equal(t, types.ChannelInfo{
+		Name:            "mychannel",
+		ClusterRelation: "member",
+		Status:          "active",
+		Height:          1,
+		Consensus:       &types.ConsensusStatus{Role: "leader"},
+	}, info)
+
+	_, err = manager.ChannelInfo("nonexistent")
+	assert.Equal(t, types.ErrChannelNotExist, err)
+
+	assert.Equal(t, types.ErrChannelNotExist, manager.PauseChannel("nonexistent"))
+	assert.Equal(t, types.ErrSystemChannelPause, manager.PauseChannel("testchannelid"))
+	assert.Equal(t, types.ErrChannelNotPaused, manager.ResumeChannel("mychannel"))
+
+	// Pausing halts the chain, while keeping the channel
+	assert.NoError(t, manager.PauseChannel("mychannel"))
+	_, ok := <-chain.Chain.(*mockChain).queue
+	assert.False(t, ok)
+	pausedChain := manager.GetChain("mychannel")
+	assert.NotNil(t, pausedChain)
+	assert.EqualError(t, pausedChain.Order(nil, 0), "channel mychannel is paused")
+	assert.Equal(t, uint64(1), pausedChain.Height())
+	info, err = manager.ChannelInfo("mychannel")
+	assert.NoError(t, err)
+	assert.Equal(t, "paused", info.Status)
+	assert.Nil(t, info.Consensus)
+	assert.Equal(t, types.ErrChannelPaused, manager.PauseChannel("mychannel"))
+
+	// A paused channel is not created by others, e.g., upon onboarding
+	manager.CreateChain("mychannel")
+	assert.Equal(t, pausedChain, manager.GetChain("mychannel"))
+
+	// Resuming starts a new chain
+	assert.NoError(t, manager.ResumeChannel("mychannel"))
+	resumedChain := manager.GetChain("mychannel")
+	_, ok = resumedChain.Chain.(*mockChain)
+	assert.True(t, ok)
+	info, err = manager.ChannelInfo("mychannel")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", info.Status)
+	close(resumedChain.Chain.(*mockChain).queue)
+}
+
+func TestTransferLeadershipAndDrain(t *testing.T) {
+	confSys := genesisconfig.Load(genesisconfig.SampleInsecureSoloProfile, configtest.GetDevConfigDir())
+	genesisBlockSys := encoder.New(confSys).GenesisBlock()
+
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	tmpdir, err := ioutil.TempDir("", "registrar_test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	lf, _ := newLedgerAndFactory(tmpdir, "testchannelid", genesisBlockSys)
+
+	consenters := make(map[string]consensus.Consenter)
+	consenters[confSys.Orderer.OrdererType] = &mockConsenter{}
+
+	manager := NewRegistrar(localconfig.TopLevel{}, lf, mockCrypto(), &disabled.Provider{}, cryptoProvider)
+	manager.Initialize(consenters)
+
+	for _, channelID := range []string{"mychannel", "otherchannel"} {
+		ledger, err := lf.GetOrCreate(channelID)
+		assert.NoError(t, err)
+		ledger.Append(encoder.New(confSys).GenesisBlockForChannel(channelID))
+		manager.CreateChain(channelID)
+	}
+	myChain := manager.GetChain("mychannel").Chain.(*mockChain)
+	otherChain := manager.GetChain("otherchannel").Chain.(*mockChain)
+	otherChain.transferLeadershipErr = errors.New("no other consenter took over")
+
+	assert.Equal(t, types.ErrChannelNotExist, manager.TransferLeadership("nonexistent"))
+	assert.NoError(t, manager.TransferLeadership("mychannel"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&myChain.leadershipTransfers))
+	assert.EqualError(t, manager.TransferLeadership("otherchannel"), "failed transferring the leadership of channel otherchannel: no other consenter took over")
+
+	// Draining pauses the channels whose leadership was transferred
+	report := manager.Drain(nil)
+	assert.Equal(t, []string{"mychannel"}, report.Drained)
+	assert.Equal(t, map[string]string{
+		"otherchannel": "failed transferring the leadership of channel otherchannel: no other consenter took over",
+	}, report.Failed)
+	info, err := manager.ChannelInfo("mychannel")
+	assert.NoError(t, err)
+	assert.Equal(t, "paused", info.Status)
+	info, err = manager.ChannelInfo("otherchannel")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", info.Status)
+
+	// Paused channels are drained already, and the system channel cannot be drained
+	assert.NoError(t, manager.TransferLeadership("mychannel"))
+	report = manager.Drain([]string{"mychannel", "testchannelid", "nonexistent"})
+	assert.Equal(t, []string{"mychannel"}, report.Drained)
+	assert.Equal(t, map[string]string{
+		"testchannelid": types.ErrSystemChannelPause.Error(),
+		"nonexistent":   types.ErrChannelNotExist.Error(),
+	}, report.Failed)
+	close(otherChain.queue)
+}
+
 func TestResourcesCheck(t *testing.T) {
 	mockOrderer := &mocks.OrdererConfig{}
 	mockOrdererCaps := &mocks.OrdererCapabilities{}
@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package multichannel
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/common/capabilities"
@@ -18,6 +20,7 @@ import (
 	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/types"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	"github.com/hyperledger/fabric/protoutil"
 )
@@ -35,12 +38,38 @@ func (mc *mockConsenter) HandleChain(support consensus.ConsenterSupport, metadat
 	}, nil
 }
 
+type mockPlugin struct {
+	mockConsenter
+	capabilities []string
+}
+
+func (mp *mockPlugin) ValidateConsensusMetadata(oldMetadata, newMetadata []byte, newChannel bool) error {
+	return nil
+}
+
+func (mp *mockPlugin) Start() error {
+	return nil
+}
+
+func (mp *mockPlugin) Halt() {}
+
+func (mp *mockPlugin) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (mp *mockPlugin) SupportedCapabilities() []string {
+	return mp.capabilities
+}
+
 type mockChain struct {
 	queue    chan *cb.Envelope
 	cutter   blockcutter.Receiver
 	support  consensus.ConsenterSupport
 	metadata *cb.Metadata
 	done     chan struct{}
+
+	leadershipTransfers   int32
+	transferLeadershipErr error
 }
 
 func (mch *mockChain) Errored() <-chan struct{} {
@@ -61,6 +90,15 @@ func (mch *mockChain) WaitReady() error {
 	return nil
 }
 
+func (mch *mockChain) StatusReport() types.ConsensusStatus {
+	return types.ConsensusStatus{Role: "leader"}
+}
+
+func (mch *mockChain) TransferLeadership() error {
+	atomic.AddInt32(&mch.leadershipTransfers, 1)
+	return mch.transferLeadershipErr
+}
+
 func (mch *mockChain) Start() {
 	go func() {
 		defer close(mch.done)
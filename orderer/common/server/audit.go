@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+)
+
+// configUpdateAuditor records the updates of the configurations of the
+// channels in the audit log. The first bundle of each channel is its current
+// configuration, and is not an update.
+type configUpdateAuditor struct {
+	log *audit.Log
+
+	mutex     sync.Mutex
+	sequences map[string]uint64
+}
+
+func newConfigUpdateAuditor(log *audit.Log) *configUpdateAuditor {
+	return &configUpdateAuditor{
+		log:       log,
+		sequences: make(map[string]uint64),
+	}
+}
+
+func (a *configUpdateAuditor) bundleUpdate(bundle *channelconfig.Bundle) {
+	channelID := bundle.ConfigtxValidator().ChannelID()
+	sequence := bundle.ConfigtxValidator().Sequence()
+
+	a.mutex.Lock()
+	previous, ok := a.sequences[channelID]
+	if ok && sequence <= previous {
+		a.mutex.Unlock()
+		return
+	}
+	a.sequences[channelID] = sequence
+	a.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+	a.log.Record(audit.Event{
+		Type:       audit.ConfigUpdate,
+		Channel:    channelID,
+		Attributes: map[string]string{"sequence": strconv.FormatUint(sequence, 10)},
+	})
+}
+
+// auditKeyLoads records the load of the signing identity and of the TLS
+// certificate of the orderer.
+func auditKeyLoads(auditLog *audit.Log, signingIdentity []byte, secOpts comm.SecureOptions) {
+	auditLog.Record(audit.IdentityKeyLoadEvent("signing", signingIdentity))
+	if secOpts.UseTLS {
+		auditLog.Record(audit.KeyLoadEvent("tls", secOpts.Certificate))
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigUpdateAuditor(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	profile := genesisconfig.Load(genesisconfig.SampleDevModeSoloProfile, configtest.GetDevConfigDir())
+	channelGroup, err := encoder.NewChannelGroup(profile)
+	require.NoError(t, err)
+	newBundle := func(channelID string, sequence uint64) *channelconfig.Bundle {
+		bundle, err := channelconfig.NewBundle(channelID, &cb.Config{Sequence: sequence, ChannelGroup: channelGroup}, cryptoProvider)
+		require.NoError(t, err)
+		return bundle
+	}
+
+	buffer := &bytes.Buffer{}
+	auditor := newConfigUpdateAuditor(audit.NewLog(nil, audit.NewWriterSink(buffer)))
+
+	// the current configurations of the channels are not updates
+	auditor.bundleUpdate(newBundle("channel1", 3))
+	auditor.bundleUpdate(newBundle("channel2", 0))
+	assert.Zero(t, buffer.Len())
+
+	auditor.bundleUpdate(newBundle("channel1", 4))
+	auditor.bundleUpdate(newBundle("channel1", 4))
+	record, err := audit.Verify(bytes.NewReader(buffer.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), record.Sequence)
+	assert.Equal(t, audit.ConfigUpdate, record.Type)
+	assert.Equal(t, "channel1", record.Channel)
+	assert.Equal(t, map[string]string{"sequence": "4"}, record.Attributes)
+
+	auditor.bundleUpdate(newBundle("channel2", 1))
+	record, err = audit.Verify(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), record.Sequence)
+	assert.Equal(t, "channel2", record.Channel)
+	assert.Equal(t, map[string]string{"sequence": "1"}, record.Attributes)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"io/ioutil"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/hyperledger/fabric/orderer/common/cluster"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/consensus/etcdraft"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// clusterCertRotation holds the arguments of the rotate-cluster-cert command
+type clusterCertRotation struct {
+	configBlock string
+	clientCert  string
+	clientKey   string
+	serverCert  string
+	output      string
+}
+
+// rotateClusterCert prepares the rotation of the cluster TLS certificates of this node in a channel.
+// It verifies that the other consenters of the channel accept the new client certificate, and writes
+// the config update that replaces the certificates of this node to the output file. Once the config
+// update is committed, the node should be restarted with the new certificates before the overlap
+// window of the rotation ends.
+func rotateClusterCert(conf *localconfig.TopLevel, r clusterCertRotation, clientConfig comm.ClientConfig, cryptoProvider bccsp.BCCSP) error {
+	blockBytes, err := ioutil.ReadFile(r.configBlock)
+	if err != nil {
+		return errors.Wrap(err, "failed reading the config block")
+	}
+	block, err := protoutil.UnmarshalBlock(blockBytes)
+	if err != nil {
+		return errors.WithMessage(err, "failed unmarshaling the config block")
+	}
+	configEnv, err := cluster.ConfigFromBlock(block)
+	if err != nil {
+		return errors.WithMessage(err, "failed extracting the config from the block")
+	}
+	chdr, err := etcdraft.ConfigChannelHeader(block)
+	if err != nil {
+		return errors.WithMessage(err, "failed extracting the channel header from the block")
+	}
+
+	currentCertFile := conf.General.Cluster.ServerCertificate
+	if currentCertFile == "" {
+		currentCertFile = conf.General.TLS.Certificate
+	}
+	currentServerCert, err := ioutil.ReadFile(currentCertFile)
+	if err != nil {
+		return errors.Wrap(err, "failed reading the current server TLS certificate")
+	}
+	newServerCert, err := ioutil.ReadFile(r.serverCert)
+	if err != nil {
+		return errors.Wrap(err, "failed reading the new server TLS certificate")
+	}
+	newClientCert, err := ioutil.ReadFile(r.clientCert)
+	if err != nil {
+		return errors.Wrap(err, "failed reading the new client TLS certificate")
+	}
+
+	configUpdate, err := etcdraft.ConsenterCertRotationUpdate(chdr.ChannelId, configEnv.Config, currentServerCert, newClientCert, newServerCert, cryptoProvider)
+	if err != nil {
+		return errors.WithMessagef(err, "failed computing the config update of channel %s", chdr.ChannelId)
+	}
+
+	nodes, err := etcdraft.RemoteConsenters(configEnv.Config, currentServerCert)
+	if err != nil {
+		return errors.WithMessagef(err, "failed finding the consenters of channel %s", chdr.ChannelId)
+	}
+	clientConfig.SecOpts.Certificate = newClientCert
+	clientConfig.SecOpts.Key, clientConfig.SecOpts.Signer, err = loadTLSKey(conf.General.TLSKeysFromBCCSP, newClientCert, r.clientKey, ioutil.ReadFile)
+	if err != nil {
+		return errors.Wrap(err, "failed loading the new client TLS key")
+	}
+	errs := cluster.VerifyConnectivity(clientConfig, nodes)
+	for _, node := range nodes {
+		if err, exists := errs[node.ID]; exists {
+			logger.Errorf("Failed connecting to consenter %s with the new certificate: %s", node.Endpoint, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("failed connecting to %d out of %d consenters of channel %s with the new certificate", len(errs), len(nodes), chdr.ChannelId)
+	}
+	logger.Infof("Verified the connectivity to %d consenters of channel %s with the new certificate", len(nodes), chdr.ChannelId)
+
+	if err := ioutil.WriteFile(r.output, protoutil.MarshalOrPanic(configUpdate), 0640); err != nil {
+		return errors.Wrap(err, "failed writing the config update")
+	}
+	logger.Infof("Wrote the config update that rotates the certificates of this node in channel %s to %s", chdr.ChannelId, r.output)
+	return nil
+}
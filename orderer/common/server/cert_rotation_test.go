@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateClusterCertFailures(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "cert-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	writeCert := func(name string) string {
+		kp, err := ca.NewServerCertKeyPair("127.0.0.1")
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, kp.Cert, 0600))
+		return path
+	}
+
+	conf := &localconfig.TopLevel{
+		General: localconfig.General{
+			Cluster: localconfig.Cluster{ServerCertificate: writeCert("current.crt")},
+		},
+	}
+	rotation := clusterCertRotation{
+		configBlock: produceGenesisFile(t, genesisconfig.SampleSingleMSPSoloProfile, "testchannelid"),
+		clientCert:  writeCert("client.crt"),
+		serverCert:  writeCert("server.crt"),
+		output:      filepath.Join(dir, "update.pb"),
+	}
+	defer os.Remove(rotation.configBlock)
+
+	err = rotateClusterCert(conf, rotation, comm.ClientConfig{}, cryptoProvider)
+	require.EqualError(t, err, "failed computing the config update of channel testchannelid: consensus type is solo, not etcdraft")
+
+	rotation.configBlock = filepath.Join(dir, "missing.block")
+	err = rotateClusterCert(conf, rotation, comm.ClientConfig{}, cryptoProvider)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed reading the config block")
+
+	_, err = os.Stat(rotation.output)
+	require.True(t, os.IsNotExist(err))
+}
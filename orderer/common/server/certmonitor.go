@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"time"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/certmonitor"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/multichannel"
+	"github.com/pkg/errors"
+)
+
+// newCertificateMonitor returns a monitor logging the expiration alerts, and
+// posting them to the configured webhook.
+func newCertificateMonitor(conf localconfig.CertificateMonitor, metricsProvider metrics.Provider) (*certmonitor.Monitor, error) {
+	alerters := []certmonitor.Alerter{
+		&certmonitor.LogAlerter{Warn: flogging.MustGetLogger("certmonitor").Warnf},
+	}
+	if conf.Webhook != "" {
+		webhook, err := certmonitor.NewWebhookAlerter(conf.Webhook, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		alerters = append(alerters, webhook)
+	}
+	for _, threshold := range conf.Thresholds {
+		if threshold <= 0 {
+			return nil, errors.Errorf("invalid threshold %d: it must be a positive number of days", threshold)
+		}
+	}
+	return certmonitor.New(conf.Thresholds, conf.Interval, certmonitor.NewMetrics(metricsProvider), alerters...), nil
+}
+
+// channelCertificates returns the source of the certificates of the MSPs
+// defined in the configurations of the channels of the registrar, including
+// the system channel.
+func channelCertificates(registrar *multichannel.Registrar) certmonitor.Source {
+	return certmonitor.ChannelSource(
+		func() []string {
+			list := registrar.ChannelList()
+			var channels []string
+			if list.SystemChannel != nil {
+				channels = append(channels, list.SystemChannel.Name)
+			}
+			for _, channel := range list.Channels {
+				channels = append(channels, channel.Name)
+			}
+			return channels
+		},
+		func(channel string) *cb.Config {
+			if chain := registrar.GetChain(channel); chain != nil {
+				return chain.ConfigtxValidator().ConfigProto()
+			}
+			return nil
+		},
+	)
+}
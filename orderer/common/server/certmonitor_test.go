@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/certmonitor"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/hyperledger/fabric/orderer/common/bootstrap/file"
+	"github.com/hyperledger/fabric/orderer/common/cluster"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	server_mocks "github.com/hyperledger/fabric/orderer/common/server/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCertificateMonitor(t *testing.T) {
+	monitor, err := newCertificateMonitor(localconfig.CertificateMonitor{
+		Interval:   time.Minute,
+		Thresholds: []int{7, 30},
+		Webhook:    "https://alerts.example.com/certificates",
+	}, &disabled.Provider{})
+	require.NoError(t, err)
+	require.Equal(t, []int{30, 7}, monitor.Thresholds)
+	require.Equal(t, time.Minute, monitor.Interval)
+	require.Len(t, monitor.Alerters, 2)
+
+	_, err = newCertificateMonitor(localconfig.CertificateMonitor{Thresholds: []int{30, 0}}, &disabled.Provider{})
+	require.EqualError(t, err, "invalid threshold 0: it must be a positive number of days")
+
+	_, err = newCertificateMonitor(localconfig.CertificateMonitor{Webhook: "alerts.example.com"}, &disabled.Provider{})
+	require.EqualError(t, err, "invalid webhook URL alerts.example.com: the scheme must be http or https")
+}
+
+func TestChannelCertificates(t *testing.T) {
+	cleanup := configtest.SetDevFabricConfigPath(t)
+	defer cleanup()
+	genesisFile := produceGenesisFile(t, genesisconfig.SampleDevModeSoloProfile, "testchannelid")
+	defer os.Remove(genesisFile)
+
+	conf := genesisConfig(t, genesisFile)
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	lf, _, err := createLedgerFactory(conf, &disabled.Provider{})
+	require.NoError(t, err)
+	bootBlock := file.New(genesisFile).GenesisBlock()
+	initializeBootstrapChannel(bootBlock, lf)
+	registrar := initializeMultichannelRegistrar(
+		bootBlock,
+		&replicationInitiator{cryptoProvider: cryptoProvider},
+		&cluster.PredicateDialer{},
+		comm.ServerConfig{},
+		nil,
+		conf,
+		&server_mocks.SignerSerializer{},
+		&disabled.Provider{},
+		&server_mocks.HealthChecker{},
+		lf,
+		cryptoProvider,
+		nil,
+	)
+
+	certs, err := channelCertificates(registrar)()
+	require.NoError(t, err)
+	require.NotEmpty(t, certs)
+	for _, cert := range certs {
+		require.Equal(t, certmonitor.SourceChannel, cert.Source)
+		require.Equal(t, "testchannelid", cert.Channel)
+		require.Equal(t, "SampleOrg", cert.MSPID)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"plugin"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/internal/pkg/identity"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/pkg/errors"
+)
+
+// symbolLookup looks up an exported symbol of a Go plugin
+type symbolLookup func(symName string) (plugin.Symbol, error)
+
+func openPlugin(path string) (symbolLookup, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Lookup, nil
+}
+
+// loadConsensusPlugins loads and starts the consensus plugins of the orderer configuration,
+// and registers their health checks.
+func loadConsensusPlugins(
+	conf *localconfig.TopLevel,
+	open func(path string) (symbolLookup, error),
+	signer identity.SignerSerializer,
+	metricsProvider metrics.Provider,
+	healthChecker healthChecker,
+) (map[string]consensus.Plugin, error) {
+	plugins := map[string]consensus.Plugin{}
+	for _, pluginConf := range conf.ConsensusPlugins {
+		if pluginConf.Type == "" {
+			return nil, errors.Errorf("consensus plugin %s has no type", pluginConf.Library)
+		}
+		if _, exists := plugins[pluginConf.Type]; exists {
+			return nil, errors.Errorf("consensus type %s is implemented by more than one plugin", pluginConf.Type)
+		}
+
+		lookup, err := open(pluginConf.Library)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed opening consensus plugin %s", pluginConf.Library)
+		}
+		p, err := newConsensusPlugin(lookup, consensus.PluginOptions{
+			Config:          pluginConf.Config,
+			Signer:          signer,
+			MetricsProvider: metricsProvider,
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed creating consensus plugin %s", pluginConf.Library)
+		}
+		if err := p.Start(); err != nil {
+			return nil, errors.WithMessagef(err, "failed starting consensus plugin %s", pluginConf.Library)
+		}
+		if err := healthChecker.RegisterChecker("consensus."+pluginConf.Type, p); err != nil {
+			p.Halt()
+			return nil, errors.WithMessagef(err, "failed registering the health check of consensus plugin %s", pluginConf.Library)
+		}
+
+		logger.Infof("Loaded consensus plugin %s for consensus type %s", pluginConf.Library, pluginConf.Type)
+		plugins[pluginConf.Type] = p
+	}
+	return plugins, nil
+}
+
+func newConsensusPlugin(lookup symbolLookup, opts consensus.PluginOptions) (consensus.Plugin, error) {
+	symbol, err := lookup(consensus.PluginFactoryName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin does not export %s", consensus.PluginFactoryName)
+	}
+	factory, ok := symbol.(func(consensus.PluginOptions) (consensus.Plugin, error))
+	if !ok {
+		return nil, errors.Errorf("%s is of type %T, expected func(consensus.PluginOptions) (consensus.Plugin, error)", consensus.PluginFactoryName, symbol)
+	}
+	p, err := factory(opts)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errors.Errorf("%s returned a nil plugin", consensus.PluginFactoryName)
+	}
+	return p, nil
+}
+
+// haltConsensusPlugins halts the consensus plugins when the orderer shuts down
+func haltConsensusPlugins(plugins map[string]consensus.Plugin) {
+	for consensusType, p := range plugins {
+		logger.Infof("Halting consensus plugin for consensus type %s", consensusType)
+		p.Halt()
+	}
+}
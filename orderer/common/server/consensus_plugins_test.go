@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"context"
+	"plugin"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	server_mocks "github.com/hyperledger/fabric/orderer/common/server/mocks"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsensusPlugin struct {
+	options  consensus.PluginOptions
+	startErr error
+	started  bool
+	halted   bool
+}
+
+func (p *fakeConsensusPlugin) HandleChain(support consensus.ConsenterSupport, metadata *cb.Metadata) (consensus.Chain, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakeConsensusPlugin) ValidateConsensusMetadata(oldMetadata, newMetadata []byte, newChannel bool) error {
+	return nil
+}
+
+func (p *fakeConsensusPlugin) Start() error {
+	p.started = true
+	return p.startErr
+}
+
+func (p *fakeConsensusPlugin) Halt() {
+	p.halted = true
+}
+
+func (p *fakeConsensusPlugin) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (p *fakeConsensusPlugin) SupportedCapabilities() []string {
+	return nil
+}
+
+func pluginOpener(symbols map[string]plugin.Symbol) func(string) (symbolLookup, error) {
+	return func(path string) (symbolLookup, error) {
+		if path != "plugin.so" {
+			return nil, errors.Errorf("no such file %s", path)
+		}
+		return func(symName string) (plugin.Symbol, error) {
+			symbol, ok := symbols[symName]
+			if !ok {
+				return nil, errors.Errorf("symbol %s not found", symName)
+			}
+			return symbol, nil
+		}, nil
+	}
+}
+
+func TestLoadConsensusPlugins(t *testing.T) {
+	conf := &localconfig.TopLevel{
+		ConsensusPlugins: []localconfig.ConsensusPlugin{
+			{Type: "myconsensus", Library: "plugin.so", Config: map[string]interface{}{"Foo": "bar"}},
+		},
+	}
+	fakePlugin := &fakeConsensusPlugin{}
+	open := pluginOpener(map[string]plugin.Symbol{
+		consensus.PluginFactoryName: func(opts consensus.PluginOptions) (consensus.Plugin, error) {
+			fakePlugin.options = opts
+			return fakePlugin, nil
+		},
+	})
+	healthChecker := &server_mocks.HealthChecker{}
+
+	plugins, err := loadConsensusPlugins(conf, open, nil, &disabled.Provider{}, healthChecker)
+	require.NoError(t, err)
+	require.Equal(t, map[string]consensus.Plugin{"myconsensus": fakePlugin}, plugins)
+	require.True(t, fakePlugin.started)
+	require.Equal(t, map[string]interface{}{"Foo": "bar"}, fakePlugin.options.Config)
+	require.Equal(t, &disabled.Provider{}, fakePlugin.options.MetricsProvider)
+	require.Equal(t, 1, healthChecker.RegisterCheckerCallCount())
+	component, checker := healthChecker.RegisterCheckerArgsForCall(0)
+	require.Equal(t, "consensus.myconsensus", component)
+	require.Equal(t, fakePlugin, checker)
+
+	haltConsensusPlugins(plugins)
+	require.True(t, fakePlugin.halted)
+}
+
+func TestLoadConsensusPluginsFailures(t *testing.T) {
+	newPlugin := func(consensus.PluginOptions) (consensus.Plugin, error) { return &fakeConsensusPlugin{}, nil }
+
+	tests := []struct {
+		name          string
+		plugins       []localconfig.ConsensusPlugin
+		symbols       map[string]plugin.Symbol
+		registerErr   error
+		expectedError string
+	}{
+		{
+			name:          "missing type",
+			plugins:       []localconfig.ConsensusPlugin{{Library: "plugin.so"}},
+			expectedError: "consensus plugin plugin.so has no type",
+		},
+		{
+			name: "duplicate type",
+			plugins: []localconfig.ConsensusPlugin{
+				{Type: "myconsensus", Library: "plugin.so"},
+				{Type: "myconsensus", Library: "plugin.so"},
+			},
+			symbols:       map[string]plugin.Symbol{consensus.PluginFactoryName: newPlugin},
+			expectedError: "consensus type myconsensus is implemented by more than one plugin",
+		},
+		{
+			name:          "open failure",
+			plugins:       []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "missing.so"}},
+			expectedError: "failed opening consensus plugin missing.so: no such file missing.so",
+		},
+		{
+			name:          "missing factory",
+			plugins:       []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols:       map[string]plugin.Symbol{},
+			expectedError: "failed creating consensus plugin plugin.so: plugin does not export NewPlugin: symbol NewPlugin not found",
+		},
+		{
+			name:          "wrong factory type",
+			plugins:       []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols:       map[string]plugin.Symbol{consensus.PluginFactoryName: func() {}},
+			expectedError: "failed creating consensus plugin plugin.so: NewPlugin is of type func(), expected func(consensus.PluginOptions) (consensus.Plugin, error)",
+		},
+		{
+			name:    "factory failure",
+			plugins: []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols: map[string]plugin.Symbol{consensus.PluginFactoryName: func(consensus.PluginOptions) (consensus.Plugin, error) {
+				return nil, errors.New("bad config")
+			}},
+			expectedError: "failed creating consensus plugin plugin.so: bad config",
+		},
+		{
+			name:    "nil plugin",
+			plugins: []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols: map[string]plugin.Symbol{consensus.PluginFactoryName: func(consensus.PluginOptions) (consensus.Plugin, error) {
+				return nil, nil
+			}},
+			expectedError: "failed creating consensus plugin plugin.so: NewPlugin returned a nil plugin",
+		},
+		{
+			name:    "start failure",
+			plugins: []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols: map[string]plugin.Symbol{consensus.PluginFactoryName: func(consensus.PluginOptions) (consensus.Plugin, error) {
+				return &fakeConsensusPlugin{startErr: errors.New("no quorum")}, nil
+			}},
+			expectedError: "failed starting consensus plugin plugin.so: no quorum",
+		},
+		{
+			name:          "health check registration failure",
+			plugins:       []localconfig.ConsensusPlugin{{Type: "myconsensus", Library: "plugin.so"}},
+			symbols:       map[string]plugin.Symbol{consensus.PluginFactoryName: newPlugin},
+			registerErr:   errors.New("duplicate component"),
+			expectedError: "failed registering the health check of consensus plugin plugin.so: duplicate component",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthChecker := &server_mocks.HealthChecker{}
+			healthChecker.RegisterCheckerReturns(tt.registerErr)
+			conf := &localconfig.TopLevel{ConsensusPlugins: tt.plugins}
+			_, err := loadConsensusPlugins(conf, pluginOpener(tt.symbols), nil, &disabled.Provider{}, healthChecker)
+			require.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
@@ -27,21 +27,26 @@ import (
 	ab "github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/common/audit"
+	"github.com/hyperledger/fabric/common/certmonitor"
 	"github.com/hyperledger/fabric/common/channelconfig"
-	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/flogging"
 	floggingmetrics "github.com/hyperledger/fabric/common/flogging/metrics"
+	"github.com/hyperledger/fabric/common/grpclimits"
 	"github.com/hyperledger/fabric/common/grpclogging"
 	"github.com/hyperledger/fabric/common/grpcmetrics"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/reload"
 	"github.com/hyperledger/fabric/common/tools/protolator"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/operations"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap/file"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
 	"github.com/hyperledger/fabric/orderer/common/cluster"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
 	"github.com/hyperledger/fabric/orderer/common/metadata"
@@ -65,6 +70,13 @@ var (
 	_       = app.Command("start", "Start the orderer node").Default() // preserved for cli compatibility
 	version = app.Command("version", "Show version information")
 
+	rotateClusterCertCmd = app.Command("rotate-cluster-cert", "Verify that the cluster accepts new TLS certificates of this node, and write the config update that rotates them in a channel")
+	rotateConfigBlock    = rotateClusterCertCmd.Flag("config_block", "The latest config block of the channel.").Required().String()
+	rotateClientCert     = rotateClusterCertCmd.Flag("client_cert", "The new client TLS certificate.").Required().String()
+	rotateClientKey      = rotateClusterCertCmd.Flag("client_key", "The private key of the new client TLS certificate, unless General.TLSKeysFromBCCSP is set.").String()
+	rotateServerCert     = rotateClusterCertCmd.Flag("server_cert", "The new server TLS certificate.").Required().String()
+	rotateOutput         = rotateClusterCertCmd.Flag("output", "A file to write the config update to.").Default("cert_rotation_update.pb").String()
+
 	clusterTypes = map[string]struct{}{"etcdraft": {}}
 )
 
@@ -83,23 +95,54 @@ func Main() {
 		logger.Error("failed to parse config: ", err)
 		os.Exit(1)
 	}
-	initializeLogging()
+	initializeLogging(conf.Logging)
 
 	prettyPrintStruct(conf)
 
-	cryptoProvider := factory.GetDefault()
-
 	signer, signErr := loadLocalMSP(conf).GetDefaultSigningIdentity()
 	if signErr != nil {
 		logger.Panicf("Failed to get local MSP identity: %s", signErr)
 	}
 
-	opsSystem := newOperationsSystem(conf.Operations, conf.Metrics)
+	// The default BCCSP is initialized with the local MSP
+	cryptoProvider := factory.GetDefault()
+
+	// "rotate-cluster-cert" command
+	if fullCmd == rotateClusterCertCmd.FullCommand() {
+		if conf.General.Cluster.ClientCertificate == "" {
+			logger.Panicf("General.Cluster.ClientCertificate must be set to rotate the cluster certificates")
+		}
+		rotation := clusterCertRotation{
+			configBlock: *rotateConfigBlock,
+			clientCert:  *rotateClientCert,
+			clientKey:   *rotateClientKey,
+			serverCert:  *rotateServerCert,
+			output:      *rotateOutput,
+		}
+		if err := rotateClusterCert(conf, rotation, initializeClusterClientConfig(conf), cryptoProvider); err != nil {
+			logger.Panicf("Failed rotating the cluster certificates: %s", err)
+		}
+		return
+	}
+
+	auditLog, err := audit.Open(conf.Audit.File, conf.Audit.Stderr)
+	if err != nil {
+		logger.Panicf("Failed to open the audit log: %s", err)
+	}
+	defer auditLog.Close()
+
+	opsSystem := newOperationsSystem(conf.Operations, conf.Metrics, auditLog)
 	metricsProvider := opsSystem.Provider
 	logObserver := floggingmetrics.NewObserver(metricsProvider)
 	flogging.SetObserver(logObserver)
 
 	serverConfig := initializeServerConfig(conf, metricsProvider)
+	signerBytes, err := signer.Serialize()
+	if err != nil {
+		logger.Panicf("Failed to serialize the local MSP identity: %s", err)
+	}
+	auditKeyLoads(auditLog, signerBytes, serverConfig.SecOpts)
+	tracer := initializeTracer(conf.Tracing, &serverConfig)
 	grpcServer := initializeGrpcServer(conf, serverConfig)
 	caMgr := &caManager{
 		appRootCAsByChain:     make(map[string][][]byte),
@@ -171,15 +214,22 @@ func Main() {
 		logger.Panicf("Failed serializing signing identity: %v", err)
 	}
 
-	expirationLogger := flogging.MustGetLogger("certmonitor")
-	crypto.TrackExpiration(
-		serverConfig.SecOpts.UseTLS,
-		serverConfig.SecOpts.Certificate,
-		[][]byte{clusterClientConfig.SecOpts.Certificate},
-		identityBytes,
-		expirationLogger.Warnf, // This can be used to piggyback a metric event in the future
-		time.Now(),
-		time.AfterFunc)
+	certMonitor, err := newCertificateMonitor(conf.CertificateMonitor, metricsProvider)
+	if err != nil {
+		logger.Panicf("Failed creating the certificate monitor: %s", err)
+	}
+	certMonitor.AddSource(certmonitor.LocalMSPSource(conf.General.LocalMSPDir, conf.General.LocalMSPID, identityBytes))
+	if serverConfig.SecOpts.UseTLS {
+		serverCerts, err := certmonitor.TLSCertificates("server TLS", serverConfig.SecOpts.Certificate)
+		if err != nil {
+			logger.Panicf("Failed monitoring the server TLS certificate: %s", err)
+		}
+		clientCerts, err := certmonitor.TLSCertificates("client TLS", clusterClientConfig.SecOpts.Certificate)
+		if err != nil {
+			logger.Panicf("Failed monitoring the cluster client TLS certificate: %s", err)
+		}
+		certMonitor.AddSource(certmonitor.StaticSource(append(serverCerts, clientCerts...)...))
+	}
 
 	// if cluster is reusing client-facing server, then it is already
 	// appended to serversToUpdate at this point.
@@ -198,6 +248,11 @@ func Main() {
 		}
 	}
 
+	consensusPlugins, err := loadConsensusPlugins(conf, openPlugin, signer, metricsProvider, opsSystem)
+	if err != nil {
+		logger.Panicf("Failed loading consensus plugins: %s", err)
+	}
+
 	manager := initializeMultichannelRegistrar(
 		clusterBootBlock,
 		r,
@@ -210,13 +265,42 @@ func Main() {
 		opsSystem,
 		lf,
 		cryptoProvider,
+		consensusPlugins,
 		tlsCallback,
+		newConfigUpdateAuditor(auditLog).bundleUpdate,
 	)
 
+	certMonitor.AddSource(channelCertificates(manager))
+	certMonitor.Start()
+	defer certMonitor.Stop()
+
 	opsSystem.RegisterHandler(
 		channelparticipation.URLBaseV1,
 		channelparticipation.NewHTTPHandler(conf.ChannelParticipation, manager),
 	)
+	keystoreStatus := factory.NewStatusHandler(cryptoProvider)
+	if err := opsSystem.RegisterChecker("bccsp", keystoreStatus); err != nil {
+		logger.Panicf("failed to register the BCCSP health checker: %s", err)
+	}
+	opsSystem.RegisterHandler("/keystore", keystoreStatus)
+	if serverConfig.SecOpts.UseTLS {
+		tlsRotator := newTLSRotator(conf, serverConfig, grpcServer, caMgr, serversToUpdate)
+		tlsRotationHandler := comm.NewTLSRotationHandler(tlsRotator)
+		tlsRotationHandler.AuditLog = auditLog
+		opsSystem.RegisterHandler("/tls", tlsRotationHandler)
+	}
+
+	var rateLimiter *broadcast.RateLimiter
+	if conf.General.RateLimit.Enabled {
+		rateLimit := conf.General.RateLimit
+		rateLimiter, err = broadcast.NewRateLimiter(rateLimit.Scope, rateLimit.TPS, rateLimit.Bandwidth)
+		if err != nil {
+			logger.Panicf("Failed to create the broadcast rate limiter: %s", err)
+		}
+	}
+
+	reloadManager := newReloadManager(rateLimiter)
+	opsSystem.RegisterHandler("/config/reload", reload.NewHandler(reloadManager))
 	if err = opsSystem.Start(); err != nil {
 		logger.Panicf("failed to start operations subsystem: %s", err)
 	}
@@ -230,6 +314,8 @@ func Main() {
 		conf.General.Authentication.TimeWindow,
 		mutualTLS,
 		conf.General.Authentication.NoExpirationChecks,
+		rateLimiter,
+		tracer,
 	)
 
 	logger.Infof("Starting %s", metadata.GetVersionInfo())
@@ -239,6 +325,10 @@ func Main() {
 			if clusterGRPCServer != grpcServer {
 				clusterGRPCServer.Stop()
 			}
+			haltConsensusPlugins(consensusPlugins)
+		},
+		syscall.SIGHUP: func() {
+			reloadManager.Reload()
 		},
 	}))
 
@@ -394,8 +484,11 @@ func createReplicator(
 	}
 }
 
-func initializeLogging() {
+func initializeLogging(logging localconfig.Logging) {
 	loggingSpec := os.Getenv("FABRIC_LOGGING_SPEC")
+	if loggingSpec == "" {
+		loggingSpec = logging.Spec
+	}
 	loggingFormat := os.Getenv("FABRIC_LOGGING_FORMAT")
 	flogging.Init(flogging.Config{
 		Format:  loggingFormat,
@@ -439,7 +532,7 @@ func configureClusterListener(conf *localconfig.TopLevel, generalConf comm.Serve
 		logger.Panicf("Failed to load cluster server certificate from '%s' (%s)", clusterConf.ServerCertificate, err)
 	}
 
-	key, err := loadPEM(clusterConf.ServerPrivateKey)
+	key, tlsSigner, err := loadTLSKey(conf.General.TLSKeysFromBCCSP, cert, clusterConf.ServerPrivateKey, loadPEM)
 	if err != nil {
 		logger.Panicf("Failed to load cluster server key from '%s' (%s)", clusterConf.ServerPrivateKey, err)
 	}
@@ -471,6 +564,7 @@ func configureClusterListener(conf *localconfig.TopLevel, generalConf comm.Serve
 			Certificate:       cert,
 			UseTLS:            true,
 			Key:               key,
+			Signer:            tlsSigner,
 		},
 	}
 
@@ -501,7 +595,7 @@ func initializeClusterClientConfig(conf *localconfig.TopLevel) comm.ClientConfig
 	}
 
 	keyFile := conf.General.Cluster.ClientPrivateKey
-	keyBytes, err := ioutil.ReadFile(keyFile)
+	keyBytes, tlsSigner, err := loadTLSKey(conf.General.TLSKeysFromBCCSP, certBytes, keyFile, ioutil.ReadFile)
 	if err != nil {
 		logger.Fatalf("Failed to load client TLS key file '%s' (%s)", keyFile, err)
 	}
@@ -522,6 +616,7 @@ func initializeClusterClientConfig(conf *localconfig.TopLevel) comm.ClientConfig
 		ServerRootCAs:     serverRootCAs,
 		Certificate:       certBytes,
 		Key:               keyBytes,
+		Signer:            tlsSigner,
 		UseTLS:            true,
 	}
 
@@ -543,7 +638,7 @@ func initializeServerConfig(conf *localconfig.TopLevel, metricsProvider metrics.
 			logger.Fatalf("Failed to load server Certificate file '%s' (%s)",
 				conf.General.TLS.Certificate, err)
 		}
-		serverKey, err := ioutil.ReadFile(conf.General.TLS.PrivateKey)
+		serverKey, tlsSigner, err := loadTLSKey(conf.General.TLSKeysFromBCCSP, serverCertificate, conf.General.TLS.PrivateKey, ioutil.ReadFile)
 		if err != nil {
 			logger.Fatalf("Failed to load PrivateKey file '%s' (%s)",
 				conf.General.TLS.PrivateKey, err)
@@ -569,6 +664,7 @@ func initializeServerConfig(conf *localconfig.TopLevel, metricsProvider metrics.
 			msg = "mutual TLS"
 		}
 		secureOpts.Key = serverKey
+		secureOpts.Signer = tlsSigner
 		secureOpts.Certificate = serverCertificate
 		secureOpts.ServerRootCAs = serverRootCAs
 		secureOpts.ClientRootCAs = clientRootCAs
@@ -589,7 +685,7 @@ func initializeServerConfig(conf *localconfig.TopLevel, metricsProvider metrics.
 		metricsProvider = &disabled.Provider{}
 	}
 
-	return comm.ServerConfig{
+	serverConfig := comm.ServerConfig{
 		SecOpts:            secureOpts,
 		KaOpts:             kaOpts,
 		Logger:             commLogger,
@@ -607,6 +703,46 @@ func initializeServerConfig(conf *localconfig.TopLevel, metricsProvider metrics.
 			),
 		},
 	}
+
+	limiter := grpclimits.NewLimiter(grpcLimits(conf.General.ServiceLimits), metricsProvider)
+	if !limiter.Empty() {
+		// the limiter sees the messages before the other interceptors
+		serverConfig.Codec = limiter.Codec()
+		serverConfig.StreamInterceptors = append([]grpc.StreamServerInterceptor{limiter.StreamServerInterceptor()}, serverConfig.StreamInterceptors...)
+		serverConfig.UnaryInterceptors = append([]grpc.UnaryServerInterceptor{limiter.UnaryServerInterceptor()}, serverConfig.UnaryInterceptors...)
+	}
+	return serverConfig
+}
+
+// grpcLimits returns the limits of the methods of the atomic broadcast service
+// of the orderer.
+func grpcLimits(conf localconfig.ServiceLimits) map[string]grpclimits.Limits {
+	limits := func(l localconfig.ServiceLimit) grpclimits.Limits {
+		return grpclimits.Limits{
+			MaxConcurrency: l.MaxConcurrency,
+			MaxRecvMsgSize: int(l.MaxRecvMsgSize),
+			MaxSendMsgSize: int(l.MaxSendMsgSize),
+		}
+	}
+	return map[string]grpclimits.Limits{
+		"orderer.AtomicBroadcast/Broadcast": limits(conf.Broadcast),
+		"orderer.AtomicBroadcast/Deliver":   limits(conf.Deliver),
+	}
+}
+
+// initializeTracer creates the tracer of the orderer and adds the interceptors
+// propagating the trace context of clients to the server configuration. The
+// tracer is nil when tracing is not enabled.
+func initializeTracer(conf localconfig.Tracing, serverConfig *comm.ServerConfig) *tracing.Tracer {
+	if conf.Endpoint == "" {
+		return nil
+	}
+
+	logger.Infof("Exporting traces to %s as %s", conf.Endpoint, conf.ServiceName)
+	tracer := tracing.NewTracer(tracing.NewOTLPExporter(conf.Endpoint, conf.ServiceName), conf.SampleRate)
+	serverConfig.StreamInterceptors = append(serverConfig.StreamInterceptors, tracing.StreamServerInterceptor())
+	serverConfig.UnaryInterceptors = append(serverConfig.UnaryInterceptors, tracing.UnaryServerInterceptor(tracer))
+	return tracer
 }
 
 func grpcLeveler(ctx context.Context, fullMethod string) zapcore.Level {
@@ -733,6 +869,7 @@ func initializeMultichannelRegistrar(
 	healthChecker healthChecker,
 	lf blockledger.Factory,
 	bccsp bccsp.BCCSP,
+	consensusPlugins map[string]consensus.Plugin,
 	callbacks ...channelconfig.BundleActor,
 ) *multichannel.Registrar {
 	registrar := multichannel.NewRegistrar(*conf, lf, signer, metricsProvider, bccsp, callbacks...)
@@ -753,6 +890,12 @@ func initializeMultichannelRegistrar(
 	// Note, we pass a 'nil' channel here, we could pass a channel that
 	// closes if we wished to cleanup this routine on exit.
 	go kafkaMetrics.PollGoMetricsUntilStop(time.Minute, nil)
+	for consensusType, p := range consensusPlugins {
+		if _, exists := consenters[consensusType]; exists {
+			logger.Panicf("Consensus type %s of a consensus plugin is built into the orderer", consensusType)
+		}
+		consenters[consensusType] = p
+	}
 	registrar.Initialize(consenters)
 	return registrar
 }
@@ -812,7 +955,7 @@ func initializeEtcdraftConsenter(
 	return raftConsenter
 }
 
-func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics) *operations.System {
+func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics, auditLog *audit.Log) *operations.System {
 	return operations.NewSystem(operations.Options{
 		Logger:        flogging.MustGetLogger("orderer.operations"),
 		ListenAddress: ops.ListenAddress,
@@ -832,7 +975,13 @@ func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics
 			ClientCertRequired: ops.TLS.ClientAuthRequired,
 			ClientCACertFiles:  ops.TLS.ClientRootCAs,
 		},
-		Version: metadata.Version,
+		Version:  metadata.Version,
+		AuditLog: auditLog,
+		Profiling: operations.ProfilingOptions{
+			Enabled:     ops.Profiling.Enabled,
+			MaxDuration: ops.Profiling.MaxDuration,
+			MaxCaptures: ops.Profiling.MaxCaptures,
+		},
 	})
 }
 
@@ -918,7 +1067,38 @@ func (mgr *caManager) updateTrustedRoots(
 	mgr.appRootCAsByChain[cid] = appRootCAs
 	mgr.ordererRootCAsByChain[cid] = ordererRootCAs
 
-	// now iterate over all roots for all app and orderer chains
+	// now update the client roots for the gRPC server
+	trustedRoots := mgr.trustedRoots()
+	for _, srv := range servers {
+		err = srv.SetClientRootCAs(trustedRoots)
+		if err != nil {
+			msg := "Failed to update trusted roots for orderer from latest config " +
+				"block.  This orderer may not be able to communicate " +
+				"with members of channel %s (%s)"
+			logger.Warningf(msg, cm.ConfigtxValidator().ChannelID(), err)
+		}
+	}
+}
+
+// setClientRootCAs replaces the statically configured client root CAs, and
+// updates the client roots of the given gRPC servers.
+func (mgr *caManager) setClientRootCAs(clientRootCAs [][]byte, servers ...*comm.GRPCServer) error {
+	mgr.Lock()
+	defer mgr.Unlock()
+
+	mgr.clientRootCAs = clientRootCAs
+	trustedRoots := mgr.trustedRoots()
+	for _, srv := range servers {
+		if err := srv.SetClientRootCAs(trustedRoots); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trustedRoots returns the roots of all app and orderer chains and the
+// statically configured client root CAs. It must be called with the lock held.
+func (mgr *caManager) trustedRoots() [][]byte {
 	trustedRoots := [][]byte{}
 	for _, roots := range mgr.appRootCAsByChain {
 		trustedRoots = append(trustedRoots, roots...)
@@ -930,17 +1110,7 @@ func (mgr *caManager) updateTrustedRoots(
 	if len(mgr.clientRootCAs) > 0 {
 		trustedRoots = append(trustedRoots, mgr.clientRootCAs...)
 	}
-
-	// now update the client roots for the gRPC server
-	for _, srv := range servers {
-		err = srv.SetClientRootCAs(trustedRoots)
-		if err != nil {
-			msg := "Failed to update trusted roots for orderer from latest config " +
-				"block.  This orderer may not be able to communicate " +
-				"with members of channel %s (%s)"
-			logger.Warningf(msg, cm.ConfigtxValidator().ChannelID(), err)
-		}
-	}
+	return trustedRoots
 }
 
 func (mgr *caManager) updateClusterDialer(
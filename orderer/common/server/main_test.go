@@ -25,6 +25,7 @@ import (
 	deliver_mocks "github.com/hyperledger/fabric/common/deliver/mock"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/flogging/floggingtest"
+	"github.com/hyperledger/fabric/common/grpclimits"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/common/ledger/blockledger/fileledger"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
@@ -59,11 +60,21 @@ type signerSerializer interface {
 func TestInitializeLogging(t *testing.T) {
 	origEnvValue := os.Getenv("FABRIC_LOGGING_SPEC")
 	os.Setenv("FABRIC_LOGGING_SPEC", "foo=debug")
-	initializeLogging()
+	initializeLogging(localconfig.Logging{Spec: "foo=info"})
 	assert.Equal(t, "debug", flogging.LoggerLevel("foo"))
 	os.Setenv("FABRIC_LOGGING_SPEC", origEnvValue)
 }
 
+func TestInitializeLoggingFromConfig(t *testing.T) {
+	origEnvValue := os.Getenv("FABRIC_LOGGING_SPEC")
+	defer os.Setenv("FABRIC_LOGGING_SPEC", origEnvValue)
+	os.Unsetenv("FABRIC_LOGGING_SPEC")
+	defer flogging.ActivateSpec("info")
+
+	initializeLogging(localconfig.Logging{Spec: "foo=warn"})
+	assert.Equal(t, "warn", flogging.LoggerLevel("foo"))
+}
+
 func TestInitializeProfilingService(t *testing.T) {
 	origEnvValue := os.Getenv("FABRIC_LOGGING_SPEC")
 	defer os.Setenv("FABRIC_LOGGING_SPEC", origEnvValue)
@@ -94,6 +105,17 @@ func TestInitializeProfilingService(t *testing.T) {
 	}
 }
 
+func TestGrpcLimits(t *testing.T) {
+	limits := grpcLimits(localconfig.ServiceLimits{
+		Broadcast: localconfig.ServiceLimit{MaxConcurrency: 10, MaxRecvMsgSize: 1024},
+		Deliver:   localconfig.ServiceLimit{MaxConcurrency: 20, MaxSendMsgSize: 2048},
+	})
+	assert.Equal(t, map[string]grpclimits.Limits{
+		"orderer.AtomicBroadcast/Broadcast": {MaxConcurrency: 10, MaxRecvMsgSize: 1024},
+		"orderer.AtomicBroadcast/Deliver":   {MaxConcurrency: 20, MaxSendMsgSize: 2048},
+	}, limits)
+}
+
 func TestInitializeServerConfig(t *testing.T) {
 	conf := &localconfig.TopLevel{
 		General: localconfig.General{
@@ -137,6 +159,14 @@ func TestInitializeServerConfig(t *testing.T) {
 	assert.Equal(t, comm.NewServerStatsHandler(&disabled.Provider{}), sc.ServerStatsHandler)
 	assert.Len(t, sc.UnaryInterceptors, 2)
 	assert.Len(t, sc.StreamInterceptors, 2)
+	assert.Nil(t, sc.Codec)
+
+	conf.General.ServiceLimits.Broadcast.MaxConcurrency = 10
+	sc = initializeServerConfig(conf, nil)
+	assert.Len(t, sc.UnaryInterceptors, 3)
+	assert.Len(t, sc.StreamInterceptors, 3)
+	assert.NotNil(t, sc.Codec)
+	conf.General.ServiceLimits = localconfig.ServiceLimits{}
 
 	sc = initializeServerConfig(conf, &prometheus.Provider{})
 	assert.NotNil(t, sc.ServerStatsHandler)
@@ -197,6 +227,19 @@ func TestInitializeServerConfig(t *testing.T) {
 	}
 }
 
+func TestInitializeTracer(t *testing.T) {
+	serverConfig := comm.ServerConfig{}
+	tracer := initializeTracer(localconfig.Tracing{}, &serverConfig)
+	assert.Nil(t, tracer)
+	assert.Empty(t, serverConfig.StreamInterceptors)
+	assert.Empty(t, serverConfig.UnaryInterceptors)
+
+	tracer = initializeTracer(localconfig.Tracing{Endpoint: "http://127.0.0.1:4318", ServiceName: "orderer", SampleRate: 1}, &serverConfig)
+	assert.NotNil(t, tracer)
+	assert.Len(t, serverConfig.StreamInterceptors, 1)
+	assert.Len(t, serverConfig.UnaryInterceptors, 1)
+}
+
 func TestInitializeBootstrapChannel(t *testing.T) {
 	cleanup := configtest.SetDevFabricConfigPath(t)
 	defer cleanup()
@@ -408,6 +451,7 @@ func TestInitializeMultichannelRegistrar(t *testing.T) {
 			&server_mocks.HealthChecker{},
 			lf,
 			cryptoProvider,
+			nil,
 		)
 		assert.NotNil(t, registrar)
 		assert.Equal(t, "testchannelid", registrar.SystemChannelID())
@@ -430,6 +474,7 @@ func TestInitializeMultichannelRegistrar(t *testing.T) {
 			&server_mocks.HealthChecker{},
 			lf,
 			cryptoProvider,
+			nil,
 		)
 		assert.NotNil(t, registrar)
 		assert.Empty(t, registrar.SystemChannelID())
@@ -541,6 +586,7 @@ func TestUpdateTrustedRoots(t *testing.T) {
 		&server_mocks.HealthChecker{},
 		lf,
 		cryptoProvider,
+		nil,
 		callback,
 	)
 	t.Logf("# app CAs: %d", len(caMgr.appRootCAsByChain["testchannelid"]))
@@ -592,6 +638,7 @@ func TestUpdateTrustedRoots(t *testing.T) {
 		&server_mocks.HealthChecker{},
 		lf,
 		cryptoProvider,
+		nil,
 		callback,
 	)
 	t.Logf("# app CAs: %d", len(caMgr.appRootCAsByChain["testchannelid"]))
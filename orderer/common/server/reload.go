@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/common/viperutil"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/spf13/viper"
+)
+
+// rateLimiter is the subset of the broadcast.RateLimiter whose limits are
+// reloaded.
+type rateLimiter interface {
+	Limits() (tps, bandwidth uint32)
+	SetLimits(tps, bandwidth uint32)
+}
+
+// rateLimitComponent reloads the limits of the broadcast rate limiter. The
+// rate limiting cannot be enabled, disabled or scoped differently without a
+// restart.
+type rateLimitComponent struct {
+	limiter rateLimiter
+}
+
+func (r *rateLimitComponent) Name() string {
+	return "General.RateLimit"
+}
+
+func (r *rateLimitComponent) Prepare(config *viper.Viper) (*reload.Change, error) {
+	var conf localconfig.TopLevel
+	if err := viperutil.EnhancedExactUnmarshal(config, &conf); err != nil {
+		return nil, err
+	}
+	rateLimit := conf.General.RateLimit
+
+	tps, bandwidth := r.limiter.Limits()
+	if tps == rateLimit.TPS && bandwidth == rateLimit.Bandwidth {
+		return nil, nil
+	}
+
+	return reload.NewChange(r.Name(),
+		fmt.Sprintf("TPS=%d Bandwidth=%d", tps, bandwidth),
+		fmt.Sprintf("TPS=%d Bandwidth=%d", rateLimit.TPS, rateLimit.Bandwidth),
+		func() error { r.limiter.SetLimits(rateLimit.TPS, rateLimit.Bandwidth); return nil },
+		func() error { r.limiter.SetLimits(tps, bandwidth); return nil },
+	), nil
+}
+
+// newReloadManager returns the manager reloading the runtime parameters of
+// the orderer from its configuration file. The limits of the rate limiter are
+// reloaded when it is not nil.
+func newReloadManager(limiter *broadcast.RateLimiter) *reload.Manager {
+	manager := reload.NewManager(localconfig.ReadConfig)
+	manager.Register(&reload.LoggingComponent{Key: "Logging.Spec", Logging: flogging.Global})
+	if limiter != nil {
+		manager.Register(&rateLimitComponent{limiter: limiter})
+	}
+	return manager
+}
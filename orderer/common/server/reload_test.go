@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/reload"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitComponent(t *testing.T) {
+	limiter, err := broadcast.NewRateLimiter(broadcast.ClientRateLimitScope, 100, 0)
+	require.NoError(t, err)
+	c := &rateLimitComponent{limiter: limiter}
+
+	change, err := c.Prepare(rateLimitConfig(t, "TPS: 100"))
+	require.NoError(t, err)
+	require.Nil(t, change)
+
+	change, err = c.Prepare(rateLimitConfig(t, "TPS: 50\n    Bandwidth: 1 MB"))
+	require.NoError(t, err)
+	require.Equal(t, "TPS=100 Bandwidth=0", change.From)
+	require.Equal(t, "TPS=50 Bandwidth=1048576", change.To)
+
+	manager := reload.NewManager(func() (*viper.Viper, error) {
+		return rateLimitConfig(t, "TPS: 50\n    Bandwidth: 1 MB"), nil
+	})
+	manager.Register(c)
+	_, err = manager.Reload()
+	require.NoError(t, err)
+	tps, bandwidth := limiter.Limits()
+	require.Equal(t, uint32(50), tps)
+	require.Equal(t, uint32(1048576), bandwidth)
+
+	_, err = c.Prepare(rateLimitConfig(t, "TPS: fast"))
+	require.Error(t, err)
+}
+
+func rateLimitConfig(t *testing.T, rateLimit string) *viper.Viper {
+	config := viper.New()
+	config.SetConfigType("yaml")
+	err := config.ReadConfig(strings.NewReader("General:\n  RateLimit:\n    " + rateLimit + "\n"))
+	require.NoError(t, err)
+	return config
+}
+
+func TestNewReloadManager(t *testing.T) {
+	require.Equal(t, []string{"logging"}, newReloadManager(nil).Components())
+
+	limiter, err := broadcast.NewRateLimiter(broadcast.OrgRateLimitScope, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"logging", "General.RateLimit"}, newReloadManager(limiter).Components())
+}
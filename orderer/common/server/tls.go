@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/bccsp/signer"
+	"github.com/pkg/errors"
+)
+
+// loadTLSSigner returns a signer with the private key of a TLS certificate, which
+// is looked up in the BCCSP by the public key of the certificate. This allows the
+// private key to be held by an HSM.
+func loadTLSSigner(csp bccsp.BCCSP, certPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("failed decoding the PEM of the TLS certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing the TLS certificate")
+	}
+
+	pubKey, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed importing the public key of the TLS certificate")
+	}
+	privKey, err := csp.GetKey(pubKey.SKI())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed finding the private key of the TLS certificate")
+	}
+	if !privKey.Private() {
+		return nil, errors.New("the private key of the TLS certificate is not available in BCCSP")
+	}
+	return signer.New(csp, privKey)
+}
+
+// loadTLSKey loads the private key of a TLS certificate. When the keys are taken
+// from BCCSP, the key file is ignored and a signer from the default BCCSP, which
+// is initialized with the local MSP, is returned instead of the key.
+func loadTLSKey(fromBCCSP bool, certPEM []byte, keyFile string, loadPEM loadPEMFunc) ([]byte, crypto.Signer, error) {
+	if fromBCCSP {
+		s, err := loadTLSSigner(factory.GetDefault(), certPEM)
+		return nil, s, err
+	}
+	key, err := loadPEM(keyFile)
+	return key, nil, err
+}
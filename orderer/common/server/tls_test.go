@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTLSSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlskeystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ks, err := sw.NewFileBasedKeyStore(nil, dir, false)
+	require.NoError(t, err)
+	csp, err := sw.NewWithParams(256, "SHA2", ks)
+	require.NoError(t, err)
+
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	kp, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	otherKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+
+	// store the private key of the TLS certificate in the key store
+	block, _ := pem.Decode(kp.Key)
+	_, err = csp.KeyImport(block.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{})
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		signer, err := loadTLSSigner(csp, kp.Cert)
+		require.NoError(t, err)
+		require.Equal(t, kp.TLSCert.PublicKey, signer.Public())
+
+		digest := sha256.Sum256([]byte("msg"))
+		sig, err := signer.Sign(rand.Reader, digest[:], nil)
+		require.NoError(t, err)
+		pubKey, err := csp.KeyImport(kp.TLSCert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		require.NoError(t, err)
+		valid, err := csp.Verify(pubKey, sig, digest[:], nil)
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("key not in BCCSP", func(t *testing.T) {
+		_, err := loadTLSSigner(csp, otherKP.Cert)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed finding the private key of the TLS certificate")
+	})
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		_, err := loadTLSSigner(csp, []byte("garbage"))
+		require.EqualError(t, err, "failed decoding the PEM of the TLS certificate")
+
+		_, err = loadTLSSigner(csp, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed parsing the TLS certificate")
+	})
+}
+
+func TestLoadTLSKeyFromFile(t *testing.T) {
+	loadPEM := func(fileName string) ([]byte, error) {
+		require.Equal(t, "key", fileName)
+		return []byte("key bytes"), nil
+	}
+
+	key, signer, err := loadTLSKey(false, nil, "key", loadPEM)
+	require.NoError(t, err)
+	require.Equal(t, []byte("key bytes"), key)
+	require.Nil(t, signer)
+}
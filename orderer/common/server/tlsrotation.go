@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/pkg/errors"
+)
+
+// tlsRotator rotates the TLS credentials of the server of the orderer. The
+// cluster TLS certificates of the orderer are bound to the channel
+// configurations, and are rotated with the rotate-cluster-cert command instead.
+type tlsRotator struct {
+	conf            localconfig.TLS
+	keysFromBCCSP   bool
+	server          *comm.GRPCServer
+	caMgr           *caManager
+	serversToUpdate []*comm.GRPCServer
+
+	mutex sync.Mutex
+	creds *comm.TLSCredentials
+}
+
+func newTLSRotator(conf *localconfig.TopLevel, serverConfig comm.ServerConfig, server *comm.GRPCServer, caMgr *caManager, serversToUpdate []*comm.GRPCServer) *tlsRotator {
+	return &tlsRotator{
+		conf:            conf.General.TLS,
+		keysFromBCCSP:   conf.General.TLSKeysFromBCCSP,
+		server:          server,
+		caMgr:           caMgr,
+		serversToUpdate: serversToUpdate,
+		creds: &comm.TLSCredentials{
+			ServerCertificate: server.ServerCertificate(),
+			ServerRootCAs:     serverConfig.SecOpts.ServerRootCAs,
+			ClientRootCAs:     serverConfig.SecOpts.ClientRootCAs,
+		},
+	}
+}
+
+func (r *tlsRotator) TLSCredentials() *comm.TLSCredentials {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.creds
+}
+
+func (r *tlsRotator) LoadTLSCredentials() (*comm.TLSCredentials, error) {
+	certificate, err := ioutil.ReadFile(r.conf.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load server certificate")
+	}
+	key, signer, err := loadTLSKey(r.keysFromBCCSP, certificate, r.conf.PrivateKey, ioutil.ReadFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load server private key")
+	}
+	secOpts := comm.SecureOptions{
+		Certificate: certificate,
+		Key:         key,
+		Signer:      signer,
+	}
+	serverCert, err := secOpts.KeyPair()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse server key pair")
+	}
+
+	creds := &comm.TLSCredentials{ServerCertificate: serverCert}
+	for _, serverRoot := range r.conf.RootCAs {
+		root, err := ioutil.ReadFile(serverRoot)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load server root CAs")
+		}
+		creds.ServerRootCAs = append(creds.ServerRootCAs, root)
+	}
+	if r.conf.ClientAuthRequired {
+		for _, clientRoot := range r.conf.ClientRootCAs {
+			root, err := ioutil.ReadFile(clientRoot)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load client root CAs")
+			}
+			creds.ClientRootCAs = append(creds.ClientRootCAs, root)
+		}
+	}
+
+	return creds, nil
+}
+
+func (r *tlsRotator) RotateTLSCredentials(creds *comm.TLSCredentials) error {
+	if creds.ClientCertificate != nil {
+		return errors.New("the orderer has no TLS client certificate to rotate")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.caMgr.setClientRootCAs(creds.ClientRootCAs, r.serversToUpdate...); err != nil {
+		return err
+	}
+	r.server.SetServerCertificate(creds.ServerCertificate)
+
+	r.creds = creds
+	return nil
+}
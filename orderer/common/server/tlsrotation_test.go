@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSRotator(t *testing.T) {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	serverKP, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+
+	tempDir, err := ioutil.TempDir("", "tlsrotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	for name, content := range map[string][]byte{
+		"server.crt": serverKP.Cert,
+		"server.key": serverKP.Key,
+		"ca.crt":     ca.CertBytes(),
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, name), content, 0600))
+	}
+
+	conf := &localconfig.TopLevel{
+		General: localconfig.General{
+			TLS: localconfig.TLS{
+				Enabled:            true,
+				Certificate:        filepath.Join(tempDir, "server.crt"),
+				PrivateKey:         filepath.Join(tempDir, "server.key"),
+				RootCAs:            []string{filepath.Join(tempDir, "ca.crt")},
+				ClientAuthRequired: true,
+				ClientRootCAs:      []string{filepath.Join(tempDir, "ca.crt")},
+			},
+		},
+	}
+	serverConfig := initializeServerConfig(conf, nil)
+	grpcServer, err := comm.NewGRPCServer("127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+	caMgr := &caManager{
+		appRootCAsByChain:     map[string][][]byte{"mychannel": {[]byte("app-root")}},
+		ordererRootCAsByChain: make(map[string][][]byte),
+		clientRootCAs:         serverConfig.SecOpts.ClientRootCAs,
+	}
+
+	rotator := newTLSRotator(conf, serverConfig, grpcServer, caMgr, []*comm.GRPCServer{grpcServer})
+	creds := rotator.TLSCredentials()
+	assert.Equal(t, grpcServer.ServerCertificate(), creds.ServerCertificate)
+	assert.Nil(t, creds.ClientCertificate)
+	assert.Equal(t, [][]byte{ca.CertBytes()}, creds.ClientRootCAs)
+
+	// the credentials are reloaded from the files the orderer is configured with
+	newCA, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	newServerKP, err := newCA.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "server.crt"), newServerKP.Cert, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "server.key"), newServerKP.Key, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "ca.crt"), newCA.CertBytes(), 0600))
+
+	loaded, err := rotator.LoadTLSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, newServerKP.TLSCert.Raw, loaded.ServerCertificate.Certificate[0])
+	assert.Equal(t, [][]byte{newCA.CertBytes()}, loaded.ServerRootCAs)
+	assert.Equal(t, [][]byte{newCA.CertBytes()}, loaded.ClientRootCAs)
+
+	err = rotator.RotateTLSCredentials(loaded)
+	require.NoError(t, err)
+	assert.Equal(t, loaded, rotator.TLSCredentials())
+	assert.Equal(t, loaded.ServerCertificate, grpcServer.ServerCertificate())
+	assert.Equal(t, [][]byte{newCA.CertBytes()}, caMgr.clientRootCAs)
+	assert.ElementsMatch(t, [][]byte{[]byte("app-root"), newCA.CertBytes()}, caMgr.trustedRoots())
+
+	err = rotator.RotateTLSCredentials(&comm.TLSCredentials{ClientCertificate: &tls.Certificate{}})
+	assert.EqualError(t, err, "the orderer has no TLS client certificate to rotate")
+	assert.Equal(t, loaded, rotator.TLSCredentials())
+
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "server.key")))
+	_, err = rotator.LoadTLSCredentials()
+	assert.Contains(t, err.Error(), "failed to load server private key")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "server.key"), serverKP.Key, 0600))
+	_, err = rotator.LoadTLSCredentials()
+	assert.Contains(t, err.Error(), "failed to parse server key pair")
+}
@@ -38,8 +38,29 @@ type ChannelInfo struct {
 	URL string `json:"url"`
 	// Whether the orderer is a “member” or ”follower” of the cluster, for this channel. Case insensitive.
 	ClusterRelation string `json:"clusterRelation"`
-	// Whether the orderer is ”onboarding” or ”active”, for this channel. Case insensitive.
+	// Whether the orderer is ”onboarding”, ”active” or ”paused”, for this channel. Case insensitive.
 	Status string `json:"status"`
 	// Current block height.
 	Height uint64 `json:"height"`
+	// The status of the consensus of the channel on this orderer, nil if not reported by the consensus type.
+	Consensus *ConsensusStatus `json:"consensus,omitempty"`
+}
+
+// ConsensusStatus carries the status of the consensus of a channel on an orderer.
+type ConsensusStatus struct {
+	// The role of the orderer in the consensus: "leader", "follower" or "candidate". Case insensitive.
+	Role string `json:"role"`
+	// The endpoint (host:port) of the leader known to the orderer, empty if no leader is known.
+	Leader string `json:"leader,omitempty"`
+	// The number of blocks the orderer is behind the most up to date orderer of the channel.
+	HeightLag uint64 `json:"heightLag"`
+}
+
+// DrainReport carries the response to an HTTP request to drain the channels of an orderer before maintenance.
+// This is marshaled into the body of the HTTP response.
+type DrainReport struct {
+	// The channels that were drained: the orderer is not their leader, and they are paused.
+	Drained []string `json:"drained"`
+	// The channels that could not be drained, mapped to the reason, nil or empty if all were drained.
+	Failed map[string]string `json:"failed,omitempty"`
 }
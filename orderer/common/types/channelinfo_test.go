@@ -89,3 +89,27 @@ func TestChannelInfo(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, info.Height, info2.Height)
 }
+
+func TestChannelInfoConsensus(t *testing.T) {
+	info := types.ChannelInfo{
+		Name:            "a",
+		URL:             "/api/channels/a",
+		ClusterRelation: "member",
+		Status:          "active",
+		Height:          10,
+		Consensus: &types.ConsensusStatus{
+			Role:      "follower",
+			Leader:    "orderer1:7050",
+			HeightLag: 2,
+		},
+	}
+
+	buff, err := json.Marshal(info)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"a","url":"/api/channels/a","clusterRelation":"member","status":"active","height":10,"consensus":{"role":"follower","leader":"orderer1:7050","heightLag":2}}`, string(buff))
+
+	var info2 types.ChannelInfo
+	err = json.Unmarshal(buff, &info2)
+	assert.NoError(t, err)
+	assert.Equal(t, info, info2)
+}
@@ -0,0 +1,24 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package types
+
+import "errors"
+
+// ErrChannelNotExist is returned when a channel does not exist on the orderer.
+var ErrChannelNotExist = errors.New("channel does not exist")
+
+// ErrSystemChannelPause is returned when the system channel is to be paused or resumed.
+var ErrSystemChannelPause = errors.New("the system channel cannot be paused or resumed")
+
+// ErrChannelPaused is returned when a channel that is already paused is to be paused.
+var ErrChannelPaused = errors.New("channel is already paused")
+
+// ErrChannelNotPaused is returned when a channel that is not paused is to be resumed.
+var ErrChannelNotPaused = errors.New("channel is not paused")
+
+// ErrLeadershipTransferNotSupported is returned when the leadership of a channel whose consensus type has no leader is to be transferred.
+var ErrLeadershipTransferNotSupported = errors.New("the consensus type of the channel does not support leadership transfer")
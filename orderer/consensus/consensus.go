@@ -7,11 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package consensus
 
 import (
+	"context"
+
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/types"
 	"github.com/hyperledger/fabric/protoutil"
 )
 
@@ -37,6 +41,45 @@ type MetadataValidator interface {
 	ValidateConsensusMetadata(oldMetadata, newMetadata []byte, newChannel bool) error
 }
 
+// Plugin is the interface of a consensus implementation which is loaded into the orderer from a Go plugin.
+// On top of handling chains, a Plugin takes part in the lifecycle of the orderer, reports its health,
+// validates the updates of its ConsensusMetadata, and declares the orderer capabilities it supports.
+// The chains of a Plugin may additionally implement MetadataValidator and StatusReporter.
+type Plugin interface {
+	Consenter
+	MetadataValidator
+
+	// Start is invoked once when the orderer starts, before any chain is handed to the plugin.
+	// An error is treated as irrecoverable and causes the orderer to shut down.
+	Start() error
+
+	// Halt is invoked once when the orderer shuts down, and frees the resources of the plugin.
+	Halt()
+
+	// HealthCheck returns an error when the plugin is unable to order transactions.
+	// It is registered with the health checks of the operations service.
+	HealthCheck(ctx context.Context) error
+
+	// SupportedCapabilities returns the names of the orderer capabilities the plugin supports.
+	// A channel which requires an orderer capability that the plugin does not support
+	// is not handed to the plugin.
+	SupportedCapabilities() []string
+}
+
+// PluginOptions provides the resources of the orderer to a Plugin.
+type PluginOptions struct {
+	// Config is the configuration of the plugin in the orderer configuration.
+	Config interface{}
+	// Signer signs on behalf of the orderer.
+	Signer identity.SignerSerializer
+	// MetricsProvider creates the metrics of the plugin.
+	MetricsProvider metrics.Provider
+}
+
+// PluginFactoryName is the name of the function a Go plugin exports to create its Plugin.
+// The function must be of type func(PluginOptions) (Plugin, error).
+const PluginFactoryName = "NewPlugin"
+
 // Chain defines a way to inject messages for ordering.
 // Note, that in order to allow flexibility in the implementation, it is the responsibility of the implementer
 // to take the ordered messages, send them through the blockcutter.Receiver supplied via HandleChain to cut blocks,
@@ -79,6 +122,24 @@ type Chain interface {
 	Halt()
 }
 
+// StatusReporter reports the status of the consensus of a channel on this orderer.
+// NOTE: We expect the StatusReporter interface to be optionally implemented by the Chain implementation.
+//       If a Chain does not implement StatusReporter, no consensus status is reported for the channel.
+type StatusReporter interface {
+	// StatusReport returns the role of this orderer in the consensus of the channel, the leader
+	// known to this orderer, and the number of blocks this orderer is behind.
+	StatusReport() types.ConsensusStatus
+}
+
+// LeadershipTransferrer transfers the leadership of the consensus of a channel away from this orderer.
+// NOTE: We expect the LeadershipTransferrer interface to be optionally implemented by the Chain implementation.
+//       If a Chain does not implement LeadershipTransferrer, the consensus of the channel has no leader to transfer.
+type LeadershipTransferrer interface {
+	// TransferLeadership transfers the leadership to another consenter if this orderer is the leader,
+	// and returns once another consenter is the leader. It does nothing if this orderer is not the leader.
+	TransferLeadership() error
+}
+
 //go:generate counterfeiter -o mocks/mock_consenter_support.go . ConsenterSupport
 
 // ConsenterSupport provides the resources available to a Consenter implementation.
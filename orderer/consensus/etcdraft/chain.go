@@ -22,6 +22,7 @@ import (
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/orderer/common/cluster"
+	"github.com/hyperledger/fabric/orderer/common/types"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
@@ -57,6 +58,15 @@ const (
 	// DefaultLeaderlessCheckInterval is the interval that a chain checks
 	// its own leadership status.
 	DefaultLeaderlessCheckInterval = time.Second * 10
+
+	// DefaultSnapshotCatchUpChunkSize is the default number of blocks
+	// pulled from other nodes in a chunk while catching up with a snapshot.
+	DefaultSnapshotCatchUpChunkSize = uint64(100)
+
+	// maxSnapshotCatchUpAttempts is the number of consecutive attempts
+	// to pull a chunk of blocks, each from a freshly created block puller,
+	// before catching up with a snapshot fails.
+	maxSnapshotCatchUpAttempts = 3
 )
 
 //go:generate counterfeiter -o mocks/configurator.go . Configurator
@@ -98,10 +108,24 @@ type Options struct {
 	SnapDir              string
 	SnapshotIntervalSize uint32
 
+	// SnapshotCompression makes the node compress the data of the snapshots
+	// it takes. Compressed snapshots can only be read by nodes that support
+	// them, so this should be enabled once all the nodes are upgraded.
+	SnapshotCompression bool
+
+	// EncryptionKey is the hex encoded SKI of a symmetric key of the BCCSP,
+	// which encrypts the WAL entries and snapshots persisted by the node.
+	// The data is persisted in plaintext if it is empty.
+	EncryptionKey string
+
 	// This is configurable mainly for testing purpose. Users are not
 	// expected to alter this. Instead, DefaultSnapshotCatchUpEntries is used.
 	SnapshotCatchUpEntries uint64
 
+	// This is configurable mainly for testing purpose. Users are not
+	// expected to alter this. Instead, DefaultSnapshotCatchUpChunkSize is used.
+	SnapshotCatchUpChunkSize uint64
+
 	MemoryStorage MemoryStorage
 	Logger        *flogging.FabricLogger
 
@@ -212,8 +236,16 @@ func NewChain(
 
 	lg := opts.Logger.With("channel", support.ChannelID(), "node", opts.RaftID)
 
+	var encrypter *StorageEncrypter
+	if opts.EncryptionKey != "" {
+		var err error
+		if encrypter, err = NewStorageEncrypter(cryptoProvider, opts.EncryptionKey); err != nil {
+			return nil, errors.WithMessage(err, "failed to set up the encryption of persisted raft data")
+		}
+	}
+
 	fresh := !wal.Exist(opts.WALDir)
-	storage, err := CreateStorage(lg, opts.WALDir, opts.SnapDir, opts.MemoryStorage)
+	storage, err := CreateStorage(lg, opts.WALDir, opts.SnapDir, opts.MemoryStorage, encrypter)
 	if err != nil {
 		return nil, errors.Errorf("failed to restore persisted raft data: %s", err)
 	}
@@ -233,7 +265,10 @@ func NewChain(
 	var snapBlkNum uint64
 	var cc raftpb.ConfState
 	if s := storage.Snapshot(); !raft.IsEmptySnap(s) {
-		b := protoutil.UnmarshalBlockOrPanic(s.Data)
+		b, err := snapshotBlock(s.Data)
+		if err != nil {
+			return nil, errors.Errorf("failed to read the block of the persisted snapshot: %s", err)
+		}
 		snapBlkNum = b.Header.Number
 		cc = s.Metadata.ConfState
 	}
@@ -278,6 +313,9 @@ func NewChain(
 			DataPersistDuration:     opts.Metrics.DataPersistDuration.With("channel", support.ChannelID()),
 			NormalProposalsReceived: opts.Metrics.NormalProposalsReceived.With("channel", support.ChannelID()),
 			ConfigProposalsReceived: opts.Metrics.ConfigProposalsReceived.With("channel", support.ChannelID()),
+
+			SnapshotTransferDuration:  opts.Metrics.SnapshotTransferDuration.With("channel", support.ChannelID()),
+			SnapshotTransferredBlocks: opts.Metrics.SnapshotTransferredBlocks.With("channel", support.ChannelID()),
 		},
 		logger:         lg,
 		opts:           opts,
@@ -432,6 +470,83 @@ func (c *Chain) Halt() {
 	}
 }
 
+// StatusReport returns the status of the consensus of the channel on this node. The height
+// lag of a node that is not the leader is determined by asking the other ordering nodes of
+// the channel for their heights.
+func (c *Chain) StatusReport() types.ConsensusStatus {
+	status := c.Node.Status()
+
+	report := types.ConsensusStatus{}
+	switch status.RaftState {
+	case raft.StateLeader:
+		report.Role = "leader"
+	case raft.StateCandidate, raft.StatePreCandidate:
+		report.Role = "candidate"
+	default:
+		report.Role = "follower"
+	}
+
+	if status.Lead != raft.None {
+		c.raftMetadataLock.RLock()
+		consenter, ok := c.opts.Consenters[status.Lead]
+		c.raftMetadataLock.RUnlock()
+		if ok {
+			report.Leader = fmt.Sprintf("%s:%d", consenter.Host, consenter.Port)
+		}
+	}
+
+	if status.RaftState != raft.StateLeader {
+		report.HeightLag = c.heightLag()
+	}
+
+	return report
+}
+
+// heightLag returns the number of blocks this node is behind the most up to date
+// ordering node of the channel.
+func (c *Chain) heightLag() uint64 {
+	puller, err := c.createPuller()
+	if err != nil {
+		c.logger.Warnf("Failed to create block puller to determine the height lag: %s", err)
+		return 0
+	}
+	defer puller.Close()
+
+	heights, err := puller.HeightsByEndpoints()
+	if err != nil {
+		c.logger.Warnf("Failed to retrieve the heights of all the ordering nodes: %s", err)
+	}
+
+	height := c.support.Height()
+	var lag uint64
+	for _, h := range heights {
+		if h > height && h-height > lag {
+			lag = h - height
+		}
+	}
+	return lag
+}
+
+// TransferLeadership transfers the leadership of the channel to another consenter if this node
+// is the leader, and waits for another consenter to take over, up to the election timeout.
+func (c *Chain) TransferLeadership() error {
+	if err := c.isRunning(); err != nil {
+		return err
+	}
+
+	status := c.Node.Status()
+	if status.RaftState != raft.StateLeader {
+		return nil
+	}
+
+	c.Node.abdicateLeader(status.ID)
+
+	if lead := c.Node.Status().Lead; lead == status.ID {
+		return errors.Errorf("no other consenter took over the leadership from node %d", status.ID)
+	}
+	return nil
+}
+
 func (c *Chain) isRunning() error {
 	select {
 	case <-c.startC:
@@ -871,7 +986,7 @@ func (c *Chain) propose(ch chan<- *common.Block, bc *blockCreator, batches ...[]
 }
 
 func (c *Chain) catchUp(snap *raftpb.Snapshot) error {
-	b, err := protoutil.UnmarshalBlock(snap.Data)
+	b, err := snapshotBlock(snap.Data)
 	if err != nil {
 		return errors.Errorf("failed to unmarshal snapshot data to block: %s", err)
 	}
@@ -881,17 +996,58 @@ func (c *Chain) catchUp(snap *raftpb.Snapshot) error {
 		return nil
 	}
 
-	puller, err := c.createPuller()
-	if err != nil {
-		return errors.Errorf("failed to create block puller: %s", err)
+	start := time.Now()
+	c.logger.Infof("Catching up with snapshot taken at block [%d], starting from block [%d]", b.Header.Number, c.lastBlock.Header.Number+1)
+
+	// The blocks are pulled in chunks. A chunk that fails to be pulled is resumed
+	// from the first block that is not yet written, using a new block puller, so
+	// that the progress made with the blocks already pulled is not lost.
+	var puller BlockPuller
+	defer func() {
+		if puller != nil {
+			puller.Close()
+		}
+	}()
+	for attempt := 1; c.lastBlock.Header.Number < b.Header.Number; {
+		if puller == nil {
+			if puller, err = c.createPuller(); err != nil {
+				return errors.Errorf("failed to create block puller: %s", err)
+			}
+		}
+
+		if err := c.pullChunk(puller, b.Header.Number); err != nil {
+			if attempt == maxSnapshotCatchUpAttempts {
+				return err
+			}
+			attempt++
+			c.logger.Warnf("%s, resuming the catch up from block [%d] with a new block puller", err, c.lastBlock.Header.Number+1)
+			puller.Close()
+			puller = nil
+			continue
+		}
+		attempt = 1
 	}
-	defer puller.Close()
 
-	next := c.lastBlock.Header.Number + 1
+	c.Metrics.SnapshotTransferDuration.Observe(time.Since(start).Seconds())
+	c.logger.Infof("Finished syncing with cluster up to and including block [%d]", b.Header.Number)
+	return nil
+}
+
+// pullChunk pulls and writes the blocks that follow the last block, up to a chunk of
+// blocks and at most up to the given block number.
+func (c *Chain) pullChunk(puller BlockPuller, lastBlockNum uint64) error {
+	chunkSize := c.opts.SnapshotCatchUpChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultSnapshotCatchUpChunkSize
+	}
 
-	c.logger.Infof("Catching up with snapshot taken at block [%d], starting from block [%d]", b.Header.Number, next)
+	next := c.lastBlock.Header.Number + 1
+	end := next + chunkSize - 1
+	if end > lastBlockNum {
+		end = lastBlockNum
+	}
 
-	for next <= b.Header.Number {
+	for next <= end {
 		block := puller.PullBlock(next)
 		if block == nil {
 			return errors.Errorf("failed to fetch block [%d] from cluster", next)
@@ -918,10 +1074,11 @@ func (c *Chain) catchUp(snap *raftpb.Snapshot) error {
 		}
 
 		c.lastBlock = block
+		c.Metrics.SnapshotTransferredBlocks.Add(1)
 		next++
 	}
 
-	c.logger.Infof("Finished syncing with cluster up to and including block [%d]", b.Header.Number)
+	c.logger.Infof("Caught up with blocks up to [%d] of [%d]", end, lastBlockNum)
 	return nil
 }
 
@@ -1070,7 +1227,16 @@ func (c *Chain) gc() {
 	for {
 		select {
 		case g := <-c.gcC:
-			c.Node.takeSnapshot(g.index, g.state, g.data)
+			data := g.data
+			if c.opts.SnapshotCompression {
+				compressed, err := compressSnapshotData(data)
+				if err != nil {
+					c.logger.Warnf("Taking an uncompressed snapshot at index %d: %s", g.index, err)
+				} else {
+					data = compressed
+				}
+			}
+			c.Node.takeSnapshot(g.index, g.state, data)
 		case <-c.doneC:
 			c.logger.Infof("Stop garbage collecting")
 			return
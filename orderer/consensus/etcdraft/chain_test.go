@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package etcdraft_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
@@ -253,6 +255,8 @@ var _ = Describe("Chain", func() {
 					fakeFields.fakeDataPersistDuration,
 					fakeFields.fakeNormalProposalsReceived,
 					fakeFields.fakeConfigProposalsReceived,
+					fakeFields.fakeSnapshotTransferDuration,
+					fakeFields.fakeSnapshotTransferredBlocks,
 				}
 				for _, m := range metricsList {
 					Expect(m.WithCallCount()).To(Equal(1))
@@ -304,6 +308,13 @@ var _ = Describe("Chain", func() {
 				Expect(fakeFields.fakeLeaderChanges.AddArgsForCall(0)).To(Equal(float64(1)))
 			})
 
+			It("reports itself as the leader", func() {
+				report := chain.StatusReport()
+				Expect(report.Role).To(Equal("leader"))
+				Expect(report.Leader).To(Equal(fmt.Sprintf("%s:%d", consenters[1].Host, consenters[1].Port)))
+				Expect(report.HeightLag).To(BeZero())
+			})
+
 			It("fails to order envelope if chain is halted", func() {
 				chain.Halt()
 				err := chain.Order(env, 0)
@@ -948,6 +959,64 @@ var _ = Describe("Chain", func() {
 							Eventually(c.support.WriteBlockCallCount, LongEventualTimeout).Should(Equal(2))
 						})
 
+						Context("snapshot compression is enabled", func() {
+							BeforeEach(func() {
+								opts.SnapshotCompression = true
+							})
+
+							It("writes compressed snapshots", func() {
+								Expect(chain.Order(env, uint64(0))).To(Succeed())
+								Eventually(support.WriteBlockCallCount, LongEventualTimeout).Should(Equal(1))
+								Eventually(countFiles, LongEventualTimeout).Should(Equal(1))
+
+								s, _ := opts.MemoryStorage.Snapshot()
+								r, err := gzip.NewReader(bytes.NewReader(s.Data))
+								Expect(err).NotTo(HaveOccurred())
+								data, err := ioutil.ReadAll(r)
+								Expect(err).NotTo(HaveOccurred())
+								b := protoutil.UnmarshalBlockOrPanic(data)
+								Expect(fakeFields.fakeSnapshotBlockNumber.SetArgsForCall(1)).To(Equal(float64(b.Header.Number)))
+							})
+						})
+
+						It("resumes the catch up with a new block puller after a failed pull", func() {
+							Expect(chain.Order(env, uint64(0))).To(Succeed())
+							Eventually(support.WriteBlockCallCount, LongEventualTimeout).Should(Equal(1))
+							Eventually(countFiles, LongEventualTimeout).Should(Equal(1))
+							Expect(chain.Order(env, uint64(0))).To(Succeed())
+							Eventually(support.WriteBlockCallCount, LongEventualTimeout).Should(Equal(2))
+							Eventually(countFiles, LongEventualTimeout).Should(Equal(2))
+
+							chain.Halt()
+
+							c := newChain(10*time.Second, channelID, dataDir, 1, raftMetadata, consenters, cryptoProvider, nil)
+							c.opts.SnapshotCatchUpChunkSize = 1
+							c.init()
+
+							failed := false
+							c.puller.PullBlockStub = func(i uint64) *common.Block {
+								ledgerLock.Lock()
+								defer ledgerLock.Unlock()
+								if i == 2 && !failed {
+									failed = true
+									return nil
+								}
+								if i >= uint64(len(ledger)) {
+									return nil
+								}
+								return ledger[i]
+							}
+
+							c.Start()
+							defer c.Halt()
+
+							Eventually(c.support.WriteBlockCallCount, LongEventualTimeout).Should(Equal(2))
+							Eventually(c.fakeFields.fakeSnapshotTransferDuration.ObserveCallCount, LongEventualTimeout).Should(Equal(1))
+							Expect(c.puller.PullBlockCallCount()).To(Equal(3))
+							Expect(c.puller.CloseCallCount()).To(Equal(2))
+							Expect(c.fakeFields.fakeSnapshotTransferredBlocks.AddCallCount()).To(Equal(2))
+						})
+
 						It("restores snapshot w/o extra entries", func() {
 							// Scenario:
 							// after a snapshot is taken, no more entries are appended.
@@ -1608,6 +1677,24 @@ var _ = Describe("Chain", func() {
 			})
 		})
 
+		When("the leadership is transferred before maintenance", func() {
+			It("hands the leadership over to another node", func() {
+				network.init()
+				network.start()
+				network.elect(1)
+
+				By("ignoring the transfer on a follower")
+				Expect(c2.TransferLeadership()).To(Succeed())
+
+				By("transferring the leadership away from the leader")
+				Expect(c1.TransferLeadership()).To(Succeed())
+				Eventually(c1.observe, LongEventualTimeout).Should(Receive(BeFollower()))
+				Expect(c1.Node.Status().Lead).NotTo(Equal(uint64(1)))
+
+				network.stop()
+			})
+		})
+
 		When("reconfiguring raft cluster", func() {
 			const (
 				defaultTimeout = 5 * time.Second
@@ -55,6 +55,9 @@ type Config struct {
 	WALDir            string // WAL data of <my-channel> is stored in WALDir/<my-channel>
 	SnapDir           string // Snapshots of <my-channel> are stored in SnapDir/<my-channel>
 	EvictionSuspicion string // Duration threshold that the node samples in order to suspect its eviction from the channel.
+
+	SnapshotCompression bool   // Whether the data of the snapshots taken by the node is compressed.
+	EncryptionKey       string // SKI of the BCCSP key that encrypts the WAL and snapshots, in hex. Empty disables encryption.
 }
 
 // Consenter implements etcdraft consenter
@@ -203,6 +206,9 @@ func (c *Consenter) HandleChain(support consensus.ConsenterSupport, metadata *co
 		EvictionSuspicion: evictionSuspicion,
 		Cert:              c.Cert,
 		Metrics:           c.Metrics,
+
+		SnapshotCompression: c.EtcdRaftConfig.SnapshotCompression,
+		EncryptionKey:       c.EtcdRaftConfig.EncryptionKey,
 	}
 
 	rpc := &cluster.RPC{
@@ -309,6 +315,7 @@ func createComm(clusterDialer *cluster.PredicateDialer, c *Consenter, config loc
 	comm := &cluster.Comm{
 		MinimumExpirationWarningInterval: cluster.MinimumExpirationWarningInterval,
 		CertExpWarningThreshold:          config.CertExpirationWarningThreshold,
+		CertRotationOverlap:              config.CertRotationOverlap,
 		SendBufferSize:                   config.SendBufferSize,
 		Logger:                           flogging.MustGetLogger("orderer.common.cluster"),
 		Chan2Members:                     make(map[string]cluster.MemberMapping),
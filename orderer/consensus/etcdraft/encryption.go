@@ -0,0 +1,178 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package etcdraft
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// encryptedDataMagic prefixes the data of the WAL entries and snapshots that are encrypted at rest.
+// Neither marshaled blocks nor gzip data start with a zero byte, so encrypted data can be told apart
+// from the plaintext data persisted before the encryption was enabled.
+var encryptedDataMagic = []byte{0x00, 'e', 'n', 'c', 0x01}
+
+// dataKeySize is the size of the AES-256 data encryption keys
+const dataKeySize = 32
+
+// StorageEncrypter encrypts the data of the WAL entries and snapshots with a data encryption key,
+// which is in turn encrypted with a key encryption key from the BCCSP. The encrypted data encryption
+// key is stored alongside every piece of data it encrypts, hence the data can be decrypted as long as
+// the key encryption key is available, regardless of the data encryption key in use when it was written.
+type StorageEncrypter struct {
+	csp        bccsp.BCCSP
+	kek        bccsp.Key
+	dek        cipher.AEAD
+	wrappedDEK []byte
+
+	lock      sync.Mutex
+	unwrapped map[string]cipher.AEAD
+}
+
+// NewStorageEncrypter creates a StorageEncrypter that encrypts its data encryption key with the
+// symmetric key of the BCCSP whose subject key identifier is the given hex encoded ski.
+func NewStorageEncrypter(csp bccsp.BCCSP, ski string) (*StorageEncrypter, error) {
+	skiBytes, err := hex.DecodeString(ski)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decoding the SKI of the encryption key %s", ski)
+	}
+	kek, err := csp.GetKey(skiBytes)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed finding the encryption key %s", ski)
+	}
+	if !kek.Symmetric() {
+		return nil, errors.Errorf("encryption key %s is not a symmetric key", ski)
+	}
+
+	dekBytes := make([]byte, dataKeySize)
+	if _, err := rand.Read(dekBytes); err != nil {
+		return nil, errors.Wrap(err, "failed generating the data encryption key")
+	}
+	dek, err := newAEAD(dekBytes)
+	if err != nil {
+		return nil, err
+	}
+	wrappedDEK, err := csp.Encrypt(kek, dekBytes, &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed encrypting the data encryption key")
+	}
+	if len(wrappedDEK) > 0xffff {
+		return nil, errors.Errorf("encrypted data encryption key is too long: %d bytes", len(wrappedDEK))
+	}
+
+	return &StorageEncrypter{
+		csp:        csp,
+		kek:        kek,
+		dek:        dek,
+		wrappedDEK: wrappedDEK,
+		unwrapped:  map[string]cipher.AEAD{string(wrappedDEK): dek},
+	}, nil
+}
+
+// Encrypt encrypts the given data. The encrypted data is laid out as follows:
+// magic | length of the encrypted data encryption key (2 bytes) | encrypted data encryption key | nonce | ciphertext
+func (se *StorageEncrypter) Encrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	nonce := make([]byte, se.dek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating a nonce")
+	}
+
+	header := make([]byte, 0, len(encryptedDataMagic)+2+len(se.wrappedDEK)+len(nonce))
+	header = append(header, encryptedDataMagic...)
+	header = append(header, 0, 0)
+	binary.BigEndian.PutUint16(header[len(encryptedDataMagic):], uint16(len(se.wrappedDEK)))
+	header = append(header, se.wrappedDEK...)
+	header = append(header, nonce...)
+
+	return se.dek.Seal(header, nonce, data, nil), nil
+}
+
+// Decrypt decrypts data returned by Encrypt. Data that is not encrypted is returned as is.
+func (se *StorageEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if !isEncrypted(data) {
+		return data, nil
+	}
+
+	data = data[len(encryptedDataMagic):]
+	if len(data) < 2 {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	wrappedDEKLen := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < wrappedDEKLen {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	wrappedDEK, data := data[:wrappedDEKLen], data[wrappedDEKLen:]
+
+	dek, err := se.dataKey(wrappedDEK)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < dek.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	nonce, ciphertext := data[:dek.NonceSize()], data[dek.NonceSize():]
+
+	plaintext, err := dek.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decrypting data")
+	}
+	return plaintext, nil
+}
+
+// dataKey returns the data encryption key that was encrypted into wrappedDEK
+func (se *StorageEncrypter) dataKey(wrappedDEK []byte) (cipher.AEAD, error) {
+	se.lock.Lock()
+	defer se.lock.Unlock()
+
+	if dek, exists := se.unwrapped[string(wrappedDEK)]; exists {
+		return dek, nil
+	}
+
+	// the BCCSP may decrypt in place, hence the copy
+	dekBytes, err := se.csp.Decrypt(se.kek, append([]byte{}, wrappedDEK...), &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed decrypting the data encryption key")
+	}
+	dek, err := newAEAD(dekBytes)
+	if err != nil {
+		return nil, err
+	}
+	se.unwrapped[string(wrappedDEK)] = dek
+	return dek, nil
+}
+
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedDataMagic)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, errors.Errorf("data encryption key is %d bytes long instead of %d", len(key), dataKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the block cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the GCM cipher")
+	}
+	return gcm, nil
+}
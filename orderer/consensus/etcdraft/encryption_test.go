@@ -0,0 +1,186 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package etcdraft
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft"
+	"go.etcd.io/etcd/raft/raftpb"
+	"go.uber.org/zap"
+)
+
+// newEncryptionKey returns a BCCSP backed by a keystore in the given directory,
+// and the hex encoded SKI of an AES key in the keystore
+func newEncryptionKey(t *testing.T, keystoreDir string) (bccsp.BCCSP, string) {
+	ks, err := sw.NewFileBasedKeyStore(nil, keystoreDir, false)
+	require.NoError(t, err)
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(ks)
+	require.NoError(t, err)
+	key, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	return csp, hex.EncodeToString(key.SKI())
+}
+
+func TestStorageEncrypter(t *testing.T) {
+	keystoreDir, err := ioutil.TempDir("", "etcdraft-keystore-")
+	require.NoError(t, err)
+	defer os.RemoveAll(keystoreDir)
+	csp, ski := newEncryptionKey(t, keystoreDir)
+
+	encrypter, err := NewStorageEncrypter(csp, ski)
+	require.NoError(t, err)
+
+	data := []byte("some block")
+	encrypted, err := encrypter.Encrypt(data)
+	require.NoError(t, err)
+	require.True(t, isEncrypted(encrypted))
+	require.False(t, bytes.Contains(encrypted, data))
+
+	decrypted, err := encrypter.Decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, data, decrypted)
+
+	t.Run("another data key", func(t *testing.T) {
+		other, err := NewStorageEncrypter(csp, ski)
+		require.NoError(t, err)
+		decrypted, err := other.Decrypt(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, data, decrypted)
+	})
+
+	t.Run("plaintext", func(t *testing.T) {
+		decrypted, err := encrypter.Decrypt(data)
+		require.NoError(t, err)
+		require.Equal(t, data, decrypted)
+
+		empty, err := encrypter.Encrypt(nil)
+		require.NoError(t, err)
+		require.Empty(t, empty)
+	})
+
+	t.Run("tampered data", func(t *testing.T) {
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(tampered)-1] ^= 0xff
+		_, err := encrypter.Decrypt(tampered)
+		require.EqualError(t, err, "failed decrypting data: cipher: message authentication failed")
+
+		_, err = encrypter.Decrypt(encrypted[:len(encryptedDataMagic)+10])
+		require.EqualError(t, err, "encrypted data is truncated")
+	})
+
+	t.Run("another key encryption key", func(t *testing.T) {
+		otherCSP, otherSKI := newEncryptionKey(t, keystoreDir)
+		other, err := NewStorageEncrypter(otherCSP, otherSKI)
+		require.NoError(t, err)
+		_, err = other.Decrypt(encrypted)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed decrypting the data encryption key")
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := NewStorageEncrypter(csp, "0102")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed finding the encryption key 0102")
+
+		_, err = NewStorageEncrypter(csp, "not hex")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed decoding the SKI of the encryption key not hex")
+	})
+
+	t.Run("asymmetric key", func(t *testing.T) {
+		key, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+		require.NoError(t, err)
+		ski := hex.EncodeToString(key.SKI())
+		_, err = NewStorageEncrypter(csp, ski)
+		require.EqualError(t, err, "encryption key "+ski+" is not a symmetric key")
+	})
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	lg := flogging.NewFabricLogger(zap.NewExample())
+	dir, err := ioutil.TempDir("", "etcdraft-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	walDir, snapDir := path.Join(dir, "wal"), path.Join(dir, "snapshot")
+	csp, ski := newEncryptionKey(t, path.Join(dir, "keystore"))
+
+	// persist an entry in plaintext before the encryption is enabled
+	store, err := CreateStorage(lg, walDir, snapDir, raft.NewMemoryStorage(), nil)
+	require.NoError(t, err)
+	plaintext := []byte("plaintext entry")
+	require.NoError(t, store.Store([]raftpb.Entry{{Index: 1, Term: 1, Data: plaintext}}, raftpb.HardState{Term: 1, Commit: 1}, raftpb.Snapshot{}))
+	require.NoError(t, store.Close())
+
+	encrypter, err := NewStorageEncrypter(csp, ski)
+	require.NoError(t, err)
+	ram := raft.NewMemoryStorage()
+	store, err = CreateStorage(lg, walDir, snapDir, ram, encrypter)
+	require.NoError(t, err)
+	ents, err := ram.Entries(1, 2, 1<<20)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, ents[0].Data)
+
+	snapData := []byte("encrypted snapshot")
+	require.NoError(t, store.TakeSnapshot(1, raftpb.ConfState{Nodes: []uint64{1}}, snapData))
+	secret := []byte("encrypted entry")
+	require.NoError(t, store.Store([]raftpb.Entry{{Index: 2, Term: 1, Data: secret}}, raftpb.HardState{Term: 1, Commit: 2}, raftpb.Snapshot{}))
+
+	// the data in memory is not encrypted
+	ents, err = ram.Entries(2, 3, 1<<20)
+	require.NoError(t, err)
+	require.Equal(t, secret, ents[0].Data)
+	sn, err := ram.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, snapData, sn.Data)
+	require.NoError(t, store.Close())
+
+	// the data on disk is
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.False(t, bytes.Contains(content, secret), "%s contains the plaintext entry", path)
+		require.False(t, bytes.Contains(content, snapData), "%s contains the plaintext snapshot", path)
+		return nil
+	})
+	require.NoError(t, err)
+
+	t.Run("reopened with the key", func(t *testing.T) {
+		encrypter, err := NewStorageEncrypter(csp, ski)
+		require.NoError(t, err)
+		ram := raft.NewMemoryStorage()
+		store, err := CreateStorage(lg, walDir, snapDir, ram, encrypter)
+		require.NoError(t, err)
+		defer store.Close()
+
+		sn, err := ram.Snapshot()
+		require.NoError(t, err)
+		require.Equal(t, snapData, sn.Data)
+		ents, err := ram.Entries(2, 3, 1<<20)
+		require.NoError(t, err)
+		require.Len(t, ents, 1)
+		require.Equal(t, secret, ents[0].Data)
+	})
+
+	t.Run("reopened without the key", func(t *testing.T) {
+		_, err := CreateStorage(lg, walDir, snapDir, raft.NewMemoryStorage(), nil)
+		require.EqualError(t, err, "failed to decrypt snapshot: data is encrypted but no encryption key is configured")
+	})
+}
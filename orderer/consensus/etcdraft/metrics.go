@@ -73,6 +73,22 @@ var (
 		LabelNames:   []string{"channel"},
 		StatsdFormat: "%{#fqname}.%{channel}",
 	}
+	snapshotTransferDurationOpts = metrics.HistogramOpts{
+		Namespace:    "consensus",
+		Subsystem:    "etcdraft",
+		Name:         "snapshot_transfer_duration",
+		Help:         "The time taken to catch up with a snapshot by pulling the blocks it covers from other nodes (in seconds).",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
+	snapshotTransferredBlocksOpts = metrics.CounterOpts{
+		Namespace:    "consensus",
+		Subsystem:    "etcdraft",
+		Name:         "snapshot_transferred_blocks",
+		Help:         "The number of blocks pulled from other nodes to catch up with snapshots.",
+		LabelNames:   []string{"channel"},
+		StatsdFormat: "%{#fqname}.%{channel}",
+	}
 	normalProposalsReceivedOpts = metrics.CounterOpts{
 		Namespace:    "consensus",
 		Subsystem:    "etcdraft",
@@ -102,6 +118,9 @@ type Metrics struct {
 	DataPersistDuration     metrics.Histogram
 	NormalProposalsReceived metrics.Counter
 	ConfigProposalsReceived metrics.Counter
+
+	SnapshotTransferDuration  metrics.Histogram
+	SnapshotTransferredBlocks metrics.Counter
 }
 
 func NewMetrics(p metrics.Provider) *Metrics {
@@ -116,5 +135,8 @@ func NewMetrics(p metrics.Provider) *Metrics {
 		DataPersistDuration:     p.NewHistogram(dataPersistDurationOpts),
 		NormalProposalsReceived: p.NewCounter(normalProposalsReceivedOpts),
 		ConfigProposalsReceived: p.NewCounter(configProposalsReceivedOpts),
+
+		SnapshotTransferDuration:  p.NewHistogram(snapshotTransferDurationOpts),
+		SnapshotTransferredBlocks: p.NewCounter(snapshotTransferredBlocksOpts),
 	}
 }
@@ -38,8 +38,8 @@ var _ = Describe("Metrics", func() {
 
 			Expect(metrics).NotTo(BeNil())
 			Expect(fakeProvider.NewGaugeCallCount()).To(Equal(5))
-			Expect(fakeProvider.NewCounterCallCount()).To(Equal(4))
-			Expect(fakeProvider.NewHistogramCallCount()).To(Equal(1))
+			Expect(fakeProvider.NewCounterCallCount()).To(Equal(5))
+			Expect(fakeProvider.NewHistogramCallCount()).To(Equal(2))
 
 			Expect(metrics.ClusterSize).To(Equal(fakeGauge))
 			Expect(metrics.IsLeader).To(Equal(fakeGauge))
@@ -50,6 +50,8 @@ var _ = Describe("Metrics", func() {
 			Expect(metrics.DataPersistDuration).To(Equal(fakeHistogram))
 			Expect(metrics.NormalProposalsReceived).To(Equal(fakeCounter))
 			Expect(metrics.ConfigProposalsReceived).To(Equal(fakeCounter))
+			Expect(metrics.SnapshotTransferDuration).To(Equal(fakeHistogram))
+			Expect(metrics.SnapshotTransferredBlocks).To(Equal(fakeCounter))
 		})
 	})
 })
@@ -66,6 +68,9 @@ func newFakeMetrics(fakeFields *fakeMetricsFields) *etcdraft.Metrics {
 		DataPersistDuration:     fakeFields.fakeDataPersistDuration,
 		NormalProposalsReceived: fakeFields.fakeNormalProposalsReceived,
 		ConfigProposalsReceived: fakeFields.fakeConfigProposalsReceived,
+
+		SnapshotTransferDuration:  fakeFields.fakeSnapshotTransferDuration,
+		SnapshotTransferredBlocks: fakeFields.fakeSnapshotTransferredBlocks,
 	}
 }
 
@@ -80,6 +85,9 @@ type fakeMetricsFields struct {
 	fakeDataPersistDuration     *metricsfakes.Histogram
 	fakeNormalProposalsReceived *metricsfakes.Counter
 	fakeConfigProposalsReceived *metricsfakes.Counter
+
+	fakeSnapshotTransferDuration  *metricsfakes.Histogram
+	fakeSnapshotTransferredBlocks *metricsfakes.Counter
 }
 
 func newFakeMetricsFields() *fakeMetricsFields {
@@ -94,6 +102,9 @@ func newFakeMetricsFields() *fakeMetricsFields {
 		fakeDataPersistDuration:     newFakeHistogram(),
 		fakeNormalProposalsReceived: newFakeCounter(),
 		fakeConfigProposalsReceived: newFakeCounter(),
+
+		fakeSnapshotTransferDuration:  newFakeHistogram(),
+		fakeSnapshotTransferredBlocks: newFakeCounter(),
 	}
 }
 
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package etcdraft
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/internal/configtxlator/update"
+	"github.com/hyperledger/fabric/orderer/common/cluster"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// ConsenterCertRotationUpdate computes a config update that replaces the TLS certificates
+// of the consenter whose server certificate is currentServerCert, with the given certificates.
+// The new certificates are validated against the TLS CAs of the orderer organizations, as
+// the channel would reject them otherwise. All certificates are PEM encoded.
+func ConsenterCertRotationUpdate(
+	channelID string,
+	config *common.Config,
+	currentServerCert, newClientCert, newServerCert []byte,
+	cryptoProvider bccsp.BCCSP,
+) (*common.ConfigUpdate, error) {
+	metadata, err := metadataFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	consenter, err := consenterByServerCert(metadata, currentServerCert)
+	if err != nil {
+		return nil, err
+	}
+	consenter.ClientTlsCert = newClientCert
+	consenter.ServerTlsCert = newServerCert
+	if err := MetadataHasDuplication(metadata); err != nil {
+		return nil, err
+	}
+
+	bundle, err := channelconfig.NewBundle(channelID, config, cryptoProvider)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create a bundle of the channel config")
+	}
+	ordererConfig, _ := bundle.OrdererConfig()
+	if err := validateConsenterTLSCerts(consenter, ordererConfig); err != nil {
+		return nil, errors.WithMessage(err, "invalid new certificates")
+	}
+
+	updated := proto.Clone(config).(*common.Config)
+	consensusTypeValue := updated.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Values[channelconfig.ConsensusTypeKey]
+	consensusType := &orderer.ConsensusType{}
+	if err := proto.Unmarshal(consensusTypeValue.Value, consensusType); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal consensusType config value")
+	}
+	consensusType.Metadata = protoutil.MarshalOrPanic(metadata)
+	consensusTypeValue.Value = protoutil.MarshalOrPanic(consensusType)
+
+	configUpdate, err := update.Compute(config, updated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to compute the config update")
+	}
+	configUpdate.ChannelId = channelID
+	return configUpdate, nil
+}
+
+// RemoteConsenters returns the consenters of the given channel config as cluster members,
+// except for the consenter whose server certificate is selfServerCert.
+func RemoteConsenters(config *common.Config, selfServerCert []byte) ([]cluster.RemoteNode, error) {
+	metadata, err := metadataFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	self, err := consenterByServerCert(metadata, selfServerCert)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []cluster.RemoteNode
+	for i, consenter := range metadata.Consenters {
+		if consenter == self {
+			continue
+		}
+		serverCert, _ := pem.Decode(consenter.ServerTlsCert)
+		clientCert, _ := pem.Decode(consenter.ClientTlsCert)
+		if serverCert == nil || clientCert == nil {
+			return nil, errors.Errorf("invalid PEM block in the certificates of consenter %s:%d", consenter.Host, consenter.Port)
+		}
+		nodes = append(nodes, cluster.RemoteNode{
+			// The consenters are not assigned Raft IDs in the channel config
+			ID:            uint64(i + 1),
+			Endpoint:      fmt.Sprintf("%s:%d", consenter.Host, consenter.Port),
+			ServerTLSCert: serverCert.Bytes,
+			ClientTLSCert: clientCert.Bytes,
+		})
+	}
+	return nodes, nil
+}
+
+func metadataFromConfig(config *common.Config) (*etcdraft.ConfigMetadata, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, errors.New("empty channel config")
+	}
+	ordererGroup, exists := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]
+	if !exists {
+		return nil, errors.New("no orderer group in the channel config")
+	}
+	consensusTypeValue, exists := ordererGroup.Values[channelconfig.ConsensusTypeKey]
+	if !exists {
+		return nil, errors.New("no consensus type in the channel config")
+	}
+	consensusType := &orderer.ConsensusType{}
+	if err := proto.Unmarshal(consensusTypeValue.Value, consensusType); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal consensusType config value")
+	}
+	if consensusType.Type != "etcdraft" {
+		return nil, errors.Errorf("consensus type is %s, not etcdraft", consensusType.Type)
+	}
+	return MetadataFromConfigValue(consensusTypeValue)
+}
+
+func consenterByServerCert(metadata *etcdraft.ConfigMetadata, serverCert []byte) (*etcdraft.Consenter, error) {
+	bl, _ := pem.Decode(serverCert)
+	if bl == nil {
+		return nil, errors.New("server TLS certificate is not PEM encoded")
+	}
+	for _, consenter := range metadata.Consenters {
+		consenterCert, _ := pem.Decode(consenter.ServerTlsCert)
+		if consenterCert != nil && bytes.Equal(consenterCert.Bytes, bl.Bytes) {
+			return consenter, nil
+		}
+	}
+	return nil, cluster.ErrNotInChannel
+}
@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package etcdraft
+
+import (
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	etcdraftproto "github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/orderer/common/cluster"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newConsenter(t *testing.T, tlsCA tlsgen.CA, port uint32) *etcdraftproto.Consenter {
+	serverKeyPair, err := tlsCA.NewServerCertKeyPair("localhost")
+	require.NoError(t, err)
+	clientKeyPair, err := tlsCA.NewClientCertKeyPair()
+	require.NoError(t, err)
+	return &etcdraftproto.Consenter{
+		Host:          "localhost",
+		Port:          port,
+		ServerTlsCert: serverKeyPair.Cert,
+		ClientTlsCert: clientKeyPair.Cert,
+	}
+}
+
+// raftChannelConfig returns a channel config with the given consenters,
+// whose orderer organization trusts the given TLS CA
+func raftChannelConfig(t *testing.T, tlsCA tlsgen.CA, consenters []*etcdraftproto.Consenter) *common.Config {
+	profile := genesisconfig.Load(genesisconfig.SampleDevModeSoloProfile, configtest.GetDevConfigDir())
+	channelGroup, err := encoder.NewChannelGroup(profile)
+	require.NoError(t, err)
+
+	ordererGroup := channelGroup.Groups[channelconfig.OrdererGroupKey]
+	ordererGroup.Values[channelconfig.ConsensusTypeKey].Value = protoutil.MarshalOrPanic(&orderer.ConsensusType{
+		Type:     "etcdraft",
+		Metadata: protoutil.MarshalOrPanic(&etcdraftproto.ConfigMetadata{Consenters: consenters}),
+	})
+
+	trustTLSCA(t, channelGroup, tlsCA)
+	return &common.Config{ChannelGroup: channelGroup}
+}
+
+// trustTLSCA replaces the TLS CAs of all the organizations in the group with the given TLS CA,
+// as organizations that appear in several groups must have the same MSP definition
+func trustTLSCA(t *testing.T, group *common.ConfigGroup, tlsCA tlsgen.CA) {
+	for _, subGroup := range group.Groups {
+		trustTLSCA(t, subGroup, tlsCA)
+	}
+	mspValue, exists := group.Values[channelconfig.MSPKey]
+	if !exists {
+		return
+	}
+	mspConfig := &msp.MSPConfig{}
+	require.NoError(t, proto.Unmarshal(mspValue.Value, mspConfig))
+	fabricMSPConfig := &msp.FabricMSPConfig{}
+	require.NoError(t, proto.Unmarshal(mspConfig.Config, fabricMSPConfig))
+	fabricMSPConfig.TlsRootCerts = [][]byte{tlsCA.CertBytes()}
+	fabricMSPConfig.TlsIntermediateCerts = nil
+	mspConfig.Config = protoutil.MarshalOrPanic(fabricMSPConfig)
+	mspValue.Value = protoutil.MarshalOrPanic(mspConfig)
+}
+
+func TestConsenterCertRotationUpdate(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	tlsCA, err := tlsgen.NewCA()
+	require.NoError(t, err)
+
+	consenters := []*etcdraftproto.Consenter{
+		newConsenter(t, tlsCA, 7050),
+		newConsenter(t, tlsCA, 7051),
+	}
+	config := raftChannelConfig(t, tlsCA, consenters)
+	rotated := newConsenter(t, tlsCA, 7051)
+
+	update, err := ConsenterCertRotationUpdate("mychannel", config, consenters[1].ServerTlsCert, rotated.ClientTlsCert, rotated.ServerTlsCert, cryptoProvider)
+	require.NoError(t, err)
+	require.Equal(t, "mychannel", update.ChannelId)
+
+	metadata, err := MetadataFromConfigUpdate(update)
+	require.NoError(t, err)
+	require.Len(t, metadata.Consenters, 2)
+	require.True(t, proto.Equal(consenters[0], metadata.Consenters[0]))
+	require.True(t, proto.Equal(rotated, metadata.Consenters[1]))
+
+	t.Run("unknown consenter", func(t *testing.T) {
+		_, err := ConsenterCertRotationUpdate("mychannel", config, rotated.ServerTlsCert, rotated.ClientTlsCert, rotated.ServerTlsCert, cryptoProvider)
+		require.Equal(t, cluster.ErrNotInChannel, err)
+	})
+
+	t.Run("certificates of another consenter", func(t *testing.T) {
+		_, err := ConsenterCertRotationUpdate("mychannel", config, consenters[1].ServerTlsCert, consenters[0].ClientTlsCert, rotated.ServerTlsCert, cryptoProvider)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "duplicate consenter")
+	})
+
+	t.Run("untrusted certificates", func(t *testing.T) {
+		otherCA, err := tlsgen.NewCA()
+		require.NoError(t, err)
+		untrusted := newConsenter(t, otherCA, 7051)
+		_, err = ConsenterCertRotationUpdate("mychannel", config, consenters[1].ServerTlsCert, untrusted.ClientTlsCert, untrusted.ServerTlsCert, cryptoProvider)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid new certificates: verifying tls client cert")
+	})
+
+	t.Run("not etcdraft", func(t *testing.T) {
+		profile := genesisconfig.Load(genesisconfig.SampleDevModeSoloProfile, configtest.GetDevConfigDir())
+		channelGroup, err := encoder.NewChannelGroup(profile)
+		require.NoError(t, err)
+		_, err = ConsenterCertRotationUpdate("mychannel", &common.Config{ChannelGroup: channelGroup}, consenters[1].ServerTlsCert, rotated.ClientTlsCert, rotated.ServerTlsCert, cryptoProvider)
+		require.EqualError(t, err, "consensus type is solo, not etcdraft")
+	})
+}
+
+func TestRemoteConsenters(t *testing.T) {
+	tlsCA, err := tlsgen.NewCA()
+	require.NoError(t, err)
+
+	consenters := []*etcdraftproto.Consenter{
+		newConsenter(t, tlsCA, 7050),
+		newConsenter(t, tlsCA, 7051),
+		newConsenter(t, tlsCA, 7052),
+	}
+	config := raftChannelConfig(t, tlsCA, consenters)
+
+	nodes, err := RemoteConsenters(config, consenters[1].ServerTlsCert)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	for i, consenter := range []*etcdraftproto.Consenter{consenters[0], consenters[2]} {
+		serverCert, _ := pem.Decode(consenter.ServerTlsCert)
+		clientCert, _ := pem.Decode(consenter.ClientTlsCert)
+		require.Equal(t, consenter.Port, uint32(7050+2*i))
+		require.Equal(t, serverCert.Bytes, nodes[i].ServerTLSCert)
+		require.Equal(t, clientCert.Bytes, nodes[i].ClientTLSCert)
+	}
+	require.Equal(t, "localhost:7050", nodes[0].Endpoint)
+	require.Equal(t, "localhost:7052", nodes[1].Endpoint)
+
+	_, err = RemoteConsenters(config, []byte("garbage"))
+	require.EqualError(t, err, "server TLS certificate is not PEM encoded")
+}
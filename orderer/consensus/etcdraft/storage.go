@@ -57,17 +57,22 @@ type RaftStorage struct {
 	wal  *wal.WAL
 	snap *snap.Snapshotter
 
+	// encrypts the data persisted to wal and snapshots, nil if data is persisted in plaintext
+	encrypter *StorageEncrypter
+
 	// a queue that keeps track of indices of snapshots on disk
 	snapshotIndex []uint64
 }
 
 // CreateStorage attempts to create a storage to persist etcd/raft data.
 // If data presents in specified disk, they are loaded to reconstruct storage state.
+// If encrypter is not nil, the data persisted to disk is encrypted with it.
 func CreateStorage(
 	lg *flogging.FabricLogger,
 	walDir string,
 	snapDir string,
 	ram MemoryStorage,
+	encrypter *StorageEncrypter,
 ) (*RaftStorage, error) {
 
 	sn, err := createSnapshotter(lg, snapDir)
@@ -86,6 +91,10 @@ func CreateStorage(
 		// snapshot found
 		lg.Debugf("Loaded snapshot at Term %d and Index %d, Nodes: %+v",
 			snapshot.Metadata.Term, snapshot.Metadata.Index, snapshot.Metadata.ConfState.Nodes)
+
+		if snapshot.Data, err = decryptData(encrypter, snapshot.Data); err != nil {
+			return nil, errors.Errorf("failed to decrypt snapshot: %s", err)
+		}
 	}
 
 	w, st, ents, err := createOrReadWAL(lg, walDir, snapshot)
@@ -93,6 +102,13 @@ func CreateStorage(
 		return nil, errors.Errorf("failed to create or read WAL: %s", err)
 	}
 
+	for i := range ents {
+		if ents[i].Data, err = decryptData(encrypter, ents[i].Data); err != nil {
+			w.Close()
+			return nil, errors.Errorf("failed to decrypt WAL entry at index %d: %s", ents[i].Index, err)
+		}
+	}
+
 	if snapshot != nil {
 		lg.Debugf("Applying snapshot to raft MemoryStorage")
 		if err := ram.ApplySnapshot(*snapshot); err != nil {
@@ -114,9 +130,22 @@ func CreateStorage(
 		walDir:        walDir,
 		snapDir:       snapDir,
 		snapshotIndex: ListSnapshots(lg, snapDir),
+		encrypter:     encrypter,
 	}, nil
 }
 
+// decryptData decrypts data persisted by a storage with the given encrypter.
+// Data persisted before the encryption was enabled is returned as is.
+func decryptData(encrypter *StorageEncrypter, data []byte) ([]byte, error) {
+	if encrypter == nil {
+		if isEncrypted(data) {
+			return nil, errors.New("data is encrypted but no encryption key is configured")
+		}
+		return data, nil
+	}
+	return encrypter.Decrypt(data)
+}
+
 // ListSnapshots returns a list of RaftIndex of snapshots stored on disk.
 // If a file is corrupted, rename the file.
 func ListSnapshots(logger *flogging.FabricLogger, snapDir string) []uint64 {
@@ -242,7 +271,12 @@ func (rs *RaftStorage) Snapshot() raftpb.Snapshot {
 
 // Store persists etcd/raft data
 func (rs *RaftStorage) Store(entries []raftpb.Entry, hardstate raftpb.HardState, snapshot raftpb.Snapshot) error {
-	if err := rs.wal.Save(hardstate, entries); err != nil {
+	persisted, err := rs.encryptEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := rs.wal.Save(hardstate, persisted); err != nil {
 		return err
 	}
 
@@ -268,6 +302,25 @@ func (rs *RaftStorage) Store(entries []raftpb.Entry, hardstate raftpb.HardState,
 	return nil
 }
 
+// encryptEntries returns a copy of the entries whose data is encrypted,
+// or the entries themselves if encryption is disabled.
+func (rs *RaftStorage) encryptEntries(entries []raftpb.Entry) ([]raftpb.Entry, error) {
+	if rs.encrypter == nil {
+		return entries, nil
+	}
+
+	encrypted := make([]raftpb.Entry, len(entries))
+	for i, entry := range entries {
+		data, err := rs.encrypter.Encrypt(entry.Data)
+		if err != nil {
+			return nil, errors.Errorf("failed to encrypt entry at index %d: %s", entry.Index, err)
+		}
+		entry.Data = data
+		encrypted[i] = entry
+	}
+	return encrypted, nil
+}
+
 func (rs *RaftStorage) saveSnap(snap raftpb.Snapshot) error {
 	rs.lg.Infof("Persisting snapshot (term: %d, index: %d) to WAL and disk", snap.Metadata.Term, snap.Metadata.Index)
 
@@ -283,6 +336,15 @@ func (rs *RaftStorage) saveSnap(snap raftpb.Snapshot) error {
 		return errors.Errorf("failed to save snapshot to WAL: %s", err)
 	}
 
+	if rs.encrypter != nil {
+		data, err := rs.encrypter.Encrypt(snap.Data)
+		if err != nil {
+			return errors.Errorf("failed to encrypt snapshot: %s", err)
+		}
+		// snap is passed by value, hence the snapshot in memory is left in plaintext
+		snap.Data = data
+	}
+
 	if err := rs.snap.SaveSnap(snap); err != nil {
 		return errors.Errorf("failed to save snapshot to disk: %s", err)
 	}
@@ -8,8 +8,10 @@ package etcdraft
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/x509"
 	"encoding/pem"
+	"io/ioutil"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -353,3 +355,36 @@ func CreateConsentersMap(blockMetadata *etcdraft.BlockMetadata, configMetadata *
 	}
 	return consenters
 }
+
+// gzipMagic are the first bytes of gzip compressed data. A serialized block starts with
+// the tag of its header field instead, which keeps compressed and uncompressed snapshot
+// data distinguishable.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressSnapshotData compresses the serialized block a snapshot is taken at.
+func compressSnapshotData(blockBytes []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(blockBytes); err != nil {
+		return nil, errors.Wrap(err, "failed to compress snapshot data")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress snapshot data")
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshotBlock returns the block a snapshot is taken at, given the data of the snapshot
+// in either its compressed or its uncompressed form.
+func snapshotBlock(data []byte) (*common.Block, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress snapshot data")
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, errors.Wrap(err, "failed to decompress snapshot data")
+		}
+	}
+	return protoutil.UnmarshalBlock(data)
+}
@@ -283,3 +283,22 @@ func TestCheckConfigMetadata(t *testing.T) {
 		assert.Regexp(t, testCase.errRegex, err)
 	}
 }
+
+func TestSnapshotBlock(t *testing.T) {
+	block := protoutil.NewBlock(10, []byte{1, 2, 3})
+	blockBytes := protoutil.MarshalOrPanic(block)
+
+	b, err := snapshotBlock(blockBytes)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(block, b))
+
+	compressed, err := compressSnapshotData(blockBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, gzipMagic, compressed[:2])
+	b, err = snapshotBlock(compressed)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(block, b))
+
+	_, err = snapshotBlock(compressed[:len(compressed)-4])
+	assert.Contains(t, err.Error(), "failed to decompress snapshot data")
+}
@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package encryption lets chaincode keep its state values encrypted with keys
+// that clients pass in the transient field of their proposals, so that neither
+// the keys nor the plaintext values ever reach the ledger.
+package encryption
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeyField is the default transient field holding the AES-256 key
+	KeyField = "ENCKEY"
+	// IVField is the default transient field holding the initialization vector
+	IVField = "IV"
+)
+
+// Stub is the part of shim.ChaincodeStubInterface used to access encrypted state
+type Stub interface {
+	GetTransient() (map[string][]byte, error)
+	GetState(key string) ([]byte, error)
+	PutState(key string, value []byte) error
+	GetPrivateData(collection, key string) ([]byte, error)
+	PutPrivateData(collection, key string, value []byte) error
+}
+
+// Encrypter encrypts and decrypts state values with AES-256 in CBC mode with PKCS7 padding.
+//
+// Values encrypted without an initialization vector are encrypted with a random one,
+// which makes their ciphertext differ between endorsing peers. Chaincode endorsed by
+// more than one peer must therefore be given an initialization vector by the client.
+type Encrypter struct {
+	csp bccsp.BCCSP
+	key bccsp.Key
+	iv  []byte
+}
+
+// New returns an Encrypter for the given AES-256 key and initialization vector, which may be nil.
+// The cryptographic operations are delegated to the given BCCSP, or to a software BCCSP if it is nil.
+func New(csp bccsp.BCCSP, key, iv []byte) (*Encrypter, error) {
+	if csp == nil {
+		var err error
+		csp, err = sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed creating BCCSP")
+		}
+	}
+	if len(iv) != 0 && len(iv) != 16 {
+		return nil, errors.Errorf("initialization vector must be 16 bytes long, got %d", len(iv))
+	}
+	k, err := csp.KeyImport(key, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed importing encryption key")
+	}
+	return &Encrypter{
+		csp: csp,
+		key: k,
+		iv:  iv,
+	}, nil
+}
+
+// FromTransient returns an Encrypter for the key and initialization vector found in the
+// transient fields of the proposal of the stub under KeyField and IVField.
+// The initialization vector is optional.
+func FromTransient(stub Stub, csp bccsp.BCCSP) (*Encrypter, error) {
+	return FromTransientFields(stub, csp, KeyField, IVField)
+}
+
+// FromTransientFields is like FromTransient, but reads the key and the initialization
+// vector from the given transient fields.
+func FromTransientFields(stub Stub, csp bccsp.BCCSP, keyField, ivField string) (*Encrypter, error) {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting transient fields")
+	}
+	key, exists := transient[keyField]
+	if !exists {
+		return nil, errors.Errorf("transient field %s is missing", keyField)
+	}
+	return New(csp, key, transient[ivField])
+}
+
+// Encrypt encrypts the given plaintext
+func (e *Encrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	ciphertext, err := e.csp.Encrypt(e.key, plaintext, &bccsp.AESCBCPKCS7ModeOpts{IV: e.iv})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed encrypting")
+	}
+	return ciphertext, nil
+}
+
+// Decrypt decrypts the given ciphertext
+func (e *Encrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	plaintext, err := e.csp.Decrypt(e.key, ciphertext, &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed decrypting")
+	}
+	return plaintext, nil
+}
+
+// PutState encrypts the given value and puts it in the world state under the given key
+func (e *Encrypter) PutState(stub Stub, key string, value []byte) error {
+	ciphertext, err := e.Encrypt(value)
+	if err != nil {
+		return errors.WithMessagef(err, "failed putting state of key %s", key)
+	}
+	return stub.PutState(key, ciphertext)
+}
+
+// GetState gets the value of the given key from the world state and decrypts it.
+// It returns nil if the key doesn't exist.
+func (e *Encrypter) GetState(stub Stub, key string) ([]byte, error) {
+	ciphertext, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 {
+		return nil, nil
+	}
+	value, err := e.Decrypt(ciphertext)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting state of key %s", key)
+	}
+	return value, nil
+}
+
+// PutPrivateData encrypts the given value and puts it in the given collection under the given key
+func (e *Encrypter) PutPrivateData(stub Stub, collection, key string, value []byte) error {
+	ciphertext, err := e.Encrypt(value)
+	if err != nil {
+		return errors.WithMessagef(err, "failed putting private data of key %s in collection %s", key, collection)
+	}
+	return stub.PutPrivateData(collection, key, ciphertext)
+}
+
+// GetPrivateData gets the value of the given key from the given collection and decrypts it.
+// It returns nil if the key doesn't exist.
+func (e *Encrypter) GetPrivateData(stub Stub, collection, key string) ([]byte, error) {
+	ciphertext, err := stub.GetPrivateData(collection, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 {
+		return nil, nil
+	}
+	value, err := e.Decrypt(ciphertext)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting private data of key %s from collection %s", key, collection)
+	}
+	return value, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Stub = shim.ChaincodeStubInterface(nil)
+
+type transientStub struct {
+	*shimtest.MockStub
+	transient map[string][]byte
+	err       error
+}
+
+func (ts *transientStub) GetTransient() (map[string][]byte, error) {
+	return ts.transient, ts.err
+}
+
+func newStub(transient map[string][]byte) *transientStub {
+	stub := &transientStub{
+		MockStub:  shimtest.NewMockStub("enc", nil),
+		transient: transient,
+	}
+	stub.MockTransactionStart("tx1")
+	return stub
+}
+
+func TestEncrypter(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	iv := bytes.Repeat([]byte{2}, 16)
+
+	t.Run("State", func(t *testing.T) {
+		stub := newStub(map[string][]byte{KeyField: key})
+		e, err := FromTransient(stub, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, e.PutState(stub, "a", []byte("secret")))
+		assert.NotContains(t, string(stub.State["a"]), "secret")
+		value, err := e.GetState(stub, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), value)
+
+		value, err = e.GetState(stub, "missing")
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("PrivateData", func(t *testing.T) {
+		stub := newStub(map[string][]byte{KeyField: key})
+		e, err := FromTransient(stub, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, e.PutPrivateData(stub, "coll", "a", []byte("secret")))
+		value, err := e.GetPrivateData(stub, "coll", "a")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), value)
+
+		value, err = e.GetPrivateData(stub, "coll", "missing")
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		e1, err := New(nil, key, iv)
+		assert.NoError(t, err)
+		e2, err := FromTransientFields(newStub(map[string][]byte{"k": key, "v": iv}), nil, "k", "v")
+		assert.NoError(t, err)
+
+		c1, err := e1.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		c2, err := e2.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.Equal(t, c1, c2)
+
+		random, err := New(nil, key, nil)
+		assert.NoError(t, err)
+		c3, err := random.Encrypt([]byte("secret"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, c1, c3)
+
+		plaintext, err := random.Decrypt(c1)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		stub := newStub(nil)
+		e, err := New(nil, key, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, e.PutState(stub, "a", []byte("secret")))
+
+		other, err := New(nil, bytes.Repeat([]byte{3}, 32), nil)
+		assert.NoError(t, err)
+		value, err := other.GetState(stub, "a")
+		if err == nil {
+			assert.NotEqual(t, []byte("secret"), value)
+		} else {
+			assert.Contains(t, err.Error(), "failed getting state of key a")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := FromTransient(newStub(nil), nil)
+		assert.EqualError(t, err, "transient field ENCKEY is missing")
+
+		stub := newStub(nil)
+		stub.err = errors.New("no proposal")
+		_, err = FromTransient(stub, nil)
+		assert.EqualError(t, err, "failed getting transient fields: no proposal")
+
+		_, err = New(nil, key, []byte{1, 2, 3})
+		assert.EqualError(t, err, "initialization vector must be 16 bytes long, got 3")
+
+		_, err = New(nil, []byte{1, 2, 3}, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed importing encryption key")
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package expiry lets chaincode set the time at which a private key expires,
+// overriding the time-to-live the peers configure for the collection of the key.
+//
+// The expiry of a key is stored as private data of the same collection, under a
+// composite key of the object type ObjectType. Peers that purge the collection
+// by time purge both the key and its expiry once the expiry has passed.
+package expiry
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+)
+
+// ObjectType is the object type of the composite keys holding the expiries of keys
+const ObjectType = "fabric.expiry"
+
+const (
+	minUnicodeRuneValue = "\x00"
+	keyPrefix           = minUnicodeRuneValue + ObjectType + minUnicodeRuneValue
+)
+
+// Key returns the key holding the expiry of the given key
+func Key(key string) string {
+	return keyPrefix + key + minUnicodeRuneValue
+}
+
+// SplitKey returns the key whose expiry the given key holds, and whether the given key holds an expiry
+func SplitKey(expiryKey string) (string, bool) {
+	if len(expiryKey) <= len(keyPrefix) || !strings.HasPrefix(expiryKey, keyPrefix) || !strings.HasSuffix(expiryKey, minUnicodeRuneValue) {
+		return "", false
+	}
+	return expiryKey[len(keyPrefix) : len(expiryKey)-1], true
+}
+
+// Encode returns the value holding the given expiry
+func Encode(expiry time.Time) []byte {
+	return []byte(expiry.UTC().Format(time.RFC3339Nano))
+}
+
+// Decode returns the expiry held by the given value
+func Decode(value []byte) (time.Time, error) {
+	expiry, err := time.Parse(time.RFC3339Nano, string(value))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "malformed expiry")
+	}
+	return expiry, nil
+}
+
+// Stub is the part of shim.ChaincodeStubInterface used to manage the expiries of keys
+type Stub interface {
+	GetTxTimestamp() (*timestamp.Timestamp, error)
+	GetPrivateData(collection, key string) ([]byte, error)
+	PutPrivateData(collection, key string, value []byte) error
+	DelPrivateData(collection, key string) error
+}
+
+// SetPrivateDataExpiry makes the given key of the given collection expire at the given time
+func SetPrivateDataExpiry(stub Stub, collection, key string, expiry time.Time) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	if err := stub.PutPrivateData(collection, Key(key), Encode(expiry)); err != nil {
+		return errors.WithMessagef(err, "failed setting expiry of key %s in collection %s", key, collection)
+	}
+	return nil
+}
+
+// SetPrivateDataTTL makes the given key of the given collection expire after the given
+// duration, counted from the timestamp of the transaction
+func SetPrivateDataTTL(stub Stub, collection, key string, ttl time.Duration) error {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return errors.WithMessage(err, "failed getting transaction timestamp")
+	}
+	txTime, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return errors.Wrap(err, "invalid transaction timestamp")
+	}
+	return SetPrivateDataExpiry(stub, collection, key, txTime.Add(ttl))
+}
+
+// GetPrivateDataExpiry returns the expiry of the given key of the given collection, and whether it's set
+func GetPrivateDataExpiry(stub Stub, collection, key string) (time.Time, bool, error) {
+	value, err := stub.GetPrivateData(collection, Key(key))
+	if err != nil {
+		return time.Time{}, false, errors.WithMessagef(err, "failed getting expiry of key %s in collection %s", key, collection)
+	}
+	if value == nil {
+		return time.Time{}, false, nil
+	}
+	expiry, err := Decode(value)
+	if err != nil {
+		return time.Time{}, false, errors.WithMessagef(err, "failed getting expiry of key %s in collection %s", key, collection)
+	}
+	return expiry, true, nil
+}
+
+// DelPrivateDataExpiry removes the expiry of the given key of the given collection, which then
+// expires as per the time-to-live of the collection
+func DelPrivateDataExpiry(stub Stub, collection, key string) error {
+	if err := stub.DelPrivateData(collection, Key(key)); err != nil {
+		return errors.WithMessagef(err, "failed deleting expiry of key %s in collection %s", key, collection)
+	}
+	return nil
+}
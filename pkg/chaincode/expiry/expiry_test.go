@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Stub = shim.ChaincodeStubInterface(nil)
+
+func TestKey(t *testing.T) {
+	for _, key := range []string{"asset1", "\x00asset\x00id1\x00"} {
+		expiryKey := Key(key)
+		split, ok := SplitKey(expiryKey)
+		assert.True(t, ok)
+		assert.Equal(t, key, split)
+	}
+
+	shimKey, err := shim.CreateCompositeKey(ObjectType, []string{"asset1"})
+	require.NoError(t, err)
+	assert.Equal(t, shimKey, Key("asset1"))
+
+	for _, key := range []string{"asset1", "\x00other\x00asset1\x00", "\x00fabric.expiry\x00", "\x00fabric.expiry\x00asset1"} {
+		_, ok := SplitKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+// stub adds the deletion of private data, which the mock stub doesn't implement
+type stub struct {
+	*shimtest.MockStub
+}
+
+func (s *stub) DelPrivateData(collection, key string) error {
+	delete(s.PvtState[collection], key)
+	return nil
+}
+
+func TestExpiry(t *testing.T) {
+	stub := &stub{MockStub: shimtest.NewMockStub("cc", nil)}
+	stub.MockTransactionStart("tx1")
+	txTime := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	stub.TxTimestamp, _ = ptypes.TimestampProto(txTime)
+
+	_, exists, err := GetPrivateDataExpiry(stub, "coll", "asset1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, SetPrivateDataTTL(stub, "coll", "asset1", 24*time.Hour))
+	expiry, exists, err := GetPrivateDataExpiry(stub, "coll", "asset1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.True(t, txTime.Add(24*time.Hour).Equal(expiry))
+
+	deadline := time.Date(2021, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	require.NoError(t, SetPrivateDataExpiry(stub, "coll", "asset1", deadline))
+	expiry, _, err = GetPrivateDataExpiry(stub, "coll", "asset1")
+	require.NoError(t, err)
+	assert.True(t, deadline.Equal(expiry))
+
+	require.NoError(t, DelPrivateDataExpiry(stub, "coll", "asset1"))
+	_, exists, err = GetPrivateDataExpiry(stub, "coll", "asset1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.EqualError(t, SetPrivateDataExpiry(stub, "coll", "", deadline), "key must not be empty")
+
+	require.NoError(t, stub.PutPrivateData("coll", Key("asset2"), []byte("tomorrow")))
+	_, _, err = GetPrivateDataExpiry(stub, "coll", "asset2")
+	assert.Contains(t, err.Error(), "failed getting expiry of key asset2 in collection coll: malformed expiry")
+}
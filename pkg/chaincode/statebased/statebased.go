@@ -0,0 +1,258 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package statebased helps chaincode manage the endorsement policies of
+// individual keys, which the validator evaluates in place of the endorsement
+// policy of the chaincode when the keys are written.
+//
+// It extends the statebased package of the shim with the admin and client
+// roles and with policies requiring only N of the organizations to endorse,
+// and it updates the policy of a key in a single read-modify-write.
+package statebased
+
+import (
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// RoleType is the role the endorsers of an organization must have
+type RoleType string
+
+const (
+	RoleMember RoleType = "MEMBER"
+	RolePeer   RoleType = "PEER"
+	RoleAdmin  RoleType = "ADMIN"
+	RoleClient RoleType = "CLIENT"
+)
+
+var mspRoles = map[RoleType]msp.MSPRole_MSPRoleType{
+	RoleMember: msp.MSPRole_MEMBER,
+	RolePeer:   msp.MSPRole_PEER,
+	RoleAdmin:  msp.MSPRole_ADMIN,
+	RoleClient: msp.MSPRole_CLIENT,
+}
+
+func roleType(role msp.MSPRole_MSPRoleType) (RoleType, bool) {
+	for rt, r := range mspRoles {
+		if r == role {
+			return rt, true
+		}
+	}
+	return "", false
+}
+
+// KeyPolicy is the endorsement policy of a key, which requires an endorsement
+// from N of its organizations, each by an endorser with the role of the organization
+type KeyPolicy struct {
+	roles map[string]RoleType
+	// n is the number of organizations that must endorse, or 0 for all of them
+	n int
+}
+
+// NewKeyPolicy returns the KeyPolicy of the given marshaled SignaturePolicyEnvelope,
+// or an empty KeyPolicy if it's empty. Only policies requiring N signatures out of
+// organization roles, such as the ones the shim and this package create, are supported.
+func NewKeyPolicy(policy []byte) (*KeyPolicy, error) {
+	kp := &KeyPolicy{roles: map[string]RoleType{}}
+	if len(policy) == 0 {
+		return kp, nil
+	}
+
+	spe := &common.SignaturePolicyEnvelope{}
+	if err := proto.Unmarshal(policy, spe); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling signature policy envelope")
+	}
+	nOutOf := spe.GetRule().GetNOutOf()
+	if nOutOf == nil {
+		return nil, errors.New("policy is not an N out of rule")
+	}
+
+	for _, rule := range nOutOf.Rules {
+		signedBy, ok := rule.Type.(*common.SignaturePolicy_SignedBy)
+		if !ok {
+			return nil, errors.New("policy has nested rules")
+		}
+		if signedBy.SignedBy < 0 || int(signedBy.SignedBy) >= len(spe.Identities) {
+			return nil, errors.Errorf("policy references identity %d out of %d", signedBy.SignedBy, len(spe.Identities))
+		}
+		principal := spe.Identities[signedBy.SignedBy]
+		if principal.PrincipalClassification != msp.MSPPrincipal_ROLE {
+			return nil, errors.Errorf("policy has a principal of classification %s", principal.PrincipalClassification)
+		}
+		mspRole := &msp.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, mspRole); err != nil {
+			return nil, errors.Wrap(err, "failed unmarshaling MSP role")
+		}
+		role, ok := roleType(mspRole.Role)
+		if !ok {
+			return nil, errors.Errorf("policy has the unsupported role %s", mspRole.Role)
+		}
+		if _, exists := kp.roles[mspRole.MspIdentifier]; exists {
+			return nil, errors.Errorf("policy has organization %s more than once", mspRole.MspIdentifier)
+		}
+		kp.roles[mspRole.MspIdentifier] = role
+	}
+	if int(nOutOf.N) != len(kp.roles) {
+		kp.n = int(nOutOf.N)
+	}
+	return kp, nil
+}
+
+// AddOrgs adds the given organizations with the given role, replacing the role
+// of the organizations that were already part of the policy
+func (kp *KeyPolicy) AddOrgs(role RoleType, orgs ...string) error {
+	if _, ok := mspRoles[role]; !ok {
+		return errors.Errorf("role %s does not exist", role)
+	}
+	for _, org := range orgs {
+		kp.roles[org] = role
+	}
+	return nil
+}
+
+// DelOrgs removes the given organizations
+func (kp *KeyPolicy) DelOrgs(orgs ...string) {
+	for _, org := range orgs {
+		delete(kp.roles, org)
+	}
+}
+
+// SetOrgs replaces the organizations of the policy with the given ones, with the given role
+func (kp *KeyPolicy) SetOrgs(role RoleType, orgs ...string) error {
+	if _, ok := mspRoles[role]; !ok {
+		return errors.Errorf("role %s does not exist", role)
+	}
+	kp.roles = map[string]RoleType{}
+	return kp.AddOrgs(role, orgs...)
+}
+
+// ListOrgs returns the organizations of the policy, in order
+func (kp *KeyPolicy) ListOrgs() []string {
+	orgs := make([]string, 0, len(kp.roles))
+	for org := range kp.roles {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs
+}
+
+// Role returns the role of the given organization, and whether it is part of the policy
+func (kp *KeyPolicy) Role(org string) (RoleType, bool) {
+	role, exists := kp.roles[org]
+	return role, exists
+}
+
+// RequireN makes the policy require endorsements from n of its organizations,
+// or from all of them if n is 0
+func (kp *KeyPolicy) RequireN(n int) error {
+	if n < 0 {
+		return errors.Errorf("invalid number of organizations %d", n)
+	}
+	kp.n = n
+	return nil
+}
+
+// Required returns the number of organizations that must endorse
+func (kp *KeyPolicy) Required() int {
+	if kp.n == 0 {
+		return len(kp.roles)
+	}
+	return kp.n
+}
+
+// Policy returns the policy as a marshaled SignaturePolicyEnvelope, as expected by
+// SetStateValidationParameter and SetPrivateDataValidationParameter
+func (kp *KeyPolicy) Policy() ([]byte, error) {
+	orgs := kp.ListOrgs()
+	if len(orgs) == 0 {
+		return nil, errors.New("policy has no organizations")
+	}
+	if kp.Required() > len(orgs) {
+		return nil, errors.Errorf("policy requires %d organizations out of %d", kp.Required(), len(orgs))
+	}
+
+	spe := &common.SignaturePolicyEnvelope{
+		Rule: &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_NOutOf_{
+				NOutOf: &common.SignaturePolicy_NOutOf{N: int32(kp.Required())},
+			},
+		},
+	}
+	for i, org := range orgs {
+		principal, err := proto.Marshal(&msp.MSPRole{
+			MspIdentifier: org,
+			Role:          mspRoles[kp.roles[org]],
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed marshaling MSP role")
+		}
+		spe.Identities = append(spe.Identities, &msp.MSPPrincipal{
+			PrincipalClassification: msp.MSPPrincipal_ROLE,
+			Principal:               principal,
+		})
+		nOutOf := spe.Rule.Type.(*common.SignaturePolicy_NOutOf_).NOutOf
+		nOutOf.Rules = append(nOutOf.Rules, &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_SignedBy{SignedBy: int32(i)},
+		})
+	}
+
+	policy, err := proto.Marshal(spe)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling signature policy envelope")
+	}
+	return policy, nil
+}
+
+// Stub is the part of shim.ChaincodeStubInterface used to access the policies of keys
+type Stub interface {
+	GetStateValidationParameter(key string) ([]byte, error)
+	SetStateValidationParameter(key string, ep []byte) error
+	GetPrivateDataValidationParameter(collection, key string) ([]byte, error)
+	SetPrivateDataValidationParameter(collection, key string, ep []byte) error
+}
+
+// UpdateKeyPolicy applies the given update to the policy of the given key of the world state,
+// and sets the updated policy. The policy isn't set if the update fails.
+func UpdateKeyPolicy(stub Stub, key string, update func(*KeyPolicy) error) error {
+	current, err := stub.GetStateValidationParameter(key)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting policy of key %s", key)
+	}
+	policy, err := updatedPolicy(current, update)
+	if err != nil {
+		return errors.WithMessagef(err, "failed updating policy of key %s", key)
+	}
+	return stub.SetStateValidationParameter(key, policy)
+}
+
+// UpdatePrivateKeyPolicy applies the given update to the policy of the given key of the
+// given collection, and sets the updated policy. The policy isn't set if the update fails.
+func UpdatePrivateKeyPolicy(stub Stub, collection, key string, update func(*KeyPolicy) error) error {
+	current, err := stub.GetPrivateDataValidationParameter(collection, key)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting policy of key %s in collection %s", key, collection)
+	}
+	policy, err := updatedPolicy(current, update)
+	if err != nil {
+		return errors.WithMessagef(err, "failed updating policy of key %s in collection %s", key, collection)
+	}
+	return stub.SetPrivateDataValidationParameter(collection, key, policy)
+}
+
+func updatedPolicy(current []byte, update func(*KeyPolicy) error) ([]byte, error) {
+	kp, err := NewKeyPolicy(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(kp); err != nil {
+		return nil, err
+	}
+	return kp.Policy()
+}
@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statebased
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Stub = shim.ChaincodeStubInterface(nil)
+
+func TestKeyPolicy(t *testing.T) {
+	kp, err := NewKeyPolicy(nil)
+	require.NoError(t, err)
+	_, err = kp.Policy()
+	assert.EqualError(t, err, "policy has no organizations")
+
+	require.NoError(t, kp.AddOrgs(RolePeer, "Org1MSP", "Org2MSP"))
+	require.NoError(t, kp.AddOrgs(RoleAdmin, "Org3MSP"))
+	assert.EqualError(t, kp.AddOrgs("AUDITOR", "Org4MSP"), "role AUDITOR does not exist")
+	assert.Equal(t, []string{"Org1MSP", "Org2MSP", "Org3MSP"}, kp.ListOrgs())
+	assert.Equal(t, 3, kp.Required())
+
+	require.NoError(t, kp.RequireN(2))
+	policy, err := kp.Policy()
+	require.NoError(t, err)
+
+	parsed, err := NewKeyPolicy(policy)
+	require.NoError(t, err)
+	assert.Equal(t, kp, parsed)
+	role, exists := parsed.Role("Org3MSP")
+	assert.True(t, exists)
+	assert.Equal(t, RoleAdmin, role)
+
+	parsed.DelOrgs("Org2MSP", "Org3MSP")
+	_, err = parsed.Policy()
+	assert.EqualError(t, err, "policy requires 2 organizations out of 1")
+	require.NoError(t, parsed.RequireN(0))
+	_, err = parsed.Policy()
+	assert.NoError(t, err)
+	assert.EqualError(t, parsed.RequireN(-1), "invalid number of organizations -1")
+
+	require.NoError(t, parsed.SetOrgs(RolePeer, "Org2MSP", "Org1MSP", "Org3MSP"))
+	require.NoError(t, parsed.RequireN(2))
+	policy, err = parsed.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, protoutil.MarshalOrPanic(policydsl.SignedByNOutOfGivenRole(2, msp.MSPRole_PEER, []string{"Org1MSP", "Org2MSP", "Org3MSP"})), policy)
+
+	require.NoError(t, parsed.SetOrgs(RoleClient, "Org5MSP"))
+	assert.Equal(t, []string{"Org5MSP"}, parsed.ListOrgs())
+	assert.EqualError(t, parsed.SetOrgs("", "Org5MSP"), "role  does not exist")
+}
+
+func TestKeyPolicyCompatibility(t *testing.T) {
+	// policies created by the shim are read as requiring all organizations
+	ep, err := statebased.NewStateEP(nil)
+	require.NoError(t, err)
+	require.NoError(t, ep.AddOrgs(statebased.RoleTypePeer, "Org1MSP", "Org2MSP"))
+	shimPolicy, err := ep.Policy()
+	require.NoError(t, err)
+
+	kp, err := NewKeyPolicy(shimPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Org1MSP", "Org2MSP"}, kp.ListOrgs())
+	assert.Equal(t, 2, kp.Required())
+	policy, err := kp.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, shimPolicy, policy)
+
+	// and the shim reads the policies of this package
+	require.NoError(t, kp.AddOrgs(RoleMember, "Org3MSP"))
+	policy, err = kp.Policy()
+	require.NoError(t, err)
+	ep, err = statebased.NewStateEP(policy)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Org1MSP", "Org2MSP", "Org3MSP"}, ep.ListOrgs())
+}
+
+func TestNewKeyPolicyErrors(t *testing.T) {
+	_, err := NewKeyPolicy([]byte("garbage"))
+	assert.Contains(t, err.Error(), "failed unmarshaling signature policy envelope")
+
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(policydsl.Envelope(policydsl.SignedBy(0), nil)))
+	assert.EqualError(t, err, "policy is not an N out of rule")
+
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(policydsl.SignedByMspMember("Org1MSP")))
+	assert.NoError(t, err)
+
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(policydsl.SignedByAnyMember([]string{"Org1MSP", "Org2MSP"})))
+	assert.NoError(t, err)
+
+	nested := policydsl.Envelope(
+		policydsl.NOutOf(1, []*common.SignaturePolicy{policydsl.NOutOf(1, []*common.SignaturePolicy{policydsl.SignedBy(0)})}),
+		nil,
+	)
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(nested))
+	assert.EqualError(t, err, "policy has nested rules")
+
+	outOfRange := policydsl.Envelope(policydsl.NOutOf(1, []*common.SignaturePolicy{policydsl.SignedBy(1)}), nil)
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(outOfRange))
+	assert.EqualError(t, err, "policy references identity 1 out of 0")
+
+	duplicated := policydsl.SignedByAnyMember([]string{"Org1MSP", "Org1MSP"})
+	_, err = NewKeyPolicy(protoutil.MarshalOrPanic(duplicated))
+	assert.EqualError(t, err, "policy has organization Org1MSP more than once")
+}
+
+type stub struct {
+	*shimtest.MockStub
+	err error
+}
+
+func (s *stub) GetStateValidationParameter(key string) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.MockStub.GetStateValidationParameter(key)
+}
+
+func TestUpdateKeyPolicy(t *testing.T) {
+	s := &stub{MockStub: shimtest.NewMockStub("cc", nil)}
+	s.MockTransactionStart("tx1")
+	require.NoError(t, s.PutState("asset", []byte("value")))
+	require.NoError(t, s.PutPrivateData("coll", "asset", []byte("value")))
+
+	err := UpdateKeyPolicy(s, "asset", func(kp *KeyPolicy) error {
+		if err := kp.AddOrgs(RolePeer, "Org1MSP", "Org2MSP", "Org3MSP"); err != nil {
+			return err
+		}
+		return kp.RequireN(2)
+	})
+	require.NoError(t, err)
+
+	// orgs are swapped atomically
+	err = UpdateKeyPolicy(s, "asset", func(kp *KeyPolicy) error {
+		kp.DelOrgs("Org3MSP")
+		return kp.AddOrgs(RolePeer, "Org4MSP")
+	})
+	require.NoError(t, err)
+	policy, err := s.GetStateValidationParameter("asset")
+	require.NoError(t, err)
+	kp, err := NewKeyPolicy(policy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Org1MSP", "Org2MSP", "Org4MSP"}, kp.ListOrgs())
+	assert.Equal(t, 2, kp.Required())
+
+	// a failed update leaves the policy as it was
+	err = UpdateKeyPolicy(s, "asset", func(kp *KeyPolicy) error {
+		kp.DelOrgs("Org1MSP")
+		return errors.New("not allowed")
+	})
+	assert.EqualError(t, err, "failed updating policy of key asset: not allowed")
+	unchanged, err := s.GetStateValidationParameter("asset")
+	require.NoError(t, err)
+	assert.Equal(t, policy, unchanged)
+
+	s.err = errors.New("ledger unavailable")
+	err = UpdateKeyPolicy(s, "asset", func(*KeyPolicy) error { return nil })
+	assert.EqualError(t, err, "failed getting policy of key asset: ledger unavailable")
+
+	err = UpdatePrivateKeyPolicy(s, "coll", "asset", func(kp *KeyPolicy) error {
+		return kp.AddOrgs(RoleMember, "Org1MSP")
+	})
+	require.NoError(t, err)
+	policy, err = s.GetPrivateDataValidationParameter("coll", "asset")
+	require.NoError(t, err)
+	kp, err = NewKeyPolicy(policy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Org1MSP"}, kp.ListOrgs())
+
+	err = UpdatePrivateKeyPolicy(s, "coll", "asset", func(kp *KeyPolicy) error {
+		kp.DelOrgs("Org1MSP")
+		return nil
+	})
+	assert.EqualError(t, err, "failed updating policy of key asset in collection coll: policy has no organizations")
+}
@@ -65,13 +65,27 @@ generateHelpText \
         docs/wrappers/peer_channel_postscript.md \
         "${commands[@]}"
 
-commands=("peer node start" "peer node reset" "peer node rollback")
+commands=("peer node start" "peer node reset" "peer node rollback" "peer node verify-blockstore")
 generateHelpText \
         docs/source/commands/peernode.md \
         docs/wrappers/peer_node_preamble.md \
         docs/wrappers/peer_node_postscript.md \
         "${commands[@]}"
 
+commands=("peer keystore list" "peer keystore inspect" "peer keystore delete" "peer keystore export" "peer keystore pending" "peer keystore approve" "peer keystore rotate" "peer keystore versions" "peer keystore revoke" "peer keystore backup" "peer keystore restore")
+generateHelpText \
+        docs/source/commands/peerkeystore.md \
+        docs/wrappers/peer_keystore_preamble.md \
+        docs/wrappers/peer_keystore_postscript.md \
+        "${commands[@]}"
+
+commands=("peer crypto sign" "peer crypto verify")
+generateHelpText \
+        docs/source/commands/peercrypto.md \
+        docs/wrappers/peer_crypto_preamble.md \
+        docs/wrappers/peer_crypto_postscript.md \
+        "${commands[@]}"
+
 commands=("configtxgen")
 generateHelpText \
         docs/source/commands/configtxgen.md \
@@ -86,11 +100,18 @@ generateHelpText \
         docs/wrappers/cryptogen_postscript.md \
         "${commands[@]}"
 
-commands=("configtxlator start" "configtxlator proto_encode" "configtxlator proto_decode" "configtxlator compute_update" "configtxlator version")
+commands=("configtxlator start" "configtxlator proto_encode" "configtxlator proto_decode" "configtxlator compute_update" "configtxlator describe_update" "configtxlator version")
 generateHelpText \
         docs/source/commands/configtxlator.md \
         docs/wrappers/configtxlator_preamble.md \
         docs/wrappers/configtxlator_postscript.md \
         "${commands[@]}"
 
+commands=("ledgerutil compare" "ledgerutil version")
+generateHelpText \
+        docs/source/commands/ledgerutil.md \
+        docs/wrappers/ledgerutil_preamble.md \
+        docs/wrappers/ledgerutil_postscript.md \
+        "${commands[@]}"
+
 exit
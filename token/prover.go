@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// UnspentToken is an unspent token along with its ID.
+type UnspentToken struct {
+	ID
+	*Token
+}
+
+// Prover builds the token transactions of a client, and signs them with
+// the key of the client.
+type Prover struct {
+	Signer Signer
+}
+
+// Issue builds a transaction issuing the given tokens, with the
+// client as the issuer.
+func (p *Prover) Issue(txID string, outputs ...*Token) (*SignedTransaction, error) {
+	return p.sign(&Transaction{
+		TxID: txID,
+		Issue: &Issue{
+			Issuer:  p.Signer.Identity(),
+			Outputs: outputs,
+		},
+	})
+}
+
+// Transfer builds a transaction spending the given tokens of the client
+// and creating the given tokens. Inputs owned by others must also be signed
+// by their owners, through AddSignature.
+func (p *Prover) Transfer(txID string, inputs []ID, outputs ...*Token) (*SignedTransaction, error) {
+	return p.sign(&Transaction{
+		TxID: txID,
+		Transfer: &Transfer{
+			Inputs:  inputs,
+			Outputs: outputs,
+		},
+	})
+}
+
+// Redeem builds a transaction redeeming the given quantity out of the given
+// tokens of the client. The remaining quantity is returned to the client.
+func (p *Prover) Redeem(txID string, inputs []*UnspentToken, quantity uint64) (*SignedTransaction, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("no tokens to redeem")
+	}
+	if quantity == 0 {
+		return nil, errors.New("the quantity to redeem must be positive")
+	}
+	var ids []ID
+	var total uint64
+	for _, in := range inputs {
+		if in.Type != inputs[0].Type {
+			return nil, errors.Errorf("token %s is of type %s, expected %s", in.ID, in.Type, inputs[0].Type)
+		}
+		if total+in.Quantity < total {
+			return nil, errors.New("the total quantity of the tokens overflows")
+		}
+		total += in.Quantity
+		ids = append(ids, in.ID)
+	}
+	if quantity > total {
+		return nil, errors.Errorf("cannot redeem %d out of %d tokens", quantity, total)
+	}
+	redeem := &Redeem{Inputs: ids}
+	if change := total - quantity; change > 0 {
+		redeem.Outputs = []*Token{{Owner: p.Signer.Identity(), Type: inputs[0].Type, Quantity: change}}
+	}
+	return p.sign(&Transaction{TxID: txID, Redeem: redeem})
+}
+
+func (p *Prover) sign(tx *Transaction) (*SignedTransaction, error) {
+	raw, err := json.Marshal(tx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling token transaction")
+	}
+	stx := &SignedTransaction{Transaction: raw}
+	if err := stx.AddSignature(p.Signer); err != nil {
+		return nil, err
+	}
+	return stx, nil
+}
+
+// AddSignature signs the transaction with the given signer, such as the owner
+// of some of the tokens it spends.
+func (stx *SignedTransaction) AddSignature(signer Signer) error {
+	signature, err := signer.Sign(stx.Transaction)
+	if err != nil {
+		return errors.WithMessage(err, "failed signing token transaction")
+	}
+	stx.Signatures = append(stx.Signatures, &Signature{
+		Signer:    signer.Identity(),
+		Signature: signature,
+	})
+	return nil
+}
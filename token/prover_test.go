@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProverRedeem(t *testing.T) {
+	csp := newCSP(t)
+	alice := newProver(t, csp)
+	owned := func(index uint32, tokenType string, quantity uint64) *UnspentToken {
+		return &UnspentToken{
+			ID:    ID{TxID: "mint", Index: index},
+			Token: &Token{Owner: alice.Signer.Identity(), Type: tokenType, Quantity: quantity},
+		}
+	}
+
+	_, err := alice.Redeem("tx", nil, 1)
+	require.EqualError(t, err, "no tokens to redeem")
+
+	_, err = alice.Redeem("tx", []*UnspentToken{owned(0, "USD", 10)}, 0)
+	require.EqualError(t, err, "the quantity to redeem must be positive")
+
+	_, err = alice.Redeem("tx", []*UnspentToken{owned(0, "USD", 10), owned(1, "EUR", 10)}, 1)
+	require.EqualError(t, err, "token mint:1 is of type EUR, expected USD")
+
+	_, err = alice.Redeem("tx", []*UnspentToken{owned(0, "USD", 10)}, 11)
+	require.EqualError(t, err, "cannot redeem 11 out of 10 tokens")
+
+	stx, err := alice.Redeem("tx", []*UnspentToken{owned(0, "USD", 10), owned(1, "USD", 5)}, 15)
+	require.NoError(t, err)
+	tx := &Transaction{}
+	require.NoError(t, json.Unmarshal(stx.Transaction, tx))
+	require.Equal(t, &Redeem{Inputs: []ID{{TxID: "mint", Index: 0}, {TxID: "mint", Index: 1}}}, tx.Redeem)
+
+	stx, err = alice.Redeem("tx", []*UnspentToken{owned(0, "USD", 10), owned(1, "USD", 5)}, 12)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(stx.Transaction, tx))
+	require.Equal(t, []*Token{{Owner: alice.Signer.Identity(), Type: "USD", Quantity: 3}}, tx.Redeem.Outputs)
+	require.Len(t, stx.Signatures, 1)
+	require.Equal(t, alice.Signer.Identity(), stx.Signatures[0].Signer)
+}
+
+func TestSignedTransactionMarshaling(t *testing.T) {
+	csp := newCSP(t)
+	issuer := newProver(t, csp)
+	stx, err := issuer.Issue("tx", &Token{Owner: []byte("owner"), Type: "USD", Quantity: 1})
+	require.NoError(t, err)
+
+	raw, err := stx.Marshal()
+	require.NoError(t, err)
+	decoded, err := UnmarshalSignedTransaction(raw)
+	require.NoError(t, err)
+	require.Equal(t, stx, decoded)
+
+	_, err = UnmarshalSignedTransaction([]byte("{"))
+	require.EqualError(t, err, "failed unmarshaling signed token transaction: unexpected end of JSON input")
+}
+
+func TestParseID(t *testing.T) {
+	id, err := ParseID("a:b:7")
+	require.NoError(t, err)
+	require.Equal(t, ID{TxID: "a:b", Index: 7}, id)
+	require.Equal(t, "a:b:7", id.String())
+
+	for _, s := range []string{"", "txid", ":1", "txid:", "txid:-1", "txid:4294967296"} {
+		_, err := ParseID(s)
+		require.EqualError(t, err, "invalid token ID ["+s+"], expected <txid>:<index>")
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	csp := newCSP(t)
+	signer := newProver(t, csp).Signer.(*BCCSPSigner)
+	pub, err := signer.key.PublicKey()
+	require.NoError(t, err)
+	_, err = NewSigner(csp, pub)
+	require.EqualError(t, err, "the signing key must be a private key")
+}
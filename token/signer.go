@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Signer signs token transactions on behalf of an owner or an issuer.
+type Signer interface {
+	// Identity returns the PKIX encoded public key of the signer
+	Identity() []byte
+	// Sign signs the given message
+	Sign(msg []byte) ([]byte, error)
+}
+
+// BCCSPSigner is a Signer whose private key is held by a BCCSP.
+type BCCSPSigner struct {
+	csp      bccsp.BCCSP
+	key      bccsp.Key
+	identity []byte
+}
+
+// NewSigner creates a Signer signing with the given private key.
+func NewSigner(csp bccsp.BCCSP, key bccsp.Key) (*BCCSPSigner, error) {
+	if !key.Private() {
+		return nil, errors.New("the signing key must be a private key")
+	}
+	pub, err := key.PublicKey()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting the public key")
+	}
+	identity, err := pub.Bytes()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed marshaling the public key")
+	}
+	return &BCCSPSigner{csp: csp, key: key, identity: identity}, nil
+}
+
+// Identity returns the PKIX encoded public key of the signer.
+func (s *BCCSPSigner) Identity() []byte {
+	return s.identity
+}
+
+// Sign signs the SHA-256 digest of the given message.
+func (s *BCCSPSigner) Sign(msg []byte) ([]byte, error) {
+	digest, err := s.csp.Hash(msg, &bccsp.SHA256Opts{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed computing digest")
+	}
+	return s.csp.Sign(s.key, digest, nil)
+}
+
+// verify checks the signature of the given message by the given PKIX encoded public key.
+func verify(csp bccsp.BCCSP, signer, signature, msg []byte) error {
+	key, err := csp.KeyImport(signer, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return errors.WithMessage(err, "failed importing the public key of the signer")
+	}
+	digest, err := csp.Hash(msg, &bccsp.SHA256Opts{})
+	if err != nil {
+		return errors.WithMessage(err, "failed computing digest")
+	}
+	valid, err := csp.Verify(key, signature, digest, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed verifying the signature")
+	}
+	if !valid {
+		return errors.New("the signature is invalid")
+	}
+	return nil
+}
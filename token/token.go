@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package token implements fungible tokens kept as unspent transaction outputs
+// (UTXOs) in a key value state, such as the state of a chaincode.
+//
+// Tokens are issued by authorized issuers, transferred by their owners, and
+// redeemed by their owners to take them out of circulation. The Prover builds
+// and signs the transactions of a client, and the Validator checks them and
+// applies them to the state. Owners and issuers are identified by their
+// PKIX encoded public keys, and sign the transactions through BCCSP.
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyPrefix is the prefix of the keys of the unspent tokens in the state
+const keyPrefix = "token:"
+
+// ID identifies a token by the transaction that created it, and its index
+// among the outputs of that transaction.
+type ID struct {
+	TxID  string `json:"tx_id"`
+	Index uint32 `json:"index"`
+}
+
+func (id ID) String() string {
+	return fmt.Sprintf("%s:%d", id.TxID, id.Index)
+}
+
+// Token is an amount of tokens of a given type owned by a public key.
+type Token struct {
+	// Owner is the PKIX encoded public key of the owner
+	Owner []byte `json:"owner"`
+	// Type is the type of the token, such as a currency
+	Type string `json:"type"`
+	// Quantity is the amount of tokens
+	Quantity uint64 `json:"quantity"`
+}
+
+// Transaction is a token transaction, which is exactly one of an issue,
+// a transfer, or a redemption.
+type Transaction struct {
+	// TxID is the ID of the Fabric transaction carrying the token transaction,
+	// whose uniqueness is enforced by the ledger. The outputs of the token
+	// transaction are identified by it.
+	TxID     string    `json:"tx_id"`
+	Issue    *Issue    `json:"issue,omitempty"`
+	Transfer *Transfer `json:"transfer,omitempty"`
+	Redeem   *Redeem   `json:"redeem,omitempty"`
+}
+
+// Issue creates new tokens. It is signed by the issuer.
+type Issue struct {
+	// Issuer is the PKIX encoded public key of the issuer
+	Issuer  []byte   `json:"issuer"`
+	Outputs []*Token `json:"outputs"`
+}
+
+// Transfer spends tokens and creates tokens of the same type and total quantity.
+// It is signed by the owners of the spent tokens.
+type Transfer struct {
+	Inputs  []ID     `json:"inputs"`
+	Outputs []*Token `json:"outputs"`
+}
+
+// Redeem spends tokens and creates tokens of the same type and a lower total
+// quantity, usually the change of the owner. The difference is taken out of
+// circulation. It is signed by the owners of the spent tokens.
+type Redeem struct {
+	Inputs  []ID     `json:"inputs"`
+	Outputs []*Token `json:"outputs,omitempty"`
+}
+
+// Signature is the signature of a transaction by an owner or an issuer.
+type Signature struct {
+	// Signer is the PKIX encoded public key of the signer
+	Signer    []byte `json:"signer"`
+	Signature []byte `json:"signature"`
+}
+
+// SignedTransaction is a marshaled Transaction along with the signatures over it.
+type SignedTransaction struct {
+	Transaction []byte       `json:"transaction"`
+	Signatures  []*Signature `json:"signatures"`
+}
+
+// Marshal encodes the signed transaction.
+func (stx *SignedTransaction) Marshal() ([]byte, error) {
+	return json.Marshal(stx)
+}
+
+// UnmarshalSignedTransaction decodes a signed transaction.
+func UnmarshalSignedTransaction(raw []byte) (*SignedTransaction, error) {
+	stx := &SignedTransaction{}
+	if err := json.Unmarshal(raw, stx); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling signed token transaction")
+	}
+	return stx, nil
+}
+
+// State is the key value state the unspent tokens are kept in.
+// It is implemented by the stub of a chaincode.
+type State interface {
+	GetState(key string) ([]byte, error)
+	PutState(key string, value []byte) error
+	DelState(key string) error
+}
+
+// Unspent returns the unspent token of the given ID, or nil if the token
+// doesn't exist or has been spent.
+func Unspent(state State, id ID) (*Token, error) {
+	raw, err := state.GetState(stateKey(id))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed reading token %s", id)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	t := &Token{}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshaling token %s", id)
+	}
+	return t, nil
+}
+
+func stateKey(id ID) string {
+	return keyPrefix + id.TxID + ":" + strconv.FormatUint(uint64(id.Index), 10)
+}
+
+// ParseID parses the string representation of an ID, such as 'txid:0'.
+func ParseID(s string) (ID, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return ID{}, errors.Errorf("invalid token ID [%s], expected <txid>:<index>", s)
+	}
+	index, err := strconv.ParseUint(s[i+1:], 10, 32)
+	if err != nil {
+		return ID{}, errors.Errorf("invalid token ID [%s], expected <txid>:<index>", s)
+	}
+	return ID{TxID: s[:i], Index: uint32(index)}, nil
+}
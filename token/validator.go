@@ -0,0 +1,225 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+// Validator checks token transactions against the unspent tokens in the state,
+// and applies the valid ones to it.
+type Validator struct {
+	// CSP verifies the signatures of the transactions
+	CSP bccsp.BCCSP
+	// Issuers are the PKIX encoded public keys of the issuers allowed to issue tokens
+	Issuers [][]byte
+}
+
+// Validate checks that the signed transaction is a well formed transaction carried by
+// the Fabric transaction of the given ID, that it spends unspent tokens only, that it
+// preserves the quantity of the spent tokens, and that it is signed by the owners of the
+// spent tokens or by an authorized issuer. It returns the transaction.
+func (v *Validator) Validate(state State, txID string, stx *SignedTransaction) (*Transaction, error) {
+	tx := &Transaction{}
+	if err := json.Unmarshal(stx.Transaction, tx); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling token transaction")
+	}
+	if tx.TxID != txID {
+		return nil, errors.Errorf("token transaction is bound to transaction %s, not %s", tx.TxID, txID)
+	}
+
+	var err error
+	switch {
+	case tx.Issue != nil && tx.Transfer == nil && tx.Redeem == nil:
+		err = v.validateIssue(stx, tx.Issue)
+	case tx.Transfer != nil && tx.Issue == nil && tx.Redeem == nil:
+		err = v.validateTransfer(state, stx, tx.Transfer)
+	case tx.Redeem != nil && tx.Issue == nil && tx.Transfer == nil:
+		err = v.validateRedeem(state, stx, tx.Redeem)
+	default:
+		err = errors.New("a token transaction must be exactly one of an issue, a transfer, or a redemption")
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid token transaction %s", txID)
+	}
+	return tx, nil
+}
+
+// Process validates the signed transaction, and applies it to the state: the spent tokens
+// are removed, and the created tokens are added.
+func (v *Validator) Process(state State, txID string, stx *SignedTransaction) error {
+	tx, err := v.Validate(state, txID, stx)
+	if err != nil {
+		return err
+	}
+
+	var inputs []ID
+	var outputs []*Token
+	switch {
+	case tx.Issue != nil:
+		outputs = tx.Issue.Outputs
+	case tx.Transfer != nil:
+		inputs, outputs = tx.Transfer.Inputs, tx.Transfer.Outputs
+	case tx.Redeem != nil:
+		inputs, outputs = tx.Redeem.Inputs, tx.Redeem.Outputs
+	}
+	for _, id := range inputs {
+		if err := state.DelState(stateKey(id)); err != nil {
+			return errors.WithMessagef(err, "failed spending token %s", id)
+		}
+	}
+	for i, t := range outputs {
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return errors.Wrap(err, "failed marshaling token")
+		}
+		id := ID{TxID: txID, Index: uint32(i)}
+		if err := state.PutState(stateKey(id), raw); err != nil {
+			return errors.WithMessagef(err, "failed storing token %s", id)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateIssue(stx *SignedTransaction, issue *Issue) error {
+	if !v.isIssuer(issue.Issuer) {
+		return errors.New("the issuer is not authorized to issue tokens")
+	}
+	if len(issue.Outputs) == 0 {
+		return errors.New("an issue must create tokens")
+	}
+	if _, err := sumOutputs(issue.Outputs, issue.Outputs[0].Type); err != nil {
+		return err
+	}
+	return v.verifySignatures(stx, [][]byte{issue.Issuer})
+}
+
+func (v *Validator) validateTransfer(state State, stx *SignedTransaction, transfer *Transfer) error {
+	tokenType, in, owners, err := v.spend(state, transfer.Inputs)
+	if err != nil {
+		return err
+	}
+	if len(transfer.Outputs) == 0 {
+		return errors.New("a transfer must create tokens")
+	}
+	out, err := sumOutputs(transfer.Outputs, tokenType)
+	if err != nil {
+		return err
+	}
+	if in != out {
+		return errors.Errorf("the transfer spends %d tokens but creates %d", in, out)
+	}
+	return v.verifySignatures(stx, owners)
+}
+
+func (v *Validator) validateRedeem(state State, stx *SignedTransaction, redeem *Redeem) error {
+	tokenType, in, owners, err := v.spend(state, redeem.Inputs)
+	if err != nil {
+		return err
+	}
+	out, err := sumOutputs(redeem.Outputs, tokenType)
+	if err != nil {
+		return err
+	}
+	if out >= in {
+		return errors.Errorf("the redemption spends %d tokens but leaves %d, nothing is redeemed", in, out)
+	}
+	return v.verifySignatures(stx, owners)
+}
+
+// spend checks that the inputs are distinct unspent tokens of the same type, and returns
+// their type, their total quantity, and their owners
+func (v *Validator) spend(state State, inputs []ID) (tokenType string, total uint64, owners [][]byte, err error) {
+	if len(inputs) == 0 {
+		return "", 0, nil, errors.New("no tokens are spent")
+	}
+	seen := map[ID]struct{}{}
+	for _, id := range inputs {
+		if _, exists := seen[id]; exists {
+			return "", 0, nil, errors.Errorf("token %s is spent more than once", id)
+		}
+		seen[id] = struct{}{}
+
+		t, err := Unspent(state, id)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		if t == nil {
+			return "", 0, nil, errors.Errorf("token %s does not exist or has been spent", id)
+		}
+		if tokenType == "" {
+			tokenType = t.Type
+		}
+		if t.Type != tokenType {
+			return "", 0, nil, errors.Errorf("token %s is of type %s, expected %s", id, t.Type, tokenType)
+		}
+		if total+t.Quantity < total {
+			return "", 0, nil, errors.New("the total quantity of the spent tokens overflows")
+		}
+		total += t.Quantity
+		owners = append(owners, t.Owner)
+	}
+	return tokenType, total, owners, nil
+}
+
+func sumOutputs(outputs []*Token, tokenType string) (uint64, error) {
+	var total uint64
+	for i, t := range outputs {
+		switch {
+		case t == nil:
+			return 0, errors.Errorf("output %d is empty", i)
+		case len(t.Owner) == 0:
+			return 0, errors.Errorf("output %d has no owner", i)
+		case t.Type == "":
+			return 0, errors.Errorf("output %d has no type", i)
+		case t.Type != tokenType:
+			return 0, errors.Errorf("output %d is of type %s, expected %s", i, t.Type, tokenType)
+		case t.Quantity == 0:
+			return 0, errors.Errorf("output %d has no quantity", i)
+		case total+t.Quantity < total:
+			return 0, errors.New("the total quantity of the created tokens overflows")
+		}
+		total += t.Quantity
+	}
+	return total, nil
+}
+
+func (v *Validator) isIssuer(identity []byte) bool {
+	for _, issuer := range v.Issuers {
+		if bytes.Equal(issuer, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignatures checks that each of the given signers has signed the transaction
+func (v *Validator) verifySignatures(stx *SignedTransaction, signers [][]byte) error {
+	verified := map[string]bool{}
+	for _, signer := range signers {
+		if verified[string(signer)] {
+			continue
+		}
+		var err error = errors.New("no signature")
+		for _, s := range stx.Signatures {
+			if bytes.Equal(s.Signer, signer) {
+				if err = verify(v.CSP, signer, s.Signature, stx.Transaction); err == nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "the transaction is not signed by %x", signer)
+		}
+		verified[string(signer)] = true
+	}
+	return nil
+}
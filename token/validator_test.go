@@ -0,0 +1,237 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/bccsp/sw"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryState map[string][]byte
+
+func (s memoryState) GetState(key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func (s memoryState) PutState(key string, value []byte) error {
+	s[key] = value
+	return nil
+}
+
+func (s memoryState) DelState(key string) error {
+	delete(s, key)
+	return nil
+}
+
+func newCSP(t *testing.T) bccsp.BCCSP {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	return csp
+}
+
+func newProver(t *testing.T, csp bccsp.BCCSP) *Prover {
+	key, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	signer, err := NewSigner(csp, key)
+	require.NoError(t, err)
+	return &Prover{Signer: signer}
+}
+
+func TestLifecycle(t *testing.T) {
+	csp := newCSP(t)
+	issuer, alice, bob := newProver(t, csp), newProver(t, csp), newProver(t, csp)
+	v := &Validator{CSP: csp, Issuers: [][]byte{issuer.Signer.Identity()}}
+	state := memoryState{}
+
+	// the issuer issues 100 USD to alice
+	stx, err := issuer.Issue("tx1", &Token{Owner: alice.Signer.Identity(), Type: "USD", Quantity: 100})
+	require.NoError(t, err)
+	require.NoError(t, v.Process(state, "tx1", stx))
+	minted, err := Unspent(state, ID{TxID: "tx1", Index: 0})
+	require.NoError(t, err)
+	require.Equal(t, &Token{Owner: alice.Signer.Identity(), Type: "USD", Quantity: 100}, minted)
+
+	// alice transfers 30 USD to bob and keeps the change
+	stx, err = alice.Transfer("tx2", []ID{{TxID: "tx1", Index: 0}},
+		&Token{Owner: bob.Signer.Identity(), Type: "USD", Quantity: 30},
+		&Token{Owner: alice.Signer.Identity(), Type: "USD", Quantity: 70},
+	)
+	require.NoError(t, err)
+	require.NoError(t, v.Process(state, "tx2", stx))
+	spent, err := Unspent(state, ID{TxID: "tx1", Index: 0})
+	require.NoError(t, err)
+	require.Nil(t, spent)
+
+	// the spent token cannot be spent again
+	stx, err = alice.Transfer("tx3", []ID{{TxID: "tx1", Index: 0}}, &Token{Owner: bob.Signer.Identity(), Type: "USD", Quantity: 100})
+	require.NoError(t, err)
+	require.EqualError(t, v.Process(state, "tx3", stx), "invalid token transaction tx3: token tx1:0 does not exist or has been spent")
+
+	// bob redeems 10 USD out of his 30
+	received, err := Unspent(state, ID{TxID: "tx2", Index: 0})
+	require.NoError(t, err)
+	stx, err = bob.Redeem("tx4", []*UnspentToken{{ID: ID{TxID: "tx2", Index: 0}, Token: received}}, 10)
+	require.NoError(t, err)
+	require.NoError(t, v.Process(state, "tx4", stx))
+	change, err := Unspent(state, ID{TxID: "tx4", Index: 0})
+	require.NoError(t, err)
+	require.Equal(t, &Token{Owner: bob.Signer.Identity(), Type: "USD", Quantity: 20}, change)
+	require.Len(t, state, 2)
+}
+
+func TestValidate(t *testing.T) {
+	csp := newCSP(t)
+	issuer, alice, bob := newProver(t, csp), newProver(t, csp), newProver(t, csp)
+	v := &Validator{CSP: csp, Issuers: [][]byte{issuer.Signer.Identity()}}
+
+	usd := func(owner *Prover, quantity uint64) *Token {
+		return &Token{Owner: owner.Signer.Identity(), Type: "USD", Quantity: quantity}
+	}
+	newState := func() memoryState {
+		state := memoryState{}
+		for i, t := range []*Token{usd(alice, 10), usd(alice, 20), usd(bob, 30), {Owner: alice.Signer.Identity(), Type: "EUR", Quantity: 5}} {
+			raw, _ := json.Marshal(t)
+			state[stateKey(ID{TxID: "mint", Index: uint32(i)})] = raw
+		}
+		return state
+	}
+	signed := func(tx *Transaction, signers ...*Prover) *SignedTransaction {
+		raw, err := json.Marshal(tx)
+		require.NoError(t, err)
+		stx := &SignedTransaction{Transaction: raw}
+		for _, s := range signers {
+			require.NoError(t, stx.AddSignature(s.Signer))
+		}
+		return stx
+	}
+	mint := func(index uint32) ID {
+		return ID{TxID: "mint", Index: index}
+	}
+
+	tests := []struct {
+		name     string
+		stx      *SignedTransaction
+		expected string
+	}{
+		{
+			name:     "malformed",
+			stx:      &SignedTransaction{Transaction: []byte("{")},
+			expected: "failed unmarshaling token transaction: unexpected end of JSON input",
+		},
+		{
+			name:     "other transaction",
+			stx:      signed(&Transaction{TxID: "other", Issue: &Issue{Issuer: issuer.Signer.Identity(), Outputs: []*Token{usd(bob, 1)}}}, issuer),
+			expected: "token transaction is bound to transaction other, not tx",
+		},
+		{
+			name:     "no operation",
+			stx:      signed(&Transaction{TxID: "tx"}),
+			expected: "invalid token transaction tx: a token transaction must be exactly one of an issue, a transfer, or a redemption",
+		},
+		{
+			name:     "unauthorized issuer",
+			stx:      signed(&Transaction{TxID: "tx", Issue: &Issue{Issuer: alice.Signer.Identity(), Outputs: []*Token{usd(alice, 1)}}}, alice),
+			expected: "invalid token transaction tx: the issuer is not authorized to issue tokens",
+		},
+		{
+			name:     "issue without outputs",
+			stx:      signed(&Transaction{TxID: "tx", Issue: &Issue{Issuer: issuer.Signer.Identity()}}, issuer),
+			expected: "invalid token transaction tx: an issue must create tokens",
+		},
+		{
+			name:     "issue of zero tokens",
+			stx:      signed(&Transaction{TxID: "tx", Issue: &Issue{Issuer: issuer.Signer.Identity(), Outputs: []*Token{usd(alice, 0)}}}, issuer),
+			expected: "invalid token transaction tx: output 0 has no quantity",
+		},
+		{
+			name: "issue signed by other",
+			stx:  signed(&Transaction{TxID: "tx", Issue: &Issue{Issuer: issuer.Signer.Identity(), Outputs: []*Token{usd(alice, 1)}}}, alice),
+			expected: "invalid token transaction tx: the transaction is not signed by " +
+				hexOf(issuer.Signer.Identity()) + ": no signature",
+		},
+		{
+			name:     "unbalanced transfer",
+			stx:      signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(0), mint(1)}, Outputs: []*Token{usd(bob, 31)}}}, alice),
+			expected: "invalid token transaction tx: the transfer spends 30 tokens but creates 31",
+		},
+		{
+			name:     "transfer of mixed types",
+			stx:      signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(0), mint(3)}, Outputs: []*Token{usd(bob, 15)}}}, alice),
+			expected: "invalid token transaction tx: token mint:3 is of type EUR, expected USD",
+		},
+		{
+			name:     "transfer changing the type",
+			stx:      signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(3)}, Outputs: []*Token{usd(bob, 5)}}}, alice),
+			expected: "invalid token transaction tx: output 0 is of type USD, expected EUR",
+		},
+		{
+			name:     "double spend",
+			stx:      signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(0), mint(0)}, Outputs: []*Token{usd(bob, 20)}}}, alice),
+			expected: "invalid token transaction tx: token mint:0 is spent more than once",
+		},
+		{
+			name: "transfer of tokens of others",
+			stx:  signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(0), mint(2)}, Outputs: []*Token{usd(alice, 40)}}}, alice),
+			expected: "invalid token transaction tx: the transaction is not signed by " +
+				hexOf(bob.Signer.Identity()) + ": no signature",
+		},
+		{
+			name: "forged signature",
+			stx: func() *SignedTransaction {
+				stx := signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(2)}, Outputs: []*Token{usd(alice, 30)}}}, alice)
+				stx.Signatures[0].Signer = bob.Signer.Identity()
+				return stx
+			}(),
+			expected: "invalid token transaction tx: the transaction is not signed by " +
+				hexOf(bob.Signer.Identity()) + ": the signature is invalid",
+		},
+		{
+			name:     "redemption of nothing",
+			stx:      signed(&Transaction{TxID: "tx", Redeem: &Redeem{Inputs: []ID{mint(0)}, Outputs: []*Token{usd(alice, 10)}}}, alice),
+			expected: "invalid token transaction tx: the redemption spends 10 tokens but leaves 10, nothing is redeemed",
+		},
+		{
+			name:     "redemption without inputs",
+			stx:      signed(&Transaction{TxID: "tx", Redeem: &Redeem{}}, alice),
+			expected: "invalid token transaction tx: no tokens are spent",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := v.Validate(newState(), "tx", test.stx)
+			require.EqualError(t, err, test.expected)
+		})
+	}
+
+	t.Run("transfer of tokens of several owners", func(t *testing.T) {
+		stx := signed(&Transaction{TxID: "tx", Transfer: &Transfer{Inputs: []ID{mint(0), mint(2)}, Outputs: []*Token{usd(alice, 40)}}}, alice, bob)
+		_, err := v.Validate(newState(), "tx", stx)
+		require.NoError(t, err)
+	})
+
+	t.Run("state failure", func(t *testing.T) {
+		stx := signed(&Transaction{TxID: "tx", Redeem: &Redeem{Inputs: []ID{mint(0)}}}, alice)
+		_, err := v.Validate(failingState{}, "tx", stx)
+		require.EqualError(t, err, "invalid token transaction tx: failed reading token mint:0: unavailable")
+	})
+}
+
+type failingState struct{}
+
+func (failingState) GetState(string) ([]byte, error) { return nil, errors.New("unavailable") }
+func (failingState) PutState(string, []byte) error   { return errors.New("unavailable") }
+func (failingState) DelState(string) error           { return errors.New("unavailable") }
+
+func hexOf(b []byte) string {
+	return fmt.Sprintf("%x", b)
+}